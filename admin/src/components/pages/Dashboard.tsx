@@ -155,7 +155,10 @@ class DashboardService {
   async getPrintJobTrends(): Promise<{ dates: string[]; completed: number[]; failed: number[] }> {
     try {
       const token = await this.getToken();
-      const response = await fetch('/api/v1/admin/dashboard/trends', {
+      // 按浏览器所在时区划分自然日
+      const tz = Intl.DateTimeFormat().resolvedOptions().timeZone;
+      const query = tz ? `?tz=${encodeURIComponent(tz)}` : '';
+      const response = await fetch(`/api/v1/admin/dashboard/trends${query}`, {
         headers: {
           ...(token && { 'Authorization': `Bearer ${token}` }),
         },
@@ -19,6 +19,7 @@ interface EdgeNode {
   last_heartbeat: string;
   version: string;
   printer_count: number;  // 后端返回的打印机数量字段
+  row_version: number;    // 数据版本号，修改时原样提交，防止覆盖其他管理员的修改
   key?: string;
 }
 
@@ -65,7 +66,7 @@ class EdgeNodesService {
     return [];
   }
 
-  async updateEdgeNode(id: string, name: string): Promise<boolean> {
+  async updateEdgeNode(id: string, name: string, rowVersion: number): Promise<boolean> {
     try {
       const token = await this.getToken();
       const response = await fetch(`/api/v1/admin/edge-nodes/${id}`, {
@@ -74,7 +75,7 @@ class EdgeNodesService {
           'Content-Type': 'application/json',
           ...(token && { 'Authorization': `Bearer ${token}` }),
         },
-        body: JSON.stringify({ name: name.trim() }),
+        body: JSON.stringify({ name: name.trim(), row_version: rowVersion }),
       });
       
       return response.ok;
@@ -84,7 +85,7 @@ class EdgeNodesService {
     }
   }
 
-  async updateEdgeNodeEnabled(id: string, enabled: boolean): Promise<boolean> {
+  async updateEdgeNodeEnabled(id: string, enabled: boolean, rowVersion: number): Promise<boolean> {
     try {
       const token = await this.getToken();
       // 先获取当前的Edge Node信息
@@ -101,7 +102,7 @@ class EdgeNodesService {
           'Content-Type': 'application/json',
           ...(token && { 'Authorization': `Bearer ${token}` }),
         },
-        body: JSON.stringify({ name: currentNode.name, enabled }),
+        body: JSON.stringify({ name: currentNode.name, enabled, row_version: rowVersion }),
       });
       
       return response.ok;
@@ -154,7 +155,7 @@ const EdgeNodes: React.FC = () => {
     if (!editingNode) return;
 
     try {
-      const success = await edgeNodesService.updateEdgeNode(editingNode.id, values.name);
+      const success = await edgeNodesService.updateEdgeNode(editingNode.id, values.name, editingNode.row_version);
       if (success) {
         message.success('Edge Node名称修改成功');
         setEditModalVisible(false);
@@ -174,7 +175,7 @@ const EdgeNodes: React.FC = () => {
   const handleToggleEnabled = async (node: EdgeNode) => {
     try {
       const newEnabled = !node.enabled;
-      const success = await edgeNodesService.updateEdgeNodeEnabled(node.id, newEnabled);
+      const success = await edgeNodesService.updateEdgeNodeEnabled(node.id, newEnabled, node.row_version);
       if (success) {
         message.success(`Edge Node已${newEnabled ? '启用' : '禁用'}`);
         loadEdgeNodes(); // 重新加载数据
@@ -9,10 +9,22 @@ import {
   PrinterOutlined
 } from '@ant-design/icons';
 
+// 提交来源的显示名称
+const sourceLabels: Record<string, string> = {
+  console: '管理后台',
+  api: '用户 API',
+  integration: '第三方集成',
+  email: '邮件',
+  hot_folder: '共享文件夹',
+  unknown: '未知',
+};
+
 // 打印任务接口定义
 interface PrintJob {
   id: string;
   name: string;
+  display_name?: string;
+  source?: 'console' | 'api' | 'integration' | 'email' | 'hot_folder' | 'unknown';
   user_name: string;
   printer_id: string;
   status: 'pending' | 'dispatched' | 'downloading' | 'printing' | 'completed' | 'failed' | 'cancelled';
@@ -59,6 +71,15 @@ interface Printer {
   };
 }
 
+// 能力校验不符合项（重新打印返回 400 时的 violations）
+interface CapabilityViolation {
+  field: string;
+  code: string;
+  message: string;
+  allowed?: string[];
+  max?: number;
+}
+
 // 重新打印表单数据
 interface ReprintFormData {
   edge_node_id: string;
@@ -385,6 +406,20 @@ const PrintJobs: React.FC = () => {
         loadPrintJobs(currentPage, pageSize, statusFilter);
       } else {
         const result = await response.json();
+        // 能力校验失败时逐项列出全部不符合项
+        if (Array.isArray(result.violations) && result.violations.length > 0) {
+          Modal.error({
+            title: '所选打印机不满足任务要求',
+            content: (
+              <ul style={{ paddingLeft: 20, margin: 0 }}>
+                {result.violations.map((v: CapabilityViolation, i: number) => (
+                  <li key={i}>{v.message}</li>
+                ))}
+              </ul>
+            ),
+          });
+          return;
+        }
         message.error(result.error || '重新打印任务创建失败');
       }
     } catch (error) {
@@ -400,14 +435,18 @@ const PrintJobs: React.FC = () => {
       dataIndex: 'name',
       key: 'name',
       width: 200,
-      render: (text: string) => (
-        <Space>
-          <FileTextOutlined />
-          <span title={text}>
-            {text && text.length > 30 ? `${text.substring(0, 30)}...` : text}
-          </span>
-        </Space>
-      ),
+      render: (name: string, record: PrintJob) => {
+        // 优先显示面向用户的名称，悬停时显示内部名称
+        const text = record.display_name || name;
+        return (
+          <Space>
+            <FileTextOutlined />
+            <span title={name}>
+              {text && text.length > 30 ? `${text.substring(0, 30)}...` : text}
+            </span>
+          </Space>
+        );
+      },
     },
     {
       title: '用户',
@@ -437,6 +476,12 @@ const PrintJobs: React.FC = () => {
       key: 'status',
       render: (status: string) => getStatusTag(status),
     },
+    {
+      title: '来源',
+      dataIndex: 'source',
+      key: 'source',
+      render: (source: string) => sourceLabels[source] || source || '-',
+    },
     {
       title: '页数',
       dataIndex: 'page_count',
@@ -575,7 +620,7 @@ const PrintJobs: React.FC = () => {
 
       {/* 重新打印Modal */}
       <Modal
-        title={`重新打印 - ${reprintJob?.name}`}
+        title={`重新打印 - ${reprintJob?.display_name || reprintJob?.name}`}
         open={reprintModalVisible}
         onCancel={() => {
           setReprintModalVisible(false);
@@ -25,6 +25,7 @@ interface PrinterStatus {
   edge_node_id: string;
   edge_node_name?: string; // Edge Node 名称
   queue_length: number;
+  row_version: number; // 数据版本号，修改时原样提交，防止覆盖其他管理员的修改
   key?: string;
 }
 
@@ -219,7 +220,8 @@ const Printers: React.FC = () => {
           'Authorization': `Bearer ${token}`,
         },
         body: JSON.stringify({
-          display_name: values.display_name.trim()
+          display_name: values.display_name.trim(),
+          row_version: editingPrinter.row_version,
         }),
       });
 
@@ -256,7 +258,8 @@ const Printers: React.FC = () => {
           'Authorization': `Bearer ${token}`,
         },
         body: JSON.stringify({
-          enabled: newEnabled
+          enabled: newEnabled,
+          row_version: printer.row_version,
         }),
       });
 
@@ -0,0 +1,79 @@
+// edge-simulator 模拟 Edge Node，用于在没有打印机硬件的环境中联调任务分发流程
+//
+// 模拟器通过 REST 注册节点和若干台虚拟打印机，随后建立 WebSocket 连接，定期上报心跳和打印机状态，
+// 收到 print_job 指令后依次上报 accepted → printing → completed（或按失败率注入 failed）：
+//
+//	go run ./cmd/edge-simulator -server http://localhost:8080 -token $TOKEN -printers 3 -failure-rate 0.1
+//
+// token 需要 edge:register、edge:connect、edge:printer:write 和 edge:job:update 权限；
+// 节点和打印机已注册时可使用 -register=false 跳过注册（此时不需要 edge:register）。
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	opts := defaultOptions()
+	var paperSizes string
+	flag.StringVar(&opts.ServerURL, "server", "http://localhost:8080", "云端地址（不含 /api/v1）")
+	flag.StringVar(&opts.Token, "token", os.Getenv("FLY_PRINT_EDGE_TOKEN"), "节点 OAuth2 access token，默认读取 FLY_PRINT_EDGE_TOKEN")
+	flag.StringVar(&opts.NodeID, "node-id", opts.NodeID, "节点ID")
+	flag.StringVar(&opts.NodeName, "node-name", opts.NodeName, "节点名称（注册时使用）")
+	flag.BoolVar(&opts.Register, "register", opts.Register, "启动时通过 REST 注册节点和打印机")
+	flag.BoolVar(&opts.DeferredAuth, "deferred-auth", opts.DeferredAuth, "使用延迟认证（?auth=deferred，连接后第一帧发送 token）")
+	flag.IntVar(&opts.Printers, "printers", opts.Printers, "虚拟打印机数量")
+	flag.StringVar(&opts.PrinterPrefix, "printer-prefix", opts.PrinterPrefix, "虚拟打印机名称前缀，名称为 <前缀>-<序号>")
+	flag.StringVar(&opts.PrinterModel, "printer-model", opts.PrinterModel, "虚拟打印机型号")
+	flag.StringVar(&paperSizes, "paper-sizes", strings.Join(opts.Capabilities.PaperSizes, ","), "支持的纸张尺寸，逗号分隔")
+	flag.BoolVar(&opts.Capabilities.ColorSupport, "color", opts.Capabilities.ColorSupport, "是否支持彩色")
+	flag.BoolVar(&opts.Capabilities.DuplexSupport, "duplex", opts.Capabilities.DuplexSupport, "是否支持双面")
+	flag.DurationVar(&opts.HeartbeatInterval, "heartbeat-interval", opts.HeartbeatInterval, "心跳上报间隔")
+	flag.DurationVar(&opts.StatusInterval, "status-interval", opts.StatusInterval, "打印机状态上报间隔")
+	flag.DurationVar(&opts.Latency, "latency", opts.Latency, "收到任务到上报 accepted 的延迟")
+	flag.DurationVar(&opts.PageTime, "page-time", opts.PageTime, "每页打印耗时（总耗时 = 页数 × 份数 × page-time）")
+	flag.Float64Var(&opts.Jitter, "jitter", opts.Jitter, "延迟随机抖动比例（0-1）")
+	flag.Float64Var(&opts.FailureRate, "failure-rate", opts.FailureRate, "任务失败概率（0-1），失败发生在打印过程中的随机进度")
+	flag.StringVar(&opts.FailureCode, "failure-code", opts.FailureCode, "注入失败时上报的错误码")
+	flag.StringVar(&opts.FailureMessage, "failure-message", opts.FailureMessage, "注入失败时上报的错误信息")
+	flag.BoolVar(&opts.Reconnect, "reconnect", opts.Reconnect, "连接断开后自动重连")
+	flag.DurationVar(&opts.ReconnectDelay, "reconnect-delay", opts.ReconnectDelay, "重连间隔")
+	flag.Int64Var(&opts.Seed, "seed", 0, "随机数种子，0 表示使用当前时间")
+	flag.Parse()
+
+	opts.Capabilities.PaperSizes = splitList(paperSizes)
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+
+	sim, err := NewSimulator(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 收到 SIGINT/SIGTERM 时关闭连接并退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := sim.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// splitList 解析逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
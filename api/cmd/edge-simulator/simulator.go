@@ -0,0 +1,667 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/version"
+	edgews "fly-print-cloud/api/internal/websocket"
+	"fly-print-cloud/api/pkg/client"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// 写入等待时间
+	writeWait = 10 * time.Second
+
+	// 握手超时时间
+	handshakeTimeout = 10 * time.Second
+
+	// 主动关闭连接时等待云端回应关闭帧的时间
+	closeGracePeriod = time.Second
+
+	// 注入的错误信息长度上限，云端上行消息上限为 512 字节
+	maxFailureMessageLength = 200
+)
+
+// errNotConnected 当前没有可用的 WebSocket 连接
+var errNotConnected = errors.New("edge simulator: not connected")
+
+// options 模拟器配置
+type options struct {
+	ServerURL    string
+	Token        string
+	NodeID       string
+	NodeName     string
+	Register     bool
+	DeferredAuth bool
+
+	Printers      int
+	PrinterPrefix string
+	PrinterModel  string
+	Capabilities  models.PrinterCapabilities
+
+	HeartbeatInterval time.Duration
+	StatusInterval    time.Duration
+
+	Latency        time.Duration // 收到任务到上报 accepted 的延迟
+	PageTime       time.Duration // 每页打印耗时
+	Jitter         float64       // 延迟随机抖动比例
+	FailureRate    float64       // 任务失败概率
+	FailureCode    string
+	FailureMessage string
+
+	Reconnect      bool
+	ReconnectDelay time.Duration
+	Seed           int64
+}
+
+// defaultOptions 默认配置
+func defaultOptions() options {
+	return options{
+		NodeID:        "sim-node-1",
+		NodeName:      "Edge Simulator",
+		Register:      true,
+		Printers:      2,
+		PrinterPrefix: "sim-printer",
+		PrinterModel:  "Simulated LaserJet",
+		Capabilities: models.PrinterCapabilities{
+			PaperSizes:      []string{"A4", "Letter"},
+			ColorSupport:    true,
+			DuplexSupport:   true,
+			Resolution:      "600dpi",
+			PrintSpeed:      "30ppm",
+			MediaTypes:      []string{"plain"},
+			DocumentFormats: []string{"pdf"},
+		},
+		HeartbeatInterval: 30 * time.Second,
+		StatusInterval:    time.Minute,
+		Latency:           500 * time.Millisecond,
+		PageTime:          time.Second,
+		Jitter:            0.2,
+		FailureCode:       "SIM_INJECTED_FAILURE",
+		FailureMessage:    "simulated printer failure",
+		Reconnect:         true,
+		ReconnectDelay:    5 * time.Second,
+	}
+}
+
+// validate 校验配置
+func (o *options) validate() error {
+	switch {
+	case o.ServerURL == "":
+		return errors.New("server is required")
+	case o.Token == "":
+		return errors.New("token is required (-token or FLY_PRINT_EDGE_TOKEN)")
+	case o.NodeID == "":
+		return errors.New("node-id is required")
+	case o.Printers < 1:
+		return errors.New("printers must be at least 1")
+	case o.HeartbeatInterval <= 0 || o.StatusInterval <= 0:
+		return errors.New("heartbeat-interval and status-interval must be positive")
+	case o.Latency < 0 || o.PageTime < 0 || o.ReconnectDelay < 0:
+		return errors.New("latency, page-time and reconnect-delay must not be negative")
+	case o.Jitter < 0 || o.Jitter > 1:
+		return errors.New("jitter must be between 0 and 1")
+	case o.FailureRate < 0 || o.FailureRate > 1:
+		return errors.New("failure-rate must be between 0 and 1")
+	case len(o.FailureMessage) > maxFailureMessageLength:
+		return fmt.Errorf("failure-message must not exceed %d bytes", maxFailureMessageLength)
+	}
+	return nil
+}
+
+// Simulator 模拟的 Edge Node
+type Simulator struct {
+	opts     options
+	printers []*simPrinter
+	byName   map[string]*simPrinter
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+
+	writeMu sync.Mutex
+	conn    *websocket.Conn // 当前连接，断开期间为 nil
+
+	api       *client.Client
+	connToken *client.ConnToken // 注册或 REST 心跳获取的连接令牌，建立连接前临近过期时续签
+
+	jobs sync.WaitGroup // 处理中的打印任务
+}
+
+// simPrinter 虚拟打印机
+type simPrinter struct {
+	name string
+
+	mu         sync.Mutex
+	activeJobs int
+	power      string
+}
+
+// NewSimulator 创建模拟器
+func NewSimulator(opts options) (*Simulator, error) {
+	if err := opts.validate(); err != nil {
+		return nil, fmt.Errorf("edge simulator: %w", err)
+	}
+
+	s := &Simulator{
+		opts:   opts,
+		byName: make(map[string]*simPrinter),
+		rng:    rand.New(rand.NewSource(opts.Seed)),
+	}
+	for i := 1; i <= opts.Printers; i++ {
+		printer := &simPrinter{name: fmt.Sprintf("%s-%d", opts.PrinterPrefix, i), power: models.PrinterPowerAwake}
+		s.printers = append(s.printers, printer)
+		s.byName[printer.name] = printer
+	}
+	return s, nil
+}
+
+// Run 注册节点和打印机后保持连接，直到 ctx 结束；未开启重连时连接断开即返回错误
+func (s *Simulator) Run(ctx context.Context) error {
+	if s.opts.Register {
+		if err := s.register(ctx); err != nil {
+			return err
+		}
+	}
+
+	// 退出前等待处理中的任务结束（任务在 ctx 结束时中止）
+	defer s.jobs.Wait()
+
+	for {
+		err := s.session(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !s.opts.Reconnect {
+			return err
+		}
+
+		log.Printf("Connection to %s lost: %v, reconnecting in %s", s.opts.ServerURL, err, s.opts.ReconnectDelay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.opts.ReconnectDelay):
+		}
+	}
+}
+
+// apiClient 调用云端 REST 接口的客户端
+func (s *Simulator) apiClient() (*client.Client, error) {
+	if s.api == nil {
+		c, err := client.New(s.opts.ServerURL, client.WithBearerToken(s.opts.Token), client.WithUserAgent("fly-print-edge-simulator"),
+			client.WithClientInfo(simulatorClientInfo.Name, simulatorClientInfo.Version, simulatorClientInfo.Platform))
+		if err != nil {
+			return nil, err
+		}
+		s.api = c
+	}
+	return s.api, nil
+}
+
+// register 通过 REST 注册节点和虚拟打印机（已存在时更新）
+func (s *Simulator) register(ctx context.Context) error {
+	c, err := s.apiClient()
+	if err != nil {
+		return err
+	}
+
+	node, err := c.EdgeNodes.Register(ctx, &client.RegisterEdgeNodeRequest{NodeID: s.opts.NodeID, Name: s.opts.NodeName})
+	if err != nil {
+		return fmt.Errorf("register edge node %s: %w", s.opts.NodeID, err)
+	}
+	if node.ConnToken != "" && node.ConnTokenExpiresAt != nil {
+		s.connToken = &client.ConnToken{Token: node.ConnToken, ExpiresAt: *node.ConnTokenExpiresAt}
+	}
+	log.Printf("Registered edge node %s", s.opts.NodeID)
+
+	for i, printer := range s.printers {
+		serial := fmt.Sprintf("SIM-%s-%03d", s.opts.NodeID, i+1)
+		result, err := c.EdgeNodes.RegisterPrinter(ctx, s.opts.NodeID, &client.EdgeRegisterPrinterRequest{
+			Name:         printer.name,
+			Model:        s.opts.PrinterModel,
+			SerialNumber: &serial,
+			Capabilities: s.opts.Capabilities,
+		})
+		if err != nil {
+			return fmt.Errorf("register printer %s: %w", printer.name, err)
+		}
+		if result.Approved {
+			log.Printf("Registered printer %s (%s)", printer.name, result.ID)
+		} else {
+			log.Printf("Registered printer %s (%s), awaiting admin review", printer.name, result.ID)
+		}
+	}
+	return nil
+}
+
+// refreshConnToken 连接令牌不存在或一分钟内过期时通过 REST 心跳续签；失败时不携带令牌连接（云端允许 node_id 参数时仍可连接）
+func (s *Simulator) refreshConnToken(ctx context.Context) {
+	if s.connToken != nil && time.Until(s.connToken.ExpiresAt) > time.Minute {
+		return
+	}
+	c, err := s.apiClient()
+	if err == nil {
+		var token *client.ConnToken
+		if token, err = c.EdgeNodes.HeartbeatConnToken(ctx, s.opts.NodeID); err == nil {
+			s.connToken = token
+			return
+		}
+	}
+	log.Printf("Failed to refresh connection token: %v", err)
+	s.connToken = nil
+}
+
+// session 建立一次 WebSocket 连接并处理到连接断开或 ctx 结束
+func (s *Simulator) session(ctx context.Context) error {
+	s.refreshConnToken(ctx)
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s.setConn(conn)
+	defer s.setConn(nil)
+
+	if s.opts.DeferredAuth {
+		if err := s.send(edgews.MsgTypeAuth, edgews.AuthData{Token: s.opts.Token, ClientInfo: simulatorClientInfo}); err != nil {
+			return fmt.Errorf("send auth message: %w", err)
+		}
+	}
+	log.Printf("Connected to %s as node %s (protocol %q)", s.opts.ServerURL, s.opts.NodeID, conn.Subprotocol())
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- s.readLoop(ctx, conn)
+	}()
+
+	s.sendHeartbeat()
+	s.reportPrinters()
+
+	heartbeat := time.NewTicker(s.opts.HeartbeatInterval)
+	defer heartbeat.Stop()
+	status := time.NewTicker(s.opts.StatusInterval)
+	defer status.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.writeMu.Lock()
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "simulator shutting down"),
+				time.Now().Add(writeWait))
+			s.writeMu.Unlock()
+			select {
+			case <-readErr:
+			case <-time.After(closeGracePeriod):
+			}
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case <-heartbeat.C:
+			s.sendHeartbeat()
+		case <-status.C:
+			s.reportPrinters()
+		}
+	}
+}
+
+// simulatorClientInfo 模拟器上报的客户端信息
+var simulatorClientInfo = &models.ClientInfo{Name: "fly-print-edge-simulator", Version: version.Version, Platform: runtime.GOOS}
+
+// dial 建立 WebSocket 连接，声明支持的子协议版本
+func (s *Simulator) dial(ctx context.Context) (*websocket.Conn, error) {
+	wsURL, err := s.wsURL()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: handshakeTimeout,
+		Subprotocols:     edgews.SupportedProtocolVersions,
+	}
+	header := http.Header{}
+	header.Set(models.ClientInfoHeader, simulatorClientInfo.String())
+	if !s.opts.DeferredAuth {
+		header.Set("Authorization", "Bearer "+s.opts.Token)
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("dial %s: %w (HTTP %d)", wsURL, err, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("dial %s: %w", wsURL, err)
+	}
+	return conn, nil
+}
+
+// wsURL 由云端地址生成 WebSocket 地址
+func (s *Simulator) wsURL() (string, error) {
+	u, err := url.Parse(strings.TrimRight(s.opts.ServerURL, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return "", fmt.Errorf("invalid server URL scheme %q", u.Scheme)
+	}
+
+	u.Path += "/api/v1/edge/ws"
+	query := url.Values{"node_id": {s.opts.NodeID}}
+	if s.connToken != nil {
+		query.Set("conn_token", s.connToken.Token)
+	}
+	if s.opts.DeferredAuth {
+		query.Set("auth", "deferred")
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// inboundCommand 云端下发的指令，data 按指令类型再解析
+type inboundCommand struct {
+	Type      string          `json:"type"`
+	CommandID string          `json:"command_id"`
+	Target    string          `json:"target"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// readLoop 读取并处理云端指令，直到连接断开
+func (s *Simulator) readLoop(ctx context.Context, conn *websocket.Conn) error {
+	for {
+		var cmd inboundCommand
+		if err := conn.ReadJSON(&cmd); err != nil {
+			return err
+		}
+		s.handleCommand(ctx, &cmd)
+	}
+}
+
+// handleCommand 按指令类型处理云端指令
+func (s *Simulator) handleCommand(ctx context.Context, cmd *inboundCommand) {
+	switch cmd.Type {
+	case edgews.CmdTypeWelcome:
+		var caps edgews.ServerCapabilities
+		if err := json.Unmarshal(cmd.Data, &caps); err != nil {
+			log.Printf("Failed to parse welcome message: %v", err)
+			return
+		}
+		log.Printf("Server %s (capabilities schema v%d), heartbeat timeout %ds",
+			caps.ServerVersion, caps.SchemaVersion, caps.HeartbeatTimeoutSeconds)
+		if timeout := time.Duration(caps.HeartbeatTimeoutSeconds) * time.Second; timeout > 0 && s.opts.HeartbeatInterval >= timeout {
+			log.Printf("Warning: heartbeat interval %s is not shorter than the server heartbeat timeout %s, node will be marked offline",
+				s.opts.HeartbeatInterval, timeout)
+		}
+
+	case edgews.CmdTypePrintJob:
+		var job edgews.PrintJobData
+		if err := json.Unmarshal(cmd.Data, &job); err != nil {
+			log.Printf("Failed to parse print job command %s: %v", cmd.CommandID, err)
+			return
+		}
+		log.Printf("Received print job %s for printer %s (%d page(s) x %d)", job.JobID, job.PrinterName, job.PageCount, job.Copies)
+		s.jobs.Add(1)
+		go s.runJob(ctx, &job)
+
+	case edgews.CmdTypeReportStatus:
+		s.sendHeartbeat()
+		s.reportPrinters()
+
+	case edgews.CmdTypePrinterPower:
+		var power edgews.PrinterPowerData
+		if err := json.Unmarshal(cmd.Data, &power); err != nil {
+			log.Printf("Failed to parse printer power command %s: %v", cmd.CommandID, err)
+			return
+		}
+		printer := s.byName[power.PrinterName]
+		if printer == nil {
+			log.Printf("Printer power command for unknown printer %s ignored", power.PrinterName)
+			return
+		}
+		printer.setPower(power.Action)
+		s.sendPrinterStatus(printer)
+
+	case edgews.CmdTypeError:
+		var rejected edgews.ErrorData
+		if err := json.Unmarshal(cmd.Data, &rejected); err == nil {
+			log.Printf("Server rejected %s message: %s (%s)", rejected.MessageType, rejected.Message, rejected.Code)
+		}
+
+	default:
+		log.Printf("Command %s (%s) is not simulated, ignoring", cmd.Type, cmd.CommandID)
+	}
+}
+
+// runJob 模拟打印过程：accepted → printing（按进度上报）→ completed，按失败率在随机进度上报 failed
+func (s *Simulator) runJob(ctx context.Context, job *edgews.PrintJobData) {
+	defer s.jobs.Done()
+
+	printer := s.byName[job.PrinterName]
+	if printer == nil {
+		s.sendJobUpdate(job.JobID, models.JobStatusFailed, 0, "printer not found on simulated node", "SIM_PRINTER_NOT_FOUND")
+		return
+	}
+
+	if !s.sleep(ctx, s.opts.Latency) {
+		return
+	}
+	s.sendJobUpdate(job.JobID, models.JobStatusAccepted, 0, "", "")
+
+	printer.begin()
+	s.sendPrinterStatus(printer)
+	defer func() {
+		printer.end()
+		s.sendPrinterStatus(printer)
+	}()
+
+	failAt := -1
+	if s.chance(s.opts.FailureRate) {
+		failAt = 1 + s.intn(99)
+	}
+
+	total := time.Duration(sheetCount(job)) * s.opts.PageTime
+	s.sendJobUpdate(job.JobID, models.JobStatusPrinting, 0, "", "")
+
+	progress := 0
+	for _, next := range []int{25, 50, 75, 100} {
+		if failAt >= 0 && failAt <= next {
+			if !s.sleep(ctx, total*time.Duration(failAt-progress)/100) {
+				return
+			}
+			s.sendJobUpdate(job.JobID, models.JobStatusFailed, failAt, s.opts.FailureMessage, s.opts.FailureCode)
+			log.Printf("Print job %s failed at %d%% (injected)", job.JobID, failAt)
+			return
+		}
+		if !s.sleep(ctx, total*time.Duration(next-progress)/100) {
+			return
+		}
+		progress = next
+		if progress < 100 {
+			s.sendJobUpdate(job.JobID, models.JobStatusPrinting, progress, "", "")
+		}
+	}
+
+	s.sendJobUpdate(job.JobID, models.JobStatusCompleted, 100, "", "")
+	log.Printf("Print job %s completed", job.JobID)
+}
+
+// sheetCount 任务的总打印页数（页数 × 份数），至少为 1
+func sheetCount(job *edgews.PrintJobData) int {
+	total := 0
+	if len(job.Files) > 0 {
+		for _, file := range job.Files {
+			total += max(file.PageCount, 1) * max(file.Copies, 1)
+		}
+	} else {
+		total = max(job.PageCount, 1) * max(job.Copies, 1)
+	}
+	return total
+}
+
+// sendJobUpdate 上报任务状态
+func (s *Simulator) sendJobUpdate(jobID string, status models.JobStatus, progress int, errorMessage, errorCode string) {
+	data := edgews.JobUpdateData{JobID: jobID, Status: status, Progress: progress}
+	if errorMessage != "" {
+		data.ErrorMessage = &errorMessage
+	}
+	if errorCode != "" {
+		data.ErrorCode = &errorCode
+	}
+	if err := s.send(edgews.MsgTypeJobUpdate, data); err != nil {
+		log.Printf("Failed to report job %s status %s: %v", jobID, status, err)
+	}
+}
+
+// sendHeartbeat 上报心跳和模拟的系统信息
+func (s *Simulator) sendHeartbeat() {
+	data := edgews.HeartbeatData{SystemInfo: edgews.SystemInfo{
+		CPUUsage:       5 + s.float64()*35,
+		MemoryUsage:    20 + s.float64()*40,
+		DiskUsage:      30 + s.float64()*20,
+		NetworkQuality: "good",
+		Latency:        10 + s.intn(40),
+	}}
+	if err := s.send(edgews.MsgTypeHeartbeat, data); err != nil {
+		log.Printf("Failed to send heartbeat: %v", err)
+	}
+}
+
+// reportPrinters 上报全部虚拟打印机的状态
+func (s *Simulator) reportPrinters() {
+	for _, printer := range s.printers {
+		s.sendPrinterStatus(printer)
+	}
+}
+
+// sendPrinterStatus 上报单台打印机的状态
+func (s *Simulator) sendPrinterStatus(printer *simPrinter) {
+	if err := s.send(edgews.MsgTypePrinterStatus, printer.statusData()); err != nil {
+		log.Printf("Failed to report printer %s status: %v", printer.name, err)
+	}
+}
+
+// send 在当前连接上发送上行消息
+func (s *Simulator) send(messageType string, data interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.conn == nil {
+		return errNotConnected
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return s.conn.WriteJSON(edgews.Message{
+		Type:      messageType,
+		NodeID:    s.opts.NodeID,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	})
+}
+
+// setConn 切换当前连接
+func (s *Simulator) setConn(conn *websocket.Conn) {
+	s.writeMu.Lock()
+	s.conn = conn
+	s.writeMu.Unlock()
+}
+
+// sleep 等待带抖动的时长，ctx 结束时返回 false
+func (s *Simulator) sleep(ctx context.Context, d time.Duration) bool {
+	if d > 0 && s.opts.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + s.opts.Jitter*(2*s.float64()-1)))
+	}
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// chance 以概率 p 返回 true
+func (s *Simulator) chance(p float64) bool {
+	return p > 0 && s.float64() < p
+}
+
+func (s *Simulator) float64() float64 {
+	s.rngMu.Lock()
+	defer s.rngMu.Unlock()
+	return s.rng.Float64()
+}
+
+func (s *Simulator) intn(n int) int {
+	s.rngMu.Lock()
+	defer s.rngMu.Unlock()
+	return s.rng.Intn(n)
+}
+
+// begin 开始打印一个任务
+func (p *simPrinter) begin() {
+	p.mu.Lock()
+	p.activeJobs++
+	p.power = models.PrinterPowerAwake // 收到任务的打印机会被唤醒
+	p.mu.Unlock()
+}
+
+// end 结束打印一个任务
+func (p *simPrinter) end() {
+	p.mu.Lock()
+	p.activeJobs--
+	p.mu.Unlock()
+}
+
+// setPower 执行电源指令
+func (p *simPrinter) setPower(action string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch action {
+	case models.PowerActionSleep:
+		if p.activeJobs == 0 {
+			p.power = models.PrinterPowerAsleep
+		}
+	case models.PowerActionWake:
+		p.power = models.PrinterPowerAwake
+	}
+}
+
+// statusData 当前状态的 printer_status 消息数据
+func (p *simPrinter) statusData() edgews.PrinterStatusData {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := models.PrinterStatusReady
+	if p.activeJobs > 0 {
+		status = models.PrinterStatusPrinting
+	}
+	return edgews.PrinterStatusData{
+		PrinterID:   p.name, // 云端按（名称, 节点）匹配打印机
+		Status:      status,
+		QueueLength: p.activeJobs,
+		Supplies:    map[string]interface{}{"toner": 80},
+		PowerState:  p.power,
+	}
+}
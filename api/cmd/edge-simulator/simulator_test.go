@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	edgews "fly-print-cloud/api/internal/websocket"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	testToken     = "edge-token"
+	testConnToken = "conn-token-1"
+)
+
+// uplink 模拟器发送的上行消息
+type uplink struct {
+	Type   string          `json:"type"`
+	NodeID string          `json:"node_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// handshake 云端收到的 WebSocket 握手
+type handshake struct {
+	authorization string
+	query         map[string]string
+	subprotocol   string
+	firstFrame    *uplink // 延迟认证时的第一帧
+}
+
+// fakeCloud 用 httptest 模拟云端的 Edge REST 接口和 WebSocket 端点
+type fakeCloud struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu       sync.Mutex
+	printers []string // 注册的打印机名称
+
+	handshakes chan handshake
+	conns      chan *websocket.Conn
+	messages   chan uplink
+}
+
+func newFakeCloud(t *testing.T, deferred bool) *fakeCloud {
+	f := &fakeCloud{
+		t:          t,
+		handshakes: make(chan handshake, 1),
+		conns:      make(chan *websocket.Conn, 1),
+		messages:   make(chan uplink, 256),
+	}
+	upgrader := websocket.Upgrader{Subprotocols: edgews.SupportedProtocolVersions}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/edge/register", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		expires := time.Now().Add(time.Hour).UTC()
+		writeData(w, http.StatusCreated, map[string]interface{}{
+			"id": "sim-node-1", "conn_token": testConnToken, "conn_token_expires_at": expires,
+		})
+	})
+	mux.HandleFunc("/api/v1/edge/sim-node-1/printers", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.printers = append(f.printers, req.Name)
+		f.mu.Unlock()
+		writeData(w, http.StatusCreated, map[string]interface{}{"id": "printer-" + req.Name, "name": req.Name, "approved": true})
+	})
+	mux.HandleFunc("/api/v1/edge/ws", func(w http.ResponseWriter, r *http.Request) {
+		hs := handshake{authorization: r.Header.Get("Authorization"), query: map[string]string{}}
+		for key := range r.URL.Query() {
+			hs.query[key] = r.URL.Query().Get(key)
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hs.subprotocol = conn.Subprotocol()
+		if deferred {
+			var first uplink
+			if err := conn.ReadJSON(&first); err != nil {
+				conn.Close()
+				return
+			}
+			hs.firstFrame = &first
+		}
+		f.handshakes <- hs
+		f.conns <- conn
+		for {
+			var msg uplink
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			f.messages <- msg
+		}
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// writeData 按 {code, message, data} 格式返回
+func writeData(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": status, "message": "success", "data": data})
+}
+
+// jobUpdates 收集任务状态上报，直到每个任务都上报了终态
+func (f *fakeCloud) jobUpdates(jobIDs ...string) map[string][]edgews.JobUpdateData {
+	f.t.Helper()
+	updates := map[string][]edgews.JobUpdateData{}
+	done := map[string]bool{}
+	deadline := time.After(10 * time.Second)
+	for len(done) < len(jobIDs) {
+		select {
+		case msg := <-f.messages:
+			if msg.Type != edgews.MsgTypeJobUpdate {
+				continue
+			}
+			var update edgews.JobUpdateData
+			if err := json.Unmarshal(msg.Data, &update); err != nil {
+				f.t.Fatalf("decode job_update: %v", err)
+			}
+			updates[update.JobID] = append(updates[update.JobID], update)
+			if update.Status.IsTerminal() {
+				done[update.JobID] = true
+			}
+		case <-deadline:
+			f.t.Fatalf("timed out waiting for job updates, got %+v", updates)
+		}
+	}
+	return updates
+}
+
+// sendPrintJob 下发 print_job 指令
+func sendPrintJob(t *testing.T, conn *websocket.Conn, jobID, printerName string) {
+	t.Helper()
+	err := conn.WriteJSON(edgews.Command{
+		Type:      edgews.CmdTypePrintJob,
+		CommandID: "cmd-" + jobID,
+		Timestamp: time.Now().UTC(),
+		Data:      edgews.PrintJobData{JobID: jobID, PrinterName: printerName, PageCount: 2, Copies: 2},
+	})
+	if err != nil {
+		t.Fatalf("send print job %s: %v", jobID, err)
+	}
+}
+
+// progressSteps 任务状态上报的 status:progress 序列
+func progressSteps(updates []edgews.JobUpdateData) string {
+	steps := make([]string, len(updates))
+	for i, update := range updates {
+		steps[i] = string(update.Status) + ":" + strconv.Itoa(update.Progress)
+	}
+	return strings.Join(steps, " ")
+}
+
+func TestSimulatorSession(t *testing.T) {
+	tests := []struct {
+		name        string
+		deferred    bool
+		failureRate float64
+	}{
+		{"header auth", false, 0},
+		{"deferred auth with injected failure", true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloud := newFakeCloud(t, tt.deferred)
+
+			opts := defaultOptions()
+			opts.ServerURL = cloud.server.URL
+			opts.Token = testToken
+			opts.DeferredAuth = tt.deferred
+			opts.Latency = 0
+			opts.PageTime = 4 * time.Millisecond
+			opts.Jitter = 0
+			opts.FailureRate = tt.failureRate
+			opts.Reconnect = false
+			opts.Seed = 1
+			sim, err := NewSimulator(opts)
+			if err != nil {
+				t.Fatalf("NewSimulator: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			runErr := make(chan error, 1)
+			go func() { runErr <- sim.Run(ctx) }()
+			defer func() {
+				cancel()
+				if err := <-runErr; err != nil {
+					t.Errorf("Run: %v", err)
+				}
+			}()
+
+			var hs handshake
+			select {
+			case hs = <-cloud.handshakes:
+			case err := <-runErr:
+				t.Fatalf("Run returned before connecting: %v", err)
+			case <-time.After(10 * time.Second):
+				t.Fatal("simulator did not connect")
+			}
+			conn := <-cloud.conns
+
+			cloud.mu.Lock()
+			printers := strings.Join(cloud.printers, ",")
+			cloud.mu.Unlock()
+			if printers != "sim-printer-1,sim-printer-2" {
+				t.Fatalf("registered printers = %s", printers)
+			}
+			if hs.subprotocol != edgews.SupportedProtocolVersions[0] {
+				t.Fatalf("subprotocol = %q, want %q", hs.subprotocol, edgews.SupportedProtocolVersions[0])
+			}
+			if hs.query["node_id"] != "sim-node-1" || hs.query["conn_token"] != testConnToken {
+				t.Fatalf("handshake query = %v", hs.query)
+			}
+			if tt.deferred {
+				if hs.authorization != "" || hs.query["auth"] != "deferred" {
+					t.Fatalf("deferred handshake sent Authorization %q, query %v", hs.authorization, hs.query)
+				}
+				var auth edgews.AuthData
+				if hs.firstFrame == nil || hs.firstFrame.Type != edgews.MsgTypeAuth ||
+					json.Unmarshal(hs.firstFrame.Data, &auth) != nil || auth.Token != testToken {
+					t.Fatalf("first frame = %+v, want auth with the token", hs.firstFrame)
+				}
+			} else if hs.authorization != "Bearer "+testToken || hs.query["auth"] != "" {
+				t.Fatalf("handshake Authorization = %q, query %v", hs.authorization, hs.query)
+			}
+
+			sendPrintJob(t, conn, "job-known", "sim-printer-1")
+			sendPrintJob(t, conn, "job-unknown", "ghost-printer")
+			updates := cloud.jobUpdates("job-known", "job-unknown")
+
+			unknown := updates["job-unknown"]
+			if len(unknown) != 1 || unknown[0].Status != models.JobStatusFailed ||
+				unknown[0].ErrorCode == nil || *unknown[0].ErrorCode != "SIM_PRINTER_NOT_FOUND" {
+				t.Fatalf("unknown printer updates = %s", progressSteps(unknown))
+			}
+
+			known := updates["job-known"]
+			if tt.failureRate == 0 {
+				if got := progressSteps(known); got != "accepted:0 printing:0 printing:25 printing:50 printing:75 completed:100" {
+					t.Fatalf("job updates = %s", got)
+				}
+				return
+			}
+			if len(known) < 3 || progressSteps(known[:2]) != "accepted:0 printing:0" {
+				t.Fatalf("job updates = %s, want accepted then printing before the failure", progressSteps(known))
+			}
+			failed := known[len(known)-1]
+			if failed.Status != models.JobStatusFailed || failed.Progress < 1 || failed.Progress > 99 ||
+				failed.ErrorCode == nil || *failed.ErrorCode != opts.FailureCode {
+				t.Fatalf("final update = %s (%v), want failed with %s", progressSteps(known), failed.ErrorCode, opts.FailureCode)
+			}
+			for _, update := range known[2 : len(known)-1] {
+				if update.Status != models.JobStatusPrinting || update.Progress >= failed.Progress {
+					t.Fatalf("job updates = %s, progress after the failure point", progressSteps(known))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,488 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"fly-print-cloud/api/internal/billing"
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/conversion"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/dispatch"
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/handlers"
+	"fly-print-cloud/api/internal/hotfolder"
+	"fly-print-cloud/api/internal/httpx"
+	"fly-print-cloud/api/internal/lookup"
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/notify"
+	"fly-print-cloud/api/internal/password"
+	"fly-print-cloud/api/internal/routing"
+	"fly-print-cloud/api/internal/storage"
+	"fly-print-cloud/api/internal/textlimit"
+	"fly-print-cloud/api/internal/thumbnail"
+	"fly-print-cloud/api/internal/websocket"
+	"fly-print-cloud/api/internal/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// shutdownTimeout 优雅停机时等待进行中请求完成的最长时间
+const shutdownTimeout = 15 * time.Second
+
+// App 组装完成的应用（数据库、仓储、后台任务和 Gin 路由）
+type App struct {
+	Config *config.Config // 启动时加载的配置
+	DB     *database.DB
+	Engine *gin.Engine
+
+	// Settings 当前生效的配置，Run 期间监听配置文件和 SIGHUP 热更新
+	Settings *config.Store
+
+	wsManager           *websocket.ConnectionManager
+	heartbeatMonitor    *worker.HeartbeatMonitor
+	staleConnections    *websocket.StaleConnectionMonitor
+	progressThrottle    *websocket.ProgressThrottle
+	nodeLogs            *websocket.NodeLogs
+	diagnosticsCleaner  *worker.DiagnosticsCleaner
+	powerScheduler      *worker.PowerScheduler
+	stalledJobSweeper   *worker.StalledJobSweeper
+	reservationExpirer  *worker.ReservationExpirer
+	businessHoursOpener *worker.BusinessHoursOpener
+	breakerResumer      *worker.PrinterBreakerResumer
+	queueResumer        *worker.PrinterQueueResumer
+	queueReconciler     *worker.PrinterQueueReconciler
+	tombstoneSweeper    *worker.PrinterTombstoneSweeper
+	slaMonitor          *worker.SLAMonitor
+	drainMonitor        *worker.DrainMonitor
+	fileRetention       *worker.FileRetentionSweeper
+	jobExporter         *worker.JobExporter
+	hotFolderWatcher    *hotfolder.Watcher
+	thumbnailGenerator  *thumbnail.Generator
+	workersStarted      bool
+}
+
+// Build 按配置连接数据库并装配所有依赖，返回可直接用于 httptest 的应用
+// 后台任务（WebSocket 管理器、心跳检测等）在 StartWorkers 或 Run 时启动
+func Build(cfg *config.Config) (*App, error) {
+	// 设置Gin模式
+	if !cfg.App.Debug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	// 连接数据库
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	db.SetQueryDebug(cfg.App.Debug)
+
+	// 本地账户密码按 auth.password_hash 哈希（创建默认管理员前设置）
+	passwordHasher, err := password.NewHasher(&cfg.Auth.PasswordHash)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize password hasher: %w", err)
+	}
+	db.SetPasswordHasher(passwordHasher)
+
+	// 初始化数据库表
+	if err := db.InitTables(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database tables: %w", err)
+	}
+
+	// 没有管理员时按 CREATE_DEFAULT_ADMIN 创建默认管理员，否则等待通过初始化接口创建
+	if err := db.CreateDefaultAdmin(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create default admin: %w", err)
+	}
+
+	app, err := build(cfg, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return app, nil
+}
+
+// build 基于已初始化的数据库装配仓储、服务、处理器和路由
+func build(cfg *config.Config, db *database.DB) (*App, error) {
+	// 边缘协议消息结构变化但未递增协议次版本号时拒绝启动
+	if err := websocket.CheckProtocolSchemas(); err != nil {
+		return nil, err
+	}
+
+	// 可热更新的配置项由各组件通过 settings 读取
+	settings := config.NewStore(cfg)
+
+	// 初始化出站 HTTP 客户端
+	idpClient, err := httpx.NewClient(&cfg.HTTPClient, cfg.HTTPClient.IdPTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	authenticator := middleware.NewOAuth2Authenticator(&cfg.OAuth2, idpClient)
+
+	// 初始化打印文件存储（文件传输可能很长，只限制等待响应头的时长）
+	storageClient, err := httpx.NewStreamingClient(&cfg.HTTPClient, cfg.Storage.S3.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	fileStorage, err := storage.New(&cfg.Storage, storageClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize file storage: %w", err)
+	}
+	// 任务错误信息等日志类文本字段写入前截断，按配置将完整内容转存到文件存储
+	db.SetTextLimiter(textlimit.NewLimiter(settings, fileStorage))
+
+	// 任务提交和分发路径上的打印机、Edge Node 查询经过读穿透缓存
+	var lookupCache *lookup.Cache
+	if cfg.LookupCache.Enabled {
+		lookupCache, err = lookup.New(&cfg.LookupCache, &cfg.Redis)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize lookup cache: %w", err)
+		}
+		db.SetLookupCache(lookupCache)
+	}
+	fileLinkSigner, err := storage.NewLinkSigner(cfg.Storage.SigningSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize file link signer: %w", err)
+	}
+	connTokenSigner, err := middleware.NewConnTokenSigner(cfg.Edge.ConnTokenSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize connection token signer: %w", err)
+	}
+
+	// 初始化缩略图生成（外部渲染服务复用出站 HTTP 配置）
+	rendererClient, err := httpx.NewClient(&cfg.HTTPClient, cfg.Thumbnails.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	thumbnailRenderer, err := thumbnail.NewRenderer(&cfg.Thumbnails, rendererClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize thumbnail renderer: %w", err)
+	}
+	thumbnailGenerator := thumbnail.NewGenerator(fileStorage, thumbnailRenderer, settings)
+
+	// 初始化分发前的文档格式转换（未启用时为空）
+	conversionClient, err := httpx.NewClient(&cfg.HTTPClient, cfg.Conversion.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	converter, err := conversion.New(&cfg.Conversion, conversionClient, fileStorage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize document conversion: %w", err)
+	}
+
+	// 初始化服务
+	userRepo := database.NewUserRepository(db)
+	edgeNodeRepo := database.NewEdgeNodeRepository(db)
+	printerRepo := database.NewPrinterRepository(db)
+	printJobRepo := database.NewPrintJobRepository(db)
+	auditLogRepo := database.NewAuditLogRepository(db)
+	driverRepo := database.NewPrinterDriverRepository(db)
+	diagnosticsRepo := database.NewDiagnosticsRepository(db)
+	accessPolicyRepo := database.NewAccessPolicyRepository(db)
+	powerScheduleRepo := database.NewPowerScheduleRepository(db)
+	businessHoursRepo := database.NewBusinessHoursRepository(db)
+	importRepo := database.NewImportRepository(db)
+	jobEventRepo := database.NewPrintJobEventRepository(db)
+	printerGroupRepo := database.NewPrinterGroupRepository(db)
+	printPolicyRepo := database.NewPrintPolicyRepository(db)
+	apiKeyRepo := database.NewAPIKeyRepository(db)
+	assetRepo := database.NewAssetRepository(db)
+	inventoryRepo := database.NewInventoryRepository(db)
+	storedFileRepo := database.NewStoredFileRepository(db)
+	exportRunRepo := database.NewExportRunRepository(db)
+	hotFolderRepo := database.NewHotFolderRepository(db)
+	edgeCredentialRepo := database.NewEdgeNodeCredentialRepository(db)
+	costCalculator := billing.NewCalculator(settings)
+	mailer := notify.NewMailer(settings)
+	jobNotifier := notify.NewNotifier(mailer, userRepo, printJobRepo, printerRepo)
+
+	// 初始化事件总线和心跳监控
+	eventBus := events.NewBus()
+	heartbeatMonitor := worker.NewHeartbeatMonitor(edgeNodeRepo, printerRepo, eventBus, settings)
+
+	// 初始化 WebSocket 管理器
+	wsManager := websocket.NewConnectionManager()
+	powerScheduler := worker.NewPowerScheduler(powerScheduleRepo, printerRepo, printJobRepo, wsManager, settings)
+	jobDispatcher := dispatch.NewDispatcher(printJobRepo, printerRepo, driverRepo, wsManager, fileStorage, settings, powerScheduler, jobNotifier, jobEventRepo, converter, businessHoursRepo, eventBus)
+	progressThrottle := websocket.NewProgressThrottle(printJobRepo, settings)
+	nodeLogs := websocket.NewNodeLogs(wsManager)
+	wsHandler := websocket.NewWebSocketHandler(wsManager, printerRepo, edgeNodeRepo, printJobRepo, costCalculator, heartbeatMonitor, jobNotifier, jobDispatcher, eventBus, jobEventRepo, settings, progressThrottle, nodeLogs, connTokenSigner, authenticator)
+
+	// 初始化处理器
+	userHandler := handlers.NewUserHandler(userRepo)
+	edgeNodeHandler := handlers.NewEdgeNodeHandler(edgeNodeRepo, printerRepo, heartbeatMonitor, wsManager, settings, assetRepo, auditLogRepo, printJobRepo, connTokenSigner)
+	printerHandler := handlers.NewPrinterHandler(printerRepo, edgeNodeRepo, printJobRepo, jobDispatcher, auditLogRepo, powerScheduleRepo, settings, assetRepo, jobEventRepo, businessHoursRepo)
+	dashboardHandler := handlers.NewDashboardHandler(printJobRepo, edgeNodeRepo, printerRepo, costCalculator, settings)
+	printJobHandler := handlers.NewPrintJobHandler(printJobRepo, printerRepo, jobDispatcher, costCalculator, auditLogRepo, jobNotifier, accessPolicyRepo, fileStorage, settings, eventBus, jobEventRepo, printerGroupRepo, routing.NewRouters(), printPolicyRepo, businessHoursRepo, userRepo)
+	oauth2Handler := handlers.NewOAuth2Handler(&cfg.OAuth2, &cfg.Admin, userRepo, idpClient)
+	auditLogHandler := handlers.NewAuditLogHandler(auditLogRepo)
+	eventHandler := handlers.NewEventHandler(eventBus)
+	driverHandler := handlers.NewDriverHandler(driverRepo, edgeNodeRepo, settings)
+	fileHandler := handlers.NewFileHandler(fileStorage, fileLinkSigner, printJobRepo, storedFileRepo, auditLogRepo, settings, thumbnailGenerator)
+	accessPolicyHandler := handlers.NewAccessPolicyHandler(accessPolicyRepo, printerRepo, auditLogRepo)
+	connectionHandler := handlers.NewConnectionHandler(wsManager, auditLogRepo)
+	diagnosticsHandler := handlers.NewDiagnosticsHandler(diagnosticsRepo, edgeNodeRepo, auditLogRepo, wsManager, settings)
+	nodeLogHandler := handlers.NewNodeLogHandler(edgeNodeRepo, wsManager, nodeLogs, auditLogRepo)
+	powerScheduleHandler := handlers.NewPowerScheduleHandler(powerScheduleRepo, printerRepo, edgeNodeRepo, auditLogRepo)
+	businessHoursHandler := handlers.NewBusinessHoursHandler(businessHoursRepo, printerRepo, edgeNodeRepo, auditLogRepo, settings)
+	importHandler := handlers.NewImportHandler(importRepo, auditLogRepo)
+	printerGroupHandler := handlers.NewPrinterGroupHandler(printerGroupRepo, printerRepo, auditLogRepo)
+	printPolicyHandler := handlers.NewPrintPolicyHandler(printPolicyRepo, printerGroupRepo, auditLogRepo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo, userRepo, auditLogRepo)
+	middleware.SetAPIKeyAuthenticator(apiKeyHandler.Authenticate)
+	assetHandler := handlers.NewAssetHandler(assetRepo, printerRepo, edgeNodeRepo)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryRepo)
+	bootstrapHandler := handlers.NewBootstrapHandler(userRepo, auditLogRepo)
+	exportHandler := handlers.NewExportHandler(exportRunRepo, auditLogRepo)
+	edgeCredentialHandler := handlers.NewEdgeCredentialHandler(edgeCredentialRepo, edgeNodeRepo, wsManager, auditLogRepo)
+	// 配置了客户端证书 CA 时，Edge Node 可使用登记过的证书代替 token
+	if cfg.Server.TLS.ClientCAFile != "" {
+		middleware.SetClientCertAuthenticator(edgeCredentialHandler.Authenticate)
+	}
+
+	// 邮件打印（未启用时不注册 webhook）
+	var mailInHandler *handlers.MailInHandler
+	if cfg.MailIn.Enabled {
+		if mailInHandler, err = handlers.NewMailInHandler(printJobHandler, userRepo, storedFileRepo, fileStorage, mailer, &cfg.MailIn, settings); err != nil {
+			return nil, fmt.Errorf("failed to initialize mail-in: %w", err)
+		}
+	}
+
+	// 共享文件夹打印（未启用时不监视目录，状态接口返回 enabled=false）
+	var hotFolderWatcher *hotfolder.Watcher
+	if cfg.HotFolders.Enabled {
+		hotFolderSubmitter := handlers.NewHotFolderSubmitter(printJobHandler, userRepo, storedFileRepo, fileStorage, settings)
+		hotFolderWatcher = hotfolder.NewWatcher(&cfg.HotFolders, hotFolderRepo, hotFolderSubmitter)
+	}
+	hotFolderHandler := handlers.NewHotFolderHandler(hotFolderWatcher)
+	healthHandler := handlers.NewHealthHandler(edgeNodeRepo, printerRepo, printJobRepo, wsHandler, lookupCache, settings)
+
+	// 注册自定义校验标签
+	if err := handlers.RegisterValidators(); err != nil {
+		return nil, fmt.Errorf("failed to register validators: %w", err)
+	}
+
+	// 创建Gin路由
+	r := gin.New()
+
+	// 添加中间件
+	r.Use(middleware.RequestIDMiddleware())
+	if cfg.App.Debug {
+		r.Use(middleware.QueryTrackingMiddleware())
+	}
+	r.Use(middleware.LoggerMiddleware())
+	r.Use(gin.Recovery())
+	r.Use(middleware.CORSMiddleware())
+
+	// 设置路由
+	setupRoutes(r, &routeHandlers{
+		auth:                  authenticator,
+		userHandler:           userHandler,
+		edgeNodeHandler:       edgeNodeHandler,
+		printerHandler:        printerHandler,
+		printJobHandler:       printJobHandler,
+		wsHandler:             wsHandler,
+		oauth2Handler:         oauth2Handler,
+		auditLogHandler:       auditLogHandler,
+		eventHandler:          eventHandler,
+		driverHandler:         driverHandler,
+		diagnosticsHandler:    diagnosticsHandler,
+		connectionHandler:     connectionHandler,
+		fileHandler:           fileHandler,
+		accessPolicyHandler:   accessPolicyHandler,
+		powerScheduleHandler:  powerScheduleHandler,
+		businessHoursHandler:  businessHoursHandler,
+		importHandler:         importHandler,
+		printerGroupHandler:   printerGroupHandler,
+		assetHandler:          assetHandler,
+		inventoryHandler:      inventoryHandler,
+		mailInHandler:         mailInHandler,
+		bootstrapHandler:      bootstrapHandler,
+		printPolicyHandler:    printPolicyHandler,
+		apiKeyHandler:         apiKeyHandler,
+		nodeLogHandler:        nodeLogHandler,
+		exportHandler:         exportHandler,
+		hotFolderHandler:      hotFolderHandler,
+		healthHandler:         healthHandler,
+		edgeCredentialHandler: edgeCredentialHandler,
+		dashboardHandler:      dashboardHandler,
+	}, userRepo, db)
+
+	return &App{
+		Config:              cfg,
+		DB:                  db,
+		Engine:              r,
+		Settings:            settings,
+		wsManager:           wsManager,
+		heartbeatMonitor:    heartbeatMonitor,
+		staleConnections:    websocket.NewStaleConnectionMonitor(wsManager, heartbeatMonitor, settings),
+		progressThrottle:    progressThrottle,
+		nodeLogs:            nodeLogs,
+		diagnosticsCleaner:  worker.NewDiagnosticsCleaner(diagnosticsRepo, cfg.Diagnostics.Dir, 0),
+		powerScheduler:      powerScheduler,
+		stalledJobSweeper:   worker.NewStalledJobSweeper(printJobRepo, eventBus, jobEventRepo, jobNotifier, settings),
+		reservationExpirer:  worker.NewReservationExpirer(printerRepo, jobDispatcher, settings),
+		businessHoursOpener: worker.NewBusinessHoursOpener(printJobRepo, printerRepo, jobDispatcher, settings),
+		breakerResumer:      worker.NewPrinterBreakerResumer(printerRepo, jobDispatcher, eventBus, settings),
+		queueResumer:        worker.NewPrinterQueueResumer(printerRepo, auditLogRepo, jobDispatcher, settings),
+		queueReconciler:     worker.NewPrinterQueueReconciler(printerRepo, settings),
+		tombstoneSweeper:    worker.NewPrinterTombstoneSweeper(printerRepo, 0),
+		slaMonitor:          worker.NewSLAMonitor(printJobRepo, printerGroupRepo, eventBus, settings),
+		drainMonitor:        worker.NewDrainMonitor(edgeNodeRepo, printJobRepo, eventBus, settings),
+		fileRetention:       worker.NewFileRetentionSweeper(storedFileRepo, printJobRepo, edgeNodeRepo, fileStorage, settings),
+		jobExporter:         worker.NewJobExporter(exportRunRepo, printJobRepo, fileStorage, settings),
+		hotFolderWatcher:    hotFolderWatcher,
+		thumbnailGenerator:  thumbnailGenerator,
+	}, nil
+}
+
+// StartWorkers 启动后台任务（重复调用无效）
+func (a *App) StartWorkers() {
+	if a.workersStarted {
+		return
+	}
+	a.workersStarted = true
+
+	// 启动 WebSocket 管理器
+	go a.wsManager.Run()
+
+	// 启动心跳超时检测
+	go a.heartbeatMonitor.Run()
+
+	// 启动僵死 WebSocket 连接检测
+	go a.staleConnections.Run()
+
+	// 启动被节流的任务进度补写
+	go a.progressThrottle.Run()
+
+	// 启动节点日志跟踪的空闲检测
+	go a.nodeLogs.Run()
+
+	// 启动过期诊断包清理
+	go a.diagnosticsCleaner.Run()
+
+	// 启动打印机节能计划调度
+	go a.powerScheduler.Run()
+
+	// 启动卡住任务检测
+	go a.stalledJobSweeper.Run()
+
+	// 启动打印机预留到期清理
+	go a.reservationExpirer.Run()
+
+	// 启动营业时间排队任务分发
+	go a.businessHoursOpener.Run()
+	go a.breakerResumer.Run()
+
+	// 启动打印机队列暂停自动恢复
+	go a.queueResumer.Run()
+
+	// 启动打印机队列长度核对
+	go a.queueReconciler.Run()
+
+	// 启动到期打印机墓碑清理
+	go a.tombstoneSweeper.Run()
+
+	// 启动 SLA 考核巡检
+	go a.slaMonitor.Run()
+
+	// 启动 Edge Node 排空检查
+	go a.drainMonitor.Run()
+
+	// 启动过期打印文件清理
+	go a.fileRetention.Run()
+
+	// 启动已完成任务归档导出
+	go a.jobExporter.Run()
+
+	// 启动共享文件夹监视（未启用时不启动）
+	if a.hotFolderWatcher != nil {
+		go a.hotFolderWatcher.Run()
+	}
+
+	// 启动缩略图生成（未配置渲染器时不启动）
+	go a.thumbnailGenerator.Run()
+}
+
+// serverTLSConfig 配置 client_ca_file 时请求并校验客户端证书（可选出示，未出示的客户端仍可使用 token），
+// 否则返回 nil 使用默认 TLS 配置
+func serverTLSConfig(settings *config.ServerTLSConfig) (*tls.Config, error) {
+	if settings.ClientCAFile == "" {
+		return nil, nil
+	}
+	caPEM, err := os.ReadFile(settings.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server.tls.client_ca_file: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("server.tls.client_ca_file contains no PEM certificates: %s", settings.ClientCAFile)
+	}
+	return &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  clientCAs,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// Run 启动后台任务和 HTTP 服务，ctx 取消后优雅停机并关闭数据库连接
+func (a *App) Run(ctx context.Context) error {
+	defer a.DB.Close()
+
+	// 先加载客户端证书 CA，配置有误时不启动后台任务
+	tlsSettings := a.Config.Server.TLS
+	tlsConfig, err := serverTLSConfig(&tlsSettings)
+	if err != nil {
+		return err
+	}
+
+	a.StartWorkers()
+
+	// 监听配置文件变化和 SIGHUP，热更新可在运行时生效的配置项
+	go a.Settings.Watch(ctx)
+
+	serverAddr := a.Config.Server.GetServerAddr()
+	server := &http.Server{
+		Addr:      serverAddr,
+		Handler:   a.Engine,
+		TLSConfig: tlsConfig,
+	}
+
+	log.Printf("Starting %s server on %s (tls: %v, client certificates: %v)", a.Config.App.Name, serverAddr, tlsSettings.Enabled, tlsSettings.ClientCAFile != "")
+	log.Printf("Environment: %s, Debug: %v", a.Config.App.Environment, a.Config.App.Debug)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if tlsSettings.Enabled {
+			serverErr <- server.ListenAndServeTLS(tlsSettings.CertFile, tlsSettings.KeyFile)
+			return
+		}
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("failed to start server: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down %s server", a.Config.App.Name)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package app
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/websocket"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	gorillaws "github.com/gorilla/websocket"
+)
+
+// testDatabaseURLEnv 集成测试使用的 PostgreSQL 连接串（postgres:// URL），未设置时跳过依赖数据库的测试
+const testDatabaseURLEnv = "FLY_PRINT_TEST_DATABASE_URL"
+
+// buildTestApp 在测试数据库服务器上创建独立的数据库，按默认配置装配应用并启动 WebSocket 管理器
+func buildTestApp(t *testing.T) *App {
+	t.Helper()
+	rawURL := os.Getenv(testDatabaseURLEnv)
+	if rawURL == "" {
+		t.Skipf("%s not set, skipping end-to-end test", testDatabaseURLEnv)
+	}
+	dbURL, err := url.Parse(rawURL)
+	if err != nil || (dbURL.Scheme != "postgres" && dbURL.Scheme != "postgresql") {
+		t.Fatalf("%s must be a postgres:// URL", testDatabaseURLEnv)
+	}
+
+	admin, err := sql.Open("postgres", rawURL)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	dbName := "test_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	if _, err := admin.Exec("CREATE DATABASE " + dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + dbName + " WITH (FORCE)"); err != nil {
+			t.Logf("drop database %s: %v", dbName, err)
+		}
+	})
+
+	password, _ := dbURL.User.Password()
+	port := dbURL.Port()
+	if port == "" {
+		port = "5432"
+	}
+	sslMode := dbURL.Query().Get("sslmode")
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	t.Setenv("FLY_PRINT_DATABASE_HOST", dbURL.Hostname())
+	t.Setenv("FLY_PRINT_DATABASE_PORT", port)
+	t.Setenv("FLY_PRINT_DATABASE_USER", dbURL.User.Username())
+	t.Setenv("FLY_PRINT_DATABASE_PASSWORD", password)
+	t.Setenv("FLY_PRINT_DATABASE_DBNAME", dbName)
+	t.Setenv("FLY_PRINT_DATABASE_SSLMODE", sslMode)
+	t.Setenv("FLY_PRINT_STORAGE_DIR", t.TempDir())
+	t.Setenv("FLY_PRINT_DIAGNOSTICS_DIR", t.TempDir())
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	app, err := Build(cfg)
+	if err != nil {
+		t.Fatalf("build app: %v", err)
+	}
+	t.Cleanup(func() { app.DB.Close() })
+
+	// 只启动分发依赖的 WebSocket 管理器，其余后台任务与本测试无关
+	go app.wsManager.Run()
+	return app
+}
+
+// testToken 生成测试用 JWT（中间件只解析 claims，不校验签名）
+func testToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+// doJSON 发送 JSON 请求并解析响应体
+func doJSON(t *testing.T, method, target, token string, body, out interface{}) int {
+	t.Helper()
+	return doJSONWithHeader(t, method, target, token, nil, body, out)
+}
+
+// doJSONWithHeader 携带额外请求头发送 JSON 请求并解析响应体
+func doJSONWithHeader(t *testing.T, method, target, token string, header http.Header, body, out interface{}) int {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, target, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decode %s %s response: %v", method, target, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// TestEndToEndDispatch 节点注册 → 打印机注册 → 建立 WebSocket → 提交任务 → 节点收到 print_job 指令
+func TestEndToEndDispatch(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-e2e-" + uuid.New().String()[:8]
+	edgeToken := testToken(t, jwt.MapClaims{
+		"sub":     "edge-client-" + nodeID,
+		"node_id": nodeID,
+		"scope": strings.Join([]string{
+			middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect,
+			middleware.ScopeEdgePrinterWrite, middleware.ScopeEdgeJobUpdate,
+		}, " "),
+	})
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+
+	// 1. 注册节点
+	var registered struct {
+		Data struct {
+			ConnToken string `json:"conn_token"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/register", edgeToken,
+		map[string]string{"node_id": nodeID, "name": "E2E Node"}, &registered); status != http.StatusCreated {
+		t.Fatalf("register node: status %d", status)
+	}
+	if registered.Data.ConnToken == "" {
+		t.Fatal("register node: missing conn_token")
+	}
+
+	// 2. 注册打印机（默认自动审批）
+	var printer struct {
+		Data struct {
+			ID       string `json:"id"`
+			Approved bool   `json:"approved"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/"+nodeID+"/printers", edgeToken,
+		map[string]string{"name": "E2E-Printer", "model": "LaserJet"}, &printer); status != http.StatusCreated {
+		t.Fatalf("register printer: status %d", status)
+	}
+	if printer.Data.ID == "" || !printer.Data.Approved {
+		t.Fatalf("register printer: got %+v, want approved printer", printer.Data)
+	}
+
+	// 3. 节点建立 WebSocket 连接
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/edge/ws?" + url.Values{
+		"node_id":    {nodeID},
+		"conn_token": {registered.Data.ConnToken},
+	}.Encode()
+	conn, resp, err := gorillaws.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer " + edgeToken}})
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dial websocket: %v (status %d)", err, status)
+	}
+	defer conn.Close()
+	waitForConnection(t, app, nodeID)
+
+	// 4. 管理员提交任务
+	var job struct {
+		ID string `json:"id"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, map[string]interface{}{
+		"name":       "e2e.pdf",
+		"printer_id": printer.Data.ID,
+		"file_url":   "https://files.example.com/e2e.pdf",
+		"page_count": 1,
+	}, &job); status != http.StatusCreated {
+		t.Fatalf("create job: status %d", status)
+	}
+
+	// 5. 节点收到该任务的 print_job 指令
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		var command struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := conn.ReadJSON(&command); err != nil {
+			t.Fatalf("waiting for print_job command for job %s: %v", job.ID, err)
+		}
+		if command.Type != websocket.CmdTypePrintJob {
+			continue
+		}
+		var data websocket.PrintJobData
+		if err := json.Unmarshal(command.Data, &data); err != nil {
+			t.Fatalf("decode print_job data: %v", err)
+		}
+		if data.JobID != job.ID || data.PrinterID != printer.Data.ID || data.FileURL != "https://files.example.com/e2e.pdf" {
+			t.Fatalf("print_job = {job %s, printer %s, url %s}, want job %s on printer %s",
+				data.JobID, data.PrinterID, data.FileURL, job.ID, printer.Data.ID)
+		}
+		return
+	}
+}
+
+// waitForConnection 等待管理器登记节点连接（注册在升级后异步完成）
+func waitForConnection(t *testing.T, app *App, nodeID string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if app.wsManager.IsNodeConnected(nodeID) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("node %s did not register a websocket connection", nodeID)
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestBootstrapAdminFlow 初始化状态 → 创建首个管理员 → 再次创建返回 410
+func TestBootstrapAdminFlow(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	var status struct {
+		Data struct {
+			AdminExists   bool `json:"admin_exists"`
+			SetupRequired bool `json:"setup_required"`
+		} `json:"data"`
+	}
+	if code := doJSON(t, http.MethodGet, srv.URL+"/api/v1/bootstrap/status", "", nil, &status); code != http.StatusOK {
+		t.Fatalf("bootstrap status: status %d", code)
+	}
+	if status.Data.AdminExists || !status.Data.SetupRequired {
+		t.Fatalf("fresh install status = %+v, want setup required", status.Data)
+	}
+
+	admin := map[string]string{"username": "firstadmin", "email": "first@example.com", "password": "correct-horse-battery"}
+	if code := doJSON(t, http.MethodPost, srv.URL+"/api/v1/bootstrap/admin", "", admin, nil); code != http.StatusCreated {
+		t.Fatalf("create first admin: status %d", code)
+	}
+
+	if code := doJSON(t, http.MethodGet, srv.URL+"/api/v1/bootstrap/status", "", nil, &status); code != http.StatusOK {
+		t.Fatalf("bootstrap status: status %d", code)
+	}
+	if !status.Data.AdminExists || status.Data.SetupRequired {
+		t.Fatalf("status after bootstrap = %+v, want admin exists", status.Data)
+	}
+
+	// 初始化完成后不再校验请求内容，空请求同样返回 410
+	second := map[string]string{"username": "second", "email": "second@example.com", "password": "correct-horse-battery"}
+	for _, body := range []interface{}{second, map[string]string{}} {
+		if code := doJSON(t, http.MethodPost, srv.URL+"/api/v1/bootstrap/admin", "", body, nil); code != http.StatusGone {
+			t.Fatalf("create admin after bootstrap: status %d, want 410", code)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestBusinessHoursJobAcceptance 打印机两小时后才营业：默认拒绝并返回下次营业时间，when_closed=schedule 时排队到开门，
+// 用户打印机列表显示当前不接收任务
+func TestBusinessHoursJobAcceptance(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	edgeToken := testToken(t, jwt.MapClaims{
+		"sub":     "edge-client-" + nodeID,
+		"node_id": nodeID,
+		"scope":   strings.Join([]string{middleware.ScopeEdgeRegister, middleware.ScopeEdgePrinterWrite}, " "),
+	})
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+	userToken := testToken(t, jwt.MapClaims{"sub": "user-1", "preferred_username": "alice", "scope": "print:submit"})
+
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/register", edgeToken,
+		map[string]string{"node_id": nodeID, "name": nodeID}, nil); status != http.StatusCreated {
+		t.Fatalf("register node: status %d", status)
+	}
+	var printer struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/"+nodeID+"/printers", edgeToken,
+		map[string]string{"name": "Hours-Printer", "model": "LaserJet"}, &printer); status != http.StatusCreated {
+		t.Fatalf("register printer: status %d", status)
+	}
+
+	// 营业时段从两小时后开始、持续一小时（每天），当前一定不在营业时间
+	now := time.Now().UTC()
+	opening := now.Add(2 * time.Hour).Truncate(time.Minute)
+	if status := doJSON(t, http.MethodPut, srv.URL+"/api/v1/admin/printers/"+printer.Data.ID+"/business-hours", adminToken,
+		map[string]interface{}{
+			"days":       []int{0, 1, 2, 3, 4, 5, 6},
+			"open_time":  opening.Format("15:04"),
+			"close_time": opening.Add(time.Hour).Format("15:04"),
+			"timezone":   "UTC",
+		}, nil); status != http.StatusOK {
+		t.Fatalf("set business hours: status %d", status)
+	}
+
+	jobRequest := func(whenClosed string) map[string]interface{} {
+		body := map[string]interface{}{
+			"printer_id": printer.Data.ID,
+			"file_url":   "https://files.example.com/hours.pdf",
+			"page_count": 1,
+		}
+		if whenClosed != "" {
+			body["when_closed"] = whenClosed
+		}
+		return body
+	}
+
+	var rejected struct {
+		ErrorCode  string    `json:"error_code"`
+		NextOpenAt time.Time `json:"next_open_at"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, jobRequest(""), &rejected); status != http.StatusConflict {
+		t.Fatalf("create job while closed: status %d, want 409", status)
+	}
+	if rejected.ErrorCode != models.JobErrorPrinterClosed || !rejected.NextOpenAt.Equal(opening) {
+		t.Fatalf("rejection = %+v, want %s at %s", rejected, models.JobErrorPrinterClosed, opening)
+	}
+
+	var scheduled struct {
+		ID        string           `json:"id"`
+		Status    models.JobStatus `json:"status"`
+		NotBefore *time.Time       `json:"not_before"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, jobRequest(models.WhenClosedSchedule), &scheduled); status != http.StatusCreated {
+		t.Fatalf("create scheduled job: status %d, want 201", status)
+	}
+	if scheduled.Status != models.JobStatusQueued || scheduled.NotBefore == nil || !scheduled.NotBefore.Equal(opening) {
+		t.Fatalf("scheduled job = %+v, want queued until %s", scheduled, opening)
+	}
+
+	// 排到开门之后的任务不会被提前认领
+	claimed, err := database.NewPrintJobRepository(app.DB).ClaimQueuedJobs(printer.Data.ID)
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("ClaimQueuedJobs before opening = %d jobs, %v; want none", len(claimed), err)
+	}
+
+	var list struct {
+		Data struct {
+			Items []struct {
+				ID            string     `json:"id"`
+				AcceptingJobs bool       `json:"accepting_jobs"`
+				NextOpenAt    *time.Time `json:"next_open_at"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/printers?page_size=100", userToken, nil, &list); status != http.StatusOK {
+		t.Fatalf("list printers: status %d", status)
+	}
+	for _, item := range list.Data.Items {
+		if item.ID != printer.Data.ID {
+			continue
+		}
+		if item.AcceptingJobs || item.NextOpenAt == nil || !item.NextOpenAt.Equal(opening) {
+			t.Fatalf("printer in list = %+v, want not accepting until %s", item, opening)
+		}
+		return
+	}
+	t.Fatalf("printer %s missing from user printer list", printer.Data.ID)
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	gorillaws "github.com/gorilla/websocket"
+)
+
+// TestClientInfoRecorded X-Client 请求头记录在任务详情、审计日志和 CSV 导出中；
+// 延迟认证的 auth 消息携带的 client_info 显示在连接列表中
+func TestClientInfoRecorded(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	edgeToken := testToken(t, jwt.MapClaims{
+		"sub":     "edge-client-" + nodeID,
+		"node_id": nodeID,
+		"scope":   strings.Join([]string{middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect, middleware.ScopeEdgePrinterWrite}, " "),
+	})
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+
+	var registered struct {
+		Data struct {
+			ConnToken string `json:"conn_token"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/register", edgeToken,
+		map[string]string{"node_id": nodeID, "name": nodeID}, &registered); status != http.StatusCreated {
+		t.Fatalf("register node: status %d", status)
+	}
+	var printer struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/"+nodeID+"/printers", edgeToken,
+		map[string]string{"name": "Client-Printer", "model": "LaserJet"}, &printer); status != http.StatusCreated {
+		t.Fatalf("register printer: status %d", status)
+	}
+
+	want := models.ClientInfo{Name: "fly-print-web", Version: "1.4.2", Platform: "web"}
+	header := http.Header{models.ClientInfoHeader: {"name=fly-print-web; version=1.4.2; platform=web"}}
+
+	// 任务详情
+	var job struct {
+		ID     string             `json:"id"`
+		Client *models.ClientInfo `json:"client"`
+	}
+	if status := doJSONWithHeader(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, header, map[string]interface{}{
+		"printer_id": printer.Data.ID,
+		"file_url":   "https://files.example.com/client.pdf",
+		"page_count": 1,
+	}, &job); status != http.StatusCreated {
+		t.Fatalf("create job: status %d", status)
+	}
+	var detail struct {
+		Client *models.ClientInfo `json:"client"`
+	}
+	if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/admin/print-jobs/"+job.ID, adminToken, nil, &detail); status != http.StatusOK {
+		t.Fatalf("get job: status %d", status)
+	}
+	if detail.Client == nil || *detail.Client != want {
+		t.Fatalf("job client = %+v, want %+v", detail.Client, want)
+	}
+
+	// 审计日志（取消任务）；无法解析的请求头不影响请求
+	garbled := http.Header{models.ClientInfoHeader: {"\x00;;=="}}
+	if status := doJSONWithHeader(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs/"+job.ID+"/cancel", adminToken, header, nil, nil); status != http.StatusOK {
+		t.Fatalf("cancel job: status %d", status)
+	}
+	if status := doJSONWithHeader(t, http.MethodGet, srv.URL+"/api/v1/admin/print-jobs/"+job.ID, adminToken, garbled, nil, nil); status != http.StatusOK {
+		t.Fatalf("get job with garbled X-Client: status %d", status)
+	}
+	var audit struct {
+		Data struct {
+			Items []struct {
+				Action string             `json:"action"`
+				Client *models.ClientInfo `json:"client"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/admin/audit-logs?resource_type=print_job&resource_id="+job.ID, adminToken, nil, &audit); status != http.StatusOK {
+		t.Fatalf("list audit logs: status %d", status)
+	}
+	if len(audit.Data.Items) == 0 || audit.Data.Items[0].Client == nil || *audit.Data.Items[0].Client != want {
+		t.Fatalf("audit logs = %+v, want client %+v", audit.Data.Items, want)
+	}
+
+	// CSV 导出
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/admin/print-jobs/export", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if disposition := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(disposition, `attachment; filename="print_jobs_`) ||
+		!strings.HasSuffix(disposition, `.csv"`) {
+		t.Fatalf("Content-Disposition = %q, want a quoted print_jobs_*.csv filename", disposition)
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	if err != nil || len(records) < 2 {
+		t.Fatalf("export csv: %d records, %v", len(records), err)
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	found := false
+	for _, record := range records[1:] {
+		if record[columns["id"]] != job.ID {
+			continue
+		}
+		found = true
+		got := models.ClientInfo{Name: record[columns["client_name"]], Version: record[columns["client_version"]], Platform: record[columns["client_platform"]]}
+		if got != want {
+			t.Fatalf("exported client = %+v, want %+v", got, want)
+		}
+	}
+	if !found {
+		t.Fatalf("job %s missing from export", job.ID)
+	}
+
+	// 延迟认证：auth 消息的 client_info 优先于握手请求头
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/edge/ws?" + url.Values{
+		"auth":    {"deferred"},
+		"node_id": {nodeID},
+	}.Encode()
+	conn, _, err := gorillaws.DefaultDialer.Dial(wsURL, http.Header{models.ClientInfoHeader: {"other/0.1 (linux)"}})
+	if err != nil {
+		t.Fatalf("dial websocket: %v", err)
+	}
+	defer conn.Close()
+	agent := models.ClientInfo{Name: "fly-print-edge", Version: "2.3.0", Platform: "linux"}
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.WriteJSON(map[string]interface{}{
+		"type": "auth",
+		"data": map[string]interface{}{"token": edgeToken, "conn_token": registered.Data.ConnToken, "client_info": agent},
+	}); err != nil {
+		t.Fatalf("send auth: %v", err)
+	}
+	waitForConnection(t, app, nodeID)
+
+	var sessions struct {
+		Data struct {
+			Items []struct {
+				NodeID string             `json:"node_id"`
+				Client *models.ClientInfo `json:"client"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/admin/connections", adminToken, nil, &sessions); status != http.StatusOK {
+		t.Fatalf("list connections: status %d", status)
+	}
+	for _, session := range sessions.Data.Items {
+		if session.NodeID == nodeID {
+			if session.Client == nil || *session.Client != agent {
+				t.Fatalf("session client = %+v, want %+v", session.Client, agent)
+			}
+			return
+		}
+	}
+	t.Fatalf("node %s missing from connections", nodeID)
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestDashboardWired 完整装配的应用中 Dashboard 处理器已构造并连接数据库，operator 可以读取趋势和费用统计
+func TestDashboardWired(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	operatorToken := testToken(t, jwt.MapClaims{
+		"sub":          "op-1",
+		"realm_access": map[string]interface{}{"roles": []string{middleware.RoleOperator}},
+	})
+	for _, path := range []string{"/api/v1/admin/dashboard/trends", "/api/v1/admin/dashboard/costs"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("GET %s without token: status %d, want 401", path, resp.StatusCode)
+		}
+		var body struct {
+			Data interface{} `json:"data"`
+		}
+		if status := doJSON(t, http.MethodGet, srv.URL+path, operatorToken, nil, &body); status != http.StatusOK || body.Data == nil {
+			t.Fatalf("GET %s as operator: status %d, data %v; want 200 with data", path, status, body.Data)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestJobsOnDeletedEdgeNode 打印机所属 Edge Node 删除或禁用后，创建任务和重新打印均返回 409 edge_node_deleted
+func TestJobsOnDeletedEdgeNode(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	edgeToken := testToken(t, jwt.MapClaims{
+		"sub":     "edge-client-" + nodeID,
+		"node_id": nodeID,
+		"scope":   strings.Join([]string{middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect, middleware.ScopeEdgePrinterWrite}, " "),
+	})
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/register", edgeToken,
+		map[string]string{"node_id": nodeID, "name": nodeID}, nil); status != http.StatusCreated {
+		t.Fatalf("register node: status %d", status)
+	}
+	var printer struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/"+nodeID+"/printers", edgeToken,
+		map[string]string{"name": "Deleted-Node-Printer", "model": "LaserJet"}, &printer); status != http.StatusCreated {
+		t.Fatalf("register printer: status %d", status)
+	}
+
+	createJob := map[string]interface{}{
+		"printer_id": printer.Data.ID,
+		"file_url":   "https://files.example.com/deleted-node.pdf",
+		"page_count": 1,
+	}
+	var job struct {
+		ID string `json:"id"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, createJob, &job); status != http.StatusCreated {
+		t.Fatalf("create job on active node: status %d", status)
+	}
+	if _, err := app.DB.Exec(`UPDATE print_jobs SET status = 'completed' WHERE id = $1`, job.ID); err != nil {
+		t.Fatalf("complete job: %v", err)
+	}
+
+	for _, state := range []struct {
+		name   string
+		update string
+	}{
+		{"disabled", `UPDATE edge_nodes SET enabled = false WHERE id = $1`},
+		{"deleted", `UPDATE edge_nodes SET enabled = true, deleted_at = NOW() WHERE id = $1`},
+	} {
+		if _, err := app.DB.Exec(state.update, nodeID); err != nil {
+			t.Fatalf("%s node: %v", state.name, err)
+		}
+		for _, req := range []struct {
+			name string
+			url  string
+			body interface{}
+		}{
+			{"create", srv.URL + "/api/v1/admin/print-jobs", createJob},
+			{"reprint", srv.URL + "/api/v1/admin/print-jobs/" + job.ID + "/reprint", map[string]string{"printer_id": printer.Data.ID}},
+		} {
+			var body struct {
+				ErrorCode string `json:"error_code"`
+			}
+			status := doJSON(t, http.MethodPost, req.url, adminToken, req.body, &body)
+			if status != http.StatusConflict || body.ErrorCode != models.JobErrorEdgeNodeDeleted {
+				t.Fatalf("%s on %s node: status %d, error_code %q; want 409 %s",
+					req.name, state.name, status, body.ErrorCode, models.JobErrorEdgeNodeDeleted)
+			}
+		}
+	}
+
+	var count int
+	if err := app.DB.QueryRow(`SELECT COUNT(*) FROM print_jobs WHERE printer_id = $1`, printer.Data.ID).Scan(&count); err != nil {
+		t.Fatalf("count jobs: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("printer has %d jobs, want only the original", count)
+	}
+}
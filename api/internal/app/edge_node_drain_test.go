@@ -0,0 +1,137 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// drainedNode 排空接口和详情接口返回的节点状态
+type drainedNode struct {
+	Data struct {
+		Enabled    bool `json:"enabled"`
+		Draining   bool `json:"draining"`
+		ActiveJobs *int `json:"active_jobs"`
+	} `json:"data"`
+}
+
+// TestEdgeNodeDrain 排空中的节点不再接收任务；任务全部结束或超过截止时间后排空检查禁用节点
+func TestEdgeNodeDrain(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+
+	// setup 注册节点和打印机，提交一个任务并将其标记为打印中，返回节点ID和任务ID
+	setup := func(t *testing.T) (string, string) {
+		t.Helper()
+		nodeID := "node-" + uuid.New().String()[:8]
+		edgeToken := testToken(t, jwt.MapClaims{
+			"sub":     "edge-client-" + nodeID,
+			"node_id": nodeID,
+			"scope":   strings.Join([]string{middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect, middleware.ScopeEdgePrinterWrite}, " "),
+		})
+		if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/register", edgeToken,
+			map[string]string{"node_id": nodeID, "name": nodeID}, nil); status != http.StatusCreated {
+			t.Fatalf("register node: status %d", status)
+		}
+		var printer struct {
+			Data struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/"+nodeID+"/printers", edgeToken,
+			map[string]string{"name": "Drain-Printer", "model": "LaserJet"}, &printer); status != http.StatusCreated {
+			t.Fatalf("register printer: status %d", status)
+		}
+		var job struct {
+			ID string `json:"id"`
+		}
+		if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, map[string]interface{}{
+			"printer_id": printer.Data.ID,
+			"file_url":   "https://files.example.com/drain.pdf",
+			"page_count": 1,
+		}, &job); status != http.StatusCreated {
+			t.Fatalf("create job: status %d", status)
+		}
+		if _, err := app.DB.Exec(`UPDATE print_jobs SET status = $2 WHERE id = $1`, job.ID, models.JobStatusPrinting); err != nil {
+			t.Fatalf("mark job printing: %v", err)
+		}
+
+		var drained drainedNode
+		if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/edge-nodes/"+nodeID+"/drain", adminToken, nil, &drained); status != http.StatusOK {
+			t.Fatalf("drain node: status %d", status)
+		}
+		if !drained.Data.Enabled || !drained.Data.Draining || drained.Data.ActiveJobs == nil || *drained.Data.ActiveJobs != 1 {
+			t.Fatalf("drain node = %+v, want enabled and draining with 1 active job", drained.Data)
+		}
+
+		// 排空中的节点不接收新任务
+		var rejected struct {
+			ErrorCode string `json:"error_code"`
+		}
+		if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, map[string]interface{}{
+			"printer_id": printer.Data.ID,
+			"file_url":   "https://files.example.com/drain-new.pdf",
+			"page_count": 1,
+		}, &rejected); status != http.StatusConflict || rejected.ErrorCode != "edge_node_deleted" {
+			t.Fatalf("create job on draining node: status %d, error_code %q; want 409 edge_node_deleted", status, rejected.ErrorCode)
+		}
+		return nodeID, job.ID
+	}
+
+	nodeState := func(t *testing.T, nodeID string) drainedNode {
+		t.Helper()
+		var node drainedNode
+		if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/admin/edge-nodes/"+nodeID, adminToken, nil, &node); status != http.StatusOK {
+			t.Fatalf("get node: status %d", status)
+		}
+		return node
+	}
+
+	t.Run("completes naturally", func(t *testing.T) {
+		nodeID, jobID := setup(t)
+
+		app.drainMonitor.Check(time.Now().UTC())
+		if node := nodeState(t, nodeID); !node.Data.Enabled || !node.Data.Draining {
+			t.Fatalf("node with active job = %+v, want still draining", node.Data)
+		}
+
+		if _, err := app.DB.Exec(`UPDATE print_jobs SET status = $2 WHERE id = $1`, jobID, models.JobStatusCompleted); err != nil {
+			t.Fatalf("complete job: %v", err)
+		}
+		app.drainMonitor.Check(time.Now().UTC())
+		if node := nodeState(t, nodeID); node.Data.Enabled || node.Data.Draining {
+			t.Fatalf("node after jobs finished = %+v, want disabled", node.Data)
+		}
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		nodeID, jobID := setup(t)
+
+		// 默认最长排空时间为 2 小时
+		app.drainMonitor.Check(time.Now().UTC().Add(3 * time.Hour))
+		if node := nodeState(t, nodeID); node.Data.Enabled || node.Data.Draining {
+			t.Fatalf("node past drain deadline = %+v, want disabled", node.Data)
+		}
+		var status models.JobStatus
+		if err := app.DB.QueryRow(`SELECT status FROM print_jobs WHERE id = $1`, jobID).Scan(&status); err != nil {
+			t.Fatalf("get job status: %v", err)
+		}
+		if status != models.JobStatusPrinting {
+			t.Fatalf("job status after drain timeout = %s, want %s", status, models.JobStatusPrinting)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestEdgeSummaryScopedToNode 两个节点各有打印机：节点只能读到自己的汇总，读取其他节点返回 403
+func TestEdgeSummaryScopedToNode(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	suffix := uuid.New().String()[:8]
+	nodes := map[string]string{"node-a-" + suffix: "A-Printer", "node-b-" + suffix: "B-Printer"}
+	tokens := map[string]string{}
+	for nodeID, printerName := range nodes {
+		tokens[nodeID] = testToken(t, jwt.MapClaims{
+			"sub":     "edge-client-" + nodeID,
+			"node_id": nodeID,
+			"scope": strings.Join([]string{
+				middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect, middleware.ScopeEdgePrinterWrite,
+			}, " "),
+		})
+		if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/register", tokens[nodeID],
+			map[string]string{"node_id": nodeID, "name": nodeID}, nil); status != http.StatusCreated {
+			t.Fatalf("register %s: status %d", nodeID, status)
+		}
+		if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/"+nodeID+"/printers", tokens[nodeID],
+			map[string]string{"name": printerName, "model": "LaserJet"}, nil); status != http.StatusCreated {
+			t.Fatalf("register printer on %s: status %d", nodeID, status)
+		}
+	}
+
+	for nodeID, printerName := range nodes {
+		var summary struct {
+			Data struct {
+				EdgeNodeID string `json:"edge_node_id"`
+				Printers   []struct {
+					Name string `json:"name"`
+				} `json:"printers"`
+			} `json:"data"`
+		}
+		if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/edge/"+nodeID+"/summary", tokens[nodeID], nil, &summary); status != http.StatusOK {
+			t.Fatalf("summary of %s: status %d", nodeID, status)
+		}
+		if summary.Data.EdgeNodeID != nodeID || len(summary.Data.Printers) != 1 || summary.Data.Printers[0].Name != printerName {
+			t.Fatalf("summary of %s = %+v, want only %s", nodeID, summary.Data, printerName)
+		}
+
+		for otherID := range nodes {
+			if otherID == nodeID {
+				continue
+			}
+			if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/edge/"+otherID+"/summary", tokens[nodeID], nil, nil); status != http.StatusForbidden {
+				t.Fatalf("%s reading summary of %s: status %d, want 403", nodeID, otherID, status)
+			}
+		}
+	}
+}
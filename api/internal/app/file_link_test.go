@@ -0,0 +1,137 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/storage"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestSupportFileLink 管理员生成的临时链接无需登录即可下载原始文件，访问以生成链接的管理员身份记入审计日志；
+// 过期链接返回 410，篡改的链接返回 403
+func TestSupportFileLink(t *testing.T) {
+	const secret = "support-link-test-secret"
+	t.Setenv("FLY_PRINT_STORAGE_SIGNING_SECRET", secret)
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	// 直接写入存储目录并创建引用该文件的任务
+	files, err := storage.NewLocalStorage(os.Getenv("FLY_PRINT_STORAGE_DIR"), "", "")
+	if err != nil {
+		t.Fatalf("local storage: %v", err)
+	}
+	content := []byte("%PDF-1.4 support link test")
+	key := "uploads/" + uuid.New().String() + ".pdf"
+	if err := files.Put(context.Background(), key, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("put file: %v", err)
+	}
+	nodeID := "node-" + uuid.New().String()[:8]
+	printerID := uuid.New().String()
+	jobID := uuid.New().String()
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'offline')`, []interface{}{nodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug) VALUES ($1, 'link-printer', 'ready', $2, 'link-printer')`, []interface{}{printerID, nodeID}},
+		{`INSERT INTO print_jobs (id, name, status, printer_id, user_name, storage_key, file_size, page_count, copies)
+			VALUES ($1, 'report', 'completed', $2, 'alice', $3, $4, 1, 1)`, []interface{}{jobID, printerID, key, len(content)}},
+	} {
+		if _, err := app.DB.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-ext-1",
+		"preferred_username": "support-admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+	operatorToken := testToken(t, jwt.MapClaims{
+		"sub":          "op-1",
+		"realm_access": map[string]interface{}{"roles": []string{middleware.RoleOperator}},
+	})
+	linkURL := srv.URL + "/api/v1/admin/print-jobs/" + jobID + "/file-link"
+	if status := doJSON(t, http.MethodPost, linkURL, operatorToken, nil, nil); status != http.StatusForbidden {
+		t.Fatalf("operator creating a link: status %d, want 403", status)
+	}
+
+	var link struct {
+		Data struct {
+			URL       string    `json:"url"`
+			ExpiresAt time.Time `json:"expires_at"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, linkURL, adminToken, map[string]int{"expires_in": 600}, &link); status != http.StatusCreated {
+		t.Fatalf("create link: status %d", status)
+	}
+	if until := time.Until(link.Data.ExpiresAt); until < 9*time.Minute || until > 11*time.Minute {
+		t.Fatalf("expires_at = %s, want about 10 minutes from now", link.Data.ExpiresAt)
+	}
+	_, token, ok := strings.Cut(link.Data.URL, "/files/")
+	if !ok {
+		t.Fatalf("url = %q, want a /files/ link", link.Data.URL)
+	}
+
+	// 无需登录下载
+	status, body := getFile(t, srv.URL+"/files/"+token)
+	if status != http.StatusOK || !bytes.Equal(body, content) {
+		t.Fatalf("download: status %d, body %q", status, body)
+	}
+
+	var audit struct {
+		Data struct {
+			Items []struct {
+				Action string `json:"action"`
+				Actor  string `json:"actor"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/admin/audit-logs?resource_type=print_job&resource_id="+jobID, adminToken, nil, &audit); status != http.StatusOK {
+		t.Fatalf("list audit logs: status %d", status)
+	}
+	actions := map[string]string{}
+	for _, item := range audit.Data.Items {
+		actions[item.Action] = item.Actor
+	}
+	if actions["print_job.file_link_create"] != "support-admin" || actions["print_job.file_link_access"] != "support-admin" {
+		t.Fatalf("audit logs = %+v, want create and access by support-admin", audit.Data.Items)
+	}
+
+	// 过期和篡改
+	signer, _ := storage.NewLinkSigner(secret)
+	expired, _ := signer.Sign(storage.LinkClaims{JobID: jobID, ExpiresAt: time.Now().Add(-time.Minute).Unix(), IssuedBy: "support-admin"})
+	if status, _ := getFile(t, srv.URL+"/files/"+expired); status != http.StatusGone {
+		t.Fatalf("expired link: status %d, want 410", status)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+	if status, _ := getFile(t, srv.URL+"/files/"+payload+"."+strings.ToUpper(signature)); status != http.StatusForbidden {
+		t.Fatalf("tampered link: status %d, want 403", status)
+	}
+}
+
+// getFile 不带认证信息的 GET 请求
+func getFile(t *testing.T, url string) (int, []byte) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("get %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp.StatusCode, body
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/storage"
+	"fly-print-cloud/api/internal/worker"
+	"github.com/google/uuid"
+)
+
+// flakyStorage 第 failOn 次（从 1 开始）写入失败，其余写入交给本地存储
+type flakyStorage struct {
+	storage.Storage
+	mu     sync.Mutex
+	puts   int
+	failOn int
+}
+
+func (s *flakyStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	s.mu.Lock()
+	s.puts++
+	fail := s.puts == s.failOn
+	s.mu.Unlock()
+	if fail {
+		return errors.New("simulated upload failure")
+	}
+	return s.Storage.Put(ctx, key, r, size, contentType)
+}
+
+// TestJobExportResumesAfterFailure 两天共 7 个已完成任务，每个文件 2 行：第 3 个文件上传失败后从游标继续，
+// 每个任务恰好导出一次，文件的行数和 SHA-256 与记录一致
+func TestJobExportResumesAfterFailure(t *testing.T) {
+	app := buildTestApp(t)
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	printerID := uuid.New().String()
+	if _, err := app.DB.Exec(`INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'offline')`, nodeID); err != nil {
+		t.Fatalf("seed node: %v", err)
+	}
+	if _, err := app.DB.Exec(`INSERT INTO printers (id, name, status, edge_node_id, slug) VALUES ($1, 'export-printer', 'ready', $2, 'export-printer')`, printerID, nodeID); err != nil {
+		t.Fatalf("seed printer: %v", err)
+	}
+	day1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	want := map[string]bool{}
+	seed := func(status models.JobStatus, createdAt time.Time) {
+		id := uuid.New().String()
+		if _, err := app.DB.Exec(`INSERT INTO print_jobs (id, name, status, printer_id, user_name, file_url, page_count, copies, created_at, updated_at)
+			VALUES ($1, 'export.pdf', $2, $3, 'alice', 'https://files.example.com/export.pdf', 1, 1, $4, $4)`, id, status, printerID, createdAt); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		if status == models.JobStatusCompleted || status == models.JobStatusPartiallyCompleted {
+			if !createdAt.Before(day1) && createdAt.Before(day2.AddDate(0, 0, 1)) {
+				want[id] = true
+			}
+		}
+	}
+	// 同一时刻创建的任务按 id 排序，验证游标不会跳过或重复
+	for i := 0; i < 4; i++ {
+		seed(models.JobStatusCompleted, day1.Add(time.Hour))
+	}
+	seed(models.JobStatusPartiallyCompleted, day1.Add(23*time.Hour))
+	seed(models.JobStatusCompleted, day2.Add(time.Minute))
+	seed(models.JobStatusCompleted, day2.Add(2*time.Minute))
+	seed(models.JobStatusFailed, day1.Add(2*time.Hour))     // 未完成，不导出
+	seed(models.JobStatusCompleted, day2.AddDate(0, 0, 1))  // 超出范围
+	seed(models.JobStatusCompleted, day1.Add(-time.Second)) // 超出范围
+
+	local, err := storage.NewLocalStorage(t.TempDir(), "", "")
+	if err != nil {
+		t.Fatalf("local storage: %v", err)
+	}
+	store := &flakyStorage{Storage: local, failOn: 3}
+	settings := config.NewStore(&config.Config{Exports: config.ExportsConfig{Prefix: "exports", PageSize: 1, RowsPerFile: 2}})
+	runRepo := database.NewExportRunRepository(app.DB)
+	exporter := worker.NewJobExporter(runRepo, database.NewPrintJobRepository(app.DB), store, settings)
+
+	run := &models.ExportRun{Format: models.ExportFormatJSONL, Trigger: models.ExportTriggerManual,
+		FromDate: day1.Format(models.ExportDateLayout), ToDate: day2.Format(models.ExportDateLayout)}
+	if err := runRepo.CreateExportRun(run); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+
+	if !exporter.RunNext(time.Now()) {
+		t.Fatal("first attempt did not claim the run")
+	}
+	failed, err := runRepo.GetExportRun(run.ID)
+	if err != nil || failed.Status != models.ExportStatusFailed || len(failed.Files) != 2 || failed.RowCount != 4 || failed.LastJobID == "" {
+		t.Fatalf("run after failure = %+v, %v; want failed with 2 files, 4 rows and a cursor", failed, err)
+	}
+	if exporter.RunNext(time.Now()) {
+		t.Fatal("failed run claimed again before resume")
+	}
+
+	if resumed, err := runRepo.ResumeExportRun(run.ID); err != nil || !resumed {
+		t.Fatalf("ResumeExportRun = %v, %v", resumed, err)
+	}
+	if !exporter.RunNext(time.Now()) {
+		t.Fatal("resumed run not claimed")
+	}
+	done, err := runRepo.GetExportRun(run.ID)
+	if err != nil || done.Status != models.ExportStatusCompleted || done.Attempts != 2 || done.RowCount != int64(len(want)) {
+		t.Fatalf("run after resume = %+v, %v; want completed after 2 attempts with %d rows", done, err, len(want))
+	}
+
+	// 第一天 5 行拆成 3 个文件，第二天 2 行 1 个文件；文件编号接着失败前的编号
+	wantKeys := []string{
+		worker.ExportFileKey("exports", "2026-03-01", run.ID, 0),
+		worker.ExportFileKey("exports", "2026-03-01", run.ID, 1),
+		worker.ExportFileKey("exports", "2026-03-01", run.ID, 2),
+		worker.ExportFileKey("exports", "2026-03-02", run.ID, 0),
+	}
+	if len(done.Files) != len(wantKeys) {
+		t.Fatalf("files = %+v, want %d", done.Files, len(wantKeys))
+	}
+	exported := map[string]int{}
+	for i, file := range done.Files {
+		if file.Key != wantKeys[i] {
+			t.Fatalf("file %d key = %s, want %s", i, file.Key, wantKeys[i])
+		}
+		ids := readExportFile(t, local, file)
+		for _, id := range ids {
+			exported[id]++
+		}
+	}
+	for id := range want {
+		if exported[id] != 1 {
+			t.Errorf("job %s exported %d times, want once", id, exported[id])
+		}
+	}
+	if len(exported) != len(want) {
+		t.Errorf("exported %d distinct jobs, want %d", len(exported), len(want))
+	}
+}
+
+// readExportFile 读取导出文件，校验大小、SHA-256 和行数，返回其中的任务ID
+func readExportFile(t *testing.T, store storage.Storage, file models.ExportFile) []string {
+	t.Helper()
+	reader, err := store.Get(context.Background(), file.Key)
+	if err != nil {
+		t.Fatalf("get %s: %v", file.Key, err)
+	}
+	defer reader.Close()
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read %s: %v", file.Key, err)
+	}
+	sum := sha256.Sum256(raw)
+	if int64(len(raw)) != file.Bytes || hex.EncodeToString(sum[:]) != file.SHA256 {
+		t.Fatalf("%s: size %d / checksum %x do not match recorded %d / %s", file.Key, len(raw), sum, file.Bytes, file.SHA256)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("gunzip %s: %v", file.Key, err)
+	}
+	var ids []string
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var job models.PrintJob
+		if err := json.Unmarshal(scanner.Bytes(), &job); err != nil {
+			t.Fatalf("%s: decode line: %v", file.Key, err)
+		}
+		if job.CreatedAt.UTC().Format(models.ExportDateLayout) != file.Date {
+			t.Fatalf("%s: job %s created %s outside partition", file.Key, job.ID, job.CreatedAt)
+		}
+		ids = append(ids, job.ID)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan %s: %v", file.Key, err)
+	}
+	if len(ids) != file.Rows {
+		t.Fatalf("%s: %d lines, recorded %d rows", file.Key, len(ids), file.Rows)
+	}
+	return ids
+}
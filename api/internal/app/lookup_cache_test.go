@@ -0,0 +1,121 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestLookupCacheInvalidatedOnDisable 打印机已在查询缓存中时禁用，下一次提交立即被拒绝，重新启用后立即恢复
+func TestLookupCacheInvalidatedOnDisable(t *testing.T) {
+	app := buildTestApp(t)
+	if !app.Settings.Get().LookupCache.Enabled {
+		t.Fatal("lookup cache should be enabled by default")
+	}
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	edgeToken := testToken(t, jwt.MapClaims{
+		"sub":     "edge-client-" + nodeID,
+		"node_id": nodeID,
+		"scope":   strings.Join([]string{middleware.ScopeEdgeRegister, middleware.ScopeEdgePrinterWrite}, " "),
+	})
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/register", edgeToken,
+		map[string]string{"node_id": nodeID, "name": nodeID}, nil); status != http.StatusCreated {
+		t.Fatalf("register node: status %d", status)
+	}
+	var printer struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/"+nodeID+"/printers", edgeToken,
+		map[string]string{"name": "Cached-Printer", "model": "LaserJet"}, &printer); status != http.StatusCreated {
+		t.Fatalf("register printer: status %d", status)
+	}
+	printerID := printer.Data.ID
+
+	var rejected struct {
+		ErrorCode string `json:"error_code"`
+	}
+	submit := func() int {
+		rejected.ErrorCode = ""
+		return doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, map[string]interface{}{
+			"printer_id": printerID,
+			"file_url":   "https://files.example.com/cached.pdf",
+			"page_count": 1,
+		}, &rejected)
+	}
+	setEnabled := func(enabled bool) {
+		t.Helper()
+		current, err := database.NewPrinterRepository(app.DB).GetPrinterByID(printerID)
+		if err != nil {
+			t.Fatalf("get printer: %v", err)
+		}
+		if status := doJSON(t, http.MethodPut, srv.URL+"/api/v1/admin/printers/"+printerID, adminToken,
+			map[string]interface{}{"enabled": enabled, "row_version": current.RowVersion}, nil); status != http.StatusOK {
+			t.Fatalf("set enabled=%v: status %d", enabled, status)
+		}
+	}
+
+	// 两次提交，第二次的打印机和节点查询命中缓存
+	for i := 0; i < 2; i++ {
+		if status := submit(); status != http.StatusCreated {
+			t.Fatalf("submit %d: status %d, want 201", i, status)
+		}
+	}
+	hits := cacheHits(t, srv.URL)
+	if hits == 0 {
+		t.Fatal("no lookup cache hits after repeated submissions")
+	}
+
+	setEnabled(false)
+	if status := submit(); status != http.StatusConflict || rejected.ErrorCode != "printer_disabled" {
+		t.Fatalf("submit after disable: status %d, error_code %q; want 409 printer_disabled", status, rejected.ErrorCode)
+	}
+
+	setEnabled(true)
+	if status := submit(); status != http.StatusCreated {
+		t.Fatalf("submit after re-enable: status %d, want 201", status)
+	}
+}
+
+// cacheHits 读取 /metrics 中打印机查询的缓存命中次数
+func cacheHits(t *testing.T, baseURL string) int {
+	t.Helper()
+	resp, err := http.Get(baseURL + "/metrics")
+	if err != nil {
+		t.Fatalf("get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	raw := new(strings.Builder)
+	if _, err := io.Copy(raw, resp.Body); err != nil {
+		t.Fatalf("read metrics: %v", err)
+	}
+	const prefix = `fly_print_lookup_cache_hits_total{kind="printer"} `
+	for _, line := range strings.Split(raw.String(), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			hits, err := strconv.Atoi(strings.TrimPrefix(line, prefix))
+			if err != nil {
+				t.Fatalf("parse %q: %v", line, err)
+			}
+			return hits
+		}
+	}
+	return 0
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// getETag 读取详情接口返回的 ETag
+func getETag(t *testing.T, target, token string) string {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", target, resp.StatusCode)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatalf("GET %s: missing ETag", target)
+	}
+	return etag
+}
+
+// TestAdminLostUpdateRejected 两个管理员基于同一版本修改打印机或 Edge Node 时，后保存者得到 409，
+// 先保存者的修改保留；未携带版本号的修改返回 428
+func TestAdminLostUpdateRejected(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	edgeToken := testToken(t, jwt.MapClaims{
+		"sub":     "edge-client-" + nodeID,
+		"node_id": nodeID,
+		"scope":   strings.Join([]string{middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect, middleware.ScopeEdgePrinterWrite}, " "),
+	})
+	aliceToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-alice",
+		"preferred_username": "alice",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+	bobToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-bob",
+		"preferred_username": "bob",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/register", edgeToken,
+		map[string]string{"node_id": nodeID, "name": nodeID}, nil); status != http.StatusCreated {
+		t.Fatalf("register node: status %d", status)
+	}
+	var printer struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/"+nodeID+"/printers", edgeToken,
+		map[string]string{"name": "Lost-Update-Printer", "model": "LaserJet"}, &printer); status != http.StatusCreated {
+		t.Fatalf("register printer: status %d", status)
+	}
+
+	t.Run("printer via If-Match", func(t *testing.T) {
+		target := srv.URL + "/api/v1/admin/printers/" + printer.Data.ID
+		aliceETag := getETag(t, target, aliceToken)
+		bobETag := getETag(t, target, bobToken)
+
+		if status := doJSON(t, http.MethodPut, target, aliceToken, map[string]interface{}{"display_name": "Alice"}, nil); status != http.StatusPreconditionRequired {
+			t.Fatalf("update without version: status %d, want 428", status)
+		}
+		if status := doJSONWithHeader(t, http.MethodPut, target, aliceToken, http.Header{"If-Match": {aliceETag}},
+			map[string]interface{}{"display_name": "Alice"}, nil); status != http.StatusOK {
+			t.Fatalf("alice update: status %d", status)
+		}
+		if status := doJSONWithHeader(t, http.MethodPut, target, bobToken, http.Header{"If-Match": {bobETag}},
+			map[string]interface{}{"display_name": "Bob", "enabled": false}, nil); status != http.StatusConflict {
+			t.Fatalf("bob update from stale version: status %d, want 409", status)
+		}
+
+		var got struct {
+			Data struct {
+				DisplayName string `json:"display_name"`
+				Enabled     bool   `json:"enabled"`
+			} `json:"data"`
+		}
+		if status := doJSON(t, http.MethodGet, target, aliceToken, nil, &got); status != http.StatusOK {
+			t.Fatalf("get printer: status %d", status)
+		}
+		if got.Data.DisplayName != "Alice" || !got.Data.Enabled {
+			t.Fatalf("printer = %+v, want alice's edit kept", got.Data)
+		}
+		if etag := getETag(t, target, bobToken); etag == bobETag {
+			t.Fatalf("ETag unchanged after update: %s", etag)
+		}
+	})
+
+	t.Run("edge node via row_version", func(t *testing.T) {
+		target := srv.URL + "/api/v1/admin/edge-nodes/" + nodeID
+		var read struct {
+			Data struct {
+				RowVersion int `json:"row_version"`
+			} `json:"data"`
+		}
+		if status := doJSON(t, http.MethodGet, target, aliceToken, nil, &read); status != http.StatusOK {
+			t.Fatalf("get node: status %d", status)
+		}
+
+		if status := doJSON(t, http.MethodPut, target, aliceToken,
+			map[string]interface{}{"name": "alice-node", "row_version": read.Data.RowVersion}, nil); status != http.StatusOK {
+			t.Fatalf("alice update: status %d", status)
+		}
+		if status := doJSON(t, http.MethodPut, target, bobToken,
+			map[string]interface{}{"name": "bob-node", "enabled": false, "row_version": read.Data.RowVersion}, nil); status != http.StatusConflict {
+			t.Fatalf("bob update from stale version: status %d, want 409", status)
+		}
+
+		var got struct {
+			Data struct {
+				Name       string `json:"name"`
+				Enabled    bool   `json:"enabled"`
+				RowVersion int    `json:"row_version"`
+			} `json:"data"`
+		}
+		if status := doJSON(t, http.MethodGet, target, aliceToken, nil, &got); status != http.StatusOK {
+			t.Fatalf("get node: status %d", status)
+		}
+		if got.Data.Name != "alice-node" || !got.Data.Enabled || got.Data.RowVersion != read.Data.RowVersion+1 {
+			t.Fatalf("node = %+v, want alice's edit at version %d", got.Data, read.Data.RowVersion+1)
+		}
+
+		// 节点心跳和自身信息上报不递增版本号，管理员基于最新版本的修改仍然成功
+		if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/heartbeat", edgeToken,
+			map[string]string{"node_id": nodeID}, nil); status != http.StatusOK {
+			t.Fatalf("heartbeat: status %d", status)
+		}
+		if status := doJSON(t, http.MethodPut, srv.URL+"/api/v1/edge/"+nodeID+"/info", edgeToken,
+			map[string]string{"os_version": "Linux 6.1"}, nil); status != http.StatusOK {
+			t.Fatalf("edge info update: status %d", status)
+		}
+		if status := doJSON(t, http.MethodPut, target, bobToken,
+			map[string]interface{}{"name": "bob-node", "row_version": got.Data.RowVersion}, nil); status != http.StatusOK {
+			t.Fatalf("bob update after heartbeat: status %d", status)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestReprintOnBehalfOf 用户提交的任务和管理员代该用户重打的任务都记录用户ID，
+// 出现在按 user_id 过滤的任务列表和按用户分组的费用统计中；代提交的用户不存在时返回 404
+func TestReprintOnBehalfOf(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	printerID := uuid.New().String()
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'offline')`, []interface{}{nodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug) VALUES ($1, 'behalf-printer', 'ready', $2, 'behalf-printer')`, []interface{}{printerID, nodeID}},
+	} {
+		if _, err := app.DB.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	carolID := createOAuth2User(t, app, "user-carol", "viewer")
+	var carolName string
+	if err := app.DB.QueryRow(`SELECT username FROM users WHERE id = $1`, carolID).Scan(&carolName); err != nil {
+		t.Fatalf("read username: %v", err)
+	}
+	carolToken := testToken(t, jwt.MapClaims{
+		"sub":                "user-carol",
+		"preferred_username": carolName,
+		"scope":              "print:submit",
+	})
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+
+	type job struct {
+		ID          string `json:"id"`
+		UserID      string `json:"user_id"`
+		UserName    string `json:"user_name"`
+		PerformedBy string `json:"performed_by"`
+	}
+	var original job
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/print-jobs", carolToken, map[string]interface{}{
+		"printer_id": printerID,
+		"file_url":   "https://files.example.com/behalf.pdf",
+		"page_count": 2,
+	}, &original); status != http.StatusCreated {
+		t.Fatalf("create job: status %d", status)
+	}
+	if original.UserID != carolID {
+		t.Fatalf("created job user_id = %q, want %s", original.UserID, carolID)
+	}
+	if _, err := app.DB.Exec(`UPDATE print_jobs SET status = 'completed', cost = 1 WHERE id = $1`, original.ID); err != nil {
+		t.Fatalf("complete job: %v", err)
+	}
+
+	reprintURL := srv.URL + "/api/v1/admin/print-jobs/" + original.ID + "/reprint"
+	if status := doJSON(t, http.MethodPost, reprintURL, adminToken,
+		map[string]string{"printer_id": printerID, "on_behalf_of": "nobody-" + uuid.New().String()[:8]}, nil); status != http.StatusNotFound {
+		t.Fatalf("reprint for unknown user: status %d, want 404", status)
+	}
+
+	var reprint job
+	if status := doJSON(t, http.MethodPost, reprintURL, adminToken,
+		map[string]string{"printer_id": printerID, "on_behalf_of": carolName}, &reprint); status != http.StatusCreated {
+		t.Fatalf("reprint on behalf of carol: status %d", status)
+	}
+	if reprint.UserID != carolID || reprint.UserName != carolName || reprint.PerformedBy != "admin" {
+		t.Fatalf("reprint = %+v, want user %s (%s) performed by admin", reprint, carolID, carolName)
+	}
+	if _, err := app.DB.Exec(`UPDATE print_jobs SET status = 'completed', cost = 1 WHERE id = $1`, reprint.ID); err != nil {
+		t.Fatalf("complete reprint: %v", err)
+	}
+
+	// 用户的任务历史
+	var history struct {
+		Jobs []job `json:"jobs"`
+	}
+	if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/admin/print-jobs?user_id="+carolID, adminToken, nil, &history); status != http.StatusOK {
+		t.Fatalf("list carol's jobs: status %d", status)
+	}
+	listed := map[string]bool{}
+	for _, j := range history.Jobs {
+		listed[j.ID] = true
+	}
+	if len(history.Jobs) != 2 || !listed[original.ID] || !listed[reprint.ID] {
+		t.Fatalf("carol's history = %+v, want the original and the reprint", history.Jobs)
+	}
+
+	// 按用户分组的费用统计
+	var costs struct {
+		Data struct {
+			Items []struct {
+				Key      string `json:"key"`
+				Name     string `json:"name"`
+				JobCount int    `json:"job_count"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/admin/dashboard/costs?group_by=user", adminToken, nil, &costs); status != http.StatusOK {
+		t.Fatalf("cost stats: status %d", status)
+	}
+	for _, stat := range costs.Data.Items {
+		if stat.Key == carolID {
+			if stat.JobCount != 2 || stat.Name != carolName {
+				t.Fatalf("carol's cost stat = %+v, want 2 jobs", stat)
+			}
+			return
+		}
+	}
+	t.Fatalf("cost stats %+v missing user %s", costs.Data.Items, carolID)
+}
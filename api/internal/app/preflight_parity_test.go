@@ -0,0 +1,143 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestPreflightMatchesCreate 同一请求的预检结果与随后的创建结果一致：失败时状态码和响应体相同，
+// 成功时创建的任务使用预检选出的打印机；预检本身不创建任务
+func TestPreflightMatchesCreate(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	deletedNodeID := "node-" + uuid.New().String()[:8]
+	ready := uuid.New().String()
+	disabled := uuid.New().String()
+	orphaned := uuid.New().String()
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'online')`, []interface{}{nodeID}},
+		{`INSERT INTO edge_nodes (id, name, status, deleted_at) VALUES ($1, $1, 'offline', NOW())`, []interface{}{deletedNodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug, capabilities)
+		  VALUES ($1, 'preflight-ready', 'ready', $2, 'preflight-ready', '{"paper_sizes":["iso_a4_210x297mm"]}')`, []interface{}{ready, nodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug, enabled) VALUES ($1, 'preflight-disabled', 'ready', $2, 'preflight-disabled', false)`, []interface{}{disabled, nodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug) VALUES ($1, 'preflight-orphaned', 'ready', $2, 'preflight-orphaned')`, []interface{}{orphaned, deletedNodeID}},
+	} {
+		if _, err := app.DB.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+
+	var group struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/printer-groups", adminToken, map[string]interface{}{
+		"name":        "preflight-" + nodeID,
+		"printer_ids": []string{disabled, ready, orphaned},
+	}, &group); status != http.StatusCreated {
+		t.Fatalf("create group: status %d", status)
+	}
+
+	job := func(fields map[string]interface{}) map[string]interface{} {
+		req := map[string]interface{}{"file_url": "https://files.example.com/preflight.pdf", "page_count": 1}
+		for k, v := range fields {
+			req[k] = v
+		}
+		return req
+	}
+	tests := []struct {
+		name   string
+		req    map[string]interface{}
+		ok     bool
+		status int
+	}{
+		{"accepted", job(map[string]interface{}{"printer_id": ready, "paper_size": "A4"}), true, http.StatusCreated},
+		{"group selects the eligible printer", job(map[string]interface{}{"printer_group_id": group.Data.ID}), true, http.StatusCreated},
+		{"unsupported paper size", job(map[string]interface{}{"printer_id": ready, "paper_size": "A3"}), false, http.StatusBadRequest},
+		{"unknown printer", job(map[string]interface{}{"printer_id": uuid.New().String()}), false, http.StatusBadRequest},
+		{"disabled printer", job(map[string]interface{}{"printer_id": disabled}), false, http.StatusConflict},
+		{"deleted edge node", job(map[string]interface{}{"printer_id": orphaned}), false, http.StatusConflict},
+		{"no printer", job(nil), false, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := countJobs(t, app)
+			var preflight struct {
+				OK      bool `json:"ok"`
+				Printer struct {
+					ID string `json:"id"`
+				} `json:"printer"`
+				InitialStatus string `json:"initial_status"`
+				Checks        []struct {
+					Check  string `json:"check"`
+					Status string `json:"status"`
+				} `json:"checks"`
+				Failure struct {
+					Status int                    `json:"status"`
+					Body   map[string]interface{} `json:"body"`
+				} `json:"failure"`
+			}
+			if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs/preflight", adminToken, tt.req, &preflight); status != http.StatusOK {
+				t.Fatalf("preflight: status %d", status)
+			}
+			if after := countJobs(t, app); after != before {
+				t.Fatalf("preflight created %d jobs", after-before)
+			}
+			if preflight.OK != tt.ok || len(preflight.Checks) == 0 {
+				t.Fatalf("preflight = %+v, want ok %v", preflight, tt.ok)
+			}
+			for _, check := range preflight.Checks {
+				if (check.Status == "failed") == tt.ok {
+					t.Fatalf("check %s %s with ok %v", check.Check, check.Status, tt.ok)
+				}
+			}
+
+			var created map[string]interface{}
+			status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, tt.req, &created)
+			if status != tt.status {
+				t.Fatalf("create: status %d, want %d (%v)", status, tt.status, created)
+			}
+			if tt.ok {
+				if created["printer_id"] != preflight.Printer.ID || created["status"] != preflight.InitialStatus {
+					t.Fatalf("created job on %v with status %v, preflight chose %s with %s",
+						created["printer_id"], created["status"], preflight.Printer.ID, preflight.InitialStatus)
+				}
+				if tt.req["printer_group_id"] != nil && created["printer_id"] != ready {
+					t.Fatalf("group job on %v, want the only eligible printer %s", created["printer_id"], ready)
+				}
+				return
+			}
+			if preflight.Failure.Status != status || !reflect.DeepEqual(preflight.Failure.Body, created) {
+				t.Fatalf("preflight failure %d %v, create %d %v", preflight.Failure.Status, preflight.Failure.Body, status, created)
+			}
+		})
+	}
+}
+
+// countJobs 当前任务总数
+func countJobs(t *testing.T, app *App) int {
+	t.Helper()
+	var count int
+	if err := app.DB.QueryRow(`SELECT COUNT(*) FROM print_jobs`).Scan(&count); err != nil {
+		t.Fatalf("count jobs: %v", err)
+	}
+	return count
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/dispatch"
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// panickingStorage 生成签名链接时 panic，模拟分发过程中的意外错误
+type panickingStorage struct{}
+
+func (panickingStorage) Put(context.Context, string, io.Reader, int64, string) error { return nil }
+func (panickingStorage) Get(context.Context, string) (io.ReadCloser, error)          { return nil, nil }
+func (panickingStorage) Delete(context.Context, string) error                        { return nil }
+func (panickingStorage) SignedURL(context.Context, string, time.Duration) (string, error) {
+	panic("storage unavailable")
+}
+
+// TestSubmitPrintJobFailures 提交任务的各阶段失败：校验失败和入库失败都不留下任务记录，
+// 分发 panic 时任务保留并退回待分发，记录 dispatch_error 和 dispatch_failed 事件
+func TestSubmitPrintJobFailures(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	printerID := uuid.New().String()
+	disabledID := uuid.New().String()
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'online')`, []interface{}{nodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug) VALUES ($1, 'failure-ready', 'ready', $2, 'failure-ready')`, []interface{}{printerID, nodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug, enabled) VALUES ($1, 'failure-disabled', 'ready', $2, 'failure-disabled', false)`, []interface{}{disabledID, nodeID}},
+	} {
+		if _, err := app.DB.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+	create := func(printer string) int {
+		t.Helper()
+		return doJSON(t, http.MethodPost, srv.URL+"/api/v1/print-jobs", adminToken, map[string]interface{}{
+			"printer_id": printer,
+			"file_url":   "https://files.example.com/failure.pdf",
+			"page_count": 1,
+		}, nil)
+	}
+	exec := func(query string) {
+		t.Helper()
+		if _, err := app.DB.Exec(query); err != nil {
+			t.Fatalf("exec %q: %v", query, err)
+		}
+	}
+
+	// 校验阶段
+	before := countJobs(t, app)
+	if status := create(uuid.New().String()); status != http.StatusBadRequest {
+		t.Fatalf("unknown printer: status %d, want 400", status)
+	}
+	if status := create(disabledID); status != http.StatusConflict {
+		t.Fatalf("disabled printer: status %d, want 409", status)
+	}
+	exec(`ALTER TABLE printers RENAME TO printers_unavailable`)
+	status := create(uuid.New().String())
+	exec(`ALTER TABLE printers_unavailable RENAME TO printers`)
+	if status != http.StatusInternalServerError {
+		t.Fatalf("printer lookup failure: status %d, want 500", status)
+	}
+	if after := countJobs(t, app); after != before {
+		t.Fatalf("validation failures left %d job rows", after-before)
+	}
+
+	// 入库阶段：任务记录写入后文件列表写入失败，整个事务回滚
+	exec(`CREATE FUNCTION reject_job_files() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'print_job_files insert rejected';
+		END;
+		$$ LANGUAGE plpgsql`)
+	exec(`CREATE TRIGGER reject_job_files BEFORE INSERT ON print_job_files
+		FOR EACH ROW EXECUTE FUNCTION reject_job_files()`)
+	status = create(printerID)
+	exec(`DROP TRIGGER reject_job_files ON print_job_files`)
+	if status != http.StatusInternalServerError {
+		t.Fatalf("persist failure: status %d, want 500", status)
+	}
+	if after := countJobs(t, app); after != before {
+		t.Fatalf("persist failure left %d orphaned job rows", after-before)
+	}
+
+	// 分发阶段：云端存储的文件生成签名链接时 panic
+	jobRepo := database.NewPrintJobRepository(app.DB)
+	printerRepo := database.NewPrinterRepository(app.DB)
+	eventRepo := database.NewPrintJobEventRepository(app.DB)
+	job := &models.PrintJob{
+		Name:       "failure.pdf",
+		Status:     models.JobStatusPending,
+		PrinterID:  printerID,
+		UserName:   "admin",
+		StorageKey: "uploads/" + uuid.New().String() + ".pdf",
+		FileSize:   1024,
+		PageCount:  1,
+		Copies:     1,
+		Source:     models.JobSourceAPI,
+	}
+	if err := jobRepo.CreatePrintJob(job); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	printer, err := printerRepo.GetPrinterByID(printerID)
+	if err != nil {
+		t.Fatalf("get printer: %v", err)
+	}
+	dispatcher := dispatch.NewDispatcher(jobRepo, printerRepo, nil, app.wsManager, panickingStorage{}, app.Settings, nil, nil, eventRepo, nil, nil, nil)
+	if err := dispatcher.SubmitCreated(job, printer); err == nil {
+		t.Fatal("SubmitCreated returned nil for a panicking dispatch")
+	}
+
+	stored, err := jobRepo.GetPrintJobByID(job.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("job row missing after dispatch failure: %v", err)
+	}
+	if stored.Status != models.JobStatusPending || stored.DispatchError != models.DispatchErrorInternal {
+		t.Fatalf("job after dispatch failure = %s (dispatch_error %q), want pending with %s",
+			stored.Status, stored.DispatchError, models.DispatchErrorInternal)
+	}
+	events, err := eventRepo.ListEvents(job.ID)
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	for _, event := range events {
+		if event.Type == models.JobEventDispatchFailed {
+			if event.Details["error_code"] != models.DispatchErrorInternal {
+				t.Fatalf("dispatch_failed details = %v", event.Details)
+			}
+			return
+		}
+	}
+	t.Fatalf("events = %+v, want dispatch_failed", events)
+}
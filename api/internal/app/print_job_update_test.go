@@ -0,0 +1,72 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestUpdatePrintJobFields 更新 file_url 后重新读取已保存；修改 user_id/printer_id 返回 400 且任务不变
+func TestUpdatePrintJobFields(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	printerID := uuid.New().String()
+	jobID := uuid.New().String()
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'offline')`, []interface{}{nodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug) VALUES ($1, 'update-printer', 'ready', $2, 'update-printer')`, []interface{}{printerID, nodeID}},
+		{`INSERT INTO print_jobs (id, name, status, printer_id, user_name, file_url, page_count, copies)
+		  VALUES ($1, 'update.pdf', 'pending', $2, 'alice', 'https://files.example.com/v1.pdf', 1, 1)`, []interface{}{jobID, printerID}},
+	} {
+		if _, err := app.DB.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+	jobURL := srv.URL + "/api/v1/admin/print-jobs/" + jobID
+
+	type job struct {
+		PrinterID string `json:"printer_id"`
+		FileURL   string `json:"file_url"`
+		Copies    int    `json:"copies"`
+	}
+	var updated job
+	if code := doJSON(t, http.MethodPut, jobURL, adminToken,
+		map[string]interface{}{"file_url": "https://files.example.com/v2.pdf"}, &updated); code != http.StatusOK {
+		t.Fatalf("update file_url: status %d", code)
+	}
+	if updated.FileURL != "https://files.example.com/v2.pdf" || updated.Copies != 1 {
+		t.Fatalf("update response = %+v", updated)
+	}
+
+	for name, body := range map[string]map[string]interface{}{
+		"user_id":    {"user_id": uuid.New().String()},
+		"printer_id": {"printer_id": uuid.New().String(), "copies": 5},
+	} {
+		if code := doJSON(t, http.MethodPut, jobURL, adminToken, body, nil); code != http.StatusBadRequest {
+			t.Fatalf("update %s: status %d, want 400", name, code)
+		}
+	}
+
+	var reread job
+	if code := doJSON(t, http.MethodGet, jobURL, adminToken, nil, &reread); code != http.StatusOK {
+		t.Fatalf("get job: status %d", code)
+	}
+	if reread.FileURL != "https://files.example.com/v2.pdf" || reread.PrinterID != printerID || reread.Copies != 1 {
+		t.Fatalf("job after updates = %+v", reread)
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestDeletePrinterWithJobs 有未结束任务时删除返回 409；force=true 时取消任务后删除，任务历史仍可查询
+func TestDeletePrinterWithJobs(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	printerID := uuid.New().String()
+	pendingID := uuid.New().String()
+	completedID := uuid.New().String()
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'offline')`, []interface{}{nodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug) VALUES ($1, 'delete-printer', 'ready', $2, 'delete-printer')`, []interface{}{printerID, nodeID}},
+		{`INSERT INTO print_jobs (id, name, status, printer_id, user_name, file_url, page_count, copies)
+		  VALUES ($1, 'pending.pdf', 'pending', $2, 'alice', 'https://files.example.com/a.pdf', 1, 1)`, []interface{}{pendingID, printerID}},
+		{`INSERT INTO print_jobs (id, name, status, printer_id, user_name, file_url, page_count, copies)
+		  VALUES ($1, 'done.pdf', 'completed', $2, 'alice', 'https://files.example.com/b.pdf', 1, 1)`, []interface{}{completedID, printerID}},
+	} {
+		if _, err := app.DB.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+	printerURL := srv.URL + "/api/v1/admin/printers/" + printerID
+
+	var conflict struct {
+		UnfinishedJobs int `json:"unfinished_jobs"`
+	}
+	if status := doJSON(t, http.MethodDelete, printerURL, adminToken, nil, &conflict); status != http.StatusConflict || conflict.UnfinishedJobs != 1 {
+		t.Fatalf("delete without force: status %d, unfinished %d; want 409 with 1", status, conflict.UnfinishedJobs)
+	}
+	if status := doJSON(t, http.MethodGet, printerURL, adminToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("printer after refused delete: status %d", status)
+	}
+
+	var deleted struct {
+		Data struct {
+			CancelledJobs int `json:"cancelled_jobs"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodDelete, printerURL+"?force=true", adminToken, nil, &deleted); status != http.StatusOK || deleted.Data.CancelledJobs != 1 {
+		t.Fatalf("force delete: status %d, cancelled %d; want 200 with 1", status, deleted.Data.CancelledJobs)
+	}
+	if status := doJSON(t, http.MethodGet, printerURL, adminToken, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("printer after force delete: status %d, want 404", status)
+	}
+
+	for id, want := range map[string]string{pendingID: "cancelled", completedID: "completed"} {
+		var job struct {
+			Status      string `json:"status"`
+			PrinterID   string `json:"printer_id"`
+			PrinterName string `json:"printer_name"`
+		}
+		if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/admin/print-jobs/"+id, adminToken, nil, &job); status != http.StatusOK {
+			t.Fatalf("get job %s after delete: status %d", id, status)
+		}
+		if job.Status != want || job.PrinterID != "" || job.PrinterName != "delete-printer" {
+			t.Fatalf("job %s = %+v, want %s detached from delete-printer", id, job, want)
+		}
+	}
+}
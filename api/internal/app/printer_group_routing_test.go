@@ -0,0 +1,88 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestPrinterGroupRoutingSkipsIneligible 组内负载最小的打印机已禁用时不参与选择；
+// 创建的任务上记录选中的打印机、组ID和所用策略，全部不可用时返回 409 no_eligible_printer
+func TestPrinterGroupRoutingSkipsIneligible(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	idle := uuid.New().String()
+	busy := uuid.New().String()
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'online')`, []interface{}{nodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug, enabled) VALUES ($1, 'group-idle', 'ready', $2, 'group-idle', false)`, []interface{}{idle, nodeID}},
+		{`INSERT INTO printers (id, name, status, edge_node_id, slug) VALUES ($1, 'group-busy', 'ready', $2, 'group-busy')`, []interface{}{busy, nodeID}},
+		{`INSERT INTO print_jobs (id, name, status, printer_id, user_name, file_url, page_count, copies)
+		  VALUES ($1, 'load.pdf', 'queued', $2, 'bob', 'https://files.example.com/load.pdf', 1, 1)`, []interface{}{uuid.New().String(), busy}},
+	} {
+		if _, err := app.DB.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	adminToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+
+	var group struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/printer-groups", adminToken, map[string]interface{}{
+		"name":             "routing-" + nodeID,
+		"routing_strategy": "least_queue",
+		"printer_ids":      []string{idle, busy},
+	}, &group); status != http.StatusCreated {
+		t.Fatalf("create group: status %d", status)
+	}
+
+	submit := map[string]interface{}{
+		"printer_group_id": group.Data.ID,
+		"file_url":         "https://files.example.com/group.pdf",
+		"page_count":       1,
+	}
+	var job struct {
+		PrinterID       string `json:"printer_id"`
+		PrinterGroupID  string `json:"printer_group_id"`
+		RoutingStrategy string `json:"routing_strategy"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, submit, &job); status != http.StatusCreated {
+		t.Fatalf("create group job: status %d", status)
+	}
+	if job.PrinterID != busy || job.PrinterGroupID != group.Data.ID || job.RoutingStrategy != "least_queue" {
+		t.Fatalf("job = %+v, want printer %s via least_queue in group %s", job, busy, group.Data.ID)
+	}
+
+	if _, err := app.DB.Exec(`UPDATE edge_nodes SET enabled = false WHERE id = $1`, nodeID); err != nil {
+		t.Fatalf("disable node: %v", err)
+	}
+	var rejected struct {
+		ErrorCode string `json:"error_code"`
+		Rejected  []struct {
+			PrinterID string `json:"printer_id"`
+		} `json:"rejected"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/admin/print-jobs", adminToken, submit, &rejected); status != http.StatusConflict {
+		t.Fatalf("group job with no eligible printer: status %d, want 409", status)
+	}
+	if rejected.ErrorCode != "no_eligible_printer" || len(rejected.Rejected) != 2 {
+		t.Fatalf("rejection = %+v, want no_eligible_printer with both printers", rejected)
+	}
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TestPrinterListFilteredByEdgeNodePaged 管理界面按 Edge Node 筛选打印机时返回请求的页和该节点的总数
+func TestPrinterListFilteredByEdgeNodePaged(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	seed := func(n int) (string, []string) {
+		nodeID := "node-" + uuid.New().String()[:8]
+		if _, err := app.DB.Exec(`INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'online')`, nodeID); err != nil {
+			t.Fatalf("seed node: %v", err)
+		}
+		names := make([]string, n)
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("%s-p%d", nodeID, i)
+			if _, err := app.DB.Exec(`INSERT INTO printers (id, name, status, edge_node_id, slug, created_at) VALUES ($1, $2, 'ready', $3, $2, $4)`,
+				uuid.New().String(), name, nodeID, base.Add(time.Duration(i)*time.Minute)); err != nil {
+				t.Fatalf("seed printer: %v", err)
+			}
+			names[n-1-i] = name
+		}
+		return nodeID, names
+	}
+	nodeID, names := seed(5)
+	seed(4)
+
+	operatorToken := testToken(t, jwt.MapClaims{
+		"sub":          "op-1",
+		"realm_access": map[string]interface{}{"roles": []string{middleware.RoleOperator}},
+	})
+	var list struct {
+		Data struct {
+			Items []struct {
+				Name       string `json:"name"`
+				EdgeNodeID string `json:"edge_node_id"`
+			} `json:"items"`
+			Total      int `json:"total"`
+			Page       int `json:"page"`
+			PageSize   int `json:"page_size"`
+			TotalPages int `json:"total_pages"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodGet, srv.URL+"/api/v1/admin/printers?edge_node_id="+nodeID+"&page=2&page_size=2", operatorToken, nil, &list); status != http.StatusOK {
+		t.Fatalf("list printers: status %d", status)
+	}
+	if list.Data.Total != 5 || list.Data.Page != 2 || list.Data.PageSize != 2 || list.Data.TotalPages != 3 {
+		t.Fatalf("pagination = total %d page %d size %d pages %d, want 5/2/2/3",
+			list.Data.Total, list.Data.Page, list.Data.PageSize, list.Data.TotalPages)
+	}
+	if len(list.Data.Items) != 2 || list.Data.Items[0].Name != names[2] || list.Data.Items[1].Name != names[3] {
+		t.Fatalf("page 2 = %+v, want %v", list.Data.Items, names[2:4])
+	}
+	for _, item := range list.Data.Items {
+		if item.EdgeNodeID != nodeID {
+			t.Fatalf("printer %s belongs to %s, want %s", item.Name, item.EdgeNodeID, nodeID)
+		}
+	}
+}
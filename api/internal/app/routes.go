@@ -0,0 +1,363 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/handlers"
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/version"
+	"fly-print-cloud/api/internal/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+// bootstrapRateLimit 初始化接口每个客户端 IP 每分钟允许的请求数
+const bootstrapRateLimit = 10
+
+// routeHandlers 路由使用的处理器和认证中间件，由 build 装配；未启用的可选功能（如邮件打印）为 nil
+type routeHandlers struct {
+	auth                  *middleware.OAuth2Authenticator
+	userHandler           *handlers.UserHandler
+	edgeNodeHandler       *handlers.EdgeNodeHandler
+	printerHandler        *handlers.PrinterHandler
+	printJobHandler       *handlers.PrintJobHandler
+	wsHandler             *websocket.WebSocketHandler
+	oauth2Handler         *handlers.OAuth2Handler
+	auditLogHandler       *handlers.AuditLogHandler
+	eventHandler          *handlers.EventHandler
+	driverHandler         *handlers.DriverHandler
+	diagnosticsHandler    *handlers.DiagnosticsHandler
+	connectionHandler     *handlers.ConnectionHandler
+	fileHandler           *handlers.FileHandler
+	accessPolicyHandler   *handlers.AccessPolicyHandler
+	powerScheduleHandler  *handlers.PowerScheduleHandler
+	businessHoursHandler  *handlers.BusinessHoursHandler
+	importHandler         *handlers.ImportHandler
+	printerGroupHandler   *handlers.PrinterGroupHandler
+	assetHandler          *handlers.AssetHandler
+	inventoryHandler      *handlers.InventoryHandler
+	mailInHandler         *handlers.MailInHandler
+	bootstrapHandler      *handlers.BootstrapHandler
+	printPolicyHandler    *handlers.PrintPolicyHandler
+	apiKeyHandler         *handlers.APIKeyHandler
+	nodeLogHandler        *handlers.NodeLogHandler
+	exportHandler         *handlers.ExportHandler
+	hotFolderHandler      *handlers.HotFolderHandler
+	healthHandler         *handlers.HealthHandler
+	edgeCredentialHandler *handlers.EdgeCredentialHandler
+	dashboardHandler      *handlers.DashboardHandler
+}
+
+func setupRoutes(r *gin.Engine, h *routeHandlers, userRepo *database.UserRepository, db *database.DB) {
+	// 公开路由
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    http.StatusOK,
+			"message": "success",
+			"data": gin.H{
+				"status":   "ok",
+				"service":  "fly-print-cloud-api",
+				"database": db.PoolStats(),
+			},
+		})
+	})
+
+	// Prometheus 指标（仅包含计数，不含节点或用户信息）：WebSocket 指标和机队健康指标
+	r.GET("/metrics", h.healthHandler.Metrics)
+
+	// 技术支持文件临时链接 - 由签名校验，无需登录
+	r.GET("/files/:token", h.fileHandler.ServeFileLink)
+
+	// OAuth2 认证路由
+	authGroup := r.Group("/auth")
+	{
+		authGroup.GET("/login", h.oauth2Handler.Login)
+		authGroup.GET("/callback", h.oauth2Handler.Callback)
+		authGroup.GET("/me", h.oauth2Handler.Me)
+		authGroup.GET("/verify", h.oauth2Handler.Verify)  // Nginx auth_request 使用
+		authGroup.GET("/logout", h.oauth2Handler.Logout)  // 支持 GET 请求登出
+		authGroup.POST("/logout", h.oauth2Handler.Logout) // 保留 POST 支持
+	}
+
+	// 统一 API 路由组（/api/v1）- OAuth2 Resource Server
+	apiV1Group := r.Group("/api/v1")
+	{
+		apiV1Group.GET("/health", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"code":    http.StatusOK,
+				"message": "success",
+				"data": gin.H{
+					"status":   "ok",
+					"service":  "fly-print-cloud-api",
+					"version":  version.Version,
+					"database": db.PoolStats(),
+				},
+			})
+		})
+
+		// 静态元数据（无需登录）
+		apiV1Group.GET("/meta/paper-sizes", handlers.ListPaperSizes)
+
+		// 首次安装初始化（无需登录，按 IP 限流）：没有管理员时创建首个管理员，之后返回 410
+		bootstrapGroup := apiV1Group.Group("/bootstrap", middleware.RateLimit(bootstrapRateLimit, time.Minute))
+		{
+			bootstrapGroup.GET("/status", h.bootstrapHandler.GetStatus)
+			bootstrapGroup.POST("/admin", h.bootstrapHandler.CreateAdmin)
+		}
+
+		// 邮件打印 webhook - 由共享令牌校验，仅在 mailin.enabled 时注册
+		if h.mailInHandler != nil {
+			apiV1Group.POST("/mailin/inbound", h.mailInHandler.Receive)
+		}
+
+		// Admin Console API - 角色见 middleware.RoleAdmin / RoleOperator：operator 可查看和执行日常操作，破坏性操作仅限 admin
+		adminGroup := apiV1Group.Group("/admin")
+		{
+			// Dashboard 路由 - 需要 admin 或 operator 权限
+			dashboardGroup := adminGroup.Group("/dashboard", h.auth.RequireOperator())
+			{
+				dashboardGroup.GET("/trends", h.dashboardHandler.GetTrends)
+				dashboardGroup.GET("/costs", h.dashboardHandler.GetCostStats)
+			}
+
+			// 统计报表 - 需要 admin 或 operator 权限
+			adminGroup.GET("/stats/sla", h.auth.RequireOperator(), h.dashboardHandler.GetSLAStats)
+			adminGroup.GET("/stats/sources", h.auth.RequireOperator(), h.dashboardHandler.GetSourceStats)
+
+			// 机队健康汇总（NOC 看板）- 需要 admin 或 operator 权限
+			adminGroup.GET("/health/fleet", h.auth.RequireOperator(), h.healthHandler.GetFleetHealth)
+
+			// 用户管理路由 - 需要 admin 权限
+			userGroup := adminGroup.Group("/users", h.auth.RequireAdmin(), middleware.UUIDParams("id"), middleware.LocalUser(userRepo))
+			{
+				userGroup.GET("", h.userHandler.ListUsers)
+				userGroup.POST("", h.userHandler.CreateUser)
+				userGroup.GET("/:id", h.userHandler.GetUser)
+				userGroup.PUT("/:id", h.userHandler.UpdateUser)
+				userGroup.DELETE("/:id", h.userHandler.DeleteUser)
+				userGroup.PUT("/:id/password", h.userHandler.ChangePassword)
+			}
+
+			// 已完成任务归档导出 - 需要 admin 权限
+			exportGroup := adminGroup.Group("/exports", h.auth.RequireAdmin(), middleware.UUIDParams("id"))
+			{
+				exportGroup.GET("", h.exportHandler.ListExports)
+				exportGroup.POST("", h.exportHandler.CreateExport)
+				exportGroup.GET("/:id", h.exportHandler.GetExport)
+				exportGroup.POST("/:id/resume", h.exportHandler.ResumeExport)
+			}
+
+			// 共享文件夹打印监视状态 - 需要 admin 或 operator 权限
+			adminGroup.GET("/hot-folders", h.auth.RequireOperator(), h.hotFolderHandler.ListHotFolders)
+
+			// 审计日志 - 需要 admin 权限
+			adminGroup.GET("/audit-logs", h.auth.RequireAdmin(), h.auditLogHandler.ListAuditLogs)
+
+			// 系统事件推送（SSE）- 需要 admin 或 operator 权限
+			adminGroup.GET("/events", h.auth.RequireOperator(), h.eventHandler.Stream)
+
+			// WebSocket 会话管理 - 需要 admin 权限
+			connectionGroup := adminGroup.Group("/connections", h.auth.RequireAdmin(), middleware.NodeIDParams("node_id"))
+			{
+				connectionGroup.GET("", h.connectionHandler.ListConnections)
+				connectionGroup.DELETE("/:node_id", h.connectionHandler.CloseConnection)
+			}
+
+			// 当前用户业务信息 - 任何认证用户都可以访问自己的档案
+			adminGroup.GET("/profile", h.auth.ResourceServer(), h.userHandler.GetCurrentUserProfile)
+			adminGroup.PUT("/profile/notifications", h.auth.ResourceServer(), h.userHandler.UpdateNotificationPreferences)
+
+			// Edge Node 管理路由 - 需要 admin 或 operator 权限，删除需要 admin 权限
+			edgeNodeGroup := adminGroup.Group("/edge-nodes", h.auth.RequireOperator(), middleware.NodeIDParams("id"), middleware.UUIDParams("request_id", "credential_id"))
+			{
+				edgeNodeGroup.GET("", h.edgeNodeHandler.ListEdgeNodes)
+				edgeNodeGroup.GET("/:id", h.edgeNodeHandler.GetEdgeNode)
+				edgeNodeGroup.GET("/metrics", h.edgeNodeHandler.GetFleetMetrics)
+				edgeNodeGroup.GET("/:id/stats", h.edgeNodeHandler.GetEdgeNodeStats)
+				edgeNodeGroup.GET("/:id/metrics", h.edgeNodeHandler.GetEdgeNodeMetrics)
+				edgeNodeGroup.PUT("/:id", h.edgeNodeHandler.UpdateEdgeNode)
+				edgeNodeGroup.DELETE("/:id", h.auth.RequireAdmin(), h.edgeNodeHandler.DeleteEdgeNode)
+				edgeNodeGroup.POST("/:id/drain", h.edgeNodeHandler.DrainEdgeNode)
+				edgeNodeGroup.POST("/:id/conflict/ack", h.auth.RequireAdmin(), h.edgeNodeHandler.AcknowledgeConflict)
+				edgeNodeGroup.POST("/:id/diagnostics", h.diagnosticsHandler.RequestDiagnostics)
+				edgeNodeGroup.GET("/:id/diagnostics/:request_id", h.diagnosticsHandler.DownloadDiagnostics)
+				edgeNodeGroup.GET("/:id/logs/stream", h.nodeLogHandler.StreamLogs)
+				edgeNodeGroup.GET("/:id/power-schedule", h.powerScheduleHandler.GetEdgeNodePowerSchedule)
+				edgeNodeGroup.PUT("/:id/power-schedule", h.powerScheduleHandler.SetEdgeNodePowerSchedule)
+				edgeNodeGroup.DELETE("/:id/power-schedule", h.auth.RequireAdmin(), h.powerScheduleHandler.DeleteEdgeNodePowerSchedule)
+				edgeNodeGroup.GET("/:id/business-hours", h.businessHoursHandler.GetEdgeNodeBusinessHours)
+				edgeNodeGroup.PUT("/:id/business-hours", h.businessHoursHandler.SetEdgeNodeBusinessHours)
+				edgeNodeGroup.DELETE("/:id/business-hours", h.auth.RequireAdmin(), h.businessHoursHandler.DeleteEdgeNodeBusinessHours)
+				edgeNodeGroup.GET("/:id/notes-history", h.assetHandler.GetEdgeNodeNotesHistory)
+				edgeNodeGroup.GET("/:id/credentials", h.auth.RequireAdmin(), h.edgeCredentialHandler.ListCredentials)
+				edgeNodeGroup.POST("/:id/credentials", h.auth.RequireAdmin(), h.edgeCredentialHandler.CreateCredential)
+				edgeNodeGroup.POST("/:id/credentials/:credential_id/disable", h.auth.RequireAdmin(), h.edgeCredentialHandler.DisableCredential)
+			}
+
+			// 打印机管理路由 - 需要 admin 或 operator 权限，删除和审核需要 admin 权限
+			printerGroup := adminGroup.Group("/printers", h.auth.RequireOperator(), middleware.PrinterParams("id"))
+			{
+				printerGroup.GET("", h.printerHandler.ListPrinters)
+				printerGroup.GET("/:id", h.printerHandler.GetPrinter)
+				printerGroup.PUT("/:id", h.printerHandler.UpdatePrinter)
+				printerGroup.DELETE("/:id", h.auth.RequireAdmin(), h.printerHandler.DeletePrinter)
+				printerGroup.POST("/:id/approve", h.auth.RequireAdmin(), h.printerHandler.ApprovePrinter)
+				printerGroup.POST("/:id/breaker/reset", h.auth.RequireAdmin(), h.printerHandler.ResetPrinterBreaker)
+				printerGroup.POST("/:id/pause", h.printerHandler.PausePrinterQueue)
+				printerGroup.POST("/:id/resume", h.printerHandler.ResumePrinterQueue)
+				printerGroup.POST("/:id/reject", h.auth.RequireAdmin(), h.printerHandler.RejectPrinter)
+				printerGroup.GET("/:id/power-schedule", h.powerScheduleHandler.GetPrinterPowerSchedule)
+				printerGroup.PUT("/:id/power-schedule", h.powerScheduleHandler.SetPrinterPowerSchedule)
+				printerGroup.DELETE("/:id/power-schedule", h.auth.RequireAdmin(), h.powerScheduleHandler.DeletePrinterPowerSchedule)
+				printerGroup.GET("/:id/business-hours", h.businessHoursHandler.GetPrinterBusinessHours)
+				printerGroup.PUT("/:id/business-hours", h.businessHoursHandler.SetPrinterBusinessHours)
+				printerGroup.DELETE("/:id/business-hours", h.auth.RequireAdmin(), h.businessHoursHandler.DeletePrinterBusinessHours)
+				printerGroup.GET("/:id/notes-history", h.assetHandler.GetPrinterNotesHistory)
+				printerGroup.POST("/:id/reserve", h.printerHandler.ReservePrinter)
+				printerGroup.POST("/:id/reserve/extend", h.printerHandler.ExtendPrinterReservation)
+				printerGroup.POST("/:id/reserve/release", h.printerHandler.ReleasePrinterReservation)
+			}
+
+			// 计划维护报表（保修即将到期的设备）- 需要 admin 或 operator 权限
+			adminGroup.GET("/assets/maintenance", h.auth.RequireOperator(), h.assetHandler.GetMaintenanceReport)
+
+			// 已删除打印机的墓碑（阻止节点重新注册）- 查看需要 admin 或 operator 权限，清除需要 admin 权限
+			tombstoneGroup := adminGroup.Group("/printer-tombstones", h.auth.RequireOperator(), middleware.UUIDParams("id"))
+			{
+				tombstoneGroup.GET("", h.printerHandler.ListPrinterTombstones)
+				tombstoneGroup.DELETE("/:id", h.auth.RequireAdmin(), h.printerHandler.ForgetPrinterTombstone)
+			}
+
+			// 打印机组路由 - 查看需要 admin 或 operator 权限，修改需要 admin 权限
+			printerGroupGroup := adminGroup.Group("/printer-groups", h.auth.RequireOperator(), middleware.UUIDParams("id"))
+			{
+				printerGroupGroup.GET("", h.printerGroupHandler.ListPrinterGroups)
+				printerGroupGroup.POST("", h.auth.RequireAdmin(), h.printerGroupHandler.CreatePrinterGroup)
+				printerGroupGroup.GET("/:id", h.printerGroupHandler.GetPrinterGroup)
+				printerGroupGroup.PUT("/:id", h.auth.RequireAdmin(), h.printerGroupHandler.UpdatePrinterGroup)
+				printerGroupGroup.DELETE("/:id", h.auth.RequireAdmin(), h.printerGroupHandler.DeletePrinterGroup)
+			}
+
+			// 批量打印任务路由 - 需要 admin 或 operator 权限
+			batchGroup := adminGroup.Group("/print-job-batches", h.auth.RequireOperator(), middleware.UUIDParams("id"))
+			{
+				batchGroup.GET("/:id", h.printJobHandler.GetPrintJobBatch)
+				batchGroup.POST("/:id/cancel", h.printJobHandler.CancelPrintJobBatch)
+			}
+
+			// Edge Node / 打印机批量导入 - 需要 admin 权限
+			importGroup := adminGroup.Group("/import", h.auth.RequireAdmin())
+			{
+				importGroup.POST("", h.importHandler.Import)
+				importGroup.GET("/templates/:format", h.importHandler.DownloadTemplate)
+			}
+
+			// 资产清单导出（CMDB 同步）- 需要 inventory:read 权限
+			adminGroup.GET("/inventory/export", h.auth.ResourceServer(middleware.ScopeInventoryRead), h.inventoryHandler.ExportInventory)
+
+			// 打印机访问策略路由 - 需要 admin 权限
+			accessPolicyGroup := adminGroup.Group("/access-policies", h.auth.RequireAdmin(), middleware.UUIDParams("id"))
+			{
+				accessPolicyGroup.GET("", h.accessPolicyHandler.ListAccessPolicies)
+				accessPolicyGroup.POST("", h.accessPolicyHandler.CreateAccessPolicy)
+				accessPolicyGroup.DELETE("/:id", h.accessPolicyHandler.DeleteAccessPolicy)
+			}
+
+			// 组织级打印策略路由 - 需要 admin 权限
+			printPolicyGroup := adminGroup.Group("/print-policies", h.auth.RequireAdmin(), middleware.UUIDParams("id"))
+			{
+				printPolicyGroup.GET("", h.printPolicyHandler.ListPrintPolicies)
+				printPolicyGroup.POST("", h.printPolicyHandler.CreatePrintPolicy)
+				printPolicyGroup.GET("/:id", h.printPolicyHandler.GetPrintPolicy)
+				printPolicyGroup.PUT("/:id", h.printPolicyHandler.UpdatePrintPolicy)
+				printPolicyGroup.DELETE("/:id", h.printPolicyHandler.DeletePrintPolicy)
+			}
+
+			// 打印机驱动管理路由 - 需要 admin 或 operator 权限，删除需要 admin 权限
+			driverGroup := adminGroup.Group("/printer-drivers", h.auth.RequireOperator(), middleware.UUIDParams("id"))
+			{
+				driverGroup.GET("", h.driverHandler.ListDrivers)
+				driverGroup.POST("", h.driverHandler.CreateDriver)
+				driverGroup.GET("/:id", h.driverHandler.GetDriver)
+				driverGroup.PUT("/:id", h.driverHandler.UpdateDriver)
+				driverGroup.DELETE("/:id", h.auth.RequireAdmin(), h.driverHandler.DeleteDriver)
+				driverGroup.POST("/:id/ppd", h.driverHandler.UploadPPD)
+				driverGroup.GET("/:id/ppd", h.driverHandler.DownloadPPD)
+			}
+
+			// 打印任务管理路由 - 需要 admin 或 operator 权限，删除和强制变更状态需要 admin 权限
+			printJobGroup := adminGroup.Group("/print-jobs", h.auth.RequireOperator(), middleware.UUIDParams("id"))
+			{
+				printJobGroup.POST("", h.printJobHandler.CreatePrintJob)
+				printJobGroup.GET("", h.printJobHandler.ListPrintJobs)
+				printJobGroup.GET("/export", h.printJobHandler.ExportPrintJobs)
+				printJobGroup.POST("/files", h.fileHandler.UploadFile)
+				printJobGroup.POST("/batch", h.printJobHandler.CreatePrintJobBatch)
+				printJobGroup.POST("/preflight", h.printJobHandler.PreflightPrintJob)
+				printJobGroup.POST("/recompute-cost", h.auth.RequireAdmin(), h.printJobHandler.RecomputeCosts)
+				printJobGroup.GET("/:id", h.printJobHandler.GetPrintJob)
+				printJobGroup.PUT("/:id", h.printJobHandler.UpdatePrintJob)
+				printJobGroup.DELETE("/:id", h.auth.RequireAdmin(), h.printJobHandler.DeletePrintJob)
+				printJobGroup.POST("/:id/cancel", h.printJobHandler.CancelPrintJob)
+				printJobGroup.POST("/:id/reprint", h.printJobHandler.ReprintJob)
+				printJobGroup.POST("/:id/force-complete", h.auth.RequireAdmin(), h.printJobHandler.ForceCompletePrintJob)
+				printJobGroup.POST("/:id/force-fail", h.auth.RequireAdmin(), h.printJobHandler.ForceFailPrintJob)
+				printJobGroup.GET("/:id/file", h.fileHandler.DownloadJobFile)
+				printJobGroup.GET("/:id/thumbnail", h.fileHandler.GetJobThumbnail)
+				printJobGroup.GET("/:id/timeline", h.printJobHandler.GetPrintJobTimeline)
+				printJobGroup.GET("/:id/error-message", h.auth.RequireAdmin(), h.fileHandler.DownloadJobErrorMessage)
+				printJobGroup.POST("/:id/file-link", h.auth.RequireAdmin(), h.fileHandler.CreateFileLink)
+			}
+		}
+
+		// 个人 API Key 管理 - 任何通过 OAuth2 登录的用户（API Key 认证的请求不能管理 API Key）
+		apiKeyGroup := apiV1Group.Group("/profile/api-keys", h.auth.ResourceServer(), middleware.UUIDParams("id"))
+		{
+			apiKeyGroup.GET("", h.apiKeyHandler.ListAPIKeys)
+			apiKeyGroup.POST("", h.apiKeyHandler.CreateAPIKey)
+			apiKeyGroup.DELETE("/:id", h.apiKeyHandler.DeleteAPIKey)
+		}
+
+		// 第三方打印API - 需要 print:submit 权限
+		printGroup := apiV1Group.Group("/print-jobs", h.auth.ResourceServer("print:submit"), middleware.UUIDParams("id"))
+		{
+			printGroup.POST("", h.printJobHandler.CreatePrintJob)
+			printGroup.POST("/files", h.fileHandler.UploadFile)
+			printGroup.GET("/:id", h.printJobHandler.GetPrintJob)
+		}
+
+		// 打印文件签名链接下载 - 由签名校验，无需登录
+		apiV1Group.GET("/files/*key", h.fileHandler.ServeSignedFile)
+
+		// 第三方打印机列表API - 需要 print:submit 权限
+		apiV1Group.GET("/printers", h.auth.ResourceServer("print:submit"), h.printerHandler.ListPrinters)
+
+		// Edge Node API - 权限模型见 middleware.ScopeEdge*
+		// 配置 server.tls.client_ca_file 后，未携带 token 的请求可使用登记过的客户端证书（拥有全部 edge 权限，只能操作证书所属节点）
+		edgeGroup := apiV1Group.Group("/edge")
+		{
+			edgeGroup.POST("/register", h.auth.ResourceServer(middleware.ScopeEdgeRegister), h.edgeNodeHandler.RegisterEdgeNode)
+			edgeGroup.POST("/heartbeat", h.auth.ResourceServer(middleware.ScopeEdgeConnect), h.edgeNodeHandler.Heartbeat)
+			edgeGroup.PUT("/:node_id/info", h.auth.ResourceServer(middleware.ScopeEdgeConnect), middleware.NodeIDParams("node_id"), h.edgeNodeHandler.EdgeUpdateInfo)
+			edgeGroup.GET("/:node_id/summary", h.auth.ResourceServer(middleware.ScopeEdgeConnect), middleware.NodeIDParams("node_id"), h.edgeNodeHandler.EdgeSummary)
+			edgeGroup.GET("/capabilities", h.auth.ResourceServer(middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect, middleware.ScopeEdgePrinterWrite, middleware.ScopeEdgeJobUpdate), h.edgeNodeHandler.Capabilities)
+			edgeGroup.GET("/schema", h.auth.ResourceServer(middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect, middleware.ScopeEdgePrinterWrite, middleware.ScopeEdgeJobUpdate), h.edgeNodeHandler.ProtocolSchemas)
+			edgeGroup.GET("/schema/:type", h.auth.ResourceServer(middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect, middleware.ScopeEdgePrinterWrite, middleware.ScopeEdgeJobUpdate), h.edgeNodeHandler.ProtocolSchema)
+
+			// Edge Node 的打印机管理
+			edgeGroup.POST("/:node_id/printers", h.auth.ResourceServer(middleware.ScopeEdgePrinterWrite), middleware.NodeIDParams("node_id"), h.printerHandler.EdgeRegisterPrinter)
+			edgeGroup.GET("/:node_id/printers", h.auth.ResourceServer(middleware.ScopeEdgePrinterWrite), middleware.NodeIDParams("node_id"), h.printerHandler.EdgeListPrinters)
+
+			// Edge Node 的驱动查询
+			edgeGroup.GET("/:node_id/drivers", h.auth.ResourceServer(middleware.ScopeEdgePrinterWrite), middleware.NodeIDParams("node_id"), h.driverHandler.EdgeLookupDriver)
+			edgeGroup.GET("/:node_id/drivers/:driver_id/ppd", h.auth.ResourceServer(middleware.ScopeEdgePrinterWrite), middleware.NodeIDParams("node_id"), middleware.UUIDParams("driver_id"), h.driverHandler.EdgeDownloadPPD)
+
+			// Edge Node 上传诊断包（必须对应管理员发起的诊断请求）
+			edgeGroup.POST("/:node_id/diagnostics/:request_id", h.auth.ResourceServer(middleware.ScopeEdgeConnect), middleware.NodeIDParams("node_id"), middleware.UUIDParams("request_id"), h.diagnosticsHandler.EdgeUploadDiagnostics)
+
+			// WebSocket 连接（升级时校验 edge:connect，?auth=deferred 时改为第一帧 auth 消息认证，均未提供时使用客户端证书；连接内按消息类型校验权限）
+			edgeGroup.GET("/ws", h.wsHandler.HandleConnection)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// routeParamPattern 路由中的路径参数
+var routeParamPattern = regexp.MustCompile(`:([a-z_]+)`)
+
+// freeFormRouteParams 不是资源 ID 的路径参数（签名令牌、Schema 类型、模板格式），由处理器自行校验
+var freeFormRouteParams = map[string]bool{"token": true, "type": true, "format": true}
+
+// TestRoutesRejectMalformedIDs 每个带 ID 路径参数的路由都在调用处理器和仓库之前以 400 拒绝格式无效的 ID；
+// 路由使用空处理器装配，请求一旦到达处理器就会 panic
+func TestRoutesRejectMalformedIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	setupRoutes(r, &routeHandlers{}, nil, nil)
+
+	token := testToken(t, jwt.MapClaims{
+		"sub":          "admin-1",
+		"realm_access": map[string]interface{}{"roles": []string{middleware.RoleAdmin, middleware.RoleOperator}},
+		"scope": strings.Join([]string{
+			"print:submit", middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect,
+			middleware.ScopeEdgePrinterWrite, middleware.ScopeEdgeJobUpdate,
+		}, " "),
+	})
+
+	// 其他参数使用合法值（UUID 同时也是合法的打印机和节点 ID），只让被测参数无效
+	const validID = "3f1c2a9e-8b7d-4c6e-9a51-2d7f0b3e4c5a"
+	checked := 0
+	for _, route := range r.Routes() {
+		for _, match := range routeParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			name := match[1]
+			if freeFormRouteParams[name] {
+				continue
+			}
+			path := routeParamPattern.ReplaceAllStringFunc(route.Path, func(param string) string {
+				if param == ":"+name {
+					return "not%20an%20id"
+				}
+				return validID
+			})
+			checked++
+			t.Run(route.Method+" "+route.Path+" "+name, func(t *testing.T) {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						t.Fatalf("malformed %s reached the handler: %v", name, recovered)
+					}
+				}()
+				req := httptest.NewRequest(route.Method, path, nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				var resp struct {
+					Data struct {
+						Param string `json:"param"`
+					} `json:"data"`
+				}
+				if w.Code != http.StatusBadRequest || json.Unmarshal(w.Body.Bytes(), &resp) != nil || resp.Data.Param != name {
+					t.Fatalf("status = %d, body = %s; want 400 naming %s", w.Code, w.Body.String(), name)
+				}
+			})
+		}
+	}
+	if checked < 50 {
+		t.Fatalf("only %d route parameters checked", checked)
+	}
+
+	// 只接受 UUID 的路由拒绝看起来像 slug 的值
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/print-jobs/abc", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("GET /admin/print-jobs/abc: status %d, want 400", w.Code)
+	}
+}
+
+// TestDashboardRoutes Dashboard 路由已注册，未认证返回 401，没有 admin/operator 角色返回 403，
+// admin 和 operator 到达处理器（空处理器 panic 即表示请求已路由到处理器）
+func TestDashboardRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	setupRoutes(r, &routeHandlers{}, nil, nil)
+
+	paths := []string{"/api/v1/admin/dashboard/trends", "/api/v1/admin/dashboard/costs"}
+	registered := map[string]bool{}
+	for _, route := range r.Routes() {
+		if route.Method == http.MethodGet {
+			registered[route.Path] = true
+		}
+	}
+
+	tokens := []struct {
+		name   string
+		claims jwt.MapClaims // 为空表示不携带 token
+		status int           // 0 表示应到达处理器
+	}{
+		{"unauthenticated", nil, http.StatusUnauthorized},
+		{"user", jwt.MapClaims{"sub": "user-1", "scope": "print:submit"}, http.StatusForbidden},
+		{"edge node", jwt.MapClaims{"sub": "edge-1", "scope": middleware.ScopeEdgeConnect}, http.StatusForbidden},
+		{"operator", jwt.MapClaims{"sub": "op-1", "realm_access": map[string]interface{}{"roles": []string{middleware.RoleOperator}}}, 0},
+		{"admin", jwt.MapClaims{"sub": "admin-1", "realm_access": map[string]interface{}{"roles": []string{middleware.RoleAdmin}}}, 0},
+	}
+	for _, path := range paths {
+		if !registered[path] {
+			t.Fatalf("GET %s is not registered", path)
+		}
+		for _, tt := range tokens {
+			t.Run(path+" "+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.claims != nil {
+					req.Header.Set("Authorization", "Bearer "+testToken(t, tt.claims))
+				}
+				w := httptest.NewRecorder()
+				reached := func() (reached bool) {
+					defer func() { reached = recover() != nil }()
+					r.ServeHTTP(w, req)
+					return false
+				}()
+
+				if tt.status == 0 {
+					if !reached {
+						t.Fatalf("status = %d, want the request to reach the dashboard handler", w.Code)
+					}
+					return
+				}
+				if reached || w.Code != tt.status {
+					t.Fatalf("status = %d (reached handler: %v), want %d", w.Code, reached, tt.status)
+				}
+			})
+		}
+	}
+}
+
+// adminOnlyRoutes 挂载 RequireAdmin 的管理路由：删除、用户管理、审核、强制变更状态等破坏性操作
+var adminOnlyRoutes = map[string]bool{
+	"GET /api/v1/admin/users":                                              true,
+	"POST /api/v1/admin/users":                                             true,
+	"GET /api/v1/admin/users/:id":                                          true,
+	"PUT /api/v1/admin/users/:id":                                          true,
+	"DELETE /api/v1/admin/users/:id":                                       true,
+	"PUT /api/v1/admin/users/:id/password":                                 true,
+	"GET /api/v1/admin/exports":                                            true,
+	"POST /api/v1/admin/exports":                                           true,
+	"GET /api/v1/admin/exports/:id":                                        true,
+	"POST /api/v1/admin/exports/:id/resume":                                true,
+	"GET /api/v1/admin/audit-logs":                                         true,
+	"GET /api/v1/admin/connections":                                        true,
+	"DELETE /api/v1/admin/connections/:node_id":                            true,
+	"DELETE /api/v1/admin/edge-nodes/:id":                                  true,
+	"POST /api/v1/admin/edge-nodes/:id/conflict/ack":                       true,
+	"DELETE /api/v1/admin/edge-nodes/:id/power-schedule":                   true,
+	"DELETE /api/v1/admin/edge-nodes/:id/business-hours":                   true,
+	"GET /api/v1/admin/edge-nodes/:id/credentials":                         true,
+	"POST /api/v1/admin/edge-nodes/:id/credentials":                        true,
+	"POST /api/v1/admin/edge-nodes/:id/credentials/:credential_id/disable": true,
+	"DELETE /api/v1/admin/printers/:id":                                    true,
+	"POST /api/v1/admin/printers/:id/approve":                              true,
+	"POST /api/v1/admin/printers/:id/reject":                               true,
+	"POST /api/v1/admin/printers/:id/breaker/reset":                        true,
+	"DELETE /api/v1/admin/printers/:id/power-schedule":                     true,
+	"DELETE /api/v1/admin/printers/:id/business-hours":                     true,
+	"DELETE /api/v1/admin/printer-tombstones/:id":                          true,
+	"POST /api/v1/admin/printer-groups":                                    true,
+	"PUT /api/v1/admin/printer-groups/:id":                                 true,
+	"DELETE /api/v1/admin/printer-groups/:id":                              true,
+	"POST /api/v1/admin/import":                                            true,
+	"GET /api/v1/admin/import/templates/:format":                           true,
+	"GET /api/v1/admin/access-policies":                                    true,
+	"POST /api/v1/admin/access-policies":                                   true,
+	"DELETE /api/v1/admin/access-policies/:id":                             true,
+	"GET /api/v1/admin/print-policies":                                     true,
+	"POST /api/v1/admin/print-policies":                                    true,
+	"GET /api/v1/admin/print-policies/:id":                                 true,
+	"PUT /api/v1/admin/print-policies/:id":                                 true,
+	"DELETE /api/v1/admin/print-policies/:id":                              true,
+	"DELETE /api/v1/admin/printer-drivers/:id":                             true,
+	"POST /api/v1/admin/print-jobs/recompute-cost":                         true,
+	"DELETE /api/v1/admin/print-jobs/:id":                                  true,
+	"POST /api/v1/admin/print-jobs/:id/force-complete":                     true,
+	"POST /api/v1/admin/print-jobs/:id/force-fail":                         true,
+	"GET /api/v1/admin/print-jobs/:id/error-message":                       true,
+	"POST /api/v1/admin/print-jobs/:id/file-link":                          true,
+}
+
+// TestAdminOnlyRoutes 遍历全部管理路由：operator 访问 RequireAdmin 路由返回 403，访问其余路由不被角色拦截；
+// admin 不会在任何管理路由上被拒绝
+func TestAdminOnlyRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	setupRoutes(r, &routeHandlers{}, nil, nil)
+
+	// 两个角色拥有相同的权限范围，结果只由角色决定
+	scope := strings.Join([]string{"print:submit", middleware.ScopeInventoryRead}, " ")
+	tokens := []struct {
+		role  string
+		token string
+	}{
+		{middleware.RoleOperator, testToken(t, jwt.MapClaims{
+			"sub": "op-1", "scope": scope,
+			"realm_access": map[string]interface{}{"roles": []string{middleware.RoleOperator}},
+		})},
+		{middleware.RoleAdmin, testToken(t, jwt.MapClaims{
+			"sub": "admin-1", "scope": scope,
+			"realm_access": map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+		})},
+	}
+
+	const validID = "3f1c2a9e-8b7d-4c6e-9a51-2d7f0b3e4c5a"
+	seen := map[string]bool{}
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/v1/admin/") {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		seen[key] = true
+		path := routeParamPattern.ReplaceAllStringFunc(route.Path, func(param string) string {
+			if freeFormRouteParams[param[1:]] {
+				return "csv"
+			}
+			return validID
+		})
+		for _, tt := range tokens {
+			wantForbidden := tt.role == middleware.RoleOperator && adminOnlyRoutes[key]
+			t.Run(key+" "+tt.role, func(t *testing.T) {
+				req := httptest.NewRequest(route.Method, path, nil)
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+				w := httptest.NewRecorder()
+				// 空处理器 panic 表示请求已通过角色校验
+				reached := func() (reached bool) {
+					defer func() { reached = recover() != nil }()
+					r.ServeHTTP(w, req)
+					return false
+				}()
+
+				if wantForbidden {
+					if reached || w.Code != http.StatusForbidden {
+						t.Fatalf("status = %d (reached handler: %v), want 403", w.Code, reached)
+					}
+					return
+				}
+				if !reached && w.Code == http.StatusForbidden {
+					t.Fatalf("status = 403, want the %s role to pass", tt.role)
+				}
+			})
+		}
+	}
+
+	for key := range adminOnlyRoutes {
+		if !seen[key] {
+			t.Errorf("%s is not registered", key)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// createOAuth2User 创建与 OAuth2 subject 关联的本地用户，返回用户ID
+func createOAuth2User(t *testing.T, app *App, externalID, role string) string {
+	t.Helper()
+	var id string
+	name := "user-" + uuid.New().String()[:8]
+	if err := app.DB.QueryRow(`INSERT INTO users (username, email, password_hash, role, status, external_id)
+		VALUES ($1, $2, 'oauth2_user', $3, 'active', $4) RETURNING id`,
+		name, name+"@example.com", role, externalID).Scan(&id); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return id
+}
+
+// TestAdminUserDeletion 通过 OAuth2 token 删除用户：不能删除自己，不能删除或降级最后一个管理员，其他用户正常删除
+func TestAdminUserDeletion(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	if _, err := app.DB.Exec(`UPDATE users SET status = 'inactive' WHERE role = 'admin'`); err != nil {
+		t.Fatalf("deactivate existing admins: %v", err)
+	}
+	aliceID := createOAuth2User(t, app, "admin-alice", "admin")
+	operatorID := createOAuth2User(t, app, "operator-carol", "operator")
+	aliceToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-alice",
+		"preferred_username": "alice",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+	users := srv.URL + "/api/v1/admin/users/"
+
+	if status := doJSON(t, http.MethodDelete, users+aliceID, aliceToken, nil, nil); status != http.StatusBadRequest {
+		t.Fatalf("self delete: status %d, want 400", status)
+	}
+	if status := doJSON(t, http.MethodDelete, users+operatorID, aliceToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("delete operator: status %d, want 200", status)
+	}
+
+	// bob 的 token 中有 admin 角色，但本地记录已被降级：alice 是唯一的活跃管理员
+	bobID := createOAuth2User(t, app, "admin-bob", "operator")
+	bobToken := testToken(t, jwt.MapClaims{
+		"sub":                "admin-bob",
+		"preferred_username": "bob",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})
+	if status := doJSON(t, http.MethodDelete, users+aliceID, bobToken, nil, nil); status != http.StatusBadRequest {
+		t.Fatalf("delete last admin: status %d, want 400", status)
+	}
+	var alice struct {
+		Data struct {
+			Username   string `json:"username"`
+			Email      string `json:"email"`
+			RowVersion int    `json:"row_version"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodGet, users+aliceID, bobToken, nil, &alice); status != http.StatusOK {
+		t.Fatalf("get alice: status %d", status)
+	}
+	if status := doJSON(t, http.MethodPut, users+aliceID, bobToken, map[string]interface{}{
+		"username": alice.Data.Username, "email": alice.Data.Email, "role": "viewer", "status": "active",
+		"row_version": alice.Data.RowVersion,
+	}, nil); status != http.StatusBadRequest {
+		t.Fatalf("demote last admin: status %d, want 400", status)
+	}
+
+	// 存在第二个管理员后可以删除 alice
+	if _, err := app.DB.Exec(`UPDATE users SET role = 'admin' WHERE id = $1`, bobID); err != nil {
+		t.Fatalf("promote bob: %v", err)
+	}
+	if status := doJSON(t, http.MethodDelete, users+aliceID, bobToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("delete one of two admins: status %d, want 200", status)
+	}
+}
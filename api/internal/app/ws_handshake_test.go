@@ -0,0 +1,108 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/websocket"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	gorillaws "github.com/gorilla/websocket"
+)
+
+// TestWebSocketHandshakeModes 请求头认证和 ?auth=deferred 首帧认证建立的连接相同：都注册到连接管理器，第一条下行消息都是 welcome
+func TestWebSocketHandshakeModes(t *testing.T) {
+	app := buildTestApp(t)
+	srv := httptest.NewServer(app.Engine)
+	defer srv.Close()
+
+	nodeID := "node-" + uuid.New().String()[:8]
+	edgeToken := testToken(t, jwt.MapClaims{
+		"sub":     "edge-client-" + nodeID,
+		"node_id": nodeID,
+		"scope":   strings.Join([]string{middleware.ScopeEdgeRegister, middleware.ScopeEdgeConnect}, " "),
+	})
+	var registered struct {
+		Data struct {
+			ConnToken string `json:"conn_token"`
+		} `json:"data"`
+	}
+	if status := doJSON(t, http.MethodPost, srv.URL+"/api/v1/edge/register", edgeToken,
+		map[string]string{"node_id": nodeID, "name": nodeID}, &registered); status != http.StatusCreated {
+		t.Fatalf("register node: status %d", status)
+	}
+	baseURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/edge/ws?"
+
+	// welcome 读取第一条下行消息，要求为 welcome 并返回其能力描述
+	welcome := func(conn *gorillaws.Conn) websocket.ServerCapabilities {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var command struct {
+			Type string                       `json:"type"`
+			Data websocket.ServerCapabilities `json:"data"`
+		}
+		if err := conn.ReadJSON(&command); err != nil {
+			t.Fatalf("read first message: %v", err)
+		}
+		if command.Type != websocket.CmdTypeWelcome {
+			t.Fatalf("first message = %s, want welcome", command.Type)
+		}
+		return command.Data
+	}
+	disconnect := func(conn *gorillaws.Conn) {
+		t.Helper()
+		conn.Close()
+		deadline := time.Now().Add(5 * time.Second)
+		for app.wsManager.IsNodeConnected(nodeID) {
+			if time.Now().After(deadline) {
+				t.Fatalf("node %s still connected after close", nodeID)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// 请求头认证
+	conn, resp, err := gorillaws.DefaultDialer.Dial(baseURL+url.Values{"conn_token": {registered.Data.ConnToken}}.Encode(),
+		http.Header{"Authorization": {"Bearer " + edgeToken}})
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dial with header: %v (status %d)", err, status)
+	}
+	headerCaps := welcome(conn)
+	waitForConnection(t, app, nodeID)
+	disconnect(conn)
+
+	// 首帧认证：auth 消息之前不注册连接
+	conn, _, err = gorillaws.DefaultDialer.Dial(baseURL+"auth=deferred", nil)
+	if err != nil {
+		t.Fatalf("dial deferred: %v", err)
+	}
+	defer conn.Close()
+	time.Sleep(100 * time.Millisecond)
+	if app.wsManager.IsNodeConnected(nodeID) {
+		t.Fatal("deferred connection registered before auth")
+	}
+	if err := conn.WriteJSON(map[string]interface{}{
+		"type": websocket.MsgTypeAuth,
+		"data": websocket.AuthData{Token: "Bearer " + edgeToken, ConnToken: registered.Data.ConnToken},
+	}); err != nil {
+		t.Fatalf("send auth: %v", err)
+	}
+	deferredCaps := welcome(conn)
+	waitForConnection(t, app, nodeID)
+
+	a, _ := json.Marshal(headerCaps)
+	b, _ := json.Marshal(deferredCaps)
+	if string(a) != string(b) {
+		t.Fatalf("welcome differs between handshake modes:\nheader   %s\ndeferred %s", a, b)
+	}
+}
@@ -0,0 +1,58 @@
+package billing
+
+import (
+	"math"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/models"
+)
+
+// Calculator 打印费用计算器
+type Calculator struct {
+	settings *config.Store // 全局定价支持热更新，每次计算时读取
+}
+
+// NewCalculator 创建打印费用计算器
+func NewCalculator(settings *config.Store) *Calculator {
+	return &Calculator{settings: settings}
+}
+
+// Currency 返回计费货币
+func (c *Calculator) Currency() string {
+	return c.settings.Get().Pricing.Currency
+}
+
+// Compute 计算打印任务费用：计费页数 × 单价（彩色/黑白），双面时按折扣计算
+// 计费页数为页数 × 份数，部分完成的任务为已输出页数（见 PrintJob.BilledPages）
+// printer 为空时使用全局定价
+func (c *Calculator) Compute(job *models.PrintJob, printer *models.Printer) float64 {
+	pricing := c.settings.Get().Pricing
+	perPageMono := pricing.PerPageMono
+	perPageColor := pricing.PerPageColor
+	duplexDiscount := pricing.DuplexDiscount
+
+	// 打印机级别覆盖
+	if printer != nil {
+		if printer.PricePerPageMono != nil {
+			perPageMono = *printer.PricePerPageMono
+		}
+		if printer.PricePerPageColor != nil {
+			perPageColor = *printer.PricePerPageColor
+		}
+		if printer.DuplexDiscount != nil {
+			duplexDiscount = *printer.DuplexDiscount
+		}
+	}
+
+	rate := perPageMono
+	if job.ColorMode == "color" {
+		rate = perPageColor
+	}
+	if job.DuplexMode == "duplex" && duplexDiscount > 0 && duplexDiscount < 1 {
+		rate = rate * (1 - duplexDiscount)
+	}
+
+	cost := float64(job.BilledPages()) * rate
+	// 保留4位小数，与数据库精度一致
+	return math.Round(cost*10000) / 10000
+}
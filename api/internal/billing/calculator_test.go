@@ -0,0 +1,38 @@
+package billing
+
+import (
+	"testing"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestComputePartialJob 部分完成的任务只按已输出页数计费，打印机定价和双面折扣照常生效
+func TestComputePartialJob(t *testing.T) {
+	calc := NewCalculator(config.NewStore(&config.Config{Pricing: config.PricingConfig{
+		Currency: "CNY", PerPageMono: 0.1, PerPageColor: 0.5, DuplexDiscount: 0.2,
+	}}))
+	colorPrice := 1.0
+
+	tests := []struct {
+		name    string
+		job     models.PrintJob
+		printer *models.Printer
+		want    float64
+	}{
+		{"completed mono", models.PrintJob{Status: models.JobStatusCompleted, PageCount: 100, Copies: 1}, nil, 10},
+		{"partial mono", models.PrintJob{Status: models.JobStatusPartiallyCompleted, PageCount: 100, Copies: 1, PagesPrinted: 60}, nil, 6},
+		{"partial color with copies", models.PrintJob{Status: models.JobStatusPartiallyCompleted, ColorMode: "color", PageCount: 10, Copies: 3, PagesPrinted: 14}, nil, 7},
+		{"partial duplex", models.PrintJob{Status: models.JobStatusPartiallyCompleted, DuplexMode: "duplex", PageCount: 10, Copies: 1, PagesPrinted: 5}, nil, 0.4},
+		{"partial on printer price", models.PrintJob{Status: models.JobStatusPartiallyCompleted, ColorMode: "color", PageCount: 10, Copies: 1, PagesPrinted: 3},
+			&models.Printer{PricePerPageColor: &colorPrice}, 3},
+		{"partial over-report capped", models.PrintJob{Status: models.JobStatusPartiallyCompleted, PageCount: 10, Copies: 1, PagesPrinted: 50}, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calc.Compute(&tt.job, tt.printer); got != tt.want {
+				t.Fatalf("Compute = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -16,6 +18,36 @@ type Config struct {
 	Server   ServerConfig   `mapstructure:"server"`
 	OAuth2   OAuth2Config   `mapstructure:"oauth2"`
 	Admin    AdminConfig    `mapstructure:"admin"`
+	Auth     AuthConfig     `mapstructure:"auth"`
+	Pricing  PricingConfig  `mapstructure:"pricing"`
+	Edge     EdgeConfig     `mapstructure:"edge"`
+	Drivers  DriversConfig  `mapstructure:"drivers"`
+	Mail     MailConfig     `mapstructure:"mail"`
+	Diagnostics DiagnosticsConfig `mapstructure:"diagnostics"`
+	HTTPClient  HTTPClientConfig  `mapstructure:"http_client"`
+	Storage     StorageConfig     `mapstructure:"storage"`
+	Thumbnails  ThumbnailsConfig  `mapstructure:"thumbnails"`
+	Conversion  ConversionConfig  `mapstructure:"conversion"`
+	MailIn      MailInConfig      `mapstructure:"mailin"`
+	HotFolders  HotFoldersConfig  `mapstructure:"hot_folders"`
+	Power       PowerConfig       `mapstructure:"power"`
+	Reservations ReservationsConfig `mapstructure:"reservations"`
+	Jobs        JobsConfig        `mapstructure:"jobs"`
+	SLA         SLAConfig         `mapstructure:"sla"`
+	Retention   RetentionConfig   `mapstructure:"retention"`
+	Exports     ExportsConfig     `mapstructure:"exports"`
+	Health      HealthConfig      `mapstructure:"health"`
+	TextLimits  TextLimitsConfig  `mapstructure:"text_limits"`
+	LookupCache LookupCacheConfig `mapstructure:"lookup_cache"`
+}
+
+// LookupCacheConfig 任务提交和分发路径上打印机、Edge Node 查询的缓存（修改后需要重启）
+// 打印机和节点的写操作会立即清除对应缓存；多副本部署时使用 redis 后端，清除对所有副本生效
+type LookupCacheConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Backend    string        `mapstructure:"backend"`     // local（进程内）或 redis（使用 redis 配置）
+	TTL        time.Duration `mapstructure:"ttl"`         // 缓存有效期，兜底未被清除的过期数据
+	MaxEntries int           `mapstructure:"max_entries"` // local 后端的最大缓存条数
 }
 
 // AppConfig 应用配置
@@ -24,6 +56,7 @@ type AppConfig struct {
 	Version     string `mapstructure:"version"`
 	Environment string `mapstructure:"environment"`
 	Debug       bool   `mapstructure:"debug"`
+	Timezone    string `mapstructure:"timezone"` // 部署时区（IANA 名称），仪表盘与统计按该时区划分自然日，请求可用 tz 参数覆盖
 }
 
 // DatabaseConfig 数据库配置
@@ -34,6 +67,18 @@ type DatabaseConfig struct {
 	Password string `mapstructure:"password"`
 	DBName   string `mapstructure:"dbname"`
 	SSLMode  string `mapstructure:"sslmode"`
+
+	// 连接池
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`  // 0 表示不限制
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"` // 0 表示不限制
+
+	// ConnectTimeout 启动时等待数据库就绪的最长时间，期间按指数退避重试
+	ConnectTimeout time.Duration `mapstructure:"connect_timeout"`
+
+	// SlowQueryThreshold 执行时间超过该值的语句以 WARN 记录（任何模式），0 表示不记录
+	SlowQueryThreshold time.Duration `mapstructure:"slow_query_threshold"`
 }
 
 // RedisConfig Redis配置
@@ -47,8 +92,19 @@ type RedisConfig struct {
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port int    `mapstructure:"port"`
-	Host string `mapstructure:"host"`
+	Port int             `mapstructure:"port"`
+	Host string          `mapstructure:"host"`
+	TLS  ServerTLSConfig `mapstructure:"tls"`
+}
+
+// ServerTLSConfig HTTPS 配置（修改后需要重启）
+// 配置 client_ca_file 后，Edge Node 可出示该 CA 签发的客户端证书代替 OAuth2 token 或 API Key，
+// 证书的 CN/SAN 通过 edge_node_credentials 映射到节点；不出示证书的客户端不受影响
+type ServerTLSConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	CertFile     string `mapstructure:"cert_file"`
+	KeyFile      string `mapstructure:"key_file"`
+	ClientCAFile string `mapstructure:"client_ca_file"` // 校验 Edge Node 客户端证书的 CA（PEM），为空时不请求客户端证书
 }
 
 // OAuth2Config OAuth2配置
@@ -68,24 +124,284 @@ type AdminConfig struct {
 	ConsoleURL string `mapstructure:"console_url"`
 }
 
+// AuthConfig 本地账户认证配置
+type AuthConfig struct {
+	PasswordHash PasswordHashConfig `mapstructure:"password_hash"`
+}
+
+// PasswordHashConfig 本地账户密码哈希算法和参数：新密码按此哈希，验证通过的旧哈希（其他算法或更弱的参数）自动升级
+type PasswordHashConfig struct {
+	Algorithm         string `mapstructure:"algorithm"`          // bcrypt 或 argon2id
+	BcryptCost        int    `mapstructure:"bcrypt_cost"`        // bcrypt 计算成本（4-31）
+	Argon2Memory      uint32 `mapstructure:"argon2_memory"`      // argon2id 内存（KiB）
+	Argon2Iterations  uint32 `mapstructure:"argon2_iterations"`  // argon2id 迭代次数
+	Argon2Parallelism uint8  `mapstructure:"argon2_parallelism"` // argon2id 并行度
+}
+
+// PricingConfig 打印计费配置（全局默认，可被打印机单独覆盖）
+type PricingConfig struct {
+	Currency       string  `mapstructure:"currency"`
+	PerPageMono    float64 `mapstructure:"per_page_mono"`
+	PerPageColor   float64 `mapstructure:"per_page_color"`
+	DuplexDiscount float64 `mapstructure:"duplex_discount"` // 双面折扣比例（0~1）
+}
+
+// EdgeConfig Edge Node 配置
+type EdgeConfig struct {
+	HeartbeatTimeout     time.Duration `mapstructure:"heartbeat_timeout"`      // 心跳超时，超时后节点标记为离线
+	OfflineCheckInterval time.Duration `mapstructure:"offline_check_interval"` // 离线检测间隔
+	ClockSkewThreshold   time.Duration `mapstructure:"clock_skew_threshold"`   // 节点时钟偏差告警阈值
+	PrinterDiscovery     string        `mapstructure:"printer_discovery"`      // 新发现打印机的处理方式：auto（自动启用）/review（等待管理员审核）
+	PrinterTombstoneTTL  time.Duration `mapstructure:"printer_tombstone_ttl"`  // 管理员删除打印机后阻止节点重新注册的时长，0 表示不阻止
+	HeartbeatInterval    time.Duration `mapstructure:"heartbeat_interval"`     // 建议 Edge Node 上报心跳的间隔（通过 capabilities 下发）
+	WSCompression        bool          `mapstructure:"ws_compression"`         // WebSocket 是否协商 permessage-deflate 压缩
+	ValidateMessages     bool          `mapstructure:"validate_messages"`      // 按协议 Schema 校验上行消息，不符合的消息丢弃并向节点返回 schema_violation 错误
+	MaxDrainTime         time.Duration `mapstructure:"max_drain_time"`         // 排空最长持续时间，到期时即使仍有任务未结束也禁用节点
+	DrainCheckInterval   time.Duration `mapstructure:"drain_check_interval"`   // 检查排空中节点是否可以禁用的间隔
+	TrustNodeLocation    bool          `mapstructure:"trust_node_location"`    // 是否接受 Edge Node 自行上报的经纬度（否则仅管理员可修改）
+	StaleHeartbeatFactor int           `mapstructure:"stale_heartbeat_factor"` // WebSocket 连接超过 heartbeat_interval 的该倍数未收到应用层心跳时主动断开，0 表示不检测
+	RateLimitHeartbeat     int `mapstructure:"rate_limit_heartbeat"`      // 每个节点每分钟允许的 edge_heartbeat 消息数，0 表示不限制
+	RateLimitPrinterStatus int `mapstructure:"rate_limit_printer_status"` // 每个节点每分钟允许的 printer_status 消息数，0 表示不限制
+	RateLimitJobUpdate     int `mapstructure:"rate_limit_job_update"`     // 每个节点每分钟允许的 job_update 消息数，0 表示不限制
+	RateLimitLogBatch      int `mapstructure:"rate_limit_log_batch"`      // 每个节点每分钟允许的 log_batch 消息数，0 表示不限制
+	RateLimitWarnAfter     int `mapstructure:"rate_limit_warn_after"`     // 一分钟内被丢弃的消息达到该数量时向节点发送警告，0 表示不警告
+	RateLimitCloseAfter    int `mapstructure:"rate_limit_close_after"`    // 一分钟内被丢弃的消息达到该数量时以 policy violation 断开连接，0 表示不断开
+	ConflictWindow         time.Duration `mapstructure:"conflict_window"`          // 统计同一 node_id 被不同来源地址的连接替换次数的窗口
+	ConflictReplacements   int           `mapstructure:"conflict_replacements"`    // 窗口内被不同来源地址替换的次数达到该值时标记为疑似 node_id 冲突，0 表示不检测
+	RefuseConflictingConns bool          `mapstructure:"refuse_conflicting_conns"` // 疑似冲突的节点已有连接时拒绝来自其他地址的新连接，而不是替换旧连接
+	MaxCapabilitiesSize    int           `mapstructure:"max_capabilities_size"`    // 打印机上报的 capabilities 序列化后的大小上限（字节），0 表示不限制
+	ConnTokenSecret        string        `mapstructure:"conn_token_secret"`        // 签发 WebSocket 连接令牌（conn_token）的 HMAC 密钥，为空时使用随机密钥（仅适用于单实例），修改后需要重启
+	ConnTokenTTL           time.Duration `mapstructure:"conn_token_ttl"`           // 注册和心跳响应中签发的连接令牌有效期
+	AllowLegacyNodeID      bool          `mapstructure:"allow_legacy_node_id"`     // 迁移期间允许未携带 conn_token 的连接按 node_id 参数或 token claim 确定节点
+}
+
+// DriversConfig 打印机驱动/PPD 配置
+type DriversConfig struct {
+	PPDDir     string `mapstructure:"ppd_dir"`      // PPD 文件存储目录
+	MaxPPDSize int64  `mapstructure:"max_ppd_size"` // PPD 文件大小上限（字节）
+}
+
+// DiagnosticsConfig Edge Node 诊断包配置
+type DiagnosticsConfig struct {
+	Dir           string        `mapstructure:"dir"`            // 诊断包存储目录
+	MaxSize       int64         `mapstructure:"max_size"`       // 诊断包大小上限（字节）
+	UploadTimeout time.Duration `mapstructure:"upload_timeout"` // 请求发出后等待上传的时长
+	Retention     time.Duration `mapstructure:"retention"`      // 上传后的保留时长，到期自动删除
+}
+
+// PowerConfig 打印机节能计划配置
+type PowerConfig struct {
+	CheckInterval time.Duration `mapstructure:"check_interval"` // 节能计划检查间隔
+	WakeTimeout   time.Duration `mapstructure:"wake_timeout"`   // 休眠时段内提交任务时等待打印机唤醒的最长时间，超时任务失败
+	ResleepDelay  time.Duration `mapstructure:"resleep_delay"`  // 休眠时段内被唤醒的打印机空闲多久后重新休眠
+}
+
+// ReservationsConfig 打印机预留（自助打印终端）配置
+type ReservationsConfig struct {
+	DefaultDuration time.Duration `mapstructure:"default_duration"` // 未指定时长时的预留时长
+	MaxDuration     time.Duration `mapstructure:"max_duration"`     // 单次预留（含延长）从开始计算的最长时长
+	CheckInterval   time.Duration `mapstructure:"check_interval"`   // 到期预留的清理间隔，清理后分发排队任务
+}
+
+// JobsConfig 打印任务配置
+type JobsConfig struct {
+	StallTimeout       time.Duration `mapstructure:"stall_timeout"`        // 已分发任务超过该时长没有任何状态更新时标记为 stalled
+	StallCheckInterval time.Duration `mapstructure:"stall_check_interval"` // 卡住任务检测间隔
+	DefaultCopies      int           `mapstructure:"default_copies"`       // 未指定份数时的默认值
+	MaxCopies          int           `mapstructure:"max_copies"`           // 单个任务份数上限，打印机可设置更低的上限
+	ProgressWriteInterval time.Duration `mapstructure:"progress_write_interval"` // 状态不变时同一任务的进度最多每隔该时长写入一次数据库，0 表示每条都写入
+	WhenClosed         string        `mapstructure:"when_closed"`           // 打印机不在营业时间时新任务的默认处理方式：reject 或 schedule（请求可通过 when_closed 覆盖）
+	OpenCheckInterval  time.Duration `mapstructure:"open_check_interval"`   // 检查打印机是否已开始营业并分发排队任务的间隔
+	FailoverAfter      int           `mapstructure:"failover_after"`        // 设置了备用打印机时，任务在同一打印机上因硬件错误失败达到该次数后改投备用打印机，之前在原打印机重试
+	FailoverErrorCodes []string      `mapstructure:"failover_error_codes"`  // 视为硬件故障的节点错误码（忽略大小写），其他错误不重试也不转移
+	BreakerFailures    int           `mapstructure:"breaker_failures"`      // 打印机在 breaker_window 内连续失败该数量的任务后暂停分发（熔断），0 表示不熔断
+	BreakerWindow      time.Duration `mapstructure:"breaker_window"`        // 连续失败的统计窗口，从本轮第一次失败开始计算
+	BreakerCooldown    time.Duration `mapstructure:"breaker_cooldown"`      // 熔断后自动恢复分发的冷却时间，0 表示只能由管理员手动恢复
+	BreakerCheckInterval time.Duration `mapstructure:"breaker_check_interval"` // 检查熔断冷却是否结束的间隔
+	MaxQueuePause        time.Duration `mapstructure:"max_queue_pause"`        // 管理员暂停打印机队列后自动恢复的最长时间，0 表示不自动恢复
+	QueuePauseCheckInterval time.Duration `mapstructure:"queue_pause_check_interval"` // 检查暂停的队列是否到达自动恢复时间的间隔
+	QueueReconcileInterval  time.Duration `mapstructure:"queue_reconcile_interval"`   // 按云端未结束任务数核对打印机队列长度的间隔
+}
+
+// SLAConfig 打印任务 SLA 考核配置，耗时均从任务提交开始计算；阈值为 0 表示不考核该阶段，打印机组可单独覆盖
+type SLAConfig struct {
+	TimeToDispatch  time.Duration `mapstructure:"time_to_dispatch"`  // 提交到下发给 Edge Node
+	TimeToStart     time.Duration `mapstructure:"time_to_start"`     // 提交到开始打印
+	TimeToComplete  time.Duration `mapstructure:"time_to_complete"`  // 提交到打印完成
+	CheckInterval   time.Duration `mapstructure:"check_interval"`    // 考核巡检间隔
+	AlertBreachRate float64       `mapstructure:"alert_breach_rate"` // 统计窗口内超时任务占比达到该值时发布告警事件（0-1），0 表示不告警
+	AlertWindow     time.Duration `mapstructure:"alert_window"`      // 超时率统计窗口（按任务结束时间）
+	AlertMinJobs    int           `mapstructure:"alert_min_jobs"`    // 窗口内结束的任务少于该数量时不计算超时率
+}
+
+// RetentionConfig 数据保留配置
+type RetentionConfig struct {
+	FilesDays          int           `mapstructure:"files_days"`           // 上传的打印文件保留天数，到期删除文件内容，任务记录保留；0 表示不删除
+	FilesSweepInterval time.Duration `mapstructure:"files_sweep_interval"` // 过期文件清理间隔
+	SpilledTextDays    int           `mapstructure:"spilled_text_days"`    // 转存到文件存储的完整错误信息保留天数（按任务结束时间），到期删除；0 表示不删除
+	NodeMetricsDays    int           `mapstructure:"node_metrics_days"`    // Edge Node 心跳资源采样保留天数，到期删除；0 表示不删除
+}
+
+// TextLimitsConfig 日志类文本字段（任务 error_message、时间线事件消息）的长度限制，在写入数据库时生效
+type TextLimitsConfig struct {
+	MaxLength      int    `mapstructure:"max_length"`       // 最大长度（字节），超出时截断并注明原始长度；0 表示不限制
+	SpillToStorage bool   `mapstructure:"spill_to_storage"` // 截断任务错误信息时将完整内容写入文件存储，管理员可下载
+	SpillPrefix    string `mapstructure:"spill_prefix"`     // 完整内容在存储中的对象键前缀
+}
+
+// MinTextLimit text_limits.max_length 的最小值（不为 0 时），保证截断后仍保留可读的内容和截断说明
+const MinTextLimit = 256
+
+// ExportsConfig 已完成任务归档导出配置：按天分区写入文件存储（gzip 压缩的 JSONL），供数据团队导入数据湖
+type ExportsConfig struct {
+	Prefix        string        `mapstructure:"prefix"`         // 导出文件在存储中的对象键前缀
+	Daily         bool          `mapstructure:"daily"`          // 每天自动导出前一天（UTC）创建的已完成任务
+	CheckInterval time.Duration `mapstructure:"check_interval"` // 检查待执行导出和每日导出的间隔
+	PageSize      int           `mapstructure:"page_size"`      // 每次查询读取的任务数（游标分页）
+	PageDelay     time.Duration `mapstructure:"page_delay"`     // 两次查询之间的间隔，避免导出占满数据库影响正常请求
+	RowsPerFile   int           `mapstructure:"rows_per_file"`  // 单个导出文件的最大行数，超出时同一天拆分为多个文件
+}
+
+// HealthConfig 机队健康汇总（NOC 看板和 Prometheus 指标）的阈值
+type HealthConfig struct {
+	CacheTTL         time.Duration        `mapstructure:"cache_ttl"`          // 汇总结果的缓存时间
+	NodeOfflineAfter time.Duration        `mapstructure:"node_offline_after"` // Edge Node 离线（最后心跳距今）超过该时长才计入
+	LowSupplyPercent int                  `mapstructure:"low_supply_percent"` // 耗材余量（百分比）不高于该值时视为耗材不足，0 表示不检查
+	MaxIDs           int                  `mapstructure:"max_ids"`            // 每类问题最多返回的资源ID数
+	Critical         HealthCriticalConfig `mapstructure:"critical"`
+}
+
+// HealthCriticalConfig 各类问题数量达到该值时严重程度为 critical，否则为 warning；0 表示该类问题只计为 warning
+type HealthCriticalConfig struct {
+	NodesOffline        int `mapstructure:"nodes_offline"`
+	PrintersError       int `mapstructure:"printers_error"`
+	PrintersLowSupplies int `mapstructure:"printers_low_supplies"`
+	JobsStalled         int `mapstructure:"jobs_stalled"`
+	PrintersSuspended   int `mapstructure:"printers_suspended"`
+}
+
+// HTTPClientConfig 出站 HTTP 客户端配置（IdP、Webhook、文件探测等）
+type HTTPClientConfig struct {
+	ProxyURL            string        `mapstructure:"proxy_url"` // 为空时使用 HTTP(S)_PROXY 环境变量
+	UserAgent           string        `mapstructure:"user_agent"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	TLSHandshakeTimeout time.Duration `mapstructure:"tls_handshake_timeout"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdPTimeout          time.Duration `mapstructure:"idp_timeout"`        // OAuth2 UserInfo / Token 请求超时
+	WebhookTimeout      time.Duration `mapstructure:"webhook_timeout"`    // Webhook 投递超时
+	FileProbeTimeout    time.Duration `mapstructure:"file_probe_timeout"` // file_url 探测超时
+}
+
+// StorageConfig 打印文件存储配置
+type StorageConfig struct {
+	Backend        string          `mapstructure:"backend"`         // local 或 s3
+	Dir            string          `mapstructure:"dir"`             // local 后端的存储目录
+	MaxUploadSize  int64           `mapstructure:"max_upload_size"` // 上传文件大小上限（字节）
+	SignedURLTTL   time.Duration   `mapstructure:"signed_url_ttl"`  // 下发给节点的签名链接有效期
+	SigningSecret  string          `mapstructure:"signing_secret"`  // local 后端签名链接的 HMAC 密钥
+	PublicBaseURL  string          `mapstructure:"public_base_url"` // local 后端签名链接的外部访问地址，也用于生成技术支持文件临时链接
+	SupportLinkTTL    time.Duration `mapstructure:"support_link_ttl"`     // 技术支持文件临时链接的默认有效期
+	SupportLinkMaxTTL time.Duration `mapstructure:"support_link_max_ttl"` // 技术支持文件临时链接的最长有效期
+	AllowedFormats []string        `mapstructure:"allowed_formats"` // 允许的文件格式（按文件头检测），为空时不限制
+	S3             S3StorageConfig `mapstructure:"s3"`
+}
+
+// S3StorageConfig S3 兼容存储配置（AWS S3 / MinIO）
+type S3StorageConfig struct {
+	Endpoint        string        `mapstructure:"endpoint"` // 如 https://s3.amazonaws.com、http://minio:9000
+	Region          string        `mapstructure:"region"`
+	Bucket          string        `mapstructure:"bucket"`
+	AccessKeyID     string        `mapstructure:"access_key_id"`
+	SecretAccessKey string        `mapstructure:"secret_access_key"`
+	PathStyle       bool          `mapstructure:"path_style"` // MinIO 通常需要开启
+	Timeout         time.Duration `mapstructure:"timeout"`    // 建连、TLS 握手和等待响应头的超时（不限制文件传输时长）
+}
+
+// ThumbnailsConfig 上传 PDF 的第一页缩略图配置
+type ThumbnailsConfig struct {
+	Renderer     string        `mapstructure:"renderer"`      // disabled（不生成）/ pdftoppm / http（外部渲染服务）
+	PdftoppmPath string        `mapstructure:"pdftoppm_path"` // pdftoppm 可执行文件路径
+	URL          string        `mapstructure:"url"`           // http 渲染服务地址
+	Width        int           `mapstructure:"width"`         // 缩略图宽度（像素）
+	Concurrency  int           `mapstructure:"concurrency"`   // 同时生成的缩略图数量上限
+	QueueSize    int           `mapstructure:"queue_size"`    // 等待生成的队列长度，队列满时跳过
+	Timeout      time.Duration `mapstructure:"timeout"`       // 单个缩略图的生成超时（含读取文件）
+}
+
+// ConversionConfig 分发前的文档格式转换配置：打印机声明的支持格式不含任务文件格式时，调用外部转换服务
+type ConversionConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	URL           string        `mapstructure:"url"`            // 转换服务地址
+	Timeout       time.Duration `mapstructure:"timeout"`        // 单个文件的转换超时（含读取和保存文件）
+	MaxInputSize  int64         `mapstructure:"max_input_size"`  // 发送给转换服务的文件大小上限（字节），超过时转换失败
+	MaxOutputSize int64         `mapstructure:"max_output_size"` // 转换结果大小上限（字节），超过时转换失败
+	TargetFormats []string      `mapstructure:"target_formats"`  // 转换服务可输出的格式，按优先顺序选择打印机支持的第一个
+}
+
+// MailInConfig 邮件打印配置：邮件服务（SES、Mailgun 等）将收到的邮件通过 webhook 转发给云端，附件按收件地址提交到打印机或打印机组
+type MailInConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	Token          string `mapstructure:"token"`            // webhook 共享密钥，请求需携带 X-Mailin-Token 请求头或 ?token= 参数
+	PrinterPattern string `mapstructure:"printer_pattern"`  // 收件地址到打印机的映射（正则，第一个捕获组为打印机ID或 slug），为空表示不按打印机映射
+	GroupPattern   string `mapstructure:"group_pattern"`    // 收件地址到打印机组的映射（正则，第一个捕获组为组ID），为空表示不按组映射
+	MaxMessageSize int64  `mapstructure:"max_message_size"` // 单封邮件大小上限（字节，含编码后的附件）
+}
+
+// HotFoldersConfig 共享文件夹打印配置：监视本地挂载的目录（如 SMB 共享），新文件写入完成后提交为打印任务，
+// 处理后的文件移入目录下的 done 或 failed 子目录
+type HotFoldersConfig struct {
+	Enabled           bool              `mapstructure:"enabled"`
+	ScanInterval      time.Duration     `mapstructure:"scan_interval"`      // 扫描目录的间隔
+	StableFor         time.Duration     `mapstructure:"stable_for"`         // 文件大小和修改时间保持不变达到该时长后才视为写入完成
+	AllowedExtensions []string          `mapstructure:"allowed_extensions"` // 允许的文件扩展名（不区分大小写，如 .pdf），为空时不限制；文件内容仍按 storage.allowed_formats 检测
+	MaxFileSize       int64             `mapstructure:"max_file_size"`      // 单个文件大小上限（字节）
+	Folders           []HotFolderConfig `mapstructure:"folders"`
+}
+
+// HotFolderConfig 单个共享文件夹，PrinterID 与 GroupID 只能设置一个
+type HotFolderConfig struct {
+	Path      string `mapstructure:"path"`       // 本地目录（已挂载的共享文件夹）
+	PrinterID string `mapstructure:"printer_id"` // 打印机ID或 slug
+	GroupID   string `mapstructure:"group_id"`   // 打印机组ID
+	User      string `mapstructure:"user"`       // 任务归属的本地用户名，访问策略按该用户的角色判断
+}
+
+// MailConfig 邮件通知配置
+type MailConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	SMTPHost string `mapstructure:"smtp_host"`
+	SMTPPort int    `mapstructure:"smtp_port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
 // Load 加载配置
 func Load() (*Config, error) {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("./configs")
-	viper.AddConfigPath("/etc/fly-print-cloud")
+	return load(viper.GetViper())
+}
+
+// load 使用给定的 viper 实例读取配置文件、环境变量和默认值，并校验结果
+// 热更新时使用新的实例，避免与启动后仍在读取全局 viper 的代码并发访问
+func load(v *viper.Viper) (*Config, error) {
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+	v.AddConfigPath(".")
+	v.AddConfigPath("./configs")
+	v.AddConfigPath("/etc/fly-print-cloud")
 
 	// 设置环境变量前缀
-	viper.SetEnvPrefix("FLY_PRINT")
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.SetEnvPrefix("FLY_PRINT")
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
 	// 设置默认值
-	setDefaults()
+	setDefaults(v)
 
 	// 读取配置文件
-	if err := viper.ReadInConfig(); err != nil {
+	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			return nil, fmt.Errorf("read config file error: %w", err)
 		}
@@ -93,63 +409,550 @@ func Load() (*Config, error) {
 	}
 
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("unmarshal config error: %w", err)
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return &config, nil
 }
 
-// GetOAuth2UserInfoURL 获取 OAuth2 UserInfo URL
-func GetOAuth2UserInfoURL() string {
-	return viper.GetString("oauth2.userinfo_url")
+// ConfigFileUsed 启动时加载的配置文件路径，未找到配置文件时为空
+func ConfigFileUsed() string {
+	return viper.ConfigFileUsed()
+}
+
+// Validate 校验配置取值（启动和热更新时均会执行，校验失败时热更新不生效）
+func (c *Config) Validate() error {
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		return fmt.Errorf("server.port out of range: %d", c.Server.Port)
+	}
+	if c.Server.TLS.Enabled && (c.Server.TLS.CertFile == "" || c.Server.TLS.KeyFile == "") {
+		return fmt.Errorf("server.tls.cert_file and server.tls.key_file are required when server.tls.enabled is true")
+	}
+	if c.Server.TLS.ClientCAFile != "" && !c.Server.TLS.Enabled {
+		return fmt.Errorf("server.tls.client_ca_file requires server.tls.enabled")
+	}
+	if _, err := LoadTimezone(c.App.Timezone); err != nil {
+		return fmt.Errorf("app.timezone: %w", err)
+	}
+	if c.Edge.StaleHeartbeatFactor < 0 {
+		return fmt.Errorf("edge.stale_heartbeat_factor must not be negative: %d", c.Edge.StaleHeartbeatFactor)
+	}
+	rateLimits := map[string]int{
+		"edge.rate_limit_heartbeat":      c.Edge.RateLimitHeartbeat,
+		"edge.rate_limit_printer_status": c.Edge.RateLimitPrinterStatus,
+		"edge.rate_limit_job_update":     c.Edge.RateLimitJobUpdate,
+		"edge.rate_limit_log_batch":      c.Edge.RateLimitLogBatch,
+		"edge.rate_limit_warn_after":     c.Edge.RateLimitWarnAfter,
+		"edge.rate_limit_close_after":    c.Edge.RateLimitCloseAfter,
+		"edge.conflict_replacements":     c.Edge.ConflictReplacements,
+		"edge.max_capabilities_size":     c.Edge.MaxCapabilitiesSize,
+	}
+	for key, n := range rateLimits {
+		if n < 0 {
+			return fmt.Errorf("%s must not be negative: %d", key, n)
+		}
+	}
+	if c.Edge.RateLimitWarnAfter > 0 && c.Edge.RateLimitCloseAfter > 0 && c.Edge.RateLimitCloseAfter < c.Edge.RateLimitWarnAfter {
+		return fmt.Errorf("edge.rate_limit_close_after (%d) must not be less than edge.rate_limit_warn_after (%d)", c.Edge.RateLimitCloseAfter, c.Edge.RateLimitWarnAfter)
+	}
+	if c.Edge.PrinterDiscovery != "auto" && c.Edge.PrinterDiscovery != "review" {
+		return fmt.Errorf("edge.printer_discovery must be auto or review: %q", c.Edge.PrinterDiscovery)
+	}
+	durations := map[string]time.Duration{
+		"database.slow_query_threshold":  c.Database.SlowQueryThreshold,
+		"edge.heartbeat_timeout":         c.Edge.HeartbeatTimeout,
+		"edge.offline_check_interval":    c.Edge.OfflineCheckInterval,
+		"edge.clock_skew_threshold":      c.Edge.ClockSkewThreshold,
+		"edge.heartbeat_interval":        c.Edge.HeartbeatInterval,
+		"edge.max_drain_time":            c.Edge.MaxDrainTime,
+		"edge.drain_check_interval":      c.Edge.DrainCheckInterval,
+		"edge.conflict_window":           c.Edge.ConflictWindow,
+		"edge.printer_tombstone_ttl":     c.Edge.PrinterTombstoneTTL,
+		"jobs.stall_timeout":             c.Jobs.StallTimeout,
+		"jobs.stall_check_interval":      c.Jobs.StallCheckInterval,
+		"jobs.progress_write_interval":   c.Jobs.ProgressWriteInterval,
+		"jobs.open_check_interval":       c.Jobs.OpenCheckInterval,
+		"jobs.breaker_window":            c.Jobs.BreakerWindow,
+		"jobs.breaker_cooldown":          c.Jobs.BreakerCooldown,
+		"jobs.breaker_check_interval":    c.Jobs.BreakerCheckInterval,
+		"jobs.max_queue_pause":           c.Jobs.MaxQueuePause,
+		"jobs.queue_pause_check_interval": c.Jobs.QueuePauseCheckInterval,
+		"jobs.queue_reconcile_interval":   c.Jobs.QueueReconcileInterval,
+		"sla.time_to_dispatch":           c.SLA.TimeToDispatch,
+		"sla.time_to_start":              c.SLA.TimeToStart,
+		"sla.time_to_complete":           c.SLA.TimeToComplete,
+		"sla.check_interval":             c.SLA.CheckInterval,
+		"sla.alert_window":               c.SLA.AlertWindow,
+		"retention.files_sweep_interval": c.Retention.FilesSweepInterval,
+		"exports.check_interval":         c.Exports.CheckInterval,
+		"exports.page_delay":             c.Exports.PageDelay,
+		"health.cache_ttl":               c.Health.CacheTTL,
+		"health.node_offline_after":      c.Health.NodeOfflineAfter,
+		"power.check_interval":           c.Power.CheckInterval,
+		"power.wake_timeout":             c.Power.WakeTimeout,
+		"power.resleep_delay":            c.Power.ResleepDelay,
+		"reservations.check_interval":    c.Reservations.CheckInterval,
+		"storage.signed_url_ttl":         c.Storage.SignedURLTTL,
+		"storage.support_link_ttl":       c.Storage.SupportLinkTTL,
+		"storage.support_link_max_ttl":   c.Storage.SupportLinkMaxTTL,
+		"diagnostics.upload_timeout":     c.Diagnostics.UploadTimeout,
+		"diagnostics.retention":          c.Diagnostics.Retention,
+	}
+	for key, d := range durations {
+		if d < 0 {
+			return fmt.Errorf("%s must not be negative: %s", key, d)
+		}
+	}
+	if c.Edge.HeartbeatInterval > 0 && c.Edge.HeartbeatTimeout > 0 && c.Edge.HeartbeatInterval >= c.Edge.HeartbeatTimeout {
+		return fmt.Errorf("edge.heartbeat_interval (%s) must be shorter than edge.heartbeat_timeout (%s)", c.Edge.HeartbeatInterval, c.Edge.HeartbeatTimeout)
+	}
+	if c.Edge.ConnTokenTTL <= 0 {
+		return fmt.Errorf("edge.conn_token_ttl must be positive: %s", c.Edge.ConnTokenTTL)
+	}
+	if c.Edge.ConflictReplacements > 0 && c.Edge.ConflictWindow <= 0 {
+		return fmt.Errorf("edge.conflict_window must be positive when edge.conflict_replacements is set")
+	}
+	if err := c.Auth.PasswordHash.validate(); err != nil {
+		return err
+	}
+	if c.LookupCache.Enabled {
+		if c.LookupCache.Backend != "local" && c.LookupCache.Backend != "redis" {
+			return fmt.Errorf("lookup_cache.backend must be local or redis: %q", c.LookupCache.Backend)
+		}
+		if c.LookupCache.TTL <= 0 || c.LookupCache.TTL > 5*time.Minute {
+			return fmt.Errorf("lookup_cache.ttl must be between 0 and 5m: %s", c.LookupCache.TTL)
+		}
+		if c.LookupCache.MaxEntries < 1 {
+			return fmt.Errorf("lookup_cache.max_entries must be at least 1: %d", c.LookupCache.MaxEntries)
+		}
+	}
+	if c.Health.LowSupplyPercent < 0 || c.Health.LowSupplyPercent > 100 {
+		return fmt.Errorf("health.low_supply_percent must be between 0 and 100: %d", c.Health.LowSupplyPercent)
+	}
+	if c.Health.MaxIDs < 1 {
+		return fmt.Errorf("health.max_ids must be at least 1: %d", c.Health.MaxIDs)
+	}
+	critical := c.Health.Critical
+	if critical.NodesOffline < 0 || critical.PrintersError < 0 || critical.PrintersLowSupplies < 0 || critical.JobsStalled < 0 || critical.PrintersSuspended < 0 {
+		return fmt.Errorf("health.critical thresholds must not be negative")
+	}
+	if c.SLA.AlertBreachRate < 0 || c.SLA.AlertBreachRate > 1 {
+		return fmt.Errorf("sla.alert_breach_rate must be between 0 and 1: %v", c.SLA.AlertBreachRate)
+	}
+	if c.SLA.AlertMinJobs < 0 {
+		return fmt.Errorf("sla.alert_min_jobs must not be negative: %d", c.SLA.AlertMinJobs)
+	}
+	if c.Reservations.DefaultDuration <= 0 || c.Reservations.MaxDuration < c.Reservations.DefaultDuration {
+		return fmt.Errorf("reservations.default_duration must be positive and not exceed reservations.max_duration (%s, %s)",
+			c.Reservations.DefaultDuration, c.Reservations.MaxDuration)
+	}
+	if c.Jobs.FailoverAfter < 1 {
+		return fmt.Errorf("jobs.failover_after must be at least 1: %d", c.Jobs.FailoverAfter)
+	}
+	if c.Jobs.BreakerFailures < 0 {
+		return fmt.Errorf("jobs.breaker_failures must not be negative: %d", c.Jobs.BreakerFailures)
+	}
+	if c.Jobs.BreakerFailures > 0 && c.Jobs.BreakerWindow <= 0 {
+		return fmt.Errorf("jobs.breaker_window must be positive when jobs.breaker_failures is set")
+	}
+	if c.Jobs.WhenClosed != "reject" && c.Jobs.WhenClosed != "schedule" {
+		return fmt.Errorf("jobs.when_closed must be reject or schedule: %q", c.Jobs.WhenClosed)
+	}
+	if c.Jobs.MaxCopies < 1 {
+		return fmt.Errorf("jobs.max_copies must be at least 1: %d", c.Jobs.MaxCopies)
+	}
+	if c.Jobs.DefaultCopies < 0 || c.Jobs.DefaultCopies > c.Jobs.MaxCopies {
+		return fmt.Errorf("jobs.default_copies must be between 0 and jobs.max_copies: %d", c.Jobs.DefaultCopies)
+	}
+	if c.Exports.PageSize < 1 || c.Exports.RowsPerFile < 1 {
+		return fmt.Errorf("exports.page_size and exports.rows_per_file must be at least 1: %d, %d", c.Exports.PageSize, c.Exports.RowsPerFile)
+	}
+	if strings.Trim(c.Exports.Prefix, "/") == "" {
+		return fmt.Errorf("exports.prefix must not be empty")
+	}
+	if c.Retention.FilesDays < 0 {
+		return fmt.Errorf("retention.files_days must not be negative: %d", c.Retention.FilesDays)
+	}
+	if c.Retention.SpilledTextDays < 0 {
+		return fmt.Errorf("retention.spilled_text_days must not be negative: %d", c.Retention.SpilledTextDays)
+	}
+	if c.Retention.NodeMetricsDays < 0 {
+		return fmt.Errorf("retention.node_metrics_days must not be negative: %d", c.Retention.NodeMetricsDays)
+	}
+	if c.TextLimits.MaxLength != 0 && c.TextLimits.MaxLength < MinTextLimit {
+		return fmt.Errorf("text_limits.max_length must be 0 or at least %d: %d", MinTextLimit, c.TextLimits.MaxLength)
+	}
+	if c.TextLimits.SpillToStorage && strings.Trim(c.TextLimits.SpillPrefix, "/") == "" {
+		return fmt.Errorf("text_limits.spill_prefix must not be empty when spill_to_storage is enabled")
+	}
+	if c.Pricing.PerPageMono < 0 || c.Pricing.PerPageColor < 0 {
+		return fmt.Errorf("pricing per-page prices must not be negative")
+	}
+	if c.Pricing.DuplexDiscount < 0 || c.Pricing.DuplexDiscount > 1 {
+		return fmt.Errorf("pricing.duplex_discount must be between 0 and 1: %v", c.Pricing.DuplexDiscount)
+	}
+	if c.Storage.MaxUploadSize <= 0 {
+		return fmt.Errorf("storage.max_upload_size must be positive: %d", c.Storage.MaxUploadSize)
+	}
+	if c.Storage.SupportLinkMaxTTL > 0 && c.Storage.SupportLinkTTL > c.Storage.SupportLinkMaxTTL {
+		return fmt.Errorf("storage.support_link_ttl (%s) exceeds storage.support_link_max_ttl (%s)", c.Storage.SupportLinkTTL, c.Storage.SupportLinkMaxTTL)
+	}
+	if c.Drivers.MaxPPDSize <= 0 {
+		return fmt.Errorf("drivers.max_ppd_size must be positive: %d", c.Drivers.MaxPPDSize)
+	}
+	if c.Diagnostics.MaxSize <= 0 {
+		return fmt.Errorf("diagnostics.max_size must be positive: %d", c.Diagnostics.MaxSize)
+	}
+	if c.Conversion.Enabled {
+		if c.Conversion.URL == "" {
+			return fmt.Errorf("conversion.url is required when conversion.enabled is true")
+		}
+		if c.Conversion.Timeout <= 0 {
+			return fmt.Errorf("conversion.timeout must be positive: %s", c.Conversion.Timeout)
+		}
+		if c.Conversion.MaxInputSize <= 0 || c.Conversion.MaxOutputSize <= 0 {
+			return fmt.Errorf("conversion.max_input_size and conversion.max_output_size must be positive")
+		}
+		if len(c.Conversion.TargetFormats) == 0 {
+			return fmt.Errorf("conversion.target_formats must not be empty when conversion.enabled is true")
+		}
+	}
+	if c.MailIn.Enabled {
+		if c.MailIn.Token == "" {
+			return fmt.Errorf("mailin.token is required when mailin.enabled is true")
+		}
+		if c.MailIn.PrinterPattern == "" && c.MailIn.GroupPattern == "" {
+			return fmt.Errorf("mailin.printer_pattern or mailin.group_pattern is required when mailin.enabled is true")
+		}
+		if c.MailIn.MaxMessageSize <= 0 {
+			return fmt.Errorf("mailin.max_message_size must be positive: %d", c.MailIn.MaxMessageSize)
+		}
+	}
+	if c.HotFolders.Enabled {
+		if err := c.HotFolders.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Mail.Enabled && (c.Mail.SMTPHost == "" || c.Mail.From == "") {
+		return fmt.Errorf("mail.smtp_host and mail.from are required when mail.enabled is true")
+	}
+	return nil
+}
+
+// validate 校验密码哈希算法和参数（bcrypt 成本范围与 bcrypt.MinCost/MaxCost 一致）
+func (c *PasswordHashConfig) validate() error {
+	switch c.Algorithm {
+	case "bcrypt":
+		if c.BcryptCost < 4 || c.BcryptCost > 31 {
+			return fmt.Errorf("auth.password_hash.bcrypt_cost must be between 4 and 31: %d", c.BcryptCost)
+		}
+	case "argon2id":
+		if c.Argon2Memory < 8*uint32(c.Argon2Parallelism) || c.Argon2Iterations < 1 || c.Argon2Parallelism < 1 {
+			return fmt.Errorf("auth.password_hash argon2 parameters are invalid: memory=%d, iterations=%d, parallelism=%d", c.Argon2Memory, c.Argon2Iterations, c.Argon2Parallelism)
+		}
+	default:
+		return fmt.Errorf("auth.password_hash.algorithm must be bcrypt or argon2id: %q", c.Algorithm)
+	}
+	return nil
+}
+
+// validate 校验共享文件夹配置：每个目录必须指定打印目标和归属用户，同一目录不能重复配置
+func (c *HotFoldersConfig) validate() error {
+	if c.ScanInterval <= 0 {
+		return fmt.Errorf("hot_folders.scan_interval must be positive: %s", c.ScanInterval)
+	}
+	if c.StableFor < 0 {
+		return fmt.Errorf("hot_folders.stable_for must not be negative: %s", c.StableFor)
+	}
+	if c.MaxFileSize <= 0 {
+		return fmt.Errorf("hot_folders.max_file_size must be positive: %d", c.MaxFileSize)
+	}
+	if len(c.Folders) == 0 {
+		return fmt.Errorf("hot_folders.folders must not be empty when hot_folders.enabled is true")
+	}
+	seen := make(map[string]bool, len(c.Folders))
+	for i, folder := range c.Folders {
+		if folder.Path == "" {
+			return fmt.Errorf("hot_folders.folders[%d].path is required", i)
+		}
+		path := filepath.Clean(folder.Path)
+		if seen[path] {
+			return fmt.Errorf("hot_folders.folders[%d].path %s is configured more than once", i, folder.Path)
+		}
+		seen[path] = true
+		if (folder.PrinterID == "") == (folder.GroupID == "") {
+			return fmt.Errorf("hot_folders.folders[%d] must set exactly one of printer_id and group_id", i)
+		}
+		if folder.User == "" {
+			return fmt.Errorf("hot_folders.folders[%d].user is required", i)
+		}
+	}
+	return nil
+}
+
+// LoadTimezone 解析 IANA 时区名称（如 Asia/Singapore）；时区名称会传给数据库按时区分组，不接受空值和 Local
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("timezone must be an IANA name such as UTC or Asia/Singapore: %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
 }
 
 // setDefaults 设置默认配置值
-func setDefaults() {
+func setDefaults(v *viper.Viper) {
 	// App 默认值
-	viper.SetDefault("app.name", "fly-print-cloud")
-	viper.SetDefault("app.version", "0.1.0")
-	viper.SetDefault("app.environment", "development")
-	viper.SetDefault("app.debug", true)
+	v.SetDefault("app.name", "fly-print-cloud")
+	v.SetDefault("app.version", "0.1.0")
+	v.SetDefault("app.environment", "development")
+	v.SetDefault("app.debug", true)
+	v.SetDefault("app.timezone", "UTC")
 
 	// Database 默认值
-	viper.SetDefault("database.host", "localhost")
-	viper.SetDefault("database.port", 5432)
-	viper.SetDefault("database.user", "postgres")
-	viper.SetDefault("database.password", "postgres")
-	viper.SetDefault("database.dbname", "fly_print_cloud")
-	viper.SetDefault("database.sslmode", "disable")
+	v.SetDefault("database.host", "localhost")
+	v.SetDefault("database.port", 5432)
+	v.SetDefault("database.user", "postgres")
+	v.SetDefault("database.password", "postgres")
+	v.SetDefault("database.dbname", "fly_print_cloud")
+	v.SetDefault("database.sslmode", "disable")
+	v.SetDefault("database.max_open_conns", 25)
+	v.SetDefault("database.max_idle_conns", 5)
+	v.SetDefault("database.conn_max_lifetime", "30m")
+	v.SetDefault("database.conn_max_idle_time", "5m")
+	v.SetDefault("database.connect_timeout", "60s")
+	v.SetDefault("database.slow_query_threshold", "250ms")
 
 	// Redis 默认值
-	viper.SetDefault("redis.host", "localhost")
-	viper.SetDefault("redis.port", 6379)
-	viper.SetDefault("redis.password", "")
-	viper.SetDefault("redis.db", 0)
+	v.SetDefault("redis.host", "localhost")
+	v.SetDefault("redis.port", 6379)
+	v.SetDefault("redis.password", "")
+	v.SetDefault("redis.db", 0)
 
 	// Server 默认值
-	viper.SetDefault("server.host", "0.0.0.0")
-	viper.SetDefault("server.port", 8080)
+	v.SetDefault("server.host", "0.0.0.0")
+	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.tls.enabled", false)
+	v.SetDefault("server.tls.cert_file", "")
+	v.SetDefault("server.tls.key_file", "")
+	v.SetDefault("server.tls.client_ca_file", "")
 
 	// OAuth2 默认值
-	viper.SetDefault("oauth2.client_id", "")
-	viper.SetDefault("oauth2.client_secret", "")
-	viper.SetDefault("oauth2.auth_url", "")
-	viper.SetDefault("oauth2.token_url", "")
-	viper.SetDefault("oauth2.userinfo_url", "")
-	viper.SetDefault("oauth2.redirect_uri", "")
-	viper.SetDefault("oauth2.logout_url", "")
-	viper.SetDefault("oauth2.logout_redirect_uri_param", "post_logout_redirect_uri")
-	viper.SetDefault("admin.console_url", "http://localhost:3000")
+	v.SetDefault("oauth2.client_id", "")
+	v.SetDefault("oauth2.client_secret", "")
+	v.SetDefault("oauth2.auth_url", "")
+	v.SetDefault("oauth2.token_url", "")
+	v.SetDefault("oauth2.userinfo_url", "")
+	v.SetDefault("oauth2.redirect_uri", "")
+	v.SetDefault("oauth2.logout_url", "")
+	v.SetDefault("oauth2.logout_redirect_uri_param", "post_logout_redirect_uri")
+	v.SetDefault("admin.console_url", "http://localhost:3000")
+
+	// Pricing 默认值
+	v.SetDefault("pricing.currency", "CNY")
+	v.SetDefault("pricing.per_page_mono", 0.1)
+	v.SetDefault("pricing.per_page_color", 0.5)
+	v.SetDefault("pricing.duplex_discount", 0)
+
+	// Edge 默认值
+	v.SetDefault("edge.heartbeat_timeout", "3m")
+	v.SetDefault("edge.offline_check_interval", "30s")
+	v.SetDefault("edge.clock_skew_threshold", "30s")
+	v.SetDefault("edge.printer_discovery", "auto")
+	v.SetDefault("edge.printer_tombstone_ttl", "720h")
+	v.SetDefault("edge.heartbeat_interval", "30s")
+	v.SetDefault("edge.ws_compression", false)
+	v.SetDefault("edge.validate_messages", false)
+	v.SetDefault("edge.max_drain_time", "2h")
+	v.SetDefault("edge.drain_check_interval", "15s")
+	v.SetDefault("edge.trust_node_location", false)
+	v.SetDefault("edge.stale_heartbeat_factor", 3)
+	v.SetDefault("edge.rate_limit_heartbeat", 6)
+	v.SetDefault("edge.rate_limit_printer_status", 30)
+	v.SetDefault("edge.rate_limit_job_update", 60)
+	v.SetDefault("edge.rate_limit_log_batch", 30)
+	v.SetDefault("edge.rate_limit_warn_after", 10)
+	v.SetDefault("edge.rate_limit_close_after", 300)
+	v.SetDefault("edge.conflict_window", "2m")
+	v.SetDefault("edge.conflict_replacements", 3)
+	v.SetDefault("edge.refuse_conflicting_conns", false)
+	v.SetDefault("edge.max_capabilities_size", 32*1024)
+	v.SetDefault("edge.conn_token_secret", "")
+	v.SetDefault("edge.conn_token_ttl", "10m")
+	v.SetDefault("edge.allow_legacy_node_id", true)
+
+	// Drivers 默认值
+	v.SetDefault("drivers.ppd_dir", "./data/ppd")
+	v.SetDefault("drivers.max_ppd_size", 2*1024*1024)
+
+	// Diagnostics 默认值
+	v.SetDefault("diagnostics.dir", "./data/diagnostics")
+	v.SetDefault("diagnostics.max_size", 50*1024*1024)
+	v.SetDefault("diagnostics.upload_timeout", "1h")
+	v.SetDefault("diagnostics.retention", "72h")
+
+	// Power 默认值
+	v.SetDefault("power.check_interval", "1m")
+	v.SetDefault("power.wake_timeout", "2m")
+	v.SetDefault("power.resleep_delay", "15m")
+
+	// Reservations 默认值
+	v.SetDefault("reservations.default_duration", "5m")
+	v.SetDefault("reservations.max_duration", "30m")
+	v.SetDefault("reservations.check_interval", "15s")
+
+	// Jobs 默认值
+	v.SetDefault("jobs.stall_timeout", "2h")
+	v.SetDefault("jobs.stall_check_interval", "5m")
+	v.SetDefault("jobs.default_copies", 1)
+	v.SetDefault("jobs.max_copies", 99)
+	v.SetDefault("jobs.progress_write_interval", "5s")
+	v.SetDefault("jobs.when_closed", "reject")
+	v.SetDefault("jobs.open_check_interval", "1m")
+	v.SetDefault("jobs.failover_after", 2)
+	v.SetDefault("jobs.breaker_failures", 5)
+	v.SetDefault("jobs.breaker_window", "10m")
+	v.SetDefault("jobs.breaker_cooldown", "15m")
+	v.SetDefault("jobs.breaker_check_interval", "30s")
+	v.SetDefault("jobs.max_queue_pause", "8h")
+	v.SetDefault("jobs.queue_pause_check_interval", "1m")
+	v.SetDefault("jobs.queue_reconcile_interval", "1m")
+	v.SetDefault("jobs.failover_error_codes", []string{"media-jam", "paper-jam", "cover-open", "door-open", "interlock-open", "fuser-failure", "marker-failure", "hardware-error", "printer-fault"})
+
+	// SLA 默认值
+	v.SetDefault("sla.time_to_dispatch", "1m")
+	v.SetDefault("sla.time_to_start", "2m")
+	v.SetDefault("sla.time_to_complete", "30m")
+	v.SetDefault("sla.check_interval", "30s")
+	v.SetDefault("sla.alert_breach_rate", 0)
+	v.SetDefault("sla.alert_window", "1h")
+	v.SetDefault("sla.alert_min_jobs", 20)
+
+	// 数据保留默认值
+	v.SetDefault("retention.files_days", 0)
+	v.SetDefault("retention.files_sweep_interval", "1h")
+	v.SetDefault("retention.spilled_text_days", 0)
+	v.SetDefault("retention.node_metrics_days", 31)
+
+	// 文本字段长度限制默认值
+	v.SetDefault("text_limits.max_length", 4096)
+	v.SetDefault("text_limits.spill_to_storage", false)
+	v.SetDefault("text_limits.spill_prefix", "job-errors")
+
+	// 任务归档导出默认值
+	v.SetDefault("exports.prefix", "exports")
+	v.SetDefault("exports.daily", false)
+	v.SetDefault("exports.check_interval", "1m")
+	v.SetDefault("exports.page_size", 500)
+	v.SetDefault("exports.page_delay", "50ms")
+	v.SetDefault("exports.rows_per_file", 50000)
+
+	// HTTP 客户端默认值
+	v.SetDefault("http_client.proxy_url", "")
+	v.SetDefault("http_client.user_agent", "fly-print-cloud")
+	v.SetDefault("http_client.dial_timeout", "5s")
+	v.SetDefault("http_client.tls_handshake_timeout", "5s")
+	v.SetDefault("http_client.idle_conn_timeout", "90s")
+	v.SetDefault("http_client.max_idle_conns", 100)
+	v.SetDefault("http_client.max_idle_conns_per_host", 10)
+	v.SetDefault("http_client.idp_timeout", "10s")
+	v.SetDefault("http_client.webhook_timeout", "10s")
+	v.SetDefault("http_client.file_probe_timeout", "5s")
+
+	// Storage 默认值
+	v.SetDefault("storage.backend", "local")
+	v.SetDefault("storage.dir", "./data/files")
+	v.SetDefault("storage.max_upload_size", 100*1024*1024)
+	v.SetDefault("storage.signed_url_ttl", "15m")
+	v.SetDefault("storage.signing_secret", "")
+	v.SetDefault("storage.public_base_url", "http://localhost:8080")
+	v.SetDefault("storage.support_link_ttl", "24h")
+	v.SetDefault("storage.support_link_max_ttl", "168h")
+	v.SetDefault("storage.allowed_formats", []string{"pdf", "postscript", "pcl", "png", "jpeg", "tiff", "text"})
+	v.SetDefault("storage.s3.endpoint", "")
+	v.SetDefault("storage.s3.region", "us-east-1")
+	v.SetDefault("storage.s3.bucket", "")
+	v.SetDefault("storage.s3.access_key_id", "")
+	v.SetDefault("storage.s3.secret_access_key", "")
+	v.SetDefault("storage.s3.path_style", false)
+	v.SetDefault("storage.s3.timeout", "30s")
+
+	// Thumbnails 默认值
+	v.SetDefault("thumbnails.renderer", "disabled")
+	v.SetDefault("thumbnails.pdftoppm_path", "pdftoppm")
+	v.SetDefault("thumbnails.url", "")
+	v.SetDefault("thumbnails.width", 256)
+	v.SetDefault("thumbnails.concurrency", 2)
+	v.SetDefault("thumbnails.queue_size", 100)
+	v.SetDefault("thumbnails.timeout", "30s")
+
+	// Conversion 默认值
+	v.SetDefault("conversion.enabled", false)
+	v.SetDefault("conversion.url", "")
+	v.SetDefault("conversion.timeout", "60s")
+	v.SetDefault("conversion.max_input_size", 50*1024*1024)
+	v.SetDefault("conversion.max_output_size", 100*1024*1024)
+	v.SetDefault("conversion.target_formats", []string{"pdf", "postscript", "pcl"})
+
+	// MailIn 默认值
+	v.SetDefault("mailin.enabled", false)
+	v.SetDefault("mailin.token", "")
+	v.SetDefault("mailin.printer_pattern", `^printer-([a-z0-9_-]+)@`)
+	v.SetDefault("mailin.group_pattern", `^group-([a-z0-9_-]+)@`)
+	v.SetDefault("mailin.max_message_size", 40*1024*1024)
+
+	// Auth 默认值
+	v.SetDefault("auth.password_hash.algorithm", "bcrypt")
+	v.SetDefault("auth.password_hash.bcrypt_cost", 12)
+	v.SetDefault("auth.password_hash.argon2_memory", 64*1024)
+	v.SetDefault("auth.password_hash.argon2_iterations", 3)
+	v.SetDefault("auth.password_hash.argon2_parallelism", 2)
+
+	// Health 默认值
+	v.SetDefault("health.cache_ttl", "60s")
+	v.SetDefault("health.node_offline_after", "10m")
+	v.SetDefault("health.low_supply_percent", 10)
+	v.SetDefault("health.max_ids", 50)
+	v.SetDefault("health.critical.nodes_offline", 1)
+	v.SetDefault("health.critical.printers_error", 3)
+	v.SetDefault("health.critical.printers_low_supplies", 0)
+	v.SetDefault("health.critical.jobs_stalled", 10)
+	v.SetDefault("health.critical.printers_suspended", 1)
+	v.SetDefault("lookup_cache.enabled", true)
+	v.SetDefault("lookup_cache.backend", "local")
+	v.SetDefault("lookup_cache.ttl", "15s")
+	v.SetDefault("lookup_cache.max_entries", 5000)
+
+	// HotFolders 默认值
+	v.SetDefault("hot_folders.enabled", false)
+	v.SetDefault("hot_folders.scan_interval", "10s")
+	v.SetDefault("hot_folders.stable_for", "5s")
+	v.SetDefault("hot_folders.allowed_extensions", []string{".pdf"})
+	v.SetDefault("hot_folders.max_file_size", 50*1024*1024)
+
+	// Mail 默认值
+	v.SetDefault("mail.enabled", false)
+	v.SetDefault("mail.smtp_host", "")
+	v.SetDefault("mail.smtp_port", 587)
+	v.SetDefault("mail.username", "")
+	v.SetDefault("mail.password", "")
+	v.SetDefault("mail.from", "")
 
 	// Admin 创建配置
-	viper.SetDefault("create_default_admin", "false")
-	viper.SetDefault("default_admin_password", "")
+	v.SetDefault("create_default_admin", "false")
+	v.SetDefault("default_admin_password", "")
 }
 
-// GetDSN 获取数据库连接字符串
+// GetDSN 获取数据库连接字符串（会话时区固定为 UTC，保证时间戳按 UTC 读写）
 func (c *DatabaseConfig) GetDSN() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s timezone=UTC",
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode)
 }
 
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// loadFrom 在只包含给定 config.yaml 的临时目录中加载配置（内容为空时不写文件，只使用默认值和环境变量）
+func loadFrom(t *testing.T, yaml string) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	if yaml != "" {
+		if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(yaml), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	cfg, err := load(viper.New())
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return cfg
+}
+
+func TestDatabasePoolDefaults(t *testing.T) {
+	db := loadFrom(t, "").Database
+	if db.MaxOpenConns != 25 || db.MaxIdleConns != 5 || db.ConnMaxLifetime != 30*time.Minute ||
+		db.ConnMaxIdleTime != 5*time.Minute || db.ConnectTimeout != time.Minute {
+		t.Fatalf("pool defaults = %+v", db)
+	}
+}
+
+func TestDatabasePoolFromFileAndEnv(t *testing.T) {
+	t.Setenv("FLY_PRINT_DATABASE_CONNECT_TIMEOUT", "2m30s")
+	db := loadFrom(t, `
+database:
+  max_open_conns: 80
+  max_idle_conns: 20
+  conn_max_lifetime: 1h
+  conn_max_idle_time: 0s
+  connect_timeout: 10s
+`).Database
+
+	if db.MaxOpenConns != 80 || db.MaxIdleConns != 20 || db.ConnMaxLifetime != time.Hour || db.ConnMaxIdleTime != 0 {
+		t.Fatalf("pool from file = %+v", db)
+	}
+	// 环境变量优先于配置文件
+	if db.ConnectTimeout != 150*time.Second {
+		t.Fatalf("connect_timeout = %s, want 2m30s from the environment", db.ConnectTimeout)
+	}
+}
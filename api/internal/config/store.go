@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spf13/viper"
+)
+
+// Store 当前生效的配置。热更新时构造新的 Config 整体原子替换，已取得的快照不会被修改；
+// 可热更新的配置项必须在每次使用时通过 Get 读取，不能在构造组件时缓存
+type Store struct {
+	current atomic.Pointer[Config]
+	mu      sync.Mutex // 串行化重新加载
+}
+
+// NewStore 以启动时加载的配置创建配置存储
+func NewStore(cfg *Config) *Store {
+	s := &Store{}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get 返回当前生效的配置快照（只读）
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Change 一个配置项的变化
+type Change struct {
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// String 日志格式：key: old -> new
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Key, c.Old, c.New)
+}
+
+// ReloadResult 重新加载的结果
+type ReloadResult struct {
+	Applied         []Change // 已生效的变化
+	RestartRequired []Change // 配置文件中已修改、但需要重启才能生效的变化
+}
+
+// Reload 重新读取并校验配置，将可热更新的配置项原子替换为新值
+// 读取或校验失败时返回错误，当前配置保持不变
+func (s *Store) Reload() (*ReloadResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loaded, err := load(viper.New())
+	if err != nil {
+		return nil, err
+	}
+
+	old := s.current.Load()
+	next := *old
+	applyHotSettings(&next, loaded)
+
+	result := &ReloadResult{
+		Applied:         diffConfig(old, &next),
+		RestartRequired: diffConfig(&next, loaded),
+	}
+	if len(result.Applied) > 0 {
+		s.current.Store(&next)
+	}
+	return result, nil
+}
+
+// applyHotSettings 将可热更新的配置项从 loaded 复制到 next，其余配置项保持启动时的值
+// 只包含每次使用时读取的配置：超时、间隔、上限、计费、邮件告警和数据保留；
+// 数据库、监听地址、OAuth2、存储后端、出站 HTTP 客户端等在启动时用于建立连接或客户端，修改后需要重启
+func applyHotSettings(next, loaded *Config) {
+	connTokenSecret := next.Edge.ConnTokenSecret // 连接令牌签名器在启动时创建
+	next.Edge = loaded.Edge
+	next.Edge.ConnTokenSecret = connTokenSecret
+	next.Jobs = loaded.Jobs
+	next.SLA = loaded.SLA
+	next.Power = loaded.Power
+	next.Pricing = loaded.Pricing
+	next.Mail = loaded.Mail
+	next.Retention = loaded.Retention
+	next.Health = loaded.Health
+	next.TextLimits = loaded.TextLimits
+	next.App.Timezone = loaded.App.Timezone
+
+	next.Storage.MaxUploadSize = loaded.Storage.MaxUploadSize
+	next.Storage.AllowedFormats = loaded.Storage.AllowedFormats
+	next.Storage.SignedURLTTL = loaded.Storage.SignedURLTTL
+	next.Storage.SupportLinkTTL = loaded.Storage.SupportLinkTTL
+	next.Storage.SupportLinkMaxTTL = loaded.Storage.SupportLinkMaxTTL
+
+	next.Drivers.MaxPPDSize = loaded.Drivers.MaxPPDSize
+
+	next.Diagnostics.MaxSize = loaded.Diagnostics.MaxSize
+	next.Diagnostics.UploadTimeout = loaded.Diagnostics.UploadTimeout
+	next.Diagnostics.Retention = loaded.Diagnostics.Retention
+}
+
+// diffConfig 按配置键列出两份配置的差异（敏感配置项的值不输出）
+func diffConfig(a, b *Config) []Change {
+	before := flattenConfig(a)
+	after := flattenConfig(b)
+
+	var changes []Change
+	for key, oldValue := range before {
+		newValue := after[key]
+		if oldValue == newValue {
+			continue
+		}
+		if isSecretKey(key) {
+			oldValue, newValue = "******", "******"
+		}
+		changes = append(changes, Change{Key: key, Old: oldValue, New: newValue})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// flattenConfig 将配置展开为 "section.key" → 值 的映射，键名与配置文件一致
+func flattenConfig(cfg *Config) map[string]string {
+	out := make(map[string]string)
+	flattenValue("", reflect.ValueOf(*cfg), out)
+	return out
+}
+
+func flattenValue(prefix string, v reflect.Value, out map[string]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct {
+			flattenValue(key, value, out)
+			continue
+		}
+		out[key] = fmt.Sprint(value.Interface())
+	}
+}
+
+// isSecretKey 密码、密钥类配置项
+func isSecretKey(key string) bool {
+	for _, marker := range []string{"password", "secret", "access_key"} {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
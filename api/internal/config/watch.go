@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce 编辑器保存文件时通常连续产生多个事件，合并后只重新加载一次
+const reloadDebounce = 500 * time.Millisecond
+
+// Watch 监听配置文件变化和 SIGHUP 信号并重新加载配置（阻塞，ctx 取消后返回）
+// 未使用配置文件启动时只响应 SIGHUP
+func (s *Store) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var fileEvents <-chan fsnotify.Event
+	var fileErrors <-chan error
+	path := ConfigFileUsed()
+	if path != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			log.Printf("Failed to create config file watcher, only SIGHUP reloads config: %v", err)
+		} else {
+			defer watcher.Close()
+			// 监听所在目录而不是文件本身：编辑器和 Kubernetes ConfigMap 通过替换文件更新配置
+			if err := watcher.Add(filepath.Dir(path)); err != nil {
+				log.Printf("Failed to watch config dir %s, only SIGHUP reloads config: %v", filepath.Dir(path), err)
+			} else {
+				fileEvents, fileErrors = watcher.Events, watcher.Errors
+				log.Printf("Watching config file %s for changes", path)
+			}
+		}
+	}
+
+	realPath, _ := filepath.EvalSymlinks(path)
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Printf("Received SIGHUP, reloading config")
+			s.reloadAndLog()
+		case event, ok := <-fileEvents:
+			if !ok {
+				fileEvents = nil
+				continue
+			}
+			if !configFileChanged(event, path, &realPath) {
+				continue
+			}
+			debounce.Reset(reloadDebounce)
+		case err, ok := <-fileErrors:
+			if !ok {
+				fileErrors = nil
+				continue
+			}
+			log.Printf("Config file watcher error: %v", err)
+		case <-debounce.C:
+			log.Printf("Config file %s changed, reloading config", path)
+			s.reloadAndLog()
+		}
+	}
+}
+
+// configFileChanged 事件是否修改了配置文件：文件本身被写入/替换，或符号链接指向的文件已变化
+func configFileChanged(event fsnotify.Event, path string, realPath *string) bool {
+	if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+		return false
+	}
+	if filepath.Clean(event.Name) == filepath.Clean(path) && (event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) {
+		return true
+	}
+	current, err := filepath.EvalSymlinks(path)
+	if err != nil || current == *realPath {
+		return false
+	}
+	*realPath = current
+	return true
+}
+
+// reloadAndLog 重新加载配置并记录变化
+func (s *Store) reloadAndLog() {
+	result, err := s.Reload()
+	if err != nil {
+		log.Printf("Config reload failed, keeping current config: %v", err)
+		return
+	}
+	if len(result.Applied) == 0 && len(result.RestartRequired) == 0 {
+		log.Printf("Config reloaded, no changes")
+		return
+	}
+	for _, change := range result.Applied {
+		log.Printf("Config changed: %s", change)
+	}
+	for _, change := range result.RestartRequired {
+		log.Printf("Config change requires restart to take effect: %s", change)
+	}
+	log.Printf("Config reloaded: %d applied, %d require restart", len(result.Applied), len(result.RestartRequired))
+}
@@ -0,0 +1,192 @@
+package conversion
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/docformat"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/storage"
+)
+
+// ConvertedPrefix 转换结果的对象键前缀
+const ConvertedPrefix = "converted-files"
+
+// anyFormat 打印机上报该类型时表示由打印系统自动识别格式（如 CUPS），视为支持所有格式
+const anyFormat = "application/octet-stream"
+
+// ErrNoTargetFormat 转换服务可输出的格式都不被打印机支持
+var ErrNoTargetFormat = errors.New("conversion: printer supports none of the target formats")
+
+// Converter 分发前的文档格式转换：调用外部转换服务 POST <url>?from=<格式>&to=<格式>，请求体为原文件，成功时返回 200 和转换结果
+type Converter struct {
+	url           string
+	client        *http.Client
+	storage       storage.Storage
+	timeout       time.Duration
+	maxInputSize  int64
+	maxOutputSize int64
+	targetFormats []docformat.Format
+}
+
+// Result 转换结果（已保存到存储）
+type Result struct {
+	StorageKey string
+	Format     docformat.Format
+	Size       int64
+}
+
+// New 按配置创建转换器，未启用时返回 nil
+func New(cfg *config.ConversionConfig, client *http.Client, fileStorage storage.Storage) (*Converter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("invalid conversion url: %w", err)
+	}
+	targets := make([]docformat.Format, 0, len(cfg.TargetFormats))
+	for _, name := range cfg.TargetFormats {
+		format := docformat.Parse(name)
+		if format == docformat.FormatUnknown {
+			return nil, fmt.Errorf("unsupported conversion target format: %s", name)
+		}
+		targets = append(targets, format)
+	}
+	return &Converter{
+		url:           cfg.URL,
+		client:        client,
+		storage:       fileStorage,
+		timeout:       cfg.Timeout,
+		maxInputSize:  cfg.MaxInputSize,
+		maxOutputSize: cfg.MaxOutputSize,
+		targetFormats: targets,
+	}, nil
+}
+
+// Needed 任务文件是否需要转换后再分发：打印机上报了支持格式而其中不含任务文件格式
+// 只有单文件的云端任务记录了 source_format；已转换过的任务、格式未知或打印机未上报支持格式时不转换，按原文件分发
+func (c *Converter) Needed(job *models.PrintJob, printer *models.Printer) bool {
+	if c == nil || job.StorageKey == "" || job.ConvertedStorageKey != "" {
+		return false
+	}
+	source := docformat.Format(job.SourceFormat)
+	if source == "" || source == docformat.FormatUnknown {
+		return false
+	}
+	formats := printer.Capabilities.DocumentFormats
+	return len(formats) > 0 && !Supports(formats, source)
+}
+
+// Supports 打印机上报的支持格式是否包含指定格式
+func Supports(formats []string, format docformat.Format) bool {
+	for _, declared := range formats {
+		if strings.EqualFold(strings.TrimSpace(declared), anyFormat) || docformat.Parse(declared) == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Convert 读取任务文件并转换为打印机支持的格式，结果保存到存储
+// 整个过程（读取、转换、保存）受超时限制，原文件或转换结果超过大小上限时返回错误
+func (c *Converter) Convert(ctx context.Context, job *models.PrintJob, printer *models.Printer) (*Result, error) {
+	source := docformat.Format(job.SourceFormat)
+	target, ok := c.target(printer)
+	if !ok {
+		return nil, ErrNoTargetFormat
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	data, err := c.readSource(ctx, job.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := c.post(ctx, data, source, target)
+	if err != nil {
+		return nil, err
+	}
+
+	key := storage.NewKey(ConvertedPrefix, "converted"+target.Extension())
+	if err := c.storage.Put(ctx, key, bytes.NewReader(converted), int64(len(converted)), target.ContentType()); err != nil {
+		return nil, fmt.Errorf("failed to store converted file: %w", err)
+	}
+	return &Result{StorageKey: key, Format: target, Size: int64(len(converted))}, nil
+}
+
+// target 按配置顺序选择打印机支持的第一个目标格式
+func (c *Converter) target(printer *models.Printer) (docformat.Format, bool) {
+	for _, format := range c.targetFormats {
+		if Supports(printer.Capabilities.DocumentFormats, format) {
+			return format, true
+		}
+	}
+	return docformat.FormatUnknown, false
+}
+
+// readSource 读取原文件，超过大小上限时返回错误
+func (c *Converter) readSource(ctx context.Context, key string) ([]byte, error) {
+	reader, err := c.storage.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source file: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, c.maxInputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source file: %w", err)
+	}
+	if int64(len(data)) > c.maxInputSize {
+		return nil, fmt.Errorf("source file exceeds %d bytes", c.maxInputSize)
+	}
+	return data, nil
+}
+
+// post 将文件发送给转换服务
+func (c *Converter) post(ctx context.Context, data []byte, source, target docformat.Format) ([]byte, error) {
+	endpoint, err := url.Parse(c.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid conversion url: %w", err)
+	}
+	query := endpoint.Query()
+	query.Set("from", string(source))
+	query.Set("to", string(target))
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", source.ContentType())
+	req.Header.Set("Accept", target.ContentType())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("converter request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("converter returned status %d", resp.StatusCode)
+	}
+
+	converted, err := io.ReadAll(io.LimitReader(resp.Body, c.maxOutputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read converter response: %w", err)
+	}
+	if len(converted) == 0 {
+		return nil, errors.New("converter returned an empty file")
+	}
+	if int64(len(converted)) > c.maxOutputSize {
+		return nil, fmt.Errorf("converted file exceeds %d bytes", c.maxOutputSize)
+	}
+	return converted, nil
+}
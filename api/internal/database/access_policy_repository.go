@@ -0,0 +1,127 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// AccessPolicyRepository 打印机访问策略数据访问层
+type AccessPolicyRepository struct {
+	db *DB
+}
+
+// NewAccessPolicyRepository 创建打印机访问策略数据访问层
+func NewAccessPolicyRepository(db *DB) *AccessPolicyRepository {
+	return &AccessPolicyRepository{db: db}
+}
+
+// CreatePolicy 为打印机添加允许的角色，角色已存在时返回 false
+func (r *AccessPolicyRepository) CreatePolicy(policy *models.PrinterAccessPolicy) (bool, error) {
+	query := `
+		INSERT INTO printer_access_policies (printer_id, role, created_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (printer_id, role) DO NOTHING
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, policy.PrinterID, policy.Role, nullIfEmpty(policy.CreatedBy)).
+		Scan(&policy.ID, &policy.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create access policy: %w", err)
+	}
+	return true, nil
+}
+
+// ListPolicies 获取访问策略列表，printerID 为空时返回全部
+func (r *AccessPolicyRepository) ListPolicies(printerID string) ([]*models.PrinterAccessPolicy, error) {
+	query := `SELECT id, printer_id, role, created_by, created_at FROM printer_access_policies`
+	args := []interface{}{}
+	if printerID != "" {
+		query += ` WHERE printer_id = $1`
+		args = append(args, printerID)
+	}
+	query += ` ORDER BY printer_id, role`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []*models.PrinterAccessPolicy
+	for rows.Next() {
+		policy := &models.PrinterAccessPolicy{}
+		var createdBy sql.NullString
+		if err := rows.Scan(&policy.ID, &policy.PrinterID, &policy.Role, &createdBy, &policy.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan access policy: %w", err)
+		}
+		policy.CreatedBy = createdBy.String
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// GetPolicy 根据ID获取访问策略
+func (r *AccessPolicyRepository) GetPolicy(id string) (*models.PrinterAccessPolicy, error) {
+	query := `SELECT id, printer_id, role, created_by, created_at FROM printer_access_policies WHERE id = $1`
+
+	policy := &models.PrinterAccessPolicy{}
+	var createdBy sql.NullString
+	err := r.db.QueryRow(query, id).Scan(&policy.ID, &policy.PrinterID, &policy.Role, &createdBy, &policy.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access policy: %w", err)
+	}
+	policy.CreatedBy = createdBy.String
+	return policy, nil
+}
+
+// DeletePolicy 删除访问策略
+func (r *AccessPolicyRepository) DeletePolicy(id string) error {
+	_, err := r.db.Exec(`DELETE FROM printer_access_policies WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete access policy: %w", err)
+	}
+	return nil
+}
+
+// GetAllowedRoles 获取打印机允许的角色，返回空列表表示打印机对所有人开放
+func (r *AccessPolicyRepository) GetAllowedRoles(printerID string) ([]string, error) {
+	rows, err := r.db.Query(`SELECT role FROM printer_access_policies WHERE printer_id = $1 ORDER BY role`, printerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allowed roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("failed to scan allowed role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// accessibleByRolesSQL 打印机可被指定角色使用的过滤条件（无策略或命中任一角色）
+// argIndex 为角色数组参数的占位序号
+func accessibleByRolesSQL(argIndex int) string {
+	return fmt.Sprintf(`(NOT EXISTS (SELECT 1 FROM printer_access_policies ap WHERE ap.printer_id = printers.id)
+		OR EXISTS (SELECT 1 FROM printer_access_policies ap WHERE ap.printer_id = printers.id AND ap.role = ANY($%d)))`, argIndex)
+}
+
+// rolesArg 角色数组参数
+func rolesArg(roles []string) interface{} {
+	if roles == nil {
+		roles = []string{}
+	}
+	return pq.Array(roles)
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// APIKeyRepository 用户 API Key 数据访问层
+type APIKeyRepository struct {
+	db *DB
+}
+
+// NewAPIKeyRepository 创建 API Key 数据访问层
+func NewAPIKeyRepository(db *DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// apiKeyColumns API Key 查询列（与 scanAPIKey 的扫描顺序保持一致）
+const apiKeyColumns = `k.id, k.user_id, k.name, k.key_prefix, k.scope, k.roles, k.expires_at, k.last_used_at, k.created_at`
+
+// scanAPIKey 扫描 API Key 列，extra 为追加在 apiKeyColumns 之后的列
+func scanAPIKey(row rowScanner, extra ...interface{}) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var roles pq.StringArray
+	dest := append([]interface{}{
+		&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Scope, &roles, &key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	key.Roles = []string(roles)
+	return key, nil
+}
+
+// CreateAPIKey 保存新的 API Key（只保存哈希）
+func (r *APIKeyRepository) CreateAPIKey(key *models.APIKey, keyHash string) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, key_prefix, key_hash, scope, roles, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, key.UserID, key.Name, key.Prefix, keyHash, key.Scope, pq.Array(key.Roles), key.ExpiresAt).
+		Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+// ListAPIKeysByUser 获取用户的全部 API Key（按创建时间倒序）
+func (r *APIKeyRepository) ListAPIKeysByUser(userID string) ([]*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys k WHERE k.user_id = $1 ORDER BY k.created_at DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// DeleteAPIKey 吊销用户的 API Key，Key 不存在或不属于该用户时返回 false
+func (r *APIKeyRepository) DeleteAPIKey(userID, id string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete api key: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// GetAPIKeyByHash 根据哈希获取 API Key 及其所属的有效用户，Key 不存在或用户已停用时返回 nil
+func (r *APIKeyRepository) GetAPIKeyByHash(keyHash string) (*models.APIKey, *models.User, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `, u.username, u.email, u.external_id, u.role
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND u.status = 'active'`
+
+	user := &models.User{}
+	var externalID sql.NullString
+	key, err := scanAPIKey(r.db.QueryRow(query, keyHash), &user.Username, &user.Email, &externalID, &user.Role)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	user.ID = key.UserID
+	if externalID.Valid {
+		user.ExternalID = &externalID.String
+	}
+	return key, user, nil
+}
+
+// TouchAPIKey 记录 API Key 的最近使用时间（调用方负责节流）
+func (r *APIKeyRepository) TouchAPIKey(id string, usedAt time.Time) error {
+	if _, err := r.db.Exec(`UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, usedAt); err != nil {
+		return fmt.Errorf("failed to update api key last used time: %w", err)
+	}
+	return nil
+}
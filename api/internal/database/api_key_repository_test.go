@@ -0,0 +1,65 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+func TestAPIKeyRepositoryLifecycle(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewAPIKeyRepository(db)
+
+	var userID string
+	if err := db.QueryRow(`INSERT INTO users (username, email, password_hash, role, external_id)
+		VALUES ('erp', 'erp@example.com', '', 'operator', 'ext-erp') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	expires := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Microsecond)
+	key := &models.APIKey{UserID: userID, Name: "nightly", Prefix: "fpk_abcd1234", Scope: models.APIKeyScopeSubmit,
+		Roles: []string{"print:submit"}, ExpiresAt: &expires}
+	hash := strings.Repeat("a", 64)
+	if err := repo.CreateAPIKey(key, hash); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	got, user, err := repo.GetAPIKeyByHash(hash)
+	if err != nil || got == nil {
+		t.Fatalf("GetAPIKeyByHash = %v, %v", got, err)
+	}
+	if got.ID != key.ID || got.Scope != models.APIKeyScopeSubmit || len(got.Roles) != 1 || got.LastUsedAt != nil {
+		t.Fatalf("key = %+v", got)
+	}
+	if got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) {
+		t.Fatalf("expires_at = %v, want %v", got.ExpiresAt, expires)
+	}
+	if user.ID != userID || user.Username != "erp" || user.ExternalID == nil || *user.ExternalID != "ext-erp" {
+		t.Fatalf("owner = %+v", user)
+	}
+
+	usedAt := time.Now().UTC().Truncate(time.Microsecond)
+	if err := repo.TouchAPIKey(key.ID, usedAt); err != nil {
+		t.Fatalf("TouchAPIKey: %v", err)
+	}
+	keys, err := repo.ListAPIKeysByUser(userID)
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("ListAPIKeysByUser = %d keys, %v", len(keys), err)
+	}
+	if keys[0].LastUsedAt == nil || !keys[0].LastUsedAt.Equal(usedAt) {
+		t.Fatalf("last_used_at = %v, want %v", keys[0].LastUsedAt, usedAt)
+	}
+
+	// 只能删除自己的 Key
+	if deleted, err := repo.DeleteAPIKey("00000000-0000-0000-0000-000000000000", key.ID); err != nil || deleted {
+		t.Fatalf("DeleteAPIKey by other user = %v, %v", deleted, err)
+	}
+	if deleted, err := repo.DeleteAPIKey(userID, key.ID); err != nil || !deleted {
+		t.Fatalf("DeleteAPIKey = %v, %v", deleted, err)
+	}
+	if got, _, err := repo.GetAPIKeyByHash(hash); err != nil || got != nil {
+		t.Fatalf("revoked key still resolves: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// AssetRepository 打印机 / Edge Node 资产信息数据访问层
+// 资产字段不在 printerColumns / Edge Node 查询列中，避免出现在 Edge Node 或第三方调用的响应里
+type AssetRepository struct {
+	db *DB
+}
+
+// NewAssetRepository 创建资产信息数据访问层
+func NewAssetRepository(db *DB) *AssetRepository {
+	return &AssetRepository{db: db}
+}
+
+// assetTables 资源类型对应的表
+var assetTables = map[string]string{
+	models.AssetResourcePrinter:  "printers",
+	models.AssetResourceEdgeNode: "edge_nodes",
+}
+
+// assetColumns 资产信息查询列（与 scanAsset 的扫描顺序保持一致）
+const assetColumns = `to_char(purchase_date, 'YYYY-MM-DD'), to_char(warranty_expires_on, 'YYYY-MM-DD'), asset_tag, notes`
+
+func assetTable(resourceType string) (string, error) {
+	table, ok := assetTables[resourceType]
+	if !ok {
+		return "", fmt.Errorf("unknown asset resource type %q", resourceType)
+	}
+	return table, nil
+}
+
+// scanAsset 扫描资产信息，所有字段为空时返回 nil
+func scanAsset(row rowScanner, extra ...interface{}) (*models.AssetInfo, error) {
+	var purchaseDate, warrantyExpiresOn, assetTag, notes sql.NullString
+	dest := append(extra, &purchaseDate, &warrantyExpiresOn, &assetTag, &notes)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	asset := &models.AssetInfo{}
+	empty := true
+	for _, field := range []struct {
+		value sql.NullString
+		dest  **string
+	}{
+		{purchaseDate, &asset.PurchaseDate},
+		{warrantyExpiresOn, &asset.WarrantyExpiresOn},
+		{assetTag, &asset.AssetTag},
+		{notes, &asset.Notes},
+	} {
+		if field.value.Valid {
+			value := field.value.String
+			*field.dest = &value
+			empty = false
+		}
+	}
+	if empty {
+		return nil, nil
+	}
+	return asset, nil
+}
+
+// GetAsset 获取单个打印机 / Edge Node 的资产信息，未设置任何字段时返回 nil
+func (r *AssetRepository) GetAsset(resourceType, id string) (*models.AssetInfo, error) {
+	table, err := assetTable(resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	asset, err := scanAsset(r.db.QueryRow(`SELECT `+assetColumns+` FROM `+table+` WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset info: %w", err)
+	}
+	return asset, nil
+}
+
+// GetAssets 批量获取资产信息（列表接口使用），只返回设置了资产字段的资源
+func (r *AssetRepository) GetAssets(resourceType string, ids []string) (map[string]*models.AssetInfo, error) {
+	assets := make(map[string]*models.AssetInfo)
+	if len(ids) == 0 {
+		return assets, nil
+	}
+	table, err := assetTable(resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`SELECT id::text, `+assetColumns+` FROM `+table+` WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list asset info: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		asset, err := scanAsset(rows, &id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan asset info: %w", err)
+		}
+		if asset != nil {
+			assets[id] = asset
+		}
+	}
+	return assets, rows.Err()
+}
+
+// AssetTagTaken 判断资产编号是否已被其他打印机或 Edge Node 使用（资产编号在两类设备间全局唯一）
+func (r *AssetRepository) AssetTagTaken(tag, resourceType, excludeID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM printers WHERE asset_tag = $1 AND NOT ($2 = 'printer' AND id::text = $3)
+			UNION ALL
+			SELECT 1 FROM edge_nodes WHERE asset_tag = $1 AND deleted_at IS NULL AND NOT ($2 = 'edge_node' AND id = $3)
+		)`
+
+	var taken bool
+	if err := r.db.QueryRow(query, tag, resourceType, excludeID).Scan(&taken); err != nil {
+		return false, fmt.Errorf("failed to check asset tag: %w", err)
+	}
+	return taken, nil
+}
+
+// SaveAsset 保存资产信息；备注发生变化时在同一事务中记录修改前的值
+func (r *AssetRepository) SaveAsset(resourceType, id string, asset *models.AssetInfo, editedBy string) error {
+	table, err := assetTable(resourceType)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previousNotes sql.NullString
+	if err := tx.QueryRow(`SELECT notes FROM `+table+` WHERE id = $1 FOR UPDATE`, id).Scan(&previousNotes); err != nil {
+		return fmt.Errorf("failed to lock asset row: %w", err)
+	}
+
+	query := `
+		UPDATE ` + table + ` SET purchase_date = $2::date, warranty_expires_on = $3::date, asset_tag = $4, notes = $5
+		WHERE id = $1`
+	if _, err := tx.Exec(query, id, asset.PurchaseDate, asset.WarrantyExpiresOn, asset.AssetTag, asset.Notes); err != nil {
+		return fmt.Errorf("failed to update asset info: %w", err)
+	}
+
+	newNotes := sql.NullString{}
+	if asset.Notes != nil {
+		newNotes = sql.NullString{String: *asset.Notes, Valid: true}
+	}
+	if newNotes != previousNotes {
+		_, err := tx.Exec(`
+			INSERT INTO asset_note_history (resource_type, resource_id, previous_notes, edited_by)
+			VALUES ($1, $2, $3, $4)`, resourceType, id, previousNotes, nullIfEmpty(editedBy))
+		if err != nil {
+			return fmt.Errorf("failed to record notes history: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListNoteHistory 获取备注修改历史（最新的在前）
+func (r *AssetRepository) ListNoteHistory(resourceType, id string) ([]*models.AssetNoteRevision, error) {
+	query := `
+		SELECT id, resource_type, resource_id, previous_notes, edited_by, edited_at
+		FROM asset_note_history
+		WHERE resource_type = $1 AND resource_id = $2
+		ORDER BY edited_at DESC, id DESC`
+
+	rows, err := r.db.Query(query, resourceType, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes history: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*models.AssetNoteRevision{}
+	for rows.Next() {
+		revision := &models.AssetNoteRevision{}
+		var previousNotes, editedBy sql.NullString
+		if err := rows.Scan(&revision.ID, &revision.ResourceType, &revision.ResourceID, &previousNotes, &editedBy, &revision.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notes history: %w", err)
+		}
+		if previousNotes.Valid {
+			revision.PreviousNotes = &previousNotes.String
+		}
+		revision.EditedBy = editedBy.String
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// ListWarrantyExpiring 获取 withinDays 天内保修到期的打印机和 Edge Node（按到期日排序）
+// includeExpired 为 true 时同时返回已过保修期的设备
+func (r *AssetRepository) ListWarrantyExpiring(withinDays int, includeExpired bool) ([]*models.MaintenanceItem, error) {
+	lowerBound := ` AND warranty_expires_on >= CURRENT_DATE`
+	if includeExpired {
+		lowerBound = ``
+	}
+	query := `
+		SELECT 'printer', id::text, COALESCE(display_name, name), warranty_expires_on - CURRENT_DATE, ` + assetColumns + `
+		FROM printers
+		WHERE warranty_expires_on <= CURRENT_DATE + $1::integer` + lowerBound + `
+		UNION ALL
+		SELECT 'edge_node', id, name, warranty_expires_on - CURRENT_DATE, ` + assetColumns + `
+		FROM edge_nodes
+		WHERE deleted_at IS NULL AND warranty_expires_on <= CURRENT_DATE + $1::integer` + lowerBound + `
+		ORDER BY 4, 1, 2`
+
+	rows, err := r.db.Query(query, withinDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiring warranties: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*models.MaintenanceItem{}
+	for rows.Next() {
+		item := &models.MaintenanceItem{}
+		asset, err := scanAsset(rows, &item.ResourceType, &item.ResourceID, &item.Name, &item.DaysRemaining)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan maintenance item: %w", err)
+		}
+		if asset != nil {
+			item.Asset = *asset
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// AuditLogRepository 审计日志数据访问层
+type AuditLogRepository struct {
+	db *DB
+}
+
+// NewAuditLogRepository 创建审计日志数据访问层
+func NewAuditLogRepository(db *DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// CreateAuditLog 写入审计日志
+func (r *AuditLogRepository) CreateAuditLog(entry *models.AuditLog) error {
+	query := `
+		INSERT INTO audit_logs (actor, actor_id, action, resource_type, resource_id, details, client_info)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query,
+		entry.Actor, entry.ActorID, entry.Action, entry.ResourceType, entry.ResourceID, entry.Details, clientInfoArg(entry.Client),
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return nil
+}
+
+// ListAuditLogs 获取审计日志列表
+func (r *AuditLogRepository) ListAuditLogs(offset, limit int, resourceType, resourceID string) ([]*models.AuditLog, int, error) {
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+	argIndex := 1
+
+	if resourceType != "" {
+		whereClause += fmt.Sprintf(" AND resource_type = $%d", argIndex)
+		args = append(args, resourceType)
+		argIndex++
+	}
+	if resourceID != "" {
+		whereClause += fmt.Sprintf(" AND resource_id = $%d", argIndex)
+		args = append(args, resourceID)
+		argIndex++
+	}
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM audit_logs %s", whereClause)
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, actor, actor_id, action, resource_type, resource_id, details, client_info, created_at
+		FROM audit_logs %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d`, whereClause, argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []*models.AuditLog{}
+	for rows.Next() {
+		entry := &models.AuditLog{}
+		var actor, actorID, resourceID, details sql.NullString
+		var clientInfo []byte
+		if err := rows.Scan(&entry.ID, &actor, &actorID, &entry.Action, &entry.ResourceType,
+			&resourceID, &details, &clientInfo, &entry.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		entry.Actor = actor.String
+		entry.ActorID = actorID.String
+		entry.ResourceID = resourceID.String
+		entry.Details = details.String
+		entry.Client = scanClientInfo(clientInfo)
+		logs = append(logs, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows error: %w", err)
+	}
+
+	return logs, total, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestCreateFirstAdminConcurrent 多个副本同时调用初始化接口时只创建一个管理员
+func TestCreateFirstAdminConcurrent(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewUserRepository(db)
+
+	exists, err := repo.AdminExists()
+	if err != nil {
+		t.Fatalf("AdminExists: %v", err)
+	}
+	if exists {
+		t.Fatal("fresh database already has an admin")
+	}
+
+	const callers = 8
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		created  int
+		rejected int
+	)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := &models.User{Username: fmt.Sprintf("admin%d", i), Email: fmt.Sprintf("admin%d@example.com", i)}
+			err := repo.CreateFirstAdmin(user, "correct-horse-battery")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, ErrAdminExists):
+				rejected++
+			default:
+				t.Errorf("CreateFirstAdmin: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if created != 1 || rejected != callers-1 {
+		t.Fatalf("created %d, rejected %d; want exactly one admin", created, rejected)
+	}
+	var admins int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE role = 'admin'`).Scan(&admins); err != nil {
+		t.Fatalf("count admins: %v", err)
+	}
+	if admins != 1 {
+		t.Fatalf("admins = %d, want 1", admins)
+	}
+}
+
+// TestCreateFirstAdminAfterDisabledAdmin 已禁用的管理员同样视为已初始化
+func TestCreateFirstAdminAfterDisabledAdmin(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, `INSERT INTO users (username, email, password_hash, role, status) VALUES ('old', 'old@example.com', '', 'admin', 'inactive')`)
+
+	err := NewUserRepository(db).CreateFirstAdmin(&models.User{Username: "new", Email: "new@example.com"}, "correct-horse-battery")
+	if !errors.Is(err, ErrAdminExists) {
+		t.Fatalf("CreateFirstAdmin error = %v, want ErrAdminExists", err)
+	}
+}
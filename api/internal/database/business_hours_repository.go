@@ -0,0 +1,179 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// BusinessHoursRepository 营业时间数据访问层
+type BusinessHoursRepository struct {
+	db *DB
+}
+
+// NewBusinessHoursRepository 创建营业时间数据访问层
+func NewBusinessHoursRepository(db *DB) *BusinessHoursRepository {
+	return &BusinessHoursRepository{db: db}
+}
+
+// businessHoursColumns 营业时间查询列（与 scanBusinessHours 的扫描顺序保持一致）
+const businessHoursColumns = `id, printer_id, edge_node_id, days, open_time, close_time, timezone, enabled, created_at, updated_at`
+
+// scanBusinessHours 扫描一行营业时间数据
+func scanBusinessHours(row rowScanner) (*models.BusinessHours, error) {
+	hours := &models.BusinessHours{}
+	var printerID, edgeNodeID sql.NullString
+	var days pq.Int64Array
+
+	err := row.Scan(&hours.ID, &printerID, &edgeNodeID, &days, &hours.OpenTime, &hours.CloseTime,
+		&hours.Timezone, &hours.Enabled, &hours.CreatedAt, &hours.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if printerID.Valid {
+		hours.PrinterID = &printerID.String
+	}
+	if edgeNodeID.Valid {
+		hours.EdgeNodeID = &edgeNodeID.String
+	}
+	hours.Days = make([]int, len(days))
+	for i, day := range days {
+		hours.Days[i] = int(day)
+	}
+	return hours, nil
+}
+
+// UpsertBusinessHours 创建或替换打印机/Edge Node 的营业时间（PrinterID 与 EdgeNodeID 只能设置其一）
+func (r *BusinessHoursRepository) UpsertBusinessHours(hours *models.BusinessHours) error {
+	var conflict string
+	switch {
+	case hours.PrinterID != nil && hours.EdgeNodeID == nil:
+		conflict = `(printer_id) WHERE printer_id IS NOT NULL`
+	case hours.EdgeNodeID != nil && hours.PrinterID == nil:
+		conflict = `(edge_node_id) WHERE edge_node_id IS NOT NULL`
+	default:
+		return fmt.Errorf("business hours must target exactly one printer or edge node")
+	}
+
+	query := `
+		INSERT INTO printer_business_hours (printer_id, edge_node_id, days, open_time, close_time, timezone, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT ` + conflict + ` DO UPDATE SET
+			days = EXCLUDED.days, open_time = EXCLUDED.open_time, close_time = EXCLUDED.close_time,
+			timezone = EXCLUDED.timezone, enabled = EXCLUDED.enabled, updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(query, hours.PrinterID, hours.EdgeNodeID, daysArg(hours.Days),
+		hours.OpenTime, hours.CloseTime, hours.Timezone, hours.Enabled).
+		Scan(&hours.ID, &hours.CreatedAt, &hours.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert business hours: %w", err)
+	}
+	return nil
+}
+
+// GetEdgeNodeBusinessHours 获取 Edge Node 的营业时间，不存在时返回 nil
+func (r *BusinessHoursRepository) GetEdgeNodeBusinessHours(edgeNodeID string) (*models.BusinessHours, error) {
+	query := `SELECT ` + businessHoursColumns + ` FROM printer_business_hours WHERE edge_node_id = $1`
+
+	hours, err := scanBusinessHours(r.db.QueryRow(query, edgeNodeID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get business hours: %w", err)
+	}
+	return hours, nil
+}
+
+// GetEffectiveBusinessHours 获取打印机生效的营业时间：打印机自身的设置优先于 Edge Node 的设置，都没有时返回 nil
+func (r *BusinessHoursRepository) GetEffectiveBusinessHours(printerID, edgeNodeID string) (*models.BusinessHours, error) {
+	query := `SELECT ` + businessHoursColumns + ` FROM printer_business_hours
+		WHERE printer_id = $1 OR edge_node_id = $2
+		ORDER BY printer_id IS NULL
+		LIMIT 1`
+
+	hours, err := scanBusinessHours(r.db.QueryRow(query, printerID, edgeNodeID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get effective business hours: %w", err)
+	}
+	return hours, nil
+}
+
+// GetEffectiveBusinessHoursForPrinters 批量获取打印机生效的营业时间（键为打印机ID，没有设置的打印机不在结果中）
+func (r *BusinessHoursRepository) GetEffectiveBusinessHoursForPrinters(printers []*models.Printer) (map[string]*models.BusinessHours, error) {
+	result := make(map[string]*models.BusinessHours)
+	if len(printers) == 0 {
+		return result, nil
+	}
+	printerIDs := make([]string, 0, len(printers))
+	nodeIDs := make([]string, 0, len(printers))
+	for _, printer := range printers {
+		printerIDs = append(printerIDs, printer.ID)
+		if printer.EdgeNodeID != "" {
+			nodeIDs = append(nodeIDs, printer.EdgeNodeID)
+		}
+	}
+
+	query := `SELECT ` + businessHoursColumns + ` FROM printer_business_hours
+		WHERE printer_id = ANY($1::uuid[]) OR edge_node_id = ANY($2)`
+	rows, err := r.db.Query(query, pq.Array(printerIDs), pq.Array(nodeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list business hours: %w", err)
+	}
+	defer rows.Close()
+
+	byPrinter := make(map[string]*models.BusinessHours)
+	byNode := make(map[string]*models.BusinessHours)
+	for rows.Next() {
+		hours, err := scanBusinessHours(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan business hours: %w", err)
+		}
+		if hours.PrinterID != nil {
+			byPrinter[*hours.PrinterID] = hours
+		} else if hours.EdgeNodeID != nil {
+			byNode[*hours.EdgeNodeID] = hours
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, printer := range printers {
+		if hours, ok := byPrinter[printer.ID]; ok {
+			result[printer.ID] = hours
+		} else if hours, ok := byNode[printer.EdgeNodeID]; ok {
+			result[printer.ID] = hours
+		}
+	}
+	return result, nil
+}
+
+// DeletePrinterBusinessHours 删除打印机自身的营业时间，返回是否存在
+func (r *BusinessHoursRepository) DeletePrinterBusinessHours(printerID string) (bool, error) {
+	return r.deleteBusinessHours(`printer_id = $1`, printerID)
+}
+
+// DeleteEdgeNodeBusinessHours 删除 Edge Node 的营业时间，返回是否存在
+func (r *BusinessHoursRepository) DeleteEdgeNodeBusinessHours(edgeNodeID string) (bool, error) {
+	return r.deleteBusinessHours(`edge_node_id = $1`, edgeNodeID)
+}
+
+func (r *BusinessHoursRepository) deleteBusinessHours(condition string, arg string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM printer_business_hours WHERE `+condition, arg)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete business hours: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestEffectiveBusinessHours 打印机自身的设置优先于 Edge Node 的设置；删除打印机设置后沿用节点设置
+func TestEffectiveBusinessHours(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewBusinessHoursRepository(db)
+	printerRepo := NewPrinterRepository(db)
+
+	ownID := createTestPrinter(t, db)
+	own, err := printerRepo.GetPrinterByID(ownID)
+	if err != nil {
+		t.Fatalf("get printer: %v", err)
+	}
+	inheritedID := createTestPrinter(t, db)
+	mustExec(t, db, `UPDATE printers SET edge_node_id = $1 WHERE id = $2`, own.EdgeNodeID, inheritedID)
+	inherited, err := printerRepo.GetPrinterByID(inheritedID)
+	if err != nil {
+		t.Fatalf("get printer: %v", err)
+	}
+
+	nodeHours := &models.BusinessHours{EdgeNodeID: &own.EdgeNodeID, Days: []int{1, 2, 3, 4, 5}, OpenTime: "08:00", CloseTime: "20:00", Timezone: "UTC", Enabled: true}
+	printerHours := &models.BusinessHours{PrinterID: &ownID, Days: []int{6}, OpenTime: "10:00", CloseTime: "14:00", Timezone: "Asia/Shanghai", Enabled: true}
+	for _, hours := range []*models.BusinessHours{nodeHours, printerHours} {
+		if err := repo.UpsertBusinessHours(hours); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+	if err := repo.UpsertBusinessHours(&models.BusinessHours{Days: []int{1}, OpenTime: "08:00", CloseTime: "20:00", Timezone: "UTC"}); err == nil {
+		t.Fatal("upsert without printer or edge node succeeded")
+	}
+
+	tests := []struct {
+		name    string
+		printer *models.Printer
+		want    string
+	}{
+		{"own setting", own, "10:00"},
+		{"inherited from node", inherited, "08:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hours, err := repo.GetEffectiveBusinessHours(tt.printer.ID, tt.printer.EdgeNodeID)
+			if err != nil || hours == nil || hours.OpenTime != tt.want {
+				t.Fatalf("GetEffectiveBusinessHours = %+v, %v; want open %s", hours, err, tt.want)
+			}
+		})
+	}
+
+	batch, err := repo.GetEffectiveBusinessHoursForPrinters([]*models.Printer{own, inherited})
+	if err != nil {
+		t.Fatalf("GetEffectiveBusinessHoursForPrinters: %v", err)
+	}
+	if batch[ownID].OpenTime != "10:00" || batch[inheritedID].OpenTime != "08:00" {
+		t.Fatalf("batch = own %+v, inherited %+v", batch[ownID], batch[inheritedID])
+	}
+
+	// 替换而不是新增
+	printerHours.OpenTime = "11:00"
+	if err := repo.UpsertBusinessHours(printerHours); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if hours, _ := repo.GetEffectiveBusinessHours(ownID, own.EdgeNodeID); hours.OpenTime != "11:00" || hours.Timezone != "Asia/Shanghai" {
+		t.Fatalf("replaced hours = %+v", hours)
+	}
+
+	if deleted, err := repo.DeletePrinterBusinessHours(ownID); err != nil || !deleted {
+		t.Fatalf("DeletePrinterBusinessHours = %v, %v", deleted, err)
+	}
+	if hours, _ := repo.GetEffectiveBusinessHours(ownID, own.EdgeNodeID); hours == nil || hours.OpenTime != "08:00" {
+		t.Fatalf("hours after deleting printer setting = %+v, want node setting", hours)
+	}
+	if deleted, _ := repo.DeleteEdgeNodeBusinessHours(own.EdgeNodeID); !deleted {
+		t.Fatal("DeleteEdgeNodeBusinessHours found nothing")
+	}
+	if hours, err := repo.GetEffectiveBusinessHours(ownID, own.EdgeNodeID); err != nil || hours != nil {
+		t.Fatalf("hours after deleting all settings = %+v, %v; want nil", hours, err)
+	}
+}
+
+// TestScheduledJobsClaimedWhenDue 排到营业时间之后的任务在计划时间前不认领、不列入待分发打印机
+func TestScheduledJobsClaimedWhenDue(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	printerID := createTestPrinter(t, db)
+
+	job := createTestJob(t, db, printerID, models.JobStatusQueued)
+	notBefore := time.Now().UTC().Add(time.Hour)
+	mustExec(t, db, `UPDATE print_jobs SET not_before = $1 WHERE id = $2`, notBefore, job.ID)
+
+	if printers, err := repo.ListPrintersWithDueQueuedJobs(time.Now()); err != nil || len(printers) != 0 {
+		t.Fatalf("due printers before not_before = %v, %v", printers, err)
+	}
+	if claimed, err := repo.ClaimQueuedJobs(printerID); err != nil || len(claimed) != 0 {
+		t.Fatalf("claimed before not_before = %d, %v", len(claimed), err)
+	}
+
+	mustExec(t, db, `UPDATE print_jobs SET not_before = $1 WHERE id = $2`, time.Now().UTC().Add(-time.Minute), job.ID)
+	if printers, err := repo.ListPrintersWithDueQueuedJobs(time.Now()); err != nil || len(printers) != 1 || printers[0] != printerID {
+		t.Fatalf("due printers after not_before = %v, %v", printers, err)
+	}
+	claimed, err := repo.ClaimQueuedJobs(printerID)
+	if err != nil || len(claimed) != 1 || claimed[0].ID != job.ID || claimed[0].Status != models.JobStatusDispatched {
+		t.Fatalf("claimed after not_before = %+v, %v", claimed, err)
+	}
+}
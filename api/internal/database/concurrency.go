@@ -0,0 +1,22 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict 乐观锁冲突：记录在读取后已被其他请求修改
+var ErrVersionConflict = errors.New("database: version conflict")
+
+// versionMismatch 带版本条件的 UPDATE 未匹配到行时区分原因：记录存在则为版本冲突，否则返回 notFound
+// existsQuery 以 $1 为主键返回一个布尔值
+func versionMismatch(db *DB, existsQuery string, id string, notFound string) error {
+	var exists bool
+	if err := db.QueryRow(existsQuery, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check record existence: %w", err)
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return errors.New(notFound)
+}
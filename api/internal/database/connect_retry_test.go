@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDialer 前 failures 次连接失败，之后成功；sleep 推进假时钟而不真正等待
+type fakeDialer struct {
+	now      time.Time
+	failures int
+	attempts int
+	waits    []time.Duration
+}
+
+func (d *fakeDialer) ping(ctx context.Context) error {
+	d.attempts++
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("ping without deadline")
+	}
+	if d.attempts <= d.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (d *fakeDialer) clock() time.Time { return d.now }
+
+func (d *fakeDialer) sleep(wait time.Duration) {
+	d.waits = append(d.waits, wait)
+	d.now = d.now.Add(wait)
+}
+
+func newFakeDialer(failures int) *fakeDialer {
+	return &fakeDialer{now: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), failures: failures}
+}
+
+func TestConnectWithRetryImmediate(t *testing.T) {
+	d := newFakeDialer(0)
+	if err := connectWithRetry(d.ping, time.Minute, d.clock, d.sleep); err != nil {
+		t.Fatalf("connectWithRetry: %v", err)
+	}
+	if d.attempts != 1 || len(d.waits) != 0 {
+		t.Fatalf("attempts %d, waits %v; want one attempt without waiting", d.attempts, d.waits)
+	}
+}
+
+// TestConnectWithRetryBackoff 每次失败后等待时间翻倍，上限 connectMaxBackoff
+func TestConnectWithRetryBackoff(t *testing.T) {
+	d := newFakeDialer(7)
+	if err := connectWithRetry(d.ping, 5*time.Minute, d.clock, d.sleep); err != nil {
+		t.Fatalf("connectWithRetry: %v", err)
+	}
+	want := []time.Duration{
+		500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+		connectMaxBackoff, connectMaxBackoff,
+	}
+	if d.attempts != 8 || len(d.waits) != len(want) {
+		t.Fatalf("attempts %d, waits %v; want 8 attempts and waits %v", d.attempts, d.waits, want)
+	}
+	for i := range want {
+		if d.waits[i] != want[i] {
+			t.Fatalf("wait %d = %s, want %s (all waits %v)", i, d.waits[i], want[i], d.waits)
+		}
+	}
+}
+
+// TestConnectWithRetryTimeout 超过 connect_timeout 后返回最后一次错误；最后一次等待截断到剩余时间，到达期限时再尝试一次
+func TestConnectWithRetryTimeout(t *testing.T) {
+	d := newFakeDialer(1000)
+	start := d.now
+	err := connectWithRetry(d.ping, 12*time.Second, d.clock, d.sleep)
+	if err == nil || !strings.Contains(err.Error(), "connection refused") || !strings.Contains(err.Error(), "6 attempts") {
+		t.Fatalf("connectWithRetry = %v, want failure after 6 attempts wrapping the ping error", err)
+	}
+	// 0.5 + 1 + 2 + 4 = 7.5 秒后只剩 4.5 秒，最后一次等待被截断
+	if last := d.waits[len(d.waits)-1]; last != 4500*time.Millisecond {
+		t.Fatalf("last wait = %s, want 4.5s (waits %v)", last, d.waits)
+	}
+	if elapsed := d.now.Sub(start); elapsed != 12*time.Second {
+		t.Fatalf("gave up after %s, want exactly the 12s timeout", elapsed)
+	}
+}
+
+func TestConnectWithRetryZeroTimeout(t *testing.T) {
+	d := newFakeDialer(1)
+	if err := connectWithRetry(d.ping, 0, d.clock, d.sleep); err == nil {
+		t.Fatal("connectWithRetry with no timeout retried instead of failing")
+	}
+	if d.attempts != 1 || len(d.waits) != 0 {
+		t.Fatalf("attempts %d, waits %v; want a single attempt", d.attempts, d.waits)
+	}
+}
@@ -1,40 +1,150 @@
 package database
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"time"
 
 	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/password"
 	"github.com/spf13/viper"
-	"golang.org/x/crypto/bcrypt"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // DB 数据库实例
 type DB struct {
 	*sql.DB
+	queryLog  *queryLogger
+	passwords *password.Hasher // 本地账户密码哈希，默认 bcrypt.DefaultCost，启动时按 auth.password_hash 设置
+	texts     TextLimiter      // 日志类文本字段的长度限制，未设置时不限制
+	lookups   LookupCache      // 打印机和节点查询缓存，未设置时不缓存
 }
 
-// New 创建数据库连接
+// TextLimiter 日志类文本字段（任务错误信息、时间线事件消息）写入前的长度限制（见 textlimit.Limiter）
+// 返回写入数据库的内容；spill 为 true 且完整内容已转存到文件存储时同时返回其对象键
+type TextLimiter interface {
+	Limit(jobID, field, text string, spill bool) (limited, spilledKey string)
+}
+
+// printJobBookkeepingColumns 不代表任务进展的字段：SLA 巡检写入考核结果不应重置卡住任务检测依赖的 updated_at
+const printJobBookkeepingColumns = `ARRAY['updated_at', 'sla_dispatch_breached', 'sla_start_breached', 'sla_complete_breached', 'sla_evaluated_at']`
+
+// 启动时连接重试的退避参数
+const (
+	connectInitialBackoff = 500 * time.Millisecond
+	connectMaxBackoff     = 10 * time.Second
+)
+
+// New 创建数据库连接，数据库尚未就绪时在 connect_timeout 内按指数退避重试
 func New(cfg *config.DatabaseConfig) (*DB, error) {
-	db, err := sql.Open("postgres", cfg.GetDSN())
+	connector, err := pq.NewConnector(cfg.GetDSN())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	queryLog := &queryLogger{slowThreshold: cfg.SlowQueryThreshold}
+	db := sql.OpenDB(&loggingConnector{Connector: connector, logger: queryLog})
+
+	// 设置连接池参数
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	// 测试连接
-	if err := db.Ping(); err != nil {
+	if err := connectWithRetry(db.PingContext, cfg.ConnectTimeout, time.Now, time.Sleep); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// 设置连接池参数
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
+	return &DB{DB: db, queryLog: queryLog, passwords: password.DefaultHasher()}, nil
+}
+
+// SetPasswordHasher 设置本地账户密码的哈希算法和参数（须在创建默认管理员之前调用）
+func (db *DB) SetPasswordHasher(hasher *password.Hasher) {
+	db.passwords = hasher
+}
+
+// SetTextLimiter 设置日志类文本字段的长度限制
+func (db *DB) SetTextLimiter(limiter TextLimiter) {
+	db.texts = limiter
+}
+
+// limitText 按文本字段长度限制处理 text，未设置限制时原样返回
+func (db *DB) limitText(jobID, field, text string, spill bool) (string, string) {
+	if db.texts == nil || text == "" {
+		return text, ""
+	}
+	return db.texts.Limit(jobID, field, text, spill)
+}
+
+// connectWithRetry 反复调用 ping 直到成功或超过 timeout；每次失败后等待时间翻倍，上限 connectMaxBackoff
+// now 和 sleep 由调用方提供（测试时使用假时钟）
+func connectWithRetry(ping func(ctx context.Context) error, timeout time.Duration, now func() time.Time, sleep func(time.Duration)) error {
+	deadline := now().Add(timeout)
+	backoff := connectInitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), connectMaxBackoff)
+		err := ping(ctx)
+		cancel()
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("Database connection established after %d attempts", attempt)
+			}
+			return nil
+		}
+
+		remaining := deadline.Sub(now())
+		if remaining <= 0 {
+			return fmt.Errorf("database not ready after %d attempts within %s: %w", attempt, timeout, err)
+		}
+		wait := backoff
+		if wait > remaining {
+			wait = remaining
+		}
+		log.Printf("Database not ready (attempt %d): %v; retrying in %s", attempt, err, wait)
+		sleep(wait)
+
+		backoff *= 2
+		if backoff > connectMaxBackoff {
+			backoff = connectMaxBackoff
+		}
+	}
+}
+
+// PoolStats 连接池状态（用于健康检查）
+type PoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMS     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
 
-	return &DB{db}, nil
+// PoolStats 获取当前连接池状态
+func (db *DB) PoolStats() PoolStats {
+	stats := db.Stats()
+	return PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMS:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
 }
 
 // Close 关闭数据库连接
@@ -159,7 +269,7 @@ func (db *DB) InitTables() error {
 		location VARCHAR(255),
 		
 		-- 能力信息 (JSON 格式)
-		capabilities JSONB,
+		capabilities JSONB NOT NULL DEFAULT '{}',
 		
 		-- 关联信息
 		edge_node_id VARCHAR(100) REFERENCES edge_nodes(id) ON DELETE CASCADE,
@@ -196,7 +306,7 @@ func (db *DB) InitTables() error {
 		status VARCHAR(20) NOT NULL DEFAULT 'pending',
 		
 		-- 关联信息
-		printer_id UUID REFERENCES printers(id) ON DELETE CASCADE,
+		printer_id UUID REFERENCES printers(id) ON DELETE RESTRICT,
 		user_id UUID REFERENCES users(id) ON DELETE SET NULL,
 		user_name VARCHAR(100),
 		
@@ -229,28 +339,576 @@ func (db *DB) InitTables() error {
 		return fmt.Errorf("failed to create print_jobs table: %w", err)
 	}
 
-	// 创建打印任务更新时间触发器
+	// 创建打印任务文件表（多文件任务）
+	printJobFileTableSQL := `
+	CREATE TABLE IF NOT EXISTS print_job_files (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		job_id UUID NOT NULL REFERENCES print_jobs(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		file_path VARCHAR(500),
+		file_url VARCHAR(1000),
+		storage_key VARCHAR(500),
+		file_size BIGINT DEFAULT 0,
+		page_count INTEGER DEFAULT 0,
+		copies INTEGER DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (job_id, position)
+	);`
+
+	if _, err := db.Exec(printJobFileTableSQL); err != nil {
+		return fmt.Errorf("failed to create print_job_files table: %w", err)
+	}
+
+	// 创建存储文件表（文件内容按保留期限单独清理，任务记录不受影响）
+	storedFileTableSQL := `
+	CREATE TABLE IF NOT EXISTS stored_files (
+		storage_key VARCHAR(500) PRIMARY KEY,
+		file_size BIGINT NOT NULL DEFAULT 0,
+		content_type VARCHAR(255),
+		job_id UUID REFERENCES print_jobs(id) ON DELETE SET NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		purged_at TIMESTAMP
+	);`
+
+	if _, err := db.Exec(storedFileTableSQL); err != nil {
+		return fmt.Errorf("failed to create stored_files table: %w", err)
+	}
+
+	// 创建打印任务更新时间触发器：只修改簿记字段（见 printJobBookkeepingColumns）时不更新 updated_at
 	printJobTriggerSQL := `
 	DROP TRIGGER IF EXISTS update_print_jobs_updated_at ON print_jobs;
 	CREATE TRIGGER update_print_jobs_updated_at
 		BEFORE UPDATE ON print_jobs
 		FOR EACH ROW
+		WHEN ((to_jsonb(NEW) - ` + printJobBookkeepingColumns + `) IS DISTINCT FROM (to_jsonb(OLD) - ` + printJobBookkeepingColumns + `))
 		EXECUTE FUNCTION update_updated_at_column();`
 
 	if _, err := db.Exec(printJobTriggerSQL); err != nil {
 		return fmt.Errorf("failed to create print_jobs update trigger: %w", err)
 	}
 
+	// 创建打印机驱动表
+	printerDriverTableSQL := `
+	CREATE TABLE IF NOT EXISTS printer_drivers (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		model_pattern VARCHAR(200) NOT NULL,
+		driver_name VARCHAR(200) NOT NULL,
+		ppd_file VARCHAR(500),
+		ppd_size BIGINT DEFAULT 0,
+		options JSONB,
+		priority INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	DROP TRIGGER IF EXISTS update_printer_drivers_updated_at ON printer_drivers;
+	CREATE TRIGGER update_printer_drivers_updated_at
+		BEFORE UPDATE ON printer_drivers
+		FOR EACH ROW
+		EXECUTE FUNCTION update_updated_at_column();`
+
+	if _, err := db.Exec(printerDriverTableSQL); err != nil {
+		return fmt.Errorf("failed to create printer_drivers table: %w", err)
+	}
+
+	// 创建审计日志表
+	auditLogTableSQL := `
+	CREATE TABLE IF NOT EXISTS audit_logs (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		actor VARCHAR(100),
+		actor_id VARCHAR(255),
+		action VARCHAR(100) NOT NULL,
+		resource_type VARCHAR(50) NOT NULL,
+		resource_id VARCHAR(100),
+		details TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := db.Exec(auditLogTableSQL); err != nil {
+		return fmt.Errorf("failed to create audit_logs table: %w", err)
+	}
+
+	// 创建诊断包请求表
+	diagnosticsTableSQL := `
+	CREATE TABLE IF NOT EXISTS diagnostics_requests (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		edge_node_id VARCHAR(100) NOT NULL REFERENCES edge_nodes(id) ON DELETE CASCADE,
+		status VARCHAR(20) NOT NULL DEFAULT 'requested',
+		requested_by VARCHAR(100),
+		file_name VARCHAR(255),
+		file_size BIGINT DEFAULT 0,
+		uploaded_at TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := db.Exec(diagnosticsTableSQL); err != nil {
+		return fmt.Errorf("failed to create diagnostics_requests table: %w", err)
+	}
+
+	// 创建打印机访问策略表
+	accessPolicyTableSQL := `
+	CREATE TABLE IF NOT EXISTS printer_access_policies (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		printer_id UUID NOT NULL REFERENCES printers(id) ON DELETE CASCADE,
+		role VARCHAR(100) NOT NULL,
+		created_by VARCHAR(100),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (printer_id, role)
+	);`
+
+	if _, err := db.Exec(accessPolicyTableSQL); err != nil {
+		return fmt.Errorf("failed to create printer_access_policies table: %w", err)
+	}
+
+	// 创建被拒绝的打印机表（按 名称 + Edge Node 屏蔽自动注册）
+	rejectedPrinterTableSQL := `
+	CREATE TABLE IF NOT EXISTS rejected_printers (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		name VARCHAR(100) NOT NULL,
+		edge_node_id VARCHAR(100) NOT NULL REFERENCES edge_nodes(id) ON DELETE CASCADE,
+		rejected_by VARCHAR(100),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (name, edge_node_id)
+	);`
+
+	if _, err := db.Exec(rejectedPrinterTableSQL); err != nil {
+		return fmt.Errorf("failed to create rejected_printers table: %w", err)
+	}
+
+	// 创建已删除打印机的墓碑表（有效期内阻止节点重新注册，到期自动清除）
+	printerTombstoneTableSQL := `
+	CREATE TABLE IF NOT EXISTS printer_tombstones (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		printer_id UUID NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		edge_node_id VARCHAR(100) NOT NULL REFERENCES edge_nodes(id) ON DELETE CASCADE,
+		deleted_by VARCHAR(100),
+		deleted_at TIMESTAMP NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		UNIQUE (name, edge_node_id)
+	);`
+
+	if _, err := db.Exec(printerTombstoneTableSQL); err != nil {
+		return fmt.Errorf("failed to create printer_tombstones table: %w", err)
+	}
+
+	// 创建打印机节能计划表（打印机级或 Edge Node 级，二者只能设置其一）
+	powerScheduleTableSQL := `
+	CREATE TABLE IF NOT EXISTS printer_power_schedules (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		printer_id UUID REFERENCES printers(id) ON DELETE CASCADE,
+		edge_node_id VARCHAR(100) REFERENCES edge_nodes(id) ON DELETE CASCADE,
+		days SMALLINT[] NOT NULL,
+		start_time VARCHAR(5) NOT NULL,
+		end_time VARCHAR(5) NOT NULL,
+		timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		CHECK ((printer_id IS NULL) <> (edge_node_id IS NULL))
+	);`
+
+	if _, err := db.Exec(powerScheduleTableSQL); err != nil {
+		return fmt.Errorf("failed to create printer_power_schedules table: %w", err)
+	}
+
+	// 创建营业时间表（打印机级或 Edge Node 级，二者只能设置其一）
+	businessHoursTableSQL := `
+	CREATE TABLE IF NOT EXISTS printer_business_hours (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		printer_id UUID REFERENCES printers(id) ON DELETE CASCADE,
+		edge_node_id VARCHAR(100) REFERENCES edge_nodes(id) ON DELETE CASCADE,
+		days SMALLINT[] NOT NULL,
+		open_time VARCHAR(5) NOT NULL,
+		close_time VARCHAR(5) NOT NULL,
+		timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		CHECK ((printer_id IS NULL) <> (edge_node_id IS NULL))
+	);`
+
+	if _, err := db.Exec(businessHoursTableSQL); err != nil {
+		return fmt.Errorf("failed to create printer_business_hours table: %w", err)
+	}
+
+	// 创建打印任务时间线事件表
+	printJobEventTableSQL := `
+	CREATE TABLE IF NOT EXISTS print_job_events (
+		id BIGSERIAL PRIMARY KEY,
+		job_id UUID NOT NULL REFERENCES print_jobs(id) ON DELETE CASCADE,
+		event_type VARCHAR(30) NOT NULL,
+		status VARCHAR(20),
+		progress INTEGER,
+		actor VARCHAR(100),
+		message TEXT,
+		details JSONB,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := db.Exec(printJobEventTableSQL); err != nil {
+		return fmt.Errorf("failed to create print_job_events table: %w", err)
+	}
+
+	// 创建打印机组表（提交到组的任务按 routing_strategy 自动选择组内打印机）
+	printerGroupTableSQL := `
+	CREATE TABLE IF NOT EXISTS printer_groups (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		name VARCHAR(100) NOT NULL UNIQUE,
+		description TEXT,
+		routing_strategy VARCHAR(20) NOT NULL DEFAULT 'least_queue',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS printer_group_members (
+		group_id UUID NOT NULL REFERENCES printer_groups(id) ON DELETE CASCADE,
+		printer_id UUID NOT NULL REFERENCES printers(id) ON DELETE CASCADE,
+		PRIMARY KEY (group_id, printer_id)
+	);`
+
+	if _, err := db.Exec(printerGroupTableSQL); err != nil {
+		return fmt.Errorf("failed to create printer_groups table: %w", err)
+	}
+
+	// 创建资产备注修改历史表（保存修改前的备注）
+	assetNoteHistoryTableSQL := `
+	CREATE TABLE IF NOT EXISTS asset_note_history (
+		id BIGSERIAL PRIMARY KEY,
+		resource_type VARCHAR(20) NOT NULL,
+		resource_id VARCHAR(100) NOT NULL,
+		previous_notes TEXT,
+		edited_by VARCHAR(100),
+		edited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := db.Exec(assetNoteHistoryTableSQL); err != nil {
+		return fmt.Errorf("failed to create asset_note_history table: %w", err)
+	}
+
+	// 创建打印任务幂等键表（重试创建请求时返回首次创建的任务）
+	idempotencyKeyTableSQL := `
+	CREATE TABLE IF NOT EXISTS print_job_idempotency_keys (
+		scope VARCHAR(100) NOT NULL,
+		idempotency_key VARCHAR(255) NOT NULL,
+		job_id UUID NOT NULL REFERENCES print_jobs(id) ON DELETE CASCADE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (scope, idempotency_key)
+	);`
+
+	if _, err := db.Exec(idempotencyKeyTableSQL); err != nil {
+		return fmt.Errorf("failed to create print_job_idempotency_keys table: %w", err)
+	}
+
+	// 创建组织级打印策略表（条件和效果以 JSON 保存，创建任务时按优先级评估）
+	printPolicyTableSQL := `
+	CREATE TABLE IF NOT EXISTS print_policies (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		name VARCHAR(100) NOT NULL UNIQUE,
+		description TEXT,
+		priority INTEGER NOT NULL DEFAULT 0,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		conditions JSONB NOT NULL DEFAULT '{}',
+		effects JSONB NOT NULL DEFAULT '{}',
+		created_by VARCHAR(100),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := db.Exec(printPolicyTableSQL); err != nil {
+		return fmt.Errorf("failed to create print_policies table: %w", err)
+	}
+
+	// 创建用户 API Key 表（只保存哈希，明文仅在创建时返回一次）
+	apiKeyTableSQL := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(100) NOT NULL,
+		key_prefix VARCHAR(20) NOT NULL,
+		key_hash CHAR(64) NOT NULL UNIQUE,
+		scope VARCHAR(20) NOT NULL DEFAULT 'full',
+		roles TEXT[] NOT NULL DEFAULT '{}',
+		expires_at TIMESTAMP,
+		last_used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);`
+
+	if _, err := db.Exec(apiKeyTableSQL); err != nil {
+		return fmt.Errorf("failed to create api_keys table: %w", err)
+	}
+
+	// 创建 Edge Node 客户端证书凭据表（subject 为证书 CN 或 SAN，禁用即吊销）
+	edgeNodeCredentialTableSQL := `
+	CREATE TABLE IF NOT EXISTS edge_node_credentials (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		node_id VARCHAR(100) NOT NULL REFERENCES edge_nodes(id) ON DELETE CASCADE,
+		subject VARCHAR(255) NOT NULL,
+		description VARCHAR(255),
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		created_by VARCHAR(100),
+		last_used_at TIMESTAMP,
+		disabled_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_edge_node_credentials_node_id ON edge_node_credentials(node_id);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_edge_node_credentials_subject_enabled ON edge_node_credentials(subject) WHERE enabled;`
+
+	if _, err := db.Exec(edgeNodeCredentialTableSQL); err != nil {
+		return fmt.Errorf("failed to create edge_node_credentials table: %w", err)
+	}
+
+	// 创建 Edge Node 心跳资源采样表（容量规划的历史曲线，按 retention.node_metrics_days 清理）
+	edgeNodeMetricsTableSQL := `
+	CREATE TABLE IF NOT EXISTS edge_node_metrics (
+		id BIGSERIAL PRIMARY KEY,
+		node_id VARCHAR(100) NOT NULL REFERENCES edge_nodes(id) ON DELETE CASCADE,
+		ts TIMESTAMP NOT NULL,
+		cpu_usage DOUBLE PRECISION NOT NULL,
+		memory_usage DOUBLE PRECISION NOT NULL,
+		disk_usage DOUBLE PRECISION NOT NULL,
+		latency INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_edge_node_metrics_node_ts ON edge_node_metrics(node_id, ts);
+	CREATE INDEX IF NOT EXISTS idx_edge_node_metrics_ts ON edge_node_metrics(ts);`
+
+	if _, err := db.Exec(edgeNodeMetricsTableSQL); err != nil {
+		return fmt.Errorf("failed to create edge_node_metrics table: %w", err)
+	}
+
+	// 创建已完成任务归档导出表（files 记录已写入的文件，last_job_* 为继续导出的游标）
+	exportRunTableSQL := `
+	CREATE TABLE IF NOT EXISTS export_runs (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		format VARCHAR(20) NOT NULL DEFAULT 'jsonl',
+		trigger VARCHAR(20) NOT NULL DEFAULT 'manual',
+		from_date DATE NOT NULL,
+		to_date DATE NOT NULL,
+		requested_by VARCHAR(100),
+		row_count BIGINT NOT NULL DEFAULT 0,
+		files JSONB NOT NULL DEFAULT '[]',
+		last_job_id UUID,
+		last_job_created_at TIMESTAMP,
+		error TEXT,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		started_at TIMESTAMP,
+		finished_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_export_runs_status ON export_runs(status, created_at);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_export_runs_scheduled_date ON export_runs(from_date) WHERE trigger = 'scheduled';`
+
+	if _, err := db.Exec(exportRunTableSQL); err != nil {
+		return fmt.Errorf("failed to create export_runs table: %w", err)
+	}
+
+	// 创建共享文件夹已处理文件台账（同一文件按目录、文件名、大小和修改时间识别，防止重启后重复提交）
+	hotFolderFileTableSQL := `
+	CREATE TABLE IF NOT EXISTS hot_folder_files (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		folder VARCHAR(500) NOT NULL,
+		file_name VARCHAR(500) NOT NULL,
+		file_size BIGINT NOT NULL,
+		modified_at TIMESTAMP NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'processing',
+		job_id UUID,
+		error TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_hot_folder_files_file ON hot_folder_files(folder, file_name, file_size, modified_at);`
+
+	if _, err := db.Exec(hotFolderFileTableSQL); err != nil {
+		return fmt.Errorf("failed to create hot_folder_files table: %w", err)
+	}
+
+	// 增量字段迁移（兼容已存在的表）
+	migrationsSQL := []string{
+		// OAuth2 登录用户的 sub，本地账户为 NULL
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS price_per_page_mono DECIMAL(10, 4);",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS price_per_page_color DECIMAL(10, 4);",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS duplex_discount DECIMAL(5, 4);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS cost DECIMAL(12, 4);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS performed_by VARCHAR(100);",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS last_known_status VARCHAR(20);",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_on_completion BOOLEAN NOT NULL DEFAULT FALSE;",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_on_failure BOOLEAN NOT NULL DEFAULT TRUE;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS notification_sent_at TIMESTAMP;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS max_concurrent_jobs INTEGER;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS storage_key VARCHAR(500);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS batch_id UUID;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS page_count_source VARCHAR(10) NOT NULL DEFAULT 'client';",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS slug VARCHAR(100);",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS supplies JSONB;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS approval_status VARCHAR(20) NOT NULL DEFAULT 'approved';",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS power_state VARCHAR(10);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS metadata JSONB;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS max_copies INTEGER;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS printer_group_id UUID REFERENCES printer_groups(id) ON DELETE SET NULL;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS routing_strategy VARCHAR(20);",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS purchase_date DATE;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS warranty_expires_on DATE;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS asset_tag VARCHAR(100);",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS notes TEXT;",
+		"ALTER TABLE edge_nodes ADD COLUMN IF NOT EXISTS purchase_date DATE;",
+		"ALTER TABLE edge_nodes ADD COLUMN IF NOT EXISTS warranty_expires_on DATE;",
+		"ALTER TABLE edge_nodes ADD COLUMN IF NOT EXISTS asset_tag VARCHAR(100);",
+		"ALTER TABLE edge_nodes ADD COLUMN IF NOT EXISTS notes TEXT;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS printer_name VARCHAR(100);",
+		"ALTER TABLE edge_nodes ADD COLUMN IF NOT EXISTS drain_started_at TIMESTAMP;",
+		"ALTER TABLE edge_nodes ADD COLUMN IF NOT EXISTS drain_deadline TIMESTAMP;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS file_purged BOOLEAN NOT NULL DEFAULT FALSE;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS file_purged_at TIMESTAMP;",
+		// 乐观锁版本号；edge_nodes.version 已用于节点软件版本，统一命名为 row_version
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS row_version INTEGER NOT NULL DEFAULT 1;",
+		"ALTER TABLE edge_nodes ADD COLUMN IF NOT EXISTS row_version INTEGER NOT NULL DEFAULT 1;",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS row_version INTEGER NOT NULL DEFAULT 1;",
+		// 分发前的格式转换：原文件仍为 storage_key，转换结果单独记录
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS source_format VARCHAR(20);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS converted_storage_key VARCHAR(500);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS converted_format VARCHAR(20);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS converted_file_size BIGINT;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS media_type VARCHAR(50);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS resolution VARCHAR(20);",
+		// SLA 考核：dispatched_at 记录下发时间，sla_* 为超时标记，sla_evaluated_at 为任务结束后的最终评估时间
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS dispatched_at TIMESTAMP;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS sla_dispatch_breached BOOLEAN NOT NULL DEFAULT FALSE;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS sla_start_breached BOOLEAN NOT NULL DEFAULT FALSE;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS sla_complete_breached BOOLEAN NOT NULL DEFAULT FALSE;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS sla_evaluated_at TIMESTAMP;",
+		// 添加列时已存在的历史任务为 FALSE（缺少下发时间，不参与考核），之后创建的任务为 TRUE
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS sla_tracked BOOLEAN NOT NULL DEFAULT FALSE;",
+		"ALTER TABLE print_jobs ALTER COLUMN sla_tracked SET DEFAULT TRUE;",
+		"ALTER TABLE printer_groups ADD COLUMN IF NOT EXISTS sla_time_to_dispatch INTEGER;",
+		"ALTER TABLE printer_groups ADD COLUMN IF NOT EXISTS sla_time_to_start INTEGER;",
+		"ALTER TABLE printer_groups ADD COLUMN IF NOT EXISTS sla_time_to_complete INTEGER;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS dispatch_error VARCHAR(30);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS applied_policies TEXT[];",
+		// 疑似 node_id 冲突（多台机器使用同一 node_id 连接），管理员确认后清除
+		"ALTER TABLE edge_nodes ADD COLUMN IF NOT EXISTS conflict_suspected_at TIMESTAMP;",
+		"ALTER TABLE edge_nodes ADD COLUMN IF NOT EXISTS conflict_remote_addrs TEXT[];",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS progress INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS reserved_by VARCHAR(255);",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS reserved_at TIMESTAMP;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS reserved_until TIMESTAMP;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS not_before TIMESTAMP;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS client_info JSONB;",
+		"ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS client_info JSONB;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS backup_printer_id UUID REFERENCES printers(id) ON DELETE SET NULL;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS needs_attention BOOLEAN NOT NULL DEFAULT FALSE;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS attention_reason TEXT;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS attention_at TIMESTAMP;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS breaker_state VARCHAR(20) NOT NULL DEFAULT 'closed';",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS breaker_failures INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS breaker_window_started_at TIMESTAMP;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS breaker_suspended_at TIMESTAMP;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS breaker_resume_at TIMESTAMP;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS breaker_reason TEXT;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS queue_paused BOOLEAN NOT NULL DEFAULT FALSE;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS queue_paused_by VARCHAR(100);",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS queue_paused_at TIMESTAMP;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS queue_pause_reason VARCHAR(255);",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS queue_auto_resume_at TIMESTAMP;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS pages_printed INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS failed_over_from UUID;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS page_range VARCHAR(100);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS error_message_key VARCHAR(500);",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS display_name VARCHAR(200);",
+		// queue_length 为两者的较大值：节点上报和云端核对分别写入各自的字段，互不覆盖
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS edge_queue_length INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE printers ADD COLUMN IF NOT EXISTS cloud_queue_length INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS source_name VARCHAR(255);",
+		// 记录来源之前的任务回填为 unknown；新任务由提交入口写入来源
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'unknown';",
+		// 历史打印机的能力可能为 NULL，回填为空对象后不再允许 NULL
+		"UPDATE printers SET capabilities = '{}' WHERE capabilities IS NULL OR capabilities = 'null'::jsonb;",
+		"ALTER TABLE printers ALTER COLUMN capabilities SET DEFAULT '{}';",
+		"ALTER TABLE printers ALTER COLUMN capabilities SET NOT NULL;",
+		// 删除打印机不再级联删除历史任务；NOT VALID 避免每次启动全表校验
+		"ALTER TABLE print_jobs DROP CONSTRAINT IF EXISTS print_jobs_printer_id_fkey;",
+		"ALTER TABLE print_jobs ADD CONSTRAINT print_jobs_printer_id_fkey FOREIGN KEY (printer_id) REFERENCES printers(id) ON DELETE RESTRICT NOT VALID;",
+		// 最近一次节点任务更新的服务端接收时间，用于判断乱序（updated_at 会被触发器和其他写入改为数据库时间）
+		"ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS last_edge_event_at TIMESTAMP;",
+		// 状态检查约束，取值与 models 中的状态常量保持一致；NOT VALID 避免历史脏数据阻塞启动
+		"ALTER TABLE print_jobs DROP CONSTRAINT IF EXISTS chk_print_jobs_status;",
+		"ALTER TABLE print_jobs ADD CONSTRAINT chk_print_jobs_status CHECK (status IN (" + models.StatusSQLList(models.AllJobStatuses) + ")) NOT VALID;",
+		"ALTER TABLE print_jobs DROP CONSTRAINT IF EXISTS chk_print_jobs_source;",
+		"ALTER TABLE print_jobs ADD CONSTRAINT chk_print_jobs_source CHECK (source IN (" + models.StatusSQLList(models.AllJobSources) + ")) NOT VALID;",
+		"ALTER TABLE printers DROP CONSTRAINT IF EXISTS chk_printers_status;",
+		"ALTER TABLE printers ADD CONSTRAINT chk_printers_status CHECK (status IN (" + models.StatusSQLList(models.AllPrinterStatuses) + ")) NOT VALID;",
+		"ALTER TABLE printers DROP CONSTRAINT IF EXISTS chk_printers_last_known_status;",
+		"ALTER TABLE printers ADD CONSTRAINT chk_printers_last_known_status CHECK (last_known_status IS NULL OR last_known_status IN (" + models.StatusSQLList(models.AllPrinterStatuses) + ")) NOT VALID;",
+		"ALTER TABLE edge_nodes DROP CONSTRAINT IF EXISTS chk_edge_nodes_status;",
+		"ALTER TABLE edge_nodes ADD CONSTRAINT chk_edge_nodes_status CHECK (status IN (" + models.StatusSQLList(models.AllNodeStatuses) + ")) NOT VALID;",
+	}
+
+	for _, migrationSQL := range migrationsSQL {
+		if _, err := db.Exec(migrationSQL); err != nil {
+			return fmt.Errorf("failed to apply migration: %w", err)
+		}
+	}
+
+	// 为历史打印机回填 slug（需在创建唯一索引之前）
+	if backfilled, err := NewPrinterRepository(db).BackfillSlugs(); err != nil {
+		return fmt.Errorf("failed to backfill printer slugs: %w", err)
+	} else if backfilled > 0 {
+		log.Printf("Backfilled slugs for %d printers", backfilled)
+	}
+
+	// 为历史任务回填存储文件记录，之后的上传和任务创建会自动记录
+	if backfilled, err := NewStoredFileRepository(db).BackfillStoredFiles(); err != nil {
+		return fmt.Errorf("failed to backfill stored files: %w", err)
+	} else if backfilled > 0 {
+		log.Printf("Backfilled %d stored file records from existing print jobs", backfilled)
+	}
+
 	// 创建索引
 	indexesSQL := []string{
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_external_id ON users(external_id) WHERE external_id IS NOT NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_stored_files_created_at ON stored_files(created_at) WHERE purged_at IS NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_print_jobs_storage_key ON print_jobs(storage_key);",
+		"CREATE INDEX IF NOT EXISTS idx_print_jobs_converted_storage_key ON print_jobs(converted_storage_key) WHERE converted_storage_key IS NOT NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_print_job_files_storage_key ON print_job_files(storage_key);",
+		"CREATE INDEX IF NOT EXISTS idx_print_job_events_job_id ON print_job_events(job_id, created_at, id);",
 		"CREATE INDEX IF NOT EXISTS idx_edge_nodes_status ON edge_nodes(status);",
 		"CREATE INDEX IF NOT EXISTS idx_edge_nodes_last_heartbeat ON edge_nodes(last_heartbeat);",
 		"CREATE INDEX IF NOT EXISTS idx_printers_edge_node_id ON printers(edge_node_id);",
 		"CREATE INDEX IF NOT EXISTS idx_printers_status ON printers(status);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_printers_slug ON printers(slug);",
+		"CREATE INDEX IF NOT EXISTS idx_printers_breaker_resume_at ON printers(breaker_resume_at) WHERE breaker_state = 'suspended';",
 		"CREATE INDEX IF NOT EXISTS idx_print_jobs_status ON print_jobs(status);",
 		"CREATE INDEX IF NOT EXISTS idx_print_jobs_printer_id ON print_jobs(printer_id);",
 		"CREATE INDEX IF NOT EXISTS idx_print_jobs_user_id ON print_jobs(user_id);",
 		"CREATE INDEX IF NOT EXISTS idx_print_jobs_created_at ON print_jobs(created_at);",
+		"CREATE INDEX IF NOT EXISTS idx_print_jobs_created_at_id ON print_jobs(created_at, id);",
+		"CREATE INDEX IF NOT EXISTS idx_print_jobs_source_created_at ON print_jobs(source, created_at);",
+		"CREATE INDEX IF NOT EXISTS idx_print_jobs_printer_status ON print_jobs(printer_id, status);",
+		"CREATE INDEX IF NOT EXISTS idx_print_jobs_batch_id ON print_jobs(batch_id) WHERE batch_id IS NOT NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_print_jobs_metadata ON print_jobs USING GIN (metadata jsonb_path_ops);",
+		"CREATE INDEX IF NOT EXISTS idx_print_jobs_group_user ON print_jobs(printer_group_id, user_name, created_at) WHERE printer_group_id IS NOT NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_print_jobs_sla_pending ON print_jobs(created_at) WHERE sla_tracked AND sla_evaluated_at IS NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_print_jobs_sla_evaluated_at ON print_jobs(sla_evaluated_at) WHERE sla_evaluated_at IS NOT NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_printer_group_members_printer_id ON printer_group_members(printer_id);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_printers_asset_tag ON printers(asset_tag) WHERE asset_tag IS NOT NULL;",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_edge_nodes_asset_tag ON edge_nodes(asset_tag) WHERE asset_tag IS NOT NULL AND deleted_at IS NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_printers_warranty ON printers(warranty_expires_on) WHERE warranty_expires_on IS NOT NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_edge_nodes_warranty ON edge_nodes(warranty_expires_on) WHERE warranty_expires_on IS NOT NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_asset_note_history_resource ON asset_note_history(resource_type, resource_id, edited_at);",
+		"CREATE INDEX IF NOT EXISTS idx_audit_logs_resource ON audit_logs(resource_type, resource_id);",
+		"CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);",
+		"CREATE INDEX IF NOT EXISTS idx_diagnostics_requests_edge_node_id ON diagnostics_requests(edge_node_id);",
+		"CREATE INDEX IF NOT EXISTS idx_diagnostics_requests_expires_at ON diagnostics_requests(status, expires_at);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_printer_power_schedules_printer_id ON printer_power_schedules(printer_id) WHERE printer_id IS NOT NULL;",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_printer_power_schedules_edge_node_id ON printer_power_schedules(edge_node_id) WHERE edge_node_id IS NOT NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_printers_reserved_until ON printers(reserved_until) WHERE reserved_by IS NOT NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_printer_tombstones_expires_at ON printer_tombstones(expires_at);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_printer_business_hours_printer_id ON printer_business_hours(printer_id) WHERE printer_id IS NOT NULL;",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_printer_business_hours_edge_node_id ON printer_business_hours(edge_node_id) WHERE edge_node_id IS NOT NULL;",
 	}
 
 	for _, indexSQL := range indexesSQL {
@@ -263,16 +921,15 @@ func (db *DB) InitTables() error {
 	return nil
 }
 
-// CreateDefaultAdmin 创建默认管理员账户
+// CreateDefaultAdmin 创建默认管理员账户（启动时调用，已有管理员时跳过）
 func (db *DB) CreateDefaultAdmin() error {
 	// 检查是否已存在管理员账户
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE role = 'admin'").Scan(&count)
+	exists, err := db.adminExists(db.DB)
 	if err != nil {
-		return fmt.Errorf("failed to check admin users: %w", err)
+		return err
 	}
 
-	if count > 0 {
+	if exists {
 		log.Println("Admin user already exists, skipping creation")
 		return nil
 	}
@@ -281,7 +938,7 @@ func (db *DB) CreateDefaultAdmin() error {
 	createDefault := viper.GetString("create_default_admin")
 	if createDefault != "true" {
 		log.Println("No admin users found, but CREATE_DEFAULT_ADMIN is not set to 'true'")
-		log.Println("To create a default admin, set CREATE_DEFAULT_ADMIN=true and restart")
+		log.Println("To create a default admin, set CREATE_DEFAULT_ADMIN=true and restart, or use POST /api/v1/bootstrap/admin")
 		return nil
 	}
 
@@ -289,20 +946,15 @@ func (db *DB) CreateDefaultAdmin() error {
 	adminPassword := viper.GetString("default_admin_password")
 	if adminPassword == "" {
 		adminPassword = generateRandomPassword(16)
-		log.Printf("Generated random admin password: %s", adminPassword)
-		log.Println("IMPORTANT: Save this password immediately! It will not be shown again.")
-	}
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(adminPassword), bcrypt.DefaultCost)
-	if err != nil {
-		return fmt.Errorf("failed to hash admin password: %w", err)
 	}
 
-	defaultAdminSQL := `
-	INSERT INTO users (username, email, password_hash, role, status)
-	VALUES ('admin', 'admin@flyprint.local', $1, 'admin', 'active')`
-
-	if _, err := db.Exec(defaultAdminSQL, string(hashedPassword)); err != nil {
+	admin := &models.User{Username: "admin", Email: "admin@flyprint.local"}
+	if err := db.createFirstAdmin(admin, adminPassword); err != nil {
+		if errors.Is(err, ErrAdminExists) {
+			// 其他副本同时启动并已创建管理员
+			log.Println("Admin user already exists, skipping creation")
+			return nil
+		}
 		return fmt.Errorf("failed to create default admin: %w", err)
 	}
 
@@ -324,6 +976,66 @@ func (db *DB) CreateDefaultAdmin() error {
 	return nil
 }
 
+// adminBootstrapLockKey 创建首个管理员时持有的 PostgreSQL advisory lock
+const adminBootstrapLockKey = 0x666c7961646d // "flyadm"
+
+// ErrAdminExists 已存在管理员，不能再通过初始化流程创建
+var ErrAdminExists = errors.New("database: admin already exists")
+
+// queryRower DB 与事务的公共查询接口
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// adminExists 是否已存在管理员账户（不论是否启用）
+func (db *DB) adminExists(q queryRower) (bool, error) {
+	var exists bool
+	if err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE role = 'admin')`).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check admin users: %w", err)
+	}
+	return exists, nil
+}
+
+// createFirstAdmin 在没有任何管理员时创建管理员，已存在时返回 ErrAdminExists
+// 检查和插入在持有 advisory lock 的事务中执行，多个副本同时启动或并发调用初始化接口时只会创建一个
+func (db *DB) createFirstAdmin(user *models.User, password string) error {
+	hashedPassword, err := db.passwords.Hash(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash admin password: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, adminBootstrapLockKey); err != nil {
+		return fmt.Errorf("failed to acquire admin bootstrap lock: %w", err)
+	}
+	exists, err := db.adminExists(tx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrAdminExists
+	}
+
+	query := `
+		INSERT INTO users (username, email, password_hash, role, status)
+		VALUES ($1, $2, $3, 'admin', 'active')
+		RETURNING id, role, status, created_at, updated_at`
+	if err := tx.QueryRow(query, user.Username, user.Email, hashedPassword).
+		Scan(&user.ID, &user.Role, &user.Status, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to insert admin: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
 // generateRandomPassword 生成随机密码
 func generateRandomPassword(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*"
@@ -333,4 +1045,12 @@ func generateRandomPassword(length int) string {
 		password[i] = charset[num.Int64()]
 	}
 	return string(password)
+}
+
+// nullIfEmpty 空字符串写入数据库时转为 NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
 }
\ No newline at end of file
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// DiagnosticsRepository 诊断包请求数据访问层
+type DiagnosticsRepository struct {
+	db *DB
+}
+
+// NewDiagnosticsRepository 创建诊断包请求数据访问层
+func NewDiagnosticsRepository(db *DB) *DiagnosticsRepository {
+	return &DiagnosticsRepository{db: db}
+}
+
+const diagnosticsColumns = `id, edge_node_id, status, requested_by, file_name, file_size, uploaded_at, expires_at, created_at`
+
+// scanDiagnosticsRequest 扫描一行诊断包请求数据
+func scanDiagnosticsRequest(row rowScanner) (*models.DiagnosticsRequest, error) {
+	req := &models.DiagnosticsRequest{}
+	var requestedBy, fileName sql.NullString
+	var fileSize sql.NullInt64
+	var uploadedAt sql.NullTime
+
+	err := row.Scan(&req.ID, &req.EdgeNodeID, &req.Status, &requestedBy, &fileName, &fileSize,
+		&uploadedAt, &req.ExpiresAt, &req.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	req.RequestedBy = requestedBy.String
+	req.FileName = fileName.String
+	req.FileSize = fileSize.Int64
+	if uploadedAt.Valid {
+		req.UploadedAt = &uploadedAt.Time
+	}
+
+	return req, nil
+}
+
+// CreateDiagnosticsRequest 创建诊断包请求
+func (r *DiagnosticsRepository) CreateDiagnosticsRequest(req *models.DiagnosticsRequest) error {
+	query := `
+		INSERT INTO diagnostics_requests (edge_node_id, status, requested_by, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, req.EdgeNodeID, req.Status, nullIfEmpty(req.RequestedBy), req.ExpiresAt).
+		Scan(&req.ID, &req.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create diagnostics request: %w", err)
+	}
+
+	return nil
+}
+
+// GetDiagnosticsRequest 根据ID获取诊断包请求，不存在时返回 nil
+func (r *DiagnosticsRepository) GetDiagnosticsRequest(id string) (*models.DiagnosticsRequest, error) {
+	query := `SELECT ` + diagnosticsColumns + ` FROM diagnostics_requests WHERE id = $1`
+
+	req, err := scanDiagnosticsRequest(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get diagnostics request: %w", err)
+	}
+
+	return req, nil
+}
+
+// MarkDiagnosticsUploaded 标记诊断包已上传，仅对处于 requested 状态且未过期的请求生效
+// 返回 false 表示请求已上传、已过期或不存在
+func (r *DiagnosticsRepository) MarkDiagnosticsUploaded(id, fileName string, fileSize int64, expiresAt time.Time) (bool, error) {
+	query := `
+		UPDATE diagnostics_requests
+		SET status = $2, file_name = $3, file_size = $4, uploaded_at = CURRENT_TIMESTAMP, expires_at = $5
+		WHERE id = $1 AND status = $6 AND expires_at > CURRENT_TIMESTAMP`
+
+	result, err := r.db.Exec(query, id, models.DiagnosticsStatusUploaded, fileName, fileSize, expiresAt,
+		models.DiagnosticsStatusRequested)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark diagnostics uploaded: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ExpireDiagnosticsRequests 将到期的请求标记为 expired，返回其中已上传过文件的请求ID（需删除文件）
+func (r *DiagnosticsRepository) ExpireDiagnosticsRequests(now time.Time) ([]string, error) {
+	query := `
+		UPDATE diagnostics_requests d
+		SET status = $2
+		FROM (SELECT id, status AS old_status FROM diagnostics_requests
+		      WHERE status <> $2 AND expires_at <= $1 FOR UPDATE) o
+		WHERE d.id = o.id
+		RETURNING d.id, o.old_status`
+
+	rows, err := r.db.Query(query, now, models.DiagnosticsStatusExpired)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire diagnostics requests: %w", err)
+	}
+	defer rows.Close()
+
+	var uploadedIDs []string
+	for rows.Next() {
+		var id, oldStatus string
+		if err := rows.Scan(&id, &oldStatus); err != nil {
+			return nil, fmt.Errorf("failed to scan expired diagnostics request: %w", err)
+		}
+		if oldStatus == models.DiagnosticsStatusUploaded {
+			uploadedIDs = append(uploadedIDs, id)
+		}
+	}
+
+	return uploadedIDs, rows.Err()
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestDispatchErrorPersisted 下发失败原因随任务保存，失败任务重新排队时清除
+func TestDispatchErrorPersisted(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	job := createTestJob(t, db, createTestPrinter(t, db), models.JobStatusPending)
+
+	job.DispatchError = models.DispatchErrorNodeOffline
+	if err := repo.UpdatePrintJob(job); err != nil {
+		t.Fatalf("UpdatePrintJob: %v", err)
+	}
+	got, err := repo.GetPrintJobByID(job.ID)
+	if err != nil {
+		t.Fatalf("GetPrintJobByID: %v", err)
+	}
+	if got.DispatchError != models.DispatchErrorNodeOffline {
+		t.Fatalf("dispatch_error = %q, want %q", got.DispatchError, models.DispatchErrorNodeOffline)
+	}
+
+	got.Status = models.JobStatusFailed
+	got.DispatchError = models.DispatchErrorMarshal
+	if err := repo.UpdatePrintJob(got); err != nil {
+		t.Fatalf("UpdatePrintJob: %v", err)
+	}
+	got.Status = models.JobStatusPending
+	if ok, err := repo.RequeueFailedJob(got); err != nil || !ok {
+		t.Fatalf("RequeueFailedJob = %v, %v", ok, err)
+	}
+	requeued, err := repo.GetPrintJobByID(job.ID)
+	if err != nil {
+		t.Fatalf("GetPrintJobByID: %v", err)
+	}
+	if requeued.DispatchError != "" {
+		t.Fatalf("dispatch_error after requeue = %q, want empty", requeued.DispatchError)
+	}
+}
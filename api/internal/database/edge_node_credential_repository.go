@@ -0,0 +1,126 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// ErrCredentialSubjectTaken 已有其他启用的凭据使用相同的证书 subject
+var ErrCredentialSubjectTaken = errors.New("database: credential subject already in use")
+
+// EdgeNodeCredentialRepository Edge Node 客户端证书凭据数据访问层
+type EdgeNodeCredentialRepository struct {
+	db *DB
+}
+
+// NewEdgeNodeCredentialRepository 创建 Edge Node 客户端证书凭据数据访问层
+func NewEdgeNodeCredentialRepository(db *DB) *EdgeNodeCredentialRepository {
+	return &EdgeNodeCredentialRepository{db: db}
+}
+
+// edgeNodeCredentialColumns 凭据查询列（与 scanEdgeNodeCredential 的扫描顺序保持一致）
+const edgeNodeCredentialColumns = `id, node_id, subject, description, enabled, created_by, last_used_at, disabled_at, created_at`
+
+// scanEdgeNodeCredential 扫描凭据列
+func scanEdgeNodeCredential(row rowScanner) (*models.EdgeNodeCredential, error) {
+	credential := &models.EdgeNodeCredential{}
+	var description, createdBy sql.NullString
+	err := row.Scan(&credential.ID, &credential.NodeID, &credential.Subject, &description, &credential.Enabled,
+		&createdBy, &credential.LastUsedAt, &credential.DisabledAt, &credential.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	credential.Description = description.String
+	credential.CreatedBy = createdBy.String
+	return credential, nil
+}
+
+// CreateCredential 登记节点凭据，subject 已被其他启用的凭据使用时返回 ErrCredentialSubjectTaken
+func (r *EdgeNodeCredentialRepository) CreateCredential(credential *models.EdgeNodeCredential) error {
+	query := `
+		INSERT INTO edge_node_credentials (node_id, subject, description, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		RETURNING id, enabled, created_at`
+
+	err := r.db.QueryRow(query, credential.NodeID, credential.Subject, credential.Description, credential.CreatedBy).
+		Scan(&credential.ID, &credential.Enabled, &credential.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrCredentialSubjectTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create edge node credential: %w", err)
+	}
+	return nil
+}
+
+// ListCredentialsByNode 获取节点的全部凭据（含已禁用，按创建时间倒序）
+func (r *EdgeNodeCredentialRepository) ListCredentialsByNode(nodeID string) ([]*models.EdgeNodeCredential, error) {
+	query := `SELECT ` + edgeNodeCredentialColumns + ` FROM edge_node_credentials WHERE node_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list edge node credentials: %w", err)
+	}
+	defer rows.Close()
+
+	credentials := []*models.EdgeNodeCredential{}
+	for rows.Next() {
+		credential, err := scanEdgeNodeCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan edge node credential: %w", err)
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+// DisableCredential 禁用节点的凭据（吊销证书），凭据不存在、不属于该节点或已禁用时返回 false
+func (r *EdgeNodeCredentialRepository) DisableCredential(nodeID, id string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE edge_node_credentials SET enabled = false, disabled_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND node_id = $2 AND enabled`, id, nodeID)
+	if err != nil {
+		return false, fmt.Errorf("failed to disable edge node credential: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// FindEnabledCredential 按证书名称查找启用的凭据，依次尝试 subjects，均未找到时返回 nil
+func (r *EdgeNodeCredentialRepository) FindEnabledCredential(subjects []string) (*models.EdgeNodeCredential, error) {
+	if len(subjects) == 0 {
+		return nil, nil
+	}
+	query := `
+		SELECT ` + edgeNodeCredentialColumns + `
+		FROM edge_node_credentials
+		WHERE enabled AND subject = ANY($1::text[])
+		ORDER BY array_position($1::text[], subject::text)
+		LIMIT 1`
+
+	credential, err := scanEdgeNodeCredential(r.db.QueryRow(query, pq.Array(subjects)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find edge node credential: %w", err)
+	}
+	return credential, nil
+}
+
+// TouchCredential 记录凭据的最近使用时间（调用方负责节流）
+func (r *EdgeNodeCredentialRepository) TouchCredential(id string, usedAt time.Time) error {
+	if _, err := r.db.Exec(`UPDATE edge_node_credentials SET last_used_at = $2 WHERE id = $1`, id, usedAt); err != nil {
+		return fmt.Errorf("failed to update edge node credential last used time: %w", err)
+	}
+	return nil
+}
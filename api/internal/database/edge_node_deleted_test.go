@@ -0,0 +1,107 @@
+package database
+
+import (
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// printerNodeID 返回打印机所属的 Edge Node ID
+func printerNodeID(t *testing.T, db *DB, printerID string) string {
+	t.Helper()
+	var nodeID string
+	if err := db.QueryRow(`SELECT edge_node_id FROM printers WHERE id = $1`, printerID).Scan(&nodeID); err != nil {
+		t.Fatalf("get printer node: %v", err)
+	}
+	return nodeID
+}
+
+// TestIsEdgeNodeActive 已删除、已禁用或不存在的 Edge Node 均视为不可用
+func TestIsEdgeNodeActive(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrinterRepository(db)
+
+	active := printerNodeID(t, db, createTestPrinter(t, db))
+	deleted := printerNodeID(t, db, createTestPrinter(t, db))
+	disabled := printerNodeID(t, db, createTestPrinter(t, db))
+	mustExec(t, db, `UPDATE edge_nodes SET deleted_at = NOW() WHERE id = $1`, deleted)
+	mustExec(t, db, `UPDATE edge_nodes SET enabled = false WHERE id = $1`, disabled)
+
+	tests := []struct {
+		name   string
+		nodeID string
+		want   bool
+	}{
+		{"active", active, true},
+		{"soft-deleted", deleted, false},
+		{"disabled", disabled, false},
+		{"missing", "node-missing", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.IsEdgeNodeActive(tt.nodeID)
+			if err != nil {
+				t.Fatalf("check: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("IsEdgeNodeActive(%s) = %v, want %v", tt.nodeID, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFailJobsOnDeletedNodes 只将已删除节点上待分发和排队中的任务标记为失败；
+// 已分发的任务、禁用节点和正常节点上的任务保持不变
+func TestFailJobsOnDeletedNodes(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+
+	deletedPrinter := createTestPrinter(t, db)
+	disabledPrinter := createTestPrinter(t, db)
+	activePrinter := createTestPrinter(t, db)
+	mustExec(t, db, `UPDATE edge_nodes SET deleted_at = NOW() WHERE id = $1`, printerNodeID(t, db, deletedPrinter))
+	mustExec(t, db, `UPDATE edge_nodes SET enabled = false WHERE id = $1`, printerNodeID(t, db, disabledPrinter))
+
+	pending := createTestJob(t, db, deletedPrinter, models.JobStatusPending)
+	queued := createTestJob(t, db, deletedPrinter, models.JobStatusQueued)
+	dispatched := createTestJob(t, db, deletedPrinter, models.JobStatusDispatched)
+	onDisabled := createTestJob(t, db, disabledPrinter, models.JobStatusPending)
+	onActive := createTestJob(t, db, activePrinter, models.JobStatusPending)
+
+	failed, err := repo.FailJobsOnDeletedNodes("edge node deleted")
+	if err != nil {
+		t.Fatalf("fail jobs: %v", err)
+	}
+	got := map[string]*models.PrintJob{}
+	for _, job := range failed {
+		got[job.ID] = job
+	}
+	if len(got) != 2 || got[pending.ID] == nil || got[queued.ID] == nil {
+		t.Fatalf("failed jobs = %v, want only %s and %s", got, pending.ID, queued.ID)
+	}
+	for _, job := range failed {
+		if job.Status != models.JobStatusFailed || job.ErrorMessage != "edge node deleted" || job.EndTime == nil {
+			t.Fatalf("failed job = %+v, want failed with message and end time", job)
+		}
+	}
+
+	for job, want := range map[*models.PrintJob]models.JobStatus{
+		dispatched: models.JobStatusDispatched,
+		onDisabled: models.JobStatusPending,
+		onActive:   models.JobStatusPending,
+	} {
+		reread, err := repo.GetPrintJobByID(job.ID)
+		if err != nil {
+			t.Fatalf("get job: %v", err)
+		}
+		if reread.Status != want {
+			t.Fatalf("job %s status = %s, want %s", job.ID, reread.Status, want)
+		}
+	}
+
+	// 再次巡检不会重复处理
+	again, err := repo.FailJobsOnDeletedNodes("edge node deleted")
+	if err != nil || len(again) != 0 {
+		t.Fatalf("second sweep = %d jobs, %v; want none", len(again), err)
+	}
+}
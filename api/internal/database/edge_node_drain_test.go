@@ -0,0 +1,151 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// listsDraining ListDrainingNodes 是否包含该节点
+func listsDraining(t *testing.T, repo *EdgeNodeRepository, nodeID string) bool {
+	t.Helper()
+	nodes, err := repo.ListDrainingNodes()
+	if err != nil {
+		t.Fatalf("ListDrainingNodes: %v", err)
+	}
+	for _, node := range nodes {
+		if node.ID == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// TestEdgeNodeDrainCompletesNaturally 未结束的任务全部结束后 CompleteDrain 禁用节点
+func TestEdgeNodeDrainCompletesNaturally(t *testing.T) {
+	db := openTestDB(t)
+	nodes := NewEdgeNodeRepository(db)
+	jobs := NewPrintJobRepository(db)
+
+	printerID := createTestPrinter(t, db)
+	nodeID := printerNodeID(t, db, printerID)
+	job := createTestJob(t, db, printerID, models.JobStatusPrinting)
+	createTestJob(t, db, printerID, models.JobStatusCompleted)
+
+	deadline := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	node, err := nodes.StartDrain(nodeID, deadline)
+	if err != nil {
+		t.Fatalf("StartDrain: %v", err)
+	}
+	if node == nil || !node.Draining || node.DrainDeadline == nil || !node.DrainDeadline.Equal(deadline) {
+		t.Fatalf("StartDrain = %+v, want draining until %s", node, deadline)
+	}
+	if again, err := nodes.StartDrain(nodeID, deadline); err != nil || again != nil {
+		t.Fatalf("StartDrain on draining node = %+v, %v; want nil", again, err)
+	}
+	if !listsDraining(t, nodes, nodeID) {
+		t.Fatal("draining node missing from ListDrainingNodes")
+	}
+
+	if active, err := jobs.CountActiveJobsByEdgeNode(nodeID); err != nil || active != 1 {
+		t.Fatalf("CountActiveJobsByEdgeNode = %d, %v; want 1", active, err)
+	}
+	mustExec(t, db, `UPDATE print_jobs SET status = $2 WHERE id = $1`, job.ID, models.JobStatusCompleted)
+	if active, err := jobs.CountActiveJobsByEdgeNode(nodeID); err != nil || active != 0 {
+		t.Fatalf("CountActiveJobsByEdgeNode after completion = %d, %v; want 0", active, err)
+	}
+
+	completed, err := nodes.CompleteDrain(nodeID)
+	if err != nil || !completed {
+		t.Fatalf("CompleteDrain = %v, %v; want true", completed, err)
+	}
+	got, err := nodes.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		t.Fatalf("GetEdgeNodeByID: %v", err)
+	}
+	if got.Enabled || got.Draining || got.DrainDeadline != nil {
+		t.Fatalf("node after drain = enabled %v, draining %v, deadline %v; want disabled without drain state", got.Enabled, got.Draining, got.DrainDeadline)
+	}
+	if listsDraining(t, nodes, nodeID) {
+		t.Fatal("drained node still listed as draining")
+	}
+	if again, err := nodes.StartDrain(nodeID, deadline); err != nil || again != nil {
+		t.Fatalf("StartDrain on disabled node = %+v, %v; want nil", again, err)
+	}
+}
+
+// TestEdgeNodeDrainTimesOut 超过截止时间时即使仍有任务未结束也能禁用节点
+func TestEdgeNodeDrainTimesOut(t *testing.T) {
+	db := openTestDB(t)
+	nodes := NewEdgeNodeRepository(db)
+	jobs := NewPrintJobRepository(db)
+
+	printerID := createTestPrinter(t, db)
+	nodeID := printerNodeID(t, db, printerID)
+	createTestJob(t, db, printerID, models.JobStatusDispatched)
+	createTestJob(t, db, printerID, models.JobStatusPrinting)
+
+	deadline := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	if node, err := nodes.StartDrain(nodeID, deadline); err != nil || node == nil {
+		t.Fatalf("StartDrain = %+v, %v", node, err)
+	}
+	draining, err := nodes.ListDrainingNodes()
+	if err != nil {
+		t.Fatalf("ListDrainingNodes: %v", err)
+	}
+	var listed *models.EdgeNode
+	for _, node := range draining {
+		if node.ID == nodeID {
+			listed = node
+		}
+	}
+	if listed == nil || listed.DrainDeadline == nil || !listed.DrainDeadline.Before(time.Now()) {
+		t.Fatalf("listed node = %+v, want draining past its deadline", listed)
+	}
+	if active, err := jobs.CountActiveJobsByEdgeNode(nodeID); err != nil || active != 2 {
+		t.Fatalf("CountActiveJobsByEdgeNode = %d, %v; want 2", active, err)
+	}
+
+	if completed, err := nodes.CompleteDrain(nodeID); err != nil || !completed {
+		t.Fatalf("CompleteDrain = %v, %v; want true", completed, err)
+	}
+	got, err := nodes.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		t.Fatalf("GetEdgeNodeByID: %v", err)
+	}
+	if got.Enabled || got.Draining {
+		t.Fatalf("node after timeout = enabled %v, draining %v; want disabled", got.Enabled, got.Draining)
+	}
+	// 超时禁用节点不改变任务状态
+	if active, err := jobs.CountActiveJobsByEdgeNode(nodeID); err != nil || active != 2 {
+		t.Fatalf("CountActiveJobsByEdgeNode after timeout = %d, %v; want 2", active, err)
+	}
+}
+
+// TestEdgeNodeDrainCancelled 取消排空后 CompleteDrain 不再禁用节点
+func TestEdgeNodeDrainCancelled(t *testing.T) {
+	db := openTestDB(t)
+	nodes := NewEdgeNodeRepository(db)
+
+	nodeID := printerNodeID(t, db, createTestPrinter(t, db))
+	if node, err := nodes.StartDrain(nodeID, time.Now().UTC().Add(time.Hour)); err != nil || node == nil {
+		t.Fatalf("StartDrain = %+v, %v", node, err)
+	}
+	if err := nodes.ClearDrain(nodeID); err != nil {
+		t.Fatalf("ClearDrain: %v", err)
+	}
+	if listsDraining(t, nodes, nodeID) {
+		t.Fatal("cancelled drain still listed")
+	}
+	if completed, err := nodes.CompleteDrain(nodeID); err != nil || completed {
+		t.Fatalf("CompleteDrain after cancel = %v, %v; want false", completed, err)
+	}
+	got, err := nodes.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		t.Fatalf("GetEdgeNodeByID: %v", err)
+	}
+	if !got.Enabled {
+		t.Fatal("node disabled after drain was cancelled")
+	}
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// metricsBucketColumns 按时间桶汇总采样的列：$1 为时间范围起点（时间桶从起点对齐），$3 为时间桶长度（秒）
+const metricsBucketColumns = `
+	$1::timestamp + (FLOOR(EXTRACT(EPOCH FROM (m.ts - $1::timestamp)) / $3::integer) * $3::integer)::double precision * INTERVAL '1 second' AS bucket,
+	COUNT(*), AVG(m.cpu_usage), MAX(m.cpu_usage), AVG(m.memory_usage), MAX(m.memory_usage),
+	AVG(m.disk_usage), MAX(m.disk_usage), AVG(m.latency), MAX(m.latency)`
+
+// RecordEdgeNodeMetrics 保存节点心跳上报的资源采样
+func (r *EdgeNodeRepository) RecordEdgeNodeMetrics(id string, sample models.EdgeNodeMetricsSample, at time.Time) error {
+	query := `
+		INSERT INTO edge_node_metrics (node_id, ts, cpu_usage, memory_usage, disk_usage, latency)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.db.Exec(query, id, at, sample.CPUUsage, sample.MemoryUsage, sample.DiskUsage, sample.Latency)
+	if err != nil {
+		return fmt.Errorf("failed to record edge node metrics: %w", err)
+	}
+	return nil
+}
+
+// GetEdgeNodeMetrics 按 step 汇总节点在 [from, to) 内的采样，返回有采样的时间桶（按时间排序）
+func (r *EdgeNodeRepository) GetEdgeNodeMetrics(id string, from, to time.Time, step time.Duration) ([]models.EdgeNodeMetricsPoint, error) {
+	query := `
+		SELECT ` + metricsBucketColumns + `
+		FROM edge_node_metrics m
+		WHERE m.node_id = $4 AND m.ts >= $1 AND m.ts < $2
+		GROUP BY bucket
+		ORDER BY bucket`
+	rows, err := r.db.Query(query, from, to, int(step.Seconds()), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query edge node metrics: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.EdgeNodeMetricsPoint{}
+	for rows.Next() {
+		point, err := scanMetricsPoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
+
+// GetFleetMetrics 按站点（节点 location）分组，按 step 汇总未删除节点在 [from, to) 内的采样
+func (r *EdgeNodeRepository) GetFleetMetrics(from, to time.Time, step time.Duration) ([]models.EdgeNodeSiteMetrics, error) {
+	query := `
+		SELECT COALESCE(n.location, '') AS site, ` + metricsBucketColumns + `
+		FROM edge_node_metrics m
+		JOIN edge_nodes n ON n.id = m.node_id
+		WHERE m.ts >= $1 AND m.ts < $2 AND n.deleted_at IS NULL
+		GROUP BY site, bucket
+		ORDER BY site, bucket`
+	rows, err := r.db.Query(query, from, to, int(step.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fleet metrics: %w", err)
+	}
+	defer rows.Close()
+
+	sites := []models.EdgeNodeSiteMetrics{}
+	for rows.Next() {
+		var site string
+		point, err := scanMetricsPoint(rows, &site)
+		if err != nil {
+			return nil, err
+		}
+		if len(sites) == 0 || sites[len(sites)-1].Site != site {
+			sites = append(sites, models.EdgeNodeSiteMetrics{Site: site})
+		}
+		current := &sites[len(sites)-1]
+		current.Points = append(current.Points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	nodes, err := r.countSiteMetricsNodes(from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sites {
+		sites[i].Nodes = nodes[sites[i].Site]
+	}
+	return sites, nil
+}
+
+// countSiteMetricsNodes 各站点在 [from, to) 内有采样的未删除节点数
+func (r *EdgeNodeRepository) countSiteMetricsNodes(from, to time.Time) (map[string]int, error) {
+	query := `
+		SELECT COALESCE(n.location, ''), COUNT(DISTINCT m.node_id)
+		FROM edge_node_metrics m
+		JOIN edge_nodes n ON n.id = m.node_id
+		WHERE m.ts >= $1 AND m.ts < $2 AND n.deleted_at IS NULL
+		GROUP BY 1`
+	rows, err := r.db.Query(query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count fleet metrics nodes: %w", err)
+	}
+	defer rows.Close()
+
+	nodes := make(map[string]int)
+	for rows.Next() {
+		var site string
+		var count int
+		if err := rows.Scan(&site, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan fleet metrics nodes: %w", err)
+		}
+		nodes[site] = count
+	}
+	return nodes, rows.Err()
+}
+
+// DeleteEdgeNodeMetricsBefore 删除 cutoff 之前的采样，每次最多删除 limit 条，返回删除的数量
+func (r *EdgeNodeRepository) DeleteEdgeNodeMetricsBefore(cutoff time.Time, limit int) (int, error) {
+	query := `
+		DELETE FROM edge_node_metrics
+		WHERE id IN (SELECT id FROM edge_node_metrics WHERE ts < $1 ORDER BY ts LIMIT $2)`
+	result, err := r.db.Exec(query, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired edge node metrics: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return int(rowsAffected), nil
+}
+
+// scanMetricsPoint 扫描一行时间桶汇总，prefix 为汇总列之前的列
+func scanMetricsPoint(rows *sql.Rows, prefix ...interface{}) (models.EdgeNodeMetricsPoint, error) {
+	var point models.EdgeNodeMetricsPoint
+	dest := append(prefix,
+		&point.Time, &point.Samples, &point.CPUAvg, &point.CPUMax, &point.MemoryAvg, &point.MemoryMax,
+		&point.DiskAvg, &point.DiskMax, &point.LatencyAvg, &point.LatencyMax)
+	if err := rows.Scan(dest...); err != nil {
+		return point, fmt.Errorf("failed to scan edge node metrics: %w", err)
+	}
+	return point, nil
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// nodeAndPrinterStatus 读取节点状态、打印机状态和打印机记住的原状态
+func nodeAndPrinterStatus(t *testing.T, db *DB, nodeID, printerID string) (models.NodeStatus, string, *string) {
+	t.Helper()
+	var nodeStatus models.NodeStatus
+	var printerStatus string
+	var lastKnown *string
+	if err := db.QueryRow(`SELECT status FROM edge_nodes WHERE id = $1`, nodeID).Scan(&nodeStatus); err != nil {
+		t.Fatalf("read node status: %v", err)
+	}
+	if err := db.QueryRow(`SELECT status, last_known_status FROM printers WHERE id = $1`, printerID).Scan(&printerStatus, &lastKnown); err != nil {
+		t.Fatalf("read printer status: %v", err)
+	}
+	return nodeStatus, printerStatus, lastKnown
+}
+
+// TestMarkTimedOutNodesOffline 心跳超时的节点和其打印机一起置为 offline，打印机记住原状态
+func TestMarkTimedOutNodesOffline(t *testing.T) {
+	db := openTestDB(t)
+	nodes := NewEdgeNodeRepository(db)
+
+	printerID := createTestPrinter(t, db)
+	nodeID := printerNodeID(t, db, printerID)
+	freshID := printerNodeID(t, db, createTestPrinter(t, db))
+	mustExec(t, db, `UPDATE edge_nodes SET last_heartbeat = $2 WHERE id = $1`, nodeID, time.Now().UTC().Add(-time.Hour))
+	mustExec(t, db, `UPDATE edge_nodes SET last_heartbeat = $2 WHERE id = $1`, freshID, time.Now().UTC())
+
+	offline, err := nodes.MarkTimedOutNodesOffline(time.Now().UTC().Add(-3 * time.Minute))
+	if err != nil {
+		t.Fatalf("MarkTimedOutNodesOffline: %v", err)
+	}
+	if len(offline) != 1 || offline[0].ID != nodeID || offline[0].PrintersOffline != 1 {
+		t.Fatalf("MarkTimedOutNodesOffline = %+v, want %s with 1 printer", offline, nodeID)
+	}
+	nodeStatus, printerStatus, lastKnown := nodeAndPrinterStatus(t, db, nodeID, printerID)
+	if nodeStatus != models.NodeStatusOffline || printerStatus != string(models.PrinterStatusOffline) ||
+		lastKnown == nil || *lastKnown != "ready" {
+		t.Fatalf("node %s, printer %s (last known %v); want offline, offline (ready)", nodeStatus, printerStatus, lastKnown)
+	}
+
+	again, err := nodes.MarkTimedOutNodesOffline(time.Now().UTC().Add(-3 * time.Minute))
+	if err != nil || len(again) != 0 {
+		t.Fatalf("second MarkTimedOutNodesOffline = %+v, %v; want none", again, err)
+	}
+}
+
+// TestMarkNodeOffline 在线节点置为 offline 时级联其打印机，已离线的节点不重复处理
+func TestMarkNodeOffline(t *testing.T) {
+	db := openTestDB(t)
+	nodes := NewEdgeNodeRepository(db)
+
+	printerID := createTestPrinter(t, db)
+	nodeID := printerNodeID(t, db, printerID)
+
+	changed, affected, err := nodes.MarkNodeOffline(nodeID)
+	if err != nil || !changed || affected != 1 {
+		t.Fatalf("MarkNodeOffline = %v, %d, %v; want true, 1", changed, affected, err)
+	}
+	if nodeStatus, printerStatus, _ := nodeAndPrinterStatus(t, db, nodeID, printerID); nodeStatus != models.NodeStatusOffline ||
+		printerStatus != string(models.PrinterStatusOffline) {
+		t.Fatalf("node %s, printer %s; want offline, offline", nodeStatus, printerStatus)
+	}
+
+	changed, affected, err = nodes.MarkNodeOffline(nodeID)
+	if err != nil || changed || affected != 0 {
+		t.Fatalf("MarkNodeOffline on offline node = %v, %d, %v; want false, 0", changed, affected, err)
+	}
+}
+
+// TestMarkNodeOfflineRollsBack 打印机级联失败时节点状态一并回滚，不会留下在线打印机挂在离线节点下
+func TestMarkNodeOfflineRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	nodes := NewEdgeNodeRepository(db)
+
+	printerID := createTestPrinter(t, db)
+	nodeID := printerNodeID(t, db, printerID)
+	mustExec(t, db, `UPDATE edge_nodes SET last_heartbeat = $2 WHERE id = $1`, nodeID, time.Now().UTC().Add(-time.Hour))
+	mustExec(t, db, `
+		CREATE FUNCTION reject_printer_offline() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'printer update rejected';
+		END;
+		$$ LANGUAGE plpgsql`)
+	mustExec(t, db, `CREATE TRIGGER reject_printer_offline BEFORE UPDATE ON printers
+		FOR EACH ROW EXECUTE FUNCTION reject_printer_offline()`)
+
+	if _, _, err := nodes.MarkNodeOffline(nodeID); err == nil {
+		t.Fatal("MarkNodeOffline succeeded, want printer cascade error")
+	}
+	if _, err := nodes.MarkTimedOutNodesOffline(time.Now().UTC().Add(-3 * time.Minute)); err == nil {
+		t.Fatal("MarkTimedOutNodesOffline succeeded, want printer cascade error")
+	}
+	if nodeStatus, printerStatus, _ := nodeAndPrinterStatus(t, db, nodeID, printerID); nodeStatus != models.NodeStatusOnline ||
+		printerStatus != "ready" {
+		t.Fatalf("node %s, printer %s after failed cascade; want online, ready", nodeStatus, printerStatus)
+	}
+}
@@ -2,10 +2,13 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"fly-print-cloud/api/internal/models"
+	"github.com/lib/pq"
 )
 
 // EdgeNodeRepository Edge Node 数据访问层
@@ -13,6 +16,9 @@ type EdgeNodeRepository struct {
 	db *DB
 }
 
+// ErrEdgeNodeNotFound Edge Node 不存在或已删除
+var ErrEdgeNodeNotFound = errors.New("edge node not found")
+
 // NewEdgeNodeRepository 创建 Edge Node 数据访问层
 func NewEdgeNodeRepository(db *DB) *EdgeNodeRepository {
 	return &EdgeNodeRepository{db: db}
@@ -96,101 +102,62 @@ func (r *EdgeNodeRepository) UpsertEdgeNode(node *models.EdgeNode) error {
 	if err != nil {
 		return fmt.Errorf("failed to upsert edge node: %w", err)
 	}
+	r.db.invalidateLookup(LookupKindEdgeNode, node.ID)
 
 	return nil
 }
 
-// GetEdgeNodeByID 根据ID获取 Edge Node
-func (r *EdgeNodeRepository) GetEdgeNodeByID(id string) (*models.EdgeNode, error) {
-	node := &models.EdgeNode{}
-	query := `
-		SELECT id, name, status, enabled, version, last_heartbeat,
+// edgeNodeColumns Edge Node 查询列（与 scanEdgeNode 的扫描顺序保持一致）
+const edgeNodeColumns = `id, name, status, enabled, version, last_heartbeat,
 			   location, latitude, longitude,
 			   ip_address, mac_address, network_interface,
 			   os_version, cpu_info, memory_info, disk_info,
 			   connection_quality, latency,
-			   created_at, updated_at, deleted_at
-		FROM edge_nodes WHERE id = $1 AND deleted_at IS NULL`
+			   created_at, updated_at, deleted_at,
+			   drain_started_at, drain_deadline, row_version,
+			   conflict_suspected_at, conflict_remote_addrs`
 
-	var lastHeartbeat sql.NullTime
-	var latitude, longitude sql.NullFloat64
-	var location, ipAddress, macAddress, networkInterface sql.NullString
-	var osVersion, cpuInfo, memoryInfo, diskInfo sql.NullString
-	var connectionQuality sql.NullString
-	var latency sql.NullInt32
-	var version sql.NullString
-	var deletedAt sql.NullTime
-
-	err := r.db.QueryRow(query, id).Scan(
-		&node.ID, &node.Name, &node.Status, &node.Enabled, &version, &lastHeartbeat,
-		&location, &latitude, &longitude,
-		&ipAddress, &macAddress, &networkInterface,
-		&osVersion, &cpuInfo, &memoryInfo, &diskInfo,
-		&connectionQuality, &latency,
-		&node.CreatedAt, &node.UpdatedAt, &deletedAt,
+// scanEdgeNode 扫描一行 Edge Node 数据，可为空的列直接扫描到指针字段
+func scanEdgeNode(row rowScanner) (*models.EdgeNode, error) {
+	node := &models.EdgeNode{}
+	err := row.Scan(
+		&node.ID, &node.Name, &node.Status, &node.Enabled, &node.Version, &node.LastHeartbeat,
+		&node.Location, &node.Latitude, &node.Longitude,
+		&node.IPAddress, &node.MACAddress, &node.NetworkInterface,
+		&node.OSVersion, &node.CPUInfo, &node.MemoryInfo, &node.DiskInfo,
+		&node.ConnectionQuality, &node.Latency,
+		&node.CreatedAt, &node.UpdatedAt, &node.DeletedAt,
+		&node.DrainStartedAt, &node.DrainDeadline, &node.RowVersion,
+		&node.ConflictSuspectedAt, (*pq.StringArray)(&node.ConflictRemoteAddrs),
 	)
+	if err != nil {
+		return nil, err
+	}
+	node.Draining = node.DrainStartedAt != nil
+	node.ConflictSuspected = node.ConflictSuspectedAt != nil
+	return node, nil
+}
 
+// GetEdgeNodeByID 根据ID获取 Edge Node
+func (r *EdgeNodeRepository) GetEdgeNodeByID(id string) (*models.EdgeNode, error) {
+	query := `
+		SELECT ` + edgeNodeColumns + `
+		FROM edge_nodes WHERE id = $1 AND deleted_at IS NULL`
+
+	node, err := scanEdgeNode(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("edge node not found")
+			return nil, ErrEdgeNodeNotFound
 		}
 		return nil, fmt.Errorf("failed to get edge node: %w", err)
 	}
 
-	// 处理可为空的字段
-	if version.Valid {
-		node.Version = version.String
-	}
-	if lastHeartbeat.Valid {
-		node.LastHeartbeat = lastHeartbeat.Time
-	}
-	if location.Valid {
-		node.Location = location.String
-	}
-	if latitude.Valid {
-		lat := latitude.Float64
-		node.Latitude = &lat
-	}
-	if longitude.Valid {
-		lng := longitude.Float64
-		node.Longitude = &lng
-	}
-	if ipAddress.Valid {
-		ip := ipAddress.String
-		node.IPAddress = &ip
-	}
-	if macAddress.Valid {
-		node.MACAddress = macAddress.String
-	}
-	if networkInterface.Valid {
-		node.NetworkInterface = networkInterface.String
-	}
-	if osVersion.Valid {
-		node.OSVersion = osVersion.String
-	}
-	if cpuInfo.Valid {
-		node.CPUInfo = cpuInfo.String
-	}
-	if memoryInfo.Valid {
-		node.MemoryInfo = memoryInfo.String
-	}
-	if diskInfo.Valid {
-		node.DiskInfo = diskInfo.String
-	}
-	if connectionQuality.Valid {
-		node.ConnectionQuality = connectionQuality.String
-	}
-	if latency.Valid {
-		node.Latency = int(latency.Int32)
-	}
-	if deletedAt.Valid {
-		node.DeletedAt = &deletedAt.Time
-	}
-
 	return node, nil
 }
 
-// UpdateEdgeNode 更新 Edge Node
+// UpdateEdgeNode 更新 Edge Node（管理员修改）
+// 仅当数据库中的版本号等于 node.RowVersion 时更新，成功后 node.RowVersion 为新版本号；
+// 版本号不一致时返回 ErrVersionConflict
 func (r *EdgeNodeRepository) UpdateEdgeNode(node *models.EdgeNode) error {
 	query := `
 		UPDATE edge_nodes SET
@@ -198,20 +165,58 @@ func (r *EdgeNodeRepository) UpdateEdgeNode(node *models.EdgeNode) error {
 			location = $7, latitude = $8, longitude = $9,
 			ip_address = $10, mac_address = $11, network_interface = $12,
 			os_version = $13, cpu_info = $14, memory_info = $15, disk_info = $16,
-			connection_quality = $17, latency = $18
-		WHERE id = $1`
+			connection_quality = $17, latency = $18, row_version = row_version + 1
+		WHERE id = $1 AND row_version = $19 AND deleted_at IS NULL
+		RETURNING row_version, updated_at`
 
-	_, err := r.db.Exec(query,
+	err := r.db.QueryRow(query,
 		node.ID, node.Name, node.Status, node.Enabled, node.Version, node.LastHeartbeat,
 		node.Location, node.Latitude, node.Longitude,
 		node.IPAddress, node.MACAddress, node.NetworkInterface,
 		node.OSVersion, node.CPUInfo, node.MemoryInfo, node.DiskInfo,
-		node.ConnectionQuality, node.Latency,
-	)
+		node.ConnectionQuality, node.Latency, node.RowVersion,
+	).Scan(&node.RowVersion, &node.UpdatedAt)
 
+	if err == sql.ErrNoRows {
+		return versionMismatch(r.db, "SELECT EXISTS(SELECT 1 FROM edge_nodes WHERE id = $1 AND deleted_at IS NULL)", node.ID, "edge node not found")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update edge node: %w", err)
 	}
+	r.db.invalidateLookup(LookupKindEdgeNode, node.ID)
+
+	return nil
+}
+
+// UpdateEdgeNodeSelfInfo 更新 Edge Node 自行上报的信息（版本、系统、硬件、网络，可选经纬度）
+// 字段为 nil 时保持原值；不修改名称、启用状态等管理员维护的字段，也不递增 row_version，避免打断管理员正在进行的编辑
+func (r *EdgeNodeRepository) UpdateEdgeNodeSelfInfo(node *models.EdgeNode) error {
+	query := `
+		UPDATE edge_nodes SET
+			version = COALESCE($2, version),
+			os_version = COALESCE($3, os_version),
+			cpu_info = COALESCE($4, cpu_info),
+			memory_info = COALESCE($5, memory_info),
+			disk_info = COALESCE($6, disk_info),
+			network_interface = COALESCE($7, network_interface),
+			ip_address = COALESCE($8, ip_address),
+			mac_address = COALESCE($9, mac_address),
+			latitude = COALESCE($10, latitude),
+			longitude = COALESCE($11, longitude),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := r.db.Exec(query,
+		node.ID, node.Version, node.OSVersion, node.CPUInfo, node.MemoryInfo, node.DiskInfo,
+		node.NetworkInterface, node.IPAddress, node.MACAddress, node.Latitude, node.Longitude,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update edge node info: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("edge node not found")
+	}
+	r.db.invalidateLookup(LookupKindEdgeNode, node.ID)
 
 	return nil
 }
@@ -224,6 +229,7 @@ func (r *EdgeNodeRepository) DeleteEdgeNode(id string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete edge node: %w", err)
 	}
+	r.db.invalidateLookup(LookupKindEdgeNode, id)
 
 	return nil
 }
@@ -236,12 +242,13 @@ func (r *EdgeNodeRepository) HardDeleteEdgeNode(id string) error {
 	if err != nil {
 		return fmt.Errorf("failed to hard delete edge node: %w", err)
 	}
+	r.db.invalidateLookup(LookupKindEdgeNode, id)
 
 	return nil
 }
 
 // ListEdgeNodes 获取 Edge Node 列表
-func (r *EdgeNodeRepository) ListEdgeNodes(offset, limit int, status string) ([]*models.EdgeNode, int, error) {
+func (r *EdgeNodeRepository) ListEdgeNodes(offset, limit int, status models.NodeStatus) ([]*models.EdgeNode, int, error) {
 	log.Printf("🔍 [DB DEBUG] ListEdgeNodes: offset=%d, limit=%d, status='%s'", offset, limit, status)
 	var nodes []*models.EdgeNode
 	
@@ -269,12 +276,7 @@ func (r *EdgeNodeRepository) ListEdgeNodes(offset, limit int, status string) ([]
 
 	// 查询数据
 	query := fmt.Sprintf(`
-		SELECT id, name, status, enabled, version, last_heartbeat,
-			   location, latitude, longitude,
-			   ip_address, mac_address, network_interface,
-			   os_version, cpu_info, memory_info, disk_info,
-			   connection_quality, latency,
-			   created_at, updated_at, deleted_at
+		SELECT `+edgeNodeColumns+`
 		FROM edge_nodes %s
 		ORDER BY created_at DESC
 		LIMIT $%d OFFSET $%d`, whereClause, argIndex, argIndex+1)
@@ -291,78 +293,11 @@ func (r *EdgeNodeRepository) ListEdgeNodes(offset, limit int, status string) ([]
 	defer rows.Close()
 
 	for rows.Next() {
-		node := &models.EdgeNode{}
-		var lastHeartbeat sql.NullTime
-		var latitude, longitude sql.NullFloat64
-		var location, ipAddress, macAddress, networkInterface sql.NullString
-		var osVersion, cpuInfo, memoryInfo, diskInfo sql.NullString
-		var connectionQuality sql.NullString
-		var latency sql.NullInt32
-		var version sql.NullString
-
-		var deletedAt sql.NullTime
-		err := rows.Scan(
-			&node.ID, &node.Name, &node.Status, &node.Enabled, &version, &lastHeartbeat,
-			&location, &latitude, &longitude,
-			&ipAddress, &macAddress, &networkInterface,
-			&osVersion, &cpuInfo, &memoryInfo, &diskInfo,
-			&connectionQuality, &latency,
-			&node.CreatedAt, &node.UpdatedAt, &deletedAt,
-		)
+		node, err := scanEdgeNode(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan edge node: %w", err)
 		}
 
-		// 处理可为空的字段
-		if version.Valid {
-			node.Version = version.String
-		}
-		if lastHeartbeat.Valid {
-			node.LastHeartbeat = lastHeartbeat.Time
-		}
-		if location.Valid {
-			node.Location = location.String
-		}
-		if latitude.Valid {
-			lat := latitude.Float64
-			node.Latitude = &lat
-		}
-		if longitude.Valid {
-			lng := longitude.Float64
-			node.Longitude = &lng
-		}
-		if ipAddress.Valid {
-			ip := ipAddress.String
-			node.IPAddress = &ip
-		}
-		if macAddress.Valid {
-			node.MACAddress = macAddress.String
-		}
-		if networkInterface.Valid {
-			node.NetworkInterface = networkInterface.String
-		}
-		if osVersion.Valid {
-			node.OSVersion = osVersion.String
-		}
-		if cpuInfo.Valid {
-			node.CPUInfo = cpuInfo.String
-		}
-		if memoryInfo.Valid {
-			node.MemoryInfo = memoryInfo.String
-		}
-		if diskInfo.Valid {
-			node.DiskInfo = diskInfo.String
-		}
-		if connectionQuality.Valid {
-			node.ConnectionQuality = connectionQuality.String
-		}
-		if latency.Valid {
-			node.Latency = int(latency.Int32)
-		}
-		if deletedAt.Valid {
-			node.DeletedAt = &deletedAt.Time
-		}
-
 		nodes = append(nodes, node)
 	}
 
@@ -386,36 +321,266 @@ func (r *EdgeNodeRepository) UpdateHeartbeat(id string) error {
 	return nil
 }
 
+// UpdateEdgeNodeHeartbeatAndTelemetry 更新心跳时间和节点上报的连接质量、延迟，不会覆盖管理员修改的名称、启用状态等字段
+func (r *EdgeNodeRepository) UpdateEdgeNodeHeartbeatAndTelemetry(id string, connectionQuality string, latency int) error {
+	query := `
+		UPDATE edge_nodes 
+		SET last_heartbeat = CURRENT_TIMESTAMP,
+		    connection_quality = COALESCE(NULLIF($2, ''), connection_quality),
+		    latency = $3
+		WHERE id = $1`
+	
+	_, err := r.db.Exec(query, id, connectionQuality, latency)
+	if err != nil {
+		return fmt.Errorf("failed to update heartbeat telemetry: %w", err)
+	}
+
+	return nil
+}
+
 // UpdateStatus 更新状态
-func (r *EdgeNodeRepository) UpdateStatus(id, status string) error {
+func (r *EdgeNodeRepository) UpdateStatus(id string, status models.NodeStatus) error {
 	query := `UPDATE edge_nodes SET status = $2 WHERE id = $1`
 	
 	_, err := r.db.Exec(query, id, status)
 	if err != nil {
 		return fmt.Errorf("failed to update status: %w", err)
 	}
+	r.db.invalidateLookup(LookupKindEdgeNode, id)
 
 	return nil
 }
 
-// CheckAndUpdateOfflineNodes 检查并更新超时的节点状态为离线
-func (r *EdgeNodeRepository) CheckAndUpdateOfflineNodes(timeoutMinutes int) (int, error) {
+// StartDrain 开始排空已启用的节点，返回更新后的节点
+// 节点不存在、已禁用或已在排空中时返回 nil
+func (r *EdgeNodeRepository) StartDrain(id string, deadline time.Time) (*models.EdgeNode, error) {
+	query := `
+		UPDATE edge_nodes SET drain_started_at = CURRENT_TIMESTAMP, drain_deadline = $2
+		WHERE id = $1 AND deleted_at IS NULL AND enabled AND drain_started_at IS NULL
+		RETURNING ` + edgeNodeColumns
+
+	node, err := scanEdgeNode(r.db.QueryRow(query, id, deadline))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start edge node drain: %w", err)
+	}
+	r.db.invalidateLookup(LookupKindEdgeNode, id)
+	return node, nil
+}
+
+// ListDrainingNodes 获取正在排空的节点
+func (r *EdgeNodeRepository) ListDrainingNodes() ([]*models.EdgeNode, error) {
+	query := `
+		SELECT ` + edgeNodeColumns + `
+		FROM edge_nodes WHERE drain_started_at IS NOT NULL AND deleted_at IS NULL
+		ORDER BY drain_started_at`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list draining edge nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []*models.EdgeNode
+	for rows.Next() {
+		node, err := scanEdgeNode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan edge node: %w", err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+// CompleteDrain 排空结束：禁用节点并清除排空状态，返回 false 表示节点已不在排空中（如已被管理员重新启用）
+func (r *EdgeNodeRepository) CompleteDrain(id string) (bool, error) {
+	query := `
+		UPDATE edge_nodes SET enabled = false, drain_started_at = NULL, drain_deadline = NULL
+		WHERE id = $1 AND drain_started_at IS NOT NULL`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete edge node drain: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to complete edge node drain: %w", err)
+	}
+	r.db.invalidateLookup(LookupKindEdgeNode, id)
+	return affected > 0, nil
+}
+
+// ClearDrain 取消排空（管理员直接启用或禁用节点时）
+func (r *EdgeNodeRepository) ClearDrain(id string) error {
+	query := `UPDATE edge_nodes SET drain_started_at = NULL, drain_deadline = NULL WHERE id = $1 AND drain_started_at IS NOT NULL`
+	if _, err := r.db.Exec(query, id); err != nil {
+		return fmt.Errorf("failed to clear edge node drain: %w", err)
+	}
+	r.db.invalidateLookup(LookupKindEdgeNode, id)
+	return nil
+}
+
+// MarkConflictSuspected 标记节点疑似 node_id 冲突并记录冲突的来源地址，返回是否为新标记
+// 已标记的节点只追加新的来源地址
+func (r *EdgeNodeRepository) MarkConflictSuspected(id string, remoteAddrs []string) (bool, error) {
+	query := `
+		UPDATE edge_nodes n
+		SET conflict_suspected_at = COALESCE(o.conflict_suspected_at, CURRENT_TIMESTAMP),
+		    conflict_remote_addrs = ARRAY(
+		        SELECT DISTINCT a FROM unnest(COALESCE(o.conflict_remote_addrs, '{}') || $2::TEXT[]) AS a ORDER BY a)
+		FROM (SELECT id, conflict_suspected_at, conflict_remote_addrs FROM edge_nodes WHERE id = $1 AND deleted_at IS NULL FOR UPDATE) o
+		WHERE n.id = o.id
+		RETURNING o.conflict_suspected_at IS NULL`
+
+	var newlyFlagged bool
+	err := r.db.QueryRow(query, id, pq.Array(remoteAddrs)).Scan(&newlyFlagged)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to mark edge node conflict: %w", err)
+	}
+	r.db.invalidateLookup(LookupKindEdgeNode, id)
+	return newlyFlagged, nil
+}
+
+// ClearConflict 清除节点的 node_id 冲突标记，返回节点此前是否被标记
+func (r *EdgeNodeRepository) ClearConflict(id string) (bool, error) {
+	query := `UPDATE edge_nodes SET conflict_suspected_at = NULL, conflict_remote_addrs = NULL WHERE id = $1 AND conflict_suspected_at IS NOT NULL`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear edge node conflict: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to clear edge node conflict: %w", err)
+	}
+	r.db.invalidateLookup(LookupKindEdgeNode, id)
+	return affected > 0, nil
+}
+
+// OfflineNode 被标记为离线的节点
+type OfflineNode struct {
+	ID              string
+	PrintersOffline int // 同一事务中置为 offline 的打印机数量
+}
+
+// MarkTimedOutNodesOffline 将心跳早于 cutoff 的在线节点标记为离线，并在同一事务中将其打印机置为 offline
+func (r *EdgeNodeRepository) MarkTimedOutNodesOffline(cutoff time.Time) ([]OfflineNode, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE edge_nodes 
-		SET status = 'offline' 
-		WHERE status = 'online' 
-		  AND last_heartbeat < CURRENT_TIMESTAMP - INTERVAL '%d minutes'
-		  AND deleted_at IS NULL`
-	
-	result, err := r.db.Exec(fmt.Sprintf(query, timeoutMinutes))
+		SET status = $2 
+		WHERE status = $3 
+		  AND last_heartbeat < $1
+		  AND deleted_at IS NULL
+		RETURNING id`
+
+	rows, err := tx.Query(query, cutoff, models.NodeStatusOffline, models.NodeStatusOnline)
 	if err != nil {
-		return 0, fmt.Errorf("failed to update offline nodes: %w", err)
+		return nil, fmt.Errorf("failed to update offline nodes: %w", err)
+	}
+	var nodeIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan offline node: %w", err)
+		}
+		nodeIDs = append(nodeIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
+
+	nodes := make([]OfflineNode, 0, len(nodeIDs))
+	printersOffline := 0
+	for _, id := range nodeIDs {
+		affected, err := markPrintersOfflineTx(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, OfflineNode{ID: id, PrintersOffline: affected})
+		printersOffline += affected
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit offline nodes: %w", err)
+	}
+
+	r.db.invalidateLookup(LookupKindEdgeNode, nodeIDs...)
+	if printersOffline > 0 {
+		r.db.invalidateLookupKind(LookupKindPrinter)
+	}
+	return nodes, nil
+}
+
+// MarkNodeOffline 将在线的节点标记为离线，并在同一事务中将其打印机置为 offline；
+// 返回节点此前是否为在线状态及置为 offline 的打印机数量
+func (r *EdgeNodeRepository) MarkNodeOffline(id string) (bool, int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE edge_nodes SET status = $2 WHERE id = $1 AND status = $3 AND deleted_at IS NULL`
+
+	result, err := tx.Exec(query, id, models.NodeStatusOffline, models.NodeStatusOnline)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to mark node offline: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to mark node offline: %w", err)
+	}
+	if rows == 0 {
+		return false, 0, nil
+	}
+
+	affected, err := markPrintersOfflineTx(tx, id)
+	if err != nil {
+		return false, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, 0, fmt.Errorf("failed to commit node offline: %w", err)
+	}
+
+	r.db.invalidateLookup(LookupKindEdgeNode, id)
+	if affected > 0 {
+		r.db.invalidateLookupKind(LookupKindPrinter)
+	}
+	return true, affected, nil
+}
+
+// MarkNodeOnline 更新心跳时间并标记为在线，返回节点此前是否为离线状态
+func (r *EdgeNodeRepository) MarkNodeOnline(id string) (bool, error) {
+	query := `
+		UPDATE edge_nodes n
+		SET status = $2, last_heartbeat = CURRENT_TIMESTAMP
+		FROM (SELECT id, status AS old_status FROM edge_nodes WHERE id = $1 FOR UPDATE) o
+		WHERE n.id = o.id
+		RETURNING o.old_status`
 	
-	rowsAffected, err := result.RowsAffected()
+	var oldStatus models.NodeStatus
+	err := r.db.QueryRow(query, id, models.NodeStatusOnline).Scan(&oldStatus)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		if err == sql.ErrNoRows {
+			return false, fmt.Errorf("edge node not found")
+		}
+		return false, fmt.Errorf("failed to mark node online: %w", err)
+	}
+	// 每次心跳都会调用，只有状态变化时才清除缓存
+	if oldStatus != models.NodeStatusOnline {
+		r.db.invalidateLookup(LookupKindEdgeNode, id)
 	}
 	
-	return int(rowsAffected), nil
+	return oldStatus == models.NodeStatusOffline, nil
 }
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// ExportRunRepository 已完成任务归档导出数据访问层
+type ExportRunRepository struct {
+	db *DB
+}
+
+// NewExportRunRepository 创建归档导出数据访问层
+func NewExportRunRepository(db *DB) *ExportRunRepository {
+	return &ExportRunRepository{db: db}
+}
+
+const exportRunColumns = `id, status, format, trigger, from_date, to_date, requested_by, row_count, files,
+	last_job_id, last_job_created_at, error, attempts, started_at, finished_at, created_at, updated_at`
+
+// scanExportRun 扫描一行归档导出数据
+func scanExportRun(row rowScanner) (*models.ExportRun, error) {
+	run := &models.ExportRun{}
+	var fromDate, toDate time.Time
+	var requestedBy, lastJobID, errMsg sql.NullString
+	var files []byte
+	var lastJobCreatedAt, startedAt, finishedAt sql.NullTime
+
+	err := row.Scan(&run.ID, &run.Status, &run.Format, &run.Trigger, &fromDate, &toDate, &requestedBy,
+		&run.RowCount, &files, &lastJobID, &lastJobCreatedAt, &errMsg, &run.Attempts, &startedAt, &finishedAt,
+		&run.CreatedAt, &run.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	run.FromDate = fromDate.Format(models.ExportDateLayout)
+	run.ToDate = toDate.Format(models.ExportDateLayout)
+	run.RequestedBy = requestedBy.String
+	run.LastJobID = lastJobID.String
+	run.Error = errMsg.String
+	run.Files = []models.ExportFile{}
+	if len(files) > 0 {
+		if err := json.Unmarshal(files, &run.Files); err != nil {
+			return nil, fmt.Errorf("failed to decode export files: %w", err)
+		}
+	}
+	if lastJobCreatedAt.Valid {
+		run.LastJobCreatedAt = &lastJobCreatedAt.Time
+	}
+	if startedAt.Valid {
+		run.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return run, nil
+}
+
+// CreateExportRun 创建待执行的归档导出
+func (r *ExportRunRepository) CreateExportRun(run *models.ExportRun) error {
+	query := `
+		INSERT INTO export_runs (status, format, trigger, from_date, to_date, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + exportRunColumns
+
+	created, err := scanExportRun(r.db.QueryRow(query, models.ExportStatusPending, run.Format, run.Trigger,
+		run.FromDate, run.ToDate, nullIfEmpty(run.RequestedBy)))
+	if err != nil {
+		return fmt.Errorf("failed to create export run: %w", err)
+	}
+	*run = *created
+	return nil
+}
+
+// CreateScheduledExportRun 创建某一天的每日导出，该日期已有每日导出时不重复创建（多实例部署时只有一个实例创建成功）
+func (r *ExportRunRepository) CreateScheduledExportRun(date, format string) (bool, error) {
+	query := `
+		INSERT INTO export_runs (status, format, trigger, from_date, to_date)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (from_date) WHERE trigger = 'scheduled' DO NOTHING`
+
+	result, err := r.db.Exec(query, models.ExportStatusPending, format, models.ExportTriggerScheduled, date)
+	if err != nil {
+		return false, fmt.Errorf("failed to create scheduled export run: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetExportRun 根据ID获取归档导出，不存在时返回 nil
+func (r *ExportRunRepository) GetExportRun(id string) (*models.ExportRun, error) {
+	query := `SELECT ` + exportRunColumns + ` FROM export_runs WHERE id = $1`
+
+	run, err := scanExportRun(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get export run: %w", err)
+	}
+	return run, nil
+}
+
+// ListExportRuns 分页获取归档导出（按创建时间倒序），可按状态筛选
+func (r *ExportRunRepository) ListExportRuns(page, pageSize int, status string) ([]*models.ExportRun, int, error) {
+	offset := (page - 1) * pageSize
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM export_runs WHERE ($1 = '' OR status = $1)`
+	if err := r.db.QueryRow(countQuery, status).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count export runs: %w", err)
+	}
+
+	query := `SELECT ` + exportRunColumns + ` FROM export_runs
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+	rows, err := r.db.Query(query, status, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list export runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []*models.ExportRun{}
+	for rows.Next() {
+		run, err := scanExportRun(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan export run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, total, rows.Err()
+}
+
+// ClaimExportRun 领取最早的待执行导出并标记为 running；staleBefore 之后未更新的 running 导出视为实例中断，也可被领取
+// 使用 SKIP LOCKED，多实例部署时同一导出只会被一个实例领取；没有可执行的导出时返回 nil
+func (r *ExportRunRepository) ClaimExportRun(staleBefore time.Time) (*models.ExportRun, error) {
+	query := `
+		UPDATE export_runs SET status = $1, attempts = attempts + 1, error = NULL,
+			started_at = COALESCE(started_at, CURRENT_TIMESTAMP), finished_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM export_runs
+			WHERE status = $2 OR (status = $1 AND updated_at < $3)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportRunColumns
+
+	run, err := scanExportRun(r.db.QueryRow(query, models.ExportStatusRunning, models.ExportStatusPending, staleBefore))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim export run: %w", err)
+	}
+	return run, nil
+}
+
+// RecordExportFile 记录已写入的文件并推进游标（与文件一一对应，继续导出时从游标之后开始）
+func (r *ExportRunRepository) RecordExportFile(id string, file models.ExportFile, lastJob *models.PrintJob) error {
+	encoded, err := json.Marshal([]models.ExportFile{file})
+	if err != nil {
+		return fmt.Errorf("failed to encode export file: %w", err)
+	}
+
+	query := `
+		UPDATE export_runs SET files = files || $2::jsonb, row_count = row_count + $3,
+			last_job_id = $4, last_job_created_at = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $6`
+	result, err := r.db.Exec(query, id, string(encoded), file.Rows, lastJob.ID, lastJob.CreatedAt, models.ExportStatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to record export file: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return fmt.Errorf("export run %s is no longer running", id)
+	}
+	return nil
+}
+
+// FinishExportRun 结束导出（completed 或 failed），errMsg 为失败原因
+func (r *ExportRunRepository) FinishExportRun(id, status, errMsg string) error {
+	query := `
+		UPDATE export_runs SET status = $2, error = $3, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+	if _, err := r.db.Exec(query, id, status, nullIfEmpty(errMsg)); err != nil {
+		return fmt.Errorf("failed to finish export run: %w", err)
+	}
+	return nil
+}
+
+// ResumeExportRun 将失败的导出重新标记为待执行（保留游标和已写入的文件），导出不是 failed 状态时返回 false
+func (r *ExportRunRepository) ResumeExportRun(id string) (bool, error) {
+	query := `
+		UPDATE export_runs SET status = $2, finished_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $3`
+	result, err := r.db.Exec(query, id, models.ExportStatusPending, models.ExportStatusFailed)
+	if err != nil {
+		return false, fmt.Errorf("failed to resume export run: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
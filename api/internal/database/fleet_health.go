@@ -0,0 +1,114 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// 机队健康汇总的查询：每类问题一次查询，返回前 limit 个资源ID和全部数量（COUNT(*) OVER() 在 LIMIT 之前计算）
+
+// queryIDsWithTotal 执行返回 (id, total) 的查询，没有匹配行时数量为 0
+func queryIDsWithTotal(db *DB, query string, args ...interface{}) ([]string, int, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	total := 0
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id, &total); err != nil {
+			return nil, 0, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, total, rows.Err()
+}
+
+// ListOfflineEdgeNodeIDs 离线且最后心跳（从未心跳时为注册时间）早于 cutoff 的启用节点，离线最久的在前
+func (r *EdgeNodeRepository) ListOfflineEdgeNodeIDs(cutoff time.Time, limit int) ([]string, int, error) {
+	query := `
+		SELECT id, COUNT(*) OVER()
+		FROM edge_nodes
+		WHERE deleted_at IS NULL AND enabled AND status = $1 AND COALESCE(last_heartbeat, created_at) < $2
+		ORDER BY COALESCE(last_heartbeat, created_at)
+		LIMIT $3`
+	ids, total, err := queryIDsWithTotal(r.db, query, models.NodeStatusOffline, cutoff, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list offline edge nodes: %w", err)
+	}
+	return ids, total, nil
+}
+
+// ListErrorPrinterIDs 处于 error 状态的启用打印机（所属节点未删除），最近更新的在前
+func (r *PrinterRepository) ListErrorPrinterIDs(limit int) ([]string, int, error) {
+	query := `
+		SELECT p.id, COUNT(*) OVER()
+		FROM printers p
+		JOIN edge_nodes e ON e.id = p.edge_node_id AND e.deleted_at IS NULL
+		WHERE p.enabled AND p.status = $1
+		ORDER BY p.updated_at DESC
+		LIMIT $2`
+	ids, total, err := queryIDsWithTotal(r.db, query, models.PrinterStatusError, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list error printers: %w", err)
+	}
+	return ids, total, nil
+}
+
+// ListLowSupplyPrinterIDs 上报的耗材余量（supplies 中的数值项，按百分比）不高于 percent 的启用打印机，余量最低的在前
+func (r *PrinterRepository) ListLowSupplyPrinterIDs(percent, limit int) ([]string, int, error) {
+	query := `
+		SELECT id, COUNT(*) OVER()
+		FROM (
+			SELECT p.id, (
+				SELECT MIN(s.value::text::numeric) FROM jsonb_each(p.supplies) s
+				WHERE jsonb_typeof(s.value) = 'number'
+			) AS lowest
+			FROM printers p
+			JOIN edge_nodes e ON e.id = p.edge_node_id AND e.deleted_at IS NULL
+			WHERE p.enabled AND jsonb_typeof(p.supplies) = 'object'
+		) supplies
+		WHERE lowest <= $1
+		ORDER BY lowest, id
+		LIMIT $2`
+	ids, total, err := queryIDsWithTotal(r.db, query, percent, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list low supply printers: %w", err)
+	}
+	return ids, total, nil
+}
+
+// ListSuspendedPrinterIDs 熔断（暂停分发）的打印机，最早熔断的在前
+func (r *PrinterRepository) ListSuspendedPrinterIDs(limit int) ([]string, int, error) {
+	query := `
+		SELECT id, COUNT(*) OVER()
+		FROM printers
+		WHERE breaker_state = $1
+		ORDER BY breaker_suspended_at, id
+		LIMIT $2`
+	ids, total, err := queryIDsWithTotal(r.db, query, models.BreakerSuspended, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list suspended printers: %w", err)
+	}
+	return ids, total, nil
+}
+
+// ListStalledJobIDs stalled 状态的任务，停滞最久的在前
+func (r *PrintJobRepository) ListStalledJobIDs(limit int) ([]string, int, error) {
+	query := `
+		SELECT id, COUNT(*) OVER()
+		FROM print_jobs
+		WHERE status = $1
+		ORDER BY updated_at, id
+		LIMIT $2`
+	ids, total, err := queryIDsWithTotal(r.db, query, models.JobStatusStalled, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list stalled jobs: %w", err)
+	}
+	return ids, total, nil
+}
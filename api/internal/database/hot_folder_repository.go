@@ -0,0 +1,82 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// HotFolderRepository 共享文件夹已处理文件台账数据访问层
+type HotFolderRepository struct {
+	db *DB
+}
+
+// NewHotFolderRepository 创建共享文件夹台账数据访问层
+func NewHotFolderRepository(db *DB) *HotFolderRepository {
+	return &HotFolderRepository{db: db}
+}
+
+const hotFolderFileColumns = `id, folder, file_name, file_size, modified_at, status, job_id, error, created_at, updated_at`
+
+// scanHotFolderFile 扫描一行台账数据
+func scanHotFolderFile(row rowScanner) (*models.HotFolderFile, error) {
+	file := &models.HotFolderFile{}
+	var jobID, errMsg sql.NullString
+	if err := row.Scan(&file.ID, &file.Folder, &file.FileName, &file.FileSize, &file.ModifiedAt, &file.Status,
+		&jobID, &errMsg, &file.CreatedAt, &file.UpdatedAt); err != nil {
+		return nil, err
+	}
+	file.JobID = jobID.String
+	file.Error = errMsg.String
+	return file, nil
+}
+
+// ClaimHotFolderFile 领取文件：台账中没有该文件时插入 processing 记录并返回 true；
+// 已有记录（已处理，或正在被其他实例处理）时返回已有记录和 false
+// 修改时间按微秒截断，与数据库精度一致
+func (r *HotFolderRepository) ClaimHotFolderFile(folder, fileName string, size int64, modifiedAt time.Time) (*models.HotFolderFile, bool, error) {
+	modifiedAt = modifiedAt.UTC().Truncate(time.Microsecond)
+
+	insert := `
+		INSERT INTO hot_folder_files (folder, file_name, file_size, modified_at, status)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (folder, file_name, file_size, modified_at) DO NOTHING
+		RETURNING ` + hotFolderFileColumns
+	file, err := scanHotFolderFile(r.db.QueryRow(insert, folder, fileName, size, modifiedAt, models.HotFolderFileProcessing))
+	if err == nil {
+		return file, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to claim hot folder file: %w", err)
+	}
+
+	query := `SELECT ` + hotFolderFileColumns + ` FROM hot_folder_files
+		WHERE folder = $1 AND file_name = $2 AND file_size = $3 AND modified_at = $4`
+	file, err = scanHotFolderFile(r.db.QueryRow(query, folder, fileName, size, modifiedAt))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get hot folder file: %w", err)
+	}
+	return file, false, nil
+}
+
+// FinishHotFolderFile 记录文件的处理结果（submitted 或 failed）
+func (r *HotFolderRepository) FinishHotFolderFile(id, status, jobID, errMsg string) error {
+	query := `
+		UPDATE hot_folder_files SET status = $2, job_id = $3, error = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+	if _, err := r.db.Exec(query, id, status, nullIfEmpty(jobID), nullIfEmpty(errMsg)); err != nil {
+		return fmt.Errorf("failed to finish hot folder file: %w", err)
+	}
+	return nil
+}
+
+// ReleaseHotFolderFile 删除 processing 记录，文件在下次扫描时重新提交（用于存储或数据库暂时不可用等可重试的失败）
+func (r *HotFolderRepository) ReleaseHotFolderFile(id string) error {
+	query := `DELETE FROM hot_folder_files WHERE id = $1 AND status = $2`
+	if _, err := r.db.Exec(query, id, models.HotFolderFileProcessing); err != nil {
+		return fmt.Errorf("failed to release hot folder file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"fly-print-cloud/api/internal/importer"
+	"fly-print-cloud/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ImportRepository Edge Node / 打印机批量导入数据访问层
+type ImportRepository struct {
+	db *DB
+}
+
+// NewImportRepository 创建批量导入数据访问层
+func NewImportRepository(db *DB) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+// ExistingEdgeNodes 返回已存在（未删除）的 Edge Node ID 集合
+func (r *ImportRepository) ExistingEdgeNodes(ids []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	rows, err := r.db.Query(`SELECT id FROM edge_nodes WHERE id = ANY($1) AND deleted_at IS NULL`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing edge nodes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan edge node id: %w", err)
+		}
+		existing[id] = true
+	}
+	return existing, rows.Err()
+}
+
+// ExistingPrinters 返回给定 Edge Node 下已存在的打印机（键为 importer.PrinterKey）
+func (r *ImportRepository) ExistingPrinters(nodeIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(nodeIDs) == 0 {
+		return existing, nil
+	}
+
+	rows, err := r.db.Query(`SELECT edge_node_id, name FROM printers WHERE edge_node_id = ANY($1)`, pq.Array(nodeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing printers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var nodeID, name string
+		if err := rows.Scan(&nodeID, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan printer key: %w", err)
+		}
+		existing[importer.PrinterKey(nodeID, name)] = true
+	}
+	return existing, rows.Err()
+}
+
+// ApplyBatch 在同一事务中写入一批导入行，任一失败则整批回滚
+// 导入文件中为空的字段保留已有值；新建的 Edge Node 为离线状态，新建的打印机直接审核通过
+func (r *ImportRepository) ApplyBatch(rows []*importer.Row) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, row := range rows {
+		switch row.RecordType {
+		case importer.RecordEdgeNode:
+			err = upsertImportedEdgeNode(tx, row)
+		case importer.RecordPrinter:
+			err = upsertImportedPrinter(tx, row)
+		default:
+			err = fmt.Errorf("unknown record type %q", row.RecordType)
+		}
+		if err != nil {
+			return fmt.Errorf("row %d (%s): %w", row.Row, row.Key, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.db.invalidateLookupKind(LookupKindEdgeNode)
+	r.db.invalidateLookupKind(LookupKindPrinter)
+	return nil
+}
+
+func upsertImportedEdgeNode(tx *sql.Tx, row *importer.Row) error {
+	node := row.EdgeNode()
+	query := `
+		INSERT INTO edge_nodes (id, name, status, enabled, location, latitude, longitude, ip_address)
+		VALUES ($1, $2, $3, COALESCE($4, TRUE), $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			enabled = COALESCE($4, edge_nodes.enabled),
+			location = COALESCE(EXCLUDED.location, edge_nodes.location),
+			latitude = COALESCE(EXCLUDED.latitude, edge_nodes.latitude),
+			longitude = COALESCE(EXCLUDED.longitude, edge_nodes.longitude),
+			ip_address = COALESCE(EXCLUDED.ip_address, edge_nodes.ip_address),
+			deleted_at = NULL`
+
+	_, err := tx.Exec(query, node.ID, node.Name, node.Status, row.Record.Enabled,
+		node.Location, node.Latitude, node.Longitude, node.IPAddress)
+	return err
+}
+
+func upsertImportedPrinter(tx *sql.Tx, row *importer.Row) error {
+	printer := row.Printer()
+	slug, err := findFreeSlug(tx, PrinterSlugBase(printer.Name, printer.EdgeNodeID))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO printers (
+			id, name, display_name, model, serial_number, status, enabled,
+			ip_address, mac_address, latitude, longitude, location,
+			edge_node_id, slug, approval_status
+		) VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, TRUE), $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (name, edge_node_id) DO UPDATE SET
+			display_name = COALESCE(EXCLUDED.display_name, printers.display_name),
+			model = COALESCE(EXCLUDED.model, printers.model),
+			serial_number = COALESCE(EXCLUDED.serial_number, printers.serial_number),
+			enabled = COALESCE($7, printers.enabled),
+			ip_address = COALESCE(EXCLUDED.ip_address, printers.ip_address),
+			mac_address = COALESCE(EXCLUDED.mac_address, printers.mac_address),
+			latitude = COALESCE(EXCLUDED.latitude, printers.latitude),
+			longitude = COALESCE(EXCLUDED.longitude, printers.longitude),
+			location = COALESCE(EXCLUDED.location, printers.location),
+			slug = COALESCE(printers.slug, EXCLUDED.slug)`
+
+	_, err = tx.Exec(query,
+		uuid.New().String(), printer.Name, nullIfEmpty(printer.DisplayName), nullIfEmpty(printer.Model),
+		printer.SerialNumber, printer.Status, row.Record.Enabled,
+		printer.IPAddress, printer.MACAddress, printer.Latitude, printer.Longitude, printer.Location,
+		printer.EdgeNodeID, slug, models.PrinterApprovalApproved,
+	)
+	return err
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// InventoryRepository 资产清单导出数据访问层（打印机与 Edge Node）
+type InventoryRepository struct {
+	db *DB
+}
+
+// NewInventoryRepository 创建资产清单导出数据访问层
+func NewInventoryRepository(db *DB) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+
+// inventoryQuery Edge Node 与打印机合并查询，按 updated_at 排序，便于调用方以最后一条记录的时间作为下次增量同步的起点
+// $1 为空时导出全部未删除的记录；不为空时导出 updated_at 晚于 $1 的记录，包括已软删除的 Edge Node
+// 打印机为硬删除，删除后不再出现在导出中
+const inventoryQuery = `
+	SELECT type, id, name, display_name, model, serial_number, firmware_version,
+	       mac_address, ip_address, location, latitude, longitude, edge_node_id,
+	       created_at, updated_at, deleted_at
+	FROM (
+		SELECT '` + models.InventoryTypeEdgeNode + `' AS type, id, name, NULL::varchar AS display_name,
+		       NULL::varchar AS model, NULL::varchar AS serial_number, version AS firmware_version,
+		       mac_address, host(ip_address) AS ip_address, location, latitude, longitude, NULL::varchar AS edge_node_id,
+		       created_at, updated_at, deleted_at
+		FROM edge_nodes
+		WHERE ($1::timestamp IS NULL AND deleted_at IS NULL) OR updated_at > $1::timestamp
+		UNION ALL
+		SELECT '` + models.InventoryTypePrinter + `', id::text, name, NULLIF(display_name, ''),
+		       NULLIF(model, ''), serial_number, firmware_version,
+		       mac_address, host(ip_address), location, latitude, longitude, edge_node_id,
+		       created_at, updated_at, NULL
+		FROM printers
+		WHERE $1::timestamp IS NULL OR updated_at > $1::timestamp
+	) inventory
+	ORDER BY updated_at, type, id`
+
+// ForEachRecord 逐条读取资产清单并回调 fn，不一次性加载到内存；fn 返回错误时停止并返回该错误
+// since 为空时导出全部记录，否则只导出 since 之后更新的记录
+func (r *InventoryRepository) ForEachRecord(since *time.Time, fn func(*models.InventoryRecord) error) error {
+	rows, err := r.db.Query(inventoryQuery, since)
+	if err != nil {
+		return fmt.Errorf("failed to query inventory: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record := &models.InventoryRecord{}
+		if err := rows.Scan(
+			&record.Type, &record.ID, &record.Name, &record.DisplayName, &record.Model, &record.SerialNumber, &record.FirmwareVersion,
+			&record.MACAddress, &record.IPAddress, &record.Location, &record.Latitude, &record.Longitude, &record.EdgeNodeID,
+			&record.CreatedAt, &record.UpdatedAt, &record.DeletedAt,
+		); err != nil {
+			return fmt.Errorf("failed to scan inventory record: %w", err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
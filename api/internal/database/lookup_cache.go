@@ -0,0 +1,91 @@
+package database
+
+import (
+	"errors"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/google/uuid"
+)
+
+// 查询缓存中的对象类型
+const (
+	LookupKindPrinter  = "printer"
+	LookupKindEdgeNode = "edge_node"
+)
+
+// LookupCache 任务提交和分发路径上打印机、Edge Node 查询的读穿透缓存（见 lookup.Cache）
+// 打印机和节点的写操作提交后清除对应缓存
+type LookupCache interface {
+	Fetch(kind, id string, dest interface{}, load func() (interface{}, error)) error
+	Invalidate(kind string, ids ...string)
+	InvalidateKind(kind string)
+}
+
+// SetLookupCache 设置查询缓存，未设置时 *Cached 查询直接访问数据库
+func (db *DB) SetLookupCache(cache LookupCache) {
+	db.lookups = cache
+}
+
+// invalidateLookup 清除指定对象的查询缓存
+func (db *DB) invalidateLookup(kind string, ids ...string) {
+	if db.lookups != nil {
+		db.lookups.Invalidate(kind, ids...)
+	}
+}
+
+// invalidateLookupKind 清除某类对象的全部查询缓存
+func (db *DB) invalidateLookupKind(kind string) {
+	if db.lookups != nil {
+		db.lookups.InvalidateKind(kind)
+	}
+}
+
+// GetPrinterByIDCached 任务提交和分发路径使用的打印机查询，结果经过查询缓存
+// 只缓存按 ID 的查询（按 slug 查询直接访问数据库），其他路径使用 GetPrinterByID
+func (r *PrinterRepository) GetPrinterByIDCached(printerID string) (*models.Printer, error) {
+	if r.db.lookups == nil {
+		return r.GetPrinterByID(printerID)
+	}
+	if _, err := uuid.Parse(printerID); err != nil {
+		return r.GetPrinterByID(printerID)
+	}
+	printer := &models.Printer{}
+	err := r.db.lookups.Fetch(LookupKindPrinter, printerID, printer, func() (interface{}, error) {
+		return r.GetPrinterByID(printerID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return printer, nil
+}
+
+// GetEdgeNodeByIDCached 任务提交和分发路径使用的 Edge Node 查询，结果经过查询缓存
+// 心跳时间、遥测数据的更新不清除缓存，这些字段最多延迟 lookup_cache.ttl
+func (r *EdgeNodeRepository) GetEdgeNodeByIDCached(id string) (*models.EdgeNode, error) {
+	if r.db.lookups == nil {
+		return r.GetEdgeNodeByID(id)
+	}
+	node := &models.EdgeNode{}
+	err := r.db.lookups.Fetch(LookupKindEdgeNode, id, node, func() (interface{}, error) {
+		return r.GetEdgeNodeByID(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// IsEdgeNodeActiveCached 与 IsEdgeNodeActive 相同，节点信息经过查询缓存
+func (r *PrinterRepository) IsEdgeNodeActiveCached(edgeNodeID string) (bool, error) {
+	if r.db.lookups == nil {
+		return r.IsEdgeNodeActive(edgeNodeID)
+	}
+	node, err := NewEdgeNodeRepository(r.db).GetEdgeNodeByIDCached(edgeNodeID)
+	if errors.Is(err, ErrEdgeNodeNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return node.Enabled && !node.Draining, nil
+}
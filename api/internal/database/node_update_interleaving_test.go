@@ -0,0 +1,150 @@
+package database
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestPrinterStatusDoesNotRevertAdminEdit 节点状态上报读取打印机后，管理员修改了显示名称并禁用打印机，
+// 随后写入的状态上报只更新状态字段，不会用旧数据覆盖管理员的修改，也不会让管理员手中的版本号失效
+func TestPrinterStatusDoesNotRevertAdminEdit(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrinterRepository(db)
+	printerID := createTestPrinter(t, db)
+
+	// 节点路径先读取打印机（修改前的快照）
+	stale, err := repo.GetPrinterByID(printerID)
+	if err != nil {
+		t.Fatalf("get printer: %v", err)
+	}
+
+	// 管理员修改
+	admin, err := repo.GetPrinterByID(printerID)
+	if err != nil {
+		t.Fatalf("get printer: %v", err)
+	}
+	admin.DisplayName = "Lobby (admin)"
+	admin.Enabled = false
+	if err := repo.UpdatePrinter(admin); err != nil {
+		t.Fatalf("admin update: %v", err)
+	}
+
+	// 节点的状态上报随后写入
+	if err := repo.UpdateStatusAndQueue(printerID, models.PrinterStatusPrinting, 3, map[string]interface{}{"toner": 40}, ""); err != nil {
+		t.Fatalf("UpdateStatusAndQueue: %v", err)
+	}
+
+	got, err := repo.GetPrinterByID(printerID)
+	if err != nil {
+		t.Fatalf("get printer: %v", err)
+	}
+	if got.DisplayName != "Lobby (admin)" || got.Enabled {
+		t.Fatalf("admin edit reverted: display_name %q, enabled %v", got.DisplayName, got.Enabled)
+	}
+	if got.Status != models.PrinterStatusPrinting || got.EdgeQueueLength != 3 {
+		t.Fatalf("status update lost: status %q, edge queue %d", got.Status, got.EdgeQueueLength)
+	}
+	if got.RowVersion != admin.RowVersion {
+		t.Fatalf("row_version = %d, want %d: status reports must not invalidate the admin's version", got.RowVersion, admin.RowVersion)
+	}
+
+	// 旧的写法（用快照整行更新）现在因版本号不一致而失败，不会静默覆盖
+	stale.Status = models.PrinterStatusReady
+	if err := repo.UpdatePrinter(stale); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("full update from a stale snapshot = %v, want ErrVersionConflict", err)
+	}
+}
+
+// TestPrinterStatusConcurrentWithAdminEdit 状态上报与管理员修改并发执行时，管理员的修改总能保留
+func TestPrinterStatusConcurrentWithAdminEdit(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrinterRepository(db)
+	printerID := createTestPrinter(t, db)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := repo.UpdateStatusAndQueue(printerID, models.PrinterStatusReady, i, nil, ""); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	admin, err := repo.GetPrinterByID(printerID)
+	if err != nil {
+		t.Fatalf("get printer: %v", err)
+	}
+	admin.DisplayName = "Renamed during reports"
+	if err := repo.UpdatePrinter(admin); err != nil {
+		t.Fatalf("admin update during status reports: %v", err)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("UpdateStatusAndQueue: %v", err)
+	}
+
+	if got, _ := repo.GetPrinterByID(printerID); got.DisplayName != "Renamed during reports" {
+		t.Fatalf("display_name = %q after concurrent status reports", got.DisplayName)
+	}
+}
+
+// TestHeartbeatDoesNotRevertAdminEdit 心跳遥测写入不覆盖管理员修改的节点名称和启用状态，也不递增 row_version
+func TestHeartbeatDoesNotRevertAdminEdit(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewEdgeNodeRepository(db)
+	printer, err := NewPrinterRepository(db).GetPrinterByID(createTestPrinter(t, db))
+	if err != nil {
+		t.Fatalf("get printer: %v", err)
+	}
+	nodeID := printer.EdgeNodeID
+
+	stale, err := repo.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	admin, err := repo.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	admin.Name = "Branch office"
+	admin.Enabled = false
+	if err := repo.UpdateEdgeNode(admin); err != nil {
+		t.Fatalf("admin update: %v", err)
+	}
+
+	if err := repo.UpdateEdgeNodeHeartbeatAndTelemetry(nodeID, "good", 42); err != nil {
+		t.Fatalf("UpdateEdgeNodeHeartbeatAndTelemetry: %v", err)
+	}
+	got, err := repo.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	if got.Name != "Branch office" || got.Enabled {
+		t.Fatalf("admin edit reverted: name %q, enabled %v", got.Name, got.Enabled)
+	}
+	if got.Latency == nil || *got.Latency != 42 || got.ConnectionQuality == nil || *got.ConnectionQuality != "good" || got.LastHeartbeat == nil {
+		t.Fatalf("telemetry lost: %+v", got)
+	}
+	if got.RowVersion != admin.RowVersion {
+		t.Fatalf("row_version = %d, want %d", got.RowVersion, admin.RowVersion)
+	}
+
+	// 空的连接质量保留原值
+	if err := repo.UpdateEdgeNodeHeartbeatAndTelemetry(nodeID, "", 7); err != nil {
+		t.Fatalf("UpdateEdgeNodeHeartbeatAndTelemetry: %v", err)
+	}
+	if got, _ := repo.GetEdgeNodeByID(nodeID); got.ConnectionQuality == nil || *got.ConnectionQuality != "good" || got.Latency == nil || *got.Latency != 7 {
+		t.Fatalf("telemetry after empty quality = %v / %v", got.ConnectionQuality, got.Latency)
+	}
+
+	stale.Name = "Stale"
+	if err := repo.UpdateEdgeNode(stale); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("full update from a stale snapshot = %v, want ErrVersionConflict", err)
+	}
+}
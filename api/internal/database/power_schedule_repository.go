@@ -0,0 +1,194 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// PowerScheduleRepository 打印机节能计划数据访问层
+type PowerScheduleRepository struct {
+	db *DB
+}
+
+// NewPowerScheduleRepository 创建打印机节能计划数据访问层
+func NewPowerScheduleRepository(db *DB) *PowerScheduleRepository {
+	return &PowerScheduleRepository{db: db}
+}
+
+// powerScheduleColumns 节能计划查询列（与 scanPowerSchedule 的扫描顺序保持一致）
+const powerScheduleColumns = `id, printer_id, edge_node_id, days, start_time, end_time, timezone, enabled, created_at, updated_at`
+
+// scanPowerSchedule 扫描一行节能计划数据
+func scanPowerSchedule(row rowScanner) (*models.PrinterPowerSchedule, error) {
+	schedule := &models.PrinterPowerSchedule{}
+	var printerID, edgeNodeID sql.NullString
+	var days pq.Int64Array
+
+	err := row.Scan(&schedule.ID, &printerID, &edgeNodeID, &days, &schedule.StartTime, &schedule.EndTime,
+		&schedule.Timezone, &schedule.Enabled, &schedule.CreatedAt, &schedule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if printerID.Valid {
+		schedule.PrinterID = &printerID.String
+	}
+	if edgeNodeID.Valid {
+		schedule.EdgeNodeID = &edgeNodeID.String
+	}
+	schedule.Days = make([]int, len(days))
+	for i, day := range days {
+		schedule.Days[i] = int(day)
+	}
+	return schedule, nil
+}
+
+// daysArg 星期数组参数
+func daysArg(days []int) interface{} {
+	values := make(pq.Int64Array, len(days))
+	for i, day := range days {
+		values[i] = int64(day)
+	}
+	return values
+}
+
+// UpsertSchedule 创建或替换打印机/Edge Node 的节能计划（PrinterID 与 EdgeNodeID 只能设置其一）
+func (r *PowerScheduleRepository) UpsertSchedule(schedule *models.PrinterPowerSchedule) error {
+	var conflict string
+	switch {
+	case schedule.PrinterID != nil && schedule.EdgeNodeID == nil:
+		conflict = `(printer_id) WHERE printer_id IS NOT NULL`
+	case schedule.EdgeNodeID != nil && schedule.PrinterID == nil:
+		conflict = `(edge_node_id) WHERE edge_node_id IS NOT NULL`
+	default:
+		return fmt.Errorf("power schedule must target exactly one printer or edge node")
+	}
+
+	query := `
+		INSERT INTO printer_power_schedules (printer_id, edge_node_id, days, start_time, end_time, timezone, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT ` + conflict + ` DO UPDATE SET
+			days = EXCLUDED.days, start_time = EXCLUDED.start_time, end_time = EXCLUDED.end_time,
+			timezone = EXCLUDED.timezone, enabled = EXCLUDED.enabled, updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(query, schedule.PrinterID, schedule.EdgeNodeID, daysArg(schedule.Days),
+		schedule.StartTime, schedule.EndTime, schedule.Timezone, schedule.Enabled).
+		Scan(&schedule.ID, &schedule.CreatedAt, &schedule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert power schedule: %w", err)
+	}
+	return nil
+}
+
+// GetPrinterSchedule 获取打印机自身的节能计划，不存在时返回 nil
+func (r *PowerScheduleRepository) GetPrinterSchedule(printerID string) (*models.PrinterPowerSchedule, error) {
+	return r.getSchedule(`printer_id = $1`, printerID)
+}
+
+// GetEdgeNodeSchedule 获取 Edge Node 的节能计划，不存在时返回 nil
+func (r *PowerScheduleRepository) GetEdgeNodeSchedule(edgeNodeID string) (*models.PrinterPowerSchedule, error) {
+	return r.getSchedule(`edge_node_id = $1`, edgeNodeID)
+}
+
+// GetEffectiveSchedule 获取打印机生效的节能计划：打印机自身的计划优先于 Edge Node 的计划
+func (r *PowerScheduleRepository) GetEffectiveSchedule(printerID, edgeNodeID string) (*models.PrinterPowerSchedule, error) {
+	query := `SELECT ` + powerScheduleColumns + ` FROM printer_power_schedules
+		WHERE printer_id = $1 OR edge_node_id = $2
+		ORDER BY printer_id IS NULL
+		LIMIT 1`
+
+	schedule, err := scanPowerSchedule(r.db.QueryRow(query, printerID, edgeNodeID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get effective power schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+func (r *PowerScheduleRepository) getSchedule(condition string, arg string) (*models.PrinterPowerSchedule, error) {
+	query := `SELECT ` + powerScheduleColumns + ` FROM printer_power_schedules WHERE ` + condition
+
+	schedule, err := scanPowerSchedule(r.db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get power schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// DeletePrinterSchedule 删除打印机自身的节能计划，返回是否存在
+func (r *PowerScheduleRepository) DeletePrinterSchedule(printerID string) (bool, error) {
+	return r.deleteSchedule(`printer_id = $1`, printerID)
+}
+
+// DeleteEdgeNodeSchedule 删除 Edge Node 的节能计划，返回是否存在
+func (r *PowerScheduleRepository) DeleteEdgeNodeSchedule(edgeNodeID string) (bool, error) {
+	return r.deleteSchedule(`edge_node_id = $1`, edgeNodeID)
+}
+
+func (r *PowerScheduleRepository) deleteSchedule(condition string, arg string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM printer_power_schedules WHERE `+condition, arg)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete power schedule: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ListPowerTargets 列出生效计划已启用的打印机（打印机自身的计划优先，自身计划被停用时不回退到节点计划）
+func (r *PowerScheduleRepository) ListPowerTargets() ([]*models.PrinterPowerTarget, error) {
+	query := `
+		SELECT p.id, p.name, p.edge_node_id, p.status, p.power_state,
+		       s.id, s.printer_id, s.edge_node_id, s.days, s.start_time, s.end_time, s.timezone, s.enabled, s.created_at, s.updated_at
+		FROM printers p
+		JOIN LATERAL (
+			SELECT ` + powerScheduleColumns + ` FROM printer_power_schedules ps
+			WHERE ps.printer_id = p.id OR ps.edge_node_id = p.edge_node_id
+			ORDER BY ps.printer_id IS NULL
+			LIMIT 1
+		) s ON TRUE
+		WHERE s.enabled AND p.approval_status = $1`
+
+	rows, err := r.db.Query(query, models.PrinterApprovalApproved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list power targets: %w", err)
+	}
+	defer rows.Close()
+
+	var targets []*models.PrinterPowerTarget
+	for rows.Next() {
+		target := &models.PrinterPowerTarget{}
+		var powerState sql.NullString
+		schedule, err := scanPowerSchedule(prefixScanner{row: rows, prefix: []interface{}{
+			&target.PrinterID, &target.PrinterName, &target.EdgeNodeID, &target.Status, &powerState,
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan power target: %w", err)
+		}
+		target.PowerState = powerState.String
+		target.Schedule = schedule
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
+
+// prefixScanner 在复用的扫描函数前额外扫描若干列
+type prefixScanner struct {
+	row    rowScanner
+	prefix []interface{}
+}
+
+func (s prefixScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(s.prefix, dest...)...)
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/google/uuid"
+)
+
+// TestBilledPagesSQLMatchesModel 费用统计的 SQL 计费页数与 PrintJob.BilledPages 一致，部分完成的任务按已输出页数计入用户用量
+func TestBilledPagesSQLMatchesModel(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	printerID := createTestPrinter(t, db)
+	user := "quota-" + uuid.New().String()[:8]
+
+	jobs := []struct {
+		status  models.JobStatus
+		pages   int
+		copies  int
+		printed int
+	}{
+		{models.JobStatusCompleted, 10, 2, 0},
+		{models.JobStatusPartiallyCompleted, 100, 1, 60},
+		{models.JobStatusPartiallyCompleted, 10, 3, 14},
+		{models.JobStatusPartiallyCompleted, 10, 1, 50}, // 超报的页数不超过页数 × 份数
+		{models.JobStatusPartiallyCompleted, 0, 1, 7},   // 页数未知
+		{models.JobStatusFailed, 10, 1, 4},              // 不计费
+	}
+	want := 0
+	for _, spec := range jobs {
+		job := createTestJob(t, db, printerID, spec.status)
+		mustExec(t, db, `UPDATE print_jobs SET user_name = $2, page_count = $3, copies = $4, pages_printed = $5 WHERE id = $1`,
+			job.ID, user, spec.pages, spec.copies, spec.printed)
+		job.PageCount, job.Copies, job.PagesPrinted = spec.pages, spec.copies, spec.printed
+		if spec.status != models.JobStatusFailed {
+			want += job.BilledPages()
+		}
+	}
+	if want != 20+60+14+10+7 {
+		t.Fatalf("model billed pages = %d", want)
+	}
+
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	stats, err := repo.CostStatsByUser(start, end)
+	if err != nil {
+		t.Fatalf("CostStatsByUser: %v", err)
+	}
+	var stat *models.CostStat
+	for _, s := range stats {
+		if s.Name == user {
+			stat = s
+		}
+	}
+	if stat == nil || stat.Pages != want || stat.JobCount != 5 || stat.PartialJobCount != 4 {
+		t.Fatalf("user stat = %+v; want %d pages over 5 jobs, 4 partial", stat, want)
+	}
+
+	byPrinter, err := repo.CostStatsByPrinter(start, end)
+	if err != nil {
+		t.Fatalf("CostStatsByPrinter: %v", err)
+	}
+	for _, s := range byPrinter {
+		if s.Key == printerID && s.Pages != want {
+			t.Fatalf("printer stat = %+v; want %d pages", s, want)
+		}
+	}
+}
+
+// TestCostStatsByUserGroupsByUserID 费用按 user_id 统计：同名的不同用户分开，改名前后的任务合并，名称取当前用户名
+func TestCostStatsByUserGroupsByUserID(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	users := NewUserRepository(db)
+	printerID := createTestPrinter(t, db)
+
+	alice := createTestUser(t, users, "operator")
+	other := createTestUser(t, users, "operator")
+	jobs := []struct {
+		userID   interface{}
+		userName string
+	}{
+		{alice.ID, "alice"},
+		{alice.ID, "alice-before-rename"},
+		{other.ID, "alice"},
+		{nil, "api-client"},
+	}
+	for _, spec := range jobs {
+		job := createTestJob(t, db, printerID, models.JobStatusCompleted)
+		mustExec(t, db, `UPDATE print_jobs SET user_id = $2, user_name = $3, page_count = 1, copies = 1 WHERE id = $1`,
+			job.ID, spec.userID, spec.userName)
+	}
+
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	stats, err := repo.CostStatsByUser(start, end)
+	if err != nil {
+		t.Fatalf("CostStatsByUser: %v", err)
+	}
+	byKey := map[string]*models.CostStat{}
+	for _, s := range stats {
+		byKey[s.Key] = s
+	}
+	if len(stats) != 3 {
+		t.Fatalf("CostStatsByUser = %d groups, want 3", len(stats))
+	}
+	if s := byKey[alice.ID]; s == nil || s.JobCount != 2 || s.Name != alice.Username {
+		t.Fatalf("alice stat = %+v, want 2 jobs named %s", s, alice.Username)
+	}
+	if s := byKey[other.ID]; s == nil || s.JobCount != 1 || s.Name != other.Username {
+		t.Fatalf("other stat = %+v, want 1 job named %s", s, other.Username)
+	}
+	if s := byKey["api-client"]; s == nil || s.JobCount != 1 || s.Name != "api-client" {
+		t.Fatalf("job without user_id = %+v, want grouped by user name", s)
+	}
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestListPrintJobsAfterPaging 按状态过滤的游标分页：created_at 相同的任务按 id 排序，
+// 翻页之间新建的任务不会导致已有任务重复或遗漏
+func TestListPrintJobsAfterPaging(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	printerID := createTestPrinter(t, db)
+
+	// 三个时间点各有多个任务，pending 任务穿插其中但被过滤掉
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	offsets := []int{0, 0, 0, 1, 2, 2, 3}
+	var expected []*models.PrintJob
+	for i, offset := range offsets {
+		createdAt := base.Add(-time.Duration(offset) * time.Second)
+		job := createTestJob(t, db, printerID, models.JobStatusCompleted)
+		mustExec(t, db, `UPDATE print_jobs SET created_at = $2 WHERE id = $1`, job.ID, createdAt)
+		job.CreatedAt = createdAt
+		expected = append(expected, job)
+		if i%2 == 0 {
+			pending := createTestJob(t, db, printerID, models.JobStatusPending)
+			mustExec(t, db, `UPDATE print_jobs SET created_at = $2 WHERE id = $1`, pending.ID, createdAt)
+		}
+	}
+	sort.Slice(expected, func(i, j int) bool {
+		if !expected[i].CreatedAt.Equal(expected[j].CreatedAt) {
+			return expected[i].CreatedAt.After(expected[j].CreatedAt)
+		}
+		return expected[i].ID > expected[j].ID
+	})
+
+	var got []*models.PrintJob
+	var cursor *JobCursor
+	for page := 0; page < len(expected)+1; page++ {
+		jobs, err := repo.ListPrintJobsAfter(2, cursor, string(models.JobStatusCompleted), "", "", "", nil)
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		if len(jobs) == 0 {
+			break
+		}
+		got = append(got, jobs...)
+		last := jobs[len(jobs)-1]
+		cursor = &JobCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+
+		// 翻页之间有新任务提交
+		createTestJob(t, db, printerID, models.JobStatusCompleted)
+		createTestJob(t, db, printerID, models.JobStatusPending)
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("paged %d jobs, want %d", len(got), len(expected))
+	}
+	seen := map[string]bool{}
+	for i, job := range got {
+		if seen[job.ID] {
+			t.Fatalf("job %s returned twice", job.ID)
+		}
+		seen[job.ID] = true
+		if job.ID != expected[i].ID || job.Status != models.JobStatusCompleted {
+			t.Fatalf("position %d = %s (%s at %s), want %s at %s",
+				i, job.ID, job.Status, job.CreatedAt, expected[i].ID, expected[i].CreatedAt)
+		}
+	}
+}
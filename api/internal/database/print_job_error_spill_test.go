@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/textlimit"
+)
+
+// spillStorage 记录转存的对象
+type spillStorage struct {
+	mu      sync.Mutex
+	objects map[string]string
+}
+
+func (s *spillStorage) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = string(data)
+	return nil
+}
+
+func (s *spillStorage) Get(context.Context, string) (io.ReadCloser, error) { return nil, nil }
+func (s *spillStorage) Delete(context.Context, string) error               { return nil }
+func (s *spillStorage) SignedURL(context.Context, string, time.Duration) (string, error) {
+	return "", nil
+}
+
+// TestErrorMessageSpillKey 超长错误信息转存后记录对象键；截断后的内容原样写回时保留对象键，错误信息变化时随之更新
+func TestErrorMessageSpillKey(t *testing.T) {
+	db := openTestDB(t)
+	cfg := &config.Config{}
+	cfg.TextLimits = config.TextLimitsConfig{MaxLength: config.MinTextLimit, SpillToStorage: true, SpillPrefix: "job-errors"}
+	store := &spillStorage{objects: map[string]string{}}
+	db.SetTextLimiter(textlimit.NewLimiter(config.NewStore(cfg), store))
+	repo := NewPrintJobRepository(db)
+
+	job := createTestJob(t, db, createTestPrinter(t, db), models.JobStatusPrinting)
+	long := strings.Repeat("fuser error ", 100)
+	if err := repo.UpdateJobErrorMessage(job.ID, long); err != nil {
+		t.Fatalf("UpdateJobErrorMessage: %v", err)
+	}
+	spilled, err := repo.GetPrintJobByID(job.ID)
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if spilled.ErrorMessageKey == "" || store.objects[spilled.ErrorMessageKey] != long {
+		t.Fatalf("error_message_key = %q, want the key of the full message", spilled.ErrorMessageKey)
+	}
+	if len(spilled.ErrorMessage) > config.MinTextLimit {
+		t.Fatalf("stored error_message is %d bytes, want at most %d", len(spilled.ErrorMessage), config.MinTextLimit)
+	}
+
+	// 整条更新时截断后的错误信息原样写回
+	spilled.Status = models.JobStatusFailed
+	if err := repo.UpdatePrintJob(spilled); err != nil {
+		t.Fatalf("UpdatePrintJob: %v", err)
+	}
+	rewritten, err := repo.GetPrintJobByID(job.ID)
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if rewritten.ErrorMessageKey != spilled.ErrorMessageKey || len(store.objects) != 1 {
+		t.Fatalf("after rewrite key = %q (%d objects), want %q kept", rewritten.ErrorMessageKey, len(store.objects), spilled.ErrorMessageKey)
+	}
+
+	if err := repo.UpdateJobErrorMessage(job.ID, "paper jam"); err != nil {
+		t.Fatalf("UpdateJobErrorMessage: %v", err)
+	}
+	changed, err := repo.GetPrintJobByID(job.ID)
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if changed.ErrorMessage != "paper jam" || changed.ErrorMessageKey != "" {
+		t.Fatalf("after change = %q (key %q), want the short message without a key", changed.ErrorMessage, changed.ErrorMessageKey)
+	}
+}
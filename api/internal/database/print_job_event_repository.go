@@ -0,0 +1,179 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// PrintJobEventRepository 打印任务时间线数据访问层
+type PrintJobEventRepository struct {
+	db *DB
+}
+
+// NewPrintJobEventRepository 创建打印任务时间线数据访问层
+func NewPrintJobEventRepository(db *DB) *PrintJobEventRepository {
+	return &PrintJobEventRepository{db: db}
+}
+
+// printJobEventColumns 时间线事件查询列（与 scanPrintJobEvent 的扫描顺序保持一致）
+const printJobEventColumns = `id, job_id, event_type, status, progress, actor, message, details, created_at`
+
+// scanPrintJobEvent 扫描一行时间线事件
+func scanPrintJobEvent(row rowScanner) (*models.PrintJobEvent, error) {
+	event := &models.PrintJobEvent{}
+	var status, actor, message sql.NullString
+	var progress sql.NullInt64
+	var detailsJSON []byte
+
+	if err := row.Scan(&event.ID, &event.JobID, &event.Type, &status, &progress, &actor, &message, &detailsJSON, &event.CreatedAt); err != nil {
+		return nil, err
+	}
+	event.Status = models.JobStatus(status.String)
+	event.Actor = actor.String
+	event.Message = message.String
+	if progress.Valid {
+		value := int(progress.Int64)
+		event.Progress = &value
+	}
+	if len(detailsJSON) > 0 {
+		if err := json.Unmarshal(detailsJSON, &event.Details); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event details: %w", err)
+		}
+	}
+	return event, nil
+}
+
+// eventArgs 写入事件的公共参数（可空字段转换为 NULL）
+// 消息按文本字段长度限制截断（不转存，完整内容以任务的错误信息为准）
+func (r *PrintJobEventRepository) eventArgs(event *models.PrintJobEvent) ([]interface{}, error) {
+	var details interface{}
+	if len(event.Details) > 0 {
+		encoded, err := json.Marshal(event.Details)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal event details: %w", err)
+		}
+		details = string(encoded)
+	}
+	message, _ := r.db.limitText(event.JobID, "event_message", event.Message, false)
+	return []interface{}{
+		event.JobID, event.Type, nullIfEmpty(string(event.Status)), event.Progress,
+		nullIfEmpty(event.Actor), nullIfEmpty(message), details,
+	}, nil
+}
+
+// CreateEvent 写入时间线事件
+func (r *PrintJobEventRepository) CreateEvent(event *models.PrintJobEvent) error {
+	args, err := r.eventArgs(event)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO print_job_events (job_id, event_type, status, progress, actor, message, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb)
+		RETURNING id, created_at`
+
+	if err := r.db.QueryRow(query, args...).Scan(&event.ID, &event.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create print job event: %w", err)
+	}
+	return nil
+}
+
+// Record 写入时间线事件，失败只记录日志，不影响业务流程
+func (r *PrintJobEventRepository) Record(event *models.PrintJobEvent) {
+	if r == nil {
+		return
+	}
+	if err := r.CreateEvent(event); err != nil {
+		log.Printf("Failed to record %s event for job %s: %v", event.Type, event.JobID, err)
+	}
+}
+
+// RecordStatusChange 记录 Edge Node 上报的状态变化；与该任务最近一条事件的状态相同时不重复记录
+// 失败只记录日志
+func (r *PrintJobEventRepository) RecordStatusChange(event *models.PrintJobEvent) {
+	if r == nil {
+		return
+	}
+	args, err := r.eventArgs(event)
+	if err == nil {
+		query := `
+			INSERT INTO print_job_events (job_id, event_type, status, progress, actor, message, details)
+			SELECT $1::uuid, $2::varchar, $3::varchar, $4::integer, $5::varchar, $6::text, $7::jsonb
+			WHERE (
+				SELECT status FROM print_job_events
+				WHERE job_id = $1 AND status IS NOT NULL
+				ORDER BY created_at DESC, id DESC LIMIT 1
+			) IS DISTINCT FROM $3`
+		_, err = r.db.Exec(query, args...)
+	}
+	if err != nil {
+		log.Printf("Failed to record status event for job %s: %v", event.JobID, err)
+	}
+}
+
+// RecordProgress 记录进度里程碑（每 models.JobProgressMilestone 记录一次，同一里程碑只记录一次）
+// 失败只记录日志
+func (r *PrintJobEventRepository) RecordProgress(jobID string, status models.JobStatus, progress int, actor string) {
+	if r == nil {
+		return
+	}
+	milestone := progress / models.JobProgressMilestone * models.JobProgressMilestone
+	if milestone <= 0 || milestone >= 100 {
+		return
+	}
+
+	query := `
+		INSERT INTO print_job_events (job_id, event_type, status, progress, actor, message)
+		SELECT $1::uuid, $2::varchar, $3::varchar, $4::integer, $5::varchar, $6::text
+		WHERE NOT EXISTS (
+			SELECT 1 FROM print_job_events WHERE job_id = $1 AND event_type = $2 AND progress = $4
+		)`
+	_, err := r.db.Exec(query, jobID, models.JobEventProgress, status, milestone, nullIfEmpty(actor),
+		fmt.Sprintf("打印进度 %d%%", milestone))
+	if err != nil {
+		log.Printf("Failed to record progress event for job %s: %v", jobID, err)
+	}
+}
+
+// ListEvents 按时间顺序获取任务的全部时间线事件
+func (r *PrintJobEventRepository) ListEvents(jobID string) ([]*models.PrintJobEvent, error) {
+	query := `
+		SELECT ` + printJobEventColumns + `
+		FROM print_job_events WHERE job_id = $1
+		ORDER BY created_at ASC, id ASC`
+	return r.queryEvents(query, jobID)
+}
+
+// ListRecentEvents 获取任务最近的 limit 条事件（按时间顺序返回）
+func (r *PrintJobEventRepository) ListRecentEvents(jobID string, limit int) ([]*models.PrintJobEvent, error) {
+	query := `
+		SELECT * FROM (
+			SELECT ` + printJobEventColumns + `
+			FROM print_job_events WHERE job_id = $1
+			ORDER BY created_at DESC, id DESC LIMIT $2
+		) recent ORDER BY created_at ASC, id ASC`
+	return r.queryEvents(query, jobID, limit)
+}
+
+func (r *PrintJobEventRepository) queryEvents(query string, args ...interface{}) ([]*models.PrintJobEvent, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query print job events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.PrintJobEvent{}
+	for rows.Next() {
+		event, err := scanPrintJobEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan print job event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestPatchPrintJobFields 每个字段单独更新后重新读取，只有该字段发生变化
+func TestPatchPrintJobFields(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	printerID := createTestPrinter(t, db)
+
+	str := func(s string) *string { return &s }
+	num := func(n int) *int { return &n }
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	size := int64(4096)
+	cost := 1.5
+
+	tests := []struct {
+		name   string
+		update models.PrintJobUpdate
+		check  func(job *models.PrintJob) bool
+	}{
+		{"name", models.PrintJobUpdate{Name: str("renamed.pdf")}, func(j *models.PrintJob) bool { return j.Name == "renamed.pdf" }},
+		{"display_name", models.PrintJobUpdate{DisplayName: str("Quarterly report")}, func(j *models.PrintJob) bool { return j.DisplayName == "Quarterly report" }},
+		{"file_path", models.PrintJobUpdate{FilePath: str("/data/renamed.pdf")}, func(j *models.PrintJob) bool { return j.FilePath == "/data/renamed.pdf" }},
+		{"file_url", models.PrintJobUpdate{FileURL: str("https://files.example.com/v2.pdf")}, func(j *models.PrintJob) bool { return j.FileURL == "https://files.example.com/v2.pdf" }},
+		{"file_size", models.PrintJobUpdate{FileSize: &size}, func(j *models.PrintJob) bool { return j.FileSize == size }},
+		{"page_count", models.PrintJobUpdate{PageCount: num(12), PageCountSource: str(models.PageCountSourceClient)}, func(j *models.PrintJob) bool {
+			return j.PageCount == 12 && j.PageCountSource == models.PageCountSourceClient
+		}},
+		{"copies", models.PrintJobUpdate{Copies: num(3)}, func(j *models.PrintJob) bool { return j.Copies == 3 }},
+		{"paper_size", models.PrintJobUpdate{PaperSize: str("A3")}, func(j *models.PrintJob) bool { return j.PaperSize == "A3" }},
+		{"color_mode", models.PrintJobUpdate{ColorMode: str("grayscale")}, func(j *models.PrintJob) bool { return j.ColorMode == "grayscale" }},
+		{"duplex_mode", models.PrintJobUpdate{DuplexMode: str("duplex")}, func(j *models.PrintJob) bool { return j.DuplexMode == "duplex" }},
+		{"start_time", models.PrintJobUpdate{StartTime: &at}, func(j *models.PrintJob) bool { return j.StartTime != nil && j.StartTime.Equal(at) }},
+		{"end_time", models.PrintJobUpdate{EndTime: &at}, func(j *models.PrintJob) bool { return j.EndTime != nil && j.EndTime.Equal(at) }},
+		{"error_message", models.PrintJobUpdate{ErrorMessage: str("paper jam")}, func(j *models.PrintJob) bool { return j.ErrorMessage == "paper jam" }},
+		{"retry_count", models.PrintJobUpdate{RetryCount: num(2)}, func(j *models.PrintJob) bool { return j.RetryCount == 2 }},
+		{"max_retries", models.PrintJobUpdate{MaxRetries: num(7)}, func(j *models.PrintJob) bool { return j.MaxRetries == 7 }},
+		{"cost", models.PrintJobUpdate{Cost: &cost}, func(j *models.PrintJob) bool { return j.Cost != nil && *j.Cost == cost }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := createTestJob(t, db, printerID, models.JobStatusPending)
+			before, err := repo.GetPrintJobByID(original.ID)
+			if err != nil || before == nil {
+				t.Fatalf("GetPrintJobByID: %v", err)
+			}
+
+			update := tt.update
+			patched, err := repo.PatchPrintJob(original.ID, &update)
+			if err != nil {
+				t.Fatalf("PatchPrintJob: %v", err)
+			}
+			if patched == nil || !tt.check(patched) {
+				t.Fatalf("returned job does not carry the update: %+v", patched)
+			}
+			reread, err := repo.GetPrintJobByID(original.ID)
+			if err != nil {
+				t.Fatalf("GetPrintJobByID: %v", err)
+			}
+			if !tt.check(reread) {
+				t.Fatalf("re-read job does not carry the update: %+v", reread)
+			}
+			if !reread.UpdatedAt.After(before.UpdatedAt) {
+				t.Fatalf("updated_at %v not advanced from %v", reread.UpdatedAt, before.UpdatedAt)
+			}
+
+			// 未提供的字段保持原值
+			if tt.name != "name" && reread.Name != before.Name {
+				t.Fatalf("name changed to %q", reread.Name)
+			}
+			if tt.name != "file_url" && reread.FileURL != before.FileURL {
+				t.Fatalf("file_url changed to %q", reread.FileURL)
+			}
+			if tt.name != "copies" && reread.Copies != before.Copies {
+				t.Fatalf("copies changed to %d", reread.Copies)
+			}
+			if reread.Status != before.Status || reread.PrinterID != before.PrinterID || reread.UserName != before.UserName {
+				t.Fatalf("untouched columns changed: status %s, printer %s, user %s", reread.Status, reread.PrinterID, reread.UserName)
+			}
+		})
+	}
+}
+
+// TestPatchPrintJobStatus 状态更新按 dispatchedAtOnStatus 设置和清空 dispatched_at
+func TestPatchPrintJobStatus(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	job := createTestJob(t, db, createTestPrinter(t, db), models.JobStatusPending)
+
+	status := func(s models.JobStatus) *models.PrintJobUpdate { return &models.PrintJobUpdate{Status: &s} }
+	dispatched, err := repo.PatchPrintJob(job.ID, status(models.JobStatusDispatched))
+	if err != nil || dispatched.Status != models.JobStatusDispatched || dispatched.DispatchedAt == nil {
+		t.Fatalf("dispatch: %+v, %v", dispatched, err)
+	}
+	printing, err := repo.PatchPrintJob(job.ID, status(models.JobStatusPrinting))
+	if err != nil || printing.DispatchedAt == nil || !printing.DispatchedAt.Equal(*dispatched.DispatchedAt) {
+		t.Fatalf("printing kept dispatched_at %v, want %v (%v)", printing.DispatchedAt, dispatched.DispatchedAt, err)
+	}
+	requeued, err := repo.PatchPrintJob(job.ID, status(models.JobStatusPending))
+	if err != nil || requeued.DispatchedAt != nil {
+		t.Fatalf("pending: dispatched_at %v, want NULL (%v)", requeued.DispatchedAt, err)
+	}
+}
+
+func TestPatchPrintJobNotFound(t *testing.T) {
+	db := openTestDB(t)
+	name := "missing.pdf"
+	job, err := NewPrintJobRepository(db).PatchPrintJob("00000000-0000-0000-0000-000000000000", &models.PrintJobUpdate{Name: &name})
+	if err != nil || job != nil {
+		t.Fatalf("PatchPrintJob(unknown) = %+v, %v; want nil, nil", job, err)
+	}
+}
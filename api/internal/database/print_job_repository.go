@@ -2,13 +2,120 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"fly-print-cloud/api/internal/docformat"
 	"fly-print-cloud/api/internal/models"
 )
 
+// printJobColumns 打印任务查询列（与 scanPrintJob 的扫描顺序保持一致）
+const printJobColumns = `id, name, status, printer_id, printer_name,
+			   user_id, user_name, file_path, file_url, file_size, page_count,
+			   copies, paper_size, color_mode, duplex_mode,
+			   start_time, end_time, error_message, retry_count,
+			   max_retries, cost, performed_by, priority, storage_key, batch_id, printer_group_id, routing_strategy, page_count_source, metadata,
+			   file_purged, file_purged_at, source_format, converted_storage_key, converted_format, converted_file_size,
+			   media_type, resolution, dispatched_at, dispatch_error, applied_policies, progress,
+			   sla_tracked, sla_dispatch_breached, sla_start_breached, sla_complete_breached, sla_evaluated_at,
+			   not_before, client_info, pages_printed, failed_over_from, page_range, error_message_key, display_name, source_name, source, created_at, updated_at`
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPrintJob 扫描一行打印任务数据
+func scanPrintJob(row rowScanner) (*models.PrintJob, error) {
+	job := &models.PrintJob{}
+	var userID sql.NullString
+	var cost sql.NullFloat64
+	var performedBy sql.NullString
+	var storageKey sql.NullString
+	var batchID sql.NullString
+	var groupID, routingStrategy sql.NullString
+	var printerID, printerName sql.NullString
+	var sourceFormat, convertedKey, convertedFormat sql.NullString
+	var convertedSize sql.NullInt64
+	var mediaType, resolution, dispatchError sql.NullString
+	var failedOverFrom, pageRange, errorMessageKey sql.NullString
+	var displayName, sourceName sql.NullString
+	var metadata, clientInfo []byte
+	var appliedPolicies pq.StringArray
+	var slaTracked bool
+	sla := &models.JobSLA{}
+	err := row.Scan(
+		&job.ID, &job.Name, &job.Status, &printerID, &printerName,
+		&userID, &job.UserName, &job.FilePath, &job.FileURL, &job.FileSize, &job.PageCount,
+		&job.Copies, &job.PaperSize, &job.ColorMode, &job.DuplexMode,
+		&job.StartTime, &job.EndTime, &job.ErrorMessage, &job.RetryCount,
+		&job.MaxRetries, &cost, &performedBy, &job.Priority, &storageKey, &batchID, &groupID, &routingStrategy, &job.PageCountSource, &metadata,
+		&job.FilePurged, &job.FilePurgedAt, &sourceFormat, &convertedKey, &convertedFormat, &convertedSize,
+		&mediaType, &resolution, &job.DispatchedAt, &dispatchError, &appliedPolicies, &job.Progress,
+		&slaTracked, &sla.DispatchBreached, &sla.StartBreached, &sla.CompleteBreached, &sla.EvaluatedAt,
+		&job.NotBefore, &clientInfo, &job.PagesPrinted, &failedOverFrom, &pageRange, &errorMessageKey, &displayName, &sourceName, &job.Source, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 有值就设置，没值就空着
+	job.PrinterID = printerID.String
+	job.PrinterName = printerName.String
+	job.MediaType = mediaType.String
+	job.Resolution = resolution.String
+	job.DispatchError = dispatchError.String
+	job.FailedOverFrom = failedOverFrom.String
+	job.PageRange = pageRange.String
+	job.ErrorMessageKey = errorMessageKey.String
+	job.ErrorMessageSpilled = errorMessageKey.Valid
+	job.SourceName = sourceName.String
+	// 增加显示名称之前的任务以 name 显示
+	job.DisplayName = displayName.String
+	if job.DisplayName == "" {
+		job.DisplayName = job.Name
+	}
+	job.AppliedPolicies = []string(appliedPolicies)
+	if userID.Valid {
+		job.UserID = userID.String
+	}
+	if cost.Valid {
+		c := cost.Float64
+		job.Cost = &c
+	}
+	if performedBy.Valid {
+		job.PerformedBy = performedBy.String
+	}
+	if storageKey.Valid {
+		job.StorageKey = storageKey.String
+	}
+	if batchID.Valid {
+		job.BatchID = batchID.String
+	}
+	job.PrinterGroupID = groupID.String
+	job.RoutingStrategy = routingStrategy.String
+	job.SourceFormat = sourceFormat.String
+	job.ConvertedStorageKey = convertedKey.String
+	job.ConvertedFormat = convertedFormat.String
+	job.ConvertedFileSize = convertedSize.Int64
+	if slaTracked {
+		job.SLA = sla
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &job.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse job metadata: %w", err)
+		}
+	}
+	job.Client = scanClientInfo(clientInfo)
+
+	return job, nil
+}
+
 type PrintJobRepository struct {
 	db *DB
 }
@@ -17,61 +124,256 @@ func NewPrintJobRepository(db *DB) *PrintJobRepository {
 	return &PrintJobRepository{db: db}
 }
 
-// CreatePrintJob 创建打印任务
+// execer 兼容 *sql.DB 和 *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// CreatePrintJob 创建打印任务（连同文件列表在同一事务中写入）
 func (r *PrintJobRepository) CreatePrintJob(job *models.PrintJob) error {
+	return r.CreatePrintJobs([]*models.PrintJob{job})
+}
+
+// CreatePrintJobs 在同一事务中创建多个打印任务，任一失败则全部回滚
+func (r *PrintJobRepository) CreatePrintJobs(jobs []*models.PrintJob) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, job := range jobs {
+		if err := insertPrintJob(tx, job); err != nil {
+			return fmt.Errorf("failed to create print job: %w", err)
+		}
+		if job.IdempotencyKey != "" {
+			if err := claimIdempotencyKey(tx, job); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// IdempotencyKeyTTL 幂等键有效期，过期后同一个键会创建新任务
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// DuplicateIdempotencyKeyError 幂等键在有效期内已被其他任务使用（并发的重复请求）
+type DuplicateIdempotencyKeyError struct {
+	JobID string
+}
+
+func (e *DuplicateIdempotencyKeyError) Error() string {
+	return fmt.Sprintf("database: idempotency key already used by job %s", e.JobID)
+}
+
+// IdempotencyScope 幂等键的作用范围：实际提交人（代为提交时为操作人）
+func IdempotencyScope(job *models.PrintJob) string {
+	if job.PerformedBy != "" {
+		return job.PerformedBy
+	}
+	return job.UserName
+}
+
+// claimIdempotencyKey 在创建任务的事务中登记幂等键；键在有效期内已被使用时返回 DuplicateIdempotencyKeyError
+// 并发的相同请求会在主键上等待先到的事务，先到的提交后后到的得到冲突
+func claimIdempotencyKey(tx *sql.Tx, job *models.PrintJob) error {
+	scope := IdempotencyScope(job)
+	query := `
+		INSERT INTO print_job_idempotency_keys (scope, idempotency_key, job_id, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, idempotency_key) DO UPDATE
+			SET job_id = EXCLUDED.job_id, created_at = EXCLUDED.created_at
+			WHERE print_job_idempotency_keys.created_at < $5
+		RETURNING job_id`
+
+	var claimed string
+	err := tx.QueryRow(query, scope, job.IdempotencyKey, job.ID, job.CreatedAt, job.CreatedAt.Add(-IdempotencyKeyTTL)).Scan(&claimed)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to record idempotency key: %w", err)
+	}
+
+	var existing string
+	if err := tx.QueryRow(`SELECT job_id FROM print_job_idempotency_keys WHERE scope = $1 AND idempotency_key = $2`,
+		scope, job.IdempotencyKey).Scan(&existing); err != nil {
+		return fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	return &DuplicateIdempotencyKeyError{JobID: existing}
+}
+
+// GetIdempotentJobID 返回有效期内使用该幂等键创建的任务ID，没有时返回空字符串
+func (r *PrintJobRepository) GetIdempotentJobID(scope, key string) (string, error) {
+	query := `
+		SELECT job_id FROM print_job_idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2 AND created_at >= $3`
+
+	var jobID string
+	err := r.db.QueryRow(query, scope, key, time.Now().UTC().Add(-IdempotencyKeyTTL)).Scan(&jobID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return jobID, nil
+}
+
+// metadataArg 任务元数据参数，为空时写入 NULL
+func metadataArg(metadata map[string]string) interface{} {
+	if len(metadata) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(metadata)
+	return string(data)
+}
+
+// clientInfoArg 客户端信息的写入参数（未提供时为 NULL），打印任务和审计日志共用
+func clientInfoArg(info *models.ClientInfo) interface{} {
+	if info == nil {
+		return nil
+	}
+	data, _ := json.Marshal(info)
+	return string(data)
+}
+
+// scanClientInfo 解析 client_info 列，为空或无法解析时返回 nil（仅用于展示，不影响整行读取）
+func scanClientInfo(data []byte) *models.ClientInfo {
+	if len(data) == 0 {
+		return nil
+	}
+	info := &models.ClientInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil
+	}
+	return info
+}
+
+// insertPrintJob 插入一条打印任务，生成ID和时间戳
+func insertPrintJob(db execer, job *models.PrintJob) error {
 	query := `
 		INSERT INTO print_jobs (
 			id, name, status, printer_id, 
 			user_id, user_name, file_path, file_url, file_size, page_count, 
 			copies, paper_size, color_mode, duplex_mode, 
 			start_time, end_time, error_message, retry_count, 
-			max_retries, created_at, updated_at
+			max_retries, performed_by, priority, storage_key, batch_id, printer_group_id, routing_strategy,
+			page_count_source, metadata, source_format, media_type, resolution, applied_policies, not_before, client_info, page_range,
+			display_name, source_name, source, created_at, updated_at
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 
-			$12, $13, $14, $15, $16, $17, $18, $19, $20, $21
+			$12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36,
+			$37, $38, $39
 		)`
 
-	now := time.Now()
+	// 来源必须由提交入口明确指定，unknown 只用于历史任务
+	if !job.Source.IsValid() || job.Source == models.JobSourceUnknown {
+		return fmt.Errorf("invalid job source %q", job.Source)
+	}
+
+	now := time.Now().UTC()
+	if job.PageCountSource == "" {
+		job.PageCountSource = models.PageCountSourceClient
+	}
+	if job.DisplayName == "" {
+		job.DisplayName = job.Name
+	}
 	job.ID = uuid.New().String()
 	job.CreatedAt = now
 	job.UpdatedAt = now
 
-	_, err := r.db.DB.Exec(query,
+	_, err := db.Exec(query,
 		job.ID, job.Name, job.Status, job.PrinterID,
-		nil, job.UserName, job.FilePath, job.FileURL, job.FileSize, job.PageCount, // user_id设为nil避免外键约束
+		nullIfEmpty(job.UserID), job.UserName, job.FilePath, job.FileURL, job.FileSize, job.PageCount,
 		job.Copies, job.PaperSize, job.ColorMode, job.DuplexMode,
 		job.StartTime, job.EndTime, job.ErrorMessage, job.RetryCount,
-		job.MaxRetries, job.CreatedAt, job.UpdatedAt,
+		job.MaxRetries, nullIfEmpty(job.PerformedBy), job.Priority, nullIfEmpty(job.StorageKey), nullIfEmpty(job.BatchID),
+		nullIfEmpty(job.PrinterGroupID), nullIfEmpty(job.RoutingStrategy),
+		job.PageCountSource, metadataArg(job.Metadata), nullIfEmpty(job.SourceFormat),
+		nullIfEmpty(job.MediaType), nullIfEmpty(job.Resolution), appliedPoliciesArg(job.AppliedPolicies), job.NotBefore, clientInfoArg(job.Client), nullIfEmpty(job.PageRange),
+		job.DisplayName, nullIfEmpty(job.SourceName), job.Source, job.CreatedAt, job.UpdatedAt,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if err := insertPrintJobFiles(db, job); err != nil {
+		return err
+	}
+	return linkStoredFiles(db, job)
+}
+
+// appliedPoliciesArg 生效策略列表的写入参数（没有策略生效时为 NULL）
+func appliedPoliciesArg(names []string) interface{} {
+	if len(names) == 0 {
+		return nil
+	}
+	return pq.Array(names)
+}
+
+// insertPrintJobFiles 写入任务的文件列表
+func insertPrintJobFiles(db execer, job *models.PrintJob) error {
+	query := `
+		INSERT INTO print_job_files (
+			id, job_id, position, file_path, file_url, storage_key, file_size, page_count, copies, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	for i := range job.Files {
+		file := &job.Files[i]
+		file.ID = uuid.New().String()
+		file.JobID = job.ID
+		file.Position = i + 1
+		file.CreatedAt = job.CreatedAt
+
+		_, err := db.Exec(query,
+			file.ID, file.JobID, file.Position, nullIfEmpty(file.FilePath), nullIfEmpty(file.FileURL),
+			nullIfEmpty(file.StorageKey), file.FileSize, file.PageCount, file.Copies, file.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert print job file: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListPrintJobFiles 按打印顺序获取任务的文件列表（单文件旧任务返回空列表）
+func (r *PrintJobRepository) ListPrintJobFiles(jobID string) ([]models.PrintJobFile, error) {
+	query := `
+		SELECT id, job_id, position, COALESCE(file_path, ''), COALESCE(file_url, ''), COALESCE(storage_key, ''),
+		       COALESCE(file_size, 0), COALESCE(page_count, 0), COALESCE(copies, 1), created_at
+		FROM print_job_files
+		WHERE job_id = $1
+		ORDER BY position`
+
+	rows, err := r.db.DB.Query(query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list print job files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []models.PrintJobFile
+	for rows.Next() {
+		var file models.PrintJobFile
+		if err := rows.Scan(&file.ID, &file.JobID, &file.Position, &file.FilePath, &file.FileURL, &file.StorageKey,
+			&file.FileSize, &file.PageCount, &file.Copies, &file.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan print job file: %w", err)
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
 }
 
 // GetPrintJobByID 根据ID获取打印任务
 func (r *PrintJobRepository) GetPrintJobByID(id string) (*models.PrintJob, error) {
 	query := `
-		SELECT id, name, status, printer_id, 
-			   user_id, user_name, file_path, file_url, file_size, page_count, 
-			   copies, paper_size, color_mode, duplex_mode, 
-			   start_time, end_time, error_message, retry_count, 
-			   max_retries, created_at, updated_at
+		SELECT `+printJobColumns+`
 		FROM print_jobs WHERE id = $1`
 
-	job := &models.PrintJob{}
-	var userID sql.NullString
-	err := r.db.DB.QueryRow(query, id).Scan(
-		&job.ID, &job.Name, &job.Status, &job.PrinterID,
-		&userID, &job.UserName, &job.FilePath, &job.FileURL, &job.FileSize, &job.PageCount,
-		&job.Copies, &job.PaperSize, &job.ColorMode, &job.DuplexMode,
-		&job.StartTime, &job.EndTime, &job.ErrorMessage, &job.RetryCount,
-		&job.MaxRetries, &job.CreatedAt, &job.UpdatedAt,
-	)
-	
-	// 有值就设置，没值就空着
-	if userID.Valid {
-		job.UserID = userID.String
-	}
-
+	job, err := scanPrintJob(r.db.DB.QueryRow(query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -83,37 +385,15 @@ func (r *PrintJobRepository) GetPrintJobByID(id string) (*models.PrintJob, error
 }
 
 // ListPrintJobs 获取打印任务列表
-func (r *PrintJobRepository) ListPrintJobs(limit, offset int, status, printerID, userID string) ([]*models.PrintJob, error) {
+func (r *PrintJobRepository) ListPrintJobs(limit, offset int, status, printerID, userID, source string, metadata map[string]string) ([]*models.PrintJob, error) {
 	query := `
-		SELECT id, name, status, printer_id, 
-			   user_id, user_name, file_path, file_url, file_size, page_count, 
-			   copies, paper_size, color_mode, duplex_mode, 
-			   start_time, end_time, error_message, retry_count, 
-			   max_retries, created_at, updated_at
+		SELECT `+printJobColumns+`
 		FROM print_jobs WHERE 1=1`
 
-	args := []interface{}{}
-	argIndex := 1
-
-	if status != "" {
-		query += fmt.Sprintf(" AND status = $%d", argIndex)
-		args = append(args, status)
-		argIndex++
-	}
-
-	if printerID != "" {
-		query += fmt.Sprintf(" AND printer_id = $%d", argIndex)
-		args = append(args, printerID)
-		argIndex++
-	}
-
-	if userID != "" {
-		query += fmt.Sprintf(" AND user_id = $%d", argIndex)
-		args = append(args, userID)
-		argIndex++
-	}
+	query, args := appendJobFilters(query, nil, status, printerID, userID, source, metadata)
+	argIndex := len(args) + 1
 
-	query += " ORDER BY created_at DESC"
+	query += " ORDER BY created_at DESC, id DESC"
 
 	if limit > 0 {
 		query += fmt.Sprintf(" LIMIT $%d", argIndex)
@@ -134,30 +414,135 @@ func (r *PrintJobRepository) ListPrintJobs(limit, offset int, status, printerID,
 
 	var jobs []*models.PrintJob
 	for rows.Next() {
-		job := &models.PrintJob{}
-		var userID sql.NullString
-		err := rows.Scan(
-			&job.ID, &job.Name, &job.Status, &job.PrinterID,
-			&userID, &job.UserName, &job.FilePath, &job.FileURL, &job.FileSize, &job.PageCount,
-			&job.Copies, &job.PaperSize, &job.ColorMode, &job.DuplexMode,
-			&job.StartTime, &job.EndTime, &job.ErrorMessage, &job.RetryCount,
-			&job.MaxRetries, &job.CreatedAt, &job.UpdatedAt,
-		)
+		job, err := scanPrintJob(rows)
 		if err != nil {
 			return nil, err
 		}
-		
-		// 有值就设置，没值就空着
-		if userID.Valid {
-			job.UserID = userID.String
-		}
-		
 		jobs = append(jobs, job)
 	}
 
 	return jobs, nil
 }
 
+// GetPrintJobListVersion 获取打印任务列表的版本信息（最大 updated_at 与任务数），用于生成列表 ETag
+func (r *PrintJobRepository) GetPrintJobListVersion(status, printerID, userID, source string, metadata map[string]string) (time.Time, int, error) {
+	query := `SELECT MAX(updated_at), COUNT(*) FROM print_jobs WHERE 1=1`
+	query, args := appendJobFilters(query, nil, status, printerID, userID, source, metadata)
+
+	var maxUpdatedAt sql.NullTime
+	var count int
+	if err := r.db.DB.QueryRow(query, args...).Scan(&maxUpdatedAt, &count); err != nil {
+		return time.Time{}, 0, fmt.Errorf("failed to get print job list version: %w", err)
+	}
+	return maxUpdatedAt.Time, count, nil
+}
+
+// JobCursor 打印任务游标分页位置（按 created_at DESC, id DESC 排序中的最后一条）
+type JobCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// ListPrintJobsAfter 基于游标（keyset）获取打印任务列表
+// 排序固定为 created_at DESC, id DESC；cursor 为空时从最新的任务开始
+func (r *PrintJobRepository) ListPrintJobsAfter(limit int, cursor *JobCursor, status, printerID, userID, source string, metadata map[string]string) ([]*models.PrintJob, error) {
+	query := `
+		SELECT `+printJobColumns+`
+		FROM print_jobs WHERE 1=1`
+
+	query, args := appendJobFilters(query, nil, status, printerID, userID, source, metadata)
+	argIndex := len(args) + 1
+
+	if cursor != nil {
+		query += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", argIndex, argIndex+1)
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		argIndex += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", argIndex)
+	args = append(args, limit)
+
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*models.PrintJob
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// ListCompletedJobsForExport 按游标获取 [from, to) 内创建的已完成（含部分完成）任务，用于归档导出
+// 与 ListPrintJobsAfter 相同使用 (created_at, id) 游标（idx_print_jobs_created_at_id），但按升序排列，cursor 为空时从 from 开始
+func (r *PrintJobRepository) ListCompletedJobsForExport(from, to time.Time, cursor *JobCursor, limit int) ([]*models.PrintJob, error) {
+	query := `
+		SELECT ` + printJobColumns + `
+		FROM print_jobs WHERE status IN (` + models.StatusSQLList(models.BillableJobStatuses) + `) AND created_at >= $1 AND created_at < $2`
+	args := []interface{}{from, to}
+
+	if cursor != nil {
+		query += " AND (created_at, id) > ($3, $4)"
+		args = append(args, cursor.CreatedAt, cursor.ID)
+	}
+	query += fmt.Sprintf(" ORDER BY created_at, id LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list print jobs for export: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.PrintJob
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// appendJobFilters 追加打印任务列表的过滤条件，返回新的查询语句和参数
+// metadata 过滤使用 JSONB 包含运算符（@>），命中 idx_print_jobs_metadata 索引
+func appendJobFilters(query string, args []interface{}, status, printerID, userID, source string, metadata map[string]string) (string, []interface{}) {
+	if status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	if printerID != "" {
+		args = append(args, printerID)
+		query += fmt.Sprintf(" AND printer_id = $%d", len(args))
+	}
+
+	if userID != "" {
+		args = append(args, userID)
+		query += fmt.Sprintf(" AND user_id = $%d", len(args))
+	}
+
+	if source != "" {
+		args = append(args, source)
+		query += fmt.Sprintf(" AND source = $%d", len(args))
+	}
+
+	if len(metadata) > 0 {
+		args = append(args, metadataArg(metadata))
+		query += fmt.Sprintf(" AND metadata @> $%d::jsonb", len(args))
+	}
+
+	return query, args
+}
+
 // UpdatePrintJob 更新打印任务
 func (r *PrintJobRepository) UpdatePrintJob(job *models.PrintJob) error {
 	query := `
@@ -166,22 +551,117 @@ func (r *PrintJobRepository) UpdatePrintJob(job *models.PrintJob) error {
 			file_size = $5, page_count = $6, copies = $7, paper_size = $8, 
 			color_mode = $9, duplex_mode = $10, start_time = $11, 
 			end_time = $12, error_message = $13, retry_count = $14, 
-			max_retries = $15, updated_at = $16
+			max_retries = $15, updated_at = $16, cost = $17, performed_by = $18,
+			priority = $19, page_count_source = COALESCE(NULLIF($20, ''), page_count_source),
+			dispatched_at = ` + dispatchedAtOnStatus("$3", "$16") + `,
+			dispatch_error = $21, file_url = $22, error_message_key = ` + errorMessageKeySQL("$13", "$23") + `
 		WHERE id = $1`
 
-	job.UpdatedAt = time.Now()
+	job.UpdatedAt = time.Now().UTC()
+	var spilledKey string
+	job.ErrorMessage, spilledKey = r.db.limitText(job.ID, "error_message", job.ErrorMessage, true)
 
 	_, err := r.db.DB.Exec(query,
 		job.ID, job.Name, job.Status, job.FilePath,
 		job.FileSize, job.PageCount, job.Copies, job.PaperSize,
 		job.ColorMode, job.DuplexMode, job.StartTime,
 		job.EndTime, job.ErrorMessage, job.RetryCount,
-		job.MaxRetries, job.UpdatedAt,
+		job.MaxRetries, job.UpdatedAt, job.Cost, nullIfEmpty(job.PerformedBy),
+		job.Priority, job.PageCountSource, nullIfEmpty(job.DispatchError), job.FileURL, nullIfEmpty(spilledKey),
 	)
 
 	return err
 }
 
+// errorMessageKeySQL error_message_key 的更新表达式：错误信息有变化时使用新的对象键（未转存时为 NULL），未变化时保留原值
+// 已截断的错误信息原样写回（如取消任务时整条更新）不会丢失完整内容的引用
+func errorMessageKeySQL(message, key string) string {
+	return "CASE WHEN error_message IS DISTINCT FROM " + message + " THEN " + key + " ELSE error_message_key END"
+}
+
+// PatchPrintJob 只更新 update 中提供的字段，返回更新后的任务；任务不存在时返回 nil
+func (r *PrintJobRepository) PatchPrintJob(id string, update *models.PrintJobUpdate) (*models.PrintJob, error) {
+	args := []interface{}{id}
+	var sets []string
+	set := func(column string, value interface{}) string {
+		args = append(args, value)
+		placeholder := fmt.Sprintf("$%d", len(args))
+		sets = append(sets, column+" = "+placeholder)
+		return placeholder
+	}
+
+	if update.Name != nil {
+		set("name", *update.Name)
+	}
+	if update.DisplayName != nil {
+		set("display_name", *update.DisplayName)
+	}
+	if update.FilePath != nil {
+		set("file_path", *update.FilePath)
+	}
+	if update.FileURL != nil {
+		set("file_url", *update.FileURL)
+	}
+	if update.FileSize != nil {
+		set("file_size", *update.FileSize)
+	}
+	if update.PageCount != nil {
+		set("page_count", *update.PageCount)
+	}
+	if update.PageCountSource != nil {
+		set("page_count_source", *update.PageCountSource)
+	}
+	if update.Copies != nil {
+		set("copies", *update.Copies)
+	}
+	if update.PaperSize != nil {
+		set("paper_size", *update.PaperSize)
+	}
+	if update.ColorMode != nil {
+		set("color_mode", *update.ColorMode)
+	}
+	if update.DuplexMode != nil {
+		set("duplex_mode", *update.DuplexMode)
+	}
+	if update.StartTime != nil {
+		set("start_time", *update.StartTime)
+	}
+	if update.EndTime != nil {
+		set("end_time", *update.EndTime)
+	}
+	if update.ErrorMessage != nil {
+		message, spilledKey := r.db.limitText(id, "error_message", *update.ErrorMessage, true)
+		messageArg := set("error_message", message)
+		args = append(args, nullIfEmpty(spilledKey))
+		sets = append(sets, fmt.Sprintf("error_message_key = %s", errorMessageKeySQL(messageArg, fmt.Sprintf("$%d", len(args)))))
+	}
+	if update.RetryCount != nil {
+		set("retry_count", *update.RetryCount)
+	}
+	if update.MaxRetries != nil {
+		set("max_retries", *update.MaxRetries)
+	}
+	if update.Cost != nil {
+		set("cost", *update.Cost)
+	}
+	now := set("updated_at", time.Now().UTC())
+	if update.Status != nil {
+		status := set("status", string(*update.Status))
+		sets = append(sets, "dispatched_at = "+dispatchedAtOnStatus(status, now))
+	}
+
+	query := `UPDATE print_jobs SET ` + strings.Join(sets, ", ") + ` WHERE id = $1 RETURNING ` + printJobColumns
+
+	job, err := scanPrintJob(r.db.DB.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update print job: %w", err)
+	}
+	return job, nil
+}
+
 // DeletePrintJob 删除打印任务
 func (r *PrintJobRepository) DeletePrintJob(id string) error {
 	query := `DELETE FROM print_jobs WHERE id = $1`
@@ -191,21 +671,20 @@ func (r *PrintJobRepository) DeletePrintJob(id string) error {
 
 // GetPrintJobsByPrinterID 根据打印机ID获取任务列表
 func (r *PrintJobRepository) GetPrintJobsByPrinterID(printerID string, limit, offset int) ([]*models.PrintJob, error) {
-	return r.ListPrintJobs(limit, offset, "", printerID, "")
+	return r.ListPrintJobs(limit, offset, "", printerID, "", "", nil)
 }
 
 // GetPrintJobsByUserID 根据用户ID获取任务列表
 func (r *PrintJobRepository) GetPrintJobsByUserID(userID string, limit, offset int) ([]*models.PrintJob, error) {
-	return r.ListPrintJobs(limit, offset, "", "", userID)
+	return r.ListPrintJobs(limit, offset, "", "", userID, "", nil)
 }
 
+// jobsWithPrinters 打印任务关联所属打印机（pj 为任务，p 为打印机），按 Edge Node 查询任务时使用
+const jobsWithPrinters = `print_jobs pj JOIN printers p ON pj.printer_id = p.id`
+
 // GetEdgeNodeIDByPrintJob 根据打印任务获取对应的 Edge Node ID
 func (r *PrintJobRepository) GetEdgeNodeIDByPrintJob(jobID string) (string, error) {
-	query := `
-		SELECT p.edge_node_id 
-		FROM print_jobs pj 
-		JOIN printers p ON pj.printer_id = p.id 
-		WHERE pj.id = $1`
+	query := `SELECT p.edge_node_id FROM ` + jobsWithPrinters + ` WHERE pj.id = $1`
 
 	var edgeNodeID string
 	err := r.db.DB.QueryRow(query, jobID).Scan(&edgeNodeID)
@@ -216,68 +695,745 @@ func (r *PrintJobRepository) GetEdgeNodeIDByPrintJob(jobID string) (string, erro
 	return edgeNodeID, nil
 }
 
-// UpdateJobStatus 更新打印任务状态和进度
-func (r *PrintJobRepository) UpdateJobStatus(jobID, status string, progress int) error {
+// dispatchedAtOnStatus 状态变化时 dispatched_at 的取值：下发时记录（已有值时保留），退回待分发/排队时清空
+func dispatchedAtOnStatus(status, now string) string {
+	return `CASE
+				WHEN ` + status + ` IN (` + models.StatusSQLList([]models.JobStatus{models.JobStatusPending, models.JobStatusQueued}) + `) THEN NULL
+				WHEN ` + status + ` = '` + string(models.JobStatusDispatched) + `' THEN COALESCE(dispatched_at, ` + now + `)
+				ELSE dispatched_at
+			END`
+}
+
+// UpdateJobStatus 更新打印任务状态和进度，同时记录开始打印和结束时间（用于 SLA 考核）
+// receivedAt 为服务端接收该更新的时间，记录在 last_edge_event_at（不受 updated_at 触发器和其他写入影响）；
+// 早于已记录的节点更新的视为过期，返回 false。结束状态始终写入，避免任务因乱序停留在执行中
+func (r *PrintJobRepository) UpdateJobStatus(jobID string, status models.JobStatus, progress int, receivedAt time.Time) (bool, error) {
 	query := `
 		UPDATE print_jobs SET 
 			status = $2, 
-			updated_at = $3
-		WHERE id = $1`
+			progress = $4,
+			last_edge_event_at = GREATEST(last_edge_event_at, $3),
+			start_time = CASE WHEN $2 = '` + string(models.JobStatusPrinting) + `' THEN COALESCE(start_time, $3) ELSE start_time END,
+			end_time = CASE WHEN $2 IN (` + models.StatusSQLList(models.TerminalJobStatuses) + `) THEN COALESCE(end_time, $3) ELSE end_time END
+		WHERE id = $1 AND ($2 IN (` + models.StatusSQLList(models.TerminalJobStatuses) + `) OR last_edge_event_at IS NULL OR last_edge_event_at <= $3)`
 
-	now := time.Now()
-	_, err := r.db.DB.Exec(query, jobID, status, now)
-	return err
+	result, err := r.db.DB.Exec(query, jobID, status, receivedAt, progress)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
 }
 
-// CountPrintJobs 统计打印任务总数
-func (r *PrintJobRepository) CountPrintJobs(status, printerID, userID string) (int, error) {
-	query := `SELECT COUNT(*) FROM print_jobs WHERE 1=1`
-	args := []interface{}{}
-	argIndex := 1
+// UpdateJobProgress 只更新状态未变化的任务的进度
+// 任务状态已被其他更新修改，或 receivedAt 早于已记录的节点更新时不写入，返回 false
+func (r *PrintJobRepository) UpdateJobProgress(jobID string, status models.JobStatus, progress int, receivedAt time.Time) (bool, error) {
+	query := `
+		UPDATE print_jobs SET progress = $3, last_edge_event_at = $4
+		WHERE id = $1 AND status = $2 AND (last_edge_event_at IS NULL OR last_edge_event_at <= $4)`
 
-	if status != "" {
-		query += fmt.Sprintf(" AND status = $%d", argIndex)
-		args = append(args, status)
-		argIndex++
+	result, err := r.db.DB.Exec(query, jobID, status, progress, receivedAt)
+	if err != nil {
+		return false, err
 	}
 
-	if printerID != "" {
-		query += fmt.Sprintf(" AND printer_id = $%d", argIndex)
-		args = append(args, printerID)
-		argIndex++
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
 	}
+	return rowsAffected > 0, nil
+}
 
-	if userID != "" {
-		query += fmt.Sprintf(" AND user_id = $%d", argIndex)
-		args = append(args, userID)
-		argIndex++
+// UpdateJobErrorMessage 更新任务错误信息（超出 text_limits.max_length 时截断，按配置转存完整内容）
+func (r *PrintJobRepository) UpdateJobErrorMessage(jobID, errorMessage string) error {
+	message, spilledKey := r.db.limitText(jobID, "error_message", errorMessage, true)
+	query := `UPDATE print_jobs SET error_message = $2, error_message_key = ` + errorMessageKeySQL("$2", "$3") + ` WHERE id = $1`
+	_, err := r.db.DB.Exec(query, jobID, message, nullIfEmpty(spilledKey))
+	return err
+}
+
+// UpdateJobPagesPrinted 记录节点上报的已输出页数（只增不减）
+func (r *PrintJobRepository) UpdateJobPagesPrinted(jobID string, pages int) error {
+	query := `UPDATE print_jobs SET pages_printed = GREATEST(pages_printed, $2) WHERE id = $1`
+	_, err := r.db.DB.Exec(query, jobID, pages)
+	return err
+}
+
+// SpilledErrorMessage 已转存到文件存储的完整错误信息
+type SpilledErrorMessage struct {
+	JobID string
+	Key   string
+}
+
+// ListExpiredErrorMessageKeys 列出结束时间早于 before 的任务转存的完整错误信息，最多 limit 条
+func (r *PrintJobRepository) ListExpiredErrorMessageKeys(before time.Time, limit int) ([]SpilledErrorMessage, error) {
+	query := `
+		SELECT id, error_message_key FROM print_jobs
+		WHERE error_message_key IS NOT NULL AND end_time < $1
+		ORDER BY end_time
+		LIMIT $2`
+
+	rows, err := r.db.DB.Query(query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired error message keys: %w", err)
+	}
+	defer rows.Close()
+
+	var spilled []SpilledErrorMessage
+	for rows.Next() {
+		var item SpilledErrorMessage
+		if err := rows.Scan(&item.JobID, &item.Key); err != nil {
+			return nil, err
+		}
+		spilled = append(spilled, item)
+	}
+	return spilled, rows.Err()
+}
+
+// ClearErrorMessageKey 完整错误信息删除后清除任务上的引用（引用已变化时不修改）
+func (r *PrintJobRepository) ClearErrorMessageKey(jobID, key string) error {
+	query := `UPDATE print_jobs SET error_message_key = NULL WHERE id = $1 AND error_message_key = $2`
+	_, err := r.db.DB.Exec(query, jobID, key)
+	return err
+}
+
+// RequeueFailedJob 将失败的任务重新置为待分发/排队（故障转移时同时改投打印机），清除上次执行的时间、进度和错误
+// 任务已不是失败状态（如已被重新打印或强制处理）时不修改，返回 false
+func (r *PrintJobRepository) RequeueFailedJob(job *models.PrintJob) (bool, error) {
+	job.UpdatedAt = time.Now().UTC()
+	query := `
+		UPDATE print_jobs SET
+			status = $2, printer_id = $3, retry_count = $4, failed_over_from = $5,
+			progress = 0, error_message = '', dispatch_error = NULL,
+			start_time = NULL, end_time = NULL, dispatched_at = NULL, updated_at = $6
+		WHERE id = $1 AND status = '` + string(models.JobStatusFailed) + `'`
+
+	result, err := r.db.DB.Exec(query, job.ID, job.Status, job.PrinterID, job.RetryCount,
+		nullIfEmpty(job.FailedOverFrom), job.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue job: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected > 0 {
+		job.Progress = 0
+		job.ErrorMessage = ""
+		job.DispatchError = ""
+		job.StartTime, job.EndTime, job.DispatchedAt = nil, nil, nil
+	}
+	return rowsAffected > 0, nil
+}
+
+// SetPrintJobConversion 记录分发前转换得到的文件，并登记到存储文件（随原文件一起按保留期限清理）
+func (r *PrintJobRepository) SetPrintJobConversion(job *models.PrintJob) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	job.UpdatedAt = time.Now().UTC()
+	query := `
+		UPDATE print_jobs SET converted_storage_key = $2, converted_format = $3, converted_file_size = $4, updated_at = $5
+		WHERE id = $1`
+	if _, err := tx.Exec(query, job.ID, job.ConvertedStorageKey, job.ConvertedFormat, job.ConvertedFileSize, job.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to record job conversion: %w", err)
 	}
 
+	query = `
+		INSERT INTO stored_files (storage_key, file_size, content_type, job_id, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (storage_key) DO NOTHING`
+	contentType := docformat.Format(job.ConvertedFormat).ContentType()
+	if _, err := tx.Exec(query, job.ConvertedStorageKey, job.ConvertedFileSize, contentType, job.ID, job.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to record converted file: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CountPrintJobs 统计打印任务总数
+func (r *PrintJobRepository) CountPrintJobs(status, printerID, userID, source string, metadata map[string]string) (int, error) {
+	query := `SELECT COUNT(*) FROM print_jobs WHERE 1=1`
+	query, args := appendJobFilters(query, nil, status, printerID, userID, source, metadata)
+
 	var total int
 	err := r.db.DB.QueryRow(query, args...).Scan(&total)
 	return total, err
 }
 
-// CountJobsByStatusAndDate 根据状态和日期范围统计打印任务数量
-func (r *PrintJobRepository) CountJobsByStatusAndDate(status string, startDate, endDate time.Time) (int, error) {
-	query := `SELECT COUNT(*) FROM print_jobs WHERE status = $1 AND created_at >= $2 AND created_at < $3`
-	
-	var count int
-	err := r.db.DB.QueryRow(query, status, startDate, endDate).Scan(&count)
-	return count, err
+// CountJobsByDay 按创建时间统计指定状态的任务数，按 timezone（IANA 名称）划分自然日
+// created_at 以 UTC 存储，先还原为 UTC 再转换到目标时区；返回 日期（YYYY-MM-DD）→ 状态 → 数量
+func (r *PrintJobRepository) CountJobsByDay(start, end time.Time, timezone string, statuses ...models.JobStatus) (map[string]map[models.JobStatus]int, error) {
+	query := `
+		SELECT to_char((created_at AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY-MM-DD') AS day, status, COUNT(*)
+		FROM print_jobs
+		WHERE created_at >= $1 AND created_at < $2 AND status IN (` + models.StatusSQLList(statuses) + `)
+		GROUP BY day, status`
+
+	rows, err := r.db.DB.Query(query, start.UTC(), end.UTC(), timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs by day: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]map[models.JobStatus]int)
+	for rows.Next() {
+		var day string
+		var status models.JobStatus
+		var count int
+		if err := rows.Scan(&day, &status, &count); err != nil {
+			return nil, err
+		}
+		if counts[day] == nil {
+			counts[day] = make(map[models.JobStatus]int)
+		}
+		counts[day][status] = count
+	}
+	return counts, rows.Err()
 }
 
 // ListPrintJobsWithTotal 获取打印任务列表和总数
-func (r *PrintJobRepository) ListPrintJobsWithTotal(limit, offset int, status, printerID, userID string) ([]*models.PrintJob, int, error) {
-	jobs, err := r.ListPrintJobs(limit, offset, status, printerID, userID)
+func (r *PrintJobRepository) ListPrintJobsWithTotal(limit, offset int, status, printerID, userID, source string, metadata map[string]string) ([]*models.PrintJob, int, error) {
+	jobs, err := r.ListPrintJobs(limit, offset, status, printerID, userID, source, metadata)
 	if err != nil {
 		return nil, 0, err
 	}
 	
-	total, err := r.CountPrintJobs(status, printerID, userID)
+	total, err := r.CountPrintJobs(status, printerID, userID, source, metadata)
 	if err != nil {
 		return nil, 0, err
 	}
 	
 	return jobs, total, nil
 }
+
+// UpdateJobCost 更新打印任务费用
+func (r *PrintJobRepository) UpdateJobCost(jobID string, cost float64) error {
+	query := `UPDATE print_jobs SET cost = $2 WHERE id = $1`
+	_, err := r.db.DB.Exec(query, jobID, cost)
+	return err
+}
+
+// ListCompletedJobsByDate 获取日期范围内已完成（含部分完成）的打印任务
+func (r *PrintJobRepository) ListCompletedJobsByDate(startDate, endDate time.Time) ([]*models.PrintJob, error) {
+	query := `
+		SELECT ` + printJobColumns + `
+		FROM print_jobs
+		WHERE status IN (` + models.StatusSQLList(models.BillableJobStatuses) + `) AND created_at >= $1 AND created_at < $2
+		ORDER BY created_at`
+
+	rows, err := r.db.DB.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*models.PrintJob
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// ListJobsByDate 获取日期范围内的全部打印任务（用于导出）
+func (r *PrintJobRepository) ListJobsByDate(startDate, endDate time.Time) ([]*models.PrintJob, error) {
+	query := `
+		SELECT ` + printJobColumns + `
+		FROM print_jobs
+		WHERE created_at >= $1 AND created_at < $2
+		ORDER BY created_at`
+
+	rows, err := r.db.DB.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*models.PrintJob
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// billedPagesSQL 计费页数的 SQL 表达式，与 PrintJob.BilledPages 一致：部分完成的任务按已输出页数（不超过页数 × 份数）
+func billedPagesSQL(alias string) string {
+	pages := fmt.Sprintf("COALESCE(%[1]spage_count, 0) * GREATEST(COALESCE(%[1]scopies, 1), 1)", alias)
+	return fmt.Sprintf("CASE WHEN %[1]sstatus = '%[2]s' AND (%[3]s = 0 OR %[1]spages_printed < %[3]s) THEN %[1]spages_printed ELSE %[3]s END",
+		alias, models.JobStatusPartiallyCompleted, pages)
+}
+
+// CostStatsByUser 按提交用户统计已完成（含部分完成）任务的计费页数和费用
+// 按 user_id 分组（同名用户分开统计，改名的用户不拆分），名称取当前用户名；没有 user_id 的任务按提交时的用户名分组
+func (r *PrintJobRepository) CostStatsByUser(startDate, endDate time.Time) ([]*models.CostStat, error) {
+	query := `
+		SELECT COALESCE(pj.user_id::text, pj.user_name, ''), COALESCE(MAX(u.username), MAX(pj.user_name), ''), COUNT(*),
+		       COUNT(*) FILTER (WHERE pj.status = '` + string(models.JobStatusPartiallyCompleted) + `'),
+		       COALESCE(SUM(` + billedPagesSQL("pj.") + `), 0),
+		       COALESCE(SUM(pj.cost), 0)
+		FROM print_jobs pj
+		LEFT JOIN users u ON pj.user_id = u.id
+		WHERE pj.status IN (` + models.StatusSQLList(models.BillableJobStatuses) + `) AND pj.created_at >= $1 AND pj.created_at < $2
+		GROUP BY 1
+		ORDER BY 6 DESC`
+
+	return r.queryCostStats(query, startDate, endDate)
+}
+
+// CostStatsByPrinter 按打印机统计已完成（含部分完成）任务的计费页数和费用
+func (r *PrintJobRepository) CostStatsByPrinter(startDate, endDate time.Time) ([]*models.CostStat, error) {
+	query := `
+		SELECT COALESCE(pj.printer_id::text, ''), COALESCE(NULLIF(p.display_name, ''), p.name, pj.printer_name, ''), COUNT(*),
+		       COUNT(*) FILTER (WHERE pj.status = '` + string(models.JobStatusPartiallyCompleted) + `'),
+		       COALESCE(SUM(` + billedPagesSQL("pj.") + `), 0),
+		       COALESCE(SUM(pj.cost), 0)
+		FROM print_jobs pj
+		LEFT JOIN printers p ON pj.printer_id = p.id
+		WHERE pj.status IN (` + models.StatusSQLList(models.BillableJobStatuses) + `) AND pj.created_at >= $1 AND pj.created_at < $2
+		GROUP BY pj.printer_id, p.display_name, p.name, pj.printer_name
+		ORDER BY 6 DESC`
+
+	return r.queryCostStats(query, startDate, endDate)
+}
+
+// queryCostStats 执行费用统计查询
+func (r *PrintJobRepository) queryCostStats(query string, args ...interface{}) ([]*models.CostStat, error) {
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*models.CostStat{}
+	for rows.Next() {
+		stat := &models.CostStat{}
+		if err := rows.Scan(&stat.Key, &stat.Name, &stat.JobCount, &stat.PartialJobCount, &stat.Pages, &stat.TotalCost); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
+
+// SourceStats 按提交来源统计 [startDate, endDate) 内创建的任务，按 models.AllJobSources 的顺序返回全部来源（没有任务的来源计数为 0）
+func (r *PrintJobRepository) SourceStats(startDate, endDate time.Time) ([]*models.SourceStat, error) {
+	query := `
+		SELECT source, COUNT(*),
+		       COUNT(*) FILTER (WHERE status IN (` + models.StatusSQLList(models.BillableJobStatuses) + `)),
+		       COUNT(*) FILTER (WHERE status = '` + string(models.JobStatusFailed) + `'),
+		       COUNT(*) FILTER (WHERE status = '` + string(models.JobStatusCancelled) + `'),
+		       COALESCE(SUM(` + billedPagesSQL("") + `) FILTER (WHERE status IN (` + models.StatusSQLList(models.BillableJobStatuses) + `)), 0)
+		FROM print_jobs
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY source`
+
+	rows, err := r.db.DB.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source stats: %w", err)
+	}
+	defer rows.Close()
+
+	bySource := make(map[models.JobSource]*models.SourceStat)
+	for rows.Next() {
+		stat := &models.SourceStat{}
+		if err := rows.Scan(&stat.Source, &stat.JobCount, &stat.CompletedCount, &stat.FailedCount, &stat.CancelledCount, &stat.Pages); err != nil {
+			return nil, err
+		}
+		bySource[stat.Source] = stat
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := make([]*models.SourceStat, 0, len(models.AllJobSources))
+	for _, source := range models.AllJobSources {
+		stat, ok := bySource[source]
+		if !ok {
+			stat = &models.SourceStat{Source: source}
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+// ClaimJobNotification 标记任务通知已发送，用于去重
+// 返回 false 表示该任务的通知已被发送（或正在发送），调用方不应重复发送
+func (r *PrintJobRepository) ClaimJobNotification(jobID string) (bool, error) {
+	query := `UPDATE print_jobs SET notification_sent_at = CURRENT_TIMESTAMP WHERE id = $1 AND notification_sent_at IS NULL`
+	result, err := r.db.DB.Exec(query, jobID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job notification: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ReleaseJobNotification 发送失败时清除通知标记，允许后续重试
+func (r *PrintJobRepository) ReleaseJobNotification(jobID string) error {
+	query := `UPDATE print_jobs SET notification_sent_at = NULL WHERE id = $1`
+	if _, err := r.db.DB.Exec(query, jobID); err != nil {
+		return fmt.Errorf("failed to release job notification: %w", err)
+	}
+	return nil
+}
+
+// ListPrintersWithDueQueuedJobs 获取有可认领排队任务（没有计划时间或计划时间已到）的打印机ID
+func (r *PrintJobRepository) ListPrintersWithDueQueuedJobs(now time.Time) ([]string, error) {
+	query := `
+		SELECT DISTINCT printer_id FROM print_jobs
+		WHERE status = $1 AND printer_id IS NOT NULL AND (not_before IS NULL OR not_before <= $2)`
+
+	rows, err := r.db.Query(query, models.JobStatusQueued, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list printers with queued jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var printerIDs []string
+	for rows.Next() {
+		var printerID string
+		if err := rows.Scan(&printerID); err != nil {
+			return nil, fmt.Errorf("failed to scan printer id: %w", err)
+		}
+		printerIDs = append(printerIDs, printerID)
+	}
+	return printerIDs, rows.Err()
+}
+
+// ClaimQueuedJobs 在打印机并发名额内认领排队中的任务并标记为 dispatched
+// 通过锁定打印机行串行化同一打印机的认领，避免多个任务同时结束时超额分发；
+// 打印机未设置并发上限时认领全部排队任务。按优先级从高到低、提交时间从早到晚认领。
+// 打印机被预留期间只认领预留用户的任务，其他用户的任务继续排队。
+func (r *PrintJobRepository) ClaimQueuedJobs(printerID string) ([]*models.PrintJob, error) {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	var maxConcurrent sql.NullInt64
+	var reservedBy sql.NullString
+	var reservedUntil sql.NullTime
+	err = tx.QueryRow(`SELECT max_concurrent_jobs, reserved_by, reserved_until FROM printers WHERE id = $1 FOR UPDATE`, printerID).
+		Scan(&maxConcurrent, &reservedBy, &reservedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock printer: %w", err)
+	}
+
+	// 可认领数量，-1 表示不限制
+	slots := int64(-1)
+	if maxConcurrent.Valid && maxConcurrent.Int64 > 0 {
+		var active int64
+		countQuery := `SELECT COUNT(*) FROM print_jobs WHERE printer_id = $1 AND status IN (` +
+			models.StatusSQLList(models.ActiveJobStatuses) + `)`
+		if err := tx.QueryRow(countQuery, printerID).Scan(&active); err != nil {
+			return nil, fmt.Errorf("failed to count active jobs: %w", err)
+		}
+		slots = maxConcurrent.Int64 - active
+		if slots <= 0 {
+			return nil, tx.Commit()
+		}
+	}
+
+	// 预留有效时只认领预留用户的任务（$6 为空表示不限制）；按营业时间排到之后的任务到时间后才认领
+	var holder interface{}
+	if reservedBy.Valid && reservedUntil.Valid && now.Before(reservedUntil.Time) {
+		holder = reservedBy.String
+	}
+
+	claimQuery := `
+		UPDATE print_jobs SET status = $2, updated_at = $3, dispatched_at = $3, dispatch_error = NULL, progress = 0
+		WHERE id IN (
+			SELECT id FROM print_jobs
+			WHERE printer_id = $1 AND status = $4 AND ($6::text IS NULL OR user_name = $6)
+			  AND (not_before IS NULL OR not_before <= $3)
+			ORDER BY priority DESC, created_at ASC
+			LIMIT $5
+			FOR UPDATE
+		)
+		RETURNING ` + printJobColumns
+	var limit interface{}
+	if slots >= 0 {
+		limit = slots
+	}
+
+	rows, err := tx.Query(claimQuery, printerID, models.JobStatusDispatched, now, models.JobStatusQueued, limit, holder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim queued jobs: %w", err)
+	}
+
+	var jobs []*models.PrintJob
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit job claim: %w", err)
+	}
+
+	// RETURNING 不保证顺序，按认领顺序重新排序
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].Priority != jobs[j].Priority {
+			return jobs[i].Priority > jobs[j].Priority
+		}
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	return jobs, nil
+}
+
+// ListPrintJobsByBatch 获取批次中的全部任务
+func (r *PrintJobRepository) ListPrintJobsByBatch(batchID string) ([]*models.PrintJob, error) {
+	query := `
+		SELECT ` + printJobColumns + `
+		FROM print_jobs WHERE batch_id = $1
+		ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.DB.Query(query, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.PrintJob
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// CountPrinterJobLoad 统计打印机正在执行（已分发未结束）和排队中的任务数
+func (r *PrintJobRepository) CountPrinterJobLoad(printerID string) (active int, queued int, err error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE status IN (` + models.StatusSQLList(models.ActiveJobStatuses) + `)),
+			COUNT(*) FILTER (WHERE status = $2)
+		FROM print_jobs
+		WHERE printer_id = $1`
+
+	err = r.db.DB.QueryRow(query, printerID, models.JobStatusQueued).Scan(&active, &queued)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count printer job load: %w", err)
+	}
+	return active, queued, nil
+}
+
+// LastGroupPrinterForUser 获取用户最近一次提交到打印机组时分配到的打印机ID，没有记录时返回空字符串
+func (r *PrintJobRepository) LastGroupPrinterForUser(groupID, userName string) (string, error) {
+	query := `
+		SELECT printer_id FROM print_jobs
+		WHERE printer_group_id = $1 AND user_name = $2 AND printer_id IS NOT NULL
+		ORDER BY created_at DESC LIMIT 1`
+
+	var printerID string
+	err := r.db.DB.QueryRow(query, groupID, userName).Scan(&printerID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get last group printer: %w", err)
+	}
+	return printerID, nil
+}
+
+// FailJobsOnDeletedNodes 将打印机所属 Edge Node 已删除（或已不存在）的未分发任务标记为失败，返回被标记的任务
+func (r *PrintJobRepository) FailJobsOnDeletedNodes(errorMessage string) ([]*models.PrintJob, error) {
+	query := `
+		UPDATE print_jobs SET status = $1, error_message = $2, end_time = $3
+		WHERE status IN ($4, $5) AND printer_id IN (
+			SELECT p.id FROM printers p
+			LEFT JOIN edge_nodes n ON n.id = p.edge_node_id
+			WHERE n.id IS NULL OR n.deleted_at IS NOT NULL
+		)
+		RETURNING ` + printJobColumns
+
+	rows, err := r.db.DB.Query(query, models.JobStatusFailed, errorMessage, time.Now().UTC(),
+		models.JobStatusPending, models.JobStatusQueued)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fail jobs on deleted nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.PrintJob
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// MarkStalledJobs 将最近一次更新早于 cutoff 的已分发任务标记为 stalled，返回被标记的任务
+func (r *PrintJobRepository) MarkStalledJobs(cutoff time.Time) ([]*models.PrintJob, error) {
+	query := `
+		UPDATE print_jobs SET status = $1
+		WHERE status IN (` + models.StatusSQLList(models.StallableJobStatuses) + `) AND updated_at < $2
+		RETURNING ` + printJobColumns
+
+	rows, err := r.db.DB.Query(query, models.JobStatusStalled, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark stalled jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.PrintJob
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// CountActiveJobsByEdgeNode 统计 Edge Node 下已分发且尚未结束的任务数（排空时判断是否可以禁用节点）
+func (r *PrintJobRepository) CountActiveJobsByEdgeNode(edgeNodeID string) (int, error) {
+	query := `SELECT COUNT(*) FROM ` + jobsWithPrinters + `
+		WHERE p.edge_node_id = $1 AND pj.status IN (` + models.StatusSQLList(models.ActiveJobStatuses) + `)`
+
+	var count int
+	if err := r.db.QueryRow(query, edgeNodeID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count active jobs of edge node: %w", err)
+	}
+	return count, nil
+}
+
+// GetPrinterJobCounts 节点下各打印机的未结束、卡住任务数和 today 之后完成/失败的任务数（键为打印机ID，没有任务的打印机不出现）
+func (r *PrintJobRepository) GetPrinterJobCounts(edgeNodeID string, today time.Time) (map[string]*models.PrinterJobCounts, error) {
+	terminal := models.StatusSQLList(models.TerminalJobStatuses)
+	rows, err := r.db.DB.Query(`
+		SELECT pj.printer_id,
+		       COUNT(*) FILTER (WHERE pj.status NOT IN (`+terminal+`)),
+		       COUNT(*) FILTER (WHERE pj.status = $2 AND COALESCE(pj.end_time, pj.updated_at) >= $5),
+		       COUNT(*) FILTER (WHERE pj.status = $3 AND COALESCE(pj.end_time, pj.updated_at) >= $5),
+		       COUNT(*) FILTER (WHERE pj.status = $4)
+		FROM `+jobsWithPrinters+`
+		WHERE p.edge_node_id = $1 AND (pj.status NOT IN (`+terminal+`) OR COALESCE(pj.end_time, pj.updated_at) >= $5)
+		GROUP BY pj.printer_id`,
+		edgeNodeID, models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusStalled, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count printer jobs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]*models.PrinterJobCounts)
+	for rows.Next() {
+		var printerID string
+		count := &models.PrinterJobCounts{}
+		if err := rows.Scan(&printerID, &count.Active, &count.CompletedToday, &count.FailedToday, &count.Stalled); err != nil {
+			return nil, err
+		}
+		counts[printerID] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetEdgeNodeJobStats 统计 Edge Node 下所有打印机的任务：未结束任务按状态计数、
+// 今天（today 之后）完成的任务数、since 之后完成/失败的任务数和从首次分发到完成的平均耗时
+// 任务完成时不一定写入 end_time，结束时间取 COALESCE(end_time, updated_at)
+func (r *PrintJobRepository) GetEdgeNodeJobStats(edgeNodeID string, today, since time.Time) (*models.EdgeNodeStats, error) {
+	stats := &models.EdgeNodeStats{EdgeNodeID: edgeNodeID, ActiveJobs: map[string]int{}}
+
+	terminal := models.StatusSQLList(models.TerminalJobStatuses)
+	rows, err := r.db.DB.Query(`
+		SELECT pj.status, COUNT(*) FROM `+jobsWithPrinters+`
+		WHERE p.edge_node_id = $1 AND pj.status NOT IN (`+terminal+`)
+		GROUP BY pj.status`, edgeNodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count active jobs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		stats.ActiveJobs[status] = count
+		stats.ActiveJobTotal += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = r.db.DB.QueryRow(`
+		SELECT COUNT(*) FILTER (WHERE pj.status = $2 AND COALESCE(pj.end_time, pj.updated_at) >= $4),
+		       COUNT(*) FILTER (WHERE pj.status = $2),
+		       COUNT(*) FILTER (WHERE pj.status = $3)
+		FROM `+jobsWithPrinters+`
+		WHERE p.edge_node_id = $1 AND pj.status IN ($2, $3) AND COALESCE(pj.end_time, pj.updated_at) >= $5`,
+		edgeNodeID, models.JobStatusCompleted, models.JobStatusFailed, today, since,
+	).Scan(&stats.CompletedToday, &stats.CompletedLast7Days, &stats.FailedLast7Days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count finished jobs: %w", err)
+	}
+	if finished := stats.CompletedLast7Days + stats.FailedLast7Days; finished > 0 {
+		stats.FailureRate = float64(stats.FailedLast7Days) / float64(finished)
+	}
+
+	var latency sql.NullFloat64
+	err = r.db.DB.QueryRow(`
+		SELECT AVG(EXTRACT(EPOCH FROM (COALESCE(pj.end_time, pj.updated_at) - d.dispatched_at)))
+		FROM `+jobsWithPrinters+`
+		JOIN LATERAL (
+			SELECT MIN(e.created_at) AS dispatched_at FROM print_job_events e
+			WHERE e.job_id = pj.id AND e.type = $3
+		) d ON d.dispatched_at IS NOT NULL
+		WHERE p.edge_node_id = $1 AND pj.status = $2 AND COALESCE(pj.end_time, pj.updated_at) >= $4`,
+		edgeNodeID, models.JobStatusCompleted, models.JobEventDispatched, since,
+	).Scan(&latency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute job latency: %w", err)
+	}
+	if latency.Valid {
+		stats.AvgLatencySeconds = &latency.Float64
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// ListSLAPendingJobs 获取尚未完成 SLA 最终评估的任务（执行中，或已结束但尚未评估）
+// 按 created_at, id 升序分页，after 为上一页的最后一条（首页为空）
+func (r *PrintJobRepository) ListSLAPendingJobs(after *JobCursor, limit int) ([]*models.PrintJob, error) {
+	query := `
+		SELECT ` + printJobColumns + `
+		FROM print_jobs
+		WHERE sla_tracked AND sla_evaluated_at IS NULL`
+	args := []interface{}{limit}
+	if after != nil {
+		query += ` AND (created_at, id) > ($2, $3)`
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	query += ` ORDER BY created_at ASC, id ASC LIMIT $1`
+
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sla pending jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.PrintJob
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// UpdateJobSLA 保存 SLA 考核结果：超时标记只增不减，sla.EvaluatedAt 非空时同时记录最终评估时间
+// 只修改 SLA 字段，不更新 updated_at（见 printJobBookkeepingColumns）
+// 已完成最终评估的任务不再修改，返回 false
+func (r *PrintJobRepository) UpdateJobSLA(jobID string, sla *models.JobSLA) (bool, error) {
+	query := `
+		UPDATE print_jobs SET
+			sla_dispatch_breached = sla_dispatch_breached OR $2,
+			sla_start_breached = sla_start_breached OR $3,
+			sla_complete_breached = sla_complete_breached OR $4,
+			sla_evaluated_at = $5
+		WHERE id = $1 AND sla_tracked AND sla_evaluated_at IS NULL`
+
+	result, err := r.db.DB.Exec(query, jobID, sla.DispatchBreached, sla.StartBreached, sla.CompleteBreached, sla.EvaluatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to update job sla: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// SLABreachRate 统计 since 之后完成最终评估的任务数和其中任一阶段超时的任务数
+func (r *PrintJobRepository) SLABreachRate(since time.Time) (int, int, error) {
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE ` + slaBreachedSQL + `)
+		FROM print_jobs pj
+		WHERE pj.sla_evaluated_at >= $1`
+
+	var jobs, breached int
+	if err := r.db.DB.QueryRow(query, since.UTC()).Scan(&jobs, &breached); err != nil {
+		return 0, 0, fmt.Errorf("failed to count sla breaches: %w", err)
+	}
+	return jobs, breached, nil
+}
+
+// slaBreachedSQL 任一阶段超时（pj 为任务）
+const slaBreachedSQL = `pj.sla_dispatch_breached OR pj.sla_start_breached OR pj.sla_complete_breached`
+
+// slaStatColumns SLA 统计的聚合列（与 querySLAStats 的扫描顺序保持一致）
+const slaStatColumns = `COUNT(*),
+		       COUNT(*) FILTER (WHERE ` + slaBreachedSQL + `),
+		       COUNT(*) FILTER (WHERE pj.sla_dispatch_breached),
+		       COUNT(*) FILTER (WHERE pj.sla_start_breached),
+		       COUNT(*) FILTER (WHERE pj.sla_complete_breached),
+		       AVG(EXTRACT(EPOCH FROM (pj.start_time - pj.created_at))) FILTER (WHERE pj.start_time IS NOT NULL)`
+
+// slaStatsFrom 参与 SLA 统计的任务：[$1, $2) 内提交且已完成最终评估
+const slaStatsFrom = `
+		FROM print_jobs pj
+		LEFT JOIN printers p ON pj.printer_id = p.id
+		LEFT JOIN edge_nodes n ON p.edge_node_id = n.id
+		WHERE pj.sla_evaluated_at IS NOT NULL AND pj.created_at >= $1 AND pj.created_at < $2`
+
+// SLAStatsTotal 统计 [startDate, endDate) 内提交的任务的 SLA 达成情况
+func (r *PrintJobRepository) SLAStatsTotal(startDate, endDate time.Time) (*models.SLAStat, error) {
+	query := `SELECT '', '', ` + slaStatColumns + slaStatsFrom
+	stats, err := r.querySLAStats(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return stats[0], nil
+}
+
+// SLAStatsByPrinter 按打印机统计 SLA 达成情况（已删除的打印机按任务记录的名称显示）
+func (r *PrintJobRepository) SLAStatsByPrinter(startDate, endDate time.Time) ([]*models.SLAStat, error) {
+	query := `
+		SELECT COALESCE(pj.printer_id::text, ''), COALESCE(NULLIF(p.display_name, ''), p.name, pj.printer_name, ''),
+		       ` + slaStatColumns + slaStatsFrom + `
+		GROUP BY pj.printer_id, p.display_name, p.name, pj.printer_name
+		ORDER BY 4 DESC, 3 DESC`
+	return r.querySLAStats(query, startDate, endDate)
+}
+
+// SLAStatsBySite 按站点（打印机所属 Edge Node 的位置）统计 SLA 达成情况，未设置位置的归为空站点
+func (r *PrintJobRepository) SLAStatsBySite(startDate, endDate time.Time) ([]*models.SLAStat, error) {
+	query := `
+		SELECT COALESCE(n.location, ''), COALESCE(n.location, ''),
+		       ` + slaStatColumns + slaStatsFrom + `
+		GROUP BY n.location
+		ORDER BY 4 DESC, 3 DESC`
+	return r.querySLAStats(query, startDate, endDate)
+}
+
+// querySLAStats 执行 SLA 统计查询并计算超时率
+func (r *PrintJobRepository) querySLAStats(query string, startDate, endDate time.Time) ([]*models.SLAStat, error) {
+	rows, err := r.db.DB.Query(query, startDate.UTC(), endDate.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sla stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []*models.SLAStat{}
+	for rows.Next() {
+		stat := &models.SLAStat{}
+		var avgStart sql.NullFloat64
+		if err := rows.Scan(&stat.Key, &stat.Name, &stat.Jobs, &stat.BreachedJobs,
+			&stat.DispatchBreaches, &stat.StartBreaches, &stat.CompleteBreaches, &avgStart); err != nil {
+			return nil, err
+		}
+		if stat.Jobs > 0 {
+			stat.BreachRate = float64(stat.BreachedJobs) / float64(stat.Jobs)
+		}
+		if avgStart.Valid {
+			avg := avgStart.Float64
+			stat.AvgTimeToStart = &avg
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
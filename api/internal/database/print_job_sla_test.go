@@ -0,0 +1,56 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestUpdateJobSLAKeepsUpdatedAt SLA 考核结果不重置 updated_at，卡住任务检测不受 SLA 巡检影响
+func TestUpdateJobSLAKeepsUpdatedAt(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	job := createTestJob(t, db, createTestPrinter(t, db), models.JobStatusPrinting)
+
+	lastProgress := time.Now().UTC().Add(-time.Hour).Truncate(time.Microsecond)
+	mustExec(t, db, `ALTER TABLE print_jobs DISABLE TRIGGER update_print_jobs_updated_at`)
+	mustExec(t, db, `UPDATE print_jobs SET updated_at = $2 WHERE id = $1`, job.ID, lastProgress)
+	mustExec(t, db, `ALTER TABLE print_jobs ENABLE TRIGGER update_print_jobs_updated_at`)
+
+	applied, err := repo.UpdateJobSLA(job.ID, &models.JobSLA{StartBreached: true})
+	if err != nil || !applied {
+		t.Fatalf("UpdateJobSLA = %v, %v", applied, err)
+	}
+
+	var updatedAt time.Time
+	var breached bool
+	if err := db.QueryRow(`SELECT updated_at, sla_start_breached FROM print_jobs WHERE id = $1`, job.ID).Scan(&updatedAt, &breached); err != nil {
+		t.Fatalf("load job: %v", err)
+	}
+	if !breached {
+		t.Error("sla_start_breached not saved")
+	}
+	if !updatedAt.Equal(lastProgress) {
+		t.Errorf("updated_at = %s, want unchanged %s", updatedAt, lastProgress)
+	}
+
+	stalled, err := repo.MarkStalledJobs(time.Now().UTC().Add(-30 * time.Minute))
+	if err != nil {
+		t.Fatalf("MarkStalledJobs: %v", err)
+	}
+	if len(stalled) != 1 || stalled[0].ID != job.ID {
+		t.Fatalf("stalled jobs = %d, want job %s", len(stalled), job.ID)
+	}
+
+	// 其他字段的修改仍然更新 updated_at
+	if err := repo.UpdateJobErrorMessage(job.ID, "paper jam"); err != nil {
+		t.Fatalf("UpdateJobErrorMessage: %v", err)
+	}
+	if err := db.QueryRow(`SELECT updated_at FROM print_jobs WHERE id = $1`, job.ID).Scan(&updatedAt); err != nil {
+		t.Fatalf("load job: %v", err)
+	}
+	if !updatedAt.After(lastProgress) {
+		t.Errorf("updated_at = %s after error message update, want bumped", updatedAt)
+	}
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// jobStatus 读取任务当前状态
+func jobStatus(t *testing.T, repo *PrintJobRepository, jobID string) models.JobStatus {
+	t.Helper()
+	job, err := repo.GetPrintJobByID(jobID)
+	if err != nil || job == nil {
+		t.Fatalf("get job %s: %v", jobID, err)
+	}
+	return job.Status
+}
+
+func TestUpdateJobStatusRejectsOutOfOrderUpdates(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	job := createTestJob(t, db, createTestPrinter(t, db), models.JobStatusDispatched)
+
+	t0 := time.Now().UTC().Truncate(time.Microsecond)
+	if applied, err := repo.UpdateJobStatus(job.ID, models.JobStatusPrinting, 40, t0.Add(2*time.Second)); err != nil || !applied {
+		t.Fatalf("printing: applied=%v err=%v", applied, err)
+	}
+	// 较早接收的 downloading 在 printing 之后才处理（乱序），不能覆盖
+	if applied, err := repo.UpdateJobStatus(job.ID, models.JobStatusDownloading, 10, t0.Add(time.Second)); err != nil || applied {
+		t.Fatalf("stale downloading: applied=%v err=%v, want rejected", applied, err)
+	}
+	if applied, err := repo.UpdateJobProgress(job.ID, models.JobStatusPrinting, 20, t0.Add(time.Second)); err != nil || applied {
+		t.Fatalf("stale progress: applied=%v err=%v, want rejected", applied, err)
+	}
+	if got := jobStatus(t, repo, job.ID); got != models.JobStatusPrinting {
+		t.Fatalf("status = %s, want printing", got)
+	}
+}
+
+func TestUpdateJobStatusIgnoresOtherWrites(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	job := createTestJob(t, db, createTestPrinter(t, db), models.JobStatusDispatched)
+
+	// 应用的时钟比数据库慢一小时：其他写入经触发器把 updated_at 改为数据库时间后，节点更新仍应写入
+	receivedAt := time.Now().UTC().Add(-time.Hour)
+	if applied, err := repo.UpdateJobStatus(job.ID, models.JobStatusPrinting, 50, receivedAt); err != nil || !applied {
+		t.Fatalf("printing: applied=%v err=%v", applied, err)
+	}
+	if err := repo.UpdateJobErrorMessage(job.ID, "toner low"); err != nil {
+		t.Fatalf("update error message: %v", err)
+	}
+	mustExec(t, db, `UPDATE print_jobs SET cost = 1 WHERE id = $1`, job.ID)
+
+	if applied, err := repo.UpdateJobStatus(job.ID, models.JobStatusCompleted, 100, receivedAt.Add(time.Second)); err != nil || !applied {
+		t.Fatalf("completed after unrelated writes: applied=%v err=%v", applied, err)
+	}
+	if got := jobStatus(t, repo, job.ID); got != models.JobStatusCompleted {
+		t.Fatalf("status = %s, want completed", got)
+	}
+}
+
+func TestUpdateJobStatusNeverRejectsTerminal(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	job := createTestJob(t, db, createTestPrinter(t, db), models.JobStatusDispatched)
+
+	t0 := time.Now().UTC().Truncate(time.Microsecond)
+	if applied, err := repo.UpdateJobStatus(job.ID, models.JobStatusPrinting, 90, t0.Add(time.Second)); err != nil || !applied {
+		t.Fatalf("printing: applied=%v err=%v", applied, err)
+	}
+	// 结束状态即使接收时间较早也写入，任务不会停留在执行中
+	if applied, err := repo.UpdateJobStatus(job.ID, models.JobStatusCompleted, 100, t0); err != nil || !applied {
+		t.Fatalf("completed: applied=%v err=%v, want applied", applied, err)
+	}
+	if got := jobStatus(t, repo, job.ID); got != models.JobStatusCompleted {
+		t.Fatalf("status = %s, want completed", got)
+	}
+
+	// 之后较早的非结束状态仍被拒绝
+	if applied, err := repo.UpdateJobStatus(job.ID, models.JobStatusPrinting, 10, t0.Add(500*time.Millisecond)); err != nil || applied {
+		t.Fatalf("stale printing after completion: applied=%v err=%v, want rejected", applied, err)
+	}
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestCountJobsByDayTimezone 按目标时区划分自然日：UTC 12 月 31 日晚的任务计入新加坡的 1 月 1 日
+func TestCountJobsByDayTimezone(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrintJobRepository(db)
+	printerID := createTestPrinter(t, db)
+
+	for _, createdAt := range []time.Time{
+		time.Date(2026, 12, 31, 15, 59, 0, 0, time.UTC), // 新加坡 12-31 23:59
+		time.Date(2026, 12, 31, 16, 0, 0, 0, time.UTC),  // 新加坡 01-01 00:00
+		time.Date(2027, 1, 1, 3, 0, 0, 0, time.UTC),     // 新加坡 01-01 11:00
+	} {
+		job := createTestJob(t, db, printerID, models.JobStatusCompleted)
+		mustExec(t, db, `UPDATE print_jobs SET created_at = $2 WHERE id = $1`, job.ID, createdAt)
+	}
+
+	counts, err := repo.CountJobsByDay(time.Date(2026, 12, 30, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 3, 0, 0, 0, 0, time.UTC),
+		"Asia/Singapore", models.JobStatusCompleted)
+	if err != nil {
+		t.Fatalf("CountJobsByDay: %v", err)
+	}
+	if got := counts["2026-12-31"][models.JobStatusCompleted]; got != 1 {
+		t.Errorf("2026-12-31 = %d, want 1", got)
+	}
+	if got := counts["2027-01-01"][models.JobStatusCompleted]; got != 2 {
+		t.Errorf("2027-01-01 = %d, want 2", got)
+	}
+}
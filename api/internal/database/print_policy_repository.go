@@ -0,0 +1,174 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// PrintPolicyRepository 组织级打印策略数据访问层
+type PrintPolicyRepository struct {
+	db *DB
+}
+
+// NewPrintPolicyRepository 创建打印策略数据访问层
+func NewPrintPolicyRepository(db *DB) *PrintPolicyRepository {
+	return &PrintPolicyRepository{db: db}
+}
+
+// printPolicyColumns 打印策略查询列（与 scanPrintPolicy 的扫描顺序保持一致）
+const printPolicyColumns = `id, name, description, priority, enabled, conditions, effects, created_by, created_at, updated_at`
+
+// printPolicyOrder 策略的评估顺序：优先级从高到低，相同时按名称
+const printPolicyOrder = ` ORDER BY priority DESC, name`
+
+// scanPrintPolicy 扫描一行打印策略数据
+func scanPrintPolicy(row rowScanner) (*models.PrintPolicy, error) {
+	policy := &models.PrintPolicy{}
+	var description, createdBy sql.NullString
+	var conditions, effects []byte
+
+	err := row.Scan(&policy.ID, &policy.Name, &description, &policy.Priority, &policy.Enabled,
+		&conditions, &effects, &createdBy, &policy.CreatedAt, &policy.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	policy.Description = description.String
+	policy.CreatedBy = createdBy.String
+	if err := json.Unmarshal(conditions, &policy.Conditions); err != nil {
+		return nil, fmt.Errorf("failed to parse print policy conditions: %w", err)
+	}
+	if err := json.Unmarshal(effects, &policy.Effects); err != nil {
+		return nil, fmt.Errorf("failed to parse print policy effects: %w", err)
+	}
+	return policy, nil
+}
+
+// printPolicyArgs 条件和效果的 JSON 写入参数
+func printPolicyArgs(policy *models.PrintPolicy) (string, string, error) {
+	conditions, err := json.Marshal(policy.Conditions)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode print policy conditions: %w", err)
+	}
+	effects, err := json.Marshal(policy.Effects)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode print policy effects: %w", err)
+	}
+	return string(conditions), string(effects), nil
+}
+
+// CreatePolicy 创建打印策略，名称已存在时返回 false
+func (r *PrintPolicyRepository) CreatePolicy(policy *models.PrintPolicy) (bool, error) {
+	conditions, effects, err := printPolicyArgs(policy)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO print_policies (name, description, priority, enabled, conditions, effects, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, created_at, updated_at`
+
+	err = r.db.QueryRow(query, policy.Name, nullIfEmpty(policy.Description), policy.Priority, policy.Enabled,
+		conditions, effects, nullIfEmpty(policy.CreatedBy)).
+		Scan(&policy.ID, &policy.CreatedAt, &policy.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create print policy: %w", err)
+	}
+	return true, nil
+}
+
+// UpdatePolicy 更新打印策略，策略不存在时返回 false
+func (r *PrintPolicyRepository) UpdatePolicy(policy *models.PrintPolicy) (bool, error) {
+	conditions, effects, err := printPolicyArgs(policy)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		UPDATE print_policies SET name = $2, description = $3, priority = $4, enabled = $5,
+			conditions = $6, effects = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING created_by, created_at, updated_at`
+
+	var createdBy sql.NullString
+	err = r.db.QueryRow(query, policy.ID, policy.Name, nullIfEmpty(policy.Description), policy.Priority, policy.Enabled,
+		conditions, effects).
+		Scan(&createdBy, &policy.CreatedAt, &policy.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update print policy: %w", err)
+	}
+	policy.CreatedBy = createdBy.String
+	return true, nil
+}
+
+// GetPolicy 根据ID获取打印策略，不存在时返回 nil
+func (r *PrintPolicyRepository) GetPolicy(id string) (*models.PrintPolicy, error) {
+	query := `SELECT ` + printPolicyColumns + ` FROM print_policies WHERE id = $1`
+
+	policy, err := scanPrintPolicy(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get print policy: %w", err)
+	}
+	return policy, nil
+}
+
+// PolicyNameTaken 判断名称是否已被其他打印策略使用
+func (r *PrintPolicyRepository) PolicyNameTaken(name, excludeID string) (bool, error) {
+	var taken bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM print_policies WHERE name = $1 AND id <> $2)`, name, excludeID).Scan(&taken)
+	if err != nil {
+		return false, fmt.Errorf("failed to check print policy name: %w", err)
+	}
+	return taken, nil
+}
+
+// ListPolicies 按评估顺序获取打印策略，enabledOnly 为 true 时只返回已启用的策略
+func (r *PrintPolicyRepository) ListPolicies(enabledOnly bool) ([]*models.PrintPolicy, error) {
+	query := `SELECT ` + printPolicyColumns + ` FROM print_policies`
+	if enabledOnly {
+		query += ` WHERE enabled = TRUE`
+	}
+	query += printPolicyOrder
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list print policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []*models.PrintPolicy{}
+	for rows.Next() {
+		policy, err := scanPrintPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan print policy: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// DeletePolicy 删除打印策略（已创建任务记录的策略名称保留），策略不存在时返回 false
+func (r *PrintPolicyRepository) DeletePolicy(id string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM print_policies WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete print policy: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
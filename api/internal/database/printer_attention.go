@@ -0,0 +1,37 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// 待处理标记与预留相同，不增加 row_version，也不更新 updated_at：标记由系统写入，不应使管理员正在编辑的打印机配置失效
+
+// FlagPrinterAttention 标记打印机需要管理员处理（如任务因硬件故障被转移到备用打印机），已标记时更新原因和时间
+func (r *PrinterRepository) FlagPrinterAttention(printerID, reason string, at time.Time) error {
+	query := `UPDATE printers SET needs_attention = TRUE, attention_reason = $2, attention_at = $3 WHERE id = $1`
+	if _, err := r.db.Exec(query, printerID, reason, at); err != nil {
+		return fmt.Errorf("failed to flag printer attention: %w", err)
+	}
+	r.db.invalidateLookup(LookupKindPrinter, printerID)
+	return nil
+}
+
+// ClearPrinterAttention 清除打印机的待处理标记，返回打印机是否曾被标记
+func (r *PrinterRepository) ClearPrinterAttention(printerID string) (bool, error) {
+	query := `
+		UPDATE printers SET needs_attention = FALSE, attention_reason = NULL, attention_at = NULL
+		WHERE id = $1 AND needs_attention`
+	result, err := r.db.Exec(query, printerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear printer attention: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected > 0 {
+		r.db.invalidateLookup(LookupKindPrinter, printerID)
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// 熔断状态与预留相同，不增加 row_version：由系统写入，不应使管理员正在编辑的打印机配置失效
+
+// ModifyPrinterBreaker 在事务中读取并更新打印机的熔断状态（行锁保证同一打印机的并发任务结果依次计入），
+// 返回更新前后的状态；打印机不存在时返回 sql.ErrNoRows
+func (r *PrinterRepository) ModifyPrinterBreaker(printerID string, update func(models.PrinterBreaker) models.PrinterBreaker) (models.PrinterBreaker, models.PrinterBreaker, error) {
+	var before, after models.PrinterBreaker
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return before, after, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var reason sql.NullString
+	err = tx.QueryRow(`
+		SELECT breaker_state, breaker_failures, breaker_window_started_at, breaker_suspended_at, breaker_resume_at, breaker_reason
+		FROM printers WHERE id = $1 FOR UPDATE`, printerID).
+		Scan(&before.State, &before.ConsecutiveFailures, &before.WindowStartedAt, &before.SuspendedAt, &before.ResumeAt, &reason)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return before, after, err
+		}
+		return before, after, fmt.Errorf("failed to get printer breaker: %w", err)
+	}
+	before.Reason = reason.String
+
+	after = update(before)
+	_, err = tx.Exec(`
+		UPDATE printers SET breaker_state = $2, breaker_failures = $3, breaker_window_started_at = $4,
+			breaker_suspended_at = $5, breaker_resume_at = $6, breaker_reason = $7
+		WHERE id = $1`,
+		printerID, after.State, after.ConsecutiveFailures, after.WindowStartedAt, after.SuspendedAt, after.ResumeAt, nullIfEmpty(after.Reason))
+	if err != nil {
+		return before, after, fmt.Errorf("failed to update printer breaker: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return before, after, fmt.Errorf("failed to commit printer breaker: %w", err)
+	}
+	r.db.invalidateLookup(LookupKindPrinter, printerID)
+	return before, after, nil
+}
+
+// ListBreakersDueToResume 返回熔断冷却已在 now 之前结束的打印机ID
+func (r *PrinterRepository) ListBreakersDueToResume(now time.Time) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT id FROM printers
+		WHERE breaker_state = $1 AND breaker_resume_at <= $2`, models.BreakerSuspended, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suspended printers: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan suspended printer: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
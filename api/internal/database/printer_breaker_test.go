@@ -0,0 +1,72 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestPrinterBreakerPersisted 熔断状态写入打印机记录，冷却结束后出现在待恢复列表中
+func TestPrinterBreakerPersisted(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrinterRepository(db)
+	printerID := createTestPrinter(t, db)
+
+	policy := models.BreakerPolicy{Failures: 2, Window: time.Minute, Cooldown: 10 * time.Minute}
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 2; i++ {
+		if _, _, err := repo.ModifyPrinterBreaker(printerID, func(b models.PrinterBreaker) models.PrinterBreaker {
+			b, _ = policy.RecordFailure(b, now, "paper jam")
+			return b
+		}); err != nil {
+			t.Fatalf("ModifyPrinterBreaker: %v", err)
+		}
+	}
+
+	printer, err := repo.GetPrinterByID(printerID)
+	if err != nil {
+		t.Fatalf("get printer: %v", err)
+	}
+	b := printer.Breaker
+	if !b.Suspended() || b.ConsecutiveFailures != 2 || b.Reason != "paper jam" || b.ResumeAt == nil || !b.ResumeAt.Equal(now.Add(10*time.Minute)) {
+		t.Fatalf("stored breaker = %+v; want suspended until %s", b, now.Add(10*time.Minute))
+	}
+
+	due := func(at time.Time) bool {
+		ids, err := repo.ListBreakersDueToResume(at)
+		if err != nil {
+			t.Fatalf("ListBreakersDueToResume: %v", err)
+		}
+		for _, id := range ids {
+			if id == printerID {
+				return true
+			}
+		}
+		return false
+	}
+	if due(now.Add(9 * time.Minute)) {
+		t.Fatal("printer due to resume before the cooldown ended")
+	}
+	if !due(now.Add(10 * time.Minute)) {
+		t.Fatal("printer not due to resume after the cooldown")
+	}
+
+	before, after, err := repo.ModifyPrinterBreaker(printerID, func(b models.PrinterBreaker) models.PrinterBreaker {
+		b, _ = policy.Resume(b, now.Add(10*time.Minute))
+		return b
+	})
+	if err != nil || !before.Suspended() || after.Suspended() {
+		t.Fatalf("resume = %+v -> %+v, %v", before, after, err)
+	}
+	if printer, _ = repo.GetPrinterByID(printerID); printer.Breaker.Suspended() || printer.Breaker.ConsecutiveFailures != 1 || printer.Breaker.Reason != "" {
+		t.Fatalf("breaker after resume = %+v; want closed on probation", printer.Breaker)
+	}
+	if due(now.Add(time.Hour)) {
+		t.Fatal("resumed printer still listed")
+	}
+
+	if _, _, err := repo.ModifyPrinterBreaker("00000000-0000-0000-0000-000000000000", func(b models.PrinterBreaker) models.PrinterBreaker { return b }); err == nil {
+		t.Fatal("ModifyPrinterBreaker on a missing printer succeeded")
+	}
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestUnmarshalCapabilities(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    models.PrinterCapabilities
+		wantErr bool
+	}{
+		{"sql null", nil, models.PrinterCapabilities{}, false},
+		{"empty", []byte(""), models.PrinterCapabilities{}, false},
+		{"blank", []byte("  \n"), models.PrinterCapabilities{}, false},
+		{"json null", []byte("null"), models.PrinterCapabilities{}, false},
+		{"empty object", []byte("{}"), models.PrinterCapabilities{}, false},
+		{"values", []byte(`{"paper_sizes":["iso_a4_210x297mm"],"color_support":true}`),
+			models.PrinterCapabilities{PaperSizes: []string{"iso_a4_210x297mm"}, ColorSupport: true}, false},
+		{"malformed", []byte(`{"paper_sizes":`), models.PrinterCapabilities{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 先填入旧值，确认解析结果不残留上一行的数据
+			got := models.PrinterCapabilities{DuplexSupport: true, MediaTypes: []string{"stale"}}
+			err := unmarshalCapabilities(tt.data, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("capabilities = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPrinterNullCapabilities 历史数据中 capabilities 为 NULL 的打印机在各查询中读作空能力；
+// 启动迁移将其回填为 '{}' 并恢复 NOT NULL 约束
+func TestPrinterNullCapabilities(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrinterRepository(db)
+
+	// 模拟迁移前的表结构
+	mustExec(t, db, `ALTER TABLE printers ALTER COLUMN capabilities DROP NOT NULL`)
+	nodeID := "node-" + uuid.New().String()[:8]
+	printerID := uuid.New().String()
+	mustExec(t, db, `INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'online')`, nodeID)
+	mustExec(t, db, `INSERT INTO printers (id, name, status, edge_node_id, slug, capabilities) VALUES ($1, 'legacy', 'ready', $2, 'legacy', NULL)`,
+		printerID, nodeID)
+
+	check := func(source string, printer *models.Printer, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", source, err)
+		}
+		if printer == nil || printer.ID != printerID {
+			t.Fatalf("%s: printer = %+v, want %s", source, printer, printerID)
+		}
+		if !reflect.DeepEqual(printer.Capabilities, models.PrinterCapabilities{}) {
+			t.Fatalf("%s: capabilities = %+v, want empty", source, printer.Capabilities)
+		}
+	}
+	find := func(printers []*models.Printer) *models.Printer {
+		for _, p := range printers {
+			if p.ID == printerID {
+				return p
+			}
+		}
+		return nil
+	}
+
+	printer, err := repo.GetPrinterByID(printerID)
+	check("GetPrinterByID", printer, err)
+	printer, err = repo.GetPrinterByNameAndEdgeNode("legacy", nodeID)
+	check("GetPrinterByNameAndEdgeNode", printer, err)
+	printers, _, err := repo.ListPrinters(1, 100)
+	check("ListPrinters", find(printers), err)
+	printers, err = repo.ListPrintersByEdgeNode(nodeID)
+	check("ListPrintersByEdgeNode", find(printers), err)
+	printers, _, err = repo.ListPrintersByEdgeNodePaged(nodeID, 1, 10)
+	check("ListPrintersByEdgeNodePaged", find(printers), err)
+
+	// JSON null 同样视为空
+	mustExec(t, db, `UPDATE printers SET capabilities = 'null'::jsonb WHERE id = $1`, printerID)
+	printer, err = repo.GetPrinterByID(printerID)
+	check("GetPrinterByID with JSON null", printer, err)
+
+	if err := db.InitTables(); err != nil {
+		t.Fatalf("rerun migrations: %v", err)
+	}
+	var stored string
+	if err := db.QueryRow(`SELECT capabilities::text FROM printers WHERE id = $1`, printerID).Scan(&stored); err != nil {
+		t.Fatalf("read capabilities: %v", err)
+	}
+	if stored != "{}" {
+		t.Fatalf("backfilled capabilities = %s, want {}", stored)
+	}
+	if _, err := db.Exec(`UPDATE printers SET capabilities = NULL WHERE id = $1`, printerID); err == nil {
+		t.Fatal("capabilities accepted NULL after migration")
+	}
+}
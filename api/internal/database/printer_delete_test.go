@@ -0,0 +1,122 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// printerExists 打印机行是否存在
+func printerExists(t *testing.T, db *DB, printerID string) bool {
+	t.Helper()
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM printers WHERE id = $1)`, printerID).Scan(&exists); err != nil {
+		t.Fatalf("check printer: %v", err)
+	}
+	return exists
+}
+
+// TestDeletePrinterRefusesUnfinishedJobs 未指定 force 时有未结束任务的打印机不会被删除，任务保持原样
+func TestDeletePrinterRefusesUnfinishedJobs(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrinterRepository(db)
+	jobs := NewPrintJobRepository(db)
+	printerID := createTestPrinter(t, db)
+	pending := createTestJob(t, db, printerID, models.JobStatusPending)
+	printing := createTestJob(t, db, printerID, models.JobStatusPrinting)
+	completed := createTestJob(t, db, printerID, models.JobStatusCompleted)
+
+	cancelled, err := repo.DeletePrinter(printerID, false, "admin", time.Time{})
+	var hasJobs *PrinterHasJobsError
+	if !errors.As(err, &hasJobs) || hasJobs.Unfinished != 2 || cancelled != nil {
+		t.Fatalf("delete = %v, %v; want PrinterHasJobsError with 2 unfinished jobs", cancelled, err)
+	}
+	if !printerExists(t, db, printerID) {
+		t.Fatal("printer deleted despite unfinished jobs")
+	}
+	for job, want := range map[*models.PrintJob]models.JobStatus{
+		pending:   models.JobStatusPending,
+		printing:  models.JobStatusPrinting,
+		completed: models.JobStatusCompleted,
+	} {
+		reread, err := jobs.GetPrintJobByID(job.ID)
+		if err != nil {
+			t.Fatalf("get job: %v", err)
+		}
+		if reread.Status != want || reread.PrinterID != printerID || reread.PrinterName != "" {
+			t.Fatalf("job after refused delete = %+v, want %s on %s", reread, want, printerID)
+		}
+	}
+}
+
+// TestDeletePrinterForce force 删除时先取消未结束任务，所有任务保留并记录打印机名称，同时记录墓碑
+func TestDeletePrinterForce(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrinterRepository(db)
+	jobs := NewPrintJobRepository(db)
+	printerID := createTestPrinter(t, db)
+	mustExec(t, db, `UPDATE printers SET display_name = 'Front Desk' WHERE id = $1`, printerID)
+	pending := createTestJob(t, db, printerID, models.JobStatusPending)
+	completed := createTestJob(t, db, printerID, models.JobStatusCompleted)
+
+	cancelled, err := repo.DeletePrinter(printerID, true, "admin", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("force delete: %v", err)
+	}
+	if len(cancelled) != 1 || cancelled[0].ID != pending.ID || cancelled[0].Status != models.JobStatusCancelled {
+		t.Fatalf("cancelled = %+v, want only the pending job", cancelled)
+	}
+	if printerExists(t, db, printerID) {
+		t.Fatal("printer still exists after force delete")
+	}
+
+	for job, want := range map[*models.PrintJob]models.JobStatus{
+		pending:   models.JobStatusCancelled,
+		completed: models.JobStatusCompleted,
+	} {
+		reread, err := jobs.GetPrintJobByID(job.ID)
+		if err != nil || reread == nil {
+			t.Fatalf("job %s lost after delete: %v", job.ID, err)
+		}
+		if reread.Status != want || reread.PrinterID != "" || reread.PrinterName != "Front Desk" {
+			t.Fatalf("job after delete = %+v, want %s detached with printer name", reread, want)
+		}
+	}
+	reread, _ := jobs.GetPrintJobByID(pending.ID)
+	if reread.EndTime == nil || reread.ErrorMessage == "" || reread.PerformedBy != "admin" {
+		t.Fatalf("cancelled job = %+v, want end time, reason and actor", reread)
+	}
+
+	var tombstones int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM printer_tombstones WHERE printer_id = $1`, printerID).Scan(&tombstones); err != nil {
+		t.Fatalf("count tombstones: %v", err)
+	}
+	if tombstones != 1 {
+		t.Fatalf("tombstones = %d, want 1", tombstones)
+	}
+}
+
+// TestPrinterJobsForeignKeyRestricts 直接删除仍被任务引用的打印机会被外键拒绝，不会级联删除任务
+func TestPrinterJobsForeignKeyRestricts(t *testing.T) {
+	db := openTestDB(t)
+	printerID := createTestPrinter(t, db)
+	job := createTestJob(t, db, printerID, models.JobStatusCompleted)
+
+	if _, err := db.Exec(`DELETE FROM printers WHERE id = $1`, printerID); err == nil {
+		t.Fatal("deleting a printer with jobs succeeded; want a foreign key violation")
+	}
+	if reread, err := NewPrintJobRepository(db).GetPrintJobByID(job.ID); err != nil || reread == nil {
+		t.Fatalf("job lost: %v", err)
+	}
+
+	// 没有任务的打印机直接删除
+	empty := createTestPrinter(t, db)
+	if _, err := NewPrinterRepository(db).DeletePrinter(empty, false, "admin", time.Time{}); err != nil {
+		t.Fatalf("delete printer without jobs: %v", err)
+	}
+	if printerExists(t, db, empty) {
+		t.Fatal("printer without jobs still exists")
+	}
+}
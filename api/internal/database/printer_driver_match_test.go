@@ -0,0 +1,71 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+func TestCompileModelPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		model   string
+		want    bool
+	}{
+		{"HP LaserJet*", "HP LaserJet Pro M404", true},
+		{"hp laserjet*", "HP LaserJet Pro M404", true},
+		{"HP LaserJet*", "Canon LBP", false},
+		{"Brother HL-L23?0*", "Brother HL-L2350DW", true},
+		{"Brother HL-L23?0*", "Brother HL-L2400", false},
+		{"  Epson (ET)*  ", "Epson (ET)-2850", true},
+		{"Exact Model", "Exact Model ", true},
+		{"Exact Model", "Exact Model 2", false},
+		{"a.b", "axb", false},
+	}
+	for _, tt := range tests {
+		re, err := compileModelPattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compile %q: %v", tt.pattern, err)
+		}
+		if got := re.MatchString(strings.TrimSpace(tt.model)); got != tt.want {
+			t.Fatalf("%q matches %q = %v, want %v", tt.pattern, tt.model, got, tt.want)
+		}
+	}
+}
+
+// TestModelPatternsCompileOnce 未变化的模式不重复编译，修改或删除的模式随驱动列表更新
+func TestModelPatternsCompileOnce(t *testing.T) {
+	var p modelPatterns
+	drivers := []*models.PrinterDriver{
+		{ModelPattern: "HP LaserJet*"},
+		{ModelPattern: "Brother*"},
+	}
+
+	first := p.sync(drivers)
+	hp, brother := first["HP LaserJet*"], first["Brother*"]
+	if hp == nil || brother == nil {
+		t.Fatalf("sync = %v, want both patterns compiled", first)
+	}
+
+	again := p.sync(drivers)
+	if again["HP LaserJet*"] != hp || again["Brother*"] != brother {
+		t.Fatal("unchanged patterns were recompiled")
+	}
+
+	drivers[1] = &models.PrinterDriver{ModelPattern: "Canon*"}
+	changed := p.sync(drivers)
+	if changed["HP LaserJet*"] != hp {
+		t.Fatal("unchanged pattern recompiled after another driver changed")
+	}
+	if changed["Canon*"] == nil || !changed["Canon*"].MatchString("Canon LBP6030") {
+		t.Fatal("changed pattern not compiled")
+	}
+	if _, ok := changed["Brother*"]; ok || len(changed) != 2 {
+		t.Fatalf("sync = %v, want removed pattern dropped", changed)
+	}
+
+	if len(p.sync(nil)) != 0 {
+		t.Fatal("sync with no drivers kept stale patterns")
+	}
+}
@@ -0,0 +1,235 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// PrinterDriverRepository 打印机驱动数据访问层
+type PrinterDriverRepository struct {
+	db       *DB
+	patterns modelPatterns
+}
+
+// NewPrinterDriverRepository 创建打印机驱动数据访问层
+func NewPrinterDriverRepository(db *DB) *PrinterDriverRepository {
+	return &PrinterDriverRepository{db: db}
+}
+
+const printerDriverColumns = `id, model_pattern, driver_name, ppd_file, ppd_size, options, priority, created_at, updated_at`
+
+// scanPrinterDriver 扫描一行驱动数据
+func scanPrinterDriver(row rowScanner) (*models.PrinterDriver, error) {
+	driver := &models.PrinterDriver{}
+	var ppdFile sql.NullString
+	var ppdSize sql.NullInt64
+	var optionsJSON []byte
+
+	err := row.Scan(&driver.ID, &driver.ModelPattern, &driver.DriverName, &ppdFile, &ppdSize,
+		&optionsJSON, &driver.Priority, &driver.CreatedAt, &driver.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	driver.PPDFile = ppdFile.String
+	driver.PPDSize = ppdSize.Int64
+	if len(optionsJSON) > 0 {
+		if err := json.Unmarshal(optionsJSON, &driver.Options); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal driver options: %w", err)
+		}
+	}
+
+	return driver, nil
+}
+
+// CreateDriver 创建驱动
+func (r *PrinterDriverRepository) CreateDriver(driver *models.PrinterDriver) error {
+	optionsJSON, err := json.Marshal(driver.Options)
+	if err != nil {
+		return fmt.Errorf("failed to marshal driver options: %w", err)
+	}
+
+	query := `
+		INSERT INTO printer_drivers (model_pattern, driver_name, options, priority)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+	err = r.db.QueryRow(query, driver.ModelPattern, driver.DriverName, optionsJSON, driver.Priority).
+		Scan(&driver.ID, &driver.CreatedAt, &driver.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create printer driver: %w", err)
+	}
+
+	return nil
+}
+
+// GetDriverByID 根据ID获取驱动
+func (r *PrinterDriverRepository) GetDriverByID(id string) (*models.PrinterDriver, error) {
+	query := `SELECT ` + printerDriverColumns + ` FROM printer_drivers WHERE id = $1`
+
+	driver, err := scanPrinterDriver(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("printer driver not found")
+		}
+		return nil, fmt.Errorf("failed to get printer driver: %w", err)
+	}
+
+	return driver, nil
+}
+
+// ListDrivers 获取全部驱动（按优先级排序）
+func (r *PrinterDriverRepository) ListDrivers() ([]*models.PrinterDriver, error) {
+	query := `
+		SELECT ` + printerDriverColumns + `
+		FROM printer_drivers
+		ORDER BY priority DESC, LENGTH(model_pattern) DESC, created_at`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list printer drivers: %w", err)
+	}
+	defer rows.Close()
+
+	drivers := []*models.PrinterDriver{}
+	for rows.Next() {
+		driver, err := scanPrinterDriver(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan printer driver: %w", err)
+		}
+		drivers = append(drivers, driver)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return drivers, nil
+}
+
+// UpdateDriver 更新驱动
+func (r *PrinterDriverRepository) UpdateDriver(driver *models.PrinterDriver) error {
+	optionsJSON, err := json.Marshal(driver.Options)
+	if err != nil {
+		return fmt.Errorf("failed to marshal driver options: %w", err)
+	}
+
+	query := `
+		UPDATE printer_drivers
+		SET model_pattern = $2, driver_name = $3, options = $4, priority = $5
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err = r.db.QueryRow(query, driver.ID, driver.ModelPattern, driver.DriverName, optionsJSON, driver.Priority).
+		Scan(&driver.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("printer driver not found")
+		}
+		return fmt.Errorf("failed to update printer driver: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateDriverPPD 更新驱动的 PPD 文件信息
+func (r *PrinterDriverRepository) UpdateDriverPPD(id, fileName string, size int64) error {
+	query := `UPDATE printer_drivers SET ppd_file = $2, ppd_size = $3 WHERE id = $1`
+	_, err := r.db.Exec(query, id, fileName, size)
+	if err != nil {
+		return fmt.Errorf("failed to update driver ppd: %w", err)
+	}
+	return nil
+}
+
+// DeleteDriver 删除驱动
+func (r *PrinterDriverRepository) DeleteDriver(id string) error {
+	result, err := r.db.Exec(`DELETE FROM printer_drivers WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete printer driver: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("printer driver not found")
+	}
+
+	return nil
+}
+
+// FindDriverForModel 按优先级查找匹配打印机型号的驱动，没有匹配时返回 nil
+func (r *PrinterDriverRepository) FindDriverForModel(model string) (*models.PrinterDriver, error) {
+	if model == "" {
+		return nil, nil
+	}
+
+	drivers, err := r.ListDrivers()
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := r.patterns.sync(drivers)
+	model = strings.TrimSpace(model)
+	for _, driver := range drivers {
+		if re := compiled[driver.ModelPattern]; re != nil && re.MatchString(model) {
+			return driver, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// modelPatterns 已编译的型号匹配模式，按模式字符串缓存
+// 每次查找按读取到的驱动列表同步：新增或修改的模式编译一次，已删除的模式移除，未变化的模式不重复编译
+type modelPatterns struct {
+	mutex    sync.Mutex
+	compiled map[string]*regexp.Regexp // 无法编译的模式为 nil（不匹配任何型号）
+}
+
+// sync 按驱动列表更新缓存，返回当前全部模式的匹配器（调用方只读）
+func (p *modelPatterns) sync(drivers []*models.PrinterDriver) map[string]*regexp.Regexp {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	unchanged := len(drivers) == len(p.compiled)
+	for _, driver := range drivers {
+		if _, ok := p.compiled[driver.ModelPattern]; !ok {
+			unchanged = false
+			break
+		}
+	}
+	if unchanged {
+		return p.compiled
+	}
+
+	compiled := make(map[string]*regexp.Regexp, len(drivers))
+	for _, driver := range drivers {
+		if _, ok := compiled[driver.ModelPattern]; ok {
+			continue
+		}
+		re, ok := p.compiled[driver.ModelPattern]
+		if !ok {
+			re, _ = compileModelPattern(driver.ModelPattern)
+		}
+		compiled[driver.ModelPattern] = re
+	}
+	p.compiled = compiled
+	return compiled
+}
+
+// compileModelPattern 编译型号通配符模式（* 任意字符，? 单个字符，忽略大小写）
+func compileModelPattern(pattern string) (*regexp.Regexp, error) {
+	expr := regexp.QuoteMeta(strings.TrimSpace(pattern))
+	expr = strings.ReplaceAll(expr, `\*`, `.*`)
+	expr = strings.ReplaceAll(expr, `\?`, `.`)
+	return regexp.Compile(`(?i)^` + expr + `$`)
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// seedNodePrinters 在新建的 Edge Node 下创建 n 台打印机，创建时间依次递增，返回按列表顺序（最新在前）排列的打印机ID
+func seedNodePrinters(t *testing.T, db *DB, n int) (string, []string) {
+	t.Helper()
+	nodeID := "node-" + uuid.New().String()[:8]
+	mustExec(t, db, `INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'online')`, nodeID)
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	ids := make([]string, n)
+	for i := 0; i < n; i++ {
+		id := uuid.New().String()
+		name := fmt.Sprintf("%s-p%d", nodeID, i)
+		mustExec(t, db, `INSERT INTO printers (id, name, status, edge_node_id, slug, created_at) VALUES ($1, $2, 'ready', $3, $2, $4)`,
+			id, name, nodeID, base.Add(time.Duration(i)*time.Minute))
+		ids[n-1-i] = id
+	}
+	return nodeID, ids
+}
+
+// TestListPrintersByEdgeNodePaged 按 Edge Node 筛选时分页和总数只计算该节点的打印机
+func TestListPrintersByEdgeNodePaged(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrinterRepository(db)
+	nodeID, ids := seedNodePrinters(t, db, 5)
+	seedNodePrinters(t, db, 3) // 其他节点的打印机不计入
+
+	tests := []struct {
+		page, pageSize int
+		want           []string
+	}{
+		{1, 2, ids[0:2]},
+		{2, 2, ids[2:4]},
+		{3, 2, ids[4:5]},
+		{4, 2, nil},
+		{1, 10, ids},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("page %d size %d", tt.page, tt.pageSize), func(t *testing.T) {
+			printers, total, err := repo.ListPrintersByEdgeNodePaged(nodeID, tt.page, tt.pageSize)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if total != 5 {
+				t.Fatalf("total = %d, want 5", total)
+			}
+			if len(printers) != len(tt.want) {
+				t.Fatalf("got %d printers, want %d", len(printers), len(tt.want))
+			}
+			for i, p := range printers {
+				if p.ID != tt.want[i] || p.EdgeNodeID != nodeID {
+					t.Fatalf("printer %d = %s on %s, want %s on %s", i, p.ID, p.EdgeNodeID, tt.want[i], nodeID)
+				}
+			}
+		})
+	}
+
+	all, err := repo.ListPrintersByEdgeNode(nodeID)
+	if err != nil {
+		t.Fatalf("list unpaginated: %v", err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("unpaginated list = %d printers, want 5", len(all))
+	}
+}
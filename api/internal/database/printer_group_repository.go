@@ -0,0 +1,230 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// PrinterGroupRepository 打印机组数据访问层
+type PrinterGroupRepository struct {
+	db *DB
+}
+
+// NewPrinterGroupRepository 创建打印机组数据访问层
+func NewPrinterGroupRepository(db *DB) *PrinterGroupRepository {
+	return &PrinterGroupRepository{db: db}
+}
+
+// printerGroupColumns 打印机组查询列（与 scanPrinterGroup 的扫描顺序保持一致），成员按打印机ID排序
+const printerGroupColumns = `g.id, g.name, g.description, g.routing_strategy,
+	ARRAY(SELECT m.printer_id::text FROM printer_group_members m WHERE m.group_id = g.id ORDER BY m.printer_id),
+	g.sla_time_to_dispatch, g.sla_time_to_start, g.sla_time_to_complete, g.created_at, g.updated_at`
+
+// scanPrinterGroup 扫描一行打印机组数据
+func scanPrinterGroup(row rowScanner) (*models.PrinterGroup, error) {
+	group := &models.PrinterGroup{}
+	var description sql.NullString
+	var printerIDs pq.StringArray
+	var dispatch, start, complete sql.NullInt64
+
+	err := row.Scan(&group.ID, &group.Name, &description, &group.RoutingStrategy, &printerIDs,
+		&dispatch, &start, &complete, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	group.Description = description.String
+	group.PrinterIDs = []string(printerIDs)
+	if group.PrinterIDs == nil {
+		group.PrinterIDs = []string{}
+	}
+	sla := &models.SLAThresholds{
+		TimeToDispatch: nullIntPtr(dispatch),
+		TimeToStart:    nullIntPtr(start),
+		TimeToComplete: nullIntPtr(complete),
+	}
+	if !sla.IsEmpty() {
+		group.SLA = sla
+	}
+	return group, nil
+}
+
+// nullIntPtr 可空整数转换为指针
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	n := int(value.Int64)
+	return &n
+}
+
+// slaArgs 打印机组 SLA 阈值的写入参数（未覆盖的阶段为 NULL）
+func slaArgs(sla *models.SLAThresholds) (interface{}, interface{}, interface{}) {
+	if sla == nil {
+		return nil, nil, nil
+	}
+	arg := func(value *int) interface{} {
+		if value == nil {
+			return nil
+		}
+		return *value
+	}
+	return arg(sla.TimeToDispatch), arg(sla.TimeToStart), arg(sla.TimeToComplete)
+}
+
+// CreateGroup 创建打印机组及其成员，名称已存在时返回 false
+func (r *PrinterGroupRepository) CreateGroup(group *models.PrinterGroup) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO printer_groups (name, description, routing_strategy, sla_time_to_dispatch, sla_time_to_start, sla_time_to_complete)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, created_at, updated_at`
+
+	dispatch, start, complete := slaArgs(group.SLA)
+	err = tx.QueryRow(query, group.Name, nullIfEmpty(group.Description), group.RoutingStrategy, dispatch, start, complete).
+		Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create printer group: %w", err)
+	}
+
+	if err := replaceGroupMembers(tx, group.ID, group.PrinterIDs); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// UpdateGroup 更新打印机组并替换成员，组不存在时返回 false
+func (r *PrinterGroupRepository) UpdateGroup(group *models.PrinterGroup) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE printer_groups SET name = $2, description = $3, routing_strategy = $4,
+			sla_time_to_dispatch = $5, sla_time_to_start = $6, sla_time_to_complete = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING created_at, updated_at`
+
+	dispatch, start, complete := slaArgs(group.SLA)
+	err = tx.QueryRow(query, group.ID, group.Name, nullIfEmpty(group.Description), group.RoutingStrategy, dispatch, start, complete).
+		Scan(&group.CreatedAt, &group.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update printer group: %w", err)
+	}
+
+	if err := replaceGroupMembers(tx, group.ID, group.PrinterIDs); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// replaceGroupMembers 用给定的打印机列表替换组成员
+func replaceGroupMembers(tx *sql.Tx, groupID string, printerIDs []string) error {
+	if _, err := tx.Exec(`DELETE FROM printer_group_members WHERE group_id = $1`, groupID); err != nil {
+		return fmt.Errorf("failed to clear printer group members: %w", err)
+	}
+	if len(printerIDs) == 0 {
+		return nil
+	}
+	query := `
+		INSERT INTO printer_group_members (group_id, printer_id)
+		SELECT $1, unnest($2::uuid[])
+		ON CONFLICT DO NOTHING`
+	if _, err := tx.Exec(query, groupID, pq.Array(printerIDs)); err != nil {
+		return fmt.Errorf("failed to add printer group members: %w", err)
+	}
+	return nil
+}
+
+// GetGroup 根据ID获取打印机组，不存在时返回 nil
+func (r *PrinterGroupRepository) GetGroup(id string) (*models.PrinterGroup, error) {
+	query := `SELECT ` + printerGroupColumns + ` FROM printer_groups g WHERE g.id = $1`
+
+	group, err := scanPrinterGroup(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get printer group: %w", err)
+	}
+	return group, nil
+}
+
+// GroupNameTaken 判断名称是否已被其他打印机组使用
+func (r *PrinterGroupRepository) GroupNameTaken(name, excludeID string) (bool, error) {
+	var taken bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM printer_groups WHERE name = $1 AND id <> $2)`, name, excludeID).Scan(&taken)
+	if err != nil {
+		return false, fmt.Errorf("failed to check printer group name: %w", err)
+	}
+	return taken, nil
+}
+
+// ListGroups 获取全部打印机组（按名称排序）
+func (r *PrinterGroupRepository) ListGroups() ([]*models.PrinterGroup, error) {
+	query := `SELECT ` + printerGroupColumns + ` FROM printer_groups g ORDER BY g.name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list printer groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []*models.PrinterGroup{}
+	for rows.Next() {
+		group, err := scanPrinterGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan printer group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// DeleteGroup 删除打印机组（已提交任务保留，printer_group_id 置空），组不存在时返回 false
+func (r *PrinterGroupRepository) DeleteGroup(id string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM printer_groups WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete printer group: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ListGroupIDsByPrinter 获取打印机所属的打印机组ID（按ID排序）
+func (r *PrinterGroupRepository) ListGroupIDsByPrinter(printerID string) ([]string, error) {
+	rows, err := r.db.Query(`SELECT group_id::text FROM printer_group_members WHERE printer_id = $1 ORDER BY group_id`, printerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list printer groups of printer: %w", err)
+	}
+	defer rows.Close()
+
+	var groupIDs []string
+	for rows.Next() {
+		var groupID string
+		if err := rows.Scan(&groupID); err != nil {
+			return nil, fmt.Errorf("failed to scan printer group id: %w", err)
+		}
+		groupIDs = append(groupIDs, groupID)
+	}
+	return groupIDs, rows.Err()
+}
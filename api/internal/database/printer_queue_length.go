@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// queueReconcileLockKey 核对打印机队列长度时持有的 PostgreSQL advisory lock，多实例部署时同一时间只有一个实例执行
+const queueReconcileLockKey = 0x666c7971756575 // "flyqueu"
+
+// ReconcileQueueLengths 按云端未结束的任务数重新计算所有打印机的 cloud_queue_length，
+// 并将 queue_length 更新为与节点上报值的较大值；只写入发生变化的打印机，返回其ID
+// 其他实例正在核对时直接返回（acquired 为 false）
+func (r *PrinterRepository) ReconcileQueueLengths() (printerIDs []string, acquired bool, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow(`SELECT pg_try_advisory_xact_lock($1)`, queueReconcileLockKey).Scan(&acquired); err != nil {
+		return nil, false, fmt.Errorf("failed to acquire queue reconcile lock: %w", err)
+	}
+	if !acquired {
+		return nil, false, nil
+	}
+
+	rows, err := tx.Query(`
+		UPDATE printers p
+		SET cloud_queue_length = c.jobs,
+		    queue_length = GREATEST(p.edge_queue_length, c.jobs)
+		FROM (
+			SELECT pr.id, COUNT(j.id)::integer AS jobs
+			FROM printers pr
+			LEFT JOIN print_jobs j ON j.printer_id = pr.id
+				AND j.status NOT IN (` + models.StatusSQLList(models.TerminalJobStatuses) + `)
+			GROUP BY pr.id
+		) c
+		WHERE p.id = c.id
+		  AND (p.cloud_queue_length <> c.jobs OR p.queue_length IS DISTINCT FROM GREATEST(p.edge_queue_length, c.jobs))
+		RETURNING p.id`)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to reconcile printer queue lengths: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, true, fmt.Errorf("failed to scan printer id: %w", err)
+		}
+		printerIDs = append(printerIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, true, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, true, fmt.Errorf("failed to commit queue reconcile: %w", err)
+	}
+	r.db.invalidateLookup(LookupKindPrinter, printerIDs...)
+	return printerIDs, true, nil
+}
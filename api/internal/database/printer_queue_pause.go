@@ -0,0 +1,72 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// 队列暂停与熔断相同，不增加 row_version：暂停和恢复不应使管理员正在编辑的打印机配置失效
+
+// PausePrinterQueue 暂停打印机队列，autoResumeAt 为空表示不自动恢复；返回是否暂停（已暂停时返回 false，保留原暂停信息）
+func (r *PrinterRepository) PausePrinterQueue(printerID, pausedBy, reason string, now time.Time, autoResumeAt *time.Time) (bool, error) {
+	query := `
+		UPDATE printers SET queue_paused = TRUE, queue_paused_by = $2, queue_paused_at = $3,
+			queue_pause_reason = $4, queue_auto_resume_at = $5
+		WHERE id = $1 AND NOT queue_paused`
+	result, err := r.db.Exec(query, printerID, nullIfEmpty(pausedBy), now, nullIfEmpty(reason), autoResumeAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to pause printer queue: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected > 0 {
+		r.db.invalidateLookup(LookupKindPrinter, printerID)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ResumePrinterQueue 恢复打印机队列，返回是否恢复（未暂停时返回 false）
+func (r *PrinterRepository) ResumePrinterQueue(printerID string) (bool, error) {
+	query := `
+		UPDATE printers SET queue_paused = FALSE, queue_paused_by = NULL, queue_paused_at = NULL,
+			queue_pause_reason = NULL, queue_auto_resume_at = NULL
+		WHERE id = $1 AND queue_paused`
+	result, err := r.db.Exec(query, printerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to resume printer queue: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected > 0 {
+		r.db.invalidateLookup(LookupKindPrinter, printerID)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ExpireQueuePauses 恢复自动恢复时间已在 now 之前的队列暂停，返回对应的打印机ID
+func (r *PrinterRepository) ExpireQueuePauses(now time.Time) ([]string, error) {
+	rows, err := r.db.Query(`
+		UPDATE printers SET queue_paused = FALSE, queue_paused_by = NULL, queue_paused_at = NULL,
+			queue_pause_reason = NULL, queue_auto_resume_at = NULL
+		WHERE queue_paused AND queue_auto_resume_at <= $1
+		RETURNING id`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire printer queue pauses: %w", err)
+	}
+	defer rows.Close()
+
+	var printerIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan printer id: %w", err)
+		}
+		printerIDs = append(printerIDs, id)
+	}
+	r.db.invalidateLookup(LookupKindPrinter, printerIDs...)
+	return printerIDs, rows.Err()
+}
@@ -1,13 +1,127 @@
 package database
 
 import (
+	"bytes"
+	"crypto/sha1"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 	"fly-print-cloud/api/internal/models"
+	"github.com/google/uuid"
 )
 
+// printerColumns 打印机查询列（与 scanPrinter 的扫描顺序保持一致）
+const printerColumns = `id, name, display_name, model, serial_number, status, enabled, approval_status, firmware_version, port_info,
+		       ip_address, mac_address, network_config, latitude, longitude, location,
+		       capabilities, edge_node_id, queue_length, edge_queue_length, cloud_queue_length,
+		       price_per_page_mono, price_per_page_color, duplex_discount, max_concurrent_jobs, max_copies,
+		       slug, supplies, power_state, reserved_by, reserved_at, reserved_until,
+		       backup_printer_id, needs_attention, attention_reason, attention_at,
+		       breaker_state, breaker_failures, breaker_window_started_at, breaker_suspended_at, breaker_resume_at, breaker_reason,
+		       queue_paused, queue_paused_by, queue_paused_at, queue_pause_reason, queue_auto_resume_at,
+		       row_version, created_at, updated_at`
+
+// unmarshalCapabilities 解析打印机能力；NULL、空值或 JSON null（历史数据或直接 SQL 写入）视为空能力
+func unmarshalCapabilities(data []byte, capabilities *models.PrinterCapabilities) error {
+	*capabilities = models.PrinterCapabilities{}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(trimmed, capabilities); err != nil {
+		return fmt.Errorf("failed to unmarshal capabilities: %w", err)
+	}
+	return nil
+}
+
+// scanPrinter 扫描一行打印机数据并处理可空字段
+func scanPrinter(row rowScanner) (*models.Printer, error) {
+	printer := &models.Printer{}
+	var model sql.NullString
+	var displayName sql.NullString
+	var priceMono, priceColor, duplexDiscount sql.NullFloat64
+	var maxConcurrentJobs, maxCopies sql.NullInt64
+	var slug sql.NullString
+	var powerState sql.NullString
+	var reservedBy sql.NullString
+	var reservedAt, reservedUntil sql.NullTime
+	var capabilitiesJSON []byte
+	var suppliesJSON []byte
+	var backupPrinterID, attentionReason sql.NullString
+	var breakerReason sql.NullString
+	var queuePausedBy, queuePauseReason sql.NullString
+
+	err := row.Scan(
+		&printer.ID, &printer.Name, &displayName, &model, &printer.SerialNumber, &printer.Status, &printer.Enabled, &printer.ApprovalStatus,
+		&printer.FirmwareVersion, &printer.PortInfo, &printer.IPAddress, &printer.MACAddress,
+		&printer.NetworkConfig, &printer.Latitude, &printer.Longitude, &printer.Location,
+		&capabilitiesJSON, &printer.EdgeNodeID, &printer.QueueLength, &printer.EdgeQueueLength, &printer.CloudQueueLength,
+		&priceMono, &priceColor, &duplexDiscount, &maxConcurrentJobs, &maxCopies,
+		&slug, &suppliesJSON, &powerState, &reservedBy, &reservedAt, &reservedUntil,
+		&backupPrinterID, &printer.NeedsAttention, &attentionReason, &printer.AttentionAt,
+		&printer.Breaker.State, &printer.Breaker.ConsecutiveFailures, &printer.Breaker.WindowStartedAt,
+		&printer.Breaker.SuspendedAt, &printer.Breaker.ResumeAt, &breakerReason,
+		&printer.QueuePaused, &queuePausedBy, &printer.QueuePausedAt, &queuePauseReason, &printer.QueueAutoResumeAt,
+		&printer.RowVersion, &printer.CreatedAt, &printer.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 处理可空字段（指针类型的字段已直接扫描）
+	printer.Model = model.String
+	if displayName.Valid {
+		printer.DisplayName = displayName.String
+	}
+	if slug.Valid {
+		printer.Slug = slug.String
+	}
+	printer.PowerState = powerState.String
+	if backupPrinterID.Valid {
+		printer.BackupPrinterID = &backupPrinterID.String
+	}
+	printer.AttentionReason = attentionReason.String
+	printer.Breaker.Reason = breakerReason.String
+	printer.QueuePausedBy = queuePausedBy.String
+	printer.QueuePauseReason = queuePauseReason.String
+	// 已过期但尚未被清理的预留不返回
+	if reservation := scanReservation(reservedBy, reservedAt, reservedUntil); reservation.Active(time.Now().UTC()) {
+		printer.Reservation = reservation
+	}
+	if priceMono.Valid {
+		printer.PricePerPageMono = &priceMono.Float64
+	}
+	if priceColor.Valid {
+		printer.PricePerPageColor = &priceColor.Float64
+	}
+	if duplexDiscount.Valid {
+		printer.DuplexDiscount = &duplexDiscount.Float64
+	}
+	if maxConcurrentJobs.Valid {
+		limit := int(maxConcurrentJobs.Int64)
+		printer.MaxConcurrentJobs = &limit
+	}
+	if maxCopies.Valid {
+		limit := int(maxCopies.Int64)
+		printer.MaxCopies = &limit
+	}
+
+	// 解析 JSON capabilities
+	if err := unmarshalCapabilities(capabilitiesJSON, &printer.Capabilities); err != nil {
+		return nil, err
+	}
+	if len(suppliesJSON) > 0 {
+		if err := json.Unmarshal(suppliesJSON, &printer.Supplies); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal supplies: %w", err)
+		}
+	}
+
+	return printer, nil
+}
+
 type PrinterRepository struct {
 	db *DB
 }
@@ -27,15 +141,21 @@ func (r *PrinterRepository) CreatePrinter(printer *models.Printer) error {
 	query := `
 		INSERT INTO printers (id, name, display_name, model, serial_number, status, firmware_version, 
 		                     port_info, ip_address, mac_address, network_config,
-		                     latitude, longitude, location, capabilities, edge_node_id, queue_length)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		                     latitude, longitude, location, capabilities, edge_node_id, queue_length, edge_queue_length, slug)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $17, $18)
 		RETURNING created_at, updated_at`
 	
+	if printer.Slug == "" {
+		if printer.Slug, err = r.nextFreeSlug(PrinterSlugBase(printer.Name, printer.EdgeNodeID)); err != nil {
+			return err
+		}
+	}
+
 	err = r.db.QueryRow(query,
 		printer.ID, printer.Name, printer.DisplayName, printer.Model, printer.SerialNumber, printer.Status,
 		printer.FirmwareVersion, printer.PortInfo, printer.IPAddress, printer.MACAddress,
 		printer.NetworkConfig, printer.Latitude, printer.Longitude, printer.Location,
-		capabilitiesJSON, printer.EdgeNodeID, printer.QueueLength,
+		capabilitiesJSON, printer.EdgeNodeID, printer.EdgeQueueLength, printer.Slug,
 	).Scan(&printer.CreatedAt, &printer.UpdatedAt)
 	
 	if err != nil {
@@ -47,72 +167,30 @@ func (r *PrinterRepository) CreatePrinter(printer *models.Printer) error {
 // GetPrinterByNameAndEdgeNode 根据名称和边缘节点ID获取打印机
 func (r *PrinterRepository) GetPrinterByNameAndEdgeNode(name, edgeNodeID string) (*models.Printer, error) {
 	query := `
-		SELECT id, name, display_name, model, serial_number, status, enabled, firmware_version, port_info,
-		       ip_address, mac_address, network_config, latitude, longitude, location,
-		       capabilities, edge_node_id, queue_length, created_at, updated_at
+		SELECT `+printerColumns+`
 		FROM printers 
 		WHERE name = $1 AND edge_node_id = $2`
 	
-	var printer models.Printer
-	var capabilitiesJSON []byte
-	var firmwareVersion, portInfo sql.NullString
-	
-	var displayName sql.NullString
-	err := r.db.QueryRow(query, name, edgeNodeID).Scan(
-		&printer.ID, &printer.Name, &displayName, &printer.Model, &printer.SerialNumber, &printer.Status, &printer.Enabled,
-		&firmwareVersion, &portInfo, &printer.IPAddress, &printer.MACAddress,
-		&printer.NetworkConfig, &printer.Latitude, &printer.Longitude, &printer.Location,
-		&capabilitiesJSON, &printer.EdgeNodeID, &printer.QueueLength,
-		&printer.CreatedAt, &printer.UpdatedAt,
-	)
-	
+	printer, err := scanPrinter(r.db.QueryRow(query, name, edgeNodeID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get printer by name and edge node: %w", err)
 	}
 	
-	// 处理可能为 NULL 的字段
-	if firmwareVersion.Valid {
-		printer.FirmwareVersion = firmwareVersion.String
-	}
-	if portInfo.Valid {
-		printer.PortInfo = portInfo.String
-	}
-	if displayName.Valid {
-		printer.DisplayName = displayName.String
-	}
-	
-	// 解析 capabilities JSON
-	if len(capabilitiesJSON) > 0 {
-		if err := json.Unmarshal(capabilitiesJSON, &printer.Capabilities); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal capabilities: %w", err)
-		}
-	}
-	
-	return &printer, nil
+	return printer, nil
 }
 
-// GetPrinterByID 根据ID获取打印机
+// GetPrinterByID 根据ID获取打印机，printerID 不是 UUID 时按 slug 查找
 func (r *PrinterRepository) GetPrinterByID(printerID string) (*models.Printer, error) {
 	query := `
-		SELECT id, name, display_name, model, serial_number, status, enabled, firmware_version, port_info,
-		       ip_address, mac_address, network_config, latitude, longitude, location,
-		       capabilities, edge_node_id, queue_length, created_at, updated_at
+		SELECT `+printerColumns+`
 		FROM printers WHERE id = $1`
+	if _, err := uuid.Parse(printerID); err != nil {
+		query = `
+		SELECT `+printerColumns+`
+		FROM printers WHERE slug = $1`
+	}
 	
-	printer := &models.Printer{}
-	var ipAddress sql.NullString
-	var firmwareVersion sql.NullString
-	var displayName sql.NullString
-	var capabilitiesJSON []byte
-	
-	err := r.db.QueryRow(query, printerID).Scan(
-		&printer.ID, &printer.Name, &displayName, &printer.Model, &printer.SerialNumber, &printer.Status, &printer.Enabled,
-		&firmwareVersion, &printer.PortInfo, &ipAddress, &printer.MACAddress,
-		&printer.NetworkConfig, &printer.Latitude, &printer.Longitude, &printer.Location,
-		&capabilitiesJSON, &printer.EdgeNodeID, &printer.QueueLength,
-		&printer.CreatedAt, &printer.UpdatedAt,
-	)
-	
+	printer, err := scanPrinter(r.db.QueryRow(query, printerID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("printer not found")
@@ -120,25 +198,19 @@ func (r *PrinterRepository) GetPrinterByID(printerID string) (*models.Printer, e
 		return nil, fmt.Errorf("failed to get printer: %w", err)
 	}
 	
-	// 处理可空字段
-	if ipAddress.Valid {
-		printer.IPAddress = &ipAddress.String
-	}
-	if firmwareVersion.Valid {
-		printer.FirmwareVersion = firmwareVersion.String
-	}
-	if displayName.Valid {
-		printer.DisplayName = displayName.String
-	}
-	
-	// 解析 JSON capabilities
-	if err := json.Unmarshal(capabilitiesJSON, &printer.Capabilities); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal capabilities: %w", err)
-	}
-	
 	return printer, nil
 }
 
+// IsEdgeNodeActive 打印机所属的 Edge Node 是否存在、未删除、已启用且不在排空中（可接收新任务）
+func (r *PrinterRepository) IsEdgeNodeActive(edgeNodeID string) (bool, error) {
+	var active bool
+	query := `SELECT EXISTS (SELECT 1 FROM edge_nodes WHERE id = $1 AND deleted_at IS NULL AND enabled AND drain_started_at IS NULL)`
+	if err := r.db.QueryRow(query, edgeNodeID).Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to check edge node: %w", err)
+	}
+	return active, nil
+}
+
 // ListPrinters 获取打印机列表
 func (r *PrinterRepository) ListPrinters(page, pageSize int) ([]*models.Printer, int, error) {
 	offset := (page - 1) * pageSize
@@ -153,9 +225,7 @@ func (r *PrinterRepository) ListPrinters(page, pageSize int) ([]*models.Printer,
 	
 	// 获取分页数据
 	query := `
-		SELECT id, name, display_name, model, serial_number, status, enabled, firmware_version, port_info,
-		       ip_address, mac_address, network_config, latitude, longitude, location,
-		       capabilities, edge_node_id, queue_length, created_at, updated_at
+		SELECT `+printerColumns+`
 		FROM printers 
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2`
@@ -168,45 +238,162 @@ func (r *PrinterRepository) ListPrinters(page, pageSize int) ([]*models.Printer,
 	
 	var printers []*models.Printer
 	for rows.Next() {
-		printer := &models.Printer{}
-		var ipAddress sql.NullString
-		var firmwareVersion sql.NullString
-		var displayName sql.NullString
-		var capabilitiesJSON []byte
-		
-		err := rows.Scan(
-			&printer.ID, &printer.Name, &displayName, &printer.Model, &printer.SerialNumber, &printer.Status, &printer.Enabled,
-			&firmwareVersion, &printer.PortInfo, &ipAddress, &printer.MACAddress,
-			&printer.NetworkConfig, &printer.Latitude, &printer.Longitude, &printer.Location,
-			&capabilitiesJSON, &printer.EdgeNodeID, &printer.QueueLength,
-			&printer.CreatedAt, &printer.UpdatedAt,
-		)
+		printer, err := scanPrinter(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan printer: %w", err)
 		}
 		
-		// 处理可空字段
-		if ipAddress.Valid {
-			printer.IPAddress = &ipAddress.String
-		}
-		if firmwareVersion.Valid {
-			printer.FirmwareVersion = firmwareVersion.String
-		}
-		if displayName.Valid {
-			printer.DisplayName = displayName.String
-		}
-		
-		// 解析 JSON capabilities
-		if err := json.Unmarshal(capabilitiesJSON, &printer.Capabilities); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal capabilities: %w", err)
-		}
-		
 		printers = append(printers, printer)
 	}
 	
 	return printers, total, nil
 }
 
+// ListPrintersForRoles 分页获取指定角色可使用的已审核打印机（未配置访问策略的打印机对所有人开放）
+// edgeNodeID 不为空时按 Edge Node 筛选
+func (r *PrinterRepository) ListPrintersForRoles(page, pageSize int, edgeNodeID string, roles []string) ([]*models.Printer, int, error) {
+	offset := (page - 1) * pageSize
+
+	whereClause := "WHERE " + accessibleByRolesSQL(1) + " AND approval_status = $2"
+	args := []interface{}{rolesArg(roles), models.PrinterApprovalApproved}
+	if edgeNodeID != "" {
+		whereClause += " AND edge_node_id = $3"
+		args = append(args, edgeNodeID)
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM printers ` + whereClause
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to get printer count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT `+printerColumns+`
+		FROM printers %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d`, whereClause, len(args)+1, len(args)+2)
+	args = append(args, pageSize, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list printers: %w", err)
+	}
+	defer rows.Close()
+
+	var printers []*models.Printer
+	for rows.Next() {
+		printer, err := scanPrinter(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan printer: %w", err)
+		}
+		printers = append(printers, printer)
+	}
+
+	return printers, total, rows.Err()
+}
+
+// ListPrintersByApprovalStatus 按审核状态分页获取打印机
+func (r *PrinterRepository) ListPrintersByApprovalStatus(page, pageSize int, approvalStatus string) ([]*models.Printer, int, error) {
+	offset := (page - 1) * pageSize
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM printers WHERE approval_status = $1`
+	if err := r.db.QueryRow(countQuery, approvalStatus).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to get printer count: %w", err)
+	}
+
+	query := `
+		SELECT `+printerColumns+`
+		FROM printers 
+		WHERE approval_status = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, approvalStatus, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list printers: %w", err)
+	}
+	defer rows.Close()
+
+	var printers []*models.Printer
+	for rows.Next() {
+		printer, err := scanPrinter(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan printer: %w", err)
+		}
+		printers = append(printers, printer)
+	}
+
+	return printers, total, rows.Err()
+}
+
+// ApprovePrinter 批准待审核的打印机
+func (r *PrinterRepository) ApprovePrinter(printerID string) error {
+	query := `UPDATE printers SET approval_status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+
+	result, err := r.db.Exec(query, printerID, models.PrinterApprovalApproved)
+	if err != nil {
+		return fmt.Errorf("failed to approve printer: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("printer not found")
+	}
+	r.db.invalidateLookup(LookupKindPrinter, printerID)
+	return nil
+}
+
+// RejectPrinter 拒绝打印机：删除记录并屏蔽该 名称 + Edge Node，节点重新注册时不会再次创建
+func (r *PrinterRepository) RejectPrinter(printer *models.Printer, rejectedBy string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO rejected_printers (name, edge_node_id, rejected_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name, edge_node_id) DO NOTHING`,
+		printer.Name, printer.EdgeNodeID, nullIfEmpty(rejectedBy))
+	if err != nil {
+		return fmt.Errorf("failed to record rejected printer: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM printers WHERE id = $1`, printer.ID); err != nil {
+		return fmt.Errorf("failed to delete printer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.db.invalidateLookup(LookupKindPrinter, printer.ID)
+	return nil
+}
+
+// IsPrinterRejected 判断该 名称 + Edge Node 的打印机是否已被管理员拒绝
+func (r *PrinterRepository) IsPrinterRejected(name, edgeNodeID string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM rejected_printers WHERE name = $1 AND edge_node_id = $2)`
+	if err := r.db.QueryRow(query, name, edgeNodeID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check rejected printer: %w", err)
+	}
+	return exists, nil
+}
+
+// CountPrintersByName 统计所有节点中使用该名称的打印机数量
+func (r *PrinterRepository) CountPrintersByName(name string) (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM printers WHERE name = $1`, name).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count printers by name: %w", err)
+	}
+	return count, nil
+}
+
 // CountPrintersByEdgeNode 统计边缘节点的打印机数量
 func (r *PrinterRepository) CountPrintersByEdgeNode(edgeNodeID string) (int, error) {
 	query := `SELECT COUNT(*) FROM printers WHERE edge_node_id = $1`
@@ -220,17 +407,41 @@ func (r *PrinterRepository) CountPrintersByEdgeNode(edgeNodeID string) (int, err
 	return count, nil
 }
 
-// ListPrintersByEdgeNode 根据 Edge Node ID 获取打印机列表
+// CountPrintersByStatus 按状态统计 Edge Node 下的打印机数量
+func (r *PrinterRepository) CountPrintersByStatus(edgeNodeID string) (map[string]int, error) {
+	rows, err := r.db.Query(`SELECT status, COUNT(*) FROM printers WHERE edge_node_id = $1 GROUP BY status`, edgeNodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count printers by status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// MaxEdgeInventoryPrinters ListPrintersByEdgeNode 返回的打印机数量上限
+// 仅供 Edge Node 同步自身完整库存使用，超过上限的部分不返回
+const MaxEdgeInventoryPrinters = 1000
+
+// ListPrintersByEdgeNode 根据 Edge Node ID 获取打印机列表（不分页，最多 MaxEdgeInventoryPrinters 台）
+// 管理界面请使用 ListPrintersByEdgeNodePaged
 func (r *PrinterRepository) ListPrintersByEdgeNode(edgeNodeID string) ([]*models.Printer, error) {
 	query := `
-		SELECT id, name, display_name, model, serial_number, status, enabled, firmware_version, port_info,
-		       ip_address, mac_address, network_config, latitude, longitude, location,
-		       capabilities, edge_node_id, queue_length, created_at, updated_at
+		SELECT `+printerColumns+`
 		FROM printers 
 		WHERE edge_node_id = $1
-		ORDER BY created_at DESC`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
 	
-	rows, err := r.db.Query(query, edgeNodeID)
+	rows, err := r.db.Query(query, edgeNodeID, MaxEdgeInventoryPrinters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list printers: %w", err)
 	}
@@ -238,46 +449,54 @@ func (r *PrinterRepository) ListPrintersByEdgeNode(edgeNodeID string) ([]*models
 	
 	var printers []*models.Printer
 	for rows.Next() {
-		printer := &models.Printer{}
-		var ipAddress sql.NullString
-		var firmwareVersion sql.NullString
-		var displayName sql.NullString
-		var capabilitiesJSON []byte
-		
-		err := rows.Scan(
-			&printer.ID, &printer.Name, &displayName, &printer.Model, &printer.SerialNumber, &printer.Status, &printer.Enabled,
-			&firmwareVersion, &printer.PortInfo, &ipAddress, &printer.MACAddress,
-			&printer.NetworkConfig, &printer.Latitude, &printer.Longitude, &printer.Location,
-			&capabilitiesJSON, &printer.EdgeNodeID, &printer.QueueLength,
-			&printer.CreatedAt, &printer.UpdatedAt,
-		)
+		printer, err := scanPrinter(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan printer: %w", err)
 		}
 		
-		// 处理可空字段
-		if ipAddress.Valid {
-			printer.IPAddress = &ipAddress.String
-		}
-		if firmwareVersion.Valid {
-			printer.FirmwareVersion = firmwareVersion.String
-		}
-		if displayName.Valid {
-			printer.DisplayName = displayName.String
-		}
-		
-		// 解析 JSON capabilities
-		if err := json.Unmarshal(capabilitiesJSON, &printer.Capabilities); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal capabilities: %w", err)
-		}
-		
 		printers = append(printers, printer)
 	}
 	
 	return printers, nil
 }
 
-// UpdatePrinter 更新打印机
+// ListPrintersByEdgeNodePaged 分页获取 Edge Node 的打印机列表，同时返回总数
+func (r *PrinterRepository) ListPrintersByEdgeNodePaged(edgeNodeID string, page, pageSize int) ([]*models.Printer, int, error) {
+	offset := (page - 1) * pageSize
+
+	total, err := r.CountPrintersByEdgeNode(edgeNodeID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + printerColumns + `
+		FROM printers
+		WHERE edge_node_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, edgeNodeID, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list printers: %w", err)
+	}
+	defer rows.Close()
+
+	var printers []*models.Printer
+	for rows.Next() {
+		printer, err := scanPrinter(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan printer: %w", err)
+		}
+		printers = append(printers, printer)
+	}
+
+	return printers, total, rows.Err()
+}
+
+// UpdatePrinter 更新打印机（管理员修改）
+// 仅当数据库中的版本号等于 printer.RowVersion 时更新，成功后 printer.RowVersion 为新版本号；
+// 版本号不一致时返回 ErrVersionConflict
 func (r *PrinterRepository) UpdatePrinter(printer *models.Printer) error {
 	// 将 Capabilities 结构体转换为 JSON
 	capabilitiesJSON, err := json.Marshal(printer.Capabilities)
@@ -290,47 +509,172 @@ func (r *PrinterRepository) UpdatePrinter(printer *models.Printer) error {
 		SET name = $2, display_name = $3, model = $4, serial_number = $5, status = $6, enabled = $7,
 		    firmware_version = $8, port_info = $9, ip_address = $10, mac_address = $11, network_config = $12,
 		    latitude = $13, longitude = $14, location = $15, capabilities = $16,
-		    queue_length = $17, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $1
-		RETURNING updated_at`
+		    edge_queue_length = $17, queue_length = GREATEST($17, cloud_queue_length), price_per_page_mono = $18, price_per_page_color = $19, duplex_discount = $20,
+		    max_concurrent_jobs = $21, max_copies = $22, backup_printer_id = $24,
+		    row_version = row_version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND row_version = $23
+		RETURNING row_version, updated_at`
 	
 	err = r.db.QueryRow(query,
 		printer.ID, printer.Name, printer.DisplayName, printer.Model, printer.SerialNumber, printer.Status, printer.Enabled,
 		printer.FirmwareVersion, printer.PortInfo, printer.IPAddress, printer.MACAddress,
 		printer.NetworkConfig, printer.Latitude, printer.Longitude, printer.Location,
-		capabilitiesJSON, printer.QueueLength,
-	).Scan(&printer.UpdatedAt)
+		capabilitiesJSON, printer.EdgeQueueLength,
+		printer.PricePerPageMono, printer.PricePerPageColor, printer.DuplexDiscount,
+		printer.MaxConcurrentJobs, printer.MaxCopies, printer.RowVersion, printer.BackupPrinterID,
+	).Scan(&printer.RowVersion, &printer.UpdatedAt)
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("printer not found")
+			return versionMismatch(r.db, "SELECT EXISTS(SELECT 1 FROM printers WHERE id = $1)", printer.ID, "printer not found")
 		}
 		return fmt.Errorf("failed to update printer: %w", err)
 	}
+	r.db.invalidateLookup(LookupKindPrinter, printer.ID)
 	return nil
 }
 
-// DeletePrinter 删除打印机
-func (r *PrinterRepository) DeletePrinter(printerID string) error {
-	query := `DELETE FROM printers WHERE id = $1`
-	
-	result, err := r.db.Exec(query, printerID)
+// UpdateStatusAndQueue 仅更新节点上报的状态字段（状态、队列长度、耗材、电源状态），不会覆盖管理员修改的显示名称、启用状态等字段
+// supplies 为 nil 时保留原有耗材信息；powerState 为空时保留原有电源状态，但会结束等待中的 waking 状态
+func (r *PrinterRepository) UpdateStatusAndQueue(printerID string, status models.PrinterStatus, queueLength int, supplies map[string]interface{}, powerState string) error {
+	var suppliesJSON []byte
+	if supplies != nil {
+		data, err := json.Marshal(supplies)
+		if err != nil {
+			return fmt.Errorf("failed to marshal supplies: %w", err)
+		}
+		suppliesJSON = data
+	}
+
+	query := `
+		UPDATE printers 
+		SET status = $2, edge_queue_length = $3, queue_length = GREATEST($3, cloud_queue_length), supplies = COALESCE($4::jsonb, supplies),
+		    power_state = CASE
+		        WHEN $5::varchar <> '' THEN $5::varchar
+		        WHEN power_state = '` + models.PrinterPowerWaking + `' THEN NULL
+		        ELSE power_state END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	result, err := r.db.Exec(query, printerID, status, queueLength, suppliesJSON, powerState)
 	if err != nil {
-		return fmt.Errorf("failed to delete printer: %w", err)
+		return fmt.Errorf("failed to update printer status: %w", err)
 	}
-	
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to get affected rows: %w", err)
 	}
-	
 	if rowsAffected == 0 {
 		return fmt.Errorf("printer not found")
 	}
-	
+	r.db.invalidateLookup(LookupKindPrinter, printerID)
 	return nil
 }
 
+// SetPowerState 设置打印机电源状态（云端下发唤醒指令后置为 waking，等待节点上报）
+func (r *PrinterRepository) SetPowerState(printerID, powerState string) error {
+	_, err := r.db.Exec(`UPDATE printers SET power_state = $2 WHERE id = $1`, printerID, powerState)
+	if err != nil {
+		return fmt.Errorf("failed to set printer power state: %w", err)
+	}
+	r.db.invalidateLookup(LookupKindPrinter, printerID)
+	return nil
+}
+
+// DeletePrinter 在事务中删除打印机，历史任务保留（printer_id 置空并记录打印机名称）
+// 仍有未结束的任务时：force 为 false 返回 *PrinterHasJobsError；为 true 时先取消这些任务并返回
+// tombstoneUntil 不为零值时记录墓碑，到期前节点不能重新注册该打印机
+func (r *PrinterRepository) DeletePrinter(printerID string, force bool, actor string, tombstoneUntil time.Time) ([]*models.PrintJob, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 锁定打印机行，阻止删除过程中为该打印机创建新任务
+	var name, cupsName, edgeNodeID string
+	err = tx.QueryRow(`SELECT COALESCE(NULLIF(display_name, ''), name), name, COALESCE(edge_node_id, '') FROM printers WHERE id = $1 FOR UPDATE`, printerID).
+		Scan(&name, &cupsName, &edgeNodeID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("printer not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock printer: %w", err)
+	}
+
+	terminal := models.StatusSQLList(models.TerminalJobStatuses)
+	var unfinished int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM print_jobs WHERE printer_id = $1 AND status NOT IN (`+terminal+`)`, printerID).Scan(&unfinished)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unfinished jobs: %w", err)
+	}
+	if unfinished > 0 && !force {
+		return nil, &PrinterHasJobsError{Unfinished: unfinished}
+	}
+
+	var cancelled []*models.PrintJob
+	if unfinished > 0 {
+		rows, err := tx.Query(`
+			UPDATE print_jobs SET status = $2, end_time = COALESCE(end_time, $3), error_message = $4, performed_by = $5
+			WHERE printer_id = $1 AND status NOT IN (`+terminal+`)
+			RETURNING `+printJobColumns,
+			printerID, models.JobStatusCancelled, time.Now().UTC(), "打印机已删除", nullIfEmpty(actor))
+		if err != nil {
+			return nil, fmt.Errorf("failed to cancel unfinished jobs: %w", err)
+		}
+		for rows.Next() {
+			job, err := scanPrintJob(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			cancelled = append(cancelled, job)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// 历史任务保留，解除与打印机的关联并记录打印机名称
+	if _, err := tx.Exec(`UPDATE print_jobs SET printer_id = NULL, printer_name = $2 WHERE printer_id = $1`, printerID, name); err != nil {
+		return nil, fmt.Errorf("failed to detach printer jobs: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM printers WHERE id = $1`, printerID); err != nil {
+		return nil, fmt.Errorf("failed to delete printer: %w", err)
+	}
+
+	if !tombstoneUntil.IsZero() && edgeNodeID != "" {
+		_, err = tx.Exec(`
+			INSERT INTO printer_tombstones (printer_id, name, edge_node_id, deleted_by, deleted_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (name, edge_node_id) DO UPDATE SET
+				printer_id = EXCLUDED.printer_id, deleted_by = EXCLUDED.deleted_by,
+				deleted_at = EXCLUDED.deleted_at, expires_at = EXCLUDED.expires_at`,
+			printerID, cupsName, edgeNodeID, nullIfEmpty(actor), time.Now().UTC(), tombstoneUntil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record printer tombstone: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit printer deletion: %w", err)
+	}
+	r.db.invalidateLookup(LookupKindPrinter, printerID)
+	return cancelled, nil
+}
+
+// PrinterHasJobsError 打印机仍有未结束的任务，未指定强制删除时拒绝删除
+type PrinterHasJobsError struct {
+	Unfinished int
+}
+
+func (e *PrinterHasJobsError) Error() string {
+	return fmt.Sprintf("printer has %d unfinished jobs", e.Unfinished)
+}
+
 // UpsertPrinter 插入或更新打印机（基于 name + edge_node_id 的唯一性）
 func (r *PrinterRepository) UpsertPrinter(printer *models.Printer) error {
 	// 将 Capabilities 结构体转换为 JSON
@@ -343,12 +687,13 @@ func (r *PrinterRepository) UpsertPrinter(printer *models.Printer) error {
 		INSERT INTO printers (
 			id, name, model, serial_number, status, firmware_version, port_info,
 			ip_address, mac_address, network_config, latitude, longitude, location,
-			capabilities, edge_node_id, queue_length, created_at, updated_at
+			capabilities, edge_node_id, queue_length, edge_queue_length, created_at, updated_at, slug, approval_status
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $16, $17, $18, $19, $20
 		)
 		ON CONFLICT (name, edge_node_id) 
 		DO UPDATE SET
+			slug = COALESCE(printers.slug, EXCLUDED.slug),
 			model = EXCLUDED.model,
 			serial_number = EXCLUDED.serial_number,
 			status = EXCLUDED.status,
@@ -361,10 +706,21 @@ func (r *PrinterRepository) UpsertPrinter(printer *models.Printer) error {
 			longitude = EXCLUDED.longitude,
 			location = EXCLUDED.location,
 			capabilities = EXCLUDED.capabilities,
-			queue_length = EXCLUDED.queue_length,
+			edge_queue_length = EXCLUDED.edge_queue_length,
+			queue_length = GREATEST(EXCLUDED.edge_queue_length, printers.cloud_queue_length),
 			updated_at = CURRENT_TIMESTAMP
-		RETURNING id`
+		RETURNING id, slug, approval_status`
+
+	// 已注册的打印机保留原有 slug 和审核状态，新打印机使用生成的唯一 slug
+	if printer.ApprovalStatus == "" {
+		printer.ApprovalStatus = models.PrinterApprovalApproved
+	}
+	slug, err := r.slugForRegistration(printer.Name, printer.EdgeNodeID)
+	if err != nil {
+		return err
+	}
 
+	now := time.Now().UTC()
 	var returnedID string
 	err = r.db.QueryRow(
 		query,
@@ -372,9 +728,9 @@ func (r *PrinterRepository) UpsertPrinter(printer *models.Printer) error {
 		printer.Status, printer.FirmwareVersion, printer.PortInfo,
 		printer.IPAddress, printer.MACAddress, printer.NetworkConfig,
 		printer.Latitude, printer.Longitude, printer.Location,
-		capabilitiesJSON, printer.EdgeNodeID, printer.QueueLength,
-		time.Now(), time.Now(),
-	).Scan(&returnedID)
+		capabilitiesJSON, printer.EdgeNodeID, printer.EdgeQueueLength,
+		now, now, slug, printer.ApprovalStatus,
+	).Scan(&returnedID, &printer.Slug, &printer.ApprovalStatus)
 
 	if err != nil {
 		return fmt.Errorf("failed to upsert printer: %w", err)
@@ -382,20 +738,177 @@ func (r *PrinterRepository) UpsertPrinter(printer *models.Printer) error {
 
 	// 更新返回的 ID（如果是更新操作，ID 可能不同）
 	printer.ID = returnedID
+	r.db.invalidateLookup(LookupKindPrinter, printer.ID)
 	return nil
 }
 
+// PrinterSlugBase 生成打印机 slug 的基础部分：规范化的名称 + 节点ID哈希短后缀
+func PrinterSlugBase(name, edgeNodeID string) string {
+	var b strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			b.WriteByte('-')
+			lastDash = true
+		}
+	}
+	base := strings.Trim(b.String(), "-")
+	if len(base) > 60 {
+		base = strings.TrimRight(base[:60], "-")
+	}
+	if base == "" {
+		base = "printer"
+	}
+
+	sum := sha1.Sum([]byte(edgeNodeID))
+	return base + "-" + hex.EncodeToString(sum[:3])
+}
+
+// slugForRegistration 打印机注册时使用的 slug：已存在则沿用，否则生成新的唯一 slug
+func (r *PrinterRepository) slugForRegistration(name, edgeNodeID string) (string, error) {
+	var existing sql.NullString
+	err := r.db.QueryRow(`SELECT slug FROM printers WHERE name = $1 AND edge_node_id = $2`, name, edgeNodeID).Scan(&existing)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get printer slug: %w", err)
+	}
+	if existing.Valid && existing.String != "" {
+		return existing.String, nil
+	}
+	return r.nextFreeSlug(PrinterSlugBase(name, edgeNodeID))
+}
+
+// nextFreeSlug 返回未被占用的 slug，冲突时依次追加 -2、-3 ...
+func (r *PrinterRepository) nextFreeSlug(base string) (string, error) {
+	return findFreeSlug(r.db, base)
+}
+
+// queryer 可执行查询的数据库连接或事务
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// findFreeSlug 在给定连接或事务中查找未被占用的 slug
+func findFreeSlug(q queryer, base string) (string, error) {
+	rows, err := q.Query(`SELECT slug FROM printers WHERE slug = $1 OR slug LIKE $2`, base, base+"-%")
+	if err != nil {
+		return "", fmt.Errorf("failed to check printer slug: %w", err)
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", fmt.Errorf("failed to scan printer slug: %w", err)
+		}
+		taken[slug] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	slug := base
+	for i := 2; taken[slug]; i++ {
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+	return slug, nil
+}
+
+// BackfillSlugs 为没有 slug 的历史打印机生成 slug
+func (r *PrinterRepository) BackfillSlugs() (int, error) {
+	rows, err := r.db.Query(`SELECT id, name, edge_node_id FROM printers WHERE slug IS NULL ORDER BY created_at`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list printers without slug: %w", err)
+	}
+
+	type pending struct{ id, name, edgeNodeID string }
+	var printers []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.name, &p.edgeNodeID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan printer: %w", err)
+		}
+		printers = append(printers, p)
+	}
+	rows.Close()
+
+	for _, p := range printers {
+		slug, err := r.nextFreeSlug(PrinterSlugBase(p.name, p.edgeNodeID))
+		if err != nil {
+			return 0, err
+		}
+		if _, err := r.db.Exec(`UPDATE printers SET slug = $2 WHERE id = $1`, p.id, slug); err != nil {
+			return 0, fmt.Errorf("failed to backfill printer slug: %w", err)
+		}
+	}
+	if len(printers) > 0 {
+		r.db.invalidateLookupKind(LookupKindPrinter)
+	}
+	return len(printers), nil
+}
+
 // DisablePrintersByEdgeNode 禁用指定Edge Node下的所有打印机
 func (r *PrinterRepository) DisablePrintersByEdgeNode(edgeNodeID string) error {
 	query := `UPDATE printers SET enabled = false WHERE edge_node_id = $1`
-	_, err := r.db.DB.Exec(query, edgeNodeID)
-	return err
+	if _, err := r.db.DB.Exec(query, edgeNodeID); err != nil {
+		return err
+	}
+	r.db.invalidateLookupKind(LookupKindPrinter)
+	return nil
 }
 
 // EnablePrintersByEdgeNode 启用指定Edge Node下的所有打印机
 func (r *PrinterRepository) EnablePrintersByEdgeNode(edgeNodeID string) error {
 	query := `UPDATE printers SET enabled = true WHERE edge_node_id = $1`
-	_, err := r.db.DB.Exec(query, edgeNodeID)
-	return err
+	if _, err := r.db.DB.Exec(query, edgeNodeID); err != nil {
+		return err
+	}
+	r.db.invalidateLookupKind(LookupKindPrinter)
+	return nil
+}
+
+
+
+// markPrintersOfflineTx 节点离线时将其打印机置为 offline，并记住原状态以便恢复
+// 与节点状态在同一事务中更新，调用方提交后负责清除打印机缓存
+func markPrintersOfflineTx(tx *sql.Tx, edgeNodeID string) (int, error) {
+	query := `
+		UPDATE printers
+		SET last_known_status = status, status = $2
+		WHERE edge_node_id = $1 AND status <> $2`
+	result, err := tx.Exec(query, edgeNodeID, models.PrinterStatusOffline)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark printers offline: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return int(rowsAffected), nil
 }
 
+// RestorePrintersByEdgeNode 节点恢复在线时恢复其打印机的最后已知状态
+func (r *PrinterRepository) RestorePrintersByEdgeNode(edgeNodeID string) (int, error) {
+	query := `
+		UPDATE printers
+		SET status = last_known_status, last_known_status = NULL
+		WHERE edge_node_id = $1 AND last_known_status IS NOT NULL`
+	result, err := r.db.Exec(query, edgeNodeID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore printers status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected > 0 {
+		r.db.invalidateLookupKind(LookupKindPrinter)
+	}
+	return int(rowsAffected), nil
+}
\ No newline at end of file
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// 预留字段的修改不增加 row_version，也不更新 updated_at：预留是运行时状态，不应使管理员正在编辑的打印机配置失效
+
+// scanReservation 由可空的预留字段构造预留，未预留时返回 nil
+func scanReservation(reservedBy sql.NullString, reservedAt, reservedUntil sql.NullTime) *models.PrinterReservation {
+	if !reservedBy.Valid || !reservedUntil.Valid {
+		return nil
+	}
+	reservation := &models.PrinterReservation{Holder: reservedBy.String, ExpiresAt: reservedUntil.Time}
+	if reservedAt.Valid {
+		reservation.ReservedAt = reservedAt.Time
+	}
+	return reservation
+}
+
+// currentReservation 查询打印机当前有效的预留，没有有效预留时返回 nil
+func (r *PrinterRepository) currentReservation(printerID string, now time.Time) (*models.PrinterReservation, error) {
+	var reservedBy sql.NullString
+	var reservedAt, reservedUntil sql.NullTime
+	err := r.db.QueryRow(`SELECT reserved_by, reserved_at, reserved_until FROM printers WHERE id = $1`, printerID).
+		Scan(&reservedBy, &reservedAt, &reservedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get printer reservation: %w", err)
+	}
+	if reservation := scanReservation(reservedBy, reservedAt, reservedUntil); reservation.Active(now) {
+		return reservation, nil
+	}
+	return nil, nil
+}
+
+// ReservePrinter 为 holder 预留打印机到 until。打印机没有有效预留或已由 holder 预留时成功（重新开始计时），
+// 返回新的预留和 true；已被其他用户预留时返回当前预留和 false
+func (r *PrinterRepository) ReservePrinter(printerID, holder string, now, until time.Time) (*models.PrinterReservation, bool, error) {
+	query := `
+		UPDATE printers SET reserved_by = $2, reserved_at = $3, reserved_until = $4
+		WHERE id = $1 AND (reserved_by IS NULL OR reserved_until <= $3 OR reserved_by = $2)
+		RETURNING reserved_at, reserved_until`
+	reservation := &models.PrinterReservation{Holder: holder}
+	err := r.db.QueryRow(query, printerID, holder, now, until).Scan(&reservation.ReservedAt, &reservation.ExpiresAt)
+	if err == nil {
+		r.db.invalidateLookup(LookupKindPrinter, printerID)
+		return reservation, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to reserve printer: %w", err)
+	}
+
+	current, err := r.currentReservation(printerID, now)
+	return current, false, err
+}
+
+// ExtendReservation 将 holder 的有效预留延长到 until，但不超过预留开始时间 + maxDuration
+// 成功时返回延长后的预留和 true；预留不存在、已过期或属于其他用户时返回当前预留（可能为空）和 false
+func (r *PrinterRepository) ExtendReservation(printerID, holder string, now, until time.Time, maxDuration time.Duration) (*models.PrinterReservation, bool, error) {
+	query := `
+		UPDATE printers SET reserved_until = LEAST($4::timestamp, reserved_at + $5 * INTERVAL '1 second')
+		WHERE id = $1 AND reserved_by = $2 AND reserved_until > $3
+		RETURNING reserved_at, reserved_until`
+	reservation := &models.PrinterReservation{Holder: holder}
+	err := r.db.QueryRow(query, printerID, holder, now, until, maxDuration.Seconds()).
+		Scan(&reservation.ReservedAt, &reservation.ExpiresAt)
+	if err == nil {
+		r.db.invalidateLookup(LookupKindPrinter, printerID)
+		return reservation, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to extend printer reservation: %w", err)
+	}
+
+	current, err := r.currentReservation(printerID, now)
+	return current, false, err
+}
+
+// ReleaseReservation 释放 holder 对打印机的预留，返回是否释放（预留已被他人取得或已清理时返回 false）
+func (r *PrinterRepository) ReleaseReservation(printerID, holder string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE printers SET reserved_by = NULL, reserved_at = NULL, reserved_until = NULL
+		WHERE id = $1 AND reserved_by = $2`, printerID, holder)
+	if err != nil {
+		return false, fmt.Errorf("failed to release printer reservation: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows > 0 {
+		r.db.invalidateLookup(LookupKindPrinter, printerID)
+	}
+	return rows > 0, nil
+}
+
+// ExpireReservations 清除 now 时已到期的预留，返回对应的打印机ID
+func (r *PrinterRepository) ExpireReservations(now time.Time) ([]string, error) {
+	rows, err := r.db.Query(`
+		UPDATE printers SET reserved_by = NULL, reserved_at = NULL, reserved_until = NULL
+		WHERE reserved_by IS NOT NULL AND reserved_until <= $1
+		RETURNING id`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire printer reservations: %w", err)
+	}
+	defer rows.Close()
+
+	var printerIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan printer id: %w", err)
+		}
+		printerIDs = append(printerIDs, id)
+	}
+	r.db.invalidateLookup(LookupKindPrinter, printerIDs...)
+	return printerIDs, rows.Err()
+}
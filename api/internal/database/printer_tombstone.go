@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// printerTombstoneColumns 墓碑查询列（与 scanPrinterTombstone 的扫描顺序保持一致）
+const printerTombstoneColumns = `id, printer_id, name, edge_node_id, deleted_by, deleted_at, expires_at`
+
+func scanPrinterTombstone(row rowScanner) (*models.PrinterTombstone, error) {
+	tombstone := &models.PrinterTombstone{}
+	var deletedBy sql.NullString
+	if err := row.Scan(&tombstone.ID, &tombstone.PrinterID, &tombstone.Name, &tombstone.EdgeNodeID,
+		&deletedBy, &tombstone.DeletedAt, &tombstone.ExpiresAt); err != nil {
+		return nil, err
+	}
+	tombstone.DeletedBy = deletedBy.String
+	return tombstone, nil
+}
+
+// GetPrinterTombstone 获取 名称 + Edge Node 在 now 时仍有效的墓碑，不存在时返回 nil
+func (r *PrinterRepository) GetPrinterTombstone(name, edgeNodeID string, now time.Time) (*models.PrinterTombstone, error) {
+	query := `SELECT ` + printerTombstoneColumns + ` FROM printer_tombstones
+		WHERE name = $1 AND edge_node_id = $2 AND expires_at > $3`
+	tombstone, err := scanPrinterTombstone(r.db.QueryRow(query, name, edgeNodeID, now))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get printer tombstone: %w", err)
+	}
+	return tombstone, nil
+}
+
+// ListPrinterTombstones 分页获取有效的墓碑（按删除时间倒序），edgeNodeID 为空时不按节点筛选
+func (r *PrinterRepository) ListPrinterTombstones(page, pageSize int, edgeNodeID string, now time.Time) ([]*models.PrinterTombstone, int, error) {
+	offset := (page - 1) * pageSize
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM printer_tombstones WHERE expires_at > $1 AND ($2 = '' OR edge_node_id = $2)`
+	if err := r.db.QueryRow(countQuery, now, edgeNodeID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count printer tombstones: %w", err)
+	}
+
+	query := `SELECT ` + printerTombstoneColumns + ` FROM printer_tombstones
+		WHERE expires_at > $1 AND ($2 = '' OR edge_node_id = $2)
+		ORDER BY deleted_at DESC
+		LIMIT $3 OFFSET $4`
+	rows, err := r.db.Query(query, now, edgeNodeID, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list printer tombstones: %w", err)
+	}
+	defer rows.Close()
+
+	tombstones := []*models.PrinterTombstone{}
+	for rows.Next() {
+		tombstone, err := scanPrinterTombstone(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan printer tombstone: %w", err)
+		}
+		tombstones = append(tombstones, tombstone)
+	}
+	return tombstones, total, rows.Err()
+}
+
+// DeletePrinterTombstone 清除墓碑（管理员允许节点重新注册），返回被清除的墓碑，不存在时返回 nil
+func (r *PrinterRepository) DeletePrinterTombstone(id string) (*models.PrinterTombstone, error) {
+	query := `DELETE FROM printer_tombstones WHERE id = $1 RETURNING ` + printerTombstoneColumns
+	tombstone, err := scanPrinterTombstone(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to delete printer tombstone: %w", err)
+	}
+	return tombstone, nil
+}
+
+// DeleteExpiredPrinterTombstones 删除 now 时已到期的墓碑，返回删除数量
+func (r *PrinterRepository) DeleteExpiredPrinterTombstones(now time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM printer_tombstones WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired printer tombstones: %w", err)
+	}
+	return result.RowsAffected()
+}
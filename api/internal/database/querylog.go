@@ -0,0 +1,365 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// SQL 日志：在驱动层包装连接，所有经过 *DB（含 r.db.DB 和事务）的语句都会被记录，仓库代码无需修改
+// 调试模式下记录每条语句并按请求统计（见 TrackRequest）；任何模式下超过慢查询阈值的语句以 WARN 记录
+
+// maxLoggedArgLength 日志中单个参数的最大长度（字符），超出部分截断
+const maxLoggedArgLength = 200
+
+// sensitiveSQLMarkers 语句包含这些关键字时，字符串参数在日志中一律打码
+var sensitiveSQLMarkers = []string{"password", "secret", "token", "api_key", "credential"}
+
+// queryLogger SQL 日志配置
+type queryLogger struct {
+	debug         atomic.Bool
+	slowThreshold time.Duration // 0 表示不记录慢查询
+}
+
+// SetQueryDebug 开启或关闭调试模式的 SQL 日志（记录每条语句及参数、行数和耗时）
+func (db *DB) SetQueryDebug(enabled bool) {
+	if db.queryLog != nil {
+		db.queryLog.debug.Store(enabled)
+	}
+}
+
+// QueryStats 一个 HTTP 请求执行的 SQL 统计
+type QueryStats struct {
+	RequestID string
+	count     atomic.Int64
+}
+
+// Count 已执行的语句数
+func (s *QueryStats) Count() int64 {
+	return s.count.Load()
+}
+
+var (
+	// trackedRequests goroutine ID → *QueryStats
+	// 仓库方法不接收 context，只能按执行请求的 goroutine 关联；请求处理中另起 goroutine 执行的语句不计入请求
+	// 没有登记的请求时（非调试模式）currentRequest 直接返回，不读取调用栈
+	trackedRequests sync.Map
+	trackedCount    atomic.Int64
+)
+
+// TrackRequest 将当前 goroutine 之后执行的语句计入请求统计，返回的函数结束统计
+// 必须在处理请求的 goroutine 中调用（中间件）
+func TrackRequest(requestID string) (*QueryStats, func()) {
+	stats := &QueryStats{RequestID: requestID}
+	id := goroutineID()
+	trackedRequests.Store(id, stats)
+	trackedCount.Add(1)
+	return stats, func() {
+		trackedRequests.Delete(id)
+		trackedCount.Add(-1)
+	}
+}
+
+// currentRequest 当前 goroutine 正在处理的请求，不在请求中时返回 nil
+func currentRequest() *QueryStats {
+	if trackedCount.Load() == 0 {
+		return nil
+	}
+	if stats, ok := trackedRequests.Load(goroutineID()); ok {
+		return stats.(*QueryStats)
+	}
+	return nil
+}
+
+// goroutineID 从调用栈头部（"goroutine 123 [running]:"）解析当前 goroutine ID
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	fields := bytes.Fields(bytes.TrimPrefix(buf[:n], []byte("goroutine ")))
+	if len(fields) == 0 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(string(fields[0]), 10, 64)
+	return id
+}
+
+// queryEvent 一次语句执行
+type queryEvent struct {
+	query   string
+	args    []driver.NamedValue
+	start   time.Time
+	request *QueryStats
+}
+
+// begin 记录语句开始执行并计入当前请求
+func (l *queryLogger) begin(query string, args []driver.NamedValue) *queryEvent {
+	event := &queryEvent{query: query, args: args, start: time.Now(), request: currentRequest()}
+	if event.request != nil {
+		event.request.count.Add(1)
+	}
+	return event
+}
+
+// finish 语句执行结束（查询在结果集关闭时结束），rows 为返回或影响的行数，未知时为 -1
+func (l *queryLogger) finish(event *queryEvent, rows int64, err error) {
+	duration := time.Since(event.start)
+	slow := l.slowThreshold > 0 && duration >= l.slowThreshold
+	if !slow && !l.debug.Load() {
+		return
+	}
+
+	requestID := "-"
+	if event.request != nil {
+		requestID = event.request.RequestID
+	}
+	line := fmt.Sprintf("req=%s duration=%s rows=%d | %s | args=%s",
+		requestID, duration.Round(time.Microsecond), rows, compactSQL(event.query), formatArgs(event.query, event.args))
+	if err != nil {
+		line += fmt.Sprintf(" | error=%v", err)
+	}
+	if slow {
+		log.Printf("[SQL] WARN slow query (threshold %s) %s", l.slowThreshold, line)
+		return
+	}
+	log.Printf("[SQL] %s", line)
+}
+
+// compactSQL 将语句中的换行和缩进压缩为单个空格
+func compactSQL(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// formatArgs 格式化语句参数；语句涉及密码、密钥类字段时字符串参数打码，过长的参数截断
+func formatArgs(query string, args []driver.NamedValue) string {
+	if len(args) == 0 {
+		return "[]"
+	}
+	lower := strings.ToLower(query)
+	redact := false
+	for _, marker := range sensitiveSQLMarkers {
+		if strings.Contains(lower, marker) {
+			redact = true
+			break
+		}
+	}
+
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.Value.(type) {
+		case nil:
+			parts[i] = "NULL"
+		case string:
+			if redact {
+				parts[i] = "******"
+			} else {
+				parts[i] = strconv.Quote(truncateArg(v))
+			}
+		case []byte:
+			if redact {
+				parts[i] = "******"
+			} else if utf8.Valid(v) {
+				parts[i] = strconv.Quote(truncateArg(string(v)))
+			} else {
+				parts[i] = fmt.Sprintf("<%d bytes>", len(v))
+			}
+		case time.Time:
+			parts[i] = v.Format(time.RFC3339Nano)
+		default:
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func truncateArg(s string) string {
+	if utf8.RuneCountInString(s) <= maxLoggedArgLength {
+		return s
+	}
+	return string([]rune(s)[:maxLoggedArgLength]) + "..."
+}
+
+// loggingConnector 包装驱动的 Connector，为每个连接记录 SQL 日志
+type loggingConnector struct {
+	driver.Connector
+	logger *queryLogger
+}
+
+func (c *loggingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingConn{Conn: conn, logger: c.logger}, nil
+}
+
+// loggingConn 记录 SQL 日志的连接，其余能力委托给驱动连接
+type loggingConn struct {
+	driver.Conn
+	logger *queryLogger
+}
+
+func (c *loggingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	event := c.logger.begin(query, args)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		c.logger.finish(event, -1, err)
+		return nil, err
+	}
+	return &loggingRows{Rows: rows, logger: c.logger, event: event}, nil
+}
+
+func (c *loggingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	event := c.logger.begin(query, args)
+	result, err := execer.ExecContext(ctx, query, args)
+	c.logger.finish(event, rowsAffected(result), err)
+	return result, err
+}
+
+func (c *loggingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &loggingStmt{Stmt: stmt, query: query, logger: c.logger}, nil
+}
+
+func (c *loggingConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *loggingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *loggingConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *loggingConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *loggingConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// loggingStmt 记录 SQL 日志的预编译语句
+type loggingStmt struct {
+	driver.Stmt
+	query  string
+	logger *queryLogger
+}
+
+func (s *loggingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	event := s.logger.begin(s.query, args)
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedValuesToValues(args))
+	}
+	if err != nil {
+		s.logger.finish(event, -1, err)
+		return nil, err
+	}
+	return &loggingRows{Rows: rows, logger: s.logger, event: event}, nil
+}
+
+func (s *loggingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	event := s.logger.begin(s.query, args)
+	var result driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(namedValuesToValues(args))
+	}
+	s.logger.finish(event, rowsAffected(result), err)
+	return result, err
+}
+
+func namedValuesToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// rowsAffected 执行结果影响的行数，未知时为 -1
+func rowsAffected(result driver.Result) int64 {
+	if result == nil {
+		return -1
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// loggingRows 统计读取的行数，结果集关闭时记录日志（耗时包含读取结果的时间）
+type loggingRows struct {
+	driver.Rows
+	logger *queryLogger
+	event  *queryEvent
+	count  int64
+	err    error
+	closed bool
+}
+
+func (r *loggingRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err == nil {
+		r.count++
+	} else if err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+func (r *loggingRows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.logger.finish(r.event, r.count, r.err)
+	}
+	return err
+}
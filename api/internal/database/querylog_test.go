@@ -0,0 +1,30 @@
+package database
+
+import "testing"
+
+func TestTrackRequestAttributesOwnGoroutine(t *testing.T) {
+	if currentRequest() != nil {
+		t.Fatal("currentRequest without tracked requests should be nil")
+	}
+
+	logger := &queryLogger{}
+	stats, done := TrackRequest("req-1")
+	logger.begin("SELECT 1", nil)
+
+	other := make(chan *QueryStats)
+	go func() {
+		logger.begin("SELECT 2", nil)
+		other <- currentRequest()
+	}()
+	if got := <-other; got != nil {
+		t.Errorf("other goroutine attributed to %q", got.RequestID)
+	}
+	if stats.Count() != 1 {
+		t.Errorf("count = %d, want 1", stats.Count())
+	}
+
+	done()
+	if currentRequest() != nil {
+		t.Error("request still tracked after done")
+	}
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// StoredFileRepository 存储文件数据访问层（文件内容的保留期限独立于打印任务记录）
+type StoredFileRepository struct {
+	db *DB
+}
+
+// NewStoredFileRepository 创建存储文件数据访问层
+func NewStoredFileRepository(db *DB) *StoredFileRepository {
+	return &StoredFileRepository{db: db}
+}
+
+// RecordStoredFile 记录新上传的文件，重复记录时忽略
+func (r *StoredFileRepository) RecordStoredFile(file *models.StoredFile) error {
+	if file.CreatedAt.IsZero() {
+		file.CreatedAt = time.Now().UTC()
+	}
+	query := `
+		INSERT INTO stored_files (storage_key, file_size, content_type, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (storage_key) DO NOTHING`
+
+	if _, err := r.db.Exec(query, file.StorageKey, file.FileSize, nullIfEmpty(file.ContentType), file.CreatedAt); err != nil {
+		return fmt.Errorf("failed to record stored file: %w", err)
+	}
+	return nil
+}
+
+// linkStoredFiles 将任务使用的云端文件关联到任务（与任务在同一事务中写入）
+// 文件尚无记录时（升级前上传）以任务创建时间补记
+func linkStoredFiles(db execer, job *models.PrintJob) error {
+	sizes := make(map[string]int64)
+	if job.StorageKey != "" {
+		sizes[job.StorageKey] = job.FileSize
+	}
+	for _, file := range job.Files {
+		if file.StorageKey != "" {
+			sizes[file.StorageKey] = file.FileSize
+		}
+	}
+
+	query := `
+		INSERT INTO stored_files (storage_key, file_size, job_id, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (storage_key) DO UPDATE SET job_id = COALESCE(stored_files.job_id, EXCLUDED.job_id)`
+
+	for key, size := range sizes {
+		if _, err := db.Exec(query, key, size, job.ID, job.CreatedAt); err != nil {
+			return fmt.Errorf("failed to link stored file: %w", err)
+		}
+	}
+	return nil
+}
+
+// BackfillStoredFiles 首次启用文件记录时，为历史任务使用的云端文件补记（以最早使用该文件的任务创建时间作为上传时间）
+// 已有任何文件记录时不执行
+func (r *StoredFileRepository) BackfillStoredFiles() (int64, error) {
+	query := `
+		INSERT INTO stored_files (storage_key, file_size, job_id, created_at)
+		SELECT DISTINCT ON (storage_key) storage_key, file_size, job_id, created_at
+		FROM (
+			SELECT storage_key, COALESCE(file_size, 0) AS file_size, id AS job_id, created_at
+			FROM print_jobs WHERE storage_key IS NOT NULL AND storage_key <> ''
+			UNION ALL
+			SELECT storage_key, COALESCE(file_size, 0), job_id, created_at
+			FROM print_job_files WHERE storage_key IS NOT NULL AND storage_key <> ''
+		) refs
+		WHERE NOT EXISTS (SELECT 1 FROM stored_files)
+		ORDER BY storage_key, created_at
+		ON CONFLICT (storage_key) DO NOTHING`
+
+	result, err := r.db.Exec(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill stored files: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ListExpiredStoredFiles 列出 before 之前上传、尚未删除的文件（按上传时间排序，最多 limit 个）
+// 仍被未结束任务（含排队、待分发）使用的文件不会列出，等任务结束后再删除
+func (r *StoredFileRepository) ListExpiredStoredFiles(before time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT sf.storage_key
+		FROM stored_files sf
+		WHERE sf.purged_at IS NULL AND sf.created_at < $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM print_jobs pj
+			WHERE (pj.storage_key = sf.storage_key OR pj.converted_storage_key = sf.storage_key)
+			  AND pj.status NOT IN (` + models.StatusSQLList(models.TerminalJobStatuses) + `)
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM print_job_files f JOIN print_jobs pj ON pj.id = f.job_id
+			WHERE f.storage_key = sf.storage_key
+			  AND pj.status NOT IN (` + models.StatusSQLList(models.TerminalJobStatuses) + `)
+		  )
+		ORDER BY sf.created_at
+		LIMIT $2`
+
+	rows, err := r.db.Query(query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired stored files: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan stored file: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// MarkStoredFilePurged 记录文件内容已删除，并将使用该文件的任务标记为 file_purged，返回标记的任务数
+func (r *StoredFileRepository) MarkStoredFilePurged(storageKey string, purgedAt time.Time) (int64, error) {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE stored_files SET purged_at = $2 WHERE storage_key = $1 AND purged_at IS NULL`, storageKey, purgedAt); err != nil {
+		return 0, fmt.Errorf("failed to mark stored file purged: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		UPDATE print_jobs SET file_purged = TRUE, file_purged_at = $2
+		WHERE file_purged = FALSE
+		  AND (storage_key = $1 OR id IN (SELECT job_id FROM print_job_files WHERE storage_key = $1))`,
+		storageKey, purgedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark print jobs file purged: %w", err)
+	}
+	jobs, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return jobs, nil
+}
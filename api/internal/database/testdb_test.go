@@ -0,0 +1,102 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/password"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// testDatabaseURLEnv 集成测试使用的 PostgreSQL 连接串（postgres:// URL 或 key=value 形式），未设置时跳过依赖数据库的测试
+const testDatabaseURLEnv = "FLY_PRINT_TEST_DATABASE_URL"
+
+// openTestDB 在测试数据库中创建独立的 schema 并初始化表结构，测试结束后删除
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	dsn := os.Getenv(testDatabaseURLEnv)
+	if dsn == "" {
+		t.Skipf("%s not set, skipping database test", testDatabaseURLEnv)
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		parsed, err := pq.ParseURL(dsn)
+		if err != nil {
+			t.Fatalf("parse %s: %v", testDatabaseURLEnv, err)
+		}
+		dsn = parsed
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := "test_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Logf("drop schema %s: %v", schema, err)
+		}
+	})
+
+	conn, err := sql.Open("postgres", dsn+" search_path="+schema)
+	if err != nil {
+		t.Fatalf("open test schema: %v", err)
+	}
+	db := &DB{DB: conn, queryLog: &queryLogger{}, passwords: password.DefaultHasher()}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := db.InitTables(); err != nil {
+		t.Fatalf("init tables: %v", err)
+	}
+	return db
+}
+
+// createTestPrinter 创建一个 Edge Node 和其下的打印机，返回打印机ID
+func createTestPrinter(t *testing.T, db *DB) string {
+	t.Helper()
+	nodeID := "node-" + uuid.New().String()[:8]
+	printerID := uuid.New().String()
+	if _, err := db.Exec(`INSERT INTO edge_nodes (id, name, status) VALUES ($1, $1, 'online')`, nodeID); err != nil {
+		t.Fatalf("create edge node: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO printers (id, name, status, edge_node_id, slug) VALUES ($1, $2, 'ready', $3, $2)`,
+		printerID, "printer-"+printerID[:8], nodeID); err != nil {
+		t.Fatalf("create printer: %v", err)
+	}
+	return printerID
+}
+
+// createTestJob 在打印机上创建一个指定状态的任务
+func createTestJob(t *testing.T, db *DB, printerID string, status models.JobStatus) *models.PrintJob {
+	t.Helper()
+	job := &models.PrintJob{
+		Name:      "test.pdf",
+		Status:    status,
+		PrinterID: printerID,
+		UserName:  "alice",
+		FileURL:   "https://example.com/test.pdf",
+		PageCount: 1,
+		Copies:    1,
+		Source:    models.JobSourceAPI,
+	}
+	if err := NewPrintJobRepository(db).CreatePrintJob(job); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	return job
+}
+
+// mustExec 执行 SQL，失败时终止测试
+func mustExec(t *testing.T, db *DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("exec %q: %v", query, err)
+	}
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/google/uuid"
+)
+
+// createTestUser 创建指定角色的活跃用户
+func createTestUser(t *testing.T, users *UserRepository, role string) *models.User {
+	t.Helper()
+	user := &models.User{
+		Username:     "user-" + uuid.New().String()[:8],
+		PasswordHash: "password",
+		Role:         role,
+		Status:       "active",
+	}
+	user.Email = user.Username + "@example.com"
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	created, err := users.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	return created
+}
+
+// countActiveAdmins 统计活跃管理员数量
+func countActiveAdmins(t *testing.T, db *DB) int {
+	t.Helper()
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE role = 'admin' AND status = 'active'`).Scan(&count); err != nil {
+		t.Fatalf("count admins: %v", err)
+	}
+	return count
+}
+
+// TestLastAdminGuard 唯一的活跃管理员不能被删除、降级或停用；其他用户不受影响
+func TestLastAdminGuard(t *testing.T) {
+	db := openTestDB(t)
+	users := NewUserRepository(db)
+
+	admin := createTestUser(t, users, "admin")
+	operator := createTestUser(t, users, "operator")
+
+	if err := users.DeleteUser(admin.ID); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("delete last admin = %v, want ErrLastAdmin", err)
+	}
+	demoted := *admin
+	demoted.Role = "operator"
+	if err := users.UpdateUser(&demoted); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("demote last admin = %v, want ErrLastAdmin", err)
+	}
+	deactivated := *admin
+	deactivated.Status = "inactive"
+	if err := users.UpdateUser(&deactivated); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("deactivate last admin = %v, want ErrLastAdmin", err)
+	}
+	renamed := *admin
+	renamed.Username += "-renamed"
+	if err := users.UpdateUser(&renamed); err != nil {
+		t.Fatalf("rename last admin: %v", err)
+	}
+
+	if err := users.DeleteUser(operator.ID); err != nil {
+		t.Fatalf("delete operator: %v", err)
+	}
+
+	second := createTestUser(t, users, "admin")
+	if err := users.DeleteUser(second.ID); err != nil {
+		t.Fatalf("delete one of two admins: %v", err)
+	}
+	if got := countActiveAdmins(t, db); got != 1 {
+		t.Fatalf("active admins = %d, want 1", got)
+	}
+}
+
+// TestLastAdminGuardConcurrent 两个管理员同时删除对方（或降级）时只有一个成功，始终保留一个活跃管理员
+func TestLastAdminGuardConcurrent(t *testing.T) {
+	db := openTestDB(t)
+	users := NewUserRepository(db)
+
+	for round := 0; round < 10; round++ {
+		mustExec(t, db, `UPDATE users SET status = 'inactive' WHERE role = 'admin'`)
+		alice := createTestUser(t, users, "admin")
+		bob := createTestUser(t, users, "admin")
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs[0] = users.DeleteUser(alice.ID)
+		}()
+		go func() {
+			defer wg.Done()
+			demoted := *bob
+			demoted.Role = "operator"
+			errs[1] = users.UpdateUser(&demoted)
+		}()
+		wg.Wait()
+
+		succeeded := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				succeeded++
+			case !errors.Is(err, ErrLastAdmin):
+				t.Fatalf("round %d: unexpected error %v", round, err)
+			}
+		}
+		if succeeded != 1 {
+			t.Fatalf("round %d: %d removals succeeded (%v), want exactly 1", round, succeeded, errs)
+		}
+		if got := countActiveAdmins(t, db); got != 1 {
+			t.Fatalf("round %d: active admins = %d, want 1", round, got)
+		}
+	}
+}
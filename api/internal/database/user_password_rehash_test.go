@@ -0,0 +1,64 @@
+package database
+
+import (
+	"testing"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/password"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// storedPasswordHash 读取用户当前的密码哈希
+func storedPasswordHash(t *testing.T, db *DB, userID string) string {
+	t.Helper()
+	var hash string
+	if err := db.QueryRow(`SELECT password_hash FROM users WHERE id = $1`, userID).Scan(&hash); err != nil {
+		t.Fatalf("read password hash: %v", err)
+	}
+	return hash
+}
+
+// TestVerifyPasswordRehash 登录时弱哈希按当前配置升级；验证期间密码被并发修改时不覆盖新密码
+func TestVerifyPasswordRehash(t *testing.T) {
+	db := openTestDB(t)
+	hasher, err := password.NewHasher(&config.PasswordHashConfig{Algorithm: password.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1})
+	if err != nil {
+		t.Fatalf("NewHasher: %v", err)
+	}
+	db.passwords = hasher
+	users := NewUserRepository(db)
+
+	weak, err := bcrypt.GenerateFromPassword([]byte("old-secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+
+	// 未被修改时升级哈希
+	user := createTestUser(t, users, "viewer")
+	mustExec(t, db, `UPDATE users SET password_hash = $2 WHERE id = $1`, user.ID, string(weak))
+	user.PasswordHash = string(weak)
+	if !users.VerifyPassword(user, "old-secret") {
+		t.Fatal("VerifyPassword rejected the correct password")
+	}
+	upgraded := storedPasswordHash(t, db, user.ID)
+	if cost, err := bcrypt.Cost([]byte(upgraded)); err != nil || cost != bcrypt.MinCost+1 {
+		t.Fatalf("stored hash cost = %d (%v), want %d", cost, err, bcrypt.MinCost+1)
+	}
+
+	// 登录读取用户之后密码被修改，升级不能覆盖新密码
+	user = createTestUser(t, users, "viewer")
+	mustExec(t, db, `UPDATE users SET password_hash = $2 WHERE id = $1`, user.ID, string(weak))
+	user.PasswordHash = string(weak)
+	changed, err := hasher.Hash("new-secret")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	mustExec(t, db, `UPDATE users SET password_hash = $2 WHERE id = $1`, user.ID, changed)
+
+	if !users.VerifyPassword(user, "old-secret") {
+		t.Fatal("VerifyPassword rejected the password read before the change")
+	}
+	if stored := storedPasswordHash(t, db, user.ID); stored != changed {
+		t.Fatalf("stored hash = %q, want the concurrently changed %q", stored, changed)
+	}
+}
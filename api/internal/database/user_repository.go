@@ -2,13 +2,17 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"fly-print-cloud/api/internal/models"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrLastAdmin 删除、降级或停用唯一的活跃管理员
+var ErrLastAdmin = errors.New("database: cannot remove the last active admin")
+
 // UserRepository 用户数据访问层
 type UserRepository struct {
 	db *DB
@@ -22,7 +26,7 @@ func NewUserRepository(db *DB) *UserRepository {
 // CreateUser 创建用户
 func (r *UserRepository) CreateUser(user *models.User) error {
 	// 加密密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.PasswordHash), bcrypt.DefaultCost)
+	hashedPassword, err := r.db.passwords.Hash(user.PasswordHash)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -32,7 +36,7 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, updated_at`
 
-	err = r.db.QueryRow(query, user.Username, user.Email, string(hashedPassword), user.Role, user.Status).
+	err = r.db.QueryRow(query, user.Username, user.Email, hashedPassword, user.Role, user.Status).
 		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
@@ -47,12 +51,12 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, username, email, role, status, last_login, created_at, updated_at
+		SELECT id, username, email, role, status, last_login, row_version, created_at, updated_at
 		FROM users WHERE id = $1 AND status = 'active'`
 
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Role, &user.Status,
-		&user.LastLogin, &user.CreatedAt, &user.UpdatedAt)
+		&user.LastLogin, &user.RowVersion, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -83,19 +87,80 @@ func (r *UserRepository) GetUserByUsername(username string) (*models.User, error
 	return user, nil
 }
 
-// UpdateUser 更新用户信息
+// GetActiveUserByUsername 按用户名获取启用的用户（不含密码哈希），未找到时返回 nil
+func (r *UserRepository) GetActiveUserByUsername(username string) (*models.User, error) {
+	user := &models.User{}
+	query := `
+		SELECT id, username, email, external_id, role, status, created_at, updated_at
+		FROM users WHERE username = $1 AND status = 'active'`
+
+	err := r.db.QueryRow(query, username).Scan(
+		&user.ID, &user.Username, &user.Email, &user.ExternalID, &user.Role,
+		&user.Status, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetActiveUserByEmail 按邮箱（不区分大小写）获取启用的用户，未找到时返回 nil
+func (r *UserRepository) GetActiveUserByEmail(email string) (*models.User, error) {
+	user := &models.User{}
+	query := `
+		SELECT id, username, email, external_id, role, status, created_at, updated_at
+		FROM users WHERE LOWER(email) = LOWER($1) AND status = 'active'
+		LIMIT 1`
+
+	err := r.db.QueryRow(query, email).Scan(
+		&user.ID, &user.Username, &user.Email, &user.ExternalID, &user.Role,
+		&user.Status, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	return user, nil
+}
+
+// UpdateUser 更新用户信息（管理员修改）
+// 仅当数据库中的版本号等于 user.RowVersion 时更新，成功后 user.RowVersion 为新版本号；
+// 版本号不一致时返回 ErrVersionConflict，降级或停用唯一的活跃管理员时返回 ErrLastAdmin
 func (r *UserRepository) UpdateUser(user *models.User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if user.Role != "admin" || user.Status != "active" {
+		if err := guardLastAdminTx(tx, user.ID); err != nil {
+			return err
+		}
+	}
+
 	query := `
 		UPDATE users
-		SET username = $2, email = $3, role = $4, status = $5
-		WHERE id = $1
-		RETURNING updated_at`
-
-	err := r.db.QueryRow(query, user.ID, user.Username, user.Email, user.Role, user.Status).
-		Scan(&user.UpdatedAt)
+		SET username = $2, email = $3, role = $4, status = $5, row_version = row_version + 1
+		WHERE id = $1 AND row_version = $6
+		RETURNING row_version, updated_at`
+
+	err = tx.QueryRow(query, user.ID, user.Username, user.Email, user.Role, user.Status, user.RowVersion).
+		Scan(&user.RowVersion, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return versionMismatch(r.db, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", user.ID, "user not found")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user update: %w", err)
+	}
 
 	return nil
 }
@@ -103,13 +168,13 @@ func (r *UserRepository) UpdateUser(user *models.User) error {
 // UpdatePassword 更新用户密码
 func (r *UserRepository) UpdatePassword(userID, newPassword string) error {
 	// 加密新密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	hashedPassword, err := r.db.passwords.Hash(newPassword)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
 	query := `UPDATE users SET password_hash = $2 WHERE id = $1`
-	_, err = r.db.Exec(query, userID, string(hashedPassword))
+	_, err = r.db.Exec(query, userID, hashedPassword)
 	if err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
@@ -128,10 +193,20 @@ func (r *UserRepository) UpdateLastLogin(userID string) error {
 	return nil
 }
 
-// DeleteUser 删除用户（软删除，设置状态为inactive）
+// DeleteUser 删除用户（软删除，设置状态为inactive），删除唯一的活跃管理员时返回 ErrLastAdmin
 func (r *UserRepository) DeleteUser(userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := guardLastAdminTx(tx, userID); err != nil {
+		return err
+	}
+
 	query := `UPDATE users SET status = 'inactive' WHERE id = $1`
-	result, err := r.db.Exec(query, userID)
+	result, err := tx.Exec(query, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -145,9 +220,49 @@ func (r *UserRepository) DeleteUser(userID string) error {
 		return fmt.Errorf("user not found")
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user deletion: %w", err)
+	}
 	return nil
 }
 
+// guardLastAdminTx 锁定全部活跃管理员，userID 是唯一的活跃管理员时返回 ErrLastAdmin
+// 并发的删除或降级依次持有行锁，后执行的一方看到前者提交后的状态，不会同时移除最后两个管理员
+func guardLastAdminTx(tx *sql.Tx, userID string) error {
+	rows, err := tx.Query(`SELECT id FROM users WHERE role = 'admin' AND status = 'active' FOR UPDATE`)
+	if err != nil {
+		return fmt.Errorf("failed to lock active admins: %w", err)
+	}
+	defer rows.Close()
+
+	count, isAdmin := 0, false
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan active admin: %w", err)
+		}
+		count++
+		isAdmin = isAdmin || id == userID
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to lock active admins: %w", err)
+	}
+	if isAdmin && count <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// AdminExists 是否已存在管理员账户（初始化向导据此判断是否需要创建首个管理员）
+func (r *UserRepository) AdminExists() (bool, error) {
+	return r.db.adminExists(r.db.DB)
+}
+
+// CreateFirstAdmin 创建首个管理员（密码为明文，在此加密），已存在管理员时返回 ErrAdminExists
+func (r *UserRepository) CreateFirstAdmin(user *models.User, password string) error {
+	return r.db.createFirstAdmin(user, password)
+}
+
 // ListUsers 获取用户列表
 func (r *UserRepository) ListUsers(offset, limit int) ([]*models.User, int, error) {
 	var users []*models.User
@@ -162,7 +277,7 @@ func (r *UserRepository) ListUsers(offset, limit int) ([]*models.User, int, erro
 
 	// 获取用户列表
 	query := `
-		SELECT id, username, email, role, status, last_login, created_at, updated_at
+		SELECT id, username, email, role, status, last_login, row_version, created_at, updated_at
 		FROM users 
 		WHERE status = 'active'
 		ORDER BY created_at DESC
@@ -178,7 +293,7 @@ func (r *UserRepository) ListUsers(offset, limit int) ([]*models.User, int, erro
 		user := &models.User{}
 		err := rows.Scan(
 			&user.ID, &user.Username, &user.Email, &user.Role, &user.Status,
-			&user.LastLogin, &user.CreatedAt, &user.UpdatedAt)
+			&user.LastLogin, &user.RowVersion, &user.CreatedAt, &user.UpdatedAt)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -192,10 +307,34 @@ func (r *UserRepository) ListUsers(offset, limit int) ([]*models.User, int, erro
 	return users, total, nil
 }
 
-// VerifyPassword 验证密码
+// VerifyPassword 验证密码；验证通过且哈希的算法或参数弱于当前配置时，按当前配置重新哈希并保存（失败只记录日志）
+// 无法识别的哈希格式视为验证失败
 func (r *UserRepository) VerifyPassword(user *models.User, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
-	return err == nil
+	ok, rehash, err := r.db.passwords.Verify(user.PasswordHash, password)
+	if err != nil {
+		log.Printf("Failed to verify password for user %s: %v", user.ID, err)
+		return false
+	}
+	if ok && rehash {
+		r.upgradePasswordHash(user, password)
+	}
+	return ok
+}
+
+// upgradePasswordHash 按当前配置重新哈希已验证的密码；哈希在此期间被修改（如并发修改密码）时不覆盖
+func (r *UserRepository) upgradePasswordHash(user *models.User, password string) {
+	hashedPassword, err := r.db.passwords.Hash(password)
+	if err != nil {
+		log.Printf("Failed to rehash password for user %s: %v", user.ID, err)
+		return
+	}
+
+	query := `UPDATE users SET password_hash = $2 WHERE id = $1 AND password_hash = $3`
+	if _, err := r.db.Exec(query, user.ID, hashedPassword, user.PasswordHash); err != nil {
+		log.Printf("Failed to save upgraded password hash for user %s: %v", user.ID, err)
+		return
+	}
+	user.PasswordHash = hashedPassword
 }
 
 // EmailExists 检查邮箱是否已存在
@@ -239,7 +378,8 @@ func (r *UserRepository) UsernameExists(username string, excludeUserID ...string
 // GetUserByExternalID 通过外部ID获取用户
 func (r *UserRepository) GetUserByExternalID(externalID string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, external_id, role, status, last_login, created_at, updated_at
+		SELECT id, username, email, password_hash, external_id, role, status,
+		       notify_on_completion, notify_on_failure, last_login, created_at, updated_at
 		FROM users 
 		WHERE external_id = $1`
 	
@@ -254,6 +394,8 @@ func (r *UserRepository) GetUserByExternalID(externalID string) (*models.User, e
 		&externalIDPtr,
 		&user.Role,
 		&user.Status,
+		&user.NotifyOnCompletion,
+		&user.NotifyOnFailure,
 		&user.LastLogin,
 		&user.CreatedAt,
 		&user.UpdatedAt,
@@ -284,8 +426,8 @@ func (r *UserRepository) CreateUserFromOAuth2(externalID, username, email string
 		PasswordHash: "oauth2_user", // OAuth2 用户的占位符密码哈希
 		Role:         "admin",
 		Status:       "active",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
 	}
 	
 	err := r.db.QueryRow(query,
@@ -304,4 +446,48 @@ func (r *UserRepository) CreateUserFromOAuth2(externalID, username, email string
 	}
 	
 	return user, nil
-}
\ No newline at end of file
+}
+
+// UpdateNotificationPreferences 更新用户的任务通知偏好
+func (r *UserRepository) UpdateNotificationPreferences(userID string, onCompletion, onFailure bool) error {
+	query := `UPDATE users SET notify_on_completion = $2, notify_on_failure = $3 WHERE id = $1`
+	result, err := r.db.Exec(query, userID, onCompletion, onFailure)
+	if err != nil {
+		return fmt.Errorf("failed to update notification preferences: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// GetNotificationRecipient 获取任务提交用户的邮箱及通知偏好
+// 优先按任务的用户ID匹配，没有本地用户的任务按用户名匹配；未找到时返回 nil
+func (r *UserRepository) GetNotificationRecipient(userID, username string) (*models.User, error) {
+	query := `
+		SELECT id, username, email, notify_on_completion, notify_on_failure
+		FROM users
+		WHERE status = 'active'
+		  AND ((id::text = $1 AND $1 <> '') OR username = $2)
+		ORDER BY (id::text = $1) DESC
+		LIMIT 1`
+
+	user := &models.User{}
+	err := r.db.QueryRow(query, userID, username).Scan(
+		&user.ID, &user.Username, &user.Email, &user.NotifyOnCompletion, &user.NotifyOnFailure)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get notification recipient: %w", err)
+	}
+
+	return user, nil
+}
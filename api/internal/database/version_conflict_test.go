@@ -0,0 +1,182 @@
+package database
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/google/uuid"
+)
+
+// TestAdminLostUpdate 两个管理员读取同一版本后先后保存：先保存者成功并递增版本号，
+// 后保存者得到 ErrVersionConflict，且其修改不会覆盖先保存者的修改
+func TestAdminLostUpdate(t *testing.T) {
+	db := openTestDB(t)
+	printers := NewPrinterRepository(db)
+	nodes := NewEdgeNodeRepository(db)
+	users := NewUserRepository(db)
+
+	t.Run("printer", func(t *testing.T) {
+		printerID := createTestPrinter(t, db)
+		alice, err := printers.GetPrinterByID(printerID)
+		if err != nil {
+			t.Fatalf("get printer: %v", err)
+		}
+		bob, err := printers.GetPrinterByID(printerID)
+		if err != nil {
+			t.Fatalf("get printer: %v", err)
+		}
+		read := alice.RowVersion
+
+		alice.DisplayName = "Alice"
+		if err := printers.UpdatePrinter(alice); err != nil {
+			t.Fatalf("first update: %v", err)
+		}
+		if alice.RowVersion != read+1 {
+			t.Fatalf("row_version after update = %d, want %d", alice.RowVersion, read+1)
+		}
+		bob.DisplayName = "Bob"
+		if err := printers.UpdatePrinter(bob); !errors.Is(err, ErrVersionConflict) {
+			t.Fatalf("second update = %v, want ErrVersionConflict", err)
+		}
+
+		got, err := printers.GetPrinterByID(printerID)
+		if err != nil {
+			t.Fatalf("get printer: %v", err)
+		}
+		if got.DisplayName != "Alice" || got.RowVersion != read+1 {
+			t.Fatalf("printer = %q at version %d, want %q at version %d", got.DisplayName, got.RowVersion, "Alice", read+1)
+		}
+	})
+
+	t.Run("edge node", func(t *testing.T) {
+		nodeID := printerNodeID(t, db, createTestPrinter(t, db))
+		alice, err := nodes.GetEdgeNodeByID(nodeID)
+		if err != nil {
+			t.Fatalf("get node: %v", err)
+		}
+		bob, err := nodes.GetEdgeNodeByID(nodeID)
+		if err != nil {
+			t.Fatalf("get node: %v", err)
+		}
+		read := alice.RowVersion
+
+		alice.Name = "alice-node"
+		if err := nodes.UpdateEdgeNode(alice); err != nil {
+			t.Fatalf("first update: %v", err)
+		}
+		bob.Name = "bob-node"
+		bob.Enabled = false
+		if err := nodes.UpdateEdgeNode(bob); !errors.Is(err, ErrVersionConflict) {
+			t.Fatalf("second update = %v, want ErrVersionConflict", err)
+		}
+
+		got, err := nodes.GetEdgeNodeByID(nodeID)
+		if err != nil {
+			t.Fatalf("get node: %v", err)
+		}
+		if got.Name != "alice-node" || !got.Enabled || got.RowVersion != read+1 {
+			t.Fatalf("node = %q enabled %v at version %d, want %q enabled at version %d", got.Name, got.Enabled, got.RowVersion, "alice-node", read+1)
+		}
+	})
+
+	t.Run("user", func(t *testing.T) {
+		user := &models.User{
+			Username:     "user-" + uuid.New().String()[:8],
+			PasswordHash: "password",
+			Role:         "operator",
+			Status:       "active",
+		}
+		user.Email = user.Username + "@example.com"
+		if err := users.CreateUser(user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		alice, err := users.GetUserByID(user.ID)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+		bob, err := users.GetUserByID(user.ID)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+		read := alice.RowVersion
+
+		alice.Role = "admin"
+		if err := users.UpdateUser(alice); err != nil {
+			t.Fatalf("first update: %v", err)
+		}
+		bob.Status = "inactive"
+		if err := users.UpdateUser(bob); !errors.Is(err, ErrVersionConflict) {
+			t.Fatalf("second update = %v, want ErrVersionConflict", err)
+		}
+
+		got, err := users.GetUserByID(user.ID)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+		if got.Role != "admin" || got.Status != "active" || got.RowVersion != read+1 {
+			t.Fatalf("user = role %s status %s at version %d, want admin/active at version %d", got.Role, got.Status, got.RowVersion, read+1)
+		}
+	})
+}
+
+// TestVersionMismatchNotFound 记录不存在时返回普通错误而不是 ErrVersionConflict
+func TestVersionMismatchNotFound(t *testing.T) {
+	db := openTestDB(t)
+
+	missingPrinter := &models.Printer{ID: uuid.New().String(), RowVersion: 1}
+	if err := NewPrinterRepository(db).UpdatePrinter(missingPrinter); err == nil || errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("update missing printer = %v, want not-found error", err)
+	}
+	missingNode := &models.EdgeNode{ID: "node-missing", RowVersion: 1}
+	if err := NewEdgeNodeRepository(db).UpdateEdgeNode(missingNode); err == nil || errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("update missing edge node = %v, want not-found error", err)
+	}
+	missingUser := &models.User{ID: uuid.New().String(), RowVersion: 1}
+	if err := NewUserRepository(db).UpdateUser(missingUser); err == nil || errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("update missing user = %v, want not-found error", err)
+	}
+}
+
+// TestConcurrentAdminUpdates 多个请求以同一版本号并发更新时只有一个成功
+func TestConcurrentAdminUpdates(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPrinterRepository(db)
+	printerID := createTestPrinter(t, db)
+
+	const writers = 8
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		conflicts int
+	)
+	for i := 0; i < writers; i++ {
+		printer, err := repo.GetPrinterByID(printerID)
+		if err != nil {
+			t.Fatalf("get printer: %v", err)
+		}
+		printer.DisplayName = uuid.New().String()[:8]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.UpdatePrinter(printer)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, ErrVersionConflict):
+				conflicts++
+			default:
+				t.Errorf("update printer: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 || conflicts != writers-1 {
+		t.Fatalf("succeeded %d, conflicts %d; want 1 and %d", succeeded, conflicts, writers-1)
+	}
+}
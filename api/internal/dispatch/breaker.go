@@ -0,0 +1,106 @@
+package dispatch
+
+import (
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/models"
+)
+
+// breakerPolicy 当前生效的打印机熔断阈值（jobs.breaker_*）
+func (d *Dispatcher) breakerPolicy() models.BreakerPolicy {
+	jobs := d.settings.Get().Jobs
+	return models.BreakerPolicy{
+		Failures: jobs.BreakerFailures,
+		Window:   jobs.BreakerWindow,
+		Cooldown: jobs.BreakerCooldown,
+	}
+}
+
+// recordSuccess 任务在打印机上完成，清零连续失败计数（失败只记录日志）
+func (d *Dispatcher) recordSuccess(printerID string) {
+	if printerID == "" {
+		return
+	}
+	policy := d.breakerPolicy()
+	if _, _, err := d.printerRepo.ModifyPrinterBreaker(printerID, policy.RecordSuccess); err != nil {
+		log.Printf("Failed to record job success for printer %s breaker: %v", printerID, err)
+	}
+}
+
+// recordFailure 任务在打印机上失败，计入熔断器；达到阈值时暂停分发、发布告警事件并记录到任务时间线
+// 返回更新后的熔断状态，记录失败时 ok 为 false
+func (d *Dispatcher) recordFailure(job *models.PrintJob, printerID, reason string) (breaker models.PrinterBreaker, ok bool) {
+	if printerID == "" {
+		return breaker, false
+	}
+	policy := d.breakerPolicy()
+	now := time.Now().UTC()
+	tripped := false
+	_, after, err := d.printerRepo.ModifyPrinterBreaker(printerID, func(b models.PrinterBreaker) models.PrinterBreaker {
+		b, tripped = policy.RecordFailure(b, now, reason)
+		return b
+	})
+	if err != nil {
+		log.Printf("Failed to record job failure for printer %s breaker: %v", printerID, err)
+		return breaker, false
+	}
+	if !tripped {
+		return after, true
+	}
+
+	log.Printf("Printer %s suspended after %d consecutive job failures (last: %s)", printerID, after.ConsecutiveFailures, reason)
+	data := map[string]interface{}{
+		"printer_id": printerID,
+		"failures":   after.ConsecutiveFailures,
+		"reason":     reason,
+		"job_id":     job.ID,
+	}
+	if after.ResumeAt != nil {
+		data["resume_at"] = after.ResumeAt
+	}
+	if printer, err := d.printerRepo.GetPrinterByIDCached(printerID); err == nil && printer != nil {
+		data["printer_name"] = printer.Name
+		d.publish(events.EventPrinterSuspended, printer.EdgeNodeID, data)
+	} else {
+		d.publish(events.EventPrinterSuspended, "", data)
+	}
+	d.recordEvent(job, models.JobEventPrinterSuspended, reason, map[string]interface{}{
+		"printer_id": printerID,
+		"failures":   after.ConsecutiveFailures,
+	})
+	return after, true
+}
+
+// publish 发布事件（未配置事件总线时忽略）
+func (d *Dispatcher) publish(eventType, nodeID string, data map[string]interface{}) {
+	if d.eventBus == nil {
+		return
+	}
+	d.eventBus.Publish(events.Event{Type: eventType, NodeID: nodeID, Data: data})
+}
+
+// ResetBreaker 管理员手动重置打印机熔断器（清零失败计数），已熔断时恢复分发并分发排队任务；返回重置前的状态
+func (d *Dispatcher) ResetBreaker(printer *models.Printer) (models.PrinterBreaker, error) {
+	before, after, err := d.printerRepo.ModifyPrinterBreaker(printer.ID, func(models.PrinterBreaker) models.PrinterBreaker {
+		return models.PrinterBreaker{State: models.BreakerClosed}
+	})
+	if err != nil {
+		return before, err
+	}
+	printer.Breaker = after
+	if !before.Suspended() {
+		return before, nil
+	}
+
+	log.Printf("Printer %s breaker reset manually", printer.ID)
+	d.publish(events.EventPrinterResumed, printer.EdgeNodeID, map[string]interface{}{
+		"printer_id":   printer.ID,
+		"printer_name": printer.Name,
+		"suspended_at": before.SuspendedAt,
+		"trigger":      "manual",
+	})
+	d.DispatchQueued(printer)
+	return before, nil
+}
@@ -0,0 +1,38 @@
+package dispatch
+
+import (
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestPrinterAcceptsJobs 已禁用、未审批或熔断的打印机不分发，新任务先排队
+func TestPrinterAcceptsJobs(t *testing.T) {
+	ready := func() *models.Printer {
+		return &models.Printer{ID: "p1", Enabled: true, ApprovalStatus: models.PrinterApprovalApproved,
+			Breaker: models.PrinterBreaker{State: models.BreakerClosed}}
+	}
+	tests := []struct {
+		name   string
+		modify func(*models.Printer)
+		want   bool
+	}{
+		{"ready", func(*models.Printer) {}, true},
+		{"disabled", func(p *models.Printer) { p.Enabled = false }, false},
+		{"pending approval", func(p *models.Printer) { p.ApprovalStatus = models.PrinterApprovalPendingReview }, false},
+		{"suspended", func(p *models.Printer) { p.Breaker.State = models.BreakerSuspended }, false},
+		{"closed with failures", func(p *models.Printer) { p.Breaker.ConsecutiveFailures = 2 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printer := ready()
+			tt.modify(printer)
+			if got := printerAcceptsJobs(printer); got != tt.want {
+				t.Fatalf("printerAcceptsJobs = %v, want %v", got, tt.want)
+			}
+			if tt.name == "suspended" && InitialStatus(printer, "alice") != models.JobStatusQueued {
+				t.Fatal("job for a suspended printer does not start queued")
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/websocket"
+)
+
+func TestDispatchErrorCode(t *testing.T) {
+	tests := []struct {
+		err       error
+		code      string
+		retryable bool
+	}{
+		{websocket.ErrNodeNotConnected, models.DispatchErrorNodeOffline, true},
+		{fmt.Errorf("send: %w", websocket.ErrSendBufferFull), models.DispatchErrorBufferFull, true},
+		{fmt.Errorf("%w: panic: boom", websocket.ErrMarshal), models.DispatchErrorMarshal, false},
+		{errors.New("sign url: storage unavailable"), models.DispatchErrorInternal, true},
+	}
+	for _, tt := range tests {
+		code := dispatchErrorCode(tt.err)
+		if code != tt.code {
+			t.Errorf("dispatchErrorCode(%v) = %q, want %q", tt.err, code, tt.code)
+		}
+		if got := models.DispatchErrorRetryable(code); got != tt.retryable {
+			t.Errorf("DispatchErrorRetryable(%q) = %v, want %v", code, got, tt.retryable)
+		}
+	}
+}
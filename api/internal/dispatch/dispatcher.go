@@ -0,0 +1,492 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/conversion"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/notify"
+	"fly-print-cloud/api/internal/storage"
+	"fly-print-cloud/api/internal/websocket"
+	"fly-print-cloud/api/internal/worker"
+)
+
+// errConversionPending 任务文件尚未转换为打印机支持的格式
+var errConversionPending = errors.New("dispatch: document conversion pending")
+
+// Dispatcher 打印任务分发器：按打印机并发上限在云端排队，并在名额释放时按优先级分发
+type Dispatcher struct {
+	printJobRepo *database.PrintJobRepository
+	printerRepo  *database.PrinterRepository
+	driverRepo   *database.PrinterDriverRepository
+	wsManager    *websocket.ConnectionManager
+	storage      storage.Storage
+	settings     *config.Store          // 云端文件签名链接有效期支持热更新，生成链接时读取
+	power        *worker.PowerScheduler // 休眠中的打印机先唤醒再分发（可为空）
+	notifier     *notify.Notifier
+	jobEventRepo *database.PrintJobEventRepository
+	converter    *conversion.Converter             // 打印机不支持任务文件格式时先转换（未启用时为空）
+	converting   sync.Map                          // 正在转换的任务ID，避免重复转换
+	hours        *database.BusinessHoursRepository // 不在营业时间的打印机不分发排队任务（可为空）
+	eventBus     *events.Bus                       // 打印机熔断时发布告警事件
+}
+
+// NewDispatcher 创建打印任务分发器
+func NewDispatcher(printJobRepo *database.PrintJobRepository, printerRepo *database.PrinterRepository, driverRepo *database.PrinterDriverRepository, wsManager *websocket.ConnectionManager, fileStorage storage.Storage, settings *config.Store, power *worker.PowerScheduler, notifier *notify.Notifier, jobEventRepo *database.PrintJobEventRepository, converter *conversion.Converter, hours *database.BusinessHoursRepository, eventBus *events.Bus) *Dispatcher {
+	return &Dispatcher{
+		printJobRepo: printJobRepo,
+		printerRepo:  printerRepo,
+		driverRepo:   driverRepo,
+		wsManager:    wsManager,
+		storage:      fileStorage,
+		settings:     settings,
+		power:        power,
+		notifier:     notifier,
+		jobEventRepo: jobEventRepo,
+		converter:    converter,
+		hours:        hours,
+		eventBus:     eventBus,
+	}
+}
+
+// InitialStatus 新任务的初始状态：打印机设置了并发上限、被其他用户预留、处于熔断或队列已暂停时先进入排队
+func InitialStatus(printer *models.Printer, userName string) models.JobStatus {
+	if printer != nil && (printer.Breaker.Suspended() || printer.QueuePaused) {
+		return models.JobStatusQueued
+	}
+	if printer != nil && printer.MaxConcurrentJobs != nil && *printer.MaxConcurrentJobs > 0 {
+		return models.JobStatusQueued
+	}
+	if printer != nil && printer.Reservation.Blocks(userName, time.Now().UTC()) {
+		return models.JobStatusQueued
+	}
+	return models.JobStatusPending
+}
+
+// Submit 分发新创建的任务；排队中的任务交由 DispatchQueued 按名额认领
+// 打印机不支持任务文件格式时先在后台转换；打印机处于休眠时先在后台唤醒，就绪后再分发
+func (d *Dispatcher) Submit(job *models.PrintJob, printer *models.Printer) {
+	if !printerAcceptsJobs(printer) {
+		d.holdJob(job, printer)
+		return
+	}
+	if !d.nodeAcceptsJobs(printer) {
+		return
+	}
+	if d.converter.Needed(job, printer) {
+		d.startConversion(job, printer)
+		return
+	}
+	d.wakeOrSubmit(job, printer)
+}
+
+// SubmitCreated 分发刚提交（已入库）的任务，分发过程中的意外错误（panic）不会丢失在日志里：
+// 任务退回初始状态（待分发或排队）并记录下发失败原因和事件，等待重新下发；返回该错误
+func (d *Dispatcher) SubmitCreated(job *models.PrintJob, printer *models.Printer) (err error) {
+	initial := job.Status
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("dispatch: submit job %s panicked: %v", job.ID, r)
+			log.Printf("%v", err)
+			d.dispatchFailed(job, initial, err)
+		}
+	}()
+
+	d.Submit(job, printer)
+	return nil
+}
+
+// wakeOrSubmit 打印机处于休眠时先在后台唤醒，否则直接分发
+func (d *Dispatcher) wakeOrSubmit(job *models.PrintJob, printer *models.Printer) {
+	if d.power != nil && d.power.NeedsWake(printer) {
+		go d.wakeAndSubmit(job, printer)
+		return
+	}
+	d.submit(job, printer)
+}
+
+// startConversion 在后台转换任务文件，同一任务同时只转换一次
+func (d *Dispatcher) startConversion(job *models.PrintJob, printer *models.Printer) {
+	if _, running := d.converting.LoadOrStore(job.ID, struct{}{}); running {
+		return
+	}
+	go d.convertAndSubmit(job, printer)
+}
+
+// convertAndSubmit 转换任务文件后继续分发（含唤醒），转换失败时任务失败
+func (d *Dispatcher) convertAndSubmit(job *models.PrintJob, printer *models.Printer) {
+	defer d.converting.Delete(job.ID)
+
+	result, err := d.converter.Convert(context.Background(), job, printer)
+	if err == nil {
+		// 先记录转换结果（含存储文件），任务在转换期间被取消时文件也能按保留期限清理
+		job.ConvertedStorageKey = result.StorageKey
+		job.ConvertedFormat = string(result.Format)
+		job.ConvertedFileSize = result.Size
+		if err = d.printJobRepo.SetPrintJobConversion(job); err != nil {
+			if deleteErr := d.storage.Delete(context.Background(), result.StorageKey); deleteErr != nil {
+				log.Printf("Failed to delete converted file %s: %v", result.StorageKey, deleteErr)
+			}
+		}
+	}
+
+	// 转换期间任务可能已被取消
+	current, loadErr := d.printJobRepo.GetPrintJobByID(job.ID)
+	if loadErr != nil || current == nil {
+		log.Printf("Failed to reload job %s after conversion: %v", job.ID, loadErr)
+		return
+	}
+	if current.Status != models.JobStatusPending && current.Status != models.JobStatusQueued {
+		log.Printf("Job %s is %s after conversion, skipping dispatch", job.ID, current.Status)
+		return
+	}
+	job = current
+
+	if err != nil {
+		log.Printf("Failed to convert job %s from %s for printer %s: %v", job.ID, job.SourceFormat, printer.ID, err)
+		d.failJob(job, fmt.Sprintf("文档格式转换失败：%v", err), map[string]interface{}{
+			"error_code":    models.JobErrorFormatConversionFailed,
+			"source_format": job.SourceFormat,
+		})
+		return
+	}
+	d.recordEvent(job, models.JobEventConverted, "", map[string]interface{}{
+		"source_format":         job.SourceFormat,
+		"converted_format":      job.ConvertedFormat,
+		"converted_storage_key": job.ConvertedStorageKey,
+		"converted_file_size":   job.ConvertedFileSize,
+	})
+
+	if refreshed, err := d.printerRepo.GetPrinterByIDCached(printer.ID); err == nil && refreshed != nil {
+		printer = refreshed
+	}
+	if !d.acceptsJobs(printer) {
+		return
+	}
+	d.wakeOrSubmit(job, printer)
+}
+
+// wakeAndSubmit 唤醒打印机后分发任务，超时未就绪时任务失败
+func (d *Dispatcher) wakeAndSubmit(job *models.PrintJob, printer *models.Printer) {
+	err := d.power.WakeForJob(printer)
+
+	// 等待期间任务可能已被取消
+	current, loadErr := d.printJobRepo.GetPrintJobByID(job.ID)
+	if loadErr != nil || current == nil {
+		log.Printf("Failed to reload job %s after waking printer: %v", job.ID, loadErr)
+		return
+	}
+	if current.Status != models.JobStatusPending && current.Status != models.JobStatusQueued {
+		log.Printf("Job %s is %s after waking printer %s, skipping dispatch", job.ID, current.Status, printer.ID)
+		return
+	}
+	job = current
+
+	if errors.Is(err, worker.ErrWakeTimeout) {
+		log.Printf("Printer %s did not wake up for job %s within %s", printer.ID, job.ID, d.power.WakeTimeout())
+		d.failJob(job, fmt.Sprintf("打印机未能在 %s 内从休眠中唤醒", d.power.WakeTimeout()), nil)
+		return
+	}
+	if err != nil {
+		// 唤醒指令无法下发（如节点离线）时按常规流程处理
+		log.Printf("Failed to wake printer %s for job %s: %v", printer.ID, job.ID, err)
+	}
+
+	if refreshed, err := d.printerRepo.GetPrinterByIDCached(printer.ID); err == nil {
+		printer = refreshed
+	}
+	d.submit(job, printer)
+}
+
+// failJob 将尚未分发的任务标记为失败并通知提交用户，details 记录到时间线事件（如错误码）
+func (d *Dispatcher) failJob(job *models.PrintJob, reason string, details map[string]interface{}) {
+	job.Status = models.JobStatusFailed
+	job.ErrorMessage = reason
+	now := time.Now().UTC()
+	job.EndTime = &now
+	if err := d.printJobRepo.UpdatePrintJob(job); err != nil {
+		log.Printf("Failed to mark job %s as failed: %v", job.ID, err)
+		return
+	}
+	d.recordEvent(job, models.JobEventFailed, reason, details)
+	if d.notifier != nil {
+		d.notifier.JobFinished(job.ID)
+	}
+}
+
+// acceptsJobs 打印机是否可接收新任务：打印机本身已启用、已审批、未熔断且队列未暂停，所属的 Edge Node 可接收任务
+func (d *Dispatcher) acceptsJobs(printer *models.Printer) bool {
+	if !printerAcceptsJobs(printer) {
+		log.Printf("Printer %s is disabled, unapproved, suspended or paused, skipping dispatch", printer.ID)
+		return false
+	}
+	return d.nodeAcceptsJobs(printer)
+}
+
+// printerAcceptsJobs 打印机本身是否可接收新任务（已启用、已审批、未熔断且队列未暂停）
+func printerAcceptsJobs(printer *models.Printer) bool {
+	return printer.Enabled && printer.ApprovalStatus == models.PrinterApprovalApproved && !printer.Breaker.Suspended() && !printer.QueuePaused
+}
+
+// holdJob 打印机已禁用、熔断或队列暂停时，待分发的任务转入排队，重新启用或恢复时由 DispatchQueued 认领
+func (d *Dispatcher) holdJob(job *models.PrintJob, printer *models.Printer) {
+	log.Printf("Printer %s is disabled, unapproved, suspended or paused, holding job %s", printer.ID, job.ID)
+	if job.Status != models.JobStatusPending {
+		return
+	}
+	job.Status = models.JobStatusQueued
+	if err := d.printJobRepo.UpdatePrintJob(job); err != nil {
+		log.Printf("Failed to queue job %s for unavailable printer %s: %v", job.ID, printer.ID, err)
+	}
+}
+
+// nodeAcceptsJobs 打印机所属的 Edge Node 是否可接收新任务；已禁用、已删除或排空中的节点不分发，任务保持待分发/排队
+func (d *Dispatcher) nodeAcceptsJobs(printer *models.Printer) bool {
+	active, err := d.printerRepo.IsEdgeNodeActiveCached(printer.EdgeNodeID)
+	if err != nil {
+		log.Printf("Failed to check edge node %s for printer %s: %v", printer.EdgeNodeID, printer.ID, err)
+		return false
+	}
+	if !active {
+		log.Printf("Edge node %s of printer %s is disabled or draining, skipping dispatch", printer.EdgeNodeID, printer.ID)
+	}
+	return active
+}
+
+// submit 标记为已分发并下发任务
+func (d *Dispatcher) submit(job *models.PrintJob, printer *models.Printer) {
+	// 唤醒期间打印机可能已被禁用、熔断或暂停
+	if !printerAcceptsJobs(printer) {
+		d.holdJob(job, printer)
+		return
+	}
+	// 唤醒期间节点可能已开始排空
+	if !d.nodeAcceptsJobs(printer) {
+		return
+	}
+	if job.Status == models.JobStatusQueued {
+		d.DispatchQueued(printer)
+		return
+	}
+
+	// 先标记为已分发再下发，保证节点随后上报的状态（按服务端接收时间排序）不会被覆盖
+	job.Status = models.JobStatusDispatched
+	job.DispatchError = ""
+	if err := d.printJobRepo.UpdatePrintJob(job); err != nil {
+		log.Printf("Failed to update job status to dispatched: %v", err)
+		return
+	}
+
+	if err := d.send(job, printer); err != nil {
+		d.dispatchFailed(job, models.JobStatusPending, err)
+		return
+	}
+	d.recordEvent(job, models.JobEventDispatched, "", map[string]interface{}{"edge_node_id": printer.EdgeNodeID})
+}
+
+// DispatchQueued 认领打印机空闲名额内的排队任务并下发；打印机不在营业时间时不认领，开始营业后由 BusinessHoursOpener 触发
+func (d *Dispatcher) DispatchQueued(printer *models.Printer) {
+	if !d.acceptsJobs(printer) || !d.printerOpen(printer) {
+		return
+	}
+	jobs, err := d.printJobRepo.ClaimQueuedJobs(printer.ID)
+	if err != nil {
+		log.Printf("Failed to claim queued jobs for printer %s: %v", printer.ID, err)
+		return
+	}
+
+	for _, job := range jobs {
+		if err := d.send(job, printer); err != nil {
+			if errors.Is(err, errConversionPending) {
+				// 尚未转换的任务放回队列，转换完成后重新认领
+				job.Status = models.JobStatusQueued
+				if updateErr := d.printJobRepo.UpdatePrintJob(job); updateErr != nil {
+					log.Printf("Failed to requeue job %s: %v", job.ID, updateErr)
+				}
+				d.startConversion(job, printer)
+				continue
+			}
+			// 下发失败时放回队列，等待下一次名额释放
+			d.dispatchFailed(job, models.JobStatusQueued, err)
+			continue
+		}
+		d.recordEvent(job, models.JobEventDispatched, "", map[string]interface{}{"edge_node_id": printer.EdgeNodeID})
+	}
+}
+
+// printerOpen 打印机当前是否在营业时间内；查询失败时按营业处理，避免任务被无限期搁置
+func (d *Dispatcher) printerOpen(printer *models.Printer) bool {
+	if d.hours == nil {
+		return true
+	}
+	hours, err := d.hours.GetEffectiveBusinessHours(printer.ID, printer.EdgeNodeID)
+	if err != nil {
+		log.Printf("Failed to get business hours for printer %s: %v", printer.ID, err)
+		return true
+	}
+	return hours.OpenAt(time.Now())
+}
+
+// dispatchErrorCode 下发失败的原因分类
+func dispatchErrorCode(err error) string {
+	switch {
+	case errors.Is(err, websocket.ErrNodeNotConnected):
+		return models.DispatchErrorNodeOffline
+	case errors.Is(err, websocket.ErrSendBufferFull):
+		return models.DispatchErrorBufferFull
+	case errors.Is(err, websocket.ErrMarshal):
+		return models.DispatchErrorMarshal
+	default:
+		return models.DispatchErrorInternal
+	}
+}
+
+// dispatchFailed 记录下发失败原因：可重试的失败将任务退回 fallback 状态（待分发或排队）等待重新下发，
+// 无法通过重试恢复的失败（任务无法编码为指令）直接将任务标记为失败
+func (d *Dispatcher) dispatchFailed(job *models.PrintJob, fallback models.JobStatus, err error) {
+	job.DispatchError = dispatchErrorCode(err)
+	details := map[string]interface{}{"error_code": job.DispatchError}
+	if !models.DispatchErrorRetryable(job.DispatchError) {
+		d.failJob(job, fmt.Sprintf("任务无法下发：%v", err), details)
+		return
+	}
+
+	job.Status = fallback
+	if updateErr := d.printJobRepo.UpdatePrintJob(job); updateErr != nil {
+		log.Printf("Failed to revert job %s to %s: %v", job.ID, fallback, updateErr)
+	}
+	d.recordEvent(job, models.JobEventDispatchFailed, err.Error(), details)
+}
+
+// recordEvent 记录分发相关的任务时间线事件（失败只记录日志）
+func (d *Dispatcher) recordEvent(job *models.PrintJob, eventType models.JobEventType, message string, details map[string]interface{}) {
+	d.jobEventRepo.Record(&models.PrintJobEvent{
+		JobID:   job.ID,
+		Type:    eventType,
+		Status:  job.Status,
+		Actor:   models.JobEventActorSystem,
+		Message: message,
+		Details: details,
+	})
+}
+
+// JobFinished 任务结束（完成/失败/取消）后释放名额，继续分发该打印机的排队任务
+func (d *Dispatcher) JobFinished(jobID string) {
+	d.finished(jobID, false)
+}
+
+// JobReported Edge Node 上报任务结束：完成/失败计入打印机熔断器，再释放名额；管理员修改或取消的任务不计入
+func (d *Dispatcher) JobReported(jobID string) {
+	d.finished(jobID, true)
+}
+
+// finished 释放任务占用的名额并分发排队任务，countOutcome 时先将任务结果计入打印机熔断器
+func (d *Dispatcher) finished(jobID string, countOutcome bool) {
+	job, err := d.printJobRepo.GetPrintJobByID(jobID)
+	if err != nil || job == nil {
+		log.Printf("Failed to load job %s for queue dispatch: %v", jobID, err)
+		return
+	}
+
+	if countOutcome {
+		switch job.Status {
+		case models.JobStatusCompleted:
+			d.recordSuccess(job.PrinterID)
+		case models.JobStatusFailed, models.JobStatusPartiallyCompleted: // 中途停止（如卡纸）同样计为打印机故障
+			d.recordFailure(job, job.PrinterID, job.ErrorMessage)
+		}
+	}
+
+	printer, err := d.printerRepo.GetPrinterByIDCached(job.PrinterID)
+	if err != nil || printer == nil {
+		return
+	}
+
+	d.DispatchQueued(printer)
+}
+
+// send 解析驱动并通过 WebSocket 下发任务
+func (d *Dispatcher) send(job *models.PrintJob, printer *models.Printer) error {
+	// 排队任务可能在转换完成前被认领
+	if d.converter.Needed(job, printer) {
+		return errConversionPending
+	}
+
+	// 按打印机型号解析驱动选项，随任务一起下发
+	var driver *models.PrinterDriver
+	if d.driverRepo != nil {
+		resolved, err := d.driverRepo.FindDriverForModel(printer.Model)
+		if err != nil {
+			log.Printf("Failed to resolve driver for printer %s (model %s): %v", printer.ID, printer.Model, err)
+		} else {
+			driver = resolved
+		}
+	}
+
+	files, err := d.printJobRepo.ListPrintJobFiles(job.ID)
+	if err != nil {
+		log.Printf("Failed to load files for job %s: %v", job.ID, err)
+		return err
+	}
+
+	if job, err = d.dispatchCopy(job, files); err != nil {
+		return err
+	}
+
+	result, err := d.wsManager.DispatchPrintJob(printer.EdgeNodeID, job, printer.Name, driver)
+	if err != nil {
+		log.Printf("Failed to dispatch print job %s to node %s (%s): %v", job.ID, printer.EdgeNodeID, dispatchErrorCode(err), err)
+		return err
+	}
+
+	log.Printf("Print job %s dispatched to node %s (command %s at %s)", job.ID, printer.EdgeNodeID, result.CommandID, result.DispatchedAt.Format(time.RFC3339))
+	return nil
+}
+
+// dispatchCopy 生成下发给 Edge Node 的任务副本（不持久化）：已转换格式的任务下发转换结果，
+// 任务记录中的原文件保持不变；云端存储的文件生成短期签名链接
+func (d *Dispatcher) dispatchCopy(job *models.PrintJob, files []models.PrintJobFile) (*models.PrintJob, error) {
+	dispatched := *job
+	dispatched.Files = files
+	if job.ConvertedStorageKey != "" {
+		dispatched.StorageKey = job.ConvertedStorageKey
+		dispatched.FileSize = job.ConvertedFileSize
+		for i := range dispatched.Files {
+			if dispatched.Files[i].StorageKey == job.StorageKey {
+				dispatched.Files[i].StorageKey = job.ConvertedStorageKey
+				dispatched.Files[i].FileSize = job.ConvertedFileSize
+			}
+		}
+	}
+
+	// 旧版 Edge Node 只读取顶层 file_url，必须与下发的 storage_key（转换结果）一致
+	var err error
+	if dispatched.FileURL, err = d.fileURL(dispatched.StorageKey, job.FileURL); err != nil {
+		log.Printf("Failed to sign file url for job %s: %v", job.ID, err)
+		return nil, err
+	}
+	for i := range dispatched.Files {
+		file := &dispatched.Files[i]
+		if file.FileURL, err = d.fileURL(file.StorageKey, file.FileURL); err != nil {
+			log.Printf("Failed to sign file url for job %s file %d: %v", job.ID, file.Position, err)
+			return nil, err
+		}
+	}
+	return &dispatched, nil
+}
+
+// fileURL 云端存储的文件返回短期签名链接，否则返回原始 URL
+func (d *Dispatcher) fileURL(storageKey, fileURL string) (string, error) {
+	if storageKey == "" || d.storage == nil {
+		return fileURL, nil
+	}
+	return d.storage.SignedURL(context.Background(), storageKey, d.settings.Get().Storage.SignedURLTTL)
+}
@@ -0,0 +1,88 @@
+package dispatch
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/models"
+)
+
+// signingStorage 只实现签名链接，链接中包含对象键便于断言
+type signingStorage struct{}
+
+func (signingStorage) Put(context.Context, string, io.Reader, int64, string) error { return nil }
+func (signingStorage) Get(context.Context, string) (io.ReadCloser, error)          { return nil, nil }
+func (signingStorage) Delete(context.Context, string) error                        { return nil }
+func (signingStorage) SignedURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://files.example.com/" + key + "?sig=x", nil
+}
+
+func newTestDispatcher() *Dispatcher {
+	cfg := &config.Config{}
+	cfg.Storage.SignedURLTTL = time.Minute
+	return &Dispatcher{storage: signingStorage{}, settings: config.NewStore(cfg)}
+}
+
+func TestDispatchCopyConvertedFileURLMatchesStorageKey(t *testing.T) {
+	d := newTestDispatcher()
+	job := &models.PrintJob{
+		ID:                  "job-1",
+		StorageKey:          "uploads/report.docx",
+		FileSize:            2048,
+		ConvertedStorageKey: "converted/report.pdf",
+		ConvertedFileSize:   4096,
+	}
+	files := []models.PrintJobFile{{Position: 1, StorageKey: job.StorageKey, FileSize: job.FileSize}}
+
+	dispatched, err := d.dispatchCopy(job, files)
+	if err != nil {
+		t.Fatalf("dispatchCopy: %v", err)
+	}
+
+	if dispatched.StorageKey != job.ConvertedStorageKey {
+		t.Errorf("storage_key = %q, want converted key %q", dispatched.StorageKey, job.ConvertedStorageKey)
+	}
+	if want := "https://files.example.com/" + dispatched.StorageKey + "?sig=x"; dispatched.FileURL != want {
+		t.Errorf("file_url = %q, want link for dispatched storage_key %q", dispatched.FileURL, want)
+	}
+	if dispatched.FileSize != job.ConvertedFileSize {
+		t.Errorf("file_size = %d, want %d", dispatched.FileSize, job.ConvertedFileSize)
+	}
+	if got := dispatched.Files[0]; got.StorageKey != job.ConvertedStorageKey || got.FileURL != dispatched.FileURL {
+		t.Errorf("files[0] = {%q, %q}, want converted key and matching url", got.StorageKey, got.FileURL)
+	}
+
+	// 任务记录中的原文件保持不变
+	if job.StorageKey != "uploads/report.docx" || job.FileURL != "" {
+		t.Errorf("original job modified: storage_key=%q file_url=%q", job.StorageKey, job.FileURL)
+	}
+}
+
+func TestDispatchCopyWithoutConversionKeepsOriginal(t *testing.T) {
+	d := newTestDispatcher()
+	job := &models.PrintJob{ID: "job-2", StorageKey: "uploads/a.pdf"}
+
+	dispatched, err := d.dispatchCopy(job, nil)
+	if err != nil {
+		t.Fatalf("dispatchCopy: %v", err)
+	}
+	if dispatched.StorageKey != "uploads/a.pdf" || dispatched.FileURL != "https://files.example.com/uploads/a.pdf?sig=x" {
+		t.Errorf("got storage_key=%q file_url=%q", dispatched.StorageKey, dispatched.FileURL)
+	}
+}
+
+func TestDispatchCopyExternalURLUnsigned(t *testing.T) {
+	d := newTestDispatcher()
+	job := &models.PrintJob{ID: "job-3", FileURL: "https://example.org/doc.pdf"}
+
+	dispatched, err := d.dispatchCopy(job, nil)
+	if err != nil {
+		t.Fatalf("dispatchCopy: %v", err)
+	}
+	if dispatched.FileURL != job.FileURL {
+		t.Errorf("file_url = %q, want %q", dispatched.FileURL, job.FileURL)
+	}
+}
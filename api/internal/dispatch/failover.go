@@ -0,0 +1,151 @@
+package dispatch
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/printing"
+)
+
+// HandleJobFailure 节点上报任务失败后按故障转移设置处理，任务已重新分发时返回 true（不通知提交用户任务失败）
+// 仅对设置了备用打印机的打印机生效，且错误码属于 jobs.failover_error_codes、任务尚未输出任何页面：
+// 在同一打印机上失败未达到 jobs.failover_after 次时在原打印机重试，达到后改投备用打印机（重新校验能力）并标记原打印机待处理
+// 每个任务最多转移一次，转移后在备用打印机上失败达到次数时保持失败
+func (d *Dispatcher) HandleJobFailure(jobID, errorCode string) bool {
+	settings := d.settings.Get().Jobs
+	if !isHardwareError(settings.FailoverErrorCodes, errorCode) {
+		return false
+	}
+
+	job, err := d.printJobRepo.GetPrintJobByID(jobID)
+	if err != nil || job == nil {
+		log.Printf("Failed to load job %s for failover: %v", jobID, err)
+		return false
+	}
+	if job.Status != models.JobStatusFailed || job.PrinterID == "" {
+		return false
+	}
+	if job.PagesPrinted > 0 {
+		log.Printf("Job %s already printed %d pages, not retrying after %s", job.ID, job.PagesPrinted, errorCode)
+		return false
+	}
+
+	printer, err := d.printerRepo.GetPrinterByIDCached(job.PrinterID)
+	if err != nil || printer == nil || printer.BackupPrinterID == nil {
+		return false
+	}
+
+	failures := job.RetryCount + 1
+	details := map[string]interface{}{"error_code": errorCode, "failures": failures}
+	if failures < settings.FailoverAfter {
+		job.RetryCount = failures
+		// 重试的任务不会经过 JobFinished，失败在重试前计入打印机熔断器；本次失败触发熔断时任务进入排队
+		if breaker, ok := d.recordFailure(job, printer.ID, job.ErrorMessage); ok {
+			printer.Breaker = breaker
+		}
+		return d.requeue(job, printer, models.JobEventRequeued,
+			fmt.Sprintf("打印机硬件错误（%s），在原打印机重试（第 %d 次）", errorCode, failures), details)
+	}
+	if job.FailedOverFrom != "" {
+		log.Printf("Job %s already failed over from printer %s, leaving it failed", job.ID, job.FailedOverFrom)
+		return false
+	}
+
+	d.flagAttention(printer, fmt.Sprintf("任务 %s 因硬件错误（%s）连续失败 %d 次", job.ID, errorCode, failures))
+
+	backup, reason := d.failoverTarget(job, printer)
+	if backup == nil {
+		log.Printf("Job %s cannot fail over from printer %s: %s", job.ID, printer.ID, reason)
+		details["backup_printer_id"] = *printer.BackupPrinterID
+		d.recordEvent(job, models.JobEventFailoverSkipped, reason, details)
+		return false
+	}
+
+	failureMessage := job.ErrorMessage
+	details["from_printer_id"] = printer.ID
+	details["to_printer_id"] = backup.ID
+	job.FailedOverFrom = printer.ID
+	job.PrinterID = backup.ID
+	job.RetryCount = 0
+	if !d.requeue(job, backup, models.JobEventFailover,
+		fmt.Sprintf("打印机 %s 硬件错误（%s），改投备用打印机 %s", printer.Name, errorCode, backup.Name), details) {
+		return false
+	}
+
+	// 原打印机释放了名额，继续分发其排队任务
+	d.recordFailure(job, printer.ID, failureMessage)
+	d.DispatchQueued(printer)
+	return true
+}
+
+// failoverTarget 返回可接收该任务的备用打印机，不可用时返回原因
+func (d *Dispatcher) failoverTarget(job *models.PrintJob, printer *models.Printer) (*models.Printer, string) {
+	backup, err := d.printerRepo.GetPrinterByIDCached(*printer.BackupPrinterID)
+	if err != nil || backup == nil {
+		return nil, "备用打印机不存在"
+	}
+	if backup.ID == printer.ID {
+		return nil, "备用打印机不能是打印机自身"
+	}
+	if !backup.Enabled || backup.ApprovalStatus != models.PrinterApprovalApproved {
+		return nil, fmt.Sprintf("备用打印机 %s 未启用", backup.Name)
+	}
+	if backup.Breaker.Suspended() {
+		return nil, fmt.Sprintf("备用打印机 %s 已熔断", backup.Name)
+	}
+	if backup.QueuePaused {
+		return nil, fmt.Sprintf("备用打印机 %s 的队列已暂停", backup.Name)
+	}
+	if !d.nodeAcceptsJobs(backup) {
+		return nil, fmt.Sprintf("备用打印机 %s 所属的 Edge Node 已禁用或排空中", backup.Name)
+	}
+	if violations := printing.NewValidator(d.settings).Validate(job, backup); len(violations) > 0 {
+		return nil, "备用打印机不支持该任务：" + violations.Summary()
+	}
+	return backup, ""
+}
+
+// requeue 将失败的任务退回待分发/排队并重新分发，记录时间线事件
+func (d *Dispatcher) requeue(job *models.PrintJob, printer *models.Printer, eventType models.JobEventType, message string, details map[string]interface{}) bool {
+	job.Status = InitialStatus(printer, job.UserName)
+	requeued, err := d.printJobRepo.RequeueFailedJob(job)
+	if err != nil {
+		log.Printf("Failed to requeue job %s: %v", job.ID, err)
+		return false
+	}
+	if !requeued {
+		log.Printf("Job %s is no longer failed, skipping requeue", job.ID)
+		return false
+	}
+	log.Printf("Job %s requeued on printer %s (%s)", job.ID, printer.ID, eventType)
+	d.recordEvent(job, eventType, message, details)
+
+	if err := d.SubmitCreated(job, printer); err != nil {
+		log.Printf("Failed to dispatch requeued job %s: %v", job.ID, err)
+	}
+	return true
+}
+
+// flagAttention 标记打印机待处理（失败只记录日志）
+func (d *Dispatcher) flagAttention(printer *models.Printer, reason string) {
+	if err := d.printerRepo.FlagPrinterAttention(printer.ID, reason, time.Now().UTC()); err != nil {
+		log.Printf("Failed to flag printer %s for attention: %v", printer.ID, err)
+	}
+}
+
+// isHardwareError 错误码是否属于配置的硬件故障错误码（忽略大小写）
+func isHardwareError(codes []string, errorCode string) bool {
+	errorCode = strings.TrimSpace(errorCode)
+	if errorCode == "" {
+		return false
+	}
+	for _, code := range codes {
+		if strings.EqualFold(code, errorCode) {
+			return true
+		}
+	}
+	return false
+}
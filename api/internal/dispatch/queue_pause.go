@@ -0,0 +1,71 @@
+package dispatch
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/websocket"
+)
+
+// PauseQueue 管理员暂停打印机队列：停止分发新任务（已下发的任务继续打印），并通知 Edge Node 暂停本地队列
+// 超过 jobs.max_queue_pause 后自动恢复；已暂停时返回 false
+func (d *Dispatcher) PauseQueue(printer *models.Printer, pausedBy, reason string) (bool, error) {
+	now := time.Now().UTC()
+	var autoResumeAt *time.Time
+	if maxPause := d.settings.Get().Jobs.MaxQueuePause; maxPause > 0 {
+		at := now.Add(maxPause)
+		autoResumeAt = &at
+	}
+
+	paused, err := d.printerRepo.PausePrinterQueue(printer.ID, pausedBy, reason, now, autoResumeAt)
+	if err != nil || !paused {
+		return paused, err
+	}
+	printer.QueuePaused = true
+	printer.QueuePausedBy = pausedBy
+	printer.QueuePausedAt = &now
+	printer.QueuePauseReason = reason
+	printer.QueueAutoResumeAt = autoResumeAt
+
+	log.Printf("Printer %s queue paused by %s", printer.ID, pausedBy)
+	d.sendQueueCommand(printer, websocket.CmdTypePauseQueue, reason)
+	return true, nil
+}
+
+// ResumeQueue 管理员恢复打印机队列并分发暂停期间排队的任务；未暂停时返回 false
+func (d *Dispatcher) ResumeQueue(printer *models.Printer) (bool, error) {
+	resumed, err := d.printerRepo.ResumePrinterQueue(printer.ID)
+	if err != nil || !resumed {
+		return resumed, err
+	}
+	printer.QueuePaused = false
+	printer.QueuePausedBy = ""
+	printer.QueuePausedAt = nil
+	printer.QueuePauseReason = ""
+	printer.QueueAutoResumeAt = nil
+
+	log.Printf("Printer %s queue resumed manually", printer.ID)
+	d.QueueResumed(printer)
+	return true, nil
+}
+
+// QueueResumed 队列已恢复（手动或到达自动恢复时间）：通知 Edge Node 恢复本地队列，并分发排队任务
+func (d *Dispatcher) QueueResumed(printer *models.Printer) {
+	d.sendQueueCommand(printer, websocket.CmdTypeResumeQueue, "")
+	d.DispatchQueued(printer)
+}
+
+// sendQueueCommand 向打印机所属的 Edge Node 发送队列暂停/恢复指令
+// 节点离线时忽略：重连时会重新下发 pause_queue，未收到的 resume_queue 以重连时未暂停为准
+func (d *Dispatcher) sendQueueCommand(printer *models.Printer, cmdType, reason string) {
+	err := d.wsManager.SendPrinterQueue(printer.EdgeNodeID, cmdType, websocket.PrinterQueueData{
+		PrinterID:   printer.ID,
+		PrinterName: printer.Name,
+		Reason:      reason,
+	})
+	if err != nil && !errors.Is(err, websocket.ErrNodeNotConnected) {
+		log.Printf("Failed to send %s for printer %s to node %s: %v", cmdType, printer.ID, printer.EdgeNodeID, err)
+	}
+}
@@ -0,0 +1,123 @@
+package docformat
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format 文件格式
+type Format string
+
+const (
+	FormatPDF        Format = "pdf"
+	FormatPostScript Format = "postscript"
+	FormatPCL        Format = "pcl"
+	FormatPNG        Format = "png"
+	FormatJPEG       Format = "jpeg"
+	FormatTIFF       Format = "tiff"
+	FormatZIP        Format = "zip" // Office Open XML 等基于 ZIP 的文档
+	FormatText       Format = "text"
+	FormatUnknown    Format = "unknown"
+)
+
+var (
+	// ErrUnsupportedFormat 文件格式不在允许列表中
+	ErrUnsupportedFormat = errors.New("docformat: unsupported file format")
+	// ErrCorruptPDF PDF 结构损坏，无法确定页数
+	ErrCorruptPDF = errors.New("docformat: corrupt pdf")
+)
+
+// sniffLen 格式检测读取的头部长度
+const sniffLen = 512
+
+// Detect 根据文件头部的魔数检测文件格式
+func Detect(data []byte) Format {
+	head := data
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+
+	switch {
+	case hasPDFHeader(head):
+		return FormatPDF
+	case bytes.HasPrefix(head, []byte("%!PS")), bytes.HasPrefix(head, []byte("\x04%!PS")):
+		return FormatPostScript
+	case bytes.HasPrefix(head, []byte("\x1b%-12345X")), bytes.HasPrefix(head, []byte("\x1bE")):
+		return FormatPCL
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG
+	case bytes.HasPrefix(head, []byte("\xff\xd8\xff")):
+		return FormatJPEG
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return FormatTIFF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return FormatZIP
+	case isText(head):
+		return FormatText
+	}
+	return FormatUnknown
+}
+
+// hasPDFHeader PDF 规范允许 %PDF- 出现在前 1024 字节内，这里只检查检测窗口
+func hasPDFHeader(head []byte) bool {
+	return bytes.Contains(head, []byte("%PDF-"))
+}
+
+// isText 判断是否为纯文本（合法 UTF-8 且不含 NUL 等二进制控制字符）
+func isText(head []byte) bool {
+	if len(head) == 0 {
+		return false
+	}
+	// 截断处可能切开多字节字符
+	for i := 0; i < utf8.UTFMax && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	if !utf8.Valid(head) {
+		return false
+	}
+	for _, b := range head {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' {
+			return false
+		}
+	}
+	return true
+}
+
+// Allowed 判断格式是否在允许列表中（忽略大小写），列表为空时全部允许
+func Allowed(format Format, allowList []string) bool {
+	if len(allowList) == 0 {
+		return true
+	}
+	for _, allowed := range allowList {
+		if strings.EqualFold(strings.TrimSpace(allowed), string(format)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Info 文件检测结果
+type Info struct {
+	Format Format
+	Pages  int    // 仅 PDF 有效，其他格式为 0
+	Title  string // PDF 文档标题，Inspect 不读取，由调用方按需用 PDFTitle 填充
+}
+
+// Inspect 检测文件格式并校验允许列表；PDF 会计算实际页数，结构损坏时返回 ErrCorruptPDF
+func Inspect(data []byte, allowList []string) (Info, error) {
+	info := Info{Format: Detect(data)}
+	if !Allowed(info.Format, allowList) {
+		return info, ErrUnsupportedFormat
+	}
+
+	if info.Format == FormatPDF {
+		pages, err := CountPDFPages(data)
+		if err != nil {
+			return info, err
+		}
+		info.Pages = pages
+	}
+	return info, nil
+}
@@ -0,0 +1,82 @@
+package docformat
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Format
+	}{
+		{"pdf", "%PDF-1.7\n", FormatPDF},
+		{"pdf after preamble", "\x00\x00junk%PDF-1.4", FormatPDF},
+		{"postscript", "%!PS-Adobe-3.0", FormatPostScript},
+		{"postscript ctrl-d", "\x04%!PS-Adobe-3.0", FormatPostScript},
+		{"pcl pjl", "\x1b%-12345X@PJL", FormatPCL},
+		{"pcl reset", "\x1bE\x1b&l0O", FormatPCL},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00", FormatPNG},
+		{"jpeg", "\xff\xd8\xff\xe0", FormatJPEG},
+		{"tiff little endian", "II*\x00", FormatTIFF},
+		{"tiff big endian", "MM\x00*", FormatTIFF},
+		{"zip", "PK\x03\x04", FormatZIP},
+		{"text", "hello, printer\n", FormatText},
+		{"utf-8 text", "你好\n", FormatText},
+		{"binary", "\x00\x01\x02\x03", FormatUnknown},
+		{"empty", "", FormatUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect([]byte(tt.data)); got != tt.want {
+				t.Fatalf("Detect = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDetectTruncatedMultibyte 检测窗口切开多字节字符时仍识别为文本
+func TestDetectTruncatedMultibyte(t *testing.T) {
+	data := make([]byte, 0, sniffLen+3)
+	for len(data) < sniffLen-1 {
+		data = append(data, 'a')
+	}
+	data = append(data, "中"...)
+	if got := Detect(data); got != FormatText {
+		t.Fatalf("Detect = %s, want text", got)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	info, err := Inspect(buildPDF(4), []string{"pdf"})
+	if err != nil || info.Format != FormatPDF || info.Pages != 4 {
+		t.Fatalf("Inspect pdf = %+v, %v", info, err)
+	}
+
+	if _, err := Inspect([]byte("plain text"), []string{" PDF ", "postscript"}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("text with pdf-only allow list: err = %v, want ErrUnsupportedFormat", err)
+	}
+	if info, err := Inspect([]byte("plain text"), nil); err != nil || info.Format != FormatText || info.Pages != 0 {
+		t.Fatalf("empty allow list = %+v, %v", info, err)
+	}
+	if _, err := Inspect([]byte("%PDF-1.4 truncated"), nil); !errors.Is(err, ErrCorruptPDF) {
+		t.Fatalf("corrupt pdf: err = %v, want ErrCorruptPDF", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]Format{
+		"pdf":                             FormatPDF,
+		"application/pdf; charset=binary": FormatPDF,
+		" Image/JPG ":                     FormatJPEG,
+		"application/vnd.cups-postscript": FormatPostScript,
+		"application/x-unknown":           FormatUnknown,
+	}
+	for input, want := range tests {
+		if got := Parse(input); got != want {
+			t.Errorf("Parse(%q) = %s, want %s", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package docformat
+
+import "testing"
+
+// FuzzInspect 上传、邮件打印和共享文件夹的文件都经过 Inspect，任意输入都不能 panic
+// 种子语料位于 testdata/fuzz/FuzzInspect；运行：go test -fuzz=FuzzInspect -fuzztime=30s ./internal/docformat
+func FuzzInspect(f *testing.F) {
+	f.Add(buildPDF(1))
+	f.Add(buildPDF(3))
+	f.Add(buildObjStmPDF(2))
+	f.Add([]byte("%PDF-1.5\n10 0 obj\n<< /Type /ObjStm /N 1 /First 5 /Length 9 >>\nstream\n1 -9 abcd\nendstream\nendobj\n%%EOF\n"))
+	f.Add([]byte("%!PS-Adobe-3.0"))
+	f.Add([]byte("hello"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := Inspect(data, nil)
+		if err == nil && info.Format == FormatPDF && info.Pages <= 0 {
+			t.Fatalf("pdf accepted with %d pages", info.Pages)
+		}
+		PDFTitle(data)
+	})
+}
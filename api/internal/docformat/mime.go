@@ -0,0 +1,70 @@
+package docformat
+
+import "strings"
+
+// contentTypes 格式对应的 MIME 类型
+var contentTypes = map[Format]string{
+	FormatPDF:        "application/pdf",
+	FormatPostScript: "application/postscript",
+	FormatPCL:        "application/vnd.hp-pcl",
+	FormatPNG:        "image/png",
+	FormatJPEG:       "image/jpeg",
+	FormatTIFF:       "image/tiff",
+	FormatZIP:        "application/zip",
+	FormatText:       "text/plain",
+}
+
+// extensions 格式对应的文件扩展名
+var extensions = map[Format]string{
+	FormatPDF:        ".pdf",
+	FormatPostScript: ".ps",
+	FormatPCL:        ".pcl",
+	FormatPNG:        ".png",
+	FormatJPEG:       ".jpg",
+	FormatTIFF:       ".tif",
+	FormatZIP:        ".zip",
+	FormatText:       ".txt",
+}
+
+// mimeAliases 打印机上报的其他常见 MIME 类型
+var mimeAliases = map[string]Format{
+	"application/vnd.cups-postscript": FormatPostScript,
+	"application/vnd.hp-pclxl":        FormatPCL,
+	"image/jpg":                       FormatJPEG,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   FormatZIP,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         FormatZIP,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": FormatZIP,
+}
+
+// ContentType 格式对应的 MIME 类型，未知格式返回 application/octet-stream
+func (f Format) ContentType() string {
+	if contentType, ok := contentTypes[f]; ok {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// Extension 格式对应的文件扩展名，未知格式返回空
+func (f Format) Extension() string {
+	return extensions[f]
+}
+
+// Parse 解析格式名或 MIME 类型（忽略大小写和参数，如 pdf、application/pdf），无法识别时返回 FormatUnknown
+func Parse(s string) Format {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.Index(s, ";"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if _, ok := contentTypes[Format(s)]; ok {
+		return Format(s)
+	}
+	for format, contentType := range contentTypes {
+		if s == contentType {
+			return format
+		}
+	}
+	if format, ok := mimeAliases[s]; ok {
+		return format
+	}
+	return FormatUnknown
+}
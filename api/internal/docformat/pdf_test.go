@@ -0,0 +1,131 @@
+package docformat
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF 生成 pages 页的最小 PDF（页面树为普通对象）
+func buildPDF(pages int) []byte {
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	kids := make([]string, pages)
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", i+3)
+	}
+	fmt.Fprintf(&b, "2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), pages)
+	for i := 0; i < pages; i++ {
+		fmt.Fprintf(&b, "%d 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>\nendobj\n", i+3)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return []byte(b.String())
+}
+
+// objectStream 生成包含 members 的对象流对象（对象号从 2 开始），compress 时使用 FlateDecode
+func objectStream(members []string, compress bool) string {
+	var header, body strings.Builder
+	for i, member := range members {
+		fmt.Fprintf(&header, "%d %d ", i+2, body.Len())
+		body.WriteString(member)
+		body.WriteString("\n")
+	}
+	raw := header.String() + body.String()
+	first := header.Len()
+
+	filter := ""
+	if compress {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		w.Write([]byte(raw))
+		w.Close()
+		raw = buf.String()
+		filter = " /Filter /FlateDecode"
+	}
+	return fmt.Sprintf("10 0 obj\n<< /Type /ObjStm /N %d /First %d /Length %d%s >>\nstream\n%s\nendstream\nendobj\n",
+		len(members), first, len(raw), filter, raw)
+}
+
+// buildObjStmPDF 生成页面树位于压缩对象流中的 PDF（PDF 1.5+）
+func buildObjStmPDF(pages int) []byte {
+	members := []string{fmt.Sprintf("<< /Type /Pages /Kids [] /Count %d >>", pages)}
+	for i := 0; i < pages; i++ {
+		members = append(members, "<< /Type /Page /Parent 2 0 R >>")
+	}
+	return []byte("%PDF-1.5\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n" +
+		objectStream(members, true) + "trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+}
+
+func TestCountPDFPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    int
+		wantErr error
+	}{
+		{"single page", buildPDF(1), 1, nil},
+		{"page tree count", buildPDF(7), 7, nil},
+		{"compressed object stream", buildObjStmPDF(5), 5, nil},
+		{"leaf pages without tree", []byte("%PDF-1.4\n3 0 obj\n<< /Type /Page >>\nendobj\n4 0 obj\n<< /Type /Page >>\nendobj\n%%EOF"), 2, nil},
+		{"truncated", buildPDF(3)[:60], 0, ErrCorruptPDF},
+		{"no header", []byte("hello\n%%EOF"), 0, ErrCorruptPDF},
+		{"no objects", []byte("%PDF-1.4\n%%EOF"), 0, ErrCorruptPDF},
+		{"no pages", []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n%%EOF"), 0, ErrCorruptPDF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CountPDFPages(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("pages = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestObjectStreamInvalidOffsets 对象流头部的偏移量为负数或倒序时按损坏处理，不能越界
+func TestObjectStreamInvalidOffsets(t *testing.T) {
+	streams := map[string]string{
+		"negative offset":   "10 0 obj\n<< /Type /ObjStm /N 1 /First 5 /Length 9 >>\nstream\n1 -9 abcd\nendstream\nendobj\n",
+		"decreasing offset": "10 0 obj\n<< /Type /ObjStm /N 2 /First 9 /Length 33 >>\nstream\n2 12 3 0 << /Type /Pages /Count 4 >>\nendstream\nendobj\n",
+		"offset past end":   "10 0 obj\n<< /Type /ObjStm /N 1 /First 5 /Length 9 >>\nstream\n1 99 abcd\nendstream\nendobj\n",
+	}
+
+	for name, stream := range streams {
+		t.Run(name, func(t *testing.T) {
+			data := []byte("%PDF-1.5\n" + stream + "%%EOF\n")
+			if _, err := CountPDFPages(data); !errors.Is(err, ErrCorruptPDF) {
+				t.Fatalf("err = %v, want ErrCorruptPDF", err)
+			}
+		})
+	}
+}
+
+func TestPDFTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"info dictionary", "%PDF-1.4\n5 0 obj\n<< /Title (Quarterly Report) >>\nendobj\ntrailer\n<< /Info 5 0 R >>\n%%EOF", "Quarterly Report"},
+		{"office prefix", "%PDF-1.4\n5 0 obj\n<< /Title (Microsoft Word - minutes.docx) >>\nendobj\ntrailer\n<< /Info 5 0 R >>\n%%EOF", "minutes"},
+		{"utf-16 hex", "%PDF-1.4\n5 0 obj\n<< /Title <FEFF62A5544A> >>\nendobj\ntrailer\n<< /Info 5 0 R >>\n%%EOF", "报告"},
+		{"outline title ignored", "%PDF-1.4\n5 0 obj\n<< /Title (Chapter 1) /Parent 4 0 R /Dest [3 0 R] >>\nendobj\n%%EOF", ""},
+		{"not a pdf", "hello", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PDFTitle([]byte(tt.data)); got != tt.want {
+				t.Fatalf("PDFTitle = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package docformat
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxTitleLength 标题字符串的最大字节数，超出的按损坏处理
+const maxTitleLength = 4096
+
+var (
+	pdfInfoRef  = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	pdfTitleKey = regexp.MustCompile(`/Title\s*([(<])`)
+	pdfDest     = regexp.MustCompile(`/(Dest|A|First|Prev|Next)\b`)
+)
+
+// titlePrefixes Office 导出 PDF 时写入标题的应用名前缀
+var titlePrefixes = []string{"Microsoft Word - ", "Microsoft PowerPoint - ", "Microsoft Excel - "}
+
+// PDFTitle 读取 PDF 文档信息字典中的 /Title，无法读取或为空时返回空字符串
+// 优先读取 trailer /Info 引用的对象；找不到时取第一个不属于书签（/Parent、/Dest 等）的 /Title
+func PDFTitle(data []byte) string {
+	if !hasPDFHeader(headOf(data, 1024)) {
+		return ""
+	}
+
+	if m := pdfInfoRef.FindAllSubmatch(data, -1); len(m) > 0 {
+		// 增量更新时最后一个 trailer 生效
+		ref := m[len(m)-1]
+		header := regexp.MustCompile(`(?:^|[^\d])` + string(ref[1]) + `\s+` + string(ref[2]) + `\s+obj\b`)
+		if loc := header.FindIndex(data); loc != nil {
+			body := data[loc[1]:]
+			if end := bytes.Index(body, []byte("endobj")); end >= 0 {
+				body = body[:end]
+			}
+			if title := titleEntry(dictPart(body)); title != "" {
+				return title
+			}
+		}
+	}
+
+	for _, obj := range pdfObjects(data) {
+		dict := dictPart(obj)
+		if pdfParent.Match(dict) || pdfDest.Match(dict) {
+			continue
+		}
+		if title := titleEntry(dict); title != "" {
+			return title
+		}
+	}
+	return ""
+}
+
+// titleEntry 解析字典中的 /Title 字符串并整理为单行文本
+func titleEntry(dict []byte) string {
+	loc := pdfTitleKey.FindSubmatchIndex(dict)
+	if loc == nil {
+		return ""
+	}
+	var raw []byte
+	var ok bool
+	if dict[loc[2]] == '(' {
+		raw, ok = literalString(dict[loc[3]:])
+	} else {
+		raw, ok = hexString(dict[loc[3]:])
+	}
+	if !ok {
+		return ""
+	}
+
+	title := strings.Join(strings.FieldsFunc(decodeTextString(raw), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	// Office 写入的标题为“应用名 - 文件名”，去掉应用名和扩展名
+	for _, prefix := range titlePrefixes {
+		if name, ok := strings.CutPrefix(title, prefix); ok {
+			if ext := path.Ext(name); ext != "" && len(ext) <= 5 {
+				name = strings.TrimSuffix(name, ext)
+			}
+			return name
+		}
+	}
+	return title
+}
+
+// literalString 解析 ( 之后的字面量字符串（处理转义和嵌套括号）
+func literalString(data []byte) ([]byte, bool) {
+	var out []byte
+	depth := 1
+	for i := 0; i < len(data) && len(out) <= maxTitleLength; i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out, true
+			}
+		case '\\':
+			i++
+			if i >= len(data) {
+				return nil, false
+			}
+			switch e := data[i]; e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// 行尾续行
+				if e == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					for n := 0; n < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; n++ {
+						v = v*8 + int(data[i]-'0')
+						i++
+					}
+					i--
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return nil, false
+}
+
+// hexString 解析 < 之后的十六进制字符串，奇数位时末位补 0
+func hexString(data []byte) ([]byte, bool) {
+	var out []byte
+	var digits []byte
+	for _, c := range data {
+		if c == '>' {
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			for i := 0; i < len(digits); i += 2 {
+				out = append(out, hexValue(digits[i])<<4|hexValue(digits[i+1]))
+			}
+			return out, true
+		}
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'f', c >= 'A' && c <= 'F':
+			digits = append(digits, c)
+		case c == ' ', c == '\t', c == '\r', c == '\n', c == '\f':
+		default:
+			return nil, false
+		}
+		if len(digits) > 2*maxTitleLength {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
+
+// decodeTextString 按 PDF 文本字符串规则解码：UTF-16BE（FE FF）、UTF-8（EF BB BF），
+// 其余按 UTF-8 尝试，不合法时按 PDFDocEncoding 近似为 Latin-1
+func decodeTextString(raw []byte) string {
+	switch {
+	case bytes.HasPrefix(raw, []byte{0xFE, 0xFF}):
+		raw = raw[2:]
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 0; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	case bytes.HasPrefix(raw, []byte{0xEF, 0xBB, 0xBF}):
+		return strings.ToValidUTF8(string(raw[3:]), "")
+	case utf8.Valid(raw):
+		return string(raw)
+	}
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventNodeOffline            = "node.offline"
+	EventNodeOnline             = "node.online"
+	EventNodeClockSkew          = "node.clock_skew"
+	EventNodeDrained            = "node.drained"            // 排空结束，节点已禁用
+	EventNodeConflictSuspected  = "node.conflict_suspected" // 同一 node_id 被来自不同地址的连接反复替换
+	EventJobUpdated             = "job.updated"
+	EventJobSLABreached         = "job.sla_breached"          // 任务某一阶段超过 SLA 阈值
+	EventSLABreachRateExceeded  = "sla.breach_rate_exceeded"  // 统计窗口内的超时率达到告警阈值
+	EventSLABreachRateRecovered = "sla.breach_rate_recovered" // 超时率回落到告警阈值以下
+	EventPrinterSuspended       = "printer.suspended"         // 打印机连续失败触发熔断，暂停分发
+	EventPrinterResumed         = "printer.resumed"           // 熔断冷却结束或管理员重置，恢复分发
+)
+
+// Event 系统事件（用于告警和 SSE 推送）
+type Event struct {
+	Type      string                 `json:"type"`
+	NodeID    string                 `json:"node_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Bus 进程内事件总线
+type Bus struct {
+	subscribers map[int]chan Event
+	nextID      int
+	mutex       sync.RWMutex
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[int]chan Event),
+	}
+}
+
+// Publish 发布事件（非阻塞，订阅方处理不过来时丢弃）
+func (b *Bus) Publish(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for id, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Event subscriber %d is full, dropping event %s", id, event.Type)
+		}
+	}
+}
+
+// Subscribe 订阅事件，返回事件通道和取消订阅函数
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	ch := make(chan Event, buffer)
+	b.subscribers[id] = ch
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+			delete(b.subscribers, id)
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
@@ -0,0 +1,108 @@
+// Package golden 测试辅助：比较 JSON 序列化结果与 testdata/golden 下的样例文件
+// 序列化有意变更时运行 go test ./... -update 重新生成样例文件，并在代码评审中检查差异
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+// FixedTime 填充的时间值（UTC）
+var FixedTime = time.Date(2026, 3, 2, 9, 30, 15, 123000000, time.UTC)
+
+// maxDepth 填充嵌套结构的最大深度，避免自引用类型无限展开
+const maxDepth = 6
+
+// Fill 将 v（指向结构体的指针）的每个可导出字段填充为固定的非零值：字符串为字段名，数字为 1，布尔为 true，
+// 时间为 FixedTime，指针、切片和 map 各含一个元素；用于让样例文件覆盖每个字段
+func Fill(v interface{}) {
+	fill(reflect.ValueOf(v).Elem(), "", 0)
+}
+
+func fill(v reflect.Value, name string, depth int) {
+	if depth > maxDepth {
+		return
+	}
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		v.Set(reflect.ValueOf(FixedTime))
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(name)
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		fill(elem.Elem(), name, depth+1)
+		v.Set(elem)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), 1, 1)
+		fill(slice.Index(0), name, depth+1)
+		v.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		key := reflect.New(v.Type().Key()).Elem()
+		fill(key, "key", depth+1)
+		value := reflect.New(v.Type().Elem()).Elem()
+		fill(value, name, depth+1)
+		m.SetMapIndex(key, value)
+		v.Set(m)
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(name))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			if v.Field(i).CanSet() {
+				fill(v.Field(i), field.Name, depth+1)
+			}
+		}
+	}
+}
+
+// AssertJSON 将 v 序列化为缩进 JSON 并与 testdata/golden/<name>.json 比较，-update 时改为写入
+func AssertJSON(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal %s: %v", name, err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v (run go test with -update to create it)", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match %s; if the change is intended, run go test with -update\ngot:\n%s", name, path, got)
+	}
+}
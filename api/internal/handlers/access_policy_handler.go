@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// AccessPolicyHandler 打印机访问策略处理器
+type AccessPolicyHandler struct {
+	accessPolicyRepo *database.AccessPolicyRepository
+	printerRepo      *database.PrinterRepository
+	auditRepo        *database.AuditLogRepository
+}
+
+// NewAccessPolicyHandler 创建打印机访问策略处理器
+func NewAccessPolicyHandler(accessPolicyRepo *database.AccessPolicyRepository, printerRepo *database.PrinterRepository, auditRepo *database.AuditLogRepository) *AccessPolicyHandler {
+	return &AccessPolicyHandler{
+		accessPolicyRepo: accessPolicyRepo,
+		printerRepo:      printerRepo,
+		auditRepo:        auditRepo,
+	}
+}
+
+// CreateAccessPolicyRequest 创建访问策略请求
+type CreateAccessPolicyRequest struct {
+	PrinterID string `json:"printer_id" binding:"required"`
+	Role      string `json:"role" binding:"required,max=100"`
+}
+
+// ListAccessPolicies 获取访问策略列表，支持按 printer_id 筛选
+func (h *AccessPolicyHandler) ListAccessPolicies(c *gin.Context) {
+	printerID := c.Query("printer_id")
+	if printerID != "" {
+		// 支持使用 slug 筛选
+		if printer, err := h.printerRepo.GetPrinterByID(printerID); err == nil {
+			printerID = printer.ID
+		}
+	}
+
+	policies, err := h.accessPolicyRepo.ListPolicies(printerID)
+	if err != nil {
+		log.Printf("Failed to list access policies: %v", err)
+		InternalErrorResponse(c, "获取访问策略失败")
+		return
+	}
+	if policies == nil {
+		policies = []*models.PrinterAccessPolicy{}
+	}
+
+	SuccessResponse(c, gin.H{"items": policies})
+}
+
+// CreateAccessPolicy 为打印机添加允许的角色
+func (h *AccessPolicyHandler) CreateAccessPolicy(c *gin.Context) {
+	var req CreateAccessPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	role := strings.TrimSpace(req.Role)
+	if role == "" {
+		BadRequestResponse(c, "角色不能为空")
+		return
+	}
+
+	printer, err := h.printerRepo.GetPrinterByID(req.PrinterID)
+	if err != nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	actor, _ := currentActor(c)
+	policy := &models.PrinterAccessPolicy{
+		PrinterID: printer.ID,
+		Role:      role,
+		CreatedBy: actor,
+	}
+	created, err := h.accessPolicyRepo.CreatePolicy(policy)
+	if err != nil {
+		log.Printf("Failed to create access policy for printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "创建访问策略失败")
+		return
+	}
+	if !created {
+		ErrorResponse(c, http.StatusConflict, "该打印机已允许此角色")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "access_policy.create", "printer", printer.ID,
+		fmt.Sprintf("role=%s", role))
+
+	CreatedResponse(c, policy)
+}
+
+// DeleteAccessPolicy 删除访问策略；打印机的策略全部删除后对所有人开放
+func (h *AccessPolicyHandler) DeleteAccessPolicy(c *gin.Context) {
+	policy, err := h.accessPolicyRepo.GetPolicy(c.Param("id"))
+	if err != nil {
+		log.Printf("Failed to get access policy %s: %v", c.Param("id"), err)
+		InternalErrorResponse(c, "获取访问策略失败")
+		return
+	}
+	if policy == nil {
+		NotFoundResponse(c, "访问策略不存在")
+		return
+	}
+
+	if err := h.accessPolicyRepo.DeletePolicy(policy.ID); err != nil {
+		log.Printf("Failed to delete access policy %s: %v", policy.ID, err)
+		InternalErrorResponse(c, "删除访问策略失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "access_policy.delete", "printer", policy.PrinterID,
+		fmt.Sprintf("role=%s", policy.Role))
+
+	SuccessResponse(c, gin.H{"id": policy.ID})
+}
+
+// callerRoles 当前调用方的角色（由 OAuth2 中间件从 token 中提取）
+func callerRoles(c *gin.Context) []string {
+	roles, _ := c.Get("roles")
+	roleList, _ := roles.([]string)
+	return roleList
+}
+
+// isAdminCaller 当前调用方是否为管理员（不受打印机访问策略限制）
+func isAdminCaller(c *gin.Context) bool {
+	for _, role := range callerRoles(c) {
+		if role == "admin" || role == "fly-print-admin" {
+			return true
+		}
+	}
+	return false
+}
+
+// missingPrinterRoles 校验调用方能否使用打印机，不能使用时返回打印机允许的角色
+func missingPrinterRoles(c *gin.Context, accessPolicyRepo *database.AccessPolicyRepository, printerID string) ([]string, error) {
+	if accessPolicyRepo == nil || isAdminCaller(c) {
+		return nil, nil
+	}
+
+	allowed, err := accessPolicyRepo.GetAllowedRoles(printerID)
+	if err != nil || len(allowed) == 0 {
+		return nil, err
+	}
+
+	for _, role := range callerRoles(c) {
+		for _, allowedRole := range allowed {
+			if role == allowedRole {
+				return nil, nil
+			}
+		}
+	}
+	return allowed, nil
+}
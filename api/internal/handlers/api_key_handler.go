@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// API Key 格式：固定前缀 + 32 字节随机数的十六进制
+const (
+	apiKeyPrefix       = "fpk_"
+	apiKeyRandomBytes  = 32
+	apiKeyDisplayLen   = len(apiKeyPrefix) + 8 // 列表中显示的明文前缀长度
+	apiKeyTouchMinimum = time.Minute           // last_used_at 的最小更新间隔，避免每个请求都写数据库
+)
+
+// APIKeyHandler 个人 API Key 处理器
+type APIKeyHandler struct {
+	apiKeyRepo *database.APIKeyRepository
+	userRepo   *database.UserRepository
+	auditRepo  *database.AuditLogRepository
+}
+
+// NewAPIKeyHandler 创建个人 API Key 处理器
+func NewAPIKeyHandler(apiKeyRepo *database.APIKeyRepository, userRepo *database.UserRepository, auditRepo *database.AuditLogRepository) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+		auditRepo:  auditRepo,
+	}
+}
+
+// CreateAPIKeyRequest 创建 API Key 请求
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scope     string     `json:"scope" binding:"omitempty,oneof=full submit"` // 默认 full
+	ExpiresAt *time.Time `json:"expires_at"`                                  // 为空时永不过期
+}
+
+// CreatedAPIKey 创建 API Key 响应，key 为明文，只返回这一次
+type CreatedAPIKey struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+// hashAPIKey 计算 API Key 的存储哈希（Key 为高熵随机数，SHA-256 即可）
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyTouchDue 距上次记录的使用时间超过一分钟（或从未记录）时才更新 last_used_at
+func apiKeyTouchDue(lastUsedAt *time.Time, now time.Time) bool {
+	return lastUsedAt == nil || now.Sub(*lastUsedAt) >= apiKeyTouchMinimum
+}
+
+// currentUser 获取当前 OAuth2 登录用户的本地记录，API Key 认证的请求不能管理 API Key
+func (h *APIKeyHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	if c.GetString("api_key_id") != "" {
+		ForbiddenResponse(c, "API Key 不能用于管理 API Key，请使用 OAuth2 登录")
+		return nil, false
+	}
+	externalID := c.GetString("external_id")
+	if externalID == "" {
+		UnauthorizedResponse(c, "未认证")
+		return nil, false
+	}
+
+	user, err := h.userRepo.GetUserByExternalID(externalID)
+	if err != nil {
+		NotFoundResponse(c, "用户不存在")
+		return nil, false
+	}
+	if user.Status != "active" {
+		ForbiddenResponse(c, "用户已停用")
+		return nil, false
+	}
+	return user, true
+}
+
+// CreateAPIKey 为当前用户生成 API Key
+// full 权限的 Key 继承创建时 OAuth2 登录的角色；submit 权限的 Key 只能提交打印任务
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		BadRequestResponse(c, "过期时间必须晚于当前时间")
+		return
+	}
+
+	key := &models.APIKey{
+		UserID: user.ID,
+		Name:   req.Name,
+		Scope:  req.Scope,
+	}
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.UTC()
+		key.ExpiresAt = &expiresAt
+	}
+	roles := callerRoles(c)
+	switch key.Scope {
+	case models.APIKeyScopeSubmit:
+		if !middleware.HasScope(roles, "print:submit") {
+			ForbiddenResponse(c, "当前账号没有提交打印任务的权限")
+			return
+		}
+		key.Roles = []string{"print:submit"}
+	default:
+		key.Scope = models.APIKeyScopeFull
+		key.Roles = append([]string(nil), roles...)
+	}
+
+	random := make([]byte, apiKeyRandomBytes)
+	if _, err := rand.Read(random); err != nil {
+		log.Printf("Failed to generate api key: %v", err)
+		InternalErrorResponse(c, "生成 API Key 失败")
+		return
+	}
+	plaintext := apiKeyPrefix + hex.EncodeToString(random)
+	key.Prefix = plaintext[:apiKeyDisplayLen]
+
+	if err := h.apiKeyRepo.CreateAPIKey(key, hashAPIKey(plaintext)); err != nil {
+		log.Printf("Failed to create api key for user %s: %v", user.ID, err)
+		InternalErrorResponse(c, "生成 API Key 失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "api_key.create", "api_key", key.ID,
+		fmt.Sprintf("name=%s, scope=%s, prefix=%s", key.Name, key.Scope, key.Prefix))
+
+	CreatedResponse(c, CreatedAPIKey{APIKey: key, Key: plaintext})
+}
+
+// ListAPIKeys 获取当前用户的 API Key（不含明文）
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyRepo.ListAPIKeysByUser(user.ID)
+	if err != nil {
+		log.Printf("Failed to list api keys of user %s: %v", user.ID, err)
+		InternalErrorResponse(c, "获取 API Key 失败")
+		return
+	}
+
+	SuccessResponse(c, gin.H{"items": keys})
+}
+
+// DeleteAPIKey 吊销当前用户的 API Key，立即生效
+func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		NotFoundResponse(c, "API Key 不存在")
+		return
+	}
+	deleted, err := h.apiKeyRepo.DeleteAPIKey(user.ID, id)
+	if err != nil {
+		log.Printf("Failed to delete api key %s: %v", id, err)
+		InternalErrorResponse(c, "吊销 API Key 失败")
+		return
+	}
+	if !deleted {
+		NotFoundResponse(c, "API Key 不存在")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "api_key.revoke", "api_key", id, "")
+
+	SuccessResponse(c, gin.H{"id": id})
+}
+
+// Authenticate 校验 X-API-Key（供认证中间件使用），返回所属用户的身份
+// last_used_at 距上次记录超过一分钟时才更新
+func (h *APIKeyHandler) Authenticate(plaintext string) (*middleware.APIKeyIdentity, error) {
+	key, user, err := h.apiKeyRepo.GetAPIKeyByHash(hashAPIKey(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, middleware.ErrInvalidAPIKey
+	}
+	now := time.Now().UTC()
+	if key.Expired(now) {
+		return nil, middleware.ErrAPIKeyExpired
+	}
+
+	if apiKeyTouchDue(key.LastUsedAt, now) {
+		if err := h.apiKeyRepo.TouchAPIKey(key.ID, now); err != nil {
+			log.Printf("Failed to record api key %s usage: %v", key.ID, err)
+		}
+	}
+
+	identity := &middleware.APIKeyIdentity{
+		KeyID:    key.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Roles:    key.Roles,
+	}
+	if user.ExternalID != nil {
+		identity.ExternalID = *user.ExternalID
+	}
+	return identity, nil
+}
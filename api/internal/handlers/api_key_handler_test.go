@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyTouchDue(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	tests := []struct {
+		name     string
+		lastUsed *time.Time
+		want     bool
+	}{
+		{"never used", nil, true},
+		{"used just now", at(0), false},
+		{"used 59s ago", at(59 * time.Second), false},
+		{"used a minute ago", at(time.Minute), true},
+		{"used yesterday", at(24 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		if got := apiKeyTouchDue(tt.lastUsed, now); got != tt.want {
+			t.Errorf("%s: apiKeyTouchDue = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// 每秒一次、持续十分钟的请求只更新十次
+	var lastUsed *time.Time
+	touches := 0
+	for i := 0; i < 600; i++ {
+		usedAt := now.Add(time.Duration(i) * time.Second)
+		if apiKeyTouchDue(lastUsed, usedAt) {
+			touches++
+			lastUsed = &usedAt
+		}
+	}
+	if touches != 10 {
+		t.Fatalf("touches = %d, want 10", touches)
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	hash := hashAPIKey("fpk_" + strings.Repeat("ab", 32))
+	if len(hash) != 64 || strings.Contains(hash, "fpk_") {
+		t.Fatalf("hash = %q, want 64 hex chars without the plaintext", hash)
+	}
+	if hashAPIKey("fpk_a") == hashAPIKey("fpk_b") {
+		t.Fatal("different keys share a hash")
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// adminConsolePathPrefix 管理界面接口路径前缀；资产信息只在这些接口中返回
+const adminConsolePathPrefix = "/api/v1/admin/"
+
+// maxMaintenanceWindowDays 计划维护报表的最大查询窗口
+const maxMaintenanceWindowDays = 3650
+
+// isAdminConsoleRequest 当前请求是否来自管理界面接口（与第三方共用的处理函数据此决定是否返回资产信息）
+func isAdminConsoleRequest(c *gin.Context) bool {
+	return strings.HasPrefix(c.FullPath(), adminConsolePathPrefix)
+}
+
+// AssetUpdateRequest 资产信息更新字段（嵌入管理界面的更新请求），未提供的字段保持不变，空字符串表示清除
+type AssetUpdateRequest struct {
+	PurchaseDate      *string `json:"purchase_date"`       // YYYY-MM-DD
+	WarrantyExpiresOn *string `json:"warranty_expires_on"` // YYYY-MM-DD
+	AssetTag          *string `json:"asset_tag" binding:"omitempty,max=100"`
+	Notes             *string `json:"notes"` // 最多 models.MaxAssetNotesBytes 字节，修改会记录历史
+}
+
+// provided 是否提供了任一资产字段
+func (req *AssetUpdateRequest) provided() bool {
+	return req.PurchaseDate != nil || req.WarrantyExpiresOn != nil || req.AssetTag != nil || req.Notes != nil
+}
+
+// merge 将更新字段合并到当前资产信息上
+func (req *AssetUpdateRequest) merge(current *models.AssetInfo) *models.AssetInfo {
+	merged := models.AssetInfo{}
+	if current != nil {
+		merged = *current
+	}
+	apply := func(dest **string, value *string, trim bool) {
+		if value == nil {
+			return
+		}
+		v := *value
+		if trim {
+			v = strings.TrimSpace(v)
+		}
+		if v == "" {
+			*dest = nil
+			return
+		}
+		*dest = &v
+	}
+	apply(&merged.PurchaseDate, req.PurchaseDate, true)
+	apply(&merged.WarrantyExpiresOn, req.WarrantyExpiresOn, true)
+	apply(&merged.AssetTag, req.AssetTag, true)
+	apply(&merged.Notes, req.Notes, false)
+	return &merged
+}
+
+// applyAssetUpdate 校验并保存资产信息，返回保存后的资产信息；失败时写入错误响应并返回 false
+func applyAssetUpdate(c *gin.Context, assetRepo *database.AssetRepository, auditRepo *database.AuditLogRepository, resourceType, id string, req *AssetUpdateRequest) (*models.AssetInfo, bool) {
+	current, err := assetRepo.GetAsset(resourceType, id)
+	if err != nil {
+		log.Printf("Failed to get asset info of %s %s: %v", resourceType, id, err)
+		InternalErrorResponse(c, "获取资产信息失败")
+		return nil, false
+	}
+	if !req.provided() {
+		return current, true
+	}
+
+	asset := req.merge(current)
+	if err := asset.Validate(); err != nil {
+		BadRequestResponse(c, err.Error())
+		return nil, false
+	}
+	if asset.AssetTag != nil {
+		taken, err := assetRepo.AssetTagTaken(*asset.AssetTag, resourceType, id)
+		if err != nil {
+			log.Printf("Failed to check asset tag %s: %v", *asset.AssetTag, err)
+			InternalErrorResponse(c, "更新资产信息失败")
+			return nil, false
+		}
+		if taken {
+			ErrorResponse(c, http.StatusConflict, "资产编号已被其他设备使用")
+			return nil, false
+		}
+	}
+
+	actor, _ := currentActor(c)
+	if err := assetRepo.SaveAsset(resourceType, id, asset, actor); err != nil {
+		log.Printf("Failed to save asset info of %s %s: %v", resourceType, id, err)
+		InternalErrorResponse(c, "更新资产信息失败")
+		return nil, false
+	}
+
+	var changed []string
+	for name, value := range map[string]*string{
+		"purchase_date": req.PurchaseDate, "warranty_expires_on": req.WarrantyExpiresOn,
+		"asset_tag": req.AssetTag, "notes": req.Notes,
+	} {
+		if value != nil {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	recordAudit(c, auditRepo, resourceType+".asset_update", resourceType, id, "fields="+strings.Join(changed, ","))
+
+	if asset.PurchaseDate == nil && asset.WarrantyExpiresOn == nil && asset.AssetTag == nil && asset.Notes == nil {
+		return nil, true
+	}
+	return asset, true
+}
+
+// AssetHandler 资产管理处理器（备注历史、计划维护报表）
+type AssetHandler struct {
+	assetRepo    *database.AssetRepository
+	printerRepo  *database.PrinterRepository
+	edgeNodeRepo *database.EdgeNodeRepository
+}
+
+// NewAssetHandler 创建资产管理处理器
+func NewAssetHandler(assetRepo *database.AssetRepository, printerRepo *database.PrinterRepository, edgeNodeRepo *database.EdgeNodeRepository) *AssetHandler {
+	return &AssetHandler{
+		assetRepo:    assetRepo,
+		printerRepo:  printerRepo,
+		edgeNodeRepo: edgeNodeRepo,
+	}
+}
+
+// GetPrinterNotesHistory 获取打印机备注的修改历史
+func (h *AssetHandler) GetPrinterNotesHistory(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+	h.respondNotesHistory(c, models.AssetResourcePrinter, printer.ID)
+}
+
+// GetEdgeNodeNotesHistory 获取 Edge Node 备注的修改历史
+func (h *AssetHandler) GetEdgeNodeNotesHistory(c *gin.Context) {
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+	h.respondNotesHistory(c, models.AssetResourceEdgeNode, node.ID)
+}
+
+func (h *AssetHandler) respondNotesHistory(c *gin.Context, resourceType, id string) {
+	revisions, err := h.assetRepo.ListNoteHistory(resourceType, id)
+	if err != nil {
+		log.Printf("Failed to list notes history of %s %s: %v", resourceType, id, err)
+		InternalErrorResponse(c, "获取备注历史失败")
+		return
+	}
+
+	SuccessResponse(c, gin.H{"items": revisions})
+}
+
+// GetMaintenanceReport 计划维护报表：within_days 天内（默认 30）保修到期的打印机和 Edge Node
+// include_expired=true 时同时返回已过保修期的设备
+func (h *AssetHandler) GetMaintenanceReport(c *gin.Context) {
+	withinDays, err := strconv.Atoi(c.DefaultQuery("within_days", "30"))
+	if err != nil || withinDays < 0 || withinDays > maxMaintenanceWindowDays {
+		BadRequestResponse(c, "within_days 应为 0~3650 之间的整数")
+		return
+	}
+	includeExpired, _ := strconv.ParseBool(c.DefaultQuery("include_expired", "false"))
+
+	items, err := h.assetRepo.ListWarrantyExpiring(withinDays, includeExpired)
+	if err != nil {
+		log.Printf("Failed to build maintenance report: %v", err)
+		InternalErrorResponse(c, "获取计划维护报表失败")
+		return
+	}
+
+	SuccessResponse(c, gin.H{
+		"within_days":     withinDays,
+		"include_expired": includeExpired,
+		"items":           items,
+	})
+}
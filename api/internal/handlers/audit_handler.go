@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLogHandler 审计日志处理器
+type AuditLogHandler struct {
+	auditRepo *database.AuditLogRepository
+}
+
+// NewAuditLogHandler 创建审计日志处理器
+func NewAuditLogHandler(auditRepo *database.AuditLogRepository) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditRepo: auditRepo,
+	}
+}
+
+// ListAuditLogs 获取审计日志列表
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	logs, total, err := h.auditRepo.ListAuditLogs((page-1)*pageSize, pageSize, c.Query("resource_type"), c.Query("resource_id"))
+	if err != nil {
+		log.Printf("Failed to list audit logs: %v", err)
+		InternalErrorResponse(c, "获取审计日志失败")
+		return
+	}
+
+	PaginatedSuccessResponse(c, logs, total, page, pageSize)
+}
+
+// currentActor 获取当前操作人（用户名和外部ID）
+func currentActor(c *gin.Context) (string, string) {
+	username := c.GetString("username")
+	externalID := c.GetString("external_id")
+	return username, externalID
+}
+
+// isPrivilegedCaller 当前调用方是否为管理员或运维人员
+func isPrivilegedCaller(c *gin.Context) bool {
+	roles, _ := c.Get("roles")
+	roleList, _ := roles.([]string)
+	for _, role := range roleList {
+		if role == "admin" || role == "fly-print-admin" || role == "fly-print-operator" {
+			return true
+		}
+	}
+	return false
+}
+
+// recordAudit 记录审计日志（失败只记录日志，不影响业务流程）
+func recordAudit(c *gin.Context, auditRepo *database.AuditLogRepository, action, resourceType, resourceID, details string) {
+	if auditRepo == nil {
+		return
+	}
+
+	actor, actorID := currentActor(c)
+	saveAuditLog(auditRepo, newAuditLog(actor, actorID, action, resourceType, resourceID, details, requestClientInfo(c)))
+}
+
+// recordAuditAs 以指定操作人记录审计日志（用于无登录态的请求，如技术支持文件临时链接）
+func recordAuditAs(auditRepo *database.AuditLogRepository, actor, actorID, action, resourceType, resourceID, details string) {
+	if auditRepo == nil {
+		return
+	}
+	saveAuditLog(auditRepo, newAuditLog(actor, actorID, action, resourceType, resourceID, details, nil))
+}
+
+// requestClientInfo 解析请求的 X-Client 请求头，未提供或无法解析时返回 nil
+func requestClientInfo(c *gin.Context) *models.ClientInfo {
+	return models.ParseClientInfo(c.GetHeader(models.ClientInfoHeader))
+}
+
+func newAuditLog(actor, actorID, action, resourceType, resourceID, details string, client *models.ClientInfo) *models.AuditLog {
+	return &models.AuditLog{
+		Actor:        actor,
+		ActorID:      actorID,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Details:      details,
+		Client:       client,
+	}
+}
+
+func saveAuditLog(auditRepo *database.AuditLogRepository, entry *models.AuditLog) {
+	if err := auditRepo.CreateAuditLog(entry); err != nil {
+		log.Printf("Failed to record audit log %s for %s %s: %v", entry.Action, entry.ResourceType, entry.ResourceID, err)
+	}
+}
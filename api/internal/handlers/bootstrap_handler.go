@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// BootstrapHandler 首次安装初始化：没有任何管理员时允许无需登录创建首个管理员
+type BootstrapHandler struct {
+	userRepo  *database.UserRepository
+	auditRepo *database.AuditLogRepository
+}
+
+// NewBootstrapHandler 创建初始化处理器
+func NewBootstrapHandler(userRepo *database.UserRepository, auditRepo *database.AuditLogRepository) *BootstrapHandler {
+	return &BootstrapHandler{
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+	}
+}
+
+// BootstrapAdminRequest 创建首个管理员请求
+type BootstrapAdminRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// GetStatus 返回是否已存在管理员，管理台据此决定是否显示初始化向导
+func (h *BootstrapHandler) GetStatus(c *gin.Context) {
+	exists, err := h.userRepo.AdminExists()
+	if err != nil {
+		log.Printf("Failed to check bootstrap status: %v", err)
+		InternalErrorResponse(c, "查询初始化状态失败")
+		return
+	}
+	SuccessResponse(c, gin.H{
+		"admin_exists":   exists,
+		"setup_required": !exists,
+	})
+}
+
+// CreateAdmin 创建首个管理员；已存在管理员后返回 410
+func (h *BootstrapHandler) CreateAdmin(c *gin.Context) {
+	// 先检查状态，初始化完成后不再校验请求内容
+	exists, err := h.userRepo.AdminExists()
+	if err != nil {
+		log.Printf("Failed to check bootstrap status: %v", err)
+		InternalErrorResponse(c, "查询初始化状态失败")
+		return
+	}
+	if exists {
+		ErrorResponse(c, http.StatusGone, "系统已初始化，不能再创建初始管理员")
+		return
+	}
+
+	var req BootstrapAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	// 已有的 OAuth2 用户可能占用了用户名或邮箱
+	if exists, err := h.userRepo.UsernameExists(req.Username); err != nil {
+		log.Printf("Failed to check username existence: %v", err)
+		InternalErrorResponse(c, "检查用户名失败")
+		return
+	} else if exists {
+		BadRequestResponse(c, "用户名已存在")
+		return
+	}
+	if exists, err := h.userRepo.EmailExists(req.Email); err != nil {
+		log.Printf("Failed to check email existence: %v", err)
+		InternalErrorResponse(c, "检查邮箱失败")
+		return
+	} else if exists {
+		BadRequestResponse(c, "邮箱已存在")
+		return
+	}
+
+	admin := &models.User{Username: req.Username, Email: req.Email}
+	if err := h.userRepo.CreateFirstAdmin(admin, req.Password); err != nil {
+		if errors.Is(err, database.ErrAdminExists) {
+			// 并发请求（可能来自其他副本）已先创建了管理员
+			ErrorResponse(c, http.StatusGone, "系统已初始化，不能再创建初始管理员")
+			return
+		}
+		log.Printf("Failed to create bootstrap admin: %v", err)
+		InternalErrorResponse(c, "创建管理员失败")
+		return
+	}
+
+	recordAuditAs(h.auditRepo, "bootstrap", "", "bootstrap.create_admin", "user", admin.ID,
+		fmt.Sprintf("username=%s client_ip=%s", admin.Username, c.ClientIP()))
+	log.Printf("Bootstrap admin %s created from %s", admin.Username, c.ClientIP())
+	CreatedResponse(c, admin)
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// BusinessHoursHandler 营业时间处理器
+type BusinessHoursHandler struct {
+	hoursRepo    *database.BusinessHoursRepository
+	printerRepo  *database.PrinterRepository
+	edgeNodeRepo *database.EdgeNodeRepository
+	auditRepo    *database.AuditLogRepository
+	settings     *config.Store // 未指定时区时使用 app.timezone
+}
+
+// NewBusinessHoursHandler 创建营业时间处理器
+func NewBusinessHoursHandler(hoursRepo *database.BusinessHoursRepository, printerRepo *database.PrinterRepository, edgeNodeRepo *database.EdgeNodeRepository, auditRepo *database.AuditLogRepository, settings *config.Store) *BusinessHoursHandler {
+	return &BusinessHoursHandler{
+		hoursRepo:    hoursRepo,
+		printerRepo:  printerRepo,
+		edgeNodeRepo: edgeNodeRepo,
+		auditRepo:    auditRepo,
+		settings:     settings,
+	}
+}
+
+// BusinessHoursRequest 设置营业时间请求
+type BusinessHoursRequest struct {
+	Days      []int  `json:"days" binding:"required,min=1,max=7"` // 0=周日 … 6=周六
+	OpenTime  string `json:"open_time" binding:"required"`        // 开始营业时间 HH:MM
+	CloseTime string `json:"close_time" binding:"required"`       // 结束营业时间 HH:MM
+	Timezone  string `json:"timezone"`                            // 站点时区（IANA），默认 app.timezone
+	Enabled   *bool  `json:"enabled"`                             // 默认启用
+}
+
+// toBusinessHours 校验请求并转换为营业时间
+func (h *BusinessHoursHandler) toBusinessHours(req *BusinessHoursRequest) (*models.BusinessHours, error) {
+	hours := &models.BusinessHours{
+		Days:      req.Days,
+		OpenTime:  strings.TrimSpace(req.OpenTime),
+		CloseTime: strings.TrimSpace(req.CloseTime),
+		Timezone:  strings.TrimSpace(req.Timezone),
+		Enabled:   true,
+	}
+	if hours.Timezone == "" {
+		hours.Timezone = h.settings.Get().App.Timezone
+	}
+	if req.Enabled != nil {
+		hours.Enabled = *req.Enabled
+	}
+	return hours, hours.Validate()
+}
+
+// businessHoursView 营业时间及当前是否营业
+func businessHoursView(hours *models.BusinessHours) gin.H {
+	now := time.Now()
+	view := gin.H{
+		"business_hours": hours,
+		"inherited":      hours.PrinterID == nil,
+		"open_now":       hours.OpenAt(now),
+	}
+	if !hours.OpenAt(now) {
+		view["next_open_at"] = hours.NextOpen(now)
+	}
+	return view
+}
+
+// GetPrinterBusinessHours 获取打印机的营业时间（含继承自 Edge Node 的设置）
+func (h *BusinessHoursHandler) GetPrinterBusinessHours(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil || printer == nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	hours, err := h.hoursRepo.GetEffectiveBusinessHours(printer.ID, printer.EdgeNodeID)
+	if err != nil {
+		log.Printf("Failed to get business hours for printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "获取营业时间失败")
+		return
+	}
+	if hours == nil {
+		NotFoundResponse(c, "未设置营业时间")
+		return
+	}
+
+	SuccessResponse(c, businessHoursView(hours))
+}
+
+// SetPrinterBusinessHours 设置打印机的营业时间（覆盖 Edge Node 的设置）
+func (h *BusinessHoursHandler) SetPrinterBusinessHours(c *gin.Context) {
+	var req BusinessHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	hours, err := h.toBusinessHours(&req)
+	if err != nil {
+		BadRequestResponse(c, "营业时间无效: "+err.Error())
+		return
+	}
+
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil || printer == nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	hours.PrinterID = &printer.ID
+	if err := h.hoursRepo.UpsertBusinessHours(hours); err != nil {
+		log.Printf("Failed to set business hours for printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "设置营业时间失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "business_hours.update", "printer", printer.ID, describeBusinessHours(hours))
+
+	SuccessResponse(c, hours)
+}
+
+// DeletePrinterBusinessHours 删除打印机自身的营业时间（之后沿用 Edge Node 的设置）
+func (h *BusinessHoursHandler) DeletePrinterBusinessHours(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil || printer == nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	deleted, err := h.hoursRepo.DeletePrinterBusinessHours(printer.ID)
+	if err != nil {
+		log.Printf("Failed to delete business hours for printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "删除营业时间失败")
+		return
+	}
+	if !deleted {
+		NotFoundResponse(c, "未设置营业时间")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "business_hours.delete", "printer", printer.ID, "")
+
+	SuccessResponse(c, gin.H{"printer_id": printer.ID})
+}
+
+// GetEdgeNodeBusinessHours 获取 Edge Node（站点）的营业时间
+func (h *BusinessHoursHandler) GetEdgeNodeBusinessHours(c *gin.Context) {
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	hours, err := h.hoursRepo.GetEdgeNodeBusinessHours(node.ID)
+	if err != nil {
+		log.Printf("Failed to get business hours for edge node %s: %v", node.ID, err)
+		InternalErrorResponse(c, "获取营业时间失败")
+		return
+	}
+	if hours == nil {
+		NotFoundResponse(c, "未设置营业时间")
+		return
+	}
+
+	SuccessResponse(c, businessHoursView(hours))
+}
+
+// SetEdgeNodeBusinessHours 设置 Edge Node（站点）的营业时间，作用于其下所有没有单独设置的打印机
+func (h *BusinessHoursHandler) SetEdgeNodeBusinessHours(c *gin.Context) {
+	var req BusinessHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	hours, err := h.toBusinessHours(&req)
+	if err != nil {
+		BadRequestResponse(c, "营业时间无效: "+err.Error())
+		return
+	}
+
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	hours.EdgeNodeID = &node.ID
+	if err := h.hoursRepo.UpsertBusinessHours(hours); err != nil {
+		log.Printf("Failed to set business hours for edge node %s: %v", node.ID, err)
+		InternalErrorResponse(c, "设置营业时间失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "business_hours.update", "edge_node", node.ID, describeBusinessHours(hours))
+
+	SuccessResponse(c, hours)
+}
+
+// DeleteEdgeNodeBusinessHours 删除 Edge Node（站点）的营业时间
+func (h *BusinessHoursHandler) DeleteEdgeNodeBusinessHours(c *gin.Context) {
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	deleted, err := h.hoursRepo.DeleteEdgeNodeBusinessHours(node.ID)
+	if err != nil {
+		log.Printf("Failed to delete business hours for edge node %s: %v", node.ID, err)
+		InternalErrorResponse(c, "删除营业时间失败")
+		return
+	}
+	if !deleted {
+		NotFoundResponse(c, "未设置营业时间")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "business_hours.delete", "edge_node", node.ID, "")
+
+	SuccessResponse(c, gin.H{"edge_node_id": node.ID})
+}
+
+// describeBusinessHours 审计日志中的营业时间描述
+func describeBusinessHours(hours *models.BusinessHours) string {
+	return fmt.Sprintf("days=%v, open=%s-%s, timezone=%s, enabled=%v",
+		hours.Days, hours.OpenTime, hours.CloseTime, hours.Timezone, hours.Enabled)
+}
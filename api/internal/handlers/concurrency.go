@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 乐观锁：管理端的修改请求必须携带读取时的数据版本号（row_version），
+// 通过 If-Match 请求头（值为详情接口返回的 ETag）或请求体中的 row_version 字段传入
+
+// setVersionETag 将数据版本号作为 ETag 返回，客户端修改时原样放入 If-Match
+func setVersionETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// requireVersion 读取请求携带的数据版本号，If-Match 优先于请求体；
+// 均未提供时返回 428，格式错误时返回 400
+func requireVersion(c *gin.Context, bodyVersion *int) (int, bool) {
+	if ifMatch := strings.TrimSpace(c.GetHeader("If-Match")); ifMatch != "" {
+		raw := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+		version, err := strconv.Atoi(raw)
+		if err != nil || version < 1 {
+			BadRequestResponse(c, "If-Match 请求头格式错误，应为数据的 row_version")
+			return 0, false
+		}
+		return version, true
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, true
+	}
+	ErrorResponse(c, http.StatusPreconditionRequired, "缺少数据版本号，请通过 If-Match 请求头或 row_version 字段提供")
+	return 0, false
+}
+
+// checkVersion 校验请求携带的版本号与当前数据一致，不一致时返回 409
+// 在执行任何写入前调用；最终 UPDATE 仍带版本条件，防止校验后被并发修改
+func checkVersion(c *gin.Context, bodyVersion *int, current int) bool {
+	version, ok := requireVersion(c, bodyVersion)
+	if !ok {
+		return false
+	}
+	if version != current {
+		versionConflictResponse(c)
+		return false
+	}
+	return true
+}
+
+// versionConflictResponse 数据已被其他管理员修改
+func versionConflictResponse(c *gin.Context) {
+	ErrorResponse(c, http.StatusConflict, "数据已被其他管理员修改，请刷新后重试")
+}
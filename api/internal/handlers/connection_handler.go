@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"sort"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+// ConnectionHandler WebSocket 会话管理处理器
+type ConnectionHandler struct {
+	wsManager *websocket.ConnectionManager
+	auditRepo *database.AuditLogRepository
+}
+
+// NewConnectionHandler 创建 WebSocket 会话管理处理器
+func NewConnectionHandler(wsManager *websocket.ConnectionManager, auditRepo *database.AuditLogRepository) *ConnectionHandler {
+	return &ConnectionHandler{
+		wsManager: wsManager,
+		auditRepo: auditRepo,
+	}
+}
+
+// ListConnections 列出全部在线 WebSocket 会话
+func (h *ConnectionHandler) ListConnections(c *gin.Context) {
+	sessions := h.wsManager.ListSessions()
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].NodeID < sessions[j].NodeID
+	})
+
+	SuccessResponse(c, gin.H{
+		"items": sessions,
+		"total": len(sessions),
+	})
+}
+
+// CloseConnection 强制断开指定节点的 WebSocket 会话
+// 节点可立即重连，如需阻止重连应同时禁用节点
+func (h *ConnectionHandler) CloseConnection(c *gin.Context) {
+	nodeID := c.Param("node_id")
+
+	if err := h.wsManager.CloseConnection(nodeID, "closed by administrator"); err != nil {
+		NotFoundResponse(c, "该节点没有在线会话")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "connection.close", "edge_node", nodeID, "")
+
+	SuccessResponse(c, gin.H{"node_id": nodeID, "closed": true})
+}
@@ -1,62 +1,225 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"fly-print-cloud/api/internal/billing"
+	"fly-print-cloud/api/internal/config"
 	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
 )
 
+// trendDays 趋势图覆盖的自然日数（含今天）
+const trendDays = 7
+
 type DashboardHandler struct {
 	printJobRepo *database.PrintJobRepository
+	edgeNodeRepo *database.EdgeNodeRepository
+	printerRepo  *database.PrinterRepository
+	calculator   *billing.Calculator
+	settings     *config.Store // 部署时区（app.timezone）支持热更新
 }
 
-func NewDashboardHandler(printJobRepo *database.PrintJobRepository) *DashboardHandler {
+func NewDashboardHandler(printJobRepo *database.PrintJobRepository, edgeNodeRepo *database.EdgeNodeRepository, printerRepo *database.PrinterRepository, calculator *billing.Calculator, settings *config.Store) *DashboardHandler {
 	return &DashboardHandler{
 		printJobRepo: printJobRepo,
+		edgeNodeRepo: edgeNodeRepo,
+		printerRepo:  printerRepo,
+		calculator:   calculator,
+		settings:     settings,
 	}
 }
 
-// GetTrends 获取打印任务趋势数据
+// GetTrends 获取最近7天（含今天）每天完成、部分完成和失败的任务数
+// 按 tz 参数或部署时区划分自然日，日期标签为 YYYY-MM-DD
 func (h *DashboardHandler) GetTrends(c *gin.Context) {
-	// 获取最近7天的日期
-	dates := make([]string, 7)
-	completed := make([]int, 7)
-	failed := make([]int, 7)
-
-	now := time.Now()
-	for i := 6; i >= 0; i-- {
-		date := now.AddDate(0, 0, -i)
-		dateStr := date.Format("01-02")
-		dates[6-i] = dateStr
-
-		// 查询当天完成和失败的任务数量
-		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-		endOfDay := startOfDay.Add(24 * time.Hour)
-
-		completedCount, err := h.printJobRepo.CountJobsByStatusAndDate("completed", startOfDay, endOfDay)
-		if err != nil {
-			// 如果查询失败，使用模拟数据
-			completedCount = 5 + (i % 15)
-		}
+	loc, ok := statsTimezone(c, h.settings)
+	if !ok {
+		return
+	}
 
-		failedCount, err := h.printJobRepo.CountJobsByStatusAndDate("failed", startOfDay, endOfDay)
-		if err != nil {
-			// 如果查询失败，使用模拟数据
-			failedCount = i % 3
-		}
+	start, end, days := trendWindow(time.Now(), loc)
+	counts, err := h.printJobRepo.CountJobsByDay(start, end, loc.String(), models.JobStatusCompleted, models.JobStatusPartiallyCompleted, models.JobStatusFailed)
+	if err != nil {
+		log.Printf("Failed to get job trends: %v", err)
+		InternalErrorResponse(c, "获取任务趋势失败")
+		return
+	}
 
-		completed[6-i] = completedCount
-		failed[6-i] = failedCount
+	completed := make([]int, trendDays)
+	partial := make([]int, trendDays)
+	failed := make([]int, trendDays)
+	for i, day := range days {
+		completed[i] = counts[day][models.JobStatusCompleted]
+		partial[i] = counts[day][models.JobStatusPartiallyCompleted]
+		failed[i] = counts[day][models.JobStatusFailed]
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"data": gin.H{
-			"dates":     dates,
+			"timezone":  loc.String(),
+			"dates":     days,
 			"completed": completed,
+			"partially_completed": partial,
 			"failed":    failed,
 		},
 	})
 }
+
+// trendWindow 截至 now 所在自然日（按 loc 时区）的 trendDays 天趋势窗口，返回 [start, end) 和各天的日期标签（YYYY-MM-DD，含年份）
+// 逐日加一而不是加 24 小时，夏令时切换日的长度按实际计算
+func trendWindow(now time.Time, loc *time.Location) (time.Time, time.Time, []string) {
+	start := startOfDay(now, loc).AddDate(0, 0, -(trendDays - 1))
+	days := make([]string, trendDays)
+	for i := range days {
+		days[i] = start.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	return start, start.AddDate(0, 0, trendDays), days
+}
+
+// GetCostStats 获取打印费用统计（按用户或打印机分组）
+// 日期范围按 tz 参数或部署时区解释
+func (h *DashboardHandler) GetCostStats(c *gin.Context) {
+	loc, ok := statsTimezone(c, h.settings)
+	if !ok {
+		return
+	}
+	now := time.Now().In(loc)
+	startDate, endDate, err := parseDateRange(
+		c.DefaultQuery("start_date", now.AddDate(0, 0, -30).Format("2006-01-02")),
+		c.DefaultQuery("end_date", now.Format("2006-01-02")),
+		loc,
+	)
+	if err != nil {
+		BadRequestResponse(c, err.Error())
+		return
+	}
+	startDate, endDate = startDate.UTC(), endDate.UTC()
+
+	groupBy := c.DefaultQuery("group_by", "user")
+	var stats []*models.CostStat
+	switch groupBy {
+	case "user":
+		stats, err = h.printJobRepo.CostStatsByUser(startDate, endDate)
+	case "printer":
+		stats, err = h.printJobRepo.CostStatsByPrinter(startDate, endDate)
+	default:
+		BadRequestResponse(c, "group_by 只支持 user 或 printer")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get cost stats: %v", err)
+		InternalErrorResponse(c, "获取费用统计失败")
+		return
+	}
+
+	var totalCost float64
+	var totalPages int
+	for _, stat := range stats {
+		totalCost += stat.TotalCost
+		totalPages += stat.Pages
+	}
+
+	SuccessResponse(c, gin.H{
+		"timezone":    loc.String(),
+		"group_by":    groupBy,
+		"currency":    h.calculator.Currency(),
+		"items":       stats,
+		"total_cost":  totalCost,
+		"total_pages": totalPages,
+	})
+}
+
+// GetSourceStats 按提交来源（console/api/integration/email/hot_folder/unknown）统计任务数和计费页数
+// from/to 为提交日期（YYYY-MM-DD，含两端），按 tz 参数或部署时区解释，默认最近 30 天
+func (h *DashboardHandler) GetSourceStats(c *gin.Context) {
+	loc, ok := statsTimezone(c, h.settings)
+	if !ok {
+		return
+	}
+	now := time.Now().In(loc)
+	from := c.DefaultQuery("from", now.AddDate(0, 0, -30).Format("2006-01-02"))
+	to := c.DefaultQuery("to", now.Format("2006-01-02"))
+	startDate, endDate, err := parseDateRange(from, to, loc)
+	if err != nil {
+		BadRequestResponse(c, "from/to 格式应为 YYYY-MM-DD，且 to 不能早于 from")
+		return
+	}
+
+	stats, err := h.printJobRepo.SourceStats(startDate.UTC(), endDate.UTC())
+	if err != nil {
+		log.Printf("Failed to get source stats: %v", err)
+		InternalErrorResponse(c, "获取来源统计失败")
+		return
+	}
+
+	var totalJobs int
+	for _, stat := range stats {
+		totalJobs += stat.JobCount
+	}
+
+	SuccessResponse(c, gin.H{
+		"timezone":   loc.String(),
+		"from":       from,
+		"to":         to,
+		"items":      stats,
+		"total_jobs": totalJobs,
+	})
+}
+
+// GetSLAStats 获取 SLA 达成情况：总体、按打印机和按站点（Edge Node 位置）的超时率
+// from/to 为提交日期（YYYY-MM-DD，含两端），按 tz 参数或部署时区解释，默认最近 7 天；
+// 只统计已结束并完成最终评估的任务
+func (h *DashboardHandler) GetSLAStats(c *gin.Context) {
+	loc, ok := statsTimezone(c, h.settings)
+	if !ok {
+		return
+	}
+	now := time.Now().In(loc)
+	from := c.DefaultQuery("from", now.AddDate(0, 0, -(trendDays - 1)).Format("2006-01-02"))
+	to := c.DefaultQuery("to", now.Format("2006-01-02"))
+	startDate, endDate, err := parseDateRange(from, to, loc)
+	if err != nil {
+		BadRequestResponse(c, "from/to 格式应为 YYYY-MM-DD，且 to 不能早于 from")
+		return
+	}
+
+	total, err := h.printJobRepo.SLAStatsTotal(startDate, endDate)
+	if err != nil {
+		log.Printf("Failed to get SLA stats: %v", err)
+		InternalErrorResponse(c, "获取 SLA 统计失败")
+		return
+	}
+	byPrinter, err := h.printJobRepo.SLAStatsByPrinter(startDate, endDate)
+	if err != nil {
+		log.Printf("Failed to get SLA stats by printer: %v", err)
+		InternalErrorResponse(c, "获取 SLA 统计失败")
+		return
+	}
+	bySite, err := h.printJobRepo.SLAStatsBySite(startDate, endDate)
+	if err != nil {
+		log.Printf("Failed to get SLA stats by site: %v", err)
+		InternalErrorResponse(c, "获取 SLA 统计失败")
+		return
+	}
+
+	cfg := h.settings.Get().SLA
+	SuccessResponse(c, gin.H{
+		"timezone": loc.String(),
+		"from":     from,
+		"to":       to,
+		// 全局阈值（秒），打印机组可单独覆盖
+		"thresholds": gin.H{
+			"time_to_dispatch_seconds": int(cfg.TimeToDispatch.Seconds()),
+			"time_to_start_seconds":    int(cfg.TimeToStart.Seconds()),
+			"time_to_complete_seconds": int(cfg.TimeToComplete.Seconds()),
+		},
+		"total":      total,
+		"by_printer": byPrinter,
+		"by_site":    bySite,
+	})
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/websocket"
+	"fly-print-cloud/api/internal/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// DiagnosticsHandler Edge Node 诊断包处理器
+type DiagnosticsHandler struct {
+	diagnosticsRepo *database.DiagnosticsRepository
+	edgeNodeRepo    *database.EdgeNodeRepository
+	auditRepo       *database.AuditLogRepository
+	wsManager       *websocket.ConnectionManager
+	settings        *config.Store
+}
+
+// NewDiagnosticsHandler 创建诊断包处理器
+func NewDiagnosticsHandler(diagnosticsRepo *database.DiagnosticsRepository, edgeNodeRepo *database.EdgeNodeRepository, auditRepo *database.AuditLogRepository, wsManager *websocket.ConnectionManager, settings *config.Store) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		diagnosticsRepo: diagnosticsRepo,
+		edgeNodeRepo:    edgeNodeRepo,
+		auditRepo:       auditRepo,
+		wsManager:       wsManager,
+		settings:        settings,
+	}
+}
+
+// cfg 当前生效的诊断包配置（大小上限、上传等待和保留时长支持热更新）
+func (h *DiagnosticsHandler) cfg() *config.DiagnosticsConfig {
+	return &h.settings.Get().Diagnostics
+}
+
+// RequestDiagnostics 请求 Edge Node 收集并上传诊断包
+func (h *DiagnosticsHandler) RequestDiagnostics(c *gin.Context) {
+	nodeID := c.Param("id")
+	if _, err := h.edgeNodeRepo.GetEdgeNodeByID(nodeID); err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	if !h.wsManager.IsNodeConnected(nodeID) {
+		ErrorResponse(c, http.StatusConflict, "Edge Node 未连接，无法收集诊断信息")
+		return
+	}
+
+	actor, _ := currentActor(c)
+	req := &models.DiagnosticsRequest{
+		EdgeNodeID:  nodeID,
+		Status:      models.DiagnosticsStatusRequested,
+		RequestedBy: actor,
+		ExpiresAt:   time.Now().UTC().Add(h.cfg().UploadTimeout),
+	}
+	if err := h.diagnosticsRepo.CreateDiagnosticsRequest(req); err != nil {
+		log.Printf("Failed to create diagnostics request for node %s: %v", nodeID, err)
+		InternalErrorResponse(c, "创建诊断请求失败")
+		return
+	}
+
+	err := h.wsManager.RequestDiagnostics(nodeID, websocket.DiagnosticsRequestData{
+		RequestID: req.ID,
+		UploadURL: fmt.Sprintf("/api/v1/edge/%s/diagnostics/%s", nodeID, req.ID),
+		MaxSize:   h.cfg().MaxSize,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		log.Printf("Failed to send diagnostics request %s to node %s: %v", req.ID, nodeID, err)
+		ErrorResponse(c, http.StatusConflict, "发送诊断请求失败，Edge Node 可能已断开")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "edge_node.diagnostics_request", "edge_node", nodeID,
+		fmt.Sprintf("request_id=%s", req.ID))
+
+	log.Printf("Diagnostics request %s sent to node %s", req.ID, nodeID)
+	CreatedResponse(c, req)
+}
+
+// DownloadDiagnostics 下载 Edge Node 上传的诊断包
+func (h *DiagnosticsHandler) DownloadDiagnostics(c *gin.Context) {
+	req := h.loadRequest(c, c.Param("id"))
+	if req == nil {
+		return
+	}
+
+	if req.Status != models.DiagnosticsStatusUploaded {
+		ErrorResponse(c, http.StatusConflict, fmt.Sprintf("诊断包不可下载（当前状态：%s）", req.Status))
+		return
+	}
+
+	archivePath := worker.DiagnosticsArchivePath(h.cfg().Dir, req.ID)
+	if _, err := os.Stat(archivePath); err != nil {
+		NotFoundResponse(c, "诊断包文件不存在")
+		return
+	}
+
+	fileName := req.FileName
+	if fileName == "" {
+		fileName = fmt.Sprintf("diagnostics-%s-%s", req.EdgeNodeID, req.ID)
+	}
+	c.FileAttachment(archivePath, fileName)
+}
+
+// EdgeUploadDiagnostics Edge Node 上传诊断包（multipart，字段名 file），必须对应一个待上传的请求
+func (h *DiagnosticsHandler) EdgeUploadDiagnostics(c *gin.Context) {
+	req := h.loadRequest(c, c.Param("node_id"))
+	if req == nil {
+		return
+	}
+
+	if req.Status != models.DiagnosticsStatusRequested || !time.Now().Before(req.ExpiresAt) {
+		ErrorResponse(c, http.StatusConflict, "诊断请求已完成或已过期")
+		return
+	}
+
+	// 限制请求体大小（额外预留 multipart 头部空间）
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg().MaxSize+64*1024)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		BadRequestResponse(c, fmt.Sprintf("缺少诊断包文件或文件超过大小限制（%d 字节）", h.cfg().MaxSize))
+		return
+	}
+	if fileHeader.Size > h.cfg().MaxSize {
+		BadRequestResponse(c, fmt.Sprintf("诊断包超过大小限制（%d 字节）", h.cfg().MaxSize))
+		return
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		BadRequestResponse(c, "读取诊断包失败")
+		return
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(h.cfg().Dir, 0o750); err != nil {
+		log.Printf("Failed to create diagnostics dir %s: %v", h.cfg().Dir, err)
+		InternalErrorResponse(c, "保存诊断包失败")
+		return
+	}
+
+	// 先写入同目录下的唯一临时文件，确认大小并登记成功后原子改名就位；并发上传互不覆盖
+	archivePath := worker.DiagnosticsArchivePath(h.cfg().Dir, req.ID)
+	dst, err := os.CreateTemp(filepath.Dir(archivePath), ".upload-*")
+	if err != nil {
+		log.Printf("Failed to create diagnostics archive for request %s: %v", req.ID, err)
+		InternalErrorResponse(c, "保存诊断包失败")
+		return
+	}
+	tmpPath := dst.Name()
+	defer os.Remove(tmpPath)
+
+	size, err := io.Copy(dst, io.LimitReader(src, h.cfg().MaxSize+1))
+	if closeErr := dst.Close(); err == nil && closeErr != nil {
+		log.Printf("Failed to write diagnostics archive for request %s: %v", req.ID, closeErr)
+		InternalErrorResponse(c, "保存诊断包失败")
+		return
+	}
+	if err != nil || size > h.cfg().MaxSize {
+		BadRequestResponse(c, fmt.Sprintf("诊断包超过大小限制（%d 字节）", h.cfg().MaxSize))
+		return
+	}
+
+	fileName := filepath.Base(fileHeader.Filename)
+	uploaded, err := h.diagnosticsRepo.MarkDiagnosticsUploaded(req.ID, fileName, size, time.Now().UTC().Add(h.cfg().Retention))
+	if err != nil || !uploaded {
+		if err != nil {
+			log.Printf("Failed to mark diagnostics request %s uploaded: %v", req.ID, err)
+			InternalErrorResponse(c, "保存诊断包失败")
+			return
+		}
+		ErrorResponse(c, http.StatusConflict, "诊断请求已完成或已过期")
+		return
+	}
+
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		log.Printf("Failed to move diagnostics archive for request %s: %v", req.ID, err)
+		InternalErrorResponse(c, "保存诊断包失败")
+		return
+	}
+
+	log.Printf("Diagnostics archive %s (%d bytes) uploaded by node %s", req.ID, size, req.EdgeNodeID)
+	SuccessResponse(c, gin.H{"request_id": req.ID, "file_size": size})
+}
+
+// loadRequest 加载诊断请求并校验其归属节点，失败时已写入响应并返回 nil
+func (h *DiagnosticsHandler) loadRequest(c *gin.Context, nodeID string) *models.DiagnosticsRequest {
+	req, err := h.diagnosticsRepo.GetDiagnosticsRequest(c.Param("request_id"))
+	if err != nil {
+		log.Printf("Failed to load diagnostics request %s: %v", c.Param("request_id"), err)
+		NotFoundResponse(c, "诊断请求不存在")
+		return nil
+	}
+	if req == nil || req.EdgeNodeID != nodeID {
+		NotFoundResponse(c, "诊断请求不存在")
+		return nil
+	}
+	return req
+}
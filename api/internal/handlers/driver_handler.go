@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// DriverHandler 打印机驱动/PPD 管理处理器
+type DriverHandler struct {
+	driverRepo   *database.PrinterDriverRepository
+	edgeNodeRepo *database.EdgeNodeRepository
+	settings     *config.Store
+}
+
+// NewDriverHandler 创建打印机驱动管理处理器
+func NewDriverHandler(driverRepo *database.PrinterDriverRepository, edgeNodeRepo *database.EdgeNodeRepository, settings *config.Store) *DriverHandler {
+	return &DriverHandler{
+		driverRepo:   driverRepo,
+		edgeNodeRepo: edgeNodeRepo,
+		settings:     settings,
+	}
+}
+
+// cfg 当前生效的驱动配置（PPD 大小上限支持热更新）
+func (h *DriverHandler) cfg() *config.DriversConfig {
+	return &h.settings.Get().Drivers
+}
+
+// PrinterDriverRequest 创建/更新驱动请求
+type PrinterDriverRequest struct {
+	ModelPattern string            `json:"model_pattern" binding:"required,min=1,max=200"`
+	DriverName   string            `json:"driver_name" binding:"required,min=1,max=200"`
+	Options      map[string]string `json:"options"`
+	Priority     int               `json:"priority"`
+}
+
+// ListDrivers 获取驱动列表
+func (h *DriverHandler) ListDrivers(c *gin.Context) {
+	drivers, err := h.driverRepo.ListDrivers()
+	if err != nil {
+		log.Printf("Failed to list printer drivers: %v", err)
+		InternalErrorResponse(c, "获取驱动列表失败")
+		return
+	}
+
+	SuccessResponse(c, gin.H{"items": drivers})
+}
+
+// CreateDriver 创建驱动
+func (h *DriverHandler) CreateDriver(c *gin.Context) {
+	var req PrinterDriverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	driver := &models.PrinterDriver{
+		ModelPattern: req.ModelPattern,
+		DriverName:   req.DriverName,
+		Options:      req.Options,
+		Priority:     req.Priority,
+	}
+
+	if err := h.driverRepo.CreateDriver(driver); err != nil {
+		log.Printf("Failed to create printer driver: %v", err)
+		InternalErrorResponse(c, "创建驱动失败")
+		return
+	}
+
+	log.Printf("Printer driver %s (%s) created", driver.DriverName, driver.ModelPattern)
+	CreatedResponse(c, driver)
+}
+
+// GetDriver 获取驱动详情
+func (h *DriverHandler) GetDriver(c *gin.Context) {
+	driver, err := h.driverRepo.GetDriverByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "驱动不存在")
+		return
+	}
+
+	SuccessResponse(c, driver)
+}
+
+// UpdateDriver 更新驱动
+func (h *DriverHandler) UpdateDriver(c *gin.Context) {
+	var req PrinterDriverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	driver, err := h.driverRepo.GetDriverByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "驱动不存在")
+		return
+	}
+
+	driver.ModelPattern = req.ModelPattern
+	driver.DriverName = req.DriverName
+	driver.Options = req.Options
+	driver.Priority = req.Priority
+
+	if err := h.driverRepo.UpdateDriver(driver); err != nil {
+		log.Printf("Failed to update printer driver %s: %v", driver.ID, err)
+		InternalErrorResponse(c, "更新驱动失败")
+		return
+	}
+
+	SuccessResponse(c, driver)
+}
+
+// DeleteDriver 删除驱动（同时删除 PPD 文件）
+func (h *DriverHandler) DeleteDriver(c *gin.Context) {
+	driverID := c.Param("id")
+	if err := h.driverRepo.DeleteDriver(driverID); err != nil {
+		NotFoundResponse(c, "驱动不存在")
+		return
+	}
+
+	if err := os.Remove(h.ppdPath(driverID)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove ppd file for driver %s: %v", driverID, err)
+	}
+
+	SuccessResponse(c, gin.H{"message": "驱动删除成功"})
+}
+
+// UploadPPD 上传驱动的 PPD 文件（multipart，字段名 file）
+func (h *DriverHandler) UploadPPD(c *gin.Context) {
+	driver, err := h.driverRepo.GetDriverByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "驱动不存在")
+		return
+	}
+
+	// 限制请求体大小（额外预留 multipart 头部空间）
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg().MaxPPDSize+64*1024)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		BadRequestResponse(c, fmt.Sprintf("缺少 PPD 文件或文件超过大小限制（%d 字节）", h.cfg().MaxPPDSize))
+		return
+	}
+	if fileHeader.Size > h.cfg().MaxPPDSize {
+		BadRequestResponse(c, fmt.Sprintf("PPD 文件超过大小限制（%d 字节）", h.cfg().MaxPPDSize))
+		return
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		BadRequestResponse(c, "读取 PPD 文件失败")
+		return
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(h.cfg().PPDDir, 0o755); err != nil {
+		log.Printf("Failed to create ppd dir %s: %v", h.cfg().PPDDir, err)
+		InternalErrorResponse(c, "保存 PPD 文件失败")
+		return
+	}
+
+	dst, err := os.Create(h.ppdPath(driver.ID))
+	if err != nil {
+		log.Printf("Failed to create ppd file for driver %s: %v", driver.ID, err)
+		InternalErrorResponse(c, "保存 PPD 文件失败")
+		return
+	}
+	defer dst.Close()
+
+	size, err := io.Copy(dst, io.LimitReader(src, h.cfg().MaxPPDSize+1))
+	if err != nil || size > h.cfg().MaxPPDSize {
+		os.Remove(h.ppdPath(driver.ID))
+		BadRequestResponse(c, fmt.Sprintf("PPD 文件超过大小限制（%d 字节）", h.cfg().MaxPPDSize))
+		return
+	}
+
+	fileName := filepath.Base(fileHeader.Filename)
+	if err := h.driverRepo.UpdateDriverPPD(driver.ID, fileName, size); err != nil {
+		log.Printf("Failed to update ppd info for driver %s: %v", driver.ID, err)
+		InternalErrorResponse(c, "保存 PPD 文件失败")
+		return
+	}
+
+	driver.PPDFile = fileName
+	driver.PPDSize = size
+	log.Printf("PPD file %s (%d bytes) uploaded for driver %s", fileName, size, driver.ID)
+	SuccessResponse(c, driver)
+}
+
+// DownloadPPD 下载驱动的 PPD 文件
+func (h *DriverHandler) DownloadPPD(c *gin.Context) {
+	driver, err := h.driverRepo.GetDriverByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "驱动不存在")
+		return
+	}
+
+	h.servePPD(c, driver)
+}
+
+// EdgeLookupDriver Edge Node 按型号查询驱动（打印机安装时调用）
+func (h *DriverHandler) EdgeLookupDriver(c *gin.Context) {
+	edgeNodeID := c.Param("node_id")
+	if _, err := h.edgeNodeRepo.GetEdgeNodeByID(edgeNodeID); err != nil {
+		BadRequestResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	model := c.Query("model")
+	if model == "" {
+		BadRequestResponse(c, "model 参数不能为空")
+		return
+	}
+
+	driver, err := h.driverRepo.FindDriverForModel(model)
+	if err != nil {
+		log.Printf("Failed to resolve driver for model %s: %v", model, err)
+		InternalErrorResponse(c, "查询驱动失败")
+		return
+	}
+	if driver == nil {
+		NotFoundResponse(c, "未找到匹配的驱动")
+		return
+	}
+
+	SuccessResponse(c, driver)
+}
+
+// EdgeDownloadPPD Edge Node 下载驱动的 PPD 文件
+func (h *DriverHandler) EdgeDownloadPPD(c *gin.Context) {
+	edgeNodeID := c.Param("node_id")
+	if _, err := h.edgeNodeRepo.GetEdgeNodeByID(edgeNodeID); err != nil {
+		BadRequestResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	driver, err := h.driverRepo.GetDriverByID(c.Param("driver_id"))
+	if err != nil {
+		NotFoundResponse(c, "驱动不存在")
+		return
+	}
+
+	h.servePPD(c, driver)
+}
+
+// servePPD 输出 PPD 文件
+func (h *DriverHandler) servePPD(c *gin.Context, driver *models.PrinterDriver) {
+	if driver.PPDFile == "" {
+		NotFoundResponse(c, "该驱动未上传 PPD 文件")
+		return
+	}
+
+	path := h.ppdPath(driver.ID)
+	if _, err := os.Stat(path); err != nil {
+		NotFoundResponse(c, "PPD 文件不存在")
+		return
+	}
+
+	c.FileAttachment(path, driver.PPDFile)
+}
+
+// ppdPath PPD 文件存储路径（按驱动ID命名，避免使用用户提供的文件名）
+func (h *DriverHandler) ppdPath(driverID string) string {
+	return filepath.Join(h.cfg().PPDDir, driverID+".ppd")
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+// credentialTouchMinimum last_used_at 的最小更新间隔，避免每个请求都写数据库
+const credentialTouchMinimum = time.Minute
+
+// EdgeCredentialHandler Edge Node 客户端证书凭据处理器
+type EdgeCredentialHandler struct {
+	credentialRepo *database.EdgeNodeCredentialRepository
+	edgeNodeRepo   *database.EdgeNodeRepository
+	wsManager      *websocket.ConnectionManager
+	auditRepo      *database.AuditLogRepository
+}
+
+// NewEdgeCredentialHandler 创建 Edge Node 客户端证书凭据处理器
+func NewEdgeCredentialHandler(credentialRepo *database.EdgeNodeCredentialRepository, edgeNodeRepo *database.EdgeNodeRepository, wsManager *websocket.ConnectionManager, auditRepo *database.AuditLogRepository) *EdgeCredentialHandler {
+	return &EdgeCredentialHandler{
+		credentialRepo: credentialRepo,
+		edgeNodeRepo:   edgeNodeRepo,
+		wsManager:      wsManager,
+		auditRepo:      auditRepo,
+	}
+}
+
+// CreateEdgeCredentialRequest 登记客户端证书请求
+type CreateEdgeCredentialRequest struct {
+	Subject     string `json:"subject" binding:"required,max=255"` // 证书 CN 或 SAN（DNS、URI、邮箱）
+	Description string `json:"description" binding:"max=255"`
+}
+
+// ListCredentials 获取节点的客户端证书凭据（含已禁用）
+func (h *EdgeCredentialHandler) ListCredentials(c *gin.Context) {
+	nodeID := c.Param("id")
+	credentials, err := h.credentialRepo.ListCredentialsByNode(nodeID)
+	if err != nil {
+		log.Printf("Failed to list credentials of edge node %s: %v", nodeID, err)
+		InternalErrorResponse(c, "获取节点凭据失败")
+		return
+	}
+	SuccessResponse(c, gin.H{"items": credentials})
+}
+
+// CreateCredential 为节点登记客户端证书，之后该节点可出示 CN 或 SAN 与 subject 一致的证书代替 token
+func (h *EdgeCredentialHandler) CreateCredential(c *gin.Context) {
+	nodeID := c.Param("id")
+	var req CreateEdgeCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	req.Subject = strings.TrimSpace(req.Subject)
+	if req.Subject == "" {
+		BadRequestResponse(c, "证书 subject 不能为空")
+		return
+	}
+
+	if _, err := h.edgeNodeRepo.GetEdgeNodeByID(nodeID); err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	credential := &models.EdgeNodeCredential{
+		NodeID:      nodeID,
+		Subject:     req.Subject,
+		Description: strings.TrimSpace(req.Description),
+		CreatedBy:   c.GetString("username"),
+	}
+	if err := h.credentialRepo.CreateCredential(credential); err != nil {
+		if errors.Is(err, database.ErrCredentialSubjectTaken) {
+			ErrorResponse(c, http.StatusConflict, "该证书 subject 已登记到启用的凭据，请先禁用原凭据")
+			return
+		}
+		log.Printf("Failed to create credential for edge node %s: %v", nodeID, err)
+		InternalErrorResponse(c, "登记节点凭据失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "edge_node.credential.create", "edge_node", nodeID,
+		fmt.Sprintf("credential_id=%s, subject=%s", credential.ID, credential.Subject))
+
+	CreatedResponse(c, credential)
+}
+
+// DisableCredential 禁用节点的客户端证书凭据（吊销），立即生效并断开节点当前的 WebSocket 连接
+// 凭据只禁用不删除，保留登记记录；同一 subject 可重新登记
+func (h *EdgeCredentialHandler) DisableCredential(c *gin.Context) {
+	nodeID := c.Param("id")
+	credentialID := c.Param("credential_id")
+	disabled, err := h.credentialRepo.DisableCredential(nodeID, credentialID)
+	if err != nil {
+		log.Printf("Failed to disable credential %s of edge node %s: %v", credentialID, nodeID, err)
+		InternalErrorResponse(c, "禁用节点凭据失败")
+		return
+	}
+	if !disabled {
+		NotFoundResponse(c, "凭据不存在或已禁用")
+		return
+	}
+
+	// 已建立的连接不会重新校验证书，吊销时主动断开，节点重连时按新的凭据状态认证
+	if err := h.wsManager.CloseConnection(nodeID, "client certificate revoked"); err != nil && !errors.Is(err, websocket.ErrNodeNotConnected) {
+		log.Printf("Failed to close connection of edge node %s after credential revocation: %v", nodeID, err)
+	}
+
+	recordAudit(c, h.auditRepo, "edge_node.credential.disable", "edge_node", nodeID,
+		fmt.Sprintf("credential_id=%s", credentialID))
+
+	SuccessResponse(c, gin.H{"id": credentialID})
+}
+
+// Authenticate 将已通过 CA 校验的客户端证书映射到节点（供认证中间件和 WebSocket 握手使用）
+// 依次按 CN、SAN 查找启用的凭据；last_used_at 距上次记录超过一分钟时才更新
+func (h *EdgeCredentialHandler) Authenticate(cert *x509.Certificate) (*middleware.ClientCertIdentity, error) {
+	credential, err := h.credentialRepo.FindEnabledCredential(middleware.CertificateSubjects(cert))
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, middleware.ErrUnknownClientCert
+	}
+
+	now := time.Now().UTC()
+	if credential.LastUsedAt == nil || now.Sub(*credential.LastUsedAt) >= credentialTouchMinimum {
+		if err := h.credentialRepo.TouchCredential(credential.ID, now); err != nil {
+			log.Printf("Failed to record edge node credential %s usage: %v", credential.ID, err)
+		}
+	}
+
+	return &middleware.ClientCertIdentity{
+		CredentialID: credential.ID,
+		NodeID:       credential.NodeID,
+		Subject:      credential.Subject,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/websocket"
+)
+
+// fakeConnections 记录调用次数的连接状态
+type fakeConnections struct {
+	infos         map[string]websocket.ConnectionInfo
+	snapshotCalls int
+	getCalls      int
+}
+
+func (f *fakeConnections) GetConnectionInfo(nodeID string) websocket.ConnectionInfo {
+	f.getCalls++
+	return f.infos[nodeID]
+}
+
+func (f *fakeConnections) SnapshotConnections() map[string]websocket.ConnectionInfo {
+	f.snapshotCalls++
+	snapshot := make(map[string]websocket.ConnectionInfo, len(f.infos))
+	for id, info := range f.infos {
+		snapshot[id] = info
+	}
+	return snapshot
+}
+
+func (f *fakeConnections) ResetReplacements(nodeID string) {}
+
+var _ edgeConnections = (*websocket.ConnectionManager)(nil)
+
+func TestApplyConnectionInfo(t *testing.T) {
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	lastMessage := since.Add(time.Minute)
+
+	tests := []struct {
+		name          string
+		status        models.NodeStatus
+		conn          websocket.ConnectionInfo
+		wantConnected bool
+		wantTransport string
+		wantSince     bool
+		wantLastMsg   bool
+		wantSkewMS    *int64
+	}{
+		{"websocket", models.NodeStatusOnline, websocket.ConnectionInfo{Connected: true, ConnectedSince: since, LastMessageAt: lastMessage}, true, transportWebSocket, true, true, nil},
+		{"websocket without messages", models.NodeStatusOnline, websocket.ConnectionInfo{Connected: true, ConnectedSince: since}, true, transportWebSocket, true, false, nil},
+		{"websocket with clock skew", models.NodeStatusOnline, websocket.ConnectionInfo{Connected: true, ConnectedSince: since, ClockSkew: 1500 * time.Millisecond, HasClockSkew: true}, true, transportWebSocket, true, false, int64Ptr(1500)},
+		{"rest heartbeat", models.NodeStatusOnline, websocket.ConnectionInfo{}, false, transportREST, false, false, nil},
+		{"offline", models.NodeStatusOffline, websocket.ConnectionInfo{}, false, "", false, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := newEdgeNodeInfo(&models.EdgeNode{ID: "node-1", Status: tt.status}, 0)
+			info.applyConnectionInfo(tt.conn)
+
+			if info.Connected != tt.wantConnected || info.Transport != tt.wantTransport {
+				t.Fatalf("connected=%v transport=%q, want %v %q", info.Connected, info.Transport, tt.wantConnected, tt.wantTransport)
+			}
+			if (info.ConnectionSince != nil) != tt.wantSince || (tt.wantSince && !info.ConnectionSince.Equal(since)) {
+				t.Fatalf("connection_since = %v", info.ConnectionSince)
+			}
+			if (info.LastMessageAt != nil) != tt.wantLastMsg || (tt.wantLastMsg && !info.LastMessageAt.Equal(lastMessage)) {
+				t.Fatalf("last_message_at = %v", info.LastMessageAt)
+			}
+			if (info.ClockSkewMS == nil) != (tt.wantSkewMS == nil) || (tt.wantSkewMS != nil && *info.ClockSkewMS != *tt.wantSkewMS) {
+				t.Fatalf("clock_skew_ms = %v, want %v", info.ClockSkewMS, tt.wantSkewMS)
+			}
+		})
+	}
+}
+
+// TestApplyConnectionsSnapshotOnce 列表只获取一次连接快照，不逐行查询连接状态
+func TestApplyConnectionsSnapshotOnce(t *testing.T) {
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	conns := &fakeConnections{infos: map[string]websocket.ConnectionInfo{
+		"node-ws": {Connected: true, ConnectedSince: since},
+	}}
+	nodeInfos := []EdgeNodeInfo{
+		newEdgeNodeInfo(&models.EdgeNode{ID: "node-ws", Status: models.NodeStatusOnline}, 2),
+		newEdgeNodeInfo(&models.EdgeNode{ID: "node-rest", Status: models.NodeStatusOnline}, 1),
+		newEdgeNodeInfo(&models.EdgeNode{ID: "node-offline", Status: models.NodeStatusOffline}, 0),
+	}
+
+	applyConnections(nodeInfos, conns)
+
+	if conns.snapshotCalls != 1 || conns.getCalls != 0 {
+		t.Fatalf("snapshot calls = %d, get calls = %d, want 1 and 0", conns.snapshotCalls, conns.getCalls)
+	}
+	want := []struct {
+		connected bool
+		transport string
+	}{
+		{true, transportWebSocket},
+		{false, transportREST},
+		{false, ""},
+	}
+	for i, w := range want {
+		if nodeInfos[i].Connected != w.connected || nodeInfos[i].Transport != w.transport {
+			t.Fatalf("%s: connected=%v transport=%q, want %v %q", nodeInfos[i].ID,
+				nodeInfos[i].Connected, nodeInfos[i].Transport, w.connected, w.transport)
+		}
+	}
+	if nodeInfos[0].PrinterCount != 2 {
+		t.Fatalf("printer_count = %d, want 2", nodeInfos[0].PrinterCount)
+	}
+}
+
+func int64Ptr(v int64) *int64 { return &v }
@@ -1,12 +1,21 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"fly-print-cloud/api/internal/config"
 	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/middleware"
 	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/websocket"
+	"fly-print-cloud/api/internal/worker"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,13 +23,36 @@ import (
 type EdgeNodeHandler struct {
 	edgeNodeRepo *database.EdgeNodeRepository
 	printerRepo  *database.PrinterRepository
+	monitor      *worker.HeartbeatMonitor
+	wsManager    edgeConnections
+	settings     *config.Store // 能力描述按当前配置生成
+	assetRepo    *database.AssetRepository
+	auditRepo    *database.AuditLogRepository
+	printJobRepo *database.PrintJobRepository
+	statsCache   edgeNodeStatsCache
+	summaryCache edgeNodeSummaryCache
+	connTokens   *middleware.ConnTokenSigner
+}
+
+// edgeConnections EdgeNodeHandler 读取的节点连接状态（*websocket.ConnectionManager 实现）
+type edgeConnections interface {
+	GetConnectionInfo(nodeID string) websocket.ConnectionInfo
+	SnapshotConnections() map[string]websocket.ConnectionInfo
+	ResetReplacements(nodeID string)
 }
 
 // NewEdgeNodeHandler 创建 Edge Node 管理处理器
-func NewEdgeNodeHandler(edgeNodeRepo *database.EdgeNodeRepository, printerRepo *database.PrinterRepository) *EdgeNodeHandler {
+func NewEdgeNodeHandler(edgeNodeRepo *database.EdgeNodeRepository, printerRepo *database.PrinterRepository, monitor *worker.HeartbeatMonitor, wsManager *websocket.ConnectionManager, settings *config.Store, assetRepo *database.AssetRepository, auditRepo *database.AuditLogRepository, printJobRepo *database.PrintJobRepository, connTokens *middleware.ConnTokenSigner) *EdgeNodeHandler {
 	return &EdgeNodeHandler{
 		edgeNodeRepo: edgeNodeRepo,
 		printerRepo:  printerRepo,
+		monitor:      monitor,
+		wsManager:    wsManager,
+		settings:     settings,
+		assetRepo:    assetRepo,
+		auditRepo:    auditRepo,
+		printJobRepo: printJobRepo,
+		connTokens:   connTokens,
 	}
 }
 
@@ -30,50 +62,81 @@ type RegisterEdgeNodeRequest struct {
 	Name   string `json:"name" binding:"required,min=1,max=100"`
 }
 
-// UpdateEdgeNodeRequest Edge Node 更新请求
+// UpdateEdgeNodeRequest Edge Node 更新请求（可为空的字段未提供时置为 NULL）
 type UpdateEdgeNodeRequest struct {
-	Name              string   `json:"name" binding:"required,min=1,max=100"`
-	Status            string   `json:"status" binding:"omitempty,oneof=online offline maintenance"`
-	Enabled           *bool    `json:"enabled"`  // 使用指针类型以区分未设置和false
-	Version           string   `json:"version"`
-	Location          string   `json:"location"`
-	Latitude          *float64 `json:"latitude"`
-	Longitude         *float64 `json:"longitude"`
-	IPAddress         *string  `json:"ip_address"`
-	MACAddress        string   `json:"mac_address"`
-	NetworkInterface  string   `json:"network_interface"`
-	OSVersion         string   `json:"os_version"`
-	CPUInfo           string   `json:"cpu_info"`
-	MemoryInfo        string   `json:"memory_info"`
-	DiskInfo          string   `json:"disk_info"`
-	ConnectionQuality string   `json:"connection_quality"`
-	Latency           int      `json:"latency"`
+	Name              string            `json:"name" binding:"required,min=1,max=100"`
+	Status            models.NodeStatus `json:"status" binding:"omitempty,node_status"`
+	Enabled           *bool             `json:"enabled"` // 使用指针类型以区分未设置和false
+	Version           *string           `json:"version"`
+	Location          *string           `json:"location"`
+	Latitude          *float64          `json:"latitude"`
+	Longitude         *float64          `json:"longitude"`
+	IPAddress         *string           `json:"ip_address"`
+	MACAddress        *string           `json:"mac_address"`
+	NetworkInterface  *string           `json:"network_interface"`
+	OSVersion         *string           `json:"os_version"`
+	CPUInfo           *string           `json:"cpu_info"`
+	MemoryInfo        *string           `json:"memory_info"`
+	DiskInfo          *string           `json:"disk_info"`
+	ConnectionQuality *string           `json:"connection_quality"`
+	Latency           *int              `json:"latency"`
+	RowVersion        *int              `json:"row_version"` // 读取时的数据版本号，未提供 If-Match 请求头时必填
+	AssetUpdateRequest                  // 资产信息（购买日期、保修到期日、资产编号、备注）
 }
 
-
-// EdgeNodeInfo Edge Node 信息响应
+// EdgeNodeInfo Edge Node 信息响应（节点字段与 models.EdgeNode 一致，另附连接状态）
 type EdgeNodeInfo struct {
-	ID                string    `json:"id"`
-	Name              string    `json:"name"`
-	Status            string    `json:"status"`
-	Enabled           bool      `json:"enabled"`
-	Version           string    `json:"version"`
-	LastHeartbeat     time.Time `json:"last_heartbeat"`
-	Location          string    `json:"location"`
-	Latitude          *float64  `json:"latitude"`
-	Longitude         *float64  `json:"longitude"`
-	IPAddress         *string   `json:"ip_address,omitempty"`
-	MACAddress        string    `json:"mac_address"`
-	NetworkInterface  string    `json:"network_interface"`
-	OSVersion         string    `json:"os_version"`
-	CPUInfo           string    `json:"cpu_info"`
-	MemoryInfo        string    `json:"memory_info"`
-	DiskInfo          string    `json:"disk_info"`
-	ConnectionQuality string    `json:"connection_quality"`
-	Latency           int       `json:"latency"`
-	PrinterCount      int       `json:"printer_count"`    // 管理的打印机数量
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	*models.EdgeNode
+	PrinterCount    int        `json:"printer_count"`              // 管理的打印机数量
+	Connected       bool       `json:"connected"`                  // 是否存在活跃的 WebSocket 连接
+	ConnectionSince *time.Time `json:"connection_since,omitempty"` // 当前 WebSocket 连接建立时间
+	LastMessageAt   *time.Time `json:"last_message_at,omitempty"`  // 最近一次收到 WebSocket 消息的时间
+	Transport       string     `json:"transport,omitempty"`        // websocket 或 rest（仅 REST 心跳）
+	ClockSkewMS     *int64     `json:"clock_skew_ms,omitempty"`    // 节点时钟偏差（毫秒，正数表示节点时间偏快）
+	Asset           *models.AssetInfo `json:"asset,omitempty"`   // 资产信息（仅管理界面接口返回）
+	ActiveJobs      *int              `json:"active_jobs,omitempty"` // 已分发且尚未结束的任务数（仅排空接口返回）
+}
+
+// newEdgeNodeInfo 构造 Edge Node 信息响应
+func newEdgeNodeInfo(node *models.EdgeNode, printerCount int) EdgeNodeInfo {
+	return EdgeNodeInfo{EdgeNode: node, PrinterCount: printerCount}
+}
+
+// 节点通信方式
+const (
+	transportWebSocket = "websocket"
+	transportREST      = "rest"
+)
+
+// applyConnectionInfo 将 WebSocket 连接状态填充到节点信息中
+func (info *EdgeNodeInfo) applyConnectionInfo(conn websocket.ConnectionInfo) {
+	info.Connected = conn.Connected
+	if conn.Connected {
+		info.Transport = transportWebSocket
+		since := conn.ConnectedSince
+		info.ConnectionSince = &since
+		if !conn.LastMessageAt.IsZero() {
+			lastMessageAt := conn.LastMessageAt
+			info.LastMessageAt = &lastMessageAt
+		}
+		if conn.HasClockSkew {
+			skewMS := conn.ClockSkew.Milliseconds()
+			info.ClockSkewMS = &skewMS
+		}
+		return
+	}
+	// 无活跃连接但节点在线，说明在线状态来自 REST 心跳
+	if info.Status == models.NodeStatusOnline {
+		info.Transport = transportREST
+	}
+}
+
+// applyConnections 一次性获取全部连接状态并填充到节点列表，避免逐行加锁
+func applyConnections(nodeInfos []EdgeNodeInfo, conns edgeConnections) {
+	connections := conns.SnapshotConnections()
+	for i := range nodeInfos {
+		nodeInfos[i].applyConnectionInfo(connections[nodeInfos[i].ID])
+	}
 }
 
 // RegisterEdgeNode 注册 Edge Node
@@ -84,12 +147,17 @@ func (h *EdgeNodeHandler) RegisterEdgeNode(c *gin.Context) {
 		return
 	}
 
+	if !requireCertNode(c, req.NodeID) {
+		return
+	}
+
 	// 创建 Edge Node（按照README规划，只设置基本信息）
+	now := time.Now().UTC()
 	node := &models.EdgeNode{
 		ID:            req.NodeID, // 使用客户端提供的 node_id
 		Name:          req.Name,
-		Status:        "online", // 注册时默认为在线状态
-		LastHeartbeat: time.Now(),
+		Status:        models.NodeStatusOnline, // 注册时默认为在线状态
+		LastHeartbeat: &now,
 	}
 
 	if err := h.edgeNodeRepo.UpsertEdgeNode(node); err != nil {
@@ -98,32 +166,44 @@ func (h *EdgeNodeHandler) RegisterEdgeNode(c *gin.Context) {
 		return
 	}
 
-	// 返回节点信息
-	nodeInfo := EdgeNodeInfo{
-		ID:                node.ID,
-		Name:              node.Name,
-		Status:            node.Status,
-		Enabled:           node.Enabled,
-		Version:           node.Version,
-		LastHeartbeat:     node.LastHeartbeat,
-		Location:          node.Location,
-		Latitude:          node.Latitude,
-		Longitude:         node.Longitude,
-		IPAddress:         node.IPAddress,
-		MACAddress:        node.MACAddress,
-		NetworkInterface:  node.NetworkInterface,
-		OSVersion:         node.OSVersion,
-		CPUInfo:           node.CPUInfo,
-		MemoryInfo:        node.MemoryInfo,
-		DiskInfo:          node.DiskInfo,
-		ConnectionQuality: node.ConnectionQuality,
-		Latency:           node.Latency,
-		CreatedAt:         node.CreatedAt,
-		UpdatedAt:         node.UpdatedAt,
+	connToken, ok := h.issueConnToken(c, node.ID)
+	if !ok {
+		return
 	}
 
 	log.Printf("Edge Node %s registered successfully", node.Name)
-	CreatedResponse(c, nodeInfo)
+	CreatedResponse(c, struct {
+		EdgeNodeInfo
+		*ConnTokenResponse
+	}{newEdgeNodeInfo(node, 0), connToken})
+}
+
+// requireCertNode 使用客户端证书认证时，请求体中的 node_id 必须是证书所属的节点；不一致时写入 403 响应
+// token 认证的请求不受影响（路径中带 node_id 的接口已由认证中间件校验）
+func requireCertNode(c *gin.Context, nodeID string) bool {
+	if c.GetString("client_cert_credential_id") == "" || nodeID == c.GetString("token_node_id") {
+		return true
+	}
+	log.Printf("Edge node request rejected: client certificate for node %q used for node %q", c.GetString("token_node_id"), nodeID)
+	ForbiddenResponse(c, "客户端证书与节点身份不匹配")
+	return false
+}
+
+// ConnTokenResponse 注册和心跳响应中的 WebSocket 连接令牌，连接 /api/v1/edge/ws 时通过 ?conn_token= 携带
+type ConnTokenResponse struct {
+	ConnToken          string    `json:"conn_token"`
+	ConnTokenExpiresAt time.Time `json:"conn_token_expires_at"`
+}
+
+// issueConnToken 为节点签发连接令牌，令牌绑定 node_id 和请求 token 的 subject；失败时写入 500 响应
+func (h *EdgeNodeHandler) issueConnToken(c *gin.Context, nodeID string) (*ConnTokenResponse, bool) {
+	token, expiresAt, err := h.connTokens.Issue(nodeID, c.GetString("external_id"), h.settings.Get().Edge.ConnTokenTTL)
+	if err != nil {
+		log.Printf("Failed to issue connection token for edge node %s: %v", nodeID, err)
+		InternalErrorResponse(c, "签发连接令牌失败")
+		return nil, false
+	}
+	return &ConnTokenResponse{ConnToken: token, ConnTokenExpiresAt: expiresAt}, true
 }
 
 // ListEdgeNodes 获取 Edge Node 列表
@@ -142,16 +222,9 @@ func (h *EdgeNodeHandler) ListEdgeNodes(c *gin.Context) {
 
 	offset := (page - 1) * pageSize
 
-	// 可选：检查并更新超时的节点状态（3分钟超时）
-	if updatedCount, err := h.edgeNodeRepo.CheckAndUpdateOfflineNodes(3); err != nil {
-		log.Printf("⚠️ [DEBUG] Failed to check offline nodes: %v", err)
-	} else if updatedCount > 0 {
-		log.Printf("📱 [DEBUG] Updated %d nodes to offline status", updatedCount)
-	}
-
 	// 查询 Edge Node 列表
 	log.Printf("🔍 [DEBUG] 查询Edge Nodes: offset=%d, pageSize=%d, status='%s'", offset, pageSize, status)
-	nodes, total, err := h.edgeNodeRepo.ListEdgeNodes(offset, pageSize, status)
+	nodes, total, err := h.edgeNodeRepo.ListEdgeNodes(offset, pageSize, models.NodeStatus(status))
 	if err != nil {
 		log.Printf("❌ [DEBUG] Failed to list edge nodes: %v", err)
 		InternalErrorResponse(c, "获取 Edge Node 列表失败")
@@ -169,28 +242,23 @@ func (h *EdgeNodeHandler) ListEdgeNodes(c *gin.Context) {
 			printerCount = 0 // 如果查询失败，设置为0
 		}
 		
-		nodeInfos[i] = EdgeNodeInfo{
-			ID:                node.ID,
-			Name:              node.Name,
-			Status:            node.Status,
-			Enabled:           node.Enabled,
-			Version:           node.Version,
-			LastHeartbeat:     node.LastHeartbeat,
-			Location:          node.Location,
-			Latitude:          node.Latitude,
-			Longitude:         node.Longitude,
-			IPAddress:         node.IPAddress,
-			MACAddress:        node.MACAddress,
-			NetworkInterface:  node.NetworkInterface,
-			OSVersion:         node.OSVersion,
-			CPUInfo:           node.CPUInfo,
-			MemoryInfo:        node.MemoryInfo,
-			DiskInfo:          node.DiskInfo,
-			ConnectionQuality: node.ConnectionQuality,
-			Latency:           node.Latency,
-			PrinterCount:      printerCount,
-			CreatedAt:         node.CreatedAt,
-			UpdatedAt:         node.UpdatedAt,
+		nodeInfos[i] = newEdgeNodeInfo(node, printerCount)
+	}
+	applyConnections(nodeInfos, h.wsManager)
+
+	// 资产信息
+	if len(nodes) > 0 {
+		ids := make([]string, len(nodes))
+		for i, node := range nodes {
+			ids[i] = node.ID
+		}
+		assets, err := h.assetRepo.GetAssets(models.AssetResourceEdgeNode, ids)
+		if err != nil {
+			log.Printf("Failed to get edge node asset info: %v", err)
+		} else {
+			for i := range nodeInfos {
+				nodeInfos[i].Asset = assets[nodeInfos[i].ID]
+			}
 		}
 	}
 
@@ -210,6 +278,7 @@ func (h *EdgeNodeHandler) GetEdgeNode(c *gin.Context) {
 		NotFoundResponse(c, "Edge Node 不存在")
 		return
 	}
+	setVersionETag(c, node.RowVersion)
 
 	// 获取该边缘节点管理的打印机数量
 	printerCount, err := h.printerRepo.CountPrintersByEdgeNode(node.ID)
@@ -218,28 +287,10 @@ func (h *EdgeNodeHandler) GetEdgeNode(c *gin.Context) {
 		printerCount = 0 // 如果查询失败，设置为0
 	}
 
-	nodeInfo := EdgeNodeInfo{
-		ID:                node.ID,
-		Name:              node.Name,
-		Status:            node.Status,
-		Enabled:           node.Enabled,
-		Version:           node.Version,
-		LastHeartbeat:     node.LastHeartbeat,
-		Location:          node.Location,
-		Latitude:          node.Latitude,
-		Longitude:         node.Longitude,
-		IPAddress:         node.IPAddress,
-		MACAddress:        node.MACAddress,
-		NetworkInterface:  node.NetworkInterface,
-		OSVersion:         node.OSVersion,
-		CPUInfo:           node.CPUInfo,
-		MemoryInfo:        node.MemoryInfo,
-		DiskInfo:          node.DiskInfo,
-		ConnectionQuality: node.ConnectionQuality,
-		Latency:           node.Latency,
-		PrinterCount:      printerCount,
-		CreatedAt:         node.CreatedAt,
-		UpdatedAt:         node.UpdatedAt,
+	nodeInfo := newEdgeNodeInfo(node, printerCount)
+	nodeInfo.applyConnectionInfo(h.wsManager.GetConnectionInfo(node.ID))
+	if nodeInfo.Asset, err = h.assetRepo.GetAsset(models.AssetResourceEdgeNode, node.ID); err != nil {
+		log.Printf("Failed to get asset info for edge node %s: %v", node.ID, err)
 	}
 
 	SuccessResponse(c, nodeInfo)
@@ -258,6 +309,9 @@ func (h *EdgeNodeHandler) UpdateEdgeNode(c *gin.Context) {
 		ValidationErrorResponse(c, err)
 		return
 	}
+	if !normalizeNetworkAddresses(c, &req.IPAddress, &req.MACAddress) {
+		return
+	}
 
 	// 检查节点是否存在
 	node, err := h.edgeNodeRepo.GetEdgeNodeByID(nodeID)
@@ -265,6 +319,15 @@ func (h *EdgeNodeHandler) UpdateEdgeNode(c *gin.Context) {
 		NotFoundResponse(c, "Edge Node 不存在")
 		return
 	}
+	if !checkVersion(c, req.RowVersion, node.RowVersion) {
+		return
+	}
+
+	// 资产信息先校验保存，校验失败时不修改节点
+	asset, ok := applyAssetUpdate(c, h.assetRepo, h.auditRepo, models.AssetResourceEdgeNode, node.ID, &req.AssetUpdateRequest)
+	if !ok {
+		return
+	}
 
 	// 更新节点信息
 	node.Name = req.Name
@@ -275,8 +338,17 @@ func (h *EdgeNodeHandler) UpdateEdgeNode(c *gin.Context) {
 	}
 	
 	// 处理Enabled字段更新（逻辑级联，不修改printer的enable状态）
+	// 直接启用或禁用节点时取消正在进行的排空
 	if req.Enabled != nil {
 		node.Enabled = *req.Enabled
+		if node.Draining {
+			if err := h.edgeNodeRepo.ClearDrain(node.ID); err != nil {
+				log.Printf("Failed to clear drain of edge node %s: %v", nodeID, err)
+				InternalErrorResponse(c, "更新 Edge Node 失败")
+				return
+			}
+			node.Draining, node.DrainStartedAt, node.DrainDeadline = false, nil, nil
+		}
 	}
 	
 	node.Version = req.Version
@@ -294,33 +366,19 @@ func (h *EdgeNodeHandler) UpdateEdgeNode(c *gin.Context) {
 	node.Latency = req.Latency
 
 	if err := h.edgeNodeRepo.UpdateEdgeNode(node); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			versionConflictResponse(c)
+			return
+		}
 		log.Printf("Failed to update edge node %s: %v", nodeID, err)
 		InternalErrorResponse(c, "更新 Edge Node 失败")
 		return
 	}
+	setVersionETag(c, node.RowVersion)
 
-	nodeInfo := EdgeNodeInfo{
-		ID:                node.ID,
-		Name:              node.Name,
-		Status:            node.Status,
-		Enabled:           node.Enabled,
-		Version:           node.Version,
-		LastHeartbeat:     node.LastHeartbeat,
-		Location:          node.Location,
-		Latitude:          node.Latitude,
-		Longitude:         node.Longitude,
-		IPAddress:         node.IPAddress,
-		MACAddress:        node.MACAddress,
-		NetworkInterface:  node.NetworkInterface,
-		OSVersion:         node.OSVersion,
-		CPUInfo:           node.CPUInfo,
-		MemoryInfo:        node.MemoryInfo,
-		DiskInfo:          node.DiskInfo,
-		ConnectionQuality: node.ConnectionQuality,
-		Latency:           node.Latency,
-		CreatedAt:         node.CreatedAt,
-		UpdatedAt:         node.UpdatedAt,
-	}
+	nodeInfo := newEdgeNodeInfo(node, 0)
+	nodeInfo.applyConnectionInfo(h.wsManager.GetConnectionInfo(node.ID))
+	nodeInfo.Asset = asset
 
 	log.Printf("Edge Node %s updated successfully", node.Name)
 	SuccessResponse(c, nodeInfo)
@@ -352,11 +410,116 @@ func (h *EdgeNodeHandler) DeleteEdgeNode(c *gin.Context) {
 	SuccessResponse(c, gin.H{"message": "Edge Node 删除成功"})
 }
 
+// DrainEdgeNode 排空 Edge Node：不再向节点的打印机分发新任务，已分发的任务继续执行；
+// 任务全部结束或超过最长排空时间（edge.max_drain_time）后节点自动禁用。对排空中的节点重复调用直接返回当前状态
+func (h *EdgeNodeHandler) DrainEdgeNode(c *gin.Context) {
+	nodeID := c.Param("id")
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+	if !node.Enabled {
+		ErrorResponse(c, http.StatusConflict, "Edge Node 已禁用，无需排空")
+		return
+	}
+
+	if !node.Draining {
+		maxDrainTime := h.settings.Get().Edge.MaxDrainTime
+		if maxDrainTime <= 0 {
+			maxDrainTime = 2 * time.Hour
+		}
+		drained, err := h.edgeNodeRepo.StartDrain(nodeID, time.Now().UTC().Add(maxDrainTime))
+		if err != nil {
+			log.Printf("Failed to drain edge node %s: %v", nodeID, err)
+			InternalErrorResponse(c, "排空 Edge Node 失败")
+			return
+		}
+		if drained == nil {
+			// 并发请求已开始排空或禁用了节点
+			ErrorResponse(c, http.StatusConflict, "Edge Node 状态已变化，请刷新后重试")
+			return
+		}
+		node = drained
+		recordAudit(c, h.auditRepo, "edge_node.drain", "edge_node", nodeID,
+			fmt.Sprintf("deadline=%s", node.DrainDeadline.Format(time.RFC3339)))
+		log.Printf("Edge Node %s draining until %s", nodeID, node.DrainDeadline.Format(time.RFC3339))
+	}
+
+	active, err := h.printJobRepo.CountActiveJobsByEdgeNode(nodeID)
+	if err != nil {
+		log.Printf("Failed to count active jobs of edge node %s: %v", nodeID, err)
+		InternalErrorResponse(c, "获取 Edge Node 任务失败")
+		return
+	}
+
+	nodeInfo := newEdgeNodeInfo(node, 0)
+	nodeInfo.applyConnectionInfo(h.wsManager.GetConnectionInfo(node.ID))
+	nodeInfo.ActiveJobs = &active
+	SuccessResponse(c, nodeInfo)
+}
+
+// AcknowledgeConflict 确认节点的 node_id 冲突已解决：清除 conflict_suspected 标记并重新统计连接替换次数
+func (h *EdgeNodeHandler) AcknowledgeConflict(c *gin.Context) {
+	nodeID := c.Param("id")
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+	if !node.ConflictSuspected {
+		ErrorResponse(c, http.StatusConflict, "Edge Node 未被标记为 node_id 冲突")
+		return
+	}
+
+	if _, err := h.edgeNodeRepo.ClearConflict(nodeID); err != nil {
+		log.Printf("Failed to clear node_id conflict of edge node %s: %v", nodeID, err)
+		InternalErrorResponse(c, "确认 node_id 冲突失败")
+		return
+	}
+	h.wsManager.ResetReplacements(nodeID)
+	recordAudit(c, h.auditRepo, "edge_node.conflict_ack", "edge_node", nodeID,
+		fmt.Sprintf("remote_addrs=%s", strings.Join(node.ConflictRemoteAddrs, ",")))
+	log.Printf("Edge Node %s node_id conflict acknowledged", nodeID)
+
+	node.ConflictSuspected, node.ConflictSuspectedAt, node.ConflictRemoteAddrs = false, nil, nil
+	nodeInfo := newEdgeNodeInfo(node, 0)
+	nodeInfo.applyConnectionInfo(h.wsManager.GetConnectionInfo(node.ID))
+	SuccessResponse(c, nodeInfo)
+}
+
 // HeartbeatRequest 心跳请求
 type HeartbeatRequest struct {
 	NodeID string `json:"node_id" binding:"required"`
 }
 
+// Capabilities 获取云端能力描述（版本、协议版本、消息大小上限、心跳间隔、功能开关）
+// 与 WebSocket 连接建立后 welcome 消息的 data 相同
+func (h *EdgeNodeHandler) Capabilities(c *gin.Context) {
+	SuccessResponse(c, websocket.NewServerCapabilities(&h.settings.Get().Edge))
+}
+
+// ProtocolSchemas 列出边缘协议的 JSON Schema 及协议版本
+func (h *EdgeNodeHandler) ProtocolSchemas(c *gin.Context) {
+	SuccessResponse(c, gin.H{
+		"protocol_versions":      websocket.SupportedProtocolVersions,
+		"protocol_minor_version": websocket.ProtocolMinorVersion,
+		"protocol_schema_digest": websocket.ProtocolSchemaDigest(),
+		"types":                  websocket.ProtocolSchemaNames(),
+	})
+}
+
+// ProtocolSchema 获取消息/指令类型（或 message、command、command_ack）的 JSON Schema，直接返回 Schema 文档
+func (h *EdgeNodeHandler) ProtocolSchema(c *gin.Context) {
+	schema, ok := websocket.ProtocolSchema(c.Param("type"))
+	if !ok {
+		NotFoundResponse(c, "未知的消息类型")
+		return
+	}
+	c.Header("X-Protocol-Minor-Version", strconv.Itoa(websocket.ProtocolMinorVersion))
+	c.JSON(http.StatusOK, schema)
+}
+
 // Heartbeat Edge Node 心跳
 func (h *EdgeNodeHandler) Heartbeat(c *gin.Context) {
 	var req HeartbeatRequest
@@ -364,20 +527,117 @@ func (h *EdgeNodeHandler) Heartbeat(c *gin.Context) {
 		ValidationErrorResponse(c, err)
 		return
 	}
+	if !requireCertNode(c, req.NodeID) {
+		return
+	}
 
-	// 更新心跳时间
-	if err := h.edgeNodeRepo.UpdateHeartbeat(req.NodeID); err != nil {
+	// 更新心跳时间并标记为在线（由离线恢复时同步恢复打印机状态）
+	if err := h.monitor.NodeSeen(req.NodeID); err != nil {
 		log.Printf("Failed to update heartbeat for edge node %s: %v", req.NodeID, err)
 		InternalErrorResponse(c, "更新心跳失败")
 		return
 	}
 
-	// 更新状态为在线
-	if err := h.edgeNodeRepo.UpdateStatus(req.NodeID, "online"); err != nil {
-		log.Printf("Failed to update status for edge node %s: %v", req.NodeID, err)
-		InternalErrorResponse(c, "更新状态失败")
+	// 续签连接令牌，长时间运行的节点重连时无需重新注册
+	connToken, ok := h.issueConnToken(c, req.NodeID)
+	if !ok {
+		return
+	}
+	SuccessResponse(c, struct {
+		Message string `json:"message"`
+		*ConnTokenResponse
+	}{"心跳更新成功", connToken})
+}
+
+// EdgeNodeSelfInfoRequest Edge Node 上报自身信息，只包含节点自身掌握的字段，未提供的字段保持不变
+type EdgeNodeSelfInfoRequest struct {
+	Version          *string  `json:"version" binding:"omitempty,max=50"`
+	OSVersion        *string  `json:"os_version" binding:"omitempty,max=100"`
+	CPUInfo          *string  `json:"cpu_info" binding:"omitempty,max=200"`
+	MemoryInfo       *string  `json:"memory_info" binding:"omitempty,max=100"`
+	DiskInfo         *string  `json:"disk_info" binding:"omitempty,max=100"`
+	NetworkInterface *string  `json:"network_interface" binding:"omitempty,max=50"`
+	IPAddress        *string  `json:"ip_address"`  // 由 normalizeNetworkAddresses 校验并规范化
+	MACAddress       *string  `json:"mac_address"` // 接受常见的各种分隔写法，统一为小写冒号形式
+	Latitude         *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`    // 仅在 edge.trust_node_location 开启时生效
+	Longitude        *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"` // 仅在 edge.trust_node_location 开启时生效
+
+	// 以下字段由管理员维护，节点提交时忽略并在响应中提示
+	Name    *string `json:"name"`
+	Enabled *bool   `json:"enabled"`
+	Site    *string `json:"site"`
+}
+
+// EdgeUpdateInfo Edge Node 更新自身信息（PUT /api/v1/edge/:node_id/info）
+// token 代表的节点必须与路径中的节点一致；名称、启用状态、站点等管理员字段不会被修改
+func (h *EdgeNodeHandler) EdgeUpdateInfo(c *gin.Context) {
+	nodeID := c.Param("node_id")
+	if nodeID != c.GetString("token_node_id") {
+		log.Printf("Edge node info update rejected: token node %q does not match path node %q", c.GetString("token_node_id"), nodeID)
+		ForbiddenResponse(c, "token 与节点身份不匹配")
+		return
+	}
+
+	var req EdgeNodeSelfInfoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	if !normalizeNetworkAddresses(c, &req.IPAddress, &req.MACAddress) {
 		return
 	}
 
-	SuccessResponse(c, gin.H{"message": "心跳更新成功"})
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	warnings := []string{}
+	for field, provided := range map[string]bool{"name": req.Name != nil, "enabled": req.Enabled != nil, "site": req.Site != nil} {
+		if provided {
+			warnings = append(warnings, fmt.Sprintf("%s 由管理员维护，已忽略", field))
+		}
+	}
+	sort.Strings(warnings)
+
+	update := &models.EdgeNode{
+		ID:               node.ID,
+		Version:          req.Version,
+		OSVersion:        req.OSVersion,
+		CPUInfo:          req.CPUInfo,
+		MemoryInfo:       req.MemoryInfo,
+		DiskInfo:         req.DiskInfo,
+		NetworkInterface: req.NetworkInterface,
+		IPAddress:        req.IPAddress,
+		MACAddress:       req.MACAddress,
+	}
+	if req.Latitude != nil || req.Longitude != nil {
+		if h.settings.Get().Edge.TrustNodeLocation {
+			update.Latitude, update.Longitude = req.Latitude, req.Longitude
+		} else {
+			warnings = append(warnings, "latitude/longitude 未启用节点上报（edge.trust_node_location），已忽略")
+		}
+	}
+
+	if err := h.edgeNodeRepo.UpdateEdgeNodeSelfInfo(update); err != nil {
+		log.Printf("Failed to update self-reported info of edge node %s: %v", nodeID, err)
+		InternalErrorResponse(c, "更新 Edge Node 信息失败")
+		return
+	}
+	if len(warnings) > 0 {
+		log.Printf("Edge node %s info update ignored fields: %v", nodeID, warnings)
+	}
+
+	updated, err := h.edgeNodeRepo.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		log.Printf("Failed to reload edge node %s: %v", nodeID, err)
+		InternalErrorResponse(c, "更新 Edge Node 信息失败")
+		return
+	}
+	SuccessResponse(c, gin.H{
+		"node":     updated,
+		"warnings": warnings,
+	})
 }
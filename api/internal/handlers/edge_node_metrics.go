@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// metricsMaxRange 单次查询的最大时间范围
+	metricsMaxRange = 31 * 24 * time.Hour
+	// metricsDefaultRange 未指定 from 时查询 to 之前的时长
+	metricsDefaultRange = 24 * time.Hour
+	// metricsMinStep 最小时间桶长度，小于心跳间隔的时间桶大多为空
+	metricsMinStep = time.Minute
+	// metricsMaxPoints 单条曲线的最大时间桶数，未指定 step 时按该值自动选择
+	metricsMaxPoints = 1500
+)
+
+// metricsWindow 资源曲线的查询范围和时间桶长度
+type metricsWindow struct {
+	from, to time.Time
+	step     time.Duration
+}
+
+// parseMetricsWindow 解析 from、to（RFC3339，默认最近 24 小时）和 step（如 5m、1h，或秒数；默认按最多 1500 个时间桶自动选择，取整到分钟）
+// 参数错误时已写入 400 响应
+func parseMetricsWindow(c *gin.Context) (metricsWindow, bool) {
+	var w metricsWindow
+	w.to = time.Now().UTC()
+	if raw := c.Query("to"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			BadRequestResponse(c, "to 参数格式错误，应为 RFC3339 时间")
+			return w, false
+		}
+		w.to = t.UTC()
+	}
+	w.from = w.to.Add(-metricsDefaultRange)
+	if raw := c.Query("from"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			BadRequestResponse(c, "from 参数格式错误，应为 RFC3339 时间")
+			return w, false
+		}
+		w.from = t.UTC()
+	}
+	if !w.from.Before(w.to) {
+		BadRequestResponse(c, "from 必须早于 to")
+		return w, false
+	}
+	span := w.to.Sub(w.from)
+	if span > metricsMaxRange {
+		BadRequestResponse(c, fmt.Sprintf("查询范围不能超过 %d 天", int(metricsMaxRange/(24*time.Hour))))
+		return w, false
+	}
+
+	if raw := c.Query("step"); raw != "" {
+		step, err := time.ParseDuration(raw)
+		if err != nil {
+			seconds, convErr := strconv.Atoi(raw)
+			if convErr != nil {
+				BadRequestResponse(c, "step 参数格式错误，应为时长（如 5m、1h）或秒数")
+				return w, false
+			}
+			step = time.Duration(seconds) * time.Second
+		}
+		if step < metricsMinStep || step%time.Second != 0 {
+			BadRequestResponse(c, fmt.Sprintf("step 必须为整秒且不小于 %s", metricsMinStep))
+			return w, false
+		}
+		if span/step >= metricsMaxPoints {
+			BadRequestResponse(c, fmt.Sprintf("时间桶数量不能超过 %d，请增大 step 或缩小查询范围", metricsMaxPoints))
+			return w, false
+		}
+		w.step = step
+	} else {
+		w.step = (span/metricsMaxPoints + time.Minute - 1).Truncate(time.Minute)
+		if w.step < metricsMinStep {
+			w.step = metricsMinStep
+		}
+	}
+	return w, true
+}
+
+// GetEdgeNodeMetrics 获取 Edge Node 心跳上报的 CPU、内存、磁盘和延迟的历史曲线（容量规划）
+// 采样按 step 在数据库中汇总为每个时间桶的平均值和最大值，没有采样的时间桶不返回
+func (h *EdgeNodeHandler) GetEdgeNodeMetrics(c *gin.Context) {
+	window, ok := parseMetricsWindow(c)
+	if !ok {
+		return
+	}
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	points, err := h.edgeNodeRepo.GetEdgeNodeMetrics(node.ID, window.from, window.to, window.step)
+	if err != nil {
+		log.Printf("Failed to get metrics for edge node %s: %v", node.ID, err)
+		InternalErrorResponse(c, "获取 Edge Node 资源曲线失败")
+		return
+	}
+	SuccessResponse(c, models.EdgeNodeMetricsSeries{
+		NodeID:      node.ID,
+		From:        window.from,
+		To:          window.to,
+		StepSeconds: int(window.step.Seconds()),
+		Points:      points,
+	})
+}
+
+// GetFleetMetrics 获取按站点（节点 location）分组的机队资源曲线，同一站点所有节点的采样合并汇总
+func (h *EdgeNodeHandler) GetFleetMetrics(c *gin.Context) {
+	window, ok := parseMetricsWindow(c)
+	if !ok {
+		return
+	}
+	sites, err := h.edgeNodeRepo.GetFleetMetrics(window.from, window.to, window.step)
+	if err != nil {
+		log.Printf("Failed to get fleet metrics: %v", err)
+		InternalErrorResponse(c, "获取机队资源曲线失败")
+		return
+	}
+	SuccessResponse(c, models.FleetMetricsSeries{
+		From:        window.from,
+		To:          window.to,
+		StepSeconds: int(window.step.Seconds()),
+		Sites:       sites,
+	})
+}
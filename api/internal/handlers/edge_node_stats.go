@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// edgeNodeStatsTTL 节点统计在服务端缓存的时间，避免自动刷新的仪表盘频繁查询数据库
+const edgeNodeStatsTTL = 30 * time.Second
+
+// edgeNodeStatsCache 按节点和时区缓存统计结果
+type edgeNodeStatsCache struct {
+	mu      sync.Mutex
+	entries map[string]*models.EdgeNodeStats
+}
+
+// edgeNodeStatsKey 缓存键：今日完成数按时区划分，不同时区分别缓存
+func edgeNodeStatsKey(nodeID, timezone string) string {
+	return nodeID + "|" + timezone
+}
+
+// get 返回未过期的缓存结果
+func (c *edgeNodeStatsCache) get(key string, now time.Time) *models.EdgeNodeStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats, ok := c.entries[key]
+	if !ok || now.Sub(stats.GeneratedAt) >= edgeNodeStatsTTL {
+		return nil
+	}
+	return stats
+}
+
+// put 写入缓存，同时清理已过期的条目
+func (c *edgeNodeStatsCache) put(key string, stats *models.EdgeNodeStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]*models.EdgeNodeStats{}
+	}
+	for existing, entry := range c.entries {
+		if stats.GeneratedAt.Sub(entry.GeneratedAt) >= edgeNodeStatsTTL {
+			delete(c.entries, existing)
+		}
+	}
+	c.entries[key] = stats
+}
+
+// GetEdgeNodeStats 获取 Edge Node 的任务统计与当前负载（结果缓存 30 秒）
+// 今日完成数按 tz 参数或部署时区划分自然日
+func (h *EdgeNodeHandler) GetEdgeNodeStats(c *gin.Context) {
+	loc, ok := statsTimezone(c, h.settings)
+	if !ok {
+		return
+	}
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	now := time.Now()
+	cacheKey := edgeNodeStatsKey(node.ID, loc.String())
+	if stats := h.statsCache.get(cacheKey, now); stats != nil {
+		SuccessResponse(c, stats)
+		return
+	}
+
+	today := startOfDay(now, loc)
+	stats, err := h.printJobRepo.GetEdgeNodeJobStats(node.ID, today.UTC(), now.AddDate(0, 0, -7).UTC())
+	if err != nil {
+		log.Printf("Failed to get job stats for edge node %s: %v", node.ID, err)
+		InternalErrorResponse(c, "获取 Edge Node 统计失败")
+		return
+	}
+	stats.PrinterStatuses, err = h.printerRepo.CountPrintersByStatus(node.ID)
+	if err != nil {
+		log.Printf("Failed to get printer statuses for edge node %s: %v", node.ID, err)
+		InternalErrorResponse(c, "获取 Edge Node 统计失败")
+		return
+	}
+	stats.Timezone = loc.String()
+	stats.GeneratedAt = now.UTC()
+
+	h.statsCache.put(cacheKey, stats)
+	SuccessResponse(c, stats)
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// edgeNodeSummaryCache 按节点和时区缓存节点看板汇总，有效期与管理端统计相同（edgeNodeStatsTTL）
+type edgeNodeSummaryCache struct {
+	mu      sync.Mutex
+	entries map[string]*models.EdgeNodeSummary
+}
+
+// get 返回未过期的缓存结果
+func (c *edgeNodeSummaryCache) get(key string, now time.Time) *models.EdgeNodeSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summary, ok := c.entries[key]
+	if !ok || now.Sub(summary.GeneratedAt) >= edgeNodeStatsTTL {
+		return nil
+	}
+	return summary
+}
+
+// put 写入缓存，同时清理已过期的条目
+func (c *edgeNodeSummaryCache) put(key string, summary *models.EdgeNodeSummary) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]*models.EdgeNodeSummary{}
+	}
+	for existing, entry := range c.entries {
+		if summary.GeneratedAt.Sub(entry.GeneratedAt) >= edgeNodeStatsTTL {
+			delete(c.entries, existing)
+		}
+	}
+	c.entries[key] = summary
+}
+
+// EdgeSummary 节点本地看板汇总（Edge Node 调用）：节点下打印机的状态、队列、今日完成/失败数和当前告警
+// token 代表的节点必须与路径中的节点一致，只返回该节点自己的数据；结果缓存 30 秒
+func (h *EdgeNodeHandler) EdgeSummary(c *gin.Context) {
+	nodeID := c.Param("node_id")
+	if nodeID != c.GetString("token_node_id") {
+		log.Printf("Edge node summary rejected: token node %q does not match path node %q", c.GetString("token_node_id"), nodeID)
+		ForbiddenResponse(c, "token 与节点身份不匹配")
+		return
+	}
+	loc, ok := statsTimezone(c, h.settings)
+	if !ok {
+		return
+	}
+
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(nodeID)
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	now := time.Now()
+	cacheKey := edgeNodeStatsKey(node.ID, loc.String())
+	if summary := h.summaryCache.get(cacheKey, now); summary != nil {
+		SuccessResponse(c, summary)
+		return
+	}
+
+	summary, err := h.buildEdgeSummary(node, loc, now)
+	if err != nil {
+		log.Printf("Failed to build summary for edge node %s: %v", node.ID, err)
+		InternalErrorResponse(c, "获取 Edge Node 汇总失败")
+		return
+	}
+
+	h.summaryCache.put(cacheKey, summary)
+	SuccessResponse(c, summary)
+}
+
+// buildEdgeSummary 汇总节点数据，所有查询均按节点ID过滤
+func (h *EdgeNodeHandler) buildEdgeSummary(node *models.EdgeNode, loc *time.Location, now time.Time) (*models.EdgeNodeSummary, error) {
+	today := startOfDay(now, loc).UTC()
+	stats, err := h.printJobRepo.GetEdgeNodeJobStats(node.ID, today, now.AddDate(0, 0, -7).UTC())
+	if err != nil {
+		return nil, err
+	}
+	if stats.PrinterStatuses, err = h.printerRepo.CountPrintersByStatus(node.ID); err != nil {
+		return nil, err
+	}
+	stats.Timezone = loc.String()
+	stats.GeneratedAt = now.UTC()
+
+	printers, err := h.printerRepo.ListPrintersByEdgeNode(node.ID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := h.printJobRepo.GetPrinterJobCounts(node.ID, today)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.EdgeNodeSummary{
+		EdgeNodeID:  node.ID,
+		Stats:       stats,
+		Printers:    make([]*models.EdgeNodePrinterSummary, 0, len(printers)),
+		Alerts:      nodeAlerts(node),
+		Timezone:    loc.String(),
+		GeneratedAt: now.UTC(),
+	}
+	for _, printer := range printers {
+		item := &models.EdgeNodePrinterSummary{
+			ID:          printer.ID,
+			Name:        printer.Name,
+			DisplayName: printer.DisplayName,
+			Status:      printer.Status,
+			Enabled:     printer.Enabled,
+			QueueLength: printer.EdgeQueueLength,
+		}
+		if count, ok := counts[printer.ID]; ok {
+			item.ActiveJobs = count.Active
+			item.CompletedToday = count.CompletedToday
+			item.FailedToday = count.FailedToday
+			item.StalledJobs = count.Stalled
+		}
+		summary.Printers = append(summary.Printers, item)
+		summary.Alerts = append(summary.Alerts, printerAlerts(printer, item)...)
+	}
+	return summary, nil
+}
+
+// nodeAlerts 节点级告警
+func nodeAlerts(node *models.EdgeNode) []models.EdgeNodeAlert {
+	alerts := []models.EdgeNodeAlert{}
+	if node.ConflictSuspected {
+		alerts = append(alerts, models.EdgeNodeAlert{Type: models.EdgeAlertNodeConflict, Severity: "critical",
+			Message: "同一 node_id 被多个地址的连接使用，请检查是否有克隆的节点"})
+	}
+	if node.Draining {
+		alerts = append(alerts, models.EdgeNodeAlert{Type: models.EdgeAlertNodeDraining, Severity: "warning",
+			Message: "节点正在排空，不再接收新任务"})
+	}
+	return alerts
+}
+
+// printerAlerts 打印机级告警（已禁用的打印机不告警）
+func printerAlerts(printer *models.Printer, item *models.EdgeNodePrinterSummary) []models.EdgeNodeAlert {
+	var alerts []models.EdgeNodeAlert
+	if !printer.Enabled {
+		return alerts
+	}
+	switch printer.Status {
+	case models.PrinterStatusError:
+		alerts = append(alerts, models.EdgeNodeAlert{Type: models.EdgeAlertPrinterError, Severity: "critical",
+			PrinterID: printer.ID, Message: "打印机报告错误"})
+	case models.PrinterStatusOffline:
+		alerts = append(alerts, models.EdgeNodeAlert{Type: models.EdgeAlertPrinterOffline, Severity: "warning",
+			PrinterID: printer.ID, Message: "打印机离线"})
+	}
+	if printer.ApprovalStatus == models.PrinterApprovalPendingReview {
+		alerts = append(alerts, models.EdgeNodeAlert{Type: models.EdgeAlertPrinterReview, Severity: "warning",
+			PrinterID: printer.ID, Message: "打印机等待管理员审核"})
+	}
+	if item.StalledJobs > 0 {
+		alerts = append(alerts, models.EdgeNodeAlert{Type: models.EdgeAlertJobsStalled, Severity: "warning",
+			PrinterID: printer.ID, Message: fmt.Sprintf("%d 个任务长时间没有进展", item.StalledJobs)})
+	}
+	if printer.NeedsAttention {
+		message := printer.AttentionReason
+		if message == "" {
+			message = "有任务因硬件错误被转移到备用打印机"
+		}
+		alerts = append(alerts, models.EdgeNodeAlert{Type: models.EdgeAlertPrinterAttention, Severity: "warning",
+			PrinterID: printer.ID, Message: message})
+	}
+	if printer.Breaker.Suspended() {
+		message := fmt.Sprintf("连续 %d 个任务失败，已暂停分发", printer.Breaker.ConsecutiveFailures)
+		if printer.Breaker.Reason != "" {
+			message += "：" + printer.Breaker.Reason
+		}
+		alerts = append(alerts, models.EdgeNodeAlert{Type: models.EdgeAlertPrinterSuspended, Severity: "critical",
+			PrinterID: printer.ID, Message: message})
+	}
+	return alerts
+}
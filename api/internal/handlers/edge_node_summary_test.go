@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// edgeToken 生成测试用 JWT（中间件只解析 claims，不校验签名）
+func edgeToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+// TestEdgeSummaryRejectsOtherNodes 节点只能读取自己的汇总；处理器没有仓库，任何数据访问都会 panic
+func TestEdgeSummaryRejectsOtherNodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/edge/:node_id/summary", middleware.NewOAuth2Authenticator(&config.OAuth2Config{}, nil).ResourceServer(middleware.ScopeEdgeConnect), (&EdgeNodeHandler{}).EdgeSummary)
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   int
+	}{
+		{"other node", jwt.MapClaims{"sub": "edge-client-b", "node_id": "node-b", "scope": middleware.ScopeEdgeConnect}, http.StatusForbidden},
+		{"sub fallback other node", jwt.MapClaims{"sub": "node-b", "scope": middleware.ScopeEdgeConnect}, http.StatusForbidden},
+		{"no node identity", jwt.MapClaims{"scope": middleware.ScopeEdgeConnect}, http.StatusForbidden},
+		{"missing edge scope", jwt.MapClaims{"sub": "node-a", "node_id": "node-a", "scope": middleware.ScopeEdgeRegister}, http.StatusForbidden},
+		{"admin without edge scope", jwt.MapClaims{"sub": "admin-1", "realm_access": map[string]interface{}{"roles": []string{middleware.RoleAdmin}}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/edge/node-a/summary", nil)
+			req.Header.Set("Authorization", "Bearer "+edgeToken(t, tt.claims))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestEdgeNodeSummaryCache(t *testing.T) {
+	var cache edgeNodeSummaryCache
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	keyA := edgeNodeStatsKey("node-a", "UTC")
+	keyB := edgeNodeStatsKey("node-b", "UTC")
+
+	if cache.get(keyA, now) != nil {
+		t.Fatal("empty cache returned a summary")
+	}
+	cache.put(keyA, &models.EdgeNodeSummary{EdgeNodeID: "node-a", GeneratedAt: now})
+	if summary := cache.get(keyA, now.Add(edgeNodeStatsTTL-time.Second)); summary == nil || summary.EdgeNodeID != "node-a" {
+		t.Fatalf("cached summary = %+v", summary)
+	}
+	if cache.get(keyB, now) != nil {
+		t.Fatal("summary of node-a returned for node-b")
+	}
+	if cache.get(edgeNodeStatsKey("node-a", "Asia/Shanghai"), now) != nil {
+		t.Fatal("summary returned for a different timezone")
+	}
+	if cache.get(keyA, now.Add(edgeNodeStatsTTL)) != nil {
+		t.Fatal("expired summary returned")
+	}
+
+	// 写入新条目时清理过期条目
+	cache.put(keyB, &models.EdgeNodeSummary{EdgeNodeID: "node-b", GeneratedAt: now.Add(edgeNodeStatsTTL)})
+	if _, ok := cache.entries[keyA]; ok || len(cache.entries) != 1 {
+		t.Fatalf("cache entries after put = %d, expired entry kept: %v", len(cache.entries), ok)
+	}
+}
+
+func TestEdgeSummaryAlerts(t *testing.T) {
+	if alerts := nodeAlerts(&models.EdgeNode{}); alerts == nil || len(alerts) != 0 {
+		t.Fatalf("healthy node alerts = %#v, want empty slice", alerts)
+	}
+	alerts := nodeAlerts(&models.EdgeNode{ConflictSuspected: true, Draining: true})
+	if len(alerts) != 2 || alerts[0].Type != models.EdgeAlertNodeConflict || alerts[1].Type != models.EdgeAlertNodeDraining {
+		t.Fatalf("node alerts = %+v", alerts)
+	}
+
+	tests := []struct {
+		name    string
+		printer models.Printer
+		stalled int
+		want    []string
+	}{
+		{"healthy", models.Printer{Enabled: true, Status: models.PrinterStatusReady}, 0, nil},
+		{"error", models.Printer{Enabled: true, Status: models.PrinterStatusError}, 0, []string{models.EdgeAlertPrinterError}},
+		{"offline with stalled jobs", models.Printer{Enabled: true, Status: models.PrinterStatusOffline}, 2,
+			[]string{models.EdgeAlertPrinterOffline, models.EdgeAlertJobsStalled}},
+		{"pending review", models.Printer{Enabled: true, Status: models.PrinterStatusReady, ApprovalStatus: models.PrinterApprovalPendingReview}, 0,
+			[]string{models.EdgeAlertPrinterReview}},
+		{"needs attention and suspended", models.Printer{Enabled: true, Status: models.PrinterStatusReady, NeedsAttention: true,
+			Breaker: models.PrinterBreaker{State: models.BreakerSuspended, ConsecutiveFailures: 3}}, 0,
+			[]string{models.EdgeAlertPrinterAttention, models.EdgeAlertPrinterSuspended}},
+		{"disabled", models.Printer{Enabled: false, Status: models.PrinterStatusError, NeedsAttention: true}, 5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printer := tt.printer
+			printer.ID = "printer-1"
+			alerts := printerAlerts(&printer, &models.EdgeNodePrinterSummary{StalledJobs: tt.stalled})
+			if len(alerts) != len(tt.want) {
+				t.Fatalf("alerts = %+v, want types %v", alerts, tt.want)
+			}
+			for i, alert := range alerts {
+				if alert.Type != tt.want[i] || alert.PrinterID != "printer-1" || alert.Message == "" {
+					t.Fatalf("alert %d = %+v, want type %s", i, alert, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/events"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxLongPollWait 长轮询最长等待时间
+	maxLongPollWait = 60 * time.Second
+	// longPollRecheckInterval 长轮询兜底检查间隔（并非所有修改都会发布事件）
+	longPollRecheckInterval = 2 * time.Second
+)
+
+// jobListETag 列表 ETag：最大 updated_at + 任务数 + 查询参数（不同分页和过滤条件的结果不同）
+func jobListETag(maxUpdatedAt time.Time, count int, c *gin.Context) string {
+	h := fnv.New64a()
+	h.Write([]byte(listQueryKey(c)))
+	return fmt.Sprintf(`W/"%x-%d-%x"`, maxUpdatedAt.UnixNano(), count, h.Sum64())
+}
+
+// listQueryKey 去掉 wait 参数后的查询参数，相同的 key 对应相同的列表 ETag
+func listQueryKey(c *gin.Context) string {
+	query := c.Request.URL.Query()
+	query.Del("wait")
+	return query.Encode()
+}
+
+// jobETag 单个任务 ETag
+func jobETag(updatedAt time.Time) string {
+	return fmt.Sprintf(`W/"%x"`, updatedAt.UnixNano())
+}
+
+// etagMatches 判断 If-None-Match 是否命中当前 ETag（弱比较）
+func etagMatches(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == current {
+			return true
+		}
+	}
+	return false
+}
+
+// longPollWait 解析 wait 参数（秒），超过上限时截断
+func longPollWait(c *gin.Context) time.Duration {
+	seconds, err := strconv.Atoi(c.Query("wait"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	wait := time.Duration(seconds) * time.Second
+	if wait > maxLongPollWait {
+		wait = maxLongPollWait
+	}
+	return wait
+}
+
+// etagWatcher 长轮询共享的 ETag 检查
+// 任务更新事件和定期兜底检查推进版本号；同一版本内相同查询的 ETag 只计算一次，
+// 挂起的客户端共享计算结果，而不是每个事件按客户端数量重复查询数据库
+type etagWatcher struct {
+	bus *events.Bus
+
+	mutex       sync.Mutex
+	generation  uint64
+	changed     chan struct{} // 版本推进时关闭并替换
+	entries     map[string]*etagEntry
+	waiters     int
+	unsubscribe func()
+}
+
+// etagEntry 某一版本下某个查询的 ETag 计算结果
+type etagEntry struct {
+	done chan struct{} // 计算完成后关闭
+	etag string
+	err  error
+}
+
+// newETagWatcher 创建长轮询 ETag 检查器（bus 为空时不支持长轮询）
+func newETagWatcher(bus *events.Bus) *etagWatcher {
+	return &etagWatcher{
+		bus:     bus,
+		changed: make(chan struct{}),
+		entries: make(map[string]*etagEntry),
+	}
+}
+
+// await 客户端缓存仍然有效且请求了 wait 时挂起请求，直到 ETag 变化、超时或客户端断开
+// key 标识查询（同一 key 的 compute 结果相同）
+func (w *etagWatcher) await(ctx context.Context, wait time.Duration, key, etag string, compute func() (string, error)) (string, error) {
+	if w == nil || w.bus == nil || wait <= 0 {
+		return etag, nil
+	}
+
+	changed := w.join()
+	defer w.leave()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return etag, nil
+		case <-timer.C:
+			return etag, nil
+		case <-changed:
+		}
+
+		var current string
+		var err error
+		current, changed, err = w.compute(key, compute)
+		if err != nil {
+			return "", err
+		}
+		if current != etag {
+			return current, nil
+		}
+	}
+}
+
+// join 登记挂起的请求，第一个请求开始订阅事件；返回当前版本的变化通知
+func (w *etagWatcher) join() <-chan struct{} {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.waiters++
+	if w.waiters == 1 {
+		eventCh, unsubscribe := w.bus.Subscribe(16)
+		w.unsubscribe = unsubscribe
+		go w.run(eventCh)
+	}
+	return w.changed
+}
+
+// leave 注销挂起的请求，最后一个请求离开时取消订阅并清空缓存
+func (w *etagWatcher) leave() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.waiters--
+	if w.waiters == 0 {
+		w.unsubscribe()
+		w.unsubscribe = nil
+		w.entries = make(map[string]*etagEntry)
+	}
+}
+
+// run 任务更新事件或兜底检查间隔到达时推进版本号，取消订阅后退出
+func (w *etagWatcher) run(eventCh <-chan events.Event) {
+	ticker := time.NewTicker(longPollRecheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-eventCh:
+			if !ok {
+				return
+			}
+			if event.Type != events.EventJobUpdated {
+				continue
+			}
+		case <-ticker.C:
+		}
+		w.advance()
+	}
+}
+
+// advance 推进版本号，唤醒全部挂起的请求，旧版本的计算结果失效
+func (w *etagWatcher) advance() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.generation++
+	close(w.changed)
+	w.changed = make(chan struct{})
+	w.entries = make(map[string]*etagEntry)
+}
+
+// compute 返回当前版本下 key 的 ETag，同一版本内只调用一次 fn；同时返回该版本的变化通知
+func (w *etagWatcher) compute(key string, fn func() (string, error)) (string, <-chan struct{}, error) {
+	w.mutex.Lock()
+	changed := w.changed
+	entry, exists := w.entries[key]
+	if !exists {
+		entry = &etagEntry{done: make(chan struct{})}
+		w.entries[key] = entry
+	}
+	w.mutex.Unlock()
+
+	if exists {
+		<-entry.done
+	} else {
+		entry.etag, entry.err = fn()
+		close(entry.done)
+	}
+	return entry.etag, changed, entry.err
+}
+
+// notModified 返回 304，不序列化响应体
+func notModified(c *gin.Context, etag string) {
+	c.Header("ETag", etag)
+	c.Status(http.StatusNotModified)
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// waitForWaiters 等待指定数量的请求挂起
+func waitForWaiters(t *testing.T, w *etagWatcher, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		w.mutex.Lock()
+		waiters := w.waiters
+		w.mutex.Unlock()
+		if waiters == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("waiters did not reach %d", n)
+}
+
+// TestETagWatcherComputesOncePerEvent 一个任务事件唤醒全部挂起的请求，相同查询只计算一次 ETag
+func TestETagWatcherComputesOncePerEvent(t *testing.T) {
+	bus := events.NewBus()
+	w := newETagWatcher(bus)
+
+	var current atomic.Value
+	current.Store(`W/"v1"`)
+	var calls atomic.Int32
+	compute := func() (string, error) {
+		calls.Add(1)
+		return current.Load().(string), nil
+	}
+
+	const clients = 20
+	results := make([]string, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			etag, err := w.await(context.Background(), 5*time.Second, "list:status=pending", `W/"v1"`, compute)
+			if err != nil {
+				t.Errorf("await: %v", err)
+			}
+			results[i] = etag
+		}(i)
+	}
+	waitForWaiters(t, w, clients)
+
+	current.Store(`W/"v2"`)
+	bus.Publish(events.Event{Type: events.EventJobUpdated})
+	wg.Wait()
+
+	for i, etag := range results {
+		if etag != `W/"v2"` {
+			t.Fatalf("client %d got %s, want v2", i, etag)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("compute called %d times for one event, want 1", got)
+	}
+	if w.unsubscribe != nil || w.waiters != 0 {
+		t.Fatalf("watcher still subscribed after all clients left")
+	}
+}
+
+func TestETagWatcherAwait(t *testing.T) {
+	compute := func() (string, error) { return `W/"v1"`, nil }
+
+	t.Run("timeout", func(t *testing.T) {
+		w := newETagWatcher(events.NewBus())
+		start := time.Now()
+		etag, err := w.await(context.Background(), 50*time.Millisecond, "job:1", `W/"v1"`, compute)
+		if err != nil || etag != `W/"v1"` {
+			t.Fatalf("await = %s, %v", etag, err)
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Fatalf("returned after %s, want the full wait", elapsed)
+		}
+	})
+
+	t.Run("client disconnect", func(t *testing.T) {
+		w := newETagWatcher(events.NewBus())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if etag, err := w.await(ctx, time.Minute, "job:1", `W/"v1"`, compute); err != nil || etag != `W/"v1"` {
+			t.Fatalf("await = %s, %v", etag, err)
+		}
+	})
+
+	t.Run("no wait", func(t *testing.T) {
+		w := newETagWatcher(events.NewBus())
+		if etag, _ := w.await(context.Background(), 0, "job:1", `W/"v1"`, compute); etag != `W/"v1"` {
+			t.Fatalf("await = %s", etag)
+		}
+		if w.waiters != 0 {
+			t.Fatalf("waiters = %d, want 0", w.waiters)
+		}
+	})
+
+	t.Run("no bus", func(t *testing.T) {
+		w := newETagWatcher(nil)
+		if etag, _ := w.await(context.Background(), time.Minute, "job:1", `W/"v1"`, compute); etag != `W/"v1"` {
+			t.Fatalf("await = %s", etag)
+		}
+	})
+
+	t.Run("compute error", func(t *testing.T) {
+		bus := events.NewBus()
+		w := newETagWatcher(bus)
+		done := make(chan error, 1)
+		go func() {
+			_, err := w.await(context.Background(), 5*time.Second, "job:1", `W/"v1"`, func() (string, error) {
+				return "", fmt.Errorf("db down")
+			})
+			done <- err
+		}()
+		waitForWaiters(t, w, 1)
+		bus.Publish(events.Event{Type: events.EventJobUpdated})
+		if err := <-done; err == nil {
+			t.Fatal("await error = nil, want compute error")
+		}
+	})
+}
+
+func TestETagMatches(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`W/"abc"`, true},
+		{`"abc"`, true},
+		{`W/"other", W/"abc"`, true},
+		{"*", true},
+		{`W/"other"`, false},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.Header.Set("If-None-Match", tt.header)
+		if got := etagMatches(c, `W/"abc"`); got != tt.want {
+			t.Fatalf("If-None-Match %q = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkETagFanout 一个任务事件唤醒 100 个挂起的控制台请求时的 ETag 计算次数
+func BenchmarkETagFanout(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	const clients = 100
+	var calls int
+	compute := func() (string, error) {
+		calls++
+		return jobListETag(time.Now(), 42, benchmarkListContext()), nil
+	}
+
+	b.Run("per-client", func(b *testing.B) {
+		calls = 0
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < clients; j++ {
+				compute()
+			}
+		}
+		b.ReportMetric(float64(calls)/float64(b.N), "computes/event")
+	})
+
+	b.Run("shared", func(b *testing.B) {
+		calls = 0
+		w := newETagWatcher(events.NewBus())
+		for i := 0; i < b.N; i++ {
+			w.advance()
+			for j := 0; j < clients; j++ {
+				w.compute("list:status=pending", compute)
+			}
+		}
+		b.ReportMetric(float64(calls)/float64(b.N), "computes/event")
+	})
+}
+
+// BenchmarkJobListPoll 控制台轮询未变化的任务列表：完整响应与 If-None-Match 命中返回 304 的对比
+func BenchmarkJobListPoll(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	updatedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	jobs := make([]*models.PrintJob, 20)
+	for i := range jobs {
+		jobs[i] = &models.PrintJob{
+			ID:          fmt.Sprintf("job-%02d", i),
+			Name:        fmt.Sprintf("report-%02d.pdf", i),
+			DisplayName: fmt.Sprintf("Quarterly report %02d", i),
+			Status:      models.JobStatusCompleted,
+			PrinterID:   "printer-1",
+			UserID:      "user-1",
+			FileSize:    1 << 20,
+			PageCount:   12,
+			Copies:      1,
+			CreatedAt:   updatedAt,
+			UpdatedAt:   updatedAt,
+		}
+	}
+	etag := jobListETag(updatedAt, len(jobs), benchmarkListContext())
+
+	b.Run("full", func(b *testing.B) {
+		var written int
+		for i := 0; i < b.N; i++ {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/print-jobs?status=completed", nil)
+			c.Header("ETag", etag)
+			c.JSON(http.StatusOK, gin.H{"jobs": jobs, "pagination": gin.H{"total": len(jobs)}})
+			written += w.Body.Len()
+		}
+		b.ReportMetric(float64(written)/float64(b.N), "body-bytes/op")
+	})
+
+	b.Run("not-modified", func(b *testing.B) {
+		var written int
+		for i := 0; i < b.N; i++ {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/print-jobs?status=completed", nil)
+			c.Request.Header.Set("If-None-Match", etag)
+			if etagMatches(c, jobListETag(updatedAt, len(jobs), c)) {
+				notModified(c, etag)
+			}
+			written += w.Body.Len()
+		}
+		b.ReportMetric(float64(written)/float64(b.N), "body-bytes/op")
+	})
+}
+
+func benchmarkListContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/print-jobs?status=completed&wait=30", nil)
+	return c
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"io"
+	"time"
+
+	"fly-print-cloud/api/internal/events"
+	"github.com/gin-gonic/gin"
+)
+
+// EventHandler 系统事件推送处理器（SSE）
+type EventHandler struct {
+	eventBus *events.Bus
+}
+
+// NewEventHandler 创建系统事件推送处理器
+func NewEventHandler(eventBus *events.Bus) *EventHandler {
+	return &EventHandler{
+		eventBus: eventBus,
+	}
+}
+
+// Stream 以 Server-Sent Events 推送系统事件（节点上下线等）
+func (h *EventHandler) Stream(c *gin.Context) {
+	eventCh, unsubscribe := h.eventBus.Subscribe(64)
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 Nginx 缓冲
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-eventCh:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-keepAlive.C:
+			c.SSEvent("ping", gin.H{"timestamp": time.Now()})
+			return true
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// maxExportDays 单次归档导出的最大天数
+const maxExportDays = 366
+
+// ExportHandler 已完成任务归档导出处理器：导出由后台任务（worker.JobExporter）执行，接口只创建导出和查询状态
+type ExportHandler struct {
+	runRepo   *database.ExportRunRepository
+	auditRepo *database.AuditLogRepository
+}
+
+// NewExportHandler 创建归档导出处理器
+func NewExportHandler(runRepo *database.ExportRunRepository, auditRepo *database.AuditLogRepository) *ExportHandler {
+	return &ExportHandler{
+		runRepo:   runRepo,
+		auditRepo: auditRepo,
+	}
+}
+
+// CreateExportRequest 创建归档导出请求
+type CreateExportRequest struct {
+	FromDate string `json:"from_date" binding:"required"` // 起始日期（含，UTC），YYYY-MM-DD
+	ToDate   string `json:"to_date" binding:"required"`   // 结束日期（含，UTC），须早于今天
+	Format   string `json:"format"`                       // 默认 jsonl
+}
+
+// CreateExport 创建归档导出：导出日期范围内（按创建时间）的已完成任务，由后台任务执行，通过 GET /exports/:id 查询进度
+func (h *ExportHandler) CreateExport(c *gin.Context) {
+	var req CreateExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	switch format {
+	case "", models.ExportFormatJSONL:
+		format = models.ExportFormatJSONL
+	case models.ExportFormatParquet:
+		BadRequestResponse(c, "暂不支持 parquet 格式，请使用 jsonl")
+		return
+	default:
+		BadRequestResponse(c, fmt.Sprintf("format 无效: %s，可选值：jsonl", req.Format))
+		return
+	}
+
+	from, err := time.Parse(models.ExportDateLayout, strings.TrimSpace(req.FromDate))
+	if err != nil {
+		BadRequestResponse(c, "from_date 格式无效，应为 YYYY-MM-DD")
+		return
+	}
+	to, err := time.Parse(models.ExportDateLayout, strings.TrimSpace(req.ToDate))
+	if err != nil {
+		BadRequestResponse(c, "to_date 格式无效，应为 YYYY-MM-DD")
+		return
+	}
+	if to.Before(from) {
+		BadRequestResponse(c, "to_date 不能早于 from_date")
+		return
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > maxExportDays {
+		BadRequestResponse(c, fmt.Sprintf("单次最多导出 %d 天", maxExportDays))
+		return
+	}
+	// 当天的任务仍可能完成，只导出已结束的日期，避免同一天的数据分散在多次导出中
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if !to.Before(today) {
+		BadRequestResponse(c, "to_date 必须早于今天（UTC）")
+		return
+	}
+
+	actor, _ := currentActor(c)
+	run := &models.ExportRun{
+		Format:      format,
+		Trigger:     models.ExportTriggerManual,
+		FromDate:    from.Format(models.ExportDateLayout),
+		ToDate:      to.Format(models.ExportDateLayout),
+		RequestedBy: actor,
+	}
+	if err := h.runRepo.CreateExportRun(run); err != nil {
+		log.Printf("Failed to create export run: %v", err)
+		InternalErrorResponse(c, "创建导出失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "export.create", "export", run.ID, fmt.Sprintf("%s..%s (%s)", run.FromDate, run.ToDate, run.Format))
+	CreatedResponse(c, run)
+}
+
+// ListExports 获取归档导出列表，可按 status 筛选
+func (h *ExportHandler) ListExports(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	runs, total, err := h.runRepo.ListExportRuns(page, pageSize, c.Query("status"))
+	if err != nil {
+		log.Printf("Failed to list export runs: %v", err)
+		InternalErrorResponse(c, "获取导出列表失败")
+		return
+	}
+	PaginatedSuccessResponse(c, runs, total, page, pageSize)
+}
+
+// GetExport 获取归档导出的状态、已写入的文件（含行数和校验和）
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	run, ok := h.loadRun(c)
+	if !ok {
+		return
+	}
+	SuccessResponse(c, run)
+}
+
+// ResumeExport 从最后导出的任务继续失败的导出，已写入的文件保留
+func (h *ExportHandler) ResumeExport(c *gin.Context) {
+	run, ok := h.loadRun(c)
+	if !ok {
+		return
+	}
+
+	resumed, err := h.runRepo.ResumeExportRun(run.ID)
+	if err != nil {
+		log.Printf("Failed to resume export run %s: %v", run.ID, err)
+		InternalErrorResponse(c, "恢复导出失败")
+		return
+	}
+	if !resumed {
+		ErrorResponse(c, http.StatusConflict, "只能恢复失败的导出")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "export.resume", "export", run.ID, fmt.Sprintf("after job %s", run.LastJobID))
+	run.Status = models.ExportStatusPending
+	SuccessResponse(c, run)
+}
+
+// loadRun 根据路径参数获取归档导出，不存在时写入 404 响应
+func (h *ExportHandler) loadRun(c *gin.Context) (*models.ExportRun, bool) {
+	id := c.Param("id")
+	run, err := h.runRepo.GetExportRun(id)
+	if err != nil {
+		log.Printf("Failed to get export run %s: %v", id, err)
+		InternalErrorResponse(c, "获取导出失败")
+		return nil, false
+	}
+	if run == nil {
+		NotFoundResponse(c, "导出不存在")
+		return nil, false
+	}
+	return run, true
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestCreateExportValidation 无效的导出请求在写入数据库前返回 400；处理器没有仓库，通过校验的请求会 panic
+func TestCreateExportValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	today := time.Now().UTC().Format("2006-01-02")
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+
+	tests := []struct {
+		name string
+		body map[string]string
+	}{
+		{"missing dates", map[string]string{"format": "jsonl"}},
+		{"parquet", map[string]string{"from_date": "2026-01-01", "to_date": "2026-01-02", "format": "parquet"}},
+		{"unknown format", map[string]string{"from_date": "2026-01-01", "to_date": "2026-01-02", "format": "csv"}},
+		{"bad from date", map[string]string{"from_date": "01/01/2026", "to_date": "2026-01-02"}},
+		{"bad to date", map[string]string{"from_date": "2026-01-01", "to_date": "2026-02-30"}},
+		{"reversed range", map[string]string{"from_date": "2026-01-02", "to_date": "2026-01-01"}},
+		{"too many days", map[string]string{"from_date": "2024-01-01", "to_date": "2025-01-01"}},
+		{"includes today", map[string]string{"from_date": yesterday, "to_date": today}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, _ := json.Marshal(tt.body)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/admin/exports", bytes.NewReader(payload))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			(&ExportHandler{}).CreateExport(c)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/docformat"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/storage"
+	"fly-print-cloud/api/internal/thumbnail"
+	"github.com/gin-gonic/gin"
+)
+
+// FileHandler 打印文件上传与下载代理处理器
+type FileHandler struct {
+	storage      storage.Storage
+	linkSigner   *storage.LinkSigner
+	printJobRepo *database.PrintJobRepository
+	storedFiles  *database.StoredFileRepository
+	auditRepo    *database.AuditLogRepository
+	settings     *config.Store
+	thumbnails   *thumbnail.Generator
+}
+
+// NewFileHandler 创建打印文件处理器
+func NewFileHandler(fileStorage storage.Storage, linkSigner *storage.LinkSigner, printJobRepo *database.PrintJobRepository, storedFiles *database.StoredFileRepository, auditRepo *database.AuditLogRepository, settings *config.Store, thumbnails *thumbnail.Generator) *FileHandler {
+	return &FileHandler{
+		storage:      fileStorage,
+		linkSigner:   linkSigner,
+		printJobRepo: printJobRepo,
+		storedFiles:  storedFiles,
+		auditRepo:    auditRepo,
+		settings:     settings,
+		thumbnails:   thumbnails,
+	}
+}
+
+// cfg 当前生效的存储配置（上传大小、格式和链接有效期支持热更新）
+func (h *FileHandler) cfg() *config.StorageConfig {
+	return &h.settings.Get().Storage
+}
+
+// UploadFile 上传打印文件（multipart，字段名 file），返回的 storage_key 用于创建打印任务
+func (h *FileHandler) UploadFile(c *gin.Context) {
+	// 限制请求体大小（额外预留 multipart 头部空间）
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg().MaxUploadSize+64*1024)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		BadRequestResponse(c, fmt.Sprintf("缺少文件或文件超过大小限制（%d 字节）", h.cfg().MaxUploadSize))
+		return
+	}
+	if fileHeader.Size > h.cfg().MaxUploadSize {
+		BadRequestResponse(c, fmt.Sprintf("文件超过大小限制（%d 字节）", h.cfg().MaxUploadSize))
+		return
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		BadRequestResponse(c, "读取上传文件失败")
+		return
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, h.cfg().MaxUploadSize))
+	if err != nil {
+		BadRequestResponse(c, "读取上传文件失败")
+		return
+	}
+
+	// 按文件头检测格式，PDF 同时校验结构并计算页数
+	info, err := docformat.Inspect(data, h.cfg().AllowedFormats)
+	if err != nil {
+		status, code, message := fileFormatError(err)
+		c.JSON(status, gin.H{"code": status, "message": message, "error_code": code})
+		return
+	}
+
+	fileName := filepath.Base(fileHeader.Filename)
+	contentType := fileHeader.Header.Get("Content-Type")
+	key := storage.NewKey(storage.UploadPrefix, fileName)
+	if err := h.storage.Put(c.Request.Context(), key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		log.Printf("Failed to store uploaded file %s: %v", fileName, err)
+		InternalErrorResponse(c, "保存文件失败")
+		return
+	}
+	// 记录上传时间，供按保留期限清理；记录失败时文件仍可使用，提交任务时会补记
+	if err := h.storedFiles.RecordStoredFile(&models.StoredFile{StorageKey: key, FileSize: int64(len(data)), ContentType: contentType}); err != nil {
+		log.Printf("Failed to record stored file %s: %v", key, err)
+	}
+
+	result := gin.H{
+		"storage_key":  key,
+		"file_name":    fileName,
+		"file_size":    len(data),
+		"content_type": contentType,
+		"format":       info.Format,
+	}
+	if info.Format == docformat.FormatPDF {
+		result["page_count"] = info.Pages
+		// 后台生成第一页缩略图，失败不影响上传和打印
+		h.thumbnails.Enqueue(key)
+	}
+	CreatedResponse(c, result)
+}
+
+// fileFormatError 文件格式校验失败时返回的HTTP状态码、错误码和提示信息
+func fileFormatError(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, docformat.ErrUnsupportedFormat):
+		return http.StatusUnsupportedMediaType, "unsupported_format", "不支持的文件格式"
+	case errors.Is(err, docformat.ErrCorruptPDF):
+		return http.StatusBadRequest, "corrupt_pdf", "PDF文件已损坏，无法解析页数"
+	default:
+		return http.StatusBadRequest, "invalid_file", "文件校验失败"
+	}
+}
+
+// filePurgedResponse 任务文件已按保留期限删除
+func filePurgedResponse(c *gin.Context) {
+	c.JSON(http.StatusGone, gin.H{"code": http.StatusGone, "message": "任务文件已按保留期限删除", "error_code": models.JobErrorFilePurged})
+}
+
+// DownloadJobFile 通过云端代理下载打印任务的文件（管理员）
+func (h *FileHandler) DownloadJobFile(c *gin.Context) {
+	job, err := h.printJobRepo.GetPrintJobByID(c.Param("id"))
+	if err != nil {
+		InternalErrorResponse(c, "获取打印任务失败")
+		return
+	}
+	if job == nil {
+		NotFoundResponse(c, "打印任务不存在")
+		return
+	}
+	if job.FilePurged {
+		filePurgedResponse(c)
+		return
+	}
+	if job.StorageKey == "" {
+		NotFoundResponse(c, "该任务的文件不在云端存储")
+		return
+	}
+
+	h.streamObject(c, job.StorageKey, job.Name+path.Ext(job.StorageKey))
+}
+
+// DownloadJobErrorMessage 下载任务截断前的完整错误信息（text_limits.spill_to_storage 开启时转存）
+func (h *FileHandler) DownloadJobErrorMessage(c *gin.Context) {
+	job, err := h.printJobRepo.GetPrintJobByID(c.Param("id"))
+	if err != nil {
+		InternalErrorResponse(c, "获取打印任务失败")
+		return
+	}
+	if job == nil {
+		NotFoundResponse(c, "打印任务不存在")
+		return
+	}
+	if job.ErrorMessageKey == "" {
+		NotFoundResponse(c, "该任务没有转存的完整错误信息")
+		return
+	}
+
+	reader, err := h.storage.Get(c.Request.Context(), job.ErrorMessageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			NotFoundResponse(c, "完整错误信息已删除")
+			return
+		}
+		log.Printf("Failed to read spilled error message of job %s: %v", job.ID, err)
+		InternalErrorResponse(c, "读取完整错误信息失败")
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "text/plain; charset=utf-8", reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s-error.txt"`, job.ID),
+	})
+}
+
+// GetJobThumbnail 获取打印任务第一个文件的第一页缩略图（PNG）
+// 文件不在云端存储、不是 PDF、缩略图尚未生成或生成失败时返回 404
+func (h *FileHandler) GetJobThumbnail(c *gin.Context) {
+	job, err := h.printJobRepo.GetPrintJobByID(c.Param("id"))
+	if err != nil {
+		InternalErrorResponse(c, "获取打印任务失败")
+		return
+	}
+	if job == nil {
+		NotFoundResponse(c, "打印任务不存在")
+		return
+	}
+	if job.FilePurged {
+		filePurgedResponse(c)
+		return
+	}
+	if job.StorageKey == "" {
+		NotFoundResponse(c, "该任务的文件不在云端存储")
+		return
+	}
+
+	reader, err := h.storage.Get(c.Request.Context(), thumbnail.Key(job.StorageKey))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			NotFoundResponse(c, "缩略图不存在")
+			return
+		}
+		log.Printf("Failed to read thumbnail of job %s: %v", job.ID, err)
+		InternalErrorResponse(c, "读取缩略图失败")
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "image/png", reader, map[string]string{
+		"Cache-Control": "private, max-age=3600",
+	})
+}
+
+// ServeSignedFile 签名链接下载（无需登录，供 Edge Node 拉取文件）
+// 仅由云端代理下载的存储后端（local）使用，S3 后端的签名链接直接指向对象存储
+func (h *FileHandler) ServeSignedFile(c *gin.Context) {
+	verifier, ok := h.storage.(storage.SignatureVerifier)
+	if !ok {
+		NotFoundResponse(c, "文件不存在")
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := verifier.VerifySignature(key, c.Query("expires"), c.Query("signature")); err != nil {
+		if errors.Is(err, storage.ErrSignatureExpired) {
+			ErrorResponse(c, http.StatusGone, "链接已过期")
+			return
+		}
+		ForbiddenResponse(c, "链接无效")
+		return
+	}
+
+	h.streamObject(c, key, path.Base(key))
+}
+
+// FileLinkRequest 生成文件临时链接请求（请求体可省略）
+type FileLinkRequest struct {
+	ExpiresIn int `json:"expires_in" binding:"omitempty,min=60"` // 有效期（秒），默认 storage.support_link_ttl
+}
+
+// CreateFileLink 为打印任务文件生成带签名的临时链接（管理员），便于技术支持粘贴到工单中查看原始文件
+func (h *FileHandler) CreateFileLink(c *gin.Context) {
+	var req FileLinkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ValidationErrorResponse(c, err)
+			return
+		}
+	}
+
+	ttl := h.cfg().SupportLinkTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if ttl > h.cfg().SupportLinkMaxTTL {
+		BadRequestResponse(c, fmt.Sprintf("链接有效期不能超过 %s", h.cfg().SupportLinkMaxTTL))
+		return
+	}
+
+	job, err := h.printJobRepo.GetPrintJobByID(c.Param("id"))
+	if err != nil {
+		InternalErrorResponse(c, "获取打印任务失败")
+		return
+	}
+	if job == nil {
+		NotFoundResponse(c, "打印任务不存在")
+		return
+	}
+	if job.FilePurged {
+		filePurgedResponse(c)
+		return
+	}
+	if job.StorageKey == "" {
+		NotFoundResponse(c, "该任务的文件不在云端存储")
+		return
+	}
+
+	actor, actorID := currentActor(c)
+	claims := storage.LinkClaims{
+		JobID:      job.ID,
+		ExpiresAt:  time.Now().Add(ttl).Unix(),
+		IssuedBy:   actor,
+		IssuedByID: actorID,
+	}
+	token, err := h.linkSigner.Sign(claims)
+	if err != nil {
+		log.Printf("Failed to sign file link for job %s: %v", job.ID, err)
+		InternalErrorResponse(c, "生成链接失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "print_job.file_link_create", "print_job", job.ID,
+		fmt.Sprintf("expires_at=%s", claims.Expires().Format(time.RFC3339)))
+
+	CreatedResponse(c, gin.H{
+		"url":        fmt.Sprintf("%s/files/%s", strings.TrimRight(h.cfg().PublicBaseURL, "/"), token),
+		"expires_at": claims.Expires(),
+	})
+}
+
+// ServeFileLink 通过临时链接下载打印任务文件（无需登录）
+// 过期返回 410，签名无效返回 403；每次访问以生成链接的管理员身份记录审计日志
+// 多文件任务可通过 position 参数指定文件，默认返回第一个文件
+func (h *FileHandler) ServeFileLink(c *gin.Context) {
+	claims, err := h.linkSigner.Verify(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, storage.ErrSignatureExpired) {
+			ErrorResponse(c, http.StatusGone, "链接已过期")
+			return
+		}
+		ForbiddenResponse(c, "链接无效")
+		return
+	}
+
+	job, err := h.printJobRepo.GetPrintJobByID(claims.JobID)
+	if err != nil {
+		InternalErrorResponse(c, "获取打印任务失败")
+		return
+	}
+	if job == nil {
+		NotFoundResponse(c, "打印任务不存在")
+		return
+	}
+	if job.FilePurged {
+		filePurgedResponse(c)
+		return
+	}
+
+	key := job.StorageKey
+	position := c.Query("position")
+	if position != "" {
+		key = ""
+		pos, _ := strconv.Atoi(position)
+		files, err := h.printJobRepo.ListPrintJobFiles(job.ID)
+		if err != nil {
+			InternalErrorResponse(c, "获取打印任务文件失败")
+			return
+		}
+		for _, file := range files {
+			if file.Position == pos {
+				key = file.StorageKey
+				break
+			}
+		}
+	}
+	if key == "" {
+		NotFoundResponse(c, "该任务的文件不在云端存储")
+		return
+	}
+
+	recordAuditAs(h.auditRepo, claims.IssuedBy, claims.IssuedByID, "print_job.file_link_access", "print_job", job.ID,
+		fmt.Sprintf("ip=%s, position=%s, user_agent=%s", c.ClientIP(), position, c.Request.UserAgent()))
+
+	h.streamObject(c, key, job.Name+path.Ext(key))
+}
+
+// streamObject 从存储后端读取对象并以附件形式返回
+func (h *FileHandler) streamObject(c *gin.Context, key, fileName string) {
+	reader, err := h.storage.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			NotFoundResponse(c, "文件不存在")
+			return
+		}
+		log.Printf("Failed to read stored file %s: %v", key, err)
+		InternalErrorResponse(c, "读取文件失败")
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "application/octet-stream", reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", fileName),
+	})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// TestServeFileLinkRejects 过期链接返回 410，篡改或其他密钥签名的链接返回 403，都不会查询任务
+// 处理器没有仓库，通过校验的请求会 panic
+func TestServeFileLinkRejects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	signer, _ := storage.NewLinkSigner("secret")
+	other, _ := storage.NewLinkSigner("other")
+	h := &FileHandler{linkSigner: signer}
+
+	expired, _ := signer.Sign(storage.LinkClaims{JobID: "job-1", ExpiresAt: time.Now().Add(-time.Minute).Unix(), IssuedBy: "admin"})
+	valid, _ := signer.Sign(storage.LinkClaims{JobID: "job-1", ExpiresAt: time.Now().Add(time.Hour).Unix(), IssuedBy: "admin"})
+	foreign, _ := other.Sign(storage.LinkClaims{JobID: "job-1", ExpiresAt: time.Now().Add(time.Hour).Unix(), IssuedBy: "admin"})
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"expired", expired, http.StatusGone},
+		{"tampered", valid[:len(valid)-2] + "xx", http.StatusForbidden},
+		{"other secret", foreign, http.StatusForbidden},
+		{"garbage", "not-a-token", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/files/"+tt.token, nil)
+			c.Params = gin.Params{{Key: "token", Value: tt.token}}
+
+			h.ServeFileLink(c)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
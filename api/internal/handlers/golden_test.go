@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"testing"
+
+	"fly-print-cloud/api/internal/golden"
+	"fly-print-cloud/api/internal/models"
+)
+
+// TestViewJSONGolden Edge Node 和打印机响应视图的 JSON 序列化与样例文件一致（嵌入的实体字段与视图字段合并输出）
+func TestViewJSONGolden(t *testing.T) {
+	views := []struct {
+		name string
+		new  func() interface{}
+	}{
+		{"edge_node_info", func() interface{} { return &EdgeNodeInfo{EdgeNode: &models.EdgeNode{}} }},
+		{"printer_with_status", func() interface{} { return &PrinterWithStatus{Printer: &models.Printer{}} }},
+	}
+	for _, view := range views {
+		t.Run(view.name, func(t *testing.T) {
+			golden.AssertJSON(t, view.name+"_empty", view.new())
+			full := view.new()
+			golden.Fill(full)
+			golden.AssertJSON(t, view.name+"_full", full)
+		})
+	}
+}
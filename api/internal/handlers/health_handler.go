@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/lookup"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler 机队健康汇总：NOC 看板接口和 Prometheus 指标共用同一份缓存结果
+type HealthHandler struct {
+	edgeNodeRepo *database.EdgeNodeRepository
+	printerRepo  *database.PrinterRepository
+	printJobRepo *database.PrintJobRepository
+	wsHandler    *websocket.WebSocketHandler
+	lookups      *lookup.Cache // 未启用查询缓存时为 nil
+	settings     *config.Store // 阈值和缓存时间（health.*）支持热更新
+
+	mu     sync.Mutex // 同时只计算一次，缓存过期时并发请求等待同一次计算
+	cached *models.FleetHealth
+}
+
+// NewHealthHandler 创建机队健康处理器
+func NewHealthHandler(edgeNodeRepo *database.EdgeNodeRepository, printerRepo *database.PrinterRepository, printJobRepo *database.PrintJobRepository, wsHandler *websocket.WebSocketHandler, lookups *lookup.Cache, settings *config.Store) *HealthHandler {
+	return &HealthHandler{
+		edgeNodeRepo: edgeNodeRepo,
+		printerRepo:  printerRepo,
+		printJobRepo: printJobRepo,
+		wsHandler:    wsHandler,
+		lookups:      lookups,
+		settings:     settings,
+	}
+}
+
+// GetFleetHealth 当前需要处理的问题汇总（结果缓存 health.cache_ttl）：
+// 离线节点、故障打印机、耗材不足、停滞任务和熔断打印机的数量及资源ID，severity 为其中最严重的程度
+func (h *HealthHandler) GetFleetHealth(c *gin.Context) {
+	health, err := h.fleetHealth(time.Now())
+	if err != nil {
+		log.Printf("Failed to compute fleet health: %v", err)
+		InternalErrorResponse(c, "获取机队健康状态失败")
+		return
+	}
+	SuccessResponse(c, health)
+}
+
+// Metrics 以 Prometheus 文本格式输出 WebSocket 指标、查询缓存指标和机队健康指标；健康汇总计算失败时不输出机队健康指标
+func (h *HealthHandler) Metrics(c *gin.Context) {
+	var b strings.Builder
+	h.wsHandler.WriteMetrics(&b)
+	h.lookups.WriteMetrics(&b)
+
+	health, err := h.fleetHealth(time.Now())
+	if err != nil {
+		log.Printf("Failed to compute fleet health for metrics: %v", err)
+	} else {
+		b.WriteString("# HELP fly_print_fleet_health_severity Overall fleet health severity (0=ok, 1=warning, 2=critical).\n")
+		b.WriteString("# TYPE fly_print_fleet_health_severity gauge\n")
+		fmt.Fprintf(&b, "fly_print_fleet_health_severity %d\n", models.HealthSeverityLevel(health.Severity))
+
+		b.WriteString("# HELP fly_print_fleet_problems Number of resources affected by each fleet health problem.\n")
+		b.WriteString("# TYPE fly_print_fleet_problems gauge\n")
+		for _, problem := range health.Problems {
+			fmt.Fprintf(&b, "fly_print_fleet_problems{type=%q} %d\n", problem.Type, problem.Count)
+		}
+
+		b.WriteString("# HELP fly_print_fleet_problem_severity Severity of each fleet health problem (0=ok, 1=warning, 2=critical).\n")
+		b.WriteString("# TYPE fly_print_fleet_problem_severity gauge\n")
+		for _, problem := range health.Problems {
+			fmt.Fprintf(&b, "fly_print_fleet_problem_severity{type=%q} %d\n", problem.Type, models.HealthSeverityLevel(problem.Severity))
+		}
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// fleetHealth 返回未过期的缓存结果，过期时重新计算
+func (h *HealthHandler) fleetHealth(now time.Time) (*models.FleetHealth, error) {
+	cfg := h.settings.Get().Health
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cached != nil && now.Sub(h.cached.GeneratedAt) < cfg.CacheTTL {
+		return h.cached, nil
+	}
+
+	health, err := h.computeFleetHealth(cfg, now)
+	if err != nil {
+		return nil, err
+	}
+	h.cached = health
+	return health, nil
+}
+
+// computeFleetHealth 逐类查询问题资源，按 health.critical 阈值确定严重程度
+func (h *HealthHandler) computeFleetHealth(cfg config.HealthConfig, now time.Time) (*models.FleetHealth, error) {
+	health := &models.FleetHealth{Severity: models.HealthOK, Problems: []models.FleetProblem{}, GeneratedAt: now}
+
+	add := func(problemType, resourceType string, critical int, list func() ([]string, int, error)) error {
+		ids, count, err := list()
+		if err != nil {
+			return err
+		}
+		severity := models.HealthOK
+		if count > 0 {
+			severity = models.HealthWarning
+			if critical > 0 && count >= critical {
+				severity = models.HealthCritical
+			}
+		}
+		if models.HealthSeverityLevel(severity) > models.HealthSeverityLevel(health.Severity) {
+			health.Severity = severity
+		}
+		health.Problems = append(health.Problems, models.FleetProblem{
+			Type:         problemType,
+			Severity:     severity,
+			Count:        count,
+			ResourceType: resourceType,
+			IDs:          ids,
+		})
+		return nil
+	}
+
+	limit := cfg.MaxIDs
+	if err := add(models.FleetProblemNodesOffline, "edge_node", cfg.Critical.NodesOffline, func() ([]string, int, error) {
+		return h.edgeNodeRepo.ListOfflineEdgeNodeIDs(now.Add(-cfg.NodeOfflineAfter).UTC(), limit)
+	}); err != nil {
+		return nil, err
+	}
+	if err := add(models.FleetProblemPrintersError, "printer", cfg.Critical.PrintersError, func() ([]string, int, error) {
+		return h.printerRepo.ListErrorPrinterIDs(limit)
+	}); err != nil {
+		return nil, err
+	}
+	if cfg.LowSupplyPercent > 0 {
+		if err := add(models.FleetProblemPrintersLowSupplies, "printer", cfg.Critical.PrintersLowSupplies, func() ([]string, int, error) {
+			return h.printerRepo.ListLowSupplyPrinterIDs(cfg.LowSupplyPercent, limit)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(models.FleetProblemJobsStalled, "print_job", cfg.Critical.JobsStalled, func() ([]string, int, error) {
+		return h.printJobRepo.ListStalledJobIDs(limit)
+	}); err != nil {
+		return nil, err
+	}
+	if err := add(models.FleetProblemPrintersSuspended, "printer", cfg.Critical.PrintersSuspended, func() ([]string, int, error) {
+		return h.printerRepo.ListSuspendedPrinterIDs(limit)
+	}); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/docformat"
+	"fly-print-cloud/api/internal/hotfolder"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// HotFolderSubmitter 将共享文件夹中的文件按 API 相同的流程提交为打印任务（实现 hotfolder.Submitter）
+// 任务归属于目录配置的本地用户，访问策略按该用户在本系统中的角色判断
+type HotFolderSubmitter struct {
+	jobs        *PrintJobHandler
+	userRepo    *database.UserRepository
+	storedFiles *database.StoredFileRepository
+	fileStorage storage.Storage
+	settings    *config.Store
+}
+
+// NewHotFolderSubmitter 创建共享文件夹任务提交
+func NewHotFolderSubmitter(jobs *PrintJobHandler, userRepo *database.UserRepository, storedFiles *database.StoredFileRepository, fileStorage storage.Storage, settings *config.Store) *HotFolderSubmitter {
+	return &HotFolderSubmitter{
+		jobs:        jobs,
+		userRepo:    userRepo,
+		storedFiles: storedFiles,
+		fileStorage: fileStorage,
+		settings:    settings,
+	}
+}
+
+// Submit 保存文件并创建打印任务；文件格式不允许或任务校验失败时返回 hotfolder.ErrRejected，
+// 用户不存在、存储或数据库失败时返回其他错误（文件保留在目录中，下次扫描时重试）
+func (s *HotFolderSubmitter) Submit(ctx context.Context, folder config.HotFolderConfig, fileName string, data []byte) (string, error) {
+	user, err := s.userRepo.GetActiveUserByUsername(folder.User)
+	if err != nil {
+		return "", fmt.Errorf("查询用户 %s 失败: %w", folder.User, err)
+	}
+	if user == nil {
+		return "", fmt.Errorf("用户 %s 不存在或已停用，请检查 hot_folders 配置", folder.User)
+	}
+
+	storageCfg := s.settings.Get().Storage
+	if int64(len(data)) > storageCfg.MaxUploadSize {
+		return "", fmt.Errorf("%w: 文件超过上传大小限制（%d 字节）", hotfolder.ErrRejected, storageCfg.MaxUploadSize)
+	}
+	if _, err := docformat.Inspect(data, storageCfg.AllowedFormats); err != nil {
+		_, _, message := fileFormatError(err)
+		return "", fmt.Errorf("%w: %s", hotfolder.ErrRejected, message)
+	}
+
+	contentType := http.DetectContentType(data)
+	key := storage.NewKey(storage.UploadPrefix, fileName)
+	if err := s.fileStorage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return "", fmt.Errorf("保存文件失败: %w", err)
+	}
+	if err := s.storedFiles.RecordStoredFile(&models.StoredFile{StorageKey: key, FileSize: int64(len(data)), ContentType: contentType}); err != nil {
+		log.Printf("Failed to record stored file %s: %v", key, err)
+	}
+
+	// 没有 HTTP 请求，以目录配置的用户身份构造提交上下文
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, "/hot-folder", nil)
+	if err != nil {
+		return "", err
+	}
+	c := &gin.Context{Request: request}
+	externalID := ""
+	if user.ExternalID != nil {
+		externalID = *user.ExternalID
+	}
+	c.Set("user_id", user.ID)
+	c.Set("external_id", externalID)
+	c.Set("username", user.Username)
+	c.Set("email", user.Email)
+	c.Set("roles", []string{user.Role})
+
+	req := CreatePrintJobRequest{
+		SourceName:     fileName,
+		PrinterID:      folder.PrinterID,
+		PrinterGroupID: folder.GroupID,
+		StorageKey:     key,
+		Metadata:       map[string]string{"source": "hot_folder"},
+	}
+	job, printer, buildErr := s.jobs.buildPrintJob(c, models.JobSourceHotFolder, &req, nil)
+	if buildErr != nil {
+		if buildErr.status >= http.StatusInternalServerError {
+			return "", fmt.Errorf("创建打印任务失败: %v", buildErr.body["error"])
+		}
+		return "", fmt.Errorf("%w: %v", hotfolder.ErrRejected, buildErr.body["error"])
+	}
+
+	if err := s.jobs.submitPrintJob(c, job, printer, jobSubmission{
+		details: map[string]interface{}{"folder": folder.Path, "file": fileName},
+	}); err != nil {
+		return "", fmt.Errorf("创建打印任务失败: %w", err)
+	}
+	return job.ID, nil
+}
+
+// HotFolderHandler 共享文件夹监视状态查询
+type HotFolderHandler struct {
+	watcher *hotfolder.Watcher // 未启用时为 nil
+}
+
+// NewHotFolderHandler 创建共享文件夹状态处理器，watcher 为 nil 表示未启用
+func NewHotFolderHandler(watcher *hotfolder.Watcher) *HotFolderHandler {
+	return &HotFolderHandler{watcher: watcher}
+}
+
+// ListHotFolders 列出各共享文件夹的监视状态：最近扫描时间、等待写入完成的文件数、已提交和失败的文件数及最近的错误
+func (h *HotFolderHandler) ListHotFolders(c *gin.Context) {
+	if h.watcher == nil {
+		SuccessResponse(c, gin.H{"enabled": false, "folders": []hotfolder.FolderStatus{}})
+		return
+	}
+	SuccessResponse(c, gin.H{"enabled": true, "folders": h.watcher.Status()})
+}
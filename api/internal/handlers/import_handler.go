@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/importer"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxImportFileSize 导入文件大小上限
+	maxImportFileSize = 10 * 1024 * 1024
+	// maxImportRows 单次导入的最大行数
+	maxImportRows = 5000
+	// importBatchSize 每个事务写入的行数
+	importBatchSize = 100
+)
+
+// ImportHandler Edge Node / 打印机批量导入处理器
+type ImportHandler struct {
+	importRepo *database.ImportRepository
+	auditRepo  *database.AuditLogRepository
+}
+
+// NewImportHandler 创建批量导入处理器
+func NewImportHandler(importRepo *database.ImportRepository, auditRepo *database.AuditLogRepository) *ImportHandler {
+	return &ImportHandler{
+		importRepo: importRepo,
+		auditRepo:  auditRepo,
+	}
+}
+
+// Import 导入 CSV/JSON 文件中的 Edge Node 和打印机
+// dry_run=true 时只校验并返回逐行结果，不写入数据库
+func (h *ImportHandler) Import(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	// 限制请求体大小（额外预留 multipart 头部空间）
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize+64*1024)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		BadRequestResponse(c, fmt.Sprintf("缺少导入文件或文件超过大小限制（%d 字节）", maxImportFileSize))
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		BadRequestResponse(c, fmt.Sprintf("导入文件超过大小限制（%d 字节）", maxImportFileSize))
+		return
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		InternalErrorResponse(c, "读取导入文件失败")
+		return
+	}
+	defer src.Close()
+	data, err := io.ReadAll(src)
+	if err != nil {
+		InternalErrorResponse(c, "读取导入文件失败")
+		return
+	}
+
+	format := importer.Format(c.Query("format"))
+	if format != importer.FormatCSV && format != importer.FormatJSON {
+		format = importer.DetectFormat(fileHeader.Filename, fileHeader.Header.Get("Content-Type"), data)
+	}
+
+	rows, err := importer.Parse(format, bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, importer.ErrInvalidFile) {
+			BadRequestResponse(c, "导入文件格式错误: "+err.Error())
+			return
+		}
+		InternalErrorResponse(c, "解析导入文件失败")
+		return
+	}
+	if len(rows) == 0 {
+		BadRequestResponse(c, "导入文件中没有数据")
+		return
+	}
+	if len(rows) > maxImportRows {
+		BadRequestResponse(c, fmt.Sprintf("单次最多导入 %d 行", maxImportRows))
+		return
+	}
+
+	if err := h.resolveActions(rows); err != nil {
+		log.Printf("Failed to resolve import rows: %v", err)
+		InternalErrorResponse(c, "校验导入数据失败")
+		return
+	}
+
+	if !dryRun {
+		h.apply(rows)
+	}
+
+	summary := importer.Summarize(rows)
+	if !dryRun {
+		recordAudit(c, h.auditRepo, "import.apply", "import", "",
+			fmt.Sprintf("file=%s, format=%s, created=%d, updated=%d, failed=%d",
+				fileHeader.Filename, format, summary.Created, summary.Updated, summary.Failed))
+	}
+
+	SuccessResponse(c, gin.H{
+		"dry_run": dryRun,
+		"format":  format,
+		"summary": summary,
+		"rows":    rows,
+	})
+}
+
+// resolveActions 对照数据库判断每行是新建还是更新，并检查打印机所属的 Edge Node 是否存在
+func (h *ImportHandler) resolveActions(rows []*importer.Row) error {
+	var nodeIDs []string
+	seenNode := make(map[string]bool)
+	for _, row := range rows {
+		if row.Record.NodeID != "" && !seenNode[row.Record.NodeID] {
+			seenNode[row.Record.NodeID] = true
+			nodeIDs = append(nodeIDs, row.Record.NodeID)
+		}
+	}
+
+	existingNodes, err := h.importRepo.ExistingEdgeNodes(nodeIDs)
+	if err != nil {
+		return err
+	}
+	existingPrinters, err := h.importRepo.ExistingPrinters(nodeIDs)
+	if err != nil {
+		return err
+	}
+
+	importedNodes := make(map[string]bool)
+	for _, row := range rows {
+		if row.RecordType == importer.RecordEdgeNode && row.Valid() {
+			importedNodes[row.Record.NodeID] = true
+		}
+	}
+
+	for _, row := range rows {
+		if !row.Valid() {
+			continue
+		}
+		switch row.RecordType {
+		case importer.RecordEdgeNode:
+			row.Action = importer.ActionCreate
+			if existingNodes[row.Key] {
+				row.Action = importer.ActionUpdate
+			}
+		case importer.RecordPrinter:
+			if !existingNodes[row.Record.NodeID] && !importedNodes[row.Record.NodeID] {
+				row.Fail("node_id: Edge Node %s 不存在", row.Record.NodeID)
+				continue
+			}
+			row.Action = importer.ActionCreate
+			if existingPrinters[row.Key] {
+				row.Action = importer.ActionUpdate
+			}
+		}
+	}
+	return nil
+}
+
+// apply 按批写入校验通过的行；某批失败时整批标记为失败，依赖失败 Edge Node 的打印机行不再写入
+func (h *ImportHandler) apply(rows []*importer.Row) {
+	failedNodes := make(map[string]bool)
+	var batch []*importer.Row
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := h.importRepo.ApplyBatch(batch); err != nil {
+			log.Printf("Import batch of %d rows failed: %v", len(batch), err)
+			for _, row := range batch {
+				row.Fail("写入失败（同批次已回滚）: %v", err)
+				if row.RecordType == importer.RecordEdgeNode {
+					failedNodes[row.Record.NodeID] = true
+				}
+			}
+		}
+		batch = batch[:0]
+	}
+
+	for _, row := range rows {
+		if !row.Valid() {
+			continue
+		}
+		// Edge Node 行全部写入后再写打印机行，两类记录不混在同一批次
+		if len(batch) > 0 && batch[0].RecordType != row.RecordType {
+			flush()
+		}
+		if row.RecordType == importer.RecordPrinter && failedNodes[row.Record.NodeID] {
+			row.Fail("所属 Edge Node %s 导入失败", row.Record.NodeID)
+			continue
+		}
+		batch = append(batch, row)
+		if len(batch) >= importBatchSize {
+			flush()
+		}
+	}
+	flush()
+}
+
+// DownloadTemplate 下载导入模板（csv 或 json）
+func (h *ImportHandler) DownloadTemplate(c *gin.Context) {
+	switch importer.Format(c.Param("format")) {
+	case importer.FormatCSV:
+		c.Header("Content-Disposition", `attachment; filename="import-template.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", importer.CSVTemplate())
+	case importer.FormatJSON:
+		c.Header("Content-Disposition", `attachment; filename="import-template.json"`)
+		c.Data(http.StatusOK, "application/json; charset=utf-8", importer.JSONTemplate())
+	default:
+		NotFoundResponse(c, "模板格式仅支持 csv 或 json")
+	}
+}
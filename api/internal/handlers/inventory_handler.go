@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// inventoryFlushEvery 每写出多少条记录刷新一次响应缓冲
+const inventoryFlushEvery = 200
+
+// InventoryHandler 资产清单导出处理器
+type InventoryHandler struct {
+	inventoryRepo *database.InventoryRepository
+}
+
+// NewInventoryHandler 创建资产清单导出处理器
+func NewInventoryHandler(inventoryRepo *database.InventoryRepository) *InventoryHandler {
+	return &InventoryHandler{
+		inventoryRepo: inventoryRepo,
+	}
+}
+
+// ExportInventory 以 NDJSON 流式导出全部打印机与 Edge Node（每行一条记录，不分页），供 CMDB 同步
+// 可选参数 since（RFC3339 时间或 YYYY-MM-DD 日期）：只导出该时间之后更新的记录，用于增量同步
+func (h *InventoryHandler) ExportInventory(c *gin.Context) {
+	var since *time.Time
+	if raw := c.Query("since"); raw != "" {
+		t, err := parseInventorySince(raw)
+		if err != nil {
+			BadRequestResponse(c, "since 参数格式错误，应为 RFC3339 时间或 YYYY-MM-DD 日期")
+			return
+		}
+		since = &t
+	}
+
+	snapshotTime := time.Now()
+	c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // 关闭 Nginx 缓冲
+	c.Header("X-Inventory-Snapshot-Time", snapshotTime.Format(time.RFC3339))
+
+	encoder := json.NewEncoder(c.Writer)
+	count := 0
+	err := h.inventoryRepo.ForEachRecord(since, func(record *models.InventoryRecord) error {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+		count++
+		if count%inventoryFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// 已开始输出时无法再返回错误响应，客户端以连接中断判断导出不完整
+		if c.Writer.Written() {
+			log.Printf("Inventory export aborted after %d records: %v", count, err)
+			return
+		}
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("X-Inventory-Snapshot-Time")
+		InternalErrorResponse(c, "导出资产清单失败")
+		return
+	}
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+}
+
+// parseInventorySince 解析 since 参数：RFC3339 时间，或按本地时区解释的日期
+func parseInventorySince(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return t.In(time.Local), nil
+	}
+	return time.ParseInLocation("2006-01-02", raw, time.Local)
+}
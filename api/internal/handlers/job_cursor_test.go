@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestJobCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 3, 4, 5, 6, 7, 123456789, time.UTC)
+	const id = "3f1c2a9e-8b7d-4c6e-9a51-2d7f0b3e4c5a"
+	cursor, err := decodeJobCursor(encodeJobCursor(createdAt, id))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !cursor.CreatedAt.Equal(createdAt) || cursor.ID != id {
+		t.Fatalf("cursor = %+v, want %s|%s", cursor, createdAt, id)
+	}
+}
+
+// TestListPrintJobsByCursorRejectsMalformed 无法解析的游标在查询数据库之前以 400 拒绝
+// 处理器没有仓库，通过校验的请求会 panic
+func TestListPrintJobsByCursorRejectsMalformed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"garbage base64", "!!not*base64!!"},
+		{"padded base64", encode("x") + "=="},
+		{"missing separator", encode("2026-03-04T05:06:07Z")},
+		{"bad timestamp", encode("yesterday|3f1c2a9e-8b7d-4c6e-9a51-2d7f0b3e4c5a")},
+		{"bad id", encode("2026-03-04T05:06:07Z|42")},
+		{"empty parts", encode("|")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeJobCursor(tt.cursor); err == nil {
+				t.Fatalf("decodeJobCursor(%q) succeeded, want error", tt.cursor)
+			}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/admin/print-jobs?status=completed", nil)
+			(&PrintJobHandler{}).listPrintJobsByCursor(c, tt.cursor)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", w.Code)
+			}
+		})
+	}
+}
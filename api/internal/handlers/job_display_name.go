@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"fly-print-cloud/api/internal/docformat"
+)
+
+const (
+	// maxSourceNameLength 原始文件名的长度上限（与数据库字段定义一致，按字符计）
+	maxSourceNameLength = 255
+	// pdfTitleTimeout 提交任务时读取 PDF 标题的最长时间，超时按没有标题处理
+	pdfTitleTimeout = 200 * time.Millisecond
+)
+
+// opaqueFileName 哈希、UUID 等无意义的文件名（去掉扩展名后只剩十六进制字符和连字符）
+var opaqueFileName = regexp.MustCompile(`^[0-9a-fA-F-]{12,}$`)
+
+// defaultJobName 无法从文件得到名称时的任务名称
+func defaultJobName() string {
+	return fmt.Sprintf("打印任务_%s", time.Now().Format("20060102_150405"))
+}
+
+// cleanDisplayName 由原始文件名生成显示名称：去掉扩展名，下划线和加号换成空格；
+// 文件名为哈希、UUID 等无意义内容时返回空字符串
+func cleanDisplayName(sourceName string) string {
+	name := sourceName
+	if ext := path.Ext(name); ext != "" && len(ext) <= 6 {
+		name = strings.TrimSuffix(name, ext)
+	}
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '+' || unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	if name == "" || opaqueFileName.MatchString(name) || !strings.ContainsFunc(name, unicode.IsLetter) {
+		return ""
+	}
+	return name
+}
+
+// pdfTitle 尽力读取 PDF 标题，超过 pdfTitleTimeout 时放弃（解析在后台结束后丢弃结果），不阻塞提交
+func pdfTitle(key string, data []byte) string {
+	result := make(chan string, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Failed to read title of stored file %s: %v", key, r)
+				result <- ""
+			}
+		}()
+		result <- docformat.PDFTitle(data)
+	}()
+
+	select {
+	case title := <-result:
+		return title
+	case <-time.After(pdfTitleTimeout):
+		log.Printf("Reading title of stored file %s timed out after %s", key, pdfTitleTimeout)
+		return ""
+	}
+}
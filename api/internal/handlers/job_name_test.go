@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	cjk := strings.Repeat("年", maxJobNameLength) // 每个字符 3 字节
+	tests := []struct {
+		name string
+		in   string
+		max  int
+		want string
+	}{
+		{"ascii under limit", "report.pdf", maxJobNameLength, "report.pdf"},
+		{"cjk at limit", cjk, maxJobNameLength, cjk},
+		{"cjk one rune over", cjk + "报", maxJobNameLength, cjk},
+		{"mixed straddling byte limit", "ab年度报告", 4, "ab年度"},
+		{"empty", "", maxJobNameLength, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateRunes(tt.in, tt.max)
+			if got != tt.want {
+				t.Fatalf("truncateRunes = %q (%d runes), want %q", got, utf8.RuneCountInString(got), tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Fatalf("truncateRunes returned invalid UTF-8 %q", got)
+			}
+		})
+	}
+}
+
+func TestFilenameFromURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://files.example.com/%E5%B9%B4%E5%BA%A6%E6%8A%A5%E5%91%8A.pdf", "年度报告.pdf"},
+		{"https://files.example.com/docs/%E5%B9%B4%E5%BA%A6%E6%8A%A5%E5%91%8A.pdf?sig=abc#page=2", "年度报告.pdf"},
+		{"https://files.example.com/a%zzb.pdf", "a%zzb.pdf"}, // 无效的百分号编码保留原文
+		{"https://files.example.com/a%zzb.pdf?x=1", "a%zzb.pdf"},
+		{"https://files.example.com/", ""},
+		{"report.pdf", "report.pdf"},
+	}
+	for _, tt := range tests {
+		got := filenameFromURL(tt.url)
+		if got != tt.want {
+			t.Fatalf("filenameFromURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Fatalf("filenameFromURL(%q) returned invalid UTF-8", tt.url)
+		}
+	}
+}
+
+// TestCreatePrintJobFileURLTooLong 超长的 file_url 在访问数据库之前以 400 拒绝，错误信息包含长度上限
+// 处理器没有仓库，通过校验的请求会 panic
+func TestCreatePrintJobFileURLTooLong(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prefix := "https://files.example.com/"
+	fileURL := prefix + strings.Repeat("a", maxFileURLLength+1-len(prefix))
+
+	body, _ := json.Marshal(map[string]interface{}{"printer_id": "p1", "file_url": fileURL})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/print-jobs", strings.NewReader(string(body)))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("external_id", "user-1")
+	c.Set("username", "alice")
+	(&PrintJobHandler{}).CreatePrintJob(c)
+
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if w.Code != http.StatusBadRequest || json.Unmarshal(w.Body.Bytes(), &resp) != nil || !strings.Contains(resp.Error, "1000") {
+		t.Fatalf("status = %d, body = %s; want 400 naming the 1000 character limit", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/docformat"
+	"fly-print-cloud/api/internal/mailin"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/notify"
+	"fly-print-cloud/api/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// MailInHandler 邮件打印 webhook：邮件服务将收到的原始邮件转发到这里，附件按收件地址提交为打印任务
+// 发件人按 From 头匹配本地启用的用户，伪造发件人需由邮件服务的 SPF/DKIM 校验拦截
+type MailInHandler struct {
+	jobs        *PrintJobHandler
+	userRepo    *database.UserRepository
+	storedFiles *database.StoredFileRepository
+	fileStorage storage.Storage
+	mailer      *notify.Mailer
+	cfg         *config.MailInConfig
+	router      *mailin.Router
+	settings    *config.Store
+}
+
+// NewMailInHandler 创建邮件打印处理器
+func NewMailInHandler(jobs *PrintJobHandler, userRepo *database.UserRepository, storedFiles *database.StoredFileRepository, fileStorage storage.Storage, mailer *notify.Mailer, cfg *config.MailInConfig, settings *config.Store) (*MailInHandler, error) {
+	router, err := mailin.NewRouter(cfg.PrinterPattern, cfg.GroupPattern)
+	if err != nil {
+		return nil, err
+	}
+	return &MailInHandler{
+		jobs:        jobs,
+		userRepo:    userRepo,
+		storedFiles: storedFiles,
+		fileStorage: fileStorage,
+		mailer:      mailer,
+		cfg:         cfg,
+		router:      router,
+		settings:    settings,
+	}, nil
+}
+
+// Receive 接收入站邮件
+// 请求体为原始邮件（message/rfc822），或 multipart 表单的 body-mime 字段（Mailgun 的 MIME 格式转发）；
+// 可用 recipient 参数（查询参数或表单字段）指定实际收件地址，覆盖邮件头中的收件人。
+// 发件人、收件地址或附件不符合要求时退信并返回 200，避免邮件服务反复重试
+func (h *MailInHandler) Receive(c *gin.Context) {
+	token := c.GetHeader("X-Mailin-Token")
+	if token == "" {
+		token = c.Query("token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.Token)) != 1 {
+		UnauthorizedResponse(c, "无效的 webhook 令牌")
+		return
+	}
+
+	raw, recipient, err := h.readMessage(c)
+	if err != nil {
+		BadRequestResponse(c, err.Error())
+		return
+	}
+	msg, err := mailin.Parse(bytes.NewReader(raw))
+	if err != nil {
+		BadRequestResponse(c, fmt.Sprintf("无法解析邮件: %v", err))
+		return
+	}
+	if recipient != "" {
+		msg.Recipients = []string{strings.ToLower(recipient)}
+	}
+
+	user, err := h.userRepo.GetActiveUserByEmail(msg.From)
+	if err != nil {
+		log.Printf("Failed to look up mail-in sender %s: %v", msg.From, err)
+		InternalErrorResponse(c, "查询发件人失败")
+		return
+	}
+	if user == nil {
+		h.bounce(c, msg, "发件人不是已启用的用户，无法通过邮件提交打印任务")
+		return
+	}
+
+	target, ok := h.router.Resolve(msg.Recipients)
+	if !ok {
+		h.bounce(c, msg, "收件地址没有对应的打印机或打印机组")
+		return
+	}
+
+	keys, reason, err := h.storeAttachments(c, msg)
+	if err != nil {
+		InternalErrorResponse(c, "保存附件失败")
+		return
+	}
+	if reason != "" {
+		h.bounce(c, msg, reason)
+		return
+	}
+
+	// 以发件人身份提交，访问策略按用户在本系统中的角色判断
+	externalID := ""
+	if user.ExternalID != nil {
+		externalID = *user.ExternalID
+	}
+	c.Set("user_id", user.ID)
+	c.Set("external_id", externalID)
+	c.Set("username", user.Username)
+	c.Set("email", user.Email)
+	c.Set("roles", []string{user.Role})
+
+	req := CreatePrintJobRequest{
+		PrinterID:      target.PrinterID,
+		PrinterGroupID: target.GroupID,
+		Metadata:       map[string]string{"source": "email"},
+	}
+	if msg.Subject != "" {
+		req.Name = truncateRunes(msg.Subject, maxJobNameLength)
+	}
+	if len(keys) == 1 {
+		req.StorageKey = keys[0]
+	} else {
+		for _, key := range keys {
+			req.Files = append(req.Files, PrintJobFileRequest{StorageKey: key})
+		}
+	}
+
+	job, printer, buildErr := h.jobs.buildPrintJob(c, models.JobSourceEmail, &req, nil)
+	if buildErr != nil {
+		if buildErr.status >= http.StatusInternalServerError {
+			c.JSON(buildErr.status, buildErr.body)
+			return
+		}
+		h.bounce(c, msg, fmt.Sprint(buildErr.body["error"]))
+		return
+	}
+
+	if err := h.jobs.submitPrintJob(c, job, printer, jobSubmission{
+		details: map[string]interface{}{"sender": msg.From},
+	}); err != nil {
+		InternalErrorResponse(c, "创建打印任务失败")
+		return
+	}
+
+	log.Printf("Mail-in job %s created from %s to %s", job.ID, msg.From, target.Recipient)
+	CreatedResponse(c, gin.H{"status": "accepted", "job_id": job.ID})
+}
+
+// readMessage 读取原始邮件和可选的收件地址
+func (h *MailInHandler) readMessage(c *gin.Context) ([]byte, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxMessageSize+64*1024)
+	recipient := c.Query("recipient")
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		mime := c.PostForm("body-mime")
+		if mime == "" {
+			return nil, "", fmt.Errorf("缺少 body-mime 字段或邮件超过大小限制（%d 字节）", h.cfg.MaxMessageSize)
+		}
+		if int64(len(mime)) > h.cfg.MaxMessageSize {
+			return nil, "", fmt.Errorf("邮件超过大小限制（%d 字节）", h.cfg.MaxMessageSize)
+		}
+		if form := c.PostForm("recipient"); form != "" {
+			recipient = form
+		}
+		return []byte(strings.TrimLeft(mime, "\r\n")), recipient, nil
+	}
+
+	raw, err := mailin.ReadBody(c.Request.Body, h.cfg.MaxMessageSize)
+	if err != nil {
+		return nil, "", fmt.Errorf("读取邮件失败或邮件超过大小限制（%d 字节）", h.cfg.MaxMessageSize)
+	}
+	return raw, recipient, nil
+}
+
+// storeAttachments 校验并保存全部附件，返回存储键；任一附件不符合要求时不保存，返回退信原因
+// 存储失败时返回错误（响应 500，由邮件服务重试）
+func (h *MailInHandler) storeAttachments(c *gin.Context, msg *mailin.Message) ([]string, string, error) {
+	if len(msg.Attachments) == 0 {
+		return nil, "邮件没有附件，请将要打印的文件作为附件发送", nil
+	}
+
+	storageCfg := h.settings.Get().Storage
+	var rejected []string
+	for _, attachment := range msg.Attachments {
+		if int64(len(attachment.Data)) > storageCfg.MaxUploadSize {
+			rejected = append(rejected, fmt.Sprintf("%s：超过大小限制（%d 字节）", attachment.Filename, storageCfg.MaxUploadSize))
+			continue
+		}
+		if _, err := docformat.Inspect(attachment.Data, storageCfg.AllowedFormats); err != nil {
+			_, _, message := fileFormatError(err)
+			rejected = append(rejected, fmt.Sprintf("%s：%s", attachment.Filename, message))
+		}
+	}
+	if len(rejected) > 0 {
+		return nil, "以下附件无法打印：\n" + strings.Join(rejected, "\n"), nil
+	}
+
+	keys := make([]string, 0, len(msg.Attachments))
+	for _, attachment := range msg.Attachments {
+		fileName := filepath.Base(attachment.Filename)
+		key := storage.NewKey(storage.UploadPrefix, fileName)
+		if err := h.fileStorage.Put(c.Request.Context(), key, bytes.NewReader(attachment.Data), int64(len(attachment.Data)), attachment.ContentType); err != nil {
+			log.Printf("Failed to store mail-in attachment %s: %v", fileName, err)
+			return nil, "", err
+		}
+		if err := h.storedFiles.RecordStoredFile(&models.StoredFile{StorageKey: key, FileSize: int64(len(attachment.Data)), ContentType: attachment.ContentType}); err != nil {
+			log.Printf("Failed to record stored file %s: %v", key, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, "", nil
+}
+
+// bounce 向发件人退信并返回 200（邮件已处理，不需要重试）；邮件发送未启用时只记录日志
+func (h *MailInHandler) bounce(c *gin.Context, msg *mailin.Message, reason string) {
+	log.Printf("Mail-in message %s from %s rejected: %s", msg.MessageID, msg.From, reason)
+
+	if h.mailer.Enabled() {
+		subject := "邮件打印失败"
+		if msg.Subject != "" {
+			subject = fmt.Sprintf("邮件打印失败：%s", msg.Subject)
+		}
+		body := fmt.Sprintf("您发送的邮件未能提交打印任务。\n\n原因：%s\n", reason)
+		if err := h.mailer.Send(msg.From, subject, body); err != nil {
+			log.Printf("Failed to send mail-in bounce to %s: %v", msg.From, err)
+		}
+	}
+
+	SuccessResponse(c, gin.H{"status": "bounced", "reason": reason})
+}
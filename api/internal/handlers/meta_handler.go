@@ -0,0 +1,11 @@
+package handlers
+
+import (
+	"fly-print-cloud/api/internal/papersize"
+	"github.com/gin-gonic/gin"
+)
+
+// ListPaperSizes 获取纸张尺寸目录（规范ID、名称、尺寸和可识别的别名），供管理界面下拉框使用
+func ListPaperSizes(c *gin.Context) {
+	SuccessResponse(c, papersize.Catalog())
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"fmt"
+
+	"fly-print-cloud/api/internal/netaddr"
+	"github.com/gin-gonic/gin"
+)
+
+// normalizeNetworkAddresses 规范化请求中的 ip_address 和 mac_address（空值置为 nil，入库为 NULL）
+// 无效值返回 400 并指明字段，避免写入 INET 列时数据库报错
+func normalizeNetworkAddresses(c *gin.Context, ipAddress, macAddress **string) bool {
+	ip, err := netaddr.NormalizeIPPtr(*ipAddress)
+	if err != nil {
+		BadRequestResponse(c, fmt.Sprintf("ip_address: 无效的 IP 地址 %q", **ipAddress))
+		return false
+	}
+	mac, err := netaddr.NormalizeMACPtr(*macAddress)
+	if err != nil {
+		BadRequestResponse(c, fmt.Sprintf("mac_address: 无效的 MAC 地址 %q", **macAddress))
+		return false
+	}
+	*ipAddress, *macAddress = ip, mac
+	return true
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestNormalizeNetworkAddresses 无效地址返回 400 并指明字段，空值置为 nil
+func TestNormalizeNetworkAddresses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name      string
+		ip, mac   *string
+		wantOK    bool
+		wantIP    *string
+		wantMAC   *string
+		wantField string
+	}{
+		{name: "normalized", ip: str(" ::ffff:10.0.0.1 "), mac: str("AA-BB-CC-DD-EE-FF"), wantOK: true,
+			wantIP: str("10.0.0.1"), wantMAC: str("aa:bb:cc:dd:ee:ff")},
+		{name: "empty stored as NULL", ip: str(""), mac: str("00:00:00:00:00:00"), wantOK: true},
+		{name: "absent", wantOK: true},
+		{name: "invalid ip", ip: str("printer.local"), mac: str("aabbccddeeff"), wantField: "ip_address"},
+		{name: "invalid mac", ip: str("10.0.0.1"), mac: str("aa:bb:cc"), wantField: "mac_address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			ip, mac := tt.ip, tt.mac
+
+			ok := normalizeNetworkAddresses(c, &ip, &mac)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				var body struct {
+					Message string `json:"message"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if w.Code != http.StatusBadRequest || !strings.HasPrefix(body.Message, tt.wantField+":") {
+					t.Fatalf("response %d %q, want 400 naming %s", w.Code, body.Message, tt.wantField)
+				}
+				return
+			}
+			if !equalStringPtr(ip, tt.wantIP) || !equalStringPtr(mac, tt.wantMAC) {
+				t.Fatalf("normalized to ip %v, mac %v", ip, mac)
+			}
+		})
+	}
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
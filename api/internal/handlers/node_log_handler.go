@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultLogTail 未指定 tail 时先推送的最近日志行数
+const defaultLogTail = 200
+
+// NodeLogHandler Edge Node Agent 日志实时查看处理器（SSE）
+type NodeLogHandler struct {
+	edgeNodeRepo *database.EdgeNodeRepository
+	wsManager    *websocket.ConnectionManager
+	logs         *websocket.NodeLogs
+	auditRepo    *database.AuditLogRepository
+}
+
+// NewNodeLogHandler 创建 Agent 日志查看处理器
+func NewNodeLogHandler(edgeNodeRepo *database.EdgeNodeRepository, wsManager *websocket.ConnectionManager, logs *websocket.NodeLogs, auditRepo *database.AuditLogRepository) *NodeLogHandler {
+	return &NodeLogHandler{
+		edgeNodeRepo: edgeNodeRepo,
+		wsManager:    wsManager,
+		logs:         logs,
+		auditRepo:    auditRepo,
+	}
+}
+
+// StreamLogs 以 Server-Sent Events 推送节点最近的 Agent 日志
+// follow=true 时通知节点开始上报并持续推送新日志，所有查看者离开一分钟后节点停止上报
+func (h *NodeLogHandler) StreamLogs(c *gin.Context) {
+	nodeID := c.Param("id")
+	if _, err := h.edgeNodeRepo.GetEdgeNodeByID(nodeID); err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	follow, _ := strconv.ParseBool(c.DefaultQuery("follow", "false"))
+	tail, err := strconv.Atoi(c.DefaultQuery("tail", strconv.Itoa(defaultLogTail)))
+	if err != nil || tail < 0 {
+		BadRequestResponse(c, "tail 必须为非负整数")
+		return
+	}
+
+	var follower *websocket.LogFollower
+	if follow {
+		if !h.wsManager.IsNodeConnected(nodeID) {
+			ErrorResponse(c, http.StatusConflict, "Edge Node 未连接，无法跟踪日志")
+			return
+		}
+		var stop func()
+		follower, stop, err = h.logs.Follow(nodeID)
+		if err != nil {
+			ErrorResponse(c, http.StatusTooManyRequests, "跟踪该节点日志的连接过多，请稍后再试")
+			return
+		}
+		defer stop()
+		recordAudit(c, h.auditRepo, "edge_node.logs_follow", "edge_node", nodeID, "")
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 Nginx 缓冲
+
+	// tail=0 时不推送已缓冲的日志
+	if tail > 0 {
+		for _, line := range h.logs.Tail(nodeID, tail) {
+			c.SSEvent("log", line)
+		}
+	}
+	c.SSEvent("status", h.logs.Stats(nodeID))
+	c.Writer.Flush()
+	if follower == nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case line := <-follower.Lines:
+			// 查看者跟不上时被丢弃的行数，随下一行日志一起通知
+			if dropped := follower.TakeDropped(); dropped > 0 {
+				c.SSEvent("dropped", gin.H{"count": dropped})
+			}
+			c.SSEvent("log", line)
+			return true
+		case <-keepAlive.C:
+			c.SSEvent("ping", gin.H{"timestamp": time.Now()})
+			return true
+		}
+	})
+}
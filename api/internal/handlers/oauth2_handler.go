@@ -24,14 +24,20 @@ type OAuth2Handler struct {
 	logoutURL               string
 	logoutRedirectURIParam  string
 	userRepo                *database.UserRepository
+	httpClient              *http.Client
 }
 
 // NewOAuth2Handler 创建 OAuth2 处理器
-func NewOAuth2Handler(oauth2Cfg *config.OAuth2Config, adminCfg *config.AdminConfig, userRepo *database.UserRepository) *OAuth2Handler {
+func NewOAuth2Handler(oauth2Cfg *config.OAuth2Config, adminCfg *config.AdminConfig, userRepo *database.UserRepository, httpClient *http.Client) *OAuth2Handler {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
 	// 如果 OAuth2 配置为空，创建一个基本的处理器
 	if oauth2Cfg.ClientID == "" || oauth2Cfg.AuthURL == "" || oauth2Cfg.TokenURL == "" {
 		return &OAuth2Handler{
-			config: nil, // 配置为空时设为 nil
+			config:     nil, // 配置为空时设为 nil
+			httpClient: httpClient,
 		}
 	}
 
@@ -53,6 +59,7 @@ func NewOAuth2Handler(oauth2Cfg *config.OAuth2Config, adminCfg *config.AdminConf
 		logoutURL:               oauth2Cfg.LogoutURL,
 		logoutRedirectURIParam:  oauth2Cfg.LogoutRedirectURIParam,
 		userRepo:                userRepo,
+		httpClient:              httpClient,
 	}
 }
 
@@ -99,6 +106,7 @@ func (h *OAuth2Handler) Callback(c *gin.Context) {
 	// 交换 token
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, h.httpClient)
 
 	token, err := h.config.Exchange(ctx, code)
 	if err != nil {
@@ -210,7 +218,7 @@ func (h *OAuth2Handler) fetchOAuth2UserInfo(accessToken string) (*OAuth2UserInfo
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := h.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// PowerScheduleHandler 打印机节能计划处理器
+type PowerScheduleHandler struct {
+	scheduleRepo *database.PowerScheduleRepository
+	printerRepo  *database.PrinterRepository
+	edgeNodeRepo *database.EdgeNodeRepository
+	auditRepo    *database.AuditLogRepository
+}
+
+// NewPowerScheduleHandler 创建打印机节能计划处理器
+func NewPowerScheduleHandler(scheduleRepo *database.PowerScheduleRepository, printerRepo *database.PrinterRepository, edgeNodeRepo *database.EdgeNodeRepository, auditRepo *database.AuditLogRepository) *PowerScheduleHandler {
+	return &PowerScheduleHandler{
+		scheduleRepo: scheduleRepo,
+		printerRepo:  printerRepo,
+		edgeNodeRepo: edgeNodeRepo,
+		auditRepo:    auditRepo,
+	}
+}
+
+// PowerScheduleRequest 设置节能计划请求
+type PowerScheduleRequest struct {
+	Days      []int  `json:"days" binding:"required,min=1,max=7"` // 0=周日 … 6=周六
+	StartTime string `json:"start_time" binding:"required"`       // 休眠开始时间 HH:MM
+	EndTime   string `json:"end_time" binding:"required"`         // 唤醒时间 HH:MM
+	Timezone  string `json:"timezone"`                            // IANA 时区，默认 UTC
+	Enabled   *bool  `json:"enabled"`                             // 默认启用
+}
+
+// toSchedule 校验请求并转换为节能计划
+func (req *PowerScheduleRequest) toSchedule() (*models.PrinterPowerSchedule, error) {
+	schedule := &models.PrinterPowerSchedule{
+		Days:      req.Days,
+		StartTime: strings.TrimSpace(req.StartTime),
+		EndTime:   strings.TrimSpace(req.EndTime),
+		Timezone:  strings.TrimSpace(req.Timezone),
+		Enabled:   true,
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if req.Enabled != nil {
+		schedule.Enabled = *req.Enabled
+	}
+	return schedule, schedule.Validate()
+}
+
+// GetPrinterPowerSchedule 获取打印机的节能计划（含继承自 Edge Node 的计划）
+func (h *PowerScheduleHandler) GetPrinterPowerSchedule(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	schedule, err := h.scheduleRepo.GetEffectiveSchedule(printer.ID, printer.EdgeNodeID)
+	if err != nil {
+		log.Printf("Failed to get power schedule for printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "获取节能计划失败")
+		return
+	}
+	if schedule == nil {
+		NotFoundResponse(c, "未设置节能计划")
+		return
+	}
+
+	SuccessResponse(c, gin.H{
+		"schedule":    schedule,
+		"inherited":   schedule.PrinterID == nil,
+		"power_state": printer.PowerState,
+	})
+}
+
+// SetPrinterPowerSchedule 设置打印机的节能计划（覆盖 Edge Node 的计划）
+func (h *PowerScheduleHandler) SetPrinterPowerSchedule(c *gin.Context) {
+	var req PowerScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	schedule, err := req.toSchedule()
+	if err != nil {
+		BadRequestResponse(c, "节能计划无效: "+err.Error())
+		return
+	}
+
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	schedule.PrinterID = &printer.ID
+	if err := h.scheduleRepo.UpsertSchedule(schedule); err != nil {
+		log.Printf("Failed to set power schedule for printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "设置节能计划失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "power_schedule.update", "printer", printer.ID, describeSchedule(schedule))
+
+	SuccessResponse(c, schedule)
+}
+
+// DeletePrinterPowerSchedule 删除打印机自身的节能计划（之后沿用 Edge Node 的计划）
+func (h *PowerScheduleHandler) DeletePrinterPowerSchedule(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	deleted, err := h.scheduleRepo.DeletePrinterSchedule(printer.ID)
+	if err != nil {
+		log.Printf("Failed to delete power schedule for printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "删除节能计划失败")
+		return
+	}
+	if !deleted {
+		NotFoundResponse(c, "未设置节能计划")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "power_schedule.delete", "printer", printer.ID, "")
+
+	SuccessResponse(c, gin.H{"printer_id": printer.ID})
+}
+
+// GetEdgeNodePowerSchedule 获取 Edge Node 的节能计划
+func (h *PowerScheduleHandler) GetEdgeNodePowerSchedule(c *gin.Context) {
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	schedule, err := h.scheduleRepo.GetEdgeNodeSchedule(node.ID)
+	if err != nil {
+		log.Printf("Failed to get power schedule for edge node %s: %v", node.ID, err)
+		InternalErrorResponse(c, "获取节能计划失败")
+		return
+	}
+	if schedule == nil {
+		NotFoundResponse(c, "未设置节能计划")
+		return
+	}
+
+	SuccessResponse(c, schedule)
+}
+
+// SetEdgeNodePowerSchedule 设置 Edge Node 的节能计划，作用于其下所有没有单独计划的打印机
+func (h *PowerScheduleHandler) SetEdgeNodePowerSchedule(c *gin.Context) {
+	var req PowerScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	schedule, err := req.toSchedule()
+	if err != nil {
+		BadRequestResponse(c, "节能计划无效: "+err.Error())
+		return
+	}
+
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	schedule.EdgeNodeID = &node.ID
+	if err := h.scheduleRepo.UpsertSchedule(schedule); err != nil {
+		log.Printf("Failed to set power schedule for edge node %s: %v", node.ID, err)
+		InternalErrorResponse(c, "设置节能计划失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "power_schedule.update", "edge_node", node.ID, describeSchedule(schedule))
+
+	SuccessResponse(c, schedule)
+}
+
+// DeleteEdgeNodePowerSchedule 删除 Edge Node 的节能计划
+func (h *PowerScheduleHandler) DeleteEdgeNodePowerSchedule(c *gin.Context) {
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "Edge Node 不存在")
+		return
+	}
+
+	deleted, err := h.scheduleRepo.DeleteEdgeNodeSchedule(node.ID)
+	if err != nil {
+		log.Printf("Failed to delete power schedule for edge node %s: %v", node.ID, err)
+		InternalErrorResponse(c, "删除节能计划失败")
+		return
+	}
+	if !deleted {
+		NotFoundResponse(c, "未设置节能计划")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "power_schedule.delete", "edge_node", node.ID, "")
+
+	SuccessResponse(c, gin.H{"edge_node_id": node.ID})
+}
+
+// describeSchedule 审计日志中的计划描述
+func describeSchedule(schedule *models.PrinterPowerSchedule) string {
+	return fmt.Sprintf("days=%v, sleep=%s-%s, timezone=%s, enabled=%v",
+		schedule.Days, schedule.StartTime, schedule.EndTime, schedule.Timezone, schedule.Enabled)
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+)
+
+// maxBatchJobs 单个批次最多包含的任务数
+const maxBatchJobs = 50
+
+// BatchCreatePrintJobRequest 批量创建打印任务请求
+// 可以直接提供 jobs 列表，也可以提供 template 加 file_urls（每个 URL 生成一个任务）
+type BatchCreatePrintJobRequest struct {
+	Jobs     []CreatePrintJobRequest `json:"jobs"`
+	Template *CreatePrintJobRequest  `json:"template"`
+	FileURLs []string                `json:"file_urls"`
+}
+
+// BatchItemResult 批量创建中单个任务的结果
+type BatchItemResult struct {
+	Index int              `json:"index"`
+	JobID string           `json:"job_id,omitempty"`
+	Error string           `json:"error,omitempty"`
+	Job   *models.PrintJob `json:"job,omitempty"`
+}
+
+// CreatePrintJobBatch 批量创建打印任务
+// 全部任务校验通过后在同一事务中创建并逐个分发；任一任务校验失败时不创建任何任务
+func (h *PrintJobHandler) CreatePrintJobBatch(c *gin.Context) {
+	var req BatchCreatePrintJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效"})
+		return
+	}
+
+	specs := req.Jobs
+	if req.Template != nil {
+		for _, fileURL := range req.FileURLs {
+			spec := *req.Template
+			spec.FileURL = fileURL
+			spec.FilePath = ""
+			spec.StorageKey = ""
+			spec.Files = nil
+			specs = append(specs, spec)
+		}
+	}
+	if len(specs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "批次中至少需要一个任务"})
+		return
+	}
+	if len(specs) > maxBatchJobs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("单个批次最多%d个任务", maxBatchJobs)})
+		return
+	}
+
+	batchID := uuid.New().String()
+	results := make([]BatchItemResult, len(specs))
+	jobs := make([]*models.PrintJob, len(specs))
+	printers := make([]*models.Printer, len(specs))
+	failed := false
+	for i := range specs {
+		results[i].Index = i
+		if err := binding.Validator.ValidateStruct(&specs[i]); err != nil {
+			results[i].Error = "请求参数无效"
+			failed = true
+			continue
+		}
+		job, printer, buildErr := h.buildPrintJob(c, requestJobSource(c), &specs[i], nil)
+		if buildErr != nil {
+			results[i].Error = fmt.Sprint(buildErr.body["error"])
+			failed = true
+			continue
+		}
+		job.BatchID = batchID
+		jobs[i] = job
+		printers[i] = printer
+	}
+
+	if failed {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "部分任务校验失败，批次未创建",
+			"results": results,
+		})
+		return
+	}
+
+	if err := h.printJobRepo.CreatePrintJobs(jobs); err != nil {
+		log.Printf("Failed to create print job batch: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建批量打印任务失败"})
+		return
+	}
+
+	for i, job := range jobs {
+		h.publishPrintJob(c, job, printers[i], jobSubmission{
+			details:      map[string]interface{}{"batch_id": batchID},
+			auditDetails: fmt.Sprintf("batch=%s", batchID),
+		})
+		results[i].JobID = job.ID
+		results[i].Job = job
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"batch_id": batchID,
+		"results":  results,
+	})
+}
+
+// GetPrintJobBatch 获取批次进度（按状态统计任务数）
+func (h *PrintJobHandler) GetPrintJobBatch(c *gin.Context) {
+	batchID := c.Param("id")
+	jobs, ok := h.loadBatch(c, batchID)
+	if !ok {
+		return
+	}
+
+	counts := make(map[models.JobStatus]int)
+	finished := 0
+	for _, job := range jobs {
+		counts[job.Status]++
+		if job.Status.IsTerminal() {
+			finished++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"batch_id":      batchID,
+		"total":         len(jobs),
+		"finished":      finished,
+		"status_counts": counts,
+		"jobs":          jobs,
+	})
+}
+
+// CancelPrintJobBatch 取消批次中所有未结束的任务
+func (h *PrintJobHandler) CancelPrintJobBatch(c *gin.Context) {
+	batchID := c.Param("id")
+	jobs, ok := h.loadBatch(c, batchID)
+	if !ok {
+		return
+	}
+
+	var cancelled []string
+	var failed []string
+	for _, job := range jobs {
+		if job.Status.IsTerminal() {
+			continue
+		}
+		if err := h.cancelJob(c, job); err != nil {
+			log.Printf("Failed to cancel job %s in batch %s: %v", job.ID, batchID, err)
+			failed = append(failed, job.ID)
+			continue
+		}
+		cancelled = append(cancelled, job.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"batch_id":  batchID,
+		"cancelled": cancelled,
+		"failed":    failed,
+	})
+}
+
+// loadBatch 加载批次任务，批次不存在时已写入响应
+func (h *PrintJobHandler) loadBatch(c *gin.Context, batchID string) ([]*models.PrintJob, bool) {
+	if _, err := uuid.Parse(batchID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "批次不存在"})
+		return nil, false
+	}
+
+	jobs, err := h.printJobRepo.ListPrintJobsByBatch(batchID)
+	if err != nil {
+		log.Printf("Failed to load batch %s: %v", batchID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取批次失败"})
+		return nil, false
+	}
+	if len(jobs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "批次不存在"})
+		return nil, false
+	}
+	return jobs, true
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// applyBusinessHours 打印机不在营业时间时按 whenClosed（为空时取 jobs.when_closed）处理：
+// reject 返回 409 和下次营业时间；schedule 将任务置为排队并记录计划时间，开始营业后再分发
+func (h *PrintJobHandler) applyBusinessHours(job *models.PrintJob, printer *models.Printer, whenClosed string) *jobBuildError {
+	hours, err := h.hoursRepo.GetEffectiveBusinessHours(printer.ID, printer.EdgeNodeID)
+	if err != nil {
+		log.Printf("Failed to get business hours for printer %s: %v", printer.ID, err)
+		return newJobBuildError(http.StatusInternalServerError, "获取营业时间失败")
+	}
+	now := time.Now()
+	if hours.OpenAt(now) {
+		return nil
+	}
+
+	nextOpen := hours.NextOpen(now).UTC()
+	if whenClosed == "" {
+		whenClosed = h.settings.Get().Jobs.WhenClosed
+	}
+	if whenClosed == models.WhenClosedSchedule {
+		job.Status = models.JobStatusQueued
+		job.NotBefore = &nextOpen
+		return nil
+	}
+	return &jobBuildError{status: http.StatusConflict, body: gin.H{
+		"error":        "打印机当前不在营业时间",
+		"error_code":   models.JobErrorPrinterClosed,
+		"next_open_at": nextOpen,
+	}}
+}
@@ -1,54 +1,129 @@
 package handlers
 
 import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"fly-print-cloud/api/internal/billing"
+	"fly-print-cloud/api/internal/config"
 	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/dispatch"
+	"fly-print-cloud/api/internal/docformat"
+	"fly-print-cloud/api/internal/events"
 	"fly-print-cloud/api/internal/models"
-	"fly-print-cloud/api/internal/websocket"
+	"fly-print-cloud/api/internal/notify"
+	"fly-print-cloud/api/internal/papersize"
+	"fly-print-cloud/api/internal/printing"
+	"fly-print-cloud/api/internal/routing"
+	"fly-print-cloud/api/internal/storage"
 )
 
 type PrintJobHandler struct {
 	printJobRepo *database.PrintJobRepository
 	printerRepo  *database.PrinterRepository
-	wsManager    *websocket.ConnectionManager
+	dispatcher   *dispatch.Dispatcher
+	calculator   *billing.Calculator
+	auditRepo    *database.AuditLogRepository
+	notifier     *notify.Notifier
+	accessPolicyRepo *database.AccessPolicyRepository
+	fileStorage  storage.Storage
+	settings     *config.Store // 上传限制和份数配置支持热更新，每次使用时读取
+	eventBus     *events.Bus
+	etags        *etagWatcher
+	jobEventRepo *database.PrintJobEventRepository
+	groupRepo    *database.PrinterGroupRepository
+	routers      *routing.Routers
+	validator    *printing.Validator
+	policyRepo   *database.PrintPolicyRepository
+	hoursRepo    *database.BusinessHoursRepository
+	userRepo     *database.UserRepository
 }
 
-func NewPrintJobHandler(printJobRepo *database.PrintJobRepository, printerRepo *database.PrinterRepository, wsManager *websocket.ConnectionManager) *PrintJobHandler {
+func NewPrintJobHandler(printJobRepo *database.PrintJobRepository, printerRepo *database.PrinterRepository, dispatcher *dispatch.Dispatcher, calculator *billing.Calculator, auditRepo *database.AuditLogRepository, notifier *notify.Notifier, accessPolicyRepo *database.AccessPolicyRepository, fileStorage storage.Storage, settings *config.Store, eventBus *events.Bus, jobEventRepo *database.PrintJobEventRepository, groupRepo *database.PrinterGroupRepository, routers *routing.Routers, policyRepo *database.PrintPolicyRepository, hoursRepo *database.BusinessHoursRepository, userRepo *database.UserRepository) *PrintJobHandler {
 	return &PrintJobHandler{
 		printJobRepo: printJobRepo,
 		printerRepo:  printerRepo,
-		wsManager:    wsManager,
+		dispatcher:   dispatcher,
+		calculator:   calculator,
+		auditRepo:    auditRepo,
+		notifier:     notifier,
+		accessPolicyRepo: accessPolicyRepo,
+		fileStorage:  fileStorage,
+		settings:     settings,
+		eventBus:     eventBus,
+		etags:        newETagWatcher(eventBus),
+		jobEventRepo: jobEventRepo,
+		groupRepo:    groupRepo,
+		routers:      routers,
+		validator:    printing.NewValidator(settings),
+		policyRepo:   policyRepo,
+		hoursRepo:    hoursRepo,
+		userRepo:     userRepo,
 	}
 }
 
 // CreatePrintJobRequest 创建打印任务请求
 type CreatePrintJobRequest struct {
-	Name         string `json:"name"`                         // 可选，不提供时自动生成
-	PrinterID    string `json:"printer_id" binding:"required"`
+	Name         string `json:"name" binding:"omitempty,max=200"` // 可选，不提供时自动生成
+	DisplayName  string `json:"display_name" binding:"omitempty,max=200"` // 可选，面向用户的名称；不提供时依次取 name、PDF 标题、整理后的文件名
+	SourceName   string `json:"-"`                            // 内部调用方（如共享文件夹）提供的原始文件名，不提供时从 URL 或路径提取
+	PrinterID    string `json:"printer_id"`                   // 与 printer_group_id 二选一
+	PrinterGroupID string `json:"printer_group_id"`           // 提交到打印机组，由组的选机策略选择打印机
+	Latitude     *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`   // 可选，提交位置（nearest 策略使用）
+	Longitude    *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"` // 可选
 	FilePath     string `json:"file_path"`                    // 本地文件路径
 	FileURL      string `json:"file_url"`                     // 文件URL
+	StorageKey   string `json:"storage_key"`                  // 已上传到云端的文件（上传接口返回）
 	FileSize     int64  `json:"file_size"`                    // 可选
 	PageCount    int    `json:"page_count"`                   // 可选
+	PageRange    string `json:"page_range" binding:"omitempty,max=100"` // 可选，只打印的页码（如 1-5,8,11-），仅单文件任务
 	Copies       int    `json:"copies" binding:"omitempty,min=1"` // 可选，默认1
 	PaperSize    string `json:"paper_size"`
 	ColorMode    string `json:"color_mode"`
 	DuplexMode   string `json:"duplex_mode"`
+	MediaType    string `json:"media_type" binding:"omitempty,max=50"`  // 可选，需在打印机支持的介质类型中
+	Resolution   string `json:"resolution" binding:"omitempty,max=20"`  // 可选，如 600 或 600x600（dpi）
 	MaxRetries   int    `json:"max_retries"`                  // 可选，默认3
+	Priority     int    `json:"priority" binding:"omitempty,min=0,max=100"` // 可选，排队时优先级越高越先分发
+	OnBehalfOf   string `json:"on_behalf_of" binding:"omitempty,max=100"` // 可选，管理员代用户提交时的用户名
+	Files        []PrintJobFileRequest `json:"files" binding:"omitempty,dive"` // 可选，多文件任务按顺序打印；与单文件字段二选一
+	Metadata     map[string]string `json:"metadata"` // 可选，调用方自定义标签，原样下发给 Edge Node
+	WhenClosed   string `json:"when_closed" binding:"omitempty,oneof=reject schedule"` // 可选，打印机不在营业时间时拒绝或排到下次营业时间，默认 jobs.when_closed
+}
+
+// PrintJobFileRequest 多文件任务中的单个文件
+type PrintJobFileRequest struct {
+	FilePath   string `json:"file_path"`
+	FileURL    string `json:"file_url"`
+	StorageKey string `json:"storage_key"`
+	FileSize   int64  `json:"file_size"`  // 可选
+	PageCount  int    `json:"page_count"` // 可选，云端存储的 PDF 由服务端计算
+	Copies     int    `json:"copies" binding:"omitempty,min=1"` // 可选，该文件的份数，默认1
 }
 
 // UpdatePrintJobRequest 更新打印任务请求
 type UpdatePrintJobRequest struct {
-	Name         *string `json:"name,omitempty"`
-	Status       *string `json:"status,omitempty"`
+	Name         *string `json:"name,omitempty" binding:"omitempty,max=200"`
+	DisplayName  *string `json:"display_name,omitempty" binding:"omitempty,max=200"`
+	Status       *models.JobStatus `json:"status,omitempty" binding:"omitempty,job_status"`
 	FilePath     *string `json:"file_path,omitempty"`
+	FileURL      *string `json:"file_url,omitempty" binding:"omitempty,max=1000"`
 	FileSize     *int64  `json:"file_size,omitempty"`
 	PageCount    *int    `json:"page_count,omitempty"`
 	Copies       *int    `json:"copies,omitempty"`
@@ -58,6 +133,10 @@ type UpdatePrintJobRequest struct {
 	ErrorMessage *string `json:"error_message,omitempty"`
 	RetryCount   *int    `json:"retry_count,omitempty"`
 	MaxRetries   *int    `json:"max_retries,omitempty"`
+
+	// 创建后不可修改，提供时返回 400
+	UserID    *string `json:"user_id,omitempty"`
+	PrinterID *string `json:"printer_id,omitempty"`
 }
 
 // CreatePrintJob 创建打印任务
@@ -68,120 +147,444 @@ func (h *PrintJobHandler) CreatePrintJob(c *gin.Context) {
 		return
 	}
 
-	// 验证文件路径或URL至少有一个
-	if req.FilePath == "" && req.FileURL == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "必须提供file_path或file_url"})
+	// 携带 Idempotency-Key 的重试请求返回首次创建的任务，不重复创建
+	idempotencyKey := strings.TrimSpace(c.GetHeader(idempotencyKeyHeader))
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s 不能超过 %d 个字符", idempotencyKeyHeader, maxIdempotencyKeyLength)})
+		return
+	}
+	if idempotencyKey != "" && h.replayIdempotentJob(c, c.GetString("username"), idempotencyKey, "") {
 		return
 	}
 
-	// 从OAuth2认证中获取用户信息
-	userID, exists := c.Get("external_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+	job, printer, buildErr := h.buildPrintJob(c, requestJobSource(c), &req, nil)
+	if buildErr != nil {
+		c.JSON(buildErr.status, buildErr.body)
 		return
 	}
+	job.IdempotencyKey = idempotencyKey
+
+	if err := h.submitPrintJob(c, job, printer, jobSubmission{}); err != nil {
+		// 并发的相同请求已先创建了任务
+		var duplicate *database.DuplicateIdempotencyKeyError
+		if errors.As(err, &duplicate) && h.replayIdempotentJob(c, "", idempotencyKey, duplicate.JobID) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建打印任务失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, job)
+}
+
+// replayIdempotentJob 幂等键已使用时返回首次创建的任务（200，Idempotent-Replayed: true），已返回响应时返回 true
+// jobID 为空时按 scope 和 key 查找
+func (h *PrintJobHandler) replayIdempotentJob(c *gin.Context, scope, key, jobID string) bool {
+	if jobID == "" {
+		var err error
+		if jobID, err = h.printJobRepo.GetIdempotentJobID(scope, key); err != nil {
+			log.Printf("Failed to look up idempotency key: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建打印任务失败"})
+			return true
+		}
+		if jobID == "" {
+			return false
+		}
+	}
+
+	job, err := h.printJobRepo.GetPrintJobByID(jobID)
+	if err != nil || job == nil {
+		log.Printf("Failed to load idempotent print job %s: %v", jobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建打印任务失败"})
+		return true
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.JSON(http.StatusOK, job)
+	return true
+}
+
+// jobSubmission 提交方式相关的审计和事件信息
+type jobSubmission struct {
+	details      map[string]interface{} // 创建事件的附加信息，可为 nil
+	auditAction  string                 // 为空时只为代为提交的任务记录 print_job.create_on_behalf
+	auditDetails string                 // auditAction 为空时追加在代为提交审计的详情之后
+}
+
+// submitPrintJob 提交已通过校验的任务（校验和获取打印机必须在此之前完成，失败时不会留下任务记录）：
+// 在事务中保存任务，提交后记录审计和创建事件并分发（打印机并发已满时在云端排队）
+// 只有保存失败时返回错误；分发失败记录在任务上（dispatch_error 和时间线），任务仍视为已创建
+func (h *PrintJobHandler) submitPrintJob(c *gin.Context, job *models.PrintJob, printer *models.Printer, submission jobSubmission) error {
+	if err := h.printJobRepo.CreatePrintJob(job); err != nil {
+		log.Printf("Failed to create print job: %v", err)
+		return err
+	}
+
+	h.publishPrintJob(c, job, printer, submission)
+	return nil
+}
+
+// publishPrintJob 任务入库后记录审计和创建事件，再分发任务
+func (h *PrintJobHandler) publishPrintJob(c *gin.Context, job *models.PrintJob, printer *models.Printer, submission jobSubmission) {
+	if submission.auditAction != "" {
+		recordAudit(c, h.auditRepo, submission.auditAction, "print_job", job.ID, submission.auditDetails)
+	} else if job.PerformedBy != "" {
+		details := fmt.Sprintf("submitter=%s", job.UserName)
+		if submission.auditDetails != "" {
+			details += " " + submission.auditDetails
+		}
+		recordAudit(c, h.auditRepo, "print_job.create_on_behalf", "print_job", job.ID, details)
+	}
+	details := policyEventDetails(job, routingEventDetails(job, submission.details))
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["source"] = job.Source
+	h.recordJobEvent(c, job, models.JobEventCreated, "", details)
+
+	if err := h.dispatcher.SubmitCreated(job, printer); err != nil {
+		log.Printf("Print job %s created but dispatch failed: %v", job.ID, err)
+	}
+}
+
+// jobBuildError 构建打印任务失败时的响应
+type jobBuildError struct {
+	status int
+	body   gin.H
+}
+
+func newJobBuildError(status int, message string) *jobBuildError {
+	return &jobBuildError{status: status, body: gin.H{"error": message}}
+}
+
+// requestJobSource 按认证方式和路由判断任务的提交来源：API Key 为 integration，管理后台接口为 console，其余为 api
+func requestJobSource(c *gin.Context) models.JobSource {
+	switch {
+	case c.GetString("api_key_id") != "":
+		return models.JobSourceIntegration
+	case strings.HasPrefix(c.FullPath(), "/api/v1/admin/"):
+		return models.JobSourceConsole
+	}
+	return models.JobSourceAPI
+}
+
+// buildPrintJob 校验创建请求并构建打印任务（尚未入库），同时返回目标打印机
+// 创建、批量创建和预检共用这一流程；checks 不为 nil 时记录每项校验的结果
+// source 为提交入口（见 models.JobSource），由调用方明确指定
+func (h *PrintJobHandler) buildPrintJob(c *gin.Context, source models.JobSource, req *CreatePrintJobRequest, checks *jobCheckList) (*models.PrintJob, *models.Printer, *jobBuildError) {
+	// 单文件字段转换为只有一个元素的文件列表
+	files, buildErr := normalizeJobFiles(req)
+	if buildErr != nil {
+		return nil, nil, checks.record(jobCheckRequest, buildErr)
+	}
+	firstFile := files[0]
+
+	if err := models.ValidateJobMetadata(req.Metadata); err != nil {
+		return nil, nil, checks.record(jobCheckRequest, newJobBuildError(http.StatusBadRequest, err.Error()))
+	}
+
+	// 从OAuth2认证中获取用户信息
+	if _, exists := c.Get("external_id"); !exists {
+		return nil, nil, checks.record(jobCheckRequest, newJobBuildError(http.StatusUnauthorized, "未授权"))
+	}
 
 	userName, exists := c.Get("username")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
-		return
+		return nil, nil, checks.record(jobCheckRequest, newJobBuildError(http.StatusUnauthorized, "未授权"))
 	}
 
-	// 自动生成任务名称
+	// 代为提交：任务归属于指定用户，记录实际操作人
+	submitterName := userName.(string)
+	performedBy := ""
+	var submitterID string
+	if req.OnBehalfOf != "" {
+		user, buildErr := h.resolveOnBehalfOf(c, req.OnBehalfOf)
+		if buildErr != nil {
+			return nil, nil, checks.record(jobCheckRequest, buildErr)
+		}
+		submitterID = user.ID
+		submitterName = user.Username
+		performedBy = userName.(string)
+	} else {
+		var err error
+		if submitterID, err = h.callerUserID(c); err != nil {
+			log.Printf("Failed to resolve local user for print job: %v", err)
+			return nil, nil, checks.record(jobCheckRequest, newJobBuildError(http.StatusInternalServerError, "获取用户信息失败"))
+		}
+	}
+
+	// 原始文件名：从URL（百分号解码）或文件路径提取
+	sourceName := req.SourceName
+	if sourceName == "" {
+		if firstFile.FileURL != "" {
+			sourceName = filenameFromURL(firstFile.FileURL)
+		} else if firstFile.FilePath != "" {
+			sourceName = filepath.Base(firstFile.FilePath)
+		}
+	}
+
+	// 自动生成任务名称，按字符截断，避免超过数据库字段限制
 	jobName := req.Name
 	if jobName == "" {
-		if req.FileURL != "" {
-			// 从URL提取文件名
-			parts := strings.Split(req.FileURL, "/")
-			filename := parts[len(parts)-1]
-			// 去掉查询参数
-			if idx := strings.Index(filename, "?"); idx != -1 {
-				filename = filename[:idx]
-			}
-			// 限制长度，避免超过数据库字段限制
-			if filename != "" && len(filename) <= 150 {
-				jobName = filename
-			} else if filename != "" {
-				// 如果文件名太长，截取前150个字符
-				jobName = filename[:150]
-			} else {
-				jobName = fmt.Sprintf("打印任务_%s", time.Now().Format("20060102_150405"))
-			}
-		} else if req.FilePath != "" {
-			// 从文件路径提取文件名
-			jobName = filepath.Base(req.FilePath)
+		if sourceName != "" {
+			jobName = truncateRunes(sourceName, maxDerivedJobNameLength)
 		} else {
-			jobName = fmt.Sprintf("打印任务_%s", time.Now().Format("20060102_150405"))
+			jobName = defaultJobName()
 		}
 	}
 
 	job := &models.PrintJob{
 		Name:         jobName,
-		Status:       "pending",
+		SourceName:   truncateRunes(sourceName, maxSourceNameLength),
+		Source:       source,
+		Status:       models.JobStatusPending,
 		PrinterID:    req.PrinterID,
-		UserID:       userID.(string),
-		UserName:     userName.(string),
-		FilePath:     req.FilePath,
-		FileURL:      req.FileURL,
-		FileSize:     req.FileSize,
-		PageCount:    req.PageCount,
+		UserID:       submitterID,
+		UserName:     submitterName,
+		PerformedBy:  performedBy,
+		FilePath:     firstFile.FilePath, // 单文件字段保存第一个文件，兼容旧版 Edge Node
+		FileURL:      firstFile.FileURL,
+		StorageKey:   firstFile.StorageKey,
+		Files:        files,
 		Copies:       req.Copies,
-		PaperSize:    req.PaperSize,
+		PaperSize:    normalizePaperSize(req.PaperSize),
 		ColorMode:    req.ColorMode,
 		DuplexMode:   req.DuplexMode,
-		RetryCount:   0,  // 保留字段但不使用
+		MediaType:    strings.TrimSpace(req.MediaType),
+		Resolution:   strings.TrimSpace(req.Resolution),
+		RetryCount:   0,  // 硬件错误后云端重试的次数（故障转移）
 		MaxRetries:   req.MaxRetries,
+		Priority:     req.Priority,
+		Metadata:     req.Metadata,
+		Client:       requestClientInfo(c),
 	}
 
 	// 设置默认值
 	if job.Copies == 0 {
-		job.Copies = 1
+		job.Copies = h.defaultCopies()
 	}
 	if job.MaxRetries == 0 {
 		job.MaxRetries = 3
 	}
 
-	// 获取打印机信息进行能力校验
-	printer, err := h.printerRepo.GetPrinterByID(job.PrinterID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打印机信息失败"})
-		return
+	if (req.PrinterID == "") == (req.PrinterGroupID == "") {
+		return nil, nil, checks.record(jobCheckRequest, newJobBuildError(http.StatusBadRequest, "必须提供printer_id或printer_group_id之一"))
+	}
+	checks.record(jobCheckRequest, nil)
+
+	// 获取打印机信息进行能力校验（提交到打印机组时在文件校验之后选择打印机）
+	var printer *models.Printer
+	if req.PrinterGroupID == "" {
+		printer, buildErr = h.resolveJobPrinter(c, job.PrinterID, checks)
+		if buildErr != nil {
+			return nil, nil, buildErr
+		}
+		// printer_id 可以是 slug，统一保存为打印机ID
+		job.PrinterID = printer.ID
+	}
+
+	// 云端文件在服务端校验格式并计算页数，第一个文件为 PDF 时读取标题作为默认显示名称
+	serverCounted := 0
+	title := ""
+	for i := range job.Files {
+		file := &job.Files[i]
+		if file.StorageKey == "" {
+			continue
+		}
+		size, info, buildErr := h.inspectStoredFile(c, file.StorageKey)
+		if buildErr != nil {
+			return nil, nil, checks.record(jobCheckFiles, buildErr)
+		}
+		if i == 0 {
+			title = info.Title
+		}
+		file.FileSize = size
+		if info.Format == docformat.FormatPDF {
+			file.PageCount = info.Pages
+			serverCounted++
+		}
+		// 单文件任务记录文件格式，分发时判断是否需要转换
+		if len(job.Files) == 1 {
+			job.SourceFormat = string(info.Format)
+		}
+	}
+	aggregateJobFiles(job, serverCounted)
+	job.DisplayName = jobDisplayName(req, title, sourceName)
+	if buildErr := applyPageRange(job, req.PageRange); buildErr != nil {
+		return nil, nil, checks.record(jobCheckFiles, buildErr)
 	}
+	checks.record(jobCheckFiles, nil)
 
 	if printer == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "打印机不存在"})
-		return
+		printer, buildErr = h.routeGroupJob(c, job, req)
+		if checks.record(jobCheckRouting, buildErr) != nil {
+			return nil, nil, buildErr
+		}
+	}
+
+	// 应用组织级打印策略（强制调整在能力校验之前生效）
+	if buildErr := checks.record(jobCheckPolicy, h.applyPrintPolicies(c, job, printer, serverCounted)); buildErr != nil {
+		return nil, nil, buildErr
 	}
 
 	// 校验打印机能力
-	if err := h.validatePrintJobCapabilities(job, printer); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	if violations := h.validator.Validate(job, printer); len(violations) > 0 {
+		return nil, nil, checks.record(jobCheckCapabilities, capabilityViolationError(violations))
 	}
+	checks.record(jobCheckCapabilities, nil)
+	job.Status = dispatch.InitialStatus(printer, job.UserName)
+
+	// 营业时间（可能将任务排到下次营业时间）
+	if buildErr := checks.record(jobCheckBusinessHours, h.applyBusinessHours(job, printer, req.WhenClosed)); buildErr != nil {
+		return nil, nil, buildErr
+	}
+
+	return job, printer, nil
+}
 
-	err = h.printJobRepo.CreatePrintJob(job)
+// inspectStoredFile 读取云端存储的文件，校验格式是否允许并返回文件大小和检测结果（PDF 含实际页数）
+func (h *PrintJobHandler) inspectStoredFile(c *gin.Context, key string) (int64, docformat.Info, *jobBuildError) {
+	reader, err := h.fileStorage.Get(c.Request.Context(), key)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建打印任务失败"})
-		return
+		if errors.Is(err, storage.ErrNotFound) {
+			return 0, docformat.Info{}, newJobBuildError(http.StatusBadRequest, "storage_key对应的文件不存在")
+		}
+		log.Printf("Failed to read stored file %s: %v", key, err)
+		return 0, docformat.Info{}, newJobBuildError(http.StatusInternalServerError, "读取文件失败")
 	}
+	defer reader.Close()
 
-	// 打印机信息已在上面获取并校验过
+	storageCfg := h.settings.Get().Storage
+	data, err := io.ReadAll(io.LimitReader(reader, storageCfg.MaxUploadSize))
+	if err != nil {
+		log.Printf("Failed to read stored file %s: %v", key, err)
+		return 0, docformat.Info{}, newJobBuildError(http.StatusInternalServerError, "读取文件失败")
+	}
 
-	// 分发任务到Edge Node
-	err = h.wsManager.DispatchPrintJob(printer.EdgeNodeID, job, printer.Name)
+	info, err := docformat.Inspect(data, storageCfg.AllowedFormats)
 	if err != nil {
-		log.Printf("Failed to dispatch print job %s to node %s: %v", job.ID, printer.EdgeNodeID, err)
-		// 任务已创建，但分发失败，保持pending状态
-	} else {
-		log.Printf("Print job %s dispatched to node %s", job.ID, printer.EdgeNodeID)
-		// 更新任务状态为已分发
-		job.Status = "dispatched"
-		if updateErr := h.printJobRepo.UpdatePrintJob(job); updateErr != nil {
-			log.Printf("Failed to update job status to dispatched: %v", updateErr)
+		status, code, message := fileFormatError(err)
+		return 0, info, &jobBuildError{status: status, body: gin.H{"error": message, "code": code}}
+	}
+	if info.Format == docformat.FormatPDF {
+		info.Title = pdfTitle(key, data)
+	}
+	return int64(len(data)), info, nil
+}
+
+// jobDisplayName 任务的默认显示名称：请求中的 display_name、name，第一个文件的 PDF 标题，整理后的原始文件名，
+// 都没有时为“打印任务_时间”
+func jobDisplayName(req *CreatePrintJobRequest, title, sourceName string) string {
+	for _, name := range []string{req.DisplayName, req.Name, title, cleanDisplayName(sourceName)} {
+		if name = strings.TrimSpace(name); name != "" {
+			return truncateRunes(name, maxJobNameLength)
 		}
 	}
+	return defaultJobName()
+}
 
-	c.JSON(http.StatusCreated, job)
+// normalizeJobFiles 校验并整理任务的文件列表；未提供 files 时由单文件字段生成一个元素的列表
+func normalizeJobFiles(req *CreatePrintJobRequest) ([]models.PrintJobFile, *jobBuildError) {
+	specs := req.Files
+	multi := len(specs) > 0
+	if !multi {
+		// 验证文件路径、URL或云端文件至少有一个
+		if req.FilePath == "" && req.FileURL == "" && req.StorageKey == "" {
+			return nil, newJobBuildError(http.StatusBadRequest, "必须提供files、file_path、file_url或storage_key")
+		}
+		specs = []PrintJobFileRequest{{
+			FilePath:   req.FilePath,
+			FileURL:    req.FileURL,
+			StorageKey: req.StorageKey,
+			FileSize:   req.FileSize,
+			PageCount:  req.PageCount,
+		}}
+	} else if req.FilePath != "" || req.FileURL != "" || req.StorageKey != "" {
+		return nil, newJobBuildError(http.StatusBadRequest, "files不能与file_path、file_url、storage_key同时使用")
+	}
+	if len(specs) > maxFilesPerJob {
+		return nil, newJobBuildError(http.StatusBadRequest, fmt.Sprintf("单个任务最多%d个文件", maxFilesPerJob))
+	}
+
+	files := make([]models.PrintJobFile, 0, len(specs))
+	for i, spec := range specs {
+		prefix := ""
+		if multi {
+			prefix = fmt.Sprintf("files[%d].", i)
+		}
+		if spec.FilePath == "" && spec.FileURL == "" && spec.StorageKey == "" {
+			return nil, newJobBuildError(http.StatusBadRequest, fmt.Sprintf("%s必须提供file_path、file_url或storage_key", prefix))
+		}
+		if spec.StorageKey != "" && !storage.IsUploadKey(spec.StorageKey) {
+			return nil, newJobBuildError(http.StatusBadRequest, prefix+"storage_key无效")
+		}
+		// 超出数据库字段长度时明确拒绝，避免数据库报错返回500
+		if utf8.RuneCountInString(spec.FileURL) > maxFileURLLength {
+			return nil, newJobBuildError(http.StatusBadRequest, fmt.Sprintf("%sfile_url长度不能超过%d个字符", prefix, maxFileURLLength))
+		}
+		if utf8.RuneCountInString(spec.FilePath) > maxFilePathLength {
+			return nil, newJobBuildError(http.StatusBadRequest, fmt.Sprintf("%sfile_path长度不能超过%d个字符", prefix, maxFilePathLength))
+		}
+
+		copies := spec.Copies
+		if copies == 0 {
+			copies = 1
+		}
+		files = append(files, models.PrintJobFile{
+			Position:   i + 1,
+			FilePath:   spec.FilePath,
+			FileURL:    spec.FileURL,
+			StorageKey: spec.StorageKey,
+			FileSize:   spec.FileSize,
+			PageCount:  spec.PageCount,
+			Copies:     copies,
+		})
+	}
+	return files, nil
+}
+
+// aggregateJobFiles 汇总文件大小和页数（页数按各文件份数累计，用于配额与计费）
+// 所有文件的页数都由服务端解析时页数来源为 server
+func aggregateJobFiles(job *models.PrintJob, serverCounted int) {
+	var size int64
+	pages := 0
+	for _, file := range job.Files {
+		size += file.FileSize
+		pages += file.PageCount * file.Copies
+	}
+	job.FileSize = size
+	job.PageCount = pages
+	job.PageCountSource = models.PageCountSourceClient
+	if serverCounted > 0 && serverCounted == len(job.Files) {
+		job.PageCountSource = models.PageCountSourceServer
+	}
+}
+
+// applyPageRange 校验页码范围并按范围计算任务页数（仅单文件任务）
+// 文档页数已知时补全省略的结束页并将页数改为选中的页数；未知时只校验写法，页数保持不变
+func applyPageRange(job *models.PrintJob, pageRange string) *jobBuildError {
+	pageRange = strings.TrimSpace(pageRange)
+	if pageRange == "" {
+		job.PageRange = ""
+		return nil
+	}
+	if len(job.Files) > 1 {
+		return newJobBuildError(http.StatusBadRequest, "page_range只支持单文件任务")
+	}
+
+	// 旧任务没有文件列表，页数即文档页数
+	documentPages, fileCopies := job.PageCount, 1
+	if len(job.Files) == 1 {
+		documentPages, fileCopies = job.Files[0].PageCount, job.Files[0].Copies
+	}
+	resolved, selected, err := models.ResolvePageRange(pageRange, documentPages)
+	if err != nil {
+		return newJobBuildError(http.StatusBadRequest, "page_range无效: "+err.Error())
+	}
+	job.PageRange = resolved
+	if selected > 0 {
+		job.PageCount = selected * max(fileCopies, 1)
+	}
+	return nil
 }
 
 // GetPrintJob 获取打印任务详情
@@ -199,11 +602,64 @@ func (h *PrintJobHandler) GetPrintJob(c *gin.Context) {
 		return
 	}
 
+	// ETag 条件请求与长轮询：任务未变化时返回 304
+	etag := jobETag(job.UpdatedAt)
+	if etagMatches(c, etag) {
+		etag, err = h.etags.await(c.Request.Context(), longPollWait(c), "job:"+id, etag, func() (string, error) {
+			latest, err := h.printJobRepo.GetPrintJobByID(id)
+			if err != nil || latest == nil {
+				return "", fmt.Errorf("failed to reload print job %s: %v", id, err)
+			}
+			return jobETag(latest.UpdatedAt), nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打印任务失败"})
+			return
+		}
+		if etagMatches(c, etag) {
+			notModified(c, etag)
+			return
+		}
+		// 任务已变化，重新读取（ETag 计算结果在挂起的请求间共享）
+		if job, err = h.printJobRepo.GetPrintJobByID(id); err != nil || job == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打印任务失败"})
+			return
+		}
+		etag = jobETag(job.UpdatedAt)
+	}
+
+	files, err := h.printJobRepo.ListPrintJobFiles(job.ID)
+	if err != nil {
+		log.Printf("Failed to load files for job %s: %v", job.ID, err)
+	}
+	job.Files = files
+
+	recentEvents, err := h.jobEventRepo.ListRecentEvents(job.ID, recentJobEventsLimit)
+	if err != nil {
+		log.Printf("Failed to load events for job %s: %v", job.ID, err)
+	}
+	job.RecentEvents = recentEvents
+
+	c.Header("ETag", etag)
 	c.JSON(http.StatusOK, job)
 }
 
 // ListPrintJobs 获取打印任务列表
+// 响应携带 ETag，If-None-Match 命中时返回 304；同时传入 wait（秒）时挂起请求直到列表变化或超时（长轮询）。
+// 传入 cursor 参数（首页可为空值）时使用游标分页：结果严格按 created_at DESC, id DESC 排序，
+// 翻页期间新插入的任务不会导致重复或遗漏；pagination.next_cursor 为空表示没有更多数据。
+// 未传 cursor 时保持原有的 page/pageSize 或 limit/offset 分页方式。
 func (h *PrintJobHandler) ListPrintJobs(c *gin.Context) {
+	// ETag 条件请求与长轮询：列表未变化时返回 304
+	if h.respondListNotModified(c) {
+		return
+	}
+
+	if cursorStr, ok := c.GetQuery("cursor"); ok {
+		h.listPrintJobsByCursor(c, cursorStr)
+		return
+	}
+
 	// 支持两种分页参数格式
 	var limit, offset int
 	
@@ -245,10 +701,20 @@ func (h *PrintJobHandler) ListPrintJobs(c *gin.Context) {
 
 	// 过滤参数
 	status := c.Query("status")
-	printerID := c.Query("printer_id")
+	printerID := h.resolvePrinterID(c.Query("printer_id"))
 	userID := c.Query("user_id")
+	source, err := sourceFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	metadata, err := metadataFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	jobs, total, err := h.printJobRepo.ListPrintJobsWithTotal(limit, offset, status, printerID, userID)
+	jobs, total, err := h.printJobRepo.ListPrintJobsWithTotal(limit, offset, status, printerID, userID, source, metadata)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打印任务列表失败"})
 		return
@@ -272,6 +738,162 @@ func (h *PrintJobHandler) ListPrintJobs(c *gin.Context) {
 	})
 }
 
+// respondListNotModified 计算列表 ETag 并设置响应头；客户端缓存仍有效时返回 304 并返回 true
+func (h *PrintJobHandler) respondListNotModified(c *gin.Context) bool {
+	status := c.Query("status")
+	printerID := h.resolvePrinterID(c.Query("printer_id"))
+	userID := c.Query("user_id")
+	source, err := sourceFilter(c)
+	if err != nil {
+		// 交由列表接口返回参数错误
+		return false
+	}
+	metadata, err := metadataFilter(c)
+	if err != nil {
+		// 交由列表接口返回参数错误
+		return false
+	}
+
+	compute := func() (string, error) {
+		maxUpdatedAt, count, err := h.printJobRepo.GetPrintJobListVersion(status, printerID, userID, source, metadata)
+		if err != nil {
+			return "", err
+		}
+		return jobListETag(maxUpdatedAt, count, c), nil
+	}
+
+	etag, err := compute()
+	if err != nil {
+		log.Printf("Failed to compute print job list etag: %v", err)
+		return false
+	}
+	if etagMatches(c, etag) {
+		etag, err = h.etags.await(c.Request.Context(), longPollWait(c), "list:"+listQueryKey(c), etag, compute)
+		if err != nil {
+			log.Printf("Failed to compute print job list etag: %v", err)
+			return false
+		}
+		if etagMatches(c, etag) {
+			notModified(c, etag)
+			return true
+		}
+	}
+
+	c.Header("ETag", etag)
+	return false
+}
+
+// listPrintJobsByCursor 游标分页获取打印任务列表
+func (h *PrintJobHandler) listPrintJobsByCursor(c *gin.Context, cursorStr string) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100 // 限制最大页面大小
+	}
+
+	var cursor *database.JobCursor
+	if cursorStr != "" {
+		decoded, err := decodeJobCursor(cursorStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor参数无效"})
+			return
+		}
+		cursor = decoded
+	}
+
+	source, err := sourceFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	metadata, err := metadataFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 多取一条用于判断是否还有下一页
+	jobs, err := h.printJobRepo.ListPrintJobsAfter(limit+1, cursor, c.Query("status"), h.resolvePrinterID(c.Query("printer_id")), c.Query("user_id"), source, metadata)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打印任务列表失败"})
+		return
+	}
+
+	nextCursor := ""
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+		last := jobs[len(jobs)-1]
+		nextCursor = encodeJobCursor(last.CreatedAt, last.ID)
+	}
+	if jobs == nil {
+		jobs = []*models.PrintJob{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs": jobs,
+		"pagination": gin.H{
+			"limit":       limit,
+			"next_cursor": nextCursor,
+		},
+	})
+}
+
+// metadataFilter 解析 metadata.<key>=<value> 形式的查询参数，多个条件同时满足
+func metadataFilter(c *gin.Context) (map[string]string, error) {
+	var filter map[string]string
+	for param, values := range c.Request.URL.Query() {
+		key, ok := strings.CutPrefix(param, metadataQueryPrefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if !models.ValidJobMetadataKey(key) {
+			return nil, fmt.Errorf("metadata 过滤参数 %q 无效", param)
+		}
+		if filter == nil {
+			filter = make(map[string]string)
+		}
+		filter[key] = values[0]
+	}
+	return filter, nil
+}
+
+// sourceFilter 解析 source 查询参数（提交来源），未传时返回空字符串
+func sourceFilter(c *gin.Context) (string, error) {
+	source := c.Query("source")
+	if source != "" && !models.JobSource(source).IsValid() {
+		return "", fmt.Errorf("source 参数 %q 无效", source)
+	}
+	return source, nil
+}
+
+// encodeJobCursor 生成不透明的分页游标（created_at + id 的 base64 编码）
+func encodeJobCursor(createdAt time.Time, id string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeJobCursor 解析分页游标
+func decodeJobCursor(cursor string) (*database.JobCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	if _, err := uuid.Parse(parts[1]); err != nil {
+		return nil, err
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, err
+	}
+	return &database.JobCursor{CreatedAt: createdAt, ID: parts[1]}, nil
+}
+
 // UpdatePrintJob 更新打印任务
 func (h *PrintJobHandler) UpdatePrintJob(c *gin.Context) {
 	id := c.Param("id")
@@ -294,56 +916,81 @@ func (h *PrintJobHandler) UpdatePrintJob(c *gin.Context) {
 		return
 	}
 
-	// 更新字段
-	if req.Name != nil {
-		job.Name = *req.Name
+	if req.UserID != nil || req.PrinterID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id 和 printer_id 创建后不可修改"})
+		return
+	}
+
+	// 只写入请求中提供的字段，同时更新内存中的任务用于计算费用
+	update := &models.PrintJobUpdate{
+		Name:         req.Name,
+		DisplayName:  req.DisplayName,
+		Status:       req.Status,
+		FilePath:     req.FilePath,
+		FileURL:      req.FileURL,
+		FileSize:     req.FileSize,
+		Copies:       req.Copies,
+		ColorMode:    req.ColorMode,
+		DuplexMode:   req.DuplexMode,
+		ErrorMessage: req.ErrorMessage,
+		RetryCount:   req.RetryCount,
+		MaxRetries:   req.MaxRetries,
 	}
 	if req.Status != nil {
 		job.Status = *req.Status
 		// 状态变更时设置时间
-		if *req.Status == "printing" && job.StartTime.IsZero() {
-			job.StartTime = time.Now()
+		now := time.Now().UTC()
+		if *req.Status == models.JobStatusPrinting && job.StartTime == nil {
+			job.StartTime = &now
+			update.StartTime = &now
 		}
-		if (*req.Status == "completed" || *req.Status == "failed" || *req.Status == "cancelled") && job.EndTime.IsZero() {
-			job.EndTime = time.Now()
+		if req.Status.IsTerminal() && job.EndTime == nil {
+			job.EndTime = &now
+			update.EndTime = &now
 		}
 	}
-	if req.FilePath != nil {
-		job.FilePath = *req.FilePath
-	}
-	if req.FileSize != nil {
-		job.FileSize = *req.FileSize
-	}
 	if req.PageCount != nil {
 		job.PageCount = *req.PageCount
+		job.PageCountSource = models.PageCountSourceClient
+		update.PageCount = req.PageCount
+		update.PageCountSource = &job.PageCountSource
+	}
+	if req.PaperSize != nil {
+		job.PaperSize = normalizePaperSize(*req.PaperSize)
+		update.PaperSize = &job.PaperSize
 	}
 	if req.Copies != nil {
 		job.Copies = *req.Copies
 	}
-	if req.PaperSize != nil {
-		job.PaperSize = *req.PaperSize
-	}
 	if req.ColorMode != nil {
 		job.ColorMode = *req.ColorMode
 	}
 	if req.DuplexMode != nil {
 		job.DuplexMode = *req.DuplexMode
 	}
-	if req.ErrorMessage != nil {
-		job.ErrorMessage = *req.ErrorMessage
-	}
-	if req.RetryCount != nil {
-		job.RetryCount = *req.RetryCount
-	}
-	if req.MaxRetries != nil {
-		job.MaxRetries = *req.MaxRetries
+
+	// 任务完成（含部分完成）时计算费用
+	if req.Status != nil && req.Status.IsBillable() {
+		h.applyJobCost(job)
+		update.Cost = job.Cost
 	}
 
-	err = h.printJobRepo.UpdatePrintJob(job)
+	job, err = h.printJobRepo.PatchPrintJob(id, update)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新打印任务失败"})
 		return
 	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "打印任务不存在"})
+		return
+	}
+	h.recordJobEvent(c, job, models.JobEventUpdated, job.ErrorMessage, nil)
+
+	// 任务结束时通知提交用户，并释放打印机并发名额
+	if req.Status != nil && req.Status.IsTerminal() {
+		h.notifier.JobFinished(job.ID)
+		h.dispatcher.JobFinished(job.ID)
+	}
 
 	c.JSON(http.StatusOK, job)
 }
@@ -388,22 +1035,114 @@ func (h *PrintJobHandler) CancelPrintJob(c *gin.Context) {
 		return
 	}
 
-	// 只有pending和printing状态的任务可以取消
-	if job.Status != "pending" && job.Status != "printing" {
+	// 只有pending、queued和printing状态的任务可以取消
+	if job.Status != models.JobStatusPending && job.Status != models.JobStatusQueued && job.Status != models.JobStatusPrinting {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "任务状态不允许取消"})
 		return
 	}
 
-	job.Status = "cancelled"
-	if job.EndTime.IsZero() {
-		job.EndTime = time.Now()
+	if err := h.cancelJob(c, job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消打印任务失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// cancelJob 将任务标记为已取消、记录审计并释放打印机并发名额
+func (h *PrintJobHandler) cancelJob(c *gin.Context, job *models.PrintJob) error {
+	job.Status = models.JobStatusCancelled
+	if job.EndTime == nil {
+		now := time.Now().UTC()
+		job.EndTime = &now
+	}
+	// 记录实际操作人，提交用户保持不变
+	actor, _ := currentActor(c)
+	job.PerformedBy = actor
+
+	if err := h.printJobRepo.UpdatePrintJob(job); err != nil {
+		return err
 	}
 
-	err = h.printJobRepo.UpdatePrintJob(job)
+	recordAudit(c, h.auditRepo, "print_job.cancel", "print_job", job.ID,
+		fmt.Sprintf("submitter=%s", job.UserName))
+	h.recordJobEvent(c, job, models.JobEventCancelled, fmt.Sprintf("由 %s 取消", actor), nil)
+
+	// 释放打印机并发名额
+	h.dispatcher.JobFinished(job.ID)
+	return nil
+}
+
+// ForceFinishRequest 强制结束任务请求
+type ForceFinishRequest struct {
+	Reason string `json:"reason" binding:"required,min=1,max=500"` // 强制结束的原因，写入审计日志
+}
+
+// ForceCompletePrintJob 强制将任务标记为已完成（管理员，用于节点失联后卡住的任务）
+func (h *PrintJobHandler) ForceCompletePrintJob(c *gin.Context) {
+	h.forceFinishJob(c, models.JobStatusCompleted)
+}
+
+// ForceFailPrintJob 强制将任务标记为失败（管理员，用于节点失联后卡住的任务）
+func (h *PrintJobHandler) ForceFailPrintJob(c *gin.Context) {
+	h.forceFinishJob(c, models.JobStatusFailed)
+}
+
+// forceFinishJob 绕过状态机将未结束的任务置为终态，记录审计并释放打印机并发名额
+func (h *PrintJobHandler) forceFinishJob(c *gin.Context, status models.JobStatus) {
+	var req ForceFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "必须填写强制结束的原因"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "必须填写强制结束的原因"})
+		return
+	}
+
+	job, err := h.printJobRepo.GetPrintJobByID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消打印任务失败"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打印任务失败"})
 		return
 	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "打印任务不存在"})
+		return
+	}
+	if job.Status.IsTerminal() {
+		c.JSON(http.StatusConflict, gin.H{"error": "任务已结束，无需强制处理"})
+		return
+	}
+
+	previousStatus := job.Status
+	job.Status = status
+	if job.EndTime == nil {
+		now := time.Now().UTC()
+		job.EndTime = &now
+	}
+	if status == models.JobStatusFailed {
+		job.ErrorMessage = "强制失败: " + reason
+	} else {
+		h.applyJobCost(job)
+	}
+	actor, _ := currentActor(c)
+	job.PerformedBy = actor
+
+	if err := h.printJobRepo.UpdatePrintJob(job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新打印任务失败"})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "print_job.force_"+string(status), "print_job", job.ID,
+		fmt.Sprintf("previous_status=%s, reason=%s", previousStatus, reason))
+	h.recordJobEvent(c, job, models.JobEventForced, reason, map[string]interface{}{
+		"previous_status": previousStatus,
+	})
+
+	// 通知提交用户，并释放打印机并发名额
+	h.notifier.JobFinished(job.ID)
+	h.dispatcher.JobFinished(job.ID)
 
 	c.JSON(http.StatusOK, job)
 }
@@ -411,10 +1150,15 @@ func (h *PrintJobHandler) CancelPrintJob(c *gin.Context) {
 // ReprintRequest 重新打印请求
 type ReprintRequest struct {
 	PrinterID  string `json:"printer_id" binding:"required"`
-	Copies     int    `json:"copies" binding:"omitempty,min=1,max=99"`
+	Copies     int    `json:"copies" binding:"omitempty,min=1"` // 上限由 printing.Validator 统一校验
 	PaperSize  string `json:"paper_size"`
 	ColorMode  string `json:"color_mode"`
 	DuplexMode string `json:"duplex_mode"`
+	MediaType  string `json:"media_type" binding:"omitempty,max=50"`
+	Resolution string `json:"resolution" binding:"omitempty,max=20"`
+	PageRange  string `json:"page_range" binding:"omitempty,max=100"` // 可选，默认沿用原任务；原任务部分完成时默认从中断处的下一页续打
+	OnBehalfOf string `json:"on_behalf_of" binding:"omitempty,max=100"` // 可选，指定新任务归属的用户名
+	WhenClosed string `json:"when_closed" binding:"omitempty,oneof=reject schedule"` // 可选，打印机不在营业时间时的处理方式
 }
 
 // ReprintJob 重新打印任务（基于原任务创建新任务）
@@ -434,11 +1178,20 @@ func (h *PrintJobHandler) ReprintJob(c *gin.Context) {
 	}
 
 	// 只有已完成、失败、取消的任务可以重新打印
-	if originalJob.Status != "completed" && originalJob.Status != "failed" && originalJob.Status != "cancelled" {
+	if !originalJob.Status.IsTerminal() {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "任务状态不允许重新打印"})
 		return
 	}
 
+	// 文件内容已按保留期限删除，重新打印只会下发失效的下载链接
+	if originalJob.FilePurged {
+		c.JSON(http.StatusGone, gin.H{
+			"error":      "原任务文件已按保留期限删除，无法重新打印",
+			"error_code": models.JobErrorFilePurged,
+		})
+		return
+	}
+
 	// 解析重新打印参数
 	var req ReprintRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -446,120 +1199,516 @@ func (h *PrintJobHandler) ReprintJob(c *gin.Context) {
 		return
 	}
 
-	// 从OAuth2认证中获取用户信息
-	userID, exists := c.Get("external_id")
+	// 从OAuth2认证中获取操作人信息
+	actor, exists := c.Get("username")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
 		return
 	}
 
-	userName, exists := c.Get("username")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+	// 校验阶段：先获取并校验打印机（与创建任务相同的顺序和错误响应），全部通过后才保存任务
+	printer, buildErr := h.resolveJobPrinter(c, req.PrinterID, nil)
+	if buildErr != nil {
+		c.JSON(buildErr.status, buildErr.body)
 		return
 	}
 
+	// 默认保留原任务的提交用户，操作人单独记录
+	submitterID := originalJob.UserID
+	submitterName := originalJob.UserName
+	if req.OnBehalfOf != "" {
+		user, buildErr := h.resolveOnBehalfOf(c, req.OnBehalfOf)
+		if buildErr != nil {
+			c.JSON(buildErr.status, buildErr.body)
+			return
+		}
+		submitterID = user.ID
+		submitterName = user.Username
+	}
+
 	// 创建新任务（基于原任务和新参数）
 	newJob := &models.PrintJob{
-		Name:         fmt.Sprintf("重打-%s", originalJob.Name),
-		Status:       "pending",
-		PrinterID:    req.PrinterID,  // 使用请求中的打印机ID
-		UserID:       userID.(string),
-		UserName:     userName.(string),
+		Name:         truncateRunes(fmt.Sprintf("重打-%s", originalJob.Name), maxJobNameLength),
+		DisplayName:  truncateRunes(fmt.Sprintf("重打-%s", originalJob.DisplayName), maxJobNameLength),
+		SourceName:   originalJob.SourceName,
+		Source:       requestJobSource(c), // 重新打印的来源为发起重打的入口，而非原任务的来源
+		Status:       models.JobStatusPending,
+		PrinterID:    printer.ID,     // 使用请求中的打印机（printer_id 可以是 slug）
+		UserID:       submitterID,
+		UserName:     submitterName,
+		PerformedBy:  actor.(string),
 		FilePath:     originalJob.FilePath,  // 文件信息保持不变
 		FileURL:      originalJob.FileURL,
+		StorageKey:   originalJob.StorageKey,
 		FileSize:     originalJob.FileSize,
 		PageCount:    originalJob.PageCount,
+		PageCountSource: originalJob.PageCountSource,
+		SourceFormat: originalJob.SourceFormat, // 转换结果不复用，按新打印机重新判断
 		Copies:       req.Copies,     // 使用请求中的份数
-		PaperSize:    req.PaperSize,  // 使用请求中的纸张大小
+		PaperSize:    normalizePaperSize(req.PaperSize), // 使用请求中的纸张大小
 		ColorMode:    req.ColorMode,  // 使用请求中的颜色模式
 		DuplexMode:   req.DuplexMode, // 使用请求中的双面模式
+		MediaType:    strings.TrimSpace(req.MediaType),
+		Resolution:   strings.TrimSpace(req.Resolution),
 		RetryCount:   0,  // 新任务重置为0
 		MaxRetries:   3,  // 新任务使用默认值
+		Metadata:     originalJob.Metadata, // 标签随原任务保留
+		Client:       requestClientInfo(c), // 记录发起重打的客户端
 	}
 
 	// 设置默认值
 	if newJob.Copies == 0 {
-		newJob.Copies = 1
+		newJob.Copies = h.defaultCopies()
 	}
 
-	// 获取打印机信息进行能力校验
-	printer, err := h.printerRepo.GetPrinterByID(newJob.PrinterID)
+	// 复制原任务的文件列表（保持顺序和各文件份数）
+	files, err := h.printJobRepo.ListPrintJobFiles(originalJob.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打印机信息失败"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取原任务文件失败"})
 		return
 	}
+	for _, file := range files {
+		newJob.Files = append(newJob.Files, models.PrintJobFile{
+			FilePath:   file.FilePath,
+			FileURL:    file.FileURL,
+			StorageKey: file.StorageKey,
+			FileSize:   file.FileSize,
+			PageCount:  file.PageCount,
+			Copies:     file.Copies,
+		})
+	}
 
-	if printer == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "打印机不存在"})
+	// 页码范围：未指定时沿用原任务的范围，原任务部分完成时从中断处的下一页续打
+	details := map[string]interface{}{"reprint_of": originalJob.ID}
+	pageRange := req.PageRange
+	if strings.TrimSpace(pageRange) == "" {
+		pageRange = originalJob.PageRange
+		if resume := models.ResumePageRange(originalJob); resume != "" && len(newJob.Files) <= 1 {
+			pageRange = resume
+			details["resumed_page_range"] = resume
+		}
+	}
+	if buildErr := applyPageRange(newJob, pageRange); buildErr != nil {
+		c.JSON(buildErr.status, buildErr.body)
 		return
 	}
 
 	// 校验打印机能力
-	if err := h.validatePrintJobCapabilities(newJob, printer); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if violations := h.validator.Validate(newJob, printer); len(violations) > 0 {
+		buildErr := capabilityViolationError(violations)
+		c.JSON(buildErr.status, buildErr.body)
+		return
+	}
+	newJob.Status = dispatch.InitialStatus(printer, newJob.UserName)
+	if buildErr := h.applyBusinessHours(newJob, printer, req.WhenClosed); buildErr != nil {
+		c.JSON(buildErr.status, buildErr.body)
 		return
 	}
 
-	err = h.printJobRepo.CreatePrintJob(newJob)
-	if err != nil {
+	// 保存并分发（与创建任务共用同一提交流程）
+	if err := h.submitPrintJob(c, newJob, printer, jobSubmission{
+		details:      details,
+		auditAction:  "print_job.reprint",
+		auditDetails: fmt.Sprintf("original_job=%s, submitter=%s", originalJob.ID, newJob.UserName),
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建重新打印任务失败"})
 		return
 	}
+	h.recordJobEvent(c, originalJob, models.JobEventRetried, "", map[string]interface{}{
+		"new_job_id": newJob.ID,
+	})
 
-	// 打印机信息已在上面获取并校验过
+	c.JSON(http.StatusCreated, newJob)
+}
 
-	// 分发任务到Edge Node
-	err = h.wsManager.DispatchPrintJob(printer.EdgeNodeID, newJob, printer.Name)
+// resolveOnBehalfOf 查找代为提交的目标用户：只有管理员和运维人员可以代提交，用户不存在或已停用时返回 404
+func (h *PrintJobHandler) resolveOnBehalfOf(c *gin.Context, username string) (*models.User, *jobBuildError) {
+	if !isPrivilegedCaller(c) {
+		return nil, newJobBuildError(http.StatusForbidden, "只有管理员可以代其他用户提交任务")
+	}
+	user, err := h.userRepo.GetActiveUserByUsername(username)
 	if err != nil {
-		log.Printf("Failed to dispatch print job %s to node %s: %v", newJob.ID, printer.EdgeNodeID, err)
-		// 任务已创建，但分发失败，保持pending状态
-	} else {
-		log.Printf("Print job %s dispatched to node %s", newJob.ID, printer.EdgeNodeID)
-		// 更新任务状态为已分发
-		newJob.Status = "dispatched"
-		if updateErr := h.printJobRepo.UpdatePrintJob(newJob); updateErr != nil {
-			log.Printf("Failed to update job status to dispatched: %v", updateErr)
+		log.Printf("Failed to get on_behalf_of user %s: %v", username, err)
+		return nil, newJobBuildError(http.StatusInternalServerError, "获取用户信息失败")
+	}
+	if user == nil {
+		return nil, newJobBuildError(http.StatusNotFound, fmt.Sprintf("用户 %s 不存在", username))
+	}
+	return user, nil
+}
+
+// callerUserID 当前调用方对应的本地用户ID；没有本地用户的调用方（如 client credentials）返回空字符串
+func (h *PrintJobHandler) callerUserID(c *gin.Context) (string, error) {
+	if userID := c.GetString("user_id"); userID != "" {
+		return userID, nil
+	}
+	externalID := c.GetString("external_id")
+	if externalID == "" {
+		return "", nil
+	}
+	user, err := h.userRepo.GetUserByExternalID(externalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
+// recentJobEventsLimit 任务详情中内联返回的时间线事件数
+const recentJobEventsLimit = 5
+
+// GetPrintJobTimeline 获取任务的完整时间线（按时间顺序）
+func (h *PrintJobHandler) GetPrintJobTimeline(c *gin.Context) {
+	job, err := h.printJobRepo.GetPrintJobByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打印任务失败"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "打印任务不存在"})
+		return
+	}
+
+	timeline, err := h.jobEventRepo.ListEvents(job.ID)
+	if err != nil {
+		log.Printf("Failed to list events for job %s: %v", job.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务时间线失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id": job.ID,
+		"status": job.Status,
+		"events": timeline,
+	})
+}
+
+// recordJobEvent 以当前调用方为操作人记录任务时间线事件（失败只记录日志）
+func (h *PrintJobHandler) recordJobEvent(c *gin.Context, job *models.PrintJob, eventType models.JobEventType, message string, details map[string]interface{}) {
+	actor, _ := currentActor(c)
+	h.jobEventRepo.Record(&models.PrintJobEvent{
+		JobID:   job.ID,
+		Type:    eventType,
+		Status:  job.Status,
+		Actor:   actor,
+		Message: message,
+		Details: details,
+	})
+}
+
+// resolvePrinterID 将 slug 形式的打印机标识解析为打印机ID，无法解析时原样返回
+func (h *PrintJobHandler) resolvePrinterID(printerID string) string {
+	if _, err := uuid.Parse(printerID); err == nil || printerID == "" {
+		return printerID
+	}
+	if printer, err := h.printerRepo.GetPrinterByID(printerID); err == nil {
+		return printer.ID
+	}
+	return printerID
+}
+
+// resolveJobPrinter 获取任务的目标打印机并校验审核状态、所属节点和访问策略
+// 创建和重新打印共用，保证校验顺序和错误响应一致；checks 不为 nil 时记录每项校验的结果
+func (h *PrintJobHandler) resolveJobPrinter(c *gin.Context, printerID string, checks *jobCheckList) (*models.Printer, *jobBuildError) {
+	printer, err := h.printerRepo.GetPrinterByIDCached(printerID)
+	if err != nil {
+		log.Printf("Failed to get printer %s for print job: %v", printerID, err)
+		return nil, checks.record(jobCheckPrinter, newJobBuildError(http.StatusInternalServerError, "获取打印机信息失败"))
+	}
+	if printer == nil {
+		return nil, checks.record(jobCheckPrinter, &jobBuildError{status: http.StatusBadRequest, body: gin.H{
+			"error":      "打印机不存在",
+			"error_code": "printer_not_found",
+		}})
+	}
+	if printer.ApprovalStatus != models.PrinterApprovalApproved {
+		return nil, checks.record(jobCheckPrinter, &jobBuildError{status: http.StatusBadRequest, body: gin.H{
+			"error":      "打印机尚未通过审核",
+			"error_code": "printer_not_approved",
+		}})
+	}
+	if !printer.Enabled {
+		return nil, checks.record(jobCheckPrinter, &jobBuildError{status: http.StatusConflict, body: gin.H{
+			"error":      "打印机已被禁用",
+			"error_code": "printer_disabled",
+		}})
+	}
+	checks.record(jobCheckPrinter, nil)
+	if buildErr := checks.record(jobCheckEdgeNode, h.checkEdgeNodeActive(printer)); buildErr != nil {
+		return nil, buildErr
+	}
+
+	// 校验打印机访问策略
+	if buildErr := checks.record(jobCheckAccessPolicy, h.checkPrinterAccess(c, printer.ID)); buildErr != nil {
+		return nil, buildErr
+	}
+	return printer, nil
+}
+
+// checkEdgeNodeActive 打印机所属的 Edge Node 已删除或禁用时返回 409，避免任务一直停留在待分发状态
+func (h *PrintJobHandler) checkEdgeNodeActive(printer *models.Printer) *jobBuildError {
+	active, err := h.printerRepo.IsEdgeNodeActiveCached(printer.EdgeNodeID)
+	if err != nil {
+		log.Printf("Failed to check edge node %s for printer %s: %v", printer.EdgeNodeID, printer.ID, err)
+		return newJobBuildError(http.StatusInternalServerError, "获取打印机信息失败")
+	}
+	if !active {
+		return &jobBuildError{status: http.StatusConflict, body: gin.H{
+			"error":      "打印机所属的 Edge Node 已删除、已禁用或正在排空",
+			"error_code": models.JobErrorEdgeNodeDeleted,
+		}}
+	}
+	return nil
+}
+
+// checkPrinterAccess 校验调用方是否有权使用打印机，无权时返回 403 错误
+func (h *PrintJobHandler) checkPrinterAccess(c *gin.Context, printerID string) *jobBuildError {
+	allowed, err := missingPrinterRoles(c, h.accessPolicyRepo, printerID)
+	if err != nil {
+		return newJobBuildError(http.StatusInternalServerError, "获取打印机访问策略失败")
+	}
+	if len(allowed) > 0 {
+		return &jobBuildError{status: http.StatusForbidden, body: gin.H{
+			"error":         fmt.Sprintf("无权使用该打印机，需要以下角色之一：%s", strings.Join(allowed, ", ")),
+			"allowed_roles": allowed,
+		}}
+	}
+	return nil
+}
+
+// capabilityViolationError 能力校验失败时的 400 响应，violations 列出全部不符合项供控制台逐项展示
+func capabilityViolationError(violations printing.Violations) *jobBuildError {
+	return &jobBuildError{status: http.StatusBadRequest, body: gin.H{
+		"error":      violations.Summary(),
+		"error_code": "capability_unsupported",
+		"violations": violations,
+	}}
+}
+
+// normalizePaperSize 将任务的纸张尺寸转换为规范ID，无法识别时保留原值
+func normalizePaperSize(size string) string {
+	id, known := papersize.Normalize(size)
+	if !known {
+		log.Printf("Unrecognized paper size %q in print job request", size)
+	}
+	return id
+}
+
+// defaultCopies 未指定份数时的默认值
+func (h *PrintJobHandler) defaultCopies() int {
+	if copies := h.settings.Get().Jobs.DefaultCopies; copies > 0 {
+		return copies
+	}
+	return 1
+}
+
+// applyJobCost 根据打印机定价计算任务费用（打印机不存在时使用全局定价）
+func (h *PrintJobHandler) applyJobCost(job *models.PrintJob) {
+	if h.calculator == nil {
+		return
+	}
+
+	printer, err := h.printerRepo.GetPrinterByIDCached(job.PrinterID)
+	if err != nil {
+		printer = nil
+	}
+
+	cost := h.calculator.Compute(job, printer)
+	job.Cost = &cost
+}
+
+// RecomputeCostRequest 重新计算费用请求
+type RecomputeCostRequest struct {
+	StartDate string `json:"start_date" binding:"required"` // YYYY-MM-DD
+	EndDate   string `json:"end_date" binding:"required"`   // YYYY-MM-DD（包含当天）
+}
+
+// RecomputeCosts 按当前定价重新计算日期范围内已完成任务的费用
+// 价格调整后不会自动重算历史任务，需由管理员显式调用
+func (h *PrintJobHandler) RecomputeCosts(c *gin.Context) {
+	var req RecomputeCostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	startDate, endDate, err := parseDateRange(req.StartDate, req.EndDate, time.Local)
+	if err != nil {
+		BadRequestResponse(c, err.Error())
+		return
+	}
+
+	jobs, err := h.printJobRepo.ListCompletedJobsByDate(startDate, endDate)
+	if err != nil {
+		log.Printf("Failed to list completed jobs for cost recompute: %v", err)
+		InternalErrorResponse(c, "获取已完成任务失败")
+		return
+	}
+
+	// 缓存打印机定价，避免重复查询
+	printers := make(map[string]*models.Printer)
+	updated := 0
+	for _, job := range jobs {
+		printer, ok := printers[job.PrinterID]
+		if !ok {
+			printer, err = h.printerRepo.GetPrinterByID(job.PrinterID)
+			if err != nil {
+				printer = nil
+			}
+			printers[job.PrinterID] = printer
 		}
+
+		cost := h.calculator.Compute(job, printer)
+		if err := h.printJobRepo.UpdateJobCost(job.ID, cost); err != nil {
+			log.Printf("Failed to update cost for job %s: %v", job.ID, err)
+			continue
+		}
+		updated++
 	}
 
-	c.JSON(http.StatusCreated, newJob)
+	log.Printf("Recomputed cost for %d jobs between %s and %s", updated, req.StartDate, req.EndDate)
+	SuccessResponse(c, gin.H{
+		"matched":  len(jobs),
+		"updated":  updated,
+		"currency": h.calculator.Currency(),
+	})
 }
 
-// validatePrintJobCapabilities 校验打印任务参数是否符合打印机能力
-func (h *PrintJobHandler) validatePrintJobCapabilities(job *models.PrintJob, printer *models.Printer) error {
-	// 校验颜色模式
-	if job.ColorMode == "color" && !printer.Capabilities.ColorSupport {
-		return fmt.Errorf("打印机 %s 不支持彩色打印", printer.Name)
+// ExportPrintJobs 导出日期范围内的打印任务（CSV）
+func (h *PrintJobHandler) ExportPrintJobs(c *gin.Context) {
+	now := time.Now()
+	startDate, endDate, err := parseDateRange(
+		c.DefaultQuery("start_date", now.AddDate(0, 0, -30).Format("2006-01-02")),
+		c.DefaultQuery("end_date", now.Format("2006-01-02")),
+		time.Local,
+	)
+	if err != nil {
+		BadRequestResponse(c, err.Error())
+		return
 	}
 
-	// 校验双面模式
-	if job.DuplexMode == "duplex" && !printer.Capabilities.DuplexSupport {
-		return fmt.Errorf("打印机 %s 不支持双面打印", printer.Name)
+	jobs, err := h.printJobRepo.ListJobsByDate(startDate, endDate)
+	if err != nil {
+		log.Printf("Failed to list jobs for export: %v", err)
+		InternalErrorResponse(c, "导出打印任务失败")
+		return
 	}
 
-	// 校验纸张大小
-	if job.PaperSize != "" && len(printer.Capabilities.PaperSizes) > 0 {
-		supportedSize := false
-		for _, size := range printer.Capabilities.PaperSizes {
-			if size == job.PaperSize {
-				supportedSize = true
-				break
+	filename := fmt.Sprintf("print_jobs_%s_%s.csv", startDate.Format("20060102"), endDate.AddDate(0, 0, -1).Format("20060102"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{
+		"id", "name", "status", "user_name", "printer_id", "page_count", "copies",
+		"color_mode", "duplex_mode", "cost", "currency", "created_at", "metadata", "printer_name",
+		"client_name", "client_version", "client_platform", "pages_printed", "billed_pages", "source",
+	})
+
+	var totalCost float64
+	for _, job := range jobs {
+		cost := ""
+		if job.Cost != nil {
+			cost = strconv.FormatFloat(*job.Cost, 'f', 4, 64)
+			totalCost += *job.Cost
+		}
+		metadata := ""
+		if len(job.Metadata) > 0 {
+			if encoded, err := json.Marshal(job.Metadata); err == nil {
+				metadata = string(encoded)
 			}
 		}
-		if !supportedSize {
-			return fmt.Errorf("打印机 %s 不支持纸张大小 %s，支持的大小：%v", 
-				printer.Name, job.PaperSize, printer.Capabilities.PaperSizes)
+		client := job.Client
+		if client == nil {
+			client = &models.ClientInfo{}
+		}
+		// 只有计费的任务（完成/部分完成）有计费页数
+		billedPages := ""
+		if job.Status.IsBillable() {
+			billedPages = strconv.Itoa(job.BilledPages())
 		}
+		writer.Write([]string{
+			job.ID, job.Name, string(job.Status), job.UserName, job.PrinterID,
+			strconv.Itoa(job.PageCount), strconv.Itoa(job.Copies),
+			job.ColorMode, job.DuplexMode, cost, h.calculator.Currency(),
+			job.CreatedAt.Format(time.RFC3339), metadata, job.PrinterName,
+			client.Name, client.Version, client.Platform,
+			strconv.Itoa(job.PagesPrinted), billedPages, string(job.Source),
+		})
 	}
 
-	// 校验份数（一般限制）
-	if job.Copies <= 0 {
-		return fmt.Errorf("打印份数必须大于0")
+	// 合计行
+	writer.Write([]string{
+		"total", "", "", "", "", "", "", "", "",
+		strconv.FormatFloat(totalCost, 'f', 4, 64), h.calculator.Currency(), "", "", "", "", "", "", "", "", "",
+	})
+	writer.Flush()
+}
+
+// 打印任务字段长度限制（与数据库字段定义一致，按字符计）
+const (
+	maxJobNameLength        = 200
+	maxDerivedJobNameLength = 150 // 由文件名自动生成任务名称时的长度上限
+	maxFileURLLength        = 1000
+	maxFilePathLength       = 500
+	maxFilesPerJob          = 20 // 单个任务最多包含的文件数
+)
+
+// idempotencyKeyHeader 创建打印任务的幂等键请求头，同一提交人在 database.IdempotencyKeyTTL 内重复使用时返回首次创建的任务
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+// metadataQueryPrefix 列表接口按任务标签过滤的查询参数前缀，如 ?metadata.cost_center=CC-42
+const metadataQueryPrefix = "metadata."
+
+// truncateRunes 按字符截断字符串，保证不会截断在多字节UTF-8字符中间
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
 	}
-	if job.Copies > 99 {
-		return fmt.Errorf("打印份数不能超过99份")
+	runes := []rune(s)
+	return string(runes[:max])
+}
+
+// filenameFromURL 从URL中提取文件名并进行百分号解码，无法提取时返回空字符串
+func filenameFromURL(rawURL string) string {
+	var name string
+	if u, err := url.Parse(rawURL); err == nil && u.Path != "" {
+		// u.Path 已经完成解码
+		name = path.Base(u.Path)
+	} else {
+		parts := strings.Split(rawURL, "/")
+		name = parts[len(parts)-1]
+		if idx := strings.IndexAny(name, "?#"); idx != -1 {
+			name = name[:idx]
+		}
+		if decoded, err := url.PathUnescape(name); err == nil {
+			name = decoded
+		}
+	}
+	if name == "." || name == "/" {
+		return ""
 	}
+	return name
+}
 
-	return nil
+// parseDateRange 按 loc 时区解析日期范围（YYYY-MM-DD），结束日期包含当天
+func parseDateRange(start, end string, loc *time.Location) (time.Time, time.Time, error) {
+	startDate, err := time.ParseInLocation("2006-01-02", start, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("start_date 格式无效，应为 YYYY-MM-DD")
+	}
+	endDate, err := time.ParseInLocation("2006-01-02", end, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("end_date 格式无效，应为 YYYY-MM-DD")
+	}
+	if endDate.Before(startDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end_date 不能早于 start_date")
+	}
+	return startDate, endDate.AddDate(0, 0, 1), nil
 }
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/policy"
+	"github.com/gin-gonic/gin"
+)
+
+// applyPrintPolicies 按组织级打印策略调整任务参数，违反策略时返回 403（含策略名称）
+// 在默认值合并和选机之后、能力校验之前执行，强制调整后的参数仍需通过能力校验
+func (h *PrintJobHandler) applyPrintPolicies(c *gin.Context, job *models.PrintJob, printer *models.Printer, serverCounted int) *jobBuildError {
+	if h.policyRepo == nil {
+		return nil
+	}
+	policies, err := h.policyRepo.ListPolicies(true)
+	if err != nil {
+		log.Printf("Failed to load print policies: %v", err)
+		return newJobBuildError(http.StatusInternalServerError, "获取打印策略失败")
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+
+	subject := &policy.Subject{
+		Time:            time.Now(),
+		UserName:        job.UserName,
+		Roles:           callerRoles(c),
+		DuplexSupported: printer.Capabilities.DuplexSupport,
+	}
+	if needsPrinterGroups(policies) {
+		if subject.PrinterGroupIDs, err = h.groupRepo.ListGroupIDsByPrinter(printer.ID); err != nil {
+			log.Printf("Failed to load printer groups of printer %s: %v", printer.ID, err)
+			return newJobBuildError(http.StatusInternalServerError, "获取打印策略失败")
+		}
+	}
+
+	result := policy.Evaluate(policies, job, subject)
+	if violation := result.Violation; violation != nil {
+		return &jobBuildError{status: http.StatusForbidden, body: gin.H{
+			"error":      violation.Message,
+			"error_code": "policy_violation",
+			"policy":     violation.Policy,
+			"field":      violation.Field,
+		}}
+	}
+
+	job.AppliedPolicies = result.Applied
+	job.PolicyAdjustments = result.Adjustments
+	for _, adjustment := range result.Adjustments {
+		// 文件份数调整后重新计算总页数
+		if strings.HasPrefix(adjustment.Field, "files[") {
+			aggregateJobFiles(job, serverCounted)
+			break
+		}
+	}
+	return nil
+}
+
+// needsPrinterGroups 判断是否有策略按打印机组限定范围
+func needsPrinterGroups(policies []*models.PrintPolicy) bool {
+	for _, p := range policies {
+		if len(p.Conditions.PrinterGroupIDs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// policyEventDetails 在创建事件中记录生效的策略和强制调整
+func policyEventDetails(job *models.PrintJob, details map[string]interface{}) map[string]interface{} {
+	if len(job.AppliedPolicies) == 0 {
+		return details
+	}
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["applied_policies"] = job.AppliedPolicies
+	if len(job.PolicyAdjustments) > 0 {
+		details["policy_adjustments"] = job.PolicyAdjustments
+	}
+	return details
+}
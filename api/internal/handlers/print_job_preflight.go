@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 创建打印任务时依次执行的校验项，预检接口按同样的名称返回每项结果
+const (
+	jobCheckRequest       = "request"        // 文件列表、元数据、提交人
+	jobCheckPrinter       = "printer"        // 打印机存在且已通过审核
+	jobCheckEdgeNode      = "edge_node"      // 打印机所属的 Edge Node 未删除、未禁用、未在排空
+	jobCheckAccessPolicy  = "access_policy"  // 调用方有权使用打印机
+	jobCheckFiles         = "files"          // 云端文件存在且格式允许
+	jobCheckRouting       = "routing"        // 打印机组内选出满足要求的打印机
+	jobCheckPolicy        = "policy"         // 组织级打印策略（强制调整或拒绝）
+	jobCheckCapabilities  = "capabilities"   // 任务参数符合打印机能力
+	jobCheckBusinessHours = "business_hours" // 打印机在营业时间内，或按 when_closed 排到下次营业时间
+)
+
+// 预检结果中单项校验的状态
+const (
+	jobCheckPassed  = "passed"
+	jobCheckFailed  = "failed"
+	jobCheckSkipped = "skipped" // 前面的校验失败，创建时不会执行到这一项
+)
+
+// plannedJobChecks 返回创建任务时会执行的校验项（打印机组任务的打印机、Edge Node 和访问策略在选机时逐台校验）
+func plannedJobChecks(req *CreatePrintJobRequest) []string {
+	if req.PrinterGroupID != "" {
+		return []string{jobCheckRequest, jobCheckFiles, jobCheckRouting, jobCheckPolicy, jobCheckCapabilities, jobCheckBusinessHours}
+	}
+	return []string{jobCheckRequest, jobCheckPrinter, jobCheckEdgeNode, jobCheckAccessPolicy, jobCheckFiles, jobCheckPolicy, jobCheckCapabilities, jobCheckBusinessHours}
+}
+
+// JobCheckResult 单项校验结果
+type JobCheckResult struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// jobCheckList 记录 buildPrintJob 执行过的校验；为 nil 时不记录（创建任务时）
+type jobCheckList struct {
+	results []JobCheckResult
+}
+
+// record 记录一项校验的结果并原样返回 buildErr，便于在返回语句中使用
+func (l *jobCheckList) record(check string, buildErr *jobBuildError) *jobBuildError {
+	if l == nil {
+		return buildErr
+	}
+	result := JobCheckResult{Check: check, Status: jobCheckPassed}
+	if buildErr != nil {
+		result.Status = jobCheckFailed
+		result.Code = jobBuildErrorCode(check, buildErr)
+		result.Message = fmt.Sprint(buildErr.body["error"])
+	}
+	l.results = append(l.results, result)
+	return buildErr
+}
+
+// complete 为未执行到的校验项补充 skipped 结果，按计划顺序返回
+func (l *jobCheckList) complete(planned []string) []JobCheckResult {
+	recorded := make(map[string]JobCheckResult, len(l.results))
+	for _, result := range l.results {
+		recorded[result.Check] = result
+	}
+	results := make([]JobCheckResult, 0, len(planned))
+	for _, check := range planned {
+		if result, ok := recorded[check]; ok {
+			results = append(results, result)
+			continue
+		}
+		results = append(results, JobCheckResult{Check: check, Status: jobCheckSkipped})
+	}
+	return results
+}
+
+// jobBuildErrorCode 优先使用错误响应中的错误码，否则按校验项和状态码生成
+func jobBuildErrorCode(check string, buildErr *jobBuildError) string {
+	for _, key := range []string{"error_code", "code"} {
+		if code, ok := buildErr.body[key].(string); ok && code != "" {
+			return code
+		}
+	}
+	switch buildErr.status {
+	case http.StatusInternalServerError:
+		return "internal_error"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return check + "_forbidden"
+	}
+	return check + "_failed"
+}
+
+// PreflightPrintJob 预检打印任务：执行与创建任务完全相同的校验，但不创建任务、不分发
+// 始终返回 200，ok 表示按当前请求创建能否成功；提交到打印机组时返回将被选中的打印机
+func (h *PrintJobHandler) PreflightPrintJob(c *gin.Context) {
+	var req CreatePrintJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效"})
+		return
+	}
+
+	checks := &jobCheckList{}
+	job, printer, buildErr := h.buildPrintJob(c, requestJobSource(c), &req, checks)
+
+	resp := gin.H{
+		"ok":     buildErr == nil,
+		"checks": checks.complete(plannedJobChecks(&req)),
+	}
+	if buildErr != nil {
+		// 与创建接口的失败响应一致，便于调用方直接展示
+		resp["failure"] = gin.H{"status": buildErr.status, "body": buildErr.body}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	resp["printer"] = gin.H{
+		"id":           printer.ID,
+		"name":         printer.Name,
+		"edge_node_id": printer.EdgeNodeID,
+	}
+	resp["initial_status"] = job.Status
+	resp["page_count"] = job.PageCount
+	if len(job.AppliedPolicies) > 0 {
+		resp["applied_policies"] = job.AppliedPolicies
+		resp["policy_adjustments"] = job.PolicyAdjustments
+	}
+	if job.PrinterGroupID != "" {
+		resp["printer_group_id"] = job.PrinterGroupID
+		resp["routing_strategy"] = job.RoutingStrategy
+	}
+	c.JSON(http.StatusOK, resp)
+}
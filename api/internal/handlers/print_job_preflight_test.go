@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestJobCheckListComplete 已执行的校验按计划顺序返回，失败项之后未执行的校验为 skipped
+func TestJobCheckListComplete(t *testing.T) {
+	checks := &jobCheckList{}
+	checks.record(jobCheckRequest, nil)
+	checks.record(jobCheckPrinter, nil)
+	buildErr := &jobBuildError{status: http.StatusConflict, body: gin.H{"error": "节点已删除", "error_code": "edge_node_deleted"}}
+	if got := checks.record(jobCheckEdgeNode, buildErr); got != buildErr {
+		t.Fatal("record did not return the build error")
+	}
+
+	got := checks.complete(plannedJobChecks(&CreatePrintJobRequest{PrinterID: "p1"}))
+	want := []JobCheckResult{
+		{Check: jobCheckRequest, Status: jobCheckPassed},
+		{Check: jobCheckPrinter, Status: jobCheckPassed},
+		{Check: jobCheckEdgeNode, Status: jobCheckFailed, Code: "edge_node_deleted", Message: "节点已删除"},
+		{Check: jobCheckAccessPolicy, Status: jobCheckSkipped},
+		{Check: jobCheckFiles, Status: jobCheckSkipped},
+		{Check: jobCheckPolicy, Status: jobCheckSkipped},
+		{Check: jobCheckCapabilities, Status: jobCheckSkipped},
+		{Check: jobCheckBusinessHours, Status: jobCheckSkipped},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("checks = %+v\nwant %+v", got, want)
+	}
+}
+
+// TestJobCheckListNil 创建任务时不记录校验，record 只透传错误
+func TestJobCheckListNil(t *testing.T) {
+	var checks *jobCheckList
+	buildErr := newJobBuildError(http.StatusBadRequest, "bad")
+	if checks.record(jobCheckRequest, buildErr) != buildErr || checks.record(jobCheckRequest, nil) != nil {
+		t.Fatal("nil check list changed the result")
+	}
+}
+
+// TestPlannedJobChecksForGroup 打印机组任务不单独校验打印机、Edge Node 和访问策略，改为 routing
+func TestPlannedJobChecksForGroup(t *testing.T) {
+	got := plannedJobChecks(&CreatePrintJobRequest{PrinterGroupID: "g1"})
+	want := []string{jobCheckRequest, jobCheckFiles, jobCheckRouting, jobCheckPolicy, jobCheckCapabilities, jobCheckBusinessHours}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("planned = %v, want %v", got, want)
+	}
+}
+
+func TestJobBuildErrorCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		check  string
+		status int
+		body   gin.H
+		want   string
+	}{
+		{"error_code wins", jobCheckPrinter, http.StatusConflict, gin.H{"error_code": "printer_disabled", "code": "other"}, "printer_disabled"},
+		{"code field", jobCheckPolicy, http.StatusForbidden, gin.H{"code": "policy_rejected"}, "policy_rejected"},
+		{"internal error", jobCheckFiles, http.StatusInternalServerError, gin.H{"error": "x"}, "internal_error"},
+		{"unauthorized", jobCheckRequest, http.StatusUnauthorized, gin.H{"error": "x"}, "unauthorized"},
+		{"forbidden", jobCheckAccessPolicy, http.StatusForbidden, gin.H{"error": "x"}, "access_policy_forbidden"},
+		{"other status", jobCheckRequest, http.StatusBadRequest, gin.H{"error": "x"}, "request_failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jobBuildErrorCode(tt.check, &jobBuildError{status: tt.status, body: tt.body}); got != tt.want {
+				t.Fatalf("code = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
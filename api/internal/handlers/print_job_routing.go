@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/routing"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// jobErrorNoEligiblePrinter 打印机组内没有通过校验的打印机
+const jobErrorNoEligiblePrinter = "no_eligible_printer"
+
+// routeGroupJob 按打印机组的选机策略为任务选择打印机，并在任务上记录组ID和所用策略
+// 只有通过审核、已启用、Edge Node 可用、调用方有权使用且满足任务能力要求的打印机参与选择
+func (h *PrintJobHandler) routeGroupJob(c *gin.Context, job *models.PrintJob, req *CreatePrintJobRequest) (*models.Printer, *jobBuildError) {
+	if _, err := uuid.Parse(req.PrinterGroupID); err != nil {
+		return nil, newJobBuildError(http.StatusBadRequest, "打印机组不存在")
+	}
+	group, err := h.groupRepo.GetGroup(req.PrinterGroupID)
+	if err != nil {
+		log.Printf("Failed to get printer group %s: %v", req.PrinterGroupID, err)
+		return nil, newJobBuildError(http.StatusInternalServerError, "获取打印机组失败")
+	}
+	if group == nil {
+		return nil, newJobBuildError(http.StatusBadRequest, "打印机组不存在")
+	}
+
+	router, err := h.routers.Get(group.RoutingStrategy)
+	if err != nil {
+		log.Printf("Printer group %s: %v", group.ID, err)
+		return nil, newJobBuildError(http.StatusInternalServerError, "打印机组的选机策略无效")
+	}
+
+	candidates, rejected, buildErr := h.groupCandidates(c, job, group)
+	if buildErr != nil {
+		return nil, buildErr
+	}
+	if len(candidates) == 0 {
+		return nil, &jobBuildError{status: http.StatusConflict, body: gin.H{
+			"error":      "打印机组内没有满足任务要求的可用打印机",
+			"error_code": jobErrorNoEligiblePrinter,
+			"rejected":   rejected,
+		}}
+	}
+
+	routeReq := routing.Request{
+		GroupID:   group.ID,
+		UserName:  job.UserName,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+	}
+	if router.Strategy() == models.RoutingStickyUser {
+		routeReq.LastPrinterID, err = h.printJobRepo.LastGroupPrinterForUser(group.ID, job.UserName)
+		if err != nil {
+			log.Printf("Failed to get last printer of %s in group %s: %v", job.UserName, group.ID, err)
+		}
+	}
+
+	printer, err := router.Select(routeReq, candidates)
+	if err != nil {
+		return nil, newJobBuildError(http.StatusConflict, "打印机组内没有满足任务要求的可用打印机")
+	}
+
+	job.PrinterID = printer.ID
+	job.PrinterGroupID = group.ID
+	job.RoutingStrategy = string(router.Strategy())
+	return printer, nil
+}
+
+// groupCandidates 筛选组内可参与选择的打印机，同时返回被排除的打印机及原因
+func (h *PrintJobHandler) groupCandidates(c *gin.Context, job *models.PrintJob, group *models.PrinterGroup) ([]routing.Candidate, []gin.H, *jobBuildError) {
+	var candidates []routing.Candidate
+	rejected := []gin.H{}
+	reject := func(printerID, reason string) {
+		rejected = append(rejected, gin.H{"printer_id": printerID, "reason": reason})
+	}
+
+	for _, printerID := range group.PrinterIDs {
+		printer, err := h.printerRepo.GetPrinterByIDCached(printerID)
+		if err != nil || printer == nil {
+			reject(printerID, "打印机不存在")
+			continue
+		}
+		if printer.ApprovalStatus != models.PrinterApprovalApproved {
+			reject(printer.ID, "打印机尚未通过审核")
+			continue
+		}
+		if !printer.Enabled {
+			reject(printer.ID, "打印机已禁用")
+			continue
+		}
+		if buildErr := h.checkEdgeNodeActive(printer); buildErr != nil {
+			if buildErr.status == http.StatusInternalServerError {
+				return nil, nil, buildErr
+			}
+			reject(printer.ID, "打印机所属的 Edge Node 已删除、已禁用或正在排空")
+			continue
+		}
+		if buildErr := h.checkPrinterAccess(c, printer.ID); buildErr != nil {
+			if buildErr.status == http.StatusInternalServerError {
+				return nil, nil, buildErr
+			}
+			reject(printer.ID, "无权使用该打印机")
+			continue
+		}
+		if printer.Reservation.Blocks(job.UserName, time.Now().UTC()) {
+			reject(printer.ID, "打印机已被其他用户预留")
+			continue
+		}
+		if violations := h.validator.Validate(job, printer); len(violations) > 0 {
+			rejected = append(rejected, gin.H{"printer_id": printer.ID, "reason": violations.Summary(), "violations": violations})
+			continue
+		}
+
+		active, queued, err := h.printJobRepo.CountPrinterJobLoad(printer.ID)
+		if err != nil {
+			log.Printf("Failed to count job load of printer %s: %v", printer.ID, err)
+			return nil, nil, newJobBuildError(http.StatusInternalServerError, "获取打印机负载失败")
+		}
+		candidates = append(candidates, routing.Candidate{Printer: printer, Load: active + queued})
+	}
+	return candidates, rejected, nil
+}
+
+// routingEventDetails 提交到打印机组的任务在创建事件中记录组ID和选机策略
+func routingEventDetails(job *models.PrintJob, details map[string]interface{}) map[string]interface{} {
+	if job.PrinterGroupID == "" {
+		return details
+	}
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["printer_group_id"] = job.PrinterGroupID
+	details["routing_strategy"] = job.RoutingStrategy
+	details["printer_id"] = job.PrinterID
+	return details
+}
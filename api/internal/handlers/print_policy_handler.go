@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PrintPolicyHandler 组织级打印策略处理器
+type PrintPolicyHandler struct {
+	policyRepo *database.PrintPolicyRepository
+	groupRepo  *database.PrinterGroupRepository
+	auditRepo  *database.AuditLogRepository
+}
+
+// NewPrintPolicyHandler 创建打印策略处理器
+func NewPrintPolicyHandler(policyRepo *database.PrintPolicyRepository, groupRepo *database.PrinterGroupRepository, auditRepo *database.AuditLogRepository) *PrintPolicyHandler {
+	return &PrintPolicyHandler{
+		policyRepo: policyRepo,
+		groupRepo:  groupRepo,
+		auditRepo:  auditRepo,
+	}
+}
+
+// PrintPolicyRequest 创建/更新打印策略请求
+type PrintPolicyRequest struct {
+	Name        string                       `json:"name" binding:"required,max=100"`
+	Description string                       `json:"description"`
+	Priority    int                          `json:"priority"`
+	Enabled     *bool                        `json:"enabled"` // 默认启用
+	Conditions  models.PrintPolicyConditions `json:"conditions"`
+	Effects     models.PrintPolicyEffects    `json:"effects"`
+}
+
+// toPolicy 校验请求并转换为打印策略，引用的打印机组必须存在
+func (h *PrintPolicyHandler) toPolicy(req *PrintPolicyRequest) (*models.PrintPolicy, string, error) {
+	policy := &models.PrintPolicy{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Priority:    req.Priority,
+		Enabled:     true,
+		Conditions:  req.Conditions,
+		Effects:     req.Effects,
+	}
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+	if policy.Name == "" {
+		return nil, "名称不能为空", nil
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, "打印策略无效: " + err.Error(), nil
+	}
+
+	for _, groupID := range policy.Conditions.PrinterGroupIDs {
+		if _, err := uuid.Parse(groupID); err != nil {
+			return nil, fmt.Sprintf("打印机组 %s 不存在", groupID), nil
+		}
+		group, err := h.groupRepo.GetGroup(groupID)
+		if err != nil {
+			return nil, "", err
+		}
+		if group == nil {
+			return nil, fmt.Sprintf("打印机组 %s 不存在", groupID), nil
+		}
+	}
+	return policy, "", nil
+}
+
+// loadPolicy 根据路径参数获取打印策略，不存在时写入 404 响应
+func (h *PrintPolicyHandler) loadPolicy(c *gin.Context) (*models.PrintPolicy, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		NotFoundResponse(c, "打印策略不存在")
+		return nil, false
+	}
+	policy, err := h.policyRepo.GetPolicy(id)
+	if err != nil {
+		log.Printf("Failed to get print policy %s: %v", id, err)
+		InternalErrorResponse(c, "获取打印策略失败")
+		return nil, false
+	}
+	if policy == nil {
+		NotFoundResponse(c, "打印策略不存在")
+		return nil, false
+	}
+	return policy, true
+}
+
+// ListPrintPolicies 按评估顺序获取打印策略列表
+func (h *PrintPolicyHandler) ListPrintPolicies(c *gin.Context) {
+	policies, err := h.policyRepo.ListPolicies(false)
+	if err != nil {
+		log.Printf("Failed to list print policies: %v", err)
+		InternalErrorResponse(c, "获取打印策略失败")
+		return
+	}
+
+	SuccessResponse(c, gin.H{"items": policies})
+}
+
+// GetPrintPolicy 获取打印策略详情
+func (h *PrintPolicyHandler) GetPrintPolicy(c *gin.Context) {
+	policy, ok := h.loadPolicy(c)
+	if !ok {
+		return
+	}
+
+	SuccessResponse(c, policy)
+}
+
+// CreatePrintPolicy 创建打印策略
+func (h *PrintPolicyHandler) CreatePrintPolicy(c *gin.Context) {
+	var req PrintPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	policy, msg, err := h.toPolicy(&req)
+	if err != nil {
+		log.Printf("Failed to validate print policy %s: %v", req.Name, err)
+		InternalErrorResponse(c, "创建打印策略失败")
+		return
+	}
+	if policy == nil {
+		BadRequestResponse(c, msg)
+		return
+	}
+	policy.CreatedBy, _ = currentActor(c)
+
+	created, err := h.policyRepo.CreatePolicy(policy)
+	if err != nil {
+		log.Printf("Failed to create print policy %s: %v", policy.Name, err)
+		InternalErrorResponse(c, "创建打印策略失败")
+		return
+	}
+	if !created {
+		ErrorResponse(c, http.StatusConflict, "打印策略名称已存在")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "print_policy.create", "print_policy", policy.ID,
+		fmt.Sprintf("name=%s, priority=%d, enabled=%t", policy.Name, policy.Priority, policy.Enabled))
+
+	CreatedResponse(c, policy)
+}
+
+// UpdatePrintPolicy 更新打印策略（整体替换条件和效果）
+func (h *PrintPolicyHandler) UpdatePrintPolicy(c *gin.Context) {
+	existing, ok := h.loadPolicy(c)
+	if !ok {
+		return
+	}
+
+	var req PrintPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	policy, msg, err := h.toPolicy(&req)
+	if err != nil {
+		log.Printf("Failed to validate print policy %s: %v", existing.ID, err)
+		InternalErrorResponse(c, "更新打印策略失败")
+		return
+	}
+	if policy == nil {
+		BadRequestResponse(c, msg)
+		return
+	}
+	policy.ID = existing.ID
+
+	if policy.Name != existing.Name {
+		taken, err := h.policyRepo.PolicyNameTaken(policy.Name, policy.ID)
+		if err != nil {
+			log.Printf("Failed to check print policy name %s: %v", policy.Name, err)
+			InternalErrorResponse(c, "更新打印策略失败")
+			return
+		}
+		if taken {
+			ErrorResponse(c, http.StatusConflict, "打印策略名称已存在")
+			return
+		}
+	}
+
+	updated, err := h.policyRepo.UpdatePolicy(policy)
+	if err != nil {
+		log.Printf("Failed to update print policy %s: %v", policy.ID, err)
+		InternalErrorResponse(c, "更新打印策略失败")
+		return
+	}
+	if !updated {
+		NotFoundResponse(c, "打印策略不存在")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "print_policy.update", "print_policy", policy.ID,
+		fmt.Sprintf("name=%s, priority=%d, enabled=%t", policy.Name, policy.Priority, policy.Enabled))
+
+	SuccessResponse(c, policy)
+}
+
+// DeletePrintPolicy 删除打印策略（已创建任务记录的策略名称保留）
+func (h *PrintPolicyHandler) DeletePrintPolicy(c *gin.Context) {
+	policy, ok := h.loadPolicy(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.policyRepo.DeletePolicy(policy.ID); err != nil {
+		log.Printf("Failed to delete print policy %s: %v", policy.ID, err)
+		InternalErrorResponse(c, "删除打印策略失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "print_policy.delete", "print_policy", policy.ID,
+		fmt.Sprintf("name=%s", policy.Name))
+
+	SuccessResponse(c, gin.H{"id": policy.ID})
+}
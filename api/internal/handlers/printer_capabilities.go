@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/papersize"
+	"fly-print-cloud/api/internal/printing"
+	"github.com/gin-gonic/gin"
+)
+
+// 打印机能力列表的长度限制，防止异常节点上报超大的 capabilities 撑大 JSONB 列
+const (
+	maxCapabilityEntries     = 100 // 每个列表（纸张尺寸、介质类型、文档格式）去重后的最大条目数
+	maxCapabilityValueLength = 64  // 列表条目及分辨率、打印速度的最大长度（按字符计）
+)
+
+var (
+	// capabilityResolutionPattern 分辨率，如 600dpi、1200x600 dpi，多个值以逗号分隔
+	capabilityResolutionPattern = regexp.MustCompile(`(?i)^\d{2,5}(\s*[x×*]\s*\d{2,5})?\s*(dpi)?(\s*,\s*\d{2,5}(\s*[x×*]\s*\d{2,5})?\s*(dpi)?)*$`)
+	// capabilityPrintSpeedPattern 打印速度，以数字开头，如 30、30ppm、30 页/分钟
+	capabilityPrintSpeedPattern = regexp.MustCompile(`^\d+(\.\d+)?(\s*\D.*)?$`)
+)
+
+// normalizeCapabilities 校验并规范化节点上报的打印机能力：纸张尺寸转换为规范ID（无法识别的写法保留并单独列出），
+// 列表去除空值和重复项。超出大小、条目数或格式不符时返回 400 并列出全部违规字段，不写入数据库
+func normalizeCapabilities(c *gin.Context, capabilities *models.PrinterCapabilities, maxSize int) bool {
+	normalized, violations := validateCapabilities(*capabilities, maxSize)
+	if len(violations) > 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "capabilities 无效: " + violations.Summary(),
+			Data:    gin.H{"error_code": "invalid_capabilities", "violations": violations},
+		})
+		return false
+	}
+	*capabilities = normalized
+	return true
+}
+
+// validateCapabilities 返回规范化后的能力和全部违规项，maxSize 为 0 时不限制序列化大小
+func validateCapabilities(capabilities models.PrinterCapabilities, maxSize int) (models.PrinterCapabilities, printing.Violations) {
+	var violations printing.Violations
+	if maxSize > 0 {
+		if encoded, err := json.Marshal(capabilities); err == nil && len(encoded) > maxSize {
+			// 超大的上报不再逐项校验
+			return capabilities, append(violations, printing.Violation{
+				Field:   "capabilities",
+				Code:    "too_large",
+				Message: fmt.Sprintf("capabilities 大小为 %d 字节，超过上限 %d 字节", len(encoded), maxSize),
+				Max:     maxSize,
+			})
+		}
+	}
+
+	capabilities.PaperSizes, capabilities.UnknownPaperSizes = papersize.NormalizeList(capabilities.PaperSizes)
+	capabilities.MediaTypes = dedupeCapabilityList(capabilities.MediaTypes)
+	capabilities.DocumentFormats = dedupeCapabilityList(capabilities.DocumentFormats)
+	capabilities.Resolution = strings.TrimSpace(capabilities.Resolution)
+	capabilities.PrintSpeed = strings.TrimSpace(capabilities.PrintSpeed)
+
+	violations = append(violations, checkCapabilityList("capabilities.paper_sizes", capabilities.PaperSizes)...)
+	violations = append(violations, checkCapabilityList("capabilities.media_types", capabilities.MediaTypes)...)
+	violations = append(violations, checkCapabilityList("capabilities.document_formats", capabilities.DocumentFormats)...)
+	violations = append(violations, checkCapabilityValue("capabilities.resolution", capabilities.Resolution, capabilityResolutionPattern)...)
+	violations = append(violations, checkCapabilityValue("capabilities.print_speed", capabilities.PrintSpeed, capabilityPrintSpeedPattern)...)
+	return capabilities, violations
+}
+
+// dedupeCapabilityList 去除空值和重复项（忽略大小写和首尾空白，保留第一次出现的写法）
+func dedupeCapabilityList(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		key := strings.ToLower(value)
+		if value == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, value)
+	}
+	return result
+}
+
+// checkCapabilityList 检查去重后的条目数和每个条目的长度
+func checkCapabilityList(field string, values []string) printing.Violations {
+	var violations printing.Violations
+	if len(values) > maxCapabilityEntries {
+		violations = append(violations, printing.Violation{
+			Field:   field,
+			Code:    "too_many_entries",
+			Message: fmt.Sprintf("%s 去重后有 %d 项，最多 %d 项", field, len(values), maxCapabilityEntries),
+			Max:     maxCapabilityEntries,
+		})
+	}
+	for _, value := range values {
+		if utf8.RuneCountInString(value) > maxCapabilityValueLength {
+			violations = append(violations, printing.Violation{
+				Field:   field,
+				Code:    "entry_too_long",
+				Message: fmt.Sprintf("%s 中的条目长度不能超过 %d 个字符", field, maxCapabilityValueLength),
+				Max:     maxCapabilityValueLength,
+			})
+			break
+		}
+	}
+	return violations
+}
+
+// checkCapabilityValue 检查单个值的长度和格式（宽松匹配，空值不检查）
+func checkCapabilityValue(field, value string, pattern *regexp.Regexp) printing.Violations {
+	if value == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(value) > maxCapabilityValueLength {
+		return printing.Violations{{
+			Field:   field,
+			Code:    "too_long",
+			Message: fmt.Sprintf("%s 长度不能超过 %d 个字符", field, maxCapabilityValueLength),
+			Max:     maxCapabilityValueLength,
+		}}
+	}
+	if !pattern.MatchString(value) {
+		return printing.Violations{{
+			Field:   field,
+			Code:    "invalid_format",
+			Message: fmt.Sprintf("%s 格式无效: %q", field, value),
+		}}
+	}
+	return nil
+}
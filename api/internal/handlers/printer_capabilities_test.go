@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/papersize"
+	"github.com/gin-gonic/gin"
+)
+
+// entries 构造 n 个不同的条目 prefix-0 … prefix-(n-1)
+func entries(prefix string, n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("%s-%d", prefix, i)
+	}
+	return values
+}
+
+func TestValidateCapabilities(t *testing.T) {
+	// 异常节点曾上报 40,000 项纸张尺寸
+	huge := models.PrinterCapabilities{PaperSizes: entries("custom", 40000)}
+
+	tests := []struct {
+		name         string
+		capabilities models.PrinterCapabilities
+		maxSize      int
+		wantCodes    []string // 违规字段:代码
+	}{
+		{"valid", models.PrinterCapabilities{PaperSizes: []string{"A4"}, MediaTypes: []string{"plain"}, Resolution: "600x600 dpi", PrintSpeed: "30 ppm"}, 65536, nil},
+		{"oversized payload", huge, 65536, []string{"capabilities:too_large"}},
+		{"oversized without size limit", huge, 0, []string{"capabilities.paper_sizes:too_many_entries"}},
+		{"too many media types", models.PrinterCapabilities{MediaTypes: entries("media", 150)}, 65536, []string{"capabilities.media_types:too_many_entries"}},
+		{"entry too long", models.PrinterCapabilities{DocumentFormats: []string{"pdf", strings.Repeat("x", 65)}}, 65536, []string{"capabilities.document_formats:entry_too_long"}},
+		{"bad resolution and speed", models.PrinterCapabilities{Resolution: "high", PrintSpeed: "fast"}, 65536,
+			[]string{"capabilities.resolution:invalid_format", "capabilities.print_speed:invalid_format"}},
+		{"resolution list", models.PrinterCapabilities{Resolution: "300dpi, 600x1200 DPI"}, 65536, nil},
+		{"speed too long", models.PrinterCapabilities{PrintSpeed: "30" + strings.Repeat(" ", 3) + strings.Repeat("p", 70)}, 65536, []string{"capabilities.print_speed:too_long"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, violations := validateCapabilities(tt.capabilities, tt.maxSize)
+			var got []string
+			for _, v := range violations {
+				got = append(got, v.Field+":"+v.Code)
+			}
+			if !reflect.DeepEqual(got, tt.wantCodes) {
+				t.Fatalf("violations = %v, want %v", got, tt.wantCodes)
+			}
+		})
+	}
+}
+
+// TestValidateCapabilitiesDedupes 大量重复项去重后在条目数限制之内
+func TestValidateCapabilitiesDedupes(t *testing.T) {
+	var paperSizes, mediaTypes []string
+	for i := 0; i < 5000; i++ {
+		paperSizes = append(paperSizes, "A4", " a4 ", "Letter", "", "Weird Size", "weird size")
+		mediaTypes = append(mediaTypes, "plain", "PLAIN", " glossy ", "")
+	}
+	normalized, violations := validateCapabilities(models.PrinterCapabilities{
+		PaperSizes:      paperSizes,
+		MediaTypes:      mediaTypes,
+		DocumentFormats: []string{"pdf", "PDF", "application/postscript"},
+		Resolution:      "  600 dpi ",
+	}, 0)
+	if len(violations) > 0 {
+		t.Fatalf("violations = %v", violations)
+	}
+
+	a4, _ := papersize.Normalize("A4")
+	letter, _ := papersize.Normalize("Letter")
+	if want := []string{a4, letter, "Weird Size"}; !reflect.DeepEqual(normalized.PaperSizes, want) {
+		t.Errorf("paper sizes = %v, want %v", normalized.PaperSizes, want)
+	}
+	if want := []string{"Weird Size"}; !reflect.DeepEqual(normalized.UnknownPaperSizes, want) {
+		t.Errorf("unknown paper sizes = %v, want %v", normalized.UnknownPaperSizes, want)
+	}
+	if want := []string{"plain", "glossy"}; !reflect.DeepEqual(normalized.MediaTypes, want) {
+		t.Errorf("media types = %v, want %v", normalized.MediaTypes, want)
+	}
+	if want := []string{"pdf", "application/postscript"}; !reflect.DeepEqual(normalized.DocumentFormats, want) {
+		t.Errorf("document formats = %v, want %v", normalized.DocumentFormats, want)
+	}
+	if normalized.Resolution != "600 dpi" {
+		t.Errorf("resolution = %q", normalized.Resolution)
+	}
+}
+
+// TestNormalizeCapabilitiesRejects 违规时返回 400 并列出全部违规字段，不修改请求中的能力
+func TestNormalizeCapabilitiesRejects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	capabilities := models.PrinterCapabilities{MediaTypes: append(entries("media", 150), "plain", "plain"), Resolution: "high"}
+	if normalizeCapabilities(c, &capabilities, 0) {
+		t.Fatal("normalizeCapabilities accepted invalid capabilities")
+	}
+	if len(capabilities.MediaTypes) != 152 {
+		t.Fatalf("request capabilities modified: %d media types", len(capabilities.MediaTypes))
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	var resp struct {
+		Data struct {
+			ErrorCode  string `json:"error_code"`
+			Violations []struct {
+				Field string `json:"field"`
+			} `json:"violations"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.ErrorCode != "invalid_capabilities" || len(resp.Data.Violations) != 2 ||
+		resp.Data.Violations[0].Field != "capabilities.media_types" || resp.Data.Violations[1].Field != "capabilities.resolution" {
+		t.Fatalf("response = %s", w.Body.String())
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PrinterGroupHandler 打印机组处理器
+type PrinterGroupHandler struct {
+	groupRepo   *database.PrinterGroupRepository
+	printerRepo *database.PrinterRepository
+	auditRepo   *database.AuditLogRepository
+}
+
+// NewPrinterGroupHandler 创建打印机组处理器
+func NewPrinterGroupHandler(groupRepo *database.PrinterGroupRepository, printerRepo *database.PrinterRepository, auditRepo *database.AuditLogRepository) *PrinterGroupHandler {
+	return &PrinterGroupHandler{
+		groupRepo:   groupRepo,
+		printerRepo: printerRepo,
+		auditRepo:   auditRepo,
+	}
+}
+
+// PrinterGroupRequest 创建/更新打印机组请求
+type PrinterGroupRequest struct {
+	Name            string                 `json:"name" binding:"required,max=100"`
+	Description     string                 `json:"description"`
+	RoutingStrategy models.RoutingStrategy `json:"routing_strategy"` // 默认 least_queue
+	PrinterIDs      []string               `json:"printer_ids"`      // 打印机ID或 slug
+	SLA             *models.SLAThresholds  `json:"sla"`              // 可选，覆盖全局 SLA 阈值（秒），0 表示该组不考核此阶段
+}
+
+// toGroup 校验请求并转换为打印机组，打印机标识统一解析为打印机ID
+func (h *PrinterGroupHandler) toGroup(req *PrinterGroupRequest) (*models.PrinterGroup, string) {
+	group := &models.PrinterGroup{
+		Name:            strings.TrimSpace(req.Name),
+		Description:     strings.TrimSpace(req.Description),
+		RoutingStrategy: req.RoutingStrategy,
+		PrinterIDs:      []string{},
+	}
+	if group.Name == "" {
+		return nil, "名称不能为空"
+	}
+	if group.RoutingStrategy == "" {
+		group.RoutingStrategy = models.DefaultRoutingStrategy
+	}
+	if !models.ValidRoutingStrategy(group.RoutingStrategy) {
+		return nil, fmt.Sprintf("routing_strategy 无效，可选值：%v", models.AllRoutingStrategies)
+	}
+	if req.SLA != nil {
+		for name, value := range map[string]*int{
+			"sla.time_to_dispatch_seconds": req.SLA.TimeToDispatch,
+			"sla.time_to_start_seconds":    req.SLA.TimeToStart,
+			"sla.time_to_complete_seconds": req.SLA.TimeToComplete,
+		} {
+			if value != nil && *value < 0 {
+				return nil, fmt.Sprintf("%s 不能为负数", name)
+			}
+		}
+		if !req.SLA.IsEmpty() {
+			group.SLA = req.SLA
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range req.PrinterIDs {
+		printer, err := h.printerRepo.GetPrinterByID(id)
+		if err != nil || printer == nil {
+			return nil, fmt.Sprintf("打印机 %s 不存在", id)
+		}
+		if !seen[printer.ID] {
+			seen[printer.ID] = true
+			group.PrinterIDs = append(group.PrinterIDs, printer.ID)
+		}
+	}
+	return group, ""
+}
+
+// loadGroup 根据路径参数获取打印机组，不存在时写入 404 响应
+func (h *PrinterGroupHandler) loadGroup(c *gin.Context) (*models.PrinterGroup, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		NotFoundResponse(c, "打印机组不存在")
+		return nil, false
+	}
+	group, err := h.groupRepo.GetGroup(id)
+	if err != nil {
+		log.Printf("Failed to get printer group %s: %v", id, err)
+		InternalErrorResponse(c, "获取打印机组失败")
+		return nil, false
+	}
+	if group == nil {
+		NotFoundResponse(c, "打印机组不存在")
+		return nil, false
+	}
+	return group, true
+}
+
+// ListPrinterGroups 获取打印机组列表
+func (h *PrinterGroupHandler) ListPrinterGroups(c *gin.Context) {
+	groups, err := h.groupRepo.ListGroups()
+	if err != nil {
+		log.Printf("Failed to list printer groups: %v", err)
+		InternalErrorResponse(c, "获取打印机组失败")
+		return
+	}
+
+	SuccessResponse(c, gin.H{"items": groups})
+}
+
+// GetPrinterGroup 获取打印机组详情
+func (h *PrinterGroupHandler) GetPrinterGroup(c *gin.Context) {
+	group, ok := h.loadGroup(c)
+	if !ok {
+		return
+	}
+
+	SuccessResponse(c, group)
+}
+
+// CreatePrinterGroup 创建打印机组
+func (h *PrinterGroupHandler) CreatePrinterGroup(c *gin.Context) {
+	var req PrinterGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	group, msg := h.toGroup(&req)
+	if group == nil {
+		BadRequestResponse(c, msg)
+		return
+	}
+
+	created, err := h.groupRepo.CreateGroup(group)
+	if err != nil {
+		log.Printf("Failed to create printer group %s: %v", group.Name, err)
+		InternalErrorResponse(c, "创建打印机组失败")
+		return
+	}
+	if !created {
+		ErrorResponse(c, http.StatusConflict, "打印机组名称已存在")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "printer_group.create", "printer_group", group.ID,
+		fmt.Sprintf("name=%s, routing_strategy=%s, printers=%d", group.Name, group.RoutingStrategy, len(group.PrinterIDs)))
+
+	CreatedResponse(c, group)
+}
+
+// UpdatePrinterGroup 更新打印机组（成员按 printer_ids 整体替换）
+func (h *PrinterGroupHandler) UpdatePrinterGroup(c *gin.Context) {
+	existing, ok := h.loadGroup(c)
+	if !ok {
+		return
+	}
+
+	var req PrinterGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+	group, msg := h.toGroup(&req)
+	if group == nil {
+		BadRequestResponse(c, msg)
+		return
+	}
+	group.ID = existing.ID
+
+	if group.Name != existing.Name {
+		taken, err := h.groupRepo.GroupNameTaken(group.Name, group.ID)
+		if err != nil {
+			log.Printf("Failed to check printer group name %s: %v", group.Name, err)
+			InternalErrorResponse(c, "更新打印机组失败")
+			return
+		}
+		if taken {
+			ErrorResponse(c, http.StatusConflict, "打印机组名称已存在")
+			return
+		}
+	}
+
+	updated, err := h.groupRepo.UpdateGroup(group)
+	if err != nil {
+		log.Printf("Failed to update printer group %s: %v", group.ID, err)
+		InternalErrorResponse(c, "更新打印机组失败")
+		return
+	}
+	if !updated {
+		NotFoundResponse(c, "打印机组不存在")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "printer_group.update", "printer_group", group.ID,
+		fmt.Sprintf("name=%s, routing_strategy=%s, printers=%d", group.Name, group.RoutingStrategy, len(group.PrinterIDs)))
+
+	SuccessResponse(c, group)
+}
+
+// DeletePrinterGroup 删除打印机组（不影响组内打印机和已提交的任务）
+func (h *PrinterGroupHandler) DeletePrinterGroup(c *gin.Context) {
+	group, ok := h.loadGroup(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.groupRepo.DeleteGroup(group.ID); err != nil {
+		log.Printf("Failed to delete printer group %s: %v", group.ID, err)
+		InternalErrorResponse(c, "删除打印机组失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "printer_group.delete", "printer_group", group.ID,
+		fmt.Sprintf("name=%s", group.Name))
+
+	SuccessResponse(c, gin.H{"id": group.ID})
+}
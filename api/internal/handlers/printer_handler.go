@@ -1,64 +1,112 @@
 package handlers
 
 import (
+	"fly-print-cloud/api/internal/config"
 	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/dispatch"
 	"fly-print-cloud/api/internal/models"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type PrinterHandler struct {
-	printerRepo  *database.PrinterRepository
-	edgeNodeRepo *database.EdgeNodeRepository
+	printerRepo       *database.PrinterRepository
+	edgeNodeRepo      *database.EdgeNodeRepository
+	printJobRepo      *database.PrintJobRepository
+	dispatcher        *dispatch.Dispatcher
+	auditRepo         *database.AuditLogRepository
+	powerScheduleRepo *database.PowerScheduleRepository
+	settings          *config.Store // 新发现打印机的处理方式（edge.printer_discovery）支持热更新
+	assetRepo         *database.AssetRepository
+	jobEventRepo      *database.PrintJobEventRepository
+	businessHoursRepo *database.BusinessHoursRepository
 }
 
-func NewPrinterHandler(printerRepo *database.PrinterRepository, edgeNodeRepo *database.EdgeNodeRepository) *PrinterHandler {
+func NewPrinterHandler(printerRepo *database.PrinterRepository, edgeNodeRepo *database.EdgeNodeRepository, printJobRepo *database.PrintJobRepository, dispatcher *dispatch.Dispatcher, auditRepo *database.AuditLogRepository, powerScheduleRepo *database.PowerScheduleRepository, settings *config.Store, assetRepo *database.AssetRepository, jobEventRepo *database.PrintJobEventRepository, businessHoursRepo *database.BusinessHoursRepository) *PrinterHandler {
 	return &PrinterHandler{
-		printerRepo:  printerRepo,
-		edgeNodeRepo: edgeNodeRepo,
+		printerRepo:       printerRepo,
+		edgeNodeRepo:      edgeNodeRepo,
+		printJobRepo:      printJobRepo,
+		dispatcher:        dispatcher,
+		auditRepo:         auditRepo,
+		powerScheduleRepo: powerScheduleRepo,
+		settings:          settings,
+		assetRepo:         assetRepo,
+		jobEventRepo:      jobEventRepo,
+		businessHoursRepo: businessHoursRepo,
 	}
 }
 
+// 新发现打印机的处理方式
+const (
+	PrinterDiscoveryAuto   = "auto"
+	PrinterDiscoveryReview = "review"
+)
+
 
 
 // UpdatePrinterRequest 更新打印机请求（Edge Node使用）
 type UpdatePrinterRequest struct {
 	Name            string                        `json:"name" binding:"required,min=1,max=100"`
 	Model           string                        `json:"model"`
-	SerialNumber    string                        `json:"serial_number"`
-	Status          string                        `json:"status" binding:"required,oneof=ready printing error offline"`
-	FirmwareVersion string                        `json:"firmware_version"`
-	PortInfo        string                        `json:"port_info"`
+	SerialNumber    *string                       `json:"serial_number"`
+	Status          models.PrinterStatus          `json:"status" binding:"required,printer_status"`
+	FirmwareVersion *string                       `json:"firmware_version"`
+	PortInfo        *string                       `json:"port_info"`
 	IPAddress       *string                       `json:"ip_address"`
-	MACAddress      string                        `json:"mac_address"`
-	NetworkConfig   string                        `json:"network_config"`
+	MACAddress      *string                       `json:"mac_address"`
+	NetworkConfig   *string                       `json:"network_config"`
 	Latitude        *float64                      `json:"latitude"`
 	Longitude       *float64                      `json:"longitude"`
-	Location        string                        `json:"location"`
+	Location        *string                       `json:"location"`
 	Capabilities    models.PrinterCapabilities    `json:"capabilities"`
 	QueueLength     int                           `json:"queue_length"`
+	RowVersion      *int                          `json:"row_version"` // 读取时的数据版本号，未提供 If-Match 请求头时必填
 }
 
 // AdminUpdatePrinterRequest 管理界面更新打印机请求
 type AdminUpdatePrinterRequest struct {
-	DisplayName string `json:"display_name" binding:"omitempty,max=100"`
-	Enabled     *bool  `json:"enabled"`  // 使用指针类型以区分未设置和false
+	DisplayName       string   `json:"display_name" binding:"omitempty,max=100"`
+	Enabled           *bool    `json:"enabled"`  // 使用指针类型以区分未设置和false
+	PricePerPageMono  *float64 `json:"price_per_page_mono" binding:"omitempty,min=0"`
+	PricePerPageColor *float64 `json:"price_per_page_color" binding:"omitempty,min=0"`
+	DuplexDiscount    *float64 `json:"duplex_discount" binding:"omitempty,min=0,max=1"`
+	MaxConcurrentJobs *int     `json:"max_concurrent_jobs" binding:"omitempty,min=0"` // 0 表示不限制
+	MaxCopies         *int     `json:"max_copies" binding:"omitempty,min=0"`          // 0 表示使用全局上限
+	BackupPrinterID   *string  `json:"backup_printer_id"` // 故障转移的备用打印机（ID 或 slug），空字符串表示关闭故障转移
+	NeedsAttention    *bool    `json:"needs_attention"`   // 设为 false 清除待处理标记
+	RowVersion        *int     `json:"row_version"` // 读取时的数据版本号，未提供 If-Match 请求头时必填
+	AssetUpdateRequest         // 资产信息（购买日期、保修到期日、资产编号、备注）
 }
 
 // PrinterWithStatus 包含实际状态的打印机信息
 type PrinterWithStatus struct {
 	*models.Printer
 	EdgeNodeEnabled bool   `json:"edge_node_enabled"`
+	EdgeNodeDraining bool  `json:"edge_node_draining"` // 所属节点排空中，不接收新任务
 	ActuallyEnabled bool   `json:"actually_enabled"`
 	DisabledReason  string `json:"disabled_reason,omitempty"`
+	ActiveJobs      *int   `json:"active_jobs,omitempty"` // 仅详情接口返回
+	QueuedJobs      *int   `json:"queued_jobs,omitempty"`
+	PowerSchedule   *models.PrinterPowerSchedule `json:"power_schedule,omitempty"` // 生效的节能计划（仅详情接口返回）
+	Asset           *models.AssetInfo `json:"asset,omitempty"` // 资产信息（仅管理界面接口返回）
+	AcceptingJobs   bool       `json:"accepting_jobs"`         // 实际启用且在营业时间内
+	NextOpenAt      *time.Time `json:"next_open_at,omitempty"` // 不在营业时间时的下次营业时间
 }
 
-// NewPrinterWithStatus 创建包含实际状态的打印机信息
-func NewPrinterWithStatus(printer *models.Printer, edgeNodeEnabled bool) *PrinterWithStatus {
-	actuallyEnabled := printer.Enabled && edgeNodeEnabled
+// NewPrinterWithStatus 创建包含实际状态的打印机信息，edgeNode 为空表示无法获取节点（按禁用处理）
+func NewPrinterWithStatus(printer *models.Printer, edgeNode *models.EdgeNode) *PrinterWithStatus {
+	edgeNodeEnabled := edgeNode != nil && edgeNode.Enabled
+	edgeNodeDraining := edgeNode != nil && edgeNode.Draining
+	actuallyEnabled := printer.Enabled && edgeNodeEnabled && !edgeNodeDraining
 	
 	var disabledReason string
 	if !actuallyEnabled {
@@ -66,29 +114,49 @@ func NewPrinterWithStatus(printer *models.Printer, edgeNodeEnabled bool) *Printe
 			disabledReason = "打印机被禁用"
 		} else if !edgeNodeEnabled {
 			disabledReason = "Edge Node被禁用"
+		} else {
+			disabledReason = "Edge Node排空中"
 		}
 	}
 	
 	return &PrinterWithStatus{
-		Printer:         printer,
-		EdgeNodeEnabled: edgeNodeEnabled,
-		ActuallyEnabled: actuallyEnabled,
-		DisabledReason:  disabledReason,
+		Printer:          printer,
+		EdgeNodeEnabled:  edgeNodeEnabled,
+		EdgeNodeDraining: edgeNodeDraining,
+		ActuallyEnabled:  actuallyEnabled,
+		DisabledReason:   disabledReason,
+		AcceptingJobs:    actuallyEnabled && !printer.Breaker.Suspended() && !printer.QueuePaused, // 熔断或队列暂停期间新任务排队
+	}
+}
+
+// applyBusinessHours 按生效的营业时间（可为空）计算当前是否接收任务及下次营业时间
+func (p *PrinterWithStatus) applyBusinessHours(hours *models.BusinessHours, now time.Time) {
+	if hours.OpenAt(now) {
+		return
 	}
+	nextOpen := hours.NextOpen(now)
+	p.AcceptingJobs = false
+	p.NextOpenAt = &nextOpen
 }
 
 // Edge 注册打印机请求（简化版）
 type EdgeRegisterPrinterRequest struct {
 	Name            string                        `json:"name" binding:"required,min=1,max=100"`
 	Model           string                        `json:"model"`
-	SerialNumber    string                        `json:"serial_number"`
-	FirmwareVersion string                        `json:"firmware_version"`
-	PortInfo        string                        `json:"port_info"`
+	SerialNumber    *string                       `json:"serial_number"`
+	FirmwareVersion *string                       `json:"firmware_version"`
+	PortInfo        *string                       `json:"port_info"`
 	IPAddress       *string                       `json:"ip_address"`
-	MACAddress      string                        `json:"mac_address"`
+	MACAddress      *string                       `json:"mac_address"`
 	Capabilities    models.PrinterCapabilities    `json:"capabilities"`
 }
 
+// EdgeRegisterPrinterResponse Edge 注册打印机响应，approved 表示打印机是否已可接收任务
+type EdgeRegisterPrinterResponse struct {
+	*models.Printer
+	Approved bool `json:"approved"`
+}
+
 // 管理员 API
 
 // ListPrinters 获取所有打印机列表（管理员）
@@ -108,15 +176,30 @@ func (h *PrinterHandler) ListPrinters(c *gin.Context) {
 	var total int
 	var err error
 
-	if edgeNodeID != "" {
+	if !isPrivilegedCaller(c) {
+		// 第三方调用只返回调用方有权使用的打印机
+		printers, total, err = h.printerRepo.ListPrintersForRoles(page, pageSize, edgeNodeID, callerRoles(c))
+		if err != nil {
+			log.Printf("Failed to list accessible printers: %v", err)
+			InternalErrorResponse(c, "获取打印机列表失败")
+			return
+		}
+	} else if approvalStatus := c.Query("approval_status"); approvalStatus != "" {
+		// 按审核状态筛选（如待审核队列）
+		printers, total, err = h.printerRepo.ListPrintersByApprovalStatus(page, pageSize, approvalStatus)
+		if err != nil {
+			log.Printf("Failed to list printers by approval status: %v", err)
+			InternalErrorResponse(c, "获取打印机列表失败")
+			return
+		}
+	} else if edgeNodeID != "" {
 		// 按Edge Node筛选
-		printers, err = h.printerRepo.ListPrintersByEdgeNode(edgeNodeID)
+		printers, total, err = h.printerRepo.ListPrintersByEdgeNodePaged(edgeNodeID, page, pageSize)
 		if err != nil {
 			log.Printf("Failed to list printers by edge node: %v", err)
 			InternalErrorResponse(c, "获取打印机列表失败")
 			return
 		}
-		total = len(printers)
 	} else {
 		// 获取所有打印机
 		printers, total, err = h.printerRepo.ListPrinters(page, pageSize)
@@ -128,24 +211,48 @@ func (h *PrinterHandler) ListPrinters(c *gin.Context) {
 	}
 
 	// 获取所有相关的Edge Node状态信息
-	edgeNodeStatusMap := make(map[string]bool)
+	edgeNodeStatusMap := make(map[string]*models.EdgeNode)
 	for _, printer := range printers {
 		if _, exists := edgeNodeStatusMap[printer.EdgeNodeID]; !exists {
 			edgeNode, err := h.edgeNodeRepo.GetEdgeNodeByID(printer.EdgeNodeID)
 			if err != nil {
 				log.Printf("Failed to get edge node %s: %v", printer.EdgeNodeID, err)
-				edgeNodeStatusMap[printer.EdgeNodeID] = false // 默认为禁用
-			} else {
-				edgeNodeStatusMap[printer.EdgeNodeID] = edgeNode.Enabled
+				edgeNode = nil // 默认为禁用
 			}
+			edgeNodeStatusMap[printer.EdgeNodeID] = edgeNode
 		}
 	}
 
 	// 转换为包含实际状态的打印机信息
 	printersWithStatus := make([]*PrinterWithStatus, len(printers))
 	for i, printer := range printers {
-		edgeNodeEnabled := edgeNodeStatusMap[printer.EdgeNodeID]
-		printersWithStatus[i] = NewPrinterWithStatus(printer, edgeNodeEnabled)
+		printersWithStatus[i] = NewPrinterWithStatus(printer, edgeNodeStatusMap[printer.EdgeNodeID])
+	}
+
+	// 营业时间（批量查询打印机自身或继承自 Edge Node 的设置）
+	if businessHours, err := h.businessHoursRepo.GetEffectiveBusinessHoursForPrinters(printers); err != nil {
+		log.Printf("Failed to get printer business hours: %v", err)
+	} else {
+		now := time.Now()
+		for _, printer := range printersWithStatus {
+			printer.applyBusinessHours(businessHours[printer.ID], now)
+		}
+	}
+
+	// 资产信息只在管理界面返回，第三方调用不可见
+	if isAdminConsoleRequest(c) && len(printers) > 0 {
+		ids := make([]string, len(printers))
+		for i, printer := range printers {
+			ids[i] = printer.ID
+		}
+		assets, err := h.assetRepo.GetAssets(models.AssetResourcePrinter, ids)
+		if err != nil {
+			log.Printf("Failed to get printer asset info: %v", err)
+		} else {
+			for _, printer := range printersWithStatus {
+				printer.Asset = assets[printer.ID]
+			}
+		}
 	}
 
 	totalPages := (total + pageSize - 1) / pageSize
@@ -173,18 +280,55 @@ func (h *PrinterHandler) GetPrinter(c *gin.Context) {
 		NotFoundResponse(c, "打印机不存在")
 		return
 	}
+	setVersionETag(c, printer.RowVersion)
 
 	// 获取Edge Node状态
 	edgeNode, err := h.edgeNodeRepo.GetEdgeNodeByID(printer.EdgeNodeID)
 	if err != nil {
 		log.Printf("Failed to get edge node %s: %v", printer.EdgeNodeID, err)
 		// 如果无法获取Edge Node状态，假设为禁用
-		printerWithStatus := NewPrinterWithStatus(printer, false)
+		printerWithStatus := NewPrinterWithStatus(printer, nil)
 		SuccessResponse(c, printerWithStatus)
 		return
 	}
 
-	printerWithStatus := NewPrinterWithStatus(printer, edgeNode.Enabled)
+	printerWithStatus := NewPrinterWithStatus(printer, edgeNode)
+
+	// 营业时间
+	if hours, err := h.businessHoursRepo.GetEffectiveBusinessHours(printer.ID, printer.EdgeNodeID); err != nil {
+		log.Printf("Failed to get business hours for printer %s: %v", printer.ID, err)
+	} else {
+		printerWithStatus.applyBusinessHours(hours, time.Now())
+	}
+
+	// 并发占用情况：正在执行与云端排队的任务数
+	active, queued, err := h.printJobRepo.CountPrinterJobLoad(printer.ID)
+	if err != nil {
+		log.Printf("Failed to count job load for printer %s: %v", printer.ID, err)
+	} else {
+		printerWithStatus.ActiveJobs = &active
+		printerWithStatus.QueuedJobs = &queued
+	}
+
+	// 生效的节能计划（打印机自身或继承自 Edge Node）
+	if h.powerScheduleRepo != nil {
+		schedule, err := h.powerScheduleRepo.GetEffectiveSchedule(printer.ID, printer.EdgeNodeID)
+		if err != nil {
+			log.Printf("Failed to get power schedule for printer %s: %v", printer.ID, err)
+		} else {
+			printerWithStatus.PowerSchedule = schedule
+		}
+	}
+
+	if isAdminConsoleRequest(c) {
+		asset, err := h.assetRepo.GetAsset(models.AssetResourcePrinter, printer.ID)
+		if err != nil {
+			log.Printf("Failed to get asset info for printer %s: %v", printer.ID, err)
+		} else {
+			printerWithStatus.Asset = asset
+		}
+	}
+
 	SuccessResponse(c, printerWithStatus)
 }
 
@@ -204,15 +348,63 @@ func (h *PrinterHandler) UpdatePrinter(c *gin.Context) {
 	}
 
 	// 尝试解析为管理界面的简单更新请求
+	limitChanged := false
+	reenabled := false // 禁用期间提交的任务已排队，重新启用后分发
+	var asset *models.AssetInfo
 	var adminReq AdminUpdatePrinterRequest
 	if err := c.ShouldBindJSON(&adminReq); err == nil {
+		if !checkVersion(c, adminReq.RowVersion, printer.RowVersion) {
+			return
+		}
+		if adminReq.NeedsAttention != nil && *adminReq.NeedsAttention {
+			BadRequestResponse(c, "needs_attention 只能设为 false 以清除待处理标记")
+			return
+		}
+		if adminReq.BackupPrinterID != nil {
+			backupID, ok := h.resolveBackupPrinter(c, printer, *adminReq.BackupPrinterID)
+			if !ok {
+				return
+			}
+			printer.BackupPrinterID = backupID
+		}
+		// 资产信息先校验保存，校验失败时不修改打印机
+		var ok bool
+		if asset, ok = applyAssetUpdate(c, h.assetRepo, h.auditRepo, models.AssetResourcePrinter, printer.ID, &adminReq.AssetUpdateRequest); !ok {
+			return
+		}
 		// 管理界面更新（仅更新display_name和enabled）
 		if adminReq.DisplayName != "" {
 			printer.DisplayName = adminReq.DisplayName
 		}
 		if adminReq.Enabled != nil {
+			reenabled = *adminReq.Enabled && !printer.Enabled
 			printer.Enabled = *adminReq.Enabled
 		}
+		// 打印机级别计费覆盖
+		if adminReq.PricePerPageMono != nil {
+			printer.PricePerPageMono = adminReq.PricePerPageMono
+		}
+		if adminReq.PricePerPageColor != nil {
+			printer.PricePerPageColor = adminReq.PricePerPageColor
+		}
+		if adminReq.DuplexDiscount != nil {
+			printer.DuplexDiscount = adminReq.DuplexDiscount
+		}
+		if adminReq.MaxConcurrentJobs != nil {
+			limitChanged = true
+			if *adminReq.MaxConcurrentJobs == 0 {
+				printer.MaxConcurrentJobs = nil
+			} else {
+				printer.MaxConcurrentJobs = adminReq.MaxConcurrentJobs
+			}
+		}
+		if adminReq.MaxCopies != nil {
+			if *adminReq.MaxCopies == 0 {
+				printer.MaxCopies = nil
+			} else {
+				printer.MaxCopies = adminReq.MaxCopies
+			}
+		}
 	} else {
 		// 尝试解析为Edge Node的完整更新请求
 		var req UpdatePrinterRequest
@@ -220,6 +412,15 @@ func (h *PrinterHandler) UpdatePrinter(c *gin.Context) {
 			BadRequestResponse(c, "请求参数无效")
 			return
 		}
+		if !normalizeNetworkAddresses(c, &req.IPAddress, &req.MACAddress) {
+			return
+		}
+		if !normalizeCapabilities(c, &req.Capabilities, h.settings.Get().Edge.MaxCapabilitiesSize) {
+			return
+		}
+		if !checkVersion(c, req.RowVersion, printer.RowVersion) {
+			return
+		}
 
 		// 更新所有打印机信息
 		printer.Name = req.Name
@@ -235,17 +436,57 @@ func (h *PrinterHandler) UpdatePrinter(c *gin.Context) {
 		printer.Longitude = req.Longitude
 		printer.Location = req.Location
 		printer.Capabilities = req.Capabilities
-		printer.QueueLength = req.QueueLength
+		printer.EdgeQueueLength = req.QueueLength
 	}
 
 	if err := h.printerRepo.UpdatePrinter(printer); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			versionConflictResponse(c)
+			return
+		}
 		log.Printf("Failed to update printer %s: %v", printerID, err)
 		InternalErrorResponse(c, "更新打印机失败")
 		return
 	}
+	setVersionETag(c, printer.RowVersion)
+
+	// 并发上限调整或重新启用后可能有空闲名额，继续分发排队任务
+	if limitChanged || reenabled {
+		h.dispatcher.DispatchQueued(printer)
+	}
+
+	if adminReq.NeedsAttention != nil && printer.NeedsAttention {
+		if _, err := h.printerRepo.ClearPrinterAttention(printer.ID); err != nil {
+			log.Printf("Failed to clear attention flag of printer %s: %v", printer.ID, err)
+		} else {
+			recordAudit(c, h.auditRepo, "printer.clear_attention", "printer", printer.ID, printer.AttentionReason)
+			printer.NeedsAttention, printer.AttentionReason, printer.AttentionAt = false, "", nil
+		}
+	}
 
 	log.Printf("Printer %s updated successfully", printer.Name)
-	SuccessResponse(c, printer)
+	SuccessResponse(c, struct {
+		*models.Printer
+		Asset *models.AssetInfo `json:"asset,omitempty"`
+	}{printer, asset})
+}
+
+// resolveBackupPrinter 校验故障转移的备用打印机，空值表示关闭故障转移；无效时返回 400
+func (h *PrinterHandler) resolveBackupPrinter(c *gin.Context, printer *models.Printer, value string) (*string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, true
+	}
+	backup, err := h.printerRepo.GetPrinterByID(value)
+	if err != nil || backup == nil {
+		BadRequestResponse(c, "backup_printer_id: 备用打印机不存在")
+		return nil, false
+	}
+	if backup.ID == printer.ID {
+		BadRequestResponse(c, "backup_printer_id: 备用打印机不能是打印机自身")
+		return nil, false
+	}
+	return &backup.ID, true
 }
 
 // DeletePrinter 删除打印机
@@ -256,22 +497,183 @@ func (h *PrinterHandler) DeletePrinter(c *gin.Context) {
 		return
 	}
 
-	// 检查打印机是否存在
-	_, err := h.printerRepo.GetPrinterByID(printerID)
+	// 检查打印机是否存在（支持使用 slug）
+	printer, err := h.printerRepo.GetPrinterByID(printerID)
 	if err != nil {
 		NotFoundResponse(c, "打印机不存在")
 		return
 	}
 
-	// 删除打印机
-	if err := h.printerRepo.DeletePrinter(printerID); err != nil {
+	// 删除打印机：仍有未结束的任务时需要 force=true，先取消这些任务；历史任务保留
+	// 同时记录墓碑，防止节点下次同步库存时重新注册
+	force := c.Query("force") == "true"
+	actor, _ := currentActor(c)
+	var tombstoneUntil time.Time
+	if ttl := h.settings.Get().Edge.PrinterTombstoneTTL; ttl > 0 {
+		tombstoneUntil = time.Now().UTC().Add(ttl)
+	}
+	cancelled, err := h.printerRepo.DeletePrinter(printer.ID, force, actor, tombstoneUntil)
+	if err != nil {
+		var hasJobs *database.PrinterHasJobsError
+		if errors.As(err, &hasJobs) {
+			c.JSON(http.StatusConflict, gin.H{
+				"code":            http.StatusConflict,
+				"message":         fmt.Sprintf("打印机还有 %d 个未结束的任务，请先处理或使用 force=true 强制删除", hasJobs.Unfinished),
+				"unfinished_jobs": hasJobs.Unfinished,
+			})
+			return
+		}
 		log.Printf("Failed to delete printer %s: %v", printerID, err)
 		InternalErrorResponse(c, "删除打印机失败")
 		return
 	}
 
+	for _, job := range cancelled {
+		h.jobEventRepo.Record(&models.PrintJobEvent{
+			JobID:   job.ID,
+			Type:    models.JobEventCancelled,
+			Status:  models.JobStatusCancelled,
+			Actor:   actor,
+			Message: "打印机已删除",
+		})
+	}
+	recordAudit(c, h.auditRepo, "printer.delete", "printer", printer.ID,
+		fmt.Sprintf("name=%s edge_node=%s force=%t cancelled_jobs=%d", printer.Name, printer.EdgeNodeID, force, len(cancelled)))
+
 	log.Printf("Printer %s deleted successfully", printerID)
-	SuccessResponse(c, gin.H{"message": "打印机删除成功"})
+	response := gin.H{"message": "打印机删除成功", "cancelled_jobs": len(cancelled)}
+	if !tombstoneUntil.IsZero() {
+		response["tombstone_expires_at"] = tombstoneUntil
+	}
+	SuccessResponse(c, response)
+}
+
+// ApprovePrinter 批准待审核的打印机（管理员）
+func (h *PrinterHandler) ApprovePrinter(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	if printer.ApprovalStatus != models.PrinterApprovalApproved {
+		if err := h.printerRepo.ApprovePrinter(printer.ID); err != nil {
+			log.Printf("Failed to approve printer %s: %v", printer.ID, err)
+			InternalErrorResponse(c, "批准打印机失败")
+			return
+		}
+		printer.ApprovalStatus = models.PrinterApprovalApproved
+		recordAudit(c, h.auditRepo, "printer.approve", "printer", printer.ID,
+			fmt.Sprintf("name=%s edge_node=%s", printer.Name, printer.EdgeNodeID))
+	}
+
+	SuccessResponse(c, printer)
+}
+
+// ResetPrinterBreaker 手动重置打印机熔断器（管理员）：清零连续失败计数，已熔断时恢复分发
+func (h *PrinterHandler) ResetPrinterBreaker(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil || printer == nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	before, err := h.dispatcher.ResetBreaker(printer)
+	if err != nil {
+		log.Printf("Failed to reset breaker of printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "重置打印机熔断失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "printer.breaker_reset", "printer", printer.ID,
+		fmt.Sprintf("state=%s failures=%d reason=%s", before.State, before.ConsecutiveFailures, before.Reason))
+	SuccessResponse(c, printer)
+}
+
+// PausePrinterQueueRequest 暂停打印机队列请求（请求体可省略）
+type PausePrinterQueueRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// PausePrinterQueue 暂停打印机队列（如更换特殊纸张）：不再分发新任务，已下发的任务继续打印，并通知 Edge Node 暂停本地队列
+func (h *PrinterHandler) PausePrinterQueue(c *gin.Context) {
+	var req PausePrinterQueueRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ValidationErrorResponse(c, err)
+			return
+		}
+	}
+
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil || printer == nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	actor, _ := currentActor(c)
+	paused, err := h.dispatcher.PauseQueue(printer, actor, strings.TrimSpace(req.Reason))
+	if err != nil {
+		log.Printf("Failed to pause queue of printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "暂停打印机队列失败")
+		return
+	}
+	if !paused {
+		ErrorResponse(c, http.StatusConflict, "打印机队列已暂停")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "printer.queue_pause", "printer", printer.ID,
+		fmt.Sprintf("reason=%s", printer.QueuePauseReason))
+	SuccessResponse(c, printer)
+}
+
+// ResumePrinterQueue 恢复打印机队列，分发暂停期间排队的任务
+func (h *PrinterHandler) ResumePrinterQueue(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil || printer == nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	pausedBy, pausedAt := printer.QueuePausedBy, printer.QueuePausedAt
+	resumed, err := h.dispatcher.ResumeQueue(printer)
+	if err != nil {
+		log.Printf("Failed to resume queue of printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "恢复打印机队列失败")
+		return
+	}
+	if !resumed {
+		ErrorResponse(c, http.StatusConflict, "打印机队列未暂停")
+		return
+	}
+
+	details := fmt.Sprintf("trigger=manual paused_by=%s", pausedBy)
+	if pausedAt != nil {
+		details += fmt.Sprintf(" paused_at=%s", pausedAt.Format(time.RFC3339))
+	}
+	recordAudit(c, h.auditRepo, "printer.queue_resume", "printer", printer.ID, details)
+	SuccessResponse(c, printer)
+}
+
+// RejectPrinter 拒绝打印机（管理员）：删除打印机并屏蔽该 名称 + Edge Node 的再次注册
+func (h *PrinterHandler) RejectPrinter(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+
+	actor, _ := currentActor(c)
+	if err := h.printerRepo.RejectPrinter(printer, actor); err != nil {
+		log.Printf("Failed to reject printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "拒绝打印机失败")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "printer.reject", "printer", printer.ID,
+		fmt.Sprintf("name=%s edge_node=%s", printer.Name, printer.EdgeNodeID))
+	SuccessResponse(c, gin.H{"message": "打印机已拒绝"})
 }
 
 // Edge Node API
@@ -289,6 +691,12 @@ func (h *PrinterHandler) EdgeRegisterPrinter(c *gin.Context) {
 		BadRequestResponse(c, "请求参数无效")
 		return
 	}
+	if !normalizeNetworkAddresses(c, &req.IPAddress, &req.MACAddress) {
+		return
+	}
+	if !normalizeCapabilities(c, &req.Capabilities, h.settings.Get().Edge.MaxCapabilitiesSize) {
+		return
+	}
 
 	// 验证 Edge Node 是否存在
 	_, err := h.edgeNodeRepo.GetEdgeNodeByID(edgeNodeID)
@@ -297,20 +705,65 @@ func (h *PrinterHandler) EdgeRegisterPrinter(c *gin.Context) {
 		return
 	}
 
+	// 被管理员拒绝的打印机不再注册
+	rejected, err := h.printerRepo.IsPrinterRejected(req.Name, edgeNodeID)
+	if err != nil {
+		log.Printf("Failed to check rejected printer %s on edge node %s: %v", req.Name, edgeNodeID, err)
+		InternalErrorResponse(c, "注册打印机失败")
+		return
+	}
+	if rejected {
+		c.JSON(http.StatusForbidden, Response{
+			Code:    http.StatusForbidden,
+			Message: "打印机已被管理员拒绝",
+			Data:    gin.H{"name": req.Name, "approved": false, "approval_status": "rejected"},
+		})
+		return
+	}
+
+	// 被管理员删除的打印机在墓碑有效期内不再注册，通知节点停止上报
+	tombstone, err := h.printerRepo.GetPrinterTombstone(req.Name, edgeNodeID, time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to check printer tombstone %s on edge node %s: %v", req.Name, edgeNodeID, err)
+		InternalErrorResponse(c, "注册打印机失败")
+		return
+	}
+	if tombstone != nil {
+		c.JSON(http.StatusGone, Response{
+			Code:    http.StatusGone,
+			Message: "打印机已被管理员删除，请停止上报",
+			Data: gin.H{
+				"name":            req.Name,
+				"approved":        false,
+				"approval_status": "removed",
+				"removed":         true,
+				"removed_at":      tombstone.DeletedAt,
+				"removed_until":   tombstone.ExpiresAt,
+			},
+		})
+		return
+	}
+
+	// 审核模式下新发现的打印机进入待审核队列（已注册的打印机保留原有审核状态）
+	approvalStatus := models.PrinterApprovalApproved
+	if h.settings.Get().Edge.PrinterDiscovery == PrinterDiscoveryReview {
+		approvalStatus = models.PrinterApprovalPendingReview
+	}
+
 	printer := &models.Printer{
 		ID:              uuid.New().String(),
 		Name:            req.Name,
 		Model:           req.Model,
 		SerialNumber:    req.SerialNumber,
-		Status:          "offline", // 默认状态
+		Status:          models.PrinterStatusOffline, // 默认状态
 		FirmwareVersion: req.FirmwareVersion,
 		PortInfo:        req.PortInfo,
 		IPAddress:       req.IPAddress,
 		MACAddress:      req.MACAddress,
-		NetworkConfig:   "",
 		Capabilities:    req.Capabilities,
 		EdgeNodeID:      edgeNodeID,
-		QueueLength:     0,
+		EdgeQueueLength: 0,
+		ApprovalStatus:  approvalStatus,
 	}
 
 	if err := h.printerRepo.UpsertPrinter(printer); err != nil {
@@ -319,11 +772,15 @@ func (h *PrinterHandler) EdgeRegisterPrinter(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Printer %s registered/updated by edge node %s", printer.Name, edgeNodeID)
-	CreatedResponse(c, printer)
+	log.Printf("Printer %s registered/updated by edge node %s (approval: %s)", printer.Name, edgeNodeID, printer.ApprovalStatus)
+	CreatedResponse(c, EdgeRegisterPrinterResponse{
+		Printer:  printer,
+		Approved: printer.ApprovalStatus == models.PrinterApprovalApproved,
+	})
 }
 
 // EdgeListPrinters Edge Node 获取自己的打印机列表
+// 返回完整库存（不分页），最多 database.MaxEdgeInventoryPrinters 台；达到上限时 truncated 为 true
 func (h *PrinterHandler) EdgeListPrinters(c *gin.Context) {
 	edgeNodeID := c.Param("node_id")
 	if edgeNodeID == "" {
@@ -345,5 +802,9 @@ func (h *PrinterHandler) EdgeListPrinters(c *gin.Context) {
 		return
 	}
 
-	SuccessResponse(c, gin.H{"items": printers})
+	SuccessResponse(c, gin.H{
+		"items":     printers,
+		"limit":     database.MaxEdgeInventoryPrinters,
+		"truncated": len(printers) >= database.MaxEdgeInventoryPrinters,
+	})
 }
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ReservePrinterRequest 预留/延长请求，请求体可省略
+type ReservePrinterRequest struct {
+	DurationSeconds *int `json:"duration_seconds" binding:"omitempty,min=1"` // 预留时长（秒），为空时使用 reservations.default_duration
+}
+
+// bindReservationDuration 解析预留时长，超过 reservations.max_duration 时截断；解析失败时已写入响应
+func (h *PrinterHandler) bindReservationDuration(c *gin.Context) (time.Duration, bool) {
+	var req ReservePrinterRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		BadRequestResponse(c, "请求参数无效")
+		return 0, false
+	}
+	cfg := h.settings.Get().Reservations
+	duration := cfg.DefaultDuration
+	if req.DurationSeconds != nil {
+		duration = time.Duration(*req.DurationSeconds) * time.Second
+	}
+	if duration > cfg.MaxDuration {
+		duration = cfg.MaxDuration
+	}
+	return duration, true
+}
+
+// reservationConflict 打印机已被其他用户预留时返回 409，data 中包含当前预留（预留人和到期时间）
+func reservationConflict(c *gin.Context, reservation *models.PrinterReservation) {
+	c.JSON(http.StatusConflict, Response{
+		Code:    http.StatusConflict,
+		Message: fmt.Sprintf("打印机已被 %s 预留，%s 到期", reservation.Holder, reservation.ExpiresAt.Format(time.RFC3339)),
+		Data:    reservation,
+	})
+}
+
+// ReservePrinter 为当前用户预留打印机（自助打印终端）：预留期间只分发该用户的任务，其他用户的任务排队
+// 当前用户已持有预留时重新开始计时
+func (h *PrinterHandler) ReservePrinter(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+	holder, _ := currentActor(c)
+	if holder == "" {
+		ForbiddenResponse(c, "无法识别当前用户")
+		return
+	}
+	duration, ok := h.bindReservationDuration(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	reservation, granted, err := h.printerRepo.ReservePrinter(printer.ID, holder, now, now.Add(duration))
+	if err != nil {
+		log.Printf("Failed to reserve printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "预留打印机失败")
+		return
+	}
+	if !granted {
+		if reservation == nil {
+			// 预留恰好到期被清理或打印机已被删除
+			ErrorResponse(c, http.StatusConflict, "打印机状态已变化，请重试")
+			return
+		}
+		reservationConflict(c, reservation)
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "printer.reserve", "printer", printer.ID,
+		fmt.Sprintf("expires_at=%s", reservation.ExpiresAt.Format(time.RFC3339)))
+	SuccessResponse(c, reservation)
+}
+
+// ExtendPrinterReservation 延长当前用户的预留，预留总时长不超过 reservations.max_duration
+func (h *PrinterHandler) ExtendPrinterReservation(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+	holder, _ := currentActor(c)
+	duration, ok := h.bindReservationDuration(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	maxDuration := h.settings.Get().Reservations.MaxDuration
+	reservation, extended, err := h.printerRepo.ExtendReservation(printer.ID, holder, now, now.Add(duration), maxDuration)
+	if err != nil {
+		log.Printf("Failed to extend reservation of printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "延长预留失败")
+		return
+	}
+	if !extended {
+		if reservation == nil {
+			ErrorResponse(c, http.StatusConflict, "打印机当前没有预留或预留已到期")
+			return
+		}
+		reservationConflict(c, reservation)
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "printer.reservation_extend", "printer", printer.ID,
+		fmt.Sprintf("expires_at=%s", reservation.ExpiresAt.Format(time.RFC3339)))
+	SuccessResponse(c, reservation)
+}
+
+// ReleasePrinterReservation 释放预留并分发排队中的任务；只有预留人和管理员可以释放
+func (h *PrinterHandler) ReleasePrinterReservation(c *gin.Context) {
+	printer, err := h.printerRepo.GetPrinterByID(c.Param("id"))
+	if err != nil {
+		NotFoundResponse(c, "打印机不存在")
+		return
+	}
+	reservation := printer.Reservation
+	if reservation == nil {
+		ErrorResponse(c, http.StatusConflict, "打印机当前没有预留或预留已到期")
+		return
+	}
+	actor, _ := currentActor(c)
+	if reservation.Holder != actor && !isAdminCaller(c) {
+		ForbiddenResponse(c, "只有预留人或管理员可以释放预留")
+		return
+	}
+
+	released, err := h.printerRepo.ReleaseReservation(printer.ID, reservation.Holder)
+	if err != nil {
+		log.Printf("Failed to release reservation of printer %s: %v", printer.ID, err)
+		InternalErrorResponse(c, "释放预留失败")
+		return
+	}
+	if !released {
+		ErrorResponse(c, http.StatusConflict, "预留已变化，请刷新后重试")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "printer.reservation_release", "printer", printer.ID,
+		fmt.Sprintf("holder=%s", reservation.Holder))
+	printer.Reservation = nil
+	h.dispatcher.DispatchQueued(printer)
+	SuccessResponse(c, printer)
+}
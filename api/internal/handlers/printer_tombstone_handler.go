@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListPrinterTombstones 获取有效的打印机墓碑（已删除且暂不允许节点重新注册的打印机），可按 edge_node_id 筛选
+func (h *PrinterHandler) ListPrinterTombstones(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	tombstones, total, err := h.printerRepo.ListPrinterTombstones(page, pageSize, c.Query("edge_node_id"), time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to list printer tombstones: %v", err)
+		InternalErrorResponse(c, "获取打印机墓碑列表失败")
+		return
+	}
+	PaginatedSuccessResponse(c, tombstones, total, page, pageSize)
+}
+
+// ForgetPrinterTombstone 清除墓碑（管理员），节点下次同步时可重新注册该打印机
+func (h *PrinterHandler) ForgetPrinterTombstone(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		NotFoundResponse(c, "墓碑不存在")
+		return
+	}
+
+	tombstone, err := h.printerRepo.DeletePrinterTombstone(id)
+	if err != nil {
+		log.Printf("Failed to delete printer tombstone %s: %v", id, err)
+		InternalErrorResponse(c, "清除墓碑失败")
+		return
+	}
+	if tombstone == nil {
+		NotFoundResponse(c, "墓碑不存在")
+		return
+	}
+
+	recordAudit(c, h.auditRepo, "printer.tombstone_forget", "printer", tombstone.PrinterID,
+		fmt.Sprintf("name=%s edge_node=%s", tombstone.Name, tombstone.EdgeNodeID))
+	SuccessResponse(c, tombstone)
+}
@@ -61,6 +61,9 @@ func ValidationErrorResponse(c *gin.Context, err error) {
 
 // getFieldErrorMessage 获取字段错误信息
 func getFieldErrorMessage(fe validator.FieldError) string {
+	if msg, ok := statusValidatorMessage(fe); ok {
+		return msg
+	}
 	switch fe.Tag() {
 	case "required":
 		return fe.Field() + " 是必填字段"
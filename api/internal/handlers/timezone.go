@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// statsTimezone 统计按自然日划分使用的时区：查询参数 tz（IANA 名称）优先，否则使用部署时区 app.timezone
+// tz 无效时已返回 400
+func statsTimezone(c *gin.Context, settings *config.Store) (*time.Location, bool) {
+	name := c.Query("tz")
+	if name == "" {
+		name = settings.Get().App.Timezone
+	}
+	loc, err := config.LoadTimezone(name)
+	if err != nil {
+		BadRequestResponse(c, "tz 无效，应为 IANA 时区名称（如 Asia/Singapore）")
+		return nil, false
+	}
+	return loc, true
+}
+
+// startOfDay t 所在自然日（按 loc 时区）的零点
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+// TestTrendWindowDST 窗口跨过夏令时切换时按自然日划分，切换日只有 23 小时
+func TestTrendWindowDST(t *testing.T) {
+	loc := mustLoadLocation(t, "America/New_York")
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, loc) // 2026-03-08 02:00 开始夏令时
+
+	start, end, days := trendWindow(now, loc)
+
+	want := []string{"2026-03-04", "2026-03-05", "2026-03-06", "2026-03-07", "2026-03-08", "2026-03-09", "2026-03-10"}
+	if !reflect.DeepEqual(days, want) {
+		t.Fatalf("days = %v, want %v", days, want)
+	}
+	if !start.Equal(time.Date(2026, 3, 4, 0, 0, 0, 0, loc)) {
+		t.Errorf("start = %s, want local midnight of 2026-03-04", start)
+	}
+	if !end.Equal(time.Date(2026, 3, 11, 0, 0, 0, 0, loc)) {
+		t.Errorf("end = %s, want local midnight of 2026-03-11", end)
+	}
+	if got := end.Sub(start); got != 7*24*time.Hour-time.Hour {
+		t.Errorf("window length = %s, want 167h (one 23h day)", got)
+	}
+}
+
+// TestTrendWindowYearRollover 跨年的窗口日期标签包含年份，并按目标时区而不是 UTC 划分
+func TestTrendWindowYearRollover(t *testing.T) {
+	loc := mustLoadLocation(t, "Asia/Singapore")
+	now := time.Date(2026, 1, 2, 20, 30, 0, 0, time.UTC) // 新加坡已是 2026-01-03 04:30
+
+	start, _, days := trendWindow(now, loc)
+
+	want := []string{"2025-12-28", "2025-12-29", "2025-12-30", "2025-12-31", "2026-01-01", "2026-01-02", "2026-01-03"}
+	if !reflect.DeepEqual(days, want) {
+		t.Fatalf("days = %v, want %v", days, want)
+	}
+	if want := time.Date(2025, 12, 27, 16, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("start = %s, want %s", start.UTC(), want)
+	}
+}
+
+// TestParseDateRangeDST 日期范围的结束日为夏令时切换日时，范围覆盖整个自然日
+func TestParseDateRangeDST(t *testing.T) {
+	loc := mustLoadLocation(t, "Europe/Berlin")
+	start, end, err := parseDateRange("2026-10-25", "2026-10-25", loc) // 2026-10-25 结束夏令时
+	if err != nil {
+		t.Fatalf("parseDateRange: %v", err)
+	}
+	if got := end.Sub(start); got != 25*time.Hour {
+		t.Errorf("range = %s, want 25h", got)
+	}
+	if _, _, err := parseDateRange("2026-01-02", "2025-12-31", loc); err == nil {
+		t.Error("end before start accepted")
+	}
+}
+
+func TestStatsTimezone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.App.Timezone = "Asia/Singapore"
+	settings := config.NewStore(cfg)
+
+	tests := []struct {
+		query      string
+		wantZone   string
+		wantStatus int
+	}{
+		{"", "Asia/Singapore", http.StatusOK},
+		{"?tz=Europe/Berlin", "Europe/Berlin", http.StatusOK},
+		{"?tz=Mars/Olympus", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/stats"+tt.query, nil)
+
+		loc, ok := statsTimezone(c, settings)
+		if tt.wantStatus != http.StatusOK {
+			if ok || w.Code != tt.wantStatus {
+				t.Errorf("%q: ok=%v status=%d, want status %d", tt.query, ok, w.Code, tt.wantStatus)
+			}
+			continue
+		}
+		if !ok || loc.String() != tt.wantZone {
+			t.Errorf("%q: loc=%v ok=%v, want %s", tt.query, loc, ok, tt.wantZone)
+		}
+	}
+}
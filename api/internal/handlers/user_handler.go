@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"strconv"
 
@@ -36,6 +37,7 @@ type UpdateUserRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Role     string `json:"role" binding:"required,oneof=admin operator viewer"`
 	Status   string `json:"status" binding:"required,oneof=active inactive"`
+	RowVersion *int `json:"row_version"` // 读取时的数据版本号，未提供 If-Match 请求头时必填
 }
 
 // ChangePasswordRequest 修改密码请求
@@ -43,6 +45,12 @@ type ChangePasswordRequest struct {
 	NewPassword string `json:"new_password" binding:"required,min=6"`
 }
 
+// UpdateNotificationPreferencesRequest 更新任务通知偏好请求
+type UpdateNotificationPreferencesRequest struct {
+	NotifyOnCompletion *bool `json:"notify_on_completion"`
+	NotifyOnFailure    *bool `json:"notify_on_failure"`
+}
+
 // GetCurrentUserProfile 获取当前用户业务信息
 func (h *UserHandler) GetCurrentUserProfile(c *gin.Context) {
 	// 从认证中间件获取 external_id
@@ -62,6 +70,43 @@ func (h *UserHandler) GetCurrentUserProfile(c *gin.Context) {
 	SuccessResponse(c, user)
 }
 
+// UpdateNotificationPreferences 更新当前用户的任务通知偏好
+func (h *UserHandler) UpdateNotificationPreferences(c *gin.Context) {
+	externalID, exists := c.Get("external_id")
+	if !exists {
+		UnauthorizedResponse(c, "未认证")
+		return
+	}
+
+	var req UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, err)
+		return
+	}
+
+	user, err := h.userRepo.GetUserByExternalID(externalID.(string))
+	if err != nil {
+		NotFoundResponse(c, "用户不存在")
+		return
+	}
+
+	// 未提供的字段保持不变
+	if req.NotifyOnCompletion != nil {
+		user.NotifyOnCompletion = *req.NotifyOnCompletion
+	}
+	if req.NotifyOnFailure != nil {
+		user.NotifyOnFailure = *req.NotifyOnFailure
+	}
+
+	if err := h.userRepo.UpdateNotificationPreferences(user.ID, user.NotifyOnCompletion, user.NotifyOnFailure); err != nil {
+		log.Printf("Failed to update notification preferences for user %s: %v", user.ID, err)
+		InternalErrorResponse(c, "更新通知设置失败")
+		return
+	}
+
+	SuccessResponse(c, user)
+}
+
 // ListUsers 获取用户列表
 func (h *UserHandler) ListUsers(c *gin.Context) {
 	// 获取分页参数
@@ -155,6 +200,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		NotFoundResponse(c, "用户不存在")
 		return
 	}
+	setVersionETag(c, user.RowVersion)
 
 	// 直接返回用户信息（敏感字段已过滤）
 	SuccessResponse(c, user)
@@ -180,6 +226,9 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		NotFoundResponse(c, "用户不存在")
 		return
 	}
+	if !checkVersion(c, req.RowVersion, user.RowVersion) {
+		return
+	}
 
 	// 检查用户名是否已被其他用户使用
 	exists, err := h.userRepo.UsernameExists(req.Username, userID)
@@ -212,16 +261,26 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	user.Status = req.Status
 
 	if err := h.userRepo.UpdateUser(user); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			versionConflictResponse(c)
+			return
+		}
+		if errors.Is(err, database.ErrLastAdmin) {
+			BadRequestResponse(c, "不能降级或停用最后一个管理员")
+			return
+		}
 		log.Printf("Failed to update user %s: %v", userID, err)
 		InternalErrorResponse(c, "更新用户失败")
 		return
 	}
+	setVersionETag(c, user.RowVersion)
 
 	userInfo := models.User{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
+		ID:         user.ID,
+		Username:   user.Username,
+		Email:      user.Email,
+		Role:       user.Role,
+		RowVersion: user.RowVersion,
 	}
 
 	log.Printf("User %s updated successfully", user.Username)
@@ -236,10 +295,10 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	// 获取当前操作用户ID
+	// 获取当前操作用户ID（由 LocalUser 中间件根据 external_id 解析）
 	currentUserID, exists := c.Get("user_id")
 	if !exists {
-		UnauthorizedResponse(c, "未找到当前用户信息")
+		ForbiddenResponse(c, "当前账号没有对应的本地用户")
 		return
 	}
 
@@ -256,8 +315,12 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	// 删除用户（软删除）
+	// 删除用户（软删除），不能删除最后一个管理员（与删除在同一事务中检查）
 	if err := h.userRepo.DeleteUser(userID); err != nil {
+		if errors.Is(err, database.ErrLastAdmin) {
+			BadRequestResponse(c, "不能删除最后一个管理员")
+			return
+		}
 		log.Printf("Failed to delete user %s: %v", userID, err)
 		InternalErrorResponse(c, "删除用户失败")
 		return
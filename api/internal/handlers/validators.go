@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"strings"
+
+	"fly-print-cloud/api/internal/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// statusValidators 状态类字段的自定义校验标签，取值范围统一来自 models 包，避免与 oneof 标签不同步
+var statusValidators = map[string]struct {
+	valid  func(string) bool
+	values func() []string
+}{
+	"job_status": {
+		valid:  func(s string) bool { return models.JobStatus(s).IsValid() },
+		values: func() []string { return statusStrings(models.AllJobStatuses) },
+	},
+	"printer_status": {
+		valid:  func(s string) bool { return models.PrinterStatus(s).IsValid() },
+		values: func() []string { return statusStrings(models.AllPrinterStatuses) },
+	},
+	"node_status": {
+		valid:  func(s string) bool { return models.NodeStatus(s).IsValid() },
+		values: func() []string { return statusStrings(models.AllNodeStatuses) },
+	},
+}
+
+// RegisterValidators 向 gin 的校验器注册自定义校验标签，需在路由初始化前调用
+func RegisterValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+	for tag, sv := range statusValidators {
+		valid := sv.valid
+		if err := v.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return valid(fl.Field().String())
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statusStrings 将状态列表转换为字符串列表
+func statusStrings[T ~string](statuses []T) []string {
+	values := make([]string, len(statuses))
+	for i, s := range statuses {
+		values[i] = string(s)
+	}
+	return values
+}
+
+// statusValidatorMessage 自定义状态校验失败时的提示信息
+func statusValidatorMessage(fe validator.FieldError) (string, bool) {
+	sv, ok := statusValidators[fe.Tag()]
+	if !ok {
+		return "", false
+	}
+	return fe.Field() + " 必须是以下值之一: " + strings.Join(sv.values(), ", "), true
+}
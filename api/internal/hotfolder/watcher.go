@@ -0,0 +1,328 @@
+package hotfolder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+)
+
+// 处理后的文件移入共享文件夹下的子目录
+const (
+	DoneDir   = "done"
+	FailedDir = "failed"
+)
+
+// claimStaleAfter 台账中 processing 状态超过该时长的文件视为处理中断（实例在提交过程中退出），
+// 无法确定任务是否已创建，移入 failed 由用户确认后重新放入，避免重复打印
+const claimStaleAfter = 10 * time.Minute
+
+// maxRecentErrors 每个目录保留的最近错误数
+const maxRecentErrors = 20
+
+// ErrRejected 文件不符合要求或提交被拒绝（格式、能力校验、访问策略等），文件移入 failed，不再重试；
+// Submitter 返回的其他错误视为暂时性失败，文件保留在原处，下次扫描时重试
+var ErrRejected = errors.New("hotfolder: file rejected")
+
+// Submitter 将共享文件夹中的文件提交为打印任务，返回任务ID
+type Submitter interface {
+	Submit(ctx context.Context, folder config.HotFolderConfig, fileName string, data []byte) (string, error)
+}
+
+// FileError 最近一次处理失败的文件
+type FileError struct {
+	FileName string    `json:"file_name,omitempty"` // 为空时为目录级错误（如目录不可读）
+	Error    string    `json:"error"`
+	At       time.Time `json:"at"`
+}
+
+// FolderStatus 单个共享文件夹的运行状态（本实例启动以来）
+type FolderStatus struct {
+	Path         string      `json:"path"`
+	PrinterID    string      `json:"printer_id,omitempty"`
+	GroupID      string      `json:"group_id,omitempty"`
+	User         string      `json:"user"`
+	LastScanAt   *time.Time  `json:"last_scan_at,omitempty"`
+	LastError    *FileError  `json:"last_error,omitempty"`
+	Waiting      int         `json:"waiting"`   // 等待写入完成（大小或修改时间仍在变化）的文件数
+	Submitted    int         `json:"submitted"` // 已提交的文件数
+	Failed       int         `json:"failed"`    // 移入 failed 的文件数
+	RecentErrors []FileError `json:"recent_errors"`
+}
+
+// observation 文件上次扫描时的大小和修改时间，since 为首次观察到该状态的时间
+type observation struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+// folderState 单个目录的扫描状态
+type folderState struct {
+	cfg    config.HotFolderConfig
+	seen   map[string]observation
+	status FolderStatus
+}
+
+// Watcher 定期扫描配置的共享文件夹，文件大小和修改时间稳定后提交为打印任务，并按结果移入 done 或 failed 子目录
+// 已处理的文件记录在台账中（hot_folder_files），服务重启或多实例监视同一目录时不会重复提交
+type Watcher struct {
+	cfg       *config.HotFoldersConfig
+	ledger    *database.HotFolderRepository
+	submitter Submitter
+
+	mu      sync.Mutex
+	folders []*folderState
+}
+
+// NewWatcher 创建共享文件夹监视器
+func NewWatcher(cfg *config.HotFoldersConfig, ledger *database.HotFolderRepository, submitter Submitter) *Watcher {
+	w := &Watcher{cfg: cfg, ledger: ledger, submitter: submitter}
+	for _, folder := range cfg.Folders {
+		folder.Path = filepath.Clean(folder.Path)
+		w.folders = append(w.folders, &folderState{
+			cfg:  folder,
+			seen: make(map[string]observation),
+			status: FolderStatus{
+				Path:         folder.Path,
+				PrinterID:    folder.PrinterID,
+				GroupID:      folder.GroupID,
+				User:         folder.User,
+				RecentErrors: []FileError{},
+			},
+		})
+	}
+	return w
+}
+
+// Run 启动共享文件夹监视（阻塞）
+func (w *Watcher) Run() {
+	log.Printf("Hot folder watcher started: %d folder(s), interval=%s, stable_for=%s", len(w.folders), w.cfg.ScanInterval, w.cfg.StableFor)
+
+	ticker := time.NewTicker(w.cfg.ScanInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, folder := range w.folders {
+			w.scan(folder, time.Now())
+		}
+	}
+}
+
+// Status 返回各目录的运行状态
+func (w *Watcher) Status() []FolderStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	statuses := make([]FolderStatus, 0, len(w.folders))
+	for _, folder := range w.folders {
+		status := folder.status
+		status.RecentErrors = append([]FileError{}, folder.status.RecentErrors...)
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// scan 扫描一个目录，处理已写入完成的文件
+func (w *Watcher) scan(folder *folderState, now time.Time) {
+	entries, err := os.ReadDir(folder.cfg.Path)
+	if err != nil {
+		w.recordError(folder, "", fmt.Sprintf("读取目录失败: %v", err))
+		return
+	}
+
+	var ready []os.FileInfo
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		// 子目录（含 done/failed）、隐藏文件和 Office 锁文件不处理
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // 扫描期间被删除或移动
+		}
+		present[name] = true
+
+		// 大小和修改时间在 stable_for 内保持不变才视为写入完成
+		prev, ok := folder.seen[name]
+		if !ok || prev.size != info.Size() || !prev.modTime.Equal(info.ModTime()) {
+			folder.seen[name] = observation{size: info.Size(), modTime: info.ModTime(), since: now}
+			continue
+		}
+		if now.Sub(prev.since) >= w.cfg.StableFor {
+			ready = append(ready, info)
+		}
+	}
+	for name := range folder.seen {
+		if !present[name] {
+			delete(folder.seen, name)
+		}
+	}
+
+	sort.Slice(ready, func(i, j int) bool { return ready[i].ModTime().Before(ready[j].ModTime()) })
+	for _, info := range ready {
+		if w.process(folder, info, now) {
+			delete(folder.seen, info.Name())
+		}
+	}
+
+	w.mu.Lock()
+	folder.status.LastScanAt = &now
+	folder.status.Waiting = len(folder.seen)
+	w.mu.Unlock()
+}
+
+// process 处理一个写入完成的文件，文件已移出目录时返回 true
+func (w *Watcher) process(folder *folderState, info os.FileInfo, now time.Time) bool {
+	name := info.Name()
+	entry, claimed, err := w.ledger.ClaimHotFolderFile(folder.cfg.Path, name, info.Size(), info.ModTime())
+	if err != nil {
+		w.recordError(folder, name, fmt.Sprintf("记录处理台账失败: %v", err))
+		return false
+	}
+
+	// 台账中已有记录：上次处理后未能移动文件（如服务重启），按已记录的结果移动，不重复提交
+	if !claimed {
+		switch {
+		case entry.Status == models.HotFolderFileSubmitted:
+			return w.moveFile(folder, name, DoneDir, "")
+		case entry.Status == models.HotFolderFileFailed:
+			return w.moveFile(folder, name, FailedDir, entry.Error)
+		case now.Sub(entry.UpdatedAt) < claimStaleAfter:
+			return false // 其他实例正在处理
+		}
+		reason := "上次处理在提交过程中中断，无法确认打印任务是否已创建，请检查任务列表后重新放入文件"
+		if err := w.ledger.FinishHotFolderFile(entry.ID, models.HotFolderFileFailed, "", reason); err != nil {
+			w.recordError(folder, name, fmt.Sprintf("记录处理台账失败: %v", err))
+			return false
+		}
+		return w.reject(folder, name, reason)
+	}
+
+	jobID, err := w.submit(folder, name, info)
+	if err != nil {
+		if !errors.Is(err, ErrRejected) {
+			// 暂时性失败：释放台账记录，文件保留在原处，下次扫描时重试
+			if releaseErr := w.ledger.ReleaseHotFolderFile(entry.ID); releaseErr != nil {
+				log.Printf("Failed to release hot folder file %s/%s: %v", folder.cfg.Path, name, releaseErr)
+			}
+			w.recordError(folder, name, err.Error())
+			return false
+		}
+		reason := strings.TrimPrefix(err.Error(), ErrRejected.Error()+": ")
+		if err := w.ledger.FinishHotFolderFile(entry.ID, models.HotFolderFileFailed, "", reason); err != nil {
+			log.Printf("Failed to record hot folder file %s/%s as failed: %v", folder.cfg.Path, name, err)
+		}
+		return w.reject(folder, name, reason)
+	}
+
+	if err := w.ledger.FinishHotFolderFile(entry.ID, models.HotFolderFileSubmitted, jobID, ""); err != nil {
+		log.Printf("Failed to record hot folder file %s/%s as submitted: %v", folder.cfg.Path, name, err)
+	}
+	log.Printf("Hot folder job %s created from %s/%s", jobID, folder.cfg.Path, name)
+	w.mu.Lock()
+	folder.status.Submitted++
+	w.mu.Unlock()
+	return w.moveFile(folder, name, DoneDir, "")
+}
+
+// submit 校验扩展名和大小并读取文件，提交为打印任务
+func (w *Watcher) submit(folder *folderState, name string, info os.FileInfo) (string, error) {
+	if !w.extensionAllowed(name) {
+		return "", fmt.Errorf("%w: 不支持的文件类型 %s，允许的扩展名：%s", ErrRejected, filepath.Ext(name), strings.Join(w.cfg.AllowedExtensions, ", "))
+	}
+	if info.Size() > w.cfg.MaxFileSize {
+		return "", fmt.Errorf("%w: 文件超过大小限制（%d 字节）", ErrRejected, w.cfg.MaxFileSize)
+	}
+
+	file, err := os.Open(filepath.Join(folder.cfg.Path, name))
+	if err != nil {
+		return "", fmt.Errorf("读取文件失败: %w", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, w.cfg.MaxFileSize+1))
+	if err != nil {
+		return "", fmt.Errorf("读取文件失败: %w", err)
+	}
+	if int64(len(data)) > w.cfg.MaxFileSize {
+		return "", fmt.Errorf("%w: 文件超过大小限制（%d 字节）", ErrRejected, w.cfg.MaxFileSize)
+	}
+
+	return w.submitter.Submit(context.Background(), folder.cfg, name, data)
+}
+
+// extensionAllowed 文件扩展名是否在允许列表中，列表为空时不限制
+func (w *Watcher) extensionAllowed(name string) bool {
+	if len(w.cfg.AllowedExtensions) == 0 {
+		return true
+	}
+	ext := filepath.Ext(name)
+	for _, allowed := range w.cfg.AllowedExtensions {
+		if strings.EqualFold(ext, "."+strings.TrimPrefix(allowed, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// reject 将文件移入 failed 子目录并写入失败原因
+func (w *Watcher) reject(folder *folderState, name, reason string) bool {
+	w.mu.Lock()
+	folder.status.Failed++
+	w.mu.Unlock()
+	w.recordError(folder, name, reason)
+	return w.moveFile(folder, name, FailedDir, reason)
+}
+
+// moveFile 将文件移入子目录（同名文件已存在时在文件名后追加时间），reason 不为空时写入同名 .error.txt
+func (w *Watcher) moveFile(folder *folderState, name, subdir, reason string) bool {
+	dir := filepath.Join(folder.cfg.Path, subdir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		w.recordError(folder, name, fmt.Sprintf("创建 %s 目录失败: %v", subdir, err))
+		return false
+	}
+
+	target := filepath.Join(dir, name)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(name)
+		target = filepath.Join(dir, fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), time.Now().Format("20060102T150405.000"), ext))
+	}
+	if err := os.Rename(filepath.Join(folder.cfg.Path, name), target); err != nil {
+		w.recordError(folder, name, fmt.Sprintf("移动文件到 %s 目录失败: %v", subdir, err))
+		return false
+	}
+
+	if reason != "" {
+		if err := os.WriteFile(target+".error.txt", []byte(reason+"\n"), 0644); err != nil {
+			log.Printf("Failed to write hot folder error file for %s: %v", target, err)
+		}
+	}
+	return true
+}
+
+// recordError 记录目录或文件的处理错误，供管理接口查看
+func (w *Watcher) recordError(folder *folderState, name, message string) {
+	log.Printf("Hot folder %s: %s: %s", folder.cfg.Path, name, message)
+	fileErr := FileError{FileName: name, Error: message, At: time.Now()}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	folder.status.LastError = &fileErr
+	folder.status.RecentErrors = append(folder.status.RecentErrors, fileErr)
+	if len(folder.status.RecentErrors) > maxRecentErrors {
+		folder.status.RecentErrors = folder.status.RecentErrors[len(folder.status.RecentErrors)-maxRecentErrors:]
+	}
+}
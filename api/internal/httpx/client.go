@@ -0,0 +1,109 @@
+package httpx
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+)
+
+// 默认参数（配置缺省时使用）
+const (
+	defaultTimeout             = 10 * time.Second
+	defaultDialTimeout         = 5 * time.Second
+	defaultTLSHandshakeTimeout = 5 * time.Second
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultUserAgent           = "fly-print-cloud"
+)
+
+// NewClient 创建出站 HTTP 客户端
+// timeout 为单次请求的整体超时（含读取响应体），<= 0 时使用默认值
+func NewClient(cfg *config.HTTPClientConfig, timeout time.Duration) (*http.Client, error) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	client, err := NewStreamingClient(cfg, timeout)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = timeout
+	return client, nil
+}
+
+// NewStreamingClient 创建用于传输大文件的出站 HTTP 客户端
+// 只限制建连、TLS 握手和等待响应头的时长（headerTimeout，<= 0 时使用默认值），不限制读取响应体的总时长，
+// 传输时长由调用方通过 context 控制
+func NewStreamingClient(cfg *config.HTTPClientConfig, headerTimeout time.Duration) (*http.Client, error) {
+	if cfg == nil {
+		cfg = &config.HTTPClientConfig{}
+	}
+	if headerTimeout <= 0 {
+		headerTimeout = defaultTimeout
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   durationOr(cfg.DialTimeout, defaultDialTimeout),
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   durationOr(cfg.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: headerTimeout,
+		IdleConnTimeout:       durationOr(cfg.IdleConnTimeout, defaultIdleConnTimeout),
+		MaxIdleConns:          intOr(cfg.MaxIdleConns, defaultMaxIdleConns),
+		MaxIdleConnsPerHost:   intOr(cfg.MaxIdleConnsPerHost, defaultMaxIdleConnsPerHost),
+		ForceAttemptHTTP2:     true,
+	}
+
+	// 显式配置的代理优先于环境变量
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+
+	return &http.Client{
+		Transport: &userAgentTransport{base: transport, userAgent: userAgent},
+	}, nil
+}
+
+// userAgentTransport 为未设置 User-Agent 的请求补充默认值
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return fallback
+}
+
+func intOr(n, fallback int) int {
+	if n > 0 {
+		return n
+	}
+	return fallback
+}
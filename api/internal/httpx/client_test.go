@@ -0,0 +1,171 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+)
+
+// hangingServer 接受连接后不返回响应头，直到客户端放弃请求
+func hangingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// stalledBodyServer 立即返回响应头和部分响应体，随后停止写出
+func stalledBodyServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "partial")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestClientTimesOutOnHangingServer 服务端不响应时请求在超时后失败，而不是一直阻塞
+func TestClientTimesOutOnHangingServer(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	srv := hangingServer(t)
+
+	newClients := map[string]func() (*http.Client, error){
+		"NewClient":          func() (*http.Client, error) { return NewClient(nil, timeout) },
+		"NewStreamingClient": func() (*http.Client, error) { return NewStreamingClient(nil, timeout) },
+	}
+	for name, newClient := range newClients {
+		t.Run(name, func(t *testing.T) {
+			client, err := newClient()
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			start := time.Now()
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+				t.Fatal("request to hanging server succeeded")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("request returned after %s, want about %s", elapsed, timeout)
+			}
+		})
+	}
+}
+
+// TestClientTimeoutCoversBody NewClient 的超时包含读取响应体；NewStreamingClient 不限制读取响应体
+func TestClientTimeoutCoversBody(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	srv := stalledBodyServer(t)
+
+	client, err := NewClient(nil, timeout)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	start := time.Now()
+	_, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err == nil {
+		t.Fatal("reading a stalled body succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("body read returned after %s, want about %s", elapsed, timeout)
+	}
+
+	streaming, err := NewStreamingClient(nil, timeout)
+	if err != nil {
+		t.Fatalf("NewStreamingClient: %v", err)
+	}
+	if streaming.Timeout != 0 {
+		t.Fatalf("streaming client Timeout = %s, want none", streaming.Timeout)
+	}
+}
+
+func TestClientDefaultTimeout(t *testing.T) {
+	client, err := NewClient(nil, 0)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if client.Timeout != defaultTimeout {
+		t.Fatalf("Timeout = %s, want %s", client.Timeout, defaultTimeout)
+	}
+}
+
+func TestClientUserAgent(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("User-Agent"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		cfg       *config.HTTPClientConfig
+		requestUA string
+		want      string
+	}{
+		{"default", nil, "", defaultUserAgent},
+		{"configured", &config.HTTPClientConfig{UserAgent: "fly-print-cloud/1.2"}, "", "fly-print-cloud/1.2"},
+		{"request overrides", &config.HTTPClientConfig{UserAgent: "fly-print-cloud/1.2"}, "custom/1.0", "custom/1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(tt.cfg, time.Second)
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			if tt.requestUA != "" {
+				req.Header.Set("User-Agent", tt.requestUA)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			resp.Body.Close()
+			if last := got[len(got)-1]; last != tt.want {
+				t.Fatalf("User-Agent = %q, want %q", last, tt.want)
+			}
+		})
+	}
+}
+
+// TestClientProxy 显式配置的代理优先于环境变量
+func TestClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+	t.Setenv("HTTP_PROXY", "http://127.0.0.1:1")
+
+	client, err := NewClient(&config.HTTPClientConfig{ProxyURL: proxy.URL}, time.Second)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	resp, err := client.Get("http://idp.example.com/userinfo")
+	if err != nil {
+		t.Fatalf("get through proxy: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://idp.example.com/userinfo" {
+		t.Fatalf("proxy saw %q", proxied)
+	}
+
+	if _, err := NewClient(&config.HTTPClientConfig{ProxyURL: "http://[::1"}, time.Second); err == nil || !strings.Contains(err.Error(), "invalid proxy url") {
+		t.Fatalf("invalid proxy url = %v, want error", err)
+	}
+}
@@ -0,0 +1,423 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/netaddr"
+)
+
+// RecordType 导入记录类型
+type RecordType string
+
+const (
+	RecordEdgeNode RecordType = "edge_node"
+	RecordPrinter  RecordType = "printer"
+)
+
+// Action 导入行的处理结果
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionError  Action = "error"
+)
+
+// Format 导入文件格式
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+)
+
+// ErrInvalidFile 文件无法解析（表头缺失、未知列、JSON 结构错误等），整个文件被拒绝
+var ErrInvalidFile = errors.New("importer: invalid import file")
+
+// CSVHeaders CSV 模板表头；record_type 为 edge_node 或 printer，printer 行的 node_id 为所属 Edge Node
+var CSVHeaders = []string{
+	"record_type", "node_id", "name", "display_name", "model", "serial_number",
+	"location", "latitude", "longitude", "ip_address", "mac_address", "enabled",
+}
+
+// requiredHeaders CSV 必须包含的列
+var requiredHeaders = []string{"record_type", "node_id", "name"}
+
+// Record 一条导入记录（CSV 行或 JSON 对象），空值表示不修改已有数据
+type Record struct {
+	NodeID       string   `json:"node_id"`
+	Name         string   `json:"name"`
+	DisplayName  string   `json:"display_name,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	SerialNumber string   `json:"serial_number,omitempty"`
+	Location     string   `json:"location,omitempty"`
+	Latitude     *float64 `json:"latitude,omitempty"`
+	Longitude    *float64 `json:"longitude,omitempty"`
+	IPAddress    string   `json:"ip_address,omitempty"`
+	MACAddress   string   `json:"mac_address,omitempty"`
+	Enabled      *bool    `json:"enabled,omitempty"`
+}
+
+// Document JSON 导入文件结构
+type Document struct {
+	EdgeNodes []Record `json:"edge_nodes"`
+	Printers  []Record `json:"printers"`
+}
+
+// Row 导入行及其处理结果
+// CSV 的 Row 为文件中的行号（表头为第 1 行），JSON 的 Row 为该记录在所属数组中的序号（从 1 开始）
+type Row struct {
+	Row        int        `json:"row"`
+	RecordType RecordType `json:"record_type"`
+	Key        string     `json:"key"` // Edge Node 为 node_id，打印机为 node_id/name
+	Action     Action     `json:"action"`
+	Errors     []string   `json:"errors,omitempty"`
+
+	Record Record `json:"-"`
+}
+
+// Fail 记录行错误
+func (r *Row) Fail(format string, args ...interface{}) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	r.Action = ActionError
+}
+
+// Valid 行是否通过校验
+func (r *Row) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// EdgeNode 转换为 Edge Node 模型
+func (r *Row) EdgeNode() *models.EdgeNode {
+	return &models.EdgeNode{
+		ID:        r.Record.NodeID,
+		Name:      r.Record.Name,
+		Status:    models.NodeStatusOffline,
+		Enabled:   r.Record.Enabled == nil || *r.Record.Enabled,
+		Location:  optional(r.Record.Location),
+		Latitude:  r.Record.Latitude,
+		Longitude: r.Record.Longitude,
+		IPAddress: optional(r.Record.IPAddress),
+	}
+}
+
+// Printer 转换为打印机模型
+func (r *Row) Printer() *models.Printer {
+	return &models.Printer{
+		Name:         r.Record.Name,
+		DisplayName:  r.Record.DisplayName,
+		Model:        r.Record.Model,
+		SerialNumber: optional(r.Record.SerialNumber),
+		Status:       models.PrinterStatusOffline,
+		Enabled:      r.Record.Enabled == nil || *r.Record.Enabled,
+		IPAddress:    optional(r.Record.IPAddress),
+		MACAddress:   optional(r.Record.MACAddress),
+		Latitude:     r.Record.Latitude,
+		Longitude:    r.Record.Longitude,
+		Location:     optional(r.Record.Location),
+		EdgeNodeID:   r.Record.NodeID,
+	}
+}
+
+// PrinterKey 打印机去重键（名称在同一 Edge Node 下唯一）
+func PrinterKey(nodeID, name string) string {
+	return nodeID + "/" + name
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// DetectFormat 根据文件名或 Content-Type 判断文件格式，无法判断时按内容首字符推断
+func DetectFormat(fileName, contentType string, head []byte) Format {
+	name := strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(name, ".json"), strings.Contains(contentType, "json"):
+		return FormatJSON
+	case strings.HasSuffix(name, ".csv"), strings.Contains(contentType, "csv"):
+		return FormatCSV
+	}
+	if trimmed := bytes.TrimSpace(head); len(trimmed) > 0 && trimmed[0] == '{' {
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+// Parse 解析导入文件并校验每一行；Edge Node 行排在打印机行之前
+func Parse(format Format, r io.Reader) ([]*Row, error) {
+	var rows []*Row
+	var err error
+	if format == FormatJSON {
+		rows, err = parseJSON(r)
+	} else {
+		rows, err = parseCSV(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	validate(rows)
+	return rows, nil
+}
+
+// parseJSON 解析 JSON 导入文件
+func parseJSON(r io.Reader) ([]*Row, error) {
+	var doc Document
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	rows := make([]*Row, 0, len(doc.EdgeNodes)+len(doc.Printers))
+	for i, record := range doc.EdgeNodes {
+		rows = append(rows, &Row{Row: i + 1, RecordType: RecordEdgeNode, Record: record})
+	}
+	for i, record := range doc.Printers {
+		rows = append(rows, &Row{Row: i + 1, RecordType: RecordPrinter, Record: record})
+	}
+	return rows, nil
+}
+
+// parseCSV 解析 CSV 导入文件（首行为表头，列顺序不限）
+func parseCSV(r io.Reader) ([]*Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: 文件为空", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !knownHeader(name) {
+			return nil, fmt.Errorf("%w: 未知的列 %q", ErrInvalidFile, name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredHeaders {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: 缺少列 %q", ErrInvalidFile, name)
+		}
+	}
+
+	var nodes, printers []*Row
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		value := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		if isBlank(fields) {
+			continue
+		}
+
+		row := &Row{Row: line, RecordType: RecordType(strings.ToLower(value("record_type")))}
+		row.Record = Record{
+			NodeID:       value("node_id"),
+			Name:         value("name"),
+			DisplayName:  value("display_name"),
+			Model:        value("model"),
+			SerialNumber: value("serial_number"),
+			Location:     value("location"),
+			IPAddress:    value("ip_address"),
+			MACAddress:   value("mac_address"),
+		}
+		row.Record.Latitude = parseFloat(row, "latitude", value("latitude"))
+		row.Record.Longitude = parseFloat(row, "longitude", value("longitude"))
+		row.Record.Enabled = parseBool(row, "enabled", value("enabled"))
+
+		switch row.RecordType {
+		case RecordEdgeNode:
+			nodes = append(nodes, row)
+		case RecordPrinter:
+			printers = append(printers, row)
+		default:
+			row.Fail("record_type 必须为 %s 或 %s", RecordEdgeNode, RecordPrinter)
+			printers = append(printers, row)
+		}
+	}
+
+	return append(nodes, printers...), nil
+}
+
+func knownHeader(name string) bool {
+	for _, header := range CSVHeaders {
+		if header == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlank(fields []string) bool {
+	for _, field := range fields {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseFloat(row *Row, column, value string) *float64 {
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		row.Fail("%s: 无效的数字 %q", column, value)
+		return nil
+	}
+	return &parsed
+}
+
+func parseBool(row *Row, column, value string) *bool {
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(strings.ToLower(value))
+	if err != nil {
+		row.Fail("%s: 无效的布尔值 %q", column, value)
+		return nil
+	}
+	return &parsed
+}
+
+// validate 校验字段并检测文件内的重复记录
+func validate(rows []*Row) {
+	seen := make(map[string]int)
+	for _, row := range rows {
+		record := &row.Record
+		switch row.RecordType {
+		case RecordEdgeNode:
+			row.Key = record.NodeID
+			validateLength(row, "node_id", record.NodeID, 1, 100)
+			if record.DisplayName != "" || record.Model != "" || record.SerialNumber != "" || record.MACAddress != "" {
+				row.Fail("display_name、model、serial_number、mac_address 仅适用于打印机")
+			}
+		case RecordPrinter:
+			row.Key = PrinterKey(record.NodeID, record.Name)
+			validateLength(row, "node_id", record.NodeID, 1, 100)
+			validateLength(row, "display_name", record.DisplayName, 0, 255)
+			validateLength(row, "model", record.Model, 0, 100)
+			validateLength(row, "serial_number", record.SerialNumber, 0, 100)
+			if mac, err := netaddr.NormalizeMAC(record.MACAddress); err != nil {
+				row.Fail("mac_address: 无效的 MAC 地址 %q", record.MACAddress)
+			} else {
+				record.MACAddress = mac
+			}
+		default:
+			continue
+		}
+
+		validateLength(row, "name", record.Name, 1, 100)
+		validateLength(row, "location", record.Location, 0, 255)
+		if record.Latitude != nil && (*record.Latitude < -90 || *record.Latitude > 90) {
+			row.Fail("latitude: 必须在 -90 到 90 之间")
+		}
+		if record.Longitude != nil && (*record.Longitude < -180 || *record.Longitude > 180) {
+			row.Fail("longitude: 必须在 -180 到 180 之间")
+		}
+		if ip, err := netaddr.NormalizeIP(record.IPAddress); err != nil {
+			row.Fail("ip_address: 无效的 IP 地址 %q", record.IPAddress)
+		} else {
+			record.IPAddress = ip
+		}
+
+		dedupKey := string(row.RecordType) + ":" + row.Key
+		if first, ok := seen[dedupKey]; ok {
+			row.Fail("与第 %d 行重复", first)
+			continue
+		}
+		seen[dedupKey] = row.Row
+	}
+}
+
+func validateLength(row *Row, column, value string, min, max int) {
+	length := len([]rune(value))
+	if length < min {
+		row.Fail("%s: 必填", column)
+	} else if length > max {
+		row.Fail("%s: 不能超过 %d 个字符", column, max)
+	}
+}
+
+// Summary 导入结果统计
+type Summary struct {
+	Total   int `json:"total"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Failed  int `json:"failed"`
+}
+
+// Summarize 统计各行的处理结果
+func Summarize(rows []*Row) Summary {
+	summary := Summary{Total: len(rows)}
+	for _, row := range rows {
+		switch row.Action {
+		case ActionCreate:
+			summary.Created++
+		case ActionUpdate:
+			summary.Updated++
+		default:
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// CSVTemplate CSV 导入模板（表头及示例行）
+func CSVTemplate() []byte {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(CSVHeaders)
+	writer.Write([]string{"edge_node", "edge-office-1", "Office Edge Node", "", "", "", "Building A, Floor 3", "31.2304", "121.4737", "", "", "true"})
+	writer.Write([]string{"printer", "edge-office-1", "HP_LaserJet_M404", "3F Copy Room", "HP LaserJet Pro M404dn", "VNB3K12345", "3F Copy Room", "", "", "192.168.1.50", "00:1A:2B:3C:4D:5E", "true"})
+	writer.Flush()
+	return buf.Bytes()
+}
+
+// JSONTemplate JSON 导入模板
+func JSONTemplate() []byte {
+	lat, lng, enabled := 31.2304, 121.4737, true
+	doc := Document{
+		EdgeNodes: []Record{{
+			NodeID: "edge-office-1", Name: "Office Edge Node", Location: "Building A, Floor 3",
+			Latitude: &lat, Longitude: &lng, Enabled: &enabled,
+		}},
+		Printers: []Record{{
+			NodeID: "edge-office-1", Name: "HP_LaserJet_M404", DisplayName: "3F Copy Room",
+			Model: "HP LaserJet Pro M404dn", SerialNumber: "VNB3K12345", Location: "3F Copy Room",
+			IPAddress: "192.168.1.50", MACAddress: "00:1A:2B:3C:4D:5E", Enabled: &enabled,
+		}},
+	}
+	data, _ := json.MarshalIndent(doc, "", "  ")
+	return data
+}
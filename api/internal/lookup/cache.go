@@ -0,0 +1,181 @@
+package lookup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+)
+
+// keyPrefix 缓存键前缀，键格式为 fly-print:lookup:<kind>:<id>
+const keyPrefix = "fly-print:lookup:"
+
+// storeTimeout 单次缓存读写的超时时间，缓存不可用时直接查询数据库，不拖慢任务分发
+const storeTimeout = 200 * time.Millisecond
+
+// kindCounters 单类查询的命中统计
+type kindCounters struct {
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	errors        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+// Cache 打印机和 Edge Node 查询的读穿透缓存（实现 database.LookupCache）
+// 缓存 JSON 编码的结果，每次读取都解码出新的对象，调用方可以随意修改；只缓存查询成功的结果
+type Cache struct {
+	store Store
+	ttl   time.Duration
+
+	mu       sync.Mutex
+	counters map[string]*kindCounters
+}
+
+// New 按 lookup_cache 配置创建缓存，redis 后端连接失败时返回错误
+func New(cfg *config.LookupCacheConfig, redisCfg *config.RedisConfig) (*Cache, error) {
+	var store Store
+	switch cfg.Backend {
+	case "redis":
+		redisStore, err := NewRedisStore(redisCfg)
+		if err != nil {
+			return nil, err
+		}
+		store = redisStore
+	default:
+		store = NewLocalStore(cfg.MaxEntries)
+	}
+	return NewCache(store, cfg.TTL), nil
+}
+
+// NewCache 使用指定存储创建缓存
+func NewCache(store Store, ttl time.Duration) *Cache {
+	return &Cache{store: store, ttl: ttl, counters: make(map[string]*kindCounters)}
+}
+
+// key 缓存键
+func key(kind, id string) string {
+	return keyPrefix + kind + ":" + id
+}
+
+// countersFor 获取某类查询的统计
+func (c *Cache) countersFor(kind string) *kindCounters {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counters, ok := c.counters[kind]
+	if !ok {
+		counters = &kindCounters{}
+		c.counters[kind] = counters
+	}
+	return counters
+}
+
+// Fetch 读取缓存并解码到 dest；未命中时调用 load 查询，成功后写入缓存再解码到 dest
+// 缓存读写失败时记录日志并按未命中处理，load 的错误原样返回
+func (c *Cache) Fetch(kind, id string, dest interface{}, load func() (interface{}, error)) error {
+	counters := c.countersFor(kind)
+	k := key(kind, id)
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	value, ok, err := c.store.Get(ctx, k)
+	cancel()
+	if err != nil {
+		counters.errors.Add(1)
+		log.Printf("Lookup cache read failed for %s: %v", k, err)
+	}
+	if ok {
+		if err := json.Unmarshal(value, dest); err == nil {
+			counters.hits.Add(1)
+			return nil
+		}
+		counters.errors.Add(1)
+	}
+	counters.misses.Add(1)
+
+	loaded, err := load()
+	if err != nil {
+		return err
+	}
+	value, err = json.Marshal(loaded)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s for lookup cache: %w", kind, err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), storeTimeout)
+	if err := c.store.Set(ctx, k, value, c.ttl); err != nil {
+		counters.errors.Add(1)
+		log.Printf("Lookup cache write failed for %s: %v", k, err)
+	}
+	cancel()
+	return json.Unmarshal(value, dest)
+}
+
+// Invalidate 清除指定对象的缓存（写操作提交后调用）
+func (c *Cache) Invalidate(kind string, ids ...string) {
+	if len(ids) == 0 {
+		return
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = key(kind, id)
+	}
+	counters := c.countersFor(kind)
+	counters.invalidations.Add(uint64(len(ids)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := c.store.Delete(ctx, keys...); err != nil {
+		counters.errors.Add(1)
+		log.Printf("Lookup cache invalidation failed for %s %v, stale entries expire within %s: %v", kind, ids, c.ttl, err)
+	}
+}
+
+// InvalidateKind 清除某类对象的全部缓存（按节点批量修改打印机等无法逐个列出的变更）
+func (c *Cache) InvalidateKind(kind string) {
+	counters := c.countersFor(kind)
+	counters.invalidations.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.store.DeletePrefix(ctx, key(kind, "")); err != nil {
+		counters.errors.Add(1)
+		log.Printf("Lookup cache invalidation failed for all %s entries, stale entries expire within %s: %v", kind, c.ttl, err)
+	}
+}
+
+// WriteMetrics 以 Prometheus 文本格式写入各类查询的命中、未命中、错误和清除次数（由 /metrics 与其他指标合并输出）
+// 未启用缓存（c 为 nil）时不输出
+func (c *Cache) WriteMetrics(b *strings.Builder) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	kinds := make([]string, 0, len(c.counters))
+	for kind := range c.counters {
+		kinds = append(kinds, kind)
+	}
+	c.mu.Unlock()
+	sort.Strings(kinds)
+
+	metrics := []struct {
+		name, help string
+		value      func(*kindCounters) uint64
+	}{
+		{"fly_print_lookup_cache_hits_total", "Printer and edge node lookups served from the cache.", func(k *kindCounters) uint64 { return k.hits.Load() }},
+		{"fly_print_lookup_cache_misses_total", "Printer and edge node lookups that queried the database.", func(k *kindCounters) uint64 { return k.misses.Load() }},
+		{"fly_print_lookup_cache_errors_total", "Lookup cache read, write or invalidation failures.", func(k *kindCounters) uint64 { return k.errors.Load() }},
+		{"fly_print_lookup_cache_invalidations_total", "Lookup cache entries invalidated by writes.", func(k *kindCounters) uint64 { return k.invalidations.Load() }},
+	}
+	for _, metric := range metrics {
+		fmt.Fprintf(b, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(b, "# TYPE %s counter\n", metric.name)
+		for _, kind := range kinds {
+			fmt.Fprintf(b, "%s{kind=%q} %d\n", metric.name, kind, metric.value(c.countersFor(kind)))
+		}
+	}
+}
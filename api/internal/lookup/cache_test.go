@@ -0,0 +1,240 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingStore 读写都失败的存储，模拟 Redis 不可用
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+func (failingStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("connection refused")
+}
+func (failingStore) Delete(context.Context, ...string) error { return errors.New("connection refused") }
+func (failingStore) DeletePrefix(context.Context, string) error {
+	return errors.New("connection refused")
+}
+
+type testPrinter struct {
+	ID      string `json:"id"`
+	Enabled bool   `json:"enabled"`
+}
+
+// countingLoader 返回 printer 当前值的加载函数，并记录调用次数
+func countingLoader(printer *testPrinter, calls *int) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		*calls++
+		loaded := *printer
+		return &loaded, nil
+	}
+}
+
+func TestLocalStoreExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	store := NewLocalStore(10)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	store.Set(ctx, "a", []byte("1"), 15*time.Second)
+	now = now.Add(14 * time.Second)
+	if _, ok, _ := store.Get(ctx, "a"); !ok {
+		t.Fatal("entry expired before its ttl")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := store.Get(ctx, "a"); ok {
+		t.Fatal("entry served after its ttl")
+	}
+	if len(store.entries) != 0 {
+		t.Fatalf("expired entry kept: %v", store.entries)
+	}
+}
+
+func TestLocalStoreBounded(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	store := NewLocalStore(3)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	store.Set(ctx, "old", []byte("1"), time.Second)
+	store.Set(ctx, "b", []byte("2"), time.Minute)
+	store.Set(ctx, "c", []byte("3"), time.Minute)
+	now = now.Add(2 * time.Second)
+
+	// 已满时先清理过期条目，不淘汰有效条目
+	store.Set(ctx, "d", []byte("4"), time.Minute)
+	for _, key := range []string{"b", "c", "d"} {
+		if _, ok, _ := store.Get(ctx, key); !ok {
+			t.Fatalf("live entry %s evicted while an expired one was available", key)
+		}
+	}
+
+	// 仍已满时淘汰任意一条，条数不超过上限
+	for i := 0; i < 20; i++ {
+		store.Set(ctx, strings.Repeat("k", i+1), []byte("v"), time.Minute)
+		if len(store.entries) > 3 {
+			t.Fatalf("%d entries, want at most 3", len(store.entries))
+		}
+	}
+	// 覆盖已存在的键不淘汰其他条目
+	before := len(store.entries)
+	store.Set(ctx, "k", []byte("new"), time.Minute)
+	if len(store.entries) != before {
+		t.Fatalf("overwrite changed size from %d to %d", before, len(store.entries))
+	}
+}
+
+func TestLocalStoreDeletePrefix(t *testing.T) {
+	store := NewLocalStore(10)
+	ctx := context.Background()
+	store.Set(ctx, key("printer", "p1"), []byte("1"), time.Minute)
+	store.Set(ctx, key("printer", "p2"), []byte("2"), time.Minute)
+	store.Set(ctx, key("edge_node", "n1"), []byte("3"), time.Minute)
+
+	store.DeletePrefix(ctx, key("printer", ""))
+	if len(store.entries) != 1 {
+		t.Fatalf("entries after prefix delete = %v", store.entries)
+	}
+	if _, ok, _ := store.Get(ctx, key("edge_node", "n1")); !ok {
+		t.Fatal("other kind removed by prefix delete")
+	}
+}
+
+// TestCacheInvalidation 写操作清除缓存后，下一次查询立即读到新值（禁用打印机在下一次分发前生效）
+func TestCacheInvalidation(t *testing.T) {
+	cache := NewCache(NewLocalStore(100), time.Minute)
+	printer := &testPrinter{ID: "p1", Enabled: true}
+	calls := 0
+	load := countingLoader(printer, &calls)
+
+	var got testPrinter
+	for i := 0; i < 3; i++ {
+		if err := cache.Fetch("printer", "p1", &got, load); err != nil || !got.Enabled {
+			t.Fatalf("fetch %d = %+v, %v", i, got, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("loader called %d times, want 1", calls)
+	}
+
+	// 未清除缓存时仍返回旧值，最多延迟一个 TTL
+	printer.Enabled = false
+	cache.Fetch("printer", "p1", &got, load)
+	if !got.Enabled {
+		t.Fatal("cache reloaded without invalidation")
+	}
+
+	cache.Invalidate("printer", "p1")
+	got = testPrinter{}
+	if err := cache.Fetch("printer", "p1", &got, load); err != nil || got.Enabled {
+		t.Fatalf("fetch after invalidation = %+v, %v; want the disabled printer", got, err)
+	}
+	if calls != 2 {
+		t.Fatalf("loader called %d times, want 2", calls)
+	}
+
+	printer.Enabled = true
+	cache.InvalidateKind("printer")
+	cache.Fetch("printer", "p1", &got, load)
+	if !got.Enabled || calls != 3 {
+		t.Fatalf("fetch after kind invalidation = %+v with %d loads", got, calls)
+	}
+}
+
+// TestCacheReturnsCopies 每次读取都解码出新对象，调用方修改结果不影响缓存
+func TestCacheReturnsCopies(t *testing.T) {
+	cache := NewCache(NewLocalStore(100), time.Minute)
+	calls := 0
+	load := countingLoader(&testPrinter{ID: "p1", Enabled: true}, &calls)
+
+	var first testPrinter
+	cache.Fetch("printer", "p1", &first, load)
+	first.Enabled = false
+	var second testPrinter
+	cache.Fetch("printer", "p1", &second, load)
+	if !second.Enabled || calls != 1 {
+		t.Fatalf("second fetch = %+v after %d loads; want the cached value", second, calls)
+	}
+}
+
+// TestCacheLoadErrorNotCached 查询失败不写入缓存，错误原样返回
+func TestCacheLoadErrorNotCached(t *testing.T) {
+	cache := NewCache(NewLocalStore(100), time.Minute)
+	notFound := errors.New("printer not found")
+	calls := 0
+	load := func() (interface{}, error) {
+		calls++
+		return nil, notFound
+	}
+
+	var got testPrinter
+	for i := 0; i < 2; i++ {
+		if err := cache.Fetch("printer", "missing", &got, load); !errors.Is(err, notFound) {
+			t.Fatalf("fetch = %v, want the loader error", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("loader called %d times, want 2", calls)
+	}
+}
+
+// TestCacheStoreFailure 存储不可用时直接查询数据库，并计入错误
+func TestCacheStoreFailure(t *testing.T) {
+	cache := NewCache(failingStore{}, time.Minute)
+	calls := 0
+	load := countingLoader(&testPrinter{ID: "p1", Enabled: true}, &calls)
+
+	var got testPrinter
+	for i := 0; i < 2; i++ {
+		if err := cache.Fetch("printer", "p1", &got, load); err != nil || !got.Enabled {
+			t.Fatalf("fetch = %+v, %v", got, err)
+		}
+	}
+	cache.Invalidate("printer", "p1")
+	if calls != 2 {
+		t.Fatalf("loader called %d times, want 2", calls)
+	}
+	counters := cache.countersFor("printer")
+	if counters.hits.Load() != 0 || counters.misses.Load() != 2 || counters.errors.Load() != 5 {
+		t.Fatalf("hits %d, misses %d, errors %d; want 0, 2, 5",
+			counters.hits.Load(), counters.misses.Load(), counters.errors.Load())
+	}
+}
+
+func TestCacheMetrics(t *testing.T) {
+	var disabled *Cache
+	var b strings.Builder
+	disabled.WriteMetrics(&b)
+	if b.Len() != 0 {
+		t.Fatalf("disabled cache wrote metrics: %q", b.String())
+	}
+
+	cache := NewCache(NewLocalStore(100), time.Minute)
+	calls := 0
+	var printer testPrinter
+	load := countingLoader(&testPrinter{ID: "p1"}, &calls)
+	cache.Fetch("printer", "p1", &printer, load)
+	cache.Fetch("printer", "p1", &printer, load)
+	cache.Fetch("printer", "p1", &printer, load)
+	cache.Invalidate("edge_node", "n1", "n2")
+
+	cache.WriteMetrics(&b)
+	for _, line := range []string{
+		`fly_print_lookup_cache_hits_total{kind="printer"} 2`,
+		`fly_print_lookup_cache_misses_total{kind="printer"} 1`,
+		`fly_print_lookup_cache_errors_total{kind="printer"} 0`,
+		`fly_print_lookup_cache_invalidations_total{kind="edge_node"} 2`,
+		`fly_print_lookup_cache_hits_total{kind="edge_node"} 0`,
+		"# TYPE fly_print_lookup_cache_misses_total counter",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("metrics missing %q:\n%s", line, b.String())
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisScanCount DeletePrefix 每次 SCAN 的键数
+const redisScanCount = 500
+
+// RedisStore 基于 Redis 的缓存，多个副本共享，任一副本清除缓存对所有副本生效
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore 连接 Redis 并检查可用性
+func NewRedisStore(cfg *config.RedisConfig) (*RedisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return &RedisStore{client: client}, nil
+}
+
+// Get 读取条目
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set 写入条目
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+// Delete 删除条目
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
+
+// DeletePrefix 删除键以 prefix 开头的全部条目（SCAN 遍历，仅用于节点下线等批量变更）
+func (s *RedisStore) DeletePrefix(ctx context.Context, prefix string) error {
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, prefix+"*", redisScanCount).Result()
+		if err != nil {
+			return err
+		}
+		if err := s.Delete(ctx, keys...); err != nil {
+			return err
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Close 关闭 Redis 连接
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
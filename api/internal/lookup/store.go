@@ -0,0 +1,94 @@
+package lookup
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store 缓存存储，值为 JSON 编码的查询结果
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// localEntry 进程内缓存条目
+type localEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// LocalStore 进程内缓存，条数达到上限时先清理已过期条目，仍已满时淘汰任意一条
+type LocalStore struct {
+	mu         sync.Mutex
+	entries    map[string]localEntry
+	maxEntries int
+	now        func() time.Time
+}
+
+// NewLocalStore 创建进程内缓存
+func NewLocalStore(maxEntries int) *LocalStore {
+	return &LocalStore{entries: make(map[string]localEntry), maxEntries: maxEntries, now: time.Now}
+}
+
+// Get 读取未过期的条目
+func (s *LocalStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !s.now().Before(entry.expiresAt) {
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Set 写入条目
+func (s *LocalStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if _, exists := s.entries[key]; !exists && len(s.entries) >= s.maxEntries {
+		for k, entry := range s.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		for k := range s.entries {
+			if len(s.entries) < s.maxEntries {
+				break
+			}
+			delete(s.entries, k)
+		}
+	}
+	s.entries[key] = localEntry{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// Delete 删除条目
+func (s *LocalStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// DeletePrefix 删除键以 prefix 开头的全部条目
+func (s *LocalStore) DeletePrefix(_ context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
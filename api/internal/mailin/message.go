@@ -0,0 +1,190 @@
+package mailin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+)
+
+// maxPartDepth multipart 嵌套层数上限
+const maxPartDepth = 10
+
+// ErrNoSender 邮件没有可解析的发件人
+var ErrNoSender = errors.New("mailin: message has no sender")
+
+// Attachment 邮件附件（已解码）
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message 解析后的入站邮件
+type Message struct {
+	From        string   // 发件人地址（小写）
+	Recipients  []string // 收件地址（小写）：Delivered-To、X-Original-To、To、Cc
+	Subject     string
+	MessageID   string
+	Attachments []Attachment // Content-Disposition 为 attachment 或带文件名的非文本部分；正文和内嵌图片不计入
+}
+
+var wordDecoder = &mime.WordDecoder{}
+
+// Parse 解析 RFC 822 邮件
+func Parse(r io.Reader) (*Message, error) {
+	raw, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("mailin: invalid message: %w", err)
+	}
+
+	msg := &Message{
+		Subject:   decodeHeader(raw.Header.Get("Subject")),
+		MessageID: strings.TrimSpace(raw.Header.Get("Message-Id")),
+	}
+
+	from, err := raw.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, ErrNoSender
+	}
+	msg.From = strings.ToLower(from[0].Address)
+
+	seen := map[string]bool{}
+	for _, key := range []string{"Delivered-To", "X-Original-To", "To", "Cc"} {
+		addresses, err := raw.Header.AddressList(key)
+		if err != nil {
+			continue
+		}
+		for _, address := range addresses {
+			recipient := strings.ToLower(address.Address)
+			if !seen[recipient] {
+				seen[recipient] = true
+				msg.Recipients = append(msg.Recipients, recipient)
+			}
+		}
+	}
+
+	if err := msg.walk(raw.Header, raw.Body, 0); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// partHeader 邮件头（mail.Header）和 multipart 部分头（textproto.MIMEHeader）的公共读取接口
+type partHeader interface {
+	Get(key string) string
+}
+
+// walk 递归遍历邮件正文，收集附件
+func (m *Message) walk(header partHeader, body io.Reader, depth int) error {
+	if depth > maxPartDepth {
+		return fmt.Errorf("mailin: multipart nested too deeply")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		boundary := params["boundary"]
+		if boundary == "" {
+			return fmt.Errorf("mailin: multipart without boundary")
+		}
+		reader := multipart.NewReader(body, boundary)
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("mailin: invalid multipart body: %w", err)
+			}
+			if err := m.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	// message/rfc822（转发的邮件）不展开，避免打印被转发邮件里的附件
+	filename, attachment := attachmentName(header, params)
+	if !attachment {
+		return nil
+	}
+
+	data, err := io.ReadAll(decodeBody(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("mailin: failed to decode attachment %q: %w", filename, err)
+	}
+	m.Attachments = append(m.Attachments, Attachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		Data:        data,
+	})
+	return nil
+}
+
+// attachmentName 判断部分是否为附件并返回文件名
+// disposition 为 attachment 时总是附件；inline 或未声明时，只有带文件名的非文本、非图片部分视为附件（排除签名中的内嵌图片）
+func attachmentName(header partHeader, contentParams map[string]string) (string, bool) {
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = contentParams["name"]
+	}
+	filename = decodeHeader(filename)
+
+	if disposition == "attachment" {
+		if filename == "" {
+			filename = "attachment"
+		}
+		return filename, true
+	}
+	if filename == "" {
+		return "", false
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "text/") || strings.HasPrefix(mediaType, "image/") || mediaType == "message/rfc822" {
+		return "", false
+	}
+	return filename, true
+}
+
+// decodeBody 按 Content-Transfer-Encoding 解码
+func decodeBody(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// decodeHeader 解码 RFC 2047 编码的头部值，无法解码时原样返回
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// ReadBody 从 webhook 请求中读取原始邮件，超过 maxSize 时返回错误
+func ReadBody(r io.Reader, maxSize int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("mailin: message exceeds %d bytes", maxSize)
+	}
+	return bytes.TrimLeft(data, "\r\n"), nil
+}
@@ -0,0 +1,161 @@
+package mailin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseFixture 解析 testdata 下的样例邮件
+func parseFixture(t *testing.T, name string) (*Message, error) {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+func TestParseFixtures(t *testing.T) {
+	type attachment struct {
+		filename    string
+		contentType string
+		prefix      string
+	}
+	tests := []struct {
+		fixture     string
+		from        string
+		recipients  []string
+		subject     string
+		messageID   string
+		attachments []attachment
+	}{
+		{
+			// Gmail：multipart/alternative 正文 + base64 PDF 附件，To 与 Delivered-To 大小写不同需去重
+			fixture:     "gmail_pdf.eml",
+			from:        "alice.chen@example.com",
+			recipients:  []string{"printer-lobby@print.example.com"},
+			subject:     "月度报告",
+			messageID:   "<CAF1abc123@mail.gmail.com>",
+			attachments: []attachment{{"report.pdf", "application/pdf", "%PDF-1.4"}},
+		},
+		{
+			// Outlook：签名中的内嵌图片不计入附件，RFC 2047 编码的文件名需解码
+			fixture:     "outlook_inline_image.eml",
+			from:        "jonas.brandt@example.de",
+			recipients:  []string{"group-finance@print.example.com", "printer-lobby@print.example.com"},
+			subject:     "Bericht für März",
+			messageID:   "<AM0PR01MB1234@AM0PR01MB1234.eurprd01.prod.outlook.com>",
+			attachments: []attachment{{"Bericht über März.pdf", "application/pdf", "%PDF-1.4"}},
+		},
+		{
+			// 转发的邮件（message/rfc822）不展开，其中的附件不打印
+			fixture:    "forwarded.eml",
+			from:       "bob@example.com",
+			recipients: []string{"printer-lobby@print.example.com"},
+			subject:    "Fwd: invoice",
+		},
+		{
+			// CRLF 换行、无引号 boundary、quoted-printable 编码的文本附件
+			fixture:     "crlf_quoted_printable.eml",
+			from:        "carol@example.com",
+			recipients:  []string{"printer-3f@print.example.com"},
+			subject:     "notes",
+			attachments: []attachment{{"notes.txt", "text/plain", "Line one\nprice = 5€"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			msg, err := parseFixture(t, tt.fixture)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if msg.From != tt.from {
+				t.Errorf("From = %q, want %q", msg.From, tt.from)
+			}
+			if !reflect.DeepEqual(msg.Recipients, tt.recipients) {
+				t.Errorf("Recipients = %q, want %q", msg.Recipients, tt.recipients)
+			}
+			if msg.Subject != tt.subject {
+				t.Errorf("Subject = %q, want %q", msg.Subject, tt.subject)
+			}
+			if msg.MessageID != tt.messageID {
+				t.Errorf("MessageID = %q, want %q", msg.MessageID, tt.messageID)
+			}
+			if len(msg.Attachments) != len(tt.attachments) {
+				t.Fatalf("got %d attachments, want %d", len(msg.Attachments), len(tt.attachments))
+			}
+			for i, want := range tt.attachments {
+				got := msg.Attachments[i]
+				if got.Filename != want.filename || got.ContentType != want.contentType {
+					t.Errorf("attachment %d = {%q, %q}, want {%q, %q}", i, got.Filename, got.ContentType, want.filename, want.contentType)
+				}
+				if !strings.HasPrefix(string(got.Data), want.prefix) {
+					t.Errorf("attachment %d data = %q, want prefix %q", i, got.Data, want.prefix)
+				}
+			}
+		})
+	}
+}
+
+func TestParseNoSender(t *testing.T) {
+	if _, err := parseFixture(t, "no_sender.eml"); !errors.Is(err, ErrNoSender) {
+		t.Fatalf("Parse error = %v, want ErrNoSender", err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"not a message", "this is not an email"},
+		{"multipart without boundary", "From: a@example.com\nContent-Type: multipart/mixed\n\nbody\n"},
+		{"bad base64", "From: a@example.com\nContent-Type: application/pdf\nContent-Disposition: attachment; filename=a.pdf\nContent-Transfer-Encoding: base64\n\n!!!not base64!!!\n"},
+		{"nested too deeply", nestedMultipart(maxPartDepth + 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.raw)); err == nil {
+				t.Fatal("Parse succeeded, want error")
+			}
+		})
+	}
+}
+
+// nestedMultipart 构造嵌套 depth 层的 multipart 邮件
+func nestedMultipart(depth int) string {
+	var b strings.Builder
+	b.WriteString("From: a@example.com\n")
+	for i := 0; i < depth; i++ {
+		b.WriteString("Content-Type: multipart/mixed; boundary=b" + string(rune('a'+i%26)) + strings.Repeat("x", i) + "\n\n")
+		b.WriteString("--b" + string(rune('a'+i%26)) + strings.Repeat("x", i) + "\n")
+	}
+	b.WriteString("Content-Type: text/plain\n\nhello\n")
+	for i := depth - 1; i >= 0; i-- {
+		b.WriteString("--b" + string(rune('a'+i%26)) + strings.Repeat("x", i) + "--\n")
+	}
+	return b.String()
+}
+
+func TestReadBody(t *testing.T) {
+	data, err := ReadBody(strings.NewReader("\r\n\nFrom: a@example.com\n\nhi\n"), 64)
+	if err != nil {
+		t.Fatalf("ReadBody: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "From:") {
+		t.Errorf("leading newlines not trimmed: %q", data)
+	}
+
+	if _, err := ReadBody(strings.NewReader(strings.Repeat("x", 65)), 64); err == nil {
+		t.Error("ReadBody accepted message over the size limit")
+	}
+	if _, err := ReadBody(strings.NewReader(strings.Repeat("x", 64)), 64); err != nil {
+		t.Errorf("ReadBody rejected message at the size limit: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package mailin
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Target 收件地址对应的打印目标，PrinterID 与 GroupID 只有一个非空
+type Target struct {
+	Recipient string
+	PrinterID string // 打印机ID或 slug
+	GroupID   string // 打印机组ID
+}
+
+// Router 按配置的正则将收件地址映射到打印机或打印机组，正则的第一个捕获组为打印机（组）标识
+type Router struct {
+	printer *regexp.Regexp
+	group   *regexp.Regexp
+}
+
+// NewRouter 编译收件地址映射规则，规则为空时不映射对应目标
+func NewRouter(printerPattern, groupPattern string) (*Router, error) {
+	router := &Router{}
+	var err error
+	if router.printer, err = compilePattern(printerPattern); err != nil {
+		return nil, fmt.Errorf("invalid printer pattern: %w", err)
+	}
+	if router.group, err = compilePattern(groupPattern); err != nil {
+		return nil, fmt.Errorf("invalid group pattern: %w", err)
+	}
+	return router, nil
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, err
+	}
+	if re.NumSubexp() < 1 {
+		return nil, fmt.Errorf("pattern %q has no capture group", pattern)
+	}
+	return re, nil
+}
+
+// Resolve 返回第一个能映射到打印机或打印机组的收件地址，打印机规则优先
+func (r *Router) Resolve(recipients []string) (Target, bool) {
+	for _, recipient := range recipients {
+		if id := firstGroup(r.printer, recipient); id != "" {
+			return Target{Recipient: recipient, PrinterID: id}, true
+		}
+		if id := firstGroup(r.group, recipient); id != "" {
+			return Target{Recipient: recipient, GroupID: id}, true
+		}
+	}
+	return Target{}, false
+}
+
+func firstGroup(re *regexp.Regexp, s string) string {
+	if re == nil {
+		return ""
+	}
+	match := re.FindStringSubmatch(s)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
@@ -0,0 +1,81 @@
+package mailin
+
+import "testing"
+
+const (
+	testPrinterPattern = `^printer-([a-z0-9-]+)@print\.example\.com$`
+	testGroupPattern   = `^group-([a-z0-9-]+)@print\.example\.com$`
+)
+
+func TestRouterResolve(t *testing.T) {
+	router, err := NewRouter(testPrinterPattern, testGroupPattern)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		recipients []string
+		want       Target
+		ok         bool
+	}{
+		{"printer", []string{"printer-lobby@print.example.com"}, Target{Recipient: "printer-lobby@print.example.com", PrinterID: "lobby"}, true},
+		{"group", []string{"group-finance@print.example.com"}, Target{Recipient: "group-finance@print.example.com", GroupID: "finance"}, true},
+		{"case insensitive", []string{"Printer-Lobby@Print.Example.com"}, Target{Recipient: "Printer-Lobby@Print.Example.com", PrinterID: "Lobby"}, true},
+		{"skips unrelated recipients", []string{"bob@example.com", "group-hr@print.example.com"}, Target{Recipient: "group-hr@print.example.com", GroupID: "hr"}, true},
+		{"first matching recipient wins", []string{"group-hr@print.example.com", "printer-3f@print.example.com"}, Target{Recipient: "group-hr@print.example.com", GroupID: "hr"}, true},
+		{"other domain", []string{"printer-lobby@evil.example.com"}, Target{}, false},
+		{"no recipients", nil, Target{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := router.Resolve(tt.recipients)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("Resolve(%q) = %+v, %v; want %+v, %v", tt.recipients, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRouterPrinterRuleTakesPriority(t *testing.T) {
+	// 两条规则都能匹配同一地址时按打印机处理
+	router, err := NewRouter(`^print-(\w+)@`, `^print-(\w+)@`)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	got, ok := router.Resolve([]string{"print-lobby@example.com"})
+	if !ok || got.PrinterID != "lobby" || got.GroupID != "" {
+		t.Errorf("Resolve = %+v, %v; want printer lobby", got, ok)
+	}
+}
+
+func TestRouterEmptyPatternDisablesTarget(t *testing.T) {
+	router, err := NewRouter("", testGroupPattern)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	if got, ok := router.Resolve([]string{"printer-lobby@print.example.com"}); ok {
+		t.Errorf("Resolve = %+v, want no match without printer pattern", got)
+	}
+	if _, ok := router.Resolve([]string{"group-hr@print.example.com"}); !ok {
+		t.Error("group pattern not applied")
+	}
+}
+
+func TestNewRouterInvalidPattern(t *testing.T) {
+	tests := []struct {
+		name           string
+		printer, group string
+	}{
+		{"printer without capture group", `^printer-[a-z]+@`, ""},
+		{"group without capture group", "", `^group-[a-z]+@`},
+		{"invalid regexp", `^printer-([a-z]+@`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRouter(tt.printer, tt.group); err == nil {
+				t.Fatal("NewRouter succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader 携带个人 API Key 的请求头（未提供 Authorization 头时使用）
+const APIKeyHeader = "X-API-Key"
+
+// ErrInvalidAPIKey API Key 不存在、已吊销、已过期或所属用户已停用
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// ErrAPIKeyExpired API Key 已过期
+var ErrAPIKeyExpired = fmt.Errorf("%w: expired", ErrInvalidAPIKey)
+
+// APIKeyIdentity API Key 认证得到的用户身份，与 OAuth2 token 设置相同的上下文值
+type APIKeyIdentity struct {
+	KeyID      string
+	ExternalID string
+	Username   string
+	Email      string
+	Roles      []string
+}
+
+// APIKeyAuthenticator 校验 API Key 并返回所属用户身份，Key 无效时返回 ErrInvalidAPIKey
+type APIKeyAuthenticator func(key string) (*APIKeyIdentity, error)
+
+// apiKeyAuthenticator 启动时设置，未设置时不接受 API Key
+var apiKeyAuthenticator APIKeyAuthenticator
+
+// SetAPIKeyAuthenticator 设置 API Key 认证方式（启动时调用）
+func SetAPIKeyAuthenticator(authenticator APIKeyAuthenticator) {
+	apiKeyAuthenticator = authenticator
+}
+
+// authenticateAPIKey 使用 X-API-Key 认证，成功时返回 true 并设置与 OAuth2 相同的上下文值，失败时已写入响应
+func authenticateAPIKey(c *gin.Context, key string, allowed func(userRoles []string) bool) bool {
+	if apiKeyAuthenticator == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":             "unauthorized",
+			"error_description": "api keys are not enabled",
+		})
+		c.Abort()
+		return false
+	}
+
+	identity, err := apiKeyAuthenticator(key)
+	if err != nil {
+		status, code, description := http.StatusUnauthorized, "invalid_token", err.Error()
+		if !errors.Is(err, ErrInvalidAPIKey) {
+			log.Printf("Failed to verify api key: %v", err)
+			status, code, description = http.StatusInternalServerError, "server_error", "failed to verify api key"
+		}
+		c.JSON(status, gin.H{
+			"error":             code,
+			"error_description": description,
+		})
+		c.Abort()
+		return false
+	}
+
+	if !allowed(identity.Roles) {
+		abortInsufficientScope(c)
+		return false
+	}
+
+	c.Set("api_key_id", identity.KeyID)
+	c.Set("external_id", identity.ExternalID)
+	c.Set("username", identity.Username)
+	c.Set("email", identity.Email)
+	c.Set("roles", identity.Roles)
+	c.Set("token_node_id", "")
+	return true
+}
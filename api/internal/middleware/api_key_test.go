@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fly-print-cloud/api/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeAPIKeys 测试用 API Key 认证：full 为管理员 Key，submit 只能提交任务
+func fakeAPIKeys(key string) (*APIKeyIdentity, error) {
+	switch key {
+	case "fpk_full":
+		return &APIKeyIdentity{KeyID: "key-1", ExternalID: "ext-1", Username: "erp", Email: "erp@example.com", Roles: []string{"admin"}}, nil
+	case "fpk_submit":
+		return &APIKeyIdentity{KeyID: "key-2", ExternalID: "ext-1", Username: "erp", Roles: []string{"print:submit"}}, nil
+	case "fpk_expired":
+		return nil, ErrAPIKeyExpired
+	case "fpk_db_down":
+		return nil, errors.New("connection refused")
+	default:
+		return nil, ErrInvalidAPIKey
+	}
+}
+
+// newAPIKeyRouter 创建使用 API Key 认证的测试路由，响应中返回中间件设置的上下文值
+func newAPIKeyRouter(t *testing.T, authenticator APIKeyAuthenticator) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	previous := apiKeyAuthenticator
+	SetAPIKeyAuthenticator(authenticator)
+	t.Cleanup(func() { SetAPIKeyAuthenticator(previous) })
+
+	identity := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"api_key_id":  c.GetString("api_key_id"),
+			"external_id": c.GetString("external_id"),
+			"username":    c.GetString("username"),
+			"roles":       c.GetStringSlice("roles"),
+		})
+	}
+	auth := NewOAuth2Authenticator(&config.OAuth2Config{}, nil)
+	r := gin.New()
+	r.GET("/admin", auth.ResourceServer("admin"), identity)
+	r.GET("/submit", auth.ResourceServer("print:submit"), identity)
+	return r
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	r := newAPIKeyRouter(t, fakeAPIKeys)
+
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		status int
+		error  string
+	}{
+		{"full key on admin route", "/admin", "fpk_full", http.StatusOK, ""},
+		{"full key on submit route", "/submit", "fpk_full", http.StatusOK, ""},
+		{"submit key on submit route", "/submit", "fpk_submit", http.StatusOK, ""},
+		{"submit key on admin route", "/admin", "fpk_submit", http.StatusForbidden, "insufficient_scope"},
+		{"unknown key", "/submit", "fpk_nope", http.StatusUnauthorized, "invalid_token"},
+		{"expired key", "/submit", "fpk_expired", http.StatusUnauthorized, "invalid_token"},
+		{"lookup failure", "/submit", "fpk_db_down", http.StatusInternalServerError, "server_error"},
+		{"no credentials", "/submit", "", http.StatusUnauthorized, "unauthorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.status, w.Body.String())
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if tt.error != "" && body["error"] != tt.error {
+				t.Fatalf("error = %v, want %q", body["error"], tt.error)
+			}
+		})
+	}
+}
+
+// TestAPIKeyContext API Key 认证设置与 OAuth2 相同的上下文值
+func TestAPIKeyContext(t *testing.T) {
+	r := newAPIKeyRouter(t, fakeAPIKeys)
+	req := httptest.NewRequest(http.MethodGet, "/submit", nil)
+	req.Header.Set(APIKeyHeader, "fpk_submit")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body struct {
+		APIKeyID   string   `json:"api_key_id"`
+		ExternalID string   `json:"external_id"`
+		Username   string   `json:"username"`
+		Roles      []string `json:"roles"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body.APIKeyID != "key-2" || body.ExternalID != "ext-1" || body.Username != "erp" ||
+		len(body.Roles) != 1 || body.Roles[0] != "print:submit" {
+		t.Fatalf("context = %+v", body)
+	}
+}
+
+// TestAPIKeyBearerTakesPrecedence 同时携带 Authorization 时只按 token 认证
+func TestAPIKeyBearerTakesPrecedence(t *testing.T) {
+	r := newAPIKeyRouter(t, fakeAPIKeys)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":                "user-1",
+		"preferred_username": "alice",
+		"realm_access":       map[string]interface{}{"roles": []string{"print:submit"}},
+	}).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(APIKeyHeader, "fpk_full")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403 from the bearer token's roles", w.Code)
+	}
+}
+
+func TestAPIKeyDisabled(t *testing.T) {
+	r := newAPIKeyRouter(t, nil)
+	req := httptest.NewRequest(http.MethodGet, "/submit", nil)
+	req.Header.Set(APIKeyHeader, "fpk_full")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401 when api keys are not enabled", w.Code)
+	}
+}
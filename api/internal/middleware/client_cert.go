@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrUnknownClientCert 证书由受信任 CA 签发，但 CN/SAN 未登记到启用的节点凭据（未登记或已吊销）
+var ErrUnknownClientCert = errors.New("client certificate is not registered or has been revoked")
+
+// ClientCertIdentity 客户端证书映射得到的 Edge Node 身份
+type ClientCertIdentity struct {
+	CredentialID string
+	NodeID       string
+	Subject      string // 匹配到的证书名称（CN 或 SAN）
+}
+
+// ClientCertAuthenticator 将已通过 CA 校验的证书映射到节点，未登记或已吊销时返回 ErrUnknownClientCert
+type ClientCertAuthenticator func(cert *x509.Certificate) (*ClientCertIdentity, error)
+
+// clientCertAuthenticator 启动时设置，未设置时忽略客户端证书
+var clientCertAuthenticator ClientCertAuthenticator
+
+// SetClientCertAuthenticator 设置客户端证书认证方式（启动时调用）
+func SetClientCertAuthenticator(authenticator ClientCertAuthenticator) {
+	clientCertAuthenticator = authenticator
+}
+
+// clientCertRoutePrefix 接受客户端证书的路由前缀，证书只代表 Edge Node，不能访问用户和管理接口
+const clientCertRoutePrefix = "/api/v1/edge/"
+
+// ClientCertScopes 证书认证的节点拥有的权限：证书与节点一一绑定，授予全部 Edge Node 权限
+var ClientCertScopes = []string{ScopeEdgeRegister, ScopeEdgeConnect, ScopeEdgePrinterWrite, ScopeEdgeJobUpdate}
+
+// VerifiedClientCert 获取 TLS 握手中已通过 CA 校验的客户端证书，未出示证书、未启用 TLS
+// 或由反向代理终止 TLS 时返回 nil
+func VerifiedClientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// CertificateSubjects 证书中可映射到节点凭据的名称，按 CN、DNS、URI、邮箱 SAN 的顺序
+func CertificateSubjects(cert *x509.Certificate) []string {
+	var subjects []string
+	if cert.Subject.CommonName != "" {
+		subjects = append(subjects, cert.Subject.CommonName)
+	}
+	subjects = append(subjects, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		subjects = append(subjects, uri.String())
+	}
+	subjects = append(subjects, cert.EmailAddresses...)
+	return subjects
+}
+
+// AuthenticateClientCert 使用请求的客户端证书认证节点，未出示已校验的证书或未启用证书认证时返回 nil, nil
+func AuthenticateClientCert(r *http.Request) (*ClientCertIdentity, error) {
+	cert := VerifiedClientCert(r)
+	if cert == nil || clientCertAuthenticator == nil {
+		return nil, nil
+	}
+	return clientCertAuthenticator(cert)
+}
+
+// authenticateClientCert 使用客户端证书认证（仅在未携带 Authorization 和 X-API-Key 时使用）
+// 非 Edge 路由或未出示证书时 handled 为 false，不写入响应；否则 ok 表示是否通过，通过时设置与 OAuth2 相同的上下文值，失败时已写入响应
+// 证书与节点绑定，路径中带 node_id 的接口要求与证书所属节点一致
+func authenticateClientCert(c *gin.Context, allowed func(userRoles []string) bool) (handled, ok bool) {
+	if !strings.HasPrefix(c.FullPath(), clientCertRoutePrefix) {
+		return false, false
+	}
+	identity, err := AuthenticateClientCert(c.Request)
+	if err == nil && identity == nil {
+		return false, false
+	}
+	if err != nil {
+		status, code, description := http.StatusUnauthorized, "invalid_client_certificate", err.Error()
+		if !errors.Is(err, ErrUnknownClientCert) {
+			log.Printf("Failed to verify client certificate: %v", err)
+			status, code, description = http.StatusInternalServerError, "server_error", "failed to verify client certificate"
+		}
+		c.JSON(status, gin.H{
+			"error":             code,
+			"error_description": description,
+		})
+		c.Abort()
+		return true, false
+	}
+
+	if !allowed(ClientCertScopes) {
+		abortInsufficientScope(c)
+		return true, false
+	}
+	if nodeID := c.Param("node_id"); nodeID != "" && nodeID != identity.NodeID {
+		log.Printf("Client certificate for node %s rejected on path node %s", identity.NodeID, nodeID)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":             "insufficient_scope",
+			"error_description": "client certificate does not belong to this edge node",
+		})
+		c.Abort()
+		return true, false
+	}
+
+	c.Set("client_cert_credential_id", identity.CredentialID)
+	c.Set("external_id", "cert:"+identity.Subject)
+	c.Set("username", identity.NodeID)
+	c.Set("email", "")
+	c.Set("roles", ClientCertScopes)
+	c.Set("token_node_id", identity.NodeID)
+	return true, true
+}
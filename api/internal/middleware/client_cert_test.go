@@ -0,0 +1,297 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testCA 测试用 CA，签发服务端和客户端证书
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fly-print test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA: %v", err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// issue 签发证书，template 中只需填写名称和用途
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("issue certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// fakeCredentials 测试用证书凭据：edge-01 已登记，revoked 已吊销，broken 查询失败
+func fakeCredentials(cert *x509.Certificate) (*ClientCertIdentity, error) {
+	for _, subject := range CertificateSubjects(cert) {
+		switch subject {
+		case "edge-01.fleet.example.com":
+			return &ClientCertIdentity{CredentialID: "cred-1", NodeID: "edge-01", Subject: subject}, nil
+		case "broken":
+			return nil, errors.New("connection refused")
+		}
+	}
+	return nil, ErrUnknownClientCert
+}
+
+// newMutualTLSServer 启动与生产相同的可选客户端证书 TLS 服务，响应中返回中间件设置的上下文值
+func newMutualTLSServer(t *testing.T, ca *testCA) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	previousCert, previousKey := clientCertAuthenticator, apiKeyAuthenticator
+	SetClientCertAuthenticator(fakeCredentials)
+	SetAPIKeyAuthenticator(fakeAPIKeys)
+	t.Cleanup(func() {
+		SetClientCertAuthenticator(previousCert)
+		SetAPIKeyAuthenticator(previousKey)
+	})
+
+	identity := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"external_id":   c.GetString("external_id"),
+			"username":      c.GetString("username"),
+			"token_node_id": c.GetString("token_node_id"),
+		})
+	}
+	auth := NewOAuth2Authenticator(&config.OAuth2Config{}, nil)
+	r := gin.New()
+	r.POST("/api/v1/edge/register", auth.ResourceServer(ScopeEdgeRegister), identity)
+	r.POST("/api/v1/edge/:node_id/printers", auth.ResourceServer(ScopeEdgePrinterWrite), identity)
+	r.GET("/api/v1/admin/printers", auth.ResourceServer("admin"), identity)
+
+	server := httptest.NewUnstartedServer(r)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, &x509.Certificate{
+			IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		})},
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  pool,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTLSClient 信任测试 CA 的客户端，cert 为空时不出示客户端证书
+func newTLSClient(ca *testCA, cert *tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: pool}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}, Timeout: 10 * time.Second}
+}
+
+// TestClientCertPrecedence 优先级：Authorization > X-API-Key > 客户端证书；携带了 token 时不回退到证书，
+// 证书只能访问 Edge 路由，路径中的 node_id 须与证书所属节点一致
+func TestClientCertPrecedence(t *testing.T) {
+	ca := newTestCA(t)
+	server := newMutualTLSServer(t, ca)
+
+	nodeCert := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "unregistered-cn"},
+		DNSNames:    []string{"edge-01.fleet.example.com"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	revokedCert := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "revoked"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	brokenCert := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "broken"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	edgeToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":     "edge-client",
+		"node_id": "edge-02",
+		"scope":   ScopeEdgeRegister + " " + ScopeEdgePrinterWrite,
+	}).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	userToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":          "user-1",
+		"realm_access": map[string]interface{}{"roles": []string{"print:submit"}},
+	}).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		cert       *tls.Certificate
+		header     map[string]string
+		status     int
+		error      string
+		externalID string
+	}{
+		{"certificate only", http.MethodPost, "/api/v1/edge/register", &nodeCert, nil, http.StatusOK, "", "cert:edge-01.fleet.example.com"},
+		{"certificate for path node", http.MethodPost, "/api/v1/edge/edge-01/printers", &nodeCert, nil, http.StatusOK, "", "cert:edge-01.fleet.example.com"},
+		{"certificate for other node", http.MethodPost, "/api/v1/edge/edge-02/printers", &nodeCert, nil, http.StatusForbidden, "insufficient_scope", ""},
+		{"certificate on admin route", http.MethodGet, "/api/v1/admin/printers", &nodeCert, nil, http.StatusUnauthorized, "unauthorized", ""},
+		{"revoked certificate", http.MethodPost, "/api/v1/edge/register", &revokedCert, nil, http.StatusUnauthorized, "invalid_client_certificate", ""},
+		{"credential lookup failure", http.MethodPost, "/api/v1/edge/register", &brokenCert, nil, http.StatusInternalServerError, "server_error", ""},
+		{"no credentials", http.MethodPost, "/api/v1/edge/register", nil, nil, http.StatusUnauthorized, "unauthorized", ""},
+		{"token without certificate", http.MethodPost, "/api/v1/edge/register", nil,
+			map[string]string{"Authorization": "Bearer " + edgeToken}, http.StatusOK, "", "edge-client"},
+		{"token wins over certificate", http.MethodPost, "/api/v1/edge/edge-02/printers", &nodeCert,
+			map[string]string{"Authorization": "Bearer " + edgeToken}, http.StatusOK, "", "edge-client"},
+		{"token scopes apply despite certificate", http.MethodPost, "/api/v1/edge/register", &nodeCert,
+			map[string]string{"Authorization": "Bearer " + userToken}, http.StatusForbidden, "insufficient_scope", ""},
+		{"invalid token does not fall back", http.MethodPost, "/api/v1/edge/register", &nodeCert,
+			map[string]string{"Authorization": "Bearer not-a-jwt"}, http.StatusUnauthorized, "invalid_token", ""},
+		{"malformed authorization does not fall back", http.MethodPost, "/api/v1/edge/register", &nodeCert,
+			map[string]string{"Authorization": "Basic ZWRnZTpzZWNyZXQ="}, http.StatusUnauthorized, "unauthorized", ""},
+		{"api key wins over certificate", http.MethodPost, "/api/v1/edge/register", &nodeCert,
+			map[string]string{APIKeyHeader: "fpk_submit"}, http.StatusForbidden, "insufficient_scope", ""},
+		{"invalid api key does not fall back", http.MethodPost, "/api/v1/edge/register", &nodeCert,
+			map[string]string{APIKeyHeader: "fpk_nope"}, http.StatusUnauthorized, "invalid_token", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			resp, err := newTLSClient(ca, tt.cert).Do(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			var body map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d (%v)", resp.StatusCode, tt.status, body)
+			}
+			if tt.error != "" && body["error"] != tt.error {
+				t.Fatalf("error = %v, want %q", body["error"], tt.error)
+			}
+			if tt.externalID != "" && body["external_id"] != tt.externalID {
+				t.Fatalf("external_id = %v, want %q", body["external_id"], tt.externalID)
+			}
+		})
+	}
+}
+
+// TestClientCertIdentityContext 证书认证设置与 OAuth2 相同的上下文值，节点来自登记的凭据
+func TestClientCertIdentityContext(t *testing.T) {
+	ca := newTestCA(t)
+	server := newMutualTLSServer(t, ca)
+	cert := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "edge-01.fleet.example.com"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	resp, err := newTLSClient(ca, &cert).Post(server.URL+"/api/v1/edge/register", "application/json", nil)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		ExternalID  string `json:"external_id"`
+		Username    string `json:"username"`
+		TokenNodeID string `json:"token_node_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Username != "edge-01" || body.TokenNodeID != "edge-01" ||
+		body.ExternalID != "cert:edge-01.fleet.example.com" {
+		t.Fatalf("status %d, context = %+v", resp.StatusCode, body)
+	}
+}
+
+// TestClientCertUntrustedIssuer 不受信任 CA 签发的证书在 TLS 握手时被拒绝，不会到达认证中间件
+func TestClientCertUntrustedIssuer(t *testing.T) {
+	ca := newTestCA(t)
+	server := newMutualTLSServer(t, ca)
+	rogue := newTestCA(t).issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "edge-01.fleet.example.com"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	resp, err := newTLSClient(ca, &rogue).Post(server.URL+"/api/v1/edge/register", "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("status = %d, want the handshake to fail", resp.StatusCode)
+	}
+}
+
+// TestVerifiedClientCert 未启用 TLS 或未通过校验（例如由反向代理终止 TLS）时没有可用的证书
+func TestVerifiedClientCert(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/edge/register", nil)
+	if VerifiedClientCert(req) != nil {
+		t.Fatal("plain HTTP request has a client certificate")
+	}
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}
+	if VerifiedClientCert(req) != nil {
+		t.Fatal("unverified peer certificate accepted")
+	}
+	leaf := &x509.Certificate{Subject: pkix.Name{CommonName: "edge-01"}}
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+	if VerifiedClientCert(req) != leaf {
+		t.Fatal("verified leaf not returned")
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 连接令牌的签发方和受众，避免与其他用途的令牌混用
+const (
+	connTokenIssuer   = "fly-print-cloud"
+	connTokenAudience = "edge-ws"
+)
+
+// ErrConnTokenInvalid 连接令牌签名无效、已过期或不是连接令牌
+var ErrConnTokenInvalid = errors.New("invalid connection token")
+
+// ConnTokenClaims 连接令牌携带的信息：subject 为申请令牌的 OAuth2 客户端
+type ConnTokenClaims struct {
+	NodeID string `json:"node_id"`
+	jwt.RegisteredClaims
+}
+
+// ConnTokenSigner 签发和校验 Edge Node 的 WebSocket 连接令牌（HS256 JWT）
+// 节点通过 REST 注册时使用共享的客户端凭据，token 无法区分节点；连接令牌由云端签发并绑定 node_id
+type ConnTokenSigner struct {
+	secret []byte
+}
+
+// NewConnTokenSigner 创建连接令牌签名器
+// secret 为空时使用随机密钥，令牌在服务重启后失效（节点通过下一次注册或心跳重新获取），多副本部署时必须配置
+func NewConnTokenSigner(secret string) (*ConnTokenSigner, error) {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate connection token secret: %w", err)
+		}
+		log.Printf("edge.conn_token_secret not set, using a random secret; connection tokens will not survive restarts")
+	}
+	return &ConnTokenSigner{secret: key}, nil
+}
+
+// Issue 为节点签发有效期为 ttl 的连接令牌，subject 为申请令牌的 OAuth2 客户端（token 的 sub）
+func (s *ConnTokenSigner) Issue(nodeID, subject string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(ttl)
+	claims := ConnTokenClaims{
+		NodeID: nodeID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    connTokenIssuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{connTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign connection token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// Verify 校验连接令牌的签名、有效期、签发方和受众，返回其中的节点信息
+func (s *ConnTokenSigner) Verify(token string) (*ConnTokenClaims, error) {
+	claims := &ConnTokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(connTokenIssuer),
+		jwt.WithAudience(connTokenAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.NodeID == "" {
+		return nil, ErrConnTokenInvalid
+	}
+	return claims, nil
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"log"
+	"sync"
+)
+
+// Edge Node 权限模型
+const (
+	ScopeEdgeRegister     = "edge:register"      // 注册 Edge Node
+	ScopeEdgeConnect      = "edge:connect"       // 建立 WebSocket 连接、上报心跳、上传诊断包
+	ScopeEdgePrinterWrite = "edge:printer:write" // 注册打印机、上报打印机状态、查询驱动
+	ScopeEdgeJobUpdate    = "edge:job:update"    // 上报打印任务状态
+)
+
+// legacyEdgeScopes 旧版 scope 到新 scope 的兼容映射，仅保留一个版本，之后移除
+// 旧版 edge:heartbeat 即可建立 WebSocket 并上报全部消息，因此映射到全部连接内权限
+var legacyEdgeScopes = map[string][]string{
+	"edge:heartbeat": {ScopeEdgeConnect, ScopeEdgePrinterWrite, ScopeEdgeJobUpdate},
+	"edge:printer":   {ScopeEdgePrinterWrite},
+}
+
+// legacyScopeWarned 已输出弃用提示的旧版 scope，每个 scope 只提示一次
+var legacyScopeWarned sync.Map
+
+// expandLegacyScopes 为持有旧版 scope 的 token 补充对应的新 scope
+func expandLegacyScopes(scopes []string) []string {
+	expanded := append([]string(nil), scopes...)
+	for _, scope := range scopes {
+		mapped, ok := legacyEdgeScopes[scope]
+		if !ok {
+			continue
+		}
+		if _, warned := legacyScopeWarned.LoadOrStore(scope, true); !warned {
+			log.Printf("Deprecated edge scope %q in use, mapped to %v; migrate tokens before the next release", scope, mapped)
+		}
+		expanded = append(expanded, mapped...)
+	}
+	return removeDuplicates(expanded)
+}
+
+// TokenScopes 获取 token 的全部权限（角色与 scope，已展开旧版 edge scope）
+func TokenScopes(tokenInfo *OAuth2TokenInfo) []string {
+	return extractStandardRoles(tokenInfo)
+}
+
+// HasScope 判断权限列表中是否包含指定权限（admin 拥有所有权限）
+func HasScope(scopes []string, required string) bool {
+	return validateScopes(scopes, []string{required})
+}
+
+// TokenNodeID 获取 token 代表的 Edge Node ID：优先使用自定义 node_id claim，
+// 否则使用 subject（Client Credentials Flow 下每个节点一个 client 时 subject 即节点ID）
+func TokenNodeID(tokenInfo *OAuth2TokenInfo) string {
+	if tokenInfo.NodeID != "" {
+		return tokenInfo.NodeID
+	}
+	return tokenInfo.Sub
+}
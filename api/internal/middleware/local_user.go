@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"log"
+
+	"fly-print-cloud/api/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// LocalUser 根据 OAuth2 的 external_id 解析本地用户记录，设置 user_id 和 user_role
+// 需放在 OAuth2Authenticator.ResourceServer 之后；找不到本地用户时不中断请求，由处理器自行判断
+func LocalUser(userRepo *database.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		externalID, exists := c.Get("external_id")
+		if !exists {
+			c.Next()
+			return
+		}
+
+		user, err := userRepo.GetUserByExternalID(externalID.(string))
+		if err != nil {
+			log.Printf("Local user not found for external_id %v: %v", externalID, err)
+			c.Next()
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("user_role", user.Role)
+		c.Next()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"fly-print-cloud/api/internal/config"
 	"github.com/gin-gonic/gin"
@@ -19,6 +20,7 @@ type OAuth2TokenInfo struct {
 	Groups            []string `json:"groups,omitempty"`           // OIDC 标准 groups claim
 	Roles             []string `json:"roles,omitempty"`            // 常见 roles claim
 	Scope             string   `json:"scope,omitempty"`            // OAuth2 标准 scope
+	NodeID            string   `json:"node_id,omitempty"`          // Edge Node 身份（自定义 claim）
 	RealmAccess       struct {
 		Roles []string `json:"roles"`
 	} `json:"realm_access,omitempty"`                              // Keycloak realm roles
@@ -27,13 +29,63 @@ type OAuth2TokenInfo struct {
 	} `json:"resource_access,omitempty"`                           // Keycloak client roles
 }
 
-// OAuth2ResourceServer OAuth2 资源服务器中间件（AND逻辑）
+// defaultUserInfoTimeout 未提供 HTTP 客户端时调用 UserInfo 端点的超时
+const defaultUserInfoTimeout = 10 * time.Second
+
+// OAuth2Authenticator 验证 Bearer token：JWT 直接解析 claims，其他 token 通过 UserInfo 端点验证
+type OAuth2Authenticator struct {
+	userInfoURL    string
+	userInfoClient *http.Client
+}
+
+// NewOAuth2Authenticator 创建 token 验证器，userInfoClient 为调用 UserInfo 端点使用的 HTTP 客户端，
+// 为空时使用 10 秒超时的默认客户端
+func NewOAuth2Authenticator(cfg *config.OAuth2Config, userInfoClient *http.Client) *OAuth2Authenticator {
+	if userInfoClient == nil {
+		userInfoClient = &http.Client{Timeout: defaultUserInfoTimeout}
+	}
+	return &OAuth2Authenticator{userInfoURL: cfg.UserInfoURL, userInfoClient: userInfoClient}
+}
+
+// ResourceServer OAuth2 资源服务器中间件（AND逻辑）
 // 验证 Bearer token 和 scope 权限，需要拥有所有指定权限
-func OAuth2ResourceServer(requiredScopes ...string) gin.HandlerFunc {
+func (a *OAuth2Authenticator) ResourceServer(requiredScopes ...string) gin.HandlerFunc {
+	return a.guard(func(userRoles []string) bool {
+		return validateScopes(userRoles, requiredScopes)
+	})
+}
+
+// ResourceServerAny OAuth2 资源服务器中间件（OR逻辑），拥有任一指定权限即可
+func (a *OAuth2Authenticator) ResourceServerAny(scopes ...string) gin.HandlerFunc {
+	return a.guard(func(userRoles []string) bool {
+		return validateAnyScope(userRoles, scopes)
+	})
+}
+
+// guard 验证 Bearer token（未提供时接受 X-API-Key，两者都未提供时接受 Edge Node 客户端证书），
+// 并由 allowed 判断 token 的角色是否满足权限要求
+// 优先级：Authorization > X-API-Key > 客户端证书；携带了 token 时只按 token 认证，token 无效时不回退到证书
+func (a *OAuth2Authenticator) guard(allowed func(userRoles []string) bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取 Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
+			// 无法使用 OAuth2 的系统使用个人 API Key
+			if key := c.GetHeader(APIKeyHeader); key != "" {
+				if authenticateAPIKey(c, key, allowed) {
+					c.Next()
+				}
+				return
+			}
+
+			// Edge Node 使用 TLS 客户端证书
+			if handled, ok := authenticateClientCert(c, allowed); handled {
+				if ok {
+					c.Next()
+				}
+				return
+			}
+
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":             "unauthorized", 
 				"error_description": "missing authorization header",
@@ -64,7 +116,7 @@ func OAuth2ResourceServer(requiredScopes ...string) gin.HandlerFunc {
 		}
 
 		// 验证 token 有效性
-		tokenInfo, err := validateOAuth2Token(token)
+		tokenInfo, err := a.ValidateToken(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":             "invalid_token",
@@ -78,12 +130,8 @@ func OAuth2ResourceServer(requiredScopes ...string) gin.HandlerFunc {
 		userRoles := extractStandardRoles(tokenInfo)
 		
 		// 验证权限
-		if !validateScopes(userRoles, requiredScopes) {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":             "insufficient_scope",
-				"error_description": "token does not have required scopes",
-			})
-			c.Abort()
+		if !allowed(userRoles) {
+			abortInsufficientScope(c)
 			return
 		}
 
@@ -93,25 +141,21 @@ func OAuth2ResourceServer(requiredScopes ...string) gin.HandlerFunc {
 		c.Set("username", tokenInfo.PreferredUsername)
 		c.Set("email", tokenInfo.Email)
 		c.Set("roles", userRoles)
+		c.Set("token_node_id", TokenNodeID(tokenInfo))
 		
 		c.Next()
 	}
 }
 
-// ValidateOAuth2Token 验证 OAuth2 token（导出方法）
-func ValidateOAuth2Token(token string) (*OAuth2TokenInfo, error) {
-	return validateOAuth2Token(token)
-}
-
-// validateOAuth2Token 验证 OAuth2 token 有效性（内部方法）
-func validateOAuth2Token(token string) (*OAuth2TokenInfo, error) {
+// ValidateToken 验证 OAuth2 token 有效性
+func (a *OAuth2Authenticator) ValidateToken(token string) (*OAuth2TokenInfo, error) {
 	// 首先尝试解析 JWT token（用于 Client Credentials Flow）
 	if tokenInfo, err := parseJWTToken(token); err == nil {
 		return tokenInfo, nil
 	}
 	
 	// 如果 JWT 解析失败，回退到 UserInfo 端点验证（用于 Authorization Code Flow）
-	return validateTokenViaUserInfo(token)
+	return a.validateTokenViaUserInfo(token)
 }
 
 // parseJWTToken 解析 JWT token（不验证签名，仅提取 claims）
@@ -143,6 +187,9 @@ func parseJWTToken(tokenString string) (*OAuth2TokenInfo, error) {
 	if scope, ok := claims["scope"].(string); ok {
 		tokenInfo.Scope = scope
 	}
+	if nodeID, ok := claims["node_id"].(string); ok {
+		tokenInfo.NodeID = nodeID
+	}
 
 	// 提取 realm_access roles
 	if realmAccess, ok := claims["realm_access"].(map[string]interface{}); ok {
@@ -181,15 +228,13 @@ func parseJWTToken(tokenString string) (*OAuth2TokenInfo, error) {
 }
 
 // validateTokenViaUserInfo 通过 UserInfo 端点验证 token
-func validateTokenViaUserInfo(token string) (*OAuth2TokenInfo, error) {
-	// 从配置中获取 UserInfo URL
-	userInfoURL := config.GetOAuth2UserInfoURL()
-	if userInfoURL == "" {
+func (a *OAuth2Authenticator) validateTokenViaUserInfo(token string) (*OAuth2TokenInfo, error) {
+	if a.userInfoURL == "" {
 		return nil, fmt.Errorf("OAuth2 UserInfo URL not configured")
 	}
 
 	// 创建请求
-	req, err := http.NewRequest("GET", userInfoURL, nil)
+	req, err := http.NewRequest("GET", a.userInfoURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create userinfo request: %w", err)
 	}
@@ -197,7 +242,7 @@ func validateTokenViaUserInfo(token string) (*OAuth2TokenInfo, error) {
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	// 发送请求
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := a.userInfoClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("userinfo request failed: %w", err)
 	}
@@ -240,8 +285,8 @@ func extractStandardRoles(tokenInfo *OAuth2TokenInfo) []string {
 		allRoles = append(allRoles, scopeRoles...)
 	}
 	
-	// 去重
-	return removeDuplicates(allRoles)
+	// 去重，并展开旧版 edge scope
+	return expandLegacyScopes(allRoles)
 }
 
 // HasRequiredScope 检查是否有必需的 scope（导出方法）
@@ -287,8 +332,25 @@ func validateScopes(userRoles []string, requiredScopes []string) bool {
 	return true
 }
 
+// validateAnyScope 验证用户角色是否拥有任一指定权限（admin 角色拥有所有权限）
+func validateAnyScope(userRoles []string, scopes []string) bool {
+	for _, scope := range scopes {
+		if validateScopes(userRoles, []string{scope}) {
+			return true
+		}
+	}
+	return false
+}
+
+// abortInsufficientScope token 有效但权限不足
+func abortInsufficientScope(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":             "insufficient_scope",
+		"error_description": "token does not have required scopes",
+	})
+	c.Abort()
+}
 
-// removeDuplicates 去除重复的角色
 func removeDuplicates(roles []string) []string {
 	keys := make(map[string]bool)
 	var result []string
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// newUserInfoRouter 创建使用 UserInfo 端点验证不透明 token 的测试路由
+func newUserInfoRouter(userInfoURL string, client *http.Client) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	auth := NewOAuth2Authenticator(&config.OAuth2Config{UserInfoURL: userInfoURL}, client)
+	r := gin.New()
+	r.GET("/admin", auth.ResourceServer("admin"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"username": c.GetString("username")})
+	})
+	return r
+}
+
+func serveOpaqueToken(r *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer opaque-access-token")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestUserInfoValidation 非 JWT token 通过注入的客户端调用 UserInfo 端点验证
+func TestUserInfoValidation(t *testing.T) {
+	var gotAuth string
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		switch gotAuth {
+		case "Bearer opaque-access-token":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"sub":                "user-1",
+				"preferred_username": "alice",
+				"roles":              []string{"admin"},
+			})
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer idp.Close()
+
+	w := serveOpaqueToken(newUserInfoRouter(idp.URL, idp.Client()))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if gotAuth != "Bearer opaque-access-token" {
+		t.Fatalf("userinfo Authorization = %q", gotAuth)
+	}
+	var body struct {
+		Username string `json:"username"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Username != "alice" {
+		t.Fatalf("username = %q, want alice", body.Username)
+	}
+
+	r := newUserInfoRouter(idp.URL, idp.Client())
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer revoked-token")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token status = %d, want 401", w.Code)
+	}
+}
+
+// TestUserInfoNotConfigured 未配置 UserInfo 端点时不透明 token 被拒绝
+func TestUserInfoNotConfigured(t *testing.T) {
+	if w := serveOpaqueToken(newUserInfoRouter("", nil)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+}
+
+// TestUserInfoHangingServer UserInfo 端点不响应时按注入客户端的超时拒绝请求
+func TestUserInfoHangingServer(t *testing.T) {
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer idp.Close()
+
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	w := serveOpaqueToken(newUserInfoRouter(idp.URL, client))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("request returned after %s, want about 100ms", elapsed)
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxNodeIDLength Edge Node ID 的最大长度，与注册接口的 node_id 校验（max=100）及 edge_nodes.id 列一致
+const maxNodeIDLength = 100
+
+// printerSlugPattern 打印机 slug 格式（见 database.PrinterSlugBase）：小写字母和数字，以 - 分隔
+var printerSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// UUIDParams 校验路径参数为 UUID，格式无效时在访问数据库前返回 400（否则 PostgreSQL 报错导致 500）
+// 路由中不存在的参数不校验，因此可以注册在包含列表接口的路由组上
+func UUIDParams(names ...string) gin.HandlerFunc {
+	return pathParams(names, "UUID", isUUID)
+}
+
+// PrinterParams 校验打印机路径参数为 UUID 或 slug
+func PrinterParams(names ...string) gin.HandlerFunc {
+	return pathParams(names, "UUID 或 slug", func(value string) bool {
+		return isUUID(value) || (len(value) <= 100 && printerSlugPattern.MatchString(value))
+	})
+}
+
+// NodeIDParams 校验 Edge Node 路径参数：1~100 个字符，不含空白和控制字符
+func NodeIDParams(names ...string) gin.HandlerFunc {
+	return pathParams(names, "有效的 node_id", isNodeID)
+}
+
+func pathParams(names []string, format string, valid func(string) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, name := range names {
+			value, ok := pathParam(c, name)
+			if ok && !valid(value) {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"code":    http.StatusBadRequest,
+					"message": "路径参数 " + name + " 必须是" + format,
+					"data":    gin.H{"error_code": "invalid_path_param", "param": name},
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// pathParam 返回路由中声明的路径参数，未声明时 ok 为 false
+func pathParam(c *gin.Context, name string) (string, bool) {
+	for _, param := range c.Params {
+		if param.Key == name {
+			return param.Value, true
+		}
+	}
+	return "", false
+}
+
+func isUUID(value string) bool {
+	// uuid.Parse 还接受带 urn:uuid: 前缀或花括号的写法，这里只接受标准的 36 位格式
+	if len(value) != 36 {
+		return false
+	}
+	_, err := uuid.Parse(value)
+	return err == nil
+}
+
+func isNodeID(value string) bool {
+	if value == "" || utf8.RuneCountInString(value) > maxNodeIDLength || !utf8.ValidString(value) {
+		return false
+	}
+	for _, r := range value {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
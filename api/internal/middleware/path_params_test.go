@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPathParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r := gin.New()
+	r.GET("/jobs", UUIDParams("id"), ok)
+	r.GET("/jobs/:id", UUIDParams("id"), ok)
+	r.GET("/printers/:id", PrinterParams("id"), ok)
+	r.GET("/nodes/:node_id/drivers/:driver_id", NodeIDParams("node_id"), UUIDParams("driver_id"), ok)
+
+	const id = "3f1c2a9e-8b7d-4c6e-9a51-2d7f0b3e4c5a"
+	tests := []struct {
+		name  string
+		path  string
+		param string // 为空表示通过校验
+	}{
+		{"list route without param", "/jobs", ""},
+		{"uuid", "/jobs/" + id, ""},
+		{"not a uuid", "/jobs/abc", "id"},
+		{"uuid with braces", "/jobs/" + url.PathEscape("{"+id+"}"), "id"},
+		{"urn uuid", "/jobs/urn:uuid:" + id, "id"},
+		{"uuid without dashes", "/jobs/" + strings.ReplaceAll(id, "-", ""), "id"},
+		{"sql injection", "/jobs/" + url.PathEscape("1' OR '1'='1"), "id"},
+		{"printer uuid", "/printers/" + id, ""},
+		{"printer slug", "/printers/lobby-hp-2", ""},
+		{"printer uppercase slug", "/printers/Lobby", "id"},
+		{"printer slug with space", "/printers/" + url.PathEscape("lobby hp"), "id"},
+		{"printer slug too long", "/printers/" + strings.Repeat("a", 101), "id"},
+		{"node and driver", "/nodes/edge-01/drivers/" + id, ""},
+		{"node with unicode", "/nodes/" + url.PathEscape("节点-1") + "/drivers/" + id, ""},
+		{"node with space", "/nodes/" + url.PathEscape("edge 01") + "/drivers/" + id, "node_id"},
+		{"node with control character", "/nodes/" + url.PathEscape("edge\x01") + "/drivers/" + id, "node_id"},
+		{"node too long", "/nodes/" + strings.Repeat("n", 101) + "/drivers/" + id, "node_id"},
+		{"bad driver", "/nodes/edge-01/drivers/abc", "driver_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if tt.param == "" {
+				if w.Code != http.StatusNoContent {
+					t.Fatalf("status = %d, want 204: %s", w.Code, w.Body.String())
+				}
+				return
+			}
+			var resp struct {
+				Data struct {
+					ErrorCode string `json:"error_code"`
+					Param     string `json:"param"`
+				} `json:"data"`
+			}
+			if w.Code != http.StatusBadRequest || json.Unmarshal(w.Body.Bytes(), &resp) != nil ||
+				resp.Data.ErrorCode != "invalid_path_param" || resp.Data.Param != tt.param {
+				t.Fatalf("status = %d, body = %s; want 400 naming %s", w.Code, w.Body.String(), tt.param)
+			}
+		})
+	}
+}
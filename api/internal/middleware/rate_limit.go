@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit 按客户端 IP 限制请求频率：每个窗口内最多 limit 次，超过时返回 429
+// 计数保存在进程内存中，多副本部署时每个副本单独计数；用于无需登录的公开接口
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	limiter := &fixedWindowLimiter{
+		limit:   limit,
+		window:  window,
+		clients: make(map[string]*windowCounter),
+	}
+	return func(c *gin.Context) {
+		retryAfter, ok := limiter.allow(c.ClientIP(), time.Now())
+		if !ok {
+			c.Header("Retry-After", fmt.Sprintf("%d", int(retryAfter.Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"code":    http.StatusTooManyRequests,
+				"message": "请求过于频繁，请稍后重试",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// windowCounter 单个客户端在当前窗口内的请求次数
+type windowCounter struct {
+	start time.Time
+	count int
+}
+
+// fixedWindowLimiter 固定窗口计数器
+type fixedWindowLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	clients   map[string]*windowCounter
+	lastSweep time.Time
+}
+
+// allow 记录一次请求，超过限制时返回距窗口结束的时间
+func (l *fixedWindowLimiter) allow(client string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 每个窗口最多清理一次过期的客户端，避免内存随来源 IP 增长
+	if now.Sub(l.lastSweep) >= l.window {
+		for key, c := range l.clients {
+			if now.Sub(c.start) >= l.window {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	counter, ok := l.clients[client]
+	if !ok || now.Sub(counter.start) >= l.window {
+		l.clients[client] = &windowCounter{start: now, count: 1}
+		return 0, true
+	}
+
+	if counter.count >= l.limit {
+		return l.window - now.Sub(counter.start), false
+	}
+	counter.count++
+	return 0, true
+}
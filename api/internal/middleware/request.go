@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"regexp"
+	"strconv"
+
+	"fly-print-cloud/api/internal/database"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 请求ID头，沿用上游（Nginx、调用方）传入的值，否则生成新ID
+const RequestIDHeader = "X-Request-ID"
+
+// DBQueriesHeader 调试模式下返回本次请求执行的 SQL 语句数，便于在浏览器中发现 N+1 查询
+const DBQueriesHeader = "X-DB-Queries"
+
+// validRequestID 接受的上游请求ID格式，其余情况重新生成，避免日志注入
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestIDMiddleware 为每个请求分配请求ID（上下文键 request_id），并在响应头中返回
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// QueryTrackingMiddleware 统计请求执行的 SQL 语句，SQL 日志以请求ID标记，并在响应头 X-DB-Queries 中返回语句数（开始输出响应时的计数）
+// 按 goroutine 关联请求需要读取调用栈，开销不可忽略，仅在调试模式下注册；需要在 RequestIDMiddleware 之后注册
+func QueryTrackingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats, done := database.TrackRequest(c.GetString("request_id"))
+		defer done()
+
+		writer := &queryCountWriter{ResponseWriter: c.Writer, stats: stats}
+		c.Writer = writer
+		c.Next()
+		// 没有响应体时（如 204、304）由框架在处理结束后写出响应头
+		writer.setHeader()
+	}
+}
+
+// queryCountWriter 在写出响应头前设置 X-DB-Queries
+type queryCountWriter struct {
+	gin.ResponseWriter
+	stats *database.QueryStats
+	set   bool
+}
+
+func (w *queryCountWriter) setHeader() {
+	if w.set || w.ResponseWriter.Written() {
+		return
+	}
+	w.set = true
+	w.Header().Set(DBQueriesHeader, strconv.FormatInt(w.stats.Count(), 10))
+}
+
+func (w *queryCountWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *queryCountWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *queryCountWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *queryCountWriter) Flush() {
+	w.setHeader()
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// 管理后台角色（admin 角色拥有所有权限）
+const (
+	RoleAdmin    = "fly-print-admin"    // 管理员：全部管理操作，含删除资源、用户管理和审核
+	RoleOperator = "fly-print-operator" // 运维人员：查看和日常操作（提交、取消、重新打印等），不能执行破坏性操作
+)
+
+// RequireOperator 管理员或运维人员可访问
+func (a *OAuth2Authenticator) RequireOperator() gin.HandlerFunc {
+	return a.ResourceServerAny(RoleAdmin, RoleOperator)
+}
+
+// RequireAdmin 仅管理员可访问，用于删除、用户管理、审核等破坏性操作
+// 挂在已认证的路由组内时只校验上游中间件提取的角色，不重复验证 token
+func (a *OAuth2Authenticator) RequireAdmin() gin.HandlerFunc {
+	authenticate := a.ResourceServer(RoleAdmin)
+	return func(c *gin.Context) {
+		roles, authenticated := c.Get("roles")
+		if !authenticated {
+			authenticate(c)
+			return
+		}
+		userRoles, _ := roles.([]string)
+		if !validateScopes(userRoles, []string{RoleAdmin}) {
+			abortInsufficientScope(c)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,6 @@
+package middleware
+
+// 管理端集成权限，授予外部系统使用的 token，不随 fly-print-admin / fly-print-operator 角色授予（admin 角色除外）
+const (
+	ScopeInventoryRead = "inventory:read" // 导出打印机与 Edge Node 资产清单（CMDB 同步）
+)
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// API Key 权限范围
+const (
+	APIKeyScopeFull   = "full"   // 与创建时的 OAuth2 登录权限相同
+	APIKeyScopeSubmit = "submit" // 仅提交打印任务（print:submit）
+)
+
+// APIKey 用户的个人 API Key，供无法使用交互式 OAuth2 登录的系统（如 ERP）调用接口
+// 明文只在创建时返回一次，数据库中只保存 SHA-256 哈希
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`                 // 明文前缀，用于识别 Key
+	Scope      string     `json:"scope"`                  // full/submit
+	Roles      []string   `json:"roles"`                  // 认证后设置的角色（创建时确定）
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`   // 为空时永不过期
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // 每分钟最多更新一次
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Expired 判断 API Key 在 now 时是否已过期
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
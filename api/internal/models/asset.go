@@ -0,0 +1,61 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// 资产信息所属的资源类型
+const (
+	AssetResourcePrinter  = "printer"
+	AssetResourceEdgeNode = "edge_node"
+)
+
+// AssetDateLayout 资产日期格式
+const AssetDateLayout = "2006-01-02"
+
+// MaxAssetNotesBytes 备注大小上限（字节）
+const MaxAssetNotesBytes = 10 * 1024
+
+// AssetInfo 打印机 / Edge Node 的资产管理信息，仅管理界面可见
+type AssetInfo struct {
+	PurchaseDate      *string `json:"purchase_date,omitempty"`       // 购买日期 YYYY-MM-DD
+	WarrantyExpiresOn *string `json:"warranty_expires_on,omitempty"` // 保修到期日 YYYY-MM-DD
+	AssetTag          *string `json:"asset_tag,omitempty"`           // 资产编号，全局唯一
+	Notes             *string `json:"notes,omitempty"`               // 管理备注，修改历史见 AssetNoteRevision
+}
+
+// Validate 校验日期格式和备注长度
+func (a *AssetInfo) Validate() error {
+	for name, value := range map[string]*string{"purchase_date": a.PurchaseDate, "warranty_expires_on": a.WarrantyExpiresOn} {
+		if value == nil {
+			continue
+		}
+		if _, err := time.Parse(AssetDateLayout, *value); err != nil {
+			return fmt.Errorf("%s 格式应为 YYYY-MM-DD", name)
+		}
+	}
+	if a.Notes != nil && len(*a.Notes) > MaxAssetNotesBytes {
+		return fmt.Errorf("notes 不能超过 %d 字节", MaxAssetNotesBytes)
+	}
+	return nil
+}
+
+// AssetNoteRevision 备注修改记录（保存修改前的值）
+type AssetNoteRevision struct {
+	ID            int64     `json:"id"`
+	ResourceType  string    `json:"resource_type"`
+	ResourceID    string    `json:"resource_id"`
+	PreviousNotes *string   `json:"previous_notes"` // 修改前的备注，为空表示此前没有备注
+	EditedBy      string    `json:"edited_by,omitempty"`
+	EditedAt      time.Time `json:"edited_at"`
+}
+
+// MaintenanceItem 计划维护报表中的一项（保修即将到期或已到期的设备）
+type MaintenanceItem struct {
+	ResourceType  string    `json:"resource_type"`
+	ResourceID    string    `json:"resource_id"`
+	Name          string    `json:"name"`
+	Asset         AssetInfo `json:"asset"`
+	DaysRemaining int       `json:"days_remaining"` // 距保修到期的天数，已到期为负数
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// 打印机不在营业时间时新任务的处理方式（创建任务请求的 when_closed 字段）
+const (
+	WhenClosedReject   = "reject"   // 拒绝（409，返回下次营业时间）
+	WhenClosedSchedule = "schedule" // 排队到下次营业时间再分发
+)
+
+// JobErrorPrinterClosed 打印机不在营业时间，任务被拒绝（接口错误码）
+const JobErrorPrinterClosed = "printer_closed"
+
+// BusinessHours 营业时间：只在营业时段内接收和分发打印任务
+// 可设置在打印机上，也可设置在 Edge Node（站点）上作用于其下所有没有单独设置的打印机
+type BusinessHours struct {
+	ID         string    `json:"id"`
+	PrinterID  *string   `json:"printer_id,omitempty"`
+	EdgeNodeID *string   `json:"edge_node_id,omitempty"`
+	Days       []int     `json:"days"`       // 营业的星期（0=周日 … 6=周六）
+	OpenTime   string    `json:"open_time"`  // 开始营业时间 HH:MM
+	CloseTime  string    `json:"close_time"` // 结束营业时间 HH:MM，不晚于开始时间时表示跨越午夜
+	Timezone   string    `json:"timezone"`   // 站点时区（IANA），如 Asia/Shanghai
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Validate 校验营业时间的星期、时间和时区
+func (b *BusinessHours) Validate() error {
+	if len(b.Days) == 0 {
+		return fmt.Errorf("days must not be empty")
+	}
+	for _, day := range b.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("invalid day %d, expected 0-6", day)
+		}
+	}
+	open, err := ParseClock(b.OpenTime)
+	if err != nil {
+		return err
+	}
+	closing, err := ParseClock(b.CloseTime)
+	if err != nil {
+		return err
+	}
+	if open == closing {
+		return fmt.Errorf("open_time and close_time must differ")
+	}
+	if _, err := time.LoadLocation(b.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", b.Timezone)
+	}
+	return nil
+}
+
+// OpenAt 判断 t 时刻是否在营业时段内；未设置、已停用或配置无效时视为始终营业
+func (b *BusinessHours) OpenAt(t time.Time) bool {
+	loc, open, closing, ok := b.window()
+	if !ok {
+		return true
+	}
+	return inClockWindow(b.Days, open, closing, t.In(loc))
+}
+
+// NextOpen 返回 t 之后最近的开始营业时间；t 时刻已在营业时返回 t
+func (b *BusinessHours) NextOpen(t time.Time) time.Time {
+	loc, open, _, ok := b.window()
+	if !ok || b.OpenAt(t) {
+		return t
+	}
+
+	local := t.In(loc)
+	for i := 0; i <= 7; i++ {
+		day := local.AddDate(0, 0, i)
+		if !containsDay(b.Days, int(day.Weekday())) {
+			continue
+		}
+		opening := time.Date(day.Year(), day.Month(), day.Day(), open/60, open%60, 0, 0, loc)
+		if opening.After(t) {
+			return opening.UTC()
+		}
+	}
+	return t
+}
+
+// window 解析时区和营业时段，未设置、已停用或配置无效时 ok 为 false
+func (b *BusinessHours) window() (loc *time.Location, open, closing int, ok bool) {
+	if b == nil || !b.Enabled {
+		return nil, 0, 0, false
+	}
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		return nil, 0, 0, false
+	}
+	if open, err = ParseClock(b.OpenTime); err != nil {
+		return nil, 0, 0, false
+	}
+	if closing, err = ParseClock(b.CloseTime); err != nil {
+		return nil, 0, 0, false
+	}
+	return loc, open, closing, true
+}
+
+func containsDay(days []int, day int) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusinessHoursValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		hours BusinessHours
+		ok    bool
+	}{
+		{"weekdays", BusinessHours{Days: []int{1, 2, 3, 4, 5}, OpenTime: "09:00", CloseTime: "18:00", Timezone: "Asia/Shanghai"}, true},
+		{"overnight", BusinessHours{Days: []int{5}, OpenTime: "22:00", CloseTime: "02:00", Timezone: "UTC"}, true},
+		{"no days", BusinessHours{OpenTime: "09:00", CloseTime: "18:00", Timezone: "UTC"}, false},
+		{"invalid day", BusinessHours{Days: []int{7}, OpenTime: "09:00", CloseTime: "18:00", Timezone: "UTC"}, false},
+		{"bad open time", BusinessHours{Days: []int{1}, OpenTime: "9am", CloseTime: "18:00", Timezone: "UTC"}, false},
+		{"bad close time", BusinessHours{Days: []int{1}, OpenTime: "09:00", CloseTime: "24:30", Timezone: "UTC"}, false},
+		{"empty window", BusinessHours{Days: []int{1}, OpenTime: "09:00", CloseTime: "09:00", Timezone: "UTC"}, false},
+		{"bad timezone", BusinessHours{Days: []int{1}, OpenTime: "09:00", CloseTime: "18:00", Timezone: "Mars/Olympus"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.hours.Validate()
+			if (err == nil) != tt.ok {
+				t.Fatalf("Validate() = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+// TestBusinessHoursOpenAt 2026-03-02 为周一；时刻均以 UTC 给出，按站点时区判断
+func TestBusinessHoursOpenAt(t *testing.T) {
+	weekdays := &BusinessHours{Days: []int{1, 2, 3, 4, 5}, OpenTime: "09:00", CloseTime: "18:00", Timezone: "Asia/Shanghai", Enabled: true}
+	fridayNight := &BusinessHours{Days: []int{5}, OpenTime: "22:00", CloseTime: "02:00", Timezone: "UTC", Enabled: true}
+
+	tests := []struct {
+		name         string
+		hours        *BusinessHours
+		at           time.Time
+		wantOpen     bool
+		wantNextOpen time.Time
+	}{
+		{"monday morning", weekdays, utc(2026, 3, 2, 2, 0), true, utc(2026, 3, 2, 2, 0)},
+		{"before opening", weekdays, utc(2026, 3, 2, 0, 59), false, utc(2026, 3, 2, 1, 0)},
+		{"at opening", weekdays, utc(2026, 3, 2, 1, 0), true, utc(2026, 3, 2, 1, 0)},
+		{"at closing", weekdays, utc(2026, 3, 2, 10, 0), false, utc(2026, 3, 3, 1, 0)},
+		{"friday evening", weekdays, utc(2026, 3, 6, 11, 0), false, utc(2026, 3, 9, 1, 0)},
+		{"saturday", weekdays, utc(2026, 3, 7, 4, 0), false, utc(2026, 3, 9, 1, 0)},
+		// UTC 周日晚上已是站点周一早上
+		{"sunday in UTC, monday on site", weekdays, utc(2026, 3, 1, 23, 0), false, utc(2026, 3, 2, 1, 0)},
+		{"overnight before midnight", fridayNight, utc(2026, 3, 6, 23, 0), true, utc(2026, 3, 6, 23, 0)},
+		{"overnight after midnight", fridayNight, utc(2026, 3, 7, 1, 0), true, utc(2026, 3, 7, 1, 0)},
+		{"overnight closed", fridayNight, utc(2026, 3, 7, 2, 0), false, utc(2026, 3, 13, 22, 0)},
+		{"overnight next week", fridayNight, utc(2026, 3, 6, 21, 59), false, utc(2026, 3, 6, 22, 0)},
+		{"unset", nil, utc(2026, 3, 7, 4, 0), true, utc(2026, 3, 7, 4, 0)},
+		{"disabled", &BusinessHours{Days: []int{1}, OpenTime: "09:00", CloseTime: "18:00", Timezone: "UTC"}, utc(2026, 3, 7, 4, 0), true, utc(2026, 3, 7, 4, 0)},
+		{"invalid timezone", &BusinessHours{Days: []int{1}, OpenTime: "09:00", CloseTime: "18:00", Timezone: "Mars/Olympus", Enabled: true},
+			utc(2026, 3, 7, 4, 0), true, utc(2026, 3, 7, 4, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hours.OpenAt(tt.at); got != tt.wantOpen {
+				t.Fatalf("OpenAt(%s) = %v, want %v", tt.at, got, tt.wantOpen)
+			}
+			if got := tt.hours.NextOpen(tt.at); !got.Equal(tt.wantNextOpen) {
+				t.Fatalf("NextOpen(%s) = %s, want %s", tt.at, got, tt.wantNextOpen)
+			}
+		})
+	}
+}
+
+// TestBusinessHoursNextOpenDST 夏令时切换（纽约 2026-03-08）后按当地时间开门，UTC 时刻提前一小时
+func TestBusinessHoursNextOpenDST(t *testing.T) {
+	hours := &BusinessHours{Days: []int{0, 1, 2, 3, 4, 5, 6}, OpenTime: "09:00", CloseTime: "17:00", Timezone: "America/New_York", Enabled: true}
+
+	// 周六 18:00 EST 关门后，周日 09:00 EDT 开门
+	next := hours.NextOpen(utc(2026, 3, 7, 23, 0))
+	if want := utc(2026, 3, 8, 13, 0); !next.Equal(want) {
+		t.Fatalf("NextOpen across DST = %s, want %s", next, want)
+	}
+	if next.Location() != time.UTC {
+		t.Fatalf("NextOpen location = %s, want UTC", next.Location())
+	}
+	if !hours.OpenAt(utc(2026, 3, 8, 13, 0)) || hours.OpenAt(utc(2026, 3, 8, 12, 59)) {
+		t.Fatal("OpenAt does not follow the DST opening time")
+	}
+}
+
+func utc(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ClientInfoHeader 调用方客户端信息请求头（应用名称、版本、平台）
+const ClientInfoHeader = "X-Client"
+
+// maxClientInfoFieldLength 客户端信息单个字段的最大长度（按字符计），超出部分截断
+const maxClientInfoFieldLength = 64
+
+// ClientInfo 提交任务或建立连接的客户端信息，用于排查问题时定位来源
+type ClientInfo struct {
+	Name     string `json:"name,omitempty"`     // 应用名称，如 fly-print-web
+	Version  string `json:"version,omitempty"`  // 应用版本
+	Platform string `json:"platform,omitempty"` // 运行平台，如 web/ios/windows
+}
+
+// ParseClientInfo 解析 X-Client 请求头，支持两种格式：
+//
+//	name=fly-print-web; version=1.4.2; platform=web
+//	fly-print-web/1.4.2 (web)
+//
+// 无法识别的部分忽略，解析不出任何字段时返回 nil
+func ParseClientInfo(header string) *ClientInfo {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil
+	}
+
+	info := &ClientInfo{}
+	if strings.Contains(header, "=") {
+		for _, part := range strings.FieldsFunc(header, func(r rune) bool { return r == ';' || r == ',' }) {
+			key, value, ok := strings.Cut(part, "=")
+			if !ok {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "name", "app":
+				info.Name = value
+			case "version", "ver":
+				info.Version = value
+			case "platform", "os":
+				info.Platform = value
+			}
+		}
+	} else {
+		product := header
+		if open := strings.Index(header, "("); open >= 0 {
+			product = header[:open]
+			platform := header[open+1:]
+			if end := strings.Index(platform, ")"); end >= 0 {
+				platform = platform[:end]
+			}
+			info.Platform = platform
+		}
+		if fields := strings.Fields(product); len(fields) > 0 {
+			info.Name, info.Version, _ = strings.Cut(fields[0], "/")
+		}
+	}
+	return info.Normalize()
+}
+
+// Normalize 清理各字段（去除控制字符和无效 UTF-8、截断超长值），全部为空时返回 nil
+func (i *ClientInfo) Normalize() *ClientInfo {
+	if i == nil {
+		return nil
+	}
+	normalized := &ClientInfo{
+		Name:     cleanClientInfoField(i.Name),
+		Version:  cleanClientInfoField(i.Version),
+		Platform: cleanClientInfoField(i.Platform),
+	}
+	if *normalized == (ClientInfo{}) {
+		return nil
+	}
+	return normalized
+}
+
+// String 形如 fly-print-web/1.4.2 (web)，用于日志和审计
+func (i *ClientInfo) String() string {
+	if i == nil {
+		return ""
+	}
+	s := i.Name
+	if i.Version != "" {
+		s += "/" + i.Version
+	}
+	if i.Platform != "" {
+		s = strings.TrimSpace(s + " (" + i.Platform + ")")
+	}
+	return s
+}
+
+func cleanClientInfoField(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(value, ""))
+	value = strings.TrimSpace(value)
+	if runes := []rune(value); len(runes) > maxClientInfoFieldLength {
+		value = strings.TrimSpace(string(runes[:maxClientInfoFieldLength]))
+	}
+	return value
+}
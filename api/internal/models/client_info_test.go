@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseClientInfo(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	tests := []struct {
+		name   string
+		header string
+		want   *ClientInfo
+	}{
+		{"key value", "name=fly-print-web; version=1.4.2; platform=web", &ClientInfo{Name: "fly-print-web", Version: "1.4.2", Platform: "web"}},
+		{"key aliases and quotes", `app="kiosk", ver=2.0, os=android`, &ClientInfo{Name: "kiosk", Version: "2.0", Platform: "android"}},
+		{"product form", "fly-print-web/1.4.2 (web)", &ClientInfo{Name: "fly-print-web", Version: "1.4.2", Platform: "web"}},
+		{"product without version", "kiosk (windows)", &ClientInfo{Name: "kiosk", Platform: "windows"}},
+		{"product without platform", "cli/0.9 extra tokens", &ClientInfo{Name: "cli", Version: "0.9"}},
+		{"unclosed parenthesis", "app/1.0 (linux", &ClientInfo{Name: "app", Version: "1.0", Platform: "linux"}},
+		{"partial keys", "version=3; color=blue", &ClientInfo{Version: "3"}},
+		{"unknown keys only", "foo=bar; baz=qux", nil},
+		{"no values", "name=; version=", nil},
+		{"empty", "   ", nil},
+		{"control characters", "name=ki\x00osk\x1b; version=1\n.0", &ClientInfo{Name: "kiosk", Version: "1.0"}},
+		{"invalid utf-8", "name=\xff\xfeapp; platform=w\xc3eb", &ClientInfo{Name: "app", Platform: "web"}},
+		{"truncated", "name=" + long, &ClientInfo{Name: long[:maxClientInfoFieldLength]}},
+		{"multibyte truncation", "name=" + strings.Repeat("打", 70), &ClientInfo{Name: strings.Repeat("打", maxClientInfoFieldLength)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseClientInfo(tt.header)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("ParseClientInfo(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientInfoString(t *testing.T) {
+	tests := []struct {
+		info *ClientInfo
+		want string
+	}{
+		{nil, ""},
+		{&ClientInfo{Name: "app", Version: "1.0", Platform: "web"}, "app/1.0 (web)"},
+		{&ClientInfo{Name: "app"}, "app"},
+		{&ClientInfo{Platform: "ios"}, "(ios)"},
+	}
+	for _, tt := range tests {
+		if got := tt.info.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.info, got, tt.want)
+		}
+	}
+	// String 的输出可以重新解析
+	info := &ClientInfo{Name: "app", Version: "1.0", Platform: "web"}
+	if parsed := ParseClientInfo(info.String()); parsed == nil || *parsed != *info {
+		t.Fatalf("round trip = %+v", parsed)
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// EdgeNodeCredential Edge Node 的客户端证书凭据
+// 出示受信任 CA 签发、CN 或 SAN 与 Subject 一致的证书即认证为该节点；禁用后证书立即失效
+type EdgeNodeCredential struct {
+	ID          string     `json:"id"`
+	NodeID      string     `json:"node_id"`
+	Subject     string     `json:"subject"` // 证书 CN 或 SAN（DNS、URI、邮箱）
+	Description string     `json:"description,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"` // 每分钟最多更新一次
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// EdgeNodeMetricsSample Edge Node 心跳上报的一次资源采样
+type EdgeNodeMetricsSample struct {
+	CPUUsage    float64 // CPU 使用率（%）
+	MemoryUsage float64 // 内存使用率（%）
+	DiskUsage   float64 // 磁盘使用率（%）
+	Latency     int     // 延迟(ms)
+}
+
+// EdgeNodeMetricsPoint 一个时间桶内采样的平均值和最大值，Time 为时间桶起点
+type EdgeNodeMetricsPoint struct {
+	Time       time.Time `json:"ts"`
+	Samples    int       `json:"samples"`
+	CPUAvg     float64   `json:"cpu_avg"`
+	CPUMax     float64   `json:"cpu_max"`
+	MemoryAvg  float64   `json:"memory_avg"`
+	MemoryMax  float64   `json:"memory_max"`
+	DiskAvg    float64   `json:"disk_avg"`
+	DiskMax    float64   `json:"disk_max"`
+	LatencyAvg float64   `json:"latency_avg"`
+	LatencyMax int       `json:"latency_max"`
+}
+
+// EdgeNodeMetricsSeries 单个节点的资源曲线，没有采样的时间桶不返回
+type EdgeNodeMetricsSeries struct {
+	NodeID      string                 `json:"node_id"`
+	From        time.Time              `json:"from"`
+	To          time.Time              `json:"to"`
+	StepSeconds int                    `json:"step_seconds"`
+	Points      []EdgeNodeMetricsPoint `json:"points"`
+}
+
+// EdgeNodeSiteMetrics 一个站点（节点 location 相同）所有节点采样合并后的资源曲线
+type EdgeNodeSiteMetrics struct {
+	Site   string                 `json:"site"`  // 节点 location，未设置时为空
+	Nodes  int                    `json:"nodes"` // 时间范围内有采样的节点数
+	Points []EdgeNodeMetricsPoint `json:"points"`
+}
+
+// FleetMetricsSeries 按站点分组的机队资源曲线
+type FleetMetricsSeries struct {
+	From        time.Time             `json:"from"`
+	To          time.Time             `json:"to"`
+	StepSeconds int                   `json:"step_seconds"`
+	Sites       []EdgeNodeSiteMetrics `json:"sites"`
+}
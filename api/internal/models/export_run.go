@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// 归档导出状态
+const (
+	ExportStatusPending   = "pending"   // 等待后台任务执行（新建或失败后恢复）
+	ExportStatusRunning   = "running"   // 正在导出
+	ExportStatusCompleted = "completed" // 已导出日期范围内的全部任务
+	ExportStatusFailed    = "failed"    // 导出失败，可从最后导出的任务继续
+)
+
+// 归档导出格式
+const (
+	ExportFormatJSONL   = "jsonl"   // gzip 压缩的 JSON Lines，每行一个任务
+	ExportFormatParquet = "parquet" // 暂不支持
+)
+
+// 归档导出的触发方式
+const (
+	ExportTriggerManual    = "manual"    // 管理员通过接口发起
+	ExportTriggerScheduled = "scheduled" // exports.daily 每日自动导出
+)
+
+// ExportDateLayout 导出日期范围和分区目录使用的日期格式（UTC 自然日）
+const ExportDateLayout = "2006-01-02"
+
+// ExportRun 已完成任务的归档导出：将日期范围内（按任务创建时间，UTC）的已完成任务按天分区写入文件存储
+// 导出按 created_at, id 升序进行，每写完一个文件记录游标，失败后从游标继续，已写入的文件不重复导出
+type ExportRun struct {
+	ID          string       `json:"id"`
+	Status      string       `json:"status"` // pending/running/completed/failed
+	Format      string       `json:"format"`
+	Trigger     string       `json:"trigger"`                // manual/scheduled
+	FromDate    string       `json:"from_date"`              // 起始日期（含），YYYY-MM-DD
+	ToDate      string       `json:"to_date"`                // 结束日期（含），YYYY-MM-DD
+	RequestedBy string       `json:"requested_by,omitempty"` // 发起导出的管理员
+	RowCount    int64        `json:"row_count"`              // 已导出的任务数
+	Files       []ExportFile `json:"files"`                  // 已写入的文件
+
+	// 游标：最后导出的任务（按 created_at, id 升序），继续导出时从其后开始
+	LastJobID        string     `json:"last_job_id,omitempty"`
+	LastJobCreatedAt *time.Time `json:"last_job_created_at,omitempty"`
+
+	Error      string     `json:"error,omitempty"` // 最近一次失败的原因
+	Attempts   int        `json:"attempts"`        // 执行次数（含失败后恢复）
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"` // 导出期间每写完一个文件更新，长时间未更新的 running 导出视为中断
+}
+
+// ExportFile 导出写入的单个文件
+type ExportFile struct {
+	Date   string `json:"date"` // 分区日期，YYYY-MM-DD
+	Key    string `json:"key"`  // 对象键
+	Rows   int    `json:"rows"`
+	Bytes  int64  `json:"bytes"`  // 压缩后的大小
+	SHA256 string `json:"sha256"` // 压缩后内容的 SHA-256（十六进制）
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"fly-print-cloud/api/internal/golden"
+)
+
+// TestEntityJSONGolden 各实体的 JSON 序列化与样例文件一致：empty 为零值（可省略的字段不出现），
+// full 为每个字段都有值；字段、标签或时间格式的变化都需要更新样例文件
+func TestEntityJSONGolden(t *testing.T) {
+	entities := []struct {
+		name string
+		new  func() interface{}
+	}{
+		{"edge_node", func() interface{} { return &EdgeNode{} }},
+		{"printer", func() interface{} { return &Printer{} }},
+		{"print_job", func() interface{} { return &PrintJob{} }},
+		{"print_job_file", func() interface{} { return &PrintJobFile{} }},
+		{"printer_tombstone", func() interface{} { return &PrinterTombstone{} }},
+		{"printer_driver", func() interface{} { return &PrinterDriver{} }},
+		{"user", func() interface{} { return &User{} }},
+		{"audit_log", func() interface{} { return &AuditLog{} }},
+	}
+	for _, entity := range entities {
+		t.Run(entity.name, func(t *testing.T) {
+			golden.AssertJSON(t, entity.name+"_empty", entity.new())
+			full := entity.new()
+			golden.Fill(full)
+			golden.AssertJSON(t, entity.name+"_full", full)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// 机队健康严重程度，按 ok < warning < critical 排序
+const (
+	HealthOK       = "ok"
+	HealthWarning  = "warning"
+	HealthCritical = "critical"
+)
+
+// 机队健康问题类型
+const (
+	FleetProblemNodesOffline        = "nodes_offline"         // Edge Node 离线超过 health.node_offline_after
+	FleetProblemPrintersError       = "printers_error"        // 打印机处于 error 状态
+	FleetProblemPrintersLowSupplies = "printers_low_supplies" // 耗材余量不高于 health.low_supply_percent
+	FleetProblemJobsStalled         = "jobs_stalled"          // 任务处于 stalled 状态
+	FleetProblemPrintersSuspended   = "printers_suspended"    // 打印机熔断，暂停分发
+)
+
+// HealthSeverityLevel 严重程度的数值（Prometheus 指标使用）：ok=0、warning=1、critical=2
+func HealthSeverityLevel(severity string) int {
+	switch severity {
+	case HealthCritical:
+		return 2
+	case HealthWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// FleetProblem 一类需要处理的问题，IDs 为按紧急程度排序的前 health.max_ids 个资源ID
+type FleetProblem struct {
+	Type         string   `json:"type"`
+	Severity     string   `json:"severity"`      // ok/warning/critical
+	Count        int      `json:"count"`         // 全部受影响资源数
+	ResourceType string   `json:"resource_type"` // edge_node/printer/print_job
+	IDs          []string `json:"ids"`
+}
+
+// FleetHealth 机队健康汇总（NOC 看板），Severity 为各类问题中最严重的程度
+type FleetHealth struct {
+	Severity    string         `json:"severity"`
+	Problems    []FleetProblem `json:"problems"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
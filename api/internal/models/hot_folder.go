@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// 共享文件夹文件的处理状态
+const (
+	HotFolderFileProcessing = "processing" // 已领取，正在提交打印任务
+	HotFolderFileSubmitted  = "submitted"  // 已创建打印任务，文件移入 done
+	HotFolderFileFailed     = "failed"     // 文件不符合要求或提交被拒绝，文件移入 failed
+)
+
+// HotFolderFile 共享文件夹中已处理文件的台账记录，按目录、文件名、大小和修改时间识别同一文件，
+// 服务重启或多实例部署时已处理（或正在处理）的文件不会重复提交
+type HotFolderFile struct {
+	ID         string    `json:"id"`
+	Folder     string    `json:"folder"`
+	FileName   string    `json:"file_name"`
+	FileSize   int64     `json:"file_size"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Status     string    `json:"status"`           // processing/submitted/failed
+	JobID      string    `json:"job_id,omitempty"` // 创建的打印任务
+	Error      string    `json:"error,omitempty"`  // 失败原因
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
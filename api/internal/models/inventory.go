@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// 资产清单记录类型
+const (
+	InventoryTypePrinter  = "printer"
+	InventoryTypeEdgeNode = "edge_node"
+)
+
+// InventoryRecord 资产清单导出记录（CMDB 同步）
+// 打印机和 Edge Node 使用同一组字段，字段集合保持稳定：不适用或未上报的字段输出为 null，不省略
+type InventoryRecord struct {
+	Type            string     `json:"type"` // printer / edge_node
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	DisplayName     *string    `json:"display_name"`
+	Model           *string    `json:"model"`
+	SerialNumber    *string    `json:"serial_number"`
+	FirmwareVersion *string    `json:"firmware_version"` // Edge Node 为节点软件版本
+	MACAddress      *string    `json:"mac_address"`
+	IPAddress       *string    `json:"ip_address"`
+	Location        *string    `json:"location"`
+	Latitude        *float64   `json:"latitude"`
+	Longitude       *float64   `json:"longitude"`
+	EdgeNodeID      *string    `json:"edge_node_id"` // 打印机绑定的 Edge Node，Edge Node 记录为 null
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at"` // 已删除的 Edge Node 只在增量导出中出现
+}
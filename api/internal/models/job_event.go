@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// JobEventType 打印任务时间线事件类型
+type JobEventType string
+
+// 打印任务时间线事件类型
+const (
+	JobEventCreated          JobEventType = "created"           // 任务创建（含代提交、批量、重新打印）
+	JobEventDispatched       JobEventType = "dispatched"        // 已下发到 Edge Node
+	JobEventDispatchFailed   JobEventType = "dispatch_failed"   // 下发失败，回退为待分发/排队
+	JobEventStatusChanged    JobEventType = "status_changed"    // Edge Node 上报的状态变化
+	JobEventProgress         JobEventType = "progress"          // 打印进度里程碑（25%/50%/75%）
+	JobEventStalled          JobEventType = "stalled"           // 长时间没有进展
+	JobEventCancelled        JobEventType = "cancelled"         // 被取消
+	JobEventForced           JobEventType = "forced"            // 管理员强制完成/失败
+	JobEventUpdated          JobEventType = "updated"           // 通过管理接口修改
+	JobEventFailed           JobEventType = "failed"            // 云端判定失败（如打印机唤醒超时）
+	JobEventRetried          JobEventType = "retried"           // 已基于该任务重新打印
+	JobEventConverted        JobEventType = "converted"         // 打印机不支持原文件格式，分发前已转换
+	JobEventRequeued         JobEventType = "requeued"          // 硬件错误后在原打印机重试
+	JobEventFailover         JobEventType = "failover"          // 硬件错误反复失败后改投备用打印机
+	JobEventFailoverSkipped  JobEventType = "failover_skipped"  // 应改投备用打印机但备用打印机不可用，任务保持失败
+	JobEventPrinterSuspended JobEventType = "printer_suspended" // 该任务失败使打印机连续失败达到阈值，打印机熔断
+)
+
+// JobProgressMilestone 记录进度事件的间隔（百分比）
+const JobProgressMilestone = 25
+
+// PrintJobEvent 打印任务时间线事件
+type PrintJobEvent struct {
+	ID        int64                  `json:"id"`
+	JobID     string                 `json:"job_id"`
+	Type      JobEventType           `json:"type"`
+	Status    JobStatus              `json:"status,omitempty"`   // 事件发生后的任务状态
+	Progress  *int                   `json:"progress,omitempty"` // 进度事件的百分比
+	Actor     string                 `json:"actor,omitempty"`    // 操作人用户名、edge:<节点ID> 或 system
+	Message   string                 `json:"message,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"` // 如错误码、关联任务ID
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// JobErrorEdgeNodeDeleted 打印机所属的 Edge Node 已删除或禁用（接口错误码与时间线事件 details.error_code）
+const JobErrorEdgeNodeDeleted = "edge_node_deleted"
+
+// JobErrorFormatConversionFailed 分发前的文档格式转换失败（时间线事件 details.error_code）
+const JobErrorFormatConversionFailed = "format_conversion_failed"
+
+// 下发失败原因（print_jobs.dispatch_error 和 dispatch_failed 时间线事件 details.error_code）
+const (
+	DispatchErrorNodeOffline = "node_offline"   // Edge Node 未连接，节点重连后可重新下发
+	DispatchErrorBufferFull  = "buffer_full"    // 节点连接的发送缓冲区已满，稍后可重新下发
+	DispatchErrorMarshal     = "marshal_error"  // 任务无法编码为下发指令，重试不会成功
+	DispatchErrorInternal    = "internal_error" // 云端错误（如文件签名链接生成失败）
+)
+
+// DispatchErrorRetryable 该下发失败原因是否可通过稍后重新下发恢复
+func DispatchErrorRetryable(code string) bool {
+	return code == DispatchErrorNodeOffline || code == DispatchErrorBufferFull || code == DispatchErrorInternal
+}
+
+// 非用户触发事件的操作人
+const (
+	JobEventActorSystem     = "system"
+	JobEventActorEdgePrefix = "edge:"
+)
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// 打印任务元数据限制
+const (
+	MaxJobMetadataKeys        = 20  // 最多键数
+	MaxJobMetadataValueLength = 256 // 单个值的最大字符数
+)
+
+// jobMetadataKeyPattern 元数据键：字母、数字、下划线和连字符，最长 64 个字符
+var jobMetadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidJobMetadataKey 元数据键是否合法
+func ValidJobMetadataKey(key string) bool {
+	return jobMetadataKeyPattern.MatchString(key)
+}
+
+// ValidateJobMetadata 校验打印任务元数据（集成方附加的关联数据，如订单号、工单号）
+func ValidateJobMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxJobMetadataKeys {
+		return fmt.Errorf("metadata 最多 %d 个键", MaxJobMetadataKeys)
+	}
+	for key, value := range metadata {
+		if !ValidJobMetadataKey(key) {
+			return fmt.Errorf("metadata 键 %q 无效（仅允许字母、数字、下划线和连字符，最长 64 个字符）", key)
+		}
+		if utf8.RuneCountInString(value) > MaxJobMetadataValueLength {
+			return fmt.Errorf("metadata 键 %q 的值超过 %d 个字符", key, MaxJobMetadataValueLength)
+		}
+	}
+	return nil
+}
+
+// SortedMetadataKeys 按字典序返回元数据键（用于导出、邮件等需要稳定顺序的场景）
+func SortedMetadataKeys(metadata map[string]string) []string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
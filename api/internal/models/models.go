@@ -5,120 +5,308 @@ import (
 )
 
 // EdgeNode Edge节点
+// 可为空的数据库字段使用指针类型，未设置时不出现在 JSON 中
 type EdgeNode struct {
 	ID              string     `json:"id"`
 	Name            string     `json:"name"`            // 用户友好的显示名称（可修改）
-	Status          string     `json:"status"` // online/offline
+	Status          NodeStatus `json:"status"` // online/offline/maintenance
 	Enabled         bool       `json:"enabled"`         // 云端启用/禁用状态
-	Version         string     `json:"version"`
-	LastHeartbeat   time.Time  `json:"last_heartbeat"`
+	Version         *string    `json:"version,omitempty"`
+	LastHeartbeat   *time.Time `json:"last_heartbeat,omitempty"` // 从未上报心跳时为空
 	DeletedAt       *time.Time `json:"deleted_at,omitempty"` // 软删除时间
+
+	// 排空：不再向节点分发新任务，已分发的任务结束（或到达截止时间）后自动禁用节点
+	Draining        bool       `json:"draining"`
+	DrainStartedAt  *time.Time `json:"drain_started_at,omitempty"`
+	DrainDeadline   *time.Time `json:"drain_deadline,omitempty"`
+
+	// 疑似 node_id 冲突：同一 node_id 短时间内被来自不同地址的连接反复替换（如克隆的虚拟机镜像），管理员确认后清除
+	ConflictSuspected   bool       `json:"conflict_suspected"`
+	ConflictSuspectedAt *time.Time `json:"conflict_suspected_at,omitempty"`
+	ConflictRemoteAddrs []string   `json:"conflict_remote_addrs,omitempty"` // 冲突连接的来源地址
 	
 	// 位置信息
-	Location        string    `json:"location"`        // 地理位置描述
+	Location        *string   `json:"location,omitempty"`      // 地理位置描述
 	Latitude        *float64  `json:"latitude,omitempty"`      // 纬度
 	Longitude       *float64  `json:"longitude,omitempty"`     // 经度
 	
 	// 网络信息
-	IPAddress      *string   `json:"ip_address,omitempty"`      // IP地址
-	MACAddress     string    `json:"mac_address"`     // MAC地址
-	NetworkInterface string  `json:"network_interface"` // 网络接口
+	IPAddress        *string `json:"ip_address,omitempty"`        // IP地址
+	MACAddress       *string `json:"mac_address,omitempty"`       // MAC地址
+	NetworkInterface *string `json:"network_interface,omitempty"` // 网络接口
 	
 	// 系统信息
-	OSVersion      string    `json:"os_version"`      // 操作系统版本
-	CPUInfo        string    `json:"cpu_info"`        // CPU信息
-	MemoryInfo     string    `json:"memory_info"`     // 内存信息
-	DiskInfo       string    `json:"disk_info"`       // 磁盘信息
+	OSVersion      *string   `json:"os_version,omitempty"`      // 操作系统版本
+	CPUInfo        *string   `json:"cpu_info,omitempty"`        // CPU信息
+	MemoryInfo     *string   `json:"memory_info,omitempty"`     // 内存信息
+	DiskInfo       *string   `json:"disk_info,omitempty"`       // 磁盘信息
 	
 	// 连接信息
-	ConnectionQuality string `json:"connection_quality"` // 连接质量
-	Latency         int     `json:"latency"`         // 延迟(ms)
+	ConnectionQuality *string `json:"connection_quality,omitempty"` // 连接质量
+	Latency           *int    `json:"latency,omitempty"`            // 延迟(ms)
 	
+	RowVersion      int       `json:"row_version"` // 数据版本号（乐观锁），管理员每次修改后加一
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
 
+// 打印机审核状态
+const (
+	PrinterApprovalApproved      = "approved"
+	PrinterApprovalPendingReview = "pending_review"
+)
+
 // Printer 打印机
 type Printer struct {
 	ID           string   `json:"id"`
 	Name         string   `json:"name"`          // CUPS printer-name (技术名称，Edge node范围内唯一)
-	DisplayName  string   `json:"display_name"`  // 用户友好的显示名称
+	Slug         string   `json:"slug,omitempty"`          // 全局唯一的可读标识（名称 + 节点短后缀），可代替 ID 使用
+	DisplayName  string   `json:"display_name,omitempty"`  // 用户友好的显示名称
 	Model        string   `json:"model"`
-	SerialNumber string   `json:"serial_number"`    // 序列号
-	Status       string   `json:"status"`           // ready/printing/error/offline
+	SerialNumber *string  `json:"serial_number,omitempty"` // 序列号
+	Status       PrinterStatus `json:"status"`      // ready/printing/error/offline
 	Enabled      bool     `json:"enabled"`          // 云端启用/禁用状态
+	ApprovalStatus string `json:"approval_status"`  // 审核状态：approved/pending_review
 	
 	// 硬件信息
-	FirmwareVersion string `json:"firmware_version"` // 固件版本
-	PortInfo       string `json:"port_info"`        // 端口信息
+	FirmwareVersion *string `json:"firmware_version,omitempty"` // 固件版本
+	PortInfo        *string `json:"port_info,omitempty"`        // 端口信息
 	
 	// 网络信息
-	IPAddress     *string `json:"ip_address"`        // IP地址 (修复：改为指针类型以处理NULL)
-	MACAddress    string `json:"mac_address"`       // MAC地址
-	NetworkConfig string `json:"network_config"`    // 网络配置
+	IPAddress     *string `json:"ip_address,omitempty"`     // IP地址
+	MACAddress    *string `json:"mac_address,omitempty"`    // MAC地址
+	NetworkConfig *string `json:"network_config,omitempty"` // 网络配置
 	
 	// 地理位置信息 (可选)
 	Latitude     *float64 `json:"latitude,omitempty"`      // 纬度
 	Longitude    *float64 `json:"longitude,omitempty"`     // 经度
-	Location     string   `json:"location,omitempty"`      // 位置描述
+	Location     *string  `json:"location,omitempty"`      // 位置描述
 	
 	// 能力信息
 	Capabilities  PrinterCapabilities `json:"capabilities"`
 	
 	// 关联信息
 	EdgeNodeID   string `json:"edge_node_id"`       // 关联Edge Node
-	QueueLength  int    `json:"queue_length"`       // 队列长度
+	QueueLength  int    `json:"queue_length"`       // 队列长度：edge_queue_length 与 cloud_queue_length 的较大值
+	EdgeQueueLength  int `json:"edge_queue_length"`  // 节点上报的本地队列长度
+	CloudQueueLength int `json:"cloud_queue_length"` // 云端未结束的任务数，由定期核对任务更新
+	Supplies     map[string]interface{} `json:"supplies,omitempty"` // 耗材状态（节点上报）
+	PowerState   string `json:"power_state,omitempty"`  // 电源状态：awake/asleep/waking，为空表示节点未上报
+	MaxConcurrentJobs *int `json:"max_concurrent_jobs,omitempty"` // 同时分发的任务上限，为空表示不限制
+	MaxCopies         *int `json:"max_copies,omitempty"`          // 单个任务份数上限，为空时使用全局 jobs.max_copies
+	Reservation       *PrinterReservation `json:"reservation,omitempty"` // 当前有效的预留，没有预留时为空
+
+	// 故障转移（可选）：任务因硬件错误在本打印机反复失败时改投备用打印机
+	BackupPrinterID *string    `json:"backup_printer_id,omitempty"` // 备用打印机，为空表示不启用故障转移
+	NeedsAttention  bool       `json:"needs_attention"`             // 有任务因硬件错误被转移到备用打印机，管理员处理后清除
+	AttentionReason string     `json:"attention_reason,omitempty"`
+	AttentionAt     *time.Time `json:"attention_at,omitempty"`
+
+	// 熔断：连续失败过多时暂停分发，新任务排队
+	Breaker PrinterBreaker `json:"breaker"`
+
+	// 队列暂停：管理员暂停后不再分发新任务（已分发的任务继续），新任务排队；恢复或到达自动恢复时间后分发
+	QueuePaused       bool       `json:"queue_paused"`
+	QueuePausedBy     string     `json:"queue_paused_by,omitempty"`
+	QueuePausedAt     *time.Time `json:"queue_paused_at,omitempty"`
+	QueuePauseReason  string     `json:"queue_pause_reason,omitempty"`
+	QueueAutoResumeAt *time.Time `json:"queue_auto_resume_at,omitempty"` // 为空表示不自动恢复
+	
+	// 计费覆盖（为空时使用全局定价）
+	PricePerPageMono  *float64 `json:"price_per_page_mono,omitempty"`  // 黑白单价
+	PricePerPageColor *float64 `json:"price_per_page_color,omitempty"` // 彩色单价
+	DuplexDiscount    *float64 `json:"duplex_discount,omitempty"`      // 双面折扣（0~1）
 	
+	RowVersion   int       `json:"row_version"` // 数据版本号（乐观锁），管理员每次修改后加一
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // PrinterCapabilities 打印机能力
 type PrinterCapabilities struct {
-	PaperSizes   []string `json:"paper_sizes"`     // 支持的纸张尺寸
+	PaperSizes   []string `json:"paper_sizes"`     // 支持的纸张尺寸（规范ID，无法识别的写法原样保留）
+	UnknownPaperSizes []string `json:"unknown_paper_sizes,omitempty"` // paper_sizes 中无法识别的写法
 	ColorSupport bool     `json:"color_support"`   // 是否支持彩色
 	DuplexSupport bool    `json:"duplex_support"`  // 是否支持双面
 	Resolution   string   `json:"resolution"`      // 分辨率
 	PrintSpeed   string   `json:"print_speed"`     // 打印速度
 	MediaTypes   []string `json:"media_types"`     // 支持的介质类型
+	DocumentFormats []string `json:"document_formats,omitempty"` // 可直接打印的文档格式（格式名或 MIME 类型，如 pdf、application/postscript），为空表示未上报
 }
 
+// 打印任务页数来源
+const (
+	PageCountSourceClient = "client"
+	PageCountSourceServer = "server"
+)
+
 // PrintJob 打印任务
 type PrintJob struct {
 	ID           string    `json:"id"`
 	Name         string    `json:"name"`
-	Status       string    `json:"status"`        // pending/dispatched/downloading/printing/completed/failed/cancelled
+	DisplayName  string    `json:"display_name"`          // 面向用户的名称（释放站、通知、Edge 本地队列），默认取 PDF 标题或整理后的文件名；旧任务为 name
+	SourceName   string    `json:"source_name,omitempty"` // 原始文件名（URL 或路径的最后一段，未整理）
+	Source       JobSource `json:"source"`                // 提交来源（console/api/integration/email/hot_folder），记录来源之前的任务为 unknown
+	Status       JobStatus `json:"status"`        // pending/dispatched/downloading/printing/completed/failed/cancelled
+	Progress     int       `json:"progress"`      // Edge Node 上报的打印进度（百分比），状态不变时按 jobs.progress_write_interval 节流写入
 	
 	// 关联信息
-	PrinterID    string    `json:"printer_id"`
+	PrinterID    string    `json:"printer_id"`    // 打印机被删除后为空，历史任务保留
+	PrinterName  string    `json:"printer_name,omitempty"` // 打印机被删除时记录的名称，用于报表
 	UserID       string    `json:"user_id"`       // 提交用户
-	UserName     string    `json:"user_name"`     // 提交用户名
+	UserName     string    `json:"user_name,omitempty"`     // 提交用户名
 	
 	// 任务信息
-	FilePath     string    `json:"file_path"`     // 文件路径（本地文件）
-	FileURL      string    `json:"file_url"`      // 文件URL（第三方API使用）
+	FilePath     string    `json:"file_path,omitempty"`     // 文件路径（本地文件）
+	FileURL      string    `json:"file_url,omitempty"`      // 文件URL（第三方API使用）
+	StorageKey   string    `json:"storage_key,omitempty"` // 云端存储的对象键（上传到云端的文件）
 	FileSize     int64     `json:"file_size"`     // 文件大小
+	SourceFormat string    `json:"source_format,omitempty"` // 云端文件的检测格式（见 docformat）
+	ConvertedStorageKey string `json:"converted_storage_key,omitempty"` // 打印机不支持原格式时，分发前转换得到的文件（下发此文件）
+	ConvertedFormat     string `json:"converted_format,omitempty"`
+	ConvertedFileSize   int64  `json:"converted_file_size,omitempty"`
+	FilePurged   bool       `json:"file_purged"`              // 文件内容已按保留期限删除，任务记录保留
+	FilePurgedAt *time.Time `json:"file_purged_at,omitempty"` // 文件内容删除时间
 	PageCount    int       `json:"page_count"`    // 页数
+	PageCountSource string `json:"page_count_source"` // 页数来源：client（客户端声明）/server（服务端解析）
+	Files        []PrintJobFile `json:"files,omitempty"` // 按打印顺序排列的文件（多文件任务），仅详情接口返回
+	RecentEvents []*PrintJobEvent `json:"recent_events,omitempty"` // 最近的时间线事件，仅详情接口返回
 	Copies       int       `json:"copies"`        // 份数
+	PageRange    string    `json:"page_range,omitempty"` // 只打印的页码（如 1-5,8,11-20），page_count 为选中的页数；仅单文件任务
 	
 	// 打印设置
-	PaperSize    string    `json:"paper_size"`
-	ColorMode    string    `json:"color_mode"`    // color/grayscale
-	DuplexMode   string    `json:"duplex_mode"`   // single/duplex
+	PaperSize    string    `json:"paper_size,omitempty"`
+	ColorMode    string    `json:"color_mode,omitempty"`    // color/grayscale
+	DuplexMode   string    `json:"duplex_mode,omitempty"`   // single/duplex
+	MediaType    string    `json:"media_type,omitempty"`    // 介质类型，需在打印机 media_types 中
+	Resolution   string    `json:"resolution,omitempty"`    // 分辨率（dpi，如 600 或 600x600），不超过打印机上报的最高分辨率
+	
+	// 打印策略（创建时生效的策略名称，按评估顺序；强制调整仅在创建响应中返回，并记录在创建事件中）
+	AppliedPolicies   []string           `json:"applied_policies,omitempty"`
+	PolicyAdjustments []PolicyAdjustment `json:"policy_adjustments,omitempty"`
 	
 	// 执行信息
-	StartTime    time.Time `json:"start_time"`
-	EndTime      time.Time `json:"end_time"`
-	ErrorMessage string    `json:"error_message"`
+	DispatchedAt *time.Time `json:"dispatched_at,omitempty"` // 最近一次下发给 Edge Node 的时间，退回待分发/排队时清空
+	NotBefore    *time.Time `json:"not_before,omitempty"`    // 提交时打印机不在营业时间（when_closed=schedule），排队到下次营业时间再分发
+	StartTime    *time.Time `json:"start_time,omitempty"`
+	EndTime      *time.Time `json:"end_time,omitempty"`
+	ErrorMessage string     `json:"error_message,omitempty"` // 超出 text_limits.max_length 时已截断，末尾注明原始长度
+	ErrorMessageKey     string `json:"-"`                               // 截断前的完整错误信息在文件存储中的对象键
+	ErrorMessageSpilled bool   `json:"error_message_spilled,omitempty"` // 完整错误信息已转存，管理员可下载
+	DispatchError string    `json:"dispatch_error,omitempty"` // 最近一次下发失败的原因（见 DispatchError* 常量），下发成功后清空
 	
 	// 重试信息
-	RetryCount   int       `json:"retry_count"`
+	RetryCount   int       `json:"retry_count"` // 在当前打印机上因硬件错误失败后云端重试的次数（故障转移），改投备用打印机后清零
 	MaxRetries   int       `json:"max_retries"`
+	PagesPrinted   int     `json:"pages_printed"`              // 节点上报的已输出页数（各份累计），大于 0 的任务失败后不重试也不转移；部分完成的任务按此计费
+	FailedOverFrom string  `json:"failed_over_from,omitempty"` // 因硬件故障从该打印机转移而来（每个任务最多转移一次）
+	
+	// 调度信息（排队时按优先级从高到低分发）
+	Priority     int       `json:"priority"`
+	BatchID      string    `json:"batch_id,omitempty"` // 批量提交时的批次ID
+	PrinterGroupID  string `json:"printer_group_id,omitempty"` // 提交到打印机组时的组ID
+	RoutingStrategy string `json:"routing_strategy,omitempty"` // 提交到打印机组时选择打印机所用的策略
+	
+	// 集成方附加的关联数据（订单号、工单号等），原样下发给 Edge Node 并随任务返回
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	
+	// 操作信息（管理员代为操作时记录实际操作人）
+	PerformedBy  string    `json:"performed_by,omitempty"`
+
+	// 提交任务的客户端（X-Client 请求头），未提供时为空
+	Client       *ClientInfo `json:"client,omitempty"`
+
+	// 创建请求携带的幂等键（保存在 print_job_idempotency_keys，不随任务返回）
+	IdempotencyKey string  `json:"-"`
+	
+	// SLA 考核结果（功能上线前的历史任务为空）
+	SLA          *JobSLA   `json:"sla,omitempty"`
+	
+	// 计费信息（任务完成时计算）
+	Cost         *float64  `json:"cost,omitempty"`
 	
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// BilledPages 计费和用量统计的页数：部分完成的任务按节点上报的已输出页数（不超过页数 × 份数），其他任务按页数 × 份数
+func (j *PrintJob) BilledPages() int {
+	copies := j.Copies
+	if copies <= 0 {
+		copies = 1
+	}
+	pages := j.PageCount * copies
+	if j.Status == JobStatusPartiallyCompleted && (pages == 0 || j.PagesPrinted < pages) {
+		return j.PagesPrinted
+	}
+	return pages
+}
+
+// PrinterReservation 自助打印终端对打印机的临时预留：预留期间只分发预留用户的任务，其他用户的任务排队
+type PrinterReservation struct {
+	Holder     string    `json:"holder"` // 预留用户（与任务的 user_name 比较）
+	ReservedAt time.Time `json:"reserved_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Active 预留在 now 时是否仍然有效
+func (r *PrinterReservation) Active(now time.Time) bool {
+	return r != nil && now.Before(r.ExpiresAt)
+}
+
+// Blocks 预留在 now 时是否阻止 userName 的任务分发到该打印机
+func (r *PrinterReservation) Blocks(userName string, now time.Time) bool {
+	return r.Active(now) && r.Holder != userName
+}
+
+// PrinterTombstone 已删除打印机的墓碑：有效期内阻止 Edge Node 按 名称 + 节点 重新注册
+type PrinterTombstone struct {
+	ID         string    `json:"id"`
+	PrinterID  string    `json:"printer_id"` // 被删除的打印机ID
+	Name       string    `json:"name"`       // CUPS printer-name
+	EdgeNodeID string    `json:"edge_node_id"`
+	DeletedBy  string    `json:"deleted_by,omitempty"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	ExpiresAt  time.Time `json:"expires_at"` // 到期后自动清除，节点可重新注册
+}
+
+// PrintJobUpdate 打印任务的部分更新（写模型），只写入非空字段，未提供的字段保持数据库中的值
+// 提交用户和打印机在创建后不可修改，因此不在其中
+type PrintJobUpdate struct {
+	Name            *string
+	DisplayName     *string
+	Status          *JobStatus
+	FilePath        *string
+	FileURL         *string
+	FileSize        *int64
+	PageCount       *int
+	PageCountSource *string
+	Copies          *int
+	PaperSize       *string
+	ColorMode       *string
+	DuplexMode      *string
+	StartTime       *time.Time
+	EndTime         *time.Time
+	ErrorMessage    *string
+	RetryCount      *int
+	MaxRetries      *int
+	Cost            *float64
+}
+
+// PrintJobFile 打印任务中的单个文件（多文件任务按 position 顺序打印）
+type PrintJobFile struct {
+	ID         string    `json:"id"`
+	JobID      string    `json:"job_id"`
+	Position   int       `json:"position"`              // 打印顺序，从 1 开始
+	FilePath   string    `json:"file_path,omitempty"`
+	FileURL    string    `json:"file_url,omitempty"`
+	StorageKey string    `json:"storage_key,omitempty"`
+	FileSize   int64     `json:"file_size"`
+	PageCount  int       `json:"page_count"`
+	Copies     int       `json:"copies"`                // 该文件的份数，默认 1
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 // User 用户
 type User struct {
 	ID           string    `json:"id"`
@@ -128,7 +316,153 @@ type User struct {
 	ExternalID   *string   `json:"external_id,omitempty"` // OAuth2 外部ID
 	Role         string    `json:"role"`         // 角色: admin/operator/viewer
 	Status       string    `json:"status"`       // 状态: active/inactive
-	LastLogin    time.Time `json:"last_login"`   // 最后登录时间
+	NotifyOnCompletion bool `json:"notify_on_completion"` // 任务完成时邮件通知
+	NotifyOnFailure    bool `json:"notify_on_failure"`    // 任务失败时邮件通知
+	LastLogin    *time.Time `json:"last_login,omitempty"` // 最后登录时间，从未登录时为空
+	RowVersion   int       `json:"row_version"` // 数据版本号（乐观锁），管理员每次修改后加一
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
+
+// CostStat 打印费用统计
+type CostStat struct {
+	Key       string  `json:"key"`        // 用户ID（没有用户ID的任务为用户名）或打印机ID
+	Name      string  `json:"name"`       // 显示名称
+	JobCount  int     `json:"job_count"`  // 已完成任务数（含部分完成）
+	PartialJobCount int `json:"partial_job_count"` // 其中部分完成的任务数
+	Pages     int     `json:"pages"`      // 计费页数（页数 × 份数，部分完成的任务为已输出页数）
+	TotalCost float64 `json:"total_cost"` // 费用合计
+}
+
+// SourceStat 按提交来源统计的任务数
+type SourceStat struct {
+	Source         JobSource `json:"source"`
+	JobCount       int       `json:"job_count"`       // 统计时间范围内提交的任务数
+	CompletedCount int       `json:"completed_count"` // 其中已完成的任务数（含部分完成）
+	FailedCount    int       `json:"failed_count"`
+	CancelledCount int       `json:"cancelled_count"`
+	Pages          int       `json:"pages"` // 计费页数（页数 × 份数，部分完成的任务为已输出页数）
+}
+
+// EdgeNodeStats Edge Node 的任务统计与当前负载
+type EdgeNodeStats struct {
+	EdgeNodeID         string         `json:"edge_node_id"`
+	ActiveJobs         map[string]int `json:"active_jobs"`          // 未结束任务按状态计数（节点下所有打印机）
+	ActiveJobTotal     int            `json:"active_job_total"`
+	CompletedToday     int            `json:"completed_today"`      // 今天（按 timezone 划分）完成的任务数
+	CompletedLast7Days int            `json:"completed_last_7_days"`
+	FailedLast7Days    int            `json:"failed_last_7_days"`
+	FailureRate        float64        `json:"failure_rate"`         // 最近7天失败数 / (完成数 + 失败数)，没有结束的任务时为 0
+	AvgLatencySeconds  *float64       `json:"avg_latency_seconds"`  // 最近7天完成任务从首次分发到完成的平均耗时，没有数据时为 null
+	PrinterStatuses    map[string]int `json:"printer_statuses"`     // 打印机按状态计数
+	Timezone           string         `json:"timezone"`             // 划分“今天”使用的时区
+	GeneratedAt        time.Time      `json:"generated_at"`
+}
+
+// EdgeNodeSummary 提供给节点本地看板的汇总（只包含该节点自己的数据）
+type EdgeNodeSummary struct {
+	EdgeNodeID  string                    `json:"edge_node_id"`
+	Stats       *EdgeNodeStats            `json:"stats"` // 与管理端节点统计相同的口径
+	Printers    []*EdgeNodePrinterSummary `json:"printers"`
+	Alerts      []EdgeNodeAlert           `json:"alerts"` // 当前仍存在的告警
+	Timezone    string                    `json:"timezone"`
+	GeneratedAt time.Time                 `json:"generated_at"`
+}
+
+// EdgeNodePrinterSummary 看板上单台打印机的状态和今日任务数
+type EdgeNodePrinterSummary struct {
+	ID             string        `json:"id"`
+	Name           string        `json:"name"`
+	DisplayName    string        `json:"display_name,omitempty"`
+	Status         PrinterStatus `json:"status"`
+	Enabled        bool          `json:"enabled"`
+	QueueLength    int           `json:"queue_length"` // 节点上报的本地队列长度
+	ActiveJobs     int           `json:"active_jobs"`  // 云端未结束的任务数（含排队中）
+	CompletedToday int           `json:"completed_today"`
+	FailedToday    int           `json:"failed_today"`
+	StalledJobs    int           `json:"stalled_jobs"`
+}
+
+// PrinterJobCounts 单台打印机的任务计数
+type PrinterJobCounts struct {
+	Active         int
+	CompletedToday int
+	FailedToday    int
+	Stalled        int
+}
+
+// 节点看板告警类型
+const (
+	EdgeAlertNodeConflict     = "node_conflict_suspected" // 疑似 node_id 冲突
+	EdgeAlertNodeDraining     = "node_draining"
+	EdgeAlertPrinterError     = "printer_error"
+	EdgeAlertPrinterOffline   = "printer_offline"
+	EdgeAlertPrinterReview    = "printer_pending_review"
+	EdgeAlertJobsStalled      = "jobs_stalled"
+	EdgeAlertPrinterAttention = "printer_needs_attention" // 有任务因硬件错误被转移到备用打印机
+	EdgeAlertPrinterSuspended = "printer_suspended"       // 连续任务失败触发熔断，暂停分发
+)
+
+// EdgeNodeAlert 节点看板告警，printer_id 为空表示节点级告警
+type EdgeNodeAlert struct {
+	Type      string `json:"type"`
+	Severity  string `json:"severity"` // warning/critical
+	PrinterID string `json:"printer_id,omitempty"`
+	Message   string `json:"message"`
+}
+
+// AuditLog 审计日志
+type AuditLog struct {
+	ID           string    `json:"id"`
+	Actor        string    `json:"actor"`         // 操作人用户名
+	ActorID      string    `json:"actor_id,omitempty"`      // 操作人外部ID
+	Action       string    `json:"action"`        // 操作类型，如 print_job.cancel
+	ResourceType string    `json:"resource_type"` // 资源类型
+	ResourceID   string    `json:"resource_id"`   // 资源ID
+	Details      string    `json:"details,omitempty"`       // 详细信息
+	Client       *ClientInfo `json:"client,omitempty"`      // 发起操作的客户端（X-Client 请求头）
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PrinterDriver 打印机驱动/PPD 元数据
+type PrinterDriver struct {
+	ID           string            `json:"id"`
+	ModelPattern string            `json:"model_pattern"`          // 型号匹配模式，支持 * 和 ? 通配符
+	DriverName   string            `json:"driver_name"`            // 驱动名称
+	PPDFile      string            `json:"ppd_file,omitempty"`     // PPD 文件名（已上传时）
+	PPDSize      int64             `json:"ppd_size,omitempty"`     // PPD 文件大小
+	Options      map[string]string `json:"options,omitempty"`      // 驱动选项
+	Priority     int               `json:"priority"`               // 优先级，数值越大越优先
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// 诊断包请求状态
+const (
+	DiagnosticsStatusRequested = "requested"
+	DiagnosticsStatusUploaded  = "uploaded"
+	DiagnosticsStatusExpired   = "expired"
+)
+
+// DiagnosticsRequest Edge Node 诊断包收集请求
+type DiagnosticsRequest struct {
+	ID          string     `json:"id"`
+	EdgeNodeID  string     `json:"edge_node_id"`
+	Status      string     `json:"status"`       // requested/uploaded/expired
+	RequestedBy string     `json:"requested_by,omitempty"` // 发起请求的管理员
+	FileName    string     `json:"file_name,omitempty"`
+	FileSize    int64      `json:"file_size"`
+	UploadedAt  *time.Time `json:"uploaded_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"` // 未上传时为上传截止时间，已上传时为归档删除时间
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+
+// PrinterAccessPolicy 打印机访问策略：打印机配置了策略后，只有具备其中任一角色的用户可以使用
+type PrinterAccessPolicy struct {
+	ID        string    `json:"id"`
+	PrinterID string    `json:"printer_id"`
+	Role      string    `json:"role"` // IdP 角色/用户组
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
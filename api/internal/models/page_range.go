@@ -0,0 +1,120 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PageSpan 页码区间（从 1 开始，包含两端），Last 为 0 表示到文档最后一页
+type PageSpan struct {
+	First int
+	Last  int
+}
+
+// ParsePageRange 解析页码范围（CUPS page-ranges 写法，如 "1-5,8,11-"）
+// 区间必须按页码升序排列且互不重叠，只有最后一个区间可以省略结束页
+func ParsePageRange(s string) ([]PageSpan, error) {
+	parts := strings.Split(s, ",")
+	spans := make([]PageSpan, 0, len(parts))
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		span := PageSpan{}
+		var err error
+		if span.First, err = strconv.Atoi(strings.TrimSpace(first)); err != nil || span.First < 1 {
+			return nil, fmt.Errorf("页码范围 %q 无效", part)
+		}
+		span.Last = span.First
+		if isRange {
+			last = strings.TrimSpace(last)
+			if last == "" {
+				if i != len(parts)-1 {
+					return nil, fmt.Errorf("页码范围 %q 只能出现在最后", part)
+				}
+				span.Last = 0
+			} else if span.Last, err = strconv.Atoi(last); err != nil || span.Last < span.First {
+				return nil, fmt.Errorf("页码范围 %q 无效", part)
+			}
+		}
+		if i > 0 && span.First <= spans[i-1].Last {
+			return nil, errors.New("页码范围必须按升序排列且不能重叠")
+		}
+		spans = append(spans, span)
+	}
+	return spans, nil
+}
+
+// FormatPageRange 将页码区间格式化为 page-ranges 写法
+func FormatPageRange(spans []PageSpan) string {
+	parts := make([]string, len(spans))
+	for i, span := range spans {
+		switch {
+		case span.Last == 0:
+			parts[i] = fmt.Sprintf("%d-", span.First)
+		case span.Last == span.First:
+			parts[i] = strconv.Itoa(span.First)
+		default:
+			parts[i] = fmt.Sprintf("%d-%d", span.First, span.Last)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// ResolvePageRange 按文档页数规范化页码范围，返回规范化后的写法和选中的页数
+// documentPages 为 0（页数未知）时只校验写法，选中页数返回 0；已知页数时补全省略的结束页，超出文档页数时报错
+func ResolvePageRange(s string, documentPages int) (string, int, error) {
+	spans, err := ParsePageRange(s)
+	if err != nil {
+		return "", 0, err
+	}
+	if documentPages <= 0 {
+		return FormatPageRange(spans), 0, nil
+	}
+
+	selected := 0
+	for i := range spans {
+		if spans[i].Last == 0 {
+			spans[i].Last = documentPages
+		}
+		if spans[i].Last > documentPages || spans[i].First > spans[i].Last {
+			return "", 0, fmt.Errorf("页码范围超出文档页数（共 %d 页）", documentPages)
+		}
+		selected += spans[i].Last - spans[i].First + 1
+	}
+	return FormatPageRange(spans), selected, nil
+}
+
+// ResumePageRange 部分完成的任务续打时的页码范围：从中断处的下一页到本份结束
+// 已输出的整份不再续打（需要时通过份数重打）；任务未部分完成、页数未知或页码范围未规范化时返回空
+func ResumePageRange(job *PrintJob) string {
+	if job.Status != JobStatusPartiallyCompleted || job.PagesPrinted <= 0 || job.PageCount <= 0 {
+		return ""
+	}
+	spans := []PageSpan{{First: 1, Last: job.PageCount}}
+	if job.PageRange != "" {
+		var err error
+		if spans, err = ParsePageRange(job.PageRange); err != nil {
+			return ""
+		}
+	}
+
+	// 跳过本份中已输出的页
+	skip := job.PagesPrinted % job.PageCount
+	if skip == 0 {
+		return ""
+	}
+	for i, span := range spans {
+		if span.Last == 0 {
+			return ""
+		}
+		pages := span.Last - span.First + 1
+		if skip < pages {
+			remaining := append([]PageSpan{{First: span.First + skip, Last: span.Last}}, spans[i+1:]...)
+			return FormatPageRange(remaining)
+		}
+		skip -= pages
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package models
+
+import "testing"
+
+// TestBilledPages 部分完成的任务按已输出页数计费和计入用量，不超过页数 × 份数；其他任务按页数 × 份数
+func TestBilledPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  JobStatus
+		pages   int
+		copies  int
+		printed int
+		want    int
+	}{
+		{"completed", JobStatusCompleted, 100, 1, 0, 100},
+		{"completed with copies", JobStatusCompleted, 10, 3, 0, 30},
+		{"completed ignores printed", JobStatusCompleted, 10, 3, 7, 30},
+		{"copies unset", JobStatusCompleted, 10, 0, 0, 10},
+		{"jam at page 60", JobStatusPartiallyCompleted, 100, 1, 60, 60},
+		{"jam in second copy", JobStatusPartiallyCompleted, 10, 3, 14, 14},
+		{"printed beyond total is capped", JobStatusPartiallyCompleted, 10, 2, 25, 20},
+		{"printed equals total", JobStatusPartiallyCompleted, 10, 2, 20, 20},
+		{"unknown page count", JobStatusPartiallyCompleted, 0, 1, 7, 7},
+		{"failed job", JobStatusFailed, 10, 1, 4, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &PrintJob{Status: tt.status, PageCount: tt.pages, Copies: tt.copies, PagesPrinted: tt.printed}
+			if got := job.BilledPages(); got != tt.want {
+				t.Fatalf("BilledPages = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePageRange(t *testing.T) {
+	tests := []struct {
+		in       string
+		pages    int
+		want     string
+		selected int
+		wantErr  bool
+	}{
+		{"1-5,8,11-", 20, "1-5,8,11-20", 16, false},
+		{" 3 - 4 , 6 ", 10, "3-4,6", 3, false},
+		{"7-", 0, "7-", 0, false},
+		{"5-5", 10, "5", 1, false},
+		{"1-30", 20, "", 0, true},
+		{"21", 20, "", 0, true},
+		{"0-3", 10, "", 0, true},
+		{"5-3", 10, "", 0, true},
+		{"3-,5", 10, "", 0, true},
+		{"1-5,4-6", 10, "", 0, true},
+		{"8,2", 10, "", 0, true},
+		{"a-b", 10, "", 0, true},
+		{"", 10, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, selected, err := ResolvePageRange(tt.in, tt.pages)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolvePageRange(%q, %d) error = %v, wantErr %v", tt.in, tt.pages, err, tt.wantErr)
+			}
+			if got != tt.want || selected != tt.selected {
+				t.Fatalf("ResolvePageRange(%q, %d) = %q, %d; want %q, %d", tt.in, tt.pages, got, selected, tt.want, tt.selected)
+			}
+		})
+	}
+}
+
+// TestResumePageRange 续打从中断处的下一页到本份结束，已输出的整份不再续打
+func TestResumePageRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    JobStatus
+		pages     int
+		pageRange string
+		printed   int
+		want      string
+	}{
+		{"jam at page 60", JobStatusPartiallyCompleted, 100, "", 60, "61-100"},
+		{"jam in second copy", JobStatusPartiallyCompleted, 10, "", 14, "5-10"},
+		{"whole copies printed", JobStatusPartiallyCompleted, 10, "", 20, ""},
+		{"within a range", JobStatusPartiallyCompleted, 8, "1-5,8,11-12", 2, "3-5,8,11-12"},
+		{"across ranges", JobStatusPartiallyCompleted, 8, "1-5,8,11-12", 6, "11-12"},
+		{"last page of a span", JobStatusPartiallyCompleted, 8, "1-5,8,11-12", 5, "8,11-12"},
+		{"open range", JobStatusPartiallyCompleted, 8, "3-", 2, ""},
+		{"completed job", JobStatusCompleted, 10, "", 4, ""},
+		{"nothing printed", JobStatusPartiallyCompleted, 10, "", 0, ""},
+		{"unknown page count", JobStatusPartiallyCompleted, 0, "", 4, ""},
+		{"invalid stored range", JobStatusPartiallyCompleted, 10, "x", 4, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &PrintJob{Status: tt.status, PageCount: tt.pages, PageRange: tt.pageRange, PagesPrinted: tt.printed, Copies: 2}
+			if got := ResumePageRange(job); got != tt.want {
+				t.Fatalf("ResumePageRange = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
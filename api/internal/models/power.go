@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// 打印机电源状态
+const (
+	PrinterPowerAwake  = "awake"
+	PrinterPowerAsleep = "asleep"
+	PrinterPowerWaking = "waking" // 云端已下发唤醒指令，等待节点上报
+)
+
+// 打印机电源指令
+const (
+	PowerActionSleep = "sleep"
+	PowerActionWake  = "wake"
+)
+
+// PrinterPowerSchedule 打印机节能计划：在计划时段内让打印机休眠
+// 计划可设置在打印机上，也可设置在 Edge Node 上作用于其下所有没有单独计划的打印机
+type PrinterPowerSchedule struct {
+	ID         string    `json:"id"`
+	PrinterID  *string   `json:"printer_id,omitempty"`
+	EdgeNodeID *string   `json:"edge_node_id,omitempty"`
+	Days       []int     `json:"days"`       // 休眠开始的星期（0=周日 … 6=周六）
+	StartTime  string    `json:"start_time"` // 休眠开始时间 HH:MM
+	EndTime    string    `json:"end_time"`   // 唤醒时间 HH:MM，不晚于开始时间时表示跨越午夜
+	Timezone   string    `json:"timezone"`   // IANA 时区，如 Asia/Shanghai
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// PrinterPowerTarget 节能计划调度对象：打印机及其生效的计划
+type PrinterPowerTarget struct {
+	PrinterID   string
+	PrinterName string
+	EdgeNodeID  string
+	Status      PrinterStatus
+	PowerState  string
+	Schedule    *PrinterPowerSchedule
+}
+
+// ParseClock 解析 HH:MM，返回距午夜的分钟数
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate 校验计划的星期、时间和时区
+func (s *PrinterPowerSchedule) Validate() error {
+	if len(s.Days) == 0 {
+		return fmt.Errorf("days must not be empty")
+	}
+	for _, day := range s.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("invalid day %d, expected 0-6", day)
+		}
+	}
+	start, err := ParseClock(s.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := ParseClock(s.EndTime)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("start_time and end_time must differ")
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", s.Timezone)
+	}
+	return nil
+}
+
+// AsleepAt 判断 t 时刻是否处于休眠时段；跨午夜的时段归属于开始的那一天
+func (s *PrinterPowerSchedule) AsleepAt(t time.Time) bool {
+	if s == nil || !s.Enabled {
+		return false
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err := ParseClock(s.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := ParseClock(s.EndTime)
+	if err != nil {
+		return false
+	}
+
+	return inClockWindow(s.Days, start, end, t.In(loc))
+}
+
+// inClockWindow 判断本地时间是否处于按星期和时段定义的窗口内
+// days 为空表示每天；start == end 表示全天；start > end 表示跨越午夜，归属于开始的那一天
+func inClockWindow(days []int, start, end int, local time.Time) bool {
+	minute := local.Hour()*60 + local.Minute()
+	today := int(local.Weekday())
+	yesterday := (today + 6) % 7
+
+	hasDay := func(day int) bool {
+		if len(days) == 0 {
+			return true
+		}
+		for _, d := range days {
+			if d == day {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case start == end:
+		return hasDay(today)
+	case start < end:
+		return hasDay(today) && minute >= start && minute < end
+	}
+	// 跨午夜：当天开始时间之后，或前一天开始、尚未到结束时间
+	return (hasDay(today) && minute >= start) || (hasDay(yesterday) && minute < end)
+}
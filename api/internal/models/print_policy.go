@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// PolicyAction 打印策略对任务参数的处理方式
+type PolicyAction string
+
+// 打印策略处理方式
+// force 直接把任务参数改为合规的取值；forbid 拒绝明确请求了不合规取值的任务，
+// 未指定该参数的任务同样被设为合规取值（避免打印机默认值绕过策略）
+const (
+	PolicyActionForce  PolicyAction = "force"
+	PolicyActionForbid PolicyAction = "forbid"
+)
+
+// validPolicyAction 判断是否为可识别的处理方式（空表示策略不约束该参数）
+func validPolicyAction(action PolicyAction) bool {
+	return action == "" || action == PolicyActionForce || action == PolicyActionForbid
+}
+
+// PrintPolicy 组织级打印策略：满足条件的任务按效果强制调整或拒绝
+// 按 priority 从高到低评估，同一参数由第一条命中的策略决定
+type PrintPolicy struct {
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Priority    int                   `json:"priority"` // 越大越先评估，相同时按名称排序
+	Enabled     bool                  `json:"enabled"`
+	Conditions  PrintPolicyConditions `json:"conditions"`
+	Effects     PrintPolicyEffects    `json:"effects"`
+	CreatedBy   string                `json:"created_by,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// PrintPolicyConditions 策略生效条件，全部满足时策略生效；未设置的条件不限制
+type PrintPolicyConditions struct {
+	Days            []int    `json:"days,omitempty"`              // 生效的星期（0=周日 … 6=周六），为空表示每天
+	StartTime       string   `json:"start_time,omitempty"`        // 生效时段 HH:MM，与 end_time 同时为空表示全天
+	EndTime         string   `json:"end_time,omitempty"`          // 不晚于开始时间时表示跨越午夜
+	Timezone        string   `json:"timezone,omitempty"`          // IANA 时区，默认 UTC
+	Roles           []string `json:"roles,omitempty"`             // 提交人具备其中任一角色时生效
+	PrinterGroupIDs []string `json:"printer_group_ids,omitempty"` // 目标打印机属于其中任一打印机组时生效
+	ExemptUsers     []string `json:"exempt_users,omitempty"`      // 白名单用户（用户名），不受策略约束
+}
+
+// PrintPolicyEffects 策略效果
+type PrintPolicyEffects struct {
+	Color     PolicyAction `json:"color,omitempty"`      // 彩色打印：force 改为黑白，forbid 拒绝彩色任务
+	Simplex   PolicyAction `json:"simplex,omitempty"`    // 单面打印：force 改为双面（打印机不支持双面时不调整），forbid 拒绝单面任务
+	MaxCopies *int         `json:"max_copies,omitempty"` // 份数上限（任务份数和多文件任务中每个文件的份数）
+	Copies    PolicyAction `json:"copies,omitempty"`     // 超过 max_copies 时：force 降为上限，forbid（默认）拒绝
+}
+
+// IsEmpty 判断策略是否没有任何效果
+func (e *PrintPolicyEffects) IsEmpty() bool {
+	return e.Color == "" && e.Simplex == "" && e.MaxCopies == nil
+}
+
+// PolicyAdjustment 策略对任务参数的一次强制调整
+type PolicyAdjustment struct {
+	Policy string `json:"policy"` // 策略名称
+	Field  string `json:"field"`  // 请求字段，多文件任务的文件份数为 files[i].copies
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// Validate 校验策略的时段、时区和效果
+func (p *PrintPolicy) Validate() error {
+	cond := &p.Conditions
+	for _, day := range cond.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("invalid day %d, expected 0-6", day)
+		}
+	}
+	if (cond.StartTime == "") != (cond.EndTime == "") {
+		return fmt.Errorf("start_time and end_time must be set together")
+	}
+	if cond.StartTime != "" {
+		start, err := ParseClock(cond.StartTime)
+		if err != nil {
+			return err
+		}
+		end, err := ParseClock(cond.EndTime)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return fmt.Errorf("start_time and end_time must differ")
+		}
+	}
+	if cond.Timezone != "" {
+		if _, err := time.LoadLocation(cond.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", cond.Timezone)
+		}
+	}
+
+	effects := &p.Effects
+	if effects.IsEmpty() {
+		return fmt.Errorf("at least one effect is required")
+	}
+	for name, action := range map[string]PolicyAction{"color": effects.Color, "simplex": effects.Simplex, "copies": effects.Copies} {
+		if !validPolicyAction(action) {
+			return fmt.Errorf("invalid %s action %q, expected force or forbid", name, action)
+		}
+	}
+	if effects.MaxCopies != nil && *effects.MaxCopies < 1 {
+		return fmt.Errorf("max_copies must be at least 1")
+	}
+	if effects.Copies != "" && effects.MaxCopies == nil {
+		return fmt.Errorf("copies action requires max_copies")
+	}
+	return nil
+}
+
+// ActiveAt 判断 t 时刻是否处于策略的生效时段；跨午夜的时段归属于开始的那一天
+func (c *PrintPolicyConditions) ActiveAt(t time.Time) bool {
+	loc := time.UTC
+	if c.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(c.Timezone); err != nil {
+			return false
+		}
+	}
+
+	start, end := 0, 0
+	if c.StartTime != "" {
+		var err error
+		if start, err = ParseClock(c.StartTime); err != nil {
+			return false
+		}
+		if end, err = ParseClock(c.EndTime); err != nil {
+			return false
+		}
+	}
+	return inClockWindow(c.Days, start, end, t.In(loc))
+}
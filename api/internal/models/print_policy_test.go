@@ -0,0 +1,75 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrintPolicyValidate(t *testing.T) {
+	two := 2
+	zero := 0
+	color := PrintPolicyEffects{Color: PolicyActionForce}
+	tests := []struct {
+		name   string
+		policy PrintPolicy
+		ok     bool
+	}{
+		{"color only", PrintPolicy{Effects: color}, true},
+		{"quiet hours", PrintPolicy{Conditions: PrintPolicyConditions{Days: []int{1, 5}, StartTime: "18:00", EndTime: "07:00", Timezone: "Asia/Shanghai"}, Effects: color}, true},
+		{"copies ceiling", PrintPolicy{Effects: PrintPolicyEffects{MaxCopies: &two, Copies: PolicyActionForce}}, true},
+		{"no effect", PrintPolicy{}, false},
+		{"invalid day", PrintPolicy{Conditions: PrintPolicyConditions{Days: []int{7}}, Effects: color}, false},
+		{"start without end", PrintPolicy{Conditions: PrintPolicyConditions{StartTime: "18:00"}, Effects: color}, false},
+		{"empty window", PrintPolicy{Conditions: PrintPolicyConditions{StartTime: "18:00", EndTime: "18:00"}, Effects: color}, false},
+		{"bad clock", PrintPolicy{Conditions: PrintPolicyConditions{StartTime: "25:00", EndTime: "07:00"}, Effects: color}, false},
+		{"bad timezone", PrintPolicy{Conditions: PrintPolicyConditions{Timezone: "Mars/Olympus"}, Effects: color}, false},
+		{"unknown action", PrintPolicy{Effects: PrintPolicyEffects{Simplex: "maybe"}}, false},
+		{"zero copies", PrintPolicy{Effects: PrintPolicyEffects{MaxCopies: &zero}}, false},
+		{"copies action without ceiling", PrintPolicy{Effects: PrintPolicyEffects{Color: PolicyActionForce, Copies: PolicyActionForce}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err == nil) != tt.ok {
+				t.Fatalf("Validate() = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPrintPolicyConditionsActiveAt(t *testing.T) {
+	// 周一至周五 18:00-07:00（上海时间）
+	weeknights := PrintPolicyConditions{Days: []int{1, 2, 3, 4, 5}, StartTime: "18:00", EndTime: "07:00", Timezone: "Asia/Shanghai"}
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		// 2026-03-02 为周一
+		return time.Date(2026, 3, day, hour, minute, 0, 0, shanghai).UTC()
+	}
+
+	tests := []struct {
+		name string
+		cond PrintPolicyConditions
+		t    time.Time
+		want bool
+	}{
+		{"always", PrintPolicyConditions{}, at(1, 12, 0), true},
+		{"monday evening", weeknights, at(2, 18, 0), true},
+		{"monday afternoon", weeknights, at(2, 17, 59), false},
+		{"tuesday early morning from monday window", weeknights, at(3, 6, 59), true},
+		{"tuesday 07:00 window closed", weeknights, at(3, 7, 0), false},
+		{"saturday early morning from friday window", weeknights, at(7, 3, 0), true},
+		{"saturday evening", weeknights, at(7, 20, 0), false},
+		{"monday early morning, sunday not active", weeknights, at(2, 3, 0), false},
+		{"invalid timezone never active", PrintPolicyConditions{Timezone: "Mars/Olympus"}, at(2, 12, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cond.ActiveAt(tt.t); got != tt.want {
+				t.Fatalf("ActiveAt(%s) = %v, want %v", tt.t.In(shanghai).Format(time.RFC1123), got, tt.want)
+			}
+		})
+	}
+}
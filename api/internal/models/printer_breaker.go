@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// 打印机熔断状态
+const (
+	BreakerClosed    = "closed"    // 正常分发
+	BreakerSuspended = "suspended" // 连续失败过多，暂停分发，新任务排队
+)
+
+// PrinterBreaker 打印机熔断器：窗口内连续失败的任务达到阈值时暂停分发，冷却结束或管理员重置后恢复
+type PrinterBreaker struct {
+	State               string     `json:"state"`                       // closed/suspended
+	ConsecutiveFailures int        `json:"consecutive_failures"`        // 本轮连续失败的任务数，任务完成后清零
+	WindowStartedAt     *time.Time `json:"window_started_at,omitempty"` // 本轮第一次失败的时间
+	SuspendedAt         *time.Time `json:"suspended_at,omitempty"`
+	ResumeAt            *time.Time `json:"resume_at,omitempty"` // 冷却结束时间，为空表示需要管理员重置
+	Reason              string     `json:"reason,omitempty"`    // 触发熔断的最后一次失败原因
+}
+
+// Suspended 是否处于熔断（暂停分发）状态
+func (b PrinterBreaker) Suspended() bool {
+	return b.State == BreakerSuspended
+}
+
+// BreakerPolicy 熔断阈值（来自 jobs.breaker_* 配置），状态转换均以传入的 now 为准
+type BreakerPolicy struct {
+	Failures int           // 窗口内连续失败该数量的任务后熔断，0 表示不熔断
+	Window   time.Duration // 连续失败的统计窗口，从本轮第一次失败开始计算
+	Cooldown time.Duration // 冷却时间，0 表示只能手动重置
+}
+
+// RecordFailure 记录一次任务失败，返回新的状态以及本次是否触发熔断
+// 超出窗口的失败开始新一轮计数；已熔断时只累加计数
+func (p BreakerPolicy) RecordFailure(b PrinterBreaker, now time.Time, reason string) (PrinterBreaker, bool) {
+	if b.State == "" {
+		b.State = BreakerClosed
+	}
+	if b.Suspended() {
+		b.ConsecutiveFailures++
+		return b, false
+	}
+	if p.Failures <= 0 {
+		return b, false
+	}
+
+	if b.WindowStartedAt == nil || now.Sub(*b.WindowStartedAt) > p.Window {
+		b.ConsecutiveFailures = 0
+		b.WindowStartedAt = &now
+	}
+	b.ConsecutiveFailures++
+	if b.ConsecutiveFailures < p.Failures {
+		return b, false
+	}
+
+	b.State = BreakerSuspended
+	b.SuspendedAt = &now
+	b.ResumeAt = nil
+	if p.Cooldown > 0 {
+		resumeAt := now.Add(p.Cooldown)
+		b.ResumeAt = &resumeAt
+	}
+	b.Reason = reason
+	return b, true
+}
+
+// RecordSuccess 记录一次任务完成：未熔断时清零连续失败计数；已熔断时保持熔断，等待冷却结束或手动重置
+func (p BreakerPolicy) RecordSuccess(b PrinterBreaker) PrinterBreaker {
+	if b.Suspended() {
+		return b
+	}
+	return PrinterBreaker{State: BreakerClosed}
+}
+
+// Resume 冷却结束时恢复分发，返回新的状态以及是否恢复
+// 恢复后处于试探期：计数保留为阈值减一，窗口内下一个任务再失败立即重新熔断，完成则清零
+func (p BreakerPolicy) Resume(b PrinterBreaker, now time.Time) (PrinterBreaker, bool) {
+	if !b.Suspended() || b.ResumeAt == nil || now.Before(*b.ResumeAt) {
+		return b, false
+	}
+	resumed := PrinterBreaker{State: BreakerClosed, WindowStartedAt: &now}
+	if p.Failures > 1 {
+		resumed.ConsecutiveFailures = p.Failures - 1
+	}
+	return resumed, true
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock 测试用时钟，只在调用 advance 时前进
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) advance(d time.Duration) time.Time {
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}
+}
+
+// TestBreakerTripsWithinWindow 窗口内连续失败达到阈值时熔断，冷却时间从熔断时刻开始计算
+func TestBreakerTripsWithinWindow(t *testing.T) {
+	policy := BreakerPolicy{Failures: 3, Window: 10 * time.Minute, Cooldown: 5 * time.Minute}
+	clock := newFakeClock()
+	first := clock.now
+
+	var b PrinterBreaker
+	var tripped bool
+	for i := 1; i <= 2; i++ {
+		b, tripped = policy.RecordFailure(b, clock.now, "paper jam")
+		if tripped || b.Suspended() || b.ConsecutiveFailures != i {
+			t.Fatalf("failure %d: %+v, tripped %v; want closed with %d failures", i, b, tripped, i)
+		}
+		clock.advance(4 * time.Minute)
+	}
+	if b.State != BreakerClosed || !b.WindowStartedAt.Equal(first) {
+		t.Fatalf("window = %v, state %q; want window from the first failure", b.WindowStartedAt, b.State)
+	}
+
+	now := clock.now
+	b, tripped = policy.RecordFailure(b, now, "toner empty")
+	if !tripped || !b.Suspended() {
+		t.Fatalf("third failure: %+v, tripped %v; want suspended", b, tripped)
+	}
+	if !b.SuspendedAt.Equal(now) || b.ResumeAt == nil || !b.ResumeAt.Equal(now.Add(5*time.Minute)) || b.Reason != "toner empty" {
+		t.Fatalf("suspended breaker = %+v; want resume at %s with the last reason", b, now.Add(5*time.Minute))
+	}
+}
+
+// TestBreakerWindowExpires 超出窗口的失败开始新一轮计数，不会累积到熔断
+func TestBreakerWindowExpires(t *testing.T) {
+	policy := BreakerPolicy{Failures: 3, Window: 10 * time.Minute, Cooldown: 5 * time.Minute}
+	clock := newFakeClock()
+
+	var b PrinterBreaker
+	b, _ = policy.RecordFailure(b, clock.now, "jam")
+	b, _ = policy.RecordFailure(b, clock.advance(5*time.Minute), "jam")
+	// 距本轮第一次失败恰好一个窗口，仍在窗口内
+	b, tripped := policy.RecordFailure(b, clock.advance(5*time.Minute), "jam")
+	if !tripped {
+		t.Fatalf("failure at the window edge: %+v; want suspended", b)
+	}
+
+	b = PrinterBreaker{}
+	b, _ = policy.RecordFailure(b, clock.now, "jam")
+	b, _ = policy.RecordFailure(b, clock.advance(6*time.Minute), "jam")
+	restart := clock.advance(5 * time.Minute)
+	b, tripped = policy.RecordFailure(b, restart, "jam")
+	if tripped || b.ConsecutiveFailures != 1 || !b.WindowStartedAt.Equal(restart) {
+		t.Fatalf("failure after the window: %+v, tripped %v; want a new window with 1 failure", b, tripped)
+	}
+}
+
+// TestBreakerSuccessResets 未熔断时任务完成清零计数；熔断后完成不解除熔断，失败只累加计数
+func TestBreakerSuccessResets(t *testing.T) {
+	policy := BreakerPolicy{Failures: 2, Window: time.Hour, Cooldown: time.Minute}
+	clock := newFakeClock()
+
+	var b PrinterBreaker
+	b, _ = policy.RecordFailure(b, clock.now, "jam")
+	b = policy.RecordSuccess(b)
+	if b.State != BreakerClosed || b.ConsecutiveFailures != 0 || b.WindowStartedAt != nil {
+		t.Fatalf("after success: %+v; want a clean closed breaker", b)
+	}
+	b, tripped := policy.RecordFailure(b, clock.advance(time.Second), "jam")
+	if tripped {
+		t.Fatal("success did not reset the count")
+	}
+
+	b, tripped = policy.RecordFailure(b, clock.advance(time.Second), "jam")
+	if !tripped {
+		t.Fatalf("second failure: %+v; want suspended", b)
+	}
+	suspendedAt := *b.SuspendedAt
+	b = policy.RecordSuccess(b)
+	if !b.Suspended() {
+		t.Fatal("success cleared a suspended breaker")
+	}
+	b, tripped = policy.RecordFailure(b, clock.advance(time.Second), "late report")
+	if tripped || !b.Suspended() || b.ConsecutiveFailures != 3 || !b.SuspendedAt.Equal(suspendedAt) || b.Reason != "jam" {
+		t.Fatalf("failure while suspended: %+v, tripped %v; want only the count to grow", b, tripped)
+	}
+}
+
+// TestBreakerResumeAfterCooldown 冷却结束前不恢复；恢复后处于试探期，下一次失败立即重新熔断，完成则清零
+func TestBreakerResumeAfterCooldown(t *testing.T) {
+	policy := BreakerPolicy{Failures: 3, Window: 10 * time.Minute, Cooldown: 5 * time.Minute}
+	clock := newFakeClock()
+
+	var b PrinterBreaker
+	for i := 0; i < 3; i++ {
+		b, _ = policy.RecordFailure(b, clock.advance(time.Second), "jam")
+	}
+	if !b.Suspended() {
+		t.Fatalf("breaker = %+v; want suspended", b)
+	}
+
+	if _, resumed := policy.Resume(b, clock.advance(5*time.Minute-time.Second)); resumed {
+		t.Fatal("resumed before the cooldown ended")
+	}
+	now := clock.advance(time.Second)
+	probe, resumed := policy.Resume(b, now)
+	if !resumed || probe.State != BreakerClosed || probe.ConsecutiveFailures != 2 || !probe.WindowStartedAt.Equal(now) ||
+		probe.SuspendedAt != nil || probe.ResumeAt != nil || probe.Reason != "" {
+		t.Fatalf("after cooldown: %+v, resumed %v; want closed on probation with 2 failures", probe, resumed)
+	}
+	if _, again := policy.Resume(probe, clock.advance(time.Minute)); again {
+		t.Fatal("closed breaker resumed again")
+	}
+
+	retripped, tripped := policy.RecordFailure(probe, clock.advance(time.Minute), "jam again")
+	if !tripped || !retripped.Suspended() || !retripped.ResumeAt.Equal(clock.now.Add(5*time.Minute)) {
+		t.Fatalf("failure on probation: %+v, tripped %v; want suspended again", retripped, tripped)
+	}
+
+	cleared := policy.RecordSuccess(probe)
+	if cleared.ConsecutiveFailures != 0 {
+		t.Fatalf("success on probation: %+v; want the count cleared", cleared)
+	}
+	if _, tripped := policy.RecordFailure(cleared, clock.advance(time.Second), "jam"); tripped {
+		t.Fatal("single failure after a successful probe tripped the breaker")
+	}
+
+	// 阈值为 1 时恢复后没有可保留的计数
+	single := BreakerPolicy{Failures: 1, Window: time.Minute, Cooldown: time.Minute}
+	b, _ = single.RecordFailure(PrinterBreaker{}, clock.now, "jam")
+	b, resumed = single.Resume(b, clock.advance(time.Minute))
+	if !resumed || b.ConsecutiveFailures != 0 {
+		t.Fatalf("threshold 1 after cooldown: %+v, resumed %v", b, resumed)
+	}
+}
+
+// TestBreakerManualOnlyAndDisabled 冷却时间为 0 时只能手动重置；阈值为 0 时从不熔断
+func TestBreakerManualOnlyAndDisabled(t *testing.T) {
+	clock := newFakeClock()
+
+	manual := BreakerPolicy{Failures: 1, Window: time.Minute}
+	b, tripped := manual.RecordFailure(PrinterBreaker{}, clock.now, "jam")
+	if !tripped || b.ResumeAt != nil {
+		t.Fatalf("manual-only breaker = %+v, tripped %v; want suspended without resume_at", b, tripped)
+	}
+	if _, resumed := manual.Resume(b, clock.advance(365*24*time.Hour)); resumed {
+		t.Fatal("manual-only breaker resumed on its own")
+	}
+
+	disabled := BreakerPolicy{Window: time.Minute, Cooldown: time.Minute}
+	b = PrinterBreaker{}
+	for i := 0; i < 100; i++ {
+		b, tripped = disabled.RecordFailure(b, clock.advance(time.Second), "jam")
+		if tripped || b.Suspended() {
+			t.Fatalf("disabled breaker tripped after %d failures: %+v", i+1, b)
+		}
+	}
+	if b.State != BreakerClosed {
+		t.Fatalf("state = %q, want closed", b.State)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// RoutingStrategy 打印机组的自动选机策略
+type RoutingStrategy string
+
+// 自动选机策略
+const (
+	RoutingLeastQueue RoutingStrategy = "least_queue" // 选择执行中 + 排队任务最少的打印机
+	RoutingRoundRobin RoutingStrategy = "round_robin" // 在候选打印机之间轮流分配
+	RoutingNearest    RoutingStrategy = "nearest"     // 选择距离提交位置最近的打印机
+	RoutingStickyUser RoutingStrategy = "sticky_user" // 同一用户优先使用上次分配的打印机
+)
+
+// AllRoutingStrategies 所有可选的选机策略
+var AllRoutingStrategies = []RoutingStrategy{RoutingLeastQueue, RoutingRoundRobin, RoutingNearest, RoutingStickyUser}
+
+// DefaultRoutingStrategy 打印机组未指定策略时使用的策略
+const DefaultRoutingStrategy = RoutingLeastQueue
+
+// ValidRoutingStrategy 判断是否为可识别的选机策略
+func ValidRoutingStrategy(strategy RoutingStrategy) bool {
+	for _, s := range AllRoutingStrategies {
+		if s == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// PrinterGroup 打印机组：提交到组的任务由选机策略自动选择组内打印机
+type PrinterGroup struct {
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	Description     string          `json:"description,omitempty"`
+	RoutingStrategy RoutingStrategy `json:"routing_strategy"`
+	PrinterIDs      []string        `json:"printer_ids"`   // 组内打印机
+	SLA             *SLAThresholds  `json:"sla,omitempty"` // 覆盖全局 SLA 阈值
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// SLAPhase SLA 考核阶段，耗时均从任务提交（created_at）开始计算
+type SLAPhase string
+
+// SLA 考核阶段
+const (
+	SLAPhaseDispatch SLAPhase = "dispatch" // 提交到下发给 Edge Node
+	SLAPhaseStart    SLAPhase = "start"    // 提交到开始打印（进入 printing）
+	SLAPhaseComplete SLAPhase = "complete" // 提交到打印完成
+)
+
+// AllSLAPhases 全部 SLA 考核阶段
+var AllSLAPhases = []SLAPhase{SLAPhaseDispatch, SLAPhaseStart, SLAPhaseComplete}
+
+// SLAThresholds 打印机组的 SLA 阈值（秒），为空的阶段使用全局配置，0 表示该组不考核此阶段
+type SLAThresholds struct {
+	TimeToDispatch *int `json:"time_to_dispatch_seconds,omitempty"`
+	TimeToStart    *int `json:"time_to_start_seconds,omitempty"`
+	TimeToComplete *int `json:"time_to_complete_seconds,omitempty"`
+}
+
+// IsEmpty 是否没有任何覆盖
+func (t *SLAThresholds) IsEmpty() bool {
+	return t == nil || (t.TimeToDispatch == nil && t.TimeToStart == nil && t.TimeToComplete == nil)
+}
+
+// JobSLA 打印任务的 SLA 考核结果
+// 执行中的任务超过阈值时即标记超时（只会由 false 变为 true）；任务结束后完成最终评估并记录 evaluated_at，之后不再重新评估
+type JobSLA struct {
+	DispatchBreached bool       `json:"dispatch_breached"`
+	StartBreached    bool       `json:"start_breached"`
+	CompleteBreached bool       `json:"complete_breached"`
+	EvaluatedAt      *time.Time `json:"evaluated_at,omitempty"` // 最终评估时间，为空表示任务尚未结束
+}
+
+// Breached 指定阶段是否超时
+func (s *JobSLA) Breached(phase SLAPhase) bool {
+	switch phase {
+	case SLAPhaseDispatch:
+		return s.DispatchBreached
+	case SLAPhaseStart:
+		return s.StartBreached
+	case SLAPhaseComplete:
+		return s.CompleteBreached
+	}
+	return false
+}
+
+// SetBreached 标记指定阶段超时
+func (s *JobSLA) SetBreached(phase SLAPhase) {
+	switch phase {
+	case SLAPhaseDispatch:
+		s.DispatchBreached = true
+	case SLAPhaseStart:
+		s.StartBreached = true
+	case SLAPhaseComplete:
+		s.CompleteBreached = true
+	}
+}
+
+// AnyBreached 是否有任一阶段超时
+func (s *JobSLA) AnyBreached() bool {
+	return s.DispatchBreached || s.StartBreached || s.CompleteBreached
+}
+
+// SLAStat SLA 统计（按打印机或站点分组），只统计已完成最终评估的任务
+type SLAStat struct {
+	Key              string   `json:"key"`  // 打印机ID或站点（Edge Node 位置）
+	Name             string   `json:"name"` // 显示名称
+	Jobs             int      `json:"jobs"`
+	BreachedJobs     int      `json:"breached_jobs"` // 任一阶段超时的任务数
+	DispatchBreaches int      `json:"dispatch_breaches"`
+	StartBreaches    int      `json:"start_breaches"`
+	CompleteBreaches int      `json:"complete_breaches"`
+	BreachRate       float64  `json:"breach_rate"`                         // breached_jobs / jobs
+	AvgTimeToStart   *float64 `json:"avg_time_to_start_seconds,omitempty"` // 已开始打印的任务从提交到开始的平均耗时
+}
@@ -0,0 +1,156 @@
+package models
+
+import "strings"
+
+// JobStatus 打印任务状态
+type JobStatus string
+
+// 打印任务状态
+const (
+	JobStatusPending            JobStatus = "pending"
+	JobStatusQueued             JobStatus = "queued" // 打印机并发已满，在云端排队等待分发
+	JobStatusDispatched         JobStatus = "dispatched"
+	JobStatusAccepted           JobStatus = "accepted" // Edge Node 已接收
+	JobStatusDownloading        JobStatus = "downloading"
+	JobStatusPrinting           JobStatus = "printing"
+	JobStatusStalled            JobStatus = "stalled" // 已分发但长时间没有进展（疑似 Edge Node 失联），等待节点恢复或管理员处理
+	JobStatusCompleted          JobStatus = "completed"
+	JobStatusPartiallyCompleted JobStatus = "partially_completed" // 中途停止（如卡纸）且无法继续，只输出了 pages_printed 页
+	JobStatusFailed             JobStatus = "failed"
+	JobStatusCancelled          JobStatus = "cancelled"
+)
+
+// AllJobStatuses 全部打印任务状态（校验、数据库约束均以此为准）
+var AllJobStatuses = []JobStatus{
+	JobStatusPending, JobStatusQueued, JobStatusDispatched, JobStatusAccepted, JobStatusDownloading, JobStatusPrinting,
+	JobStatusStalled, JobStatusCompleted, JobStatusPartiallyCompleted, JobStatusFailed, JobStatusCancelled,
+}
+
+// ActiveJobStatuses 已分发到 Edge Node 且尚未结束的状态（占用打印机并发名额）
+// stalled 任务仍可能在节点上执行，直到节点上报或管理员强制结束前继续占用名额
+var ActiveJobStatuses = []JobStatus{
+	JobStatusDispatched, JobStatusAccepted, JobStatusDownloading, JobStatusPrinting, JobStatusStalled,
+}
+
+// TerminalJobStatuses 终态（与 IsTerminal 一致），任务不会再使用其文件
+var TerminalJobStatuses = []JobStatus{
+	JobStatusCompleted, JobStatusPartiallyCompleted, JobStatusFailed, JobStatusCancelled,
+}
+
+// BillableJobStatuses 计费的终态：已完成按页数 × 份数计费，部分完成按已输出页数计费
+var BillableJobStatuses = []JobStatus{
+	JobStatusCompleted, JobStatusPartiallyCompleted,
+}
+
+// StallableJobStatuses 长时间没有进展时会被标记为 stalled 的状态
+var StallableJobStatuses = []JobStatus{
+	JobStatusDispatched, JobStatusAccepted, JobStatusDownloading, JobStatusPrinting,
+}
+
+// IsValid 是否为合法的任务状态
+func (s JobStatus) IsValid() bool {
+	for _, status := range AllJobStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal 是否为终态（完成/部分完成/失败/取消）
+func (s JobStatus) IsTerminal() bool {
+	return s == JobStatusCompleted || s == JobStatusPartiallyCompleted || s == JobStatusFailed || s == JobStatusCancelled
+}
+
+// IsBillable 是否为计费的终态（完成/部分完成）
+func (s JobStatus) IsBillable() bool {
+	return s == JobStatusCompleted || s == JobStatusPartiallyCompleted
+}
+
+// JobSource 任务的提交来源
+type JobSource string
+
+// 任务提交来源
+const (
+	JobSourceConsole     JobSource = "console"     // 管理后台
+	JobSourceAPI         JobSource = "api"         // 用户 API（OAuth2 登录）
+	JobSourceIntegration JobSource = "integration" // 第三方集成（API Key）
+	JobSourceEmail       JobSource = "email"       // 邮件打印
+	JobSourceHotFolder   JobSource = "hot_folder"  // 热文件夹
+	JobSourceUnknown     JobSource = "unknown"     // 记录来源之前创建的历史任务
+)
+
+// AllJobSources 全部任务来源（校验、数据库约束均以此为准）
+var AllJobSources = []JobSource{
+	JobSourceConsole, JobSourceAPI, JobSourceIntegration, JobSourceEmail, JobSourceHotFolder, JobSourceUnknown,
+}
+
+// IsValid 是否为合法的任务来源
+func (s JobSource) IsValid() bool {
+	for _, source := range AllJobSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// PrinterStatus 打印机状态
+type PrinterStatus string
+
+// 打印机状态
+const (
+	PrinterStatusReady    PrinterStatus = "ready"
+	PrinterStatusPrinting PrinterStatus = "printing"
+	PrinterStatusError    PrinterStatus = "error"
+	PrinterStatusOffline  PrinterStatus = "offline"
+)
+
+// AllPrinterStatuses 全部打印机状态
+var AllPrinterStatuses = []PrinterStatus{
+	PrinterStatusReady, PrinterStatusPrinting, PrinterStatusError, PrinterStatusOffline,
+}
+
+// IsValid 是否为合法的打印机状态
+func (s PrinterStatus) IsValid() bool {
+	for _, status := range AllPrinterStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeStatus Edge Node 状态
+type NodeStatus string
+
+// Edge Node 状态
+const (
+	NodeStatusOnline      NodeStatus = "online"
+	NodeStatusOffline     NodeStatus = "offline"
+	NodeStatusMaintenance NodeStatus = "maintenance"
+)
+
+// AllNodeStatuses 全部 Edge Node 状态
+var AllNodeStatuses = []NodeStatus{
+	NodeStatusOnline, NodeStatusOffline, NodeStatusMaintenance,
+}
+
+// IsValid 是否为合法的节点状态
+func (s NodeStatus) IsValid() bool {
+	for _, status := range AllNodeStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusSQLList 生成 SQL IN 列表，如 'a', 'b'（仅用于内部常量，不可传入用户输入）
+func StatusSQLList[T ~string](statuses []T) string {
+	quoted := make([]string, len(statuses))
+	for i, status := range statuses {
+		quoted[i] = "'" + string(status) + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
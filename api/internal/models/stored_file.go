@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// StoredFile 上传到云端存储的打印文件，独立于打印任务记录保留和清理
+type StoredFile struct {
+	StorageKey  string     `json:"storage_key"`
+	FileSize    int64      `json:"file_size"`
+	ContentType string     `json:"content_type,omitempty"`
+	JobID       *string    `json:"job_id,omitempty"` // 首个使用该文件的任务，尚未提交任务时为空
+	CreatedAt   time.Time  `json:"created_at"`
+	PurgedAt    *time.Time `json:"purged_at,omitempty"` // 按保留期限删除文件内容的时间
+}
+
+// JobErrorFilePurged 任务文件已按保留期限删除，无法重新打印或下载（接口错误码）
+const JobErrorFilePurged = "file_purged"
@@ -0,0 +1,117 @@
+// Package netaddr 规范化节点上报的 MAC 和 IP 地址，入库前统一格式，无效值在请求阶段拒绝
+package netaddr
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+)
+
+var (
+	ErrInvalidMAC = errors.New("invalid MAC address")
+	ErrInvalidIP  = errors.New("invalid IP address")
+)
+
+// NormalizeMAC 将 MAC 地址规范化为小写冒号分隔形式（aa:bb:cc:dd:ee:ff）
+// 接受冒号、连字符、点分（aabb.ccdd.eeff）、空格分隔或无分隔符的 48 位地址，大小写不限；
+// 空值和全零地址（部分网卡未初始化时上报）返回空字符串，表示未知
+func NormalizeMAC(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+
+	digits := make([]byte, 0, 12)
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		switch {
+		case ch >= '0' && ch <= '9', ch >= 'a' && ch <= 'f':
+			digits = append(digits, ch)
+		case ch >= 'A' && ch <= 'F':
+			digits = append(digits, ch+('a'-'A'))
+		case ch == ':' || ch == '-' || ch == '.' || ch == ' ':
+		default:
+			return "", ErrInvalidMAC
+		}
+	}
+	if len(digits) != 12 || !validMACGrouping(value) {
+		return "", ErrInvalidMAC
+	}
+	if strings.Trim(string(digits), "0") == "" {
+		return "", nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < 12; i += 2 {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		b.Write(digits[i : i+2])
+	}
+	return b.String(), nil
+}
+
+// validMACGrouping 有分隔符时各组长度必须一致：6 组 2 位（冒号/连字符/空格）或 3 组 4 位（点分）
+// 拒绝 "a:bb:cc:dd:ee:fff" 这类碰巧凑够 12 位的错误写法
+func validMACGrouping(value string) bool {
+	groups := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ':' || r == '-' || r == '.' || r == ' '
+	})
+	if len(groups) == 1 {
+		return true
+	}
+	size := len(groups[0])
+	if !(len(groups) == 6 && size == 2) && !(len(groups) == 3 && size == 4) {
+		return false
+	}
+	for _, group := range groups {
+		if len(group) != size {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeIP 校验并规范化 IPv4/IPv6 地址
+// 去除首尾空白、方括号（[::1]）、区域标识（fe80::1%eth0）和前缀长度（192.168.1.10/24），
+// IPv4 映射的 IPv6 地址转换为 IPv4；空值返回空字符串
+func NormalizeIP(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return prefix.Addr().Unmap().String(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return "", ErrInvalidIP
+	}
+	return addr.WithZone("").Unmap().String(), nil
+}
+
+// NormalizeMACPtr 规范化可为空的 MAC 字段，结果为空时返回 nil（入库为 NULL）
+func NormalizeMACPtr(value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	normalized, err := NormalizeMAC(*value)
+	if err != nil || normalized == "" {
+		return nil, err
+	}
+	return &normalized, nil
+}
+
+// NormalizeIPPtr 规范化可为空的 IP 字段，结果为空时返回 nil（入库为 NULL）
+func NormalizeIPPtr(value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	normalized, err := NormalizeIP(*value)
+	if err != nil || normalized == "" {
+		return nil, err
+	}
+	return &normalized, nil
+}
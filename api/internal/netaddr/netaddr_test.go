@@ -0,0 +1,100 @@
+package netaddr
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeMAC(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"AA:BB:CC:DD:EE:FF", "aa:bb:cc:dd:ee:ff", nil},
+		{"aa-bb-cc-dd-ee-ff", "aa:bb:cc:dd:ee:ff", nil},
+		{"AABB.CCDD.EEFF", "aa:bb:cc:dd:ee:ff", nil},
+		{"aabbccddeeff", "aa:bb:cc:dd:ee:ff", nil},
+		{"AA BB CC DD EE FF", "aa:bb:cc:dd:ee:ff", nil},
+		{"  00:1A:2b:3C:4d:5E \n", "00:1a:2b:3c:4d:5e", nil},
+		{"", "", nil},
+		{"   ", "", nil},
+		{"00:00:00:00:00:00", "", nil},
+		{"000000000000", "", nil},
+		{"a:bb:cc:dd:ee:fff", "", ErrInvalidMAC},
+		{"aa:bb:cc:dd:ee", "", ErrInvalidMAC},
+		{"aa:bb:cc:dd:ee:ff:00", "", ErrInvalidMAC},
+		{"aabb.ccdd.eef", "", ErrInvalidMAC},
+		{"aabbc.cdde.eff", "", ErrInvalidMAC},
+		{"gg:bb:cc:dd:ee:ff", "", ErrInvalidMAC},
+		{"N/A", "", ErrInvalidMAC},
+		{"unknown", "", ErrInvalidMAC},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeMAC(tt.input)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("NormalizeMAC(%q) = %q, %v; want %q, %v", tt.input, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestNormalizeIP(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"192.168.1.10", "192.168.1.10", nil},
+		{" 10.0.0.1\t", "10.0.0.1", nil},
+		{"192.168.1.10/24", "192.168.1.10", nil},
+		{"::ffff:10.0.0.1", "10.0.0.1", nil},
+		{"FE80::1", "fe80::1", nil},
+		{"[fe80::1]", "fe80::1", nil},
+		{"fe80::1%eth0", "fe80::1", nil},
+		{"2001:db8::1/64", "2001:db8::1", nil},
+		{"2001:0db8:0000:0000:0000:0000:0000:0001", "2001:db8::1", nil},
+		{"", "", nil},
+		{"192.168.001.010", "", ErrInvalidIP},
+		{"256.1.1.1", "", ErrInvalidIP},
+		{"192.168.1", "", ErrInvalidIP},
+		{"printer.local", "", ErrInvalidIP},
+		{"N/A", "", ErrInvalidIP},
+		{"192.168.1.10:631", "", ErrInvalidIP},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeIP(tt.input)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("NormalizeIP(%q) = %q, %v; want %q, %v", tt.input, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+// TestNormalizePtr 空值和全零 MAC 返回 nil（入库为 NULL）
+func TestNormalizePtr(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	if got, err := NormalizeMACPtr(nil); got != nil || err != nil {
+		t.Fatalf("NormalizeMACPtr(nil) = %v, %v", got, err)
+	}
+	for _, input := range []string{"", "00-00-00-00-00-00"} {
+		if got, err := NormalizeMACPtr(str(input)); got != nil || err != nil {
+			t.Fatalf("NormalizeMACPtr(%q) = %v, %v; want nil", input, got, err)
+		}
+	}
+	if got, err := NormalizeMACPtr(str("AABBCCDDEEFF")); err != nil || got == nil || *got != "aa:bb:cc:dd:ee:ff" {
+		t.Fatalf("NormalizeMACPtr = %v, %v", got, err)
+	}
+	if _, err := NormalizeMACPtr(str("bogus")); !errors.Is(err, ErrInvalidMAC) {
+		t.Fatalf("NormalizeMACPtr(bogus) error = %v", err)
+	}
+
+	if got, err := NormalizeIPPtr(str(" ")); got != nil || err != nil {
+		t.Fatalf("NormalizeIPPtr(blank) = %v, %v; want nil", got, err)
+	}
+	if got, err := NormalizeIPPtr(str("[::1]")); err != nil || got == nil || *got != "::1" {
+		t.Fatalf("NormalizeIPPtr = %v, %v", got, err)
+	}
+	if _, err := NormalizeIPPtr(str("bogus")); !errors.Is(err, ErrInvalidIP) {
+		t.Fatalf("NormalizeIPPtr(bogus) error = %v", err)
+	}
+}
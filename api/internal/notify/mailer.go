@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+
+	"fly-print-cloud/api/internal/config"
+)
+
+// Mailer SMTP 邮件发送器
+type Mailer struct {
+	settings *config.Store // SMTP 配置支持热更新，每次发送时读取
+}
+
+// NewMailer 创建邮件发送器
+func NewMailer(settings *config.Store) *Mailer {
+	return &Mailer{settings: settings}
+}
+
+// Enabled 邮件发送是否已启用并完成配置
+func (m *Mailer) Enabled() bool {
+	if m == nil || m.settings == nil {
+		return false
+	}
+	return mailConfigured(&m.settings.Get().Mail)
+}
+
+func mailConfigured(cfg *config.MailConfig) bool {
+	return cfg.Enabled && cfg.SMTPHost != "" && cfg.From != ""
+}
+
+// Send 发送纯文本邮件
+func (m *Mailer) Send(to, subject, body string) error {
+	if !m.Enabled() {
+		return fmt.Errorf("mailer is not enabled")
+	}
+	cfg := m.settings.Get().Mail
+
+	addr := fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort)
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.SMTPHost)
+	}
+
+	headers := []string{
+		"From: " + cfg.From,
+		"To: " + to,
+		"Subject: " + mime.BEncoding.Encode("UTF-8", subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+	msg := strings.Join(headers, "\r\n") + "\r\n\r\n" + body
+
+	if err := smtp.SendMail(addr, auth, cfg.From, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send mail to %s: %w", to, err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+)
+
+// Notifier 打印任务结束通知（完成/失败时通知提交用户）
+type Notifier struct {
+	mailer       *Mailer
+	userRepo     *database.UserRepository
+	printJobRepo *database.PrintJobRepository
+	printerRepo  *database.PrinterRepository
+}
+
+// NewNotifier 创建任务通知器
+func NewNotifier(mailer *Mailer, userRepo *database.UserRepository, printJobRepo *database.PrintJobRepository, printerRepo *database.PrinterRepository) *Notifier {
+	return &Notifier{
+		mailer:       mailer,
+		userRepo:     userRepo,
+		printJobRepo: printJobRepo,
+		printerRepo:  printerRepo,
+	}
+}
+
+// JobFinished 任务进入终态时调用，异步发送通知，不阻塞调用方
+func (n *Notifier) JobFinished(jobID string) {
+	if n == nil || !n.mailer.Enabled() {
+		return
+	}
+	go func() {
+		if err := n.notifyJob(jobID); err != nil {
+			log.Printf("Failed to notify submitter of job %s: %v", jobID, err)
+		}
+	}()
+}
+
+// notifyJob 按用户偏好发送任务结束通知，同一任务只发送一次
+func (n *Notifier) notifyJob(jobID string) error {
+	job, err := n.printJobRepo.GetPrintJobByID(jobID)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return fmt.Errorf("job not found")
+	}
+	if job.Status != models.JobStatusCompleted && job.Status != models.JobStatusPartiallyCompleted && job.Status != models.JobStatusFailed {
+		return nil
+	}
+
+	user, err := n.userRepo.GetNotificationRecipient(job.UserID, job.UserName)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Email == "" {
+		return nil
+	}
+	if job.Status == models.JobStatusCompleted && !user.NotifyOnCompletion {
+		return nil
+	}
+	// 部分完成的任务需要用户处理剩余页，按失败通知偏好发送
+	if (job.Status == models.JobStatusFailed || job.Status == models.JobStatusPartiallyCompleted) && !user.NotifyOnFailure {
+		return nil
+	}
+
+	// 先占用通知标记，避免重复状态上报导致重复发送
+	claimed, err := n.printJobRepo.ClaimJobNotification(job.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	subject, body := n.composeMessage(job)
+	if err := n.mailer.Send(user.Email, subject, body); err != nil {
+		if releaseErr := n.printJobRepo.ReleaseJobNotification(job.ID); releaseErr != nil {
+			log.Printf("Failed to release notification marker for job %s: %v", job.ID, releaseErr)
+		}
+		return err
+	}
+
+	log.Printf("Sent %s notification for job %s to %s", job.Status, job.ID, user.Username)
+	return nil
+}
+
+// composeMessage 生成通知邮件的标题和正文
+func (n *Notifier) composeMessage(job *models.PrintJob) (string, string) {
+	printerName := job.PrinterID
+	if printer, err := n.printerRepo.GetPrinterByID(job.PrinterID); err == nil && printer != nil {
+		printerName = printer.Name
+		if printer.DisplayName != "" {
+			printerName = printer.DisplayName
+		}
+	}
+
+	var b strings.Builder
+	var subject string
+	switch job.Status {
+	case models.JobStatusCompleted:
+		subject = fmt.Sprintf("打印完成：%s", job.DisplayName)
+		b.WriteString("您的打印任务已完成，请前往打印机取件。\n\n")
+	case models.JobStatusPartiallyCompleted:
+		subject = fmt.Sprintf("打印未全部完成：%s", job.DisplayName)
+		b.WriteString("您的打印任务中途停止，已输出的部分请前往打印机取件，剩余页可重新打印。\n\n")
+	default:
+		subject = fmt.Sprintf("打印失败：%s", job.DisplayName)
+		b.WriteString("您的打印任务未能完成。\n\n")
+	}
+	fmt.Fprintf(&b, "任务名称：%s\n", job.DisplayName)
+	fmt.Fprintf(&b, "打印机：%s\n", printerName)
+	fmt.Fprintf(&b, "页数：%d（%d 份）\n", job.PageCount, job.Copies)
+	if job.Status == models.JobStatusPartiallyCompleted {
+		fmt.Fprintf(&b, "已输出：%d 页\n", job.PagesPrinted)
+	}
+	if job.Status != models.JobStatusCompleted && job.ErrorMessage != "" {
+		fmt.Fprintf(&b, "错误信息：%s\n", job.ErrorMessage)
+	}
+	for _, key := range models.SortedMetadataKeys(job.Metadata) {
+		fmt.Fprintf(&b, "%s：%s\n", key, job.Metadata[key])
+	}
+	return subject, b.String()
+}
@@ -0,0 +1,167 @@
+package papersize
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 纸张标准
+const (
+	StandardISO  = "iso"
+	StandardANSI = "ansi" // 北美尺寸（Letter、Legal 等）
+	StandardJIS  = "jis"
+)
+
+// Size 纸张尺寸（竖向，宽 × 高，单位毫米）
+type Size struct {
+	ID       string   `json:"id"` // 规范ID，存储和比较均使用该值
+	Name     string   `json:"name"`
+	Standard string   `json:"standard"`
+	WidthMM  float64  `json:"width_mm"`
+	HeightMM float64  `json:"height_mm"`
+	Aliases  []string `json:"aliases"` // 可识别的其他写法（CUPS PPD、IPP/PWG 媒体名等）
+}
+
+// catalog 支持的纸张尺寸，按常用程度排列
+var catalog = []Size{
+	{ID: "A4", Name: "A4", Standard: StandardISO, WidthMM: 210, HeightMM: 297,
+		Aliases: []string{"iso_a4_210x297mm", "iso-a4", "a4small", "a4-portrait"}},
+	{ID: "A3", Name: "A3", Standard: StandardISO, WidthMM: 297, HeightMM: 420,
+		Aliases: []string{"iso_a3_297x420mm", "iso-a3"}},
+	{ID: "A5", Name: "A5", Standard: StandardISO, WidthMM: 148, HeightMM: 210,
+		Aliases: []string{"iso_a5_148x210mm", "iso-a5"}},
+	{ID: "A6", Name: "A6", Standard: StandardISO, WidthMM: 105, HeightMM: 148,
+		Aliases: []string{"iso_a6_105x148mm", "iso-a6"}},
+	{ID: "B4", Name: "B4 (ISO)", Standard: StandardISO, WidthMM: 250, HeightMM: 353,
+		Aliases: []string{"iso_b4_250x353mm", "iso-b4", "isob4"}},
+	{ID: "B5", Name: "B5 (ISO)", Standard: StandardISO, WidthMM: 176, HeightMM: 250,
+		Aliases: []string{"iso_b5_176x250mm", "iso-b5", "isob5"}},
+	{ID: "JIS-B4", Name: "B4 (JIS)", Standard: StandardJIS, WidthMM: 257, HeightMM: 364,
+		Aliases: []string{"jis_b4_257x364mm", "jis-b4", "b4jis"}},
+	{ID: "JIS-B5", Name: "B5 (JIS)", Standard: StandardJIS, WidthMM: 182, HeightMM: 257,
+		Aliases: []string{"jis_b5_182x257mm", "jis-b5", "b5jis"}},
+	{ID: "C5", Name: "C5 信封", Standard: StandardISO, WidthMM: 162, HeightMM: 229,
+		Aliases: []string{"iso_c5_162x229mm", "envc5"}},
+	{ID: "DL", Name: "DL 信封", Standard: StandardISO, WidthMM: 110, HeightMM: 220,
+		Aliases: []string{"iso_dl_110x220mm", "envdl"}},
+	{ID: "Letter", Name: "Letter", Standard: StandardANSI, WidthMM: 215.9, HeightMM: 279.4,
+		Aliases: []string{"na_letter_8.5x11in", "na-letter", "us-letter", "usletter", "ansi-a", "lettersmall"}},
+	{ID: "Legal", Name: "Legal", Standard: StandardANSI, WidthMM: 215.9, HeightMM: 355.6,
+		Aliases: []string{"na_legal_8.5x14in", "na-legal", "us-legal", "uslegal"}},
+	{ID: "Tabloid", Name: "Tabloid / Ledger", Standard: StandardANSI, WidthMM: 279.4, HeightMM: 431.8,
+		Aliases: []string{"na_ledger_11x17in", "ledger", "ansi-b", "11x17"}},
+	{ID: "Executive", Name: "Executive", Standard: StandardANSI, WidthMM: 184.15, HeightMM: 266.7,
+		Aliases: []string{"na_executive_7.25x10.5in", "na-executive"}},
+	{ID: "Env10", Name: "#10 信封", Standard: StandardANSI, WidthMM: 104.775, HeightMM: 241.3,
+		Aliases: []string{"na_number-10_4.125x9.5in", "com10", "number-10"}},
+}
+
+// aliasIndex 规范化后的写法 → 规范ID
+var aliasIndex = buildAliasIndex()
+
+func buildAliasIndex() map[string]string {
+	index := make(map[string]string)
+	for _, size := range catalog {
+		index[aliasKey(size.ID)] = size.ID
+		for _, alias := range size.Aliases {
+			index[aliasKey(alias)] = size.ID
+		}
+	}
+	return index
+}
+
+// aliasKey 忽略大小写、空格、下划线和连字符
+func aliasKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(s)))
+}
+
+// dimensionPattern 匹配 PWG 自描述媒体名或直接书写的尺寸，如 iso_a4_210x297mm、custom_8.5x11in、210x297mm
+var dimensionPattern = regexp.MustCompile(`(?:^|_)(\d+(?:\.\d+)?)x(\d+(?:\.\d+)?)(mm|in)$`)
+
+// dimensionTolerance 按尺寸匹配时允许的误差（毫米）
+const dimensionTolerance = 1.0
+
+// Catalog 返回支持的纸张尺寸列表
+func Catalog() []Size {
+	sizes := make([]Size, len(catalog))
+	copy(sizes, catalog)
+	return sizes
+}
+
+// Normalize 将纸张尺寸写法转换为规范ID
+// 无法识别时返回去除首尾空格的原值和 false，调用方应保留原值并标记为未识别
+func Normalize(s string) (string, bool) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return "", true
+	}
+	if id, ok := aliasIndex[aliasKey(trimmed)]; ok {
+		return id, true
+	}
+	if id, ok := matchDimensions(strings.ToLower(trimmed)); ok {
+		return id, true
+	}
+	return trimmed, false
+}
+
+// NormalizeList 规范化纸张尺寸列表并去重，同时返回无法识别的写法（已保留在结果中）
+func NormalizeList(sizes []string) (normalized []string, unknown []string) {
+	seen := make(map[string]bool, len(sizes))
+	for _, s := range sizes {
+		id, known := Normalize(s)
+		key := strings.ToLower(id) // 无法识别的写法忽略大小写去重
+		if id == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, id)
+		if !known {
+			unknown = append(unknown, id)
+		}
+	}
+	return normalized, unknown
+}
+
+// Equal 两个写法是否表示同一纸张尺寸（无法识别的写法按忽略大小写比较）
+func Equal(a, b string) bool {
+	idA, knownA := Normalize(a)
+	idB, knownB := Normalize(b)
+	if knownA && knownB {
+		return idA == idB
+	}
+	return strings.EqualFold(idA, idB)
+}
+
+// matchDimensions 按名称中的尺寸匹配目录中的纸张（横向尺寸同样匹配）
+func matchDimensions(s string) (string, bool) {
+	m := dimensionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return "", false
+	}
+	width, err1 := strconv.ParseFloat(m[1], 64)
+	height, err2 := strconv.ParseFloat(m[2], 64)
+	if err1 != nil || err2 != nil {
+		return "", false
+	}
+	if m[3] == "in" {
+		width *= 25.4
+		height *= 25.4
+	}
+	if width > height {
+		width, height = height, width
+	}
+	for _, size := range catalog {
+		if math.Abs(size.WidthMM-width) <= dimensionTolerance && math.Abs(size.HeightMM-height) <= dimensionTolerance {
+			return size.ID, true
+		}
+	}
+	return "", false
+}
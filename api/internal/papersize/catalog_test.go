@@ -0,0 +1,121 @@
+package papersize
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestNormalizeAliases 常见写法（大小写、CUPS PPD 名称、IPP/PWG 媒体名、直接书写的尺寸）转换为规范ID
+func TestNormalizeAliases(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		known bool
+	}{
+		{"A4", "A4", true},
+		{"a4", "A4", true},
+		{"  A4 ", "A4", true},
+		{"ISO A4", "A4", true},
+		{"iso_a4_210x297mm", "A4", true},
+		{"iso-a4", "A4", true},
+		{"A4Small", "A4", true},
+		{"210x297mm", "A4", true},
+		{"297x210mm", "A4", true}, // 横向
+		{"custom_210.5x297mm", "A4", true},
+		{"iso_a3_297x420mm", "A3", true},
+		{"a5", "A5", true},
+		{"B5", "B5", true},
+		{"B5JIS", "JIS-B5", true},
+		{"jis_b5_182x257mm", "JIS-B5", true},
+		{"Letter", "Letter", true},
+		{"letter", "Letter", true},
+		{"US Letter", "Letter", true},
+		{"na_letter_8.5x11in", "Letter", true},
+		{"na-letter", "Letter", true},
+		{"8.5x11in", "Letter", true},
+		{"11x8.5in", "Letter", true},
+		{"Legal", "Legal", true},
+		{"na_legal_8.5x14in", "Legal", true},
+		{"Ledger", "Tabloid", true},
+		{"11x17", "Tabloid", true},
+		{"na_ledger_11x17in", "Tabloid", true},
+		{"Executive", "Executive", true},
+		{"COM10", "Env10", true},
+		{"EnvDL", "DL", true},
+		{"iso_c5_162x229mm", "C5", true},
+		{"", "", true},
+		{"Weird Size", "Weird Size", false},
+		{" custom_100x100mm ", "custom_100x100mm", false},
+		{"A4x", "A4x", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, known := Normalize(tt.in)
+			if got != tt.want || known != tt.known {
+				t.Fatalf("Normalize(%q) = %q, %v; want %q, %v", tt.in, got, known, tt.want, tt.known)
+			}
+		})
+	}
+}
+
+// TestNormalizeList 去重后保留第一次出现的位置，无法识别的写法保留在结果中并单独列出
+func TestNormalizeList(t *testing.T) {
+	normalized, unknown := NormalizeList([]string{
+		"iso_a4_210x297mm", "A4", "na_letter_8.5x11in", "", "Weird Size", "weird size", "Letter", "a3",
+	})
+	if want := []string{"A4", "Letter", "Weird Size", "A3"}; !reflect.DeepEqual(normalized, want) {
+		t.Fatalf("normalized = %v, want %v", normalized, want)
+	}
+	if want := []string{"Weird Size"}; !reflect.DeepEqual(unknown, want) {
+		t.Fatalf("unknown = %v, want %v", unknown, want)
+	}
+
+	normalized, unknown = NormalizeList(nil)
+	if normalized != nil || unknown != nil {
+		t.Fatalf("empty list = %v, %v", normalized, unknown)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"A4", "iso_a4_210x297mm", true},
+		{"letter", "na_letter_8.5x11in", true},
+		{"A4", "Letter", false},
+		{"B5", "B5JIS", false}, // ISO B5 与 JIS B5 尺寸不同
+		{"Weird Size", "weird size", true},
+		{"Weird Size", "A4", false},
+	}
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.want {
+			t.Errorf("Equal(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestCatalogConsistent 目录中的每个ID和别名都转换回自身，别名不冲突，尺寸为竖向
+func TestCatalogConsistent(t *testing.T) {
+	owner := map[string]string{}
+	for _, size := range Catalog() {
+		if size.WidthMM <= 0 || size.WidthMM > size.HeightMM {
+			t.Errorf("%s: %.2f x %.2f mm is not portrait", size.ID, size.WidthMM, size.HeightMM)
+		}
+		for _, name := range append([]string{size.ID}, size.Aliases...) {
+			if id, known := Normalize(name); !known || id != size.ID {
+				t.Errorf("Normalize(%q) = %q, %v; want %q", name, id, known, size.ID)
+			}
+			if previous, ok := owner[aliasKey(name)]; ok && previous != size.ID {
+				t.Errorf("alias %q belongs to both %s and %s", name, previous, size.ID)
+			}
+			owner[aliasKey(name)] = size.ID
+		}
+	}
+
+	sizes := Catalog()
+	sizes[0].ID = "changed"
+	if Catalog()[0].ID == "changed" {
+		t.Fatal("Catalog returned the shared slice")
+	}
+}
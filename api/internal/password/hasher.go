@@ -0,0 +1,153 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"fly-print-cloud/api/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 支持的哈希算法
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// argon2id 盐和输出长度（字节）
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// ErrUnknownFormat 哈希不是可识别的格式（bcrypt 的 $2a$/$2b$/$2y$ 或 argon2id 的 PHC 字符串）
+var ErrUnknownFormat = errors.New("password: unknown hash format")
+
+// Hasher 按配置的算法哈希密码；验证时按哈希前缀识别算法，迁移期间 bcrypt 和 argon2id 的哈希可以共存
+type Hasher struct {
+	cfg config.PasswordHashConfig
+}
+
+// NewHasher 创建密码哈希器
+func NewHasher(cfg *config.PasswordHashConfig) (*Hasher, error) {
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("password: invalid bcrypt cost %d", cfg.BcryptCost)
+		}
+	case AlgorithmArgon2id:
+		if cfg.Argon2Iterations < 1 || cfg.Argon2Parallelism < 1 || cfg.Argon2Memory < 8*uint32(cfg.Argon2Parallelism) {
+			return nil, fmt.Errorf("password: invalid argon2id parameters")
+		}
+	default:
+		return nil, fmt.Errorf("password: unsupported algorithm %q", cfg.Algorithm)
+	}
+	return &Hasher{cfg: *cfg}, nil
+}
+
+// DefaultHasher bcrypt.DefaultCost 的哈希器，未配置时使用（与引入配置之前的行为一致）
+func DefaultHasher() *Hasher {
+	return &Hasher{cfg: config.PasswordHashConfig{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.DefaultCost}}
+}
+
+// Hash 按配置的算法和参数哈希密码
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.cfg.Algorithm == AlgorithmArgon2id {
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("password: failed to generate salt: %w", err)
+		}
+		params := argon2Params{memory: h.cfg.Argon2Memory, iterations: h.cfg.Argon2Iterations, parallelism: h.cfg.Argon2Parallelism}
+		key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, argon2KeyLength)
+		return params.encode(salt, key), nil
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+	if err != nil {
+		return "", fmt.Errorf("password: failed to hash: %w", err)
+	}
+	return string(hashed), nil
+}
+
+// Verify 验证密码，返回是否匹配以及哈希是否需要按当前配置重新生成（算法不同或参数弱于配置）
+// 哈希格式无法识别时返回 ErrUnknownFormat
+func (h *Hasher) Verify(hash, password string) (bool, bool, error) {
+	switch {
+	case isBcrypt(hash):
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return false, false, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+		}
+		return true, h.cfg.Algorithm != AlgorithmBcrypt || cost < h.cfg.BcryptCost, nil
+
+	case strings.HasPrefix(hash, "$"+AlgorithmArgon2id+"$"):
+		params, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false, false, err
+		}
+		actual := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(actual, key) != 1 {
+			return false, false, nil
+		}
+		weaker := params.memory < h.cfg.Argon2Memory || params.iterations < h.cfg.Argon2Iterations ||
+			params.parallelism < h.cfg.Argon2Parallelism || len(key) < argon2KeyLength
+		return true, h.cfg.Algorithm != AlgorithmArgon2id || weaker, nil
+	}
+	return false, false, ErrUnknownFormat
+}
+
+// isBcrypt 是否为 bcrypt 哈希（$2a$、$2b$、$2y$ 前缀）
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// argon2Params argon2id 参数
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// encode 编码为 PHC 字符串：$argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>（base64 无填充）
+func (p argon2Params) encode(salt, key []byte) string {
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", AlgorithmArgon2id, argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+// decodeArgon2 解析 argon2id 的 PHC 字符串
+func decodeArgon2(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: unsupported argon2 version %q", ErrUnknownFormat, parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil ||
+		params.iterations < 1 || params.parallelism < 1 {
+		return params, nil, nil, fmt.Errorf("%w: invalid argon2 parameters %q", ErrUnknownFormat, parts[3])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("%w: invalid argon2 salt", ErrUnknownFormat)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("%w: invalid argon2 hash", ErrUnknownFormat)
+	}
+	return params, salt, key, nil
+}
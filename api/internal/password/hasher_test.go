@@ -0,0 +1,99 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"fly-print-cloud/api/internal/config"
+)
+
+// testArgon2 测试用的低成本 argon2id 参数
+func testArgon2(memory, iterations uint32) *config.PasswordHashConfig {
+	return &config.PasswordHashConfig{Algorithm: AlgorithmArgon2id, Argon2Memory: memory, Argon2Iterations: iterations, Argon2Parallelism: 1}
+}
+
+func mustHasher(t *testing.T, cfg *config.PasswordHashConfig) *Hasher {
+	t.Helper()
+	h, err := NewHasher(cfg)
+	if err != nil {
+		t.Fatalf("NewHasher(%+v): %v", cfg, err)
+	}
+	return h
+}
+
+func TestVerifyBcryptBelowConfiguredCost(t *testing.T) {
+	hash, err := mustHasher(t, &config.PasswordHashConfig{Algorithm: AlgorithmBcrypt, BcryptCost: 10}).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$2a$10$") {
+		t.Fatalf("hash = %q, want bcrypt cost 10", hash)
+	}
+
+	h := mustHasher(t, &config.PasswordHashConfig{Algorithm: AlgorithmBcrypt, BcryptCost: 12})
+	ok, rehash, err := h.Verify(hash, "secret")
+	if err != nil || !ok || !rehash {
+		t.Fatalf("Verify = %v, %v, %v; want match needing rehash", ok, rehash, err)
+	}
+	if ok, _, err := h.Verify(hash, "wrong"); err != nil || ok {
+		t.Fatalf("Verify wrong password = %v, %v", ok, err)
+	}
+}
+
+func TestVerifyArgon2id(t *testing.T) {
+	current := testArgon2(64, 2)
+	hash, err := mustHasher(t, current).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=2,p=1$") {
+		t.Fatalf("hash = %q, want PHC string with the configured parameters", hash)
+	}
+	weak, err := mustHasher(t, testArgon2(32, 1)).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	bcryptHash, err := mustHasher(t, &config.PasswordHashConfig{Algorithm: AlgorithmBcrypt, BcryptCost: 4}).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	h := mustHasher(t, current)
+	tests := []struct {
+		name       string
+		hash       string
+		password   string
+		wantOK     bool
+		wantRehash bool
+	}{
+		{"round trip", hash, "secret", true, false},
+		{"wrong password", hash, "wrong", false, false},
+		{"weaker parameters", weak, "secret", true, true},
+		{"bcrypt migrated to argon2id", bcryptHash, "secret", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := h.Verify(tt.hash, tt.password)
+			if err != nil || ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Fatalf("Verify = %v, %v, %v; want %v, %v", ok, rehash, err, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestVerifyUnknownFormat(t *testing.T) {
+	h := DefaultHasher()
+	for _, hash := range []string{
+		"$foo$bar$baz",
+		"plaintext",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",             // 缺少哈希段
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$aGFzaA",      // 不支持的版本
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$aGFzaA",      // 迭代次数为 0
+		"$argon2id$v=19$m=64,t=1,p=1$not base64!$aGFzaA", // 无效的盐
+	} {
+		if ok, _, err := h.Verify(hash, "secret"); ok || !errors.Is(err, ErrUnknownFormat) {
+			t.Fatalf("Verify(%q) = %v, %v; want ErrUnknownFormat", hash, ok, err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package policy 组织级打印策略的评估
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// 策略约束的任务参数（与创建请求字段一致）
+const (
+	FieldColorMode  = "color_mode"
+	FieldDuplexMode = "duplex_mode"
+	FieldCopies     = "copies"
+)
+
+// 策略评估使用的参数取值
+const (
+	colorModeColor     = "color"
+	colorModeGrayscale = "grayscale"
+	duplexModeSingle   = "single"
+	duplexModeDuplex   = "duplex"
+)
+
+// Subject 评估策略时的任务上下文
+type Subject struct {
+	Time            time.Time
+	UserName        string   // 任务归属用户，白名单按此匹配
+	Roles           []string // 提交人角色
+	PrinterGroupIDs []string // 目标打印机所属的打印机组
+	DuplexSupported bool     // 目标打印机是否支持双面
+}
+
+// Violation 任务违反的策略
+type Violation struct {
+	Policy  string `json:"policy"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Result 策略评估结果
+type Result struct {
+	Applied     []string                  // 生效的策略名称（按评估顺序）
+	Adjustments []models.PolicyAdjustment // 已应用到任务的强制调整
+	Violation   *Violation                // 不为空时任务应被拒绝，任务未被修改
+}
+
+// Sort 按评估顺序排序：优先级从高到低，相同时按名称，再按ID
+func Sort(policies []*models.PrintPolicy) {
+	sort.SliceStable(policies, func(i, j int) bool {
+		a, b := policies[i], policies[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+}
+
+// Matches 判断策略对任务是否生效：已启用、提交人不在白名单中且全部条件满足
+func Matches(p *models.PrintPolicy, subject *Subject) bool {
+	cond := &p.Conditions
+	if !p.Enabled || contains(cond.ExemptUsers, subject.UserName) {
+		return false
+	}
+	if len(cond.Roles) > 0 && !containsAny(cond.Roles, subject.Roles) {
+		return false
+	}
+	if len(cond.PrinterGroupIDs) > 0 && !containsAny(cond.PrinterGroupIDs, subject.PrinterGroupIDs) {
+		return false
+	}
+	return cond.ActiveAt(subject.Time)
+}
+
+// decision 决定某一参数的策略及其在评估顺序中的位置
+type decision struct {
+	policy *models.PrintPolicy
+	order  int
+}
+
+// Evaluate 按评估顺序对任务应用策略
+// 颜色、单双面和份数各由第一条生效且约束该参数的策略决定，之后策略对同一参数的效果被忽略。
+// 任一参数被禁止时返回评估顺序最靠前的违规项且不修改任务；否则将强制调整应用到任务
+func Evaluate(policies []*models.PrintPolicy, job *models.PrintJob, subject *Subject) *Result {
+	ordered := append([]*models.PrintPolicy(nil), policies...)
+	Sort(ordered)
+
+	result := &Result{}
+	var color, simplex, copies *decision
+	for i, p := range ordered {
+		if !Matches(p, subject) {
+			continue
+		}
+		decided := false
+		if p.Effects.Color != "" && color == nil {
+			color, decided = &decision{p, i}, true
+		}
+		if p.Effects.Simplex != "" && simplex == nil {
+			simplex, decided = &decision{p, i}, true
+		}
+		if p.Effects.MaxCopies != nil && copies == nil {
+			copies, decided = &decision{p, i}, true
+		}
+		if decided {
+			result.Applied = append(result.Applied, p.Name)
+		}
+	}
+
+	// 先收集全部调整和违规项，没有违规时才修改任务
+	var adjustments []models.PolicyAdjustment
+	var changes []func()
+	var violation *Violation
+	violationOrder := len(ordered)
+	reject := func(d *decision, field, message string) {
+		if d.order < violationOrder {
+			violation = &Violation{Policy: d.policy.Name, Field: field, Message: message}
+			violationOrder = d.order
+		}
+	}
+	adjust := func(d *decision, field, from, to string, change func()) {
+		adjustments = append(adjustments, models.PolicyAdjustment{Policy: d.policy.Name, Field: field, From: from, To: to})
+		changes = append(changes, change)
+	}
+
+	if color != nil && job.ColorMode != colorModeGrayscale {
+		if color.policy.Effects.Color == models.PolicyActionForbid && job.ColorMode == colorModeColor {
+			reject(color, FieldColorMode, fmt.Sprintf("策略 %s 禁止彩色打印", color.policy.Name))
+		} else {
+			adjust(color, FieldColorMode, job.ColorMode, colorModeGrayscale, func() { job.ColorMode = colorModeGrayscale })
+		}
+	}
+
+	if simplex != nil && job.DuplexMode != duplexModeDuplex {
+		forbid := simplex.policy.Effects.Simplex == models.PolicyActionForbid
+		switch {
+		case forbid && job.DuplexMode == duplexModeSingle:
+			reject(simplex, FieldDuplexMode, fmt.Sprintf("策略 %s 禁止单面打印", simplex.policy.Name))
+		case !subject.DuplexSupported && forbid:
+			reject(simplex, FieldDuplexMode, fmt.Sprintf("策略 %s 禁止单面打印，目标打印机不支持双面", simplex.policy.Name))
+		case subject.DuplexSupported:
+			adjust(simplex, FieldDuplexMode, job.DuplexMode, duplexModeDuplex, func() { job.DuplexMode = duplexModeDuplex })
+		}
+	}
+
+	if copies != nil {
+		limit := *copies.policy.Effects.MaxCopies
+		force := copies.policy.Effects.Copies == models.PolicyActionForce
+		if job.Copies > limit {
+			if force {
+				adjust(copies, FieldCopies, fmt.Sprint(job.Copies), fmt.Sprint(limit), func() { job.Copies = limit })
+			} else {
+				reject(copies, FieldCopies, fmt.Sprintf("策略 %s 限制打印份数不超过%d份", copies.policy.Name, limit))
+			}
+		}
+		for i := range job.Files {
+			file := &job.Files[i]
+			if file.Copies <= limit {
+				continue
+			}
+			field := fmt.Sprintf("files[%d].copies", i)
+			if force {
+				adjust(copies, field, fmt.Sprint(file.Copies), fmt.Sprint(limit), func() { file.Copies = limit })
+			} else {
+				reject(copies, field, fmt.Sprintf("策略 %s 限制第%d个文件的打印份数不超过%d份", copies.policy.Name, i+1, limit))
+			}
+		}
+	}
+
+	if violation != nil {
+		result.Violation = violation
+		return result
+	}
+
+	for _, change := range changes {
+		change()
+	}
+	result.Adjustments = adjustments
+	return result
+}
+
+// contains 判断列表中是否包含 value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny 判断两个列表是否有交集
+func containsAny(values, targets []string) bool {
+	for _, target := range targets {
+		if contains(values, target) {
+			return true
+		}
+	}
+	return false
+}
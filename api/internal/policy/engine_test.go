@@ -0,0 +1,251 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+// quietHours 18:00 之后（纽约时间）的彩色打印处理
+func quietHours(name string, priority int, action models.PolicyAction) *models.PrintPolicy {
+	return &models.PrintPolicy{
+		ID: name, Name: name, Priority: priority, Enabled: true,
+		Conditions: models.PrintPolicyConditions{StartTime: "18:00", EndTime: "07:00", Timezone: "America/New_York"},
+		Effects:    models.PrintPolicyEffects{Color: action},
+	}
+}
+
+// always 全天生效的策略
+func always(name string, priority int, effects models.PrintPolicyEffects) *models.PrintPolicy {
+	return &models.PrintPolicy{ID: name, Name: name, Priority: priority, Enabled: true, Effects: effects}
+}
+
+func TestEvaluate(t *testing.T) {
+	evening := time.Date(2026, 3, 3, 23, 30, 0, 0, time.UTC)     // 纽约 18:30
+	afternoon := time.Date(2026, 3, 3, 20, 0, 0, 0, time.UTC)    // 纽约 15:00
+	earlyMorning := time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC) // 纽约 06:00，前一晚的时段仍生效
+
+	tests := []struct {
+		name        string
+		policies    []*models.PrintPolicy
+		job         models.PrintJob
+		subject     Subject
+		applied     []string
+		adjustments []models.PolicyAdjustment
+		violation   *Violation
+		want        models.PrintJob // 评估后的任务参数
+	}{
+		{
+			name:    "no policies",
+			job:     models.PrintJob{ColorMode: "color", DuplexMode: "single", Copies: 3},
+			subject: Subject{Time: evening},
+			want:    models.PrintJob{ColorMode: "color", DuplexMode: "single", Copies: 3},
+		},
+		{
+			name:        "quiet hours force grayscale",
+			policies:    []*models.PrintPolicy{quietHours("quiet-hours", 0, models.PolicyActionForce)},
+			job:         models.PrintJob{ColorMode: "color"},
+			subject:     Subject{Time: evening, UserName: "alice"},
+			applied:     []string{"quiet-hours"},
+			adjustments: []models.PolicyAdjustment{{Policy: "quiet-hours", Field: FieldColorMode, From: "color", To: "grayscale"}},
+			want:        models.PrintJob{ColorMode: "grayscale"},
+		},
+		{
+			name:     "outside quiet hours",
+			policies: []*models.PrintPolicy{quietHours("quiet-hours", 0, models.PolicyActionForce)},
+			job:      models.PrintJob{ColorMode: "color"},
+			subject:  Subject{Time: afternoon},
+			want:     models.PrintJob{ColorMode: "color"},
+		},
+		{
+			name:        "overnight window belongs to start day",
+			policies:    []*models.PrintPolicy{quietHours("quiet-hours", 0, models.PolicyActionForce)},
+			job:         models.PrintJob{ColorMode: "color"},
+			subject:     Subject{Time: earlyMorning},
+			applied:     []string{"quiet-hours"},
+			adjustments: []models.PolicyAdjustment{{Policy: "quiet-hours", Field: FieldColorMode, From: "color", To: "grayscale"}},
+			want:        models.PrintJob{ColorMode: "grayscale"},
+		},
+		{
+			name: "exempt user",
+			policies: []*models.PrintPolicy{func() *models.PrintPolicy {
+				p := quietHours("quiet-hours", 0, models.PolicyActionForbid)
+				p.Conditions.ExemptUsers = []string{"ceo"}
+				return p
+			}()},
+			job:     models.PrintJob{ColorMode: "color"},
+			subject: Subject{Time: evening, UserName: "ceo"},
+			want:    models.PrintJob{ColorMode: "color"},
+		},
+		{
+			name:     "disabled policy",
+			policies: []*models.PrintPolicy{{Name: "off", Effects: models.PrintPolicyEffects{Color: models.PolicyActionForbid}}},
+			job:      models.PrintJob{ColorMode: "color"},
+			subject:  Subject{Time: evening},
+			want:     models.PrintJob{ColorMode: "color"},
+		},
+		{
+			name:      "forbid color rejects and leaves job untouched",
+			policies:  []*models.PrintPolicy{quietHours("quiet-hours", 0, models.PolicyActionForbid), always("duplex", 0, models.PrintPolicyEffects{Simplex: models.PolicyActionForce})},
+			job:       models.PrintJob{ColorMode: "color", DuplexMode: "single"},
+			subject:   Subject{Time: evening, DuplexSupported: true},
+			applied:   []string{"duplex", "quiet-hours"},
+			violation: &Violation{Policy: "quiet-hours", Field: FieldColorMode, Message: "策略 quiet-hours 禁止彩色打印"},
+			want:      models.PrintJob{ColorMode: "color", DuplexMode: "single"},
+		},
+		{
+			name:        "forbid color sets unspecified mode to grayscale",
+			policies:    []*models.PrintPolicy{quietHours("quiet-hours", 0, models.PolicyActionForbid)},
+			subject:     Subject{Time: evening},
+			applied:     []string{"quiet-hours"},
+			adjustments: []models.PolicyAdjustment{{Policy: "quiet-hours", Field: FieldColorMode, From: "", To: "grayscale"}},
+			want:        models.PrintJob{ColorMode: "grayscale"},
+		},
+		{
+			name: "higher priority decides the attribute",
+			policies: []*models.PrintPolicy{
+				always("forbid-simplex", 1, models.PrintPolicyEffects{Simplex: models.PolicyActionForbid}),
+				always("force-duplex", 10, models.PrintPolicyEffects{Simplex: models.PolicyActionForce}),
+			},
+			job:         models.PrintJob{DuplexMode: "single"},
+			subject:     Subject{Time: evening, DuplexSupported: true},
+			applied:     []string{"force-duplex"},
+			adjustments: []models.PolicyAdjustment{{Policy: "force-duplex", Field: FieldDuplexMode, From: "single", To: "duplex"}},
+			want:        models.PrintJob{DuplexMode: "duplex"},
+		},
+		{
+			name: "equal priority ordered by name",
+			policies: []*models.PrintPolicy{
+				always("b-forbid", 5, models.PrintPolicyEffects{Simplex: models.PolicyActionForbid}),
+				always("a-force", 5, models.PrintPolicyEffects{Simplex: models.PolicyActionForce}),
+			},
+			job:         models.PrintJob{DuplexMode: "single"},
+			subject:     Subject{Time: evening, DuplexSupported: true},
+			applied:     []string{"a-force"},
+			adjustments: []models.PolicyAdjustment{{Policy: "a-force", Field: FieldDuplexMode, From: "single", To: "duplex"}},
+			want:        models.PrintJob{DuplexMode: "duplex"},
+		},
+		{
+			name:     "force duplex skipped on simplex-only printer",
+			policies: []*models.PrintPolicy{always("duplex", 0, models.PrintPolicyEffects{Simplex: models.PolicyActionForce})},
+			job:      models.PrintJob{DuplexMode: "single"},
+			subject:  Subject{Time: evening},
+			applied:  []string{"duplex"},
+			want:     models.PrintJob{DuplexMode: "single"},
+		},
+		{
+			name:      "forbid simplex on simplex-only printer",
+			policies:  []*models.PrintPolicy{always("duplex", 0, models.PrintPolicyEffects{Simplex: models.PolicyActionForbid})},
+			subject:   Subject{Time: evening},
+			applied:   []string{"duplex"},
+			violation: &Violation{Policy: "duplex", Field: FieldDuplexMode, Message: "策略 duplex 禁止单面打印，目标打印机不支持双面"},
+		},
+		{
+			name:     "force copies ceiling on job and files",
+			policies: []*models.PrintPolicy{always("max-5", 0, models.PrintPolicyEffects{MaxCopies: intPtr(5), Copies: models.PolicyActionForce})},
+			job:      models.PrintJob{Copies: 10, Files: []models.PrintJobFile{{Copies: 2}, {Copies: 8}}},
+			subject:  Subject{Time: evening},
+			applied:  []string{"max-5"},
+			adjustments: []models.PolicyAdjustment{
+				{Policy: "max-5", Field: FieldCopies, From: "10", To: "5"},
+				{Policy: "max-5", Field: "files[1].copies", From: "8", To: "5"},
+			},
+			want: models.PrintJob{Copies: 5, Files: []models.PrintJobFile{{Copies: 2}, {Copies: 5}}},
+		},
+		{
+			name:      "forbid copies over ceiling",
+			policies:  []*models.PrintPolicy{always("max-5", 0, models.PrintPolicyEffects{MaxCopies: intPtr(5)})},
+			job:       models.PrintJob{Copies: 1, Files: []models.PrintJobFile{{Copies: 2}, {Copies: 8}}},
+			subject:   Subject{Time: evening},
+			applied:   []string{"max-5"},
+			violation: &Violation{Policy: "max-5", Field: "files[1].copies", Message: "策略 max-5 限制第2个文件的打印份数不超过5份"},
+			want:      models.PrintJob{Copies: 1, Files: []models.PrintJobFile{{Copies: 2}, {Copies: 8}}},
+		},
+		{
+			name: "earliest violating policy reported",
+			policies: []*models.PrintPolicy{
+				always("copies", 1, models.PrintPolicyEffects{MaxCopies: intPtr(1)}),
+				always("no-color", 2, models.PrintPolicyEffects{Color: models.PolicyActionForbid}),
+			},
+			job:       models.PrintJob{ColorMode: "color", Copies: 3},
+			subject:   Subject{Time: evening},
+			applied:   []string{"no-color", "copies"},
+			violation: &Violation{Policy: "no-color", Field: FieldColorMode, Message: "策略 no-color 禁止彩色打印"},
+			want:      models.PrintJob{ColorMode: "color", Copies: 3},
+		},
+		{
+			name: "role and printer group conditions",
+			policies: []*models.PrintPolicy{
+				{Name: "students", Enabled: true, Conditions: models.PrintPolicyConditions{Roles: []string{"student"}}, Effects: models.PrintPolicyEffects{Color: models.PolicyActionForce}},
+				{Name: "lab", Enabled: true, Conditions: models.PrintPolicyConditions{PrinterGroupIDs: []string{"lab"}}, Effects: models.PrintPolicyEffects{Simplex: models.PolicyActionForce}},
+			},
+			job:         models.PrintJob{ColorMode: "color", DuplexMode: "single"},
+			subject:     Subject{Time: evening, Roles: []string{"staff"}, PrinterGroupIDs: []string{"office", "lab"}, DuplexSupported: true},
+			applied:     []string{"lab"},
+			adjustments: []models.PolicyAdjustment{{Policy: "lab", Field: FieldDuplexMode, From: "single", To: "duplex"}},
+			want:        models.PrintJob{ColorMode: "color", DuplexMode: "duplex"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := tt.job
+			job.Files = append([]models.PrintJobFile(nil), tt.job.Files...)
+			result := Evaluate(tt.policies, &job, &tt.subject)
+
+			if !reflect.DeepEqual(result.Applied, tt.applied) {
+				t.Errorf("applied = %q, want %q", result.Applied, tt.applied)
+			}
+			if !reflect.DeepEqual(result.Adjustments, tt.adjustments) {
+				t.Errorf("adjustments = %+v, want %+v", result.Adjustments, tt.adjustments)
+			}
+			if !reflect.DeepEqual(result.Violation, tt.violation) {
+				t.Errorf("violation = %+v, want %+v", result.Violation, tt.violation)
+			}
+			if job.ColorMode != tt.want.ColorMode || job.DuplexMode != tt.want.DuplexMode || job.Copies != tt.want.Copies {
+				t.Errorf("job = {%q, %q, %d}, want {%q, %q, %d}",
+					job.ColorMode, job.DuplexMode, job.Copies, tt.want.ColorMode, tt.want.DuplexMode, tt.want.Copies)
+			}
+			for i := range tt.want.Files {
+				if job.Files[i].Copies != tt.want.Files[i].Copies {
+					t.Errorf("files[%d].copies = %d, want %d", i, job.Files[i].Copies, tt.want.Files[i].Copies)
+				}
+			}
+		})
+	}
+}
+
+// TestEvaluateDeterministic 评估结果与策略的加载顺序无关
+func TestEvaluateDeterministic(t *testing.T) {
+	policies := []*models.PrintPolicy{
+		always("c", 1, models.PrintPolicyEffects{Color: models.PolicyActionForce}),
+		always("b", 1, models.PrintPolicyEffects{Color: models.PolicyActionForbid, Simplex: models.PolicyActionForce}),
+		always("a", 0, models.PrintPolicyEffects{Simplex: models.PolicyActionForbid, MaxCopies: intPtr(2), Copies: models.PolicyActionForce}),
+		{ID: "z2", Name: "same", Priority: 3, Enabled: true, Effects: models.PrintPolicyEffects{MaxCopies: intPtr(1), Copies: models.PolicyActionForce}},
+		{ID: "z1", Name: "same", Priority: 3, Enabled: true, Effects: models.PrintPolicyEffects{MaxCopies: intPtr(4), Copies: models.PolicyActionForce}},
+	}
+	subject := &Subject{Time: time.Now(), DuplexSupported: true}
+
+	var first *Result
+	var firstJob models.PrintJob
+	for i := 0; i < len(policies); i++ {
+		rotated := append(append([]*models.PrintPolicy(nil), policies[i:]...), policies[:i]...)
+		job := models.PrintJob{DuplexMode: "single", Copies: 9}
+		result := Evaluate(rotated, &job, subject)
+		if first == nil {
+			first, firstJob = result, job
+			continue
+		}
+		if !reflect.DeepEqual(result, first) || job.ColorMode != firstJob.ColorMode || job.Copies != firstJob.Copies {
+			t.Fatalf("rotation %d: result %+v (job %+v), want %+v (job %+v)", i, result, job, first, firstJob)
+		}
+	}
+	// 同名同优先级时按 ID 排序，z1 决定份数上限
+	if first.Violation != nil || firstJob.ColorMode != "grayscale" || firstJob.DuplexMode != "duplex" || firstJob.Copies != 4 {
+		t.Fatalf("result %+v, job %+v; want grayscale duplex job capped at 4 copies by policy z1", first, firstJob)
+	}
+}
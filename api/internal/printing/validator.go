@@ -0,0 +1,233 @@
+package printing
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/papersize"
+)
+
+// 违规代码
+const (
+	ViolationColorUnsupported      = "color_unsupported"      // 打印机不支持彩色
+	ViolationDuplexUnsupported     = "duplex_unsupported"     // 打印机不支持双面
+	ViolationPaperSizeUnsupported  = "paper_size_unsupported" // 纸张尺寸不在打印机支持列表中
+	ViolationMediaTypeUnsupported  = "media_type_unsupported" // 介质类型不在打印机支持列表中
+	ViolationResolutionInvalid     = "resolution_invalid"     // 分辨率写法无法识别
+	ViolationResolutionUnsupported = "resolution_unsupported" // 分辨率超过打印机上报的最高分辨率
+	ViolationCopiesInvalid         = "copies_invalid"         // 份数不大于 0
+	ViolationCopiesExceeded        = "copies_exceeded"        // 份数超过上限
+)
+
+// Violation 任务参数与打印机能力不符的一项
+type Violation struct {
+	Field   string   `json:"field"`             // 请求字段，多文件任务的文件份数为 files[i].copies
+	Code    string   `json:"code"`              // 违规代码
+	Message string   `json:"message"`           // 可直接展示的说明
+	Allowed []string `json:"allowed,omitempty"` // 打印机支持的取值（可枚举时）
+	Max     int      `json:"max,omitempty"`     // 数值上限（份数）
+}
+
+// Violations 一次校验发现的全部违规项，为空表示通过
+type Violations []Violation
+
+// Summary 将全部违规项的说明合并为一句话
+func (v Violations) Summary() string {
+	messages := make([]string, len(v))
+	for i, violation := range v {
+		messages[i] = violation.Message
+	}
+	return strings.Join(messages, "；")
+}
+
+// Validator 校验打印任务参数是否符合打印机能力，一次返回全部违规项
+// 份数上限读取当前配置，支持热更新
+type Validator struct {
+	settings *config.Store
+}
+
+// NewValidator 创建能力校验器
+func NewValidator(settings *config.Store) *Validator {
+	return &Validator{settings: settings}
+}
+
+// Validate 校验任务的颜色、双面、纸张、介质类型、分辨率和份数
+// 打印机未上报纸张、介质或分辨率时不校验对应参数
+func (v *Validator) Validate(job *models.PrintJob, printer *models.Printer) Violations {
+	var violations Violations
+	caps := printer.Capabilities
+
+	if job.ColorMode == "color" && !caps.ColorSupport {
+		violations = append(violations, Violation{
+			Field:   "color_mode",
+			Code:    ViolationColorUnsupported,
+			Message: fmt.Sprintf("打印机 %s 不支持彩色打印", printer.Name),
+			Allowed: []string{"grayscale"},
+		})
+	}
+
+	if job.DuplexMode == "duplex" && !caps.DuplexSupport {
+		violations = append(violations, Violation{
+			Field:   "duplex_mode",
+			Code:    ViolationDuplexUnsupported,
+			Message: fmt.Sprintf("打印机 %s 不支持双面打印", printer.Name),
+			Allowed: []string{"single"},
+		})
+	}
+
+	if job.PaperSize != "" && len(caps.PaperSizes) > 0 && !containsFunc(caps.PaperSizes, job.PaperSize, papersize.Equal) {
+		violations = append(violations, Violation{
+			Field:   "paper_size",
+			Code:    ViolationPaperSizeUnsupported,
+			Message: fmt.Sprintf("打印机 %s 不支持纸张大小 %s，支持的大小：%v", printer.Name, job.PaperSize, caps.PaperSizes),
+			Allowed: caps.PaperSizes,
+		})
+	}
+
+	if job.MediaType != "" && len(caps.MediaTypes) > 0 && !containsFunc(caps.MediaTypes, job.MediaType, strings.EqualFold) {
+		violations = append(violations, Violation{
+			Field:   "media_type",
+			Code:    ViolationMediaTypeUnsupported,
+			Message: fmt.Sprintf("打印机 %s 不支持介质类型 %s，支持的类型：%v", printer.Name, job.MediaType, caps.MediaTypes),
+			Allowed: caps.MediaTypes,
+		})
+	}
+
+	if job.Resolution != "" {
+		if violation, ok := checkResolution(job.Resolution, printer); !ok {
+			violations = append(violations, violation)
+		}
+	}
+
+	violations = append(violations, v.checkCopies(job, printer)...)
+	return violations
+}
+
+// CopiesLimit 返回生效的份数上限及其来源（0 表示不限制）：全局上限与打印机上限取较小值
+func (v *Validator) CopiesLimit(printer *models.Printer) (int, string) {
+	limit, source := v.settings.Get().Jobs.MaxCopies, "全局上限"
+	if printer.MaxCopies != nil && *printer.MaxCopies > 0 && (limit <= 0 || *printer.MaxCopies < limit) {
+		limit, source = *printer.MaxCopies, fmt.Sprintf("打印机 %s 的上限", printer.Name)
+	}
+	return limit, source
+}
+
+// checkCopies 校验任务份数和多文件任务中各文件的份数
+func (v *Validator) checkCopies(job *models.PrintJob, printer *models.Printer) Violations {
+	if job.Copies <= 0 {
+		return Violations{{Field: "copies", Code: ViolationCopiesInvalid, Message: "打印份数必须大于0"}}
+	}
+	limit, source := v.CopiesLimit(printer)
+	if limit <= 0 {
+		return nil
+	}
+
+	var violations Violations
+	if job.Copies > limit {
+		violations = append(violations, Violation{
+			Field:   "copies",
+			Code:    ViolationCopiesExceeded,
+			Message: fmt.Sprintf("打印份数不能超过%d份（%s）", limit, source),
+			Max:     limit,
+		})
+	}
+	for _, file := range job.Files {
+		if file.Copies > limit {
+			violations = append(violations, Violation{
+				Field:   fmt.Sprintf("files[%d].copies", file.Position-1),
+				Code:    ViolationCopiesExceeded,
+				Message: fmt.Sprintf("第%d个文件的打印份数不能超过%d份（%s）", file.Position, limit, source),
+				Max:     limit,
+			})
+		}
+	}
+	return violations
+}
+
+// resolutionPattern 分辨率写法：600、600dpi、600x600、1200x600 dpi
+var resolutionPattern = regexp.MustCompile(`(?i)^\s*(\d+)\s*(?:[x×*]\s*(\d+))?\s*(?:dpi)?\s*$`)
+
+// resolution 水平 × 垂直分辨率（dpi）
+type resolution struct {
+	x, y int
+}
+
+func (r resolution) String() string {
+	return fmt.Sprintf("%dx%d", r.x, r.y)
+}
+
+// parseResolution 解析单个分辨率，只写一个数值时水平与垂直相同
+func parseResolution(s string) (resolution, bool) {
+	match := resolutionPattern.FindStringSubmatch(s)
+	if match == nil {
+		return resolution{}, false
+	}
+	x, err := strconv.Atoi(match[1])
+	if err != nil || x <= 0 {
+		return resolution{}, false
+	}
+	y := x
+	if match[2] != "" {
+		if y, err = strconv.Atoi(match[2]); err != nil || y <= 0 {
+			return resolution{}, false
+		}
+	}
+	return resolution{x: x, y: y}, true
+}
+
+// parseResolutionList 解析打印机上报的分辨率，可为逗号、分号或斜杠分隔的多个值（如 "300, 600, 1200x1200 dpi"）
+// 单位只写在末尾时对每个值都有效；无法识别的值忽略
+func parseResolutionList(s string) []resolution {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "dpi"), "DPI")
+	var list []resolution
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == '/' || r == '，' }) {
+		if res, ok := parseResolution(part); ok {
+			list = append(list, res)
+		}
+	}
+	return list
+}
+
+// checkResolution 请求的分辨率不能超过打印机上报的最高分辨率（打印机可以较低分辨率输出）
+func checkResolution(requested string, printer *models.Printer) (Violation, bool) {
+	want, ok := parseResolution(requested)
+	if !ok {
+		return Violation{
+			Field:   "resolution",
+			Code:    ViolationResolutionInvalid,
+			Message: fmt.Sprintf("无法识别的分辨率 %s，应为 600 或 600x600 形式（dpi）", requested),
+		}, false
+	}
+
+	supported := parseResolutionList(printer.Capabilities.Resolution)
+	if len(supported) == 0 {
+		return Violation{}, true
+	}
+	allowed := make([]string, len(supported))
+	for i, res := range supported {
+		if want.x <= res.x && want.y <= res.y {
+			return Violation{}, true
+		}
+		allowed[i] = res.String()
+	}
+	return Violation{
+		Field:   "resolution",
+		Code:    ViolationResolutionUnsupported,
+		Message: fmt.Sprintf("打印机 %s 不支持分辨率 %s dpi，支持的分辨率：%s", printer.Name, want, printer.Capabilities.Resolution),
+		Allowed: allowed,
+	}, false
+}
+
+func containsFunc(values []string, target string, equal func(a, b string) bool) bool {
+	for _, value := range values {
+		if equal(value, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// ErrNoCandidates 没有通过能力和启用校验的候选打印机
+var ErrNoCandidates = errors.New("no eligible printer in group")
+
+// Candidate 通过能力、启用和访问校验的候选打印机
+type Candidate struct {
+	Printer *models.Printer
+	Load    int // 执行中 + 排队中的任务数
+}
+
+// Request 选机请求
+type Request struct {
+	GroupID       string
+	UserName      string   // 提交用户（sticky_user 使用）
+	Latitude      *float64 // 提交位置（nearest 使用）
+	Longitude     *float64
+	LastPrinterID string // 该用户上次在组内分配到的打印机（sticky_user 使用）
+}
+
+// Router 选机策略：从候选打印机中选择一台
+// 实现必须是确定性的：相同的输入（含轮询状态）总是选出同一台打印机，并列时按打印机ID排序
+type Router interface {
+	Strategy() models.RoutingStrategy
+	Select(req Request, candidates []Candidate) (*models.Printer, error)
+}
+
+// Routers 按策略获取选机实现；轮询状态保存在进程内，多副本部署时各副本独立轮询
+type Routers struct {
+	routers map[models.RoutingStrategy]Router
+}
+
+// NewRouters 创建所有内置选机策略
+func NewRouters() *Routers {
+	leastQueue := LeastQueue{}
+	return &Routers{routers: map[models.RoutingStrategy]Router{
+		models.RoutingLeastQueue: leastQueue,
+		models.RoutingRoundRobin: NewRoundRobin(),
+		models.RoutingNearest:    Nearest{Fallback: leastQueue},
+		models.RoutingStickyUser: StickyUser{Fallback: leastQueue},
+	}}
+}
+
+// Get 获取策略对应的选机实现，空策略使用默认策略
+func (r *Routers) Get(strategy models.RoutingStrategy) (Router, error) {
+	if strategy == "" {
+		strategy = models.DefaultRoutingStrategy
+	}
+	router, ok := r.routers[strategy]
+	if !ok {
+		return nil, fmt.Errorf("unknown routing strategy %q", strategy)
+	}
+	return router, nil
+}
+
+// sortedByID 按打印机ID排序后的候选副本，保证并列时结果稳定
+func sortedByID(candidates []Candidate) []Candidate {
+	sorted := append([]Candidate(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Printer.ID < sorted[j].Printer.ID })
+	return sorted
+}
+
+// LeastQueue 选择负载最小的打印机
+type LeastQueue struct{}
+
+// Strategy 策略名称
+func (LeastQueue) Strategy() models.RoutingStrategy { return models.RoutingLeastQueue }
+
+// Select 选择负载最小的打印机，负载相同时选择ID最小的
+func (LeastQueue) Select(_ Request, candidates []Candidate) (*models.Printer, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoCandidates
+	}
+	sorted := sortedByID(candidates)
+	best := sorted[0]
+	for _, candidate := range sorted[1:] {
+		if candidate.Load < best.Load {
+			best = candidate
+		}
+	}
+	return best.Printer, nil
+}
+
+// RoundRobin 在候选打印机之间按ID顺序轮流分配（每个组独立计数）
+type RoundRobin struct {
+	mu       sync.Mutex
+	counters map[string]uint64
+}
+
+// NewRoundRobin 创建轮询策略
+func NewRoundRobin() *RoundRobin {
+	return &RoundRobin{counters: make(map[string]uint64)}
+}
+
+// Strategy 策略名称
+func (*RoundRobin) Strategy() models.RoutingStrategy { return models.RoutingRoundRobin }
+
+// Select 选择本组下一台打印机；候选集合变化时按新集合继续取模
+func (r *RoundRobin) Select(req Request, candidates []Candidate) (*models.Printer, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoCandidates
+	}
+	sorted := sortedByID(candidates)
+
+	r.mu.Lock()
+	next := r.counters[req.GroupID]
+	r.counters[req.GroupID] = next + 1
+	r.mu.Unlock()
+
+	return sorted[next%uint64(len(sorted))].Printer, nil
+}
+
+// Nearest 选择距离提交位置最近的打印机；未提供位置或候选打印机都没有坐标时使用 Fallback
+type Nearest struct {
+	Fallback Router
+}
+
+// Strategy 策略名称
+func (Nearest) Strategy() models.RoutingStrategy { return models.RoutingNearest }
+
+// Select 选择最近的打印机，没有坐标的打印机不参与比较，距离相同时选择ID最小的
+func (n Nearest) Select(req Request, candidates []Candidate) (*models.Printer, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoCandidates
+	}
+	if req.Latitude == nil || req.Longitude == nil {
+		return n.Fallback.Select(req, candidates)
+	}
+
+	var best *models.Printer
+	bestDistance := math.Inf(1)
+	for _, candidate := range sortedByID(candidates) {
+		printer := candidate.Printer
+		if printer.Latitude == nil || printer.Longitude == nil {
+			continue
+		}
+		distance := DistanceKm(*req.Latitude, *req.Longitude, *printer.Latitude, *printer.Longitude)
+		if distance < bestDistance {
+			best, bestDistance = printer, distance
+		}
+	}
+	if best == nil {
+		return n.Fallback.Select(req, candidates)
+	}
+	return best, nil
+}
+
+// DistanceKm 两个经纬度之间的球面距离（公里，haversine 公式）
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// StickyUser 同一用户优先使用上次分配的打印机；上次的打印机不再是候选时使用 Fallback
+type StickyUser struct {
+	Fallback Router
+}
+
+// Strategy 策略名称
+func (StickyUser) Strategy() models.RoutingStrategy { return models.RoutingStickyUser }
+
+// Select 上次分配的打印机仍可用时继续使用，否则由 Fallback 选择
+func (s StickyUser) Select(req Request, candidates []Candidate) (*models.Printer, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoCandidates
+	}
+	if req.LastPrinterID != "" {
+		for _, candidate := range candidates {
+			if candidate.Printer.ID == req.LastPrinterID {
+				return candidate.Printer, nil
+			}
+		}
+	}
+	return s.Fallback.Select(req, candidates)
+}
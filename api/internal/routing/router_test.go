@@ -0,0 +1,288 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// coord 返回坐标指针
+func coord(v float64) *float64 { return &v }
+
+// candidate 构造候选打印机
+func candidate(id string, load int) Candidate {
+	return Candidate{Printer: &models.Printer{ID: id}, Load: load}
+}
+
+// located 构造带坐标的候选打印机
+func located(id string, lat, lon float64) Candidate {
+	return Candidate{Printer: &models.Printer{ID: id, Latitude: coord(lat), Longitude: coord(lon)}}
+}
+
+// seededCandidates 用固定种子生成候选打印机：负载在 [0,4) 内以制造并列，约三分之一没有坐标
+func seededCandidates(rng *rand.Rand, n int) []Candidate {
+	candidates := make([]Candidate, n)
+	for i := range candidates {
+		printer := &models.Printer{ID: fmt.Sprintf("printer-%02d", rng.Intn(100))}
+		if rng.Intn(3) > 0 {
+			printer.Latitude = coord(30 + rng.Float64()*10)
+			printer.Longitude = coord(110 + rng.Float64()*10)
+		}
+		candidates[i] = Candidate{Printer: printer, Load: rng.Intn(4)}
+	}
+	// ID 唯一
+	seen := map[string]bool{}
+	unique := candidates[:0]
+	for _, c := range candidates {
+		if !seen[c.Printer.ID] {
+			seen[c.Printer.ID] = true
+			unique = append(unique, c)
+		}
+	}
+	return unique
+}
+
+// shuffled 返回候选打印机的随机排列副本
+func shuffled(rng *rand.Rand, candidates []Candidate) []Candidate {
+	out := append([]Candidate(nil), candidates...)
+	rng.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	return out
+}
+
+func selectID(t *testing.T, router Router, req Request, candidates []Candidate) string {
+	t.Helper()
+	printer, err := router.Select(req, candidates)
+	if err != nil {
+		t.Fatalf("%s select: %v", router.Strategy(), err)
+	}
+	return printer.ID
+}
+
+func TestLeastQueue(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []Candidate
+		want       string
+	}{
+		{"lowest load", []Candidate{candidate("a", 3), candidate("b", 1), candidate("c", 2)}, "b"},
+		{"tie picks lowest id", []Candidate{candidate("c", 1), candidate("b", 1), candidate("a", 2)}, "b"},
+		{"single", []Candidate{candidate("z", 9)}, "z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectID(t, LeastQueue{}, Request{}, tt.candidates); got != tt.want {
+				t.Fatalf("selected %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLeastQueueSeeded 随机生成的候选集合上结果等于最小负载中ID最小的打印机，且与候选顺序无关
+func TestLeastQueueSeeded(t *testing.T) {
+	rng := rand.New(rand.NewSource(394))
+	for i := 0; i < 200; i++ {
+		candidates := seededCandidates(rng, 1+rng.Intn(12))
+		want := candidates[0]
+		for _, c := range candidates[1:] {
+			if c.Load < want.Load || (c.Load == want.Load && c.Printer.ID < want.Printer.ID) {
+				want = c
+			}
+		}
+		for j := 0; j < 3; j++ {
+			if got := selectID(t, LeastQueue{}, Request{}, shuffled(rng, candidates)); got != want.Printer.ID {
+				t.Fatalf("round %d: selected %s, want %s", i, got, want.Printer.ID)
+			}
+		}
+	}
+}
+
+// TestRoundRobin 按ID顺序轮流分配，各组独立计数，候选集合变化后按新集合取模
+func TestRoundRobin(t *testing.T) {
+	rr := NewRoundRobin()
+	three := []Candidate{candidate("c", 0), candidate("a", 0), candidate("b", 0)}
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, selectID(t, rr, Request{GroupID: "g1"}, three))
+	}
+	if fmt.Sprint(got) != "[a b c a]" {
+		t.Fatalf("group g1 sequence = %v, want [a b c a]", got)
+	}
+	if first := selectID(t, rr, Request{GroupID: "g2"}, three); first != "a" {
+		t.Fatalf("group g2 first pick = %s, want a", first)
+	}
+
+	// g1 计数为 4，候选剩两台时 4 % 2 = 0
+	two := []Candidate{candidate("b", 0), candidate("c", 0)}
+	if next := selectID(t, rr, Request{GroupID: "g1"}, two); next != "b" {
+		t.Fatalf("after shrink = %s, want b", next)
+	}
+}
+
+// TestRoundRobinSeeded 固定候选集合上轮询 k 轮后每台打印机被选中的次数相同，且结果与候选顺序无关
+func TestRoundRobinSeeded(t *testing.T) {
+	rng := rand.New(rand.NewSource(394))
+	for i := 0; i < 50; i++ {
+		candidates := seededCandidates(rng, 1+rng.Intn(8))
+		rr := NewRoundRobin()
+		rounds := 1 + rng.Intn(4)
+		counts := map[string]int{}
+		for j := 0; j < rounds*len(candidates); j++ {
+			counts[selectID(t, rr, Request{GroupID: "g"}, shuffled(rng, candidates))]++
+		}
+		for _, c := range candidates {
+			if counts[c.Printer.ID] != rounds {
+				t.Fatalf("round %d: %s selected %d times, want %d (%v)", i, c.Printer.ID, counts[c.Printer.ID], rounds, counts)
+			}
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	shanghai := located("sh", 31.23, 121.47)
+	beijing := located("bj", 39.90, 116.40)
+	unlocated := candidate("aa", 0)
+	busyUnlocated := candidate("ab", 5)
+
+	tests := []struct {
+		name       string
+		req        Request
+		candidates []Candidate
+		want       string
+	}{
+		{"closest wins", Request{Latitude: coord(31.0), Longitude: coord(121.0)}, []Candidate{beijing, shanghai, unlocated}, "sh"},
+		{"other side", Request{Latitude: coord(40.0), Longitude: coord(116.0)}, []Candidate{shanghai, beijing}, "bj"},
+		{"no request location falls back", Request{}, []Candidate{beijing, busyUnlocated, unlocated}, "aa"},
+		{"no printer location falls back", Request{Latitude: coord(31.0), Longitude: coord(121.0)}, []Candidate{busyUnlocated, unlocated}, "aa"},
+		{"equal distance picks lowest id", Request{Latitude: coord(0), Longitude: coord(0)},
+			[]Candidate{located("b", 0, 1), located("a", 0, -1)}, "a"},
+	}
+	router := Nearest{Fallback: LeastQueue{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectID(t, router, tt.req, tt.candidates); got != tt.want {
+				t.Fatalf("selected %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNearestSeeded 随机位置下结果等于有坐标的打印机中距离最近的一台，且与候选顺序无关
+func TestNearestSeeded(t *testing.T) {
+	rng := rand.New(rand.NewSource(394))
+	router := Nearest{Fallback: LeastQueue{}}
+	for i := 0; i < 200; i++ {
+		candidates := seededCandidates(rng, 1+rng.Intn(12))
+		req := Request{Latitude: coord(30 + rng.Float64()*10), Longitude: coord(110 + rng.Float64()*10)}
+
+		want := ""
+		best := math.Inf(1)
+		for _, c := range candidates {
+			if c.Printer.Latitude == nil {
+				continue
+			}
+			d := DistanceKm(*req.Latitude, *req.Longitude, *c.Printer.Latitude, *c.Printer.Longitude)
+			if d < best || (d == best && c.Printer.ID < want) {
+				want, best = c.Printer.ID, d
+			}
+		}
+		if want == "" {
+			want = selectID(t, LeastQueue{}, req, candidates)
+		}
+		for j := 0; j < 3; j++ {
+			if got := selectID(t, router, req, shuffled(rng, candidates)); got != want {
+				t.Fatalf("round %d: selected %s, want %s", i, got, want)
+			}
+		}
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	// 上海 - 北京约 1067 公里
+	if d := DistanceKm(31.23, 121.47, 39.90, 116.40); math.Abs(d-1067) > 5 {
+		t.Fatalf("Shanghai-Beijing = %.1f km, want about 1067", d)
+	}
+	if d := DistanceKm(10, 20, 10, 20); d != 0 {
+		t.Fatalf("same point = %f, want 0", d)
+	}
+	// 对跖点为半个地球周长
+	if d := DistanceKm(0, 0, 0, 180); math.Abs(d-math.Pi*6371) > 1e-6 {
+		t.Fatalf("antipodes = %f, want %f", d, math.Pi*6371)
+	}
+}
+
+func TestStickyUser(t *testing.T) {
+	candidates := []Candidate{candidate("a", 5), candidate("b", 0), candidate("c", 2)}
+	router := StickyUser{Fallback: LeastQueue{}}
+
+	tests := []struct {
+		name string
+		last string
+		want string
+	}{
+		{"last printer still eligible", "c", "c"},
+		{"busy last printer is kept", "a", "a"},
+		{"last printer gone falls back", "gone", "b"},
+		{"first submission falls back", "", "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectID(t, router, Request{UserName: "alice", LastPrinterID: tt.last}, candidates); got != tt.want {
+				t.Fatalf("selected %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestStickyUserSeeded 上次的打印机在候选中时总是选中它，否则与 Fallback 结果一致
+func TestStickyUserSeeded(t *testing.T) {
+	rng := rand.New(rand.NewSource(394))
+	router := StickyUser{Fallback: LeastQueue{}}
+	for i := 0; i < 200; i++ {
+		candidates := seededCandidates(rng, 1+rng.Intn(12))
+		last := fmt.Sprintf("printer-%02d", rng.Intn(100))
+		want := selectID(t, LeastQueue{}, Request{}, candidates)
+		for _, c := range candidates {
+			if c.Printer.ID == last {
+				want = last
+			}
+		}
+		if got := selectID(t, router, Request{LastPrinterID: last}, shuffled(rng, candidates)); got != want {
+			t.Fatalf("round %d: last %s, selected %s, want %s", i, last, got, want)
+		}
+	}
+}
+
+// TestNoCandidates 所有策略在没有候选打印机时返回 ErrNoCandidates
+func TestNoCandidates(t *testing.T) {
+	routers := NewRouters()
+	for _, strategy := range []models.RoutingStrategy{
+		models.RoutingLeastQueue, models.RoutingRoundRobin, models.RoutingNearest, models.RoutingStickyUser,
+	} {
+		router, err := routers.Get(strategy)
+		if err != nil {
+			t.Fatalf("get %s: %v", strategy, err)
+		}
+		if router.Strategy() != strategy {
+			t.Fatalf("router for %s reports %s", strategy, router.Strategy())
+		}
+		if _, err := router.Select(Request{LastPrinterID: "a", Latitude: coord(0), Longitude: coord(0)}, nil); !errors.Is(err, ErrNoCandidates) {
+			t.Fatalf("%s with no candidates: %v, want ErrNoCandidates", strategy, err)
+		}
+	}
+}
+
+func TestRoutersGet(t *testing.T) {
+	routers := NewRouters()
+	router, err := routers.Get("")
+	if err != nil || router.Strategy() != models.DefaultRoutingStrategy {
+		t.Fatalf("default router = %v, %v; want %s", router, err, models.DefaultRoutingStrategy)
+	}
+	if _, err := routers.Get("random"); err == nil {
+		t.Fatal("unknown strategy accepted")
+	}
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// LinkClaims 打印任务文件临时链接携带的信息
+type LinkClaims struct {
+	JobID      string `json:"job"`
+	ExpiresAt  int64  `json:"exp"`
+	IssuedBy   string `json:"by"`              // 生成链接的管理员用户名
+	IssuedByID string `json:"by_id,omitempty"` // 生成链接的管理员外部ID
+}
+
+// Expires 链接过期时间
+func (c *LinkClaims) Expires() time.Time {
+	return time.Unix(c.ExpiresAt, 0).UTC()
+}
+
+// LinkSigner 生成和校验打印任务文件临时链接（供技术支持粘贴到工单中访问）
+// 与存储后端无关：链接由云端校验后代理下载，不依赖对象存储的签名能力
+type LinkSigner struct {
+	secret []byte
+}
+
+// NewLinkSigner 创建文件临时链接签名器
+// secret 为空时使用随机密钥，链接在服务重启后失效，多副本部署时必须配置
+func NewLinkSigner(secret string) (*LinkSigner, error) {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate link secret: %w", err)
+		}
+		log.Printf("storage.signing_secret not set, using a random secret for support file links; links will not survive restarts")
+	}
+	return &LinkSigner{secret: key}, nil
+}
+
+// Sign 生成 token：base64url(claims) + "." + base64url(HMAC-SHA256)
+func (s *LinkSigner) Sign(claims LinkClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded), nil
+}
+
+// Verify 校验 token，签名不匹配返回 ErrSignatureInvalid，已过期返回 ErrSignatureExpired
+func (s *LinkSigner) Verify(token string) (*LinkClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(s.sign(encoded)), []byte(signature)) {
+		return nil, ErrSignatureInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrSignatureInvalid
+	}
+	var claims LinkClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.JobID == "" {
+		return nil, ErrSignatureInvalid
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return &claims, ErrSignatureExpired
+	}
+	return &claims, nil
+}
+
+// sign 对编码后的 claims 做 HMAC-SHA256 签名
+func (s *LinkSigner) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("support-link\n"))
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
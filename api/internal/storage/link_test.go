@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkSignerRoundTrip(t *testing.T) {
+	signer, err := NewLinkSigner("secret")
+	if err != nil {
+		t.Fatalf("NewLinkSigner: %v", err)
+	}
+	claims := LinkClaims{JobID: "job-1", ExpiresAt: time.Now().Add(time.Hour).Unix(), IssuedBy: "admin", IssuedByID: "ext-1"}
+	token, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	got, err := signer.Verify(token)
+	if err != nil || *got != claims {
+		t.Fatalf("Verify = %+v, %v; want %+v", got, err, claims)
+	}
+	if strings.ContainsAny(token, "/+=") {
+		t.Fatalf("token %q is not URL safe", token)
+	}
+}
+
+// TestLinkSignerRejects 篡改或用其他密钥签名的 token 返回 ErrSignatureInvalid，过期的 token 返回 ErrSignatureExpired
+func TestLinkSignerRejects(t *testing.T) {
+	signer, _ := NewLinkSigner("secret")
+	other, _ := NewLinkSigner("other-secret")
+	valid := LinkClaims{JobID: "job-1", ExpiresAt: time.Now().Add(time.Hour).Unix(), IssuedBy: "admin"}
+	token, _ := signer.Sign(valid)
+	payload, signature, _ := strings.Cut(token, ".")
+
+	// 换成另一个任务，保留原签名
+	forgedClaims := valid
+	forgedClaims.JobID = "job-2"
+	forgedPayload, _ := json.Marshal(forgedClaims)
+	forged := base64.RawURLEncoding.EncodeToString(forgedPayload)
+	otherToken, _ := other.Sign(valid)
+	emptyJob, _ := signer.Sign(LinkClaims{ExpiresAt: valid.ExpiresAt})
+	notJSON := base64.RawURLEncoding.EncodeToString([]byte("not json"))
+
+	invalid := map[string]string{
+		"other job":         forged + "." + signature,
+		"flipped signature": payload + "." + flip(signature),
+		"other secret":      otherToken,
+		"missing signature": payload,
+		"empty":             "",
+		"no job":            emptyJob,
+		"payload not json":  notJSON + "." + signer.sign(notJSON),
+		"payload not b64":   "%%%." + signer.sign("%%%"),
+	}
+	for name, token := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := signer.Verify(token); !errors.Is(err, ErrSignatureInvalid) {
+				t.Fatalf("Verify = %v, want ErrSignatureInvalid", err)
+			}
+		})
+	}
+
+	expired, _ := signer.Sign(LinkClaims{JobID: "job-1", ExpiresAt: time.Now().Add(-time.Second).Unix(), IssuedBy: "admin"})
+	claims, err := signer.Verify(expired)
+	if !errors.Is(err, ErrSignatureExpired) || claims == nil || claims.JobID != "job-1" {
+		t.Fatalf("expired Verify = %+v, %v; want ErrSignatureExpired with claims", claims, err)
+	}
+}
+
+// TestLinkSignerRandomSecret 未配置密钥时每个签名器使用不同的随机密钥
+func TestLinkSignerRandomSecret(t *testing.T) {
+	a, _ := NewLinkSigner("")
+	b, _ := NewLinkSigner("")
+	token, _ := a.Sign(LinkClaims{JobID: "job-1", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	if _, err := b.Verify(token); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("Verify with another random secret = %v, want ErrSignatureInvalid", err)
+	}
+}
+
+// flip 修改签名的第一个字符
+func flip(s string) string {
+	if s[0] == 'A' {
+		return "B" + s[1:]
+	}
+	return "A" + s[1:]
+}
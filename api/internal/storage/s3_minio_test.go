@@ -0,0 +1,114 @@
+//go:build minio
+
+// MinIO 集成测试：go test -tags minio ./internal/storage
+// 需要可访问的 MinIO（或其他 S3 兼容服务）和已创建的存储桶，例如：
+//
+//	docker run -d -p 9000:9000 minio/minio server /data
+//	FLY_PRINT_TEST_S3_ENDPOINT=http://localhost:9000 FLY_PRINT_TEST_S3_BUCKET=fly-print-test \
+//	FLY_PRINT_TEST_S3_ACCESS_KEY_ID=minioadmin FLY_PRINT_TEST_S3_SECRET_ACCESS_KEY=minioadmin \
+//	go test -tags minio ./internal/storage
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/httpx"
+	"github.com/google/uuid"
+)
+
+// newMinIOStorage 按环境变量连接 MinIO，未配置时终止测试
+func newMinIOStorage(t *testing.T) *S3Storage {
+	t.Helper()
+	cfg := &config.S3StorageConfig{
+		Endpoint:        os.Getenv("FLY_PRINT_TEST_S3_ENDPOINT"),
+		Region:          os.Getenv("FLY_PRINT_TEST_S3_REGION"),
+		Bucket:          os.Getenv("FLY_PRINT_TEST_S3_BUCKET"),
+		AccessKeyID:     os.Getenv("FLY_PRINT_TEST_S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("FLY_PRINT_TEST_S3_SECRET_ACCESS_KEY"),
+		PathStyle:       true,
+	}
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		t.Fatal("FLY_PRINT_TEST_S3_ENDPOINT and FLY_PRINT_TEST_S3_BUCKET must be set for the minio build tag")
+	}
+	client, err := httpx.NewStreamingClient(nil, 10*time.Second)
+	if err != nil {
+		t.Fatalf("NewStreamingClient: %v", err)
+	}
+	s, err := NewS3Storage(cfg, client)
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+	return s
+}
+
+// TestMinIORoundTrip 写入、读取、预签名下载和删除对象，对象键包含需要编码的字符
+func TestMinIORoundTrip(t *testing.T) {
+	s := newMinIOStorage(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"plain.pdf", "test$file (1)+a=b.pdf", "文件.pdf"} {
+		t.Run(name, func(t *testing.T) {
+			key := UploadPrefix + "/minio-test/" + uuid.New().String() + "/" + name
+			content := []byte("%PDF-1.4 " + strings.Repeat("x", 64*1024))
+
+			if err := s.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			t.Cleanup(func() { s.Delete(context.Background(), key) })
+
+			body, err := s.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			got, err := io.ReadAll(body)
+			body.Close()
+			if err != nil || !bytes.Equal(got, content) {
+				t.Fatalf("Get returned %d bytes (%v), want %d", len(got), err, len(content))
+			}
+
+			signed, err := s.SignedURL(ctx, key, time.Minute)
+			if err != nil {
+				t.Fatalf("SignedURL: %v", err)
+			}
+			resp, err := http.Get(signed)
+			if err != nil {
+				t.Fatalf("download signed url: %v", err)
+			}
+			got, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || err != nil || !bytes.Equal(got, content) {
+				t.Fatalf("signed download: status %d, %d bytes (%v): %s", resp.StatusCode, len(got), err, got)
+			}
+
+			if err := s.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get after delete = %v, want ErrNotFound", err)
+			}
+			if err := s.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete missing object = %v, want nil", err)
+			}
+		})
+	}
+}
+
+// TestMinIORejectsBadCredentials 错误的密钥返回签名错误而不是 ErrNotFound
+func TestMinIORejectsBadCredentials(t *testing.T) {
+	s := newMinIOStorage(t)
+	s.secretKey += "-wrong"
+	_, err := s.Get(context.Background(), UploadPrefix+"/minio-test/missing.pdf")
+	if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "status 403") {
+		t.Fatalf("Get with bad secret = %v, want 403 error", err)
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound 对象不存在
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrInvalidKey 对象键不合法（为空、绝对路径或包含 ..）
+	ErrInvalidKey = errors.New("storage: invalid object key")
+	// ErrSignatureInvalid 签名链接校验失败
+	ErrSignatureInvalid = errors.New("storage: invalid signature")
+	// ErrSignatureExpired 签名链接已过期
+	ErrSignatureExpired = errors.New("storage: signature expired")
+)
+
+// Storage 打印文件存储后端
+type Storage interface {
+	// Put 写入对象，size 未知时传 -1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，调用方负责关闭
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// SignedURL 生成短期有效的下载链接
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// SignatureVerifier 由云端代理签名链接下载的后端实现（local）
+type SignatureVerifier interface {
+	VerifySignature(key, expires, signature string) error
+}
+
+// New 按配置创建存储后端，client 用于访问 S3 兼容存储
+func New(cfg *config.StorageConfig, client *http.Client) (Storage, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "", "local":
+		return NewLocalStorage(cfg.Dir, cfg.SigningSecret, cfg.PublicBaseURL)
+	case "s3":
+		return NewS3Storage(&cfg.S3, client)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", cfg.Backend)
+	}
+}
+
+// UploadPrefix 用户上传的打印文件对象键前缀
+const UploadPrefix = "print-files"
+
+// IsUploadKey 判断对象键是否为合法的打印文件上传键
+func IsUploadKey(key string) bool {
+	cleaned, err := cleanKey(key)
+	return err == nil && strings.HasPrefix(cleaned, UploadPrefix+"/")
+}
+
+// NewKey 生成对象键：<prefix>/YYYY/MM/DD/<uuid><ext>，保留原始文件扩展名
+func NewKey(prefix, filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if len(ext) > 10 {
+		ext = ""
+	}
+	return path.Join(prefix, time.Now().UTC().Format("2006/01/02"), uuid.New().String()+ext)
+}
+
+// cleanKey 校验并规范化对象键
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned != key || strings.HasPrefix(cleaned, "../") || cleaned == ".." {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,72 @@
+package textlimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/storage"
+)
+
+// TruncatedMarker 截断后追加在文本末尾的标记
+const TruncatedMarker = "[truncated]"
+
+// spillTimeout 转存完整内容的超时时间，超时后只保存截断的内容
+const spillTimeout = 10 * time.Second
+
+// Truncate 将超过 maxLength 字节的文本截断，末尾追加 "[truncated] original length: N bytes"；
+// 截断位置不会切开 UTF-8 字符，结果不超过 maxLength 字节。maxLength <= 0 或未超出时原样返回，第二个返回值为 false
+func Truncate(text string, maxLength int) (string, bool) {
+	if maxLength <= 0 || len(text) <= maxLength {
+		return text, false
+	}
+
+	suffix := fmt.Sprintf("\n%s original length: %d bytes", TruncatedMarker, len(text))
+	keep := maxLength - len(suffix)
+	if keep < 0 {
+		// 上限比截断说明还短（配置校验要求至少 MinTextLimit，这里只保证不超出上限）
+		return suffix[:maxLength], true
+	}
+	for keep > 0 && !utf8.RuneStart(text[keep]) {
+		keep--
+	}
+	return text[:keep] + suffix, true
+}
+
+// Limiter 按 text_limits 限制日志类文本字段的长度（实现 database.TextLimiter）；
+// 开启 spill_to_storage 时，截断前将任务错误信息的完整内容写入文件存储
+type Limiter struct {
+	settings *config.Store // 长度上限和转存开关支持热更新，每次使用时读取
+	storage  storage.Storage
+}
+
+// NewLimiter 创建文本字段长度限制
+func NewLimiter(settings *config.Store, fileStorage storage.Storage) *Limiter {
+	return &Limiter{settings: settings, storage: fileStorage}
+}
+
+// Limit 截断超出上限的文本，返回写入数据库的内容和完整内容在存储中的对象键（未转存时为空）
+// spill 为 false 或转存失败时只截断（转存失败记录日志，不影响写入）
+func (l *Limiter) Limit(jobID, field, text string, spill bool) (string, string) {
+	cfg := l.settings.Get().TextLimits
+	limited, truncated := Truncate(text, cfg.MaxLength)
+	if !truncated {
+		return text, ""
+	}
+	if !spill || !cfg.SpillToStorage {
+		return limited, ""
+	}
+
+	key := storage.NewKey(strings.Trim(cfg.SpillPrefix, "/"), field+".txt")
+	ctx, cancel := context.WithTimeout(context.Background(), spillTimeout)
+	defer cancel()
+	if err := l.storage.Put(ctx, key, strings.NewReader(text), int64(len(text)), "text/plain; charset=utf-8"); err != nil {
+		log.Printf("Failed to spill %s of job %s (%d bytes) to storage: %v", field, jobID, len(text), err)
+		return limited, ""
+	}
+	return limited, key
+}
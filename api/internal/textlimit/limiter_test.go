@@ -0,0 +1,141 @@
+package textlimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"fly-print-cloud/api/internal/config"
+)
+
+func TestTruncate(t *testing.T) {
+	cjk := "a" + strings.Repeat("年", 200) // 601 字节，每个汉字 3 字节
+	tests := []struct {
+		name          string
+		text          string
+		max           int
+		wantTruncated bool
+		wantKeep      int // 保留的原文字节数
+	}{
+		{"under limit", "short", 300, false, 5},
+		{"exactly at limit", strings.Repeat("x", 300), 300, false, 300},
+		{"ascii over limit", strings.Repeat("x", 1000), 300, true, 300 - len("\n[truncated] original length: 1000 bytes")},
+		// 300 - 39 = 261 落在第 87 个汉字中间，退回到字符边界
+		{"multibyte straddling limit", cjk, 300, true, 259},
+		{"limit disabled", strings.Repeat("x", 1000), 0, false, 1000},
+		{"negative limit", strings.Repeat("x", 1000), -1, false, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := Truncate(tt.text, tt.max)
+			if truncated != tt.wantTruncated {
+				t.Fatalf("truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+			if !truncated {
+				if got != tt.text {
+					t.Fatalf("Truncate changed text within the limit: %q", got)
+				}
+				return
+			}
+			if len(got) > tt.max {
+				t.Fatalf("len = %d, exceeds max_length %d", len(got), tt.max)
+			}
+			if !utf8.ValidString(got) {
+				t.Fatalf("Truncate returned invalid UTF-8 %q", got)
+			}
+			marker := fmt.Sprintf("\n%s original length: %d bytes", TruncatedMarker, len(tt.text))
+			if !strings.HasSuffix(got, marker) || got[:len(got)-len(marker)] != tt.text[:tt.wantKeep] {
+				t.Fatalf("Truncate = %q, want the first %d bytes followed by %q", got, tt.wantKeep, marker)
+			}
+		})
+	}
+}
+
+// TestTruncateLimitBelowMarker 上限比截断说明还短时只保留截断说明的开头，不超出上限
+func TestTruncateLimitBelowMarker(t *testing.T) {
+	got, truncated := Truncate(strings.Repeat("x", 100), 10)
+	if !truncated || got != "\n[truncate" {
+		t.Fatalf("Truncate = %q, %v; want the first 10 bytes of the marker", got, truncated)
+	}
+}
+
+// memStorage 记录写入的对象，failPut 不为 nil 时写入失败
+type memStorage struct {
+	mu      sync.Mutex
+	objects map[string]string
+	failPut error
+}
+
+func (s *memStorage) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	if s.failPut != nil {
+		return s.failPut
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.objects == nil {
+		s.objects = map[string]string{}
+	}
+	s.objects[key] = string(data)
+	return nil
+}
+
+func (s *memStorage) Get(context.Context, string) (io.ReadCloser, error) { return nil, nil }
+func (s *memStorage) Delete(context.Context, string) error               { return nil }
+func (s *memStorage) SignedURL(context.Context, string, time.Duration) (string, error) {
+	return "", nil
+}
+
+func newTestLimiter(spill bool, store *memStorage) *Limiter {
+	cfg := &config.Config{}
+	cfg.TextLimits = config.TextLimitsConfig{MaxLength: config.MinTextLimit, SpillToStorage: spill, SpillPrefix: "/job-errors/"}
+	return NewLimiter(config.NewStore(cfg), store)
+}
+
+func TestLimiterSpill(t *testing.T) {
+	long := strings.Repeat("打印机报错 ", 100)
+
+	store := &memStorage{}
+	limited, key := newTestLimiter(true, store).Limit("job-1", "error_message", long, true)
+	if len(limited) > config.MinTextLimit || !strings.Contains(limited, TruncatedMarker) {
+		t.Fatalf("limited = %q, want truncated to %d bytes", limited, config.MinTextLimit)
+	}
+	if !strings.HasPrefix(key, "job-errors/") || !strings.HasSuffix(key, ".txt") {
+		t.Fatalf("key = %q, want job-errors/<date>/<id>.txt", key)
+	}
+	if len(store.objects) != 1 || store.objects[key] != long {
+		t.Fatalf("stored objects = %d, want the full text under %s", len(store.objects), key)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		spill   bool
+		enabled bool
+		failPut error
+	}{
+		{"within limit", "short", true, true, nil},
+		{"field not spilled", long, false, true, nil},
+		{"spill disabled", long, true, false, nil},
+		{"storage failure", long, true, true, errors.New("bucket unavailable")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memStorage{failPut: tt.failPut}
+			limited, key := newTestLimiter(tt.enabled, store).Limit("job-1", "error_message", tt.text, tt.spill)
+			want, _ := Truncate(tt.text, config.MinTextLimit)
+			if key != "" || limited != want || len(store.objects) != 0 {
+				t.Fatalf("Limit = %q, key %q, %d objects; want %q without spilling", limited, key, len(store.objects), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/storage"
+)
+
+// Prefix 缩略图对象键前缀
+const Prefix = "thumbnails"
+
+// retryDelay 首次生成失败后重试前的等待时间
+const retryDelay = 5 * time.Second
+
+// Key 打印文件对应的缩略图对象键
+func Key(storageKey string) string {
+	return path.Join(Prefix, storageKey+".png")
+}
+
+// Generator 后台为上传的 PDF 生成第一页缩略图并写入存储后端
+// 生成在有限数量的工作协程中进行，失败只记录日志并重试一次，不影响任务处理
+type Generator struct {
+	storage     storage.Storage
+	renderer    Renderer
+	width       int
+	concurrency int
+	timeout     time.Duration
+	settings    *config.Store // 读取文件的大小上限与上传限制（storage.max_upload_size）一致，支持热更新
+	queue       chan string
+}
+
+// NewGenerator 创建缩略图生成器，renderer 为 nil 时不生成缩略图
+func NewGenerator(fileStorage storage.Storage, renderer Renderer, settings *config.Store) *Generator {
+	cfg := settings.Get().Thumbnails
+	g := &Generator{
+		storage:     fileStorage,
+		renderer:    renderer,
+		width:       cfg.Width,
+		concurrency: cfg.Concurrency,
+		timeout:     cfg.Timeout,
+		settings:    settings,
+	}
+	if g.width <= 0 {
+		g.width = 256
+	}
+	if g.concurrency <= 0 {
+		g.concurrency = 2
+	}
+	if g.timeout <= 0 {
+		g.timeout = 30 * time.Second
+	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+	g.queue = make(chan string, queueSize)
+	return g
+}
+
+// Enabled 是否配置了渲染器
+func (g *Generator) Enabled() bool {
+	return g != nil && g.renderer != nil
+}
+
+// Enqueue 提交生成请求（不阻塞），队列已满时丢弃并返回 false
+func (g *Generator) Enqueue(storageKey string) bool {
+	if !g.Enabled() {
+		return false
+	}
+	select {
+	case g.queue <- storageKey:
+		return true
+	default:
+		log.Printf("Thumbnail queue is full, skipping %s", storageKey)
+		return false
+	}
+}
+
+// Run 启动工作协程（阻塞），未配置渲染器时直接返回
+func (g *Generator) Run() {
+	if !g.Enabled() {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < g.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for storageKey := range g.queue {
+				g.process(storageKey)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// process 生成缩略图，失败时重试一次
+func (g *Generator) process(storageKey string) {
+	err := g.generate(storageKey)
+	if err == nil {
+		return
+	}
+	log.Printf("Failed to generate thumbnail for %s, retrying: %v", storageKey, err)
+	time.Sleep(retryDelay)
+	if err := g.generate(storageKey); err != nil {
+		log.Printf("Failed to generate thumbnail for %s: %v", storageKey, err)
+	}
+}
+
+// generate 读取 PDF、渲染第一页并写入存储
+func (g *Generator) generate(storageKey string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	reader, err := g.storage.Get(ctx, storageKey)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	pdf, err := io.ReadAll(io.LimitReader(reader, g.settings.Get().Storage.MaxUploadSize))
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	png, err := g.renderer.Render(ctx, pdf, g.width)
+	if err != nil {
+		return err
+	}
+	if err := g.storage.Put(ctx, Key(storageKey), bytes.NewReader(png), int64(len(png)), "image/png"); err != nil {
+		return fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+	return nil
+}
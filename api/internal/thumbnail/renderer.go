@@ -0,0 +1,117 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"fly-print-cloud/api/internal/config"
+)
+
+// maxThumbnailSize 渲染结果大小上限，超过时视为渲染失败
+const maxThumbnailSize = 5 * 1024 * 1024
+
+// Renderer 将 PDF 第一页渲染为 PNG 缩略图
+type Renderer interface {
+	Render(ctx context.Context, pdf []byte, width int) ([]byte, error)
+}
+
+// 渲染方式
+const (
+	RendererDisabled = "disabled"
+	RendererPdftoppm = "pdftoppm"
+	RendererHTTP     = "http"
+)
+
+// NewRenderer 按配置创建渲染器，禁用时返回 nil
+func NewRenderer(cfg *config.ThumbnailsConfig, client *http.Client) (Renderer, error) {
+	switch strings.ToLower(cfg.Renderer) {
+	case "", RendererDisabled:
+		return nil, nil
+	case RendererPdftoppm:
+		path := cfg.PdftoppmPath
+		if path == "" {
+			path = "pdftoppm"
+		}
+		return &PdftoppmRenderer{path: path}, nil
+	case RendererHTTP:
+		if cfg.URL == "" {
+			return nil, errors.New("thumbnails.url is required for the http renderer")
+		}
+		return &HTTPRenderer{url: cfg.URL, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported thumbnail renderer: %s", cfg.Renderer)
+	}
+}
+
+// PdftoppmRenderer 调用 poppler 的 pdftoppm 命令渲染
+type PdftoppmRenderer struct {
+	path string
+}
+
+// Render 通过标准输入传入 PDF，从标准输出读取 PNG（按宽度等比缩放）
+func (r *PdftoppmRenderer) Render(ctx context.Context, pdf []byte, width int) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, r.path,
+		"-png", "-f", "1", "-l", "1", "-singlefile",
+		"-scale-to-x", strconv.Itoa(width), "-scale-to-y", "-1", "-")
+	cmd.Stdin = bytes.NewReader(pdf)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("pdftoppm failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 || stdout.Len() > maxThumbnailSize {
+		return nil, fmt.Errorf("pdftoppm returned %d bytes", stdout.Len())
+	}
+	return stdout.Bytes(), nil
+}
+
+// HTTPRenderer 调用外部渲染服务：POST <url>?width=N，请求体为 PDF，成功时返回 200 和 PNG
+type HTTPRenderer struct {
+	url    string
+	client *http.Client
+}
+
+// Render 将 PDF 发送给外部渲染服务
+func (r *HTTPRenderer) Render(ctx context.Context, pdf []byte, width int) ([]byte, error) {
+	endpoint, err := url.Parse(r.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid renderer url: %w", err)
+	}
+	query := endpoint.Query()
+	query.Set("width", strconv.Itoa(width))
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(pdf))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/pdf")
+	req.Header.Set("Accept", "image/png")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("renderer request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("renderer returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxThumbnailSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read renderer response: %w", err)
+	}
+	if len(data) == 0 || len(data) > maxThumbnailSize {
+		return nil, fmt.Errorf("renderer returned %d bytes", len(data))
+	}
+	return data, nil
+}
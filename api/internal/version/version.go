@@ -0,0 +1,4 @@
+package version
+
+// Version 服务版本号，构建时可通过 -ldflags "-X fly-print-cloud/api/internal/version.Version=x.y.z" 覆盖
+var Version = "1.0.0"
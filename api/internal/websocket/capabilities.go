@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/version"
+)
+
+// CapabilitiesSchemaVersion ServerCapabilities 的结构版本
+// 只允许新增字段；删除或修改已有字段的含义时必须递增，Edge Node 据此判断能否解析
+const CapabilitiesSchemaVersion = 1
+
+// SupportedProtocolVersions 支持的 WebSocket 子协议版本（Sec-WebSocket-Protocol），按优先级排列
+// 未声明子协议的旧版 Edge Node 仍按 v1 处理
+var SupportedProtocolVersions = []string{"fly-print.v1"}
+
+// 功能开关名称（Edge Node 使用某项协议功能前应先确认对应开关为 true）
+const (
+	FeatureCompression    = "compression"      // WebSocket permessage-deflate 压缩
+	FeatureBatchedFrames  = "batched_frames"   // 单个帧内携带多条消息
+	FeaturePullAPI        = "pull_api"         // 通过 HTTP 拉取待执行任务
+	FeatureProxyURL       = "proxy_url"        // 下发 proxy_url 配置
+	FeaturePrinterReview  = "printer_review"   // 新发现的打印机需管理员审核后才可使用
+	FeatureMultiFileJobs  = "multi_file_jobs"  // 打印任务携带多个文件（print_job.files）
+	FeatureJobMetadata    = "job_metadata"     // 打印任务携带调用方元数据（print_job.metadata）
+	FeatureDiagnostics    = "diagnostics"      // collect_diagnostics 指令
+	FeaturePrinterPower   = "printer_power"    // printer_power 指令
+	FeatureSignedFileURLs = "signed_file_urls" // 云端存储的文件以短时签名链接下发
+	FeatureLogStreaming   = "log_streaming"    // set_log_streaming 指令和 log_batch 上行消息
+	FeatureQueuePause     = "queue_pause"      // pause_queue / resume_queue 指令
+	FeatureJobDisplayName = "job_display_name" // 打印任务携带面向用户的名称（print_job.display_name）
+)
+
+// ServerCapabilities 云端能力描述，通过 GET /api/v1/edge/capabilities 和连接建立后的 welcome 消息下发
+type ServerCapabilities struct {
+	SchemaVersion            int             `json:"schema_version"`
+	ServerVersion            string          `json:"server_version"`
+	ProtocolVersions         []string        `json:"protocol_versions"`
+	ProtocolMinorVersion     int             `json:"protocol_minor_version"`     // 消息结构变化时递增，结构见 GET /api/v1/edge/schema
+	ProtocolSchemaDigest     string          `json:"protocol_schema_digest"`     // 当前消息结构 Schema 的摘要，可用于缓存
+	ValidateMessages         bool            `json:"validate_messages"`          // 上行消息不符合 Schema 时被丢弃并返回 schema_violation 错误
+	MaxMessageSize           int64           `json:"max_message_size"`           // 上行消息大小上限（字节）
+	HeartbeatIntervalSeconds int             `json:"heartbeat_interval_seconds"` // 建议的心跳上报间隔
+	HeartbeatTimeoutSeconds  int             `json:"heartbeat_timeout_seconds"`  // 超过该时长未收到心跳时节点标记为离线
+	PingIntervalSeconds      int             `json:"ping_interval_seconds"`      // 云端发送 WebSocket ping 的间隔
+	MessageRateLimits        map[string]int  `json:"message_rate_limits"`        // 各上行消息类型每分钟允许的条数，超出的消息被丢弃；未列出的类型不限速
+	Features                 map[string]bool `json:"features"`
+}
+
+// NewServerCapabilities 根据配置生成云端能力描述
+func NewServerCapabilities(edgeCfg *config.EdgeConfig) *ServerCapabilities {
+	return &ServerCapabilities{
+		SchemaVersion:            CapabilitiesSchemaVersion,
+		ServerVersion:            version.Version,
+		ProtocolVersions:         SupportedProtocolVersions,
+		ProtocolMinorVersion:     ProtocolMinorVersion,
+		ProtocolSchemaDigest:     ProtocolSchemaDigest(),
+		ValidateMessages:         edgeCfg.ValidateMessages,
+		MaxMessageSize:           maxMessageSize,
+		HeartbeatIntervalSeconds: int(edgeCfg.HeartbeatInterval.Seconds()),
+		HeartbeatTimeoutSeconds:  int(edgeCfg.HeartbeatTimeout.Seconds()),
+		PingIntervalSeconds:      int(pingPeriod.Seconds()),
+		MessageRateLimits:        messageRateLimits(edgeCfg),
+		Features: map[string]bool{
+			FeatureCompression:    edgeCfg.WSCompression,
+			FeatureBatchedFrames:  false,
+			FeaturePullAPI:        false,
+			FeatureProxyURL:       false,
+			FeaturePrinterReview:  edgeCfg.PrinterDiscovery == "review",
+			FeatureMultiFileJobs:  true,
+			FeatureJobMetadata:    true,
+			FeatureDiagnostics:    true,
+			FeaturePrinterPower:   true,
+			FeatureSignedFileURLs: true,
+			FeatureLogStreaming:   true,
+			FeatureQueuePause:     true,
+			FeatureJobDisplayName: true,
+		},
+	}
+}
+
+// messageRateLimits 已启用限速的上行消息类型及其每分钟限额
+func messageRateLimits(edgeCfg *config.EdgeConfig) map[string]int {
+	limits := make(map[string]int)
+	for _, messageType := range []string{MsgTypeHeartbeat, MsgTypePrinterStatus, MsgTypeJobUpdate, MsgTypeLogBatch} {
+		if limit := limitPerMinute(edgeCfg, messageType); limit > 0 {
+			limits[messageType] = limit
+		}
+	}
+	return limits
+}
@@ -0,0 +1,83 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/golden"
+)
+
+// testEdgeConfig 启用压缩、审核和全部限速的 Edge 配置
+func testEdgeConfig() *config.EdgeConfig {
+	return &config.EdgeConfig{
+		HeartbeatInterval:      30 * time.Second,
+		HeartbeatTimeout:       90 * time.Second,
+		PrinterDiscovery:       "review",
+		WSCompression:          true,
+		ValidateMessages:       true,
+		RateLimitHeartbeat:     12,
+		RateLimitPrinterStatus: 120,
+		RateLimitJobUpdate:     600,
+		RateLimitLogBatch:      60,
+	}
+}
+
+// goldenCapabilities 固定随构建变化的字段（版本号和 Schema 摘要），其余字段按原样写入样例文件
+func goldenCapabilities(t *testing.T, caps *ServerCapabilities) *ServerCapabilities {
+	t.Helper()
+	if caps.ServerVersion == "" {
+		t.Fatal("server_version is empty")
+	}
+	if caps.ProtocolSchemaDigest == "" || caps.ProtocolSchemaDigest != ProtocolSchemaDigest() {
+		t.Fatalf("protocol_schema_digest = %q, want %q", caps.ProtocolSchemaDigest, ProtocolSchemaDigest())
+	}
+	fixed := *caps
+	fixed.ServerVersion = "0.0.0-golden"
+	fixed.ProtocolSchemaDigest = "golden-digest"
+	return &fixed
+}
+
+// TestServerCapabilitiesGolden Edge Node 依赖的能力描述结构，字段变化需同步递增 CapabilitiesSchemaVersion
+func TestServerCapabilitiesGolden(t *testing.T) {
+	golden.AssertJSON(t, "server_capabilities", goldenCapabilities(t, NewServerCapabilities(testEdgeConfig())))
+	golden.AssertJSON(t, "server_capabilities_minimal", goldenCapabilities(t, NewServerCapabilities(&config.EdgeConfig{
+		HeartbeatInterval: time.Minute,
+		HeartbeatTimeout:  3 * time.Minute,
+		PrinterDiscovery:  "auto",
+	})))
+}
+
+// TestWelcomeCommandGolden welcome 指令的完整消息格式
+func TestWelcomeCommandGolden(t *testing.T) {
+	golden.AssertJSON(t, "welcome_command", &Command{
+		Type:      CmdTypeWelcome,
+		CommandID: "00000000-0000-0000-0000-000000000001",
+		Timestamp: golden.FixedTime,
+		Target:    "node-1",
+		Data:      goldenCapabilities(t, NewServerCapabilities(testEdgeConfig())),
+	})
+}
+
+// TestServerCapabilitiesFromConfig 功能开关和限速随配置变化
+func TestServerCapabilitiesFromConfig(t *testing.T) {
+	caps := NewServerCapabilities(testEdgeConfig())
+	if caps.SchemaVersion != CapabilitiesSchemaVersion || caps.HeartbeatIntervalSeconds != 30 || caps.HeartbeatTimeoutSeconds != 90 {
+		t.Fatalf("capabilities = %+v", caps)
+	}
+	if !caps.Features[FeatureCompression] || !caps.Features[FeaturePrinterReview] || len(caps.MessageRateLimits) != 4 {
+		t.Fatalf("features %v, limits %v; want compression, review and four limits", caps.Features, caps.MessageRateLimits)
+	}
+
+	edge := testEdgeConfig()
+	edge.WSCompression = false
+	edge.PrinterDiscovery = "auto"
+	edge.RateLimitJobUpdate = 0
+	caps = NewServerCapabilities(edge)
+	if caps.Features[FeatureCompression] || caps.Features[FeaturePrinterReview] {
+		t.Fatalf("features = %v, want compression and review off", caps.Features)
+	}
+	if _, ok := caps.MessageRateLimits[MsgTypeJobUpdate]; ok || len(caps.MessageRateLimits) != 3 {
+		t.Fatalf("limits = %v, want job_update unlimited", caps.MessageRateLimits)
+	}
+}
@@ -0,0 +1,269 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/websocket"
+	gorillaws "github.com/gorilla/websocket"
+)
+
+const testNodeID = "node-1"
+
+// allScopes 节点 token 持有全部上行消息所需的权限
+var allScopes = []string{
+	middleware.ScopeEdgeConnect, middleware.ScopeEdgePrinterWrite, middleware.ScopeEdgeJobUpdate,
+}
+
+// syncFrame 每条固定消息之后发送的同步消息：处理器按顺序处理，收到它的错误回复时前一条消息已处理完毕
+const syncFrame = `{"type":"job_update","node_id":"node-1","timestamp":"2026-03-01T12:00:00Z","data":{"job_id":"sync","status":"not-a-status"}}`
+
+// testNode 连接到真实消息处理器的节点
+type testNode struct {
+	conn     *gorillaws.Conn
+	recorder *Recorder
+	pending  []websocket.Command // 已读取但尚未取走的下行消息
+}
+
+// startNode 启动 WebSocket 服务端（升级后按生产方式创建 Connection 并运行读写循环），返回已连接的节点
+func startNode(t *testing.T, scopes []string) *testNode {
+	t.Helper()
+	recorder := NewRecorder()
+	recorder.AddPrinter(&models.Printer{ID: "printer-1", Name: "Lobby", EdgeNodeID: testNodeID})
+	recorder.AddPrinter(&models.Printer{ID: "printer-2", Name: "Annex", EdgeNodeID: "node-2"})
+	recorder.AddJob(&models.PrintJob{ID: "job-1", PrinterID: "printer-1"})
+
+	manager := websocket.NewConnectionManager()
+	go manager.Run()
+	logs := websocket.NewNodeLogs(manager)
+
+	upgrader := gorillaws.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		c := websocket.NewConnection(testNodeID, conn, manager, recorder, recorder, recorder, nil, recorder, recorder, recorder)
+		c.Scopes = scopes
+		c.Logs = logs
+		go c.WritePump()
+		c.ReadPump()
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := gorillaws.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testNode{conn: conn, recorder: recorder}
+}
+
+// readCommand 读取下一条下行消息（写循环会把排队的消息以换行分隔合并到同一帧）
+func (n *testNode) readCommand(t *testing.T) websocket.Command {
+	t.Helper()
+	for len(n.pending) == 0 {
+		n.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, frame, err := n.conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		for _, line := range bytes.Split(frame, []byte{'\n'}) {
+			var command websocket.Command
+			if err := json.Unmarshal(line, &command); err != nil {
+				t.Fatalf("decode %s: %v", line, err)
+			}
+			n.pending = append(n.pending, command)
+		}
+	}
+	command := n.pending[0]
+	n.pending = n.pending[1:]
+	return command
+}
+
+// exchange 发送一帧和同步消息，返回该帧产生的存储调用和错误回复
+func (n *testNode) exchange(t *testing.T, frame []byte, wantErrors int) ([]string, []websocket.ErrorData) {
+	t.Helper()
+	n.recorder.Reset()
+	for _, message := range [][]byte{frame, []byte(syncFrame)} {
+		if err := n.conn.WriteMessage(gorillaws.TextMessage, message); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	var errs []websocket.ErrorData
+	for len(errs) <= wantErrors {
+		command := n.readCommand(t)
+		if command.Type != websocket.CmdTypeError {
+			t.Fatalf("unexpected %s command", command.Type)
+		}
+		raw, _ := json.Marshal(command.Data)
+		var data websocket.ErrorData
+		if err := json.Unmarshal(raw, &data); err != nil {
+			t.Fatalf("decode error data: %v", err)
+		}
+		errs = append(errs, data)
+	}
+	sync := errs[len(errs)-1]
+	if sync.MessageType != websocket.MsgTypeJobUpdate || sync.Code != websocket.ErrCodeInvalidMessage ||
+		sync.Message != "job_update requires job_id and a valid status" {
+		t.Fatalf("expected %d error(s) before the sync reply, got %+v", wantErrors, errs)
+	}
+	return n.recorder.Calls(), errs[:len(errs)-1]
+}
+
+// fixture 一条固定消息及其预期的存储调用和错误回复（错误码/被拒绝的消息类型）
+type fixture struct {
+	name   string
+	frame  string
+	calls  []string
+	errors []string // code/message_type
+}
+
+// envelope 构造上行消息帧
+func envelope(messageType, data string) string {
+	return `{"type":"` + messageType + `","node_id":"node-1","timestamp":"2026-03-01T12:00:00Z","data":` + data + `}`
+}
+
+var fixtures = []fixture{
+	// 消息信封
+	{name: "not JSON", frame: `hello`, errors: []string{"invalid_message/"}},
+	{name: "JSON array", frame: `[1,2,3]`, errors: []string{"invalid_message/"}},
+	{name: "missing type", frame: `{"node_id":"node-1","data":{}}`, errors: []string{"invalid_message/"}},
+	{name: "invalid UTF-8", frame: "{\"type\":\"edge_heartbeat\",\"data\":\"\xff\xfe\"}", errors: []string{"invalid_message/"}},
+	{name: "timestamp wrong type", frame: `{"type":"edge_heartbeat","timestamp":12345,"data":null}`, errors: []string{"invalid_message/edge_heartbeat"}},
+	{name: "unknown type", frame: envelope("teleport", `{}`)},
+
+	// edge_heartbeat
+	{name: "heartbeat", frame: envelope("edge_heartbeat", `{"system_info":{"cpu_usage":12.5,"memory_usage":40,"disk_usage":70,"network_quality":"good","latency":20}}`),
+		calls: []string{
+			"NodeSeen(node-1)",
+			"UpdateEdgeNodeHeartbeatAndTelemetry(node-1, good, 20)",
+			"RecordEdgeNodeMetrics(node-1, 12.5, 40, 70, 20)",
+		}},
+	{name: "heartbeat without data", frame: envelope("edge_heartbeat", `null`), calls: []string{"NodeSeen(node-1)"}},
+	{name: "heartbeat data wrong type", frame: envelope("edge_heartbeat", `"busy"`),
+		calls: []string{"NodeSeen(node-1)"}, errors: []string{"invalid_message/edge_heartbeat"}},
+	{name: "heartbeat field wrong type", frame: envelope("edge_heartbeat", `{"system_info":{"cpu_usage":"high"}}`),
+		calls: []string{"NodeSeen(node-1)"}, errors: []string{"invalid_message/edge_heartbeat"}},
+
+	// printer_status
+	{name: "printer status", frame: envelope("printer_status", `{"printer_id":"Lobby","status":"printing","queue_length":2,"error_code":null,"supplies":{"toner":80},"power_state":"awake"}`),
+		calls: []string{"UpdateStatusAndQueue(printer-1, printing, 2, 1, awake)"}},
+	{name: "printer status invalid power state", frame: envelope("printer_status", `{"printer_id":"Lobby","status":"ready","queue_length":0,"supplies":null,"power_state":"hibernate"}`),
+		calls: []string{"UpdateStatusAndQueue(printer-1, ready, 0, 0, )"}},
+	{name: "printer status node_id ignored", frame: `{"type":"printer_status","node_id":"node-2","timestamp":"2026-03-01T12:00:00Z","data":{"printer_id":"Annex","status":"ready"}}`},
+	{name: "printer status unknown printer", frame: envelope("printer_status", `{"printer_id":"Basement","status":"ready"}`)},
+	{name: "printer status missing printer_id", frame: envelope("printer_status", `{"status":"ready"}`),
+		errors: []string{"invalid_message/printer_status"}},
+	{name: "printer status invalid status", frame: envelope("printer_status", `{"printer_id":"Lobby","status":"on_fire"}`),
+		errors: []string{"invalid_message/printer_status"}},
+	{name: "printer status queue_length wrong type", frame: envelope("printer_status", `{"printer_id":"Lobby","status":"ready","queue_length":"two"}`),
+		errors: []string{"invalid_message/printer_status"}},
+	{name: "printer status data wrong type", frame: envelope("printer_status", `[]`),
+		errors: []string{"invalid_message/printer_status"}},
+
+	// job_update
+	{name: "job progress", frame: envelope("job_update", `{"job_id":"job-1","status":"printing","progress":40,"error_message":null}`),
+		calls: []string{"UpdateJobStatus(job-1, printing, 40)"}},
+	{name: "job completed", frame: envelope("job_update", `{"job_id":"job-1","status":"completed","progress":100,"error_message":null}`),
+		calls: []string{"UpdateJobStatus(job-1, completed, 100)", "JobFinished(job-1)", "JobReported(job-1)"}},
+	{name: "job failed with error code", frame: envelope("job_update", `{"job_id":"job-1","status":"failed","progress":10,"error_message":"paper jam","error_code":"E42"}`),
+		calls: []string{
+			"UpdateJobStatus(job-1, failed, 10)",
+			"UpdateJobErrorMessage(job-1, paper jam)",
+			"HandleJobFailure(job-1, E42)",
+			"JobFinished(job-1)",
+			"JobReported(job-1)",
+		}},
+	{name: "job partially completed", frame: envelope("job_update", `{"job_id":"job-1","status":"partially_completed","progress":60,"error_message":null,"pages_printed":3}`),
+		calls: []string{"UpdateJobStatus(job-1, partially_completed, 60)", "UpdateJobPagesPrinted(job-1, 3)", "JobFinished(job-1)", "JobReported(job-1)"}},
+	{name: "job partially completed without pages", frame: envelope("job_update", `{"job_id":"job-1","status":"partially_completed","progress":60,"error_message":null}`),
+		errors: []string{"invalid_message/job_update"}},
+	{name: "job stale update", frame: envelope("job_update", `{"job_id":"job-unknown","status":"completed","progress":100,"error_message":null}`),
+		calls: []string{"UpdateJobStatus(job-unknown, completed, 100)"}},
+	{name: "job missing job_id", frame: envelope("job_update", `{"status":"printing","progress":10}`),
+		errors: []string{"invalid_message/job_update"}},
+	{name: "job progress wrong type", frame: envelope("job_update", `{"job_id":"job-1","status":"printing","progress":"40%"}`),
+		errors: []string{"invalid_message/job_update"}},
+	{name: "job pages_printed wrong type", frame: envelope("job_update", `{"job_id":"job-1","status":"printing","progress":40,"pages_printed":true}`),
+		errors: []string{"invalid_message/job_update"}},
+
+	// log_batch（未被要求上报时丢弃）
+	{name: "log batch", frame: envelope("log_batch", `{"lines":[{"level":"info","ts":"2026-03-01T12:00:00Z","message":"started"}]}`)},
+	{name: "log batch lines wrong type", frame: envelope("log_batch", `{"lines":"started"}`),
+		errors: []string{"invalid_message/log_batch"}},
+}
+
+func TestConformance(t *testing.T) {
+	node := startNode(t, allScopes)
+	for _, tt := range fixtures {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, errs := node.exchange(t, []byte(tt.frame), len(tt.errors))
+			if len(calls) != 0 || len(tt.calls) != 0 {
+				if !reflect.DeepEqual(calls, tt.calls) {
+					t.Fatalf("calls = %q, want %q", calls, tt.calls)
+				}
+			}
+			for i, e := range errs {
+				if got := e.Code + "/" + e.MessageType; got != tt.errors[i] || e.Message == "" {
+					t.Fatalf("error %d = %+v, want %s", i, e, tt.errors[i])
+				}
+			}
+		})
+	}
+}
+
+// TestConformanceScopes 缺少消息类型所需权限时拒绝消息，不访问存储
+func TestConformanceScopes(t *testing.T) {
+	node := startNode(t, []string{middleware.ScopeEdgeConnect, middleware.ScopeEdgeJobUpdate})
+	calls, errs := node.exchange(t, []byte(envelope("printer_status", `{"printer_id":"Lobby","status":"ready"}`)), 1)
+	if len(calls) != 0 || errs[0].Code != websocket.ErrCodeInsufficientScope || errs[0].MessageType != websocket.MsgTypePrinterStatus {
+		t.Fatalf("calls %q, errors %+v; want insufficient_scope", calls, errs)
+	}
+}
+
+// TestConformancePayloadSize 接近上限的消息正常处理，超过读取上限的消息以 1009 关闭连接
+func TestConformancePayloadSize(t *testing.T) {
+	node := startNode(t, allScopes)
+
+	large := envelope("edge_heartbeat", `{"system_info":{"network_quality":"`+strings.Repeat("a", 60<<10)+`"}}`)
+	calls, _ := node.exchange(t, []byte(large), 0)
+	if len(calls) != 3 || calls[0] != "NodeSeen(node-1)" {
+		t.Fatalf("calls for 60 KiB heartbeat = %d", len(calls))
+	}
+
+	// 服务端发送关闭帧后立即断开，节点回复关闭帧可能失败，直接记录关闭码
+	closeCode := make(chan int, 1)
+	node.conn.SetCloseHandler(func(code int, _ string) error {
+		closeCode <- code
+		return nil
+	})
+	huge := envelope("edge_heartbeat", `{"system_info":{"network_quality":"`+strings.Repeat("a", 70<<10)+`"}}`)
+	if err := node.conn.WriteMessage(gorillaws.TextMessage, []byte(huge)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	node.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := node.conn.ReadMessage(); err == nil {
+		t.Fatal("connection still open after oversized frame")
+	}
+	select {
+	case code := <-closeCode:
+		if code != gorillaws.CloseMessageTooBig {
+			t.Fatalf("close code = %d, want %d", code, gorillaws.CloseMessageTooBig)
+		}
+	default:
+		t.Fatal("no close frame after oversized frame")
+	}
+	if calls := node.recorder.Calls(); len(calls) != 3 {
+		t.Fatalf("oversized frame reached the handler: %q", calls)
+	}
+}
@@ -0,0 +1,176 @@
+// Package conformance 边缘协议一致性测试：通过真实的 WebSocket 连接向消息处理器发送各类型的固定消息，
+// 断言存储调用和回复给节点的错误。修改上行消息格式或处理逻辑时须同步更新并通过这些测试。
+//
+// Recorder 为消息处理依赖的内存实现，不需要数据库，也可供 websocket 包的模糊测试使用。
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// ErrNotFound 打印机或任务不存在
+var ErrNotFound = errors.New("not found")
+
+// Recorder 记录消息处理产生的写入和通知调用（只读查询不记录）
+// 同时满足 websocket 包的 PrinterStore、EdgeNodeStore、PrintJobStore、NodeMonitor、JobNotifier 和 JobDispatcher
+type Recorder struct {
+	mu       sync.Mutex
+	calls    []string
+	printers map[string]*models.Printer // edge_node_id/name -> 打印机
+	jobs     map[string]*models.PrintJob
+}
+
+// NewRecorder 创建空的记录器
+func NewRecorder() *Recorder {
+	return &Recorder{
+		printers: make(map[string]*models.Printer),
+		jobs:     make(map[string]*models.PrintJob),
+	}
+}
+
+// AddPrinter 登记节点下的打印机
+func (r *Recorder) AddPrinter(printer *models.Printer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.printers[printer.EdgeNodeID+"/"+printer.Name] = printer
+}
+
+// AddJob 登记打印任务
+func (r *Recorder) AddJob(job *models.PrintJob) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.ID] = job
+}
+
+// Calls 返回已记录的调用，格式为 Method(arg1, arg2, ...)
+func (r *Recorder) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+// Reset 清空已记录的调用
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+func (r *Recorder) record(method string, args ...interface{}) {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = fmt.Sprint(arg)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, method+"("+strings.Join(formatted, ", ")+")")
+}
+
+// GetPrinterByNameAndEdgeNode 按名称和节点查找打印机
+func (r *Recorder) GetPrinterByNameAndEdgeNode(name, edgeNodeID string) (*models.Printer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if printer, ok := r.printers[edgeNodeID+"/"+name]; ok {
+		return printer, nil
+	}
+	return nil, ErrNotFound
+}
+
+// GetPrinterByID 按ID查找打印机
+func (r *Recorder) GetPrinterByID(printerID string) (*models.Printer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, printer := range r.printers {
+		if printer.ID == printerID {
+			return printer, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// CountPrintersByName 统计所有节点下同名的打印机
+func (r *Recorder) CountPrintersByName(name string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, printer := range r.printers {
+		if printer.Name == name {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *Recorder) UpdateStatusAndQueue(printerID string, status models.PrinterStatus, queueLength int, supplies map[string]interface{}, powerState string) error {
+	r.record("UpdateStatusAndQueue", printerID, status, queueLength, len(supplies), powerState)
+	return nil
+}
+
+func (r *Recorder) UpdateEdgeNodeHeartbeatAndTelemetry(id string, connectionQuality string, latency int) error {
+	r.record("UpdateEdgeNodeHeartbeatAndTelemetry", id, connectionQuality, latency)
+	return nil
+}
+
+func (r *Recorder) RecordEdgeNodeMetrics(id string, sample models.EdgeNodeMetricsSample, _ time.Time) error {
+	r.record("RecordEdgeNodeMetrics", id, sample.CPUUsage, sample.MemoryUsage, sample.DiskUsage, sample.Latency)
+	return nil
+}
+
+// GetPrintJobByID 按ID查找任务，不存在时返回 nil
+func (r *Recorder) GetPrintJobByID(id string) (*models.PrintJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.jobs[id], nil
+}
+
+// UpdateJobStatus 记录状态写入，未登记的任务按过期更新处理（返回 false）
+func (r *Recorder) UpdateJobStatus(jobID string, status models.JobStatus, progress int, _ time.Time) (bool, error) {
+	r.record("UpdateJobStatus", jobID, status, progress)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, exists := r.jobs[jobID]
+	return exists, nil
+}
+
+func (r *Recorder) UpdateJobErrorMessage(jobID, errorMessage string) error {
+	r.record("UpdateJobErrorMessage", jobID, errorMessage)
+	return nil
+}
+
+func (r *Recorder) UpdateJobPagesPrinted(jobID string, pages int) error {
+	r.record("UpdateJobPagesPrinted", jobID, pages)
+	return nil
+}
+
+func (r *Recorder) UpdateJobCost(jobID string, cost float64) error {
+	r.record("UpdateJobCost", jobID, cost)
+	return nil
+}
+
+func (r *Recorder) NodeSeen(nodeID string) error {
+	r.record("NodeSeen", nodeID)
+	return nil
+}
+
+// ReportClockSkew 每条带时间戳的消息都会上报，不记录
+func (r *Recorder) ReportClockSkew(string, time.Duration) {}
+
+func (r *Recorder) JobFinished(jobID string) {
+	r.record("JobFinished", jobID)
+}
+
+func (r *Recorder) JobReported(jobID string) {
+	r.record("JobReported", jobID)
+}
+
+// HandleJobFailure 记录调用，不重新分发
+func (r *Recorder) HandleJobFailure(jobID, errorCode string) bool {
+	r.record("HandleJobFailure", jobID, errorCode)
+	return false
+}
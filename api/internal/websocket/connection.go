@@ -2,10 +2,19 @@ package websocket
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
-	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/billing"
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -19,23 +28,107 @@ const (
 	// Ping 发送间隔
 	pingPeriod = (pongWait * 9) / 10
 
-	// 最大消息大小
-	maxMessageSize = 512
+	// 最大消息大小（容纳一条满载的 log_batch 消息）
+	maxMessageSize = 64 << 10
 )
 
+// JobDispatcher 任务结束后继续分发排队任务（由 dispatch 包实现，避免循环依赖）
+type JobDispatcher interface {
+	JobReported(jobID string) // 节点上报任务结束：结果计入打印机熔断器，并释放名额
+	HandleJobFailure(jobID, errorCode string) bool // 硬件错误时按故障转移设置重试或改投备用打印机，已重新分发时返回 true
+}
+
+// 消息处理依赖的存储和服务，由 database / worker / notify 包实现，测试时可替换为内存实现
+
+// PrinterStore 打印机状态消息和费用计算使用的打印机存储
+type PrinterStore interface {
+	GetPrinterByNameAndEdgeNode(name, edgeNodeID string) (*models.Printer, error)
+	GetPrinterByID(printerID string) (*models.Printer, error)
+	CountPrintersByName(name string) (int, error)
+	UpdateStatusAndQueue(printerID string, status models.PrinterStatus, queueLength int, supplies map[string]interface{}, powerState string) error
+}
+
+// EdgeNodeStore 心跳消息使用的节点存储
+type EdgeNodeStore interface {
+	UpdateEdgeNodeHeartbeatAndTelemetry(id string, connectionQuality string, latency int) error
+	RecordEdgeNodeMetrics(id string, sample models.EdgeNodeMetricsSample, at time.Time) error
+}
+
+// PrintJobStore 任务状态消息使用的任务存储
+type PrintJobStore interface {
+	GetPrintJobByID(id string) (*models.PrintJob, error)
+	UpdateJobStatus(jobID string, status models.JobStatus, progress int, receivedAt time.Time) (bool, error)
+	UpdateJobErrorMessage(jobID, errorMessage string) error
+	UpdateJobPagesPrinted(jobID string, pages int) error
+	UpdateJobCost(jobID string, cost float64) error
+}
+
+// JobEventRecorder 任务时间线记录（实现需允许 nil 接收者）
+type JobEventRecorder interface {
+	RecordStatusChange(event *models.PrintJobEvent)
+	RecordProgress(jobID string, status models.JobStatus, progress int, actor string)
+}
+
+// NodeMonitor 节点在线状态和时钟偏差跟踪
+type NodeMonitor interface {
+	NodeSeen(nodeID string) error
+	ReportClockSkew(nodeID string, skew time.Duration)
+}
+
+// JobNotifier 任务结束时通知提交用户（实现需允许 nil 接收者）
+type JobNotifier interface {
+	JobFinished(jobID string)
+}
+
 // Connection 表示单个 WebSocket 连接
 type Connection struct {
 	NodeID         string
 	Conn           *websocket.Conn
 	Send           chan []byte
 	Manager        *ConnectionManager
-	PrinterRepo    *database.PrinterRepository
-	EdgeNodeRepo   *database.EdgeNodeRepository
-	PrintJobRepo   *database.PrintJobRepository
+	PrinterRepo    PrinterStore
+	EdgeNodeRepo   EdgeNodeStore
+	PrintJobRepo   PrintJobStore
+	Calculator     *billing.Calculator
+	Monitor        NodeMonitor      // 可为空
+	Notifier       JobNotifier
+	Dispatcher     JobDispatcher
+	Events         *events.Bus  // 任务状态变化时发布事件（可为空）
+	JobEvents      JobEventRecorder // 任务时间线（可为空）
+	Scopes         []string     // 建立连接时 token 持有的权限，按消息类型校验
+	Limiter        *MessageLimiter // 上行消息限速（可为空）
+	Progress       *ProgressThrottle // 任务进度写入节流（为空时每条更新都写入）
+	Logs           *NodeLogs    // Agent 日志缓冲（为空时丢弃 log_batch）
+	Settings       *config.Store // 是否按 Schema 校验上行消息（edge.validate_messages，可为空）
+	ConnectedAt    time.Time
+	RemoteAddr     string       // 客户端地址
+	Client         *models.ClientInfo // Agent 名称/版本/平台（未提供时为空）
+	lastMessageAt  atomic.Int64 // 最近一次收到消息的时间（UnixNano）
+	lastAppHeartbeat atomic.Int64 // 最近一次收到 edge_heartbeat 消息的时间（UnixNano），用于识别进程已退出但 TCP 仍被 NAT 保持的连接
+	messagesIn     atomic.Int64 // 已接收消息数
+	messagesOut    atomic.Int64 // 已发送消息数
+
+	droppedMutex   sync.Mutex
+	dropped        map[string]int64 // 因限速丢弃的消息数（按消息类型）
+
+	// 管理员强制断开时发送的关闭帧，在关闭 Send 前由管理器设置
+	closeCode      int
+	closeReason    string
+
+	// Send 的发送与关闭互斥：读循环（错误回复）和管理器（断开、替换、广播）在不同 goroutine 中操作 Send
+	sendMutex      sync.Mutex
+	sendClosed     bool
+
+	skewMutex      sync.Mutex
+	clockSkew      time.Duration // 平滑后的时钟偏差（节点时间 - 服务端接收时间）
+	hasClockSkew   bool
 }
 
+// clockSkewSmoothing 时钟偏差指数平滑系数
+const clockSkewSmoothing = 0.2
+
 // NewConnection 创建新连接
-func NewConnection(nodeID string, conn *websocket.Conn, manager *ConnectionManager, printerRepo *database.PrinterRepository, edgeNodeRepo *database.EdgeNodeRepository, printJobRepo *database.PrintJobRepository) *Connection {
+func NewConnection(nodeID string, conn *websocket.Conn, manager *ConnectionManager, printerRepo PrinterStore, edgeNodeRepo EdgeNodeStore, printJobRepo PrintJobStore, calculator *billing.Calculator, monitor NodeMonitor, notifier JobNotifier, dispatcher JobDispatcher) *Connection {
 	return &Connection{
 		NodeID:         nodeID,
 		Conn:           conn,
@@ -44,6 +137,76 @@ func NewConnection(nodeID string, conn *websocket.Conn, manager *ConnectionManag
 		PrinterRepo:    printerRepo,
 		EdgeNodeRepo:   edgeNodeRepo,
 		PrintJobRepo:   printJobRepo,
+		Calculator:     calculator,
+		Monitor:        monitor,
+		Notifier:       notifier,
+		Dispatcher:     dispatcher,
+		ConnectedAt:    time.Now().UTC(),
+	}
+}
+
+// LastMessageAt 最近一次收到消息的时间，尚未收到消息时返回零值
+func (c *Connection) LastMessageAt() time.Time {
+	nanos := c.lastMessageAt.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
+
+// LastAppHeartbeat 最近一次收到应用层心跳（edge_heartbeat）的时间，尚未收到时返回零值
+// WebSocket ping/pong 和 TCP keepalive 不计入，只有节点进程仍在运行时才会更新
+func (c *Connection) LastAppHeartbeat() time.Time {
+	nanos := c.lastAppHeartbeat.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
+
+// MessageCounts 返回已接收和已发送的消息数
+func (c *Connection) MessageCounts() (in, out int64) {
+	return c.messagesIn.Load(), c.messagesOut.Load()
+}
+
+// DroppedCounts 返回因限速丢弃的消息数（按消息类型）
+func (c *Connection) DroppedCounts() map[string]int64 {
+	c.droppedMutex.Lock()
+	defer c.droppedMutex.Unlock()
+
+	counts := make(map[string]int64, len(c.dropped))
+	for messageType, n := range c.dropped {
+		counts[messageType] = n
+	}
+	return counts
+}
+
+// ClockSkew 返回平滑后的节点时钟偏差，尚无样本时 ok 为 false
+func (c *Connection) ClockSkew() (skew time.Duration, ok bool) {
+	c.skewMutex.Lock()
+	defer c.skewMutex.Unlock()
+	return c.clockSkew, c.hasClockSkew
+}
+
+// recordClockSkew 根据消息时间戳与接收时间更新时钟偏差
+func (c *Connection) recordClockSkew(msg *Message) {
+	if msg.Timestamp.IsZero() {
+		return
+	}
+
+	sample := msg.Timestamp.Sub(msg.ReceivedAt)
+	c.skewMutex.Lock()
+	if c.hasClockSkew {
+		c.clockSkew += time.Duration(clockSkewSmoothing * float64(sample-c.clockSkew))
+	} else {
+		c.clockSkew = sample
+		c.hasClockSkew = true
+	}
+	skew := c.clockSkew
+	c.skewMutex.Unlock()
+
+	if c.Monitor != nil {
+		c.Monitor.ReportClockSkew(c.NodeID, skew)
 	}
 }
 
@@ -69,21 +232,135 @@ func (c *Connection) ReadPump() {
 			}
 			break
 		}
+		receivedAt := time.Now().UTC()
+		c.lastMessageAt.Store(receivedAt.UnixNano())
+		c.messagesIn.Add(1)
+
+		// 先只解析消息类型做限速检查，超限的消息不解析内容、不写日志
+		messageType, err := decodeMessageType(messageBytes)
+		if err != nil {
+			log.Printf("Failed to parse message from node %s: %v", c.NodeID, err)
+			c.sendError(messageType, ErrCodeInvalidMessage, err.Error())
+			continue
+		}
+		if !c.allowMessage(messageType, receivedAt) {
+			continue
+		}
 
-		log.Printf("WebSocket received raw message from node %s: %s", c.NodeID, string(messageBytes))
+		// log_batch 内容较大且本身就是日志，不写入服务端日志
+		if messageType != MsgTypeLogBatch {
+			log.Printf("WebSocket received raw message from node %s: %s", c.NodeID, string(messageBytes))
+		}
+
+		if c.validateMessages() {
+			if violations := ValidateMessage(messageBytes); len(violations) > 0 {
+				log.Printf("Rejected %s message from node %s: %d schema violations", messageType, c.NodeID, len(violations))
+				c.sendErrorData(ErrorData{
+					Code:        ErrCodeSchemaViolation,
+					Message:     "message does not match the protocol schema",
+					MessageType: messageType,
+					Violations:  violations,
+				})
+				continue
+			}
+		}
 
 		// 解析消息
-		var msg Message
-		if err := json.Unmarshal(messageBytes, &msg); err != nil {
+		msg, err := DecodeMessage(messageBytes)
+		if err != nil {
 			log.Printf("Failed to parse message from node %s: %v", c.NodeID, err)
+			c.sendError(messageType, ErrCodeInvalidMessage, err.Error())
 			continue
 		}
 
+		msg.ReceivedAt = receivedAt
+		c.recordClockSkew(msg)
+
 		log.Printf("WebSocket parsed message from node %s: type=%s", c.NodeID, msg.Type)
 
 		// 处理消息
-		c.handleMessage(&msg)
+		c.handleMessage(msg)
+	}
+}
+
+// decodeMessageType 只解析消息类型（用于限速检查），消息不是合法的 UTF-8 JSON 对象或缺少 type 时返回错误
+// 解析失败时尽量返回已识别的类型，便于错误响应标明被拒绝的消息
+func decodeMessageType(raw []byte) (string, error) {
+	if !utf8.Valid(raw) {
+		return "", fmt.Errorf("%w: message is not valid UTF-8", ErrInvalidMessage)
+	}
+	var envelope struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if envelope.Type == "" {
+		return "", fmt.Errorf("%w: missing message type", ErrInvalidMessage)
+	}
+	return envelope.Type, nil
+}
+
+// DecodeMessage 解析上行消息帧：必须是合法 UTF-8 编码的 JSON 对象且包含 type，错误均包装 ErrInvalidMessage
+// data 在各消息处理函数中按类型解析
+func DecodeMessage(raw []byte) (*Message, error) {
+	messageType, err := decodeMessageType(raw)
+	if err != nil {
+		return nil, err
+	}
+	var msg Message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return nil, fmt.Errorf("%w: %s message: %v", ErrInvalidMessage, messageType, err)
+	}
+	return &msg, nil
+}
+
+// decodeMessageData 将消息的 data 解析为具体类型，失败时向节点返回 invalid_message 错误
+func (c *Connection) decodeMessageData(msg *Message, v interface{}) bool {
+	dataBytes, err := json.Marshal(msg.Data)
+	if err == nil {
+		err = json.Unmarshal(dataBytes, v)
+	}
+	if err != nil {
+		log.Printf("Failed to parse %s data from node %s: %v", msg.Type, c.NodeID, err)
+		c.sendError(msg.Type, ErrCodeInvalidMessage, "invalid "+msg.Type+" data: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// allowMessage 检查上行消息限速，超限的消息计数后丢弃
+// 持续超限时先向节点发送 rate_limited 错误，仍不收敛时以 policy violation 断开连接
+func (c *Connection) allowMessage(messageType string, now time.Time) bool {
+	if c.Limiter == nil {
+		return true
 	}
+	decision := c.Limiter.Allow(c.NodeID, messageType, now)
+	if decision == rateAllow {
+		return true
+	}
+
+	c.droppedMutex.Lock()
+	if c.dropped == nil {
+		c.dropped = make(map[string]int64)
+	}
+	c.dropped[messageType]++
+	c.droppedMutex.Unlock()
+
+	switch decision {
+	case rateDropWarn:
+		log.Printf("Node %s exceeded %s rate limit, excess messages are being dropped", c.NodeID, messageType)
+		c.sendError(messageType, ErrCodeRateLimited, "message rate limit exceeded, excess "+messageType+" messages are dropped")
+	case rateDropClose:
+		log.Printf("Node %s kept exceeding %s rate limit, closing connection", c.NodeID, messageType)
+		c.Manager.closeForPolicyViolation(c, "message rate limit exceeded")
+	}
+	return false
+}
+
+// validateMessages 是否按 Schema 校验上行消息（支持热更新）
+func (c *Connection) validateMessages() bool {
+	return c.Settings != nil && c.Settings.Get().Edge.ValidateMessages
 }
 
 // WritePump 处理向客户端发送消息
@@ -99,7 +376,11 @@ func (c *Connection) WritePump() {
 		case message, ok := <-c.Send:
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				closeFrame := []byte{}
+				if c.closeCode != 0 {
+					closeFrame = websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+				}
+				c.Conn.WriteMessage(websocket.CloseMessage, closeFrame)
 				return
 			}
 
@@ -119,6 +400,7 @@ func (c *Connection) WritePump() {
 			if err := w.Close(); err != nil {
 				return
 			}
+			c.messagesOut.Add(int64(n + 1))
 
 		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
@@ -129,17 +411,34 @@ func (c *Connection) WritePump() {
 	}
 }
 
+// messageScopes 上行消息类型所需的权限
+var messageScopes = map[string]string{
+	MsgTypeHeartbeat:     middleware.ScopeEdgeConnect,
+	MsgTypePrinterStatus: middleware.ScopeEdgePrinterWrite,
+	MsgTypeJobUpdate:     middleware.ScopeEdgeJobUpdate,
+	MsgTypeLogBatch:      middleware.ScopeEdgeConnect,
+}
+
 // handleMessage 处理接收到的消息
 func (c *Connection) handleMessage(msg *Message) {
 	log.Printf("Received message from node %s: type=%s", c.NodeID, msg.Type)
 
+	if required, ok := messageScopes[msg.Type]; ok && !middleware.HasScope(c.Scopes, required) {
+		log.Printf("Rejected %s message from node %s: missing scope %s", msg.Type, c.NodeID, required)
+		c.sendError(msg.Type, ErrCodeInsufficientScope, "token does not have required scope "+required)
+		return
+	}
+
 	switch msg.Type {
 	case MsgTypeHeartbeat:
+		c.lastAppHeartbeat.Store(msg.ReceivedAt.UnixNano())
 		c.handleHeartbeat(msg)
 	case MsgTypePrinterStatus:
 		c.handlePrinterStatus(msg)
 	case MsgTypeJobUpdate:
 		c.handleJobUpdate(msg)
+	case MsgTypeLogBatch:
+		c.handleLogBatch(msg)
 	default:
 		log.Printf("Unknown message type: %s from node %s", msg.Type, c.NodeID)
 	}
@@ -150,7 +449,7 @@ func (c *Connection) handleHeartbeat(msg *Message) {
 	log.Printf("Processing heartbeat from node %s", c.NodeID)
 	
 	// 更新 Edge Node 的最后心跳时间和状态
-	if err := c.EdgeNodeRepo.UpdateHeartbeat(c.NodeID); err != nil {
+	if err := c.Monitor.NodeSeen(c.NodeID); err != nil {
 		log.Printf("Failed to update heartbeat for node %s: %v", c.NodeID, err)
 		return
 	}
@@ -158,12 +457,26 @@ func (c *Connection) handleHeartbeat(msg *Message) {
 	// 解析心跳数据（可选）
 	if msg.Data != nil {
 		var heartbeatData HeartbeatData
-		dataBytes, err := json.Marshal(msg.Data)
-		if err == nil {
-			if err := json.Unmarshal(dataBytes, &heartbeatData); err == nil {
-				log.Printf("Heartbeat data from node %s: CPU=%.2f%%, Memory=%.2f%%, Disk=%.2f%%", 
-					c.NodeID, heartbeatData.SystemInfo.CPUUsage, 
-					heartbeatData.SystemInfo.MemoryUsage, heartbeatData.SystemInfo.DiskUsage)
+		if c.decodeMessageData(msg, &heartbeatData) {
+			log.Printf("Heartbeat data from node %s: CPU=%.2f%%, Memory=%.2f%%, Disk=%.2f%%", 
+				c.NodeID, heartbeatData.SystemInfo.CPUUsage, 
+				heartbeatData.SystemInfo.MemoryUsage, heartbeatData.SystemInfo.DiskUsage)
+			
+			// 只更新遥测字段，避免覆盖并发的管理员修改
+			if err := c.EdgeNodeRepo.UpdateEdgeNodeHeartbeatAndTelemetry(c.NodeID,
+				heartbeatData.SystemInfo.NetworkQuality, heartbeatData.SystemInfo.Latency); err != nil {
+				log.Printf("Failed to update telemetry for node %s: %v", c.NodeID, err)
+			}
+
+			// 保存资源采样，供容量规划查询历史曲线
+			sample := models.EdgeNodeMetricsSample{
+				CPUUsage:    heartbeatData.SystemInfo.CPUUsage,
+				MemoryUsage: heartbeatData.SystemInfo.MemoryUsage,
+				DiskUsage:   heartbeatData.SystemInfo.DiskUsage,
+				Latency:     heartbeatData.SystemInfo.Latency,
+			}
+			if err := c.EdgeNodeRepo.RecordEdgeNodeMetrics(c.NodeID, sample, time.Now().UTC()); err != nil {
+				log.Printf("Failed to record metrics for node %s: %v", c.NodeID, err)
 			}
 		}
 	}
@@ -171,44 +484,64 @@ func (c *Connection) handleHeartbeat(msg *Message) {
 	log.Printf("Successfully processed heartbeat from node %s", c.NodeID)
 }
 
+// handleLogBatch 处理 Agent 日志上报，只保存在内存中供管理员实时查看
+func (c *Connection) handleLogBatch(msg *Message) {
+	if c.Logs == nil {
+		return
+	}
+
+	var batch LogBatchData
+	if !c.decodeMessageData(msg, &batch) {
+		return
+	}
+
+	c.Logs.Append(c.NodeID, &batch, msg.ReceivedAt)
+}
+
 // handlePrinterStatus 处理打印机状态消息
 func (c *Connection) handlePrinterStatus(msg *Message) {
 	log.Printf("Processing printer status update from node %s", c.NodeID)
 	
 	// 解析打印机状态数据
 	var statusData PrinterStatusData
-	dataBytes, err := json.Marshal(msg.Data)
-	if err != nil {
-		log.Printf("Failed to marshal printer status data from node %s: %v", c.NodeID, err)
-		return
-	}
-	
-	if err := json.Unmarshal(dataBytes, &statusData); err != nil {
-		log.Printf("Failed to parse printer status data from node %s: %v", c.NodeID, err)
+	if !c.decodeMessageData(msg, &statusData) {
 		return
 	}
 	
 	log.Printf("Printer status data: printer_id=%s, status=%s, queue_length=%d", 
 		statusData.PrinterID, statusData.Status, statusData.QueueLength)
 	
-	// 使用消息中的node_id而不是连接时的NodeID，因为可能不匹配
-	messageNodeID := msg.NodeID
-	if messageNodeID == "" {
-		messageNodeID = c.NodeID // 如果消息中没有node_id，使用连接时的ID
+	if statusData.PrinterID == "" || !statusData.Status.IsValid() {
+		log.Printf("Invalid printer status %q for printer %q from node %s, ignoring", statusData.Status, statusData.PrinterID, c.NodeID)
+		c.sendError(msg.Type, ErrCodeInvalidMessage, "printer_status requires printer_id and a valid status")
+		return
 	}
 	
-	// 通过名称和边缘节点ID查找打印机
-	printer, err := c.PrinterRepo.GetPrinterByNameAndEdgeNode(statusData.PrinterID, messageNodeID)
+	// 打印机名称仅在节点内唯一，严格按（名称, 连接认证的节点ID）匹配，不信任消息中声明的 node_id
+	if msg.NodeID != "" && msg.NodeID != c.NodeID {
+		log.Printf("Printer status for %s claims node %s but arrived on connection of node %s, using connection node",
+			statusData.PrinterID, msg.NodeID, c.NodeID)
+	}
+	
+	printer, err := c.PrinterRepo.GetPrinterByNameAndEdgeNode(statusData.PrinterID, c.NodeID)
 	if err != nil {
-		log.Printf("Printer %s not found for node %s (connection: %s): %v", statusData.PrinterID, messageNodeID, c.NodeID, err)
+		if count, countErr := c.PrinterRepo.CountPrintersByName(statusData.PrinterID); countErr == nil && count > 0 {
+			log.Printf("Ambiguous printer status: printer %s not registered on node %s but exists on %d other node(s), ignoring",
+				statusData.PrinterID, c.NodeID, count)
+		} else {
+			log.Printf("Printer %s not found for node %s: %v", statusData.PrinterID, c.NodeID, err)
+		}
 		return
 	}
 	
-	// 直接使用客户端状态（统一标准）
-	printer.Status = statusData.Status
-	printer.QueueLength = statusData.QueueLength
+	powerState := statusData.PowerState
+	if powerState != "" && powerState != models.PrinterPowerAwake && powerState != models.PrinterPowerAsleep {
+		log.Printf("Invalid power state %q for printer %s from node %s, ignoring power state", powerState, statusData.PrinterID, c.NodeID)
+		powerState = ""
+	}
 	
-	if err := c.PrinterRepo.UpdatePrinter(printer); err != nil {
+	// 直接使用客户端状态（统一标准），只更新状态相关字段，避免覆盖并发的管理员修改
+	if err := c.PrinterRepo.UpdateStatusAndQueue(printer.ID, statusData.Status, statusData.QueueLength, statusData.Supplies, powerState); err != nil {
 		log.Printf("Failed to update printer %s status: %v", statusData.PrinterID, err)
 		return
 	}
@@ -223,30 +556,176 @@ func (c *Connection) handleJobUpdate(msg *Message) {
 	
 	// 解析任务状态数据
 	var jobData JobUpdateData
-	dataBytes, err := json.Marshal(msg.Data)
-	if err != nil {
-		log.Printf("Failed to marshal job update data from node %s: %v", c.NodeID, err)
-		return
-	}
-	
-	if err := json.Unmarshal(dataBytes, &jobData); err != nil {
-		log.Printf("Failed to parse job update data from node %s: %v", c.NodeID, err)
+	if !c.decodeMessageData(msg, &jobData) {
 		return
 	}
 	
 	log.Printf("Job update data: job_id=%s, status=%s, progress=%d", 
 		jobData.JobID, jobData.Status, jobData.Progress)
 	
-	// 更新数据库中的任务状态
-	if err := c.PrintJobRepo.UpdateJobStatus(jobData.JobID, jobData.Status, jobData.Progress); err != nil {
+	if jobData.JobID == "" || !jobData.Status.IsValid() {
+		log.Printf("Invalid job status %q for job %q from node %s, ignoring", jobData.Status, jobData.JobID, c.NodeID)
+		c.sendError(msg.Type, ErrCodeInvalidMessage, "job_update requires job_id and a valid status")
+		return
+	}
+	// 部分完成按已输出页数计费，必须同时上报 pages_printed
+	if jobData.Status == models.JobStatusPartiallyCompleted && (jobData.PagesPrinted == nil || *jobData.PagesPrinted <= 0) {
+		log.Printf("Partially completed update for job %s from node %s without pages_printed, ignoring", jobData.JobID, c.NodeID)
+		c.sendError(msg.Type, ErrCodeInvalidMessage, "partially_completed requires pages_printed > 0")
+		return
+	}
+	
+	// 更新数据库中的任务状态（以服务端接收时间排序，忽略节点时间戳）
+	// 状态变化立即写入，状态不变时进度按 jobs.progress_write_interval 节流写入
+	update, err := c.updateJobProgress(&jobData, msg.ReceivedAt)
+	if err != nil {
 		log.Printf("Failed to update job %s status: %v", jobData.JobID, err)
 		return
 	}
+	if update.StatusChanged && !update.Applied {
+		log.Printf("Stale update for job %s from node %s ignored (status=%s)", jobData.JobID, c.NodeID, jobData.Status)
+		return
+	}
+	jobData.Progress = update.Progress
+	if jobData.PagesPrinted != nil && *jobData.PagesPrinted > 0 {
+		if err := c.PrintJobRepo.UpdateJobPagesPrinted(jobData.JobID, *jobData.PagesPrinted); err != nil {
+			log.Printf("Failed to update job %s pages printed: %v", jobData.JobID, err)
+		}
+	}
+	
+	// 保存节点上报的错误信息，供失败通知使用
+	if update.StatusChanged && jobData.ErrorMessage != nil && *jobData.ErrorMessage != "" {
+		if err := c.PrintJobRepo.UpdateJobErrorMessage(jobData.JobID, *jobData.ErrorMessage); err != nil {
+			log.Printf("Failed to update job %s error message: %v", jobData.JobID, err)
+		}
+	}
+	c.recordJobEvents(&jobData, update)
+	
+	if c.Events != nil {
+		c.Events.Publish(events.Event{
+			Type:   events.EventJobUpdated,
+			NodeID: c.NodeID,
+			Data: map[string]interface{}{
+				"job_id":   jobData.JobID,
+				"status":   jobData.Status,
+				"progress": jobData.Progress,
+			},
+		})
+	}
+	
+	// 进度更新只推送事件
+	if !update.StatusChanged {
+		return
+	}
+	
+	// 任务完成（含部分完成）时计算费用
+	if jobData.Status.IsBillable() {
+		c.applyJobCost(jobData.JobID)
+	}
+	// 硬件错误导致的失败可能在原打印机重试或改投备用打印机，此时任务尚未结束
+	if jobData.Status == models.JobStatusFailed && jobData.ErrorCode != nil && c.Dispatcher != nil &&
+		c.Dispatcher.HandleJobFailure(jobData.JobID, *jobData.ErrorCode) {
+		return
+	}
+	// 任务结束时通知提交用户
+	if jobData.Status.IsTerminal() {
+		c.Notifier.JobFinished(jobData.JobID)
+		// 计入打印机熔断器，释放并发名额，继续分发排队任务
+		if c.Dispatcher != nil {
+			c.Dispatcher.JobReported(jobData.JobID)
+		}
+	}
 	
 	log.Printf("Successfully updated job %s status to %s (progress: %d%%)", 
 		jobData.JobID, jobData.Status, jobData.Progress)
 }
 
+// updateJobProgress 写入任务状态和进度，未配置节流器时每条更新都写入
+func (c *Connection) updateJobProgress(jobData *JobUpdateData, receivedAt time.Time) (ProgressUpdate, error) {
+	if c.Progress != nil {
+		return c.Progress.Update(jobData.JobID, jobData.Status, jobData.Progress, receivedAt)
+	}
+	applied, err := c.PrintJobRepo.UpdateJobStatus(jobData.JobID, jobData.Status, jobData.Progress, receivedAt)
+	return ProgressUpdate{StatusChanged: true, Applied: applied, Persisted: applied, Progress: jobData.Progress, Milestone: true}, err
+}
+
+// recordJobEvents 将节点上报的状态变化和进度里程碑写入任务时间线
+func (c *Connection) recordJobEvents(jobData *JobUpdateData, update ProgressUpdate) {
+	if c.JobEvents == nil {
+		return
+	}
+	actor := models.JobEventActorEdgePrefix + c.NodeID
+	event := &models.PrintJobEvent{
+		JobID:  jobData.JobID,
+		Type:   models.JobEventStatusChanged,
+		Status: jobData.Status,
+		Actor:  actor,
+	}
+	if jobData.ErrorMessage != nil {
+		event.Message = *jobData.ErrorMessage
+	}
+	if jobData.ErrorCode != nil && *jobData.ErrorCode != "" {
+		event.Details = map[string]interface{}{"error_code": *jobData.ErrorCode}
+	}
+	if update.StatusChanged {
+		c.JobEvents.RecordStatusChange(event)
+	}
+	if update.Milestone {
+		c.JobEvents.RecordProgress(jobData.JobID, jobData.Status, jobData.Progress, actor)
+	}
+}
+
+// applyJobCost 计算并保存已完成（含部分完成）任务的费用
+func (c *Connection) applyJobCost(jobID string) {
+	if c.Calculator == nil {
+		return
+	}
+
+	job, err := c.PrintJobRepo.GetPrintJobByID(jobID)
+	if err != nil || job == nil {
+		log.Printf("Failed to load job %s for cost accounting: %v", jobID, err)
+		return
+	}
+
+	// 打印机查询失败时使用全局定价
+	printer, err := c.PrinterRepo.GetPrinterByID(job.PrinterID)
+	if err != nil {
+		printer = nil
+	}
+
+	cost := c.Calculator.Compute(job, printer)
+	if err := c.PrintJobRepo.UpdateJobCost(jobID, cost); err != nil {
+		log.Printf("Failed to update cost for job %s: %v", jobID, err)
+	}
+}
+
+
+// sendError 通知节点某条消息被拒绝；发送队列已满时丢弃
+func (c *Connection) sendError(messageType, code, message string) {
+	c.sendErrorData(ErrorData{
+		Code:        code,
+		Message:     message,
+		MessageType: messageType,
+	})
+}
+
+// sendErrorData 发送 error 指令；发送队列已满时丢弃
+func (c *Connection) sendErrorData(errorData ErrorData) {
+	data, err := json.Marshal(Command{
+		Type:      CmdTypeError,
+		CommandID: uuid.New().String(),
+		Timestamp: time.Now(),
+		Target:    c.NodeID,
+		Data:      errorData,
+	})
+	if err != nil {
+		return
+	}
+
+	if !c.trySend(data) {
+		log.Printf("Send queue full or closed for node %s, dropping error message", c.NodeID)
+	}
+}
 
 // SendCommand 发送指令到 Edge Node
 func (c *Connection) SendCommand(cmd *Command) error {
@@ -255,11 +734,37 @@ func (c *Connection) SendCommand(cmd *Command) error {
 		return err
 	}
 
+	if !c.trySend(data) {
+		// 发送队列已满说明节点无法及时接收，关闭连接等待重连
+		c.closeSend()
+		return ErrSendBufferFull
+	}
+	return nil
+}
+
+// trySend 非阻塞地放入发送队列；队列已满或已关闭时返回 false
+func (c *Connection) trySend(data []byte) bool {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	if c.sendClosed {
+		return false
+	}
 	select {
 	case c.Send <- data:
-		return nil
+		return true
 	default:
+		return false
+	}
+}
+
+// closeSend 关闭发送队列（可重复调用），WritePump 随后发送关闭帧并退出
+func (c *Connection) closeSend() {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	if !c.sendClosed {
+		c.sendClosed = true
 		close(c.Send)
-		return err
 	}
 }
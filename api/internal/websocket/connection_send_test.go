@@ -0,0 +1,76 @@
+package websocket
+
+import (
+	"sync"
+	"testing"
+)
+
+// drain 模拟 WritePump 读取发送队列直到关闭
+func drain(conn *Connection) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range conn.Send {
+		}
+	}()
+	return done
+}
+
+// TestSendErrorRacesWithClose 读循环回复错误的同时管理器关闭连接，不能向已关闭的 Send 发送
+func TestSendErrorRacesWithClose(t *testing.T) {
+	closers := map[string]func(m *ConnectionManager, conn *Connection){
+		"admin close": func(m *ConnectionManager, conn *Connection) {
+			m.CloseConnection(conn.NodeID, "closed by test")
+		},
+		"replacement": func(m *ConnectionManager, conn *Connection) {
+			m.registerConnection(&Connection{NodeID: conn.NodeID, Send: make(chan []byte, 1)})
+		},
+		"unregister": func(m *ConnectionManager, conn *Connection) {
+			m.unregisterConnection(conn)
+		},
+		"broadcast to full queue": func(m *ConnectionManager, conn *Connection) {
+			for i := 0; i < 4; i++ {
+				m.broadcastMessage([]byte("{}"))
+			}
+		},
+	}
+
+	for name, closeConn := range closers {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				m := NewConnectionManager()
+				conn := &Connection{NodeID: "node-1", Send: make(chan []byte, 1)}
+				m.registerConnection(conn)
+
+				var wg sync.WaitGroup
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 20; j++ {
+						conn.sendError("job_update", ErrCodeInvalidMessage, "bad message")
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					closeConn(m, conn)
+				}()
+				wg.Wait()
+
+				// 关闭后继续发送被丢弃，重复关闭不会 panic
+				conn.sendError("job_update", ErrCodeInvalidMessage, "after close")
+				conn.closeSend()
+			}
+		})
+	}
+}
+
+func TestSendCommandAfterCloseReturnsError(t *testing.T) {
+	conn := &Connection{NodeID: "node-1", Send: make(chan []byte, 1)}
+	done := drain(conn)
+	conn.closeSend()
+	<-done
+
+	if err := conn.SendCommand(&Command{Type: CmdTypeError}); err != ErrSendBufferFull {
+		t.Fatalf("SendCommand after close = %v, want ErrSendBufferFull", err)
+	}
+}
@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/websocket/conformance"
+)
+
+// FuzzDecodeMessage 任意输入帧：解码失败必须返回 ErrInvalidMessage，解码成功的消息交给处理器时不能 panic
+// 运行：go test ./internal/websocket -run '^$' -fuzz FuzzDecodeMessage -fuzztime 20s
+func FuzzDecodeMessage(f *testing.F) {
+	seeds := []string{
+		`{"type":"edge_heartbeat","node_id":"n","timestamp":"2026-03-01T12:00:00Z","data":{"system_info":{"cpu_usage":1,"latency":2}}}`,
+		`{"type":"printer_status","data":{"printer_id":"Lobby","status":"ready","queue_length":1,"supplies":{"toner":80}}}`,
+		`{"type":"job_update","data":{"job_id":"job-1","status":"partially_completed","progress":50,"pages_printed":2}}`,
+		`{"type":"job_update","data":{"job_id":"job-1","status":"failed","error_message":"jam","error_code":"E1"}}`,
+		`{"type":"log_batch","data":{"lines":[{"level":"info","ts":"2026-03-01T12:00:00Z","message":"x"}],"dropped":3}}`,
+		`{"type":"auth","data":{"token":"t"}}`,
+		`{"type":"edge_heartbeat","timestamp":1}`,
+		`{"type":""}`,
+		`[]`,
+		`null`,
+		"{\"type\":\"\xff\"}",
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	// 处理器每条消息都写日志，模糊测试时丢弃以免拖慢执行
+	log.SetOutput(io.Discard)
+	f.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	recorder := conformance.NewRecorder()
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		msg, err := DecodeMessage(raw)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("DecodeMessage error %v does not wrap ErrInvalidMessage", err)
+			}
+			return
+		}
+		if msg.Type == "" {
+			t.Fatal("decoded message without type")
+		}
+
+		c := &Connection{
+			NodeID:       "node-1",
+			Send:         make(chan []byte, 4),
+			Manager:      NewConnectionManager(),
+			PrinterRepo:  recorder,
+			EdgeNodeRepo: recorder,
+			PrintJobRepo: recorder,
+			Monitor:      recorder,
+			Notifier:     recorder,
+			Dispatcher:   recorder,
+			Scopes:       []string{middleware.ScopeEdgeConnect, middleware.ScopeEdgePrinterWrite, middleware.ScopeEdgeJobUpdate},
+		}
+		c.Logs = NewNodeLogs(c.Manager)
+		c.handleMessage(msg)
+		recorder.Reset()
+	})
+}
@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// panicMarshaler 编码时 panic 的指令数据
+type panicMarshaler struct{}
+
+func (panicMarshaler) MarshalJSON() ([]byte, error) { panic("boom") }
+
+func TestDispatchPrintJobNodeNotConnected(t *testing.T) {
+	m := NewConnectionManager()
+	_, err := m.DispatchPrintJob("node-1", &models.PrintJob{ID: "job-1"}, "lobby", nil)
+	if !errors.Is(err, ErrNodeNotConnected) {
+		t.Fatalf("error = %v, want ErrNodeNotConnected", err)
+	}
+}
+
+func TestDispatchPrintJobSendBufferFull(t *testing.T) {
+	m := NewConnectionManager()
+	conn := &Connection{NodeID: "node-1", Send: make(chan []byte, 1)}
+	m.registerConnection(conn)
+	conn.Send <- []byte("{}")
+
+	_, err := m.DispatchPrintJob("node-1", &models.PrintJob{ID: "job-1"}, "lobby", nil)
+	if !errors.Is(err, ErrSendBufferFull) {
+		t.Fatalf("error = %v, want ErrSendBufferFull", err)
+	}
+	// 缓冲区满不会断开连接，节点稍后可重新接收
+	if !m.IsNodeConnected("node-1") {
+		t.Fatal("connection dropped after full buffer")
+	}
+}
+
+func TestDispatchPrintJobSuccess(t *testing.T) {
+	m := NewConnectionManager()
+	conn := &Connection{NodeID: "node-1", Send: make(chan []byte, 1)}
+	m.registerConnection(conn)
+
+	job := &models.PrintJob{ID: "job-1", PrinterID: "printer-1", FileURL: "https://files.example.com/a.pdf", Copies: 2}
+	driver := &models.PrinterDriver{ID: "driver-1", DriverName: "HP Universal"}
+	result, err := m.DispatchPrintJob("node-1", job, "lobby", driver)
+	if err != nil {
+		t.Fatalf("DispatchPrintJob: %v", err)
+	}
+	if result.CommandID != job.ID || result.DispatchedAt.IsZero() {
+		t.Fatalf("result = %+v, want command ID %s and timestamp", result, job.ID)
+	}
+
+	var command struct {
+		Type      string       `json:"type"`
+		CommandID string       `json:"command_id"`
+		Data      PrintJobData `json:"data"`
+	}
+	if err := json.Unmarshal(<-conn.Send, &command); err != nil {
+		t.Fatalf("decode command: %v", err)
+	}
+	if command.Type != CmdTypePrintJob || command.CommandID != result.CommandID {
+		t.Fatalf("command = {%s, %s}", command.Type, command.CommandID)
+	}
+	if command.Data.PrinterName != "lobby" || command.Data.Copies != 2 || command.Data.DriverName != "HP Universal" {
+		t.Fatalf("print_job data = %+v", command.Data)
+	}
+}
+
+func TestMarshalCommandErrors(t *testing.T) {
+	tests := map[string]interface{}{
+		"unsupported value": make(chan int),
+		"panic":             panicMarshaler{},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			message, err := marshalCommand(Command{Type: CmdTypePrintJob, Data: data})
+			if !errors.Is(err, ErrMarshal) {
+				t.Fatalf("error = %v, want ErrMarshal", err)
+			}
+			if message != nil {
+				t.Fatalf("message = %q, want nil", message)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import "errors"
 var (
 	ErrNodeNotConnected  = errors.New("edge node not connected")
 	ErrConnectionClosed  = errors.New("connection closed")
+	ErrSendBufferFull    = errors.New("send buffer full")         // 连接发送缓冲区已满（节点处理过慢）
+	ErrMarshal           = errors.New("failed to encode command") // 指令无法编码（含编码时的 panic）
 	ErrInvalidMessage    = errors.New("invalid message format")
 	ErrAuthenticationFailed = errors.New("authentication failed")
 )
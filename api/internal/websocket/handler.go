@@ -1,17 +1,29 @@
 package websocket
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"fly-print-cloud/api/internal/billing"
+	"fly-print-cloud/api/internal/config"
 	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/notify"
 	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/worker"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 var upgrader = websocket.Upgrader{
+	Subprotocols: SupportedProtocolVersions,
 	CheckOrigin: func(r *http.Request) bool {
 		// TODO: 生产环境需要更严格的 Origin 检查
 		return true
@@ -24,23 +36,86 @@ type WebSocketHandler struct {
 	printerRepo  *database.PrinterRepository
 	edgeNodeRepo *database.EdgeNodeRepository
 	printJobRepo *database.PrintJobRepository
+	calculator   *billing.Calculator
+	monitor      *worker.HeartbeatMonitor
+	notifier     *notify.Notifier
+	dispatcher   JobDispatcher
+	eventBus     *events.Bus
+	jobEventRepo *database.PrintJobEventRepository
+	settings     *config.Store // 下发给节点的能力描述按当前配置生成
+	limiter      *MessageLimiter
+	progress     *ProgressThrottle
+	logs         *NodeLogs
+	connTokens   *middleware.ConnTokenSigner
+	tokens       *middleware.OAuth2Authenticator
+	authTimeout  time.Duration // 延迟认证等待 auth 消息的时长
 }
 
 // NewWebSocketHandler 创建 WebSocket 处理器
-func NewWebSocketHandler(manager *ConnectionManager, printerRepo *database.PrinterRepository, edgeNodeRepo *database.EdgeNodeRepository, printJobRepo *database.PrintJobRepository) *WebSocketHandler {
+func NewWebSocketHandler(manager *ConnectionManager, printerRepo *database.PrinterRepository, edgeNodeRepo *database.EdgeNodeRepository, printJobRepo *database.PrintJobRepository, calculator *billing.Calculator, monitor *worker.HeartbeatMonitor, notifier *notify.Notifier, dispatcher JobDispatcher, eventBus *events.Bus, jobEventRepo *database.PrintJobEventRepository, settings *config.Store, progress *ProgressThrottle, logs *NodeLogs, connTokens *middleware.ConnTokenSigner, tokens *middleware.OAuth2Authenticator) *WebSocketHandler {
 	return &WebSocketHandler{
 		manager:      manager,
 		printerRepo:  printerRepo,
 		edgeNodeRepo: edgeNodeRepo,
 		printJobRepo: printJobRepo,
+		calculator:   calculator,
+		monitor:      monitor,
+		notifier:     notifier,
+		dispatcher:   dispatcher,
+		eventBus:     eventBus,
+		jobEventRepo: jobEventRepo,
+		settings:     settings,
+		limiter:      NewMessageLimiter(settings),
+		progress:     progress,
+		logs:         logs,
+		connTokens:   connTokens,
+		tokens:       tokens,
+		authTimeout:  authMessageTimeout,
 	}
 }
 
+// 延迟认证：无法在升级请求中携带 Authorization 头的节点使用 ?auth=deferred 连接，
+// 升级后第一帧必须是携带 bearer token 的 auth 消息
+const (
+	authModeDeferred   = "deferred"
+	authMessageTimeout = 5 * time.Second
+	maxAuthMessageSize = 8192 // auth 消息携带完整 token，认证前只接受较小的首帧
+)
+
+// connectionAuth 认证通过的连接信息
+type connectionAuth struct {
+	nodeID    string
+	scopes    []string
+	tokenInfo *middleware.OAuth2TokenInfo // 客户端证书认证时为空
+	client    *models.ClientInfo // 客户端信息（auth 消息的 client_info 或 X-Client 请求头）
+
+	conflictSuspected bool // 节点已被标记为疑似 node_id 冲突
+}
+
+// connectionAuthError 认证失败的 HTTP 状态码和原因（延迟认证时作为关闭原因）
+type connectionAuthError struct {
+	status  int
+	message string
+}
+
 // HandleConnection 处理 WebSocket 连接升级
 func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 	// 验证 OAuth2 token
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
+		if c.Query("auth") == authModeDeferred {
+			h.handleDeferredAuth(c)
+			return
+		}
+		// 未携带 token 时使用 TLS 客户端证书
+		if auth, authErr := h.authenticateCert(c); auth != nil || authErr != nil {
+			if authErr != nil {
+				c.JSON(authErr.status, gin.H{"error": authErr.message})
+				return
+			}
+			h.upgradeAndEstablish(c, auth)
+			return
+		}
 		log.Printf("WebSocket connection missing Authorization header: node_id=%s", c.Query("node_id"))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
 		return
@@ -53,65 +128,336 @@ func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 		return
 	}
 
-	// 使用导出的 OAuth2 中间件验证 token
-	tokenInfo, err := middleware.ValidateOAuth2Token(token)
+	auth, authErr := h.authenticate(token, c.Query("conn_token"), c.Query("node_id"))
+	if authErr != nil {
+		c.JSON(authErr.status, gin.H{"error": authErr.message})
+		return
+	}
+	h.upgradeAndEstablish(c, auth)
+}
+
+// upgradeAndEstablish 握手前已完成认证的连接：读取 X-Client 请求头，升级连接并注册
+func (h *WebSocketHandler) upgradeAndEstablish(c *gin.Context, auth *connectionAuth) {
+	auth.client = models.ParseClientInfo(c.GetHeader(models.ClientInfoHeader))
+
+	conn, err := h.upgrade(c)
 	if err != nil {
-		log.Printf("WebSocket OAuth2 token validation failed: %v", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		log.Printf("Failed to upgrade connection for node %s: %v", auth.nodeID, err)
 		return
 	}
+	h.establish(c, conn, auth)
+}
 
-	// 检查是否有 edge:* scope
-	if !middleware.HasRequiredScope(tokenInfo, "edge:heartbeat") {
-		log.Printf("WebSocket token missing required scope: edge:heartbeat")
-		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient scope"})
+// handleDeferredAuth 先升级连接，再等待第一帧 auth 消息完成认证
+// 超时、第一帧不是 auth 消息或认证失败时以 policy violation 关闭；认证前不处理其他消息，也不注册连接
+func (h *WebSocketHandler) handleDeferredAuth(c *gin.Context) {
+	conn, err := h.upgrade(c)
+	if err != nil {
+		log.Printf("Failed to upgrade deferred-auth connection: %v", err)
+		return
+	}
+
+	conn.SetReadLimit(maxAuthMessageSize)
+	conn.SetReadDeadline(time.Now().Add(h.authTimeout))
+	_, messageBytes, err := conn.ReadMessage()
+	if err != nil {
+		log.Printf("WebSocket deferred auth failed from %s: %v", c.ClientIP(), err)
+		closePolicyViolation(conn, "authentication timeout")
+		return
+	}
+
+	var msg struct {
+		Type   string   `json:"type"`
+		NodeID string   `json:"node_id"`
+		Data   AuthData `json:"data"`
+	}
+	if err := json.Unmarshal(messageBytes, &msg); err != nil || msg.Type != MsgTypeAuth || msg.Data.Token == "" {
+		log.Printf("WebSocket deferred auth from %s: first message is not an auth message", c.ClientIP())
+		closePolicyViolation(conn, "auth message required")
 		return
 	}
 
-	// 优先从 query parameter 获取 node_id
 	nodeID := c.Query("node_id")
 	if nodeID == "" {
-		// 如果 URL 参数中没有 node_id，尝试从 token 获取
+		nodeID = msg.NodeID
+	}
+	connToken := c.Query("conn_token")
+	if connToken == "" {
+		connToken = msg.Data.ConnToken
+	}
+	auth, authErr := h.authenticate(strings.TrimPrefix(msg.Data.Token, "Bearer "), connToken, nodeID)
+	if authErr != nil {
+		closePolicyViolation(conn, authErr.message)
+		return
+	}
+	auth.client = msg.Data.ClientInfo.Normalize()
+	if auth.client == nil {
+		auth.client = models.ParseClientInfo(c.GetHeader(models.ClientInfoHeader))
+	}
+
+	conn.SetReadDeadline(time.Time{})
+	h.establish(c, conn, auth)
+}
+
+// authenticate 校验 token 和 edge:connect 权限并确定节点ID，两种握手方式共用
+// 节点ID 取自云端签发的连接令牌；未携带连接令牌时仅在 edge.allow_legacy_node_id 开启时按 node_id 参数或 token claim 确定
+func (h *WebSocketHandler) authenticate(token, connToken, nodeID string) (*connectionAuth, *connectionAuthError) {
+	// 与 HTTP 接口使用同一 token 验证器
+	tokenInfo, err := h.tokens.ValidateToken(token)
+	if err != nil {
+		log.Printf("WebSocket OAuth2 token validation failed: %v", err)
+		return nil, &connectionAuthError{http.StatusUnauthorized, "invalid token"}
+	}
+
+	// 建立连接需要 edge:connect；连接内的消息按类型校验权限
+	scopes := middleware.TokenScopes(tokenInfo)
+	if !middleware.HasScope(scopes, middleware.ScopeEdgeConnect) {
+		log.Printf("WebSocket token missing required scope: %s", middleware.ScopeEdgeConnect)
+		return nil, &connectionAuthError{http.StatusForbidden, "insufficient scope"}
+	}
+
+	nodeID, authErr := h.resolveNodeID(tokenInfo, connToken, nodeID)
+	if authErr != nil {
+		return nil, authErr
+	}
+
+	log.Printf("WebSocket connection request from node: %s (user: %s)", nodeID, tokenInfo.Sub)
+
+	auth := &connectionAuth{nodeID: nodeID, scopes: scopes, tokenInfo: tokenInfo}
+	if authErr := h.checkNode(auth); authErr != nil {
+		return nil, authErr
+	}
+	return auth, nil
+}
+
+// authenticateCert 使用 TLS 客户端证书认证连接，节点由证书登记的凭据确定，无需连接令牌
+// 未出示已校验的证书时返回 nil, nil；node_id 参数与证书所属节点不一致时拒绝
+func (h *WebSocketHandler) authenticateCert(c *gin.Context) (*connectionAuth, *connectionAuthError) {
+	identity, err := middleware.AuthenticateClientCert(c.Request)
+	if err != nil {
+		if !errors.Is(err, middleware.ErrUnknownClientCert) {
+			log.Printf("Failed to verify WebSocket client certificate: %v", err)
+			return nil, &connectionAuthError{http.StatusInternalServerError, "failed to verify client certificate"}
+		}
+		log.Printf("WebSocket client certificate rejected from %s: %v", c.ClientIP(), err)
+		return nil, &connectionAuthError{http.StatusUnauthorized, "invalid client certificate"}
+	}
+	if identity == nil {
+		return nil, nil
+	}
+	if nodeID := c.Query("node_id"); nodeID != "" && nodeID != identity.NodeID {
+		log.Printf("WebSocket node_id %s does not match client certificate node %s", nodeID, identity.NodeID)
+		return nil, &connectionAuthError{http.StatusForbidden, "node_id does not match client certificate"}
+	}
+
+	log.Printf("WebSocket connection request from node: %s (client certificate: %s)", identity.NodeID, identity.Subject)
+
+	auth := &connectionAuth{nodeID: identity.NodeID, scopes: middleware.ClientCertScopes}
+	if authErr := h.checkNode(auth); authErr != nil {
+		return nil, authErr
+	}
+	return auth, nil
+}
+
+// checkNode 拒绝已禁用节点的连接，并记录节点是否已被标记为疑似 node_id 冲突
+func (h *WebSocketHandler) checkNode(auth *connectionAuth) *connectionAuthError {
+	node, err := h.edgeNodeRepo.GetEdgeNodeByID(auth.nodeID)
+	if err == nil && !node.Enabled {
+		log.Printf("WebSocket connection rejected for disabled node: %s", auth.nodeID)
+		return &connectionAuthError{http.StatusForbidden, "edge node disabled"}
+	}
+	auth.conflictSuspected = err == nil && node.ConflictSuspected
+	return nil
+}
+
+// resolveNodeID 确定连接的节点ID：优先使用连接令牌中的 node_id（须由同一 OAuth2 客户端申请，与 node_id 参数一致）
+func (h *WebSocketHandler) resolveNodeID(tokenInfo *middleware.OAuth2TokenInfo, connToken, nodeID string) (string, *connectionAuthError) {
+	if connToken != "" {
+		claims, err := h.connTokens.Verify(connToken)
+		if err != nil {
+			log.Printf("WebSocket connection token rejected: %v (node_id=%s)", err, nodeID)
+			return "", &connectionAuthError{http.StatusUnauthorized, "invalid conn_token"}
+		}
+		if claims.Subject != tokenInfo.Sub {
+			log.Printf("WebSocket connection token for node %s was issued to %q, presented by %q", claims.NodeID, claims.Subject, tokenInfo.Sub)
+			return "", &connectionAuthError{http.StatusUnauthorized, "invalid conn_token"}
+		}
+		if nodeID != "" && nodeID != claims.NodeID {
+			log.Printf("WebSocket node_id %s does not match connection token node %s", nodeID, claims.NodeID)
+			return "", &connectionAuthError{http.StatusForbidden, "node_id does not match conn_token"}
+		}
+		return claims.NodeID, nil
+	}
+
+	if !h.settings.Get().Edge.AllowLegacyNodeID {
+		return "", &connectionAuthError{http.StatusUnauthorized, "missing conn_token"}
+	}
+	// 迁移期间：优先使用 query parameter 中的 node_id，否则尝试从 token 获取
+	if nodeID == "" {
 		nodeID = h.extractNodeIDFromTokenInfo(tokenInfo)
 		if nodeID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "missing node_id"})
-			return
+			return "", &connectionAuthError{http.StatusBadRequest, "missing node_id"}
 		}
 	}
+	log.Printf("WebSocket connection for node %s without conn_token (legacy node_id)", nodeID)
+	return nodeID, nil
+}
 
-	log.Printf("WebSocket connection request from node: %s (user: %s)", nodeID, tokenInfo.Sub)
+// upgrade 升级 HTTP 连接到 WebSocket
+func (h *WebSocketHandler) upgrade(c *gin.Context) (*websocket.Conn, error) {
+	wsUpgrader := upgrader
+	wsUpgrader.EnableCompression = h.settings.Get().Edge.WSCompression
+	return wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+}
 
-	// 升级 HTTP 连接到 WebSocket
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		log.Printf("Failed to upgrade connection for node %s: %v", nodeID, err)
+// establish 注册已认证的连接，下发能力描述并启动读写协程
+func (h *WebSocketHandler) establish(c *gin.Context, conn *websocket.Conn, auth *connectionAuth) {
+	nodeID := auth.nodeID
+
+	if h.detectConflict(nodeID, c.ClientIP(), auth.conflictSuspected) {
+		log.Printf("WebSocket connection for node %s from %s refused: node_id conflict suspected", nodeID, c.ClientIP())
+		closePolicyViolation(conn, "duplicate node_id")
 		return
 	}
 
 	// 创建连接对象
-	connection := NewConnection(nodeID, conn, h.manager, h.printerRepo, h.edgeNodeRepo, h.printJobRepo)
+	connection := NewConnection(nodeID, conn, h.manager, h.printerRepo, h.edgeNodeRepo, h.printJobRepo, h.calculator, h.monitor, h.notifier, h.dispatcher)
+	connection.RemoteAddr = c.ClientIP()
+	connection.Client = auth.client
+	connection.Events = h.eventBus
+	connection.JobEvents = h.jobEventRepo
+	connection.Scopes = auth.scopes
+	connection.Limiter = h.limiter
+	connection.Progress = h.progress
+	connection.Logs = h.logs
+	connection.Settings = h.settings
 
 	// 注册连接
 	h.manager.register <- connection
 
+	// 下发云端能力描述，Edge Node 据此决定启用哪些协议功能
+	if err := connection.SendCommand(&Command{
+		Type:      CmdTypeWelcome,
+		CommandID: uuid.New().String(),
+		Timestamp: time.Now(),
+		Target:    nodeID,
+		Data:      NewServerCapabilities(&h.settings.Get().Edge),
+	}); err != nil {
+		log.Printf("Failed to send welcome message to node %s: %v", nodeID, err)
+	}
+
+	// 仍有管理员在跟踪日志时让重连的节点继续上报
+	h.logs.NodeConnected(connection)
+
+	// 重连的节点不保留暂停状态，重新下发仍处于暂停的打印机
+	h.resendQueuePauses(connection)
+
 	// 启动读写协程
 	go connection.WritePump()
 	go connection.ReadPump()
 
-	log.Printf("WebSocket connection established for Edge Node: %s", nodeID)
+	log.Printf("WebSocket connection established for Edge Node: %s (client: %s)", nodeID, auth.client.String())
 }
 
-// extractNodeIDFromTokenInfo 从 token 信息中提取 node_id
+// resendQueuePauses 为节点下仍处于暂停的打印机下发 pause_queue
+func (h *WebSocketHandler) resendQueuePauses(connection *Connection) {
+	printers, err := h.printerRepo.ListPrintersByEdgeNode(connection.NodeID)
+	if err != nil {
+		log.Printf("Failed to list printers of node %s for queue pauses: %v", connection.NodeID, err)
+		return
+	}
+	for _, printer := range printers {
+		if !printer.QueuePaused {
+			continue
+		}
+		command := newPrinterQueueCommand(CmdTypePauseQueue, PrinterQueueData{
+			PrinterID:   printer.ID,
+			PrinterName: printer.Name,
+			Reason:      printer.QueuePauseReason,
+		})
+		if err := connection.SendCommand(command); err != nil {
+			log.Printf("Failed to resend pause_queue for printer %s to node %s: %v", printer.ID, connection.NodeID, err)
+		}
+	}
+}
+
+// detectConflict 检测同一 node_id 是否被来自不同地址的连接反复替换（如克隆的虚拟机镜像），
+// 窗口内替换次数达到 edge.conflict_replacements 时标记节点并发出告警。
+// 节点已被标记、已有来自其他地址的连接且配置了 edge.refuse_conflicting_conns 时返回 true，调用方应拒绝新连接
+func (h *WebSocketHandler) detectConflict(nodeID, remoteAddr string, flagged bool) bool {
+	edge := &h.settings.Get().Edge
+	if edge.ConflictReplacements <= 0 {
+		return false
+	}
+
+	check := h.manager.CheckReplacement(nodeID, remoteAddr, time.Now(), edge.ConflictWindow)
+	if !check.Replacing {
+		return false
+	}
+	log.Printf("Edge Node %s connection from %s replaces connection from %s (%d replacements within %s)",
+		nodeID, remoteAddr, check.PreviousAddr, check.Replacements, edge.ConflictWindow)
+
+	if check.Replacements >= edge.ConflictReplacements {
+		newlyFlagged, err := h.edgeNodeRepo.MarkConflictSuspected(nodeID, check.RemoteAddrs)
+		if err != nil {
+			log.Printf("Failed to mark node_id conflict for edge node %s: %v", nodeID, err)
+		} else {
+			flagged = true
+		}
+		if newlyFlagged {
+			log.Printf("Edge Node %s suspected node_id conflict: connections from %s keep replacing each other",
+				nodeID, strings.Join(check.RemoteAddrs, ", "))
+			if h.eventBus != nil {
+				h.eventBus.Publish(events.Event{
+					Type:   events.EventNodeConflictSuspected,
+					NodeID: nodeID,
+					Data: map[string]interface{}{
+						"remote_addrs":   check.RemoteAddrs,
+						"replacements":   check.Replacements,
+						"window_seconds": int(edge.ConflictWindow.Seconds()),
+					},
+				})
+			}
+		}
+	}
+
+	return flagged && edge.RefuseConflictingConns
+}
+
+// WriteMetrics 以 Prometheus 文本格式写入 WebSocket 连接数和上行消息限速计数（由 /metrics 与其他指标合并输出）
+func (h *WebSocketHandler) WriteMetrics(b *strings.Builder) {
+	stats := h.limiter.Stats()
+
+	b.WriteString("# HELP fly_print_ws_connections Current number of edge node WebSocket connections.\n")
+	b.WriteString("# TYPE fly_print_ws_connections gauge\n")
+	fmt.Fprintf(b, "fly_print_ws_connections %d\n", h.manager.GetConnectionCount())
+
+	b.WriteString("# HELP fly_print_ws_messages_dropped_total Upstream WebSocket messages dropped by the rate limiter.\n")
+	b.WriteString("# TYPE fly_print_ws_messages_dropped_total counter\n")
+	for _, messageType := range sortedKeys(stats.Dropped) {
+		fmt.Fprintf(b, "fly_print_ws_messages_dropped_total{type=%q} %d\n", messageType, stats.Dropped[messageType])
+	}
+
+	b.WriteString("# HELP fly_print_ws_rate_limit_warnings_total Rate limit warnings sent to edge nodes.\n")
+	b.WriteString("# TYPE fly_print_ws_rate_limit_warnings_total counter\n")
+	fmt.Fprintf(b, "fly_print_ws_rate_limit_warnings_total %d\n", stats.Warnings)
+
+	b.WriteString("# HELP fly_print_ws_rate_limit_disconnects_total WebSocket connections closed for persistently exceeding rate limits.\n")
+	b.WriteString("# TYPE fly_print_ws_rate_limit_disconnects_total counter\n")
+	fmt.Fprintf(b, "fly_print_ws_rate_limit_disconnects_total %d\n", stats.Disconnects)
+}
+
+// closePolicyViolation 以 policy violation 关闭未通过认证的连接
+func closePolicyViolation(conn *websocket.Conn, reason string) {
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+		time.Now().Add(writeWait))
+	conn.Close()
+}
+
+// extractNodeIDFromTokenInfo 从 token 信息中提取 node_id（node_id claim，否则为 subject）
 func (h *WebSocketHandler) extractNodeIDFromTokenInfo(tokenInfo *middleware.OAuth2TokenInfo) string {
-	// 对于 Client Credentials Flow，subject 通常是 client_id
-	// 可以根据实际需求调整提取逻辑
-	if tokenInfo.Sub != "" {
-		return tokenInfo.Sub
-	}
-	
-	// 如果有自定义 claims，也可以从中提取
-	// 这里可以根据实际的 token 结构调整
-	return ""
+	return middleware.TokenNodeID(tokenInfo)
 }
 
 
@@ -0,0 +1,147 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+// newAuthTestServer 只包含认证所需依赖的 WebSocket 服务；认证通过后才会访问数据库，测试只覆盖拒绝的路径
+func newAuthTestServer(t *testing.T, authTimeout time.Duration) (*httptest.Server, *ConnectionManager) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	manager := NewConnectionManager()
+	h := &WebSocketHandler{
+		manager:     manager,
+		settings:    config.NewStore(&config.Config{}),
+		tokens:      middleware.NewOAuth2Authenticator(&config.OAuth2Config{}, nil),
+		authTimeout: authTimeout,
+	}
+	r := gin.New()
+	r.GET("/ws", h.HandleConnection)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv, manager
+}
+
+// edgeToken 测试用 JWT（认证只解析 claims）
+func edgeToken(t *testing.T, scope string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":     "edge-client-1",
+		"node_id": "node-1",
+		"scope":   scope,
+	}).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+func wsURL(srv *httptest.Server, query string) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws" + query
+}
+
+// TestHeaderAuthRejected 握手时携带 Authorization 头：认证失败在升级前以 HTTP 状态码拒绝
+func TestHeaderAuthRejected(t *testing.T) {
+	srv, manager := newAuthTestServer(t, time.Second)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{"no credentials", nil, http.StatusUnauthorized},
+		{"empty bearer", http.Header{"Authorization": {"Bearer "}}, http.StatusUnauthorized},
+		{"invalid token", http.Header{"Authorization": {"Bearer not-a-jwt"}}, http.StatusUnauthorized},
+		{"missing edge:connect", http.Header{"Authorization": {"Bearer " + edgeToken(t, middleware.ScopeEdgeRegister)}}, http.StatusForbidden},
+		{"missing conn_token", http.Header{"Authorization": {"Bearer " + edgeToken(t, middleware.ScopeEdgeConnect)}}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, resp, err := websocket.DefaultDialer.Dial(wsURL(srv, "?node_id=node-1"), tt.header)
+			if err == nil {
+				conn.Close()
+				t.Fatal("handshake succeeded")
+			}
+			if resp == nil || resp.StatusCode != tt.status {
+				t.Fatalf("handshake response = %v, want status %d", resp, tt.status)
+			}
+		})
+	}
+	if count := manager.GetConnectionCount(); count != 0 {
+		t.Fatalf("%d connections registered", count)
+	}
+}
+
+// TestDeferredAuthRejected ?auth=deferred 时先升级连接，第一帧不是有效的 auth 消息或超时未发送时以 policy violation 关闭
+func TestDeferredAuthRejected(t *testing.T) {
+	srv, manager := newAuthTestServer(t, 200*time.Millisecond)
+
+	tests := []struct {
+		name   string
+		first  interface{} // nil 表示不发送
+		reason string
+	}{
+		{"timeout", nil, "authentication timeout"},
+		{"heartbeat before auth", map[string]interface{}{"type": MsgTypeHeartbeat, "node_id": "node-1"}, "auth message required"},
+		{"auth without token", map[string]interface{}{"type": MsgTypeAuth, "data": map[string]string{}}, "auth message required"},
+		{"not json", "hello", "auth message required"},
+		{"invalid token", map[string]interface{}{"type": MsgTypeAuth, "data": map[string]string{"token": "not-a-jwt"}}, "invalid token"},
+		{"missing edge:connect", map[string]interface{}{"type": MsgTypeAuth,
+			"data": map[string]string{"token": "Bearer " + edgeToken(t, middleware.ScopeEdgeRegister)}}, "insufficient scope"},
+		{"missing conn_token", map[string]interface{}{"type": MsgTypeAuth,
+			"data": map[string]string{"token": edgeToken(t, middleware.ScopeEdgeConnect)}}, "missing conn_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, _, err := websocket.DefaultDialer.Dial(wsURL(srv, "?auth=deferred&node_id=node-1"), nil)
+			if err != nil {
+				t.Fatalf("deferred handshake: %v", err)
+			}
+			defer conn.Close()
+			switch first := tt.first.(type) {
+			case nil:
+			case string:
+				conn.WriteMessage(websocket.TextMessage, []byte(first))
+			default:
+				conn.WriteJSON(first)
+			}
+
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, _, err = conn.ReadMessage()
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation || closeErr.Text != tt.reason {
+				t.Fatalf("read after %s = %v, want policy violation %q", tt.name, err, tt.reason)
+			}
+		})
+	}
+	if count := manager.GetConnectionCount(); count != 0 {
+		t.Fatalf("%d connections registered", count)
+	}
+}
+
+// TestDeferredAuthOversizedFirstFrame 认证前只接受较小的首帧
+func TestDeferredAuthOversizedFirstFrame(t *testing.T) {
+	srv, _ := newAuthTestServer(t, time.Second)
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(srv, "?auth=deferred"), nil)
+	if err != nil {
+		t.Fatalf("deferred handshake: %v", err)
+	}
+	defer conn.Close()
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"auth","data":{"token":"`+strings.Repeat("x", maxAuthMessageSize)+`"}}`))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("connection still open after an oversized auth frame")
+	}
+}
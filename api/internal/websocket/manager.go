@@ -2,11 +2,15 @@ package websocket
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
 	"fly-print-cloud/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 )
 
 // ConnectionManager 管理所有 WebSocket 连接
@@ -16,6 +20,15 @@ type ConnectionManager struct {
 	register    chan *Connection      // 新连接注册
 	unregister  chan *Connection      // 连接断开
 	mutex       sync.RWMutex         // 并发安全
+
+	replacements map[string]*replacementState // node_id -> 连接替换记录，节点断开后保留
+}
+
+// replacementState 节点最近的来源地址和被不同地址连接替换的记录
+type replacementState struct {
+	lastAddr   string               // 最近一次注册的连接的来源地址
+	replacedAt []time.Time          // 窗口内被不同来源地址替换的时间
+	addrs      map[string]time.Time // 参与替换的来源地址 -> 最近一次出现时间
 }
 
 // NewConnectionManager 创建连接管理器
@@ -25,6 +38,7 @@ func NewConnectionManager() *ConnectionManager {
 		broadcast:   make(chan []byte),
 		register:    make(chan *Connection),
 		unregister:  make(chan *Connection),
+		replacements: make(map[string]*replacementState),
 	}
 }
 
@@ -52,10 +66,11 @@ func (m *ConnectionManager) registerConnection(conn *Connection) {
 	// 如果已有连接，先关闭旧连接
 	if existingConn, exists := m.connections[conn.NodeID]; exists {
 		log.Printf("Replacing existing connection for node %s", conn.NodeID)
-		close(existingConn.Send)
+		existingConn.closeSend()
 	}
 
 	m.connections[conn.NodeID] = conn
+	m.replacementStateOf(conn.NodeID).lastAddr = conn.RemoteAddr
 	log.Printf("Edge Node %s connected, total connections: %d", conn.NodeID, len(m.connections))
 }
 
@@ -64,30 +79,93 @@ func (m *ConnectionManager) unregisterConnection(conn *Connection) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	if _, exists := m.connections[conn.NodeID]; exists {
+	// 只注销当前登记的连接，避免旧连接退出时误删节点重连后的新连接
+	if existing, exists := m.connections[conn.NodeID]; exists && existing == conn {
 		delete(m.connections, conn.NodeID)
-		// 安全关闭channel，避免重复关闭
-		select {
-		case <-conn.Send:
-			// channel已经关闭
-		default:
-			close(conn.Send)
-		}
+		conn.closeSend()
 		log.Printf("Edge Node %s disconnected, total connections: %d", conn.NodeID, len(m.connections))
 	}
 }
 
+// replacementStateOf 获取节点的连接替换记录，调用方需持有写锁
+func (m *ConnectionManager) replacementStateOf(nodeID string) *replacementState {
+	state, exists := m.replacements[nodeID]
+	if !exists {
+		state = &replacementState{addrs: make(map[string]time.Time)}
+		m.replacements[nodeID] = state
+	}
+	return state
+}
+
+// ReplacementCheck 新连接对节点已有连接的替换情况
+type ReplacementCheck struct {
+	Replacing    bool     // 节点已有来自其他地址的连接
+	PreviousAddr string   // 已有连接的来源地址
+	Replacements int      // 窗口内被不同来源地址替换的次数（含本次）
+	RemoteAddrs  []string // 窗口内参与替换的来源地址
+}
+
+// CheckReplacement 在注册新连接前记录其是否替换来自其他地址的已有连接，并统计 window 内的替换次数
+// 同一 node_id 的两台机器（如克隆的虚拟机镜像）会不断互相替换连接；同一地址重连不计入
+func (m *ConnectionManager) CheckReplacement(nodeID, remoteAddr string, now time.Time, window time.Duration) ReplacementCheck {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	state := m.replacementStateOf(nodeID)
+	cutoff := now.Add(-window)
+	kept := state.replacedAt[:0]
+	for _, t := range state.replacedAt {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	state.replacedAt = kept
+	for addr, seen := range state.addrs {
+		if !seen.After(cutoff) {
+			delete(state.addrs, addr)
+		}
+	}
+
+	if _, exists := m.connections[nodeID]; !exists || state.lastAddr == "" || state.lastAddr == remoteAddr {
+		return ReplacementCheck{}
+	}
+
+	state.replacedAt = append(state.replacedAt, now)
+	state.addrs[state.lastAddr] = now
+	state.addrs[remoteAddr] = now
+	check := ReplacementCheck{
+		Replacing:    true,
+		PreviousAddr: state.lastAddr,
+		Replacements: len(state.replacedAt),
+		RemoteAddrs:  make([]string, 0, len(state.addrs)),
+	}
+	for addr := range state.addrs {
+		check.RemoteAddrs = append(check.RemoteAddrs, addr)
+	}
+	sort.Strings(check.RemoteAddrs)
+	return check
+}
+
+// ResetReplacements 清除节点的连接替换记录（管理员确认冲突已解决后重新统计）
+func (m *ConnectionManager) ResetReplacements(nodeID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if state, exists := m.replacements[nodeID]; exists {
+		state.replacedAt = nil
+		state.addrs = make(map[string]time.Time)
+	}
+}
+
 // broadcastMessage 广播消息到所有连接
 func (m *ConnectionManager) broadcastMessage(message []byte) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
 	for nodeID, conn := range m.connections {
-		select {
-		case conn.Send <- message:
-		default:
+		if !conn.trySend(message) {
 			log.Printf("Failed to send broadcast message to node %s, closing connection", nodeID)
-			close(conn.Send)
+			conn.closeSend()
 			delete(m.connections, nodeID)
 		}
 	}
@@ -103,12 +181,10 @@ func (m *ConnectionManager) SendToNode(nodeID string, message []byte) error {
 		return ErrNodeNotConnected
 	}
 
-	select {
-	case conn.Send <- message:
-		return nil
-	default:
-		return ErrConnectionClosed
+	if !conn.trySend(message) {
+		return ErrSendBufferFull
 	}
+	return nil
 }
 
 // GetConnectedNodes 获取已连接的节点列表
@@ -132,6 +208,159 @@ func (m *ConnectionManager) IsNodeConnected(nodeID string) bool {
 	return exists
 }
 
+// ConnectionInfo 节点 WebSocket 连接状态
+type ConnectionInfo struct {
+	Connected      bool
+	ConnectedSince time.Time
+	LastMessageAt  time.Time
+	ClockSkew      time.Duration // 节点时钟偏差（节点时间 - 服务端时间）
+	HasClockSkew   bool
+}
+
+// GetConnectionInfo 获取单个节点的连接状态
+func (m *ConnectionManager) GetConnectionInfo(nodeID string) ConnectionInfo {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	conn, exists := m.connections[nodeID]
+	if !exists {
+		return ConnectionInfo{}
+	}
+	return connectionInfoOf(conn)
+}
+
+// SnapshotConnections 一次性获取全部节点的连接状态，避免逐个节点加锁
+func (m *ConnectionManager) SnapshotConnections() map[string]ConnectionInfo {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	snapshot := make(map[string]ConnectionInfo, len(m.connections))
+	for nodeID, conn := range m.connections {
+		snapshot[nodeID] = connectionInfoOf(conn)
+	}
+	return snapshot
+}
+
+// connectionInfoOf 从连接中提取状态信息
+func connectionInfoOf(conn *Connection) ConnectionInfo {
+	skew, hasSkew := conn.ClockSkew()
+	return ConnectionInfo{
+		Connected:      true,
+		ConnectedSince: conn.ConnectedAt,
+		LastMessageAt:  conn.LastMessageAt(),
+		ClockSkew:      skew,
+		HasClockSkew:   hasSkew,
+	}
+}
+
+// SessionInfo WebSocket 会话详情（管理员查看）
+type SessionInfo struct {
+	NodeID           string             `json:"node_id"`
+	RemoteAddr       string             `json:"remote_addr"`
+	Client           *models.ClientInfo `json:"client,omitempty"` // 建立连接时上报的客户端信息
+	ConnectedAt      time.Time          `json:"connected_at"`
+	LastMessageAt    time.Time          `json:"last_message_at"`
+	LastAppHeartbeat *time.Time         `json:"last_app_heartbeat"` // 最近一次应用层心跳，尚未收到时为 null
+	MessagesIn       int64              `json:"messages_in"`
+	MessagesOut      int64              `json:"messages_out"`
+	MessagesDropped  int64              `json:"messages_dropped"` // 因限速丢弃的消息数
+	DroppedByType    map[string]int64   `json:"dropped_by_type"`  // 按消息类型统计的丢弃数
+	QueueDepth       int                `json:"queue_depth"`      // 待发送消息数
+}
+
+// ListSessions 列出全部在线 WebSocket 会话
+func (m *ConnectionManager) ListSessions() []SessionInfo {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	sessions := make([]SessionInfo, 0, len(m.connections))
+	for nodeID, conn := range m.connections {
+		in, out := conn.MessageCounts()
+		session := SessionInfo{
+			NodeID:        nodeID,
+			RemoteAddr:    conn.RemoteAddr,
+			Client:        conn.Client,
+			ConnectedAt:   conn.ConnectedAt,
+			LastMessageAt: conn.LastMessageAt(),
+			MessagesIn:    in,
+			MessagesOut:   out,
+			QueueDepth:    len(conn.Send),
+			DroppedByType: conn.DroppedCounts(),
+		}
+		for _, n := range session.DroppedByType {
+			session.MessagesDropped += n
+		}
+		if heartbeat := conn.LastAppHeartbeat(); !heartbeat.IsZero() {
+			session.LastAppHeartbeat = &heartbeat
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// CloseConnection 强制关闭指定节点的会话，向节点发送 policy violation 关闭帧
+// 节点随后可立即重连
+func (m *ConnectionManager) CloseConnection(nodeID string, reason string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	conn, exists := m.connections[nodeID]
+	if !exists {
+		return ErrNodeNotConnected
+	}
+
+	conn.closeCode = websocket.ClosePolicyViolation
+	conn.closeReason = reason
+	delete(m.connections, nodeID)
+	conn.closeSend()
+	log.Printf("Edge Node %s connection closed by admin: %s, total connections: %d", nodeID, reason, len(m.connections))
+	return nil
+}
+
+// closeForPolicyViolation 以 policy violation 关闭指定连接（如持续超过消息限速）
+// 连接已被替换或已关闭时不做处理，避免误关节点重连后的新连接
+func (m *ConnectionManager) closeForPolicyViolation(conn *Connection, reason string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if existing, exists := m.connections[conn.NodeID]; !exists || existing != conn {
+		return
+	}
+
+	conn.closeCode = websocket.ClosePolicyViolation
+	conn.closeReason = reason
+	delete(m.connections, conn.NodeID)
+	conn.closeSend()
+	log.Printf("Edge Node %s connection closed: %s, total connections: %d", conn.NodeID, reason, len(m.connections))
+}
+
+// CloseStaleConnections 关闭在 now 之前 maxSilence 内没有应用层心跳的连接（刚建立、尚未发送心跳的连接从建立时间起算），返回被关闭的节点ID
+// 节点随后重连时按新连接处理
+func (m *ConnectionManager) CloseStaleConnections(now time.Time, maxSilence time.Duration) []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var closed []string
+	for nodeID, conn := range m.connections {
+		last := conn.LastAppHeartbeat()
+		if last.IsZero() {
+			last = conn.ConnectedAt
+		}
+		if now.Sub(last) < maxSilence {
+			continue
+		}
+
+		conn.closeCode = websocket.CloseGoingAway
+		conn.closeReason = "heartbeat timeout"
+		delete(m.connections, nodeID)
+		conn.closeSend()
+		closed = append(closed, nodeID)
+		log.Printf("Edge Node %s connection closed: no application heartbeat since %s, total connections: %d",
+			nodeID, last.Format(time.RFC3339), len(m.connections))
+	}
+	return closed
+}
+
 // GetConnectionCount 获取连接数量
 func (m *ConnectionManager) GetConnectionCount() int {
 	m.mutex.RLock()
@@ -141,23 +370,66 @@ func (m *ConnectionManager) GetConnectionCount() int {
 }
 
 
+// DispatchResult 打印任务指令已写入节点连接的发送缓冲区
+type DispatchResult struct {
+	CommandID    string
+	DispatchedAt time.Time
+}
+
+// marshalCommand 编码指令，编码错误和编码时的 panic 均返回 ErrMarshal
+func marshalCommand(command Command) (message []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			message = nil
+			err = fmt.Errorf("%w: panic: %v", ErrMarshal, r)
+		}
+	}()
+
+	message, err = json.Marshal(command)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMarshal, err)
+	}
+	return message, nil
+}
+
 // DispatchPrintJob 分发打印任务到指定Edge Node
-func (m *ConnectionManager) DispatchPrintJob(nodeID string, job *models.PrintJob, printerName string) error {
+// driver 为按打印机型号解析出的驱动，可为空；失败时返回 ErrNodeNotConnected、ErrSendBufferFull 或 ErrMarshal
+func (m *ConnectionManager) DispatchPrintJob(nodeID string, job *models.PrintJob, printerName string, driver *models.PrinterDriver) (*DispatchResult, error) {
 	// 构造打印任务数据
 	printJobData := PrintJobData{
 		JobID:       job.ID,
 		Name:        job.Name,
+		DisplayName: job.DisplayName,
 		PrinterID:   job.PrinterID,
 		PrinterName: printerName,
 		FilePath:    job.FilePath,
 		FileURL:     job.FileURL,
 		FileSize:    job.FileSize,
 		PageCount:   job.PageCount,
+		PageRange:   job.PageRange,
 		Copies:      job.Copies,
 		PaperSize:   job.PaperSize,
 		ColorMode:   job.ColorMode,
 		DuplexMode:  job.DuplexMode,
+		MediaType:   job.MediaType,
+		Resolution:  job.Resolution,
 		MaxRetries:  job.MaxRetries,
+		Metadata:    job.Metadata,
+	}
+	for _, file := range job.Files {
+		printJobData.Files = append(printJobData.Files, PrintJobFileData{
+			Position:  file.Position,
+			FilePath:  file.FilePath,
+			FileURL:   file.FileURL,
+			FileSize:  file.FileSize,
+			PageCount: file.PageCount,
+			Copies:    file.Copies,
+		})
+	}
+	if driver != nil {
+		printJobData.DriverID = driver.ID
+		printJobData.DriverName = driver.DriverName
+		printJobData.DriverOptions = driver.Options
 	}
 
 	// 构造指令消息
@@ -170,11 +442,75 @@ func (m *ConnectionManager) DispatchPrintJob(nodeID string, job *models.PrintJob
 	}
 
 	// 序列化消息
+	message, err := marshalCommand(command)
+	if err != nil {
+		return nil, err
+	}
+
+	// 发送到指定节点
+	if err := m.SendToNode(nodeID, message); err != nil {
+		return nil, err
+	}
+	return &DispatchResult{CommandID: command.CommandID, DispatchedAt: command.Timestamp}, nil
+}
+
+// RequestDiagnostics 通知 Edge Node 收集并上传诊断包
+func (m *ConnectionManager) RequestDiagnostics(nodeID string, data DiagnosticsRequestData) error {
+	command := Command{
+		Type:      CmdTypeCollectDiagnostics,
+		CommandID: data.RequestID,
+		Timestamp: time.Now(),
+		Target:    nodeID,
+		Data:      data,
+	}
+
+	message, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+
+	return m.SendToNode(nodeID, message)
+}
+
+
+// SendPrinterQueue 通知 Edge Node 暂停（pause_queue）或恢复（resume_queue）打印机的本地队列
+func (m *ConnectionManager) SendPrinterQueue(nodeID, cmdType string, data PrinterQueueData) error {
+	message, err := json.Marshal(newPrinterQueueCommand(cmdType, data))
+	if err != nil {
+		return err
+	}
+	return m.SendToNode(nodeID, message)
+}
+
+// newPrinterQueueCommand 创建打印机队列暂停/恢复指令
+func newPrinterQueueCommand(cmdType string, data PrinterQueueData) *Command {
+	return &Command{
+		Type:      cmdType,
+		CommandID: uuid.New().String(),
+		Timestamp: time.Now(),
+		Target:    data.PrinterID,
+		Data:      data,
+	}
+}
+
+// SendPrinterPower 通知 Edge Node 让打印机休眠或唤醒
+func (m *ConnectionManager) SendPrinterPower(nodeID, printerID, printerName, action string) error {
+	command := Command{
+		Type:      CmdTypePrinterPower,
+		CommandID: uuid.New().String(),
+		Timestamp: time.Now(),
+		Target:    printerID,
+		Data: PrinterPowerData{
+			PrinterID:   printerID,
+			PrinterName: printerName,
+			Action:      action,
+		},
+	}
+
 	message, err := json.Marshal(command)
 	if err != nil {
 		return err
 	}
 
-	// 发送到指定节点
 	return m.SendToNode(nodeID, message)
 }
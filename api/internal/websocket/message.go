@@ -1,14 +1,20 @@
 package websocket
 
-import "time"
+import (
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
 
 
 // 基础消息格式
 type Message struct {
 	Type      string      `json:"type"`
 	NodeID    string      `json:"node_id"`
-	Timestamp time.Time   `json:"timestamp"`
+	Timestamp time.Time   `json:"timestamp"` // 节点本地时间，可能存在偏差，仅用于估算时钟偏差
 	Data      interface{} `json:"data"`
+
+	ReceivedAt time.Time `json:"-"` // 服务端接收时间，持久化与排序均以此为准
 }
 
 // 上行消息类型
@@ -16,6 +22,8 @@ const (
 	MsgTypeHeartbeat     = "edge_heartbeat"
 	MsgTypePrinterStatus = "printer_status"
 	MsgTypeJobUpdate     = "job_update"
+	MsgTypeAuth          = "auth" // 延迟认证（?auth=deferred）时连接后的第一帧
+	MsgTypeLogBatch      = "log_batch" // Agent 日志，仅在收到 set_log_streaming 开启后上报
 )
 
 // 下行指令类型
@@ -23,6 +31,15 @@ const (
 	CmdTypePrintJob     = "print_job"
 	CmdTypeConfigUpdate = "config_update"
 	CmdTypeReportStatus = "report_status"
+	CmdTypeCollectDiagnostics = "collect_diagnostics"
+	CmdTypePrinterPower       = "printer_power"
+	CmdTypeError              = "error" // 上行消息被拒绝
+	CmdTypeWelcome            = "welcome" // 连接建立后立即下发，data 为 ServerCapabilities
+	CmdTypeSetLogStreaming    = "set_log_streaming" // 开始/停止上报 Agent 日志
+	// 暂停/恢复打印机在节点本地的队列（已开始打印的任务继续）。节点重连后未再收到 pause_queue 的打印机视为已恢复：
+	// 云端在 welcome 之后为仍处于暂停的打印机重新下发 pause_queue
+	CmdTypePauseQueue  = "pause_queue"
+	CmdTypeResumeQueue = "resume_queue"
 )
 
 // 指令消息格式
@@ -44,6 +61,13 @@ type CommandAck struct {
 	Message   string    `json:"message"`
 }
 
+// 延迟认证消息数据
+type AuthData struct {
+	Token      string             `json:"token"`                 // bearer token，可带 "Bearer " 前缀
+	ClientInfo *models.ClientInfo `json:"client_info,omitempty"` // 可选，Agent 名称/版本/平台，优先于握手请求的 X-Client 请求头
+	ConnToken  string             `json:"conn_token,omitempty"`  // 可选，注册或心跳响应中的连接令牌，未在 ?conn_token= 中携带时使用
+}
+
 // 心跳数据
 type HeartbeatData struct {
 	SystemInfo SystemInfo `json:"system_info"`
@@ -59,34 +83,122 @@ type SystemInfo struct {
 
 // 打印机状态数据
 type PrinterStatusData struct {
-	PrinterID   string            `json:"printer_id"`
-	Status      string            `json:"status"`
+	PrinterID   string               `json:"printer_id"`
+	Status      models.PrinterStatus `json:"status"`
 	QueueLength int               `json:"queue_length"`
 	ErrorCode   *string           `json:"error_code"`
 	Supplies    map[string]interface{} `json:"supplies"`
+	PowerState  string               `json:"power_state,omitempty"` // awake/asleep，旧版本节点不上报
 }
 
 // 任务状态更新数据
 type JobUpdateData struct {
-	JobID        string  `json:"job_id"`
-	Status       string  `json:"status"`
+	JobID        string           `json:"job_id"`
+	Status       models.JobStatus `json:"status"`
 	Progress     int     `json:"progress"`
 	ErrorMessage *string `json:"error_message"`
+	ErrorCode    *string `json:"error_code,omitempty"` // 可选，节点上报的错误码（如 CUPS/IPP 状态码），记录到任务时间线
+	PagesPrinted *int    `json:"pages_printed,omitempty"` // 已输出的页数（各份累计），partially_completed 时必填；大于 0 的任务失败后云端不重试也不转移到备用打印机
 }
 
 // 打印任务分发数据
 type PrintJobData struct {
 	JobID       string `json:"job_id"`
 	Name        string `json:"name"`
+	DisplayName string `json:"display_name,omitempty"` // 面向用户的名称，本地队列和释放站优先显示（旧版 Agent 忽略，显示 name）
 	PrinterID   string `json:"printer_id"`
 	PrinterName string `json:"printer_name"`
 	FilePath    string `json:"file_path,omitempty"`
 	FileURL     string `json:"file_url,omitempty"`
 	FileSize    int64  `json:"file_size"`
 	PageCount   int    `json:"page_count"`
+	PageRange   string `json:"page_range,omitempty"` // 只打印的页码（如 1-5,8），为空时打印全部
 	Copies      int    `json:"copies"`
 	PaperSize   string `json:"paper_size"`
 	ColorMode   string `json:"color_mode"`
 	DuplexMode  string `json:"duplex_mode"`
+	MediaType   string `json:"media_type,omitempty"`
+	Resolution  string `json:"resolution,omitempty"`
 	MaxRetries  int    `json:"max_retries"`
+
+	// 多文件任务按顺序打印的文件列表；为空时使用上面的单文件字段
+	Files []PrintJobFileData `json:"files,omitempty"`
+
+	// 驱动信息（按打印机型号解析，避免 Agent 二次查询）
+	DriverID      string            `json:"driver_id,omitempty"`
+	DriverName    string            `json:"driver_name,omitempty"`
+	DriverOptions map[string]string `json:"driver_options,omitempty"`
+
+	// 调用方自定义的任务标签，Agent 可用于打印页眉或本地记账
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// 打印任务中的单个文件
+type PrintJobFileData struct {
+	Position  int    `json:"position"`
+	FilePath  string `json:"file_path,omitempty"`
+	FileURL   string `json:"file_url,omitempty"`
+	FileSize  int64  `json:"file_size"`
+	PageCount int    `json:"page_count"`
+	Copies    int    `json:"copies"`
+}
+
+// 诊断包收集指令数据
+type DiagnosticsRequestData struct {
+	RequestID string    `json:"request_id"`
+	UploadURL string    `json:"upload_url"` // 相对路径，节点需携带自身 token 上传
+	MaxSize   int64     `json:"max_size"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+
+// 错误码
+const (
+	ErrCodeInsufficientScope = "insufficient_scope"
+	ErrCodeRateLimited       = "rate_limited" // 消息超过限速，超出部分已被丢弃
+	ErrCodeSchemaViolation   = "schema_violation" // 开启 edge.validate_messages 时消息不符合 Schema，已被丢弃
+	ErrCodeInvalidMessage    = "invalid_message" // 消息无法解析（非 UTF-8、非 JSON、缺少 type 或 data 字段类型错误），已被丢弃
+)
+
+// 上行消息被拒绝时返回给节点的错误数据
+type ErrorData struct {
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	MessageType string `json:"message_type"` // 被拒绝的消息类型
+	Violations  []SchemaViolation `json:"violations,omitempty"` // schema_violation 时的具体错误
+}
+
+// 打印机电源指令数据
+type PrinterPowerData struct {
+	PrinterID   string `json:"printer_id"`
+	PrinterName string `json:"printer_name"`
+	Action      string `json:"action"` // sleep/wake
+}
+
+// 打印机队列暂停/恢复指令数据
+type PrinterQueueData struct {
+	PrinterID   string `json:"printer_id"`
+	PrinterName string `json:"printer_name"`
+	Reason      string `json:"reason,omitempty"` // 暂停原因（仅 pause_queue）
+}
+
+// Agent 日志批量上报数据
+type LogBatchData struct {
+	Lines   []LogLine `json:"lines"`             // 按时间顺序排列，最多 MaxBatchLines 行
+	Dropped int       `json:"dropped,omitempty"` // 节点因本地缓冲已满而丢弃的行数
+}
+
+// 单行 Agent 日志
+type LogLine struct {
+	Level      string    `json:"level"` // debug/info/warn/error
+	Timestamp  time.Time `json:"ts"`    // 节点本地时间
+	Message    string    `json:"message"`
+	ReceivedAt time.Time `json:"received_at,omitempty"` // 服务端接收时间，节点无需上报
+}
+
+// 日志上报开关指令数据
+type SetLogStreamingData struct {
+	Enabled       bool `json:"enabled"`
+	MaxBatchLines int  `json:"max_batch_lines"` // 单条 log_batch 消息的行数上限，超出部分被丢弃
+	MaxLineLength int  `json:"max_line_length"` // 单行 message 的长度上限（字节），超出部分被截断
 }
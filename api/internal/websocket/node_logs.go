@@ -0,0 +1,293 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// 节点日志缓冲限制：只保存在内存中，每个节点的行数、单行长度和跟踪者数量均有上限，
+// 上报频率由 edge.rate_limit_log_batch 限制
+const (
+	MaxLogBatchLines   = 200              // 单条 log_batch 消息的行数上限，超出部分丢弃
+	MaxLogLineLength   = 2048             // 单行日志长度上限（字节），超出部分截断
+	nodeLogCapacity    = 1000             // 每个节点保留的最近日志行数
+	maxLogFollowers    = 10               // 每个节点同时跟踪日志的管理员连接数上限
+	logFollowerBuffer  = 256              // 跟踪者的待发送行数，跟不上时丢弃并计数
+	logStreamingIdle   = time.Minute      // 没有跟踪者超过该时长时通知节点停止上报
+	nodeLogRetention   = 30 * time.Minute // 没有跟踪者且超过该时长未收到日志时释放节点的缓冲
+	nodeLogCheckPeriod = 10 * time.Second
+)
+
+// ErrTooManyLogFollowers 节点的日志跟踪者已达上限
+var ErrTooManyLogFollowers = errors.New("too many log followers")
+
+// LogFollower 单个管理员连接的日志跟踪
+type LogFollower struct {
+	Lines   chan LogLine
+	dropped atomic.Int64
+}
+
+// TakeDropped 返回并清零因跟不上而丢弃的行数
+func (f *LogFollower) TakeDropped() int64 {
+	return f.dropped.Swap(0)
+}
+
+// nodeLog 单个节点的日志环形缓冲和跟踪状态
+type nodeLog struct {
+	lines      []LogLine // 环形缓冲，start 为最早一行
+	start      int
+	followers  map[*LogFollower]struct{}
+	streaming  bool      // 已通知节点开始上报
+	detachedAt time.Time // 最后一个跟踪者离开的时间
+	stoppedAt  time.Time // 最近一次通知节点停止上报的时间
+	updatedAt  time.Time // 最近一次收到日志的时间
+	dropped    int64     // 节点上报的丢弃行数和超出批量上限被丢弃的行数
+}
+
+// NodeLogs 节点 Agent 日志的内存缓冲（不落库），按需通知节点开始/停止上报
+type NodeLogs struct {
+	manager *ConnectionManager
+
+	mutex sync.Mutex
+	nodes map[string]*nodeLog // node_id -> 日志缓冲
+}
+
+// NewNodeLogs 创建节点日志缓冲
+func NewNodeLogs(manager *ConnectionManager) *NodeLogs {
+	return &NodeLogs{
+		manager: manager,
+		nodes:   make(map[string]*nodeLog),
+	}
+}
+
+// nodeLogOf 获取节点的日志缓冲，调用方需持有锁
+func (l *NodeLogs) nodeLogOf(nodeID string) *nodeLog {
+	state, exists := l.nodes[nodeID]
+	if !exists {
+		state = &nodeLog{followers: make(map[*LogFollower]struct{})}
+		l.nodes[nodeID] = state
+	}
+	return state
+}
+
+// Append 保存节点上报的一批日志并推送给跟踪者，超出批量上限的行被丢弃，过长的行被截断
+// 未被要求上报时收到的日志直接丢弃，并（最多每分钟一次）再次通知节点停止上报
+func (l *NodeLogs) Append(nodeID string, batch *LogBatchData, receivedAt time.Time) {
+	lines := batch.Lines
+	dropped := int64(batch.Dropped)
+	if len(lines) > MaxLogBatchLines {
+		dropped += int64(len(lines) - MaxLogBatchLines)
+		lines = lines[len(lines)-MaxLogBatchLines:]
+	}
+
+	l.mutex.Lock()
+	state := l.nodeLogOf(nodeID)
+	if !state.streaming {
+		stop := receivedAt.Sub(state.stoppedAt) >= logStreamingIdle
+		if stop {
+			state.stoppedAt = receivedAt
+		}
+		l.mutex.Unlock()
+		if stop {
+			log.Printf("Node %s sent log_batch while log streaming is off, asking it to stop", nodeID)
+			l.sendStreaming(nodeID, false)
+		}
+		return
+	}
+
+	state.updatedAt = receivedAt
+	state.dropped += dropped
+	for _, line := range lines {
+		line.Message = truncateLogLine(line.Message)
+		line.ReceivedAt = receivedAt
+		if len(state.lines) < nodeLogCapacity {
+			state.lines = append(state.lines, line)
+		} else {
+			state.lines[state.start] = line
+			state.start = (state.start + 1) % nodeLogCapacity
+		}
+		for follower := range state.followers {
+			select {
+			case follower.Lines <- line:
+			default:
+				follower.dropped.Add(1)
+			}
+		}
+	}
+	l.mutex.Unlock()
+}
+
+// truncateLogLine 截断过长的日志行（不截断在 UTF-8 字符中间）
+func truncateLogLine(message string) string {
+	if len(message) <= MaxLogLineLength {
+		return message
+	}
+	cut := MaxLogLineLength
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut]
+}
+
+// Tail 返回节点最近的 n 行日志（n <= 0 时返回全部缓冲）
+func (l *NodeLogs) Tail(nodeID string, n int) []LogLine {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	state, exists := l.nodes[nodeID]
+	if !exists {
+		return []LogLine{}
+	}
+	total := len(state.lines)
+	if n <= 0 || n > total {
+		n = total
+	}
+	lines := make([]LogLine, 0, n)
+	for i := total - n; i < total; i++ {
+		lines = append(lines, state.lines[(state.start+i)%total])
+	}
+	return lines
+}
+
+// Follow 跟踪节点的新日志，首个跟踪者加入时通知节点开始上报
+// 返回的函数用于停止跟踪；最后一个跟踪者离开一分钟后由 Run 通知节点停止上报
+func (l *NodeLogs) Follow(nodeID string) (*LogFollower, func(), error) {
+	l.mutex.Lock()
+	state := l.nodeLogOf(nodeID)
+	if len(state.followers) >= maxLogFollowers {
+		l.mutex.Unlock()
+		return nil, nil, ErrTooManyLogFollowers
+	}
+	follower := &LogFollower{Lines: make(chan LogLine, logFollowerBuffer)}
+	state.followers[follower] = struct{}{}
+	enable := !state.streaming
+	state.streaming = true
+	l.mutex.Unlock()
+
+	if enable {
+		l.sendStreaming(nodeID, true)
+	}
+
+	stop := func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		if _, ok := state.followers[follower]; ok {
+			delete(state.followers, follower)
+			if len(state.followers) == 0 {
+				state.detachedAt = time.Now()
+			}
+		}
+	}
+	return follower, stop, nil
+}
+
+// NodeConnected 节点（重新）建立连接后调用：新连接默认不上报日志，仍有跟踪者时通过新连接通知节点开始上报
+func (l *NodeLogs) NodeConnected(conn *Connection) {
+	l.mutex.Lock()
+	state, exists := l.nodes[conn.NodeID]
+	enable := exists && len(state.followers) > 0
+	if exists {
+		state.streaming = enable
+	}
+	l.mutex.Unlock()
+
+	if enable {
+		if err := conn.SendCommand(streamingCommand(conn.NodeID, true)); err != nil {
+			log.Printf("Failed to send set_log_streaming(true) to node %s: %v", conn.NodeID, err)
+		}
+	}
+}
+
+// Run 定期通知无人跟踪的节点停止上报，并释放长时间无人使用的缓冲（阻塞）
+func (l *NodeLogs) Run() {
+	ticker := time.NewTicker(nodeLogCheckPeriod)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for _, nodeID := range l.expire(now) {
+			l.sendStreaming(nodeID, false)
+		}
+	}
+}
+
+// expire 检查跟踪状态，返回需要停止上报的节点
+func (l *NodeLogs) expire(now time.Time) []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var idle []string
+	for nodeID, state := range l.nodes {
+		if len(state.followers) > 0 {
+			continue
+		}
+		if state.streaming && now.Sub(state.detachedAt) >= logStreamingIdle {
+			state.streaming, state.stoppedAt = false, now
+			idle = append(idle, nodeID)
+		}
+		if !state.streaming && now.Sub(state.updatedAt) >= nodeLogRetention && now.Sub(state.detachedAt) >= nodeLogRetention &&
+			now.Sub(state.stoppedAt) >= nodeLogRetention {
+			delete(l.nodes, nodeID)
+		}
+	}
+	return idle
+}
+
+// streamingCommand 构造日志上报开关指令
+func streamingCommand(nodeID string, enabled bool) *Command {
+	return &Command{
+		Type:      CmdTypeSetLogStreaming,
+		CommandID: uuid.New().String(),
+		Timestamp: time.Now(),
+		Target:    nodeID,
+		Data: SetLogStreamingData{
+			Enabled:       enabled,
+			MaxBatchLines: MaxLogBatchLines,
+			MaxLineLength: MaxLogLineLength,
+		},
+	}
+}
+
+// sendStreaming 通知节点开始/停止上报日志，节点未连接时忽略（重连后由 NodeConnected 处理）
+func (l *NodeLogs) sendStreaming(nodeID string, enabled bool) {
+	message, err := json.Marshal(streamingCommand(nodeID, enabled))
+	if err != nil {
+		log.Printf("Failed to marshal set_log_streaming command for node %s: %v", nodeID, err)
+		return
+	}
+	if err := l.manager.SendToNode(nodeID, message); err != nil && !errors.Is(err, ErrNodeNotConnected) {
+		log.Printf("Failed to send set_log_streaming(%t) to node %s: %v", enabled, nodeID, err)
+	}
+}
+
+// LogStats 节点日志缓冲统计
+type LogStats struct {
+	Buffered  int   `json:"buffered"`
+	Followers int   `json:"followers"`
+	Streaming bool  `json:"streaming"`
+	Dropped   int64 `json:"dropped"`
+}
+
+// Stats 返回节点日志缓冲统计
+func (l *NodeLogs) Stats(nodeID string) LogStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	state, exists := l.nodes[nodeID]
+	if !exists {
+		return LogStats{}
+	}
+	return LogStats{
+		Buffered:  len(state.lines),
+		Followers: len(state.followers),
+		Streaming: state.streaming,
+		Dropped:   state.dropped,
+	}
+}
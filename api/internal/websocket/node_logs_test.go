@@ -0,0 +1,288 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestNodeLogs 创建日志缓冲，并为 nodeID 注册一个可读取下发指令的连接
+func newTestNodeLogs(nodeID string) (*NodeLogs, *Connection) {
+	m := NewConnectionManager()
+	conn := &Connection{NodeID: nodeID, Send: make(chan []byte, 16)}
+	m.registerConnection(conn)
+	return NewNodeLogs(m), conn
+}
+
+// logLines 构造 n 行日志，消息为 prefix-0 … prefix-(n-1)
+func logLines(prefix string, n int) []LogLine {
+	lines := make([]LogLine, n)
+	for i := range lines {
+		lines[i] = LogLine{Level: "info", Message: fmt.Sprintf("%s-%d", prefix, i)}
+	}
+	return lines
+}
+
+// nextStreamingCommand 读取下发的 set_log_streaming 指令，没有指令时终止测试
+func nextStreamingCommand(t *testing.T, conn *Connection) SetLogStreamingData {
+	t.Helper()
+	select {
+	case message := <-conn.Send:
+		var command struct {
+			Type string              `json:"type"`
+			Data SetLogStreamingData `json:"data"`
+		}
+		if err := json.Unmarshal(message, &command); err != nil {
+			t.Fatalf("decode command: %v", err)
+		}
+		if command.Type != CmdTypeSetLogStreaming {
+			t.Fatalf("command type = %s, want %s", command.Type, CmdTypeSetLogStreaming)
+		}
+		return command.Data
+	default:
+		t.Fatal("no set_log_streaming command sent")
+		return SetLogStreamingData{}
+	}
+}
+
+// expectNoCommand 确认没有下发指令
+func expectNoCommand(t *testing.T, conn *Connection) {
+	t.Helper()
+	select {
+	case message := <-conn.Send:
+		t.Fatalf("unexpected command %s", message)
+	default:
+	}
+}
+
+// TestNodeLogsRingBuffer 缓冲只保留最近 nodeLogCapacity 行，超出批量上限的行计入丢弃数
+func TestNodeLogsRingBuffer(t *testing.T) {
+	logs, _ := newTestNodeLogs("node-1")
+	_, stop, err := logs.Follow("node-1")
+	if err != nil {
+		t.Fatalf("Follow: %v", err)
+	}
+	defer stop()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for batch := 0; batch < 6; batch++ {
+		logs.Append("node-1", &LogBatchData{Lines: logLines(fmt.Sprintf("b%d", batch), MaxLogBatchLines)}, now)
+	}
+	// 超出批量上限的 50 行丢弃，保留最后 200 行
+	logs.Append("node-1", &LogBatchData{Lines: logLines("big", MaxLogBatchLines+50), Dropped: 3}, now)
+
+	stats := logs.Stats("node-1")
+	if stats.Buffered != nodeLogCapacity || stats.Dropped != 53 {
+		t.Fatalf("stats = %+v, want %d buffered and 53 dropped", stats, nodeLogCapacity)
+	}
+	tail := logs.Tail("node-1", 0)
+	if len(tail) != nodeLogCapacity {
+		t.Fatalf("Tail(0) returned %d lines, want %d", len(tail), nodeLogCapacity)
+	}
+	// 7 批共 1400 行，最早的 400 行（b0、b1）被覆盖
+	if tail[0].Message != "b2-0" || tail[len(tail)-1].Message != fmt.Sprintf("big-%d", MaxLogBatchLines+49) {
+		t.Fatalf("tail spans %q .. %q", tail[0].Message, tail[len(tail)-1].Message)
+	}
+	if last := logs.Tail("node-1", 2); len(last) != 2 || last[1].Message != tail[len(tail)-1].Message {
+		t.Fatalf("Tail(2) = %+v", last)
+	}
+	if !tail[0].ReceivedAt.Equal(now) {
+		t.Fatalf("received_at = %v, want %v", tail[0].ReceivedAt, now)
+	}
+	if lines := logs.Tail("unknown", 10); len(lines) != 0 {
+		t.Fatalf("Tail(unknown) = %+v", lines)
+	}
+}
+
+func TestTruncateLogLine(t *testing.T) {
+	if got := truncateLogLine("short"); got != "short" {
+		t.Fatalf("truncateLogLine(short) = %q", got)
+	}
+	ascii := strings.Repeat("a", MaxLogLineLength+10)
+	if got := truncateLogLine(ascii); len(got) != MaxLogLineLength {
+		t.Fatalf("ascii line truncated to %d bytes", len(got))
+	}
+	// 3 字节的汉字跨越截断位置时整个字符被丢弃
+	multi := strings.Repeat("a", MaxLogLineLength-1) + "日志"
+	if got := truncateLogLine(multi); len(got) != MaxLogLineLength-1 {
+		t.Fatalf("multibyte line truncated to %d bytes, want %d", len(got), MaxLogLineLength-1)
+	}
+}
+
+// TestNodeLogsSlowFollower 跟不上的跟踪者丢弃新行并计数，不阻塞其他跟踪者
+func TestNodeLogsSlowFollower(t *testing.T) {
+	logs, _ := newTestNodeLogs("node-1")
+	slow, stopSlow, err := logs.Follow("node-1")
+	if err != nil {
+		t.Fatalf("Follow: %v", err)
+	}
+	defer stopSlow()
+	fast, stopFast, err := logs.Follow("node-1")
+	if err != nil {
+		t.Fatalf("Follow: %v", err)
+	}
+	defer stopFast()
+
+	now := time.Now()
+	received := 0
+	for batch := 0; batch < 3; batch++ {
+		logs.Append("node-1", &LogBatchData{Lines: logLines("line", 100)}, now)
+		for len(fast.Lines) > 0 {
+			<-fast.Lines
+			received++
+		}
+	}
+	if received != 300 || fast.TakeDropped() != 0 {
+		t.Fatalf("fast follower received %d lines", received)
+	}
+	if len(slow.Lines) != logFollowerBuffer {
+		t.Fatalf("slow follower has %d pending lines, want %d", len(slow.Lines), logFollowerBuffer)
+	}
+	if dropped := slow.TakeDropped(); dropped != 300-logFollowerBuffer {
+		t.Fatalf("slow follower dropped %d, want %d", dropped, 300-logFollowerBuffer)
+	}
+	if dropped := slow.TakeDropped(); dropped != 0 {
+		t.Fatalf("TakeDropped did not reset, got %d", dropped)
+	}
+}
+
+func TestNodeLogsFollowerLimit(t *testing.T) {
+	logs, _ := newTestNodeLogs("node-1")
+	var stops []func()
+	for i := 0; i < maxLogFollowers; i++ {
+		_, stop, err := logs.Follow("node-1")
+		if err != nil {
+			t.Fatalf("Follow #%d: %v", i, err)
+		}
+		stops = append(stops, stop)
+	}
+	if _, _, err := logs.Follow("node-1"); !errors.Is(err, ErrTooManyLogFollowers) {
+		t.Fatalf("error = %v, want ErrTooManyLogFollowers", err)
+	}
+	// 停止跟踪后腾出名额，重复调用 stop 无副作用
+	stops[0]()
+	stops[0]()
+	if _, _, err := logs.Follow("node-1"); err != nil {
+		t.Fatalf("Follow after stop: %v", err)
+	}
+	if stats := logs.Stats("node-1"); stats.Followers != maxLogFollowers {
+		t.Fatalf("followers = %d, want %d", stats.Followers, maxLogFollowers)
+	}
+}
+
+// TestNodeLogsStreamingLifecycle 首个跟踪者通知节点开始上报，最后一个跟踪者离开一分钟后通知停止
+func TestNodeLogsStreamingLifecycle(t *testing.T) {
+	logs, conn := newTestNodeLogs("node-1")
+
+	_, stopFirst, err := logs.Follow("node-1")
+	if err != nil {
+		t.Fatalf("Follow: %v", err)
+	}
+	data := nextStreamingCommand(t, conn)
+	if !data.Enabled || data.MaxBatchLines != MaxLogBatchLines || data.MaxLineLength != MaxLogLineLength {
+		t.Fatalf("start command = %+v", data)
+	}
+	// 已在上报时新的跟踪者不重复通知
+	_, stopSecond, err := logs.Follow("node-1")
+	if err != nil {
+		t.Fatalf("Follow: %v", err)
+	}
+	expectNoCommand(t, conn)
+
+	stopFirst()
+	if idle := logs.expire(time.Now().Add(2 * logStreamingIdle)); len(idle) != 0 {
+		t.Fatalf("expire with a follower attached returned %v", idle)
+	}
+	stopSecond()
+	detached := time.Now()
+
+	if idle := logs.expire(detached.Add(logStreamingIdle - time.Second)); len(idle) != 0 {
+		t.Fatalf("expire before idle period returned %v", idle)
+	}
+	idle := logs.expire(detached.Add(logStreamingIdle + time.Second))
+	if len(idle) != 1 || idle[0] != "node-1" {
+		t.Fatalf("expire after idle period returned %v, want [node-1]", idle)
+	}
+	if logs.Stats("node-1").Streaming {
+		t.Fatal("node still marked as streaming")
+	}
+	// 已停止的节点不会再次出现在停止列表中
+	if idle := logs.expire(detached.Add(2 * logStreamingIdle)); len(idle) != 0 {
+		t.Fatalf("second expire returned %v", idle)
+	}
+}
+
+// TestNodeLogsUnrequestedBatch 未要求上报时收到的日志被丢弃，停止指令最多每分钟重发一次
+func TestNodeLogsUnrequestedBatch(t *testing.T) {
+	logs, conn := newTestNodeLogs("node-1")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	logs.Append("node-1", &LogBatchData{Lines: logLines("x", 5)}, now)
+	if data := nextStreamingCommand(t, conn); data.Enabled {
+		t.Fatalf("command = %+v, want stop", data)
+	}
+	logs.Append("node-1", &LogBatchData{Lines: logLines("x", 5)}, now.Add(30*time.Second))
+	expectNoCommand(t, conn)
+	logs.Append("node-1", &LogBatchData{Lines: logLines("x", 5)}, now.Add(logStreamingIdle))
+	if data := nextStreamingCommand(t, conn); data.Enabled {
+		t.Fatalf("command = %+v, want stop", data)
+	}
+	if stats := logs.Stats("node-1"); stats.Buffered != 0 {
+		t.Fatalf("buffered %d unrequested lines", stats.Buffered)
+	}
+}
+
+// TestNodeLogsReconnect 节点重连后仍有跟踪者时通过新连接恢复上报
+func TestNodeLogsReconnect(t *testing.T) {
+	logs, _ := newTestNodeLogs("node-1")
+	_, stop, err := logs.Follow("node-1")
+	if err != nil {
+		t.Fatalf("Follow: %v", err)
+	}
+
+	reconnected := &Connection{NodeID: "node-1", Send: make(chan []byte, 4)}
+	logs.NodeConnected(reconnected)
+	if data := nextStreamingCommand(t, reconnected); !data.Enabled {
+		t.Fatalf("command = %+v, want start", data)
+	}
+
+	stop()
+	again := &Connection{NodeID: "node-1", Send: make(chan []byte, 4)}
+	logs.NodeConnected(again)
+	expectNoCommand(t, again)
+	if logs.Stats("node-1").Streaming {
+		t.Fatal("node marked as streaming after reconnect without followers")
+	}
+
+	// 从未被跟踪的节点连接时不创建缓冲
+	logs.NodeConnected(&Connection{NodeID: "node-2", Send: make(chan []byte, 4)})
+	if _, exists := logs.nodes["node-2"]; exists {
+		t.Fatal("buffer created for unfollowed node")
+	}
+}
+
+// TestNodeLogsRelease 无人跟踪且长时间无日志的缓冲被释放
+func TestNodeLogsRelease(t *testing.T) {
+	logs, _ := newTestNodeLogs("node-1")
+	_, stop, err := logs.Follow("node-1")
+	if err != nil {
+		t.Fatalf("Follow: %v", err)
+	}
+	now := time.Now()
+	logs.Append("node-1", &LogBatchData{Lines: logLines("x", 5)}, now)
+	stop()
+
+	stoppedAt := now.Add(logStreamingIdle + time.Second)
+	logs.expire(stoppedAt)
+	logs.expire(stoppedAt.Add(nodeLogRetention - time.Second))
+	if _, exists := logs.nodes["node-1"]; !exists {
+		t.Fatal("buffer released before retention period")
+	}
+	logs.expire(stoppedAt.Add(nodeLogRetention))
+	if _, exists := logs.nodes["node-1"]; exists {
+		t.Fatal("buffer not released after retention period")
+	}
+}
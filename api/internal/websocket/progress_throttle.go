@@ -0,0 +1,179 @@
+package websocket
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/models"
+)
+
+// progressIdleTTL 超过该时长没有收到更新的任务从内存中移除（节点未上报结束状态时避免无限增长）
+const progressIdleTTL = 30 * time.Minute
+
+// progressStore 任务状态和进度的持久化（由 database.PrintJobRepository 实现）
+type progressStore interface {
+	UpdateJobStatus(jobID string, status models.JobStatus, progress int, receivedAt time.Time) (bool, error)
+	UpdateJobProgress(jobID string, status models.JobStatus, progress int, receivedAt time.Time) (bool, error)
+}
+
+// jobProgress 单个任务在内存中的最新状态和进度
+type jobProgress struct {
+	status     models.JobStatus
+	progress   int
+	receivedAt time.Time // 最新进度的接收时间
+	writtenAt  time.Time // 最近一次写入数据库的时间
+	dirty      bool      // 最新进度尚未写入数据库
+	milestone  int       // 已记录的进度里程碑
+}
+
+// ProgressUpdate 一条任务更新的处理结果
+type ProgressUpdate struct {
+	StatusChanged bool // 状态与内存中记录的不同（或首次收到该任务的更新），已立即写入
+	Applied       bool // 状态变化已写入；过期的更新为 false
+	Persisted     bool // 本次更新已写入数据库
+	Progress      int  // 当前进度（结束状态未携带进度时为内存中的最新值）
+	Milestone     bool // 进度到达新的里程碑，需要记录时间线事件
+}
+
+// ProgressThrottle 节流任务进度的数据库写入
+// 状态变化立即写入；状态不变时同一任务最多每隔 jobs.progress_write_interval 写入一次，
+// 其余进度只保存在内存中，由 Run 定期补写，结束状态写入时使用内存中的最新进度
+type ProgressThrottle struct {
+	store    progressStore
+	settings *config.Store // 写入间隔支持热更新，每次使用时读取
+
+	mutex sync.Mutex
+	jobs  map[string]*jobProgress // job_id -> 最新状态和进度
+}
+
+// NewProgressThrottle 创建任务进度节流器
+func NewProgressThrottle(store progressStore, settings *config.Store) *ProgressThrottle {
+	return &ProgressThrottle{
+		store:    store,
+		settings: settings,
+		jobs:     make(map[string]*jobProgress),
+	}
+}
+
+// interval 同一任务两次进度写入的最小间隔
+func (t *ProgressThrottle) interval() time.Duration {
+	return t.settings.Get().Jobs.ProgressWriteInterval
+}
+
+// Update 处理节点上报的任务状态和进度，receivedAt 为服务端接收时间
+func (t *ProgressThrottle) Update(jobID string, status models.JobStatus, progress int, receivedAt time.Time) (ProgressUpdate, error) {
+	t.mutex.Lock()
+	state, known := t.jobs[jobID]
+	if !known {
+		state = &jobProgress{}
+		t.jobs[jobID] = state
+	}
+	// 结束状态未携带进度时保留已收到的最新进度
+	if progress == 0 && status.IsTerminal() {
+		progress = state.progress
+	}
+	update := ProgressUpdate{
+		StatusChanged: !known || state.status != status,
+		Progress:      progress,
+	}
+	if milestone := progress / models.JobProgressMilestone * models.JobProgressMilestone; milestone > state.milestone {
+		state.milestone = milestone
+		update.Milestone = true
+	}
+	changed := update.StatusChanged || progress != state.progress
+	state.status, state.progress, state.receivedAt = status, progress, receivedAt
+	persist := update.StatusChanged || changed && receivedAt.Sub(state.writtenAt) >= t.interval()
+	if persist {
+		state.writtenAt, state.dirty = receivedAt, false
+	} else if changed {
+		state.dirty = true
+	}
+	if status.IsTerminal() {
+		delete(t.jobs, jobID)
+	}
+	t.mutex.Unlock()
+
+	if update.StatusChanged {
+		applied, err := t.store.UpdateJobStatus(jobID, status, progress, receivedAt)
+		if err != nil || !applied {
+			// 写入失败或过期的更新：下一条更新按状态变化重新写入
+			t.forget(jobID, state)
+		}
+		update.Applied, update.Persisted = applied, applied
+		return update, err
+	}
+	if persist {
+		if _, err := t.store.UpdateJobProgress(jobID, status, progress, receivedAt); err != nil {
+			return update, err
+		}
+		update.Persisted = true
+	}
+	return update, nil
+}
+
+// forget 移除任务在内存中的状态（仅当仍为 state 时）
+func (t *ProgressThrottle) forget(jobID string, state *jobProgress) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.jobs[jobID] == state {
+		delete(t.jobs, jobID)
+	}
+}
+
+// Run 定期补写被节流的进度，保证数据库中的进度最多落后一个写入间隔（阻塞）
+func (t *ProgressThrottle) Run() {
+	interval := t.flushInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.Flush(time.Now())
+
+		// 写入间隔被热更新时重置定时器
+		if next := t.flushInterval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// flushInterval 补写检查间隔，未开启节流时仅清理空闲任务
+func (t *ProgressThrottle) flushInterval() time.Duration {
+	if interval := t.interval(); interval > 0 {
+		return interval
+	}
+	return time.Minute
+}
+
+// Flush 写入在 now 之前超过写入间隔仍未持久化的进度，并移除长时间没有更新的任务
+func (t *ProgressThrottle) Flush(now time.Time) {
+	type pending struct {
+		jobID      string
+		status     models.JobStatus
+		progress   int
+		receivedAt time.Time
+	}
+
+	interval := t.interval()
+	var due []pending
+	t.mutex.Lock()
+	for jobID, state := range t.jobs {
+		if state.dirty && now.Sub(state.writtenAt) >= interval {
+			due = append(due, pending{jobID, state.status, state.progress, state.receivedAt})
+			state.writtenAt, state.dirty = now, false
+		}
+		if !state.dirty && now.Sub(state.receivedAt) >= progressIdleTTL {
+			delete(t.jobs, jobID)
+		}
+	}
+	t.mutex.Unlock()
+
+	for _, p := range due {
+		if _, err := t.store.UpdateJobProgress(p.jobID, p.status, p.progress, p.receivedAt); err != nil {
+			log.Printf("Failed to flush progress of job %s: %v", p.jobID, err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package websocket
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/models"
+)
+
+// progressWrite 一次数据库写入
+type progressWrite struct {
+	jobID       string
+	status      models.JobStatus
+	progress    int
+	statusWrite bool // UpdateJobStatus（否则为 UpdateJobProgress）
+}
+
+// fakeProgressStore 记录写入，staleStatus 为 true 时状态写入按过期处理
+type fakeProgressStore struct {
+	mutex       sync.Mutex
+	writes      []progressWrite
+	staleStatus bool
+}
+
+func (s *fakeProgressStore) UpdateJobStatus(jobID string, status models.JobStatus, progress int, _ time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.staleStatus {
+		return false, nil
+	}
+	s.writes = append(s.writes, progressWrite{jobID, status, progress, true})
+	return true, nil
+}
+
+func (s *fakeProgressStore) UpdateJobProgress(jobID string, status models.JobStatus, progress int, _ time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.writes = append(s.writes, progressWrite{jobID, status, progress, false})
+	return true, nil
+}
+
+// counts 返回状态写入和进度写入的次数
+func (s *fakeProgressStore) counts() (statusWrites, progressWrites int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, w := range s.writes {
+		if w.statusWrite {
+			statusWrites++
+		} else {
+			progressWrites++
+		}
+	}
+	return statusWrites, progressWrites
+}
+
+func (s *fakeProgressStore) last() progressWrite {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.writes[len(s.writes)-1]
+}
+
+func newTestThrottle(interval time.Duration) (*ProgressThrottle, *fakeProgressStore) {
+	cfg := &config.Config{}
+	cfg.Jobs.ProgressWriteInterval = interval
+	store := &fakeProgressStore{}
+	return NewProgressThrottle(store, config.NewStore(cfg)), store
+}
+
+// mustUpdate 发送一条任务更新，失败时终止测试
+func mustUpdate(t *testing.T, throttle *ProgressThrottle, status models.JobStatus, progress int, at time.Time) ProgressUpdate {
+	t.Helper()
+	update, err := throttle.Update("job-1", status, progress, at)
+	if err != nil {
+		t.Fatalf("Update(%s, %d): %v", status, progress, err)
+	}
+	return update
+}
+
+// TestProgressThrottleBurst 100 条间隔 100ms 的进度更新在 5 秒写入间隔下只写入两次
+func TestProgressThrottleBurst(t *testing.T) {
+	throttle, store := newTestThrottle(5 * time.Second)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if update := mustUpdate(t, throttle, models.JobStatusPrinting, 0, start); !update.StatusChanged || !update.Persisted {
+		t.Fatalf("first update = %+v, want status change written immediately", update)
+	}
+
+	persisted := 0
+	for i := 1; i <= 100; i++ {
+		if mustUpdate(t, throttle, models.JobStatusPrinting, i, start.Add(time.Duration(i)*100*time.Millisecond)).Persisted {
+			persisted++
+		}
+	}
+	statusWrites, progressWrites := store.counts()
+	if statusWrites != 1 || progressWrites != 2 || persisted != 2 {
+		t.Fatalf("writes: status %d, progress %d (persisted %d); want 1 and 2", statusWrites, progressWrites, persisted)
+	}
+	if last := store.last(); last.progress != 100 {
+		t.Fatalf("last progress write = %d, want 100 (at 10s)", last.progress)
+	}
+
+	// 完成状态未携带进度时使用内存中的最新进度，并立即写入
+	update := mustUpdate(t, throttle, models.JobStatusCompleted, 0, start.Add(11*time.Second))
+	if !update.StatusChanged || !update.Persisted || update.Progress != 100 {
+		t.Fatalf("completion update = %+v, want persisted with progress 100", update)
+	}
+	if last := store.last(); !last.statusWrite || last.status != models.JobStatusCompleted || last.progress != 100 {
+		t.Fatalf("completion write = %+v", last)
+	}
+}
+
+// TestProgressThrottleSameInstantBurst 同一时刻的 100 条进度只保留在内存中，由 Flush 补写最新值
+func TestProgressThrottleSameInstantBurst(t *testing.T) {
+	throttle, store := newTestThrottle(5 * time.Second)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mustUpdate(t, throttle, models.JobStatusPrinting, 0, now)
+
+	for i := 1; i <= 100; i++ {
+		mustUpdate(t, throttle, models.JobStatusPrinting, i, now.Add(time.Millisecond))
+	}
+	if _, progressWrites := store.counts(); progressWrites != 0 {
+		t.Fatalf("progress writes = %d, want 0 within interval", progressWrites)
+	}
+
+	throttle.Flush(now.Add(4 * time.Second))
+	if _, progressWrites := store.counts(); progressWrites != 0 {
+		t.Fatalf("flush before interval wrote %d times", progressWrites)
+	}
+	throttle.Flush(now.Add(5 * time.Second))
+	if _, progressWrites := store.counts(); progressWrites != 1 || store.last().progress != 100 {
+		t.Fatalf("flush wrote %+v, want one write of progress 100", store.last())
+	}
+	// 已写入的进度不重复写入
+	throttle.Flush(now.Add(time.Minute))
+	if _, progressWrites := store.counts(); progressWrites != 1 {
+		t.Fatalf("progress writes after second flush = %d, want 1", progressWrites)
+	}
+}
+
+func TestProgressThrottleDisabled(t *testing.T) {
+	throttle, store := newTestThrottle(0)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mustUpdate(t, throttle, models.JobStatusPrinting, 0, now)
+	for i := 1; i <= 100; i++ {
+		mustUpdate(t, throttle, models.JobStatusPrinting, i, now)
+	}
+	// 未变化的进度不重复写入
+	mustUpdate(t, throttle, models.JobStatusPrinting, 100, now)
+	if statusWrites, progressWrites := store.counts(); statusWrites != 1 || progressWrites != 100 {
+		t.Fatalf("writes: status %d, progress %d; want 1 and 100", statusWrites, progressWrites)
+	}
+}
+
+func TestProgressThrottleMilestones(t *testing.T) {
+	throttle, _ := newTestThrottle(time.Minute)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var milestones []int
+	for i := 0; i <= 100; i++ {
+		if update := mustUpdate(t, throttle, models.JobStatusPrinting, i, now); update.Milestone {
+			milestones = append(milestones, i)
+		}
+	}
+	// 进度回退不会重复触发里程碑
+	mustUpdate(t, throttle, models.JobStatusPrinting, 30, now)
+	if update := mustUpdate(t, throttle, models.JobStatusPrinting, 50, now); update.Milestone {
+		t.Fatal("milestone 50 reported twice")
+	}
+	want := []int{25, 50, 75, 100}
+	if len(milestones) != len(want) {
+		t.Fatalf("milestones at %v, want %v", milestones, want)
+	}
+	for i := range want {
+		if milestones[i] != want[i] {
+			t.Fatalf("milestones at %v, want %v", milestones, want)
+		}
+	}
+}
+
+// TestProgressThrottleStaleStatus 过期的状态更新未写入时，下一条更新仍按状态变化写入
+func TestProgressThrottleStaleStatus(t *testing.T) {
+	throttle, store := newTestThrottle(time.Minute)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	store.staleStatus = true
+	if update := mustUpdate(t, throttle, models.JobStatusPrinting, 10, now); update.Applied || update.Persisted {
+		t.Fatalf("stale update = %+v, want not applied", update)
+	}
+	store.staleStatus = false
+	if update := mustUpdate(t, throttle, models.JobStatusPrinting, 20, now.Add(time.Second)); !update.StatusChanged || !update.Persisted {
+		t.Fatalf("update after stale = %+v, want status write", update)
+	}
+}
+
+// TestProgressThrottleIdleCleanup 长时间没有更新且已写入的任务从内存中移除
+func TestProgressThrottleIdleCleanup(t *testing.T) {
+	throttle, _ := newTestThrottle(5 * time.Second)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mustUpdate(t, throttle, models.JobStatusPrinting, 10, now)
+
+	throttle.Flush(now.Add(progressIdleTTL - time.Second))
+	if len(throttle.jobs) != 1 {
+		t.Fatal("job removed before idle TTL")
+	}
+	throttle.Flush(now.Add(progressIdleTTL))
+	if len(throttle.jobs) != 0 {
+		t.Fatal("idle job not removed")
+	}
+}
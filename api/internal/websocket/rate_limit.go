@@ -0,0 +1,161 @@
+package websocket
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+)
+
+// rateDecision 限速检查结果
+type rateDecision int
+
+const (
+	rateAllow     rateDecision = iota // 放行
+	rateDrop                          // 丢弃
+	rateDropWarn                      // 丢弃，并向节点发送警告
+	rateDropClose                     // 丢弃，并断开连接
+)
+
+// violationWindow 统计持续超限的窗口长度
+const violationWindow = time.Minute
+
+// tokenBucket 令牌桶，容量为每分钟限额（允许一分钟内的突发）
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// nodeRateState 单个节点的限速状态，节点重连后保留，避免通过重连绕过限速
+type nodeRateState struct {
+	buckets     map[string]*tokenBucket // 消息类型 -> 令牌桶
+	windowStart time.Time
+	windowDrops int
+	warned      bool
+}
+
+// MessageLimiter 按节点和消息类型限制上行消息速率
+// 限额读取当前配置，支持热更新；未配置限额的消息类型不限速
+type MessageLimiter struct {
+	settings *config.Store
+
+	mutex       sync.Mutex
+	nodes       map[string]*nodeRateState
+	dropped     map[string]int64 // 消息类型 -> 累计丢弃数（全部节点）
+	warnings    int64
+	disconnects int64
+}
+
+// NewMessageLimiter 创建上行消息限速器
+func NewMessageLimiter(settings *config.Store) *MessageLimiter {
+	return &MessageLimiter{
+		settings: settings,
+		nodes:    make(map[string]*nodeRateState),
+		dropped:  make(map[string]int64),
+	}
+}
+
+// limitPerMinute 消息类型的每分钟限额，0 表示不限制
+func limitPerMinute(edge *config.EdgeConfig, messageType string) int {
+	switch messageType {
+	case MsgTypeHeartbeat:
+		return edge.RateLimitHeartbeat
+	case MsgTypePrinterStatus:
+		return edge.RateLimitPrinterStatus
+	case MsgTypeJobUpdate:
+		return edge.RateLimitJobUpdate
+	case MsgTypeLogBatch:
+		return edge.RateLimitLogBatch
+	default:
+		return 0
+	}
+}
+
+// Allow 消耗节点对应消息类型的一个令牌，令牌不足时丢弃
+// 一分钟内丢弃数达到 rate_limit_warn_after 时警告一次，达到 rate_limit_close_after 时要求断开连接
+func (l *MessageLimiter) Allow(nodeID, messageType string, now time.Time) rateDecision {
+	edge := &l.settings.Get().Edge
+	limit := limitPerMinute(edge, messageType)
+	if limit <= 0 {
+		return rateAllow
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	state, ok := l.nodes[nodeID]
+	if !ok {
+		state = &nodeRateState{buckets: make(map[string]*tokenBucket)}
+		l.nodes[nodeID] = state
+	}
+
+	capacity := float64(limit)
+	bucket, ok := state.buckets[messageType]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		state.buckets[messageType] = bucket
+	}
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += elapsed.Minutes() * capacity
+		bucket.last = now
+	}
+	if bucket.tokens > capacity {
+		bucket.tokens = capacity
+	}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return rateAllow
+	}
+
+	l.dropped[messageType]++
+	if now.Sub(state.windowStart) >= violationWindow {
+		state.windowStart = now
+		state.windowDrops = 0
+		state.warned = false
+	}
+	state.windowDrops++
+
+	if edge.RateLimitCloseAfter > 0 && state.windowDrops >= edge.RateLimitCloseAfter {
+		// 断开后重新计数，节点重连后仍按原令牌桶限速
+		state.windowDrops = 0
+		state.warned = false
+		l.disconnects++
+		return rateDropClose
+	}
+	if edge.RateLimitWarnAfter > 0 && state.windowDrops >= edge.RateLimitWarnAfter && !state.warned {
+		state.warned = true
+		l.warnings++
+		return rateDropWarn
+	}
+	return rateDrop
+}
+
+// RateLimitStats 限速累计统计
+type RateLimitStats struct {
+	Dropped     map[string]int64 // 消息类型 -> 丢弃数
+	Warnings    int64            // 发送的警告数
+	Disconnects int64            // 因持续超限断开的连接数
+}
+
+// Stats 返回自启动以来的限速统计
+func (l *MessageLimiter) Stats() RateLimitStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	dropped := make(map[string]int64, len(l.dropped))
+	for messageType, n := range l.dropped {
+		dropped[messageType] = n
+	}
+	return RateLimitStats{Dropped: dropped, Warnings: l.warnings, Disconnects: l.disconnects}
+}
+
+// sortedKeys 按字典序返回计数的键（输出稳定）
+func sortedKeys(counts map[string]int64) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
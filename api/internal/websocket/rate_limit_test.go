@@ -0,0 +1,199 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	gorillaws "github.com/gorilla/websocket"
+)
+
+// newTestLimiter 创建使用给定节点配置的限速器
+func newTestLimiter(edge config.EdgeConfig) *MessageLimiter {
+	return NewMessageLimiter(config.NewStore(&config.Config{Edge: edge}))
+}
+
+func TestMessageLimiterBurstAndRefill(t *testing.T) {
+	limiter := newTestLimiter(config.EdgeConfig{RateLimitPrinterStatus: 30})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// 令牌桶容量为每分钟限额，允许一次性突发
+	for i := 0; i < 30; i++ {
+		if got := limiter.Allow("node-1", MsgTypePrinterStatus, now); got != rateAllow {
+			t.Fatalf("message %d: decision %d, want allow", i+1, got)
+		}
+	}
+	if got := limiter.Allow("node-1", MsgTypePrinterStatus, now); got != rateDrop {
+		t.Fatalf("message 31: decision %d, want drop", got)
+	}
+
+	// 每 2 秒补充一个令牌
+	if got := limiter.Allow("node-1", MsgTypePrinterStatus, now.Add(time.Second)); got != rateDrop {
+		t.Fatalf("after 1s: decision %d, want drop", got)
+	}
+	if got := limiter.Allow("node-1", MsgTypePrinterStatus, now.Add(2*time.Second)); got != rateAllow {
+		t.Fatalf("after 2s: decision %d, want allow", got)
+	}
+
+	// 长时间空闲后令牌不超过容量
+	later := now.Add(time.Hour)
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		if limiter.Allow("node-1", MsgTypePrinterStatus, later) == rateAllow {
+			allowed++
+		}
+	}
+	if allowed != 30 {
+		t.Fatalf("allowed %d after idle hour, want capacity 30", allowed)
+	}
+
+	if got := limiter.Stats().Dropped[MsgTypePrinterStatus]; got != 72 {
+		t.Fatalf("dropped printer_status = %d, want 72", got)
+	}
+}
+
+func TestMessageLimiterIsolation(t *testing.T) {
+	limiter := newTestLimiter(config.EdgeConfig{RateLimitHeartbeat: 1, RateLimitJobUpdate: 1})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if limiter.Allow("node-1", MsgTypeHeartbeat, now) != rateAllow {
+		t.Fatal("first heartbeat dropped")
+	}
+	if limiter.Allow("node-1", MsgTypeHeartbeat, now) == rateAllow {
+		t.Fatal("second heartbeat allowed, want drop")
+	}
+	// 其他消息类型和其他节点有各自的令牌桶
+	if limiter.Allow("node-1", MsgTypeJobUpdate, now) != rateAllow {
+		t.Error("job_update shares the heartbeat bucket")
+	}
+	if limiter.Allow("node-2", MsgTypeHeartbeat, now) != rateAllow {
+		t.Error("node-2 shares node-1's bucket")
+	}
+	// 未配置限额的类型不限速
+	for i := 0; i < 1000; i++ {
+		if limiter.Allow("node-1", MsgTypePrinterStatus, now) != rateAllow {
+			t.Fatalf("unlimited printer_status dropped at message %d", i+1)
+		}
+	}
+}
+
+func TestMessageLimiterWarnThenClose(t *testing.T) {
+	limiter := newTestLimiter(config.EdgeConfig{
+		RateLimitPrinterStatus: 1,
+		RateLimitWarnAfter:     3,
+		RateLimitCloseAfter:    5,
+	})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter.Allow("node-1", MsgTypePrinterStatus, now)
+
+	want := []rateDecision{rateDrop, rateDrop, rateDropWarn, rateDrop, rateDropClose}
+	for i, w := range want {
+		if got := limiter.Allow("node-1", MsgTypePrinterStatus, now); got != w {
+			t.Fatalf("drop %d: decision %d, want %d", i+1, got, w)
+		}
+	}
+
+	// 断开后重新计数，但令牌桶保留，重连不能绕过限速
+	if got := limiter.Allow("node-1", MsgTypePrinterStatus, now); got != rateDrop {
+		t.Fatalf("after close: decision %d, want drop", got)
+	}
+
+	// 超过统计窗口后警告状态重置
+	next := now.Add(violationWindow + time.Second)
+	limiter.Allow("node-1", MsgTypePrinterStatus, next) // 补充的令牌
+	for i := 0; i < 2; i++ {
+		limiter.Allow("node-1", MsgTypePrinterStatus, next)
+	}
+	if got := limiter.Allow("node-1", MsgTypePrinterStatus, next); got != rateDropWarn {
+		t.Fatalf("next window: decision %d, want warn again", got)
+	}
+
+	stats := limiter.Stats()
+	if stats.Warnings != 2 || stats.Disconnects != 1 {
+		t.Fatalf("stats = %+v, want 2 warnings and 1 disconnect", stats)
+	}
+}
+
+// TestAllowMessageFloodClosesConnection 持续超限的连接先收到 rate_limited 错误，随后以 policy violation 关闭
+func TestAllowMessageFloodClosesConnection(t *testing.T) {
+	m := NewConnectionManager()
+	conn := &Connection{
+		NodeID:  "node-1",
+		Manager: m,
+		Send:    make(chan []byte, 16),
+		Limiter: newTestLimiter(config.EdgeConfig{
+			RateLimitPrinterStatus: 30,
+			RateLimitWarnAfter:     10,
+			RateLimitCloseAfter:    50,
+		}),
+	}
+	m.registerConnection(conn)
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	allowed := 0
+	for i := 0; i < 2000; i++ {
+		if conn.allowMessage(MsgTypePrinterStatus, now) {
+			allowed++
+		}
+	}
+	if allowed != 30 {
+		t.Fatalf("allowed %d messages, want 30", allowed)
+	}
+	if got := conn.DroppedCounts()[MsgTypePrinterStatus]; got != 1970 {
+		t.Fatalf("dropped printer_status = %d, want 1970", got)
+	}
+	if m.IsNodeConnected("node-1") {
+		t.Fatal("connection still registered after persistent violation")
+	}
+	if conn.closeCode != gorillaws.ClosePolicyViolation {
+		t.Fatalf("close code = %d, want %d", conn.closeCode, gorillaws.ClosePolicyViolation)
+	}
+
+	var warnings int
+	for data := range conn.Send {
+		var cmd struct {
+			Type string    `json:"type"`
+			Data ErrorData `json:"data"`
+		}
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			t.Fatalf("decode queued message: %v", err)
+		}
+		if cmd.Type == CmdTypeError && cmd.Data.Code == ErrCodeRateLimited && cmd.Data.MessageType == MsgTypePrinterStatus {
+			warnings++
+		}
+	}
+	if warnings != 1 {
+		t.Fatalf("rate_limited warnings = %d, want 1", warnings)
+	}
+}
+
+func TestWriteMetricsRateLimit(t *testing.T) {
+	limiter := newTestLimiter(config.EdgeConfig{RateLimitHeartbeat: 1, RateLimitJobUpdate: 1})
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		limiter.Allow("node-1", MsgTypeJobUpdate, now)
+		limiter.Allow("node-1", MsgTypeHeartbeat, now)
+	}
+	h := &WebSocketHandler{manager: NewConnectionManager(), limiter: limiter}
+
+	var b strings.Builder
+	h.WriteMetrics(&b)
+	out := b.String()
+	for _, want := range []string{
+		"fly_print_ws_connections 0\n",
+		`fly_print_ws_messages_dropped_total{type="edge_heartbeat"} 2` + "\n",
+		`fly_print_ws_messages_dropped_total{type="job_update"} 2` + "\n",
+		"fly_print_ws_rate_limit_warnings_total 0\n",
+		"fly_print_ws_rate_limit_disconnects_total 0\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q in:\n%s", want, out)
+		}
+	}
+	// 按消息类型字典序输出
+	if strings.Index(out, `type="edge_heartbeat"`) > strings.Index(out, `type="job_update"`) {
+		t.Error("dropped counters not sorted by type")
+	}
+}
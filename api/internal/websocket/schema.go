@@ -0,0 +1,353 @@
+package websocket
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolMinorVersion 边缘协议的次版本号，通过 capabilities 下发
+// 消息结构（message.go 中的类型）生成的 JSON Schema 发生变化时必须递增，并同步更新 protocolSchemaDigest
+const ProtocolMinorVersion = 7
+
+// protocolSchemaDigest ProtocolMinorVersion 对应的 Schema 摘要，启动时由 CheckProtocolSchemas 校验
+const protocolSchemaDigest = "bca40002ef6fe7fb"
+
+// 通用结构的 Schema 名称（其余名称为上行消息类型或下行指令类型）
+const (
+	SchemaMessage    = "message"
+	SchemaCommand    = "command"
+	SchemaCommandAck = "command_ack"
+)
+
+// maxSchemaViolations 单条消息最多返回的校验错误数
+const maxSchemaViolations = 20
+
+// upstreamSchemas 上行消息类型及其 data 结构
+var upstreamSchemas = map[string]interface{}{
+	MsgTypeHeartbeat:     HeartbeatData{},
+	MsgTypePrinterStatus: PrinterStatusData{},
+	MsgTypeJobUpdate:     JobUpdateData{},
+	MsgTypeAuth:          AuthData{},
+	MsgTypeLogBatch:      LogBatchData{},
+}
+
+// downstreamSchemas 下行指令类型及其 data 结构
+var downstreamSchemas = map[string]interface{}{
+	CmdTypePrintJob:           PrintJobData{},
+	CmdTypeCollectDiagnostics: DiagnosticsRequestData{},
+	CmdTypePrinterPower:       PrinterPowerData{},
+	CmdTypeError:              ErrorData{},
+	CmdTypeWelcome:            ServerCapabilities{},
+	CmdTypeSetLogStreaming:    SetLogStreamingData{},
+	CmdTypePauseQueue:         PrinterQueueData{},
+	CmdTypeResumeQueue:        PrinterQueueData{},
+}
+
+// schemaTypes JSON Schema 的 type，只有一个类型时编码为字符串
+type schemaTypes []string
+
+func (t schemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// Schema 由 Go 结构生成的 JSON Schema（draft 2020-12 的子集）
+// 未声明的字段不视为错误，便于云端和节点独立升级
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Direction            string             `json:"x-direction,omitempty"` // upstream（节点发送）/downstream（云端发送）
+	Type                 schemaTypes        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Const                string             `json:"const,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// SchemaViolation 上行消息不符合 Schema 的位置和原因
+type SchemaViolation struct {
+	Path    string `json:"path"` // JSON Pointer，如 /data/printer_id
+	Message string `json:"message"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf 根据 Go 类型和 json 标签生成 Schema
+// 指针、切片和 map 可为 null；非指针且未标记 omitempty 的字段为必填
+func schemaOf(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: schemaTypes{"string"}, Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := schemaOf(t.Elem())
+		if len(schema.Type) > 0 {
+			schema.Type = append(schema.Type, "null")
+		}
+		return schema
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.String:
+		return &Schema{Type: schemaTypes{"string"}}
+	case reflect.Bool:
+		return &Schema{Type: schemaTypes{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: schemaTypes{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: schemaTypes{"number"}}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: schemaTypes{"string"}} // []byte 编码为 base64 字符串
+		}
+		return &Schema{Type: schemaTypes{"array", "null"}, Items: schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: schemaTypes{"object", "null"}, AdditionalProperties: schemaOf(t.Elem())}
+	case reflect.Struct:
+		schema := &Schema{Type: schemaTypes{"object"}, Properties: map[string]*Schema{}}
+		addStructFields(schema, t)
+		sort.Strings(schema.Required)
+		return schema
+	}
+	return &Schema{}
+}
+
+// addStructFields 将结构体字段加入 Schema，匿名嵌入的结构体字段展开到上层
+func addStructFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addStructFields(schema, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = schemaOf(field.Type)
+
+		optional := strings.Contains(","+options+",", ",omitempty,")
+		switch field.Type.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			optional = true
+		}
+		if !optional {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// envelopeSchema 生成通用结构的 Schema，messageType 不为空时固定 type 并替换 data 的 Schema
+func envelopeSchema(envelope interface{}, messageType string, data interface{}) *Schema {
+	schema := schemaOf(reflect.TypeOf(envelope))
+	if messageType != "" {
+		schema.Properties["type"].Const = messageType
+	}
+	if data != nil {
+		schema.Properties["data"] = schemaOf(reflect.TypeOf(data))
+		schema.Required = append(schema.Required, "data")
+		sort.Strings(schema.Required)
+	}
+	return schema
+}
+
+var (
+	protocolSchemasOnce sync.Once
+	protocolSchemas     map[string]*Schema
+	protocolDigest      string
+)
+
+// loadProtocolSchemas 首次使用时由 Go 结构生成全部 Schema 并计算摘要
+func loadProtocolSchemas() {
+	protocolSchemasOnce.Do(func() {
+		schemas := map[string]*Schema{
+			SchemaMessage:    envelopeSchema(Message{}, "", nil),
+			SchemaCommand:    envelopeSchema(Command{}, "", nil),
+			SchemaCommandAck: envelopeSchema(CommandAck{}, "", nil),
+		}
+		schemas[SchemaMessage].Direction = "upstream"
+		schemas[SchemaCommand].Direction = "downstream"
+		schemas[SchemaCommandAck].Direction = "upstream"
+		for messageType, data := range upstreamSchemas {
+			schemas[messageType] = envelopeSchema(Message{}, messageType, data)
+			schemas[messageType].Direction = "upstream"
+		}
+		for commandType, data := range downstreamSchemas {
+			schemas[commandType] = envelopeSchema(Command{}, commandType, data)
+			schemas[commandType].Direction = "downstream"
+		}
+
+		// 摘要只覆盖结构本身，不含 $schema 和 title
+		encoded, err := json.Marshal(schemas)
+		if err != nil {
+			panic(fmt.Sprintf("failed to encode protocol schemas: %v", err))
+		}
+		sum := sha256.Sum256(encoded)
+		protocolDigest = hex.EncodeToString(sum[:8])
+
+		for name, schema := range schemas {
+			schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+			schema.Title = name
+		}
+		protocolSchemas = schemas
+	})
+}
+
+// ProtocolSchema 返回指定消息/指令类型（或 message、command、command_ack）的 Schema
+func ProtocolSchema(name string) (*Schema, bool) {
+	loadProtocolSchemas()
+	schema, ok := protocolSchemas[name]
+	return schema, ok
+}
+
+// ProtocolSchemaNames 返回全部 Schema 名称（按字母排序）
+func ProtocolSchemaNames() []string {
+	loadProtocolSchemas()
+	names := make([]string, 0, len(protocolSchemas))
+	for name := range protocolSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProtocolSchemaDigest 当前消息结构生成的 Schema 摘要
+func ProtocolSchemaDigest() string {
+	loadProtocolSchemas()
+	return protocolDigest
+}
+
+// CheckProtocolSchemas 校验消息结构变化后是否已递增 ProtocolMinorVersion（启动时调用）
+func CheckProtocolSchemas() error {
+	if digest := ProtocolSchemaDigest(); digest != protocolSchemaDigest {
+		return fmt.Errorf("edge protocol schemas changed (digest %s, expected %s): bump ProtocolMinorVersion and update protocolSchemaDigest",
+			digest, protocolSchemaDigest)
+	}
+	return nil
+}
+
+// ValidateMessage 按上行消息类型的 Schema 校验原始消息，返回全部（最多 maxSchemaViolations 条）校验错误
+func ValidateMessage(raw []byte) []SchemaViolation {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []SchemaViolation{{Path: "", Message: "invalid JSON: " + err.Error()}}
+	}
+
+	object, _ := value.(map[string]interface{})
+	messageType, _ := object["type"].(string)
+	if _, upstream := upstreamSchemas[messageType]; !upstream {
+		return []SchemaViolation{{Path: "/type", Message: fmt.Sprintf("unknown message type %q", messageType)}}
+	}
+	schema, _ := ProtocolSchema(messageType)
+
+	var violations []SchemaViolation
+	schema.validate("", value, &violations)
+	return violations
+}
+
+// validate 递归校验 value，校验错误追加到 violations
+func (s *Schema) validate(path string, value interface{}, violations *[]SchemaViolation) {
+	if len(*violations) >= maxSchemaViolations {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.allowsType(value) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), jsonTypeOf(value))
+		return
+	}
+	if s.Const != "" && value != s.Const {
+		fail("must be %q", s.Const)
+		return
+	}
+
+	switch v := value.(type) {
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				fail("must be an RFC 3339 date-time")
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, SchemaViolation{Path: path + "/" + name, Message: "required field is missing"})
+			}
+		}
+		// 按字段名排序，保证错误顺序稳定
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, ok := s.Properties[key]; ok {
+				property.validate(path+"/"+key, v[key], violations)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(path+"/"+key, v[key], violations)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s/%d", path, i), item, violations)
+			}
+		}
+	}
+}
+
+// allowsType value 的 JSON 类型是否在 Schema 允许的类型中（integer 也是 number）
+func (s *Schema) allowsType(value interface{}) bool {
+	actual := jsonTypeOf(value)
+	for _, allowed := range s.Type {
+		if allowed == actual || (allowed == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf 返回以 UseNumber 解码的值的 JSON Schema 类型
+func jsonTypeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
@@ -0,0 +1,202 @@
+package websocket
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+)
+
+// TestProtocolSchemaDigest 消息结构变化时必须递增 ProtocolMinorVersion 并更新 protocolSchemaDigest
+func TestProtocolSchemaDigest(t *testing.T) {
+	if err := CheckProtocolSchemas(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProtocolSchemaNames(t *testing.T) {
+	names := ProtocolSchemaNames()
+	want := len(upstreamSchemas) + len(downstreamSchemas) + 3
+	if len(names) != want {
+		t.Fatalf("%d schemas, want %d: %v", len(names), want, names)
+	}
+	for _, name := range names {
+		schema, ok := ProtocolSchema(name)
+		if !ok || schema.Title != name || schema.Schema == "" || schema.Direction == "" {
+			t.Fatalf("schema %s = %+v", name, schema)
+		}
+	}
+	if _, ok := ProtocolSchema("no_such_type"); ok {
+		t.Fatal("unknown schema type found")
+	}
+}
+
+// TestSchemaGenerationRules 非指针且未标记 omitempty 的字段必填，指针、切片和 map 可为 null
+func TestSchemaGenerationRules(t *testing.T) {
+	schema, _ := ProtocolSchema(MsgTypeJobUpdate)
+	if got := schema.Properties["type"].Const; got != MsgTypeJobUpdate {
+		t.Fatalf("type const = %q", got)
+	}
+	if !reflect.DeepEqual(schema.Required, []string{"data", "node_id", "timestamp", "type"}) {
+		t.Fatalf("envelope required = %v", schema.Required)
+	}
+	if format := schema.Properties["timestamp"].Format; format != "date-time" {
+		t.Fatalf("timestamp format = %q", format)
+	}
+
+	data := schema.Properties["data"]
+	if !reflect.DeepEqual(data.Required, []string{"job_id", "progress", "status"}) {
+		t.Fatalf("job_update data required = %v", data.Required)
+	}
+	if got := data.Properties["error_message"].Type; !reflect.DeepEqual([]string(got), []string{"string", "null"}) {
+		t.Fatalf("error_message type = %v, want string or null", got)
+	}
+	if got := data.Properties["progress"].Type; !reflect.DeepEqual([]string(got), []string{"integer"}) {
+		t.Fatalf("progress type = %v", got)
+	}
+
+	status, _ := ProtocolSchema(MsgTypePrinterStatus)
+	supplies := status.Properties["data"].Properties["supplies"]
+	if !reflect.DeepEqual([]string(supplies.Type), []string{"object", "null"}) || supplies.AdditionalProperties == nil {
+		t.Fatalf("supplies schema = %+v", supplies)
+	}
+
+	// 单一类型编码为字符串，多个类型编码为数组
+	encoded, err := json.Marshal(data.Properties["error_message"])
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(encoded) != `{"type":["string","null"]}` {
+		t.Fatalf("encoded schema = %s", encoded)
+	}
+}
+
+// TestValidateMessageAcceptsEncodedStructs 按 Go 结构编码的上行消息全部通过校验
+func TestValidateMessageAcceptsEncodedStructs(t *testing.T) {
+	errorMessage := "paper jam"
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	messages := map[string]interface{}{
+		MsgTypeHeartbeat:     HeartbeatData{SystemInfo: SystemInfo{CPUUsage: 12.5, NetworkQuality: "good", Latency: 20}},
+		MsgTypePrinterStatus: PrinterStatusData{PrinterID: "p-1", Status: "ready", Supplies: map[string]interface{}{"toner": 80}},
+		MsgTypeJobUpdate:     JobUpdateData{JobID: "job-1", Status: "failed", Progress: 40, ErrorMessage: &errorMessage},
+		MsgTypeAuth:          AuthData{Token: "Bearer abc"},
+		MsgTypeLogBatch:      LogBatchData{Lines: []LogLine{{Level: "info", Timestamp: now, Message: "started"}}},
+	}
+	if len(messages) != len(upstreamSchemas) {
+		t.Fatalf("test covers %d of %d upstream types", len(messages), len(upstreamSchemas))
+	}
+	for messageType, data := range messages {
+		raw, err := json.Marshal(Message{Type: messageType, NodeID: "node-1", Timestamp: now, Data: data})
+		if err != nil {
+			t.Fatalf("marshal %s: %v", messageType, err)
+		}
+		if violations := ValidateMessage(raw); len(violations) != 0 {
+			t.Fatalf("%s: unexpected violations %+v", messageType, violations)
+		}
+	}
+}
+
+func TestValidateMessageViolations(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []SchemaViolation
+	}{
+		{
+			name: "invalid JSON",
+			raw:  `{"type":`,
+			want: []SchemaViolation{{Path: "", Message: "invalid JSON: unexpected EOF"}},
+		},
+		{
+			name: "unknown type",
+			raw:  `{"type":"print_job","node_id":"n","timestamp":"2026-03-01T12:00:00Z","data":{}}`,
+			want: []SchemaViolation{{Path: "/type", Message: `unknown message type "print_job"`}},
+		},
+		{
+			name: "wrong types and bad timestamp",
+			raw:  `{"type":"job_update","node_id":7,"timestamp":"yesterday","data":{"job_id":"j","status":"printing","progress":"40"}}`,
+			want: []SchemaViolation{
+				{Path: "/data/progress", Message: "expected integer, got string"},
+				{Path: "/node_id", Message: "expected string, got integer"},
+				{Path: "/timestamp", Message: "must be an RFC 3339 date-time"},
+			},
+		},
+		{
+			name: "missing fields",
+			raw:  `{"type":"job_update","node_id":"n","timestamp":"2026-03-01T12:00:00Z","data":{"job_id":"j"}}`,
+			want: []SchemaViolation{
+				{Path: "/data/progress", Message: "required field is missing"},
+				{Path: "/data/status", Message: "required field is missing"},
+			},
+		},
+		{
+			name: "missing field inside array",
+			raw:  `{"type":"log_batch","node_id":"n","timestamp":"2026-03-01T12:00:00Z","data":{"lines":[{"level":"info","ts":"2026-03-01T12:00:00Z"}]}}`,
+			want: []SchemaViolation{{Path: "/data/lines/0/message", Message: "required field is missing"}},
+		},
+		{
+			name: "fractional integer",
+			raw:  `{"type":"edge_heartbeat","node_id":"n","timestamp":"2026-03-01T12:00:00Z","data":{"system_info":{"cpu_usage":1,"memory_usage":1,"disk_usage":1,"network_quality":"good","latency":1.5}}}`,
+			want: []SchemaViolation{{Path: "/data/system_info/latency", Message: "expected integer, got number"}},
+		},
+		{
+			name: "unknown fields allowed",
+			raw:  `{"type":"auth","node_id":"n","timestamp":"2026-03-01T12:00:00Z","data":{"token":"t","future_field":1},"extra":true}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateMessage([]byte(tt.raw))
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("violations = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateMessageViolationLimit 错误数超过 maxSchemaViolations 时截断
+func TestValidateMessageViolationLimit(t *testing.T) {
+	lines := make([]map[string]interface{}, maxSchemaViolations+10)
+	for i := range lines {
+		lines[i] = map[string]interface{}{"level": "info", "ts": "2026-03-01T12:00:00Z", "message": i}
+	}
+	raw, err := json.Marshal(map[string]interface{}{
+		"type": MsgTypeLogBatch, "node_id": "n", "timestamp": "2026-03-01T12:00:00Z",
+		"data": map[string]interface{}{"lines": lines},
+	})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if violations := ValidateMessage(raw); len(violations) != maxSchemaViolations {
+		t.Fatalf("%d violations, want %d", len(violations), maxSchemaViolations)
+	}
+}
+
+// TestSchemaViolationError 校验失败时以 error 指令返回 schema_violation 和违规列表
+func TestSchemaViolationError(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Edge.ValidateMessages = true
+	conn := &Connection{NodeID: "node-1", Send: make(chan []byte, 1), Settings: config.NewStore(cfg)}
+	if !conn.validateMessages() || (&Connection{}).validateMessages() {
+		t.Fatal("validateMessages does not follow edge.validate_messages")
+	}
+
+	violations := ValidateMessage([]byte(`{"type":"job_update","node_id":"n","timestamp":"2026-03-01T12:00:00Z","data":{"job_id":"j"}}`))
+	conn.sendErrorData(ErrorData{Code: ErrCodeSchemaViolation, MessageType: MsgTypeJobUpdate, Violations: violations})
+
+	var command struct {
+		Type string    `json:"type"`
+		Data ErrorData `json:"data"`
+	}
+	if err := json.Unmarshal(<-conn.Send, &command); err != nil {
+		t.Fatalf("decode error command: %v", err)
+	}
+	if command.Type != CmdTypeError || command.Data.Code != ErrCodeSchemaViolation || !reflect.DeepEqual(command.Data.Violations, violations) {
+		t.Fatalf("error command = %+v", command)
+	}
+}
@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/worker"
+)
+
+// StaleConnectionMonitor 后台关闭长时间没有应用层心跳的 WebSocket 连接
+// 节点进程退出后，部分 NAT 仍会保持 TCP 连接并回应 ping，IsNodeConnected 会一直返回 true；
+// 连接超过 edge.heartbeat_interval × edge.stale_heartbeat_factor 未发送 edge_heartbeat 时主动断开并将节点标记为离线
+type StaleConnectionMonitor struct {
+	manager  *ConnectionManager
+	monitor  *worker.HeartbeatMonitor
+	settings *config.Store // 心跳间隔和倍数支持热更新，每次使用时读取
+}
+
+// NewStaleConnectionMonitor 创建僵死连接检测
+func NewStaleConnectionMonitor(manager *ConnectionManager, monitor *worker.HeartbeatMonitor, settings *config.Store) *StaleConnectionMonitor {
+	return &StaleConnectionMonitor{
+		manager:  manager,
+		monitor:  monitor,
+		settings: settings,
+	}
+}
+
+// interval 检查间隔，与建议的心跳间隔相同
+func (s *StaleConnectionMonitor) interval() time.Duration {
+	if interval := s.settings.Get().Edge.HeartbeatInterval; interval > 0 {
+		return interval
+	}
+	return 30 * time.Second
+}
+
+// maxSilence 允许的最长无心跳时间，0 表示不检测
+func (s *StaleConnectionMonitor) maxSilence() time.Duration {
+	return s.interval() * time.Duration(s.settings.Get().Edge.StaleHeartbeatFactor)
+}
+
+// Run 启动僵死连接检测（阻塞）
+func (s *StaleConnectionMonitor) Run() {
+	interval := s.interval()
+	log.Printf("Stale connection monitor started: interval=%s, max_silence=%s", interval, s.maxSilence())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.Check(time.Now().UTC())
+
+		// 心跳间隔被热更新时重置定时器
+		if next := s.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// Check 断开在 now 之前超过期限没有应用层心跳的连接，并将对应节点标记为离线
+func (s *StaleConnectionMonitor) Check(now time.Time) {
+	maxSilence := s.maxSilence()
+	if maxSilence <= 0 {
+		return
+	}
+
+	for _, nodeID := range s.manager.CloseStaleConnections(now, maxSilence) {
+		s.monitor.NodeConnectionStale(nodeID)
+	}
+}
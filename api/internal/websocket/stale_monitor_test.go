@@ -0,0 +1,95 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+)
+
+// 测试使用固定时间代替 time.Now，心跳和检查时间均由测试给出
+var staleT0 = time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+
+func newStaleTestConn(nodeID string, connectedAt time.Time) *Connection {
+	return &Connection{NodeID: nodeID, ConnectedAt: connectedAt, Send: make(chan []byte, 1)}
+}
+
+func TestCloseStaleConnections(t *testing.T) {
+	m := NewConnectionManager()
+	maxSilence := 90 * time.Second
+
+	alive := newStaleTestConn("node-alive", staleT0)
+	alive.lastAppHeartbeat.Store(staleT0.Add(50 * time.Second).UnixNano())
+	silent := newStaleTestConn("node-silent", staleT0) // 建立后从未发送 edge_heartbeat
+	m.registerConnection(alive)
+	m.registerConnection(silent)
+
+	if closed := m.CloseStaleConnections(staleT0.Add(89*time.Second), maxSilence); len(closed) != 0 {
+		t.Fatalf("closed %v before max silence elapsed", closed)
+	}
+
+	closed := m.CloseStaleConnections(staleT0.Add(100*time.Second), maxSilence)
+	if len(closed) != 1 || closed[0] != "node-silent" {
+		t.Fatalf("closed = %v, want [node-silent]", closed)
+	}
+	if m.IsNodeConnected("node-silent") || !m.IsNodeConnected("node-alive") {
+		t.Fatal("wrong connection removed")
+	}
+	if silent.closeReason != "heartbeat timeout" {
+		t.Errorf("close reason = %q", silent.closeReason)
+	}
+	if _, open := <-silent.Send; open {
+		t.Error("send queue of stale connection not closed")
+	}
+
+	// 最近一次心跳之后超过期限
+	closed = m.CloseStaleConnections(staleT0.Add(141*time.Second), maxSilence)
+	if len(closed) != 1 || closed[0] != "node-alive" {
+		t.Fatalf("closed = %v, want [node-alive]", closed)
+	}
+	if m.GetConnectionCount() != 0 {
+		t.Errorf("connections = %d, want 0", m.GetConnectionCount())
+	}
+}
+
+func TestListSessionsLastAppHeartbeat(t *testing.T) {
+	m := NewConnectionManager()
+	withHeartbeat := newStaleTestConn("node-1", staleT0)
+	withHeartbeat.lastAppHeartbeat.Store(staleT0.Add(time.Minute).UnixNano())
+	m.registerConnection(withHeartbeat)
+	m.registerConnection(newStaleTestConn("node-2", staleT0))
+
+	for _, session := range m.ListSessions() {
+		switch session.NodeID {
+		case "node-1":
+			if session.LastAppHeartbeat == nil || !session.LastAppHeartbeat.Equal(staleT0.Add(time.Minute)) {
+				t.Errorf("node-1 last_app_heartbeat = %v", session.LastAppHeartbeat)
+			}
+		case "node-2":
+			if session.LastAppHeartbeat != nil {
+				t.Errorf("node-2 last_app_heartbeat = %v, want null", session.LastAppHeartbeat)
+			}
+		}
+	}
+}
+
+func TestStaleConnectionMonitorMaxSilence(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Edge.HeartbeatInterval = 30 * time.Second
+	cfg.Edge.StaleHeartbeatFactor = 3
+	settings := config.NewStore(cfg)
+	monitor := NewStaleConnectionMonitor(NewConnectionManager(), nil, settings)
+
+	if got := monitor.maxSilence(); got != 90*time.Second {
+		t.Errorf("max silence = %s, want 90s", got)
+	}
+
+	// 倍数为 0 时不检测，连接不会被关闭
+	cfg.Edge.StaleHeartbeatFactor = 0
+	monitor.settings = config.NewStore(cfg)
+	monitor.manager.registerConnection(newStaleTestConn("node-1", staleT0))
+	monitor.Check(staleT0.Add(24 * time.Hour))
+	if !monitor.manager.IsNodeConnected("node-1") {
+		t.Error("connection closed with stale detection disabled")
+	}
+}
@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+)
+
+// BusinessHoursOpener 定期分发排队任务：打印机开始营业、或按营业时间排到之后的任务计划时间已到时，
+// 由分发器认领（仍不在营业时间的打印机由分发器跳过）
+type BusinessHoursOpener struct {
+	printJobRepo *database.PrintJobRepository
+	printerRepo  *database.PrinterRepository
+	dispatcher   QueueDispatcher
+	settings     *config.Store // 检查间隔支持热更新，每次使用时读取
+}
+
+// NewBusinessHoursOpener 创建营业时间排队任务分发
+func NewBusinessHoursOpener(printJobRepo *database.PrintJobRepository, printerRepo *database.PrinterRepository, dispatcher QueueDispatcher, settings *config.Store) *BusinessHoursOpener {
+	return &BusinessHoursOpener{
+		printJobRepo: printJobRepo,
+		printerRepo:  printerRepo,
+		dispatcher:   dispatcher,
+		settings:     settings,
+	}
+}
+
+// interval 检查间隔
+func (w *BusinessHoursOpener) interval() time.Duration {
+	if interval := w.settings.Get().Jobs.OpenCheckInterval; interval > 0 {
+		return interval
+	}
+	return time.Minute
+}
+
+// Run 启动检查（阻塞）
+func (w *BusinessHoursOpener) Run() {
+	interval := w.interval()
+	log.Printf("Business hours opener started: interval=%s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.dispatchDue()
+
+		// 检查间隔被热更新时重置定时器
+		if next := w.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// dispatchDue 分发有可认领排队任务的打印机
+func (w *BusinessHoursOpener) dispatchDue() {
+	printerIDs, err := w.printJobRepo.ListPrintersWithDueQueuedJobs(time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to list printers with queued jobs: %v", err)
+		return
+	}
+	for _, printerID := range printerIDs {
+		printer, err := w.printerRepo.GetPrinterByID(printerID)
+		if err != nil || printer == nil {
+			log.Printf("Failed to get printer %s for queued dispatch: %v", printerID, err)
+			continue
+		}
+		w.dispatcher.DispatchQueued(printer)
+	}
+}
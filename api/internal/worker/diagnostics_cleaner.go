@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fly-print-cloud/api/internal/database"
+)
+
+// DiagnosticsCleaner 后台清理过期的诊断包请求及其归档文件
+type DiagnosticsCleaner struct {
+	repo     *database.DiagnosticsRepository
+	dir      string
+	interval time.Duration
+}
+
+// NewDiagnosticsCleaner 创建诊断包清理任务
+func NewDiagnosticsCleaner(repo *database.DiagnosticsRepository, dir string, interval time.Duration) *DiagnosticsCleaner {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	return &DiagnosticsCleaner{
+		repo:     repo,
+		dir:      dir,
+		interval: interval,
+	}
+}
+
+// Run 启动清理任务（阻塞）
+func (w *DiagnosticsCleaner) Run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.cleanup()
+	}
+}
+
+// cleanup 标记过期请求并删除已上传的归档文件
+func (w *DiagnosticsCleaner) cleanup() {
+	uploadedIDs, err := w.repo.ExpireDiagnosticsRequests(time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to expire diagnostics requests: %v", err)
+		return
+	}
+
+	for _, id := range uploadedIDs {
+		if err := os.Remove(DiagnosticsArchivePath(w.dir, id)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove diagnostics archive %s: %v", id, err)
+			continue
+		}
+		log.Printf("Diagnostics archive %s expired and removed", id)
+	}
+}
+
+// DiagnosticsArchivePath 诊断包在存储目录中的路径（以请求ID命名，避免使用节点上传的文件名）
+func DiagnosticsArchivePath(dir, requestID string) string {
+	return filepath.Join(dir, requestID+".archive")
+}
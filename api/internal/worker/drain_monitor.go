@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/models"
+)
+
+// 排空结束的原因
+const (
+	DrainReasonCompleted = "completed" // 已分发的任务全部结束
+	DrainReasonTimeout   = "timeout"   // 超过最长排空时间
+)
+
+// drainingNodes DrainMonitor 读取和结束排空状态所需的 Edge Node 操作（*database.EdgeNodeRepository 实现）
+type drainingNodes interface {
+	ListDrainingNodes() ([]*models.EdgeNode, error)
+	CompleteDrain(id string) (bool, error)
+}
+
+// activeJobCounter 统计节点上已分发且未结束的任务（*database.PrintJobRepository 实现）
+type activeJobCounter interface {
+	CountActiveJobsByEdgeNode(edgeNodeID string) (int, error)
+}
+
+// DrainMonitor 后台检查排空中的 Edge Node：已分发的任务全部结束或超过截止时间后禁用节点
+type DrainMonitor struct {
+	edgeNodeRepo drainingNodes
+	printJobRepo activeJobCounter
+	eventBus     *events.Bus
+	settings     *config.Store // 检查间隔支持热更新，每次使用时读取
+}
+
+// NewDrainMonitor 创建排空检查
+func NewDrainMonitor(edgeNodeRepo *database.EdgeNodeRepository, printJobRepo *database.PrintJobRepository, eventBus *events.Bus, settings *config.Store) *DrainMonitor {
+	return &DrainMonitor{
+		edgeNodeRepo: edgeNodeRepo,
+		printJobRepo: printJobRepo,
+		eventBus:     eventBus,
+		settings:     settings,
+	}
+}
+
+// interval 检查间隔
+func (m *DrainMonitor) interval() time.Duration {
+	if interval := m.settings.Get().Edge.DrainCheckInterval; interval > 0 {
+		return interval
+	}
+	return 15 * time.Second
+}
+
+// Run 启动排空检查（阻塞）
+func (m *DrainMonitor) Run() {
+	interval := m.interval()
+	log.Printf("Drain monitor started: interval=%s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.Check(time.Now().UTC())
+
+		// 检查间隔被热更新时重置定时器
+		if next := m.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// Check 禁用已排空或已超过截止时间的节点
+func (m *DrainMonitor) Check(now time.Time) {
+	nodes, err := m.edgeNodeRepo.ListDrainingNodes()
+	if err != nil {
+		log.Printf("Failed to list draining edge nodes: %v", err)
+		return
+	}
+
+	for _, node := range nodes {
+		active, err := m.printJobRepo.CountActiveJobsByEdgeNode(node.ID)
+		if err != nil {
+			log.Printf("Failed to count active jobs of draining node %s: %v", node.ID, err)
+			continue
+		}
+
+		reason := DrainReasonCompleted
+		if active > 0 {
+			if node.DrainDeadline == nil || now.Before(*node.DrainDeadline) {
+				continue
+			}
+			reason = DrainReasonTimeout
+		}
+
+		completed, err := m.edgeNodeRepo.CompleteDrain(node.ID)
+		if err != nil {
+			log.Printf("Failed to disable drained node %s: %v", node.ID, err)
+			continue
+		}
+		if !completed {
+			// 排空已被管理员取消
+			continue
+		}
+
+		log.Printf("Edge Node %s drain finished (%s, %d active jobs), node disabled", node.ID, reason, active)
+		m.eventBus.Publish(events.Event{
+			Type:   events.EventNodeDrained,
+			NodeID: node.ID,
+			Data: map[string]interface{}{
+				"reason":      reason,
+				"active_jobs": active,
+			},
+		})
+	}
+}
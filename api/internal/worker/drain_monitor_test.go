@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/models"
+)
+
+var drainNow = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+// fakeDrainNodes 内存中的排空节点；cancelled 中的节点模拟检查期间被管理员取消排空
+type fakeDrainNodes struct {
+	nodes     []*models.EdgeNode
+	cancelled map[string]bool
+	completed []string
+}
+
+func (f *fakeDrainNodes) ListDrainingNodes() ([]*models.EdgeNode, error) {
+	return f.nodes, nil
+}
+
+func (f *fakeDrainNodes) CompleteDrain(id string) (bool, error) {
+	if f.cancelled[id] {
+		return false, nil
+	}
+	f.completed = append(f.completed, id)
+	return true, nil
+}
+
+// fakeActiveJobs 每个节点未结束的任务数；failing 中的节点统计失败
+type fakeActiveJobs struct {
+	active  map[string]int
+	failing map[string]bool
+}
+
+func (f *fakeActiveJobs) CountActiveJobsByEdgeNode(edgeNodeID string) (int, error) {
+	if f.failing[edgeNodeID] {
+		return 0, errors.New("count failed")
+	}
+	return f.active[edgeNodeID], nil
+}
+
+func drainingNode(id string, deadline *time.Time) *models.EdgeNode {
+	return &models.EdgeNode{ID: id, Draining: true, DrainDeadline: deadline}
+}
+
+func drainDeadline(d time.Duration) *time.Time {
+	t := drainNow.Add(d)
+	return &t
+}
+
+func TestDrainMonitorCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		node       *models.EdgeNode
+		active     int
+		countFails bool
+		cancelled  bool
+		wantReason string // 为空表示节点保持排空状态、不发布事件
+		wantActive int
+	}{
+		{
+			name:       "completes naturally once jobs finish",
+			node:       drainingNode("node-done", drainDeadline(time.Hour)),
+			wantReason: DrainReasonCompleted,
+		},
+		{
+			name:       "completes naturally without deadline",
+			node:       drainingNode("node-done-no-deadline", nil),
+			wantReason: DrainReasonCompleted,
+		},
+		{
+			name:   "waits for active jobs before deadline",
+			node:   drainingNode("node-busy", drainDeadline(time.Minute)),
+			active: 2,
+		},
+		{
+			name:   "waits for active jobs without deadline",
+			node:   drainingNode("node-busy-no-deadline", nil),
+			active: 1,
+		},
+		{
+			name:       "times out at deadline with active jobs",
+			node:       drainingNode("node-deadline", drainDeadline(0)),
+			active:     3,
+			wantReason: DrainReasonTimeout,
+			wantActive: 3,
+		},
+		{
+			name:       "times out past deadline with active jobs",
+			node:       drainingNode("node-late", drainDeadline(-time.Minute)),
+			active:     1,
+			wantReason: DrainReasonTimeout,
+			wantActive: 1,
+		},
+		{
+			name:      "drain cancelled by admin",
+			node:      drainingNode("node-cancelled", drainDeadline(-time.Minute)),
+			cancelled: true,
+		},
+		{
+			name:       "count failure leaves node draining",
+			node:       drainingNode("node-error", drainDeadline(-time.Minute)),
+			countFails: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := &fakeDrainNodes{nodes: []*models.EdgeNode{tt.node}, cancelled: map[string]bool{tt.node.ID: tt.cancelled}}
+			jobs := &fakeActiveJobs{active: map[string]int{tt.node.ID: tt.active}, failing: map[string]bool{tt.node.ID: tt.countFails}}
+			bus := events.NewBus()
+			ch, unsubscribe := bus.Subscribe(4)
+			defer unsubscribe()
+
+			monitor := &DrainMonitor{edgeNodeRepo: nodes, printJobRepo: jobs, eventBus: bus}
+			monitor.Check(drainNow)
+
+			select {
+			case event := <-ch:
+				if tt.wantReason == "" {
+					t.Fatalf("unexpected event %+v", event)
+				}
+				if event.Type != events.EventNodeDrained || event.NodeID != tt.node.ID {
+					t.Fatalf("event = %s for %s, want %s for %s", event.Type, event.NodeID, events.EventNodeDrained, tt.node.ID)
+				}
+				if event.Data["reason"] != tt.wantReason || event.Data["active_jobs"] != tt.wantActive {
+					t.Fatalf("event data = %v, want reason %s with %d active jobs", event.Data, tt.wantReason, tt.wantActive)
+				}
+			default:
+				if tt.wantReason != "" {
+					t.Fatalf("no %s event published", events.EventNodeDrained)
+				}
+			}
+
+			disabled := len(nodes.completed) == 1 && nodes.completed[0] == tt.node.ID
+			if wantDisabled := tt.wantReason != ""; disabled != wantDisabled {
+				t.Fatalf("node disabled = %v, want %v", disabled, wantDisabled)
+			}
+		})
+	}
+}
+
+// TestDrainMonitorCheckContinuesAfterError 单个节点统计失败不影响其他节点
+func TestDrainMonitorCheckContinuesAfterError(t *testing.T) {
+	nodes := &fakeDrainNodes{nodes: []*models.EdgeNode{
+		drainingNode("node-error", drainDeadline(-time.Minute)),
+		drainingNode("node-done", drainDeadline(time.Hour)),
+		drainingNode("node-late", drainDeadline(-time.Second)),
+	}}
+	jobs := &fakeActiveJobs{active: map[string]int{"node-late": 1}, failing: map[string]bool{"node-error": true}}
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe(4)
+	defer unsubscribe()
+
+	monitor := &DrainMonitor{edgeNodeRepo: nodes, printJobRepo: jobs, eventBus: bus}
+	monitor.Check(drainNow)
+
+	if len(nodes.completed) != 2 || nodes.completed[0] != "node-done" || nodes.completed[1] != "node-late" {
+		t.Fatalf("completed = %v, want [node-done node-late]", nodes.completed)
+	}
+	for _, want := range []string{DrainReasonCompleted, DrainReasonTimeout} {
+		event := <-ch
+		if event.Data["reason"] != want {
+			t.Fatalf("event %s reason = %v, want %s", event.NodeID, event.Data["reason"], want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/storage"
+	"fly-print-cloud/api/internal/thumbnail"
+)
+
+// fileRetentionBatchSize 每次清理最多处理的文件数，剩余文件在下一轮处理
+const fileRetentionBatchSize = 200
+
+// nodeMetricsDeleteBatch 删除过期节点资源采样时每条语句删除的行数，避免长事务
+const nodeMetricsDeleteBatch = 5000
+
+// FileRetentionSweeper 按 retention.files_days 删除过期的打印文件内容（连同缩略图），
+// 任务记录保留并标记 file_purged；仍被未结束任务使用的文件等任务结束后再删除。
+// 同时按 retention.spilled_text_days 删除转存到文件存储的完整错误信息，按 retention.node_metrics_days 删除节点资源采样
+type FileRetentionSweeper struct {
+	storedFileRepo *database.StoredFileRepository
+	printJobRepo   *database.PrintJobRepository
+	edgeNodeRepo   *database.EdgeNodeRepository
+	storage        storage.Storage
+	settings       *config.Store // 保留天数和清理间隔支持热更新，每次使用时读取
+}
+
+// NewFileRetentionSweeper 创建过期文件清理任务
+func NewFileRetentionSweeper(storedFileRepo *database.StoredFileRepository, printJobRepo *database.PrintJobRepository, edgeNodeRepo *database.EdgeNodeRepository, fileStorage storage.Storage, settings *config.Store) *FileRetentionSweeper {
+	return &FileRetentionSweeper{
+		storedFileRepo: storedFileRepo,
+		printJobRepo:   printJobRepo,
+		edgeNodeRepo:   edgeNodeRepo,
+		storage:        fileStorage,
+		settings:       settings,
+	}
+}
+
+// retention 文件保留时长，为 0 表示不删除
+func (w *FileRetentionSweeper) retention() time.Duration {
+	return time.Duration(w.settings.Get().Retention.FilesDays) * 24 * time.Hour
+}
+
+// interval 过期文件清理间隔
+func (w *FileRetentionSweeper) interval() time.Duration {
+	if interval := w.settings.Get().Retention.FilesSweepInterval; interval > 0 {
+		return interval
+	}
+	return time.Hour
+}
+
+// Run 启动过期文件、完整错误信息和节点资源采样的清理（阻塞）；未配置保留天数时空转，热更新开启后生效
+func (w *FileRetentionSweeper) Run() {
+	interval := w.interval()
+	log.Printf("File retention sweeper started: retention=%s, interval=%s", w.retention(), interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now().UTC()
+		w.Sweep(now)
+		w.SweepSpilledTexts(now)
+		w.SweepNodeMetrics(now)
+
+		// 清理间隔被热更新时重置定时器
+		if next := w.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// Sweep 删除 now 之前超过保留期限的文件，返回删除的文件数
+// 先删除存储中的对象再标记记录：标记失败时下一轮会重新删除（对象不存在时不报错）并补记
+func (w *FileRetentionSweeper) Sweep(now time.Time) int {
+	retention := w.retention()
+	if retention <= 0 {
+		return 0
+	}
+
+	keys, err := w.storedFileRepo.ListExpiredStoredFiles(now.Add(-retention), fileRetentionBatchSize)
+	if err != nil {
+		log.Printf("Failed to list expired stored files: %v", err)
+		return 0
+	}
+
+	purged := 0
+	for _, key := range keys {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := w.storage.Delete(ctx, key)
+		if err == nil {
+			err = w.storage.Delete(ctx, thumbnail.Key(key))
+		}
+		cancel()
+		if err != nil {
+			log.Printf("Failed to delete expired file %s: %v", key, err)
+			continue
+		}
+
+		jobs, err := w.storedFileRepo.MarkStoredFilePurged(key, now)
+		if err != nil {
+			log.Printf("Failed to mark file %s purged: %v", key, err)
+			continue
+		}
+		purged++
+		log.Printf("Expired file %s purged after %s, %d print jobs marked file_purged", key, retention, jobs)
+	}
+	return purged
+}
+
+// SweepSpilledTexts 删除结束超过 retention.spilled_text_days 的任务转存的完整错误信息，返回删除的数量
+// 任务上的截断内容保留，只清除完整内容的引用
+func (w *FileRetentionSweeper) SweepSpilledTexts(now time.Time) int {
+	retention := time.Duration(w.settings.Get().Retention.SpilledTextDays) * 24 * time.Hour
+	if retention <= 0 {
+		return 0
+	}
+
+	spilled, err := w.printJobRepo.ListExpiredErrorMessageKeys(now.Add(-retention), fileRetentionBatchSize)
+	if err != nil {
+		log.Printf("Failed to list expired spilled error messages: %v", err)
+		return 0
+	}
+
+	deleted := 0
+	for _, item := range spilled {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := w.storage.Delete(ctx, item.Key)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to delete spilled error message %s of job %s: %v", item.Key, item.JobID, err)
+			continue
+		}
+		if err := w.printJobRepo.ClearErrorMessageKey(item.JobID, item.Key); err != nil {
+			log.Printf("Failed to clear spilled error message reference of job %s: %v", item.JobID, err)
+			continue
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		log.Printf("Deleted %d spilled error messages older than %s", deleted, retention)
+	}
+	return deleted
+}
+
+// SweepNodeMetrics 删除早于 retention.node_metrics_days 的 Edge Node 资源采样，返回删除的数量
+func (w *FileRetentionSweeper) SweepNodeMetrics(now time.Time) int {
+	retention := time.Duration(w.settings.Get().Retention.NodeMetricsDays) * 24 * time.Hour
+	if retention <= 0 {
+		return 0
+	}
+
+	deleted := 0
+	for {
+		n, err := w.edgeNodeRepo.DeleteEdgeNodeMetricsBefore(now.Add(-retention), nodeMetricsDeleteBatch)
+		if err != nil {
+			log.Printf("Failed to delete expired edge node metrics: %v", err)
+			break
+		}
+		deleted += n
+		if n < nodeMetricsDeleteBatch {
+			break
+		}
+	}
+	if deleted > 0 {
+		log.Printf("Deleted %d edge node metrics samples older than %s", deleted, retention)
+	}
+	return deleted
+}
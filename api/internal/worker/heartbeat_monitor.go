@@ -0,0 +1,181 @@
+package worker
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/events"
+)
+
+// 节点离线原因（离线事件的 reason）
+const (
+	OfflineReasonHeartbeatTimeout = "heartbeat_timeout" // 超过 edge.heartbeat_timeout 没有心跳
+	OfflineReasonStaleConnection  = "stale_connection"  // WebSocket 连接仍在但长时间没有应用层心跳，已被断开
+)
+
+// HeartbeatMonitor 后台检测心跳超时的 Edge Node，并级联更新其打印机状态
+type HeartbeatMonitor struct {
+	edgeNodeRepo *database.EdgeNodeRepository
+	printerRepo  *database.PrinterRepository
+	eventBus     *events.Bus
+	settings     *config.Store // 超时、检测间隔和时钟偏差阈值支持热更新，每次使用时读取
+
+	skewMutex  sync.Mutex
+	skewWarned map[string]bool // 已发出时钟偏差告警的节点，恢复正常前不重复告警
+}
+
+// NewHeartbeatMonitor 创建心跳监控
+func NewHeartbeatMonitor(edgeNodeRepo *database.EdgeNodeRepository, printerRepo *database.PrinterRepository, eventBus *events.Bus, settings *config.Store) *HeartbeatMonitor {
+	return &HeartbeatMonitor{
+		edgeNodeRepo: edgeNodeRepo,
+		printerRepo:  printerRepo,
+		eventBus:     eventBus,
+		settings:     settings,
+		skewWarned:   make(map[string]bool),
+	}
+}
+
+// timeout 心跳超时，超时后节点标记为离线
+func (m *HeartbeatMonitor) timeout() time.Duration {
+	if timeout := m.settings.Get().Edge.HeartbeatTimeout; timeout > 0 {
+		return timeout
+	}
+	return 3 * time.Minute
+}
+
+// interval 离线检测间隔
+func (m *HeartbeatMonitor) interval() time.Duration {
+	if interval := m.settings.Get().Edge.OfflineCheckInterval; interval > 0 {
+		return interval
+	}
+	return 30 * time.Second
+}
+
+// skewLimit 时钟偏差告警阈值
+func (m *HeartbeatMonitor) skewLimit() time.Duration {
+	if limit := m.settings.Get().Edge.ClockSkewThreshold; limit > 0 {
+		return limit
+	}
+	return 30 * time.Second
+}
+
+// Run 启动心跳监控（阻塞）
+func (m *HeartbeatMonitor) Run() {
+	interval := m.interval()
+	log.Printf("Heartbeat monitor started: timeout=%s, interval=%s", m.timeout(), interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.checkOfflineNodes()
+
+		// 检测间隔被热更新时重置定时器
+		if next := m.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// checkOfflineNodes 将心跳超时的节点标记为离线，并将其打印机置为 offline
+func (m *HeartbeatMonitor) checkOfflineNodes() {
+	cutoff := time.Now().UTC().Add(-m.timeout())
+	nodes, err := m.edgeNodeRepo.MarkTimedOutNodesOffline(cutoff)
+	if err != nil {
+		log.Printf("Failed to check offline nodes: %v", err)
+		return
+	}
+
+	for _, node := range nodes {
+		m.nodeWentOffline(node.ID, OfflineReasonHeartbeatTimeout, node.PrintersOffline)
+	}
+}
+
+// NodeConnectionStale 节点的 WebSocket 连接长时间没有应用层心跳而被断开时，立即将节点标记为离线
+// 节点已是离线状态时不重复发布事件
+func (m *HeartbeatMonitor) NodeConnectionStale(nodeID string) {
+	changed, affected, err := m.edgeNodeRepo.MarkNodeOffline(nodeID)
+	if err != nil {
+		log.Printf("Failed to mark stale node %s offline: %v", nodeID, err)
+		return
+	}
+	if changed {
+		m.nodeWentOffline(nodeID, OfflineReasonStaleConnection, affected)
+	}
+}
+
+// nodeWentOffline 节点及其打印机已标记为离线：发布离线事件
+func (m *HeartbeatMonitor) nodeWentOffline(nodeID, reason string, affected int) {
+	log.Printf("Edge Node %s marked offline: %s (%d printers)", nodeID, reason, affected)
+	m.eventBus.Publish(events.Event{
+		Type:   events.EventNodeOffline,
+		NodeID: nodeID,
+		Data: map[string]interface{}{
+			"reason":           reason,
+			"printers_offline": affected,
+		},
+	})
+}
+
+// NodeSeen 记录节点心跳；节点由离线恢复为在线时恢复其打印机的最后已知状态
+func (m *HeartbeatMonitor) NodeSeen(nodeID string) error {
+	wasOffline, err := m.edgeNodeRepo.MarkNodeOnline(nodeID)
+	if err != nil {
+		return err
+	}
+
+	if wasOffline {
+		restored, err := m.printerRepo.RestorePrintersByEdgeNode(nodeID)
+		if err != nil {
+			log.Printf("Failed to restore printer status for node %s: %v", nodeID, err)
+		}
+
+		log.Printf("Edge Node %s is back online (%d printers restored)", nodeID, restored)
+		m.eventBus.Publish(events.Event{
+			Type:   events.EventNodeOnline,
+			NodeID: nodeID,
+			Data: map[string]interface{}{
+				"printers_restored": restored,
+			},
+		})
+	}
+
+	return nil
+}
+
+// ReportClockSkew 上报节点时钟偏差，超过阈值时发布告警事件（恢复到阈值以内后可再次告警）
+func (m *HeartbeatMonitor) ReportClockSkew(nodeID string, skew time.Duration) {
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+
+	limit := m.skewLimit()
+	m.skewMutex.Lock()
+	exceeded := abs > limit
+	alreadyWarned := m.skewWarned[nodeID]
+	if exceeded {
+		m.skewWarned[nodeID] = true
+	} else {
+		delete(m.skewWarned, nodeID)
+	}
+	m.skewMutex.Unlock()
+
+	if !exceeded || alreadyWarned {
+		return
+	}
+
+	log.Printf("Edge Node %s clock skew %s exceeds threshold %s, check NTP", nodeID, skew, limit)
+	m.eventBus.Publish(events.Event{
+		Type:   events.EventNodeClockSkew,
+		NodeID: nodeID,
+		Data: map[string]interface{}{
+			"clock_skew_ms": skew.Milliseconds(),
+			"threshold_ms":  limit.Milliseconds(),
+		},
+	})
+}
@@ -0,0 +1,243 @@
+package worker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/storage"
+)
+
+// exportStaleAfter running 状态的导出超过该时长未更新（每写完一个文件更新一次）时视为实例中断，可被重新领取并从游标继续
+const exportStaleAfter = 15 * time.Minute
+
+// exportUploadTimeout 单个导出文件的上传超时
+const exportUploadTimeout = 5 * time.Minute
+
+// JobExporter 执行已完成任务的归档导出：领取待执行的导出，按天分区写入 gzip 压缩的 JSONL 文件；
+// 开启 exports.daily 时每天自动创建前一天的导出。同一时间只执行一个导出，每页之间暂停 exports.page_delay，避免影响正常请求
+type JobExporter struct {
+	runRepo      *database.ExportRunRepository
+	printJobRepo *database.PrintJobRepository
+	storage      storage.Storage
+	settings     *config.Store // 导出配置支持热更新，每次使用时读取
+
+	lastScheduled string // 本实例最近一次创建每日导出的日期，避免每次检查都写数据库
+}
+
+// NewJobExporter 创建归档导出任务
+func NewJobExporter(runRepo *database.ExportRunRepository, printJobRepo *database.PrintJobRepository, fileStorage storage.Storage, settings *config.Store) *JobExporter {
+	return &JobExporter{
+		runRepo:      runRepo,
+		printJobRepo: printJobRepo,
+		storage:      fileStorage,
+		settings:     settings,
+	}
+}
+
+// cfg 当前生效的导出配置
+func (w *JobExporter) cfg() config.ExportsConfig {
+	return w.settings.Get().Exports
+}
+
+// interval 检查待执行导出的间隔
+func (w *JobExporter) interval() time.Duration {
+	if interval := w.cfg().CheckInterval; interval > 0 {
+		return interval
+	}
+	return time.Minute
+}
+
+// Run 启动归档导出（阻塞）
+func (w *JobExporter) Run() {
+	interval := w.interval()
+	log.Printf("Job exporter started: interval=%s, daily=%t", interval, w.cfg().Daily)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now().UTC()
+		w.scheduleDaily(now)
+		for w.RunNext(now) {
+		}
+
+		// 检查间隔被热更新时重置定时器
+		if next := w.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// scheduleDaily 开启每日导出时创建前一天的导出（已存在时不重复创建）
+func (w *JobExporter) scheduleDaily(now time.Time) {
+	if !w.cfg().Daily {
+		return
+	}
+	date := now.AddDate(0, 0, -1).Format(models.ExportDateLayout)
+	if date == w.lastScheduled {
+		return
+	}
+	created, err := w.runRepo.CreateScheduledExportRun(date, models.ExportFormatJSONL)
+	if err != nil {
+		log.Printf("Failed to schedule daily export for %s: %v", date, err)
+		return
+	}
+	w.lastScheduled = date
+	if created {
+		log.Printf("Daily export for %s scheduled", date)
+	}
+}
+
+// RunNext 领取并执行一个待执行的导出，没有可执行的导出时返回 false
+func (w *JobExporter) RunNext(now time.Time) bool {
+	run, err := w.runRepo.ClaimExportRun(now.Add(-exportStaleAfter))
+	if err != nil {
+		log.Printf("Failed to claim export run: %v", err)
+		return false
+	}
+	if run == nil {
+		return false
+	}
+
+	log.Printf("Export %s started: %s..%s, attempt %d, resuming after job %q", run.ID, run.FromDate, run.ToDate, run.Attempts, run.LastJobID)
+	if err := w.export(run); err != nil {
+		log.Printf("Export %s failed: %v", run.ID, err)
+		if err := w.runRepo.FinishExportRun(run.ID, models.ExportStatusFailed, err.Error()); err != nil {
+			log.Printf("Failed to mark export %s failed: %v", run.ID, err)
+		}
+		return true
+	}
+
+	if err := w.runRepo.FinishExportRun(run.ID, models.ExportStatusCompleted, ""); err != nil {
+		log.Printf("Failed to mark export %s completed: %v", run.ID, err)
+		return true
+	}
+	log.Printf("Export %s completed: %d rows in %d files", run.ID, run.RowCount, len(run.Files))
+	return true
+}
+
+// export 从游标之后继续导出日期范围内的任务，每写完一个文件记录一次游标
+func (w *JobExporter) export(run *models.ExportRun) error {
+	if run.Format != models.ExportFormatJSONL {
+		return fmt.Errorf("unsupported export format: %s", run.Format)
+	}
+	from, err := time.Parse(models.ExportDateLayout, run.FromDate)
+	if err != nil {
+		return fmt.Errorf("invalid from_date: %w", err)
+	}
+	to, err := time.Parse(models.ExportDateLayout, run.ToDate)
+	if err != nil {
+		return fmt.Errorf("invalid to_date: %w", err)
+	}
+
+	var cursor *database.JobCursor
+	if run.LastJobID != "" && run.LastJobCreatedAt != nil {
+		cursor = &database.JobCursor{CreatedAt: *run.LastJobCreatedAt, ID: run.LastJobID}
+	}
+	parts := make(map[string]int) // 每个分区已写入的文件数，继续导出时文件编号接着往后排
+	for _, file := range run.Files {
+		parts[file.Date]++
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		if cursor != nil && !cursor.CreatedAt.Before(next) {
+			continue // 该日已导出
+		}
+		date := day.Format(models.ExportDateLayout)
+		for {
+			file, last, err := w.exportFile(run.ID, date, parts[date], day, next, cursor)
+			if err != nil {
+				return err
+			}
+			if file == nil {
+				break
+			}
+			if err := w.runRepo.RecordExportFile(run.ID, *file, last); err != nil {
+				return err
+			}
+			run.Files = append(run.Files, *file)
+			run.RowCount += int64(file.Rows)
+			parts[date]++
+			cursor = &database.JobCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+			if file.Rows < w.cfg().RowsPerFile {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// exportFile 从游标之后读取 [day, next) 内的任务写入一个文件（最多 exports.rows_per_file 行），返回文件和最后一个任务；没有任务时返回 nil
+func (w *JobExporter) exportFile(runID, date string, part int, day, next time.Time, cursor *database.JobCursor) (*models.ExportFile, *models.PrintJob, error) {
+	cfg := w.cfg()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+
+	rows := 0
+	var last *models.PrintJob
+	for rows < cfg.RowsPerFile {
+		if last != nil && cfg.PageDelay > 0 {
+			time.Sleep(cfg.PageDelay)
+		}
+		limit := min(cfg.PageSize, cfg.RowsPerFile-rows)
+		jobs, err := w.printJobRepo.ListCompletedJobsForExport(day, next, cursor, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, job := range jobs {
+			if err := encoder.Encode(job); err != nil {
+				return nil, nil, fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+			}
+		}
+		rows += len(jobs)
+		if len(jobs) > 0 {
+			last = jobs[len(jobs)-1]
+			cursor = &database.JobCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+		if len(jobs) < limit {
+			break
+		}
+	}
+	if rows == 0 {
+		return nil, nil, nil
+	}
+	if err := gz.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to compress export file: %w", err)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	file := &models.ExportFile{
+		Date:   date,
+		Key:    ExportFileKey(cfg.Prefix, date, runID, part),
+		Rows:   rows,
+		Bytes:  int64(buf.Len()),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportUploadTimeout)
+	defer cancel()
+	if err := w.storage.Put(ctx, file.Key, bytes.NewReader(buf.Bytes()), file.Bytes, "application/gzip"); err != nil {
+		return nil, nil, fmt.Errorf("failed to upload %s: %w", file.Key, err)
+	}
+	return file, last, nil
+}
+
+// ExportFileKey 导出文件的对象键：<prefix>/print_jobs/dt=YYYY-MM-DD/<导出ID>-part-NNNNN.jsonl.gz
+func ExportFileKey(prefix, date, runID string, part int) string {
+	return path.Join(strings.Trim(prefix, "/"), "print_jobs", "dt="+date, fmt.Sprintf("%s-part-%05d.jsonl.gz", runID, part))
+}
@@ -0,0 +1,20 @@
+package worker
+
+import "testing"
+
+func TestExportFileKey(t *testing.T) {
+	tests := []struct {
+		prefix string
+		part   int
+		want   string
+	}{
+		{"exports", 0, "exports/print_jobs/dt=2026-03-01/run-1-part-00000.jsonl.gz"},
+		{"/lake/exports/", 12, "lake/exports/print_jobs/dt=2026-03-01/run-1-part-00012.jsonl.gz"},
+		{"", 3, "print_jobs/dt=2026-03-01/run-1-part-00003.jsonl.gz"},
+	}
+	for _, tt := range tests {
+		if got := ExportFileKey(tt.prefix, "2026-03-01", "run-1", tt.part); got != tt.want {
+			t.Errorf("ExportFileKey(%q, %d) = %q, want %q", tt.prefix, tt.part, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package worker
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+)
+
+// ErrWakeTimeout 打印机未能在等待时间内唤醒并就绪
+var ErrWakeTimeout = errors.New("printer did not wake up in time")
+
+// wakePollInterval 等待唤醒时检查打印机状态的间隔
+const wakePollInterval = 2 * time.Second
+
+// PowerCommander 向 Edge Node 下发打印机电源指令（由 websocket 包实现，避免循环依赖）
+type PowerCommander interface {
+	SendPrinterPower(nodeID, printerID, printerName, action string) error
+}
+
+// PowerScheduler 按节能计划让打印机休眠/唤醒，并在休眠时段内为新任务唤醒打印机
+type PowerScheduler struct {
+	scheduleRepo *database.PowerScheduleRepository
+	printerRepo  *database.PrinterRepository
+	printJobRepo *database.PrintJobRepository
+	commander    PowerCommander
+	settings     *config.Store // 检查间隔、唤醒超时和重新休眠延迟支持热更新，每次使用时读取
+
+	mutex    sync.Mutex
+	lastSent map[string]powerCommand // 按计划最近一次下发的指令（printer_id -> 指令）
+	wokenAt  map[string]time.Time    // 休眠时段内因任务被唤醒的时间
+}
+
+type powerCommand struct {
+	action string
+	at     time.Time
+}
+
+// NewPowerScheduler 创建节能计划调度
+func NewPowerScheduler(scheduleRepo *database.PowerScheduleRepository, printerRepo *database.PrinterRepository, printJobRepo *database.PrintJobRepository, commander PowerCommander, settings *config.Store) *PowerScheduler {
+	return &PowerScheduler{
+		scheduleRepo: scheduleRepo,
+		printerRepo:  printerRepo,
+		printJobRepo: printJobRepo,
+		commander:    commander,
+		settings:     settings,
+		lastSent:     make(map[string]powerCommand),
+		wokenAt:      make(map[string]time.Time),
+	}
+}
+
+// interval 节能计划检查间隔
+func (s *PowerScheduler) interval() time.Duration {
+	if interval := s.settings.Get().Power.CheckInterval; interval > 0 {
+		return interval
+	}
+	return time.Minute
+}
+
+// wakeTimeout 等待打印机唤醒的最长时间
+func (s *PowerScheduler) wakeTimeout() time.Duration {
+	if timeout := s.settings.Get().Power.WakeTimeout; timeout > 0 {
+		return timeout
+	}
+	return 2 * time.Minute
+}
+
+// resleepDelay 被唤醒的打印机空闲多久后重新休眠
+func (s *PowerScheduler) resleepDelay() time.Duration {
+	if delay := s.settings.Get().Power.ResleepDelay; delay > 0 {
+		return delay
+	}
+	return 15 * time.Minute
+}
+
+// Run 启动节能计划调度（阻塞）
+func (s *PowerScheduler) Run() {
+	interval := s.interval()
+	log.Printf("Power scheduler started: interval=%s, wake_timeout=%s", interval, s.wakeTimeout())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.apply(time.Now())
+
+		// 检查间隔被热更新时重置定时器
+		if next := s.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// apply 到达计划边界时下发 sleep/wake 指令
+func (s *PowerScheduler) apply(now time.Time) {
+	targets, err := s.scheduleRepo.ListPowerTargets()
+	if err != nil {
+		log.Printf("Failed to list power schedule targets: %v", err)
+		return
+	}
+
+	for _, target := range targets {
+		action := models.PowerActionWake
+		if target.Schedule.AsleepAt(now) {
+			action = models.PowerActionSleep
+		}
+		if !s.shouldSend(target, action, now) {
+			continue
+		}
+		// 有任务在执行或排队时推迟休眠，下次检查时重试
+		if action == models.PowerActionSleep && !s.idle(target.PrinterID) {
+			continue
+		}
+
+		if err := s.commander.SendPrinterPower(target.EdgeNodeID, target.PrinterID, target.PrinterName, action); err != nil {
+			log.Printf("Failed to send %s command to printer %s on node %s: %v", action, target.PrinterID, target.EdgeNodeID, err)
+			continue
+		}
+
+		s.mutex.Lock()
+		s.lastSent[target.PrinterID] = powerCommand{action: action, at: now}
+		delete(s.wokenAt, target.PrinterID)
+		s.mutex.Unlock()
+		log.Printf("Power schedule: sent %s to printer %s on node %s", action, target.PrinterID, target.EdgeNodeID)
+	}
+}
+
+// shouldSend 判断是否需要向打印机下发指令
+func (s *PowerScheduler) shouldSend(target *models.PrinterPowerTarget, action string, now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if action == models.PowerActionWake {
+		delete(s.wokenAt, target.PrinterID)
+	} else if wokenAt, ok := s.wokenAt[target.PrinterID]; ok {
+		// 因任务被唤醒的打印机空闲超过 resleepDelay 后重新休眠
+		return now.Sub(wokenAt) >= s.resleepDelay()
+	}
+
+	last, ok := s.lastSent[target.PrinterID]
+	if !ok || last.action != action {
+		// 计划边界（或服务启动后首次检查）：节点已上报为目标状态时无需下发
+		return !reportedAs(target.PowerState, action)
+	}
+	// 已下发过相同指令但节点上报的状态仍不一致（如被手动唤醒），间隔 resleepDelay 后重发
+	return target.PowerState != "" && !reportedAs(target.PowerState, action) && now.Sub(last.at) >= s.resleepDelay()
+}
+
+// reportedAs 节点上报的电源状态是否已满足指令
+func reportedAs(powerState, action string) bool {
+	if action == models.PowerActionSleep {
+		return powerState == models.PrinterPowerAsleep
+	}
+	return powerState == models.PrinterPowerAwake || powerState == models.PrinterPowerWaking
+}
+
+// idle 打印机没有执行中或排队中的任务
+func (s *PowerScheduler) idle(printerID string) bool {
+	active, queued, err := s.printJobRepo.CountPrinterJobLoad(printerID)
+	if err != nil {
+		log.Printf("Failed to count job load for printer %s: %v", printerID, err)
+		return false
+	}
+	return active == 0 && queued == 0
+}
+
+// NeedsWake 打印机是否需要先唤醒：节点上报为休眠，或未上报电源状态但处于计划休眠时段
+func (s *PowerScheduler) NeedsWake(printer *models.Printer) bool {
+	switch printer.PowerState {
+	case models.PrinterPowerAsleep, models.PrinterPowerWaking:
+		return true
+	case models.PrinterPowerAwake:
+		return false
+	}
+
+	schedule, err := s.scheduleRepo.GetEffectiveSchedule(printer.ID, printer.EdgeNodeID)
+	if err != nil {
+		log.Printf("Failed to get power schedule for printer %s: %v", printer.ID, err)
+		return false
+	}
+	now := time.Now()
+	if !schedule.AsleepAt(now) {
+		return false
+	}
+
+	// 刚因任务唤醒过的打印机仍处于唤醒状态
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	wokenAt, ok := s.wokenAt[printer.ID]
+	return !ok || now.Sub(wokenAt) >= s.resleepDelay()
+}
+
+// WakeForJob 下发唤醒指令并等待节点上报打印机就绪，超时返回 ErrWakeTimeout
+func (s *PowerScheduler) WakeForJob(printer *models.Printer) error {
+	s.mutex.Lock()
+	s.wokenAt[printer.ID] = time.Now()
+	s.mutex.Unlock()
+
+	// 置为 waking，节点随后上报的任意状态都会结束该状态
+	if err := s.printerRepo.SetPowerState(printer.ID, models.PrinterPowerWaking); err != nil {
+		return err
+	}
+	if err := s.commander.SendPrinterPower(printer.EdgeNodeID, printer.ID, printer.Name, models.PowerActionWake); err != nil {
+		return err
+	}
+	log.Printf("Waking printer %s on node %s for incoming job", printer.ID, printer.EdgeNodeID)
+
+	deadline := time.NewTimer(s.wakeTimeout())
+	defer deadline.Stop()
+	ticker := time.NewTicker(wakePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-deadline.C:
+			return ErrWakeTimeout
+		case <-ticker.C:
+		}
+
+		current, err := s.printerRepo.GetPrinterByID(printer.ID)
+		if err != nil {
+			log.Printf("Failed to check printer %s while waking: %v", printer.ID, err)
+			continue
+		}
+		if current.PowerState == models.PrinterPowerWaking || current.PowerState == models.PrinterPowerAsleep {
+			continue
+		}
+		if current.Status == models.PrinterStatusReady || current.Status == models.PrinterStatusPrinting {
+			return nil
+		}
+	}
+}
+
+// WakeTimeout 等待唤醒的最长时间
+func (s *PowerScheduler) WakeTimeout() time.Duration {
+	return s.wakeTimeout()
+}
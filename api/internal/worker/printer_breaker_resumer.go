@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/models"
+)
+
+// PrinterBreakerResumer 熔断冷却结束后恢复打印机分发，并分发熔断期间排队的任务
+type PrinterBreakerResumer struct {
+	printerRepo *database.PrinterRepository
+	dispatcher  QueueDispatcher
+	eventBus    *events.Bus
+	settings    *config.Store // 熔断阈值和检查间隔支持热更新，每次使用时读取
+}
+
+// NewPrinterBreakerResumer 创建熔断恢复任务
+func NewPrinterBreakerResumer(printerRepo *database.PrinterRepository, dispatcher QueueDispatcher, eventBus *events.Bus, settings *config.Store) *PrinterBreakerResumer {
+	return &PrinterBreakerResumer{
+		printerRepo: printerRepo,
+		dispatcher:  dispatcher,
+		eventBus:    eventBus,
+		settings:    settings,
+	}
+}
+
+// interval 冷却结束的检查间隔
+func (w *PrinterBreakerResumer) interval() time.Duration {
+	if interval := w.settings.Get().Jobs.BreakerCheckInterval; interval > 0 {
+		return interval
+	}
+	return 30 * time.Second
+}
+
+// Run 启动熔断恢复（阻塞）
+func (w *PrinterBreakerResumer) Run() {
+	interval := w.interval()
+	log.Printf("Printer breaker resumer started: interval=%s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.resume(time.Now().UTC())
+
+		// 检查间隔被热更新时重置定时器
+		if next := w.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// resume 恢复冷却已结束的打印机并分发其排队任务
+func (w *PrinterBreakerResumer) resume(now time.Time) {
+	printerIDs, err := w.printerRepo.ListBreakersDueToResume(now)
+	if err != nil {
+		log.Printf("Failed to list suspended printers: %v", err)
+		return
+	}
+
+	jobs := w.settings.Get().Jobs
+	policy := models.BreakerPolicy{Failures: jobs.BreakerFailures, Window: jobs.BreakerWindow, Cooldown: jobs.BreakerCooldown}
+	for _, printerID := range printerIDs {
+		resumed := false
+		before, _, err := w.printerRepo.ModifyPrinterBreaker(printerID, func(b models.PrinterBreaker) models.PrinterBreaker {
+			b, resumed = policy.Resume(b, now)
+			return b
+		})
+		if err != nil {
+			log.Printf("Failed to resume printer %s breaker: %v", printerID, err)
+			continue
+		}
+		if !resumed {
+			continue // 已被管理员重置
+		}
+
+		printer, err := w.printerRepo.GetPrinterByID(printerID)
+		if err != nil || printer == nil {
+			log.Printf("Failed to get printer %s after breaker resumed: %v", printerID, err)
+			continue
+		}
+		log.Printf("Printer %s resumed after breaker cooldown", printerID)
+		w.eventBus.Publish(events.Event{
+			Type:   events.EventPrinterResumed,
+			NodeID: printer.EdgeNodeID,
+			Data: map[string]interface{}{
+				"printer_id":   printer.ID,
+				"printer_name": printer.Name,
+				"suspended_at": before.SuspendedAt,
+				"trigger":      "cooldown",
+			},
+		})
+		w.dispatcher.DispatchQueued(printer)
+	}
+}
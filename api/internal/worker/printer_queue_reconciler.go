@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+)
+
+// PrinterQueueReconciler 定期按云端未结束的任务数核对打印机队列长度
+// 节点只在状态消息中上报本地队列，云端排队、完成的任务不会反映到 queue_length；
+// 核对结果写入 cloud_queue_length，不覆盖节点上报的 edge_queue_length
+type PrinterQueueReconciler struct {
+	printerRepo *database.PrinterRepository
+	settings    *config.Store // 核对间隔支持热更新，每次使用时读取
+}
+
+// NewPrinterQueueReconciler 创建队列长度核对任务
+func NewPrinterQueueReconciler(printerRepo *database.PrinterRepository, settings *config.Store) *PrinterQueueReconciler {
+	return &PrinterQueueReconciler{
+		printerRepo: printerRepo,
+		settings:    settings,
+	}
+}
+
+// interval 核对间隔
+func (w *PrinterQueueReconciler) interval() time.Duration {
+	if interval := w.settings.Get().Jobs.QueueReconcileInterval; interval > 0 {
+		return interval
+	}
+	return time.Minute
+}
+
+// Run 启动队列长度核对（阻塞）
+func (w *PrinterQueueReconciler) Run() {
+	interval := w.interval()
+	log.Printf("Printer queue reconciler started: interval=%s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.reconcile()
+
+		// 核对间隔被热更新时重置定时器
+		if next := w.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// reconcile 执行一次核对；其他实例正在核对时跳过本轮
+func (w *PrinterQueueReconciler) reconcile() {
+	printerIDs, acquired, err := w.printerRepo.ReconcileQueueLengths()
+	if err != nil {
+		log.Printf("Failed to reconcile printer queue lengths: %v", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+	if len(printerIDs) > 0 {
+		log.Printf("Reconciled queue length of %d printers", len(printerIDs))
+	}
+}
@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+)
+
+// QueueResumeNotifier 队列恢复后通知 Edge Node 并分发排队任务（由 dispatch 包实现，避免循环依赖）
+type QueueResumeNotifier interface {
+	QueueResumed(printer *models.Printer)
+}
+
+// PrinterQueueResumer 暂停超过 jobs.max_queue_pause 的打印机队列自动恢复，避免管理员忘记恢复
+type PrinterQueueResumer struct {
+	printerRepo *database.PrinterRepository
+	auditRepo   *database.AuditLogRepository
+	dispatcher  QueueResumeNotifier
+	settings    *config.Store // 检查间隔支持热更新，每次使用时读取
+}
+
+// NewPrinterQueueResumer 创建队列暂停自动恢复任务
+func NewPrinterQueueResumer(printerRepo *database.PrinterRepository, auditRepo *database.AuditLogRepository, dispatcher QueueResumeNotifier, settings *config.Store) *PrinterQueueResumer {
+	return &PrinterQueueResumer{
+		printerRepo: printerRepo,
+		auditRepo:   auditRepo,
+		dispatcher:  dispatcher,
+		settings:    settings,
+	}
+}
+
+// interval 自动恢复时间的检查间隔
+func (w *PrinterQueueResumer) interval() time.Duration {
+	if interval := w.settings.Get().Jobs.QueuePauseCheckInterval; interval > 0 {
+		return interval
+	}
+	return time.Minute
+}
+
+// Run 启动队列暂停自动恢复（阻塞）
+func (w *PrinterQueueResumer) Run() {
+	interval := w.interval()
+	log.Printf("Printer queue resumer started: interval=%s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.resume(time.Now().UTC())
+
+		// 检查间隔被热更新时重置定时器
+		if next := w.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// resume 恢复已到自动恢复时间的队列，记录审计日志并分发排队任务
+func (w *PrinterQueueResumer) resume(now time.Time) {
+	printerIDs, err := w.printerRepo.ExpireQueuePauses(now)
+	if err != nil {
+		log.Printf("Failed to expire printer queue pauses: %v", err)
+		return
+	}
+
+	for _, printerID := range printerIDs {
+		log.Printf("Printer %s queue resumed automatically after max pause", printerID)
+		w.recordAudit(printerID)
+
+		printer, err := w.printerRepo.GetPrinterByID(printerID)
+		if err != nil || printer == nil {
+			log.Printf("Failed to get printer %s after queue resumed: %v", printerID, err)
+			continue
+		}
+		w.dispatcher.QueueResumed(printer)
+	}
+}
+
+// recordAudit 以系统身份记录自动恢复（失败只记录日志）
+func (w *PrinterQueueResumer) recordAudit(printerID string) {
+	if w.auditRepo == nil {
+		return
+	}
+	entry := &models.AuditLog{
+		Actor:        "system",
+		Action:       "printer.queue_resume",
+		ResourceType: "printer",
+		ResourceID:   printerID,
+		Details:      fmt.Sprintf("trigger=auto max_pause=%s", w.settings.Get().Jobs.MaxQueuePause),
+	}
+	if err := w.auditRepo.CreateAuditLog(entry); err != nil {
+		log.Printf("Failed to record audit log for printer %s queue resume: %v", printerID, err)
+	}
+}
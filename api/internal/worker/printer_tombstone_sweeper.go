@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/database"
+)
+
+// PrinterTombstoneSweeper 后台清除到期的打印机墓碑（到期后节点可重新注册该打印机）
+type PrinterTombstoneSweeper struct {
+	printerRepo *database.PrinterRepository
+	interval    time.Duration
+}
+
+// NewPrinterTombstoneSweeper 创建打印机墓碑清理任务
+func NewPrinterTombstoneSweeper(printerRepo *database.PrinterRepository, interval time.Duration) *PrinterTombstoneSweeper {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	return &PrinterTombstoneSweeper{
+		printerRepo: printerRepo,
+		interval:    interval,
+	}
+}
+
+// Run 启动清理任务（阻塞）
+func (w *PrinterTombstoneSweeper) Run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.sweep()
+	}
+}
+
+// sweep 删除到期的墓碑
+func (w *PrinterTombstoneSweeper) sweep() {
+	deleted, err := w.printerRepo.DeleteExpiredPrinterTombstones(time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to delete expired printer tombstones: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Deleted %d expired printer tombstones", deleted)
+	}
+}
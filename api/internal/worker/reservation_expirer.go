@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/models"
+)
+
+// QueueDispatcher 分发打印机的排队任务（由 dispatch 包实现，避免循环依赖）
+type QueueDispatcher interface {
+	DispatchQueued(printer *models.Printer)
+}
+
+// ReservationExpirer 清理到期的打印机预留，并分发预留期间排队的其他用户任务
+type ReservationExpirer struct {
+	printerRepo *database.PrinterRepository
+	dispatcher  QueueDispatcher
+	settings    *config.Store // 检查间隔支持热更新，每次使用时读取
+}
+
+// NewReservationExpirer 创建预留到期清理
+func NewReservationExpirer(printerRepo *database.PrinterRepository, dispatcher QueueDispatcher, settings *config.Store) *ReservationExpirer {
+	return &ReservationExpirer{
+		printerRepo: printerRepo,
+		dispatcher:  dispatcher,
+		settings:    settings,
+	}
+}
+
+// interval 到期预留的检查间隔
+func (w *ReservationExpirer) interval() time.Duration {
+	if interval := w.settings.Get().Reservations.CheckInterval; interval > 0 {
+		return interval
+	}
+	return 15 * time.Second
+}
+
+// Run 启动预留到期清理（阻塞）
+func (w *ReservationExpirer) Run() {
+	interval := w.interval()
+	log.Printf("Reservation expirer started: interval=%s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.expire()
+
+		// 检查间隔被热更新时重置定时器
+		if next := w.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// expire 清除到期的预留并分发对应打印机的排队任务
+func (w *ReservationExpirer) expire() {
+	printerIDs, err := w.printerRepo.ExpireReservations(time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to expire printer reservations: %v", err)
+		return
+	}
+	for _, printerID := range printerIDs {
+		log.Printf("Reservation of printer %s expired", printerID)
+		printer, err := w.printerRepo.GetPrinterByID(printerID)
+		if err != nil {
+			log.Printf("Failed to get printer %s after reservation expired: %v", printerID, err)
+			continue
+		}
+		w.dispatcher.DispatchQueued(printer)
+	}
+}
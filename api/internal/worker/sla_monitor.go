@@ -0,0 +1,280 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/models"
+)
+
+// slaBatchSize SLA 巡检每次查询的任务数
+const slaBatchSize = 500
+
+// SLAMonitor 打印任务 SLA 考核：执行中的任务超过阈值时标记超时并发布事件；
+// 任务结束后按当时的阈值完成最终评估，之后阈值变化不再影响已结束的任务。
+// 同时按统计窗口计算超时率，达到告警阈值时发布告警事件（回落后再次达到时重新发布）
+type SLAMonitor struct {
+	printJobRepo *database.PrintJobRepository
+	groupRepo    *database.PrinterGroupRepository
+	eventBus     *events.Bus
+	settings     *config.Store // 阈值和巡检间隔支持热更新，每次使用时读取
+
+	alerting bool // 超时率告警中
+}
+
+// NewSLAMonitor 创建 SLA 考核巡检
+func NewSLAMonitor(printJobRepo *database.PrintJobRepository, groupRepo *database.PrinterGroupRepository, eventBus *events.Bus, settings *config.Store) *SLAMonitor {
+	return &SLAMonitor{
+		printJobRepo: printJobRepo,
+		groupRepo:    groupRepo,
+		eventBus:     eventBus,
+		settings:     settings,
+	}
+}
+
+// interval SLA 巡检间隔
+func (m *SLAMonitor) interval() time.Duration {
+	if interval := m.settings.Get().SLA.CheckInterval; interval > 0 {
+		return interval
+	}
+	return 30 * time.Second
+}
+
+// Run 启动 SLA 考核巡检（阻塞）
+func (m *SLAMonitor) Run() {
+	interval := m.interval()
+	cfg := m.settings.Get().SLA
+	log.Printf("SLA monitor started: dispatch=%s, start=%s, complete=%s, interval=%s",
+		cfg.TimeToDispatch, cfg.TimeToStart, cfg.TimeToComplete, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.evaluate()
+		m.checkBreachRate()
+
+		// 巡检间隔被热更新时重置定时器
+		if next := m.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// evaluate 评估全部尚未完成最终评估的任务
+func (m *SLAMonitor) evaluate() {
+	cfg := m.settings.Get().SLA
+	groups := m.loadGroups()
+
+	var cursor *database.JobCursor
+	for {
+		// 先取时间再查询：任务在该时间点之前的状态已反映在查询结果中，据此判定的超时不会误报
+		now := time.Now().UTC()
+		jobs, err := m.printJobRepo.ListSLAPendingJobs(cursor, slaBatchSize)
+		if err != nil {
+			log.Printf("Failed to list jobs for SLA evaluation: %v", err)
+			return
+		}
+		for _, job := range jobs {
+			m.evaluateJob(job, slaThresholds(&cfg, groups[job.PrinterGroupID]), now)
+		}
+		if len(jobs) < slaBatchSize {
+			return
+		}
+		last := jobs[len(jobs)-1]
+		cursor = &database.JobCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// loadGroups 加载打印机组的 SLA 覆盖，失败时全部任务使用全局阈值
+func (m *SLAMonitor) loadGroups() map[string]*models.PrinterGroup {
+	groups := make(map[string]*models.PrinterGroup)
+	list, err := m.groupRepo.ListGroups()
+	if err != nil {
+		log.Printf("Failed to load printer groups for SLA evaluation: %v", err)
+		return groups
+	}
+	for _, group := range list {
+		groups[group.ID] = group
+	}
+	return groups
+}
+
+// evaluateJob 评估单个任务并保存新增的超时标记，任务已结束时记录最终评估
+func (m *SLAMonitor) evaluateJob(job *models.PrintJob, thresholds map[models.SLAPhase]time.Duration, now time.Time) {
+	previous := models.JobSLA{}
+	if job.SLA != nil {
+		previous = *job.SLA
+	}
+	result, elapsed := evaluateSLA(job, &previous, thresholds, now)
+
+	var breached []models.SLAPhase
+	for _, phase := range models.AllSLAPhases {
+		if result.Breached(phase) && !previous.Breached(phase) {
+			breached = append(breached, phase)
+		}
+	}
+	if len(breached) == 0 && result.EvaluatedAt == nil {
+		return
+	}
+
+	applied, err := m.printJobRepo.UpdateJobSLA(job.ID, &result)
+	if err != nil {
+		log.Printf("Failed to save SLA result for job %s: %v", job.ID, err)
+		return
+	}
+	if !applied {
+		return
+	}
+
+	for _, phase := range breached {
+		log.Printf("Print job %s on printer %s breached %s SLA: %s > %s", job.ID, job.PrinterID, phase, elapsed[phase].Round(time.Second), thresholds[phase])
+		m.eventBus.Publish(events.Event{
+			Type: events.EventJobSLABreached,
+			Data: map[string]interface{}{
+				"job_id":            job.ID,
+				"printer_id":        job.PrinterID,
+				"printer_group_id":  job.PrinterGroupID,
+				"status":            job.Status,
+				"source":            job.Source,
+				"phase":             phase,
+				"threshold_seconds": int(thresholds[phase].Seconds()),
+				"elapsed_seconds":   int(elapsed[phase].Seconds()),
+			},
+		})
+	}
+}
+
+// slaThresholds 任务适用的各阶段阈值：打印机组的覆盖优先，否则使用全局配置
+func slaThresholds(cfg *config.SLAConfig, group *models.PrinterGroup) map[models.SLAPhase]time.Duration {
+	thresholds := map[models.SLAPhase]time.Duration{
+		models.SLAPhaseDispatch: cfg.TimeToDispatch,
+		models.SLAPhaseStart:    cfg.TimeToStart,
+		models.SLAPhaseComplete: cfg.TimeToComplete,
+	}
+	if group == nil || group.SLA == nil {
+		return thresholds
+	}
+	overrides := map[models.SLAPhase]*int{
+		models.SLAPhaseDispatch: group.SLA.TimeToDispatch,
+		models.SLAPhaseStart:    group.SLA.TimeToStart,
+		models.SLAPhaseComplete: group.SLA.TimeToComplete,
+	}
+	for phase, seconds := range overrides {
+		if seconds != nil {
+			thresholds[phase] = time.Duration(*seconds) * time.Second
+		}
+	}
+	return thresholds
+}
+
+// evaluateSLA 计算各阶段耗时并判定是否超时，阈值为 0 的阶段不考核
+// 已到达的阶段按到达时间计算；执行中尚未到达的按当前时间计算（已超过阈值即判定超时）；
+// 已结束但未到达的（如排队中被取消、打印失败）按结束时间计算；
+// 按营业时间排到之后的任务从计划时间（not_before）开始计算
+func evaluateSLA(job *models.PrintJob, previous *models.JobSLA, thresholds map[models.SLAPhase]time.Duration, now time.Time) (models.JobSLA, map[models.SLAPhase]time.Duration) {
+	result := *previous
+	elapsed := make(map[models.SLAPhase]time.Duration)
+	terminal := job.Status.IsTerminal()
+	submitted := job.CreatedAt
+	if job.NotBefore != nil && job.NotBefore.After(submitted) {
+		submitted = *job.NotBefore
+	}
+
+	for _, phase := range models.AllSLAPhases {
+		threshold := thresholds[phase]
+		if threshold <= 0 {
+			continue
+		}
+		end := now
+		if reached := slaReachedAt(job, phase); reached != nil {
+			end = *reached
+		} else if terminal && job.EndTime != nil {
+			end = *job.EndTime
+		}
+		elapsed[phase] = end.Sub(submitted)
+		if elapsed[phase] > threshold {
+			result.SetBreached(phase)
+		}
+	}
+
+	if terminal {
+		result.EvaluatedAt = &now
+	}
+	return result, elapsed
+}
+
+// slaReachedAt 任务到达考核阶段的时间，尚未到达时返回 nil
+// 节点可能不上报中间状态：已开始打印的任务视为已下发，已完成的任务视为已开始
+func slaReachedAt(job *models.PrintJob, phase models.SLAPhase) *time.Time {
+	var completedAt *time.Time
+	if job.Status == models.JobStatusCompleted {
+		completedAt = job.EndTime
+	}
+
+	switch phase {
+	case models.SLAPhaseDispatch:
+		if job.DispatchedAt != nil {
+			return job.DispatchedAt
+		}
+		if job.StartTime != nil {
+			return job.StartTime
+		}
+		return completedAt
+	case models.SLAPhaseStart:
+		if job.StartTime != nil {
+			return job.StartTime
+		}
+		return completedAt
+	case models.SLAPhaseComplete:
+		return completedAt
+	}
+	return nil
+}
+
+// checkBreachRate 计算统计窗口内的超时率，越过告警阈值时发布事件
+func (m *SLAMonitor) checkBreachRate() {
+	cfg := m.settings.Get().SLA
+	if cfg.AlertBreachRate <= 0 || cfg.AlertWindow <= 0 {
+		m.alerting = false
+		return
+	}
+
+	jobs, breached, err := m.printJobRepo.SLABreachRate(time.Now().UTC().Add(-cfg.AlertWindow))
+	if err != nil {
+		log.Printf("Failed to compute SLA breach rate: %v", err)
+		return
+	}
+	if jobs == 0 || jobs < cfg.AlertMinJobs {
+		return
+	}
+
+	rate := float64(breached) / float64(jobs)
+	exceeded := rate >= cfg.AlertBreachRate
+	if exceeded == m.alerting {
+		return
+	}
+	m.alerting = exceeded
+
+	eventType := events.EventSLABreachRateRecovered
+	if exceeded {
+		eventType = events.EventSLABreachRateExceeded
+		log.Printf("SLA breach rate %.1f%% over the last %s exceeds %.1f%% (%d of %d jobs)", rate*100, cfg.AlertWindow, cfg.AlertBreachRate*100, breached, jobs)
+	} else {
+		log.Printf("SLA breach rate recovered to %.1f%% over the last %s (%d of %d jobs)", rate*100, cfg.AlertWindow, breached, jobs)
+	}
+	m.eventBus.Publish(events.Event{
+		Type: eventType,
+		Data: map[string]interface{}{
+			"window_seconds": int(cfg.AlertWindow.Seconds()),
+			"jobs":           jobs,
+			"breached_jobs":  breached,
+			"breach_rate":    rate,
+			"threshold":      cfg.AlertBreachRate,
+		},
+	})
+}
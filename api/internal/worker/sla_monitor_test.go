@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/models"
+)
+
+var slaNow = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+func testThresholds() map[models.SLAPhase]time.Duration {
+	return map[models.SLAPhase]time.Duration{
+		models.SLAPhaseDispatch: time.Minute,
+		models.SLAPhaseStart:    5 * time.Minute,
+		models.SLAPhaseComplete: 30 * time.Minute,
+	}
+}
+
+func at(d time.Duration) *time.Time {
+	t := slaNow.Add(d)
+	return &t
+}
+
+func TestEvaluateSLA(t *testing.T) {
+	tests := []struct {
+		name          string
+		job           models.PrintJob
+		previous      models.JobSLA
+		want          models.JobSLA
+		wantEvaluated bool
+	}{
+		{
+			name: "pending past dispatch threshold",
+			job:  models.PrintJob{Status: models.JobStatusPending, CreatedAt: slaNow.Add(-2 * time.Minute)},
+			want: models.JobSLA{DispatchBreached: true},
+		},
+		{
+			name: "printing within thresholds",
+			job: models.PrintJob{Status: models.JobStatusPrinting, CreatedAt: slaNow.Add(-4 * time.Minute),
+				DispatchedAt: at(-230 * time.Second), StartTime: at(-3 * time.Minute)},
+		},
+		{
+			name: "completed late",
+			job: models.PrintJob{Status: models.JobStatusCompleted, CreatedAt: slaNow.Add(-time.Hour),
+				DispatchedAt: at(-59*time.Minute - 30*time.Second), StartTime: at(-50 * time.Minute), EndTime: at(-10 * time.Minute)},
+			want:          models.JobSLA{StartBreached: true, CompleteBreached: true},
+			wantEvaluated: true,
+		},
+		{
+			name: "completed without intermediate reports",
+			job: models.PrintJob{Status: models.JobStatusCompleted, CreatedAt: slaNow.Add(-10 * time.Minute),
+				EndTime: at(-9*time.Minute - 30*time.Second)},
+			wantEvaluated: true,
+		},
+		{
+			name: "cancelled while queued uses end time",
+			job: models.PrintJob{Status: models.JobStatusCancelled, CreatedAt: slaNow.Add(-time.Hour),
+				EndTime: at(-time.Hour + 30*time.Second)},
+			wantEvaluated: true,
+		},
+		{
+			name: "scheduled job measured from not_before",
+			job: models.PrintJob{Status: models.JobStatusQueued, CreatedAt: slaNow.Add(-12 * time.Hour),
+				NotBefore: at(-30 * time.Second)},
+		},
+		{
+			name:          "previous breach kept",
+			job:           models.PrintJob{Status: models.JobStatusFailed, CreatedAt: slaNow.Add(-2 * time.Minute), DispatchedAt: at(-110 * time.Second), EndTime: at(-time.Minute)},
+			previous:      models.JobSLA{DispatchBreached: true},
+			want:          models.JobSLA{DispatchBreached: true},
+			wantEvaluated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := evaluateSLA(&tt.job, &tt.previous, testThresholds(), slaNow)
+			if got.DispatchBreached != tt.want.DispatchBreached || got.StartBreached != tt.want.StartBreached || got.CompleteBreached != tt.want.CompleteBreached {
+				t.Errorf("breaches = %+v, want %+v", got, tt.want)
+			}
+			if (got.EvaluatedAt != nil) != tt.wantEvaluated {
+				t.Errorf("evaluated_at = %v, want evaluated %v", got.EvaluatedAt, tt.wantEvaluated)
+			}
+		})
+	}
+}
+
+func TestEvaluateSLAZeroThresholdNotTracked(t *testing.T) {
+	thresholds := testThresholds()
+	thresholds[models.SLAPhaseDispatch] = 0
+	job := models.PrintJob{Status: models.JobStatusPending, CreatedAt: slaNow.Add(-time.Hour)}
+
+	got, elapsed := evaluateSLA(&job, &models.JobSLA{}, thresholds, slaNow)
+	if got.DispatchBreached {
+		t.Error("dispatch phase with zero threshold marked breached")
+	}
+	if _, ok := elapsed[models.SLAPhaseDispatch]; ok {
+		t.Error("elapsed reported for untracked phase")
+	}
+	if !got.StartBreached || !got.CompleteBreached {
+		t.Errorf("got %+v, want start and complete breached", got)
+	}
+}
+
+func TestSLAThresholdsGroupOverride(t *testing.T) {
+	cfg := &config.SLAConfig{TimeToDispatch: time.Minute, TimeToStart: 5 * time.Minute, TimeToComplete: 30 * time.Minute}
+	zero, twoMinutes := 0, 120
+	group := &models.PrinterGroup{SLA: &models.SLAThresholds{TimeToDispatch: &twoMinutes, TimeToComplete: &zero}}
+
+	got := slaThresholds(cfg, group)
+	want := map[models.SLAPhase]time.Duration{
+		models.SLAPhaseDispatch: 2 * time.Minute,
+		models.SLAPhaseStart:    5 * time.Minute,
+		models.SLAPhaseComplete: 0,
+	}
+	for phase, threshold := range want {
+		if got[phase] != threshold {
+			t.Errorf("%s threshold = %s, want %s", phase, got[phase], threshold)
+		}
+	}
+
+	if global := slaThresholds(cfg, nil); global[models.SLAPhaseComplete] != 30*time.Minute {
+		t.Errorf("without group: complete threshold = %s, want global 30m", global[models.SLAPhaseComplete])
+	}
+}
@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/database"
+	"fly-print-cloud/api/internal/events"
+	"fly-print-cloud/api/internal/models"
+	"fly-print-cloud/api/internal/notify"
+)
+
+// StalledJobSweeper 后台任务巡检：将长时间没有进展的已分发任务标记为 stalled，便于管理员筛选处理；
+// 同时将打印机所属 Edge Node 已删除、永远无法分发的任务标记为失败
+type StalledJobSweeper struct {
+	printJobRepo *database.PrintJobRepository
+	eventBus     *events.Bus
+	jobEventRepo *database.PrintJobEventRepository
+	notifier     *notify.Notifier
+	settings     *config.Store // 超时和检测间隔支持热更新，每次使用时读取
+}
+
+// NewStalledJobSweeper 创建卡住任务检测
+func NewStalledJobSweeper(printJobRepo *database.PrintJobRepository, eventBus *events.Bus, jobEventRepo *database.PrintJobEventRepository, notifier *notify.Notifier, settings *config.Store) *StalledJobSweeper {
+	return &StalledJobSweeper{
+		printJobRepo: printJobRepo,
+		eventBus:     eventBus,
+		jobEventRepo: jobEventRepo,
+		notifier:     notifier,
+		settings:     settings,
+	}
+}
+
+// maxAge 已分发任务没有状态更新多久后标记为 stalled
+func (w *StalledJobSweeper) maxAge() time.Duration {
+	if maxAge := w.settings.Get().Jobs.StallTimeout; maxAge > 0 {
+		return maxAge
+	}
+	return 2 * time.Hour
+}
+
+// interval 卡住任务检测间隔
+func (w *StalledJobSweeper) interval() time.Duration {
+	if interval := w.settings.Get().Jobs.StallCheckInterval; interval > 0 {
+		return interval
+	}
+	return 5 * time.Minute
+}
+
+// Run 启动卡住任务检测（阻塞）
+func (w *StalledJobSweeper) Run() {
+	interval := w.interval()
+	log.Printf("Stalled job sweeper started: max_age=%s, interval=%s", w.maxAge(), interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.sweep()
+		w.failStrandedJobs()
+
+		// 检测间隔被热更新时重置定时器
+		if next := w.interval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// sweep 标记超过 maxAge 没有任何状态更新的任务
+func (w *StalledJobSweeper) sweep() {
+	maxAge := w.maxAge()
+	cutoff := time.Now().UTC().Add(-maxAge)
+	jobs, err := w.printJobRepo.MarkStalledJobs(cutoff)
+	if err != nil {
+		log.Printf("Failed to sweep stalled jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		log.Printf("Print job %s on printer %s has made no progress for %s, marked stalled", job.ID, job.PrinterID, maxAge)
+		w.jobEventRepo.Record(&models.PrintJobEvent{
+			JobID:   job.ID,
+			Type:    models.JobEventStalled,
+			Status:  models.JobStatusStalled,
+			Actor:   models.JobEventActorSystem,
+			Message: fmt.Sprintf("超过 %s 没有状态更新", maxAge),
+		})
+		w.eventBus.Publish(events.Event{
+			Type: events.EventJobUpdated,
+			Data: map[string]interface{}{
+				"job_id":     job.ID,
+				"printer_id": job.PrinterID,
+				"status":     models.JobStatusStalled,
+				"source":     job.Source,
+			},
+		})
+	}
+}
+
+// failStrandedJobs 将打印机所属 Edge Node 已删除的待分发/排队任务标记为失败并通知提交用户
+func (w *StalledJobSweeper) failStrandedJobs() {
+	jobs, err := w.printJobRepo.FailJobsOnDeletedNodes("打印机所属的 Edge Node 已删除")
+	if err != nil {
+		log.Printf("Failed to fail jobs on deleted edge nodes: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		log.Printf("Print job %s on printer %s failed: edge node deleted", job.ID, job.PrinterID)
+		w.jobEventRepo.Record(&models.PrintJobEvent{
+			JobID:   job.ID,
+			Type:    models.JobEventFailed,
+			Status:  models.JobStatusFailed,
+			Actor:   models.JobEventActorSystem,
+			Message: job.ErrorMessage,
+			Details: map[string]interface{}{"error_code": models.JobErrorEdgeNodeDeleted},
+		})
+		w.eventBus.Publish(events.Event{
+			Type: events.EventJobUpdated,
+			Data: map[string]interface{}{
+				"job_id":     job.ID,
+				"printer_id": job.PrinterID,
+				"status":     models.JobStatusFailed,
+				"source":     job.Source,
+			},
+		})
+		if w.notifier != nil {
+			w.notifier.JobFinished(job.ID)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Authenticator 为请求添加认证信息
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *http.Request) error
+}
+
+// BearerToken 固定的 OAuth2 access token
+type BearerToken string
+
+// Authenticate 设置 Authorization: Bearer 请求头
+func (t BearerToken) Authenticate(_ context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// TokenSource 每次请求时获取 access token，适用于客户端凭证模式下自动续期的 token
+type TokenSource func(ctx context.Context) (string, error)
+
+// Authenticate 获取 token 并设置 Authorization: Bearer 请求头
+func (s TokenSource) Authenticate(ctx context.Context, req *http.Request) error {
+	token, err := s(ctx)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return fmt.Errorf("empty access token")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// APIKey 在请求头中携带 API key
+// 使用默认请求头时即为云端的个人 API Key（在 /api/v1/profile/api-keys 创建）
+type APIKey struct {
+	Header string // 为空时使用 X-API-Key
+	Key    string
+}
+
+// Authenticate 设置 API key 请求头
+func (k APIKey) Authenticate(_ context.Context, req *http.Request) error {
+	header := k.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+	req.Header.Set(header, k.Key)
+	return nil
+}
@@ -0,0 +1,277 @@
+// Package client fly-print-cloud API 的 Go 客户端
+//
+// 资源类型直接使用服务端的 models 定义（类型别名），请求结构与服务端处理器的 JSON 字段保持一致。
+// 只依赖标准库和 models 包，可供 Edge Agent 和内部工具直接引用：
+//
+//	c, err := client.New("https://print.example.com", client.WithBearerToken(token))
+//	result, err := c.PrintJobs.Create(ctx, &client.CreatePrintJobRequest{PrinterID: "lobby", StorageKey: key},
+//		client.WithIdempotencyKey(orderID))
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// apiPrefix 服务端 API 路径前缀
+const apiPrefix = "/api/v1"
+
+// Client fly-print-cloud API 客户端，可被多个 goroutine 并发使用
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	auth       Authenticator
+	userAgent  string
+	clientInfo string // X-Client 请求头，为空时不发送
+	timeout    time.Duration
+
+	PrintJobs *PrintJobsService
+	Printers  *PrintersService
+	EdgeNodes *EdgeNodesService
+	Users     *UsersService
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义的 http.Client（代理、TLS、连接池等）
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAuthenticator 使用自定义的认证方式
+func WithAuthenticator(auth Authenticator) Option {
+	return func(c *Client) {
+		c.auth = auth
+	}
+}
+
+// WithBearerToken 使用固定的 OAuth2 access token 认证
+func WithBearerToken(token string) Option {
+	return WithAuthenticator(BearerToken(token))
+}
+
+// WithTokenSource 每次请求时获取 access token（用于自动续期的 token）
+func WithTokenSource(source func(ctx context.Context) (string, error)) Option {
+	return WithAuthenticator(TokenSource(source))
+}
+
+// WithAPIKey 在指定请求头中携带 API key，header 为空时使用云端个人 API Key 的 X-API-Key 请求头
+func WithAPIKey(header, key string) Option {
+	return WithAuthenticator(APIKey{Header: header, Key: key})
+}
+
+// WithUserAgent 设置 User-Agent
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithClientInfo 通过 X-Client 请求头上报调用方应用名称、版本和平台，服务端记录到创建的任务和审计日志
+func WithClientInfo(name, version, platform string) Option {
+	return func(c *Client) {
+		c.clientInfo = fmt.Sprintf("name=%s; version=%s; platform=%s", name, version, platform)
+	}
+}
+
+// WithTimeout 单次请求的超时时间，仅在调用方的 context 没有截止时间时生效
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// New 创建客户端，baseURL 为服务端地址（如 https://print.example.com），不含 /api/v1
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL must be http or https: %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: http.DefaultClient,
+		userAgent:  "fly-print-cloud-go-client",
+		timeout:    30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.PrintJobs = &PrintJobsService{client: c}
+	c.Printers = &PrintersService{client: c}
+	c.EdgeNodes = &EdgeNodesService{client: c}
+	c.Users = &UsersService{client: c}
+	return c, nil
+}
+
+// request 单次 API 请求
+type request struct {
+	method  string
+	path    string // 相对 /api/v1 的路径
+	query   url.Values
+	body    interface{} // JSON 请求体，为 nil 时不发送
+	raw     io.Reader   // 非 JSON 请求体（如 multipart），与 body 二选一
+	header  http.Header
+	wrapped bool // 响应为 {code, message, data} 包装格式
+}
+
+// response 已读取的响应
+type response struct {
+	StatusCode int
+	Header     http.Header
+}
+
+// do 发送请求并将响应解码到 out（可为 nil），非 2xx 响应返回 *APIError
+func (c *Client) do(ctx context.Context, req *request, out interface{}) (*response, error) {
+	if c.timeout > 0 {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+		}
+	}
+
+	// req.path 中的路径参数已转义，同时设置 RawPath 避免再次转义
+	endpoint := *c.baseURL
+	escapedPath := c.baseURL.EscapedPath() + apiPrefix + req.path
+	unescapedPath, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid request path: %w", err)
+	}
+	endpoint.Path = unescapedPath
+	endpoint.RawPath = escapedPath
+	if len(req.query) > 0 {
+		endpoint.RawQuery = req.query.Encode()
+	}
+
+	body := req.raw
+	if req.body != nil {
+		data, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, fmt.Errorf("client: failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, endpoint.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to build request: %w", err)
+	}
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	if req.body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if c.clientInfo != "" {
+		httpReq.Header.Set(models.ClientInfoHeader, c.clientInfo)
+	}
+	if c.auth != nil {
+		if err := c.auth.Authenticate(ctx, httpReq); err != nil {
+			return nil, fmt.Errorf("client: authentication failed: %w", err)
+		}
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to read response: %w", err)
+	}
+	resp := &response{StatusCode: httpResp.StatusCode, Header: httpResp.Header}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+		return resp, newAPIError(httpResp.StatusCode, data)
+	}
+	if out == nil || len(data) == 0 {
+		return resp, nil
+	}
+
+	if req.wrapped {
+		envelope := struct {
+			Data json.RawMessage `json:"data"`
+		}{}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return resp, fmt.Errorf("client: failed to decode response: %w", err)
+		}
+		data = envelope.Data
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return resp, fmt.Errorf("client: failed to decode response: %w", err)
+	}
+	return resp, nil
+}
+
+// APIError 服务端返回的错误响应
+type APIError struct {
+	StatusCode int
+	Message    string          // message 或 error 字段
+	Code       string          // error_code 字段（部分接口返回）
+	Body       json.RawMessage // 原始响应体，包含 violations 等附加字段
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("fly-print-cloud: %d %s (%s)", e.StatusCode, e.Message, e.Code)
+	}
+	return fmt.Sprintf("fly-print-cloud: %d %s", e.StatusCode, e.Message)
+}
+
+// newAPIError 解析错误响应，兼容 {code, message} 和 {error, error_code} 两种格式
+func newAPIError(status int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: status, Body: body}
+	var parsed struct {
+		Message   string `json:"message"`
+		Error     string `json:"error"`
+		ErrorCode string `json:"error_code"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		apiErr.Message = parsed.Message
+		if parsed.Error != "" {
+			apiErr.Message = parsed.Error
+		}
+		apiErr.Code = parsed.ErrorCode
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(status)
+	}
+	return apiErr
+}
+
+// IsStatus 判断错误是否为指定状态码的 APIError
+func IsStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// IsNotFound 判断错误是否为 404
+func IsNotFound(err error) bool {
+	return IsStatus(err, http.StatusNotFound)
+}
+
+// pathEscape 转义路径参数
+func pathEscape(segment string) string {
+	return url.PathEscape(segment)
+}
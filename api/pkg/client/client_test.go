@@ -0,0 +1,263 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestClient 创建指向 handler 的客户端
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := New(srv.URL, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func TestNewRejectsInvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"ftp://example.com", "example.com", "://bad"} {
+		if _, err := New(baseURL); err == nil {
+			t.Errorf("New(%q) succeeded, want error", baseURL)
+		}
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name   string
+		opt    Option
+		header string
+		want   string
+	}{
+		{"bearer token", WithBearerToken("abc"), "Authorization", "Bearer abc"},
+		{"token source", WithTokenSource(func(context.Context) (string, error) { return "fresh", nil }), "Authorization", "Bearer fresh"},
+		{"api key", WithAPIKey("", "key-1"), "X-API-Key", "key-1"},
+		{"custom api key header", WithAPIKey("X-Tenant-Key", "key-2"), "X-Tenant-Key", "key-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get(tt.header)
+				writeJSON(w, http.StatusOK, map[string]interface{}{"code": 200, "data": map[string]string{"id": "u1"}})
+			}, tt.opt)
+			if _, err := c.Users.Get(context.Background(), "u1"); err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenSourceErrorStopsRequest(t *testing.T) {
+	called := false
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) { called = true },
+		WithTokenSource(func(context.Context) (string, error) { return "", errors.New("token endpoint down") }))
+	if _, err := c.Users.Get(context.Background(), "u1"); err == nil {
+		t.Fatal("Get succeeded without a token")
+	}
+	if called {
+		t.Error("request sent without authentication")
+	}
+}
+
+func TestRequestHeadersAndPath(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v1/admin/printers/a%2Fb" {
+			t.Errorf("path = %q", r.URL.EscapedPath())
+		}
+		if got := r.Header.Get("User-Agent"); got != "agent/1.0" {
+			t.Errorf("User-Agent = %q", got)
+		}
+		if got := r.Header.Get("X-Client"); got != "name=edge; version=2.1.0; platform=linux" {
+			t.Errorf("X-Client = %q", got)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"id": "a/b", "actually_enabled": true}})
+	}, WithUserAgent("agent/1.0"), WithClientInfo("edge", "2.1.0", "linux"))
+
+	printer, err := c.Printers.Get(context.Background(), "a/b")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if printer.ID != "a/b" || !printer.ActuallyEnabled {
+		t.Errorf("printer = %+v", printer)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		message  string
+		code     string
+		notFound bool
+	}{
+		{"wrapped", http.StatusNotFound, `{"code":404,"message":"打印机不存在"}`, "打印机不存在", "", true},
+		{"error field", http.StatusConflict, `{"error":"version conflict","error_code":"stale_version"}`, "version conflict", "stale_version", false},
+		{"not json", http.StatusBadGateway, `<html>bad gateway</html>`, "Bad Gateway", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			_, err := c.Printers.Get(context.Background(), "p1")
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != tt.message || apiErr.Code != tt.code {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+			if string(apiErr.Body) != tt.body {
+				t.Errorf("Body = %q, want raw response", apiErr.Body)
+			}
+			if IsNotFound(err) != tt.notFound {
+				t.Errorf("IsNotFound = %v", !tt.notFound)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}, WithTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	_, err := c.Printers.Get(context.Background(), "p1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("request took %v, timeout not applied", elapsed)
+	}
+
+	// 调用方 context 的截止时间优先于客户端默认超时
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := c.Printers.Get(ctx, "p1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("request gave up after %v, want caller deadline", elapsed)
+	}
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	seen := map[string]string{}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if id, ok := seen[key]; ok && key != "" {
+			w.Header().Set("Idempotent-Replayed", "true")
+			writeJSON(w, http.StatusOK, map[string]string{"id": id})
+			return
+		}
+		id := "job-" + strconv.Itoa(len(seen)+1)
+		seen[key] = id
+		writeJSON(w, http.StatusCreated, map[string]string{"id": id})
+	})
+	ctx := context.Background()
+	req := &CreatePrintJobRequest{PrinterID: "lobby", StorageKey: "uploads/a.pdf"}
+
+	first, err := c.PrintJobs.Create(ctx, req, WithIdempotencyKey("order-42"))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	again, err := c.PrintJobs.Create(ctx, req, WithIdempotencyKey("order-42"))
+	if err != nil {
+		t.Fatalf("Create retry: %v", err)
+	}
+	if first.Replayed || !again.Replayed || again.Job.ID != first.Job.ID {
+		t.Fatalf("first = {%s, %v}, retry = {%s, %v}; want replay of the same job",
+			first.Job.ID, first.Replayed, again.Job.ID, again.Replayed)
+	}
+}
+
+func TestPageIterator(t *testing.T) {
+	const total = 7
+	var requests []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+		var items []map[string]string
+		for i := (page - 1) * size; i < page*size && i < total; i++ {
+			items = append(items, map[string]string{"id": "u" + strconv.Itoa(i+1)})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"items": items, "total": total, "page": page, "page_size": size, "total_pages": (total + size - 1) / size,
+		}})
+	})
+
+	it := c.Users.Iter(ListOptions{PageSize: 3})
+	var ids []string
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Value().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iter: %v", err)
+	}
+	if len(ids) != total || ids[0] != "u1" || ids[total-1] != "u7" {
+		t.Fatalf("ids = %v", ids)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("requests = %v, want 3 pages", requests)
+	}
+}
+
+func TestCursorIteratorStopsOnError(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"jobs":       []map[string]string{{"id": "j1"}, {"id": "j2"}},
+				"pagination": map[string]string{"next_cursor": "c2"},
+			})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "boom"})
+		}
+	})
+
+	it := c.PrintJobs.Iter(ListPrintJobsOptions{Status: "queued"})
+	var ids []string
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Value().ID)
+	}
+	if len(ids) != 2 || !IsStatus(it.Err(), http.StatusInternalServerError) {
+		t.Fatalf("ids = %v, err = %v; want 2 jobs then the 500", ids, it.Err())
+	}
+	// 出错后不再请求
+	if it.Next(context.Background()) || calls != 2 {
+		t.Fatalf("iterator continued after error (%d calls)", calls)
+	}
+}
@@ -0,0 +1,211 @@
+package client_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"fly-print-cloud/api/internal/app"
+	"fly-print-cloud/api/internal/config"
+	"fly-print-cloud/api/internal/middleware"
+	"fly-print-cloud/api/pkg/client"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// testDatabaseURLEnv 集成测试使用的 PostgreSQL 连接串（postgres:// URL），未设置时跳过依赖数据库的测试
+const testDatabaseURLEnv = "FLY_PRINT_TEST_DATABASE_URL"
+
+// startServer 在独立的测试数据库上启动真实路由，客户端与服务端的请求、响应结构由此对齐
+func startServer(t *testing.T) string {
+	t.Helper()
+	rawURL := os.Getenv(testDatabaseURLEnv)
+	if rawURL == "" {
+		t.Skipf("%s not set, skipping client contract test", testDatabaseURLEnv)
+	}
+	dbURL, err := url.Parse(rawURL)
+	if err != nil || (dbURL.Scheme != "postgres" && dbURL.Scheme != "postgresql") {
+		t.Fatalf("%s must be a postgres:// URL", testDatabaseURLEnv)
+	}
+
+	admin, err := sql.Open("postgres", rawURL)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	dbName := "test_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	if _, err := admin.Exec("CREATE DATABASE " + dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + dbName + " WITH (FORCE)"); err != nil {
+			t.Logf("drop database %s: %v", dbName, err)
+		}
+	})
+
+	password, _ := dbURL.User.Password()
+	port := dbURL.Port()
+	if port == "" {
+		port = "5432"
+	}
+	sslMode := dbURL.Query().Get("sslmode")
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	t.Setenv("FLY_PRINT_DATABASE_HOST", dbURL.Hostname())
+	t.Setenv("FLY_PRINT_DATABASE_PORT", port)
+	t.Setenv("FLY_PRINT_DATABASE_USER", dbURL.User.Username())
+	t.Setenv("FLY_PRINT_DATABASE_PASSWORD", password)
+	t.Setenv("FLY_PRINT_DATABASE_DBNAME", dbName)
+	t.Setenv("FLY_PRINT_DATABASE_SSLMODE", sslMode)
+	t.Setenv("FLY_PRINT_STORAGE_DIR", t.TempDir())
+	t.Setenv("FLY_PRINT_DIAGNOSTICS_DIR", t.TempDir())
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	a, err := app.Build(cfg)
+	if err != nil {
+		t.Fatalf("build app: %v", err)
+	}
+	t.Cleanup(func() { a.DB.Close() })
+
+	srv := httptest.NewServer(a.Engine)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// token 生成测试用 JWT（中间件只解析 claims，不校验签名）
+func token(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func TestClientAgainstRouter(t *testing.T) {
+	baseURL := startServer(t)
+	ctx := context.Background()
+
+	nodeID := "node-sdk-" + uuid.New().String()[:8]
+	edge, err := client.New(baseURL, client.WithBearerToken(token(t, jwt.MapClaims{
+		"sub":     "edge-client-" + nodeID,
+		"node_id": nodeID,
+		"scope":   middleware.ScopeEdgeRegister + " " + middleware.ScopeEdgeConnect + " " + middleware.ScopeEdgePrinterWrite,
+	})))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	admin, err := client.New(baseURL, client.WithBearerToken(token(t, jwt.MapClaims{
+		"sub":                "admin-1",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{middleware.RoleAdmin}},
+	})))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// Edge Node 注册、心跳和打印机注册
+	node, err := edge.EdgeNodes.Register(ctx, &client.RegisterEdgeNodeRequest{NodeID: nodeID, Name: "SDK Node"})
+	if err != nil {
+		t.Fatalf("EdgeNodes.Register: %v", err)
+	}
+	if node.ID != nodeID || node.ConnToken == "" {
+		t.Fatalf("registered node = {%s, conn_token %q}", node.ID, node.ConnToken)
+	}
+	if renewed, err := edge.EdgeNodes.HeartbeatConnToken(ctx, nodeID); err != nil || renewed.Token == "" {
+		t.Fatalf("EdgeNodes.HeartbeatConnToken = %+v, %v", renewed, err)
+	}
+	var printerIDs []string
+	for _, name := range []string{"SDK-A", "SDK-B", "SDK-C"} {
+		printer, err := edge.EdgeNodes.RegisterPrinter(ctx, nodeID, &client.EdgeRegisterPrinterRequest{Name: name, Model: "LaserJet"})
+		if err != nil {
+			t.Fatalf("EdgeNodes.RegisterPrinter(%s): %v", name, err)
+		}
+		if printer.ID == "" || !printer.Approved {
+			t.Fatalf("registered printer %s = {%s, approved %v}", name, printer.ID, printer.Approved)
+		}
+		printerIDs = append(printerIDs, printer.ID)
+	}
+
+	// 管理接口：节点和打印机
+	gotNode, err := admin.EdgeNodes.Get(ctx, nodeID)
+	if err != nil || gotNode.ID != nodeID || gotNode.PrinterCount != 3 {
+		t.Fatalf("EdgeNodes.Get = %+v, %v", gotNode, err)
+	}
+	it := admin.Printers.Iter(client.PrinterListOptions{ListOptions: client.ListOptions{PageSize: 2}, EdgeNodeID: nodeID})
+	seen := 0
+	for it.Next(ctx) {
+		seen++
+	}
+	if err := it.Err(); err != nil || seen != 3 {
+		t.Fatalf("Printers.Iter: %d printers, %v", seen, err)
+	}
+	if printer, err := admin.Printers.Get(ctx, printerIDs[0]); err != nil || printer.ID != printerIDs[0] {
+		t.Fatalf("Printers.Get = %+v, %v", printer, err)
+	}
+	if _, err := admin.Printers.Get(ctx, uuid.New().String()); !client.IsNotFound(err) {
+		t.Fatalf("Printers.Get(unknown) error = %v, want 404", err)
+	}
+
+	// 打印任务：幂等创建、详情、列表、取消
+	req := &client.CreatePrintJobRequest{Name: "sdk.pdf", PrinterID: printerIDs[0], FileURL: "https://files.example.com/sdk.pdf", PageCount: 1}
+	created, err := admin.PrintJobs.Create(ctx, req, client.WithIdempotencyKey("sdk-order-1"))
+	if err != nil {
+		t.Fatalf("PrintJobs.Create: %v", err)
+	}
+	replayed, err := admin.PrintJobs.Create(ctx, req, client.WithIdempotencyKey("sdk-order-1"))
+	if err != nil {
+		t.Fatalf("PrintJobs.Create retry: %v", err)
+	}
+	if created.Replayed || !replayed.Replayed || replayed.Job.ID != created.Job.ID {
+		t.Fatalf("idempotent create: first {%s, %v}, retry {%s, %v}",
+			created.Job.ID, created.Replayed, replayed.Job.ID, replayed.Replayed)
+	}
+	if job, err := admin.PrintJobs.Get(ctx, created.Job.ID); err != nil || job.PrinterID != printerIDs[0] {
+		t.Fatalf("PrintJobs.Get = %+v, %v", job, err)
+	}
+	list, err := admin.PrintJobs.List(ctx, client.ListPrintJobsOptions{PrinterID: printerIDs[0]})
+	if err != nil || len(list.Jobs) != 1 || list.Jobs[0].ID != created.Job.ID {
+		t.Fatalf("PrintJobs.List = %+v, %v", list, err)
+	}
+	if job, err := admin.PrintJobs.Cancel(ctx, created.Job.ID); err != nil || job.Status != "cancelled" {
+		t.Fatalf("PrintJobs.Cancel = %+v, %v", job, err)
+	}
+
+	// 用户管理
+	user, err := admin.Users.Create(ctx, &client.CreateUserRequest{
+		Username: "sdkuser", Email: "sdk@example.com", Password: "correct-horse", Role: "viewer",
+	})
+	if err != nil {
+		t.Fatalf("Users.Create: %v", err)
+	}
+	got, err := admin.Users.Get(ctx, user.ID)
+	if err != nil || got.Username != "sdkuser" {
+		t.Fatalf("Users.Get = %+v, %v", got, err)
+	}
+	stale := got.RowVersion - 1
+	update := &client.UpdateUserRequest{Username: "sdkuser", Email: "sdk@example.com", Role: "operator", Status: "active", RowVersion: &stale}
+	if _, err := admin.Users.Update(ctx, user.ID, update); !client.IsStatus(err, http.StatusConflict) {
+		t.Fatalf("Users.Update with stale version error = %v, want 409", err)
+	}
+	update.RowVersion = &got.RowVersion
+	if updated, err := admin.Users.Update(ctx, user.ID, update); err != nil || updated.Role != "operator" {
+		t.Fatalf("Users.Update = %+v, %v", updated, err)
+	}
+	if err := admin.Users.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Users.Delete: %v", err)
+	}
+	if _, err := admin.Users.Get(ctx, user.ID); !client.IsNotFound(err) {
+		t.Fatalf("Users.Get after delete error = %v, want 404", err)
+	}
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// EdgeNodesService Edge Node 接口：管理接口（/admin/edge-nodes）和节点自身使用的接口（/edge）
+type EdgeNodesService struct {
+	client *Client
+}
+
+// EdgeNodeListOptions Edge Node 列表筛选条件
+type EdgeNodeListOptions struct {
+	ListOptions
+	Status string
+}
+
+// List 获取一页 Edge Node
+func (s *EdgeNodesService) List(ctx context.Context, opts EdgeNodeListOptions) (*Page[*EdgeNodeInfo], error) {
+	query := opts.values()
+	if opts.Status != "" {
+		query.Set("status", opts.Status)
+	}
+	page := &Page[*EdgeNodeInfo]{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodGet, path: "/admin/edge-nodes", query: query, wrapped: true}, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Iter 遍历符合条件的全部 Edge Node
+func (s *EdgeNodesService) Iter(opts EdgeNodeListOptions) *Iterator[*EdgeNodeInfo] {
+	return pageIterator(opts.ListOptions, func(ctx context.Context, list ListOptions) (*Page[*EdgeNodeInfo], error) {
+		opts.ListOptions = list
+		return s.List(ctx, opts)
+	})
+}
+
+// Get 获取 Edge Node 详情
+func (s *EdgeNodesService) Get(ctx context.Context, id string) (*EdgeNodeInfo, error) {
+	node := &EdgeNodeInfo{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodGet, path: "/admin/edge-nodes/" + pathEscape(id), wrapped: true}, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// Register 注册 Edge Node（节点使用，需要 edge:register 权限）
+func (s *EdgeNodesService) Register(ctx context.Context, req *RegisterEdgeNodeRequest) (*EdgeNodeInfo, error) {
+	node := &EdgeNodeInfo{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodPost, path: "/edge/register", body: req, wrapped: true}, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// Heartbeat 通过 REST 发送心跳（节点使用，需要 edge:connect 权限）
+func (s *EdgeNodesService) Heartbeat(ctx context.Context, nodeID string) error {
+	_, err := s.HeartbeatConnToken(ctx, nodeID)
+	return err
+}
+
+// HeartbeatConnToken 通过 REST 发送心跳并获取续签的 WebSocket 连接令牌（节点使用，需要 edge:connect 权限）
+func (s *EdgeNodesService) HeartbeatConnToken(ctx context.Context, nodeID string) (*ConnToken, error) {
+	body := map[string]string{"node_id": nodeID}
+	token := &ConnToken{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodPost, path: "/edge/heartbeat", body: body, wrapped: true}, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RegisterPrinter 注册或更新节点上的打印机（节点使用，需要 edge:printer:write 权限）
+// 审核模式下新打印机返回 approved=false，被管理员拒绝的打印机返回 403
+func (s *EdgeNodesService) RegisterPrinter(ctx context.Context, nodeID string, req *EdgeRegisterPrinterRequest) (*EdgeRegisteredPrinter, error) {
+	printer := &EdgeRegisteredPrinter{}
+	path := "/edge/" + pathEscape(nodeID) + "/printers"
+	if _, err := s.client.do(ctx, &request{method: http.MethodPost, path: path, body: req, wrapped: true}, printer); err != nil {
+		return nil, err
+	}
+	return printer, nil
+}
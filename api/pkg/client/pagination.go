@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Page 分页列表的一页
+type Page[T any] struct {
+	Items      []T `json:"items"`
+	Total      int `json:"total"`
+	Page       int `json:"page"`
+	PageSize   int `json:"page_size"`
+	TotalPages int `json:"total_pages"`
+}
+
+// ListOptions 页码分页参数
+type ListOptions struct {
+	Page     int // 从 1 开始，为 0 时使用第 1 页
+	PageSize int // 为 0 时使用服务端默认值，最大 100
+}
+
+// values 转换为查询参数
+func (o ListOptions) values() url.Values {
+	query := url.Values{}
+	if o.Page > 0 {
+		query.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(o.PageSize))
+	}
+	return query
+}
+
+// fetchFunc 获取 token 对应的一页，返回下一页的 token（为空表示没有更多数据）
+type fetchFunc[T any] func(ctx context.Context, token string) (items []T, next string, err error)
+
+// Iterator 自动翻页遍历列表
+//
+//	it := c.Printers.Iter(client.PrinterListOptions{})
+//	for it.Next(ctx) {
+//		printer := it.Value()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator[T any] struct {
+	fetch   fetchFunc[T]
+	token   string
+	started bool
+	done    bool
+	buffer  []T
+	current T
+	err     error
+}
+
+func newIterator[T any](first string, fetch fetchFunc[T]) *Iterator[T] {
+	return &Iterator[T]{fetch: fetch, token: first}
+}
+
+// Next 前进到下一项，没有更多数据或出错时返回 false
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	for len(it.buffer) == 0 {
+		if it.err != nil || (it.started && it.done) {
+			return false
+		}
+		items, next, err := it.fetch(ctx, it.token)
+		it.started = true
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.buffer = items
+		it.token = next
+		it.done = next == ""
+		if len(items) == 0 && it.done {
+			return false
+		}
+	}
+	it.current = it.buffer[0]
+	it.buffer = it.buffer[1:]
+	return true
+}
+
+// Value 当前项
+func (it *Iterator[T]) Value() T {
+	return it.current
+}
+
+// Err 遍历中遇到的错误
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// pageIterator 页码分页的遍历：token 为页码
+func pageIterator[T any](opts ListOptions, list func(ctx context.Context, opts ListOptions) (*Page[T], error)) *Iterator[T] {
+	first := 1
+	if opts.Page > 0 {
+		first = opts.Page
+	}
+	return newIterator(strconv.Itoa(first), func(ctx context.Context, token string) ([]T, string, error) {
+		page, _ := strconv.Atoi(token)
+		opts.Page = page
+		result, err := list(ctx, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		if result.Page >= result.TotalPages || len(result.Items) == 0 {
+			return result.Items, "", nil
+		}
+		return result.Items, strconv.Itoa(result.Page + 1), nil
+	})
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// PrintJobsService 打印任务接口（/admin/print-jobs，需要 admin 或 operator 角色）
+type PrintJobsService struct {
+	client *Client
+}
+
+// createOptions 创建打印任务的附加选项
+type createOptions struct {
+	idempotencyKey string
+}
+
+// CreateOption 创建打印任务的附加选项
+type CreateOption func(*createOptions)
+
+// WithIdempotencyKey 设置幂等键：24 小时内以相同的键重复提交时返回首次创建的任务，不会重复打印
+func WithIdempotencyKey(key string) CreateOption {
+	return func(o *createOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// CreateResult 创建打印任务的结果
+type CreateResult struct {
+	Job      *PrintJob
+	Replayed bool // 幂等键命中，Job 为之前创建的任务
+}
+
+// Create 创建打印任务
+func (s *PrintJobsService) Create(ctx context.Context, req *CreatePrintJobRequest, opts ...CreateOption) (*CreateResult, error) {
+	var options createOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	header := http.Header{}
+	if options.idempotencyKey != "" {
+		header.Set("Idempotency-Key", options.idempotencyKey)
+	}
+
+	job := &PrintJob{}
+	resp, err := s.client.do(ctx, &request{method: http.MethodPost, path: "/admin/print-jobs", body: req, header: header}, job)
+	if err != nil {
+		return nil, err
+	}
+	return &CreateResult{Job: job, Replayed: resp.Header.Get("Idempotent-Replayed") == "true"}, nil
+}
+
+// Get 获取打印任务详情
+func (s *PrintJobsService) Get(ctx context.Context, id string) (*PrintJob, error) {
+	job := &PrintJob{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodGet, path: "/admin/print-jobs/" + pathEscape(id)}, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Cancel 取消打印任务，返回取消后的任务
+func (s *PrintJobsService) Cancel(ctx context.Context, id string) (*PrintJob, error) {
+	job := &PrintJob{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodPost, path: "/admin/print-jobs/" + pathEscape(id) + "/cancel"}, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ListPrintJobsOptions 打印任务列表筛选条件（游标分页）
+type ListPrintJobsOptions struct {
+	Status    string
+	PrinterID string
+	UserID    string
+	Metadata  map[string]string // 按 metadata 标签筛选，多个条件同时满足
+	Limit     int               // 每页数量，为 0 时使用服务端默认值，最大 100
+	Cursor    string            // 上一页返回的 NextCursor，首页为空
+}
+
+// PrintJobList 一页打印任务
+type PrintJobList struct {
+	Jobs       []*PrintJob
+	NextCursor string // 为空表示没有更多数据
+}
+
+// List 获取一页打印任务，按创建时间倒序
+func (s *PrintJobsService) List(ctx context.Context, opts ListPrintJobsOptions) (*PrintJobList, error) {
+	query := url.Values{}
+	query.Set("cursor", opts.Cursor)
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Status != "" {
+		query.Set("status", opts.Status)
+	}
+	if opts.PrinterID != "" {
+		query.Set("printer_id", opts.PrinterID)
+	}
+	if opts.UserID != "" {
+		query.Set("user_id", opts.UserID)
+	}
+	for key, value := range opts.Metadata {
+		query.Set("metadata."+key, value)
+	}
+
+	var result struct {
+		Jobs       []*PrintJob `json:"jobs"`
+		Pagination struct {
+			NextCursor string `json:"next_cursor"`
+		} `json:"pagination"`
+	}
+	if _, err := s.client.do(ctx, &request{method: http.MethodGet, path: "/admin/print-jobs", query: query}, &result); err != nil {
+		return nil, err
+	}
+	return &PrintJobList{Jobs: result.Jobs, NextCursor: result.Pagination.NextCursor}, nil
+}
+
+// Iter 遍历符合条件的全部打印任务
+func (s *PrintJobsService) Iter(opts ListPrintJobsOptions) *Iterator[*PrintJob] {
+	return newIterator(opts.Cursor, func(ctx context.Context, cursor string) ([]*PrintJob, string, error) {
+		opts.Cursor = cursor
+		page, err := s.List(ctx, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Jobs, page.NextCursor, nil
+	})
+}
+
+// UploadFile 上传打印文件，返回的 StorageKey 用于创建打印任务
+func (s *PrintJobsService) UploadFile(ctx context.Context, fileName string, content io.Reader) (*UploadedFile, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to build upload: %w", err)
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return nil, fmt.Errorf("client: failed to read upload: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("client: failed to build upload: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", writer.FormDataContentType())
+	uploaded := &UploadedFile{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodPost, path: "/admin/print-jobs/files", raw: &buf, header: header, wrapped: true}, uploaded); err != nil {
+		return nil, err
+	}
+	return uploaded, nil
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// PrintersService 打印机接口（/admin/printers，需要 admin 或 operator 角色）
+type PrintersService struct {
+	client *Client
+}
+
+// PrinterListOptions 打印机列表筛选条件
+type PrinterListOptions struct {
+	ListOptions
+	EdgeNodeID     string
+	ApprovalStatus string
+}
+
+// List 获取一页打印机
+func (s *PrintersService) List(ctx context.Context, opts PrinterListOptions) (*Page[*PrinterInfo], error) {
+	query := opts.values()
+	if opts.EdgeNodeID != "" {
+		query.Set("edge_node_id", opts.EdgeNodeID)
+	}
+	if opts.ApprovalStatus != "" {
+		query.Set("approval_status", opts.ApprovalStatus)
+	}
+	page := &Page[*PrinterInfo]{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodGet, path: "/admin/printers", query: query, wrapped: true}, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Iter 遍历符合条件的全部打印机
+func (s *PrintersService) Iter(opts PrinterListOptions) *Iterator[*PrinterInfo] {
+	return pageIterator(opts.ListOptions, func(ctx context.Context, list ListOptions) (*Page[*PrinterInfo], error) {
+		opts.ListOptions = list
+		return s.List(ctx, opts)
+	})
+}
+
+// Get 获取打印机详情
+func (s *PrintersService) Get(ctx context.Context, id string) (*PrinterInfo, error) {
+	printer := &PrinterInfo{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodGet, path: "/admin/printers/" + pathEscape(id), wrapped: true}, printer); err != nil {
+		return nil, err
+	}
+	return printer, nil
+}
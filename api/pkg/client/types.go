@@ -0,0 +1,141 @@
+package client
+
+import (
+	"time"
+
+	"fly-print-cloud/api/internal/models"
+)
+
+// 资源类型直接使用服务端定义
+type (
+	PrintJob      = models.PrintJob
+	Printer       = models.Printer
+	EdgeNode      = models.EdgeNode
+	User          = models.User
+	JobStatus     = models.JobStatus
+	PrinterStatus = models.PrinterStatus
+	NodeStatus    = models.NodeStatus
+)
+
+// PrinterInfo 打印机列表和详情接口返回的打印机信息（包含实际可用状态）
+type PrinterInfo struct {
+	*models.Printer
+	EdgeNodeEnabled  bool                         `json:"edge_node_enabled"`
+	EdgeNodeDraining bool                         `json:"edge_node_draining"`
+	ActuallyEnabled  bool                         `json:"actually_enabled"`
+	DisabledReason   string                       `json:"disabled_reason,omitempty"`
+	ActiveJobs       *int                         `json:"active_jobs,omitempty"`
+	QueuedJobs       *int                         `json:"queued_jobs,omitempty"`
+	PowerSchedule    *models.PrinterPowerSchedule `json:"power_schedule,omitempty"`
+	Asset            *models.AssetInfo            `json:"asset,omitempty"`
+}
+
+// EdgeNodeInfo Edge Node 列表和详情接口返回的节点信息（包含连接状态）
+type EdgeNodeInfo struct {
+	*models.EdgeNode
+	PrinterCount    int               `json:"printer_count"`
+	Connected       bool              `json:"connected"`
+	ConnectionSince *time.Time        `json:"connection_since,omitempty"`
+	LastMessageAt   *time.Time        `json:"last_message_at,omitempty"`
+	Transport       string            `json:"transport,omitempty"`
+	ClockSkewMS     *int64            `json:"clock_skew_ms,omitempty"`
+	Asset           *models.AssetInfo `json:"asset,omitempty"`
+	ActiveJobs      *int              `json:"active_jobs,omitempty"`
+
+	// 以下字段仅注册接口返回
+	ConnToken          string     `json:"conn_token,omitempty"`
+	ConnTokenExpiresAt *time.Time `json:"conn_token_expires_at,omitempty"`
+}
+
+// ConnToken 云端签发的 WebSocket 连接令牌，连接 /api/v1/edge/ws 时通过 ?conn_token= 携带
+type ConnToken struct {
+	Token     string    `json:"conn_token"`
+	ExpiresAt time.Time `json:"conn_token_expires_at"`
+}
+
+// CreatePrintJobRequest 创建打印任务请求，字段含义与服务端一致
+type CreatePrintJobRequest struct {
+	Name           string                `json:"name,omitempty"`
+	PrinterID      string                `json:"printer_id,omitempty"`       // 与 printer_group_id 二选一
+	PrinterGroupID string                `json:"printer_group_id,omitempty"` // 提交到打印机组
+	Latitude       *float64              `json:"latitude,omitempty"`
+	Longitude      *float64              `json:"longitude,omitempty"`
+	FilePath       string                `json:"file_path,omitempty"`
+	FileURL        string                `json:"file_url,omitempty"`
+	StorageKey     string                `json:"storage_key,omitempty"` // UploadFile 返回的存储键
+	FileSize       int64                 `json:"file_size,omitempty"`
+	PageCount      int                   `json:"page_count,omitempty"`
+	PageRange      string                `json:"page_range,omitempty"` // 只打印的页码（如 1-5,8,11-），仅单文件任务
+	Copies         int                   `json:"copies,omitempty"`
+	PaperSize      string                `json:"paper_size,omitempty"`
+	ColorMode      string                `json:"color_mode,omitempty"`
+	DuplexMode     string                `json:"duplex_mode,omitempty"`
+	MediaType      string                `json:"media_type,omitempty"`
+	Resolution     string                `json:"resolution,omitempty"`
+	MaxRetries     int                   `json:"max_retries,omitempty"`
+	Priority       int                   `json:"priority,omitempty"`
+	OnBehalfOf     string                `json:"on_behalf_of,omitempty"`
+	Files          []PrintJobFileRequest `json:"files,omitempty"` // 多文件任务，与单文件字段二选一
+	Metadata       map[string]string     `json:"metadata,omitempty"`
+}
+
+// PrintJobFileRequest 多文件任务中的单个文件
+type PrintJobFileRequest struct {
+	FilePath   string `json:"file_path,omitempty"`
+	FileURL    string `json:"file_url,omitempty"`
+	StorageKey string `json:"storage_key,omitempty"`
+	FileSize   int64  `json:"file_size,omitempty"`
+	PageCount  int    `json:"page_count,omitempty"`
+	Copies     int    `json:"copies,omitempty"`
+}
+
+// UploadedFile 文件上传结果
+type UploadedFile struct {
+	StorageKey  string `json:"storage_key"`
+	FileName    string `json:"file_name"`
+	FileSize    int64  `json:"file_size"`
+	ContentType string `json:"content_type"`
+	Format      string `json:"format"`
+	PageCount   int    `json:"page_count,omitempty"` // 仅 PDF
+}
+
+// CreateUserRequest 创建用户请求
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"` // admin、operator 或 viewer
+}
+
+// UpdateUserRequest 更新用户请求
+type UpdateUserRequest struct {
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	Status     string `json:"status"`                // active 或 inactive
+	RowVersion *int   `json:"row_version,omitempty"` // 读取时的数据版本号，用于乐观锁
+}
+
+// RegisterEdgeNodeRequest Edge Node 注册请求
+type RegisterEdgeNodeRequest struct {
+	NodeID string `json:"node_id"`
+	Name   string `json:"name"`
+}
+
+// EdgeRegisterPrinterRequest Edge Node 注册打印机请求
+type EdgeRegisterPrinterRequest struct {
+	Name            string                     `json:"name"`
+	Model           string                     `json:"model,omitempty"`
+	SerialNumber    *string                    `json:"serial_number,omitempty"`
+	FirmwareVersion *string                    `json:"firmware_version,omitempty"`
+	PortInfo        *string                    `json:"port_info,omitempty"`
+	IPAddress       *string                    `json:"ip_address,omitempty"`
+	MACAddress      *string                    `json:"mac_address,omitempty"`
+	Capabilities    models.PrinterCapabilities `json:"capabilities"`
+}
+
+// EdgeRegisteredPrinter Edge Node 注册打印机的结果，approved 表示打印机是否已可接收任务
+type EdgeRegisteredPrinter struct {
+	*models.Printer
+	Approved bool `json:"approved"`
+}
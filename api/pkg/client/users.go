@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// UsersService 用户管理接口（/admin/users，需要 admin 角色）
+type UsersService struct {
+	client *Client
+}
+
+// List 获取一页用户
+func (s *UsersService) List(ctx context.Context, opts ListOptions) (*Page[*User], error) {
+	page := &Page[*User]{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodGet, path: "/admin/users", query: opts.values(), wrapped: true}, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Iter 遍历全部用户
+func (s *UsersService) Iter(opts ListOptions) *Iterator[*User] {
+	return pageIterator(opts, s.List)
+}
+
+// Get 获取用户详情
+func (s *UsersService) Get(ctx context.Context, id string) (*User, error) {
+	user := &User{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodGet, path: "/admin/users/" + pathEscape(id), wrapped: true}, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Create 创建本地用户
+func (s *UsersService) Create(ctx context.Context, req *CreateUserRequest) (*User, error) {
+	user := &User{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodPost, path: "/admin/users", body: req, wrapped: true}, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Update 更新用户；RowVersion 与服务端不一致时返回 409
+func (s *UsersService) Update(ctx context.Context, id string, req *UpdateUserRequest) (*User, error) {
+	user := &User{}
+	if _, err := s.client.do(ctx, &request{method: http.MethodPut, path: "/admin/users/" + pathEscape(id), body: req, wrapped: true}, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Delete 删除用户
+func (s *UsersService) Delete(ctx context.Context, id string) error {
+	_, err := s.client.do(ctx, &request{method: http.MethodDelete, path: "/admin/users/" + pathEscape(id), wrapped: true}, nil)
+	return err
+}